@@ -122,6 +122,9 @@ type state struct {
 	// controllerAccess holds the access level of the user to the connected controller.
 	controllerAccess string
 
+	// userDefaults holds the preferences of the user reported at login.
+	userDefaults *params.UserDefaults
+
 	// broken is a channel that gets closed when the connection is
 	// broken.
 	broken chan struct{}
@@ -295,6 +295,15 @@ func (c *Client) AbortCurrentUpgrade() error {
 	return c.facade.FacadeCall("AbortCurrentUpgrade", nil, nil)
 }
 
+// ApproveCurrentUpgrade approves the current upgrade to proceed,
+// if it is awaiting approval.
+func (c *Client) ApproveCurrentUpgrade() error {
+	if c.facade.BestAPIVersion() < 3 {
+		return errors.NotSupportedf("approving upgrades on this version of Juju")
+	}
+	return c.facade.FacadeCall("ApproveCurrentUpgrade", nil, nil)
+}
+
 // FindTools returns a List containing all tools matching the specified parameters.
 func (c *Client) FindTools(majorVersion, minorVersion int, series, arch, agentStream string) (result params.FindToolsResult, err error) {
 	if c.facade.BestAPIVersion() == 1 && agentStream != "" {
@@ -705,6 +705,23 @@ func (s *clientSuite) TestAbortCurrentUpgrade(c *gc.C) {
 	c.Assert(err, gc.Equals, someErr) // Confirms that the correct facade was called
 }
 
+func (s *clientSuite) TestApproveCurrentUpgrade(c *gc.C) {
+	client := s.APIState.Client()
+	someErr := errors.New("random")
+	cleanup := api.PatchClientFacadeCall(client,
+		func(request string, args interface{}, response interface{}) error {
+			c.Assert(request, gc.Equals, "ApproveCurrentUpgrade")
+			c.Assert(args, gc.IsNil)
+			c.Assert(response, gc.IsNil)
+			return someErr
+		},
+	)
+	defer cleanup()
+
+	err := client.ApproveCurrentUpgrade()
+	c.Assert(err, gc.Equals, someErr) // Confirms that the correct facade was called
+}
+
 func (s *clientSuite) TestWebsocketDialWithErrorsJSON(c *gc.C) {
 	errorResult := params.ErrorResult{
 		Error: servercommon.ServerError(errors.New("kablooie")),
@@ -848,3 +865,10 @@ func (s *IsolatedClientSuite) TestFindAllErrorsOnOlderController(c *gc.C) {
 	_, err := client.FindTools(0, 0, "", "", "proposed")
 	c.Assert(err, gc.ErrorMatches, "passing agent-stream not supported by the controller")
 }
+
+func (s *IsolatedClientSuite) TestApproveCurrentUpgradeErrorsOnOlderController(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{BestVersion: 2}
+	client := api.APIClient(apiCaller)
+	err := client.ApproveCurrentUpgrade()
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
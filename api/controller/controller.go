@@ -289,6 +289,11 @@ type MigrationSpec struct {
 	TargetUser            string
 	TargetPassword        string
 	TargetMacaroons       []macaroon.Slice
+
+	// Permissions, if set, requests that the controller and cloud
+	// access of the model's users are migrated, and applied by the
+	// target controller with this policy.
+	Permissions string
 }
 
 // Validate performs sanity checks on the migration configuration it
@@ -340,6 +345,7 @@ func (c *Client) InitiateMigration(spec MigrationSpec) (string, error) {
 				Password:        spec.TargetPassword,
 				Macaroons:       macsJSON,
 			},
+			Permissions: spec.Permissions,
 		}},
 	}
 	response := params.InitiateMigrationResults{}
@@ -382,3 +388,44 @@ func (c *Client) ControllerVersion() (ControllerVersion, error) {
 	}
 	return out, err
 }
+
+// CheckUnitStates validates the unit state documents of the specified
+// model, returning the problems found. If repair is true, the problems
+// are also fixed, with values that cannot be fixed in place quarantined.
+func (c *Client) CheckUnitStates(modelUUID string, repair bool) ([]params.UnitStateProblem, error) {
+	if c.BestAPIVersion() < 10 {
+		return nil, errors.NotSupportedf("checking unit states")
+	}
+	if !names.IsValidModel(modelUUID) {
+		return nil, errors.NotValidf("model UUID %q", modelUUID)
+	}
+	args := params.CheckUnitStatesArgs{
+		Args: []params.CheckUnitStatesArg{{
+			ModelTag: names.NewModelTag(modelUUID).String(),
+			Repair:   repair,
+		}},
+	}
+	var results params.CheckUnitStatesResults
+	if err := c.facade.FacadeCall("CheckUnitStates", args, &results); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(results.Results) != 1 {
+		return nil, errors.Errorf("expected 1 result, got %d", len(results.Results))
+	}
+	if err := results.Results[0].Error; err != nil {
+		return nil, errors.Trace(err)
+	}
+	return results.Results[0].Problems, nil
+}
+
+// UniterStateFormats reports the formats of the uniter state of the
+// units across all models of the controller, and whether every unit
+// agent has moved its state into the controller.
+func (c *Client) UniterStateFormats() (params.UniterStateFormatsResult, error) {
+	var result params.UniterStateFormatsResult
+	if c.BestAPIVersion() < 11 {
+		return result, errors.NotSupportedf("reporting uniter state formats")
+	}
+	err := c.facade.FacadeCall("UniterStateFormats", nil, &result)
+	return result, errors.Trace(err)
+}
@@ -419,3 +419,103 @@ func (s *Suite) TestWatchAllModelSummaries(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, "some error")
 	c.Assert(watcher, gc.IsNil)
 }
+
+func (s *Suite) TestCheckUnitStates(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		BestVersion: 10,
+		APICallerFunc: func(objType string, version int, id, request string, args, result interface{}) error {
+			c.Check(objType, gc.Equals, "Controller")
+			c.Check(version, gc.Equals, 10)
+			c.Check(request, gc.Equals, "CheckUnitStates")
+			c.Check(args, jc.DeepEquals, params.CheckUnitStatesArgs{
+				Args: []params.CheckUnitStatesArg{{
+					ModelTag: coretesting.ModelTag.String(),
+					Repair:   true,
+				}},
+			})
+			c.Assert(result, gc.FitsTypeOf, &params.CheckUnitStatesResults{})
+			*(result.(*params.CheckUnitStatesResults)) = params.CheckUnitStatesResults{
+				Results: []params.CheckUnitStatesResult{{
+					Problems: []params.UnitStateProblem{{
+						UnitTag: "unit-mysql-0",
+						Field:   "state",
+						Key:     "a.b",
+						Detail:  `unescaped key "a.b"`,
+						Fix:     "repair",
+					}},
+				}},
+			}
+			return nil
+		},
+	}
+	client := controller.NewClient(apiCaller)
+	problems, err := client.CheckUnitStates(coretesting.ModelTag.Id(), true)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(problems, jc.DeepEquals, []params.UnitStateProblem{{
+		UnitTag: "unit-mysql-0",
+		Field:   "state",
+		Key:     "a.b",
+		Detail:  `unescaped key "a.b"`,
+		Fix:     "repair",
+	}})
+}
+
+func (s *Suite) TestCheckUnitStatesError(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		BestVersion: 10,
+		APICallerFunc: func(objType string, version int, id, request string, args, result interface{}) error {
+			*(result.(*params.CheckUnitStatesResults)) = params.CheckUnitStatesResults{
+				Results: []params.CheckUnitStatesResult{{
+					Error: common.ServerError(errors.New("boom")),
+				}},
+			}
+			return nil
+		},
+	}
+	client := controller.NewClient(apiCaller)
+	_, err := client.CheckUnitStates(coretesting.ModelTag.Id(), false)
+	c.Assert(err, gc.ErrorMatches, "boom")
+}
+
+func (s *Suite) TestCheckUnitStatesAgainstOlderAPIVersion(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{BestVersion: 9}
+	client := controller.NewClient(apiCaller)
+	_, err := client.CheckUnitStates(coretesting.ModelTag.Id(), false)
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *Suite) TestUniterStateFormats(c *gc.C) {
+	expected := params.UniterStateFormatsResult{
+		CurrentFormat: 2,
+		Models: []params.ModelUniterStateFormats{{
+			ModelTag: coretesting.ModelTag.String(),
+			Formats: []params.UniterStateFormatCount{
+				{Format: 0, Units: 1},
+				{Format: 2, Units: 3},
+			},
+		}},
+	}
+	apiCaller := apitesting.BestVersionCaller{
+		BestVersion: 11,
+		APICallerFunc: func(objType string, version int, id, request string, args, result interface{}) error {
+			c.Check(objType, gc.Equals, "Controller")
+			c.Check(version, gc.Equals, 11)
+			c.Check(request, gc.Equals, "UniterStateFormats")
+			c.Check(args, gc.IsNil)
+			c.Assert(result, gc.FitsTypeOf, &params.UniterStateFormatsResult{})
+			*(result.(*params.UniterStateFormatsResult)) = expected
+			return nil
+		},
+	}
+	client := controller.NewClient(apiCaller)
+	formats, err := client.UniterStateFormats()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(formats, jc.DeepEquals, expected)
+}
+
+func (s *Suite) TestUniterStateFormatsAgainstOlderAPIVersion(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{BestVersion: 10}
+	client := controller.NewClient(apiCaller)
+	_, err := client.UniterStateFormats()
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
@@ -396,7 +396,7 @@ func (s *CrossModelRelationsSuite) TestWatchRelationChangesV1Fallback(c *gc.C) {
 						RelationUnitsWatcherId: "123",
 						Changes: params.RelationUnitsChange{
 							Changed: map[string]params.UnitSettings{
-								"app/1": {456},
+								"app/1": {Version: 456},
 							},
 						},
 					}},
@@ -33,9 +33,9 @@ var facadeVersions = map[string]int{
 	"CharmRevisionUpdater":         2,
 	"Charms":                       2,
 	"Cleaner":                      2,
-	"Client":                       2,
+	"Client":                       3,
 	"Cloud":                        6,
-	"Controller":                   9,
+	"Controller":                   11,
 	"CredentialManager":            1,
 	"CredentialValidator":          2,
 	"CrossController":              1,
@@ -70,10 +70,10 @@ var facadeVersions = map[string]int{
 	"MetricsDebug":                 2,
 	"MetricsManager":               1,
 	"MigrationFlag":                1,
-	"MigrationMaster":              2,
+	"MigrationMaster":              3,
 	"MigrationMinion":              1,
 	"MigrationStatusWatcher":       1,
-	"MigrationTarget":              1,
+	"MigrationTarget":              2,
 	"ModelConfig":                  2,
 	"ModelGeneration":              4,
 	"ModelManager":                 8,
@@ -105,11 +105,12 @@ var facadeVersions = map[string]int{
 	"Subnets":                      4,
 	"Undertaker":                   1,
 	"UnitAssigner":                 1,
-	"Uniter":                       15,
+	"Uniter":                       19,
 	"Upgrader":                     1,
+	"UpgradeInfo":                  1,
 	"UpgradeSeries":                1,
-	"UpgradeSteps":                 1,
-	"UserManager":                  2,
+	"UpgradeSteps":                 2,
+	"UserManager":                  21,
 	"VolumeAttachmentsWatcher":     2,
 	"VolumeAttachmentPlansWatcher": 1,
 }
@@ -25,6 +25,7 @@ import (
 	"github.com/juju/juju/api/unitassigner"
 	"github.com/juju/juju/api/uniter"
 	"github.com/juju/juju/api/upgrader"
+	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/core/network"
 	"github.com/juju/juju/rpc/jsoncodec"
 )
@@ -295,6 +296,11 @@ type Connection interface {
 	// ControllerAccess returns the access level of authorized user to the controller.
 	ControllerAccess() string
 
+	// UserDefaults returns the preferences the authorized user has stored
+	// on the controller, as reported at login. It returns nil if there
+	// are none.
+	UserDefaults() *params.UserDefaults
+
 	// CookieURL returns the URL that HTTP cookies for the API will be
 	// associated with.
 	CookieURL() *url.URL
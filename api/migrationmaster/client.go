@@ -130,6 +130,23 @@ func (c *Client) SetStatusMessage(message string) error {
 	return c.caller.FacadeCall("SetStatusMessage", args, nil)
 }
 
+// SetImportProgress records the progress of the transfer of the
+// model to the target controller of the migration.
+func (c *Client) SetImportProgress(progress migration.ImportProgress) error {
+	args := params.MigrationImportProgress{
+		Entities:      make([]params.EntityImportProgress, len(progress.Entities)),
+		BinariesBytes: progress.BinariesBytes,
+	}
+	for i, entity := range progress.Entities {
+		args.Entities[i] = params.EntityImportProgress{
+			Kind:     entity.Kind,
+			Imported: entity.Imported,
+			Total:    entity.Total,
+		}
+	}
+	return c.caller.FacadeCall("SetImportProgress", args, nil)
+}
+
 // ModelInfo return basic information about the model to migrated.
 func (c *Client) ModelInfo() (migration.ModelInfo, error) {
 	var info params.MigrationModelInfo
@@ -182,11 +199,35 @@ func (c *Client) Export() (migration.SerializedModel, error) {
 		return empty, errors.Trace(err)
 	}
 
+	manifest, err := convertManifest(serialized.Manifest)
+	if err != nil {
+		return empty, errors.Trace(err)
+	}
+
 	return migration.SerializedModel{
 		Bytes:     serialized.Bytes,
 		Charms:    serialized.Charms,
 		Tools:     tools,
 		Resources: resources,
+		Manifest:  manifest,
+	}, nil
+}
+
+// convertManifest returns the export manifest described by the
+// params, or nil if the controller did not provide one.
+func convertManifest(in *params.SerializedModelManifest) (*migration.ExportManifest, error) {
+	if in == nil {
+		return nil, nil
+	}
+	exporterVersion, err := version.Parse(in.ExporterVersion)
+	if err != nil {
+		return nil, errors.Annotate(err, "error parsing exporter version")
+	}
+	return &migration.ExportManifest{
+		ExporterVersion: exporterVersion,
+		Exported:        in.Exported,
+		Hash:            in.Hash,
+		Sections:        in.Sections,
 	}, nil
 }
 
@@ -164,6 +164,31 @@ func (s *ClientSuite) TestSetStatusMessage(c *gc.C) {
 	})
 }
 
+func (s *ClientSuite) TestSetImportProgress(c *gc.C) {
+	var stub jujutesting.Stub
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		stub.AddCall(objType+"."+request, id, arg)
+		return nil
+	})
+	client := migrationmaster.NewClient(apiCaller, nil)
+	err := client.SetImportProgress(migration.ImportProgress{
+		Entities: []migration.EntityProgress{
+			{Kind: migration.ImportUnits, Imported: 4, Total: 8},
+		},
+		BinariesBytes: 2048,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	expectedArg := params.MigrationImportProgress{
+		Entities: []params.EntityImportProgress{
+			{Kind: "units", Imported: 4, Total: 8},
+		},
+		BinariesBytes: 2048,
+	}
+	stub.CheckCalls(c, []jujutesting.StubCall{
+		{"MigrationMaster.SetImportProgress", []interface{}{"", expectedArg}},
+	})
+}
+
 func (s *ClientSuite) TestSetStatusMessageError(c *gc.C) {
 	apiCaller := apitesting.APICallerFunc(func(string, int, string, string, interface{}, interface{}) error {
 		return errors.New("boom")
@@ -367,6 +392,32 @@ func (s *ClientSuite) TestExport(c *gc.C) {
 	})
 }
 
+func (s *ClientSuite) TestExportManifest(c *gc.C) {
+	exported := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	apiCaller := apitesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		out := result.(*params.SerializedModel)
+		*out = params.SerializedModel{
+			Bytes: []byte("foo: bar\n"),
+			Manifest: &params.SerializedModelManifest{
+				ExporterVersion: "2.8.0",
+				Exported:        exported,
+				Hash:            "abcd",
+				Sections:        map[string]string{"foo": "ef01"},
+			},
+		}
+		return nil
+	})
+	client := migrationmaster.NewClient(apiCaller, nil)
+	out, err := client.Export()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(out.Manifest, jc.DeepEquals, &migration.ExportManifest{
+		ExporterVersion: version.MustParse("2.8.0"),
+		Exported:        exported,
+		Hash:            "abcd",
+		Sections:        map[string]string{"foo": "ef01"},
+	})
+}
+
 func (s *ClientSuite) TestExportError(c *gc.C) {
 	apiCaller := apitesting.APICallerFunc(func(string, int, string, string, interface{}, interface{}) error {
 		return errors.New("blam")
@@ -54,9 +54,18 @@ func (c *Client) Prechecks(model coremigration.ModelInfo) error {
 }
 
 // Import takes a serialized model and imports it into the target
-// controller.
-func (c *Client) Import(bytes []byte) error {
+// controller. If the manifest is not nil, the target controller
+// verifies the serialized model against it before importing it.
+func (c *Client) Import(bytes []byte, manifest *coremigration.ExportManifest) error {
 	serialized := params.SerializedModel{Bytes: bytes}
+	if manifest != nil {
+		serialized.Manifest = &params.SerializedModelManifest{
+			ExporterVersion: manifest.ExporterVersion.String(),
+			Exported:        manifest.Exported,
+			Hash:            manifest.Hash,
+			Sections:        manifest.Sections,
+		}
+	}
 	return errors.Trace(c.caller.FacadeCall("Import", serialized, nil))
 }
 
@@ -203,6 +212,31 @@ func (c *Client) LatestLogTime(modelUUID string) (time.Time, error) {
 	return result, nil
 }
 
+// ImportProgress asks the target controller how many of the entities
+// of each kind have been imported into the model so far. Target
+// controllers which don't report the progress of imports return a
+// NotSupported error.
+func (c *Client) ImportProgress(modelUUID string) ([]coremigration.EntityProgress, error) {
+	if c.caller.BestAPIVersion() < 2 {
+		return nil, errors.NotSupportedf("ImportProgress")
+	}
+	var result params.MigrationImportProgress
+	args := params.ModelArgs{ModelTag: names.NewModelTag(modelUUID).String()}
+	err := c.caller.FacadeCall("ImportProgress", args, &result)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	progress := make([]coremigration.EntityProgress, len(result.Entities))
+	for i, entity := range result.Entities {
+		progress[i] = coremigration.EntityProgress{
+			Kind:     entity.Kind,
+			Imported: entity.Imported,
+			Total:    entity.Total,
+		}
+	}
+	return progress, nil
+}
+
 // AdoptResources asks the cloud provider to update the controller
 // tags for a model's resources. This prevents the resources from
 // being destroyed if the source controller is destroyed after the
@@ -81,7 +81,7 @@ func (s *ClientSuite) TestPrechecks(c *gc.C) {
 func (s *ClientSuite) TestImport(c *gc.C) {
 	client, stub := s.getClientAndStub(c)
 
-	err := client.Import([]byte("foo"))
+	err := client.Import([]byte("foo"), nil)
 
 	expectedArg := params.SerializedModel{Bytes: []byte("foo")}
 	stub.CheckCalls(c, []jujutesting.StubCall{
@@ -90,6 +90,28 @@ func (s *ClientSuite) TestImport(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, "boom")
 }
 
+func (s *ClientSuite) TestImportWithManifest(c *gc.C) {
+	client, stub := s.getClientAndStub(c)
+
+	exported := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	manifest := coremigration.NewExportManifest([]byte("foo: bar\n"), version.MustParse("2.8.0"), exported)
+	err := client.Import([]byte("foo: bar\n"), &manifest)
+
+	expectedArg := params.SerializedModel{
+		Bytes: []byte("foo: bar\n"),
+		Manifest: &params.SerializedModelManifest{
+			ExporterVersion: "2.8.0",
+			Exported:        exported,
+			Hash:            manifest.Hash,
+			Sections:        manifest.Sections,
+		},
+	}
+	stub.CheckCalls(c, []jujutesting.StubCall{
+		{"MigrationTarget.Import", []interface{}{"", expectedArg}},
+	})
+	c.Assert(err, gc.ErrorMatches, "boom")
+}
+
 func (s *ClientSuite) TestAbort(c *gc.C) {
 	client, stub := s.getClientAndStub(c)
 
@@ -137,6 +159,39 @@ func (s *ClientSuite) TestLatestLogTime(c *gc.C) {
 	s.AssertModelCall(c, &stub, names.NewModelTag("fake"), "LatestLogTime", err, false)
 }
 
+func (s *ClientSuite) TestImportProgress(c *gc.C) {
+	var stub jujutesting.Stub
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			stub.AddCall(objType+"."+request, id, arg)
+			out := result.(*params.MigrationImportProgress)
+			*out = params.MigrationImportProgress{
+				Entities: []params.EntityImportProgress{
+					{Kind: "machines", Imported: 1, Total: 3},
+				},
+			}
+			return nil
+		},
+		BestVersion: 2,
+	}
+	client := migrationtarget.NewClient(apiCaller)
+	progress, err := client.ImportProgress("fake")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(progress, jc.DeepEquals, []coremigration.EntityProgress{
+		{Kind: coremigration.ImportMachines, Imported: 1, Total: 3},
+	})
+	stub.CheckCalls(c, []jujutesting.StubCall{
+		{"MigrationTarget.ImportProgress", []interface{}{"", params.ModelArgs{ModelTag: names.NewModelTag("fake").String()}}},
+	})
+}
+
+func (s *ClientSuite) TestImportProgressNotSupported(c *gc.C) {
+	client, stub := s.getClientAndStub(c)
+	_, err := client.ImportProgress("fake")
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+	stub.CheckNoCalls(c)
+}
+
 func (s *ClientSuite) TestLatestLogTimeError(c *gc.C) {
 	client, stub := s.getClientAndStub(c)
 	result, err := client.LatestLogTime("fake")
@@ -148,6 +148,7 @@ func (st *state) Login(tag names.Tag, password, nonce string, macaroons []macaro
 
 	var controllerAccess string
 	var modelAccess string
+	var userDefaults *params.UserDefaults
 	if result.UserInfo != nil {
 		tag, err = names.ParseTag(result.UserInfo.Identity)
 		if err != nil {
@@ -155,6 +156,7 @@ func (st *state) Login(tag names.Tag, password, nonce string, macaroons []macaro
 		}
 		controllerAccess = result.UserInfo.ControllerAccess
 		modelAccess = result.UserInfo.ModelAccess
+		userDefaults = result.UserInfo.Defaults
 	}
 	servers := params.ToMachineHostsPorts(result.Servers)
 	if err = st.setLoginResult(loginResultParams{
@@ -166,6 +168,7 @@ func (st *state) Login(tag names.Tag, password, nonce string, macaroons []macaro
 		facades:          result.Facades,
 		modelAccess:      modelAccess,
 		controllerAccess: controllerAccess,
+		userDefaults:     userDefaults,
 	}); err != nil {
 		return errors.Trace(err)
 	}
@@ -182,6 +185,7 @@ type loginResultParams struct {
 	controllerTag    string
 	modelAccess      string
 	controllerAccess string
+	userDefaults     *params.UserDefaults
 	servers          []network.MachineHostPorts
 	facades          []params.FacadeVersions
 	publicDNSName    string
@@ -207,6 +211,7 @@ func (st *state) setLoginResult(p loginResultParams) error {
 	st.controllerTag = ctag
 	st.controllerAccess = p.controllerAccess
 	st.modelAccess = p.modelAccess
+	st.userDefaults = p.userDefaults
 
 	hostPorts, err := addAddress(p.servers, st.addr)
 	if err != nil {
@@ -242,6 +247,12 @@ func (st *state) ControllerAccess() string {
 	return st.controllerAccess
 }
 
+// UserDefaults returns the preferences the authorized user has stored
+// on the controller, as reported at login.
+func (st *state) UserDefaults() *params.UserDefaults {
+	return st.userDefaults
+}
+
 // CookieURL returns the URL that HTTP cookies for the API will be
 // associated with.
 func (st *state) CookieURL() *url.URL {
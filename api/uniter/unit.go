@@ -865,6 +865,61 @@ func (u *Unit) State() (params.UnitStateResult, error) {
 	return result, nil
 }
 
+// PeerUnitStates returns the state persisted by the charm running in each
+// of the supplied units of this unit's application, in the same order.
+// Only the application's leader may read the state of its peers.
+func (u *Unit) PeerUnitStates(peers []names.UnitTag) ([]params.UnitStateResult, error) {
+	if u.st.facade.BestAPIVersion() < 17 {
+		return nil, errors.NotImplementedf("PeerUnitStates() (need V17+)")
+	}
+	args := params.Entities{
+		Entities: make([]params.Entity, len(peers)),
+	}
+	for i, peer := range peers {
+		args.Entities[i].Tag = peer.String()
+	}
+	var results params.UnitStateResults
+	if err := u.st.facade.FacadeCall("PeerUnitStates", args, &results); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(results.Results) != len(peers) {
+		return nil, errors.Errorf("expected %d results, got %d", len(peers), len(results.Results))
+	}
+	return results.Results, nil
+}
+
+// AdjustCharmCounter atomically adds delta to the counter with the input
+// key shared by the units of this unit's application, and returns its new
+// value. If floor or ceiling are not nil, the counter is left unchanged,
+// and an error satisfying params.IsCodeCharmCounterLimit is returned, if
+// the new value would be beyond them.
+func (u *Unit) AdjustCharmCounter(key string, delta int64, floor, ceiling *int64) (int64, error) {
+	if u.st.facade.BestAPIVersion() < 18 {
+		return 0, errors.NotImplementedf("AdjustCharmCounter() (need V18+)")
+	}
+	args := params.AdjustCharmCounterArgs{
+		Args: []params.AdjustCharmCounterArg{{
+			Tag:     u.tag.String(),
+			Key:     key,
+			Delta:   delta,
+			Floor:   floor,
+			Ceiling: ceiling,
+		}},
+	}
+	var results params.CharmCounterResults
+	if err := u.st.facade.FacadeCall("AdjustCharmCounters", args, &results); err != nil {
+		return 0, errors.Trace(err)
+	}
+	if len(results.Results) != 1 {
+		return 0, errors.Errorf("expected 1 result, got %d", len(results.Results))
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.Value, nil
+}
+
 // SetState sets the state persisted by the charm running in this unit
 // and the state internal to the uniter for this unit.
 func (u *Unit) SetState(unitState params.SetUnitStateArg) error {
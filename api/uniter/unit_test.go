@@ -1027,7 +1027,7 @@ func (s *unitSuite) TestStateSingleResult(c *gc.C) {
 			result := results.(*params.UnitStateResults)
 			result.Results = make([]params.UnitStateResult, 1)
 			result.Results[0].State = expectedUnitState
-			result.Results[0].UniterState = expectedUniterState
+			result.Results[0].UniterState = &expectedUniterState
 			return nil
 		},
 	)
@@ -1035,7 +1035,9 @@ func (s *unitSuite) TestStateSingleResult(c *gc.C) {
 	obtainedUnitState, err := s.apiUnit.State()
 	c.Assert(err, jc.ErrorIsNil)
 	c.Assert(expectedUnitState, gc.DeepEquals, obtainedUnitState.State)
-	c.Assert(expectedUniterState, gc.DeepEquals, obtainedUnitState.UniterState)
+	c.Assert(obtainedUnitState.UniterState, gc.NotNil)
+	c.Assert(*obtainedUnitState.UniterState, gc.Equals, expectedUniterState)
+	c.Assert(obtainedUnitState.StorageState, gc.IsNil)
 }
 
 func (s *unitSuite) TestStateMultipleReturnsError(c *gc.C) {
@@ -1051,6 +1053,71 @@ func (s *unitSuite) TestStateMultipleReturnsError(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, "expected 1 result, got 2")
 }
 
+func (s *unitSuite) TestPeerUnitStates(c *gc.C) {
+	uniter.PatchUnitResponse(s, s.apiUnit, "PeerUnitStates",
+		func(results interface{}) error {
+			result := results.(*params.UnitStateResults)
+			result.Results = []params.UnitStateResult{
+				{State: map[string]string{"primary-candidate": "true"}},
+				{Error: &params.Error{Message: "permission denied", Code: params.CodeUnauthorized}},
+			}
+			return nil
+		},
+	)
+
+	results, err := s.apiUnit.PeerUnitStates([]names.UnitTag{
+		names.NewUnitTag("wordpress/1"),
+		names.NewUnitTag("mysql/0"),
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.HasLen, 2)
+	c.Assert(results[0].State, gc.DeepEquals, map[string]string{"primary-candidate": "true"})
+	c.Assert(results[1].Error, jc.Satisfies, params.IsCodeUnauthorized)
+}
+
+func (s *unitSuite) TestPeerUnitStatesWrongResultCount(c *gc.C) {
+	uniter.PatchUnitResponse(s, s.apiUnit, "PeerUnitStates",
+		func(results interface{}) error {
+			result := results.(*params.UnitStateResults)
+			result.Results = make([]params.UnitStateResult, 2)
+			return nil
+		},
+	)
+
+	_, err := s.apiUnit.PeerUnitStates([]names.UnitTag{names.NewUnitTag("wordpress/1")})
+	c.Assert(err, gc.ErrorMatches, "expected 1 results, got 2")
+}
+
+func (s *unitSuite) TestAdjustCharmCounter(c *gc.C) {
+	ceiling := int64(10)
+	uniter.PatchUnitResponse(s, s.apiUnit, "AdjustCharmCounters",
+		func(results interface{}) error {
+			result := results.(*params.CharmCounterResults)
+			result.Results = []params.CharmCounterResult{{Value: 4}}
+			return nil
+		},
+	)
+
+	value, err := s.apiUnit.AdjustCharmCounter("next-id", 1, nil, &ceiling)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, int64(4))
+}
+
+func (s *unitSuite) TestAdjustCharmCounterLimit(c *gc.C) {
+	uniter.PatchUnitResponse(s, s.apiUnit, "AdjustCharmCounters",
+		func(results interface{}) error {
+			result := results.(*params.CharmCounterResults)
+			result.Results = []params.CharmCounterResult{{
+				Error: &params.Error{Message: "charm counter limit reached", Code: params.CodeCharmCounterLimit},
+			}}
+			return nil
+		},
+	)
+
+	_, err := s.apiUnit.AdjustCharmCounter("next-id", 1, nil, nil)
+	c.Assert(err, jc.Satisfies, params.IsCodeCharmCounterLimit)
+}
+
 func (s *unitSuite) TestSetStateSingleResult(c *gc.C) {
 	uniter.PatchUnitResponse(s, s.apiUnit, "SetState",
 		func(results interface{}) error {
@@ -421,6 +421,56 @@ func (st *State) WatchRelationUnits(
 	return w, nil
 }
 
+// WatchRelationIngressNetworks returns a watcher notifying of changes to
+// the ingress CIDRs of the relation, which are only set for cross model
+// relations.
+func (st *State) WatchRelationIngressNetworks(
+	relationTag names.RelationTag,
+	unitTag names.UnitTag,
+) (watcher.StringsWatcher, error) {
+	return st.watchRelationNetworks("WatchRelationIngressNetworks", relationTag, unitTag)
+}
+
+// WatchRelationEgressNetworks returns a watcher notifying of changes to
+// the egress CIDRs of the relation, which are only set for cross model
+// relations.
+func (st *State) WatchRelationEgressNetworks(
+	relationTag names.RelationTag,
+	unitTag names.UnitTag,
+) (watcher.StringsWatcher, error) {
+	return st.watchRelationNetworks("WatchRelationEgressNetworks", relationTag, unitTag)
+}
+
+func (st *State) watchRelationNetworks(
+	methodName string,
+	relationTag names.RelationTag,
+	unitTag names.UnitTag,
+) (watcher.StringsWatcher, error) {
+	if st.facade.BestAPIVersion() < 19 {
+		return nil, errors.NotImplementedf("%s() (need V19+)", methodName)
+	}
+	var results params.StringsWatchResults
+	args := params.RelationUnits{
+		RelationUnits: []params.RelationUnit{{
+			Relation: relationTag.String(),
+			Unit:     unitTag.String(),
+		}},
+	}
+	err := st.facade.FacadeCall(methodName, args, &results)
+	if err != nil {
+		return nil, err
+	}
+	if len(results.Results) != 1 {
+		return nil, fmt.Errorf("expected 1 result, got %d", len(results.Results))
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	w := apiwatcher.NewStringsWatcher(st.facade.RawAPICaller(), result)
+	return w, nil
+}
+
 // ErrIfNotVersionFn returns a function which can be used to check for
 // the minimum supported version, and, if appropriate, generate an
 // error.
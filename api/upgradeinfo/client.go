@@ -0,0 +1,106 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgradeinfo
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/version"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/api/base"
+	apiwatcher "github.com/juju/juju/api/watcher"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/core/watcher"
+)
+
+// Info describes a controller upgrade in progress.
+type Info struct {
+	// InProgress is true if the controller is being upgraded.
+	// The other fields are only set if InProgress is true.
+	InProgress bool
+
+	PreviousVersion version.Number
+	TargetVersion   version.Number
+	Status          string
+	Started         time.Time
+
+	// DeferredMigrations holds the model migrations refused
+	// while the controller database is being upgraded.
+	DeferredMigrations []DeferredMigration
+}
+
+// DeferredMigration describes a model migration refused because the
+// controller database was being upgraded.
+type DeferredMigration struct {
+	ModelUUID string
+
+	// Direction is "outgoing" for migrations from the
+	// controller, and "incoming" for those to it.
+	Direction string
+	Attempted time.Time
+}
+
+// Client provides access to the UpgradeInfo API facade.
+type Client struct {
+	facade base.FacadeCaller
+}
+
+// NewClient returns a new UpgradeInfo client.
+func NewClient(caller base.APICaller) *Client {
+	return &Client{
+		facade: base.NewFacadeCaller(caller, "UpgradeInfo"),
+	}
+}
+
+// UpgradeInfo returns details of the controller upgrade in progress.
+func (c *Client) UpgradeInfo() (Info, error) {
+	var result params.UpgradeInfoResult
+	if err := c.facade.FacadeCall("UpgradeInfo", nil, &result); err != nil {
+		return Info{}, errors.Trace(err)
+	}
+	if !result.InProgress {
+		return Info{}, nil
+	}
+	info := Info{
+		InProgress: true,
+		Status:     result.Status,
+	}
+	var err error
+	if info.PreviousVersion, err = version.Parse(result.PreviousVersion); err != nil {
+		return Info{}, errors.Annotate(err, "parsing previous version")
+	}
+	if info.TargetVersion, err = version.Parse(result.TargetVersion); err != nil {
+		return Info{}, errors.Annotate(err, "parsing target version")
+	}
+	if result.Started != nil {
+		info.Started = *result.Started
+	}
+	for _, mig := range result.DeferredMigrations {
+		modelTag, err := names.ParseModelTag(mig.ModelTag)
+		if err != nil {
+			return Info{}, errors.Trace(err)
+		}
+		info.DeferredMigrations = append(info.DeferredMigrations, DeferredMigration{
+			ModelUUID: modelTag.Id(),
+			Direction: mig.Direction,
+			Attempted: mig.Attempted,
+		})
+	}
+	return info, nil
+}
+
+// WatchUpgradeInfo returns a NotifyWatcher that fires whenever a
+// controller upgrade is started, changes status, or is completed.
+func (c *Client) WatchUpgradeInfo() (watcher.NotifyWatcher, error) {
+	var result params.NotifyWatchResult
+	if err := c.facade.FacadeCall("WatchUpgradeInfo", nil, &result); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return apiwatcher.NewNotifyWatcher(c.facade.RawAPICaller(), result), nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgradeinfo_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/version"
+	gc "gopkg.in/check.v1"
+
+	basetesting "github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/upgradeinfo"
+	"github.com/juju/juju/apiserver/params"
+)
+
+type clientSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&clientSuite{})
+
+func (s *clientSuite) TestUpgradeInfo(c *gc.C) {
+	started := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	apiCaller := basetesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "UpgradeInfo")
+		c.Check(id, gc.Equals, "")
+		c.Check(request, gc.Equals, "UpgradeInfo")
+		c.Check(arg, gc.IsNil)
+		c.Assert(result, gc.FitsTypeOf, &params.UpgradeInfoResult{})
+		*(result.(*params.UpgradeInfoResult)) = params.UpgradeInfoResult{
+			InProgress:      true,
+			PreviousVersion: "2.7.5",
+			TargetVersion:   "2.8.0",
+			Status:          "pending",
+			Started:         &started,
+			DeferredMigrations: []params.DeferredMigration{{
+				ModelTag:  "model-deadbeef-0bad-400d-8000-4b1d0d06f00d",
+				Direction: "outgoing",
+				Attempted: started,
+			}},
+		}
+		return nil
+	})
+	info, err := upgradeinfo.NewClient(apiCaller).UpgradeInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info, jc.DeepEquals, upgradeinfo.Info{
+		InProgress:      true,
+		PreviousVersion: version.MustParse("2.7.5"),
+		TargetVersion:   version.MustParse("2.8.0"),
+		Status:          "pending",
+		Started:         started,
+		DeferredMigrations: []upgradeinfo.DeferredMigration{{
+			ModelUUID: "deadbeef-0bad-400d-8000-4b1d0d06f00d",
+			Direction: "outgoing",
+			Attempted: started,
+		}},
+	})
+}
+
+func (s *clientSuite) TestUpgradeInfoNotInProgress(c *gc.C) {
+	apiCaller := basetesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		return nil
+	})
+	info, err := upgradeinfo.NewClient(apiCaller).UpgradeInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info, jc.DeepEquals, upgradeinfo.Info{})
+}
+
+func (s *clientSuite) TestUpgradeInfoError(c *gc.C) {
+	apiCaller := basetesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		return errors.New("boom")
+	})
+	_, err := upgradeinfo.NewClient(apiCaller).UpgradeInfo()
+	c.Assert(err, gc.ErrorMatches, "boom")
+}
+
+func (s *clientSuite) TestWatchUpgradeInfoError(c *gc.C) {
+	apiCaller := basetesting.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(request, gc.Equals, "WatchUpgradeInfo")
+		*(result.(*params.NotifyWatchResult)) = params.NotifyWatchResult{
+			Error: &params.Error{Message: "denied"},
+		}
+		return nil
+	})
+	_, err := upgradeinfo.NewClient(apiCaller).WatchUpgradeInfo()
+	c.Assert(err, gc.ErrorMatches, "denied")
+}
@@ -44,3 +44,20 @@ func (c *Client) ResetKVMMachineModificationStatusIdle(tag names.Tag) error {
 	}
 	return nil
 }
+
+// UpgradeStepCollections returns the database collections read and written
+// by each of the upgrade steps run for the upgrade in progress.
+func (c *Client) UpgradeStepCollections() (params.UpgradeStepCollectionsResult, error) {
+	var result params.UpgradeStepCollectionsResult
+	if c.facade.BestAPIVersion() < 2 {
+		return result, errors.NotSupportedf("UpgradeStepCollections on this controller")
+	}
+	err := c.facade.FacadeCall("UpgradeStepCollections", nil, &result)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	if result.Error != nil {
+		return result, result.Error
+	}
+	return result, nil
+}
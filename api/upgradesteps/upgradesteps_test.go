@@ -5,6 +5,7 @@ package upgradesteps_test
 
 import (
 	"github.com/golang/mock/gomock"
+	"github.com/juju/errors"
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 	"gopkg.in/juju/names.v3"
@@ -52,6 +53,38 @@ func (s *upgradeStepsSuite) TestResetKVMMachineModificationStatusIdleError(c *gc
 	c.Assert(err, gc.ErrorMatches, "did not find")
 }
 
+func (s *upgradeStepsSuite) TestUpgradeStepCollections(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	resultSource := params.UpgradeStepCollectionsResult{
+		PreviousVersion: "2.7.6",
+		TargetVersion:   "2.8.0",
+		Steps: []params.UpgradeStepCollections{{
+			Description: "increment tasks sequence by 1",
+			Read:        []string{"sequence"},
+			Write:       []string{"sequence"},
+		}},
+	}
+	fExp := s.fCaller.EXPECT()
+	fExp.BestAPIVersion().Return(2)
+	fExp.FacadeCall("UpgradeStepCollections", nil, gomock.Any()).SetArg(2, resultSource)
+
+	client := upgradesteps.NewClientFromFacade(s.fCaller)
+	result, err := client.UpgradeStepCollections()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.DeepEquals, resultSource)
+}
+
+func (s *upgradeStepsSuite) TestUpgradeStepCollectionsNotSupported(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	s.fCaller.EXPECT().BestAPIVersion().Return(1)
+
+	client := upgradesteps.NewClientFromFacade(s.fCaller)
+	_, err := client.UpgradeStepCollections()
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
 func (s *upgradeStepsSuite) setupMocks(c *gc.C) *gomock.Controller {
 	ctrl := gomock.NewController(c)
 	s.fCaller = mocks.NewMockFacadeCaller(ctrl)
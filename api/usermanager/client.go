@@ -6,10 +6,14 @@ package usermanager
 import (
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/juju/clock"
 	"github.com/juju/errors"
 	"github.com/juju/loggo"
 	"gopkg.in/juju/names.v3"
+	"gopkg.in/macaroon-bakery.v2/bakery"
+	"gopkg.in/macaroon.v2"
 
 	"github.com/juju/juju/api/base"
 	"github.com/juju/juju/apiserver/params"
@@ -35,16 +39,45 @@ func NewClient(st base.APICallCloser) *Client {
 func (c *Client) AddUser(
 	username, displayName, password string,
 ) (_ names.UserTag, secretKey []byte, _ error) {
-	if !names.IsValidUser(username) {
-		return names.UserTag{}, nil, fmt.Errorf("invalid user name %q", username)
+	return c.addUser(params.AddUser{
+		Username:    username,
+		DisplayName: displayName,
+		Password:    password,
+	})
+}
+
+// AddUserWithModelAccess adds a user as AddUser does, granting the
+// user the access to the models with the given UUIDs. Users granted
+// user-admin access to models may only add users in this way.
+func (c *Client) AddUserWithModelAccess(
+	username, displayName, password, access string, modelUUIDs ...string,
+) (_ names.UserTag, secretKey []byte, _ error) {
+	if c.BestAPIVersion() < 16 {
+		return names.UserTag{}, nil, errors.NotSupportedf("adding users with model access")
+	}
+	modelTags := make([]string, len(modelUUIDs))
+	for i, uuid := range modelUUIDs {
+		if !names.IsValidModel(uuid) {
+			return names.UserTag{}, nil, errors.NotValidf("model UUID %q", uuid)
+		}
+		modelTags[i] = names.NewModelTag(uuid).String()
+	}
+	return c.addUser(params.AddUser{
+		Username:    username,
+		DisplayName: displayName,
+		Password:    password,
+		ModelTags:   modelTags,
+		ModelAccess: params.UserAccessPermission(access),
+	})
+}
+
+func (c *Client) addUser(arg params.AddUser) (_ names.UserTag, secretKey []byte, _ error) {
+	if !names.IsValidUser(arg.Username) {
+		return names.UserTag{}, nil, fmt.Errorf("invalid user name %q", arg.Username)
 	}
 
 	userArgs := params.AddUsers{
-		Users: []params.AddUser{{
-			Username:    username,
-			DisplayName: displayName,
-			Password:    password,
-		}},
+		Users: []params.AddUser{arg},
 	}
 	var results params.AddUserResults
 	err := c.facade.FacadeCall("AddUser", userArgs, &results)
@@ -162,6 +195,71 @@ func (c *Client) UserInfo(usernames []string, all IncludeDisabled) ([]params.Use
 	return info, nil
 }
 
+// ListUsersArgs describes a page of users to be returned by ListUsers.
+type ListUsersArgs struct {
+	// NamePrefix, if not empty, restricts the results to users
+	// whose names start with it (case-insensitive).
+	NamePrefix string
+
+	// IncludeDisabled indicates whether disabled users are returned.
+	IncludeDisabled IncludeDisabled
+
+	// Labels, if not empty, restricts the results
+	// to users that have all of the labels.
+	Labels map[string]string
+
+	// SortBy is the attribute used to order users; one of "name"
+	// or "date-created". If empty, users are ordered by name.
+	SortBy string
+
+	// Descending reverses the sort order.
+	Descending bool
+
+	// Limit is the maximum number of users to return.
+	// If zero, the controller's default page size is used.
+	Limit int
+
+	// Continuation is the token returned with the previous
+	// page. It is empty when requesting the first page.
+	Continuation string
+}
+
+// ListUsers returns a page of users matching the input arguments,
+// along with a continuation token that can be used to request the
+// next page. The returned token is empty if there are no more users.
+func (c *Client) ListUsers(args ListUsersArgs) ([]params.UserInfo, string, error) {
+	if c.BestAPIVersion() < 3 {
+		return nil, "", errors.NotSupportedf("listing users by page")
+	}
+	if len(args.Labels) > 0 && c.BestAPIVersion() < 15 {
+		return nil, "", errors.NotSupportedf("filtering users by label")
+	}
+	request := params.ListUsersRequest{
+		NamePrefix:      args.NamePrefix,
+		IncludeDisabled: bool(args.IncludeDisabled),
+		Labels:          args.Labels,
+		SortBy:          args.SortBy,
+		Descending:      args.Descending,
+		Limit:           args.Limit,
+		Continuation:    args.Continuation,
+	}
+	var results params.ListUsersResults
+	if err := c.facade.FacadeCall("ListUsers", request, &results); err != nil {
+		return nil, "", errors.Trace(err)
+	}
+	info := make([]params.UserInfo, len(results.Results))
+	for i, result := range results.Results {
+		if result.Error != nil {
+			return nil, "", errors.Trace(result.Error)
+		}
+		if result.Result == nil {
+			return nil, "", errors.Errorf("unexpected nil result at position %d", i)
+		}
+		info[i] = *result.Result
+	}
+	return info, results.Continuation, nil
+}
+
 // SetPassword changes the password for the specified user.
 func (c *Client) SetPassword(username, password string) error {
 	if !names.IsValidUser(username) {
@@ -207,3 +305,680 @@ func (c *Client) ResetPassword(username string) ([]byte, error) {
 	}
 	return result.SecretKey, nil
 }
+
+// GrantTemporaryAccess grants the user the specified access to the model
+// until ttl has passed, after which the controller restores the access the
+// user had beforehand. It returns the time at which the access expires.
+func (c *Client) GrantTemporaryAccess(user, modelUUID, access string, ttl time.Duration) (time.Time, error) {
+	if c.BestAPIVersion() < 4 {
+		return time.Time{}, errors.NotSupportedf("granting temporary access")
+	}
+	if !names.IsValidUser(user) {
+		return time.Time{}, errors.Errorf("invalid user name %q", user)
+	}
+	if !names.IsValidModel(modelUUID) {
+		return time.Time{}, errors.Errorf("invalid model %q", modelUUID)
+	}
+	args := params.GrantTemporaryAccessRequest{
+		Grants: []params.GrantTemporaryAccess{{
+			UserTag:  names.NewUserTag(user).String(),
+			ModelTag: names.NewModelTag(modelUUID).String(),
+			Access:   params.UserAccessPermission(access),
+			TTL:      ttl,
+		}},
+	}
+	var results params.TemporaryAccessResults
+	if err := c.facade.FacadeCall("GrantTemporaryAccess", args, &results); err != nil {
+		return time.Time{}, errors.Trace(err)
+	}
+	if count := len(results.Results); count != 1 {
+		return time.Time{}, errors.Errorf("expected 1 result, got %d", count)
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return time.Time{}, errors.Trace(result.Error)
+	}
+	if result.Expires == nil {
+		return time.Time{}, errors.New("missing expiry time")
+	}
+	return *result.Expires, nil
+}
+
+// SetUserDefaults stores the preferences of the user on the controller.
+// The defaults are returned when the user logs in, and replace any that
+// were previously stored.
+func (c *Client) SetUserDefaults(user string, defaults params.UserDefaults) error {
+	if c.BestAPIVersion() < 5 {
+		return errors.NotSupportedf("setting user defaults")
+	}
+	if !names.IsValidUser(user) {
+		return errors.Errorf("invalid user name %q", user)
+	}
+	args := params.SetUserDefaultsArgs{
+		Args: []params.SetUserDefaults{{
+			UserTag:  names.NewUserTag(user).String(),
+			Defaults: defaults,
+		}},
+	}
+	var results params.ErrorResults
+	if err := c.facade.FacadeCall("SetUserDefaults", args, &results); err != nil {
+		return errors.Trace(err)
+	}
+	return results.OneError()
+}
+
+// UserNotifications returns the notifications of events on the user's
+// account, with their delivery status, oldest first.
+func (c *Client) UserNotifications(user string) ([]params.UserNotification, error) {
+	if c.BestAPIVersion() < 6 {
+		return nil, errors.NotSupportedf("user notifications")
+	}
+	if !names.IsValidUser(user) {
+		return nil, errors.Errorf("invalid user name %q", user)
+	}
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: names.NewUserTag(user).String()}},
+	}
+	var results params.UserNotificationsResults
+	if err := c.facade.FacadeCall("UserNotifications", args, &results); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(results.Results) != 1 {
+		return nil, errors.Errorf("expected 1 result, got %d", len(results.Results))
+	}
+	if err := results.Results[0].Error; err != nil {
+		return nil, errors.Trace(err)
+	}
+	return results.Results[0].Result, nil
+}
+
+// PreviewUsername checks whether a local user could be added with the
+// name, without adding it. An error satisfying
+// params.IsCodeUsernameNotAllowed is returned if the name is not allowed
+// by the controller's username policy.
+func (c *Client) PreviewUsername(username string) error {
+	if c.BestAPIVersion() < 7 {
+		return errors.NotSupportedf("previewing usernames")
+	}
+	args := params.PreviewUsernames{Usernames: []string{username}}
+	var results params.ErrorResults
+	if err := c.facade.FacadeCall("PreviewUsername", args, &results); err != nil {
+		return errors.Trace(err)
+	}
+	return results.OneError()
+}
+
+// ExportPermissions returns a document describing the access granted
+// to every user of the controller. If labels are specified, only the
+// users that have all of them are described.
+func (c *Client) ExportPermissions(labels map[string]string) (params.PermissionsDocument, error) {
+	var result params.PermissionsDocument
+	if c.BestAPIVersion() < 8 {
+		return result, errors.NotSupportedf("exporting permissions")
+	}
+	var args interface{}
+	if len(labels) > 0 {
+		if c.BestAPIVersion() < 15 {
+			return result, errors.NotSupportedf("filtering permissions by label")
+		}
+		args = params.ExportPermissionsArgs{Labels: labels}
+	}
+	err := c.facade.FacadeCall("ExportPermissions", args, &result)
+	return result, errors.Trace(err)
+}
+
+// ImportPermissions grants each user in the document the access it
+// describes. If revoke is true, any access those users have that is not
+// in the document is revoked. The errors for each user that could not
+// be updated are combined into the returned error.
+func (c *Client) ImportPermissions(doc params.PermissionsDocument, revoke bool) error {
+	if c.BestAPIVersion() < 8 {
+		return errors.NotSupportedf("importing permissions")
+	}
+	args := params.ImportPermissionsArgs{
+		Users:  doc.Users,
+		Revoke: revoke,
+	}
+	var results params.ErrorResults
+	if err := c.facade.FacadeCall("ImportPermissions", args, &results); err != nil {
+		return errors.Trace(err)
+	}
+	if len(results.Results) != len(args.Users) {
+		return errors.Errorf("expected %d results, got %d", len(args.Users), len(results.Results))
+	}
+	return results.Combine()
+}
+
+// RequestAccess asks the administrators of the model to grant the
+// logged in user the specified access to it, giving the reason the
+// access is needed. It returns the pending request.
+func (c *Client) RequestAccess(modelUUID, access, reason string) (params.AccessRequest, error) {
+	if c.BestAPIVersion() < 9 {
+		return params.AccessRequest{}, errors.NotSupportedf("requesting access")
+	}
+	if !names.IsValidModel(modelUUID) {
+		return params.AccessRequest{}, errors.Errorf("invalid model %q", modelUUID)
+	}
+	args := params.RequestAccessArgs{
+		Requests: []params.RequestAccess{{
+			ModelTag: names.NewModelTag(modelUUID).String(),
+			Access:   params.UserAccessPermission(access),
+			Reason:   reason,
+		}},
+	}
+	var results params.AccessRequestResults
+	if err := c.facade.FacadeCall("RequestAccess", args, &results); err != nil {
+		return params.AccessRequest{}, errors.Trace(err)
+	}
+	if count := len(results.Results); count != 1 {
+		return params.AccessRequest{}, errors.Errorf("expected 1 result, got %d", count)
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return params.AccessRequest{}, errors.Trace(result.Error)
+	}
+	if result.Result == nil {
+		return params.AccessRequest{}, errors.New("missing access request")
+	}
+	return *result.Result, nil
+}
+
+// ListAccessRequests returns the pending access requests that the logged
+// in user can approve or deny, along with the user's own pending requests.
+func (c *Client) ListAccessRequests() ([]params.AccessRequest, error) {
+	if c.BestAPIVersion() < 9 {
+		return nil, errors.NotSupportedf("listing access requests")
+	}
+	var result params.AccessRequestsResult
+	if err := c.facade.FacadeCall("ListAccessRequests", nil, &result); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return result.Requests, nil
+}
+
+// ApproveAccess grants the access asked for by the specified requests.
+func (c *Client) ApproveAccess(ids ...string) error {
+	if c.BestAPIVersion() < 9 {
+		return errors.NotSupportedf("approving access requests")
+	}
+	return c.decideAccess("ApproveAccess", ids)
+}
+
+// DenyAccess denies the specified access requests.
+func (c *Client) DenyAccess(ids ...string) error {
+	if c.BestAPIVersion() < 9 {
+		return errors.NotSupportedf("denying access requests")
+	}
+	return c.decideAccess("DenyAccess", ids)
+}
+
+func (c *Client) decideAccess(method string, ids []string) error {
+	args := params.AccessRequestIDs{IDs: ids}
+	var results params.ErrorResults
+	if err := c.facade.FacadeCall(method, args, &results); err != nil {
+		return errors.Trace(err)
+	}
+	if len(results.Results) != len(ids) {
+		return errors.Errorf("expected %d results, got %d", len(ids), len(results.Results))
+	}
+	return results.Combine()
+}
+
+// EnrollTOTP starts enrolling an authenticator application as a second
+// factor for the specified user, which must be the logged in user. It
+// returns the secret with which to provision the authenticator.
+func (c *Client) EnrollTOTP(username string) (params.TOTPEnrollment, error) {
+	if c.BestAPIVersion() < 10 {
+		return params.TOTPEnrollment{}, errors.NotSupportedf("enrolling a second factor")
+	}
+	if !names.IsValidUser(username) {
+		return params.TOTPEnrollment{}, errors.Errorf("%q is not a valid username", username)
+	}
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: names.NewUserTag(username).String()}},
+	}
+	var results params.TOTPEnrollmentResults
+	if err := c.facade.FacadeCall("EnrollTOTP", args, &results); err != nil {
+		return params.TOTPEnrollment{}, errors.Trace(err)
+	}
+	if count := len(results.Results); count != 1 {
+		return params.TOTPEnrollment{}, errors.Errorf("expected 1 result, got %d", count)
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return params.TOTPEnrollment{}, errors.Trace(result.Error)
+	}
+	if result.Result == nil {
+		return params.TOTPEnrollment{}, errors.New("missing enrollment")
+	}
+	return *result.Result, nil
+}
+
+// VerifyTOTP completes the specified user's second factor enrollment
+// with a code from their authenticator. It returns the user's recovery
+// codes, which cannot be retrieved again.
+func (c *Client) VerifyTOTP(username, code string) ([]string, error) {
+	if c.BestAPIVersion() < 10 {
+		return nil, errors.NotSupportedf("enrolling a second factor")
+	}
+	if !names.IsValidUser(username) {
+		return nil, errors.Errorf("%q is not a valid username", username)
+	}
+	args := params.VerifyTOTPArgs{
+		Args: []params.VerifyTOTPArg{{
+			Tag:  names.NewUserTag(username).String(),
+			Code: code,
+		}},
+	}
+	var results params.RecoveryCodesResults
+	if err := c.facade.FacadeCall("VerifyTOTP", args, &results); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if count := len(results.Results); count != 1 {
+		return nil, errors.Errorf("expected 1 result, got %d", count)
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return nil, errors.Trace(result.Error)
+	}
+	return result.RecoveryCodes, nil
+}
+
+// RemoveTOTP removes the second factor enrolled by the specified user.
+func (c *Client) RemoveTOTP(username string) error {
+	if c.BestAPIVersion() < 10 {
+		return errors.NotSupportedf("removing a second factor")
+	}
+	return c.userCall(username, "RemoveTOTP")
+}
+
+// PermissionWebhookDeadLetters returns the changes to users' access
+// whose delivery to the controller's permission webhook was abandoned.
+func (c *Client) PermissionWebhookDeadLetters() ([]params.PermissionEvent, error) {
+	if c.BestAPIVersion() < 11 {
+		return nil, errors.NotSupportedf("listing permission webhook dead letters")
+	}
+	var result params.PermissionEventsResult
+	if err := c.facade.FacadeCall("PermissionWebhookDeadLetters", nil, &result); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return result.Events, nil
+}
+
+// RetryPermissionWebhookEvents queues the specified abandoned
+// events to be delivered to the permission webhook again.
+func (c *Client) RetryPermissionWebhookEvents(ids ...string) error {
+	if c.BestAPIVersion() < 11 {
+		return errors.NotSupportedf("retrying permission webhook events")
+	}
+	args := params.PermissionEventIDs{IDs: ids}
+	var results params.ErrorResults
+	if err := c.facade.FacadeCall("RetryPermissionWebhookEvents", args, &results); err != nil {
+		return errors.Trace(err)
+	}
+	if len(results.Results) != len(ids) {
+		return errors.Errorf("expected %d results, got %d", len(ids), len(results.Results))
+	}
+	return results.Combine()
+}
+
+// LoginActivity returns a summary of the user's logins to each model,
+// and to the controller, since the given time.
+func (c *Client) LoginActivity(username string, since time.Time) ([]params.ModelLoginActivity, error) {
+	if c.BestAPIVersion() < 12 {
+		return nil, errors.NotSupportedf("reporting login activity")
+	}
+	if !names.IsValidUser(username) {
+		return nil, errors.Errorf("%q is not a valid username", username)
+	}
+	args := params.LoginActivityArgs{
+		Entities: []params.Entity{{Tag: names.NewUserTag(username).String()}},
+		Since:    since,
+	}
+	var results params.LoginActivityResults
+	if err := c.facade.FacadeCall("LoginActivity", args, &results); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(results.Results) != 1 {
+		return nil, errors.Errorf("expected 1 result, got %d", len(results.Results))
+	}
+	if err := results.Results[0].Error; err != nil {
+		return nil, errors.Trace(err)
+	}
+	return results.Results[0].Models, nil
+}
+
+// TransferCredential transfers the ownership of the cloud credential to
+// newOwner. It returns the tag of the transferred credential, and the
+// models that were updated to use it.
+func (c *Client) TransferCredential(credential names.CloudCredentialTag, newOwner names.UserTag) (names.CloudCredentialTag, []params.TransferredCredentialModel, error) {
+	if c.BestAPIVersion() < 13 {
+		return names.CloudCredentialTag{}, nil, errors.NotSupportedf("transferring cloud credentials")
+	}
+	args := params.TransferCredentialArgs{
+		Credentials: []params.TransferCredentialArg{{
+			CredentialTag: credential.String(),
+			NewOwnerTag:   newOwner.String(),
+		}},
+	}
+	var results params.TransferCredentialResults
+	if err := c.facade.FacadeCall("TransferCredential", args, &results); err != nil {
+		return names.CloudCredentialTag{}, nil, errors.Trace(err)
+	}
+	if len(results.Results) != 1 {
+		return names.CloudCredentialTag{}, nil, errors.Errorf("expected 1 result, got %d", len(results.Results))
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return names.CloudCredentialTag{}, nil, errors.Trace(result.Error)
+	}
+	transferred, err := names.ParseCloudCredentialTag(result.CredentialTag)
+	if err != nil {
+		return names.CloudCredentialTag{}, nil, errors.Trace(err)
+	}
+	return transferred, result.Models, nil
+}
+
+// PasswordExpiry returns the length of time after which the passwords of
+// local users expire, and the users whose passwords have expired or will
+// expire within the given length of time.
+func (c *Client) PasswordExpiry(within time.Duration) (time.Duration, []params.UserPasswordExpiry, error) {
+	if c.BestAPIVersion() < 14 {
+		return 0, nil, errors.NotSupportedf("reporting password expiry")
+	}
+	args := params.PasswordExpiryArgs{Within: within}
+	var result params.PasswordExpiryResult
+	if err := c.facade.FacadeCall("PasswordExpiry", args, &result); err != nil {
+		return 0, nil, errors.Trace(err)
+	}
+	return result.MaxAge, result.Users, nil
+}
+
+// UpdateUserLabels attaches the labels in set to the user, replacing the
+// values of any with the same keys, and removes the labels with the keys
+// in unset.
+func (c *Client) UpdateUserLabels(user string, set map[string]string, unset []string) error {
+	if c.BestAPIVersion() < 15 {
+		return errors.NotSupportedf("user labels")
+	}
+	if !names.IsValidUser(user) {
+		return errors.Errorf("invalid user name %q", user)
+	}
+	args := params.UpdateUserLabelsArgs{
+		Args: []params.UpdateUserLabels{{
+			UserTag: names.NewUserTag(user).String(),
+			Set:     set,
+			Unset:   unset,
+		}},
+	}
+	var results params.ErrorResults
+	if err := c.facade.FacadeCall("UpdateUserLabels", args, &results); err != nil {
+		return errors.Trace(err)
+	}
+	return results.OneError()
+}
+
+// GrantModelAccess grants the user the access to the models with the
+// given UUIDs. Users granted user-admin access to a model may grant
+// access lower than their own to it.
+func (c *Client) GrantModelAccess(user, access string, modelUUIDs ...string) error {
+	if c.BestAPIVersion() < 16 {
+		return errors.NotSupportedf("granting model access")
+	}
+	if !names.IsValidUser(user) {
+		return errors.Errorf("invalid user name %q", user)
+	}
+	args := params.GrantModelAccessRequest{
+		Grants: make([]params.GrantModelAccess, len(modelUUIDs)),
+	}
+	for i, uuid := range modelUUIDs {
+		if !names.IsValidModel(uuid) {
+			return errors.NotValidf("model UUID %q", uuid)
+		}
+		args.Grants[i] = params.GrantModelAccess{
+			UserTag:  names.NewUserTag(user).String(),
+			ModelTag: names.NewModelTag(uuid).String(),
+			Access:   params.UserAccessPermission(access),
+		}
+	}
+	var results params.ErrorResults
+	if err := c.facade.FacadeCall("GrantModelAccess", args, &results); err != nil {
+		return errors.Trace(err)
+	}
+	return results.Combine()
+}
+
+// deviceLoginSlowDown is how much longer to wait between polls of a
+// device login each time the controller reports it is polled too often,
+// as RFC 8628 requires.
+const deviceLoginSlowDown = 5 * time.Second
+
+// StartDeviceLogin starts logging in with the controller's identity
+// provider using the device authorization flow, for clients that cannot
+// open a browser. It can be called on an anonymous connection. The user
+// approves the login by entering the returned user code at the returned
+// verification URI, on any device, while the client waits for it with
+// WaitDeviceLogin or polls it with PollDeviceLogin.
+func (c *Client) StartDeviceLogin() (params.DeviceLoginResult, error) {
+	var result params.DeviceLoginResult
+	if c.BestAPIVersion() < 17 {
+		return result, errors.NotSupportedf("device login")
+	}
+	if err := c.facade.FacadeCall("StartDeviceLogin", nil, &result); err != nil {
+		return result, errors.Trace(err)
+	}
+	return result, nil
+}
+
+// PollDeviceLogin polls once for the completion of the device login with
+// the device code. Once the user has approved it, it returns the user
+// that logged in and the macaroon with which to log in as them. Logins
+// yet to be approved fail with an error satisfying
+// params.IsCodeDeviceLoginPending, and those polled too often with one
+// satisfying params.IsCodeDeviceLoginSlowDown.
+func (c *Client) PollDeviceLogin(deviceCode string) (names.UserTag, *macaroon.Macaroon, error) {
+	if c.BestAPIVersion() < 17 {
+		return names.UserTag{}, nil, errors.NotSupportedf("device login")
+	}
+	args := params.PollDeviceLoginArgs{
+		Logins: []params.PollDeviceLogin{{
+			DeviceCode:    deviceCode,
+			BakeryVersion: bakery.LatestVersion,
+		}},
+	}
+	var results params.PollDeviceLoginResults
+	if err := c.facade.FacadeCall("PollDeviceLogin", args, &results); err != nil {
+		return names.UserTag{}, nil, errors.Trace(err)
+	}
+	if count := len(results.Results); count != 1 {
+		logger.Errorf("expected 1 result, got %#v", results)
+		return names.UserTag{}, nil, errors.Errorf("expected 1 result, got %d", count)
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return names.UserTag{}, nil, result.Error
+	}
+	tag, err := names.ParseUserTag(result.UserTag)
+	if err != nil {
+		return names.UserTag{}, nil, errors.Trace(err)
+	}
+	return tag, result.Macaroon, nil
+}
+
+// WaitDeviceLogin polls for the completion of the device login started
+// by StartDeviceLogin, at the interval it returned, until the user has
+// approved or denied it, it expires, or abort is closed.
+func (c *Client) WaitDeviceLogin(
+	login params.DeviceLoginResult, clock clock.Clock, abort <-chan struct{},
+) (names.UserTag, *macaroon.Macaroon, error) {
+	interval := login.Interval
+	for {
+		select {
+		case <-abort:
+			return names.UserTag{}, nil, errors.New("device login aborted")
+		case <-clock.After(interval):
+		}
+		tag, mac, err := c.PollDeviceLogin(login.DeviceCode)
+		switch {
+		case params.IsCodeDeviceLoginPending(err):
+		case params.IsCodeDeviceLoginSlowDown(err):
+			interval += deviceLoginSlowDown
+		case err != nil:
+			return names.UserTag{}, nil, errors.Trace(err)
+		default:
+			return tag, mac, nil
+		}
+		if !login.Expiry.IsZero() && clock.Now().After(login.Expiry) {
+			return names.UserTag{}, nil, errors.New("device login expired")
+		}
+	}
+}
+
+// ListSessions returns the live sessions of the user on the API
+// server the client is connected to.
+func (c *Client) ListSessions(username string) ([]params.Session, error) {
+	if c.BestAPIVersion() < 18 {
+		return nil, errors.NotSupportedf("listing sessions")
+	}
+	if !names.IsValidUser(username) {
+		return nil, errors.Errorf("%q is not a valid username", username)
+	}
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: names.NewUserTag(username).String()}},
+	}
+	var results params.SessionsResults
+	if err := c.facade.FacadeCall("ListSessions", args, &results); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(results.Results) != 1 {
+		return nil, errors.Errorf("expected 1 result, got %d", len(results.Results))
+	}
+	if err := results.Results[0].Error; err != nil {
+		return nil, errors.Trace(err)
+	}
+	return results.Results[0].Sessions, nil
+}
+
+// TerminateSession closes the connection of the live session with
+// the given id, as returned by ListSessions, logging its user out.
+func (c *Client) TerminateSession(sessionID string) error {
+	if c.BestAPIVersion() < 18 {
+		return errors.NotSupportedf("terminating sessions")
+	}
+	args := params.TerminateSessionArgs{
+		SessionIDs: []string{sessionID},
+	}
+	var results params.ErrorResults
+	if err := c.facade.FacadeCall("TerminateSession", args, &results); err != nil {
+		return errors.Trace(err)
+	}
+	return results.OneError()
+}
+
+// GuestAccess reports whether clients may log in, without credentials,
+// as the read-only guest user, and the UUIDs of the models the guest
+// user may see.
+func (c *Client) GuestAccess() (enabled bool, modelUUIDs []string, _ error) {
+	if c.BestAPIVersion() < 19 {
+		return false, nil, errors.NotSupportedf("guest access")
+	}
+	var result params.GuestAccessResult
+	if err := c.facade.FacadeCall("GuestAccess", nil, &result); err != nil {
+		return false, nil, errors.Trace(err)
+	}
+	modelUUIDs = make([]string, len(result.ModelTags))
+	for i, tag := range result.ModelTags {
+		modelTag, err := names.ParseModelTag(tag)
+		if err != nil {
+			return false, nil, errors.Trace(err)
+		}
+		modelUUIDs[i] = modelTag.Id()
+	}
+	return result.Enabled, modelUUIDs, nil
+}
+
+// EnableGuestAccess allows clients to log in, without credentials, as
+// the read-only guest user, which may see only the models with the
+// given UUIDs.
+func (c *Client) EnableGuestAccess(modelUUIDs ...string) error {
+	if c.BestAPIVersion() < 19 {
+		return errors.NotSupportedf("guest access")
+	}
+	args := params.GuestAccessArgs{
+		ModelTags: make([]string, len(modelUUIDs)),
+	}
+	for i, uuid := range modelUUIDs {
+		if !names.IsValidModel(uuid) {
+			return errors.NotValidf("model UUID %q", uuid)
+		}
+		args.ModelTags[i] = names.NewModelTag(uuid).String()
+	}
+	return errors.Trace(c.facade.FacadeCall("EnableGuestAccess", args, nil))
+}
+
+// DisableGuestAccess stops clients logging in as the guest user,
+// and revokes the guest user's access to all models.
+func (c *Client) DisableGuestAccess() error {
+	if c.BestAPIVersion() < 19 {
+		return errors.NotSupportedf("guest access")
+	}
+	return errors.Trace(c.facade.FacadeCall("DisableGuestAccess", nil, nil))
+}
+
+// Impersonate returns a macaroon with which the caller, who must be a
+// controller superuser, can log in as the local user with the given name
+// until the returned expiry. The reason is recorded with the
+// impersonation, and every call made while logged in with the macaroon is
+// recorded in the audit log.
+func (c *Client) Impersonate(username, reason string) (*macaroon.Macaroon, time.Time, error) {
+	if c.BestAPIVersion() < 20 {
+		return nil, time.Time{}, errors.NotSupportedf("impersonating users")
+	}
+	if !names.IsValidUser(username) {
+		return nil, time.Time{}, errors.Errorf("%q is not a valid username", username)
+	}
+	args := params.ImpersonateArgs{
+		Impersonations: []params.Impersonation{{
+			UserTag:       names.NewUserTag(username).String(),
+			Reason:        reason,
+			BakeryVersion: bakery.LatestVersion,
+		}},
+	}
+	var results params.ImpersonateResults
+	if err := c.facade.FacadeCall("Impersonate", args, &results); err != nil {
+		return nil, time.Time{}, errors.Trace(err)
+	}
+	if len(results.Results) != 1 {
+		return nil, time.Time{}, errors.Errorf("expected 1 result, got %d", len(results.Results))
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return nil, time.Time{}, errors.Trace(result.Error)
+	}
+	return result.Macaroon, result.Expiry, nil
+}
+
+// AccessReport returns a report of the users of the controller and the
+// access they have been granted, which conforms to the schema returned
+// by AccessReportSchema. If labels are specified, only the local users
+// that have all of them are reported.
+func (c *Client) AccessReport(labels map[string]string) (params.AccessReport, error) {
+	var result params.AccessReport
+	if c.BestAPIVersion() < 21 {
+		return result, errors.NotSupportedf("access reports")
+	}
+	args := params.AccessReportArgs{Labels: labels}
+	err := c.facade.FacadeCall("AccessReport", args, &result)
+	return result, errors.Trace(err)
+}
+
+// AccessReportSchema returns the JSON schema to which the reports
+// returned by AccessReport conform, and its version.
+func (c *Client) AccessReportSchema() (params.AccessReportSchema, error) {
+	var result params.AccessReportSchema
+	if c.BestAPIVersion() < 21 {
+		return result, errors.NotSupportedf("access reports")
+	}
+	err := c.facade.FacadeCall("AccessReportSchema", nil, &result)
+	return result, errors.Trace(err)
+}
@@ -4,14 +4,21 @@
 package usermanager_test
 
 import (
+	"time"
+
+	"github.com/juju/clock/testclock"
 	"github.com/juju/errors"
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v3"
+	"gopkg.in/macaroon-bakery.v2/bakery"
+	"gopkg.in/macaroon.v2"
 
 	apitesting "github.com/juju/juju/api/base/testing"
 	"github.com/juju/juju/api/usermanager"
 	"github.com/juju/juju/apiserver/params"
 	jujutesting "github.com/juju/juju/juju/testing"
+	coretesting "github.com/juju/juju/testing"
 	"github.com/juju/juju/testing/factory"
 )
 
@@ -155,6 +162,45 @@ func (s *usermanagerSuite) TestUserInfo(c *gc.C) {
 	c.Assert(obtained, jc.DeepEquals, expected)
 }
 
+func (s *usermanagerSuite) TestListUsers(c *gc.C) {
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "bob"})
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "bobby"})
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "mary"})
+
+	obtained, token, err := s.usermanager.ListUsers(usermanager.ListUsersArgs{
+		NamePrefix: "bob",
+		Limit:      1,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(obtained, gc.HasLen, 1)
+	c.Check(obtained[0].Username, gc.Equals, "bob")
+	c.Assert(token, gc.Not(gc.Equals), "")
+
+	obtained, token, err = s.usermanager.ListUsers(usermanager.ListUsersArgs{
+		NamePrefix:   "bob",
+		Limit:        1,
+		Continuation: token,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(obtained, gc.HasLen, 1)
+	c.Check(obtained[0].Username, gc.Equals, "bobby")
+	c.Check(token, gc.Equals, "")
+}
+
+func (s *usermanagerSuite) TestListUsersResultError(c *gc.C) {
+	usermanager.PatchResponses(s, s.usermanager,
+		func(result interface{}) error {
+			if result, ok := result.(*params.ListUsersResults); ok {
+				result.Results = []params.UserInfoResult{{Error: &params.Error{Message: "boom"}}}
+				return nil
+			}
+			return errors.New("wrong result type")
+		},
+	)
+	_, _, err := s.usermanager.ListUsers(usermanager.ListUsersArgs{})
+	c.Assert(err, gc.ErrorMatches, "boom")
+}
+
 func (s *usermanagerSuite) TestUserInfoMoreThanOneResult(c *gc.C) {
 	usermanager.PatchResponses(s, s.usermanager,
 		func(result interface{}) error {
@@ -270,3 +316,979 @@ func (s *usermanagerSuite) TestResetPasswordResultCount(c *gc.C) {
 	_, err := client.ResetPassword("foobar")
 	c.Assert(err, gc.ErrorMatches, "expected 1 result, got 2")
 }
+
+func (s *usermanagerSuite) TestGrantTemporaryAccess(c *gc.C) {
+	expires := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "GrantTemporaryAccess")
+			c.Assert(arg, jc.DeepEquals, params.GrantTemporaryAccessRequest{
+				Grants: []params.GrantTemporaryAccess{{
+					UserTag:  "user-foobar",
+					ModelTag: "model-deadbeef-0bad-400d-8000-4b1d0d06f00d",
+					Access:   params.ModelAdminAccess,
+					TTL:      time.Hour,
+				}},
+			})
+			results, ok := result.(*params.TemporaryAccessResults)
+			c.Assert(ok, jc.IsTrue)
+			results.Results = []params.TemporaryAccessResult{{Expires: &expires}}
+			return nil
+		},
+		BestVersion: 4,
+	}
+	client := usermanager.NewClient(apiCaller)
+	obtained, err := client.GrantTemporaryAccess("foobar", "deadbeef-0bad-400d-8000-4b1d0d06f00d", "admin", time.Hour)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(obtained, gc.Equals, expires)
+}
+
+func (s *usermanagerSuite) TestGrantTemporaryAccessResultError(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			results := result.(*params.TemporaryAccessResults)
+			results.Results = []params.TemporaryAccessResult{{Error: &params.Error{Message: "boom"}}}
+			return nil
+		},
+		BestVersion: 4,
+	}
+	client := usermanager.NewClient(apiCaller)
+	_, err := client.GrantTemporaryAccess("foobar", "deadbeef-0bad-400d-8000-4b1d0d06f00d", "admin", time.Hour)
+	c.Assert(err, gc.ErrorMatches, "boom")
+}
+
+func (s *usermanagerSuite) TestGrantTemporaryAccessNotSupported(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Fatalf("unexpected API call %q", request)
+			return nil
+		},
+		BestVersion: 3,
+	}
+	client := usermanager.NewClient(apiCaller)
+	_, err := client.GrantTemporaryAccess("foobar", "deadbeef-0bad-400d-8000-4b1d0d06f00d", "admin", time.Hour)
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *usermanagerSuite) TestSetUserDefaults(c *gc.C) {
+	defaults := params.UserDefaults{
+		DefaultModelTag: "model-deadbeef-0bad-400d-8000-4b1d0d06f00d",
+		Timezone:        "Europe/Paris",
+	}
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "SetUserDefaults")
+			c.Assert(arg, jc.DeepEquals, params.SetUserDefaultsArgs{
+				Args: []params.SetUserDefaults{{
+					UserTag:  "user-foobar",
+					Defaults: defaults,
+				}},
+			})
+			results, ok := result.(*params.ErrorResults)
+			c.Assert(ok, jc.IsTrue)
+			results.Results = []params.ErrorResult{{}}
+			return nil
+		},
+		BestVersion: 5,
+	}
+	client := usermanager.NewClient(apiCaller)
+	err := client.SetUserDefaults("foobar", defaults)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *usermanagerSuite) TestSetUserDefaultsNotSupported(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Fatalf("unexpected API call %q", request)
+			return nil
+		},
+		BestVersion: 4,
+	}
+	client := usermanager.NewClient(apiCaller)
+	err := client.SetUserDefaults("foobar", params.UserDefaults{})
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *usermanagerSuite) TestUserNotifications(c *gc.C) {
+	when := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	expected := []params.UserNotification{{
+		Event:     "user-created",
+		Channel:   "webhook",
+		Time:      when,
+		Delivered: true,
+	}}
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "UserNotifications")
+			c.Assert(arg, jc.DeepEquals, params.Entities{
+				Entities: []params.Entity{{Tag: "user-foobar"}},
+			})
+			results, ok := result.(*params.UserNotificationsResults)
+			c.Assert(ok, jc.IsTrue)
+			results.Results = []params.UserNotificationsResult{{Result: expected}}
+			return nil
+		},
+		BestVersion: 6,
+	}
+	client := usermanager.NewClient(apiCaller)
+	notifications, err := client.UserNotifications("foobar")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(notifications, jc.DeepEquals, expected)
+}
+
+func (s *usermanagerSuite) TestUserNotificationsNotSupported(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Fatalf("unexpected API call %q", request)
+			return nil
+		},
+		BestVersion: 5,
+	}
+	client := usermanager.NewClient(apiCaller)
+	_, err := client.UserNotifications("foobar")
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *usermanagerSuite) TestPreviewUsername(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "PreviewUsername")
+			c.Assert(arg, jc.DeepEquals, params.PreviewUsernames{Usernames: []string{"everyone"}})
+			results, ok := result.(*params.ErrorResults)
+			c.Assert(ok, jc.IsTrue)
+			results.Results = []params.ErrorResult{{Error: &params.Error{
+				Message: `username "everyone" not allowed: name is reserved`,
+				Code:    params.CodeUsernameNotAllowed,
+			}}}
+			return nil
+		},
+		BestVersion: 7,
+	}
+	client := usermanager.NewClient(apiCaller)
+	err := client.PreviewUsername("everyone")
+	c.Assert(err, gc.ErrorMatches, `username "everyone" not allowed: name is reserved`)
+	c.Assert(err, jc.Satisfies, params.IsCodeUsernameNotAllowed)
+}
+
+func (s *usermanagerSuite) TestPreviewUsernameNotSupported(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Fatalf("unexpected API call %q", request)
+			return nil
+		},
+		BestVersion: 6,
+	}
+	client := usermanager.NewClient(apiCaller)
+	err := client.PreviewUsername("foobar")
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *usermanagerSuite) TestExportPermissions(c *gc.C) {
+	doc := params.PermissionsDocument{
+		Users: []params.UserPermissions{{
+			Username:   "bob",
+			Controller: "login",
+			Models:     map[string]string{"deadbeef": "read"},
+		}},
+	}
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "ExportPermissions")
+			c.Assert(arg, gc.IsNil)
+			*(result.(*params.PermissionsDocument)) = doc
+			return nil
+		},
+		BestVersion: 8,
+	}
+	client := usermanager.NewClient(apiCaller)
+	result, err := client.ExportPermissions(nil)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, doc)
+}
+
+func (s *usermanagerSuite) TestImportPermissions(c *gc.C) {
+	doc := params.PermissionsDocument{
+		Users: []params.UserPermissions{{
+			Username:   "bob",
+			Controller: "login",
+		}, {
+			Username: "not/valid",
+		}},
+	}
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "ImportPermissions")
+			c.Assert(arg, jc.DeepEquals, params.ImportPermissionsArgs{Users: doc.Users, Revoke: true})
+			results := result.(*params.ErrorResults)
+			results.Results = []params.ErrorResult{{}, {
+				Error: &params.Error{Message: `username "not/valid" not valid`},
+			}}
+			return nil
+		},
+		BestVersion: 8,
+	}
+	client := usermanager.NewClient(apiCaller)
+	err := client.ImportPermissions(doc, true)
+	c.Assert(err, gc.ErrorMatches, `username "not/valid" not valid`)
+}
+
+func (s *usermanagerSuite) TestPermissionsNotSupported(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Fatalf("unexpected API call %q", request)
+			return nil
+		},
+		BestVersion: 7,
+	}
+	client := usermanager.NewClient(apiCaller)
+	_, err := client.ExportPermissions(nil)
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+	err = client.ImportPermissions(params.PermissionsDocument{}, false)
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *usermanagerSuite) TestRequestAccess(c *gc.C) {
+	modelUUID := "deadbeef-0bad-400d-8000-4b1d0d06f00d"
+	req := params.AccessRequest{
+		ID:       "42",
+		UserTag:  "user-bob",
+		ModelTag: names.NewModelTag(modelUUID).String(),
+		Access:   "write",
+		Reason:   "deploying",
+		Status:   "pending",
+	}
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "RequestAccess")
+			c.Assert(arg, jc.DeepEquals, params.RequestAccessArgs{
+				Requests: []params.RequestAccess{{
+					ModelTag: names.NewModelTag(modelUUID).String(),
+					Access:   "write",
+					Reason:   "deploying",
+				}},
+			})
+			results := result.(*params.AccessRequestResults)
+			results.Results = []params.AccessRequestResult{{Result: &req}}
+			return nil
+		},
+		BestVersion: 9,
+	}
+	client := usermanager.NewClient(apiCaller)
+	result, err := client.RequestAccess(modelUUID, "write", "deploying")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, req)
+}
+
+func (s *usermanagerSuite) TestListAccessRequests(c *gc.C) {
+	reqs := []params.AccessRequest{{ID: "42", UserTag: "user-bob", Status: "pending"}}
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "ListAccessRequests")
+			c.Assert(arg, gc.IsNil)
+			result.(*params.AccessRequestsResult).Requests = reqs
+			return nil
+		},
+		BestVersion: 9,
+	}
+	client := usermanager.NewClient(apiCaller)
+	result, err := client.ListAccessRequests()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, reqs)
+}
+
+func (s *usermanagerSuite) TestApproveAndDenyAccess(c *gc.C) {
+	var calls []string
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			calls = append(calls, request)
+			c.Assert(arg, jc.DeepEquals, params.AccessRequestIDs{IDs: []string{"1", "2"}})
+			results := result.(*params.ErrorResults)
+			results.Results = []params.ErrorResult{{}, {
+				Error: &params.Error{Message: "permission denied"},
+			}}
+			return nil
+		},
+		BestVersion: 9,
+	}
+	client := usermanager.NewClient(apiCaller)
+	err := client.ApproveAccess("1", "2")
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+	err = client.DenyAccess("1", "2")
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+	c.Assert(calls, jc.DeepEquals, []string{"ApproveAccess", "DenyAccess"})
+}
+
+func (s *usermanagerSuite) TestAccessRequestsNotSupported(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Fatalf("unexpected API call %q", request)
+			return nil
+		},
+		BestVersion: 8,
+	}
+	client := usermanager.NewClient(apiCaller)
+	_, err := client.RequestAccess("deadbeef-0bad-400d-8000-4b1d0d06f00d", "read", "")
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+	_, err = client.ListAccessRequests()
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+	err = client.ApproveAccess("1")
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+	err = client.DenyAccess("1")
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *usermanagerSuite) TestEnrollTOTP(c *gc.C) {
+	enrollment := params.TOTPEnrollment{
+		Secret:          "GEZDGNBVGY3TQOJQ",
+		ProvisioningURI: "otpauth://totp/juju:bob@ctrl?secret=GEZDGNBVGY3TQOJQ",
+	}
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "EnrollTOTP")
+			c.Assert(arg, jc.DeepEquals, params.Entities{
+				Entities: []params.Entity{{Tag: "user-bob"}},
+			})
+			result.(*params.TOTPEnrollmentResults).Results = []params.TOTPEnrollmentResult{{
+				Result: &enrollment,
+			}}
+			return nil
+		},
+		BestVersion: 10,
+	}
+	client := usermanager.NewClient(apiCaller)
+	result, err := client.EnrollTOTP("bob")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, enrollment)
+}
+
+func (s *usermanagerSuite) TestVerifyTOTP(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "VerifyTOTP")
+			c.Assert(arg, jc.DeepEquals, params.VerifyTOTPArgs{
+				Args: []params.VerifyTOTPArg{{Tag: "user-bob", Code: "123456"}},
+			})
+			result.(*params.RecoveryCodesResults).Results = []params.RecoveryCodesResult{{
+				RecoveryCodes: []string{"01234-56789"},
+			}}
+			return nil
+		},
+		BestVersion: 10,
+	}
+	client := usermanager.NewClient(apiCaller)
+	codes, err := client.VerifyTOTP("bob", "123456")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(codes, jc.DeepEquals, []string{"01234-56789"})
+}
+
+func (s *usermanagerSuite) TestRemoveTOTP(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "RemoveTOTP")
+			c.Assert(arg, jc.DeepEquals, params.Entities{
+				Entities: []params.Entity{{Tag: "user-bob"}},
+			})
+			result.(*params.ErrorResults).Results = []params.ErrorResult{{}}
+			return nil
+		},
+		BestVersion: 10,
+	}
+	client := usermanager.NewClient(apiCaller)
+	err := client.RemoveTOTP("bob")
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *usermanagerSuite) TestTOTPNotSupported(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Fatalf("unexpected API call %q", request)
+			return nil
+		},
+		BestVersion: 9,
+	}
+	client := usermanager.NewClient(apiCaller)
+	_, err := client.EnrollTOTP("bob")
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+	_, err = client.VerifyTOTP("bob", "123456")
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+	err = client.RemoveTOTP("bob")
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *usermanagerSuite) TestPermissionWebhookDeadLetters(c *gc.C) {
+	events := []params.PermissionEvent{{
+		ID:        "5e9d8c1f2a3b4c5d6e7f8091",
+		Kind:      "grant",
+		UserTag:   "user-bob",
+		TargetTag: "model-deadbeef-0bad-400d-8000-4b1d0d06f00d",
+		Access:    "write",
+		Attempts:  8,
+		LastError: "webhook returned 502 Bad Gateway",
+	}}
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "PermissionWebhookDeadLetters")
+			c.Assert(arg, gc.IsNil)
+			result.(*params.PermissionEventsResult).Events = events
+			return nil
+		},
+		BestVersion: 11,
+	}
+	client := usermanager.NewClient(apiCaller)
+	result, err := client.PermissionWebhookDeadLetters()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, events)
+}
+
+func (s *usermanagerSuite) TestRetryPermissionWebhookEvents(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "RetryPermissionWebhookEvents")
+			c.Assert(arg, jc.DeepEquals, params.PermissionEventIDs{IDs: []string{"1", "2"}})
+			result.(*params.ErrorResults).Results = []params.ErrorResult{{}, {
+				Error: &params.Error{Message: `abandoned permission event "2" not found`},
+			}}
+			return nil
+		},
+		BestVersion: 11,
+	}
+	client := usermanager.NewClient(apiCaller)
+	err := client.RetryPermissionWebhookEvents("1", "2")
+	c.Assert(err, gc.ErrorMatches, `abandoned permission event "2" not found`)
+}
+
+func (s *usermanagerSuite) TestPermissionWebhookNotSupported(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Fatalf("unexpected API call %q", request)
+			return nil
+		},
+		BestVersion: 10,
+	}
+	client := usermanager.NewClient(apiCaller)
+	_, err := client.PermissionWebhookDeadLetters()
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+	err = client.RetryPermissionWebhookEvents("1")
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *usermanagerSuite) TestLoginActivity(c *gc.C) {
+	since := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	models := []params.ModelLoginActivity{{
+		ModelTag:       "model-deadbeef-0bad-400d-8000-4b1d0d06f00d",
+		Connections:    3,
+		LastConnection: since.Add(time.Hour),
+		Addresses:      []string{"10.0.0.1", "10.0.0.2"},
+	}}
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "LoginActivity")
+			c.Assert(arg, jc.DeepEquals, params.LoginActivityArgs{
+				Entities: []params.Entity{{Tag: "user-bob"}},
+				Since:    since,
+			})
+			result.(*params.LoginActivityResults).Results = []params.LoginActivityResult{{Models: models}}
+			return nil
+		},
+		BestVersion: 12,
+	}
+	client := usermanager.NewClient(apiCaller)
+	result, err := client.LoginActivity("bob", since)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, models)
+}
+
+func (s *usermanagerSuite) TestLoginActivityNotSupported(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Fatalf("unexpected API call %q", request)
+			return nil
+		},
+		BestVersion: 11,
+	}
+	client := usermanager.NewClient(apiCaller)
+	_, err := client.LoginActivity("bob", time.Time{})
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *usermanagerSuite) TestTransferCredential(c *gc.C) {
+	models := []params.TransferredCredentialModel{{
+		ModelUUID: "deadbeef-0bad-400d-8000-4b1d0d06f00d",
+		ModelName: "prod",
+	}}
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "TransferCredential")
+			c.Assert(arg, jc.DeepEquals, params.TransferCredentialArgs{
+				Credentials: []params.TransferCredentialArg{{
+					CredentialTag: "cloudcred-aws_bob_default",
+					NewOwnerTag:   "user-mary",
+				}},
+			})
+			result.(*params.TransferCredentialResults).Results = []params.TransferCredentialResult{{
+				CredentialTag: "cloudcred-aws_mary_default",
+				Models:        models,
+			}}
+			return nil
+		},
+		BestVersion: 13,
+	}
+	client := usermanager.NewClient(apiCaller)
+	transferred, result, err := client.TransferCredential(
+		names.NewCloudCredentialTag("aws/bob/default"), names.NewUserTag("mary"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(transferred, gc.Equals, names.NewCloudCredentialTag("aws/mary/default"))
+	c.Assert(result, jc.DeepEquals, models)
+}
+
+func (s *usermanagerSuite) TestTransferCredentialError(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			result.(*params.TransferCredentialResults).Results = []params.TransferCredentialResult{{
+				Error: &params.Error{Message: "boom"},
+			}}
+			return nil
+		},
+		BestVersion: 13,
+	}
+	client := usermanager.NewClient(apiCaller)
+	_, _, err := client.TransferCredential(
+		names.NewCloudCredentialTag("aws/bob/default"), names.NewUserTag("mary"))
+	c.Assert(err, gc.ErrorMatches, "boom")
+}
+
+func (s *usermanagerSuite) TestTransferCredentialNotSupported(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Fatalf("unexpected API call %q", request)
+			return nil
+		},
+		BestVersion: 12,
+	}
+	client := usermanager.NewClient(apiCaller)
+	_, _, err := client.TransferCredential(
+		names.NewCloudCredentialTag("aws/bob/default"), names.NewUserTag("mary"))
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *usermanagerSuite) TestPasswordExpiry(c *gc.C) {
+	set := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	users := []params.UserPasswordExpiry{{
+		UserTag:     "user-bob",
+		PasswordSet: set,
+		Expires:     set.Add(720 * time.Hour),
+		Expired:     true,
+	}}
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "PasswordExpiry")
+			c.Assert(arg, jc.DeepEquals, params.PasswordExpiryArgs{Within: 24 * time.Hour})
+			*(result.(*params.PasswordExpiryResult)) = params.PasswordExpiryResult{
+				MaxAge: 720 * time.Hour,
+				Users:  users,
+			}
+			return nil
+		},
+		BestVersion: 14,
+	}
+	client := usermanager.NewClient(apiCaller)
+	maxAge, result, err := client.PasswordExpiry(24 * time.Hour)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(maxAge, gc.Equals, 720*time.Hour)
+	c.Assert(result, jc.DeepEquals, users)
+}
+
+func (s *usermanagerSuite) TestPasswordExpiryNotSupported(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Fatalf("unexpected API call %q", request)
+			return nil
+		},
+		BestVersion: 13,
+	}
+	client := usermanager.NewClient(apiCaller)
+	_, _, err := client.PasswordExpiry(24 * time.Hour)
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *usermanagerSuite) TestExportPermissionsWithLabels(c *gc.C) {
+	labels := map[string]string{"team": "platform"}
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "ExportPermissions")
+			c.Assert(arg, jc.DeepEquals, params.ExportPermissionsArgs{Labels: labels})
+			return nil
+		},
+		BestVersion: 15,
+	}
+	client := usermanager.NewClient(apiCaller)
+	_, err := client.ExportPermissions(labels)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *usermanagerSuite) TestUpdateUserLabels(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "UpdateUserLabels")
+			c.Assert(arg, jc.DeepEquals, params.UpdateUserLabelsArgs{
+				Args: []params.UpdateUserLabels{{
+					UserTag: "user-foobar",
+					Set:     map[string]string{"team": "platform"},
+					Unset:   []string{"contractor"},
+				}},
+			})
+			results, ok := result.(*params.ErrorResults)
+			c.Assert(ok, jc.IsTrue)
+			results.Results = []params.ErrorResult{{}}
+			return nil
+		},
+		BestVersion: 15,
+	}
+	client := usermanager.NewClient(apiCaller)
+	err := client.UpdateUserLabels("foobar", map[string]string{"team": "platform"}, []string{"contractor"})
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *usermanagerSuite) TestUserLabelsNotSupported(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Fatalf("unexpected API call %q", request)
+			return nil
+		},
+		BestVersion: 14,
+	}
+	client := usermanager.NewClient(apiCaller)
+	labels := map[string]string{"team": "platform"}
+	err := client.UpdateUserLabels("foobar", labels, nil)
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+	_, err = client.ExportPermissions(labels)
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+	_, _, err = client.ListUsers(usermanager.ListUsersArgs{Labels: labels})
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *usermanagerSuite) TestGrantModelAccess(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(objType, gc.Equals, "UserManager")
+			c.Assert(request, gc.Equals, "GrantModelAccess")
+			c.Assert(arg, jc.DeepEquals, params.GrantModelAccessRequest{
+				Grants: []params.GrantModelAccess{{
+					UserTag:  "user-foobar",
+					ModelTag: coretesting.ModelTag.String(),
+					Access:   "write",
+				}},
+			})
+			results, ok := result.(*params.ErrorResults)
+			c.Assert(ok, jc.IsTrue)
+			results.Results = []params.ErrorResult{{}}
+			return nil
+		},
+		BestVersion: 16,
+	}
+	client := usermanager.NewClient(apiCaller)
+	err := client.GrantModelAccess("foobar", "write", coretesting.ModelTag.Id())
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *usermanagerSuite) TestAddUserWithModelAccess(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(request, gc.Equals, "AddUser")
+			c.Assert(arg, jc.DeepEquals, params.AddUsers{
+				Users: []params.AddUser{{
+					Username:    "foobar",
+					DisplayName: "Foo Bar",
+					Password:    "password",
+					ModelTags:   []string{coretesting.ModelTag.String()},
+					ModelAccess: "read",
+				}},
+			})
+			results, ok := result.(*params.AddUserResults)
+			c.Assert(ok, jc.IsTrue)
+			results.Results = []params.AddUserResult{{Tag: "user-foobar"}}
+			return nil
+		},
+		BestVersion: 16,
+	}
+	client := usermanager.NewClient(apiCaller)
+	tag, _, err := client.AddUserWithModelAccess("foobar", "Foo Bar", "password", "read", coretesting.ModelTag.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(tag, gc.Equals, names.NewUserTag("foobar"))
+}
+
+func (s *usermanagerSuite) TestModelAccessNotSupported(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Fatalf("unexpected API call %q", request)
+			return nil
+		},
+		BestVersion: 15,
+	}
+	client := usermanager.NewClient(apiCaller)
+	err := client.GrantModelAccess("foobar", "read", coretesting.ModelTag.Id())
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+	_, _, err = client.AddUserWithModelAccess("foobar", "Foo Bar", "password", "read", coretesting.ModelTag.Id())
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *usermanagerSuite) TestStartDeviceLogin(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(request, gc.Equals, "StartDeviceLogin")
+			c.Assert(arg, gc.IsNil)
+			*(result.(*params.DeviceLoginResult)) = params.DeviceLoginResult{
+				DeviceCode:      "device-code",
+				UserCode:        "ABCD-EFGH",
+				VerificationURI: "https://idp.example.com/device",
+				Interval:        5 * time.Second,
+			}
+			return nil
+		},
+		BestVersion: 17,
+	}
+	client := usermanager.NewClient(apiCaller)
+	login, err := client.StartDeviceLogin()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(login.UserCode, gc.Equals, "ABCD-EFGH")
+	c.Assert(login.VerificationURI, gc.Equals, "https://idp.example.com/device")
+}
+
+func (s *usermanagerSuite) TestWaitDeviceLogin(c *gc.C) {
+	mac, err := macaroon.New([]byte("root-key"), []byte("id"), "juju", macaroon.LatestVersion)
+	c.Assert(err, jc.ErrorIsNil)
+	codes := []string{params.CodeDeviceLoginPending, params.CodeDeviceLoginSlowDown, ""}
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Assert(request, gc.Equals, "PollDeviceLogin")
+			c.Assert(arg, jc.DeepEquals, params.PollDeviceLoginArgs{
+				Logins: []params.PollDeviceLogin{{
+					DeviceCode:    "device-code",
+					BakeryVersion: bakery.LatestVersion,
+				}},
+			})
+			code := codes[0]
+			codes = codes[1:]
+			results := result.(*params.PollDeviceLoginResults)
+			if code != "" {
+				results.Results = []params.PollDeviceLoginResult{{
+					Error: &params.Error{Code: code, Message: code},
+				}}
+				return nil
+			}
+			results.Results = []params.PollDeviceLoginResult{{
+				UserTag:  "user-foobar",
+				Macaroon: mac,
+			}}
+			return nil
+		},
+		BestVersion: 17,
+	}
+	client := usermanager.NewClient(apiCaller)
+	clock := testclock.NewClock(time.Now())
+	login := params.DeviceLoginResult{
+		DeviceCode: "device-code",
+		Interval:   5 * time.Second,
+		Expiry:     clock.Now().Add(time.Minute),
+	}
+
+	type loginResult struct {
+		tag names.UserTag
+		mac *macaroon.Macaroon
+		err error
+	}
+	done := make(chan loginResult)
+	go func() {
+		tag, mac, err := client.WaitDeviceLogin(login, clock, nil)
+		done <- loginResult{tag, mac, err}
+	}()
+	// Pending, then polled too often, after which
+	// the interval is increased by five seconds.
+	for _, interval := range []time.Duration{5, 5, 10} {
+		c.Assert(clock.WaitAdvance(interval*time.Second, coretesting.LongWait, 1), jc.ErrorIsNil)
+	}
+	select {
+	case result := <-done:
+		c.Assert(result.err, jc.ErrorIsNil)
+		c.Assert(result.tag, gc.Equals, names.NewUserTag("foobar"))
+		c.Assert(result.mac, gc.Equals, mac)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for device login")
+	}
+	c.Assert(codes, gc.HasLen, 0)
+}
+
+func (s *usermanagerSuite) TestDeviceLoginNotSupported(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Fatalf("unexpected API call %q", request)
+			return nil
+		},
+		BestVersion: 16,
+	}
+	client := usermanager.NewClient(apiCaller)
+	_, err := client.StartDeviceLogin()
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+	_, _, err = client.PollDeviceLogin("device-code")
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *usermanagerSuite) TestListAndTerminateSession(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", Password: "secret"})
+	conn := s.OpenAPIAs(c, user.Tag(), "secret")
+
+	sessions, err := s.usermanager.ListSessions("bob")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(sessions, gc.HasLen, 1)
+	c.Assert(sessions[0].UserTag, gc.Equals, "user-bob")
+	c.Assert(sessions[0].ModelTag, gc.Equals, s.Model.ModelTag().String())
+	c.Assert(sessions[0].RemoteAddress, gc.Not(gc.Equals), "")
+
+	err = s.usermanager.TerminateSession(sessions[0].SessionID)
+	c.Assert(err, jc.ErrorIsNil)
+	select {
+	case <-conn.Broken():
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for the session to be terminated")
+	}
+
+	sessions, err = s.usermanager.ListSessions("bob")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(sessions, gc.HasLen, 0)
+
+	err = s.usermanager.TerminateSession("999999")
+	c.Assert(err, gc.ErrorMatches, `session "999999" not found`)
+}
+
+func (s *usermanagerSuite) TestSessionsNotSupported(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Fatalf("unexpected API call %q", request)
+			return nil
+		},
+		BestVersion: 17,
+	}
+	client := usermanager.NewClient(apiCaller)
+	_, err := client.ListSessions("bob")
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+	err = client.TerminateSession("1")
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *usermanagerSuite) TestGuestAccess(c *gc.C) {
+	modelUUID := s.Model.UUID()
+	err := s.usermanager.EnableGuestAccess(modelUUID)
+	c.Assert(err, jc.ErrorIsNil)
+
+	enabled, modelUUIDs, err := s.usermanager.GuestAccess()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(enabled, jc.IsTrue)
+	c.Assert(modelUUIDs, jc.DeepEquals, []string{modelUUID})
+
+	err = s.usermanager.DisableGuestAccess()
+	c.Assert(err, jc.ErrorIsNil)
+
+	enabled, modelUUIDs, err = s.usermanager.GuestAccess()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(enabled, jc.IsFalse)
+	c.Assert(modelUUIDs, gc.HasLen, 0)
+}
+
+func (s *usermanagerSuite) TestEnableGuestAccessInvalidModel(c *gc.C) {
+	err := s.usermanager.EnableGuestAccess("not-a-uuid")
+	c.Assert(err, gc.ErrorMatches, `model UUID "not-a-uuid" not valid`)
+}
+
+func (s *usermanagerSuite) TestGuestAccessNotSupported(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Fatalf("unexpected API call %q", request)
+			return nil
+		},
+		BestVersion: 18,
+	}
+	client := usermanager.NewClient(apiCaller)
+	_, _, err := client.GuestAccess()
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+	err = client.EnableGuestAccess()
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+	err = client.DisableGuestAccess()
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *usermanagerSuite) TestImpersonate(c *gc.C) {
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	before := time.Now()
+	mac, expiry, err := s.usermanager.Impersonate("alex", "support case 42")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(mac, gc.NotNil)
+	c.Assert(expiry.After(before), jc.IsTrue)
+}
+
+func (s *usermanagerSuite) TestImpersonateWithoutReason(c *gc.C) {
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	_, _, err := s.usermanager.Impersonate("alex", "")
+	c.Assert(err, gc.ErrorMatches, `impersonation of "alex" without a reason not valid`)
+}
+
+func (s *usermanagerSuite) TestImpersonateNotSupported(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Fatalf("unexpected API call %q", request)
+			return nil
+		},
+		BestVersion: 19,
+	}
+	client := usermanager.NewClient(apiCaller)
+	_, _, err := client.Impersonate("alex", "support")
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *usermanagerSuite) TestAccessReport(c *gc.C) {
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	report, err := s.usermanager.AccessReport(nil)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(report.SchemaVersion, gc.Equals, 1)
+	c.Assert(report.Users, gc.HasLen, 2)
+	c.Assert(report.Users[0].Username, gc.Equals, "admin")
+	c.Assert(report.Users[1].Username, gc.Equals, "alex")
+
+	schema, err := s.usermanager.AccessReportSchema()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(schema.Version, gc.Equals, report.SchemaVersion)
+	c.Assert(schema.Schema, gc.Not(gc.Equals), "")
+}
+
+func (s *usermanagerSuite) TestAccessReportNotSupported(c *gc.C) {
+	apiCaller := apitesting.BestVersionCaller{
+		APICallerFunc: func(objType string, version int, id, request string, arg, result interface{}) error {
+			c.Fatalf("unexpected API call %q", request)
+			return nil
+		},
+		BestVersion: 20,
+	}
+	client := usermanager.NewClient(apiCaller)
+	_, err := client.AccessReport(nil)
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+	_, err = client.AccessReportSchema()
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
@@ -270,9 +270,13 @@ func copyRelationUnitsChanged(src params.RelationUnitsChange) watcher.RelationUn
 	if src.Changed != nil {
 		dst.Changed = make(map[string]watcher.UnitSettings, len(src.Changed))
 		for name, unitSettings := range src.Changed {
-			dst.Changed[name] = watcher.UnitSettings{
+			settings := watcher.UnitSettings{
 				Version: unitSettings.Version,
 			}
+			if unitSettings.Observed != nil {
+				settings.Observed = *unitSettings.Observed
+			}
+			dst.Changed[name] = settings
 		}
 	}
 	if src.AppChanged != nil {
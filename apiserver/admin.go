@@ -18,6 +18,7 @@ import (
 	"github.com/juju/juju/apiserver/common"
 	"github.com/juju/juju/apiserver/facade"
 	"github.com/juju/juju/apiserver/facades/agent/presence"
+	"github.com/juju/juju/apiserver/httpcontext"
 	"github.com/juju/juju/apiserver/observer"
 	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/core/auditlog"
@@ -101,6 +102,12 @@ func (a *admin) login(ctx context.Context, req params.LoginRequest, loginVersion
 	if err != nil {
 		return fail, errors.Trace(err)
 	}
+	// Everything done while impersonating a user is recorded
+	// in the audit log, so impersonation requires it.
+	auditConfig := a.srv.GetAuditConfig()
+	if authResult.impersonator.Id() != "" && !auditConfig.Enabled {
+		return fail, errors.NotSupportedf("impersonation with the audit log disabled")
+	}
 
 	// Fetch the API server addresses from state.
 	// If the login comes from a client, return all available addresses.
@@ -146,6 +153,9 @@ func (a *admin) login(ctx context.Context, req params.LoginRequest, loginVersion
 	if authResult.anonymousLogin {
 		facadeFilters = append(facadeFilters, IsAnonymousFacade)
 	}
+	if authResult.guestLogin {
+		facadeFilters = append(facadeFilters, IsGuestFacade)
+	}
 	if authResult.controllerOnlyLogin {
 		facadeFilters = append(facadeFilters, IsControllerFacade)
 	} else {
@@ -153,7 +163,14 @@ func (a *admin) login(ctx context.Context, req params.LoginRequest, loginVersion
 		modelTag = a.root.model.Tag().String()
 	}
 
-	auditConfig := a.srv.GetAuditConfig()
+	if authResult.userLogin {
+		// Impersonations are not the user's own activity.
+		if authResult.impersonator.Id() == "" {
+			a.recordLogin(authResult.controllerOnlyLogin)
+		}
+		a.recordSession(authResult.controllerOnlyLogin, authResult.impersonator)
+	}
+
 	auditRecorder, err := a.getAuditRecorder(req, authResult, auditConfig)
 	if err != nil {
 		return fail, errors.Trace(err)
@@ -174,22 +191,67 @@ func (a *admin) login(ctx context.Context, req params.LoginRequest, loginVersion
 	}, nil
 }
 
+// recordLogin records the user's login, and the address they logged
+// in from, for reporting their activity. A failure to record the
+// login is logged rather than failing the login.
+func (a *admin) recordLogin(controllerOnlyLogin bool) {
+	userTag, ok := a.root.entity.Tag().(names.UserTag)
+	if !ok {
+		return
+	}
+	var modelUUID string
+	if !controllerOnlyLogin {
+		modelUUID = a.root.model.UUID()
+	}
+	if err := a.root.state.RecordLogin(userTag, modelUUID, a.root.clientAddress); err != nil {
+		logger.Warningf("cannot record login activity for %q: %v", userTag.Id(), err)
+	}
+}
+
+// recordSession records the user's session, so that it can be
+// listed and terminated through the user manager facade.
+func (a *admin) recordSession(controllerOnlyLogin bool, impersonator names.UserTag) {
+	userTag, ok := a.root.entity.Tag().(names.UserTag)
+	if !ok {
+		return
+	}
+	info := params.Session{
+		RemoteAddress: a.root.clientAddress,
+		UserAgent:     a.root.userAgent,
+		Started:       a.srv.clock.Now(),
+	}
+	if impersonator.Id() != "" {
+		info.ImpersonatorTag = impersonator.String()
+	}
+	if !controllerOnlyLogin {
+		info.ModelTag = a.root.model.ModelTag().String()
+	}
+	a.srv.sessions.add(a.root.connectionID, userTag, info, a.root.terminate)
+}
+
 func (a *admin) getAuditRecorder(req params.LoginRequest, authResult *authResult, cfg auditlog.Config) (*auditlog.Recorder, error) {
 	if !authResult.userLogin || !cfg.Enabled {
 		return nil, nil
 	}
 	// Wrap the audit logger in a filter that prevents us from logging
 	// lots of readonly conversations (like "juju status" requests).
-	filter := observer.MakeInterestingRequestFilter(cfg.ExcludeMethods)
+	// Everything done while impersonating another user is logged;
+	// login refuses impersonations while the audit log is disabled.
+	target := cfg.Target
+	if authResult.impersonator.Id() == "" {
+		filter := observer.MakeInterestingRequestFilter(cfg.ExcludeMethods)
+		target = observer.NewAuditLogFilter(cfg.Target, filter)
+	}
 	result, err := auditlog.NewRecorder(
-		observer.NewAuditLogFilter(cfg.Target, filter),
+		target,
 		a.srv.clock,
 		auditlog.ConversationArgs{
-			Who:          a.root.entity.Tag().Id(),
-			What:         req.CLIArgs,
-			ModelName:    a.root.model.Name(),
-			ModelUUID:    a.root.model.UUID(),
-			ConnectionID: a.root.connectionID,
+			Who:            a.root.entity.Tag().Id(),
+			What:           req.CLIArgs,
+			ModelName:      a.root.model.Name(),
+			ModelUUID:      a.root.model.UUID(),
+			ConnectionID:   a.root.connectionID,
+			ImpersonatedBy: authResult.impersonator.Id(),
 		},
 	)
 	if err != nil {
@@ -202,10 +264,24 @@ func (a *admin) getAuditRecorder(req params.LoginRequest, authResult *authResult
 type authResult struct {
 	tag                    names.Tag // nil if external user login
 	anonymousLogin         bool
+	guestLogin             bool
 	userLogin              bool // false if anonymous user
 	controllerOnlyLogin    bool
 	controllerMachineLogin bool
 	userInfo               *params.AuthUserInfo
+
+	// secondFactorEnrollmentRequired is true if the controller
+	// requires a second factor of local users, and the user
+	// logging in has not yet enrolled one.
+	secondFactorEnrollmentRequired bool
+
+	// passwordExpired is true if the password of the
+	// local user logging in has expired.
+	passwordExpired bool
+
+	// impersonator is the controller superuser logged in as the
+	// user, if the login was made with an impersonation macaroon.
+	impersonator names.UserTag
 }
 
 func (a *admin) authenticate(ctx context.Context, req params.LoginRequest) (*authResult, error) {
@@ -249,7 +325,7 @@ func (a *admin) authenticate(ctx context.Context, req params.LoginRequest) (*aut
 		return nil, errors.Trace(err)
 	}
 
-	switch result.tag.(type) {
+	switch tag := result.tag.(type) {
 	case nil:
 		// Macaroon logins are always for users.
 	case names.UserTag:
@@ -257,6 +333,7 @@ func (a *admin) authenticate(ctx context.Context, req params.LoginRequest) (*aut
 			result.anonymousLogin = true
 			result.userLogin = false
 		}
+		result.guestLogin = isGuestLogin(tag, req.Credentials, len(req.Macaroons))
 	default:
 		result.userLogin = false
 	}
@@ -268,7 +345,13 @@ func (a *admin) authenticate(ctx context.Context, req params.LoginRequest) (*aut
 
 	// Only attempt to login with credentials if we are not doing an anonymous login.
 	if !result.anonymousLogin {
-		authInfo, err := a.srv.authenticator.AuthenticateLoginRequest(ctx, a.root.serverHost, modelUUID, req)
+		var authInfo httpcontext.AuthInfo
+		var err error
+		if result.guestLogin {
+			authInfo, err = a.authenticateGuest()
+		} else {
+			authInfo, err = a.srv.authenticator.AuthenticateLoginRequest(ctx, a.root.serverHost, modelUUID, req)
+		}
 		if err != nil {
 			return nil, a.handleAuthError(err)
 		}
@@ -284,6 +367,20 @@ func (a *admin) authenticate(ctx context.Context, req params.LoginRequest) (*aut
 			controllerConn = true
 		}
 
+		// The guest user has no password or second factor, and
+		// superusers impersonating a user may not change them.
+		result.impersonator = authInfo.Impersonator
+		ownLogin := result.userLogin && !result.guestLogin && result.impersonator.Id() == ""
+		if ownLogin && a.srv.shared.requireSecondFactor() {
+			result.secondFactorEnrollmentRequired = !secondFactorEnrolled(authInfo.Entity)
+		}
+		if ownLogin {
+			result.passwordExpired = passwordExpired(authInfo.Entity, a.srv.shared.passwordMaxAge())
+		}
+		if result.impersonator.Id() != "" {
+			logger.Infof("%q logged in as %q by impersonation", result.impersonator.Id(), authInfo.Entity.Tag().Id())
+		}
+
 		// TODO(wallyworld) - we can't yet observe anonymous logins as entity must be non-nil
 		a.root.entity = authInfo.Entity
 		a.apiObserver.Login(authInfo.Entity.Tag(), a.root.model.ModelTag(), controllerConn, req.UserData)
@@ -390,6 +487,13 @@ func (a *admin) fillLoginDetails(result *authResult, lastConnection *time.Time)
 			return errors.Trace(err)
 		}
 		result.userInfo.LastConnection = lastConnection
+		if userTag.IsLocal() {
+			user, err := a.root.state.User(userTag)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			result.userInfo.Defaults = common.UserDefaultsParams(user.Defaults())
+		}
 	}
 	if result.controllerOnlyLogin {
 		if result.anonymousLogin {
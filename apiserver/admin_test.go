@@ -665,9 +665,75 @@ func (s *loginSuite) TestAnonymousControllerLogin(c *gc.C) {
 	c.Assert(result.Facades, jc.DeepEquals, []params.FacadeVersions{
 		{Name: "CrossController", Versions: []int{1}},
 		{Name: "NotifyWatcher", Versions: []int{1}},
+		{Name: "UserManager", Versions: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21}},
 	})
 }
 
+func (s *loginSuite) enableGuestAccess(c *gc.C) names.UserTag {
+	err := s.State.UpdateControllerConfig(map[string]interface{}{
+		corecontroller.GuestAccess: true,
+	}, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	guest, err := s.State.EnsureGuestUser(s.Owner.Name())
+	c.Assert(err, jc.ErrorIsNil)
+	return guest.UserTag()
+}
+
+func (s *loginSuite) TestGuestModelLogin(c *gc.C) {
+	guestTag := s.enableGuestAccess(c)
+	_, err := s.Model.AddUser(state.UserAccessSpec{
+		User:      guestTag,
+		CreatedBy: s.Owner,
+		Access:    permission.ReadAccess,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	info := s.newServer(c)
+	conn := s.openAPIWithoutLogin(c, info)
+
+	var result params.LoginResult
+	request := &params.LoginRequest{
+		AuthTag: guestTag.String(),
+	}
+	err = conn.APICall("Admin", 3, "", "Login", request, &result)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.UserInfo, gc.NotNil)
+	c.Check(result.UserInfo.Identity, gc.Equals, guestTag.String())
+	c.Check(result.UserInfo.ModelAccess, gc.Equals, "read")
+	var facades []string
+	for _, facade := range result.Facades {
+		facades = append(facades, facade.Name)
+	}
+	c.Check(facades, jc.DeepEquals, []string{"Client", "Pinger", "Storage"})
+}
+
+func (s *loginSuite) TestGuestLoginModelNotAllowed(c *gc.C) {
+	guestTag := s.enableGuestAccess(c)
+
+	info := s.newServer(c)
+	conn := s.openAPIWithoutLogin(c, info)
+
+	request := &params.LoginRequest{
+		AuthTag: guestTag.String(),
+	}
+	err := conn.APICall("Admin", 3, "", "Login", request, &params.LoginResult{})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
+func (s *loginSuite) TestGuestLoginDisabled(c *gc.C) {
+	guest, err := s.State.EnsureGuestUser(s.Owner.Name())
+	c.Assert(err, jc.ErrorIsNil)
+
+	info := s.newServer(c)
+	conn := s.openAPIWithoutLogin(c, info)
+
+	request := &params.LoginRequest{
+		AuthTag: guest.Tag().String(),
+	}
+	err = conn.APICall("Admin", 3, "", "Login", request, &params.LoginResult{})
+	c.Assert(err, gc.ErrorMatches, "guest access is disabled")
+}
+
 func (s *loginSuite) TestControllerModel(c *gc.C) {
 	info := s.newServer(c)
 	st := s.openAPIWithoutLogin(c, info)
@@ -930,6 +996,34 @@ func (s *loginSuite) TestLoginResultLocalUser(c *gc.C) {
 	c.Check(result.UserInfo.ModelAccess, gc.Equals, "admin")
 }
 
+func (s *loginSuite) TestLoginResultLocalUserDefaults(c *gc.C) {
+	info := s.newServer(c)
+
+	password := "shhh..."
+	user := s.Factory.MakeUser(c, &factory.UserParams{
+		Password: password,
+	})
+	err := user.SetDefaults(state.UserDefaults{
+		DefaultModel: s.Model.UUID(),
+		Timezone:     "Asia/Tokyo",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	conn := s.openAPIWithoutLogin(c, info)
+
+	var result params.LoginResult
+	request := &params.LoginRequest{
+		AuthTag:     user.Tag().String(),
+		Credentials: password,
+	}
+	err = conn.APICall("Admin", 3, "", "Login", request, &result)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.UserInfo, gc.NotNil)
+	c.Check(result.UserInfo.Defaults, jc.DeepEquals, &params.UserDefaults{
+		DefaultModelTag: s.Model.ModelTag().String(),
+		Timezone:        "Asia/Tokyo",
+	})
+}
+
 func (s *loginSuite) TestLoginResultLocalUserEveryoneCreateOnlyNonLocal(c *gc.C) {
 	info := s.newServer(c)
 
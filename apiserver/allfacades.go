@@ -39,6 +39,7 @@ import (
 	"github.com/juju/juju/apiserver/facades/agent/storageprovisioner"
 	"github.com/juju/juju/apiserver/facades/agent/unitassigner"
 	"github.com/juju/juju/apiserver/facades/agent/uniter"
+	"github.com/juju/juju/apiserver/facades/agent/upgradeinfo"
 	"github.com/juju/juju/apiserver/facades/agent/upgrader"
 	"github.com/juju/juju/apiserver/facades/agent/upgradeseries"
 	"github.com/juju/juju/apiserver/facades/agent/upgradesteps"
@@ -169,7 +170,8 @@ func AllFacades() *facade.Registry {
 	reg("Charms", 2, charms.NewFacade)
 	reg("Cleaner", 2, cleaner.NewCleanerAPI)
 	reg("Client", 1, client.NewFacadeV1)
-	reg("Client", 2, client.NewFacade)
+	reg("Client", 2, client.NewFacadeV2)
+	reg("Client", 3, client.NewFacade) // Adds ApproveCurrentUpgrade
 	reg("Cloud", 1, cloud.NewFacadeV1)
 	reg("Cloud", 2, cloud.NewFacadeV2) // adds AddCloud, AddCredentials, CredentialContents, RemoveClouds
 	reg("Cloud", 3, cloud.NewFacadeV3) // changes signature of UpdateCredentials, adds ModifyCloudAccess
@@ -193,6 +195,8 @@ func AllFacades() *facade.Registry {
 	reg("Controller", 7, controller.NewControllerAPIv7)
 	reg("Controller", 8, controller.NewControllerAPIv8)
 	reg("Controller", 9, controller.NewControllerAPIv9)
+	reg("Controller", 10, controller.NewControllerAPIv10)
+	reg("Controller", 11, controller.NewControllerAPIv11)
 	reg("CrossModelRelations", 1, crossmodelrelations.NewStateCrossModelRelationsAPIV1)
 	reg("CrossModelRelations", 2, crossmodelrelations.NewStateCrossModelRelationsAPI) // Adds WatchRelationChanges, removes WatchRelationUnits
 	reg("CrossController", 1, crosscontroller.NewStateCrossControllerAPI)
@@ -250,8 +254,10 @@ func AllFacades() *facade.Registry {
 	reg("MigrationFlag", 1, migrationflag.NewFacade)
 	reg("MigrationMaster", 1, migrationmaster.NewMigrationMasterFacade)
 	reg("MigrationMaster", 2, migrationmaster.NewMigrationMasterFacadeV2)
+	reg("MigrationMaster", 3, migrationmaster.NewMigrationMasterFacadeV3)
 	reg("MigrationMinion", 1, migrationminion.NewFacade)
-	reg("MigrationTarget", 1, migrationtarget.NewFacade)
+	reg("MigrationTarget", 1, migrationtarget.NewFacadeV1)
+	reg("MigrationTarget", 2, migrationtarget.NewFacade)
 
 	reg("ModelConfig", 1, modelconfig.NewFacadeV1)
 	reg("ModelConfig", 2, modelconfig.NewFacadeV2)
@@ -333,13 +339,38 @@ func AllFacades() *facade.Registry {
 	reg("Uniter", 12, uniter.NewUniterAPIV12)
 	reg("Uniter", 13, uniter.NewUniterAPIV13)
 	reg("Uniter", 14, uniter.NewUniterAPIV14)
-	reg("Uniter", 15, uniter.NewUniterAPI)
+	reg("Uniter", 15, uniter.NewUniterAPIV15)
+	reg("Uniter", 16, uniter.NewUniterAPIV16)
+	reg("Uniter", 17, uniter.NewUniterAPIV17)
+	reg("Uniter", 18, uniter.NewUniterAPIV18)
+	reg("Uniter", 19, uniter.NewUniterAPI)
 
 	reg("Upgrader", 1, upgrader.NewUpgraderFacade)
+	reg("UpgradeInfo", 1, upgradeinfo.NewFacade)
 	reg("UpgradeSeries", 1, upgradeseries.NewAPI)
 	reg("UpgradeSteps", 1, upgradesteps.NewFacadeV1)
-	reg("UserManager", 1, usermanager.NewUserManagerAPI)
-	reg("UserManager", 2, usermanager.NewUserManagerAPI) // Adds ResetPassword
+	reg("UpgradeSteps", 2, upgradesteps.NewFacadeV2)
+	reg("UserManager", 1, usermanager.NewUserManagerAPIV2)
+	reg("UserManager", 2, usermanager.NewUserManagerAPIV2)   // Adds ResetPassword
+	reg("UserManager", 3, usermanager.NewUserManagerAPIV3)   // Adds ListUsers
+	reg("UserManager", 4, usermanager.NewUserManagerAPIV4)   // Adds GrantTemporaryAccess
+	reg("UserManager", 5, usermanager.NewUserManagerAPIV5)   // Adds SetUserDefaults
+	reg("UserManager", 6, usermanager.NewUserManagerAPIV6)   // Adds UserNotifications
+	reg("UserManager", 7, usermanager.NewUserManagerAPIV7)   // Adds PreviewUsername
+	reg("UserManager", 8, usermanager.NewUserManagerAPIV8)   // Adds ExportPermissions and ImportPermissions
+	reg("UserManager", 9, usermanager.NewUserManagerAPIV9)   // Adds RequestAccess, ListAccessRequests, ApproveAccess and DenyAccess
+	reg("UserManager", 10, usermanager.NewUserManagerAPIV10) // Adds EnrollTOTP, VerifyTOTP and RemoveTOTP
+	reg("UserManager", 11, usermanager.NewUserManagerAPIV11) // Adds PermissionWebhookDeadLetters and RetryPermissionWebhookEvents
+	reg("UserManager", 12, usermanager.NewUserManagerAPIV12) // Adds LoginActivity
+	reg("UserManager", 13, usermanager.NewUserManagerAPIV13) // Adds TransferCredential
+	reg("UserManager", 14, usermanager.NewUserManagerAPIV14) // Adds PasswordExpiry
+	reg("UserManager", 15, usermanager.NewUserManagerAPIV15) // Adds UpdateUserLabels and label filtering
+	reg("UserManager", 16, usermanager.NewUserManagerAPIV16) // Adds GrantModelAccess and delegated user administration
+	reg("UserManager", 17, usermanager.NewUserManagerAPIV17) // Adds StartDeviceLogin and PollDeviceLogin
+	reg("UserManager", 18, usermanager.NewUserManagerAPIV18) // Adds ListSessions and TerminateSession
+	reg("UserManager", 19, usermanager.NewUserManagerAPIV19) // Adds GuestAccess, EnableGuestAccess and DisableGuestAccess
+	reg("UserManager", 20, usermanager.NewUserManagerAPIV20) // Adds Impersonate
+	reg("UserManager", 21, usermanager.NewUserManagerAPI)    // Adds AccessReport and AccessReportSchema
 
 	regRaw("AllWatcher", 1, NewAllWatcher, reflect.TypeOf((*SrvAllWatcher)(nil)))
 	// Note: AllModelWatcher uses the same infrastructure as AllWatcher
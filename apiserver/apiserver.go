@@ -74,6 +74,7 @@ type Server struct {
 	authenticator          httpcontext.LocalMacaroonAuthenticator
 	offerAuthCtxt          *crossmodel.AuthContext
 	lastConnectionID       uint64
+	sessions               *sessionRegistry
 	newObserver            observer.ObserverFactory
 	allowModelAccess       bool
 	logSinkWriter          io.WriteCloser
@@ -313,6 +314,7 @@ func newServer(cfg ServerConfig) (_ *Server, err error) {
 			dbLoggerFlushInterval: cfg.LogSinkConfig.DBLoggerFlushInterval,
 		},
 		metricsCollector: cfg.MetricsCollector,
+		sessions:         newSessionRegistry(),
 
 		healthStatus: "starting",
 	}
@@ -936,6 +938,8 @@ func (srv *Server) apiHandler(w http.ResponseWriter, req *http.Request) {
 			connectionID,
 			apiObserver,
 			req.Host,
+			req.RemoteAddr,
+			req.UserAgent(),
 		); err != nil {
 			logger.Errorf("error serving RPCs: %v", err)
 		}
@@ -949,6 +953,8 @@ func (srv *Server) serveConn(
 	connectionID uint64,
 	apiObserver observer.Observer,
 	host string,
+	remoteAddr string,
+	userAgent string,
 ) error {
 	codec := jsoncodec.NewWebsocket(wsConn.Conn)
 	recorderFactory := observer.NewRecorderFactory(
@@ -971,15 +977,21 @@ func (srv *Server) serveConn(
 	st, err := statePool.Get(resolvedModelUUID)
 	if err == nil {
 		defer st.Release()
-		h, err = newAPIHandler(srv, st.State, conn, modelUUID, connectionID, host)
+		h, err = newAPIHandler(srv, st.State, conn, modelUUID, connectionID, host, remoteAddr, userAgent)
 	}
 	if errors.IsNotFound(err) {
 		err = errors.Wrap(err, common.UnknownModelError(resolvedModelUUID))
 	}
 
+	// The connection is closed if the session of
+	// the user logged in on it is terminated.
+	var terminated <-chan struct{}
 	if err != nil {
 		conn.ServeRoot(&errRoot{errors.Trace(err)}, recorderFactory, serverError)
 	} else {
+		terminated = h.terminate
+		defer srv.sessions.remove(connectionID)
+
 		// Set up the admin apis used to accept logins and direct
 		// requests to the relevant business facade.
 		// There may be more than one since we need a new API each
@@ -994,6 +1006,8 @@ func (srv *Server) serveConn(
 	select {
 	case <-conn.Dead():
 	case <-srv.tomb.Dying():
+	case <-terminated:
+		logger.Infof("terminating session of connection %d", connectionID)
 	}
 	return conn.Close()
 }
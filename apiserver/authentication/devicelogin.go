@@ -0,0 +1,274 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package authentication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/juju/clock"
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/apiserver/common"
+)
+
+const (
+	// deviceCodeGrantType is the grant type with which a device
+	// code is exchanged for an access token.
+	deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// defaultDeviceLoginInterval is the interval at which a device
+	// login is polled when the identity provider does not say.
+	defaultDeviceLoginInterval = 5 * time.Second
+
+	// maxIdentityProviderResponse bounds the size of the responses
+	// read from the identity provider.
+	maxIdentityProviderResponse = 1 << 20
+)
+
+// DeviceLoginProvider logs local users in with an external OpenID
+// Connect identity provider, using the OAuth 2.0 device authorization
+// grant (RFC 8628). This allows users of clients without a browser,
+// such as a CLI used over SSH, to log in: the user is shown a code
+// to enter at the provider's verification page on another device,
+// and the client polls until they have done so.
+//
+// The controller, rather than the client, exchanges the device code
+// for an access token and reads the user's identity from the provider,
+// so the identity cannot be forged by the client.
+type DeviceLoginProvider struct {
+	// IssuerURL is the URL of the identity provider, from which
+	// its endpoints are discovered.
+	IssuerURL string
+
+	// ClientID is the client ID with which the controller is
+	// registered at the identity provider.
+	ClientID string
+
+	// UsernameClaim is the claim in the user info returned by the
+	// identity provider that holds the name of the local user.
+	UsernameClaim string
+
+	// HTTPClient is used to make requests to the identity provider.
+	// If it is nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// Clock is used to calculate when device codes expire. If it is
+	// nil, the wall clock is used.
+	Clock clock.Clock
+}
+
+// DeviceAuthorization holds the codes with which a device login
+// is completed.
+type DeviceAuthorization struct {
+	// DeviceCode identifies the login when polling for its
+	// completion. It is not shown to the user.
+	DeviceCode string
+
+	// UserCode is the code that the user enters at the
+	// verification URI.
+	UserCode string
+
+	// VerificationURI is where the user goes to approve the login.
+	VerificationURI string
+
+	// VerificationURIComplete, if set, is the verification
+	// URI with the user code included.
+	VerificationURIComplete string
+
+	// Interval is the minimum time to wait between polls.
+	Interval time.Duration
+
+	// Expiry is when the codes expire.
+	Expiry time.Time
+}
+
+// providerConfig holds the parts of the identity provider's
+// OpenID Connect discovery document that are used.
+type providerConfig struct {
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	TokenEndpoint               string `json:"token_endpoint"`
+	UserInfoEndpoint            string `json:"userinfo_endpoint"`
+}
+
+// oauthError is an error response from the identity
+// provider, as defined in RFC 6749 section 5.2.
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// Error is part of the error interface.
+func (e *oauthError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+// StartLogin starts a device login, returning the codes with which
+// the user approves it and the client polls for its completion.
+func (p *DeviceLoginProvider) StartLogin(ctx context.Context) (*DeviceAuthorization, error) {
+	config, err := p.discover(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if config.DeviceAuthorizationEndpoint == "" {
+		return nil, errors.NotSupportedf("device authorization by identity provider %q", p.IssuerURL)
+	}
+	var resp struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int    `json:"expires_in"`
+		Interval                int    `json:"interval"`
+	}
+	form := url.Values{
+		"client_id": {p.ClientID},
+		"scope":     {"openid profile"},
+	}
+	if err := p.postForm(ctx, config.DeviceAuthorizationEndpoint, form, &resp); err != nil {
+		return nil, errors.Annotate(err, "requesting device authorization")
+	}
+	if resp.DeviceCode == "" || resp.UserCode == "" || resp.VerificationURI == "" {
+		return nil, errors.New("incomplete device authorization from identity provider")
+	}
+	interval := time.Duration(resp.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultDeviceLoginInterval
+	}
+	return &DeviceAuthorization{
+		DeviceCode:              resp.DeviceCode,
+		UserCode:                resp.UserCode,
+		VerificationURI:         resp.VerificationURI,
+		VerificationURIComplete: resp.VerificationURIComplete,
+		Interval:                interval,
+		Expiry:                  p.clock().Now().Add(time.Duration(resp.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// CompleteLogin polls for the completion of the device login with the
+// device code, returning the tag of the local user named by the user
+// info of the user that approved it. If the user has yet to approve
+// the login, common.ErrDeviceLoginPending is returned; if the login is
+// polled too often, common.ErrDeviceLoginSlowDown. Logins that the user
+// denied, or that expired, fail with common.ErrPerm and
+// common.ErrLoginExpired respectively.
+func (p *DeviceLoginProvider) CompleteLogin(ctx context.Context, deviceCode string) (names.UserTag, error) {
+	config, err := p.discover(ctx)
+	if err != nil {
+		return names.UserTag{}, errors.Trace(err)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	form := url.Values{
+		"grant_type":  {deviceCodeGrantType},
+		"device_code": {deviceCode},
+		"client_id":   {p.ClientID},
+	}
+	err = p.postForm(ctx, config.TokenEndpoint, form, &token)
+	if oauthErr, ok := errors.Cause(err).(*oauthError); ok {
+		switch oauthErr.Code {
+		case "authorization_pending":
+			return names.UserTag{}, common.ErrDeviceLoginPending
+		case "slow_down":
+			return names.UserTag{}, common.ErrDeviceLoginSlowDown
+		case "expired_token":
+			return names.UserTag{}, common.ErrLoginExpired
+		case "access_denied":
+			return names.UserTag{}, common.ErrPerm
+		}
+	}
+	if err != nil {
+		return names.UserTag{}, errors.Annotate(err, "requesting access token")
+	}
+
+	var info map[string]interface{}
+	if err := p.get(ctx, config.UserInfoEndpoint, token.AccessToken, &info); err != nil {
+		return names.UserTag{}, errors.Annotate(err, "requesting user info")
+	}
+	name, _ := info[p.UsernameClaim].(string)
+	if !names.IsValidUserName(name) {
+		return names.UserTag{}, errors.NotValidf("%s claim %q", p.UsernameClaim, name)
+	}
+	return names.NewLocalUserTag(name), nil
+}
+
+// discover reads the identity provider's endpoints
+// from its OpenID Connect discovery document.
+func (p *DeviceLoginProvider) discover(ctx context.Context) (*providerConfig, error) {
+	var config providerConfig
+	u := strings.TrimSuffix(p.IssuerURL, "/") + "/.well-known/openid-configuration"
+	if err := p.get(ctx, u, "", &config); err != nil {
+		return nil, errors.Annotatef(err, "discovering identity provider %q", p.IssuerURL)
+	}
+	if config.TokenEndpoint == "" || config.UserInfoEndpoint == "" {
+		return nil, errors.Errorf("identity provider %q has no token or user info endpoint", p.IssuerURL)
+	}
+	return &config, nil
+}
+
+func (p *DeviceLoginProvider) get(ctx context.Context, u, accessToken string, v interface{}) error {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return p.do(ctx, req, v)
+}
+
+func (p *DeviceLoginProvider) postForm(ctx context.Context, u string, form url.Values, v interface{}) error {
+	req, err := http.NewRequest("POST", u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return p.do(ctx, req, v)
+}
+
+// do makes the request, decoding the JSON response into v. OAuth error
+// responses are returned as *oauthError.
+func (p *DeviceLoginProvider) do(ctx context.Context, req *http.Request, v interface{}) error {
+	client := p.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer resp.Body.Close()
+
+	body := io.LimitReader(resp.Body, maxIdentityProviderResponse)
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr oauthError
+		if err := json.NewDecoder(body).Decode(&oauthErr); err == nil && oauthErr.Code != "" {
+			return &oauthErr
+		}
+		return errors.Errorf("%s %s: %s", req.Method, req.URL, resp.Status)
+	}
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		return errors.Annotatef(err, "decoding response from %s", req.URL)
+	}
+	return nil
+}
+
+func (p *DeviceLoginProvider) clock() clock.Clock {
+	if p.Clock == nil {
+		return clock.WallClock
+	}
+	return p.Clock
+}
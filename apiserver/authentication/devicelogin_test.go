@@ -0,0 +1,166 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package authentication_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/juju/clock/testclock"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/apiserver/authentication"
+	"github.com/juju/juju/apiserver/common"
+)
+
+type deviceLoginSuite struct {
+	testing.IsolationSuite
+
+	server *httptest.Server
+	clock  *testclock.Clock
+
+	// tokenError is the OAuth error returned by the token
+	// endpoint, if any, and userInfo the user info returned.
+	tokenError string
+	userInfo   map[string]interface{}
+	form       map[string]string
+}
+
+var _ = gc.Suite(&deviceLoginSuite{})
+
+func (s *deviceLoginSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.clock = testclock.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	s.tokenError = ""
+	s.userInfo = map[string]interface{}{"preferred_username": "bob", "sub": "1234"}
+	s.form = nil
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                        s.server.URL,
+			"device_authorization_endpoint": s.server.URL + "/device",
+			"token_endpoint":                s.server.URL + "/token",
+			"userinfo_endpoint":             s.server.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, req *http.Request) {
+		s.recordForm(req)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": "https://idp.example.com/device",
+			"expires_in":       600,
+			"interval":         10,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		s.recordForm(req)
+		if s.tokenError != "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": s.tokenError})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer access-token" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+			return
+		}
+		writeJSON(w, http.StatusOK, s.userInfo)
+	})
+	s.server = httptest.NewServer(mux)
+	s.AddCleanup(func(*gc.C) { s.server.Close() })
+}
+
+func (s *deviceLoginSuite) recordForm(req *http.Request) {
+	req.ParseForm()
+	s.form = make(map[string]string)
+	for key := range req.PostForm {
+		s.form[key] = req.PostForm.Get(key)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *deviceLoginSuite) provider() *authentication.DeviceLoginProvider {
+	return &authentication.DeviceLoginProvider{
+		IssuerURL:     s.server.URL,
+		ClientID:      "juju",
+		UsernameClaim: "preferred_username",
+		HTTPClient:    s.server.Client(),
+		Clock:         s.clock,
+	}
+}
+
+func (s *deviceLoginSuite) TestStartLogin(c *gc.C) {
+	auth, err := s.provider().StartLogin(context.Background())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(auth, jc.DeepEquals, &authentication.DeviceAuthorization{
+		DeviceCode:      "device-code",
+		UserCode:        "ABCD-EFGH",
+		VerificationURI: "https://idp.example.com/device",
+		Interval:        10 * time.Second,
+		Expiry:          s.clock.Now().Add(10 * time.Minute),
+	})
+	c.Assert(s.form, jc.DeepEquals, map[string]string{
+		"client_id": "juju",
+		"scope":     "openid profile",
+	})
+}
+
+func (s *deviceLoginSuite) TestCompleteLogin(c *gc.C) {
+	tag, err := s.provider().CompleteLogin(context.Background(), "device-code")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(tag, gc.Equals, names.NewLocalUserTag("bob"))
+	c.Assert(s.form, jc.DeepEquals, map[string]string{
+		"grant_type":  "urn:ietf:params:oauth:grant-type:device_code",
+		"device_code": "device-code",
+		"client_id":   "juju",
+	})
+}
+
+func (s *deviceLoginSuite) TestCompleteLoginErrors(c *gc.C) {
+	for _, test := range []struct {
+		tokenError string
+		expect     error
+	}{
+		{"authorization_pending", common.ErrDeviceLoginPending},
+		{"slow_down", common.ErrDeviceLoginSlowDown},
+		{"expired_token", common.ErrLoginExpired},
+		{"access_denied", common.ErrPerm},
+	} {
+		c.Logf("token error %q", test.tokenError)
+		s.tokenError = test.tokenError
+		_, err := s.provider().CompleteLogin(context.Background(), "device-code")
+		c.Check(err, gc.Equals, test.expect)
+	}
+
+	s.tokenError = "invalid_grant"
+	_, err := s.provider().CompleteLogin(context.Background(), "device-code")
+	c.Assert(err, gc.ErrorMatches, `requesting access token: invalid_grant`)
+}
+
+func (s *deviceLoginSuite) TestCompleteLoginInvalidUsername(c *gc.C) {
+	s.userInfo = map[string]interface{}{"preferred_username": "bob@external"}
+	_, err := s.provider().CompleteLogin(context.Background(), "device-code")
+	c.Assert(err, gc.ErrorMatches, `preferred_username claim "bob@external" not valid`)
+
+	s.userInfo = map[string]interface{}{"sub": "1234"}
+	_, err = s.provider().CompleteLogin(context.Background(), "device-code")
+	c.Assert(err, gc.ErrorMatches, `preferred_username claim "" not valid`)
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package authentication
+
+import (
+	"context"
+	"time"
+
+	"github.com/juju/clock"
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+	"gopkg.in/macaroon-bakery.v2/bakery"
+	"gopkg.in/macaroon-bakery.v2/bakery/checkers"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/charmstore"
+	"github.com/juju/juju/state"
+)
+
+const (
+	impersonatorKey = "impersonator"
+
+	// ImpersonationExpiryTime is how long a controller superuser
+	// can log in as another user with an impersonation macaroon.
+	ImpersonationExpiryTime = 15 * time.Minute
+)
+
+// ImpersonationOp is the operation authorized by the macaroons with which
+// controller superusers log in as other users. Only macaroons created for
+// it authorize it, so caveats added to an ordinary login macaroon by the
+// user holding it cannot make it look like an impersonation.
+var ImpersonationOp = bakery.Op{Entity: "login", Action: "impersonate"}
+
+// ImpersonatedEntity is the entity returned by the UserAuthenticator for
+// logins with an impersonation macaroon. The login acts as the entity,
+// with its permissions, on behalf of the impersonator.
+type ImpersonatedEntity struct {
+	state.Entity
+
+	// Impersonator is the controller superuser
+	// that created the impersonation macaroon.
+	Impersonator names.UserTag
+}
+
+// CreateImpersonationMacaroon creates a macaroon with which the
+// impersonator can log in as the local user, without presenting a
+// password or obtaining a discharge, until it expires. Logins with it
+// are marked as impersonations, so must only be allowed for controller
+// superusers.
+func CreateImpersonationMacaroon(
+	ctx context.Context,
+	tag, impersonator names.UserTag,
+	b ExpirableStorageBakery,
+	clock clock.Clock,
+	version bakery.Version,
+) (*bakery.Macaroon, error) {
+	minter, err := b.ExpireStorageAfter(ImpersonationExpiryTime)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return minter.NewMacaroon(ctx, version, []checkers.Caveat{
+		checkers.DeclaredCaveat(usernameKey, tag.Id()),
+		checkers.DeclaredCaveat(impersonatorKey, impersonator.Id()),
+		checkers.TimeBeforeCaveat(clock.Now().Add(ImpersonationExpiryTime)),
+	}, ImpersonationOp)
+}
+
+// authenticateImpersonation authenticates a login with an impersonation
+// macaroon, returning false if the request holds none. The login operation
+// is authorized by any macaroon, including impersonation macaroons, so
+// logins must be checked for impersonation first.
+func (u *UserAuthenticator) authenticateImpersonation(
+	ctx context.Context, entityFinder EntityFinder, tag names.UserTag, req params.LoginRequest,
+) (state.Entity, bool, error) {
+	if len(req.Macaroons) == 0 {
+		return nil, false, nil
+	}
+	ai, err := u.Bakery.Auth(req.Macaroons...).Allow(ctx, ImpersonationOp)
+	if err != nil || len(ai.Conditions()) == 0 {
+		return nil, false, nil
+	}
+	mac := ai.Macaroons[ai.OpIndexes[ImpersonationOp]]
+	// Conflicting declarations of a key, as made by caveats added
+	// to the macaroon after it was created, remove the key.
+	declared := checkers.InferDeclared(charmstore.MacaroonNamespace, mac)
+	impersonator := declared[impersonatorKey]
+	if tag.Id() != declared[usernameKey] || !names.IsValidUser(impersonator) {
+		return nil, true, common.ErrPerm
+	}
+	entity, err := entityFinder.FindEntity(tag)
+	if errors.IsNotFound(err) {
+		logger.Debugf("entity %s not found", tag.String())
+		return nil, true, errors.Trace(common.ErrBadCreds)
+	} else if err != nil {
+		return nil, true, errors.Trace(err)
+	}
+	return &ImpersonatedEntity{
+		Entity:       entity,
+		Impersonator: names.NewUserTag(impersonator),
+	}, true, nil
+}
@@ -66,7 +66,8 @@ var _ EntityAuthenticator = (*UserAuthenticator)(nil)
 // error on authentication failure.
 //
 // If and only if no password is supplied, then Authenticate will check for any
-// valid macaroons. Otherwise, password authentication will be performed.
+// valid macaroons. Otherwise, password authentication will be performed, and
+// users who have enrolled a second factor must also supply it.
 func (u *UserAuthenticator) Authenticate(
 	ctx context.Context, entityFinder EntityFinder, tag names.Tag, req params.LoginRequest,
 ) (state.Entity, error) {
@@ -77,7 +78,44 @@ func (u *UserAuthenticator) Authenticate(
 	if req.Credentials == "" && userTag.IsLocal() {
 		return u.authenticateMacaroons(ctx, entityFinder, userTag, req)
 	}
-	return u.AgentAuthenticator.Authenticate(ctx, entityFinder, tag, req)
+	entity, err := u.AgentAuthenticator.Authenticate(ctx, entityFinder, tag, req)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := checkSecondFactor(entity, req.SecondFactor); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return entity, nil
+}
+
+// secondFactorAuthenticator is implemented by users that may have
+// enrolled a second authentication factor.
+type secondFactorAuthenticator interface {
+	SecondFactorEnrolled() bool
+	SecondFactorValid(code string) (bool, error)
+}
+
+// checkSecondFactor checks the second factor presented with a password,
+// if the entity has enrolled one. Logins with macaroons do not need to
+// present it again, since the macaroons are only issued to such users
+// after a login with both the password and the second factor: device
+// logins are refused for them.
+func checkSecondFactor(entity state.Entity, code string) error {
+	authenticator, ok := entity.(secondFactorAuthenticator)
+	if !ok || !authenticator.SecondFactorEnrolled() {
+		return nil
+	}
+	if code == "" {
+		return common.ErrSecondFactorRequired
+	}
+	valid, err := authenticator.SecondFactorValid(code)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !valid {
+		return common.ErrBadCreds
+	}
+	return nil
 }
 
 // CreateLocalLoginMacaroon creates a macaroon that may be provided to a
@@ -155,9 +193,35 @@ func DischargeCaveats(tag names.UserTag, clock clock.Clock) []checkers.Caveat {
 	return firstPartyCaveats
 }
 
+// CreateLoginMacaroon creates a macaroon with which the local user can
+// log in, without presenting a password or obtaining a discharge, until
+// it expires. It must only be given to users whose identity has been
+// established some other way, such as by a DeviceLoginProvider.
+func CreateLoginMacaroon(
+	ctx context.Context,
+	tag names.UserTag,
+	b ExpirableStorageBakery,
+	clock clock.Clock,
+	version bakery.Version,
+) (*bakery.Macaroon, error) {
+	// The root keys for these macaroons are stored in MongoDB.
+	// Expire the documents along with the macaroons.
+	minter, err := b.ExpireStorageAfter(localLoginExpiryTime)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return minter.NewMacaroon(ctx, version, DischargeCaveats(tag, clock), identchecker.LoginOp)
+}
+
 func (u *UserAuthenticator) authenticateMacaroons(
 	ctx context.Context, entityFinder EntityFinder, tag names.UserTag, req params.LoginRequest,
 ) (state.Entity, error) {
+	// Impersonation macaroons also authorize the login operation,
+	// so must be checked for first.
+	if entity, ok, err := u.authenticateImpersonation(ctx, entityFinder, tag, req); ok {
+		return entity, errors.Trace(err)
+	}
+
 	// Check for a valid request macaroon.
 	a := u.Bakery.Auth(req.Macaroons...)
 	ai, err := a.Allow(ctx, identchecker.LoginOp)
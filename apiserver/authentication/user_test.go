@@ -24,6 +24,7 @@ import (
 	"github.com/juju/juju/apiserver/authentication"
 	"github.com/juju/juju/apiserver/common"
 	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/core/totp"
 	jujutesting "github.com/juju/juju/juju/testing"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/testing/factory"
@@ -111,6 +112,38 @@ func (s *userAuthenticatorSuite) TestUserLoginWrongPassword(c *gc.C) {
 
 }
 
+func (s *userAuthenticatorSuite) TestUserLoginSecondFactor(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{
+		Name:     "bobbrown",
+		Password: "password",
+	})
+	secret, err := user.StartTOTPEnrollment()
+	c.Assert(err, jc.ErrorIsNil)
+	code := totp.Code(secret, totp.Step(time.Now()))
+	recoveryCodes, err := user.CompleteTOTPEnrollment(code)
+	c.Assert(err, jc.ErrorIsNil)
+
+	authenticator := &authentication.UserAuthenticator{}
+	login := func(secondFactor string) error {
+		_, err := authenticator.Authenticate(context.TODO(), s.State, user.Tag(), params.LoginRequest{
+			Credentials:  "password",
+			SecondFactor: secondFactor,
+		})
+		return err
+	}
+	err = login("")
+	c.Assert(errors.Cause(err), gc.Equals, common.ErrSecondFactorRequired)
+
+	// The code used to complete the enrollment cannot be used again.
+	err = login(code)
+	c.Assert(errors.Cause(err), gc.Equals, common.ErrBadCreds)
+
+	err = login(recoveryCodes[0])
+	c.Assert(err, jc.ErrorIsNil)
+	err = login(recoveryCodes[0])
+	c.Assert(errors.Cause(err), gc.Equals, common.ErrBadCreds)
+}
+
 func (s *userAuthenticatorSuite) TestInvalidRelationLogin(c *gc.C) {
 
 	// add relation
@@ -152,7 +185,8 @@ func (s *userAuthenticatorSuite) TestValidMacaroonUserLogin(c *gc.C) {
 	})
 	c.Assert(err, jc.ErrorIsNil)
 
-	service.CheckCallNames(c, "Auth")
+	// The macaroons are first checked for impersonation.
+	service.CheckCallNames(c, "Auth", "Auth")
 	call := service.Calls()[0]
 	c.Assert(call.Args, gc.HasLen, 1)
 	c.Assert(call.Args[0], jc.DeepEquals, macaroons)
@@ -173,6 +207,83 @@ func (s *userAuthenticatorSuite) TestCreateLocalLoginMacaroon(c *gc.C) {
 	})
 }
 
+func (s *userAuthenticatorSuite) TestCreateLoginMacaroon(c *gc.C) {
+	service := mockBakeryService{}
+	clock := testclock.NewClock(time.Time{})
+	_, err := authentication.CreateLoginMacaroon(
+		context.TODO(),
+		names.NewUserTag("bobbrown"), &service, clock, bakery.LatestVersion,
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	service.CheckCallNames(c, "ExpireStorageAfter", "NewMacaroon")
+	service.CheckCall(c, 0, "ExpireStorageAfter", 24*time.Hour)
+	service.CheckCall(c, 1, "NewMacaroon", []checkers.Caveat{
+		{Condition: "declared username bobbrown", Namespace: "std"},
+		{Condition: "time-before 0001-01-02T00:00:00Z", Namespace: "std"},
+	})
+}
+
+func (s *userAuthenticatorSuite) TestCreateImpersonationMacaroon(c *gc.C) {
+	service := mockBakeryService{}
+	clock := testclock.NewClock(time.Time{})
+	_, err := authentication.CreateImpersonationMacaroon(
+		context.TODO(),
+		names.NewUserTag("bobbrown"), names.NewUserTag("admin"),
+		&service, clock, bakery.LatestVersion,
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	service.CheckCallNames(c, "ExpireStorageAfter", "NewMacaroon")
+	service.CheckCall(c, 0, "ExpireStorageAfter", 15*time.Minute)
+	service.CheckCall(c, 1, "NewMacaroon", []checkers.Caveat{
+		{Condition: "declared username bobbrown", Namespace: "std"},
+		{Condition: "declared impersonator admin", Namespace: "std"},
+		{Condition: "time-before 0001-01-01T00:15:00Z", Namespace: "std"},
+	})
+}
+
+func (s *userAuthenticatorSuite) TestImpersonationMacaroonUserLogin(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{
+		Name: "bob",
+	})
+	mac, err := macaroon.New(nil, nil, "", macaroon.LatestVersion)
+	c.Assert(err, jc.ErrorIsNil)
+	err = mac.AddFirstPartyCaveat([]byte("declared username bob"))
+	c.Assert(err, jc.ErrorIsNil)
+	err = mac.AddFirstPartyCaveat([]byte("declared impersonator admin"))
+	c.Assert(err, jc.ErrorIsNil)
+	service := mockBakeryService{verifier: mockImpersonationVerifier{}}
+
+	authenticator := &authentication.UserAuthenticator{Bakery: &service, Clock: testclock.NewClock(time.Time{})}
+	entity, err := authenticator.Authenticate(context.TODO(), s.State, user.Tag(), params.LoginRequest{
+		Macaroons: []macaroon.Slice{{mac}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	impersonated, ok := entity.(*authentication.ImpersonatedEntity)
+	c.Assert(ok, jc.IsTrue)
+	c.Check(impersonated.Tag(), gc.Equals, user.Tag())
+	c.Check(impersonated.Impersonator, gc.Equals, names.NewUserTag("admin"))
+	service.CheckCallNames(c, "Auth")
+}
+
+func (s *userAuthenticatorSuite) TestImpersonationMacaroonOtherUserLogin(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{
+		Name: "alice",
+	})
+	mac, err := macaroon.New(nil, nil, "", macaroon.LatestVersion)
+	c.Assert(err, jc.ErrorIsNil)
+	err = mac.AddFirstPartyCaveat([]byte("declared username bob"))
+	c.Assert(err, jc.ErrorIsNil)
+	err = mac.AddFirstPartyCaveat([]byte("declared impersonator admin"))
+	c.Assert(err, jc.ErrorIsNil)
+	service := mockBakeryService{verifier: mockImpersonationVerifier{}}
+
+	authenticator := &authentication.UserAuthenticator{Bakery: &service, Clock: testclock.NewClock(time.Time{})}
+	_, err = authenticator.Authenticate(context.TODO(), s.State, user.Tag(), params.LoginRequest{
+		Macaroons: []macaroon.Slice{{mac}},
+	})
+	c.Assert(errors.Cause(err), gc.Equals, common.ErrPerm)
+}
+
 func (s *userAuthenticatorSuite) TestAuthenticateLocalLoginMacaroon(c *gc.C) {
 	service := mockBakeryService{}
 	clock := testclock.NewClock(time.Time{})
@@ -211,13 +322,21 @@ func (s *userAuthenticatorSuite) TestAuthenticateLocalLoginMacaroon(c *gc.C) {
 
 type mockBakeryService struct {
 	testing.Stub
+
+	// verifier, if set, verifies macaroons
+	// in place of a mockVerifier.
+	verifier bakery.MacaroonVerifier
 }
 
 func (s *mockBakeryService) Auth(mss ...macaroon.Slice) *bakery.AuthChecker {
 	s.MethodCall(s, "Auth", mss)
+	var verifier bakery.MacaroonVerifier = mockVerifier{}
+	if s.verifier != nil {
+		verifier = s.verifier
+	}
 	checker := bakery.NewChecker(bakery.CheckerParams{
 		OpsAuthorizer:    mockAuthorizer{},
-		MacaroonVerifier: mockVerifier{},
+		MacaroonVerifier: verifier,
 	})
 	return checker.Auth(mss...)
 }
@@ -252,6 +371,15 @@ func (mockVerifier) VerifyMacaroon(ctx context.Context, ms macaroon.Slice) ([]ba
 	return []bakery.Op{identchecker.LoginOp}, []string{"declared username bob"}, nil
 }
 
+type mockImpersonationVerifier struct{}
+
+func (mockImpersonationVerifier) VerifyMacaroon(ctx context.Context, ms macaroon.Slice) ([]bakery.Op, []string, error) {
+	return []bakery.Op{authentication.ImpersonationOp}, []string{
+		"declared username bob",
+		"declared impersonator admin",
+	}, nil
+}
+
 type macaroonAuthenticatorSuite struct {
 	jujutesting.JujuConnSuite
 	// username holds the username that will be
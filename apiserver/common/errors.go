@@ -132,6 +132,27 @@ var (
 	ErrBadRequest         = errors.New("invalid request")
 	ErrTryAgain           = errors.New("try again")
 	ErrActionNotAvailable = errors.New("action no longer available")
+
+	// ErrSecondFactorRequired is returned when a user that has enrolled
+	// a second authentication factor logs in without presenting it.
+	ErrSecondFactorRequired = errors.New("second factor required")
+
+	// ErrSecondFactorEnrollmentRequired is returned to users that have
+	// not enrolled a second authentication factor for any call other
+	// than those needed to enroll one, when the controller requires it.
+	ErrSecondFactorEnrollmentRequired = errors.New("second factor enrollment required by the controller")
+
+	// ErrPasswordExpired is returned to users whose passwords have
+	// expired for any call other than that needed to change them.
+	ErrPasswordExpired = errors.New("password expired")
+
+	// ErrDeviceLoginPending is returned when polling a device login
+	// that the user has yet to approve at the identity provider.
+	ErrDeviceLoginPending = errors.New("device login pending")
+
+	// ErrDeviceLoginSlowDown is returned when a device login is polled
+	// more often than the identity provider allows.
+	ErrDeviceLoginSlowDown = errors.New("device login polled too often")
 )
 
 // OperationBlockedError returns an error which signifies that
@@ -152,6 +173,7 @@ var singletonErrorCodes = map[error]string{
 	state.ErrCannotEnterScope:    params.CodeCannotEnterScope,
 	state.ErrUnitHasSubordinates: params.CodeUnitHasSubordinates,
 	state.ErrDead:                params.CodeDead,
+	state.ErrCounterLimit:        params.CodeCharmCounterLimit,
 	txn.ErrExcessiveContention:   params.CodeExcessiveContention,
 	leadership.ErrClaimDenied:    params.CodeLeadershipClaimDenied,
 	lease.ErrClaimDenied:         params.CodeLeaseClaimDenied,
@@ -165,6 +187,12 @@ var singletonErrorCodes = map[error]string{
 	ErrStoppedWatcher:            params.CodeStopped,
 	ErrTryAgain:                  params.CodeTryAgain,
 	ErrActionNotAvailable:        params.CodeActionNotAvailable,
+
+	ErrSecondFactorRequired:           params.CodeSecondFactorRequired,
+	ErrSecondFactorEnrollmentRequired: params.CodeSecondFactorEnrollmentRequired,
+	ErrPasswordExpired:                params.CodePasswordExpired,
+	ErrDeviceLoginPending:             params.CodeDeviceLoginPending,
+	ErrDeviceLoginSlowDown:            params.CodeDeviceLoginSlowDown,
 }
 
 func singletonCode(err error) (string, bool) {
@@ -198,7 +226,8 @@ func ServerErrorAndStatus(err error) (*params.Error, int) {
 	}
 	status := http.StatusInternalServerError
 	switch err1.Code {
-	case params.CodeUnauthorized:
+	case params.CodeUnauthorized,
+		params.CodeSecondFactorRequired:
 		status = http.StatusUnauthorized
 	case params.CodeNotFound,
 		params.CodeUserNotFound,
@@ -212,7 +241,9 @@ func ServerErrorAndStatus(err error) (*params.Error, int) {
 		// This should really be http.StatusForbidden but earlier versions
 		// of juju clients rely on the 400 status, so we leave it like that.
 		status = http.StatusBadRequest
-	case params.CodeForbidden:
+	case params.CodeForbidden,
+		params.CodeSecondFactorEnrollmentRequired,
+		params.CodePasswordExpired:
 		status = http.StatusForbidden
 	case params.CodeDischargeRequired:
 		status = http.StatusUnauthorized
@@ -285,6 +316,20 @@ func ServerError(err error) *params.Error {
 		code = params.CodeForbidden
 	case state.IsIncompatibleSeriesError(err):
 		code = params.CodeIncompatibleSeries
+	case state.IsInvalidUnitStateError(err):
+		stateErr := errors.Cause(err).(*state.ErrInvalidUnitState)
+		code = params.CodeInvalidCharmState
+		errInfo := params.InvalidUnitStateErrorInfo{
+			TotalSize: stateErr.TotalSize,
+		}
+		for _, k := range stateErr.Keys {
+			errInfo.Keys = append(errInfo.Keys, params.InvalidUnitStateKey{
+				Key:    k.Key,
+				Size:   k.Size,
+				Reason: k.Reason,
+			})
+		}
+		info = errInfo.AsMap()
 	case IsDischargeRequiredError(err):
 		dischErr := errors.Cause(err).(*DischargeRequiredError)
 		code = params.CodeDischargeRequired
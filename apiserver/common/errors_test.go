@@ -71,6 +71,21 @@ var errorTransformTests = []struct {
 	code:       params.CodeExcessiveContention,
 	status:     http.StatusInternalServerError,
 	helperFunc: params.IsCodeExcessiveContention,
+}, {
+	err:        state.ErrCounterLimit,
+	code:       params.CodeCharmCounterLimit,
+	status:     http.StatusInternalServerError,
+	helperFunc: params.IsCodeCharmCounterLimit,
+}, {
+	err:        common.ErrDeviceLoginPending,
+	code:       params.CodeDeviceLoginPending,
+	status:     http.StatusInternalServerError,
+	helperFunc: params.IsCodeDeviceLoginPending,
+}, {
+	err:        common.ErrDeviceLoginSlowDown,
+	code:       params.CodeDeviceLoginSlowDown,
+	status:     http.StatusInternalServerError,
+	helperFunc: params.IsCodeDeviceLoginSlowDown,
 }, {
 	err:        state.ErrUnitHasSubordinates,
 	code:       params.CodeUnitHasSubordinates,
@@ -209,6 +224,22 @@ var errorTransformTests = []struct {
 		}
 		return true
 	},
+}, {
+	err: &state.ErrInvalidUnitState{
+		Keys: []state.InvalidUnitStateKey{{Key: "", Size: 3, Reason: "is empty"}},
+	},
+	status: http.StatusInternalServerError,
+	code:   params.CodeInvalidCharmState,
+	helperFunc: func(err error) bool {
+		err1, ok := err.(*params.Error)
+		exp := asMap(params.InvalidUnitStateErrorInfo{
+			Keys: []params.InvalidUnitStateKey{{Key: "", Size: 3, Reason: "is empty"}},
+		})
+		if !ok || err1.Info == nil || !reflect.DeepEqual(err1.Info, exp) {
+			return false
+		}
+		return params.IsCodeInvalidCharmState(err)
+	},
 }, {
 	err:    nil,
 	code:   "",
@@ -275,7 +306,8 @@ func (s *errorsSuite) TestErrorTransform(c *gc.C) {
 			params.CodeDischargeRequired,
 			params.CodeModelNotFound,
 			params.CodeRetry,
-			params.CodeRedirect:
+			params.CodeRedirect,
+			params.CodeInvalidCharmState:
 			continue
 		case params.CodeOperationBlocked:
 			// ServerError doesn't actually have a case for this code.
@@ -66,6 +66,7 @@ type ModelManagerBackend interface {
 	Export() (description.Model, error)
 	ExportPartial(state.ExportConfig) (description.Model, error)
 	SetUserAccess(subject names.UserTag, target names.Tag, access permission.Access) (permission.UserAccess, error)
+	QueuePermissionEvent(state.PermissionEvent) error
 	SetModelMeterStatus(string, string) error
 	ReloadSpaces(environ environs.BootstrapEnviron) error
 	LatestMigration() (state.ModelMigration, error)
@@ -50,6 +50,8 @@ func StateToParamsUserAccessPermission(descriptionAccess permission.Access) (par
 		return params.ModelReadAccess, nil
 	case permission.WriteAccess:
 		return params.ModelWriteAccess, nil
+	case permission.UserAdminAccess:
+		return params.ModelUserAdminAccess, nil
 	case permission.AdminAccess:
 		return params.ModelAdminAccess, nil
 	}
@@ -57,3 +59,19 @@ func StateToParamsUserAccessPermission(descriptionAccess permission.Access) (par
 	return "", errors.NotValidf("model access permission %q", descriptionAccess)
 
 }
+
+// UserDefaultsParams converts state.UserDefaults to params.UserDefaults.
+// It returns nil if the user has no stored defaults.
+func UserDefaultsParams(defaults state.UserDefaults) *params.UserDefaults {
+	if defaults.DefaultModel == "" && defaults.Timezone == "" && len(defaults.DisplayOptions) == 0 {
+		return nil
+	}
+	result := &params.UserDefaults{
+		Timezone:       defaults.Timezone,
+		DisplayOptions: defaults.DisplayOptions,
+	}
+	if defaults.DefaultModel != "" {
+		result.DefaultModelTag = names.NewModelTag(defaults.DefaultModel).String()
+	}
+	return result
+}
@@ -76,7 +76,12 @@ func (w *relationUnitsWatcher) convert(
 	if event.Changed != nil {
 		changed = make(map[string]params.UnitSettings, len(event.Changed))
 		for key, val := range event.Changed {
-			changed[key] = params.UnitSettings{Version: val.Version}
+			settings := params.UnitSettings{Version: val.Version}
+			if !val.Observed.IsZero() {
+				observed := val.Observed
+				settings.Observed = &observed
+			}
+			changed[key] = settings
 		}
 	}
 	return params.RelationUnitsChange{
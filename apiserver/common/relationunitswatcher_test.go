@@ -36,9 +36,11 @@ func (s *relationUnitsWatcherSuite) TestRelationUnitsWatcherFromState(c *gc.C) {
 	c.Assert(source.Err(), gc.Equals, tomb.ErrStillAlive)
 	c.Assert(w.Err(), gc.Equals, tomb.ErrStillAlive)
 
+	observed := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
 	event := watcher.RelationUnitsChange{
 		Changed: map[string]watcher.UnitSettings{
-			"joni/1": {Version: 23},
+			"joni/1": {Version: 23, Observed: observed},
+			"joni/2": {Version: 7},
 		},
 		AppChanged: map[string]int64{
 			"mitchell": 42,
@@ -58,7 +60,8 @@ func (s *relationUnitsWatcherSuite) TestRelationUnitsWatcherFromState(c *gc.C) {
 	case result := <-w.Changes():
 		c.Assert(result, gc.DeepEquals, params.RelationUnitsChange{
 			Changed: map[string]params.UnitSettings{
-				"joni/1": {Version: 23},
+				"joni/1": {Version: 23, Observed: &observed},
+				"joni/2": {Version: 7},
 			},
 			AppChanged: map[string]int64{
 				"mitchell": 42,
@@ -62,8 +62,9 @@ func TestingAPIHandler(c *gc.C, pool *state.StatePool, st *state.State) (*apiHan
 		offerAuthCtxt: offerAuthCtxt,
 		shared:        &sharedServerContext{statePool: pool},
 		tag:           names.NewMachineTag("0"),
+		sessions:      newSessionRegistry(),
 	}
-	h, err := newAPIHandler(srv, st, nil, st.ModelUUID(), 6543, "testing.invalid:1234")
+	h, err := newAPIHandler(srv, st, nil, st.ModelUUID(), 6543, "testing.invalid:1234", "10.0.0.1:54321", "")
 	c.Assert(err, jc.ErrorIsNil)
 	return h, h.getResources()
 }
@@ -84,6 +85,34 @@ func TestingUpgradingRoot() rpc.Root {
 	return restrictRoot(r, upgradeMethodsOnly)
 }
 
+// TestingSecondFactorEnrollmentRoot returns a restricted srvRoot for
+// a user that must enroll a second factor.
+func TestingSecondFactorEnrollmentRoot() rpc.Root {
+	r := TestingAPIRoot(AllFacades())
+	return restrictRoot(r, secondFactorEnrollmentMethodsOnly)
+}
+
+// TestingPasswordExpiredRoot returns a restricted srvRoot for
+// a user whose password has expired.
+func TestingPasswordExpiredRoot() rpc.Root {
+	r := TestingAPIRoot(AllFacades())
+	return restrictRoot(r, passwordExpiredMethodsOnly)
+}
+
+// TestingGuestRoot returns a restricted srvRoot for
+// a client logged in as the guest user.
+func TestingGuestRoot() rpc.Root {
+	r := TestingAPIRoot(AllFacades())
+	return restrictRoot(r, guestMethodsOnly)
+}
+
+// TestingImpersonationRoot returns a restricted srvRoot for a
+// controller superuser logged in as another user.
+func TestingImpersonationRoot() rpc.Root {
+	r := TestingAPIRoot(AllFacades())
+	return restrictRoot(r, impersonationMethodsBlocked)
+}
+
 // TestingMigratingRoot returns a resricted srvRoot in a migration
 // scenario.
 func TestingMigratingRoot() rpc.Root {
@@ -36,9 +36,8 @@ import (
 
 var logger = loggo.GetLogger("juju.apiserver.uniter")
 
-// UniterAPI implements the latest version (v15) of the Uniter API, which adds
-// the State, CommitHookChanges calls and changes WatchActionNotifications to
-// notify on action changes.
+// UniterAPI implements the latest version (v18) of the Uniter API, which
+// adds AdjustCharmCounters.
 type UniterAPI struct {
 	*common.LifeGetter
 	*StatusAPI
@@ -75,10 +74,35 @@ type UniterAPI struct {
 	cloudSpec       cloudspec.CloudSpecAPI
 }
 
+// UniterAPIV18 implements version (v18) of the Uniter API, which
+// adds AdjustCharmCounters.
+type UniterAPIV18 struct {
+	UniterAPI
+}
+
+// UniterAPIV17 implements version (v17) of the Uniter API, which
+// adds PeerUnitStates.
+type UniterAPIV17 struct {
+	UniterAPIV18
+}
+
+// UniterAPIV16 implements version (v16) of the Uniter API, which
+// allows State to be read for Dying units.
+type UniterAPIV16 struct {
+	UniterAPIV17
+}
+
+// UniterAPIV15 implements version (v15) of the Uniter API, which adds
+// the State, CommitHookChanges calls and changes WatchActionNotifications to
+// notify on action changes.
+type UniterAPIV15 struct {
+	UniterAPIV16
+}
+
 // UniterAPIV14 implements version (v14) of the Uniter API,
 // which adds GetPodSpec
 type UniterAPIV14 struct {
-	UniterAPI
+	UniterAPIV15
 }
 
 // UniterAPIV13 implements version (v13) of the Uniter API,
@@ -235,14 +259,58 @@ func NewUniterAPI(context facade.Context) (*UniterAPI, error) {
 	}, nil
 }
 
+// NewUniterAPIV18 creates an instance of the V18 uniter API.
+func NewUniterAPIV18(context facade.Context) (*UniterAPIV18, error) {
+	uniterAPI, err := NewUniterAPI(context)
+	if err != nil {
+		return nil, err
+	}
+	return &UniterAPIV18{
+		UniterAPI: *uniterAPI,
+	}, nil
+}
+
+// NewUniterAPIV17 creates an instance of the V17 uniter API.
+func NewUniterAPIV17(context facade.Context) (*UniterAPIV17, error) {
+	uniterAPI, err := NewUniterAPIV18(context)
+	if err != nil {
+		return nil, err
+	}
+	return &UniterAPIV17{
+		UniterAPIV18: *uniterAPI,
+	}, nil
+}
+
+// NewUniterAPIV16 creates an instance of the V16 uniter API.
+func NewUniterAPIV16(context facade.Context) (*UniterAPIV16, error) {
+	uniterAPI, err := NewUniterAPIV17(context)
+	if err != nil {
+		return nil, err
+	}
+	return &UniterAPIV16{
+		UniterAPIV17: *uniterAPI,
+	}, nil
+}
+
+// NewUniterAPIV15 creates an instance of the V15 uniter API.
+func NewUniterAPIV15(context facade.Context) (*UniterAPIV15, error) {
+	uniterAPI, err := NewUniterAPIV16(context)
+	if err != nil {
+		return nil, err
+	}
+	return &UniterAPIV15{
+		UniterAPIV16: *uniterAPI,
+	}, nil
+}
+
 // NewUniterAPIV14 creates an instance of the V14 uniter API.
 func NewUniterAPIV14(context facade.Context) (*UniterAPIV14, error) {
-	uniterAPI, err := NewUniterAPI(context)
+	uniterAPI, err := NewUniterAPIV15(context)
 	if err != nil {
 		return nil, err
 	}
 	return &UniterAPIV14{
-		UniterAPI: *uniterAPI,
+		UniterAPIV15: *uniterAPI,
 	}, nil
 }
 
@@ -1846,6 +1914,62 @@ func (u *UniterAPI) WatchRelationUnits(args params.RelationUnits) (params.Relati
 	return result, nil
 }
 
+// WatchRelationIngressNetworks isn't on the v18 API.
+func (u *UniterAPIV18) WatchRelationIngressNetworks(_, _ struct{}) {}
+
+// WatchRelationEgressNetworks isn't on the v18 API.
+func (u *UniterAPIV18) WatchRelationEgressNetworks(_, _ struct{}) {}
+
+// WatchRelationIngressNetworks returns a StringsWatcher for each of the
+// relation units, notifying of the ingress CIDRs of the relation. They
+// are only set for cross model relations.
+func (u *UniterAPI) WatchRelationIngressNetworks(args params.RelationUnits) (params.StringsWatchResults, error) {
+	return u.watchRelationNetworks(args, (*state.Relation).WatchRelationIngressNetworks)
+}
+
+// WatchRelationEgressNetworks returns a StringsWatcher for each of the
+// relation units, notifying of the egress CIDRs of the relation. They
+// are only set for cross model relations.
+func (u *UniterAPI) WatchRelationEgressNetworks(args params.RelationUnits) (params.StringsWatchResults, error) {
+	return u.watchRelationNetworks(args, (*state.Relation).WatchRelationEgressNetworks)
+}
+
+func (u *UniterAPI) watchRelationNetworks(
+	args params.RelationUnits, getWatcher func(*state.Relation) state.StringsWatcher,
+) (params.StringsWatchResults, error) {
+	result := params.StringsWatchResults{
+		Results: make([]params.StringsWatchResult, len(args.RelationUnits)),
+	}
+	canAccess, err := u.accessUnit()
+	if err != nil {
+		return params.StringsWatchResults{}, err
+	}
+	for i, arg := range args.RelationUnits {
+		unit, err := names.ParseUnitTag(arg.Unit)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+		relUnit, err := u.getRelationUnit(canAccess, arg.Relation, unit)
+		if err == nil {
+			result.Results[i], err = u.watchOneRelationNetworks(getWatcher(relUnit.Relation()))
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
+func (u *UniterAPI) watchOneRelationNetworks(w state.StringsWatcher) (params.StringsWatchResult, error) {
+	// Consume the initial event and forward it to the result.
+	if changes, ok := <-w.Changes(); ok {
+		return params.StringsWatchResult{
+			StringsWatcherId: u.resources.Register(w),
+			Changes:          changes,
+		}, nil
+	}
+	return params.StringsWatchResult{}, watcher.EnsureErr(w)
+}
+
 // SetRelationStatus updates the status of the specified relations.
 func (u *UniterAPI) SetRelationStatus(args params.RelationStatusArgs) (params.ErrorResults, error) {
 	var statusResults params.ErrorResults
@@ -3299,7 +3423,72 @@ func (u *UniterAPIV14) State(_ struct{}) {}
 
 // State returns the state persisted by the charm running in this unit
 // and the state internal to the uniter for this unit.
+// Prior to v16, state can not be read for units that are not Alive.
+func (u *UniterAPIV15) State(args params.Entities) (params.UnitStateResults, error) {
+	return u.unitState(args, true)
+}
+
+// State returns the state persisted by the charm running in this unit
+// and the state internal to the uniter for this unit.
+// State can be read for Dying units so that hooks run during
+// teardown have access to it.
 func (u *UniterAPI) State(args params.Entities) (params.UnitStateResults, error) {
+	return u.unitState(args, false)
+}
+
+// PeerUnitStates isn't on the v16 API.
+func (u *UniterAPIV16) PeerUnitStates(_ struct{}) {}
+
+// PeerUnitStates returns the state persisted by the charm for each of the
+// supplied units, which must belong to the same application as the
+// calling unit. Only the application's leader may read its peers' state,
+// so that it can coordinate them; the state cannot be written, and the
+// state internal to the peers' uniters is not returned.
+func (u *UniterAPI) PeerUnitStates(args params.Entities) (params.UnitStateResults, error) {
+	res := params.UnitStateResults{
+		Results: make([]params.UnitStateResult, len(args.Entities)),
+	}
+	callerTag, ok := u.auth.GetAuthTag().(names.UnitTag)
+	if !ok {
+		return params.UnitStateResults{}, common.ErrPerm
+	}
+	appName, err := names.UnitApplication(callerTag.Id())
+	if err != nil {
+		return params.UnitStateResults{}, errors.Trace(err)
+	}
+	token := u.leadershipChecker.LeadershipCheck(appName, callerTag.Id())
+	if err := token.Check(0, nil); leadership.IsNotLeaderError(err) {
+		return params.UnitStateResults{}, common.ErrPerm
+	} else if err != nil {
+		return params.UnitStateResults{}, errors.Trace(err)
+	}
+
+	for i, entity := range args.Entities {
+		unitTag, err := names.ParseUnitTag(entity.Tag)
+		if err != nil {
+			res.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		if peerApp, _ := names.UnitApplication(unitTag.Id()); peerApp != appName {
+			res.Results[i].Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+		unit, err := u.getUnit(unitTag)
+		if err != nil {
+			res.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		unitState, err := unit.State()
+		if err != nil {
+			res.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		res.Results[i].State, _ = unitState.State()
+	}
+	return res, nil
+}
+
+func (u *UniterAPI) unitState(args params.Entities, aliveOnly bool) (params.UnitStateResults, error) {
 	canAccess, err := u.accessUnit()
 	if err != nil {
 		return params.UnitStateResults{}, errors.Trace(err)
@@ -3323,6 +3512,10 @@ func (u *UniterAPI) State(args params.Entities) (params.UnitStateResults, error)
 			res[i].Error = common.ServerError(err)
 			continue
 		}
+		if aliveOnly && unit.Life() != state.Alive {
+			res[i].Error = common.ServerError(errors.NotFoundf("unit %s", unit.Name()))
+			continue
+		}
 		unitState, err := unit.State()
 		if err != nil {
 			res[i].Error = common.ServerError(err)
@@ -3330,12 +3523,14 @@ func (u *UniterAPI) State(args params.Entities) (params.UnitStateResults, error)
 		}
 		uState, _ := unitState.State()
 		res[i].State = uState
-		uUState, _ := unitState.UniterState()
-		res[i].UniterState = uUState
+		if uUState, found := unitState.UniterState(); found {
+			res[i].UniterState = &uUState
+		}
 		rState, _ := unitState.RelationState()
 		res[i].RelationState = rState
-		sState, _ := unitState.StorageState()
-		res[i].StorageState = sState
+		if sState, found := unitState.StorageState(); found {
+			res[i].StorageState = &sState
+		}
 	}
 
 	return params.UnitStateResults{Results: res}, nil
@@ -3394,6 +3589,57 @@ func (u *UniterAPI) SetState(args params.SetUnitStateArgs) (params.ErrorResults,
 	return params.ErrorResults{Results: res}, nil
 }
 
+// AdjustCharmCounters isn't on the v17 API.
+func (u *UniterAPIV17) AdjustCharmCounters(_ struct{}) {}
+
+// AdjustCharmCounters atomically adjusts counters shared by the units of
+// the application of each of the supplied units, returning their new
+// values. Units use them to allocate sequence numbers without a
+// read-modify-write of their charm state. An adjustment that would take
+// a counter beyond its floor or ceiling fails with a charm counter limit
+// error; one of zero returns the counter's value.
+func (u *UniterAPI) AdjustCharmCounters(args params.AdjustCharmCounterArgs) (params.CharmCounterResults, error) {
+	canAccess, err := u.accessUnit()
+	if err != nil {
+		return params.CharmCounterResults{}, errors.Trace(err)
+	}
+
+	res := make([]params.CharmCounterResult, len(args.Args))
+	for i, arg := range args.Args {
+		unitTag, err := names.ParseUnitTag(arg.Tag)
+		if err != nil {
+			res[i].Error = common.ServerError(err)
+			continue
+		}
+		if !canAccess(unitTag) {
+			res[i].Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+
+		unit, err := u.getUnit(unitTag)
+		if err != nil {
+			res[i].Error = common.ServerError(err)
+			continue
+		}
+		app, err := unit.Application()
+		if err != nil {
+			res[i].Error = common.ServerError(err)
+			continue
+		}
+		limits := state.CounterLimits{
+			Floor:   arg.Floor,
+			Ceiling: arg.Ceiling,
+		}
+		value, err := app.AdjustCharmCounter(arg.Key, arg.Delta, limits)
+		if err != nil {
+			res[i].Error = common.ServerError(err)
+			continue
+		}
+		res[i].Value = value
+	}
+	return params.CharmCounterResults{Results: res}, nil
+}
+
 // CommitHookChanges isn't on the v14 API.
 func (u *UniterAPIV14) CommitHookChanges(_ struct{}) {}
 
@@ -298,9 +298,9 @@ func (s *uniterSuite) TestState(c *gc.C) {
 			{
 				Error:         nil,
 				State:         expState,
-				UniterState:   expUniterState,
+				UniterState:   &expUniterState,
 				RelationState: expRelationState,
-				StorageState:  expStorageState,
+				StorageState:  &expStorageState,
 			},
 			{Error: apiservertesting.ErrUnauthorized},
 			{Error: apiservertesting.ErrUnauthorized},
@@ -308,6 +308,133 @@ func (s *uniterSuite) TestState(c *gc.C) {
 	})
 }
 
+func (s *uniterSuite) TestStateDyingUnit(c *gc.C) {
+	expUniterState := "testing"
+	unitState := state.NewUnitState()
+	unitState.SetUniterState(expUniterState)
+	err := s.wordpressUnit.SetState(unitState)
+	c.Assert(err, jc.ErrorIsNil)
+
+	s.makeWordpressUnitDying(c)
+
+	args := params.Entities{
+		Entities: []params.Entity{
+			{Tag: "unit-wordpress-0"},
+		},
+	}
+	result, err := s.uniter.State(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.DeepEquals, params.UnitStateResults{
+		Results: []params.UnitStateResult{
+			{UniterState: &expUniterState},
+		},
+	})
+}
+
+func (s *uniterSuite) TestStateEmptyStorageState(c *gc.C) {
+	expUniterState := "testing"
+	expStorageState := ""
+	unitState := state.NewUnitState()
+	unitState.SetUniterState(expUniterState)
+	unitState.SetStorageState(expStorageState)
+	err := s.wordpressUnit.SetState(unitState)
+	c.Assert(err, jc.ErrorIsNil)
+
+	args := params.Entities{
+		Entities: []params.Entity{
+			{Tag: "unit-wordpress-0"},
+		},
+	}
+	result, err := s.uniter.State(args)
+	c.Assert(err, jc.ErrorIsNil)
+	// The cleared storage state is distinct from storage state
+	// never written.
+	c.Assert(result, gc.DeepEquals, params.UnitStateResults{
+		Results: []params.UnitStateResult{
+			{UniterState: &expUniterState, StorageState: &expStorageState},
+		},
+	})
+}
+
+func (s *uniterSuite) TestPeerUnitStates(c *gc.C) {
+	peer := s.Factory.MakeUnit(c, &factory.UnitParams{
+		Application: s.wordpress,
+		Machine:     s.machine1,
+	})
+	unitState := state.NewUnitState()
+	unitState.SetState(map[string]string{"primary-candidate": "true"})
+	unitState.SetUniterState("internal")
+	err := peer.SetState(unitState)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.State.LeadershipClaimer().ClaimLeadership("wordpress", "wordpress/0", time.Minute)
+	c.Assert(err, jc.ErrorIsNil)
+
+	args := params.Entities{
+		Entities: []params.Entity{
+			{Tag: "not-a-unit-tag"},
+			{Tag: peer.Tag().String()},
+			{Tag: "unit-wordpress-0"},
+			{Tag: "unit-mysql-0"}, // not a peer
+			{Tag: "unit-wordpress-42"},
+		},
+	}
+	result, err := s.uniter.PeerUnitStates(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.DeepEquals, params.UnitStateResults{
+		Results: []params.UnitStateResult{
+			{Error: &params.Error{Message: `"not-a-unit-tag" is not a valid tag`}},
+			// Only the charm's state is returned.
+			{State: map[string]string{"primary-candidate": "true"}},
+			{},
+			{Error: apiservertesting.ErrUnauthorized},
+			{Error: &params.Error{Message: `unit "wordpress/42" not found`, Code: params.CodeNotFound}},
+		},
+	})
+}
+
+func (s *uniterSuite) TestPeerUnitStatesNotLeader(c *gc.C) {
+	err := s.State.LeadershipClaimer().ClaimLeadership("wordpress", "wordpress/1", time.Minute)
+	c.Assert(err, jc.ErrorIsNil)
+
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: "unit-wordpress-1"}},
+	}
+	_, err = s.uniter.PeerUnitStates(args)
+	c.Assert(err, gc.Equals, common.ErrPerm)
+}
+
+func (s *uniterSuite) TestAdjustCharmCounters(c *gc.C) {
+	ceiling := int64(1)
+	args := params.AdjustCharmCounterArgs{
+		Args: []params.AdjustCharmCounterArg{
+			{Tag: "not-a-unit-tag", Key: "next-id", Delta: 1},
+			{Tag: "unit-wordpress-0", Key: "next-id", Delta: 1, Ceiling: &ceiling},
+			{Tag: "unit-wordpress-0", Key: "next-id", Delta: 1, Ceiling: &ceiling},
+			{Tag: "unit-wordpress-0", Key: "next-id", Delta: 0},
+			{Tag: "unit-mysql-0", Key: "next-id", Delta: 1}, // not accessible by current user
+		},
+	}
+	result, err := s.uniter.AdjustCharmCounters(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.DeepEquals, params.CharmCounterResults{
+		Results: []params.CharmCounterResult{
+			{Error: &params.Error{Message: `"not-a-unit-tag" is not a valid tag`}},
+			{Value: 1},
+			{Error: &params.Error{
+				Message: `cannot adjust counter "next-id" for application "wordpress": charm counter limit reached`,
+				Code:    params.CodeCharmCounterLimit,
+			}},
+			{Value: 1},
+			{Error: apiservertesting.ErrUnauthorized},
+		},
+	})
+
+	value, err := s.wordpress.CharmCounter("next-id")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, int64(1))
+}
+
 func (s *uniterSuite) TestSetStateUniterState(c *gc.C) {
 	expUniterState := "testing"
 	args := params.SetUnitStateArgs{
@@ -340,6 +467,7 @@ func (s *uniterSuite) TestSetStateUniterState(c *gc.C) {
 	c.Assert(found, jc.IsFalse)
 	c.Assert(uState, gc.IsNil)
 	sState, found := wpUnitState.StorageState()
+	c.Assert(found, jc.IsFalse)
 	c.Assert(sState, gc.Equals, "")
 	rState, found := wpUnitState.RelationState()
 	c.Assert(found, jc.IsFalse)
@@ -370,14 +498,40 @@ func (s *uniterSuite) TestSetStateState(c *gc.C) {
 
 	// Ensure other values aren't set
 	sState, found := wpUnitState.StorageState()
+	c.Assert(found, jc.IsFalse)
 	c.Assert(sState, gc.Equals, "")
 	uState, found := wpUnitState.UniterState()
+	c.Assert(found, jc.IsFalse)
 	c.Assert(uState, gc.Equals, "")
 	rState, found := wpUnitState.RelationState()
 	c.Assert(found, jc.IsFalse)
 	c.Assert(rState, gc.IsNil)
 }
 
+func (s *uniterSuite) TestSetStateInvalidState(c *gc.C) {
+	badState := map[string]string{"foo": "bar", "": "empty"}
+	args := params.SetUnitStateArgs{
+		Args: []params.SetUnitStateArg{
+			{Tag: "unit-wordpress-0", State: &badState},
+		},
+	}
+
+	result, err := s.uniter.SetState(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results, gc.HasLen, 1)
+	resultErr := result.Results[0].Error
+	c.Assert(resultErr, gc.NotNil)
+	c.Assert(resultErr, jc.Satisfies, params.IsCodeInvalidCharmState)
+	c.Assert(resultErr.Message, gc.Equals, `cannot persist state for unit "wordpress/0": invalid charm state: key "" is empty`)
+
+	var info params.InvalidUnitStateErrorInfo
+	err = resultErr.UnmarshalInfo(&info)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info, jc.DeepEquals, params.InvalidUnitStateErrorInfo{
+		Keys: []params.InvalidUnitStateKey{{Key: "", Size: 5, Reason: "is empty"}},
+	})
+}
+
 func (s *uniterSuite) TestSetAgentStatus(c *gc.C) {
 	now := time.Now()
 	sInfo := status.StatusInfo{
@@ -2978,9 +3132,10 @@ func (s *uniterSuite) TestWatchRelationUnits(c *gc.C) {
 	c.Assert(mysqlChanges, gc.NotNil)
 	changed, ok := mysqlChanges.Changed["mysql/0"]
 	c.Assert(ok, jc.IsTrue)
+	c.Assert(changed.Observed, gc.NotNil)
 	expectChanges := params.RelationUnitsChange{
 		Changed: map[string]params.UnitSettings{
-			"mysql/0": {changed.Version},
+			"mysql/0": {Version: changed.Version, Observed: changed.Observed},
 		},
 		AppChanged: map[string]int64{
 			"mysql": 0,
@@ -3076,6 +3231,48 @@ func (s *uniterSuite) TestAPIAddresses(c *gc.C) {
 	})
 }
 
+func (s *uniterSuite) TestWatchRelationIngressNetworks(c *gc.C) {
+	rel := s.addRelation(c, "wordpress", "mysql")
+	_, err := state.NewRelationIngressNetworks(s.State).Save(rel.Tag().Id(), false, []string{"10.0.0.0/24"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.resources.Count(), gc.Equals, 0)
+
+	args := params.RelationUnits{RelationUnits: []params.RelationUnit{
+		{Relation: "relation-42", Unit: "unit-wordpress-0"},
+		{Relation: rel.Tag().String(), Unit: "unit-wordpress-0"},
+		{Relation: rel.Tag().String(), Unit: "unit-mysql-0"},
+		{Relation: rel.Tag().String(), Unit: "application-wordpress"},
+	}}
+	result, err := s.uniter.WatchRelationIngressNetworks(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.DeepEquals, params.StringsWatchResults{
+		Results: []params.StringsWatchResult{
+			{Error: apiservertesting.ErrUnauthorized},
+			{
+				StringsWatcherId: "1",
+				Changes:          []string{"10.0.0.0/24"},
+			},
+			{Error: apiservertesting.ErrUnauthorized},
+			{Error: apiservertesting.ErrUnauthorized},
+		},
+	})
+
+	// Verify the resource was registered and stop when done
+	c.Assert(s.resources.Count(), gc.Equals, 1)
+	resource := s.resources.Get("1")
+	defer statetesting.AssertStop(c, resource)
+
+	// Check that the Watch has consumed the initial event ("returned" in
+	// the Watch call)
+	wc := statetesting.NewStringsWatcherC(c, s.State, resource.(state.StringsWatcher))
+	wc.AssertNoChange()
+
+	_, err = state.NewRelationIngressNetworks(s.State).Save(rel.Tag().Id(), false, []string{"10.0.1.0/24"})
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertChange("10.0.1.0/24")
+	wc.AssertNoChange()
+}
+
 func (s *uniterSuite) TestWatchUnitAddressesHash(c *gc.C) {
 	c.Assert(s.resources.Count(), gc.Equals, 0)
 
@@ -5348,6 +5545,56 @@ func (t *fakeToken) Check(int, interface{}) error {
 	return t.err
 }
 
+// makeWordpressUnitDying gives the wordpress unit a non-allocating agent
+// status, so that it is not removed outright, then destroys it.
+func (s *uniterSuiteBase) makeWordpressUnitDying(c *gc.C) {
+	now := time.Now()
+	err := s.wordpressUnit.SetAgentStatus(status.StatusInfo{
+		Status: status.Idle,
+		Since:  &now,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.wordpressUnit.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.wordpressUnit.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.wordpressUnit.Life(), gc.Equals, state.Dying)
+}
+
+type uniterV15Suite struct {
+	uniterSuiteBase
+	uniterV15 *uniter.UniterAPIV15
+}
+
+var _ = gc.Suite(&uniterV15Suite{})
+
+func (s *uniterV15Suite) SetUpTest(c *gc.C) {
+	s.uniterSuiteBase.SetUpTest(c)
+
+	uniterV15, err := uniter.NewUniterAPIV15(s.facadeContext())
+	c.Assert(err, jc.ErrorIsNil)
+	s.uniterV15 = uniterV15
+}
+
+func (s *uniterV15Suite) TestStateDyingUnitNotFound(c *gc.C) {
+	unitState := state.NewUnitState()
+	unitState.SetUniterState("testing")
+	err := s.wordpressUnit.SetState(unitState)
+	c.Assert(err, jc.ErrorIsNil)
+
+	s.makeWordpressUnitDying(c)
+
+	args := params.Entities{
+		Entities: []params.Entity{
+			{Tag: "unit-wordpress-0"},
+		},
+	}
+	result, err := s.uniterV15.State(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results, gc.HasLen, 1)
+	c.Assert(result.Results[0].Error, jc.Satisfies, params.IsCodeNotFound)
+}
+
 type uniterV14Suite struct {
 	uniterSuiteBase
 	uniterV14 *uniter.UniterAPIV14
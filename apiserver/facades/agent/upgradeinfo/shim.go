@@ -0,0 +1,44 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgradeinfo
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/apiserver/facade"
+	"github.com/juju/juju/state"
+)
+
+// NewFacade wraps NewAPI to express the supplied *state.State as a Backend.
+func NewFacade(st *state.State, resources facade.Resources, auth facade.Authorizer) (*API, error) {
+	api, err := NewAPI(&backend{st}, resources, auth)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return api, nil
+}
+
+// backend implements Backend by wrapping a *state.State.
+type backend struct {
+	st *state.State
+}
+
+// ModelUUID is part of the Backend interface.
+func (shim *backend) ModelUUID() string {
+	return shim.st.ModelUUID()
+}
+
+// CurrentUpgradeInfo is part of the Backend interface.
+func (shim *backend) CurrentUpgradeInfo() (UpgradeInfo, error) {
+	info, err := shim.st.CurrentUpgradeInfo()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return info, nil
+}
+
+// WatchUpgradeInfo is part of the Backend interface.
+func (shim *backend) WatchUpgradeInfo() state.NotifyWatcher {
+	return shim.st.WatchUpgradeInfo()
+}
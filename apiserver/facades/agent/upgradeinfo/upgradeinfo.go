@@ -0,0 +1,111 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgradeinfo
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/version"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/facade"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/watcher"
+)
+
+// Backend exposes the controller upgrade information to the facade.
+type Backend interface {
+	ModelUUID() string
+	CurrentUpgradeInfo() (UpgradeInfo, error)
+	WatchUpgradeInfo() state.NotifyWatcher
+}
+
+// UpgradeInfo describes a controller upgrade in progress.
+type UpgradeInfo interface {
+	PreviousVersion() version.Number
+	TargetVersion() version.Number
+	Status() state.UpgradeStatus
+	Started() time.Time
+	DeferredMigrations() []state.UpgradeDeferredMigration
+}
+
+// API lets machine and unit agents observe controller upgrades, so
+// that they can report them and hold off non-essential API calls
+// until the controller database upgrade has completed.
+type API struct {
+	backend   Backend
+	resources facade.Resources
+
+	// modelUUID is the model of the agent, whose deferred migration
+	// it is told of. Controller agents are told of all of them.
+	modelUUID  string
+	controller bool
+}
+
+// NewAPI returns a new UpgradeInfo API. If auth doesn't identify the
+// client as a machine agent or a unit agent, it will return common.ErrPerm.
+func NewAPI(backend Backend, resources facade.Resources, auth facade.Authorizer) (*API, error) {
+	if !auth.AuthMachineAgent() && !auth.AuthUnitAgent() && !auth.AuthApplicationAgent() {
+		return nil, common.ErrPerm
+	}
+	return &API{
+		backend:    backend,
+		resources:  resources,
+		modelUUID:  backend.ModelUUID(),
+		controller: auth.AuthController(),
+	}, nil
+}
+
+// WatchUpgradeInfo returns a NotifyWatcher that fires whenever a
+// controller upgrade is started, changes status, or is completed.
+func (api *API) WatchUpgradeInfo() (params.NotifyWatchResult, error) {
+	w := api.backend.WatchUpgradeInfo()
+	if _, ok := <-w.Changes(); !ok {
+		return params.NotifyWatchResult{}, watcher.EnsureErr(w)
+	}
+	return params.NotifyWatchResult{
+		NotifyWatcherId: api.resources.Register(w),
+	}, nil
+}
+
+// UpgradeInfo returns details of the controller upgrade in progress,
+// including the model migrations deferred until its database upgrade
+// has completed. Agents other than those of controller machines are
+// only told of a deferred migration of their own model. If there is no
+// upgrade in progress, InProgress is false.
+func (api *API) UpgradeInfo() (params.UpgradeInfoResult, error) {
+	info, err := api.backend.CurrentUpgradeInfo()
+	if errors.IsNotFound(err) {
+		return params.UpgradeInfoResult{}, nil
+	} else if err != nil {
+		return params.UpgradeInfoResult{}, errors.Trace(err)
+	}
+	status := info.Status()
+	if status == state.UpgradeComplete || status == state.UpgradeAborted {
+		return params.UpgradeInfoResult{}, nil
+	}
+	started := info.Started()
+	var deferred []params.DeferredMigration
+	for _, mig := range info.DeferredMigrations() {
+		if !api.controller && mig.ModelUUID != api.modelUUID {
+			continue
+		}
+		deferred = append(deferred, params.DeferredMigration{
+			ModelTag:  names.NewModelTag(mig.ModelUUID).String(),
+			Direction: string(mig.Direction),
+			Attempted: mig.Attempted,
+		})
+	}
+	return params.UpgradeInfoResult{
+		InProgress:         true,
+		PreviousVersion:    info.PreviousVersion().String(),
+		TargetVersion:      info.TargetVersion().String(),
+		Status:             string(status),
+		Started:            &started,
+		DeferredMigrations: deferred,
+	}, nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgradeinfo_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/version"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/facades/agent/upgradeinfo"
+	"github.com/juju/juju/apiserver/params"
+	apiservertesting "github.com/juju/juju/apiserver/testing"
+	"github.com/juju/juju/state"
+)
+
+type upgradeInfoSuite struct {
+	testing.IsolationSuite
+
+	backend    *mockBackend
+	resources  *common.Resources
+	authorizer apiservertesting.FakeAuthorizer
+}
+
+var _ = gc.Suite(&upgradeInfoSuite{})
+
+func (s *upgradeInfoSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.backend = &mockBackend{modelUUID: "deadbeef-0bad-400d-8000-4b1d0d06f00d"}
+	s.resources = common.NewResources()
+	s.AddCleanup(func(*gc.C) { s.resources.StopAll() })
+	s.authorizer = apiservertesting.FakeAuthorizer{
+		Tag: names.NewMachineTag("1"),
+	}
+}
+
+func (s *upgradeInfoSuite) newAPI(c *gc.C) *upgradeinfo.API {
+	api, err := upgradeinfo.NewAPI(s.backend, s.resources, s.authorizer)
+	c.Assert(err, jc.ErrorIsNil)
+	return api
+}
+
+func (s *upgradeInfoSuite) TestAcceptsUnitAgent(c *gc.C) {
+	s.authorizer.Tag = names.NewUnitTag("mysql/0")
+	_, err := upgradeinfo.NewAPI(s.backend, s.resources, s.authorizer)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *upgradeInfoSuite) TestRejectsClient(c *gc.C) {
+	s.authorizer.Tag = names.NewUserTag("bob")
+	_, err := upgradeinfo.NewAPI(s.backend, s.resources, s.authorizer)
+	c.Assert(err, gc.Equals, common.ErrPerm)
+}
+
+func (s *upgradeInfoSuite) TestUpgradeInfoNotUpgrading(c *gc.C) {
+	s.backend.SetErrors(errors.NotFoundf("current upgrade info"))
+
+	result, err := s.newAPI(c).UpgradeInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, params.UpgradeInfoResult{})
+	s.backend.CheckCallNames(c, "CurrentUpgradeInfo")
+}
+
+func (s *upgradeInfoSuite) TestUpgradeInfoInProgress(c *gc.C) {
+	started := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	attempted := started.Add(time.Minute)
+	s.backend.info = &mockUpgradeInfo{
+		previous: version.MustParse("2.7.5"),
+		target:   version.MustParse("2.8.0"),
+		status:   state.UpgradePending,
+		started:  started,
+		deferred: []state.UpgradeDeferredMigration{{
+			ModelUUID: "deadbeef-0bad-400d-8000-4b1d0d06f00d",
+			Direction: state.MigrationIncoming,
+			Attempted: attempted,
+		}},
+	}
+
+	result, err := s.newAPI(c).UpgradeInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, params.UpgradeInfoResult{
+		InProgress:      true,
+		PreviousVersion: "2.7.5",
+		TargetVersion:   "2.8.0",
+		Status:          "pending",
+		Started:         &started,
+		DeferredMigrations: []params.DeferredMigration{{
+			ModelTag:  "model-deadbeef-0bad-400d-8000-4b1d0d06f00d",
+			Direction: "incoming",
+			Attempted: attempted,
+		}},
+	})
+}
+
+func (s *upgradeInfoSuite) TestUpgradeInfoOtherModelsDeferred(c *gc.C) {
+	s.backend.info = &mockUpgradeInfo{
+		status: state.UpgradePending,
+		deferred: []state.UpgradeDeferredMigration{{
+			ModelUUID: "c0ffee00-0bad-400d-8000-4b1d0d06f00d",
+			Direction: state.MigrationOutgoing,
+		}, {
+			ModelUUID: "deadbeef-0bad-400d-8000-4b1d0d06f00d",
+			Direction: state.MigrationIncoming,
+		}},
+	}
+
+	// Agents are only told of their own model's deferred migration.
+	result, err := s.newAPI(c).UpgradeInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.DeferredMigrations, jc.DeepEquals, []params.DeferredMigration{{
+		ModelTag:  "model-deadbeef-0bad-400d-8000-4b1d0d06f00d",
+		Direction: "incoming",
+	}})
+
+	// Controller agents are told of all of them.
+	s.authorizer.Controller = true
+	result, err = s.newAPI(c).UpgradeInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.DeferredMigrations, gc.HasLen, 2)
+}
+
+func (s *upgradeInfoSuite) TestUpgradeInfoComplete(c *gc.C) {
+	s.backend.info = &mockUpgradeInfo{status: state.UpgradeComplete}
+
+	result, err := s.newAPI(c).UpgradeInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.InProgress, jc.IsFalse)
+}
+
+func (s *upgradeInfoSuite) TestUpgradeInfoError(c *gc.C) {
+	s.backend.SetErrors(errors.New("boom"))
+
+	_, err := s.newAPI(c).UpgradeInfo()
+	c.Assert(err, gc.ErrorMatches, "boom")
+}
+
+func (s *upgradeInfoSuite) TestWatchUpgradeInfo(c *gc.C) {
+	result, err := s.newAPI(c).WatchUpgradeInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.NotifyWatcherId, gc.Equals, "1")
+	c.Assert(s.resources.Count(), gc.Equals, 1)
+	s.backend.CheckCallNames(c, "WatchUpgradeInfo")
+}
+
+type mockBackend struct {
+	testing.Stub
+	modelUUID string
+	info      *mockUpgradeInfo
+}
+
+func (b *mockBackend) ModelUUID() string {
+	return b.modelUUID
+}
+
+func (b *mockBackend) CurrentUpgradeInfo() (upgradeinfo.UpgradeInfo, error) {
+	b.MethodCall(b, "CurrentUpgradeInfo")
+	if err := b.NextErr(); err != nil {
+		return nil, err
+	}
+	return b.info, nil
+}
+
+func (b *mockBackend) WatchUpgradeInfo() state.NotifyWatcher {
+	b.MethodCall(b, "WatchUpgradeInfo")
+	return apiservertesting.NewFakeNotifyWatcher()
+}
+
+type mockUpgradeInfo struct {
+	previous version.Number
+	target   version.Number
+	status   state.UpgradeStatus
+	started  time.Time
+	deferred []state.UpgradeDeferredMigration
+}
+
+func (i *mockUpgradeInfo) PreviousVersion() version.Number { return i.previous }
+func (i *mockUpgradeInfo) TargetVersion() version.Number   { return i.target }
+func (i *mockUpgradeInfo) Status() state.UpgradeStatus     { return i.status }
+func (i *mockUpgradeInfo) Started() time.Time              { return i.started }
+func (i *mockUpgradeInfo) DeferredMigrations() []state.UpgradeDeferredMigration {
+	return i.deferred
+}
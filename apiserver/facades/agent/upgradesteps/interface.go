@@ -4,6 +4,8 @@
 package upgradesteps
 
 import (
+	"github.com/juju/version"
+
 	"github.com/juju/juju/core/instance"
 	"github.com/juju/juju/core/status"
 	"github.com/juju/juju/state"
@@ -11,6 +13,17 @@ import (
 
 type UpgradeStepsState interface {
 	state.EntityFinder
+
+	// CurrentUpgradeInfo returns the upgrade in progress,
+	// or a NotFound error if there is none.
+	CurrentUpgradeInfo() (UpgradeInfo, error)
+}
+
+// UpgradeInfo represents point of use methods from the state upgrade info.
+type UpgradeInfo interface {
+	PreviousVersion() version.Number
+	TargetVersion() version.Number
+	StepCollections() []state.UpgradeStepCollections
 }
 
 // Machine represents point of use methods from the state machine object
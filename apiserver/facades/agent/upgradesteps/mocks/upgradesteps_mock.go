@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/juju/juju/apiserver/facades/agent/upgradesteps (interfaces: UpgradeStepsState,Machine)
+// Source: github.com/juju/juju/apiserver/facades/agent/upgradesteps (interfaces: UpgradeStepsState,Machine,UpgradeInfo)
 
 // Package mocks is a generated GoMock package.
 package mocks
@@ -8,9 +8,11 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	upgradesteps "github.com/juju/juju/apiserver/facades/agent/upgradesteps"
 	instance "github.com/juju/juju/core/instance"
 	status "github.com/juju/juju/core/status"
 	state "github.com/juju/juju/state"
+	version "github.com/juju/version"
 	names_v3 "gopkg.in/juju/names.v3"
 )
 
@@ -37,6 +39,19 @@ func (m *MockUpgradeStepsState) EXPECT() *MockUpgradeStepsStateMockRecorder {
 	return m.recorder
 }
 
+// CurrentUpgradeInfo mocks base method
+func (m *MockUpgradeStepsState) CurrentUpgradeInfo() (upgradesteps.UpgradeInfo, error) {
+	ret := m.ctrl.Call(m, "CurrentUpgradeInfo")
+	ret0, _ := ret[0].(upgradesteps.UpgradeInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CurrentUpgradeInfo indicates an expected call of CurrentUpgradeInfo
+func (mr *MockUpgradeStepsStateMockRecorder) CurrentUpgradeInfo() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CurrentUpgradeInfo", reflect.TypeOf((*MockUpgradeStepsState)(nil).CurrentUpgradeInfo))
+}
+
 // FindEntity mocks base method
 func (m *MockUpgradeStepsState) FindEntity(arg0 names_v3.Tag) (state.Entity, error) {
 	ret := m.ctrl.Call(m, "FindEntity", arg0)
@@ -109,3 +124,62 @@ func (m *MockMachine) SetModificationStatus(arg0 status.StatusInfo) error {
 func (mr *MockMachineMockRecorder) SetModificationStatus(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetModificationStatus", reflect.TypeOf((*MockMachine)(nil).SetModificationStatus), arg0)
 }
+
+// MockUpgradeInfo is a mock of UpgradeInfo interface
+type MockUpgradeInfo struct {
+	ctrl     *gomock.Controller
+	recorder *MockUpgradeInfoMockRecorder
+}
+
+// MockUpgradeInfoMockRecorder is the mock recorder for MockUpgradeInfo
+type MockUpgradeInfoMockRecorder struct {
+	mock *MockUpgradeInfo
+}
+
+// NewMockUpgradeInfo creates a new mock instance
+func NewMockUpgradeInfo(ctrl *gomock.Controller) *MockUpgradeInfo {
+	mock := &MockUpgradeInfo{ctrl: ctrl}
+	mock.recorder = &MockUpgradeInfoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockUpgradeInfo) EXPECT() *MockUpgradeInfoMockRecorder {
+	return m.recorder
+}
+
+// PreviousVersion mocks base method
+func (m *MockUpgradeInfo) PreviousVersion() version.Number {
+	ret := m.ctrl.Call(m, "PreviousVersion")
+	ret0, _ := ret[0].(version.Number)
+	return ret0
+}
+
+// PreviousVersion indicates an expected call of PreviousVersion
+func (mr *MockUpgradeInfoMockRecorder) PreviousVersion() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PreviousVersion", reflect.TypeOf((*MockUpgradeInfo)(nil).PreviousVersion))
+}
+
+// StepCollections mocks base method
+func (m *MockUpgradeInfo) StepCollections() []state.UpgradeStepCollections {
+	ret := m.ctrl.Call(m, "StepCollections")
+	ret0, _ := ret[0].([]state.UpgradeStepCollections)
+	return ret0
+}
+
+// StepCollections indicates an expected call of StepCollections
+func (mr *MockUpgradeInfoMockRecorder) StepCollections() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StepCollections", reflect.TypeOf((*MockUpgradeInfo)(nil).StepCollections))
+}
+
+// TargetVersion mocks base method
+func (m *MockUpgradeInfo) TargetVersion() version.Number {
+	ret := m.ctrl.Call(m, "TargetVersion")
+	ret0, _ := ret[0].(version.Number)
+	return ret0
+}
+
+// TargetVersion indicates an expected call of TargetVersion
+func (mr *MockUpgradeInfoMockRecorder) TargetVersion() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TargetVersion", reflect.TypeOf((*MockUpgradeInfo)(nil).TargetVersion))
+}
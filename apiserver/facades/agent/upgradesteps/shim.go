@@ -3,8 +3,21 @@
 
 package upgradesteps
 
-import "github.com/juju/juju/state"
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/state"
+)
 
 type upgradeStepsStateShim struct {
 	*state.State
 }
+
+// CurrentUpgradeInfo is part of the UpgradeStepsState interface.
+func (s *upgradeStepsStateShim) CurrentUpgradeInfo() (UpgradeInfo, error) {
+	info, err := s.State.CurrentUpgradeInfo()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return info, nil
+}
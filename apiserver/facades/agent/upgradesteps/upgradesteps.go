@@ -15,11 +15,16 @@ import (
 	"github.com/juju/juju/core/status"
 )
 
-//go:generate mockgen -package mocks -destination mocks/upgradesteps_mock.go github.com/juju/juju/apiserver/facades/agent/upgradesteps UpgradeStepsState,Machine
+//go:generate mockgen -package mocks -destination mocks/upgradesteps_mock.go github.com/juju/juju/apiserver/facades/agent/upgradesteps UpgradeStepsState,Machine,UpgradeInfo
 //go:generate mockgen -package mocks -destination mocks/state_mock.go github.com/juju/juju/state EntityFinder,Entity
 
 var logger = loggo.GetLogger("juju.apiserver.upgradesteps")
 
+type UpgradeStepsV2 interface {
+	UpgradeStepsV1
+	UpgradeStepCollections() (params.UpgradeStepCollectionsResult, error)
+}
+
 type UpgradeStepsV1 interface {
 	ResetKVMMachineModificationStatusIdle(params.Entity) (params.ErrorResult, error)
 }
@@ -31,17 +36,31 @@ type UpgradeStepsAPI struct {
 	getAuthFunc common.GetAuthFunc
 }
 
+type UpgradeStepsAPIV1 struct {
+	*UpgradeStepsAPI
+}
+
 // using apiserver/facades/client/cloud as an example.
 var (
-	_ UpgradeStepsV1 = (*UpgradeStepsAPI)(nil)
+	_ UpgradeStepsV2 = (*UpgradeStepsAPI)(nil)
+	_ UpgradeStepsV1 = (*UpgradeStepsAPIV1)(nil)
 )
 
-// NewFacadeV1 is used for API registration.
-func NewFacadeV1(ctx facade.Context) (*UpgradeStepsAPI, error) {
+// NewFacadeV2 is used for API registration.
+func NewFacadeV2(ctx facade.Context) (*UpgradeStepsAPI, error) {
 	st := &upgradeStepsStateShim{State: ctx.State()}
 	return NewUpgradeStepsAPI(st, ctx.Resources(), ctx.Auth())
 }
 
+// NewFacadeV1 is used for API registration.
+func NewFacadeV1(ctx facade.Context) (*UpgradeStepsAPIV1, error) {
+	v2, err := NewFacadeV2(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UpgradeStepsAPIV1{v2}, nil
+}
+
 func NewUpgradeStepsAPI(st UpgradeStepsState,
 	resources facade.Resources,
 	authorizer facade.Authorizer,
@@ -97,6 +116,33 @@ func (api *UpgradeStepsAPI) ResetKVMMachineModificationStatusIdle(arg params.Ent
 	return result, nil
 }
 
+// UpgradeStepCollections returns the database collections read and
+// written by each of the upgrade steps run for the upgrade in progress.
+// This allows the impact of a failed step to be assessed.
+func (api *UpgradeStepsAPI) UpgradeStepCollections() (params.UpgradeStepCollectionsResult, error) {
+	var result params.UpgradeStepCollectionsResult
+	info, err := api.st.CurrentUpgradeInfo()
+	if err != nil {
+		result.Error = common.ServerError(err)
+		return result, nil
+	}
+	result.PreviousVersion = info.PreviousVersion().String()
+	result.TargetVersion = info.TargetVersion().String()
+	steps := info.StepCollections()
+	result.Steps = make([]params.UpgradeStepCollections, len(steps))
+	for i, step := range steps {
+		result.Steps[i] = params.UpgradeStepCollections{
+			Description: step.Description,
+			Read:        step.Read,
+			Write:       step.Write,
+		}
+	}
+	return result, nil
+}
+
+// UpgradeStepCollections isn't on the v1 API.
+func (api *UpgradeStepsAPIV1) UpgradeStepCollections(_, _ struct{}) {}
+
 func (api *UpgradeStepsAPI) getMachine(canAccess common.AuthFunc, tag names.MachineTag) (Machine, error) {
 	if !canAccess(tag) {
 		return nil, common.ErrPerm
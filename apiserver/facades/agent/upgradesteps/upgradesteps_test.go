@@ -8,6 +8,7 @@ import (
 	"github.com/juju/errors"
 	jujutesting "github.com/juju/juju/testing"
 	jc "github.com/juju/testing/checkers"
+	"github.com/juju/version"
 	gc "gopkg.in/check.v1"
 	"gopkg.in/juju/names.v3"
 
@@ -26,6 +27,7 @@ type upgradeStepsSuite struct {
 	tag names.Tag
 	arg params.Entity
 
+	ctrl       *gomock.Controller
 	api        *upgradesteps.UpgradeStepsAPI
 	authorizer *facademocks.MockAuthorizer
 	entity     *mocks.MockEntity
@@ -106,8 +108,51 @@ func (s *upgradeStepsSuite) TestResetKVMMachineModificationStatusIdleLXD(c *gc.C
 	c.Assert(err, jc.ErrorIsNil)
 }
 
+func (s *upgradeStepsSuite) TestUpgradeStepCollections(c *gc.C) {
+	defer s.setup(c).Finish()
+
+	s.expectAuthCalls()
+	info := mocks.NewMockUpgradeInfo(s.ctrl)
+	info.EXPECT().PreviousVersion().Return(version.MustParse("2.7.6"))
+	info.EXPECT().TargetVersion().Return(version.MustParse("2.8.0"))
+	info.EXPECT().StepCollections().Return([]state.UpgradeStepCollections{{
+		Description: "add machine ID to subordinate units",
+		Read:        []string{"units"},
+		Write:       []string{"units"},
+	}})
+	s.state.EXPECT().CurrentUpgradeInfo().Return(info, nil)
+
+	s.setupFacadeAPI(c)
+
+	result, err := s.api.UpgradeStepCollections()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.DeepEquals, params.UpgradeStepCollectionsResult{
+		PreviousVersion: "2.7.6",
+		TargetVersion:   "2.8.0",
+		Steps: []params.UpgradeStepCollections{{
+			Description: "add machine ID to subordinate units",
+			Read:        []string{"units"},
+			Write:       []string{"units"},
+		}},
+	})
+}
+
+func (s *upgradeStepsSuite) TestUpgradeStepCollectionsNoUpgrade(c *gc.C) {
+	defer s.setup(c).Finish()
+
+	s.expectAuthCalls()
+	s.state.EXPECT().CurrentUpgradeInfo().Return(nil, errors.NotFoundf("current upgrade info"))
+
+	s.setupFacadeAPI(c)
+
+	result, err := s.api.UpgradeStepCollections()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Error, jc.Satisfies, params.IsCodeNotFound)
+}
+
 func (s *upgradeStepsSuite) setup(c *gc.C) *gomock.Controller {
 	ctrl := gomock.NewController(c)
+	s.ctrl = ctrl
 
 	s.authorizer = facademocks.NewMockAuthorizer(ctrl)
 	s.entity = mocks.NewMockEntity(ctrl)
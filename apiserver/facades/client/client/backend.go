@@ -30,6 +30,7 @@ type Backend interface {
 	network.SpaceLookup
 
 	AbortCurrentUpgrade() error
+	ApproveCurrentUpgrade() error
 	AddControllerUser(state.UserAccessSpec) (permission.UserAccess, error)
 	AddMachineInsideMachine(state.MachineTemplate, string, instance.ContainerType) (*state.Machine, error)
 	AddMachineInsideNewMachine(template, parentTemplate state.MachineTemplate, containerType instance.ContainerType) (*state.Machine, error)
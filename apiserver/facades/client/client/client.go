@@ -78,9 +78,14 @@ type Client struct {
 	openCSRepo  application.OpenCSRepoFunc
 }
 
+// ClientV2 serves the (v2) client-specific API methods.
+type ClientV2 struct {
+	*Client
+}
+
 // ClientV1 serves the (v1) client-specific API methods.
 type ClientV1 struct {
-	*Client
+	*ClientV2
 }
 
 func (c *Client) checkCanRead() error {
@@ -131,14 +136,23 @@ func (c *Client) checkIsAdmin() error {
 	return nil
 }
 
-// NewFacade creates a version 1 Client facade to handle API requests.
+// NewFacade creates a version 3 Client facade to handle API requests.
 func NewFacade(ctx facade.Context) (*Client, error) {
 	return newFacade(ctx)
 }
 
+// NewFacadeV2 creates a version 2 Client facade to handle API requests.
+func NewFacadeV2(ctx facade.Context) (*ClientV2, error) {
+	client, err := newFacade(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &ClientV2{client}, nil
+}
+
 // NewFacadeV1 creates a version 1 Client facade to handle API requests.
 func NewFacadeV1(ctx facade.Context) (*ClientV1, error) {
-	client, err := newFacade(ctx)
+	client, err := NewFacadeV2(ctx)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -767,6 +781,27 @@ func (c *Client) AbortCurrentUpgrade() error {
 	return c.api.stateAccessor.AbortCurrentUpgrade()
 }
 
+// ApproveCurrentUpgrade approves the current upgrade to proceed, when
+// the controller config requires database upgrades to be approved by
+// an operator. Only controller superusers may approve upgrades.
+func (c *Client) ApproveCurrentUpgrade() error {
+	isAdmin, err := c.api.auth.HasPermission(permission.SuperuserAccess, c.api.stateAccessor.ControllerTag())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !isAdmin {
+		return common.ErrPerm
+	}
+
+	if err := c.check.ChangeAllowed(); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(c.api.stateAccessor.ApproveCurrentUpgrade())
+}
+
+// ApproveCurrentUpgrade isn't on the v2 API.
+func (c *ClientV2) ApproveCurrentUpgrade(_, _ struct{}) {}
+
 // FindTools returns a List containing all tools matching the given parameters.
 func (c *Client) FindTools(args params.FindToolsParams) (params.FindToolsResult, error) {
 	if err := c.checkCanWrite(); err != nil {
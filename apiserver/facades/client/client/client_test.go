@@ -551,6 +551,33 @@ func (s *serverSuite) TestAbortCurrentUpgrade(c *gc.C) {
 	c.Assert(isUpgrading, jc.IsFalse)
 }
 
+func (s *serverSuite) TestApproveCurrentUpgrade(c *gc.C) {
+	// Create a provisioned controller.
+	machine, err := s.State.AddMachine("series", state.JobManageModel)
+	c.Assert(err, jc.ErrorIsNil)
+	err = machine.SetProvisioned(instance.Id("i-blah"), "", "fake-nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Start an upgrade that awaits approval.
+	info, err := s.State.EnsureUpgradeInfo(
+		machine.Id(),
+		version.MustParse("1.2.3"),
+		version.MustParse("9.8.7"),
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	err = info.SetAwaitingApproval()
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Approve it.
+	err = s.client.ApproveCurrentUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = info.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.AwaitingApproval(), jc.IsFalse)
+	c.Assert(info.Approved(), jc.IsTrue)
+}
+
 func (s *serverSuite) assertAbortCurrentUpgradeBlocked(c *gc.C, msg string) {
 	err := s.client.AbortCurrentUpgrade()
 	s.AssertBlocked(c, err, msg)
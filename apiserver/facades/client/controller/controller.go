@@ -56,10 +56,23 @@ type ControllerAPI struct {
 	multiwatcherFactory multiwatcher.Factory
 }
 
+// ControllerAPIv10 provides the v10 Controller API. The only difference
+// between this and v11 is that v10 doesn't have the UniterStateFormats
+// method.
+type ControllerAPIv10 struct {
+	*ControllerAPI
+}
+
+// ControllerAPIv9 provides the v9 Controller API. The only difference
+// between this and v10 is that v9 doesn't have the CheckUnitStates method.
+type ControllerAPIv9 struct {
+	*ControllerAPIv10
+}
+
 // ControllerAPIv8 provides the v8 Controller API. The only difference
 // between this and v9 is that v8 doesn't have the model summary watchers.
 type ControllerAPIv8 struct {
-	*ControllerAPI
+	*ControllerAPIv9
 }
 
 // ControllerAPIv7 provides the v7 Controller API. The only difference
@@ -94,10 +107,10 @@ type ControllerAPIv3 struct {
 
 // LatestAPI is used for testing purposes to create the latest
 // controller API.
-var LatestAPI = NewControllerAPIv9
+var LatestAPI = NewControllerAPIv11
 
-// NewControllerAPIv9 creates a new ControllerAPIv9.
-func NewControllerAPIv9(ctx facade.Context) (*ControllerAPI, error) {
+// NewControllerAPIv11 creates a new ControllerAPIv11.
+func NewControllerAPIv11(ctx facade.Context) (*ControllerAPI, error) {
 	st := ctx.State()
 	authorizer := ctx.Auth()
 	pool := ctx.StatePool()
@@ -119,6 +132,24 @@ func NewControllerAPIv9(ctx facade.Context) (*ControllerAPI, error) {
 	)
 }
 
+// NewControllerAPIv10 creates a new ControllerAPIv10.
+func NewControllerAPIv10(ctx facade.Context) (*ControllerAPIv10, error) {
+	v11, err := NewControllerAPIv11(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &ControllerAPIv10{v11}, nil
+}
+
+// NewControllerAPIv9 creates a new ControllerAPIv9.
+func NewControllerAPIv9(ctx facade.Context) (*ControllerAPIv9, error) {
+	v10, err := NewControllerAPIv10(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &ControllerAPIv9{v10}, nil
+}
+
 // NewControllerAPIv8 creates a new ControllerAPIv8.
 func NewControllerAPIv8(ctx facade.Context) (*ControllerAPIv8, error) {
 	v9, err := NewControllerAPIv9(ctx)
@@ -653,6 +684,7 @@ func (c *ControllerAPI) initiateOneMigration(spec params.MigrationSpec) (string,
 	mig, err := hostedState.CreateMigration(state.MigrationSpec{
 		InitiatedBy: c.apiUser,
 		TargetInfo:  targetInfo,
+		Permissions: state.PermissionConflictPolicy(spec.Permissions),
 	})
 	if err != nil {
 		return "", errors.Trace(err)
@@ -696,8 +728,11 @@ func (c *ControllerAPI) ModifyControllerAccess(args params.ModifyControllerAcces
 			continue
 		}
 
-		result.Results[i].Error = common.ServerError(
-			ChangeControllerAccess(c.state, c.apiUser, targetUserTag, arg.Action, controllerAccess))
+		err = ChangeControllerAccess(c.state, c.apiUser, targetUserTag, arg.Action, controllerAccess)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
 	}
 	return result, nil
 }
@@ -727,6 +762,134 @@ func (c *ControllerAPI) ConfigSet(args params.ControllerConfigSet) error {
 	return nil
 }
 
+// CheckUnitStates validates the unit state documents of each of the
+// specified models against the current schema, returning the problems
+// found. If Repair is set, the problems are also fixed: values that can
+// be fixed in place are, and the rest are quarantined so that they can
+// be recovered by hand.
+func (c *ControllerAPI) CheckUnitStates(args params.CheckUnitStatesArgs) (params.CheckUnitStatesResults, error) {
+	results := params.CheckUnitStatesResults{
+		Results: make([]params.CheckUnitStatesResult, len(args.Args)),
+	}
+	if err := c.checkIsSuperUser(); err != nil {
+		return results, errors.Trace(err)
+	}
+	for i, arg := range args.Args {
+		problems, err := c.checkUnitStates(arg)
+		if err != nil {
+			results.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		results.Results[i].Problems = problems
+	}
+	return results, nil
+}
+
+func (c *ControllerAPI) checkUnitStates(arg params.CheckUnitStatesArg) ([]params.UnitStateProblem, error) {
+	modelTag, err := names.ParseModelTag(arg.ModelTag)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	st, err := c.statePool.Get(modelTag.Id())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer st.Release()
+
+	check := st.CheckUnitStates
+	if arg.Repair {
+		check = st.RepairUnitStates
+	}
+	problems, err := check()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	result := make([]params.UnitStateProblem, len(problems))
+	for i, problem := range problems {
+		result[i] = params.UnitStateProblem{
+			UnitTag: names.NewUnitTag(problem.UnitName).String(),
+			Field:   problem.Field,
+			Key:     problem.Key,
+			Detail:  problem.Detail,
+			Fix:     string(problem.Fix),
+		}
+	}
+	return result, nil
+}
+
+// UniterStateFormats reports the formats of the uniter state of the
+// units of every model, so that it can be known whether every unit agent
+// has moved its state into the controller. The formats are derived from
+// the unit state documents, and models that cannot be read are reported
+// with an error.
+func (c *ControllerAPI) UniterStateFormats() (params.UniterStateFormatsResult, error) {
+	result := params.UniterStateFormatsResult{
+		CurrentFormat: int(state.CurrentUniterStateFormat),
+	}
+	if err := c.checkIsSuperUser(); err != nil {
+		return result, errors.Trace(err)
+	}
+
+	modelUUIDs, err := c.state.AllModelUUIDs()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	converted := true
+	for _, modelUUID := range modelUUIDs {
+		formats, err := c.uniterStateFormats(modelUUID)
+		if errors.IsNotFound(err) {
+			// This model could have been removed.
+			continue
+		}
+		modelFormats := params.ModelUniterStateFormats{
+			ModelTag: names.NewModelTag(modelUUID).String(),
+			Formats:  formats,
+		}
+		if err != nil {
+			modelFormats.Error = common.ServerError(err)
+			converted = false
+		}
+		for _, count := range formats {
+			if count.Format != result.CurrentFormat {
+				converted = false
+			}
+		}
+		result.Models = append(result.Models, modelFormats)
+	}
+	result.Converted = converted
+	return result, nil
+}
+
+func (c *ControllerAPI) uniterStateFormats(modelUUID string) ([]params.UniterStateFormatCount, error) {
+	st, err := c.statePool.Get(modelUUID)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer st.Release()
+
+	formats, err := st.UniterStateFormats()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	result := make([]params.UniterStateFormatCount, 0, len(formats))
+	for format, units := range formats {
+		result = append(result, params.UniterStateFormatCount{
+			Format: int(format),
+			Units:  units,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Format < result[j].Format
+	})
+	return result, nil
+}
+
+// UniterStateFormats isn't on the v10 API.
+func (c *ControllerAPIv10) UniterStateFormats(_, _ struct{}) {}
+
+// CheckUnitStates isn't on the v9 API.
+func (c *ControllerAPIv9) CheckUnitStates(_, _ struct{}) {}
+
 // Mask the ConfigSet method from the v4 API. The API reflection code
 // in rpc/rpcreflect/type.go:newMethod skips 2-argument methods, so
 // this removes the method as far as the RPC machinery is concerned.
@@ -934,13 +1097,13 @@ func targetToAPIInfo(ti *coremigration.TargetInfo) *api.Info {
 
 // grantControllerCloudAccess exists for backwards compatibility since older clients
 // still set add-model on the controller rather than the controller cloud.
-func grantControllerCloudAccess(accessor *state.State, targetUserTag names.UserTag, access permission.Access) error {
+func grantControllerCloudAccess(accessor *state.State, targetUserTag names.UserTag, access permission.Access, ev state.PermissionEvent) error {
 	controllerInfo, err := accessor.ControllerInfo()
 	if err != nil {
 		return errors.Trace(err)
 	}
 	cloud := controllerInfo.CloudName
-	err = accessor.CreateCloudAccess(cloud, targetUserTag, access)
+	err = accessor.CreateCloudAccessWithEvent(cloud, targetUserTag, access, ev)
 	if errors.IsAlreadyExists(err) {
 		cloudAccess, err := accessor.GetCloudAccess(cloud, targetUserTag)
 		if errors.IsNotFound(err) {
@@ -967,14 +1130,14 @@ func grantControllerCloudAccess(accessor *state.State, targetUserTag names.UserT
 	return nil
 }
 
-func grantControllerAccess(accessor *state.State, targetUserTag, apiUser names.UserTag, access permission.Access) error {
+func grantControllerAccess(accessor *state.State, targetUserTag, apiUser names.UserTag, access permission.Access, ev state.PermissionEvent) error {
 	// TODO(wallyworld) - remove in Juju 3.0
 	// Older clients still use the controller facade to manage add-model access.
 	if access == permission.AddModelAccess {
-		return grantControllerCloudAccess(accessor, targetUserTag, access)
+		return grantControllerCloudAccess(accessor, targetUserTag, access, ev)
 	}
 
-	_, err := accessor.AddControllerUser(state.UserAccessSpec{User: targetUserTag, CreatedBy: apiUser, Access: access})
+	_, err := accessor.AddControllerUserWithEvent(state.UserAccessSpec{User: targetUserTag, CreatedBy: apiUser, Access: access}, ev)
 	if errors.IsAlreadyExists(err) {
 		controllerTag := accessor.ControllerTag()
 		controllerUser, err := accessor.UserAccess(targetUserTag, controllerTag)
@@ -990,7 +1153,7 @@ func grantControllerAccess(accessor *state.State, targetUserTag, apiUser names.U
 		if controllerUser.Access.EqualOrGreaterControllerAccessThan(access) {
 			return errors.Errorf("user already has %q access or greater", access)
 		}
-		if _, err = accessor.SetUserAccess(controllerUser.UserTag, controllerUser.Object, access); err != nil {
+		if _, err = accessor.SetControllerAccessWithEvent(controllerUser.UserTag, access, ev); err != nil {
 			return errors.Annotate(err, "could not set controller access for user")
 		}
 		return nil
@@ -1002,7 +1165,7 @@ func grantControllerAccess(accessor *state.State, targetUserTag, apiUser names.U
 	return nil
 }
 
-func revokeControllerAccess(accessor *state.State, targetUserTag, apiUser names.UserTag, access permission.Access) error {
+func revokeControllerAccess(accessor *state.State, targetUserTag, apiUser names.UserTag, access permission.Access, ev state.PermissionEvent) error {
 	// TODO(wallyworld) - remove in Juju 3.0
 	// Older clients still use the controller facade to manage add-model access.
 	if access == permission.AddModelAccess {
@@ -1010,14 +1173,14 @@ func revokeControllerAccess(accessor *state.State, targetUserTag, apiUser names.
 		if err != nil {
 			return errors.Trace(err)
 		}
-		return accessor.RemoveCloudAccess(controllerInfo.CloudName, targetUserTag)
+		return accessor.RemoveCloudAccessWithEvent(controllerInfo.CloudName, targetUserTag, ev)
 	}
 
 	controllerTag := accessor.ControllerTag()
 	switch access {
 	case permission.LoginAccess:
 		// Revoking login access removes all access.
-		err := accessor.RemoveUserAccess(targetUserTag, controllerTag)
+		err := accessor.RemoveControllerUserWithEvent(targetUserTag, ev)
 		return errors.Annotate(err, "could not revoke controller access")
 	case permission.SuperuserAccess:
 		// Revoking superuser sets login.
@@ -1025,7 +1188,7 @@ func revokeControllerAccess(accessor *state.State, targetUserTag, apiUser names.
 		if err != nil {
 			return errors.Annotate(err, "could not look up controller access for user")
 		}
-		_, err = accessor.SetUserAccess(controllerUser.UserTag, controllerUser.Object, permission.LoginAccess)
+		_, err = accessor.SetControllerAccessWithEvent(controllerUser.UserTag, permission.LoginAccess, ev)
 		return errors.Annotate(err, "could not set controller access to login")
 
 	default:
@@ -1034,17 +1197,26 @@ func revokeControllerAccess(accessor *state.State, targetUserTag, apiUser names.
 }
 
 // ChangeControllerAccess performs the requested access grant or revoke action for the
-// specified user on the controller.
+// specified user on the controller. The permission event for the change is
+// queued in the same transaction as the change itself.
 func ChangeControllerAccess(accessor *state.State, apiUser, targetUserTag names.UserTag, action params.ControllerAction, access permission.Access) error {
+	ev := state.PermissionEvent{
+		User:   targetUserTag,
+		Target: accessor.ControllerTag(),
+		Access: access,
+		By:     apiUser,
+	}
 	switch action {
 	case params.GrantControllerAccess:
-		err := grantControllerAccess(accessor, targetUserTag, apiUser, access)
+		ev.Kind = state.PermissionGranted
+		err := grantControllerAccess(accessor, targetUserTag, apiUser, access, ev)
 		if err != nil {
 			return errors.Annotate(err, "could not grant controller access")
 		}
 		return nil
 	case params.RevokeControllerAccess:
-		return revokeControllerAccess(accessor, targetUserTag, apiUser, access)
+		ev.Kind = state.PermissionRevoked
+		return revokeControllerAccess(accessor, targetUserTag, apiUser, access, ev)
 	default:
 		return errors.Errorf("unknown action %q", action)
 	}
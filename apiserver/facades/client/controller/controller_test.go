@@ -1032,6 +1032,110 @@ func (s *controllerSuite) TestMongoVersion(c *gc.C) {
 	c.Assert(result.Result, gc.Matches, "^([0-9]{1,}).([0-9]{1,}).([0-9]{1,})$")
 }
 
+func (s *controllerSuite) TestCheckUnitStates(c *gc.C) {
+	unit := s.Factory.MakeUnit(c, nil)
+	us := state.NewUnitState()
+	us.SetRelationState(map[int]string{42: "changed-pending: true\n"})
+	err := unit.SetState(us)
+	c.Assert(err, jc.ErrorIsNil)
+
+	expected := []params.UnitStateProblem{{
+		UnitTag: unit.Tag().String(),
+		Field:   "relation-state",
+		Key:     "42",
+		Detail:  "relation 42 not found",
+		Fix:     "repair",
+	}}
+	for _, repair := range []bool{false, true} {
+		results, err := s.controller.CheckUnitStates(params.CheckUnitStatesArgs{
+			Args: []params.CheckUnitStatesArg{
+				{ModelTag: s.Model.ModelTag().String(), Repair: repair},
+				{ModelTag: "bad-tag"},
+			},
+		})
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(results.Results, gc.HasLen, 2)
+		c.Check(results.Results[0], jc.DeepEquals, params.CheckUnitStatesResult{Problems: expected})
+		c.Check(results.Results[1].Error, gc.ErrorMatches, `"bad-tag" is not a valid tag`)
+	}
+
+	unitState, err := unit.State()
+	c.Assert(err, jc.ErrorIsNil)
+	relationState, _ := unitState.RelationState()
+	c.Check(relationState, gc.HasLen, 0)
+}
+
+func (s *controllerSuite) TestCheckUnitStatesRequiresSuperUser(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{
+		Access: permission.ReadAccess,
+	})
+	anAuthoriser := apiservertesting.FakeAuthorizer{
+		Tag: user.Tag(),
+	}
+	endpoint, err := controller.LatestAPI(
+		facadetest.Context{
+			State_:     s.State,
+			Resources_: s.resources,
+			Auth_:      anAuthoriser,
+		})
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = endpoint.CheckUnitStates(params.CheckUnitStatesArgs{
+		Args: []params.CheckUnitStatesArg{{ModelTag: s.Model.ModelTag().String()}},
+	})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
+func (s *controllerSuite) TestUniterStateFormats(c *gc.C) {
+	unit := s.Factory.MakeUnit(c, nil)
+
+	result, err := s.controller.UniterStateFormats()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, params.UniterStateFormatsResult{
+		CurrentFormat: 2,
+		Models: []params.ModelUniterStateFormats{{
+			ModelTag: s.Model.ModelTag().String(),
+			Formats:  []params.UniterStateFormatCount{{Format: 0, Units: 1}},
+		}},
+	})
+
+	us := state.NewUnitState()
+	us.SetUniterState("uniter")
+	us.SetStorageState("storage")
+	err = unit.SetState(us)
+	c.Assert(err, jc.ErrorIsNil)
+
+	result, err = s.controller.UniterStateFormats()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, params.UniterStateFormatsResult{
+		CurrentFormat: 2,
+		Converted:     true,
+		Models: []params.ModelUniterStateFormats{{
+			ModelTag: s.Model.ModelTag().String(),
+			Formats:  []params.UniterStateFormatCount{{Format: 2, Units: 1}},
+		}},
+	})
+}
+
+func (s *controllerSuite) TestUniterStateFormatsRequiresSuperUser(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{
+		Access: permission.ReadAccess,
+	})
+	anAuthoriser := apiservertesting.FakeAuthorizer{
+		Tag: user.Tag(),
+	}
+	endpoint, err := controller.LatestAPI(
+		facadetest.Context{
+			State_:     s.State,
+			Resources_: s.resources,
+			Auth_:      anAuthoriser,
+		})
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = endpoint.UniterStateFormats()
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
 func (s *controllerSuite) TestIdentityProviderURL(c *gc.C) {
 	// Preserve default controller config as we will be mutating it just
 	// for this test
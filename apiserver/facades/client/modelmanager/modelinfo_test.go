@@ -24,6 +24,7 @@ import (
 	"github.com/juju/juju/controller"
 	"github.com/juju/juju/core/instance"
 	"github.com/juju/juju/core/life"
+	"github.com/juju/juju/core/migration"
 	"github.com/juju/juju/core/permission"
 	"github.com/juju/juju/core/status"
 	"github.com/juju/juju/environs"
@@ -397,6 +398,35 @@ func (s *modelInfoSuite) TestRunningMigration(c *gc.C) {
 	c.Assert(migrationResult.Status, gc.Equals, "computing optimal bin packing")
 	c.Assert(*migrationResult.Start, gc.Equals, start)
 	c.Assert(migrationResult.End, gc.IsNil)
+	c.Assert(migrationResult.Progress, gc.IsNil)
+}
+
+func (s *modelInfoSuite) TestRunningMigrationProgress(c *gc.C) {
+	s.st.migration = &mockMigration{
+		status: "uploading model binaries into target controller",
+		start:  time.Now().Add(-20 * time.Minute),
+		progress: migration.ImportProgress{
+			Entities: []migration.EntityProgress{
+				{Kind: migration.ImportMachines, Imported: 4, Total: 4},
+				{Kind: migration.ImportUnits, Imported: 7, Total: 9},
+			},
+			BinariesBytes: 1 << 20,
+		},
+	}
+
+	results, err := s.modelmanager.ModelInfo(params.Entities{
+		Entities: []params.Entity{{coretesting.ModelTag.String()}},
+	})
+
+	c.Assert(err, jc.ErrorIsNil)
+	migrationResult := results.Results[0].Result.Migration
+	c.Assert(migrationResult.Progress, jc.DeepEquals, &params.MigrationImportProgress{
+		Entities: []params.EntityImportProgress{
+			{Kind: "machines", Imported: 4, Total: 4},
+			{Kind: "units", Imported: 7, Total: 9},
+		},
+		BinariesBytes: 1 << 20,
+	})
 }
 
 func (s *modelInfoSuite) TestFailedMigration(c *gc.C) {
@@ -867,6 +897,11 @@ func (st *mockState) RemoveUserAccess(subject names.UserTag, target names.Tag) e
 	return st.NextErr()
 }
 
+func (st *mockState) QueuePermissionEvent(ev state.PermissionEvent) error {
+	st.MethodCall(st, "QueuePermissionEvent", ev)
+	return st.NextErr()
+}
+
 func (st *mockState) SetUserAccess(subject names.UserTag, target names.Tag, access permission.Access) (permission.UserAccess, error) {
 	st.MethodCall(st, "SetUserAccess", subject, target, access)
 	return permission.UserAccess{}, st.NextErr()
@@ -1213,15 +1248,20 @@ type mockModelUser struct {
 type mockMigration struct {
 	state.ModelMigration
 
-	status string
-	start  time.Time
-	end    time.Time
+	status   string
+	start    time.Time
+	end      time.Time
+	progress migration.ImportProgress
 }
 
 func (m *mockMigration) StatusMessage() string {
 	return m.status
 }
 
+func (m *mockMigration) ImportProgress() migration.ImportProgress {
+	return m.progress
+}
+
 func (m *mockMigration) StartTime() time.Time {
 	return m.start
 }
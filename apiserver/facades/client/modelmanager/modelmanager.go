@@ -28,6 +28,7 @@ import (
 	jujucloud "github.com/juju/juju/cloud"
 	"github.com/juju/juju/controller/modelmanager"
 	"github.com/juju/juju/core/life"
+	coremigration "github.com/juju/juju/core/migration"
 	"github.com/juju/juju/core/permission"
 	"github.com/juju/juju/environs"
 	"github.com/juju/juju/environs/config"
@@ -1230,14 +1231,36 @@ func (m *ModelManagerAPI) getModelInfo(tag names.ModelTag) (params.ModelInfo, er
 			endTime = nil
 		}
 		info.Migration = &params.ModelMigrationStatus{
-			Status: migration.StatusMessage(),
-			Start:  &startTime,
-			End:    endTime,
+			Status:   migration.StatusMessage(),
+			Start:    &startTime,
+			End:      endTime,
+			Progress: migrationImportProgress(migration.ImportProgress()),
 		}
 	}
 	return info, nil
 }
 
+// migrationImportProgress converts the progress of the transfer of a
+// migrating model to its params form, returning nil if no progress
+// has been reported.
+func migrationImportProgress(progress coremigration.ImportProgress) *params.MigrationImportProgress {
+	if progress.IsZero() {
+		return nil
+	}
+	result := &params.MigrationImportProgress{
+		Entities:      make([]params.EntityImportProgress, len(progress.Entities)),
+		BinariesBytes: progress.BinariesBytes,
+	}
+	for i, entity := range progress.Entities {
+		result.Entities[i] = params.EntityImportProgress{
+			Kind:     entity.Kind,
+			Imported: entity.Imported,
+			Total:    entity.Total,
+		}
+	}
+	return result
+}
+
 // ModifyModelAccess changes the model access granted to users.
 func (m *ModelManagerAPI) ModifyModelAccess(args params.ModifyModelAccessRequest) (result params.ErrorResults, _ error) {
 	result = params.ErrorResults{
@@ -1282,8 +1305,24 @@ func (m *ModelManagerAPI) ModifyModelAccess(args params.ModifyModelAccessRequest
 			continue
 		}
 
-		result.Results[i].Error = common.ServerError(
-			changeModelAccess(m.state, modelTag, m.apiUser, targetUserTag, arg.Action, modelAccess, m.isAdmin))
+		err = changeModelAccess(m.state, modelTag, m.apiUser, targetUserTag, arg.Action, modelAccess, m.isAdmin)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		kind := state.PermissionGranted
+		if arg.Action == params.RevokeModelAccess {
+			kind = state.PermissionRevoked
+		}
+		if err := m.state.QueuePermissionEvent(state.PermissionEvent{
+			Kind:   kind,
+			User:   targetUserTag,
+			Target: modelTag,
+			Access: modelAccess,
+			By:     m.apiUser,
+		}); err != nil {
+			logger.Errorf("cannot queue permission event: %v", err)
+		}
 	}
 	return result, nil
 }
@@ -1370,6 +1409,14 @@ func changeModelAccess(accessor common.ModelManagerBackend, modelTag names.Model
 			}
 			_, err = st.SetUserAccess(modelUser.UserTag, modelUser.Object, permission.ReadAccess)
 			return errors.Annotate(err, "could not set model access to read-only")
+		case permission.UserAdminAccess:
+			// Revoking user-admin access sets read-write.
+			modelUser, err := st.UserAccess(targetUserTag, modelTag)
+			if err != nil {
+				return errors.Annotate(err, "could not look up model access for user")
+			}
+			_, err = st.SetUserAccess(modelUser.UserTag, modelUser.Object, permission.WriteAccess)
+			return errors.Annotate(err, "could not set model access to read-write")
 		case permission.AdminAccess:
 			// Revoking admin access sets read-write.
 			modelUser, err := st.UserAccess(targetUserTag, modelTag)
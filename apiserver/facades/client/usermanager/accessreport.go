@@ -0,0 +1,100 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package usermanager
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
+)
+
+// AccessReport returns a report of the users of the controller and the
+// access they have been granted, for tools that reconcile Juju's access
+// control with an external identity and access management system. Its
+// JSON encoding conforms to the schema returned by AccessReportSchema.
+// Unlike ExportPermissions, local users that have been granted no access
+// are included, along with whether each user is disabled. If labels are
+// specified, only the local users that have all of them are reported.
+// Only controller superusers may call it.
+func (api *UserManagerAPI) AccessReport(args params.AccessReportArgs) (params.AccessReport, error) {
+	result := params.AccessReport{
+		SchemaVersion:  accessReportSchemaVersion,
+		ControllerUUID: api.state.ControllerUUID(),
+	}
+	if !api.isAdmin {
+		return result, common.ErrPerm
+	}
+
+	all, err := api.state.AllUserPermissions()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	perms := make(map[string]state.UserPermissions, len(all))
+	for _, p := range all {
+		perms[strings.ToLower(p.User.Id())] = p
+	}
+	users, err := api.state.AllUsers(true)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+
+	result.Users = make([]params.AccessReportUser, 0, len(users))
+	for _, user := range users {
+		key := strings.ToLower(user.UserTag().Id())
+		userPerms := perms[key]
+		delete(perms, key)
+		if !hasLabels(user.Labels(), args.Labels) {
+			continue
+		}
+		result.Users = append(result.Users, accessReportUser(user.Name(), user.Labels(), userPerms, params.AccessReportUser{
+			DisplayName: user.DisplayName(),
+			Disabled:    user.IsDisabled(),
+		}))
+	}
+	// The users left have been granted access but are not local,
+	// so have no labels; they are only reported if no labels are
+	// specified.
+	if len(args.Labels) == 0 {
+		for _, userPerms := range perms {
+			result.Users = append(result.Users, accessReportUser(userPerms.User.Id(), nil, userPerms, params.AccessReportUser{
+				External: !userPerms.User.IsLocal(),
+			}))
+		}
+	}
+	sort.Slice(result.Users, func(i, j int) bool {
+		return result.Users[i].Username < result.Users[j].Username
+	})
+	return result, nil
+}
+
+// accessReportUser returns the report of the named user, given
+// the user's labels and access, completing the input details.
+func accessReportUser(
+	name string, labels map[string]string, perms state.UserPermissions, details params.AccessReportUser,
+) params.AccessReportUser {
+	details.Username = name
+	if len(labels) > 0 {
+		details.Labels = labels
+	}
+	details.Controller = string(perms.Controller)
+	details.Models = accessToParams(perms.Models)
+	details.Clouds = accessToParams(perms.Clouds)
+	details.Offers = accessToParams(perms.Offers)
+	return details
+}
+
+// AccessReportSchema returns the JSON schema to which the reports
+// returned by AccessReport conform, so that tools consuming them can
+// check that they understand them. Any user may call it.
+func (api *UserManagerAPI) AccessReportSchema() (params.AccessReportSchema, error) {
+	return params.AccessReportSchema{
+		Version: accessReportSchemaVersion,
+		Schema:  accessReportSchema,
+	}, nil
+}
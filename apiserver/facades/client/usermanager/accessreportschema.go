@@ -0,0 +1,74 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package usermanager
+
+// accessReportSchemaVersion is the version of the schema to which
+// access reports conform. It must be incremented whenever the schema
+// changes, so that tools consuming the reports can tell.
+const accessReportSchemaVersion = 1
+
+// accessReportSchema is the JSON schema to which the JSON
+// encoding of params.AccessReport conforms.
+const accessReportSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "Juju access report",
+  "description": "The users of a Juju controller and the access they have been granted.",
+  "type": "object",
+  "required": ["schema-version", "controller-uuid", "users"],
+  "additionalProperties": false,
+  "properties": {
+    "schema-version": {
+      "description": "The version of this schema.",
+      "const": 1
+    },
+    "controller-uuid": {
+      "description": "The UUID of the controller reported on.",
+      "type": "string"
+    },
+    "users": {
+      "type": "array",
+      "items": {"$ref": "#/definitions/user"}
+    }
+  },
+  "definitions": {
+    "user": {
+      "type": "object",
+      "required": ["username", "external", "disabled"],
+      "additionalProperties": false,
+      "properties": {
+        "username": {"type": "string"},
+        "display-name": {"type": "string"},
+        "external": {
+          "description": "Whether the user is managed by an external identity provider.",
+          "type": "boolean"
+        },
+        "disabled": {"type": "boolean"},
+        "labels": {
+          "type": "object",
+          "additionalProperties": {"type": "string"}
+        },
+        "controller": {
+          "description": "The user's access to the controller.",
+          "enum": ["login", "add-model", "superuser"]
+        },
+        "models": {
+          "description": "The user's access to models, keyed by model UUID.",
+          "type": "object",
+          "additionalProperties": {"enum": ["read", "write", "user-admin", "admin"]}
+        },
+        "clouds": {
+          "description": "The user's access to clouds, keyed by cloud name.",
+          "type": "object",
+          "additionalProperties": {"enum": ["add-model", "admin"]}
+        },
+        "offers": {
+          "description": "The user's access to application offers, keyed by offer UUID.",
+          "type": "object",
+          "additionalProperties": {"enum": ["read", "consume", "admin"]}
+        }
+      }
+    }
+  }
+}
+`
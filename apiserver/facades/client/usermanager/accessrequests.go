@@ -0,0 +1,160 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package usermanager
+
+import (
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/core/permission"
+	"github.com/juju/juju/state"
+)
+
+// RequestAccess records requests by the calling user for access to
+// models, which the models' administrators can approve or deny.
+func (api *UserManagerAPI) RequestAccess(args params.RequestAccessArgs) (params.AccessRequestResults, error) {
+	result := params.AccessRequestResults{
+		Results: make([]params.AccessRequestResult, len(args.Requests)),
+	}
+	if err := api.check.ChangeAllowed(); err != nil {
+		return result, errors.Trace(err)
+	}
+
+	for i, arg := range args.Requests {
+		req, err := api.requestAccess(arg)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		result.Results[i].Result = accessRequestToParams(req)
+	}
+	return result, nil
+}
+
+func (api *UserManagerAPI) requestAccess(arg params.RequestAccess) (state.AccessRequest, error) {
+	modelTag, err := names.ParseModelTag(arg.ModelTag)
+	if err != nil {
+		return state.AccessRequest{}, errors.Trace(err)
+	}
+	access := permission.Access(arg.Access)
+	if err := permission.ValidateModelAccess(access); err != nil {
+		return state.AccessRequest{}, errors.Trace(err)
+	}
+	req, err := api.state.RequestModelAccess(api.apiUser, modelTag, access, arg.Reason)
+	return req, errors.Trace(err)
+}
+
+// ListAccessRequests returns the pending requests for access that the
+// calling user can decide, along with the user's own pending requests.
+// Controller superusers can decide all requests, and model administrators
+// the requests for their models.
+func (api *UserManagerAPI) ListAccessRequests() (params.AccessRequestsResult, error) {
+	var result params.AccessRequestsResult
+	isSuperUser, err := api.hasControllerAdminAccess()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+
+	pending, err := api.state.PendingAccessRequests()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	result.Requests = []params.AccessRequest{}
+	for _, req := range pending {
+		if !isSuperUser && req.User != api.apiUser {
+			isModelAdmin, err := api.authorizer.HasPermission(permission.AdminAccess, req.Model)
+			if err != nil {
+				return result, errors.Trace(err)
+			}
+			if !isModelAdmin {
+				continue
+			}
+		}
+		result.Requests = append(result.Requests, *accessRequestToParams(req))
+	}
+	return result, nil
+}
+
+// ApproveAccess grants the access requested by each of the specified
+// requests. The caller must be a controller superuser or an
+// administrator of the requested model.
+func (api *UserManagerAPI) ApproveAccess(args params.AccessRequestIDs) (params.ErrorResults, error) {
+	return api.decideAccess(args, api.approveAccess)
+}
+
+func (api *UserManagerAPI) approveAccess(id string, approvedBy names.UserTag) error {
+	if err := api.state.ApproveAccessRequest(id, approvedBy); err != nil {
+		return errors.Trace(err)
+	}
+	req, err := api.state.AccessRequest(id)
+	if err != nil {
+		logger.Errorf("cannot queue permission event: %v", err)
+		return nil
+	}
+	api.queuePermissionEvent(state.PermissionGranted, req.User, req.Model, req.Access)
+	return nil
+}
+
+// DenyAccess denies each of the specified requests for access. The caller
+// must be a controller superuser or an administrator of the requested model.
+func (api *UserManagerAPI) DenyAccess(args params.AccessRequestIDs) (params.ErrorResults, error) {
+	return api.decideAccess(args, api.state.DenyAccessRequest)
+}
+
+func (api *UserManagerAPI) decideAccess(
+	args params.AccessRequestIDs,
+	decide func(string, names.UserTag) error,
+) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.IDs)),
+	}
+	if err := api.check.ChangeAllowed(); err != nil {
+		return result, errors.Trace(err)
+	}
+	isSuperUser, err := api.hasControllerAdminAccess()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+
+	for i, id := range args.IDs {
+		req, err := api.state.AccessRequest(id)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		if !isSuperUser {
+			isModelAdmin, err := api.authorizer.HasPermission(permission.AdminAccess, req.Model)
+			if err != nil {
+				result.Results[i].Error = common.ServerError(err)
+				continue
+			}
+			if !isModelAdmin {
+				result.Results[i].Error = common.ServerError(common.ErrPerm)
+				continue
+			}
+		}
+		result.Results[i].Error = common.ServerError(decide(id, api.apiUser))
+	}
+	return result, nil
+}
+
+func accessRequestToParams(req state.AccessRequest) *params.AccessRequest {
+	result := &params.AccessRequest{
+		ID:        req.ID,
+		UserTag:   req.User.String(),
+		ModelTag:  req.Model.String(),
+		Access:    string(req.Access),
+		Reason:    req.Reason,
+		Requested: req.Requested,
+		Status:    string(req.Status),
+	}
+	if req.Status != state.AccessRequestPending {
+		decided := req.Decided
+		result.DecidedBy = req.DecidedBy.String()
+		result.Decided = &decided
+	}
+	return result
+}
@@ -0,0 +1,182 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package usermanager
+
+import (
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/core/permission"
+	"github.com/juju/juju/state"
+)
+
+// userAdminScope describes the users and models that the API user may
+// administer. Controller superusers may administer all of them; users
+// granted user-admin access to models, or greater, may administer users
+// only within those models.
+type userAdminScope struct {
+	superuser bool
+
+	// models holds the API user's access to each of the
+	// models within which they administer users, keyed by
+	// model UUID. It is not used for superusers.
+	models map[string]permission.Access
+}
+
+// userAdminScope returns the scope within which the API user may
+// administer users.
+func (api *UserManagerAPI) userAdminScope() (userAdminScope, error) {
+	isSuperUser, err := api.hasControllerAdminAccess()
+	if err != nil {
+		return userAdminScope{}, errors.Trace(err)
+	}
+	if isSuperUser {
+		return userAdminScope{superuser: true}, nil
+	}
+	perms, err := api.state.UserPermissions(api.apiUser)
+	if err != nil {
+		return userAdminScope{}, errors.Trace(err)
+	}
+	scope := userAdminScope{models: make(map[string]permission.Access)}
+	for uuid, access := range perms.Models {
+		if access.EqualOrGreaterModelAccessThan(permission.UserAdminAccess) {
+			scope.models[uuid] = access
+		}
+	}
+	return scope, nil
+}
+
+// empty reports whether the API user may not administer any users.
+func (s userAdminScope) empty() bool {
+	return !s.superuser && len(s.models) == 0
+}
+
+// checkGrant returns an error if the API user may not grant the access
+// to the model. Users who administer users within a model may only grant
+// access lower than their own.
+func (s userAdminScope) checkGrant(modelTag names.ModelTag, access permission.Access) error {
+	if err := permission.ValidateModelAccess(access); err != nil {
+		return errors.Trace(err)
+	}
+	if s.superuser {
+		return nil
+	}
+	own, ok := s.models[modelTag.Id()]
+	if !ok || !own.GreaterModelAccessThan(access) {
+		return common.ErrPerm
+	}
+	return nil
+}
+
+// checkUser returns an error if the API user may not administer the
+// user with the input permissions. Users who administer users within
+// models may only administer users that are not superusers and whose
+// access is limited to those models.
+func (s userAdminScope) checkUser(perms state.UserPermissions) error {
+	if s.superuser {
+		return nil
+	}
+	if perms.Controller == permission.SuperuserAccess || len(perms.Models) == 0 {
+		return common.ErrPerm
+	}
+	for uuid := range perms.Models {
+		if _, ok := s.models[uuid]; !ok {
+			return common.ErrPerm
+		}
+	}
+	return nil
+}
+
+// GrantModelAccess grants users access to models. Controller superusers
+// may grant any access to any model. Users granted user-admin access to
+// a model may grant access lower than their own to it, so that central
+// administrators can delegate the administration of a team's users.
+// Access is only ever raised; users who already have the access, or
+// greater, are reported as errors.
+func (api *UserManagerAPI) GrantModelAccess(args params.GrantModelAccessRequest) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Grants)),
+	}
+	if err := api.check.ChangeAllowed(); err != nil {
+		return result, errors.Trace(err)
+	}
+	if len(args.Grants) == 0 {
+		return result, nil
+	}
+	scope, err := api.userAdminScope()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	if scope.empty() {
+		return result, common.ErrPerm
+	}
+
+	for i, arg := range args.Grants {
+		result.Results[i].Error = common.ServerError(api.grantModelAccessArg(scope, arg))
+	}
+	return result, nil
+}
+
+func (api *UserManagerAPI) grantModelAccessArg(scope userAdminScope, arg params.GrantModelAccess) error {
+	userTag, err := names.ParseUserTag(arg.UserTag)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	modelTag, err := names.ParseModelTag(arg.ModelTag)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	access := permission.Access(arg.Access)
+	if err := scope.checkGrant(modelTag, access); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(api.grantModelAccess(userTag, modelTag, access))
+}
+
+// grantModelAccess raises the user's access to the model to that input.
+func (api *UserManagerAPI) grantModelAccess(userTag names.UserTag, modelTag names.ModelTag, access permission.Access) error {
+	current, err := api.state.UserPermissions(userTag)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if current.Models[modelTag.Id()].EqualOrGreaterModelAccessThan(access) {
+		return errors.Errorf("user already has %q access or greater", access)
+	}
+	if err := api.state.SetUserPermissions(state.UserPermissions{
+		User:   userTag,
+		Models: map[string]permission.Access{modelTag.Id(): access},
+	}, api.apiUser, false); err != nil {
+		return errors.Annotate(err, "could not grant model access")
+	}
+	api.queuePermissionEvent(state.PermissionGranted, userTag, modelTag, access)
+	return nil
+}
+
+// addUserModelAccess returns the models to which a user added with the
+// input arguments is granted access, and the access granted, or an error
+// if the API user may not grant it. Users who administer users within
+// models must grant the users they add access to at least one of them.
+func (s userAdminScope) addUserModelAccess(arg params.AddUser) ([]names.ModelTag, permission.Access, error) {
+	access := permission.Access(arg.ModelAccess)
+	if access == permission.NoAccess {
+		access = permission.ReadAccess
+	}
+	if len(arg.ModelTags) == 0 && !s.superuser {
+		return nil, "", common.ErrPerm
+	}
+	modelTags := make([]names.ModelTag, len(arg.ModelTags))
+	for i, tag := range arg.ModelTags {
+		modelTag, err := names.ParseModelTag(tag)
+		if err != nil {
+			return nil, "", errors.Trace(err)
+		}
+		if err := s.checkGrant(modelTag, access); err != nil {
+			return nil, "", errors.Trace(err)
+		}
+		modelTags[i] = modelTag
+	}
+	return modelTags, access, nil
+}
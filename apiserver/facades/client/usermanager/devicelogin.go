@@ -0,0 +1,159 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package usermanager
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+	"gopkg.in/macaroon-bakery.v2/bakery"
+	"gopkg.in/macaroon.v2"
+
+	"github.com/juju/juju/apiserver/authentication"
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+)
+
+// identityProviderTimeout is the time allowed
+// for each request to the identity provider.
+const identityProviderTimeout = 30 * time.Second
+
+// identityProviderClient is used to make requests to the
+// identity provider with which users log in with a device login.
+var identityProviderClient = &http.Client{Timeout: identityProviderTimeout}
+
+// loginMacaroonMinter mints macaroons with which users can log in.
+type loginMacaroonMinter interface {
+	CreateLoginMacaroon(context.Context, names.UserTag, bakery.Version) (*macaroon.Macaroon, error)
+}
+
+// StartDeviceLogin starts logging in with the controller's OpenID Connect
+// identity provider, using the device authorization flow, for clients
+// that cannot open a browser. The user approves the login by entering the
+// returned user code at the verification URI, on any device, while the
+// client polls for its completion with PollDeviceLogin. It can be called
+// using an anonymous connection.
+func (api *UserManagerAPI) StartDeviceLogin() (params.DeviceLoginResult, error) {
+	var result params.DeviceLoginResult
+	provider, err := api.deviceLoginProvider()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	auth, err := provider.StartLogin(context.TODO())
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	return params.DeviceLoginResult{
+		DeviceCode:              auth.DeviceCode,
+		UserCode:                auth.UserCode,
+		VerificationURI:         auth.VerificationURI,
+		VerificationURIComplete: auth.VerificationURIComplete,
+		Interval:                auth.Interval,
+		Expiry:                  auth.Expiry,
+	}, nil
+}
+
+// PollDeviceLogin polls for the completion of each of the device logins
+// started by StartDeviceLogin. Once the user has approved a login, the
+// result holds the local user named by the user's identity at the
+// identity provider, and a macaroon with which the client can log in as
+// that user. Users that have enrolled a second factor cannot log in with
+// a device login. Logins the user has yet to approve fail with
+// CodeDeviceLoginPending, and those polled more often than the interval
+// returned by StartDeviceLogin with CodeDeviceLoginSlowDown. It can be
+// called using an anonymous connection.
+func (api *UserManagerAPI) PollDeviceLogin(args params.PollDeviceLoginArgs) (params.PollDeviceLoginResults, error) {
+	result := params.PollDeviceLoginResults{
+		Results: make([]params.PollDeviceLoginResult, len(args.Logins)),
+	}
+	if len(args.Logins) == 0 {
+		return result, nil
+	}
+	provider, err := api.deviceLoginProvider()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	minter, err := api.loginMacaroonMinter()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+
+	for i, arg := range args.Logins {
+		userTag, mac, err := api.pollDeviceLogin(provider, minter, arg)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		result.Results[i].UserTag = userTag.String()
+		result.Results[i].Macaroon = mac
+	}
+	return result, nil
+}
+
+func (api *UserManagerAPI) pollDeviceLogin(
+	provider *authentication.DeviceLoginProvider,
+	minter loginMacaroonMinter,
+	arg params.PollDeviceLogin,
+) (names.UserTag, *macaroon.Macaroon, error) {
+	ctx := context.TODO()
+	userTag, err := provider.CompleteLogin(ctx, arg.DeviceCode)
+	if err != nil {
+		return names.UserTag{}, nil, errors.Trace(err)
+	}
+	// The identity provider names the local user, which must
+	// already exist; device logins never create users.
+	user, err := api.state.User(userTag)
+	if errors.IsNotFound(err) {
+		logger.Debugf("device login for unknown user %q", userTag.Id())
+		return names.UserTag{}, nil, common.ErrPerm
+	} else if err != nil {
+		return names.UserTag{}, nil, errors.Trace(err)
+	}
+	if user.IsDisabled() {
+		logger.Debugf("device login for disabled user %q", userTag.Id())
+		return names.UserTag{}, nil, common.ErrPerm
+	}
+	// The identity provider does not check the second factor a user
+	// has enrolled here, so such users must log in with a password.
+	if user.SecondFactorEnrolled() {
+		return names.UserTag{}, nil, errors.NotSupportedf("device login for user %q with a second factor", userTag.Id())
+	}
+	mac, err := minter.CreateLoginMacaroon(ctx, userTag, arg.BakeryVersion)
+	if err != nil {
+		return names.UserTag{}, nil, errors.Trace(err)
+	}
+	return userTag, mac, nil
+}
+
+// deviceLoginProvider returns the provider with which users log in
+// with a device login, as configured in the controller config.
+func (api *UserManagerAPI) deviceLoginProvider() (*authentication.DeviceLoginProvider, error) {
+	cfg, err := api.state.ControllerConfig()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if cfg.OIDCIssuerURL() == "" {
+		return nil, errors.NotSupportedf("device login without an identity provider")
+	}
+	return &authentication.DeviceLoginProvider{
+		IssuerURL:     cfg.OIDCIssuerURL(),
+		ClientID:      cfg.OIDCClientID(),
+		UsernameClaim: cfg.OIDCUsernameClaim(),
+		HTTPClient:    identityProviderClient,
+	}, nil
+}
+
+// loginMacaroonMinter returns the minter of the macaroons
+// with which users that complete a device login log in.
+func (api *UserManagerAPI) loginMacaroonMinter() (loginMacaroonMinter, error) {
+	resource, _ := api.resources.Get("localMacaroonAuthenticator").(common.ValueResource)
+	minter, ok := resource.Value.(loginMacaroonMinter)
+	if !ok {
+		return nil, errors.New("login macaroons not available")
+	}
+	return minter, nil
+}
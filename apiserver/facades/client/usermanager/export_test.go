@@ -0,0 +1,31 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package usermanager
+
+import (
+	"net/http"
+)
+
+// SendMail is the function used to email notifications.
+var SendMail = &sendMail
+
+// Patcher is satisfied by test suites that can patch values.
+type Patcher interface {
+	PatchValue(dest, value interface{})
+}
+
+// PatchPostWebhook replaces the function used to post notifications
+// to a webhook with one that is passed the URL and the event kind,
+// user and actor.
+func PatchPostWebhook(p Patcher, f func(url, event, user, by string) error) {
+	p.PatchValue(&postWebhook, func(url string, ev accountEvent) error {
+		return f(url, ev.Event, ev.User, ev.By)
+	})
+}
+
+// PatchIdentityProviderClient replaces the client used to
+// make requests to the device login identity provider.
+func PatchIdentityProviderClient(p Patcher, client *http.Client) {
+	p.PatchValue(&identityProviderClient, client)
+}
@@ -0,0 +1,135 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package usermanager
+
+import (
+	"sort"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/controller"
+	"github.com/juju/juju/core/permission"
+	"github.com/juju/juju/state"
+)
+
+// GuestAccess reports whether clients may log in, without credentials,
+// as the built-in read-only guest user, and the models the guest user
+// can see. Only controller superusers may call it.
+func (api *UserManagerAPI) GuestAccess() (params.GuestAccessResult, error) {
+	var result params.GuestAccessResult
+	if !api.isAdmin {
+		return result, common.ErrPerm
+	}
+	cfg, err := api.state.ControllerConfig()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	result.Enabled = cfg.GuestAccess()
+	perms, err := api.state.UserPermissions(names.NewUserTag(state.GuestUserName))
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	result.ModelTags = make([]string, 0, len(perms.Models))
+	for uuid := range perms.Models {
+		result.ModelTags = append(result.ModelTags, names.NewModelTag(uuid).String())
+	}
+	sort.Strings(result.ModelTags)
+	return result, nil
+}
+
+// EnableGuestAccess allows clients to log in, without credentials, as the
+// built-in read-only guest user, adding the guest user if it does not
+// exist yet. The guest user is given read access to the specified models,
+// and its access to any other models is revoked. Only controller
+// superusers may call it.
+func (api *UserManagerAPI) EnableGuestAccess(args params.GuestAccessArgs) error {
+	if err := api.check.ChangeAllowed(); err != nil {
+		return errors.Trace(err)
+	}
+	if !api.isAdmin {
+		return common.ErrPerm
+	}
+	models := make(map[string]permission.Access, len(args.ModelTags))
+	for _, tag := range args.ModelTags {
+		modelTag, err := names.ParseModelTag(tag)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		models[modelTag.Id()] = permission.ReadAccess
+	}
+
+	guest, err := api.state.EnsureGuestUser(api.apiUser.Id())
+	if err != nil {
+		return errors.Annotate(err, "adding guest user")
+	}
+	if err := api.setGuestModels(guest.UserTag(), models); err != nil {
+		return errors.Trace(err)
+	}
+	logger.Infof("%s enabled guest access to %d models", api.apiUser.Id(), len(models))
+	return errors.Trace(api.state.UpdateControllerConfig(map[string]interface{}{
+		controller.GuestAccess: true,
+	}, nil))
+}
+
+// DisableGuestAccess stops clients logging in as the guest user, and
+// revokes the guest user's access to all models, so that enabling guest
+// access again does not expose any models unexpectedly. The guest user's
+// sessions on the API server the client is connected to are terminated.
+// Only controller superusers may call it.
+func (api *UserManagerAPI) DisableGuestAccess() error {
+	if err := api.check.ChangeAllowed(); err != nil {
+		return errors.Trace(err)
+	}
+	if !api.isAdmin {
+		return common.ErrPerm
+	}
+	if err := api.state.UpdateControllerConfig(map[string]interface{}{
+		controller.GuestAccess: false,
+	}, nil); err != nil {
+		return errors.Trace(err)
+	}
+	logger.Infof("%s disabled guest access", api.apiUser.Id())
+
+	guestTag := names.NewUserTag(state.GuestUserName)
+	if _, err := api.state.User(guestTag); errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	if err := api.setGuestModels(guestTag, nil); err != nil {
+		return errors.Trace(err)
+	}
+	api.terminateGuestSessions(guestTag)
+	return nil
+}
+
+// setGuestModels sets the guest user's access to be read access to
+// exactly the input models, and login access to the controller.
+func (api *UserManagerAPI) setGuestModels(guestTag names.UserTag, models map[string]permission.Access) error {
+	err := api.state.SetUserPermissions(state.UserPermissions{
+		User:       guestTag,
+		Controller: permission.LoginAccess,
+		Models:     models,
+	}, api.apiUser, true)
+	return errors.Annotate(err, "setting guest user access")
+}
+
+// terminateGuestSessions logs out the clients logged in as the guest
+// user on the API server. As guest access has already been disabled,
+// failures are logged rather than returned.
+func (api *UserManagerAPI) terminateGuestSessions(guestTag names.UserTag) {
+	registry, err := api.sessionRegistry()
+	if err != nil {
+		logger.Warningf("cannot terminate guest sessions: %v", err)
+		return
+	}
+	for _, session := range registry.UserSessions(guestTag) {
+		if err := registry.TerminateSession(session.SessionID); err != nil {
+			logger.Warningf("cannot terminate guest session %s: %v", session.SessionID, err)
+		}
+	}
+}
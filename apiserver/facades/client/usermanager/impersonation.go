@@ -0,0 +1,114 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package usermanager
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+	"gopkg.in/macaroon-bakery.v2/bakery"
+	"gopkg.in/macaroon.v2"
+
+	"github.com/juju/juju/apiserver/authentication"
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
+)
+
+// impersonationMacaroonMinter mints macaroons with which
+// controller superusers can log in as other users.
+type impersonationMacaroonMinter interface {
+	CreateImpersonationMacaroon(ctx context.Context, tag, impersonator names.UserTag, version bakery.Version) (*macaroon.Macaroon, error)
+}
+
+// Impersonate returns, for each of the specified local users, a macaroon
+// with which the caller can log in as the user, to reproduce what the
+// user sees. The macaroons expire after a short time. Logins made with
+// them are recorded in the audit log as impersonations, along with every
+// call made, and cannot change the user's password, second factor or
+// credentials. A reason must be given for each impersonation. Only
+// controller superusers may call it, and only while the audit log is
+// enabled, as impersonations must not go unrecorded.
+func (api *UserManagerAPI) Impersonate(args params.ImpersonateArgs) (params.ImpersonateResults, error) {
+	result := params.ImpersonateResults{
+		Results: make([]params.ImpersonateResult, len(args.Impersonations)),
+	}
+	if !api.isAdmin {
+		return result, common.ErrPerm
+	}
+	if len(args.Impersonations) == 0 {
+		return result, nil
+	}
+	cfg, err := api.state.ControllerConfig()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	if !cfg.AuditingEnabled() {
+		return result, errors.NotSupportedf("impersonation with the audit log disabled")
+	}
+	minter, err := api.impersonationMacaroonMinter()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+
+	for i, arg := range args.Impersonations {
+		// The macaroon expires a little after the reported expiry.
+		expiry := time.Now().UTC().Add(authentication.ImpersonationExpiryTime)
+		mac, err := api.impersonate(minter, arg)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		result.Results[i].Macaroon = mac
+		result.Results[i].Expiry = expiry
+	}
+	return result, nil
+}
+
+func (api *UserManagerAPI) impersonate(minter impersonationMacaroonMinter, arg params.Impersonation) (*macaroon.Macaroon, error) {
+	userTag, err := names.ParseUserTag(arg.UserTag)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	reason := strings.TrimSpace(arg.Reason)
+	if reason == "" {
+		return nil, errors.NotValidf("impersonation of %q without a reason", userTag.Id())
+	}
+	if !userTag.IsLocal() {
+		return nil, errors.NotValidf("impersonation of non-local user %q", userTag.Id())
+	}
+	if userTag == api.apiUser {
+		return nil, errors.NotValidf("impersonation of yourself")
+	}
+	if userTag.Name() == state.GuestUserName {
+		return nil, errors.NotValidf("impersonation of the guest user")
+	}
+	user, err := api.state.User(userTag)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if user.IsDisabled() {
+		return nil, errors.NotValidf("impersonation of disabled user %q", userTag.Id())
+	}
+	mac, err := minter.CreateImpersonationMacaroon(context.TODO(), userTag, api.apiUser, arg.BakeryVersion)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	logger.Infof("%s may impersonate %s for %v: %s", api.apiUser.Id(), userTag.Id(), authentication.ImpersonationExpiryTime, reason)
+	return mac, nil
+}
+
+// impersonationMacaroonMinter returns the minter of the macaroons
+// with which controller superusers log in as other users.
+func (api *UserManagerAPI) impersonationMacaroonMinter() (impersonationMacaroonMinter, error) {
+	resource, _ := api.resources.Get("localMacaroonAuthenticator").(common.ValueResource)
+	minter, ok := resource.Value.(impersonationMacaroonMinter)
+	if !ok {
+		return nil, errors.New("impersonation macaroons not available")
+	}
+	return minter, nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package usermanager
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+)
+
+// UpdateUserLabels attaches labels to, and removes labels from, the
+// specified users. Labels organise users for access reviews, so only
+// controller superusers may change them.
+func (api *UserManagerAPI) UpdateUserLabels(args params.UpdateUserLabelsArgs) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Args)),
+	}
+	if err := api.check.ChangeAllowed(); err != nil {
+		return result, errors.Trace(err)
+	}
+	isSuperUser, err := api.hasControllerAdminAccess()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	if !isSuperUser {
+		return result, common.ErrPerm
+	}
+
+	for i, arg := range args.Args {
+		user, err := api.getUser(arg.UserTag)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		if err := user.UpdateLabels(arg.Set, arg.Unset); err != nil {
+			result.Results[i].Error = common.ServerError(err)
+		}
+	}
+	return result, nil
+}
+
+// hasLabels reports whether the user labels include all of the
+// wanted labels.
+func hasLabels(labels, wanted map[string]string) bool {
+	for key, value := range wanted {
+		if actual, ok := labels[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
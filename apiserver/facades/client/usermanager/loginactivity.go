@@ -0,0 +1,57 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package usermanager
+
+import (
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+)
+
+// LoginActivity returns, for each of the specified users, the number of
+// times they logged in to each model and to the controller since the given
+// time, when they last did so, and the addresses they logged in from.
+// Controller superusers may report on any user; other users only on
+// themselves.
+func (api *UserManagerAPI) LoginActivity(args params.LoginActivityArgs) (params.LoginActivityResults, error) {
+	result := params.LoginActivityResults{
+		Results: make([]params.LoginActivityResult, len(args.Entities)),
+	}
+	isSuperUser, err := api.hasControllerAdminAccess()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+
+	for i, arg := range args.Entities {
+		userTag, err := names.ParseUserTag(arg.Tag)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		if !isSuperUser && !api.authorizer.AuthOwner(userTag) {
+			result.Results[i].Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+		activity, err := api.state.LoginActivity(args.Since, userTag)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		models := make([]params.ModelLoginActivity, len(activity))
+		for j, a := range activity {
+			models[j] = params.ModelLoginActivity{
+				Connections:    a.Connections,
+				LastConnection: a.LastConnection,
+				Addresses:      a.Addresses,
+			}
+			if a.ModelUUID != "" {
+				models[j].ModelTag = names.NewModelTag(a.ModelUUID).String()
+			}
+		}
+		result.Results[i].Models = models
+	}
+	return result, nil
+}
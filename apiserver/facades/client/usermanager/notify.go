@@ -0,0 +1,183 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package usermanager
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/controller"
+	"github.com/juju/juju/state"
+)
+
+// The user account events of which notifications are sent.
+const (
+	UserCreatedEvent   = "user-created"
+	PasswordResetEvent = "password-reset"
+	UserDisabledEvent  = "user-disabled"
+)
+
+// notificationTimeout is the time allowed to
+// deliver a notification on each channel.
+const notificationTimeout = 10 * time.Second
+
+// accountEvent describes an event on a user's account,
+// as it is sent in notifications.
+type accountEvent struct {
+	Event string    `json:"event"`
+	User  string    `json:"user"`
+	By    string    `json:"by"`
+	Time  time.Time `json:"time"`
+}
+
+// notificationChannel delivers notifications of account events.
+type notificationChannel struct {
+	name    string
+	deliver func(accountEvent) error
+}
+
+// notificationChannels returns the channels configured in the
+// controller config for delivering notifications of account events.
+func notificationChannels(cfg controller.Config) []notificationChannel {
+	var channels []notificationChannel
+	if url := cfg.UserNotificationWebhookURL(); url != "" {
+		channels = append(channels, notificationChannel{
+			name: "webhook",
+			deliver: func(ev accountEvent) error {
+				return postWebhook(url, ev)
+			},
+		})
+	}
+	if server := cfg.UserNotificationSMTPServer(); server != "" {
+		from, to := cfg.UserNotificationEmailFrom(), cfg.UserNotificationEmailTo()
+		channels = append(channels, notificationChannel{
+			name: "email",
+			deliver: func(ev accountEvent) error {
+				return sendMail(server, from, to, emailMessage(from, to, ev))
+			},
+		})
+	}
+	return channels
+}
+
+// emailMessage returns the email notifying of the account event.
+func emailMessage(from, to string, ev accountEvent) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Subject: Juju user %q: %s\r\n", ev.User, ev.Event)
+	fmt.Fprintf(&buf, "\r\n")
+	fmt.Fprintf(&buf, "Event: %s\r\n", ev.Event)
+	fmt.Fprintf(&buf, "User: %s\r\n", ev.User)
+	fmt.Fprintf(&buf, "By: %s\r\n", ev.By)
+	fmt.Fprintf(&buf, "Time: %s\r\n", ev.Time.Format(time.RFC3339))
+	return buf.Bytes()
+}
+
+// postWebhook posts the account event, as JSON, to the URL.
+// It is a variable so that it can be replaced in tests.
+var postWebhook = func(url string, ev accountEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	client := &http.Client{Timeout: notificationTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// sendMail sends the message through the SMTP server, using TLS if
+// the server supports it. It is a variable so that it can be replaced
+// in tests.
+var sendMail = func(server, from, to string, msg []byte) error {
+	conn, err := net.DialTimeout("tcp", server, notificationTimeout)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(notificationTimeout))
+	host, _, _ := net.SplitHostPort(server)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return errors.Trace(err)
+	}
+	defer func() { _ = client.Close() }()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return errors.Trace(err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return errors.Trace(err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return errors.Trace(err)
+	}
+	if err := w.Close(); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(client.Quit())
+}
+
+// notify delivers a notification of the event on the user's account to
+// each of the channels configured in the controller config, and records
+// whether it was delivered. Failing to notify does not fail the operation
+// that caused the event, so errors are only logged.
+func (api *UserManagerAPI) notify(event string, user names.UserTag) {
+	cfg, err := api.state.ControllerConfig()
+	if err != nil {
+		logger.Errorf("cannot read controller config to notify %s for %q: %v", event, user.Id(), err)
+		return
+	}
+	channels := notificationChannels(cfg)
+	if len(channels) == 0 {
+		return
+	}
+	ev := accountEvent{
+		Event: event,
+		User:  user.Id(),
+		By:    api.apiUser.Id(),
+		Time:  time.Now().UTC(),
+	}
+	for _, channel := range channels {
+		notification := state.UserNotification{
+			User:      user,
+			Event:     event,
+			Channel:   channel.name,
+			Delivered: true,
+		}
+		if err := channel.deliver(ev); err != nil {
+			logger.Warningf("cannot deliver %s notification of %s for %q: %v", channel.name, event, user.Id(), err)
+			notification.Delivered = false
+			notification.Error = err.Error()
+		}
+		if err := api.state.RecordUserNotification(notification); err != nil {
+			logger.Errorf("%v", err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package usermanager
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+)
+
+// PasswordExpiry returns the local users whose passwords have expired,
+// or will expire within the given length of time, under the controller's
+// password-max-age policy. Only controller superusers may call it.
+func (api *UserManagerAPI) PasswordExpiry(args params.PasswordExpiryArgs) (params.PasswordExpiryResult, error) {
+	var result params.PasswordExpiryResult
+	isSuperUser, err := api.hasControllerAdminAccess()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	if !isSuperUser {
+		return result, common.ErrPerm
+	}
+	if args.Within < 0 {
+		return result, errors.NotValidf("negative password expiry window")
+	}
+
+	cfg, err := api.state.ControllerConfig()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	result.MaxAge = cfg.PasswordMaxAge()
+	users, err := api.state.UsersWithExpiringPasswords(result.MaxAge, args.Within)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	result.Users = make([]params.UserPasswordExpiry, len(users))
+	for i, user := range users {
+		expires, _ := user.PasswordExpiry(result.MaxAge)
+		result.Users[i] = params.UserPasswordExpiry{
+			UserTag:     user.UserTag().String(),
+			PasswordSet: user.PasswordSetTime(),
+			Expires:     expires,
+			Expired:     user.PasswordExpired(result.MaxAge),
+		}
+	}
+	return result, nil
+}
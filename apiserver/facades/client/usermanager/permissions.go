@@ -0,0 +1,153 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package usermanager
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/core/permission"
+	"github.com/juju/juju/state"
+)
+
+// ExportPermissions returns a document describing the access granted to
+// every user of the controller, so that access control can be reviewed
+// and later reconciled with ImportPermissions. If labels are specified,
+// only the local users that have all of them are described.
+func (api *UserManagerAPI) ExportPermissions(args params.ExportPermissionsArgs) (params.PermissionsDocument, error) {
+	var result params.PermissionsDocument
+	isSuperUser, err := api.hasControllerAdminAccess()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	if !isSuperUser {
+		return result, common.ErrPerm
+	}
+
+	all, err := api.state.AllUserPermissions()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	users, err := api.state.AllUsers(true)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	labels := make(map[string]map[string]string, len(users))
+	for _, user := range users {
+		labels[strings.ToLower(user.UserTag().Id())] = user.Labels()
+	}
+
+	result.Users = make([]params.UserPermissions, 0, len(all))
+	for _, perms := range all {
+		// External users have no labels, so are
+		// only described if no labels are specified.
+		userLabels := labels[strings.ToLower(perms.User.Id())]
+		if !hasLabels(userLabels, args.Labels) {
+			continue
+		}
+		result.Users = append(result.Users, params.UserPermissions{
+			Username:   perms.User.Id(),
+			Labels:     userLabels,
+			Controller: string(perms.Controller),
+			Models:     accessToParams(perms.Models),
+			Clouds:     accessToParams(perms.Clouds),
+			Offers:     accessToParams(perms.Offers),
+		})
+	}
+	return result, nil
+}
+
+// ImportPermissions grants each user the access described in the args.
+// If Revoke is set, any access a user has that is not described is
+// revoked; users not in the args are left alone.
+func (api *UserManagerAPI) ImportPermissions(args params.ImportPermissionsArgs) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Users)),
+	}
+	if err := api.check.ChangeAllowed(); err != nil {
+		return result, errors.Trace(err)
+	}
+	isSuperUser, err := api.hasControllerAdminAccess()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	if !isSuperUser {
+		return result, common.ErrPerm
+	}
+
+	for i, arg := range args.Users {
+		result.Results[i].Error = common.ServerError(api.importPermissions(arg, args.Revoke))
+	}
+	return result, nil
+}
+
+func (api *UserManagerAPI) importPermissions(arg params.UserPermissions, revoke bool) error {
+	if !names.IsValidUser(arg.Username) {
+		return errors.NotValidf("username %q", arg.Username)
+	}
+	perms := state.UserPermissions{
+		User:       names.NewUserTag(arg.Username),
+		Controller: permission.Access(arg.Controller),
+	}
+	if perms.Controller != permission.NoAccess {
+		if err := permission.ValidateControllerAccess(perms.Controller); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if perms.User == api.apiUser && perms.Controller != permission.SuperuserAccess &&
+		(revoke || perms.Controller != permission.NoAccess) {
+		// Stop superusers locking themselves out of the controller,
+		// for example with a document exported before they were added.
+		return errors.Errorf("cannot remove superuser access from %q", arg.Username)
+	}
+	var err error
+	if perms.Models, err = accessFromParams(arg.Models, permission.ValidateModelAccess); err != nil {
+		return errors.Annotate(err, "models")
+	}
+	if perms.Clouds, err = accessFromParams(arg.Clouds, permission.ValidateCloudAccess); err != nil {
+		return errors.Annotate(err, "clouds")
+	}
+	if perms.Offers, err = accessFromParams(arg.Offers, permission.ValidateOfferAccess); err != nil {
+		return errors.Annotate(err, "offers")
+	}
+	before, err := api.state.UserPermissions(perms.User)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// Some access may have changed even if setting the rest failed.
+	setErr := api.state.SetUserPermissions(perms, api.apiUser, revoke)
+	if after, err := api.state.UserPermissions(perms.User); err != nil {
+		logger.Errorf("cannot queue permission events: %v", err)
+	} else {
+		api.queuePermissionChanges(before, after)
+	}
+	return errors.Trace(setErr)
+}
+
+func accessToParams(in map[string]permission.Access) map[string]string {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(in))
+	for key, access := range in {
+		out[key] = string(access)
+	}
+	return out
+}
+
+func accessFromParams(in map[string]string, validate func(permission.Access) error) (map[string]permission.Access, error) {
+	out := make(map[string]permission.Access, len(in))
+	for key, value := range in {
+		access := permission.Access(value)
+		if err := validate(access); err != nil {
+			return nil, errors.Annotatef(err, "%q", key)
+		}
+		out[key] = access
+	}
+	return out, nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package usermanager
+
+import (
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/core/permission"
+	"github.com/juju/juju/state"
+)
+
+// PermissionWebhookDeadLetters returns the changes to users' access
+// whose delivery to the permission webhook has been abandoned after
+// repeated failures. Only controller superusers may list them.
+func (api *UserManagerAPI) PermissionWebhookDeadLetters() (params.PermissionEventsResult, error) {
+	var result params.PermissionEventsResult
+	isSuperUser, err := api.hasControllerAdminAccess()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	if !isSuperUser {
+		return result, common.ErrPerm
+	}
+
+	events, err := api.state.PermissionEventDeadLetters()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	result.Events = make([]params.PermissionEvent, len(events))
+	for i, ev := range events {
+		result.Events[i] = params.PermissionEvent{
+			ID:        ev.ID,
+			Kind:      string(ev.Kind),
+			UserTag:   ev.User.String(),
+			TargetTag: ev.Target.String(),
+			Access:    string(ev.Access),
+			Time:      ev.Time,
+			Attempts:  ev.Attempts,
+			LastError: ev.LastError,
+		}
+		if ev.By.Id() != "" {
+			result.Events[i].By = ev.By.String()
+		}
+	}
+	return result, nil
+}
+
+// RetryPermissionWebhookEvents returns each of the specified abandoned
+// events to the queue, to be delivered to the permission webhook again.
+// Only controller superusers may retry events.
+func (api *UserManagerAPI) RetryPermissionWebhookEvents(args params.PermissionEventIDs) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.IDs)),
+	}
+	isSuperUser, err := api.hasControllerAdminAccess()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	if !isSuperUser {
+		return result, common.ErrPerm
+	}
+
+	for i, id := range args.IDs {
+		result.Results[i].Error = common.ServerError(api.state.RetryPermissionEvent(id))
+	}
+	return result, nil
+}
+
+// queuePermissionEvent queues a change to the user's access to the
+// target for delivery to the permission webhook. A failure to queue
+// the event is logged rather than failing the change, which has
+// already been made.
+func (api *UserManagerAPI) queuePermissionEvent(
+	kind state.PermissionEventKind, user names.UserTag, target names.Tag, access permission.Access,
+) {
+	if err := api.state.QueuePermissionEvent(state.PermissionEvent{
+		Kind:   kind,
+		User:   user,
+		Target: target,
+		Access: access,
+		By:     api.apiUser,
+	}); err != nil {
+		logger.Errorf("cannot queue permission event: %v", err)
+	}
+}
+
+// queuePermissionChanges queues an event for each change to the user's
+// controller and model access between before and after.
+func (api *UserManagerAPI) queuePermissionChanges(before, after state.UserPermissions) {
+	api.queueAccessChange(after.User, api.state.ControllerTag(), before.Controller, after.Controller)
+	for uuid, access := range after.Models {
+		api.queueAccessChange(after.User, names.NewModelTag(uuid), before.Models[uuid], access)
+	}
+	for uuid, access := range before.Models {
+		if _, ok := after.Models[uuid]; !ok {
+			api.queueAccessChange(after.User, names.NewModelTag(uuid), access, permission.NoAccess)
+		}
+	}
+}
+
+func (api *UserManagerAPI) queueAccessChange(user names.UserTag, target names.Tag, before, after permission.Access) {
+	switch {
+	case before == after:
+	case after == permission.NoAccess:
+		api.queuePermissionEvent(state.PermissionRevoked, user, target, before)
+	default:
+		api.queuePermissionEvent(state.PermissionGranted, user, target, after)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package usermanager
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/controller"
+	"github.com/juju/juju/state"
+)
+
+// UsernamePolicyError is returned when the name of a new user is
+// not allowed by the username policy in the controller config.
+type UsernamePolicyError struct {
+	// Username is the name that is not allowed.
+	Username string
+
+	// Reason describes the rule that the name breaks.
+	Reason string
+}
+
+// Error is part of the error interface.
+func (e *UsernamePolicyError) Error() string {
+	return fmt.Sprintf("username %q not allowed: %s", e.Username, e.Reason)
+}
+
+// ErrorCode returns the code with which the error is sent to clients.
+func (e *UsernamePolicyError) ErrorCode() string {
+	return params.CodeUsernameNotAllowed
+}
+
+// IsUsernamePolicyError returns true if err is caused by
+// a UsernamePolicyError.
+func IsUsernamePolicyError(err error) bool {
+	_, ok := errors.Cause(err).(*UsernamePolicyError)
+	return ok
+}
+
+// checkUsernamePolicy returns a UsernamePolicyError if the name is
+// reserved, too long, or does not match the configured pattern. The
+// name of the built-in guest user is always reserved.
+func checkUsernamePolicy(cfg controller.Config, name string) error {
+	lowercaseName := strings.ToLower(name)
+	if lowercaseName == state.GuestUserName || cfg.ReservedUsernames().Contains(lowercaseName) {
+		return &UsernamePolicyError{Username: name, Reason: "name is reserved"}
+	}
+	if max := cfg.MaxUsernameLength(); max > 0 && len(name) > max {
+		return &UsernamePolicyError{
+			Username: name,
+			Reason:   fmt.Sprintf("longer than %d characters", max),
+		}
+	}
+	if pattern := cfg.UsernamePattern(); pattern != "" {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return errors.Annotatef(err, "invalid %s", controller.UsernamePattern)
+		}
+		if !re.MatchString(name) {
+			return &UsernamePolicyError{
+				Username: name,
+				Reason:   fmt.Sprintf("does not match %q", pattern),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package usermanager
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/core/totp"
+)
+
+// totpIssuer identifies the controller in users' authenticator
+// applications, along with the controller and user names.
+const totpIssuer = "juju"
+
+// EnrollTOTP starts enrolling an authenticator application as a second
+// factor for the calling user, returning the secret to provision it with.
+// The enrollment is completed by VerifyTOTP. Users can only enroll their
+// own second factor.
+func (api *UserManagerAPI) EnrollTOTP(args params.Entities) (params.TOTPEnrollmentResults, error) {
+	result := params.TOTPEnrollmentResults{
+		Results: make([]params.TOTPEnrollmentResult, len(args.Entities)),
+	}
+	if err := api.check.ChangeAllowed(); err != nil {
+		return result, errors.Trace(err)
+	}
+	if len(args.Entities) == 0 {
+		return result, nil
+	}
+	cfg, err := api.state.ControllerConfig()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+
+	for i, arg := range args.Entities {
+		enrollment, err := api.enrollTOTP(arg.Tag, cfg.ControllerName())
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		result.Results[i].Result = enrollment
+	}
+	return result, nil
+}
+
+func (api *UserManagerAPI) enrollTOTP(tag, controllerName string) (*params.TOTPEnrollment, error) {
+	user, err := api.getUser(tag)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if api.apiUser != user.UserTag() {
+		return nil, errors.Trace(common.ErrPerm)
+	}
+	secret, err := user.StartTOTPEnrollment()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	account := user.Name() + "@" + controllerName
+	return &params.TOTPEnrollment{
+		Secret:          totp.EncodeSecret(secret),
+		ProvisioningURI: totp.ProvisioningURI(totpIssuer, account, secret),
+	}, nil
+}
+
+// VerifyTOTP completes the enrollment started by EnrollTOTP, given a
+// code from the user's authenticator application. It returns recovery
+// codes that can each be used once in place of a code, which cannot be
+// retrieved again.
+func (api *UserManagerAPI) VerifyTOTP(args params.VerifyTOTPArgs) (params.RecoveryCodesResults, error) {
+	result := params.RecoveryCodesResults{
+		Results: make([]params.RecoveryCodesResult, len(args.Args)),
+	}
+	if err := api.check.ChangeAllowed(); err != nil {
+		return result, errors.Trace(err)
+	}
+
+	for i, arg := range args.Args {
+		codes, err := api.verifyTOTP(arg)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		result.Results[i].RecoveryCodes = codes
+	}
+	return result, nil
+}
+
+func (api *UserManagerAPI) verifyTOTP(arg params.VerifyTOTPArg) ([]string, error) {
+	user, err := api.getUser(arg.Tag)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if api.apiUser != user.UserTag() {
+		return nil, errors.Trace(common.ErrPerm)
+	}
+	codes, err := user.CompleteTOTPEnrollment(arg.Code)
+	return codes, errors.Trace(err)
+}
+
+// RemoveTOTP removes the second factor enrolled by each user, so that
+// they can log in with only their password. Users can remove their own
+// second factor, and controller superusers that of any user.
+func (api *UserManagerAPI) RemoveTOTP(args params.Entities) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Entities)),
+	}
+	if err := api.check.ChangeAllowed(); err != nil {
+		return result, errors.Trace(err)
+	}
+	if len(args.Entities) == 0 {
+		return result, nil
+	}
+	isSuperUser, err := api.hasControllerAdminAccess()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+
+	for i, arg := range args.Entities {
+		user, err := api.getUser(arg.Tag)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		if api.apiUser != user.UserTag() && !isSuperUser {
+			result.Results[i].Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+		if err := user.RemoveSecondFactor(); err != nil {
+			result.Results[i].Error = common.ServerError(err)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package usermanager
+
+import (
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+)
+
+// sessionRegistry records the live sessions of the
+// users logged in to the API server.
+type sessionRegistry interface {
+	UserSessions(names.UserTag) []params.Session
+	Session(sessionID string) (params.Session, error)
+	TerminateSession(sessionID string) error
+}
+
+// ListSessions returns the live sessions of each of the specified users
+// on the API server the client is connected to, with the address each
+// session comes from, the agent string of its client, and the model it
+// is connected to. Controller superusers may list the sessions of any
+// user; other users only their own.
+func (api *UserManagerAPI) ListSessions(args params.Entities) (params.SessionsResults, error) {
+	result := params.SessionsResults{
+		Results: make([]params.SessionsResult, len(args.Entities)),
+	}
+	if len(args.Entities) == 0 {
+		return result, nil
+	}
+	registry, err := api.sessionRegistry()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+
+	for i, arg := range args.Entities {
+		userTag, err := names.ParseUserTag(arg.Tag)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		if !api.isAdmin && !api.authorizer.AuthOwner(userTag) {
+			result.Results[i].Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+		result.Results[i].Sessions = registry.UserSessions(userTag)
+	}
+	return result, nil
+}
+
+// TerminateSession closes the connections of the specified live sessions
+// on the API server the client is connected to, logging their users out.
+// Controller superusers may terminate the sessions of any user; other
+// users only their own.
+func (api *UserManagerAPI) TerminateSession(args params.TerminateSessionArgs) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.SessionIDs)),
+	}
+	if len(args.SessionIDs) == 0 {
+		return result, nil
+	}
+	registry, err := api.sessionRegistry()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+
+	for i, sessionID := range args.SessionIDs {
+		err := api.terminateSession(registry, sessionID)
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
+func (api *UserManagerAPI) terminateSession(registry sessionRegistry, sessionID string) error {
+	session, err := registry.Session(sessionID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	userTag, err := names.ParseUserTag(session.UserTag)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !api.isAdmin && !api.authorizer.AuthOwner(userTag) {
+		return common.ErrPerm
+	}
+	logger.Infof("%s terminating session %s of %s", api.apiUser.Id(), sessionID, userTag.Id())
+	return errors.Trace(registry.TerminateSession(sessionID))
+}
+
+// sessionRegistry returns the registry of the
+// sessions of users logged in to the API server.
+func (api *UserManagerAPI) sessionRegistry() (sessionRegistry, error) {
+	resource, _ := api.resources.Get("sessionRegistry").(common.ValueResource)
+	registry, ok := resource.Value.(sessionRegistry)
+	if !ok {
+		return nil, errors.New("session registry not available")
+	}
+	return registry, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package usermanager
+
+import (
+	"sort"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+)
+
+// TransferCredential transfers the ownership of each of the specified
+// cloud credentials to another user, without its content having to be
+// uploaded again, so that the credentials of users that are disabled or
+// removed remain usable. Models that use a credential are updated to use
+// the transferred credential, and are reported in the results. Only
+// controller superusers may transfer credentials.
+func (api *UserManagerAPI) TransferCredential(args params.TransferCredentialArgs) (params.TransferCredentialResults, error) {
+	result := params.TransferCredentialResults{
+		Results: make([]params.TransferCredentialResult, len(args.Credentials)),
+	}
+	if err := api.check.ChangeAllowed(); err != nil {
+		return result, errors.Trace(err)
+	}
+	isSuperUser, err := api.hasControllerAdminAccess()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	if !isSuperUser {
+		return result, common.ErrPerm
+	}
+
+	for i, arg := range args.Credentials {
+		credentialTag, err := names.ParseCloudCredentialTag(arg.CredentialTag)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		newOwner, err := names.ParseUserTag(arg.NewOwnerTag)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		newTag, models, err := api.state.TransferCloudCredential(credentialTag, newOwner)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		result.Results[i].CredentialTag = newTag.String()
+		for uuid, name := range models {
+			result.Results[i].Models = append(result.Results[i].Models, params.TransferredCredentialModel{
+				ModelUUID: uuid,
+				ModelName: name,
+			})
+		}
+		sort.Slice(result.Results[i].Models, func(a, b int) bool {
+			return result.Results[i].Models[a].ModelName < result.Results[i].Models[b].ModelName
+		})
+	}
+	return result, nil
+}
@@ -4,6 +4,9 @@
 package usermanager
 
 import (
+	"encoding/base64"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/juju/errors"
@@ -13,6 +16,7 @@ import (
 	"github.com/juju/juju/apiserver/common"
 	"github.com/juju/juju/apiserver/facade"
 	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/controller"
 	"github.com/juju/juju/core/permission"
 	"github.com/juju/juju/state"
 )
@@ -21,8 +25,32 @@ var logger = loggo.GetLogger("juju.apiserver.usermanager")
 
 // UserManagerAPI implements the user manager interface and is the concrete
 // implementation of the api end point.
+// Version 3 adds ListUsers.
+// Version 4 adds GrantTemporaryAccess.
+// Version 5 adds SetUserDefaults.
+// Version 6 adds UserNotifications.
+// Version 7 adds PreviewUsername.
+// Version 8 adds ExportPermissions and ImportPermissions.
+// Version 9 adds RequestAccess, ListAccessRequests, ApproveAccess
+// and DenyAccess.
+// Version 10 adds EnrollTOTP, VerifyTOTP and RemoveTOTP.
+// Version 11 adds PermissionWebhookDeadLetters and
+// RetryPermissionWebhookEvents.
+// Version 12 adds LoginActivity.
+// Version 13 adds TransferCredential.
+// Version 14 adds PasswordExpiry.
+// Version 15 adds UpdateUserLabels, and label filtering to ListUsers
+// and ExportPermissions.
+// Version 16 adds GrantModelAccess, and model access to AddUser, so
+// that user administration can be delegated within models.
+// Version 17 adds StartDeviceLogin and PollDeviceLogin.
+// Version 18 adds ListSessions and TerminateSession.
+// Version 19 adds GuestAccess, EnableGuestAccess and DisableGuestAccess.
+// Version 20 adds Impersonate.
+// Version 21 adds AccessReport and AccessReportSchema.
 type UserManagerAPI struct {
 	state      *state.State
+	resources  facade.Resources
 	authorizer facade.Authorizer
 	check      *common.BlockChecker
 	apiUser    names.UserTag
@@ -35,6 +63,16 @@ func NewUserManagerAPI(
 	resources facade.Resources,
 	authorizer facade.Authorizer,
 ) (*UserManagerAPI, error) {
+	if authorizer.GetAuthTag() == nil {
+		// Anonymous connections can only call the device login
+		// methods, which establish the user's identity themselves.
+		return &UserManagerAPI{
+			state:      st,
+			resources:  resources,
+			authorizer: authorizer,
+			check:      common.NewBlockChecker(st),
+		}, nil
+	}
 	if !authorizer.AuthClient() {
 		return nil, common.ErrPerm
 	}
@@ -51,6 +89,7 @@ func NewUserManagerAPI(
 
 	return &UserManagerAPI{
 		state:      st,
+		resources:  resources,
 		authorizer: authorizer,
 		check:      common.NewBlockChecker(st),
 		apiUser:    apiUser,
@@ -58,6 +97,490 @@ func NewUserManagerAPI(
 	}, nil
 }
 
+// UserManagerAPIV20 implements version 20 of the user manager API,
+// which adds Impersonate.
+type UserManagerAPIV20 struct {
+	*UserManagerAPI
+}
+
+// UserManagerAPIV19 implements version 19 of the user manager API,
+// which adds GuestAccess, EnableGuestAccess and DisableGuestAccess.
+type UserManagerAPIV19 struct {
+	*UserManagerAPIV20
+}
+
+// UserManagerAPIV18 implements version 18 of the user manager API,
+// which adds ListSessions and TerminateSession.
+type UserManagerAPIV18 struct {
+	*UserManagerAPIV19
+}
+
+// UserManagerAPIV17 implements version 17 of the user manager API,
+// which adds StartDeviceLogin and PollDeviceLogin.
+type UserManagerAPIV17 struct {
+	*UserManagerAPIV18
+}
+
+// UserManagerAPIV16 implements version 16 of the user manager API,
+// which adds GrantModelAccess, and model access to AddUser.
+type UserManagerAPIV16 struct {
+	*UserManagerAPIV17
+}
+
+// UserManagerAPIV15 implements version 15 of the user manager API,
+// which adds UpdateUserLabels, and label filtering to ListUsers and
+// ExportPermissions.
+type UserManagerAPIV15 struct {
+	*UserManagerAPIV16
+}
+
+// UserManagerAPIV14 implements version 14 of the user manager API,
+// which adds PasswordExpiry.
+type UserManagerAPIV14 struct {
+	*UserManagerAPIV15
+}
+
+// UserManagerAPIV13 implements version 13 of the user manager API,
+// which adds TransferCredential.
+type UserManagerAPIV13 struct {
+	*UserManagerAPIV14
+}
+
+// UserManagerAPIV12 implements version 12 of the user manager API,
+// which adds LoginActivity.
+type UserManagerAPIV12 struct {
+	*UserManagerAPIV13
+}
+
+// UserManagerAPIV11 implements version 11 of the user manager API,
+// which adds PermissionWebhookDeadLetters and RetryPermissionWebhookEvents.
+type UserManagerAPIV11 struct {
+	*UserManagerAPIV12
+}
+
+// UserManagerAPIV10 implements version 10 of the user manager API,
+// which adds EnrollTOTP, VerifyTOTP and RemoveTOTP.
+type UserManagerAPIV10 struct {
+	*UserManagerAPIV11
+}
+
+// UserManagerAPIV9 implements version 9 of the user manager API,
+// which adds RequestAccess, ListAccessRequests, ApproveAccess
+// and DenyAccess.
+type UserManagerAPIV9 struct {
+	*UserManagerAPIV10
+}
+
+// UserManagerAPIV8 implements version 8 of the user manager API,
+// which adds ExportPermissions and ImportPermissions.
+type UserManagerAPIV8 struct {
+	*UserManagerAPIV9
+}
+
+// UserManagerAPIV7 implements version 7 of the user manager API,
+// which adds PreviewUsername.
+type UserManagerAPIV7 struct {
+	*UserManagerAPIV8
+}
+
+// UserManagerAPIV6 implements version 6 of the user manager API,
+// which adds UserNotifications.
+type UserManagerAPIV6 struct {
+	*UserManagerAPIV7
+}
+
+// UserManagerAPIV5 implements version 5 of the user manager API,
+// which adds SetUserDefaults.
+type UserManagerAPIV5 struct {
+	*UserManagerAPIV6
+}
+
+// UserManagerAPIV4 implements version 4 of the user manager API,
+// which adds GrantTemporaryAccess.
+type UserManagerAPIV4 struct {
+	*UserManagerAPIV5
+}
+
+// UserManagerAPIV3 implements version 3 of the user manager API,
+// which adds ListUsers.
+type UserManagerAPIV3 struct {
+	*UserManagerAPIV4
+}
+
+// UserManagerAPIV2 implements version 2 of the user manager API,
+// which adds ResetPassword.
+type UserManagerAPIV2 struct {
+	*UserManagerAPIV3
+}
+
+// NewUserManagerAPIV20 provides the signature required for
+// facade registration of version 20.
+func NewUserManagerAPIV20(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV20, error) {
+	api, err := NewUserManagerAPI(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV20{api}, nil
+}
+
+// NewUserManagerAPIV19 provides the signature required for
+// facade registration of version 19.
+func NewUserManagerAPIV19(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV19, error) {
+	api, err := NewUserManagerAPIV20(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV19{api}, nil
+}
+
+// NewUserManagerAPIV18 provides the signature required for
+// facade registration of version 18.
+func NewUserManagerAPIV18(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV18, error) {
+	api, err := NewUserManagerAPIV19(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV18{api}, nil
+}
+
+// NewUserManagerAPIV17 provides the signature required for
+// facade registration of version 17.
+func NewUserManagerAPIV17(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV17, error) {
+	api, err := NewUserManagerAPIV18(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV17{api}, nil
+}
+
+// NewUserManagerAPIV16 provides the signature required for
+// facade registration of version 16.
+func NewUserManagerAPIV16(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV16, error) {
+	if authorizer.GetAuthTag() == nil {
+		return nil, common.ErrPerm
+	}
+	api, err := NewUserManagerAPIV17(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV16{api}, nil
+}
+
+// NewUserManagerAPIV15 provides the signature required for
+// facade registration of version 15.
+func NewUserManagerAPIV15(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV15, error) {
+	api, err := NewUserManagerAPIV16(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV15{api}, nil
+}
+
+// NewUserManagerAPIV14 provides the signature required for
+// facade registration of version 14.
+func NewUserManagerAPIV14(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV14, error) {
+	api, err := NewUserManagerAPIV15(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV14{api}, nil
+}
+
+// NewUserManagerAPIV13 provides the signature required for
+// facade registration of version 13.
+func NewUserManagerAPIV13(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV13, error) {
+	api, err := NewUserManagerAPIV14(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV13{api}, nil
+}
+
+// NewUserManagerAPIV12 provides the signature required for
+// facade registration of version 12.
+func NewUserManagerAPIV12(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV12, error) {
+	api, err := NewUserManagerAPIV13(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV12{api}, nil
+}
+
+// NewUserManagerAPIV11 provides the signature required for
+// facade registration of version 11.
+func NewUserManagerAPIV11(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV11, error) {
+	api, err := NewUserManagerAPIV12(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV11{api}, nil
+}
+
+// NewUserManagerAPIV10 provides the signature required for
+// facade registration of version 10.
+func NewUserManagerAPIV10(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV10, error) {
+	api, err := NewUserManagerAPIV11(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV10{api}, nil
+}
+
+// NewUserManagerAPIV9 provides the signature required for
+// facade registration of version 9.
+func NewUserManagerAPIV9(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV9, error) {
+	api, err := NewUserManagerAPIV10(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV9{api}, nil
+}
+
+// NewUserManagerAPIV8 provides the signature required for
+// facade registration of version 8.
+func NewUserManagerAPIV8(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV8, error) {
+	api, err := NewUserManagerAPIV9(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV8{api}, nil
+}
+
+// NewUserManagerAPIV7 provides the signature required for
+// facade registration of version 7.
+func NewUserManagerAPIV7(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV7, error) {
+	api, err := NewUserManagerAPIV8(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV7{api}, nil
+}
+
+// NewUserManagerAPIV6 provides the signature required for
+// facade registration of version 6.
+func NewUserManagerAPIV6(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV6, error) {
+	api, err := NewUserManagerAPIV7(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV6{api}, nil
+}
+
+// NewUserManagerAPIV5 provides the signature required for
+// facade registration of version 5.
+func NewUserManagerAPIV5(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV5, error) {
+	api, err := NewUserManagerAPIV6(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV5{api}, nil
+}
+
+// NewUserManagerAPIV4 provides the signature required for
+// facade registration of version 4.
+func NewUserManagerAPIV4(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV4, error) {
+	api, err := NewUserManagerAPIV5(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV4{api}, nil
+}
+
+// NewUserManagerAPIV3 provides the signature required for
+// facade registration of version 3.
+func NewUserManagerAPIV3(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV3, error) {
+	api, err := NewUserManagerAPIV4(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV3{api}, nil
+}
+
+// NewUserManagerAPIV2 provides the signature required for
+// facade registration of versions 1 and 2.
+func NewUserManagerAPIV2(
+	st *state.State,
+	resources facade.Resources,
+	authorizer facade.Authorizer,
+) (*UserManagerAPIV2, error) {
+	api, err := NewUserManagerAPIV3(st, resources, authorizer)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UserManagerAPIV2{api}, nil
+}
+
+// AccessReport isn't on the v20 API.
+func (api *UserManagerAPIV20) AccessReport(_, _ struct{}) {}
+
+// AccessReportSchema isn't on the v20 API.
+func (api *UserManagerAPIV20) AccessReportSchema(_, _ struct{}) {}
+
+// Impersonate isn't on the v19 API.
+func (api *UserManagerAPIV19) Impersonate(_, _ struct{}) {}
+
+// GuestAccess isn't on the v18 API.
+func (api *UserManagerAPIV18) GuestAccess(_, _ struct{}) {}
+
+// EnableGuestAccess isn't on the v18 API.
+func (api *UserManagerAPIV18) EnableGuestAccess(_, _ struct{}) {}
+
+// DisableGuestAccess isn't on the v18 API.
+func (api *UserManagerAPIV18) DisableGuestAccess(_, _ struct{}) {}
+
+// ListSessions isn't on the v17 API.
+func (api *UserManagerAPIV17) ListSessions(_, _ struct{}) {}
+
+// TerminateSession isn't on the v17 API.
+func (api *UserManagerAPIV17) TerminateSession(_, _ struct{}) {}
+
+// StartDeviceLogin isn't on the v16 API.
+func (api *UserManagerAPIV16) StartDeviceLogin(_, _ struct{}) {}
+
+// PollDeviceLogin isn't on the v16 API.
+func (api *UserManagerAPIV16) PollDeviceLogin(_, _ struct{}) {}
+
+// GrantModelAccess isn't on the v15 API.
+func (api *UserManagerAPIV15) GrantModelAccess(_, _ struct{}) {}
+
+// UpdateUserLabels isn't on the v14 API.
+func (api *UserManagerAPIV14) UpdateUserLabels(_, _ struct{}) {}
+
+// ExportPermissions returns a document describing the access granted
+// to every user of the controller. The v14 API does not take arguments.
+func (api *UserManagerAPIV14) ExportPermissions() (params.PermissionsDocument, error) {
+	return api.UserManagerAPI.ExportPermissions(params.ExportPermissionsArgs{})
+}
+
+// PasswordExpiry isn't on the v13 API.
+func (api *UserManagerAPIV13) PasswordExpiry(_, _ struct{}) {}
+
+// TransferCredential isn't on the v12 API.
+func (api *UserManagerAPIV12) TransferCredential(_, _ struct{}) {}
+
+// LoginActivity isn't on the v11 API.
+func (api *UserManagerAPIV11) LoginActivity(_, _ struct{}) {}
+
+// PermissionWebhookDeadLetters isn't on the v10 API.
+func (api *UserManagerAPIV10) PermissionWebhookDeadLetters(_, _ struct{}) {}
+
+// RetryPermissionWebhookEvents isn't on the v10 API.
+func (api *UserManagerAPIV10) RetryPermissionWebhookEvents(_, _ struct{}) {}
+
+// EnrollTOTP isn't on the v9 API.
+func (api *UserManagerAPIV9) EnrollTOTP(_, _ struct{}) {}
+
+// VerifyTOTP isn't on the v9 API.
+func (api *UserManagerAPIV9) VerifyTOTP(_, _ struct{}) {}
+
+// RemoveTOTP isn't on the v9 API.
+func (api *UserManagerAPIV9) RemoveTOTP(_, _ struct{}) {}
+
+// RequestAccess isn't on the v8 API.
+func (api *UserManagerAPIV8) RequestAccess(_, _ struct{}) {}
+
+// ListAccessRequests isn't on the v8 API.
+func (api *UserManagerAPIV8) ListAccessRequests(_, _ struct{}) {}
+
+// ApproveAccess isn't on the v8 API.
+func (api *UserManagerAPIV8) ApproveAccess(_, _ struct{}) {}
+
+// DenyAccess isn't on the v8 API.
+func (api *UserManagerAPIV8) DenyAccess(_, _ struct{}) {}
+
+// ExportPermissions isn't on the v7 API.
+func (api *UserManagerAPIV7) ExportPermissions(_, _ struct{}) {}
+
+// ImportPermissions isn't on the v7 API.
+func (api *UserManagerAPIV7) ImportPermissions(_, _ struct{}) {}
+
+// PreviewUsername isn't on the v6 API.
+func (api *UserManagerAPIV6) PreviewUsername(_, _ struct{}) {}
+
+// UserNotifications isn't on the v5 API.
+func (api *UserManagerAPIV5) UserNotifications(_, _ struct{}) {}
+
+// SetUserDefaults isn't on the v4 API.
+func (api *UserManagerAPIV4) SetUserDefaults(_, _ struct{}) {}
+
+// GrantTemporaryAccess isn't on the v3 API.
+func (api *UserManagerAPIV3) GrantTemporaryAccess(_, _ struct{}) {}
+
+// ListUsers isn't on the v2 API.
+func (api *UserManagerAPIV2) ListUsers(_, _ struct{}) {}
+
 func (api *UserManagerAPI) hasControllerAdminAccess() (bool, error) {
 	isAdmin, err := api.authorizer.HasPermission(permission.SuperuserAccess, api.state.ControllerTag())
 	if errors.IsNotFound(err) {
@@ -67,7 +590,12 @@ func (api *UserManagerAPI) hasControllerAdminAccess() (bool, error) {
 }
 
 // AddUser adds a user with a username, and either a password or
-// a randomly generated secret key which will be returned.
+// a randomly generated secret key which will be returned, granting
+// the user access to any models specified.
+// Usernames not allowed by the username policy in the controller
+// config are rejected with a UsernamePolicyError.
+// Users granted user-admin access to models may add users, but must
+// grant them access lower than their own to at least one of those models.
 func (api *UserManagerAPI) AddUser(args params.AddUsers) (params.AddUserResults, error) {
 	var result params.AddUserResults
 
@@ -82,17 +610,30 @@ func (api *UserManagerAPI) AddUser(args params.AddUsers) (params.AddUserResults,
 	// Create the results list to populate.
 	result.Results = make([]params.AddUserResult, len(args.Users))
 
-	isSuperUser, err := api.hasControllerAdminAccess()
+	scope, err := api.userAdminScope()
 	if err != nil {
 		return result, errors.Trace(err)
 	}
-	if !isSuperUser {
+	if scope.empty() {
 		return result, common.ErrPerm
 	}
 
+	cfg, err := api.state.ControllerConfig()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+
 	for i, arg := range args.Users {
+		if err := checkUsernamePolicy(cfg, arg.Username); err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		modelTags, modelAccess, err := scope.addUserModelAccess(arg)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
 		var user *state.User
-		var err error
 		if arg.Password != "" {
 			user, err = api.state.AddUser(arg.Username, arg.DisplayName, arg.Password, api.apiUser.Id())
 		} else {
@@ -107,12 +648,68 @@ func (api *UserManagerAPI) AddUser(args params.AddUsers) (params.AddUserResults,
 				Tag:       user.Tag().String(),
 				SecretKey: user.SecretKey(),
 			}
+			api.notify(UserCreatedEvent, user.UserTag())
+		}
+		for _, modelTag := range modelTags {
+			if err := api.grantModelAccess(user.UserTag(), modelTag, modelAccess); err != nil {
+				// The user has been added, so the result
+				// still reports the user's tag and key.
+				err = errors.Annotatef(err, "granting access to model %q", modelTag.Id())
+				result.Results[i].Error = common.ServerError(err)
+				break
+			}
 		}
 
 	}
 	return result, nil
 }
 
+// PreviewUsername reports, for each of the usernames, whether a local
+// user could be added with that name: that the name is valid, that it is
+// allowed by the username policy in the controller config, and that there
+// is no such user already. This allows tooling to validate names before
+// calling AddUser.
+func (api *UserManagerAPI) PreviewUsername(args params.PreviewUsernames) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Usernames)),
+	}
+	isSuperUser, err := api.hasControllerAdminAccess()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	if !isSuperUser {
+		return result, common.ErrPerm
+	}
+
+	cfg, err := api.state.ControllerConfig()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	for i, name := range args.Usernames {
+		result.Results[i].Error = common.ServerError(api.previewUsername(cfg, name))
+	}
+	return result, nil
+}
+
+func (api *UserManagerAPI) previewUsername(cfg controller.Config, name string) error {
+	if !names.IsValidUserName(name) {
+		return errors.NotValidf("username %q", name)
+	}
+	if err := checkUsernamePolicy(cfg, name); err != nil {
+		return errors.Trace(err)
+	}
+	// Deleted users are kept for auditing, so their names
+	// cannot be reused.
+	_, err := api.state.User(names.NewLocalUserTag(name))
+	if _, deleted := errors.Cause(err).(state.DeletedUserError); err == nil || deleted {
+		return errors.AlreadyExistsf("user %q", name)
+	}
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return errors.Trace(err)
+}
+
 // RemoveUser permanently removes a user from the current controller for each
 // entity provided. While the user is permanently removed we keep it's
 // information around for auditing purposes.
@@ -184,38 +781,42 @@ func (api *UserManagerAPI) getUser(tag string) (*state.User, error) {
 // EnableUser enables one or more users.  If the user is already enabled,
 // the action is considered a success.
 func (api *UserManagerAPI) EnableUser(users params.Entities) (params.ErrorResults, error) {
-	isSuperUser, err := api.hasControllerAdminAccess()
+	scope, err := api.userAdminScope()
 	if err != nil {
 		return params.ErrorResults{}, errors.Trace(err)
 	}
-	if !isSuperUser {
+	if scope.empty() {
 		return params.ErrorResults{}, common.ErrPerm
 	}
 
 	if err := api.check.ChangeAllowed(); err != nil {
 		return params.ErrorResults{}, errors.Trace(err)
 	}
-	return api.enableUserImpl(users, "enable", (*state.User).Enable)
+	return api.enableUserImpl(users, scope, "enable", (*state.User).Enable, "")
 }
 
 // DisableUser disables one or more users.  If the user is already disabled,
 // the action is considered a success.
 func (api *UserManagerAPI) DisableUser(users params.Entities) (params.ErrorResults, error) {
-	isSuperUser, err := api.hasControllerAdminAccess()
+	scope, err := api.userAdminScope()
 	if err != nil {
 		return params.ErrorResults{}, errors.Trace(err)
 	}
-	if !isSuperUser {
+	if scope.empty() {
 		return params.ErrorResults{}, common.ErrPerm
 	}
 
 	if err := api.check.ChangeAllowed(); err != nil {
 		return params.ErrorResults{}, errors.Trace(err)
 	}
-	return api.enableUserImpl(users, "disable", (*state.User).Disable)
+	return api.enableUserImpl(users, scope, "disable", (*state.User).Disable, UserDisabledEvent)
 }
 
-func (api *UserManagerAPI) enableUserImpl(args params.Entities, action string, method func(*state.User) error) (params.ErrorResults, error) {
+// enableUserImpl calls method on each of the users within the scope,
+// sending a notification of event for each user if event is not empty.
+func (api *UserManagerAPI) enableUserImpl(
+	args params.Entities, scope userAdminScope, action string, method func(*state.User) error, event string,
+) (params.ErrorResults, error) {
 	var result params.ErrorResults
 
 	if len(args.Entities) == 0 {
@@ -240,9 +841,23 @@ func (api *UserManagerAPI) enableUserImpl(args params.Entities, action string, m
 			result.Results[i].Error = common.ServerError(err)
 			continue
 		}
+		if !scope.superuser {
+			perms, err := api.state.UserPermissions(user.UserTag())
+			if err == nil {
+				err = scope.checkUser(perms)
+			}
+			if err != nil {
+				result.Results[i].Error = common.ServerError(err)
+				continue
+			}
+		}
 		err = method(user)
 		if err != nil {
 			result.Results[i].Error = common.ServerError(errors.Errorf("failed to %s user: %s", action, err))
+			continue
+		}
+		if event != "" {
+			api.notify(event, user.UserTag())
 		}
 	}
 	return result, nil
@@ -256,46 +871,6 @@ func (api *UserManagerAPI) UserInfo(request params.UserInfoRequest) (params.User
 		return results, errors.Trace(err)
 	}
 
-	var accessForUser = func(userTag names.UserTag, result *params.UserInfoResult) {
-		// Lookup the access the specified user has to the controller.
-		access, err := common.GetPermission(api.state.UserPermission, userTag, api.state.ControllerTag())
-		if err == nil {
-			result.Result.Access = string(access)
-		} else if err != nil && !errors.IsNotFound(err) {
-			result.Result = nil
-			result.Error = common.ServerError(err)
-		}
-	}
-
-	var infoForUser = func(user *state.User) params.UserInfoResult {
-		var lastLogin *time.Time
-		userLastLogin, err := user.LastLogin()
-		if err != nil {
-			if !state.IsNeverLoggedInError(err) {
-				logger.Debugf("error getting last login: %v", err)
-			}
-		} else {
-			lastLogin = &userLastLogin
-		}
-		result := params.UserInfoResult{
-			Result: &params.UserInfo{
-				Username:       user.Name(),
-				DisplayName:    user.DisplayName(),
-				CreatedBy:      user.CreatedBy(),
-				DateCreated:    user.DateCreated(),
-				LastConnection: lastLogin,
-				Disabled:       user.IsDisabled(),
-			},
-		}
-		if user.IsDisabled() {
-			// disabled users have no access to the controller.
-			result.Result.Access = string(permission.NoAccess)
-		} else {
-			accessForUser(user.UserTag(), &result)
-		}
-		return result
-	}
-
 	argCount := len(request.Entities)
 	if argCount == 0 {
 		users, err := api.state.AllUsers(request.IncludeDisabled)
@@ -306,7 +881,7 @@ func (api *UserManagerAPI) UserInfo(request params.UserInfoRequest) (params.User
 			if !isAdmin && !api.authorizer.AuthOwner(user.Tag()) {
 				continue
 			}
-			results.Results = append(results.Results, infoForUser(user))
+			results.Results = append(results.Results, api.infoForUser(user))
 		}
 		return results, nil
 	}
@@ -329,7 +904,7 @@ func (api *UserManagerAPI) UserInfo(request params.UserInfoRequest) (params.User
 					Username: userTag.Id(),
 				},
 			}
-			accessForUser(userTag, &result)
+			api.accessForUser(userTag, &result)
 			results.Results = append(results.Results, result)
 			continue
 		}
@@ -338,12 +913,155 @@ func (api *UserManagerAPI) UserInfo(request params.UserInfoRequest) (params.User
 			results.Results = append(results.Results, params.UserInfoResult{Error: common.ServerError(err)})
 			continue
 		}
-		results.Results = append(results.Results, infoForUser(user))
+		results.Results = append(results.Results, api.infoForUser(user))
+	}
+
+	return results, nil
+}
+
+// accessForUser populates the input result with the
+// access the specified user has to the controller.
+func (api *UserManagerAPI) accessForUser(userTag names.UserTag, result *params.UserInfoResult) {
+	access, err := common.GetPermission(api.state.UserPermission, userTag, api.state.ControllerTag())
+	if err == nil {
+		result.Result.Access = string(access)
+	} else if err != nil && !errors.IsNotFound(err) {
+		result.Result = nil
+		result.Error = common.ServerError(err)
+	}
+}
+
+// infoForUser returns the user information for the input local user.
+func (api *UserManagerAPI) infoForUser(user *state.User) params.UserInfoResult {
+	var lastLogin *time.Time
+	userLastLogin, err := user.LastLogin()
+	if err != nil {
+		if !state.IsNeverLoggedInError(err) {
+			logger.Debugf("error getting last login: %v", err)
+		}
+	} else {
+		lastLogin = &userLastLogin
+	}
+	result := params.UserInfoResult{
+		Result: &params.UserInfo{
+			Username:       user.Name(),
+			DisplayName:    user.DisplayName(),
+			CreatedBy:      user.CreatedBy(),
+			DateCreated:    user.DateCreated(),
+			LastConnection: lastLogin,
+			Disabled:       user.IsDisabled(),
+			Defaults:       common.UserDefaultsParams(user.Defaults()),
+			Labels:         user.Labels(),
+		},
+	}
+	if user.IsDisabled() {
+		// disabled users have no access to the controller.
+		result.Result.Access = string(permission.NoAccess)
+	} else {
+		api.accessForUser(user.UserTag(), &result)
+	}
+	return result
+}
+
+const (
+	// defaultListUsersLimit is the page size used
+	// by ListUsers when none is requested.
+	defaultListUsersLimit = 100
+
+	// maxListUsersLimit is the largest page size
+	// that can be requested from ListUsers.
+	maxListUsersLimit = 1000
+)
+
+// ListUsers returns a page of users, filtered by name prefix and labels,
+// and ordered as requested. If there are more users to be read, the results
+// include a continuation token to be supplied in order to get the next page.
+// Users without controller admin access only ever see themselves.
+func (api *UserManagerAPI) ListUsers(request params.ListUsersRequest) (params.ListUsersResults, error) {
+	var results params.ListUsersResults
+	isAdmin, err := api.hasControllerAdminAccess()
+	if err != nil {
+		return results, errors.Trace(err)
 	}
 
+	limit := request.Limit
+	switch {
+	case limit < 0:
+		return results, errors.NotValidf("negative limit %d", limit)
+	case limit == 0:
+		limit = defaultListUsersLimit
+	case limit > maxListUsersLimit:
+		limit = maxListUsersLimit
+	}
+
+	offset, err := decodeUsersContinuation(request.Continuation)
+	if err != nil {
+		return results, errors.Trace(err)
+	}
+
+	if !isAdmin {
+		// Non-admin users can only see themselves, so there is
+		// never more than a single page.
+		if offset > 0 || !strings.HasPrefix(strings.ToLower(api.apiUser.Id()), strings.ToLower(request.NamePrefix)) {
+			return results, nil
+		}
+		user, err := api.state.User(api.apiUser)
+		if err != nil {
+			return results, errors.Trace(err)
+		}
+		if (!user.IsDisabled() || request.IncludeDisabled) && hasLabels(user.Labels(), request.Labels) {
+			results.Results = append(results.Results, api.infoForUser(user))
+		}
+		return results, nil
+	}
+
+	// Read one more user than requested,
+	// to find out whether there is another page.
+	users, err := api.state.UsersPage(state.UsersPageArgs{
+		NamePrefix:         request.NamePrefix,
+		IncludeDeactivated: request.IncludeDisabled,
+		Labels:             request.Labels,
+		SortBy:             state.UserSortField(request.SortBy),
+		Descending:         request.Descending,
+		Offset:             offset,
+		Limit:              limit + 1,
+	})
+	if err != nil {
+		return results, errors.Trace(err)
+	}
+	if len(users) > limit {
+		users = users[:limit]
+		results.Continuation = encodeUsersContinuation(offset + limit)
+	}
+	for _, user := range users {
+		results.Results = append(results.Results, api.infoForUser(user))
+	}
 	return results, nil
 }
 
+// encodeUsersContinuation returns an opaque token
+// identifying the page of users starting at offset.
+func encodeUsersContinuation(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+// decodeUsersContinuation returns the offset identified by the input token.
+// An empty token identifies the first page.
+func decodeUsersContinuation(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, errors.NotValidf("continuation token %q", token)
+	}
+	offset, err := strconv.Atoi(string(raw))
+	if err != nil || offset < 0 {
+		return 0, errors.NotValidf("continuation token %q", token)
+	}
+	return offset, nil
+}
+
 // SetPassword changes the stored password for the specified users.
 func (api *UserManagerAPI) SetPassword(args params.EntityPasswords) (params.ErrorResults, error) {
 	if err := api.check.ChangeAllowed(); err != nil {
@@ -424,9 +1142,174 @@ func (api *UserManagerAPI) ResetPassword(args params.Entities) (params.AddUserRe
 				continue
 			}
 			result.Results[i].SecretKey = key
+			api.notify(PasswordResetEvent, user.UserTag())
 		} else {
 			result.Results[i].Error = common.ServerError(common.ErrPerm)
 		}
 	}
 	return result, nil
 }
+
+// GrantTemporaryAccess grants users access to models for a limited time.
+// Once a grant expires, the access each user had beforehand is restored
+// by the controller. Only controller superusers may grant temporary access.
+func (api *UserManagerAPI) GrantTemporaryAccess(args params.GrantTemporaryAccessRequest) (params.TemporaryAccessResults, error) {
+	var result params.TemporaryAccessResults
+
+	if err := api.check.ChangeAllowed(); err != nil {
+		return result, errors.Trace(err)
+	}
+
+	if len(args.Grants) == 0 {
+		return result, nil
+	}
+
+	isSuperUser, err := api.hasControllerAdminAccess()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	if !isSuperUser {
+		return result, common.ErrPerm
+	}
+
+	result.Results = make([]params.TemporaryAccessResult, len(args.Grants))
+	for i, arg := range args.Grants {
+		grant, err := api.grantTemporaryAccess(arg)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		expires := grant.Expires
+		result.Results[i] = params.TemporaryAccessResult{
+			Expires:        &expires,
+			PreviousAccess: string(grant.PreviousAccess),
+		}
+	}
+	return result, nil
+}
+
+func (api *UserManagerAPI) grantTemporaryAccess(arg params.GrantTemporaryAccess) (state.TemporaryAccessGrant, error) {
+	userTag, err := names.ParseUserTag(arg.UserTag)
+	if err != nil {
+		return state.TemporaryAccessGrant{}, errors.Trace(err)
+	}
+	modelTag, err := names.ParseModelTag(arg.ModelTag)
+	if err != nil {
+		return state.TemporaryAccessGrant{}, errors.Trace(err)
+	}
+	access := permission.Access(arg.Access)
+	if err := permission.ValidateModelAccess(access); err != nil {
+		return state.TemporaryAccessGrant{}, errors.Trace(err)
+	}
+	if arg.TTL <= 0 {
+		return state.TemporaryAccessGrant{}, errors.NotValidf("ttl %v", arg.TTL)
+	}
+	grant, err := api.state.GrantTemporaryModelAccess(userTag, modelTag, access, arg.TTL, api.apiUser)
+	if err != nil {
+		return state.TemporaryAccessGrant{}, errors.Trace(err)
+	}
+	api.queuePermissionEvent(state.PermissionGranted, userTag, modelTag, access)
+	return grant, nil
+}
+
+// SetUserDefaults stores the preferences of the specified users, which
+// are returned to clients when the users log in. Users may only set their
+// own defaults, unless they are a controller superuser.
+func (api *UserManagerAPI) SetUserDefaults(args params.SetUserDefaultsArgs) (params.ErrorResults, error) {
+	var result params.ErrorResults
+
+	if err := api.check.ChangeAllowed(); err != nil {
+		return result, errors.Trace(err)
+	}
+
+	if len(args.Args) == 0 {
+		return result, nil
+	}
+
+	isSuperUser, err := api.hasControllerAdminAccess()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+
+	result.Results = make([]params.ErrorResult, len(args.Args))
+	for i, arg := range args.Args {
+		if err := api.setUserDefaults(arg, isSuperUser); err != nil {
+			result.Results[i].Error = common.ServerError(err)
+		}
+	}
+	return result, nil
+}
+
+func (api *UserManagerAPI) setUserDefaults(arg params.SetUserDefaults, isSuperUser bool) error {
+	user, err := api.getUser(arg.UserTag)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if api.apiUser != user.UserTag() && !isSuperUser {
+		return errors.Trace(common.ErrPerm)
+	}
+	defaults := state.UserDefaults{
+		Timezone:       arg.Defaults.Timezone,
+		DisplayOptions: arg.Defaults.DisplayOptions,
+	}
+	if arg.Defaults.DefaultModelTag != "" {
+		modelTag, err := names.ParseModelTag(arg.Defaults.DefaultModelTag)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		defaults.DefaultModel = modelTag.Id()
+	}
+	return errors.Trace(user.SetDefaults(defaults))
+}
+
+// UserNotifications returns the notifications of events on the accounts of
+// the specified users, with their delivery status. Users may only see the
+// notifications for their own account, unless they are a controller superuser.
+func (api *UserManagerAPI) UserNotifications(args params.Entities) (params.UserNotificationsResults, error) {
+	result := params.UserNotificationsResults{
+		Results: make([]params.UserNotificationsResult, len(args.Entities)),
+	}
+	if len(args.Entities) == 0 {
+		return result, nil
+	}
+
+	isSuperUser, err := api.hasControllerAdminAccess()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+
+	for i, arg := range args.Entities {
+		notifications, err := api.userNotifications(arg.Tag, isSuperUser)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		result.Results[i].Result = notifications
+	}
+	return result, nil
+}
+
+func (api *UserManagerAPI) userNotifications(tag string, isSuperUser bool) ([]params.UserNotification, error) {
+	userTag, err := names.ParseUserTag(tag)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if api.apiUser != userTag && !isSuperUser {
+		return nil, errors.Trace(common.ErrPerm)
+	}
+	notifications, err := api.state.UserNotifications(userTag)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	result := make([]params.UserNotification, len(notifications))
+	for i, n := range notifications {
+		result[i] = params.UserNotification{
+			Event:     n.Event,
+			Channel:   n.Channel,
+			Time:      n.Time,
+			Delivered: n.Delivered,
+			Error:     n.Error,
+		}
+	}
+	return result, nil
+}
@@ -4,21 +4,33 @@
 package usermanager_test
 
 import (
+	"context"
+	"encoding/base32"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"time"
 
 	"github.com/juju/errors"
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 	"gopkg.in/juju/names.v3"
+	"gopkg.in/macaroon-bakery.v2/bakery"
+	"gopkg.in/macaroon.v2"
 
+	"github.com/juju/juju/apiserver/authentication"
 	"github.com/juju/juju/apiserver/common"
 	commontesting "github.com/juju/juju/apiserver/common/testing"
 	"github.com/juju/juju/apiserver/facades/client/controller"
 	"github.com/juju/juju/apiserver/facades/client/usermanager"
 	"github.com/juju/juju/apiserver/params"
 	apiservertesting "github.com/juju/juju/apiserver/testing"
+	"github.com/juju/juju/cloud"
+	jujucontroller "github.com/juju/juju/controller"
 	"github.com/juju/juju/core/permission"
+	"github.com/juju/juju/core/totp"
 	jujutesting "github.com/juju/juju/juju/testing"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/testing/factory"
@@ -508,6 +520,75 @@ func (s *userManagerSuite) TestUserInfoNonControllerAdmin(c *gc.C) {
 	})
 }
 
+func (s *userManagerSuite) listUserNames(c *gc.C, results params.ListUsersResults) []string {
+	var names []string
+	for _, r := range results.Results {
+		c.Assert(r.Error, gc.IsNil)
+		names = append(names, r.Result.Username)
+	}
+	return names
+}
+
+func (s *userManagerSuite) TestListUsersPaged(c *gc.C) {
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "bob"})
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "bobby"})
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "bobcat", Disabled: true})
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "mary"})
+
+	args := params.ListUsersRequest{NamePrefix: "bob", IncludeDisabled: true, Limit: 2}
+	results, err := s.usermanager.ListUsers(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(s.listUserNames(c, results), jc.DeepEquals, []string{"bob", "bobby"})
+	c.Assert(results.Continuation, gc.Not(gc.Equals), "")
+
+	args.Continuation = results.Continuation
+	results, err = s.usermanager.ListUsers(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(s.listUserNames(c, results), jc.DeepEquals, []string{"bobcat"})
+	c.Check(results.Continuation, gc.Equals, "")
+}
+
+func (s *userManagerSuite) TestListUsersSortedDescending(c *gc.C) {
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "bob"})
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "mary"})
+
+	results, err := s.usermanager.ListUsers(params.ListUsersRequest{SortBy: "name", Descending: true})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(s.listUserNames(c, results), jc.DeepEquals, []string{s.adminName, "mary", "bob"})
+	c.Check(results.Continuation, gc.Equals, "")
+}
+
+func (s *userManagerSuite) TestListUsersInvalidArgs(c *gc.C) {
+	_, err := s.usermanager.ListUsers(params.ListUsersRequest{Continuation: "bad token"})
+	c.Check(err, gc.ErrorMatches, `continuation token "bad token" not valid`)
+
+	_, err = s.usermanager.ListUsers(params.ListUsersRequest{Limit: -1})
+	c.Check(err, gc.ErrorMatches, `negative limit -1 not valid`)
+
+	_, err = s.usermanager.ListUsers(params.ListUsersRequest{SortBy: "shoe-size"})
+	c.Check(err, gc.ErrorMatches, `sort field "shoe-size" not valid`)
+}
+
+func (s *userManagerSuite) TestListUsersNonControllerAdmin(c *gc.C) {
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "foobar"})
+	userAardvark := s.Factory.MakeUser(c, &factory.UserParams{Name: "aardvark"})
+
+	authorizer := apiservertesting.FakeAuthorizer{
+		Tag: userAardvark.Tag(),
+	}
+	usermanager, err := usermanager.NewUserManagerAPI(s.State, s.resources, authorizer)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Non admin users can only see themselves.
+	results, err := usermanager.ListUsers(params.ListUsersRequest{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(s.listUserNames(c, results), jc.DeepEquals, []string{"aardvark"})
+
+	results, err = usermanager.ListUsers(params.ListUsersRequest{NamePrefix: "foo"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(results.Results, gc.HasLen, 0)
+}
+
 func (s *userManagerSuite) TestUserInfoEveryonePermission(c *gc.C) {
 	_, err := s.State.AddControllerUser(state.UserAccessSpec{
 		User:      names.NewUserTag("everyone@external"),
@@ -992,3 +1073,1614 @@ func (s *userManagerSuite) TestResetPasswordEmpty(c *gc.C) {
 	c.Assert(err, jc.ErrorIsNil)
 	c.Assert(results.Results, gc.HasLen, 0)
 }
+
+func (s *userManagerSuite) TestGrantTemporaryAccess(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex", NoModelUser: true})
+	args := params.GrantTemporaryAccessRequest{Grants: []params.GrantTemporaryAccess{{
+		UserTag:  alex.Tag().String(),
+		ModelTag: s.Model.ModelTag().String(),
+		Access:   params.ModelAdminAccess,
+		TTL:      time.Hour,
+	}, {
+		UserTag:  alex.Tag().String(),
+		ModelTag: s.Model.ModelTag().String(),
+		Access:   "superuser",
+		TTL:      time.Hour,
+	}, {
+		UserTag:  alex.Tag().String(),
+		ModelTag: s.Model.ModelTag().String(),
+		Access:   params.ModelReadAccess,
+	}}}
+
+	results, err := s.usermanager.GrantTemporaryAccess(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 3)
+	c.Assert(results.Results[0].Error, gc.IsNil)
+	c.Assert(results.Results[0].Expires, gc.NotNil)
+	c.Assert(results.Results[0].PreviousAccess, gc.Equals, "")
+	c.Assert(results.Results[1].Error, gc.ErrorMatches, `"superuser" model access not valid`)
+	c.Assert(results.Results[2].Error, gc.ErrorMatches, `ttl 0s not valid`)
+
+	access, err := s.State.UserAccess(alex.UserTag(), s.Model.ModelTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(access.Access, gc.Equals, permission.AdminAccess)
+}
+
+func (s *userManagerSuite) TestGrantTemporaryAccessNotControllerAdmin(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex", NoModelUser: true})
+	usermanager, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	args := params.GrantTemporaryAccessRequest{Grants: []params.GrantTemporaryAccess{{
+		UserTag:  alex.Tag().String(),
+		ModelTag: s.Model.ModelTag().String(),
+		Access:   params.ModelAdminAccess,
+		TTL:      time.Hour,
+	}}}
+	_, err = usermanager.GrantTemporaryAccess(args)
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+
+	_, err = s.State.UserAccess(alex.UserTag(), s.Model.ModelTag())
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *userManagerSuite) TestBlockGrantTemporaryAccess(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex", NoModelUser: true})
+	s.BlockAllChanges(c, "TestBlockGrantTemporaryAccess")
+
+	args := params.GrantTemporaryAccessRequest{Grants: []params.GrantTemporaryAccess{{
+		UserTag:  alex.Tag().String(),
+		ModelTag: s.Model.ModelTag().String(),
+		Access:   params.ModelAdminAccess,
+		TTL:      time.Hour,
+	}}}
+	_, err := s.usermanager.GrantTemporaryAccess(args)
+	s.AssertBlocked(c, err, "TestBlockGrantTemporaryAccess")
+}
+
+func (s *userManagerSuite) TestSetUserDefaults(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	args := params.SetUserDefaultsArgs{Args: []params.SetUserDefaults{{
+		UserTag: alex.Tag().String(),
+		Defaults: params.UserDefaults{
+			DefaultModelTag: s.Model.ModelTag().String(),
+			Timezone:        "Pacific/Auckland",
+			DisplayOptions:  map[string]string{"format": "yaml"},
+		},
+	}, {
+		UserTag:  alex.Tag().String(),
+		Defaults: params.UserDefaults{Timezone: "Nowhere/Special"},
+	}, {
+		UserTag: "user-nobody",
+	}}}
+
+	results, err := s.usermanager.SetUserDefaults(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 3)
+	c.Assert(results.Results[0].Error, gc.IsNil)
+	c.Assert(results.Results[1].Error, gc.ErrorMatches, `timezone "Nowhere/Special" not valid`)
+	c.Assert(results.Results[2].Error, gc.ErrorMatches, "permission denied")
+
+	info, err := s.usermanager.UserInfo(params.UserInfoRequest{
+		Entities: []params.Entity{{Tag: alex.Tag().String()}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.Results, gc.HasLen, 1)
+	c.Assert(info.Results[0].Result.Defaults, jc.DeepEquals, &params.UserDefaults{
+		DefaultModelTag: s.Model.ModelTag().String(),
+		Timezone:        "Pacific/Auckland",
+		DisplayOptions:  map[string]string{"format": "yaml"},
+	})
+}
+
+func (s *userManagerSuite) TestSetUserDefaultsOtherUserNotControllerAdmin(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	barb := s.Factory.MakeUser(c, &factory.UserParams{Name: "barb"})
+	usermanager, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	results, err := usermanager.SetUserDefaults(params.SetUserDefaultsArgs{Args: []params.SetUserDefaults{{
+		UserTag:  alex.Tag().String(),
+		Defaults: params.UserDefaults{Timezone: "UTC"},
+	}, {
+		UserTag:  barb.Tag().String(),
+		Defaults: params.UserDefaults{Timezone: "UTC"},
+	}}})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 2)
+	c.Assert(results.Results[0].Error, gc.IsNil)
+	c.Assert(results.Results[1].Error, gc.ErrorMatches, "permission denied")
+}
+
+func (s *userManagerSuite) enableNotifications(c *gc.C) {
+	err := s.State.UpdateControllerConfig(map[string]interface{}{
+		jujucontroller.UserNotificationWebhookURL: "https://hooks.example.com/juju",
+		jujucontroller.UserNotificationSMTPServer: "smtp.example.com:25",
+		jujucontroller.UserNotificationEmailFrom:  "juju@example.com",
+		jujucontroller.UserNotificationEmailTo:    "audit@example.com",
+	}, nil)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *userManagerSuite) TestAccountEventNotifications(c *gc.C) {
+	s.enableNotifications(c)
+	var posted []string
+	usermanager.PatchPostWebhook(s, func(url, event, user, by string) error {
+		c.Check(url, gc.Equals, "https://hooks.example.com/juju")
+		c.Check(by, gc.Equals, s.adminName)
+		posted = append(posted, event+" "+user)
+		return nil
+	})
+	var mailed []string
+	s.PatchValue(usermanager.SendMail, func(server, from, to string, msg []byte) error {
+		c.Check(server, gc.Equals, "smtp.example.com:25")
+		c.Check(from, gc.Equals, "juju@example.com")
+		c.Check(to, gc.Equals, "audit@example.com")
+		mailed = append(mailed, string(msg))
+		return errors.New("connection refused")
+	})
+
+	_, err := s.usermanager.AddUser(params.AddUsers{
+		Users: []params.AddUser{{Username: "foobar", Password: "password"}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	foobarTag := names.NewLocalUserTag("foobar")
+	entities := params.Entities{Entities: []params.Entity{{Tag: foobarTag.String()}}}
+	_, err = s.usermanager.ResetPassword(entities)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.usermanager.DisableUser(entities)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.usermanager.EnableUser(entities)
+	c.Assert(err, jc.ErrorIsNil)
+
+	c.Assert(posted, jc.DeepEquals, []string{
+		"user-created foobar",
+		"password-reset foobar",
+		"user-disabled foobar",
+	})
+	c.Assert(mailed, gc.HasLen, 3)
+	c.Assert(mailed[0], jc.Contains, `Subject: Juju user "foobar": user-created`)
+
+	results, err := s.usermanager.UserNotifications(entities)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 1)
+	c.Assert(results.Results[0].Error, gc.IsNil)
+	notifications := results.Results[0].Result
+	c.Assert(notifications, gc.HasLen, 6)
+	var delivered, failed []string
+	for _, n := range notifications {
+		if n.Delivered {
+			c.Check(n.Channel, gc.Equals, "webhook")
+			delivered = append(delivered, n.Event)
+		} else {
+			c.Check(n.Channel, gc.Equals, "email")
+			c.Check(n.Error, gc.Equals, "connection refused")
+			failed = append(failed, n.Event)
+		}
+	}
+	c.Assert(delivered, jc.SameContents, []string{"user-created", "password-reset", "user-disabled"})
+	c.Assert(failed, jc.SameContents, []string{"user-created", "password-reset", "user-disabled"})
+}
+
+func (s *userManagerSuite) TestAccountEventNotificationsNotConfigured(c *gc.C) {
+	usermanager.PatchPostWebhook(s, func(string, string, string, string) error {
+		c.Fatalf("unexpected webhook notification")
+		return nil
+	})
+
+	_, err := s.usermanager.AddUser(params.AddUsers{
+		Users: []params.AddUser{{Username: "foobar", Password: "password"}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	results, err := s.usermanager.UserNotifications(params.Entities{
+		Entities: []params.Entity{{Tag: names.NewLocalUserTag("foobar").String()}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 1)
+	c.Assert(results.Results[0].Error, gc.IsNil)
+	c.Assert(results.Results[0].Result, gc.HasLen, 0)
+}
+
+func (s *userManagerSuite) TestUserNotificationsOtherUserNotControllerAdmin(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	barb := s.Factory.MakeUser(c, &factory.UserParams{Name: "barb"})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	results, err := api.UserNotifications(params.Entities{
+		Entities: []params.Entity{{Tag: alex.Tag().String()}, {Tag: barb.Tag().String()}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 2)
+	c.Assert(results.Results[0].Error, gc.IsNil)
+	c.Assert(results.Results[1].Error, gc.ErrorMatches, "permission denied")
+}
+
+func (s *userManagerSuite) TestAddUserUsernamePolicy(c *gc.C) {
+	err := s.State.UpdateControllerConfig(map[string]interface{}{
+		jujucontroller.UsernamePattern:   "[a-z]+",
+		jujucontroller.MaxUsernameLength: 8,
+	}, nil)
+	c.Assert(err, jc.ErrorIsNil)
+
+	result, err := s.usermanager.AddUser(params.AddUsers{
+		Users: []params.AddUser{
+			{Username: "everyone", Password: "password"},
+			{Username: "foo-bar", Password: "password"},
+			{Username: "foobarbaz", Password: "password"},
+			{Username: "foobar", Password: "password"},
+		},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results, gc.HasLen, 4)
+	c.Check(result.Results[0].Error, gc.ErrorMatches, `username "everyone" not allowed: name is reserved`)
+	c.Check(result.Results[1].Error, gc.ErrorMatches, `username "foo-bar" not allowed: does not match "\[a-z\]\+"`)
+	c.Check(result.Results[2].Error, gc.ErrorMatches, `username "foobarbaz" not allowed: longer than 8 characters`)
+	for _, r := range result.Results[:3] {
+		c.Check(r.Error, jc.Satisfies, params.IsCodeUsernameNotAllowed)
+	}
+	c.Check(result.Results[3].Error, gc.IsNil)
+	c.Check(result.Results[3].Tag, gc.Equals, "user-foobar")
+
+	_, err = s.State.User(names.NewLocalUserTag("everyone"))
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *userManagerSuite) TestPreviewUsername(c *gc.C) {
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+
+	results, err := s.usermanager.PreviewUsername(params.PreviewUsernames{
+		Usernames: []string{"bob", "alex", "admin", "not/valid"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 4)
+	c.Check(results.Results[0].Error, gc.IsNil)
+	c.Check(results.Results[1].Error, gc.ErrorMatches, `user "alex" already exists`)
+	c.Check(results.Results[1].Error, jc.Satisfies, params.IsCodeAlreadyExists)
+	c.Check(results.Results[2].Error, jc.Satisfies, params.IsCodeUsernameNotAllowed)
+	c.Check(results.Results[3].Error, gc.ErrorMatches, `username "not/valid" not valid`)
+
+	// Previewing a name does not add the user.
+	_, err = s.State.User(names.NewLocalUserTag("bob"))
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *userManagerSuite) TestPreviewUsernameNotControllerAdmin(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = api.PreviewUsername(params.PreviewUsernames{Usernames: []string{"bob"}})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
+func (s *userManagerSuite) TestExportPermissions(c *gc.C) {
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "alex", Access: permission.ReadAccess})
+
+	doc, err := s.usermanager.ExportPermissions(params.ExportPermissionsArgs{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(doc.Users, gc.HasLen, 2)
+	c.Assert(doc.Users[0], jc.DeepEquals, params.UserPermissions{
+		Username:   "admin",
+		Controller: "superuser",
+		Models:     map[string]string{s.Model.UUID(): "admin"},
+		Clouds:     map[string]string{s.Model.CloudName(): "admin"},
+	})
+	c.Assert(doc.Users[1], jc.DeepEquals, params.UserPermissions{
+		Username:   "alex",
+		Controller: "login",
+		Models:     map[string]string{s.Model.UUID(): "read"},
+	})
+}
+
+func (s *userManagerSuite) TestExportPermissionsNotControllerAdmin(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = api.ExportPermissions(params.ExportPermissionsArgs{})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
+func (s *userManagerSuite) TestImportPermissions(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex", NoModelUser: true})
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "bob"})
+
+	results, err := s.usermanager.ImportPermissions(params.ImportPermissionsArgs{
+		Users: []params.UserPermissions{{
+			Username:   "alex",
+			Controller: "login",
+			Models:     map[string]string{s.Model.UUID(): "write"},
+		}, {
+			Username:   "bob",
+			Controller: "login",
+			Models:     map[string]string{s.Model.UUID(): "superuser"},
+		}, {
+			Username: "not/valid",
+		}, {
+			Username:   s.adminName,
+			Controller: "login",
+		}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 4)
+	c.Check(results.Results[0].Error, gc.IsNil)
+	c.Check(results.Results[1].Error, gc.ErrorMatches, `models: "`+s.Model.UUID()+`": "superuser" model access not valid`)
+	c.Check(results.Results[2].Error, gc.ErrorMatches, `username "not/valid" not valid`)
+	c.Check(results.Results[3].Error, gc.ErrorMatches, `cannot remove superuser access from "admin"`)
+
+	access, err := s.State.UserAccess(alex.UserTag(), s.Model.ModelTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(access.Access, gc.Equals, permission.WriteAccess)
+}
+
+func (s *userManagerSuite) TestImportPermissionsRevoke(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+
+	results, err := s.usermanager.ImportPermissions(params.ImportPermissionsArgs{
+		Users: []params.UserPermissions{{
+			Username:   "alex",
+			Controller: "login",
+		}},
+		Revoke: true,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.OneError(), jc.ErrorIsNil)
+
+	_, err = s.State.UserAccess(alex.UserTag(), s.Model.ModelTag())
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *userManagerSuite) TestImportPermissionsNotControllerAdmin(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = api.ImportPermissions(params.ImportPermissionsArgs{})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
+func (s *userManagerSuite) TestBlockImportPermissions(c *gc.C) {
+	s.BlockAllChanges(c, "TestBlockImportPermissions")
+	_, err := s.usermanager.ImportPermissions(params.ImportPermissionsArgs{})
+	s.AssertBlocked(c, err, "TestBlockImportPermissions")
+}
+
+func (s *userManagerSuite) TestRequestAndApproveAccess(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex", NoModelUser: true})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	results, err := api.RequestAccess(params.RequestAccessArgs{
+		Requests: []params.RequestAccess{{
+			ModelTag: s.Model.ModelTag().String(),
+			Access:   "write",
+			Reason:   "deploying",
+		}, {
+			ModelTag: s.Model.ModelTag().String(),
+			Access:   "superuser",
+		}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 2)
+	c.Assert(results.Results[0].Error, gc.IsNil)
+	req := results.Results[0].Result
+	c.Assert(req.UserTag, gc.Equals, alex.Tag().String())
+	c.Assert(req.Access, gc.Equals, "write")
+	c.Assert(req.Reason, gc.Equals, "deploying")
+	c.Assert(req.Status, gc.Equals, "pending")
+	c.Assert(results.Results[1].Error, gc.ErrorMatches, `"superuser" model access not valid`)
+
+	// The requesting user can see, but not approve, their own request.
+	listed, err := api.ListAccessRequests()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(listed.Requests, jc.DeepEquals, []params.AccessRequest{*req})
+	decided, err := api.ApproveAccess(params.AccessRequestIDs{IDs: []string{req.ID}})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(decided.OneError(), gc.ErrorMatches, "permission denied")
+
+	listed, err = s.usermanager.ListAccessRequests()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(listed.Requests, gc.HasLen, 1)
+	decided, err = s.usermanager.ApproveAccess(params.AccessRequestIDs{IDs: []string{req.ID}})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(decided.OneError(), jc.ErrorIsNil)
+
+	access, err := s.State.UserAccess(alex.UserTag(), s.Model.ModelTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(access.Access, gc.Equals, permission.WriteAccess)
+
+	listed, err = s.usermanager.ListAccessRequests()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(listed.Requests, gc.HasLen, 0)
+}
+
+func (s *userManagerSuite) TestListAccessRequestsOtherUsers(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex", NoModelUser: true})
+	_, err := s.State.RequestModelAccess(alex.UserTag(), s.Model.ModelTag(), permission.ReadAccess, "")
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Users that cannot decide a request do not see it.
+	bob := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", Access: permission.WriteAccess})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: bob.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+	listed, err := api.ListAccessRequests()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(listed.Requests, gc.HasLen, 0)
+}
+
+func (s *userManagerSuite) TestDenyAccess(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex", NoModelUser: true})
+	req, err := s.State.RequestModelAccess(alex.UserTag(), s.Model.ModelTag(), permission.ReadAccess, "")
+	c.Assert(err, jc.ErrorIsNil)
+
+	results, err := s.usermanager.DenyAccess(params.AccessRequestIDs{IDs: []string{req.ID, "nope"}})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 2)
+	c.Assert(results.Results[0].Error, gc.IsNil)
+	c.Assert(results.Results[1].Error, gc.ErrorMatches, `access request "nope" not found`)
+
+	_, err = s.State.UserAccess(alex.UserTag(), s.Model.ModelTag())
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *userManagerSuite) TestEnrollAndVerifyTOTP(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex", NoModelUser: true})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	enrolled, err := api.EnrollTOTP(params.Entities{
+		Entities: []params.Entity{{Tag: alex.Tag().String()}, {Tag: s.AdminUserTag(c).String()}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(enrolled.Results, gc.HasLen, 2)
+	c.Assert(enrolled.Results[0].Error, gc.IsNil)
+	c.Assert(enrolled.Results[1].Error, gc.ErrorMatches, "permission denied")
+	enrollment := enrolled.Results[0].Result
+	c.Assert(enrollment.ProvisioningURI, gc.Matches, `otpauth://totp/juju:alex@`+jujutesting.ControllerName+`\?.*secret=`+enrollment.Secret+`.*`)
+
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enrollment.Secret)
+	c.Assert(err, jc.ErrorIsNil)
+	code := totp.Code(secret, totp.Step(time.Now()))
+	verified, err := api.VerifyTOTP(params.VerifyTOTPArgs{
+		Args: []params.VerifyTOTPArg{{Tag: alex.Tag().String(), Code: code}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(verified.Results, gc.HasLen, 1)
+	c.Assert(verified.Results[0].Error, gc.IsNil)
+	c.Assert(verified.Results[0].RecoveryCodes, gc.HasLen, 10)
+
+	err = alex.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(alex.SecondFactorEnrolled(), jc.IsTrue)
+}
+
+func (s *userManagerSuite) TestVerifyTOTPInvalidCode(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex", NoModelUser: true})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = alex.StartTOTPEnrollment()
+	c.Assert(err, jc.ErrorIsNil)
+
+	verified, err := api.VerifyTOTP(params.VerifyTOTPArgs{
+		Args: []params.VerifyTOTPArg{{Tag: alex.Tag().String(), Code: "not-a-code"}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(verified.Results, gc.HasLen, 1)
+	c.Assert(verified.Results[0].Error, gc.ErrorMatches, "code not valid")
+}
+
+func (s *userManagerSuite) TestRemoveTOTP(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex", NoModelUser: true})
+	_, err := alex.StartTOTPEnrollment()
+	c.Assert(err, jc.ErrorIsNil)
+	bob := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", NoModelUser: true})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: bob.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Only controller superusers can remove other users' second factors.
+	results, err := api.RemoveTOTP(params.Entities{
+		Entities: []params.Entity{{Tag: alex.Tag().String()}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.OneError(), gc.ErrorMatches, "permission denied")
+
+	results, err = s.usermanager.RemoveTOTP(params.Entities{
+		Entities: []params.Entity{{Tag: alex.Tag().String()}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.OneError(), jc.ErrorIsNil)
+
+	err = alex.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(alex.SecondFactorEnrolled(), jc.IsFalse)
+}
+
+func (s *userManagerSuite) TestBlockEnrollTOTP(c *gc.C) {
+	s.BlockAllChanges(c, "TestBlockEnrollTOTP")
+	_, err := s.usermanager.EnrollTOTP(params.Entities{
+		Entities: []params.Entity{{Tag: s.AdminUserTag(c).String()}},
+	})
+	s.AssertBlocked(c, err, "TestBlockEnrollTOTP")
+}
+
+func (s *userManagerSuite) enablePermissionWebhook(c *gc.C) {
+	err := s.State.UpdateControllerConfig(map[string]interface{}{
+		jujucontroller.PermissionWebhookURL:    "https://audit.example.com/juju",
+		jujucontroller.PermissionWebhookSecret: "sekrit",
+	}, nil)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *userManagerSuite) TestImportPermissionsQueuesPermissionEvents(c *gc.C) {
+	s.enablePermissionWebhook(c)
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex", NoModelUser: true})
+
+	results, err := s.usermanager.ImportPermissions(params.ImportPermissionsArgs{
+		Users: []params.UserPermissions{{
+			Username:   "alex",
+			Controller: "superuser",
+			Models:     map[string]string{s.Model.UUID(): "write"},
+		}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.OneError(), jc.ErrorIsNil)
+
+	events, err := s.State.DuePermissionEvents(time.Now().Add(time.Hour))
+	c.Assert(err, jc.ErrorIsNil)
+	targets := make(map[names.Tag]permission.Access)
+	for _, ev := range events {
+		c.Check(ev.Kind, gc.Equals, state.PermissionGranted)
+		c.Check(ev.User, gc.Equals, alex.UserTag())
+		c.Check(ev.By, gc.Equals, s.AdminUserTag(c))
+		targets[ev.Target] = ev.Access
+	}
+	c.Assert(targets, jc.DeepEquals, map[names.Tag]permission.Access{
+		s.State.ControllerTag(): permission.SuperuserAccess,
+		s.Model.ModelTag():      permission.WriteAccess,
+	})
+}
+
+func (s *userManagerSuite) TestPermissionWebhookDeadLetters(c *gc.C) {
+	s.enablePermissionWebhook(c)
+	err := s.State.QueuePermissionEvent(state.PermissionEvent{
+		Kind:   state.PermissionRevoked,
+		User:   names.NewUserTag("alex"),
+		Target: s.Model.ModelTag(),
+		Access: permission.ReadAccess,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	events, err := s.State.DuePermissionEvents(time.Now().Add(time.Hour))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(events, gc.HasLen, 1)
+	id := events[0].ID
+	err = s.State.PermissionEventFailed(id, "webhook returned 502 Bad Gateway", time.Time{})
+	c.Assert(err, jc.ErrorIsNil)
+
+	dead, err := s.usermanager.PermissionWebhookDeadLetters()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dead.Events, gc.HasLen, 1)
+	c.Check(dead.Events[0], jc.DeepEquals, params.PermissionEvent{
+		ID:        id,
+		Kind:      "revoke",
+		UserTag:   "user-alex",
+		TargetTag: s.Model.ModelTag().String(),
+		Access:    "read",
+		Time:      events[0].Time,
+		Attempts:  1,
+		LastError: "webhook returned 502 Bad Gateway",
+	})
+
+	results, err := s.usermanager.RetryPermissionWebhookEvents(params.PermissionEventIDs{IDs: []string{id, id}})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 2)
+	c.Check(results.Results[0].Error, gc.IsNil)
+	c.Check(results.Results[1].Error, gc.ErrorMatches, `abandoned permission event ".*" not found`)
+
+	dead, err = s.usermanager.PermissionWebhookDeadLetters()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dead.Events, gc.HasLen, 0)
+}
+
+func (s *userManagerSuite) TestPermissionWebhookNotControllerAdmin(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = api.PermissionWebhookDeadLetters()
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+	_, err = api.RetryPermissionWebhookEvents(params.PermissionEventIDs{IDs: []string{"1"}})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
+func (s *userManagerSuite) TestLoginActivity(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	err := s.State.RecordLogin(alex.UserTag(), s.Model.UUID(), "10.0.0.2")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.RecordLogin(alex.UserTag(), s.Model.UUID(), "10.0.0.1")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.RecordLogin(alex.UserTag(), "", "10.0.0.1")
+	c.Assert(err, jc.ErrorIsNil)
+
+	results, err := s.usermanager.LoginActivity(params.LoginActivityArgs{
+		Entities: []params.Entity{{Tag: "user-alex"}, {Tag: "user-nobody"}, {Tag: "machine-0"}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 3)
+	c.Assert(results.Results[0].Error, gc.IsNil)
+	models := results.Results[0].Models
+	c.Assert(models, gc.HasLen, 2)
+	c.Check(models[0].ModelTag, gc.Equals, "")
+	c.Check(models[0].Connections, gc.Equals, 1)
+	c.Check(models[0].Addresses, jc.DeepEquals, []string{"10.0.0.1"})
+	c.Check(models[1].ModelTag, gc.Equals, s.Model.ModelTag().String())
+	c.Check(models[1].Connections, gc.Equals, 2)
+	c.Check(models[1].Addresses, jc.DeepEquals, []string{"10.0.0.1", "10.0.0.2"})
+	c.Check(models[1].LastConnection.IsZero(), jc.IsFalse)
+	c.Check(results.Results[1], jc.DeepEquals, params.LoginActivityResult{Models: []params.ModelLoginActivity{}})
+	c.Check(results.Results[2].Error, gc.ErrorMatches, `"machine-0" is not a valid user tag`)
+}
+
+func (s *userManagerSuite) TestLoginActivityNotControllerAdmin(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	err := s.State.RecordLogin(alex.UserTag(), s.Model.UUID(), "10.0.0.1")
+	c.Assert(err, jc.ErrorIsNil)
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	results, err := api.LoginActivity(params.LoginActivityArgs{
+		Entities: []params.Entity{{Tag: "user-alex"}, {Tag: s.AdminUserTag(c).String()}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 2)
+	c.Check(results.Results[0].Error, gc.IsNil)
+	c.Check(results.Results[0].Models, gc.HasLen, 1)
+	c.Check(results.Results[1].Error, gc.ErrorMatches, "permission denied")
+}
+
+func (s *userManagerSuite) makeCredentialModel(c *gc.C, owner names.UserTag) (names.CloudCredentialTag, string) {
+	credentialTag := names.NewCloudCredentialTag(fmt.Sprintf("dummy/%s/cred", owner.Name()))
+	err := s.State.UpdateCloudCredential(credentialTag, cloud.NewCredential(cloud.UserPassAuthType, map[string]string{
+		"username": "barb",
+		"password": "sekrit",
+	}))
+	c.Assert(err, jc.ErrorIsNil)
+	st := s.Factory.MakeModel(c, &factory.ModelParams{
+		Name:            "barbs-model",
+		Owner:           owner,
+		CloudCredential: credentialTag,
+	})
+	defer st.Close()
+	return credentialTag, st.ModelUUID()
+}
+
+func (s *userManagerSuite) TestTransferCredential(c *gc.C) {
+	barb := s.Factory.MakeUser(c, &factory.UserParams{Name: "barb"})
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	credentialTag, modelUUID := s.makeCredentialModel(c, barb.UserTag())
+
+	results, err := s.usermanager.TransferCredential(params.TransferCredentialArgs{
+		Credentials: []params.TransferCredentialArg{{
+			CredentialTag: credentialTag.String(),
+			NewOwnerTag:   alex.Tag().String(),
+		}, {
+			CredentialTag: "cloudcred-dummy_barb_missing",
+			NewOwnerTag:   alex.Tag().String(),
+		}, {
+			CredentialTag: credentialTag.String(),
+			NewOwnerTag:   "machine-0",
+		}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 3)
+	c.Check(results.Results[0], jc.DeepEquals, params.TransferCredentialResult{
+		CredentialTag: "cloudcred-dummy_alex_cred",
+		Models: []params.TransferredCredentialModel{{
+			ModelUUID: modelUUID,
+			ModelName: "barbs-model",
+		}},
+	})
+	c.Check(results.Results[1].Error, gc.ErrorMatches, `transferring cloud credential "dummy/barb/missing": cloud credential "dummy/barb/missing" not found`)
+	c.Check(results.Results[2].Error, gc.ErrorMatches, `"machine-0" is not a valid user tag`)
+
+	transferred, err := s.State.CloudCredential(names.NewCloudCredentialTag("dummy/alex/cred"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(transferred.Attributes, jc.DeepEquals, map[string]string{
+		"username": "barb",
+		"password": "sekrit",
+	})
+}
+
+func (s *userManagerSuite) TestTransferCredentialNotControllerAdmin(c *gc.C) {
+	barb := s.Factory.MakeUser(c, &factory.UserParams{Name: "barb"})
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	credentialTag, _ := s.makeCredentialModel(c, barb.UserTag())
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: barb.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = api.TransferCredential(params.TransferCredentialArgs{
+		Credentials: []params.TransferCredentialArg{{
+			CredentialTag: credentialTag.String(),
+			NewOwnerTag:   alex.Tag().String(),
+		}},
+	})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+	_, err = s.State.CloudCredential(credentialTag)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *userManagerSuite) TestBlockTransferCredential(c *gc.C) {
+	barb := s.Factory.MakeUser(c, &factory.UserParams{Name: "barb"})
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	credentialTag, _ := s.makeCredentialModel(c, barb.UserTag())
+
+	s.BlockAllChanges(c, "TestBlockTransferCredential")
+	_, err := s.usermanager.TransferCredential(params.TransferCredentialArgs{
+		Credentials: []params.TransferCredentialArg{{
+			CredentialTag: credentialTag.String(),
+			NewOwnerTag:   alex.Tag().String(),
+		}},
+	})
+	s.AssertBlocked(c, err, "TestBlockTransferCredential")
+	_, err = s.State.CloudCredential(credentialTag)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *userManagerSuite) TestPasswordExpiry(c *gc.C) {
+	err := s.State.UpdateControllerConfig(map[string]interface{}{
+		jujucontroller.PasswordMaxAge: "720h",
+	}, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+
+	result, err := s.usermanager.PasswordExpiry(params.PasswordExpiryArgs{Within: 31 * 24 * time.Hour})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.MaxAge, gc.Equals, 720*time.Hour)
+	var found bool
+	for _, user := range result.Users {
+		c.Check(user.Expired, jc.IsFalse)
+		c.Check(user.Expires, gc.Equals, user.PasswordSet.Add(720*time.Hour))
+		if user.UserTag == alex.Tag().String() {
+			found = true
+			c.Check(user.PasswordSet, gc.Equals, alex.PasswordSetTime())
+		}
+	}
+	c.Assert(found, jc.IsTrue)
+
+	result, err = s.usermanager.PasswordExpiry(params.PasswordExpiryArgs{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Users, gc.HasLen, 0)
+}
+
+func (s *userManagerSuite) TestPasswordExpiryNoMaxAge(c *gc.C) {
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	result, err := s.usermanager.PasswordExpiry(params.PasswordExpiryArgs{Within: 31 * 24 * time.Hour})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, params.PasswordExpiryResult{
+		Users: []params.UserPasswordExpiry{},
+	})
+}
+
+func (s *userManagerSuite) TestPasswordExpiryNotControllerAdmin(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = api.PasswordExpiry(params.PasswordExpiryArgs{})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
+func (s *userManagerSuite) TestUpdateUserLabels(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	results, err := s.usermanager.UpdateUserLabels(params.UpdateUserLabelsArgs{
+		Args: []params.UpdateUserLabels{{
+			UserTag: alex.Tag().String(),
+			Set:     map[string]string{"team": "platform", "contractor": "true"},
+		}, {
+			UserTag: alex.Tag().String(),
+			Unset:   []string{"contractor"},
+		}, {
+			UserTag: alex.Tag().String(),
+			Set:     map[string]string{"team.name": "platform"},
+		}, {
+			UserTag: "user-nobody",
+			Set:     map[string]string{"team": "platform"},
+		}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 4)
+	c.Check(results.Results[0].Error, gc.IsNil)
+	c.Check(results.Results[1].Error, gc.IsNil)
+	c.Check(results.Results[2].Error, gc.ErrorMatches, `label key "team.name" not valid`)
+	c.Check(results.Results[3].Error, gc.ErrorMatches, "permission denied")
+
+	info, err := s.usermanager.UserInfo(params.UserInfoRequest{
+		Entities: []params.Entity{{Tag: alex.Tag().String()}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.Results, gc.HasLen, 1)
+	c.Check(info.Results[0].Result.Labels, jc.DeepEquals, map[string]string{"team": "platform"})
+}
+
+func (s *userManagerSuite) TestUpdateUserLabelsNotControllerAdmin(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = api.UpdateUserLabels(params.UpdateUserLabelsArgs{
+		Args: []params.UpdateUserLabels{{
+			UserTag: alex.Tag().String(),
+			Set:     map[string]string{"team": "platform"},
+		}},
+	})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+	err = alex.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(alex.Labels(), gc.IsNil)
+}
+
+func (s *userManagerSuite) makeLabelledUsers(c *gc.C) {
+	for name, labels := range map[string]map[string]string{
+		"alex":  {"team": "platform", "contractor": "true"},
+		"bob":   {"team": "platform"},
+		"carol": {"team": "storage"},
+	} {
+		user := s.Factory.MakeUser(c, &factory.UserParams{Name: name, Access: permission.ReadAccess})
+		err := user.UpdateLabels(labels, nil)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+}
+
+func (s *userManagerSuite) TestListUsersLabels(c *gc.C) {
+	s.makeLabelledUsers(c)
+
+	results, err := s.usermanager.ListUsers(params.ListUsersRequest{
+		Labels: map[string]string{"team": "platform"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(s.listUserNames(c, results), jc.DeepEquals, []string{"alex", "bob"})
+	c.Check(results.Results[0].Result.Labels, jc.DeepEquals, map[string]string{"team": "platform", "contractor": "true"})
+
+	results, err = s.usermanager.ListUsers(params.ListUsersRequest{
+		Labels: map[string]string{"team": "platform", "contractor": "true"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(s.listUserNames(c, results), jc.DeepEquals, []string{"alex"})
+}
+
+func (s *userManagerSuite) TestExportPermissionsLabels(c *gc.C) {
+	s.makeLabelledUsers(c)
+
+	doc, err := s.usermanager.ExportPermissions(params.ExportPermissionsArgs{
+		Labels: map[string]string{"team": "platform"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(doc.Users, jc.DeepEquals, []params.UserPermissions{{
+		Username:   "alex",
+		Labels:     map[string]string{"team": "platform", "contractor": "true"},
+		Controller: "login",
+		Models:     map[string]string{s.Model.UUID(): "read"},
+	}, {
+		Username:   "bob",
+		Labels:     map[string]string{"team": "platform"},
+		Controller: "login",
+		Models:     map[string]string{s.Model.UUID(): "read"},
+	}})
+}
+
+func (s *userManagerSuite) TestExportPermissionsV14(c *gc.C) {
+	s.makeLabelledUsers(c)
+	api, err := usermanager.NewUserManagerAPIV14(s.State, s.resources, s.authorizer)
+	c.Assert(err, jc.ErrorIsNil)
+
+	doc, err := api.ExportPermissions()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(doc.Users, gc.HasLen, 4)
+}
+
+func (s *userManagerSuite) TestAccessReport(c *gc.C) {
+	s.makeLabelledUsers(c)
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "dave", DisplayName: "Dave", NoModelUser: true, Disabled: true})
+
+	report, err := s.usermanager.AccessReport(params.AccessReportArgs{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(report.SchemaVersion, gc.Equals, 1)
+	c.Assert(report.ControllerUUID, gc.Equals, s.State.ControllerUUID())
+	c.Assert(report.Users, gc.HasLen, 5)
+	c.Check(report.Users[0].Username, gc.Equals, "admin")
+	c.Check(report.Users[0].Controller, gc.Equals, "superuser")
+	c.Check(report.Users[0].Models, jc.DeepEquals, map[string]string{s.Model.UUID(): "admin"})
+	c.Check(report.Users[0].Clouds, jc.DeepEquals, map[string]string{s.Model.CloudName(): "admin"})
+	c.Check(report.Users[4], jc.DeepEquals, params.AccessReportUser{
+		Username:    "dave",
+		DisplayName: "Dave",
+		Disabled:    true,
+		Controller:  "login",
+	})
+
+	report, err = s.usermanager.AccessReport(params.AccessReportArgs{
+		Labels: map[string]string{"team": "storage"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(report.Users, gc.HasLen, 1)
+	c.Check(report.Users[0].Username, gc.Equals, "carol")
+	c.Check(report.Users[0].Labels, jc.DeepEquals, map[string]string{"team": "storage"})
+}
+
+func (s *userManagerSuite) TestAccessReportNotControllerAdmin(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = api.AccessReport(params.AccessReportArgs{})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+
+	// The schema is not a secret.
+	schema, err := api.AccessReportSchema()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(schema.Version, gc.Equals, 1)
+}
+
+func (s *userManagerSuite) TestAccessReportSchema(c *gc.C) {
+	schema, err := s.usermanager.AccessReportSchema()
+	c.Assert(err, jc.ErrorIsNil)
+	var doc struct {
+		Properties  map[string]interface{} `json:"properties"`
+		Definitions struct {
+			User struct {
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"user"`
+		} `json:"definitions"`
+	}
+	err = json.Unmarshal([]byte(schema.Schema), &doc)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Every field of a report is described by the schema.
+	data, err := json.Marshal(params.AccessReport{
+		Users: []params.AccessReportUser{{
+			DisplayName: "x",
+			Labels:      map[string]string{"x": "x"},
+			Controller:  "x",
+			Models:      map[string]string{"x": "x"},
+			Clouds:      map[string]string{"x": "x"},
+			Offers:      map[string]string{"x": "x"},
+		}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	var report struct {
+		Users []map[string]interface{} `json:"users"`
+	}
+	var fields map[string]interface{}
+	c.Assert(json.Unmarshal(data, &fields), jc.ErrorIsNil)
+	c.Assert(json.Unmarshal(data, &report), jc.ErrorIsNil)
+	for field := range fields {
+		c.Check(doc.Properties[field], gc.NotNil, gc.Commentf("report field %q", field))
+	}
+	for field := range report.Users[0] {
+		c.Check(doc.Definitions.User.Properties[field], gc.NotNil, gc.Commentf("user field %q", field))
+	}
+}
+
+// makeUserAdmin returns a user manager API for a user granted user-admin
+// access to a new model, and that model's UUID.
+func (s *userManagerSuite) makeUserAdmin(c *gc.C) (*usermanager.UserManagerAPI, string) {
+	st := s.Factory.MakeModel(c, nil)
+	defer st.Close()
+	dana := s.Factory.MakeUser(c, &factory.UserParams{Name: "dana", NoModelUser: true})
+	factory.NewFactory(st, s.StatePool).MakeModelUser(c, &factory.ModelUserParams{
+		User:   dana.Name(),
+		Access: permission.UserAdminAccess,
+	})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: dana.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+	return api, st.ModelUUID()
+}
+
+func (s *userManagerSuite) TestAddUserAsUserAdmin(c *gc.C) {
+	api, modelUUID := s.makeUserAdmin(c)
+
+	result, err := api.AddUser(params.AddUsers{
+		Users: []params.AddUser{{
+			Username:    "foobar",
+			DisplayName: "Foo Bar",
+			Password:    "password",
+			ModelTags:   []string{names.NewModelTag(modelUUID).String()},
+			ModelAccess: params.ModelWriteAccess,
+		}, {
+			Username:    "nomodel",
+			DisplayName: "No Model",
+			Password:    "password",
+		}, {
+			Username:    "outside",
+			DisplayName: "Outside",
+			Password:    "password",
+			ModelTags:   []string{s.Model.ModelTag().String()},
+		}, {
+			Username:    "equal",
+			DisplayName: "Equal",
+			Password:    "password",
+			ModelTags:   []string{names.NewModelTag(modelUUID).String()},
+			ModelAccess: params.ModelUserAdminAccess,
+		}}})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results, gc.HasLen, 4)
+	c.Check(result.Results[0], jc.DeepEquals, params.AddUserResult{
+		Tag: names.NewLocalUserTag("foobar").String(),
+	})
+	for _, r := range result.Results[1:] {
+		c.Check(r.Error, gc.ErrorMatches, "permission denied")
+	}
+
+	perms, err := s.State.UserPermissions(names.NewLocalUserTag("foobar"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(perms.Models, jc.DeepEquals, map[string]permission.Access{
+		modelUUID: permission.WriteAccess,
+	})
+	for _, name := range []string{"nomodel", "outside", "equal"} {
+		_, err = s.State.User(names.NewLocalUserTag(name))
+		c.Check(err, jc.Satisfies, errors.IsNotFound)
+	}
+}
+
+func (s *userManagerSuite) TestDisableUserAsUserAdmin(c *gc.C) {
+	api, modelUUID := s.makeUserAdmin(c)
+	added, err := api.AddUser(params.AddUsers{
+		Users: []params.AddUser{{
+			Username:    "foobar",
+			DisplayName: "Foo Bar",
+			Password:    "password",
+			ModelTags:   []string{names.NewModelTag(modelUUID).String()},
+		}}})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(added.Results[0].Error, gc.IsNil)
+	// barb has access to the controller model, which dana
+	// does not administer.
+	barb := s.Factory.MakeUser(c, &factory.UserParams{Name: "barb"})
+
+	result, err := api.DisableUser(params.Entities{
+		Entities: []params.Entity{
+			{names.NewLocalUserTag("foobar").String()},
+			{barb.Tag().String()},
+			{s.AdminUserTag(c).String()},
+		}})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results, gc.HasLen, 3)
+	c.Check(result.Results[0].Error, gc.IsNil)
+	c.Check(result.Results[1].Error, gc.ErrorMatches, "permission denied")
+	c.Check(result.Results[2].Error, gc.ErrorMatches, "permission denied")
+
+	foobar, err := s.State.User(names.NewLocalUserTag("foobar"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(foobar.IsDisabled(), jc.IsTrue)
+	err = barb.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(barb.IsDisabled(), jc.IsFalse)
+}
+
+func (s *userManagerSuite) TestGrantModelAccessAsUserAdmin(c *gc.C) {
+	api, modelUUID := s.makeUserAdmin(c)
+	barb := s.Factory.MakeUser(c, &factory.UserParams{Name: "barb", NoModelUser: true})
+	modelTag := names.NewModelTag(modelUUID).String()
+
+	result, err := api.GrantModelAccess(params.GrantModelAccessRequest{
+		Grants: []params.GrantModelAccess{{
+			UserTag:  barb.Tag().String(),
+			ModelTag: modelTag,
+			Access:   params.ModelWriteAccess,
+		}, {
+			UserTag:  barb.Tag().String(),
+			ModelTag: modelTag,
+			Access:   params.ModelReadAccess,
+		}, {
+			UserTag:  barb.Tag().String(),
+			ModelTag: modelTag,
+			Access:   params.ModelUserAdminAccess,
+		}, {
+			UserTag:  barb.Tag().String(),
+			ModelTag: s.Model.ModelTag().String(),
+			Access:   params.ModelReadAccess,
+		}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results, gc.HasLen, 4)
+	c.Check(result.Results[0].Error, gc.IsNil)
+	c.Check(result.Results[1].Error, gc.ErrorMatches, `user already has "read" access or greater`)
+	c.Check(result.Results[2].Error, gc.ErrorMatches, "permission denied")
+	c.Check(result.Results[3].Error, gc.ErrorMatches, "permission denied")
+
+	perms, err := s.State.UserPermissions(barb.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(perms.Models, jc.DeepEquals, map[string]permission.Access{
+		modelUUID: permission.WriteAccess,
+	})
+}
+
+func (s *userManagerSuite) TestGrantModelAccessAsNormalUser(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex", NoModelUser: true})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = api.GrantModelAccess(params.GrantModelAccessRequest{
+		Grants: []params.GrantModelAccess{{
+			UserTag:  alex.Tag().String(),
+			ModelTag: s.Model.ModelTag().String(),
+			Access:   params.ModelReadAccess,
+		}},
+	})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
+// fakeIdentityProvider is an OpenID Connect identity provider
+// that supports the device authorization flow.
+type fakeIdentityProvider struct {
+	*httptest.Server
+
+	// approvedBy names the user that approved the device
+	// login, or is empty if the login has yet to be approved.
+	approvedBy string
+}
+
+func newFakeIdentityProvider() *fakeIdentityProvider {
+	idp := &fakeIdentityProvider{}
+	mux := http.NewServeMux()
+	writeJSON := func(w http.ResponseWriter, status int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"device_authorization_endpoint": idp.URL + "/device",
+			"token_endpoint":                idp.URL + "/token",
+			"userinfo_endpoint":             idp.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": "https://idp.example.com/device",
+			"expires_in":       600,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		if idp.approvedBy == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "authorization_pending"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"access_token": idp.approvedBy})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, req *http.Request) {
+		// The access token is the name of the user.
+		writeJSON(w, http.StatusOK, map[string]string{
+			"nick": strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "),
+		})
+	})
+	idp.Server = httptest.NewTLSServer(mux)
+	return idp
+}
+
+// fakeMacaroonMinter records the users for which it mints login macaroons.
+type fakeMacaroonMinter struct {
+	minted []names.UserTag
+}
+
+func (m *fakeMacaroonMinter) CreateLoginMacaroon(ctx context.Context, tag names.UserTag, version bakery.Version) (*macaroon.Macaroon, error) {
+	m.minted = append(m.minted, tag)
+	return macaroon.New([]byte("root-key"), []byte(tag.Id()), "juju", macaroon.LatestVersion)
+}
+
+func (m *fakeMacaroonMinter) CreateImpersonationMacaroon(ctx context.Context, tag, impersonator names.UserTag, version bakery.Version) (*macaroon.Macaroon, error) {
+	m.minted = append(m.minted, tag)
+	return macaroon.New([]byte("root-key"), []byte(impersonator.Id()+":"+tag.Id()), "juju", macaroon.LatestVersion)
+}
+
+func (s *userManagerSuite) setUpDeviceLogin(c *gc.C) (*fakeIdentityProvider, *fakeMacaroonMinter, *usermanager.UserManagerAPI) {
+	idp := newFakeIdentityProvider()
+	s.AddCleanup(func(*gc.C) { idp.Close() })
+	usermanager.PatchIdentityProviderClient(s, idp.Client())
+	err := s.State.UpdateControllerConfig(map[string]interface{}{
+		jujucontroller.OIDCIssuerURL:     idp.URL,
+		jujucontroller.OIDCClientID:      "juju",
+		jujucontroller.OIDCUsernameClaim: "nick",
+	}, nil)
+	c.Assert(err, jc.ErrorIsNil)
+
+	minter := &fakeMacaroonMinter{}
+	err = s.resources.RegisterNamed("localMacaroonAuthenticator", common.ValueResource{Value: minter})
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Device logins are made using an anonymous connection.
+	api, err := usermanager.NewUserManagerAPI(s.State, s.resources, apiservertesting.FakeAuthorizer{})
+	c.Assert(err, jc.ErrorIsNil)
+	return idp, minter, api
+}
+
+func (s *userManagerSuite) TestDeviceLogin(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	idp, minter, api := s.setUpDeviceLogin(c)
+
+	start, err := api.StartDeviceLogin()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(start.DeviceCode, gc.Equals, "device-code")
+	c.Check(start.UserCode, gc.Equals, "ABCD-EFGH")
+	c.Check(start.VerificationURI, gc.Equals, "https://idp.example.com/device")
+	c.Check(start.Interval, gc.Equals, 5*time.Second)
+
+	args := params.PollDeviceLoginArgs{
+		Logins: []params.PollDeviceLogin{{DeviceCode: start.DeviceCode}},
+	}
+	result, err := api.PollDeviceLogin(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results, gc.HasLen, 1)
+	c.Assert(result.Results[0].Error, jc.Satisfies, params.IsCodeDeviceLoginPending)
+	c.Assert(minter.minted, gc.HasLen, 0)
+
+	idp.approvedBy = "alex"
+	result, err = api.PollDeviceLogin(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results[0].Error, gc.IsNil)
+	c.Assert(result.Results[0].UserTag, gc.Equals, alex.Tag().String())
+	c.Assert(result.Results[0].Macaroon, gc.NotNil)
+	c.Assert(minter.minted, jc.DeepEquals, []names.UserTag{alex.UserTag()})
+}
+
+func (s *userManagerSuite) TestDeviceLoginUnknownOrDisabledUser(c *gc.C) {
+	barb := s.Factory.MakeUser(c, &factory.UserParams{Name: "barb", Disabled: true})
+	idp, minter, api := s.setUpDeviceLogin(c)
+
+	args := params.PollDeviceLoginArgs{
+		Logins: []params.PollDeviceLogin{{DeviceCode: "device-code"}},
+	}
+	for _, name := range []string{"nobody", barb.Name()} {
+		idp.approvedBy = name
+		result, err := api.PollDeviceLogin(args)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(result.Results[0].Error, gc.ErrorMatches, "permission denied")
+		c.Check(result.Results[0].Macaroon, gc.IsNil)
+	}
+	c.Assert(minter.minted, gc.HasLen, 0)
+}
+
+func (s *userManagerSuite) TestDeviceLoginSecondFactorEnrolled(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	secret, err := alex.StartTOTPEnrollment()
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = alex.CompleteTOTPEnrollment(totp.Code(secret, totp.Step(time.Now())))
+	c.Assert(err, jc.ErrorIsNil)
+	idp, minter, api := s.setUpDeviceLogin(c)
+
+	idp.approvedBy = "alex"
+	result, err := api.PollDeviceLogin(params.PollDeviceLoginArgs{
+		Logins: []params.PollDeviceLogin{{DeviceCode: "device-code"}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(result.Results[0].Error, gc.ErrorMatches, `device login for user "alex" with a second factor not supported`)
+	c.Check(result.Results[0].Macaroon, gc.IsNil)
+	c.Assert(minter.minted, gc.HasLen, 0)
+}
+
+func (s *userManagerSuite) TestDeviceLoginNotConfigured(c *gc.C) {
+	_, err := s.usermanager.StartDeviceLogin()
+	c.Assert(err, gc.ErrorMatches, "device login without an identity provider not supported")
+}
+
+func (s *userManagerSuite) TestOlderVersionsRefuseAnonymous(c *gc.C) {
+	_, err := usermanager.NewUserManagerAPIV16(s.State, s.resources, apiservertesting.FakeAuthorizer{})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
+// fakeSessionRegistry holds the sessions listed and
+// records those terminated through the facade.
+type fakeSessionRegistry struct {
+	sessions   []params.Session
+	terminated []string
+}
+
+func (r *fakeSessionRegistry) UserSessions(user names.UserTag) []params.Session {
+	var result []params.Session
+	for _, session := range r.sessions {
+		if session.UserTag == user.String() {
+			result = append(result, session)
+		}
+	}
+	return result
+}
+
+func (r *fakeSessionRegistry) Session(sessionID string) (params.Session, error) {
+	for _, session := range r.sessions {
+		if session.SessionID == sessionID {
+			return session, nil
+		}
+	}
+	return params.Session{}, errors.NotFoundf("session %q", sessionID)
+}
+
+func (r *fakeSessionRegistry) TerminateSession(sessionID string) error {
+	r.terminated = append(r.terminated, sessionID)
+	return nil
+}
+
+func (s *userManagerSuite) setUpSessions(c *gc.C) *fakeSessionRegistry {
+	registry := &fakeSessionRegistry{
+		sessions: []params.Session{{
+			SessionID:     "1",
+			UserTag:       "user-alex",
+			ModelTag:      s.Model.ModelTag().String(),
+			RemoteAddress: "10.0.0.1",
+			UserAgent:     "Go-http-client/1.1",
+			Started:       time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+		}, {
+			SessionID:     "2",
+			UserTag:       "user-bob",
+			RemoteAddress: "10.0.0.2",
+			Started:       time.Date(2020, 6, 2, 0, 0, 0, 0, time.UTC),
+		}},
+	}
+	err := s.resources.RegisterNamed("sessionRegistry", common.ValueResource{Value: registry})
+	c.Assert(err, jc.ErrorIsNil)
+	return registry
+}
+
+func (s *userManagerSuite) TestListSessions(c *gc.C) {
+	registry := s.setUpSessions(c)
+
+	result, err := s.usermanager.ListSessions(params.Entities{
+		Entities: []params.Entity{{Tag: "user-alex"}, {Tag: "user-bob"}, {Tag: "user-carol"}, {Tag: "machine-0"}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results, gc.HasLen, 4)
+	c.Check(result.Results[0].Sessions, jc.DeepEquals, registry.sessions[:1])
+	c.Check(result.Results[1].Sessions, jc.DeepEquals, registry.sessions[1:])
+	c.Check(result.Results[2].Sessions, gc.HasLen, 0)
+	c.Check(result.Results[2].Error, gc.IsNil)
+	c.Check(result.Results[3].Error, gc.ErrorMatches, `"machine-0" is not a valid user tag`)
+}
+
+func (s *userManagerSuite) TestListSessionsNotAdmin(c *gc.C) {
+	registry := s.setUpSessions(c)
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	result, err := api.ListSessions(params.Entities{
+		Entities: []params.Entity{{Tag: "user-alex"}, {Tag: "user-bob"}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(result.Results[0].Sessions, jc.DeepEquals, registry.sessions[:1])
+	c.Check(result.Results[1].Error, gc.ErrorMatches, "permission denied")
+	c.Check(result.Results[1].Sessions, gc.HasLen, 0)
+}
+
+func (s *userManagerSuite) TestTerminateSession(c *gc.C) {
+	registry := s.setUpSessions(c)
+
+	result, err := s.usermanager.TerminateSession(params.TerminateSessionArgs{
+		SessionIDs: []string{"2", "3"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results, gc.HasLen, 2)
+	c.Check(result.Results[0].Error, gc.IsNil)
+	c.Check(result.Results[1].Error, gc.ErrorMatches, `session "3" not found`)
+	c.Assert(registry.terminated, jc.DeepEquals, []string{"2"})
+}
+
+func (s *userManagerSuite) TestTerminateSessionNotAdmin(c *gc.C) {
+	registry := s.setUpSessions(c)
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	result, err := api.TerminateSession(params.TerminateSessionArgs{
+		SessionIDs: []string{"1", "2"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(result.Results[0].Error, gc.IsNil)
+	c.Check(result.Results[1].Error, gc.ErrorMatches, "permission denied")
+	c.Assert(registry.terminated, jc.DeepEquals, []string{"1"})
+}
+
+func (s *userManagerSuite) TestEnableGuestAccess(c *gc.C) {
+	other := s.Factory.MakeModel(c, nil)
+	defer other.Close()
+	otherModel, err := other.Model()
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.usermanager.EnableGuestAccess(params.GuestAccessArgs{
+		ModelTags: []string{s.Model.ModelTag().String(), otherModel.ModelTag().String()},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	cfg, err := s.State.ControllerConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.GuestAccess(), jc.IsTrue)
+	guest, err := s.State.User(names.NewUserTag(state.GuestUserName))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(guest.IsGuest(), jc.IsTrue)
+
+	// Enabling guest access again replaces the models the guest user sees.
+	err = s.usermanager.EnableGuestAccess(params.GuestAccessArgs{
+		ModelTags: []string{otherModel.ModelTag().String()},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	result, err := s.usermanager.GuestAccess()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, params.GuestAccessResult{
+		Enabled:   true,
+		ModelTags: []string{otherModel.ModelTag().String()},
+	})
+	access, err := other.UserPermission(guest.UserTag(), otherModel.ModelTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(access, gc.Equals, permission.ReadAccess)
+}
+
+func (s *userManagerSuite) TestEnableGuestAccessInvalidModelTag(c *gc.C) {
+	err := s.usermanager.EnableGuestAccess(params.GuestAccessArgs{
+		ModelTags: []string{"user-alex"},
+	})
+	c.Assert(err, gc.ErrorMatches, `"user-alex" is not a valid model tag`)
+	cfg, err := s.State.ControllerConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.GuestAccess(), jc.IsFalse)
+}
+
+func (s *userManagerSuite) TestEnableGuestAccessBlocked(c *gc.C) {
+	s.BlockAllChanges(c, "TestEnableGuestAccessBlocked")
+	err := s.usermanager.EnableGuestAccess(params.GuestAccessArgs{})
+	s.AssertBlocked(c, err, "TestEnableGuestAccessBlocked")
+}
+
+func (s *userManagerSuite) TestDisableGuestAccess(c *gc.C) {
+	registry := s.setUpSessions(c)
+	registry.sessions = append(registry.sessions, params.Session{
+		SessionID: "3",
+		UserTag:   names.NewUserTag(state.GuestUserName).String(),
+	})
+	err := s.usermanager.EnableGuestAccess(params.GuestAccessArgs{
+		ModelTags: []string{s.Model.ModelTag().String()},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.usermanager.DisableGuestAccess()
+	c.Assert(err, jc.ErrorIsNil)
+
+	result, err := s.usermanager.GuestAccess()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, params.GuestAccessResult{
+		ModelTags: []string{},
+	})
+	c.Assert(registry.terminated, jc.DeepEquals, []string{"3"})
+}
+
+func (s *userManagerSuite) TestDisableGuestAccessNoGuestUser(c *gc.C) {
+	err := s.usermanager.DisableGuestAccess()
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.State.User(names.NewUserTag(state.GuestUserName))
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *userManagerSuite) TestGuestAccessNotAdmin(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = api.GuestAccess()
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+	err = api.EnableGuestAccess(params.GuestAccessArgs{})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+	err = api.DisableGuestAccess()
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
+func (s *userManagerSuite) TestAddUserGuestNameReserved(c *gc.C) {
+	result, err := s.usermanager.AddUser(params.AddUsers{
+		Users: []params.AddUser{{Username: state.GuestUserName, Password: "password"}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results[0].Error, gc.ErrorMatches, `username "juju-guest" not allowed: name is reserved`)
+}
+
+func (s *userManagerSuite) setUpImpersonation(c *gc.C) *fakeMacaroonMinter {
+	minter := &fakeMacaroonMinter{}
+	err := s.resources.RegisterNamed("localMacaroonAuthenticator", common.ValueResource{Value: minter})
+	c.Assert(err, jc.ErrorIsNil)
+	return minter
+}
+
+func (s *userManagerSuite) TestImpersonate(c *gc.C) {
+	minter := s.setUpImpersonation(c)
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+
+	before := time.Now()
+	result, err := s.usermanager.Impersonate(params.ImpersonateArgs{
+		Impersonations: []params.Impersonation{{
+			UserTag: alex.Tag().String(),
+			Reason:  "support case 42",
+		}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results, gc.HasLen, 1)
+	c.Assert(result.Results[0].Error, gc.IsNil)
+	c.Assert(result.Results[0].Macaroon, gc.NotNil)
+	c.Check(string(result.Results[0].Macaroon.Id()), gc.Equals, s.adminName+":alex")
+	c.Check(result.Results[0].Expiry.Before(before.Add(authentication.ImpersonationExpiryTime)), jc.IsFalse)
+	c.Assert(minter.minted, jc.DeepEquals, []names.UserTag{alex.UserTag()})
+}
+
+func (s *userManagerSuite) TestImpersonateInvalid(c *gc.C) {
+	minter := s.setUpImpersonation(c)
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	barb := s.Factory.MakeUser(c, &factory.UserParams{Name: "barb", Disabled: true})
+
+	result, err := s.usermanager.Impersonate(params.ImpersonateArgs{
+		Impersonations: []params.Impersonation{
+			{UserTag: alex.Tag().String()},
+			{UserTag: barb.Tag().String(), Reason: "support"},
+			{UserTag: s.AdminUserTag(c).String(), Reason: "support"},
+			{UserTag: "user-bob@external", Reason: "support"},
+			{UserTag: "user-nobody", Reason: "support"},
+		},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results, gc.HasLen, 5)
+	c.Check(result.Results[0].Error, gc.ErrorMatches, `impersonation of "alex" without a reason not valid`)
+	c.Check(result.Results[1].Error, gc.ErrorMatches, `impersonation of disabled user "barb" not valid`)
+	c.Check(result.Results[2].Error, gc.ErrorMatches, `impersonation of yourself not valid`)
+	c.Check(result.Results[3].Error, gc.ErrorMatches, `impersonation of non-local user "bob@external" not valid`)
+	c.Check(result.Results[4].Error, gc.ErrorMatches, `user "nobody" not found`)
+	c.Assert(minter.minted, gc.HasLen, 0)
+}
+
+func (s *userManagerSuite) TestImpersonateAuditingDisabled(c *gc.C) {
+	minter := s.setUpImpersonation(c)
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	err := s.State.UpdateControllerConfig(map[string]interface{}{
+		jujucontroller.AuditingEnabled: false,
+	}, nil)
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = s.usermanager.Impersonate(params.ImpersonateArgs{
+		Impersonations: []params.Impersonation{{UserTag: alex.Tag().String(), Reason: "support"}},
+	})
+	c.Assert(err, gc.ErrorMatches, "impersonation with the audit log disabled not supported")
+	c.Assert(minter.minted, gc.HasLen, 0)
+}
+
+func (s *userManagerSuite) TestImpersonateNotAdmin(c *gc.C) {
+	minter := s.setUpImpersonation(c)
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "barb"})
+	api, err := usermanager.NewUserManagerAPI(
+		s.State, s.resources, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = api.Impersonate(params.ImpersonateArgs{
+		Impersonations: []params.Impersonation{{UserTag: "user-barb", Reason: "support"}},
+	})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+	c.Assert(minter.minted, gc.HasLen, 0)
+}
@@ -4,6 +4,7 @@
 package migrationmaster
 
 import (
+	"github.com/juju/description"
 	"github.com/juju/version"
 	"gopkg.in/juju/names.v3"
 
@@ -19,6 +20,7 @@ import (
 // migrationmaster facade.
 type Backend interface {
 	migration.StateExporter
+	ExportPartial(state.ExportConfig) (description.Model, error)
 
 	WatchForMigration() state.NotifyWatcher
 	LatestMigration() (state.ModelMigration, error)
@@ -27,6 +29,8 @@ type Backend interface {
 	ModelOwner() (names.UserTag, error)
 	AgentVersion() (version.Number, error)
 	RemoveExportingModelDocs() error
+	ApplicationLeaders() (map[string]string, error)
+	DeferMigrationIfUpgrading(string, state.MigrationDirection) error
 }
 
 // OfferConnection describes methods offer connection methods
@@ -5,10 +5,12 @@ package migrationmaster
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/juju/collections/set"
 	"github.com/juju/description"
 	"github.com/juju/errors"
+	"github.com/juju/loggo"
 	"github.com/juju/naturalsort"
 	"github.com/juju/version"
 	"gopkg.in/juju/names.v3"
@@ -16,12 +18,17 @@ import (
 	"github.com/juju/juju/apiserver/common"
 	"github.com/juju/juju/apiserver/facade"
 	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/core/leadership"
 	coremigration "github.com/juju/juju/core/migration"
 	coremodel "github.com/juju/juju/core/model"
 	"github.com/juju/juju/migration"
+	"github.com/juju/juju/state"
 	"github.com/juju/juju/state/watcher"
+	jujuversion "github.com/juju/juju/version"
 )
 
+var logger = loggo.GetLogger("juju.apiserver.migrationmaster")
+
 // API implements the API required for the model migration
 // master worker.
 type API struct {
@@ -31,20 +38,33 @@ type API struct {
 	authorizer      facade.Authorizer
 	resources       facade.Resources
 	presence        facade.Presence
+	leadership      leadership.Pinner
 }
 
-type APIV1 struct {
+// APIV2 implements the API V2.
+type APIV2 struct {
 	*API
 }
 
-// NewMigrationMasterFacadeV2 exists to provide the required signature for API
+// APIV1 implements the API V1.
+type APIV1 struct {
+	*APIV2
+}
+
+// NewMigrationMasterFacadeV3 exists to provide the required signature for API
 // registration, converting st to backend.
-func NewMigrationMasterFacadeV2(ctx facade.Context) (*API, error) {
+func NewMigrationMasterFacadeV3(ctx facade.Context) (*API, error) {
 	controllerState := ctx.StatePool().SystemState()
 	precheckBackend, err := migration.PrecheckShim(ctx.State(), controllerState)
 	if err != nil {
 		return nil, errors.Annotate(err, "creating precheck backend")
 	}
+	// Leadership cannot be pinned with the legacy lease manager,
+	// in which case leaders are exported as they are found.
+	pinner, err := ctx.LeadershipPinner(ctx.State().ModelUUID())
+	if err != nil && !errors.IsNotImplemented(err) {
+		return nil, errors.Trace(err)
+	}
 	return NewAPI(
 		newBacked(ctx.State()),
 		precheckBackend,
@@ -52,9 +72,20 @@ func NewMigrationMasterFacadeV2(ctx facade.Context) (*API, error) {
 		ctx.Resources(),
 		ctx.Auth(),
 		ctx.Presence(),
+		pinner,
 	)
 }
 
+// NewMigrationMasterFacadeV2 exists to provide the required signature for API
+// registration, converting st to backend.
+func NewMigrationMasterFacadeV2(ctx facade.Context) (*APIV2, error) {
+	v3, err := NewMigrationMasterFacadeV3(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &APIV2{v3}, nil
+}
+
 // NewMigrationMasterFacade exists to provide the required signature for API
 // registration, converting st to backend.
 func NewMigrationMasterFacade(ctx facade.Context) (*APIV1, error) {
@@ -74,6 +105,7 @@ func NewAPI(
 	resources facade.Resources,
 	authorizer facade.Authorizer,
 	presence facade.Presence,
+	pinner leadership.Pinner,
 ) (*API, error) {
 	if !authorizer.AuthController() {
 		return nil, common.ErrPerm
@@ -85,6 +117,7 @@ func NewAPI(
 		authorizer:      authorizer,
 		resources:       resources,
 		presence:        presence,
+		leadership:      pinner,
 	}, nil
 }
 
@@ -184,8 +217,62 @@ func (api *API) SetPhase(args params.SetMigrationPhaseArgs) error {
 		return errors.Errorf("invalid phase: %q", args.Phase)
 	}
 
-	err = mig.SetPhase(phase)
-	return errors.Annotate(err, "failed to set phase")
+	// Application leadership is pinned while the model is in transit,
+	// so that the leaders exported are those that held leadership
+	// before the agents were quiesced.
+	if phase == coremigration.QUIESCE {
+		if err := api.pinLeadership(); err != nil {
+			return errors.Annotate(err, "pinning leadership")
+		}
+	}
+	if err := mig.SetPhase(phase); err != nil {
+		return errors.Annotate(err, "failed to set phase")
+	}
+	if phase == coremigration.SUCCESS || phase == coremigration.ABORT {
+		api.unpinLeadership()
+	}
+	return nil
+}
+
+// pinLeadership pins the leadership of the model's applications.
+func (api *API) pinLeadership() error {
+	if api.leadership == nil {
+		return nil
+	}
+	leaders, err := api.backend.ApplicationLeaders()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	appNames := set.NewStrings()
+	for appName := range leaders {
+		appNames.Add(appName)
+	}
+	for _, appName := range appNames.SortedValues() {
+		if err := api.leadership.PinLeadership(appName, coremigration.LeadershipPinEntity); err != nil {
+			return errors.Annotatef(err, "pinning leadership for %q", appName)
+		}
+	}
+	return nil
+}
+
+// unpinLeadership restores normal expiry of the leadership of the
+// model's applications. Failures are logged, rather than returned,
+// as they must not prevent the migration from completing or aborting.
+func (api *API) unpinLeadership() {
+	if api.leadership == nil {
+		return
+	}
+	appNames := set.NewStrings()
+	for appName, entities := range api.leadership.PinnedLeadership() {
+		if set.NewStrings(entities...).Contains(coremigration.LeadershipPinEntity) {
+			appNames.Add(appName)
+		}
+	}
+	for _, appName := range appNames.SortedValues() {
+		if err := api.leadership.UnpinLeadership(appName, coremigration.LeadershipPinEntity); err != nil {
+			logger.Warningf("unpinning leadership for %q: %v", appName, err)
+		}
+	}
 }
 
 // Prechecks performs pre-migration checks on the model and
@@ -222,11 +309,49 @@ func (api *API) SetStatusMessage(args params.SetMigrationStatusMessageArgs) erro
 	return errors.Annotate(err, "failed to set status message")
 }
 
-// Export serializes the model associated with the API connection.
+// SetImportProgress is not available on V2 or earlier.
+func (api *APIV2) SetImportProgress(_, _ struct{}) {}
+
+// SetImportProgress records the progress of the transfer of the
+// model to the target controller, so that it can be shown to the
+// end user while the migration runs.
+func (api *API) SetImportProgress(args params.MigrationImportProgress) error {
+	mig, err := api.backend.LatestMigration()
+	if err != nil {
+		return errors.Annotate(err, "could not get migration")
+	}
+	progress := coremigration.ImportProgress{
+		BinariesBytes: args.BinariesBytes,
+	}
+	for _, entity := range args.Entities {
+		progress.Entities = append(progress.Entities, coremigration.EntityProgress{
+			Kind:     entity.Kind,
+			Imported: entity.Imported,
+			Total:    entity.Total,
+		})
+	}
+	err = mig.SetImportProgress(progress)
+	return errors.Annotate(err, "failed to set import progress")
+}
+
+// Export serializes the model associated with the API connection. The
+// model is not exported while the controller database is being upgraded,
+// so that upgrade steps cannot change its documents as they are read;
+// an upgrade in progress error is returned instead, and the export
+// should be retried later. The controller and cloud access of the model's
+// users is only exported if the migration was started with a policy for
+// applying it on the target controller.
 func (api *API) Export() (params.SerializedModel, error) {
 	var serialized params.SerializedModel
 
-	model, err := api.backend.Export()
+	if err := api.backend.DeferMigrationIfUpgrading(api.backend.ModelUUID(), state.MigrationOutgoing); err != nil {
+		return serialized, errors.Trace(err)
+	}
+	mig, err := api.backend.LatestMigration()
+	if err != nil {
+		return serialized, errors.Annotate(err, "could not get migration")
+	}
+	model, err := api.backend.ExportPartial(state.ExportConfig{Permissions: mig.Permissions()})
 	if err != nil {
 		return serialized, err
 	}
@@ -236,6 +361,7 @@ func (api *API) Export() (params.SerializedModel, error) {
 		return serialized, err
 	}
 	serialized.Bytes = bytes
+	serialized.Manifest = exportManifest(bytes)
 	serialized.Charms = getUsedCharms(model)
 	serialized.Resources = getUsedResources(model)
 	if model.Type() == string(coremodel.IAAS) {
@@ -244,6 +370,18 @@ func (api *API) Export() (params.SerializedModel, error) {
 	return serialized, nil
 }
 
+// exportManifest returns the manifest describing the serialized
+// model, with which the target controller verifies its integrity.
+func exportManifest(bytes []byte) *params.SerializedModelManifest {
+	manifest := coremigration.NewExportManifest(bytes, jujuversion.Current, time.Now())
+	return &params.SerializedModelManifest{
+		ExporterVersion: manifest.ExporterVersion.String(),
+		Exported:        manifest.Exported,
+		Hash:            manifest.Hash,
+		Sections:        manifest.Sections,
+	}
+}
+
 // ProcessRelations is masked on older versions of the migration master API
 func (api *APIV1) ProcessRelations(_, _ struct{}) {}
 
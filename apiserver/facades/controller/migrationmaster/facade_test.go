@@ -23,6 +23,7 @@ import (
 	"github.com/juju/juju/apiserver/facades/controller/migrationmaster/mocks"
 	"github.com/juju/juju/apiserver/params"
 	apiservertesting "github.com/juju/juju/apiserver/testing"
+	leadershipmocks "github.com/juju/juju/core/leadership/mocks"
 	coremigration "github.com/juju/juju/core/migration"
 	"github.com/juju/juju/core/presence"
 	"github.com/juju/juju/state"
@@ -35,6 +36,7 @@ type Suite struct {
 
 	backend         *mocks.MockBackend
 	precheckBackend *mocks.MockPrecheckBackend
+	pinner          *leadershipmocks.MockPinner
 
 	controllerUUID string
 	modelUUID      string
@@ -178,12 +180,68 @@ func (s *Suite) TestSetPhase(c *gc.C) {
 	mig.EXPECT().SetPhase(coremigration.ABORT).Return(nil)
 
 	s.backend.EXPECT().LatestMigration().Return(mig, nil)
+	s.pinner.EXPECT().PinnedLeadership().Return(nil)
 
 	err := s.mustMakeAPI(c).SetPhase(params.SetMigrationPhaseArgs{Phase: "ABORT"})
 	c.Assert(err, jc.ErrorIsNil)
 
 }
 
+func (s *Suite) TestSetPhaseQuiescePinsLeadership(c *gc.C) {
+	ctrl := s.setupMocks(c)
+	defer ctrl.Finish()
+
+	mig := mocks.NewMockModelMigration(ctrl)
+	s.backend.EXPECT().LatestMigration().Return(mig, nil)
+	s.backend.EXPECT().ApplicationLeaders().Return(map[string]string{
+		"wordpress": "wordpress/1",
+		"mysql":     "mysql/0",
+	}, nil)
+	gomock.InOrder(
+		s.pinner.EXPECT().PinLeadership("mysql", coremigration.LeadershipPinEntity).Return(nil),
+		s.pinner.EXPECT().PinLeadership("wordpress", coremigration.LeadershipPinEntity).Return(nil),
+		mig.EXPECT().SetPhase(coremigration.QUIESCE).Return(nil),
+	)
+
+	err := s.mustMakeAPI(c).SetPhase(params.SetMigrationPhaseArgs{Phase: "QUIESCE"})
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *Suite) TestSetPhaseQuiescePinError(c *gc.C) {
+	ctrl := s.setupMocks(c)
+	defer ctrl.Finish()
+
+	mig := mocks.NewMockModelMigration(ctrl)
+	s.backend.EXPECT().LatestMigration().Return(mig, nil)
+	s.backend.EXPECT().ApplicationLeaders().Return(map[string]string{"mysql": "mysql/0"}, nil)
+	s.pinner.EXPECT().PinLeadership("mysql", coremigration.LeadershipPinEntity).Return(errors.New("boom"))
+
+	// The phase is not changed unless leadership is pinned.
+	err := s.mustMakeAPI(c).SetPhase(params.SetMigrationPhaseArgs{Phase: "QUIESCE"})
+	c.Assert(err, gc.ErrorMatches, `pinning leadership: pinning leadership for "mysql": boom`)
+}
+
+func (s *Suite) TestSetPhaseSuccessUnpinsLeadership(c *gc.C) {
+	ctrl := s.setupMocks(c)
+	defer ctrl.Finish()
+
+	mig := mocks.NewMockModelMigration(ctrl)
+	mig.EXPECT().SetPhase(coremigration.SUCCESS).Return(nil)
+	s.backend.EXPECT().LatestMigration().Return(mig, nil)
+	s.pinner.EXPECT().PinnedLeadership().Return(map[string][]string{
+		"mysql":     {coremigration.LeadershipPinEntity},
+		"wordpress": {"machine-0", coremigration.LeadershipPinEntity},
+		"redis":     {"machine-1"},
+	})
+	// Failing to unpin one application does not prevent
+	// the others being unpinned, or the phase changing.
+	s.pinner.EXPECT().UnpinLeadership("mysql", coremigration.LeadershipPinEntity).Return(errors.New("boom"))
+	s.pinner.EXPECT().UnpinLeadership("wordpress", coremigration.LeadershipPinEntity).Return(nil)
+
+	err := s.mustMakeAPI(c).SetPhase(params.SetMigrationPhaseArgs{Phase: "SUCCESS"})
+	c.Assert(err, jc.ErrorIsNil)
+}
+
 func (s *Suite) TestSetPhaseNoMigration(c *gc.C) {
 	defer s.setupMocks(c).Finish()
 
@@ -246,6 +304,42 @@ func (s *Suite) TestSetStatusMessageError(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, "failed to set status message: blam")
 }
 
+func (s *Suite) TestSetImportProgress(c *gc.C) {
+	ctrl := s.setupMocks(c)
+	defer ctrl.Finish()
+
+	mig := mocks.NewMockModelMigration(ctrl)
+	mig.EXPECT().SetImportProgress(coremigration.ImportProgress{
+		Entities: []coremigration.EntityProgress{
+			{Kind: coremigration.ImportMachines, Imported: 1, Total: 2},
+		},
+		BinariesBytes: 1024,
+	}).Return(nil)
+
+	s.backend.EXPECT().LatestMigration().Return(mig, nil)
+
+	err := s.mustMakeAPI(c).SetImportProgress(params.MigrationImportProgress{
+		Entities: []params.EntityImportProgress{
+			{Kind: "machines", Imported: 1, Total: 2},
+		},
+		BinariesBytes: 1024,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *Suite) TestSetImportProgressError(c *gc.C) {
+	ctrl := s.setupMocks(c)
+	defer ctrl.Finish()
+
+	mig := mocks.NewMockModelMigration(ctrl)
+	mig.EXPECT().SetImportProgress(coremigration.ImportProgress{}).Return(errors.New("blam"))
+
+	s.backend.EXPECT().LatestMigration().Return(mig, nil)
+
+	err := s.mustMakeAPI(c).SetImportProgress(params.MigrationImportProgress{})
+	c.Assert(err, gc.ErrorMatches, "failed to set import progress: blam")
+}
+
 func (s *Suite) TestPrechecksModelError(c *gc.C) {
 	defer s.setupMocks(c).Finish()
 
@@ -276,7 +370,8 @@ func (s *Suite) TestExportCAAS(c *gc.C) {
 }
 
 func (s *Suite) assertExport(c *gc.C, modelType string) {
-	defer s.setupMocks(c).Finish()
+	ctrl := s.setupMocks(c)
+	defer ctrl.Finish()
 
 	app := s.model.AddApplication(description.ApplicationArgs{
 		Tag:      names.NewApplicationTag("foo"),
@@ -336,7 +431,12 @@ func (s *Suite) assertExport(c *gc.C, modelType string) {
 	})
 	unitRev := unitRes.Revision()
 
-	s.backend.EXPECT().Export().Return(s.model, nil)
+	s.backend.EXPECT().ModelUUID().Return(s.modelUUID)
+	s.backend.EXPECT().DeferMigrationIfUpgrading(s.modelUUID, state.MigrationOutgoing).Return(nil)
+	mig := mocks.NewMockModelMigration(ctrl)
+	mig.EXPECT().Permissions().Return(state.PermissionConflictPolicy(""))
+	s.backend.EXPECT().LatestMigration().Return(mig, nil)
+	s.backend.EXPECT().ExportPartial(state.ExportConfig{}).Return(s.model, nil)
 
 	serialized, err := s.mustMakeAPI(c).Export()
 	c.Assert(err, jc.ErrorIsNil)
@@ -346,6 +446,16 @@ func (s *Suite) assertExport(c *gc.C, modelType string) {
 	// is in the serialised output.
 	c.Check(string(serialized.Bytes), jc.Contains, jujuversion.Current.String())
 
+	c.Assert(serialized.Manifest, gc.NotNil)
+	c.Check(serialized.Manifest.ExporterVersion, gc.Equals, jujuversion.Current.String())
+	c.Check(serialized.Manifest.Exported.IsZero(), jc.IsFalse)
+	c.Check(serialized.Manifest.Sections["applications"], gc.Not(gc.Equals), "")
+	manifest := coremigration.ExportManifest{
+		Hash:     serialized.Manifest.Hash,
+		Sections: serialized.Manifest.Sections,
+	}
+	c.Check(manifest.Verify(serialized.Bytes), jc.ErrorIsNil)
+
 	c.Check(serialized.Charms, gc.DeepEquals, []string{"cs:foo-0"})
 	if modelType == "caas" {
 		c.Check(serialized.Tools, gc.HasLen, 0)
@@ -519,11 +629,40 @@ func (s *Suite) TestMinionReports(c *gc.C) {
 	})
 }
 
+func (s *Suite) TestExportWithPermissions(c *gc.C) {
+	ctrl := s.setupMocks(c)
+	defer ctrl.Finish()
+
+	s.backend.EXPECT().ModelUUID().Return(s.modelUUID)
+	s.backend.EXPECT().DeferMigrationIfUpgrading(s.modelUUID, state.MigrationOutgoing).Return(nil)
+	mig := mocks.NewMockModelMigration(ctrl)
+	mig.EXPECT().Permissions().Return(state.PermissionConflictGreater)
+	s.backend.EXPECT().LatestMigration().Return(mig, nil)
+	s.backend.EXPECT().ExportPartial(state.ExportConfig{
+		Permissions: state.PermissionConflictGreater,
+	}).Return(s.model, nil)
+
+	_, err := s.mustMakeAPI(c).Export()
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *Suite) TestExportDuringDatabaseUpgrade(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	s.backend.EXPECT().ModelUUID().Return(s.modelUUID)
+	s.backend.EXPECT().DeferMigrationIfUpgrading(s.modelUUID, state.MigrationOutgoing).Return(
+		errors.Annotate(params.UpgradeInProgressError, "controller database is being upgraded"))
+
+	_, err := s.mustMakeAPI(c).Export()
+	c.Assert(err, jc.Satisfies, common.IsUpgradeInProgressError)
+}
+
 func (s *Suite) setupMocks(c *gc.C) *gomock.Controller {
 	ctrl := gomock.NewController(c)
 
 	s.backend = mocks.NewMockBackend(ctrl)
 	s.precheckBackend = mocks.NewMockPrecheckBackend(ctrl)
+	s.pinner = leadershipmocks.NewMockPinner(ctrl)
 
 	return ctrl
 }
@@ -542,6 +681,7 @@ func (s *Suite) makeAPI() (*migrationmaster.API, error) {
 		s.resources,
 		s.authorizer,
 		&stubPresence{},
+		s.pinner,
 	)
 }
 
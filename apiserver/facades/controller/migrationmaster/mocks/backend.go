@@ -52,6 +52,35 @@ func (mr *MockBackendMockRecorder) AgentVersion() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AgentVersion", reflect.TypeOf((*MockBackend)(nil).AgentVersion))
 }
 
+// ApplicationLeaders mocks base method
+func (m *MockBackend) ApplicationLeaders() (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplicationLeaders")
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplicationLeaders indicates an expected call of ApplicationLeaders
+func (mr *MockBackendMockRecorder) ApplicationLeaders() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplicationLeaders", reflect.TypeOf((*MockBackend)(nil).ApplicationLeaders))
+}
+
+// DeferMigrationIfUpgrading mocks base method
+func (m *MockBackend) DeferMigrationIfUpgrading(arg0 string, arg1 state.MigrationDirection) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeferMigrationIfUpgrading", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeferMigrationIfUpgrading indicates an expected call of DeferMigrationIfUpgrading
+func (mr *MockBackendMockRecorder) DeferMigrationIfUpgrading(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeferMigrationIfUpgrading", reflect.TypeOf((*MockBackend)(nil).DeferMigrationIfUpgrading), arg0, arg1)
+}
+
 // Export mocks base method
 func (m *MockBackend) Export() (description.Model, error) {
 	m.ctrl.T.Helper()
@@ -67,6 +96,21 @@ func (mr *MockBackendMockRecorder) Export() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockBackend)(nil).Export))
 }
 
+// ExportPartial mocks base method
+func (m *MockBackend) ExportPartial(arg0 state.ExportConfig) (description.Model, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportPartial", arg0)
+	ret0, _ := ret[0].(description.Model)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExportPartial indicates an expected call of ExportPartial
+func (mr *MockBackendMockRecorder) ExportPartial(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportPartial", reflect.TypeOf((*MockBackend)(nil).ExportPartial), arg0)
+}
+
 // LatestMigration mocks base method
 func (m *MockBackend) LatestMigration() (state.ModelMigration, error) {
 	m.ctrl.T.Helper()
@@ -81,6 +81,20 @@ func (mr *MockModelMigrationMockRecorder) Id() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Id", reflect.TypeOf((*MockModelMigration)(nil).Id))
 }
 
+// ImportProgress mocks base method
+func (m *MockModelMigration) ImportProgress() migration.ImportProgress {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportProgress")
+	ret0, _ := ret[0].(migration.ImportProgress)
+	return ret0
+}
+
+// ImportProgress indicates an expected call of ImportProgress
+func (mr *MockModelMigrationMockRecorder) ImportProgress() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportProgress", reflect.TypeOf((*MockModelMigration)(nil).ImportProgress))
+}
+
 // InitiatedBy mocks base method
 func (m *MockModelMigration) InitiatedBy() string {
 	m.ctrl.T.Helper()
@@ -138,6 +152,20 @@ func (mr *MockModelMigrationMockRecorder) ModelUserAccess(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModelUserAccess", reflect.TypeOf((*MockModelMigration)(nil).ModelUserAccess), arg0)
 }
 
+// Permissions mocks base method
+func (m *MockModelMigration) Permissions() state.PermissionConflictPolicy {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Permissions")
+	ret0, _ := ret[0].(state.PermissionConflictPolicy)
+	return ret0
+}
+
+// Permissions indicates an expected call of Permissions
+func (mr *MockModelMigrationMockRecorder) Permissions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Permissions", reflect.TypeOf((*MockModelMigration)(nil).Permissions))
+}
+
 // Phase mocks base method
 func (m *MockModelMigration) Phase() (migration.Phase, error) {
 	m.ctrl.T.Helper()
@@ -181,6 +209,20 @@ func (mr *MockModelMigrationMockRecorder) Refresh() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockModelMigration)(nil).Refresh))
 }
 
+// SetImportProgress mocks base method
+func (m *MockModelMigration) SetImportProgress(arg0 migration.ImportProgress) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetImportProgress", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetImportProgress indicates an expected call of SetImportProgress
+func (mr *MockModelMigrationMockRecorder) SetImportProgress(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetImportProgress", reflect.TypeOf((*MockModelMigration)(nil).SetImportProgress), arg0)
+}
+
 // SetPhase mocks base method
 func (m *MockModelMigration) SetPhase(arg0 migration.Phase) error {
 	m.ctrl.T.Helper()
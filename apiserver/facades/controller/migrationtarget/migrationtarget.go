@@ -6,7 +6,11 @@ package migrationtarget
 import (
 	"time"
 
+	"github.com/juju/collections/set"
+	"github.com/juju/description"
 	"github.com/juju/errors"
+	"github.com/juju/loggo"
+	"github.com/juju/version"
 	"gopkg.in/juju/names.v3"
 
 	"github.com/juju/juju/apiserver/common"
@@ -14,6 +18,7 @@ import (
 	"github.com/juju/juju/apiserver/facade"
 	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/caas"
+	"github.com/juju/juju/core/leadership"
 	coremigration "github.com/juju/juju/core/migration"
 	"github.com/juju/juju/core/permission"
 	"github.com/juju/juju/core/status"
@@ -24,6 +29,8 @@ import (
 	"github.com/juju/juju/state/stateenvirons"
 )
 
+var logger = loggo.GetLogger("juju.apiserver.migrationtarget")
+
 // API implements the API required for the model migration
 // master worker when communicating with the target controller.
 type API struct {
@@ -33,10 +40,25 @@ type API struct {
 	resources     facade.Resources
 	presence      facade.Presence
 	getClaimer    migration.ClaimerFunc
+	getPinner     migration.PinnerFunc
 	getEnviron    stateenvirons.NewEnvironFunc
 	getCAASBroker stateenvirons.NewCAASBrokerFunc
 }
 
+// APIV1 implements the API V1.
+type APIV1 struct {
+	*API
+}
+
+// NewFacadeV1 is used for API registration.
+func NewFacadeV1(ctx facade.Context) (*APIV1, error) {
+	api, err := NewFacade(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &APIV1{api}, nil
+}
+
 // NewFacade is used for API registration.
 func NewFacade(ctx facade.Context) (*API, error) {
 	return NewAPI(
@@ -60,6 +82,7 @@ func NewAPI(ctx facade.Context, getEnviron stateenvirons.NewEnvironFunc, getCAAS
 		resources:     ctx.Resources(),
 		presence:      ctx.Presence(),
 		getClaimer:    ctx.LeadershipClaimer,
+		getPinner:     ctx.LeadershipPinner,
 		getEnviron:    getEnviron,
 		getCAASBroker: getCAASBroker,
 	}, nil
@@ -80,13 +103,17 @@ func checkAuth(authorizer facade.Authorizer, st *state.State) error {
 }
 
 // Prechecks ensure that the target controller is ready to accept a
-// model migration.
+// model migration. Migrations are refused with an upgrade in progress
+// error while the controller database is being upgraded.
 func (api *API) Prechecks(model params.MigrationModelInfo) error {
 	ownerTag, err := names.ParseUserTag(model.OwnerTag)
 	if err != nil {
 		return errors.Trace(err)
 	}
 	controllerState := api.pool.SystemState()
+	if err := controllerState.DeferMigrationIfUpgrading(model.UUID, state.MigrationIncoming); err != nil {
+		return errors.Trace(err)
+	}
 	// NOTE (thumper): it isn't clear to me why api.state would be different
 	// from the controllerState as I had thought that the Precheck call was
 	// on the controller model, in which case it should be the same as the
@@ -110,20 +137,83 @@ func (api *API) Prechecks(model params.MigrationModelInfo) error {
 }
 
 // Import takes a serialized Juju model, deserializes it, and
-// recreates it in the receiving controller.
+// recreates it in the receiving controller. If the source controller
+// provides a manifest, the serialized model is verified against it,
+// and the provenance of the model is recorded in its annotations.
+// If the migration was started with a policy for migrating the
+// controller and cloud access of the model's users, it is applied on
+// behalf of the user importing the model. Models are not imported while
+// the controller database is being upgraded.
 func (api *API) Import(serialized params.SerializedModel) error {
+	manifest, err := verifyManifest(serialized)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	described, err := description.Deserialize(serialized.Bytes)
+	if err != nil {
+		return errors.Trace(err)
+	}
 	controller := state.NewController(api.pool)
-	_, st, err := migration.ImportModel(controller, api.getClaimer, serialized.Bytes)
+	model, st, err := migration.ImportDescribedModel(controller, api.getClaimer, api.getPinner, described)
 	if err != nil {
 		return err
 	}
 	defer st.Close()
+	if manifest != nil {
+		if err := model.SetAnnotations(model, manifest.Annotations(time.Now())); err != nil {
+			return errors.Annotate(err, "recording model provenance")
+		}
+	}
+	if err := api.applyPermissions(st, described); err != nil {
+		return errors.Annotate(err, "applying users' controller and cloud access")
+	}
 	// TODO(mjs) - post import checks
 	// NOTE(fwereade) - checks here would be sensible, but we will
 	// also need to check after the binaries are imported too.
 	return err
 }
 
+// applyPermissions applies the controller and cloud access of the
+// model's users held by the model description, which the source
+// controller only exports when the migration requests it.
+func (api *API) applyPermissions(st *state.State, model description.Model) error {
+	section, err := state.PermissionsSectionOf(model)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	user, ok := api.authorizer.GetAuthTag().(names.UserTag)
+	if !ok {
+		return errors.Trace(common.ErrPerm)
+	}
+	return errors.Trace(st.ApplyPermissionsSection(section, user))
+}
+
+// verifyManifest checks that the serialized model matches the manifest
+// provided by the source controller, returning the manifest. Source
+// controllers that predate manifests do not provide one, in which case
+// nil is returned.
+func verifyManifest(serialized params.SerializedModel) (*coremigration.ExportManifest, error) {
+	if serialized.Manifest == nil {
+		return nil, nil
+	}
+	exporterVersion, err := version.Parse(serialized.Manifest.ExporterVersion)
+	if err != nil {
+		return nil, errors.Annotate(err, "parsing exporter version")
+	}
+	manifest := &coremigration.ExportManifest{
+		ExporterVersion: exporterVersion,
+		Exported:        serialized.Manifest.Exported,
+		Hash:            serialized.Manifest.Hash,
+		Sections:        serialized.Manifest.Sections,
+	}
+	if err := manifest.Verify(serialized.Bytes); err != nil {
+		return nil, errors.Annotate(err, "verifying serialized model")
+	}
+	return manifest, nil
+}
+
 func (api *API) getModel(modelTag string) (*state.Model, func(), error) {
 	tag, err := names.ParseModelTag(modelTag)
 	if err != nil {
@@ -148,6 +238,37 @@ func (api *API) getImportingModel(args params.ModelArgs) (*state.Model, func(),
 	return model, release, nil
 }
 
+// ImportProgress is not available on V1.
+func (api *APIV1) ImportProgress(_, _ struct{}) {}
+
+// ImportProgress reports how many of the entities of each kind have
+// been imported into the specified model so far. It may be called
+// while the model is being imported, to report the progress of the
+// import to the source controller.
+func (api *API) ImportProgress(args params.ModelArgs) (params.MigrationImportProgress, error) {
+	model, release, err := api.getModel(args.ModelTag)
+	if err != nil {
+		return params.MigrationImportProgress{}, errors.Trace(err)
+	}
+	defer release()
+
+	progress, err := model.ImportProgress()
+	if err != nil {
+		return params.MigrationImportProgress{}, errors.Trace(err)
+	}
+	result := params.MigrationImportProgress{
+		Entities: make([]params.EntityImportProgress, len(progress)),
+	}
+	for i, entity := range progress {
+		result.Entities[i] = params.EntityImportProgress{
+			Kind:     entity.Kind,
+			Imported: entity.Imported,
+			Total:    entity.Total,
+		}
+	}
+	return result, nil
+}
+
 // Abort removes the specified model from the database. It is an error to
 // attempt to Abort a model that has a migration mode other than importing.
 func (api *API) Abort(args params.ModelArgs) error {
@@ -162,7 +283,11 @@ func (api *API) Abort(args params.ModelArgs) error {
 		return errors.Trace(err)
 	}
 	defer st.Release()
-	return st.RemoveImportingModelDocs()
+	if err := st.RemoveImportingModelDocs(); err != nil {
+		return errors.Trace(err)
+	}
+	api.unpinLeadership(st.State, false)
+	return nil
 }
 
 // Activate sets the migration mode of the model to "none", meaning it
@@ -180,7 +305,64 @@ func (api *API) Activate(args params.ModelArgs) error {
 	}
 
 	// TODO(fwereade) - need to validate binaries here.
-	return model.SetMigrationMode(state.MigrationModeNone)
+	if err := model.SetMigrationMode(state.MigrationModeNone); err != nil {
+		return errors.Trace(err)
+	}
+
+	st, err := api.pool.Get(model.UUID())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer st.Release()
+	api.unpinLeadership(st.State, true)
+	return nil
+}
+
+// unpinLeadership unpins the leadership pinned when the model was
+// imported. If renew is true, the leadership is first claimed again
+// for the imported leaders, giving them time to connect to this
+// controller before it can expire. Failures are logged, rather than
+// returned, as they must not prevent the migration from completing.
+func (api *API) unpinLeadership(st *state.State, renew bool) {
+	pinner, err := api.getPinner(st.ModelUUID())
+	if errors.IsNotImplemented(err) {
+		// Leadership is not pinned with the legacy lease manager.
+		return
+	} else if err != nil {
+		logger.Warningf("getting leadership pinner: %v", err)
+		return
+	}
+	appNames := set.NewStrings()
+	for appName, entities := range pinner.PinnedLeadership() {
+		if set.NewStrings(entities...).Contains(coremigration.LeadershipPinEntity) {
+			appNames.Add(appName)
+		}
+	}
+	if appNames.IsEmpty() {
+		return
+	}
+
+	var (
+		leaders map[string]string
+		claimer leadership.Claimer
+	)
+	if renew {
+		if leaders, err = st.ApplicationLeaders(); err != nil {
+			logger.Warningf("reading application leaders: %v", err)
+		} else if claimer, err = api.getClaimer(st.ModelUUID()); err != nil {
+			logger.Warningf("getting leadership claimer: %v", err)
+		}
+	}
+	for _, appName := range appNames.SortedValues() {
+		if leader := leaders[appName]; leader != "" && claimer != nil {
+			if err := claimer.ClaimLeadership(appName, leader, state.InitialLeaderClaimTime); err != nil {
+				logger.Warningf("renewing leadership of %q for %q: %v", appName, leader, err)
+			}
+		}
+		if err := pinner.UnpinLeadership(appName, coremigration.LeadershipPinEntity); err != nil {
+			logger.Warningf("unpinning leadership for %q: %v", appName, err)
+		}
+	}
 }
 
 // LatestLogTime returns the time of the most recent log record
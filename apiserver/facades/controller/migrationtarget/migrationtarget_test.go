@@ -27,6 +27,8 @@ import (
 	"github.com/juju/juju/core/instance"
 	"github.com/juju/juju/core/leadership"
 	"github.com/juju/juju/core/lease"
+	coremigration "github.com/juju/juju/core/migration"
+	"github.com/juju/juju/core/permission"
 	"github.com/juju/juju/environs"
 	"github.com/juju/juju/environs/context"
 	"github.com/juju/juju/environs/instances"
@@ -45,6 +47,7 @@ type Suite struct {
 
 	facadeContext facadetest.Context
 	callContext   context.ProviderCallContext
+	pinner        *fakePinner
 }
 
 var _ = gc.Suite(&Suite{})
@@ -66,15 +69,30 @@ func (s *Suite) SetUpTest(c *gc.C) {
 		AdminTag: s.Owner,
 	}
 	s.callContext = context.NewCloudCallContext()
+	s.pinner = &fakePinner{}
 	s.facadeContext = facadetest.Context{
+		State_:            s.State,
+		StatePool_:        s.StatePool,
+		Resources_:        s.resources,
+		Auth_:             s.authorizer,
+		LeadershipPinner_: s.pinner,
+	}
+}
+
+func (s *Suite) TestFacadeRegistered(c *gc.C) {
+	aFactory, err := apiserver.AllFacades().GetFactory("MigrationTarget", 2)
+	c.Assert(err, jc.ErrorIsNil)
+
+	api, err := aFactory(&facadetest.Context{
 		State_:     s.State,
-		StatePool_: s.StatePool,
 		Resources_: s.resources,
 		Auth_:      s.authorizer,
-	}
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(api, gc.FitsTypeOf, new(migrationtarget.API))
 }
 
-func (s *Suite) TestFacadeRegistered(c *gc.C) {
+func (s *Suite) TestFacadeRegisteredV1(c *gc.C) {
 	aFactory, err := apiserver.AllFacades().GetFactory("MigrationTarget", 1)
 	c.Assert(err, jc.ErrorIsNil)
 
@@ -84,7 +102,7 @@ func (s *Suite) TestFacadeRegistered(c *gc.C) {
 		Auth_:      s.authorizer,
 	})
 	c.Assert(err, jc.ErrorIsNil)
-	c.Assert(api, gc.FitsTypeOf, new(migrationtarget.API))
+	c.Assert(api, gc.FitsTypeOf, new(migrationtarget.APIV1))
 }
 
 func (s *Suite) TestNotUser(c *gc.C) {
@@ -152,6 +170,81 @@ func (s *Suite) TestImport(c *gc.C) {
 	c.Assert(model.MigrationMode(), gc.Equals, state.MigrationModeImporting)
 }
 
+func (s *Suite) TestImportWithManifest(c *gc.C) {
+	api := s.mustNewAPI(c)
+	uuid, bytes := s.makeExportedModel(c)
+	exported := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	manifest := coremigration.NewExportManifest(bytes, version.MustParse("2.7.6"), exported)
+	err := api.Import(params.SerializedModel{
+		Bytes: bytes,
+		Manifest: &params.SerializedModelManifest{
+			ExporterVersion: "2.7.6",
+			Exported:        exported,
+			Hash:            manifest.Hash,
+			Sections:        manifest.Sections,
+		},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	model, ph, err := s.StatePool.GetModel(uuid)
+	c.Assert(err, jc.ErrorIsNil)
+	defer ph.Release()
+	annotations, err := model.Annotations(model)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(annotations[coremigration.ExporterVersionAnnotation], gc.Equals, "2.7.6")
+	c.Check(annotations[coremigration.ExportedAnnotation], gc.Equals, "2020-04-01T12:00:00Z")
+	c.Check(annotations[coremigration.HashAnnotation], gc.Equals, manifest.Hash)
+	c.Check(annotations[coremigration.ImportedAnnotation], gc.Not(gc.Equals), "")
+}
+
+func (s *Suite) TestImportWithPermissions(c *gc.C) {
+	bob := names.NewUserTag("bob@external")
+	_, err := s.State.AddControllerUser(state.UserAccessSpec{
+		User: bob, CreatedBy: s.Owner, Access: permission.AddModelAccess,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	s.Factory.MakeModelUser(c, &factory.ModelUserParams{User: bob.Id()})
+
+	model, err := s.State.ExportPartial(state.ExportConfig{Permissions: state.PermissionConflictGreater})
+	c.Assert(err, jc.ErrorIsNil)
+	model.UpdateConfig(map[string]interface{}{
+		"name": "some-model",
+		"uuid": utils.MustNewUUID().String(),
+	})
+	bytes, err := description.Serialize(model)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// The target controller has not seen bob before.
+	err = s.State.RemoveUserAccess(bob, s.State.ControllerTag())
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.mustNewAPI(c).Import(params.SerializedModel{Bytes: bytes})
+	c.Assert(err, jc.ErrorIsNil)
+	access, err := s.State.UserAccess(bob, s.State.ControllerTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(access.Access, gc.Equals, permission.AddModelAccess)
+}
+
+func (s *Suite) TestImportTamperedModel(c *gc.C) {
+	api := s.mustNewAPI(c)
+	uuid, bytes := s.makeExportedModel(c)
+	manifest := coremigration.NewExportManifest(bytes, version.MustParse("2.7.6"), time.Now())
+	err := api.Import(params.SerializedModel{
+		Bytes: bytes[:len(bytes)/2],
+		Manifest: &params.SerializedModelManifest{
+			ExporterVersion: "2.7.6",
+			Hash:            manifest.Hash,
+			Sections:        manifest.Sections,
+		},
+	})
+	c.Assert(err, gc.ErrorMatches, `verifying serialized model: serialized model \(.*missing sections: .*\) not valid`)
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+
+	exists, err := s.State.ModelExists(uuid)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(exists, jc.IsFalse)
+}
+
 func (s *Suite) TestImportLeadership(c *gc.C) {
 	application := s.Factory.MakeApplication(c, &factory.ApplicationParams{
 		Charm: s.Factory.MakeCharm(c, &factory.CharmParams{
@@ -177,6 +270,80 @@ func (s *Suite) TestImportLeadership(c *gc.C) {
 
 	c.Assert(claimer.stub.Calls(), gc.HasLen, 1)
 	claimer.stub.CheckCall(c, 0, "ClaimLeadership", "wordpress", "wordpress/2", time.Minute)
+	s.pinner.stub.CheckCalls(c, []testing.StubCall{
+		{"PinLeadership", []interface{}{"wordpress", coremigration.LeadershipPinEntity}},
+	})
+}
+
+// importLeadership imports a model in which wordpress/2 leads
+// wordpress, returning the imported model's tag.
+func (s *Suite) importLeadership(c *gc.C, api *migrationtarget.API) names.ModelTag {
+	application := s.Factory.MakeApplication(c, &factory.ApplicationParams{
+		Charm: s.Factory.MakeCharm(c, &factory.CharmParams{
+			Name: "wordpress",
+		}),
+	})
+	for i := 0; i < 3; i++ {
+		s.Factory.MakeUnit(c, &factory.UnitParams{Application: application})
+	}
+	target := s.State.LeaseNotifyTarget(
+		ioutil.Discard,
+		loggo.GetLogger("migrationtarget_test"),
+	)
+	target.Claimed(
+		lease.Key{"application-leadership", s.State.ModelUUID(), "wordpress"},
+		"wordpress/2",
+	)
+	uuid, bytes := s.makeExportedModel(c)
+	// The claim made on import is recorded as it would be
+	// by the lease manager.
+	target.Claimed(
+		lease.Key{"application-leadership", uuid, "wordpress"},
+		"wordpress/2",
+	)
+	err := api.Import(params.SerializedModel{Bytes: bytes})
+	c.Assert(err, jc.ErrorIsNil)
+	return names.NewModelTag(uuid)
+}
+
+func (s *Suite) TestActivateRenewsLeadership(c *gc.C) {
+	var claimer fakeClaimer
+	s.facadeContext.LeadershipClaimer_ = &claimer
+	api := s.mustNewAPI(c)
+	tag := s.importLeadership(c, api)
+
+	err := api.Activate(params.ModelArgs{ModelTag: tag.String()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Leadership is claimed for the leader again on activation,
+	// so that it cannot expire before the leader reconnects, and
+	// then unpinned.
+	claimer.stub.CheckCalls(c, []testing.StubCall{
+		{"ClaimLeadership", []interface{}{"wordpress", "wordpress/2", time.Minute}},
+		{"ClaimLeadership", []interface{}{"wordpress", "wordpress/2", time.Minute}},
+	})
+	s.pinner.stub.CheckCalls(c, []testing.StubCall{
+		{"PinLeadership", []interface{}{"wordpress", coremigration.LeadershipPinEntity}},
+		{"PinnedLeadership", nil},
+		{"UnpinLeadership", []interface{}{"wordpress", coremigration.LeadershipPinEntity}},
+	})
+}
+
+func (s *Suite) TestAbortUnpinsLeadership(c *gc.C) {
+	var claimer fakeClaimer
+	s.facadeContext.LeadershipClaimer_ = &claimer
+	api := s.mustNewAPI(c)
+	tag := s.importLeadership(c, api)
+
+	err := api.Abort(params.ModelArgs{ModelTag: tag.String()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	claimer.stub.CheckCallNames(c, "ClaimLeadership")
+	s.pinner.stub.CheckCalls(c, []testing.StubCall{
+		{"PinLeadership", []interface{}{"wordpress", coremigration.LeadershipPinEntity}},
+		{"PinnedLeadership", nil},
+		{"UnpinLeadership", []interface{}{"wordpress", coremigration.LeadershipPinEntity}},
+	})
 }
 
 func (s *Suite) TestAbort(c *gc.C) {
@@ -216,6 +383,25 @@ func (s *Suite) TestAbortNotImportingModel(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, `migration mode for the model is not importing`)
 }
 
+func (s *Suite) TestImportProgress(c *gc.C) {
+	api := s.mustNewAPI(c)
+	tag := s.importModel(c, api)
+
+	progress, err := api.ImportProgress(params.ModelArgs{ModelTag: tag.String()})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(progress.Entities, gc.HasLen, 6)
+	for _, entity := range progress.Entities {
+		c.Check(entity.Imported, gc.Equals, entity.Total, gc.Commentf("kind %q", entity.Kind))
+	}
+}
+
+func (s *Suite) TestImportProgressMissingModel(c *gc.C) {
+	api := s.mustNewAPI(c)
+	newUUID := utils.MustNewUUID().String()
+	_, err := api.ImportProgress(params.ModelArgs{ModelTag: names.NewModelTag(newUUID).String()})
+	c.Assert(err, gc.ErrorMatches, `model "`+newUUID+`" not found`)
+}
+
 func (s *Suite) TestActivate(c *gc.C) {
 	api := s.mustNewAPI(c)
 	tag := s.importModel(c, api)
@@ -512,3 +698,35 @@ func (c *fakeClaimer) ClaimLeadership(application, unit string, duration time.Du
 	c.stub.AddCall("ClaimLeadership", application, unit, duration)
 	return c.stub.NextErr()
 }
+
+type fakePinner struct {
+	leadership.Pinner
+	stub   testing.Stub
+	pinned map[string][]string
+}
+
+func (p *fakePinner) PinLeadership(application, entity string) error {
+	p.stub.AddCall("PinLeadership", application, entity)
+	if err := p.stub.NextErr(); err != nil {
+		return err
+	}
+	if p.pinned == nil {
+		p.pinned = make(map[string][]string)
+	}
+	p.pinned[application] = append(p.pinned[application], entity)
+	return nil
+}
+
+func (p *fakePinner) UnpinLeadership(application, entity string) error {
+	p.stub.AddCall("UnpinLeadership", application, entity)
+	if err := p.stub.NextErr(); err != nil {
+		return err
+	}
+	delete(p.pinned, application)
+	return nil
+}
+
+func (p *fakePinner) PinnedLeadership() map[string][]string {
+	p.stub.AddCall("PinnedLeadership")
+	return p.pinned
+}
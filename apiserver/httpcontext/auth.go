@@ -35,6 +35,17 @@ type LocalMacaroonAuthenticator interface {
 	// log in without presenting their password for a set amount
 	// of time.
 	CreateLocalLoginMacaroon(context.Context, names.UserTag, bakery.Version) (*macaroon.Macaroon, error)
+
+	// CreateLoginMacaroon creates a macaroon with which a local
+	// user can log in, without presenting a password or obtaining
+	// a discharge, until it expires. It must only be given to users
+	// whose identity has been established some other way.
+	CreateLoginMacaroon(context.Context, names.UserTag, bakery.Version) (*macaroon.Macaroon, error)
+
+	// CreateImpersonationMacaroon creates a macaroon with which the
+	// impersonator can log in as a local user, until it expires. It
+	// must only be given to controller superusers.
+	CreateImpersonationMacaroon(ctx context.Context, tag, impersonator names.UserTag, version bakery.Version) (*macaroon.Macaroon, error)
 }
 
 // Authenticator provides an interface for authenticating a request.
@@ -95,6 +106,10 @@ type AuthInfo struct {
 	// Controller reports whether or not the authenticated
 	// entity is a controller agent.
 	Controller bool
+
+	// Impersonator, if set, is the controller superuser on whose
+	// behalf the login acts as the authenticated entity.
+	Impersonator names.UserTag
 }
 
 // BasicAuthHandler is an http.Handler that authenticates requests that
@@ -123,6 +123,29 @@ func (e RedirectErrorInfo) AsMap() map[string]interface{} {
 	return serializeToMap(e)
 }
 
+// InvalidUnitStateErrorInfo provides additional information for
+// InvalidCharmState errors, identifying the offending charm state keys.
+type InvalidUnitStateErrorInfo struct {
+	// Keys holds the charm state keys that cannot be persisted.
+	Keys []InvalidUnitStateKey `json:"keys,omitempty"`
+
+	// TotalSize is the size in bytes of the charm state,
+	// if it exceeds the limit on the total size.
+	TotalSize int `json:"total-size,omitempty"`
+}
+
+// InvalidUnitStateKey describes why a charm state key cannot be persisted.
+type InvalidUnitStateKey struct {
+	Key    string `json:"key"`
+	Size   int    `json:"size"`
+	Reason string `json:"reason"`
+}
+
+// AsMap encodes the error info as a map that can be attached to an Error.
+func (e InvalidUnitStateErrorInfo) AsMap() map[string]interface{} {
+	return serializeToMap(e)
+}
+
 // serializeToMap is a convenience function for marshaling v into a
 // map[string]interface{}. It works by marshalling v into json and then
 // unmarshaling back to a map.
@@ -185,6 +208,15 @@ const (
 	CodeIncompatibleSeries        = "incompatible series"
 	CodeCloudRegionRequired       = "cloud region required"
 	CodeIncompatibleClouds        = "incompatible clouds"
+	CodeUsernameNotAllowed        = "username not allowed"
+	CodeInvalidCharmState         = "invalid charm state"
+	CodeCharmCounterLimit         = "charm counter limit reached"
+
+	CodeSecondFactorRequired           = "second factor required"
+	CodeSecondFactorEnrollmentRequired = "second factor enrollment required"
+	CodePasswordExpired                = "password expired"
+	CodeDeviceLoginPending             = "device login pending"
+	CodeDeviceLoginSlowDown            = "device login slow down"
 )
 
 // ErrCode returns the error code associated with
@@ -358,3 +390,35 @@ func IsCodeForbidden(err error) bool {
 func IsCodeCloudRegionRequired(err error) bool {
 	return ErrCode(err) == CodeCloudRegionRequired
 }
+
+func IsCodeUsernameNotAllowed(err error) bool {
+	return ErrCode(err) == CodeUsernameNotAllowed
+}
+
+func IsCodeInvalidCharmState(err error) bool {
+	return ErrCode(err) == CodeInvalidCharmState
+}
+
+func IsCodeCharmCounterLimit(err error) bool {
+	return ErrCode(err) == CodeCharmCounterLimit
+}
+
+func IsCodeSecondFactorRequired(err error) bool {
+	return ErrCode(err) == CodeSecondFactorRequired
+}
+
+func IsCodeSecondFactorEnrollmentRequired(err error) bool {
+	return ErrCode(err) == CodeSecondFactorEnrollmentRequired
+}
+
+func IsCodePasswordExpired(err error) bool {
+	return ErrCode(err) == CodePasswordExpired
+}
+
+func IsCodeDeviceLoginPending(err error) bool {
+	return ErrCode(err) == CodeDeviceLoginPending
+}
+
+func IsCodeDeviceLoginSlowDown(err error) bool {
+	return ErrCode(err) == CodeDeviceLoginSlowDown
+}
@@ -115,3 +115,70 @@ type ControllerVersionResults struct {
 	Version   string `json:"version"`
 	GitCommit string `json:"git-commit"`
 }
+
+// CheckUnitStatesArgs holds the models whose unit state
+// documents are to be checked.
+type CheckUnitStatesArgs struct {
+	Args []CheckUnitStatesArg `json:"args"`
+}
+
+// CheckUnitStatesArg identifies a model whose unit state documents are
+// to be checked, and whether the problems found should be repaired.
+type CheckUnitStatesArg struct {
+	ModelTag string `json:"model-tag"`
+	Repair   bool   `json:"repair,omitempty"`
+}
+
+// CheckUnitStatesResults holds the results of checking
+// the unit state documents of a number of models.
+type CheckUnitStatesResults struct {
+	Results []CheckUnitStatesResult `json:"results"`
+}
+
+// CheckUnitStatesResult holds the problems found with
+// the unit state documents of a model, or an error.
+type CheckUnitStatesResult struct {
+	Problems []UnitStateProblem `json:"problems,omitempty"`
+	Error    *Error             `json:"error,omitempty"`
+}
+
+// UnitStateProblem describes a value in a unit state document that
+// does not conform to the current schema, and how it is fixed.
+type UnitStateProblem struct {
+	UnitTag string `json:"unit-tag"`
+	Field   string `json:"field"`
+	Key     string `json:"key,omitempty"`
+	Detail  string `json:"detail"`
+	Fix     string `json:"fix"`
+}
+
+// UniterStateFormatsResult reports the formats of the uniter state
+// of the units across all models of a controller.
+type UniterStateFormatsResult struct {
+	// CurrentFormat is the format of the uniter state
+	// written by agents of the controller's version.
+	CurrentFormat int `json:"current-format"`
+
+	// Converted is true if the uniter state of every unit of every
+	// model is in the current format.
+	Converted bool `json:"converted"`
+
+	// Models holds the formats of the uniter state of
+	// the units of each model.
+	Models []ModelUniterStateFormats `json:"models"`
+}
+
+// ModelUniterStateFormats holds the number of units of a model whose
+// uniter state is in each format, or an error.
+type ModelUniterStateFormats struct {
+	ModelTag string                   `json:"model-tag"`
+	Formats  []UniterStateFormatCount `json:"formats,omitempty"`
+	Error    *Error                   `json:"error,omitempty"`
+}
+
+// UniterStateFormatCount holds the number of units whose
+// uniter state is in a format.
+type UniterStateFormatCount struct {
+	Format int `json:"format"`
+	Units  int `json:"units"`
+}
@@ -256,12 +256,14 @@ type UnitStateResult struct {
 	Error *Error `json:"error,omitempty"`
 	// Specific state set by the unit via hook tool.
 	State map[string]string `json:"state,omitempty"`
-	// Uniter internal state for this unit.
-	UniterState string `json:"uniter-state,omitempty"`
+	// Uniter internal state for this unit. It is nil if the uniter
+	// has never written its state, and empty if it has cleared it.
+	UniterState *string `json:"uniter-state,omitempty"`
 	// RelationState is a internal relation state for this unit.
 	RelationState map[int]string `json:"relation-state,omitempty"`
-	// StorageState is a internal storage state for this unit.
-	StorageState string `json:"storage-state,omitempty"`
+	// StorageState is a internal storage state for this unit. It is nil
+	// if the uniter has never written it, and empty if it has cleared it.
+	StorageState *string `json:"storage-state,omitempty"`
 }
 
 // UnitStateResults holds multiple unit state maps or errors.
@@ -281,7 +283,9 @@ type SetUnitStateArgs struct {
 //
 // Each field with omitempty is optional, setting it will cause the field
 // to be evaluated for changes to the persisted data.  A pointer to nil or
-// empty data will cause the persisted data to be deleted.
+// empty data will cause the persisted data to be deleted, except for
+// UniterState and StorageState, which are recorded as empty so that
+// they can be told apart from data never written.
 type SetUnitStateArg struct {
 	Tag           string             `json:"tag"`
 	State         *map[string]string `json:"state,omitempty"`
@@ -290,6 +294,34 @@ type SetUnitStateArg struct {
 	StorageState  *string            `json:"storage-state,omitempty"`
 }
 
+// AdjustCharmCounterArgs holds the adjustments to be made to charm counters.
+type AdjustCharmCounterArgs struct {
+	Args []AdjustCharmCounterArg `json:"args"`
+}
+
+// AdjustCharmCounterArg holds an adjustment to be made to a counter
+// shared by the units of the application of the unit identified by Tag.
+// The counter is left unchanged if the adjusted value would be less
+// than Floor or greater than Ceiling, where they are set.
+type AdjustCharmCounterArg struct {
+	Tag     string `json:"tag"`
+	Key     string `json:"key"`
+	Delta   int64  `json:"delta"`
+	Floor   *int64 `json:"floor,omitempty"`
+	Ceiling *int64 `json:"ceiling,omitempty"`
+}
+
+// CharmCounterResult holds the value of a charm counter, or an error.
+type CharmCounterResult struct {
+	Value int64  `json:"value"`
+	Error *Error `json:"error,omitempty"`
+}
+
+// CharmCounterResults holds the values of multiple charm counters.
+type CharmCounterResults struct {
+	Results []CharmCounterResult `json:"results"`
+}
+
 // CommitHookChangesArgs serves as a container for CommitHookChangesArg objects
 // to be processed by the controller.
 type CommitHookChangesArgs struct {
@@ -643,6 +675,10 @@ type EntitiesWatchResults struct {
 // UnitSettings specifies the version of some unit's settings in some relation.
 type UnitSettings struct {
 	Version int64 `json:"version"`
+
+	// Observed is when the controller observed the unit's
+	// settings change, or the unit entering the relation scope.
+	Observed *time.Time `json:"observed,omitempty"`
 }
 
 // RelationUnitsChange describes the membership and settings of; or changes to;
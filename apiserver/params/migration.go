@@ -25,6 +25,12 @@ type InitiateMigrationArgs struct {
 type MigrationSpec struct {
 	ModelTag   string              `json:"model-tag"`
 	TargetInfo MigrationTargetInfo `json:"target-info"`
+
+	// Permissions, if set, requests that the controller and cloud
+	// access of the model's users are migrated with the model, and
+	// names the policy with which the target controller applies it:
+	// "keep", "greater" or "replace".
+	Permissions string `json:"permissions,omitempty"`
 }
 
 // MigrationTargetInfo holds the details required to connect to and
@@ -72,6 +78,20 @@ type SerializedModel struct {
 	Charms    []string                  `json:"charms"`
 	Tools     []SerializedModelTools    `json:"tools"`
 	Resources []SerializedModelResource `json:"resources"`
+
+	// Manifest, if set, describes the serialized model so that
+	// the target controller can verify its integrity.
+	Manifest *SerializedModelManifest `json:"manifest,omitempty"`
+}
+
+// SerializedModelManifest holds the hashes of a serialized model,
+// along with the version of the exporting controller and when it
+// exported the model.
+type SerializedModelManifest struct {
+	ExporterVersion string            `json:"exporter-version"`
+	Exported        time.Time         `json:"exported"`
+	Hash            string            `json:"sha256"`
+	Sections        map[string]string `json:"sections"`
 }
 
 // SerializedModelTools holds the version and URI for a given tools
@@ -204,6 +224,31 @@ type MinionReports struct {
 	Failed []string `json:"failed"`
 }
 
+// EntityImportProgress reports how many of the entities of a kind
+// have been imported into the target controller of a migration.
+type EntityImportProgress struct {
+	// Kind identifies the kind of entity, such as "machines".
+	Kind string `json:"kind"`
+
+	// Imported holds the number of entities imported so far.
+	Imported int `json:"imported"`
+
+	// Total holds the number of entities to be imported.
+	Total int `json:"total"`
+}
+
+// MigrationImportProgress reports the progress of the transfer of a
+// model to the target controller of its migration.
+type MigrationImportProgress struct {
+	// Entities reports the progress of the import of each
+	// kind of entity.
+	Entities []EntityImportProgress `json:"entities"`
+
+	// BinariesBytes holds the number of bytes of charms, agent
+	// binaries and resources uploaded to the target controller.
+	BinariesBytes int64 `json:"binaries-bytes,omitempty"`
+}
+
 // AdoptResourcesArgs holds the information required to ask the
 // provider to update the controller tags for a model's
 // resources.
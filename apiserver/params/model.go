@@ -134,6 +134,11 @@ type ModelMigrationStatus struct {
 	Status string     `json:"status"`
 	Start  *time.Time `json:"start"`
 	End    *time.Time `json:"end,omitempty"`
+
+	// Progress reports the progress of the transfer of the model
+	// to the target controller. It'll be nil if the transfer
+	// hasn't started.
+	Progress *MigrationImportProgress `json:"progress,omitempty"`
 }
 
 // ModelInfo holds information about the Juju model.
@@ -386,9 +391,10 @@ type UserAccessPermission string
 
 // Model access permissions that may be set on a user.
 const (
-	ModelAdminAccess UserAccessPermission = "admin"
-	ModelReadAccess  UserAccessPermission = "read"
-	ModelWriteAccess UserAccessPermission = "write"
+	ModelAdminAccess     UserAccessPermission = "admin"
+	ModelReadAccess      UserAccessPermission = "read"
+	ModelWriteAccess     UserAccessPermission = "write"
+	ModelUserAdminAccess UserAccessPermission = "user-admin"
 )
 
 // DestroyModelsParams holds the arguments for destroying models.
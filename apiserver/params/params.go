@@ -520,6 +520,11 @@ type LoginRequest struct {
 	BakeryVersion bakery.Version   `json:"bakery-version,omitempty"`
 	CLIArgs       string           `json:"cli-args,omitempty"`
 	UserData      string           `json:"user-data"`
+
+	// SecondFactor holds a code from the user's authenticator, or
+	// one of their recovery codes, for users that have enrolled a
+	// second authentication factor.
+	SecondFactor string `json:"second-factor,omitempty"`
 }
 
 // LoginRequestCompat holds credentials for identifying an entity to the Login v1
@@ -766,6 +771,10 @@ type AuthUserInfo struct {
 
 	// ModelAccess holds the access the user has to the connected model.
 	ModelAccess string `json:"model-access"`
+
+	// Defaults holds the preferences the user has stored
+	// on the controller, if any.
+	Defaults *UserDefaults `json:"defaults,omitempty"`
 }
 
 // LoginResult holds the result of an Admin Login call.
@@ -1099,6 +1108,49 @@ type DumpModelRequest struct {
 	Simplified bool     `json:"simplified"`
 }
 
+// UpgradeStepCollections describes the database collections
+// read and written by a single upgrade step.
+type UpgradeStepCollections struct {
+	Description string   `json:"description"`
+	Read        []string `json:"read,omitempty"`
+	Write       []string `json:"write,omitempty"`
+}
+
+// UpgradeStepCollectionsResult holds the collections touched by each
+// of the database upgrade steps run for the upgrade in progress.
+type UpgradeStepCollectionsResult struct {
+	PreviousVersion string                   `json:"previous-version"`
+	TargetVersion   string                   `json:"target-version"`
+	Steps           []UpgradeStepCollections `json:"steps"`
+	Error           *Error                   `json:"error,omitempty"`
+}
+
+// UpgradeInfoResult describes the controller upgrade in progress,
+// for display by agents that are not themselves being upgraded.
+type UpgradeInfoResult struct {
+	InProgress      bool       `json:"in-progress"`
+	PreviousVersion string     `json:"previous-version,omitempty"`
+	TargetVersion   string     `json:"target-version,omitempty"`
+	Status          string     `json:"status,omitempty"`
+	Started         *time.Time `json:"started,omitempty"`
+
+	// DeferredMigrations holds the model migrations refused
+	// while the controller database is being upgraded.
+	DeferredMigrations []DeferredMigration `json:"deferred-migrations,omitempty"`
+}
+
+// DeferredMigration describes a model migration refused because the
+// controller database was being upgraded, to be attempted again once
+// the upgrade has completed.
+type DeferredMigration struct {
+	ModelTag string `json:"model-tag"`
+
+	// Direction is "outgoing" for migrations from the
+	// controller, and "incoming" for those to it.
+	Direction string    `json:"direction"`
+	Attempted time.Time `json:"attempted"`
+}
+
 // UpgradeSeriesStatusResult contains the upgrade series status result for an upgrading
 // machine or unit
 type UpgradeSeriesStatusResult struct {
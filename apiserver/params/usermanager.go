@@ -5,6 +5,9 @@ package params
 
 import (
 	"time"
+
+	"gopkg.in/macaroon-bakery.v2/bakery"
+	"gopkg.in/macaroon.v2"
 )
 
 // UserInfo holds information on a user.
@@ -16,6 +19,13 @@ type UserInfo struct {
 	DateCreated    time.Time  `json:"date-created"`
 	LastConnection *time.Time `json:"last-connection,omitempty"`
 	Disabled       bool       `json:"disabled"`
+
+	// Defaults holds the preferences the user has stored
+	// on the controller, if any.
+	Defaults *UserDefaults `json:"defaults,omitempty"`
+
+	// Labels holds the labels attached to the user, if any.
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // UserInfoResult holds the result of a UserInfo call.
@@ -36,6 +46,46 @@ type UserInfoRequest struct {
 	IncludeDisabled bool     `json:"include-disabled"`
 }
 
+// ListUsersRequest defines a page of users to return.
+// Users are filtered by name prefix and ordered by the
+// requested attribute before the page is taken.
+type ListUsersRequest struct {
+	// NamePrefix, if not empty, restricts the results to users
+	// whose names start with it (case-insensitive).
+	NamePrefix string `json:"name-prefix,omitempty"`
+
+	// IncludeDisabled indicates whether disabled users are returned.
+	IncludeDisabled bool `json:"include-disabled"`
+
+	// Labels, if not empty, restricts the results
+	// to users that have all of the labels.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// SortBy is the attribute used to order users; one of "name"
+	// or "date-created". If empty, users are ordered by name.
+	SortBy string `json:"sort-by,omitempty"`
+
+	// Descending reverses the sort order.
+	Descending bool `json:"descending,omitempty"`
+
+	// Limit is the maximum number of users to return.
+	// If zero, a server-side default is used.
+	Limit int `json:"limit,omitempty"`
+
+	// Continuation is the token returned with the previous page.
+	// It is empty when requesting the first page.
+	Continuation string `json:"continuation,omitempty"`
+}
+
+// ListUsersResults holds a page of users returned by ListUsers.
+type ListUsersResults struct {
+	Results []UserInfoResult `json:"results"`
+
+	// Continuation, if not empty, is the token to supply
+	// in order to request the next page of users.
+	Continuation string `json:"continuation,omitempty"`
+}
+
 // AddUsers holds the parameters for adding new users.
 type AddUsers struct {
 	Users []AddUser `json:"users"`
@@ -52,6 +102,15 @@ type AddUser struct {
 	// be possible to login with a password until
 	// registration with the secret key is completed.
 	Password string `json:"password,omitempty"`
+
+	// ModelTags holds the models to which the user is granted
+	// ModelAccess once added. Users who administer users only
+	// within some models must specify at least one of them.
+	ModelTags []string `json:"model-tags,omitempty"`
+
+	// ModelAccess is the access granted to the models in ModelTags.
+	// If empty, read access is granted.
+	ModelAccess UserAccessPermission `json:"model-access,omitempty"`
 }
 
 // AddUserResults holds the results of the bulk AddUser API call.
@@ -67,3 +126,612 @@ type AddUserResult struct {
 	SecretKey []byte `json:"secret-key,omitempty"`
 	Error     *Error `json:"error,omitempty"`
 }
+
+// GrantModelAccessRequest holds the parameters for making
+// GrantModelAccess calls.
+type GrantModelAccessRequest struct {
+	Grants []GrantModelAccess `json:"grants"`
+}
+
+// GrantModelAccess holds the parameters for granting a user
+// access to a model.
+type GrantModelAccess struct {
+	UserTag  string               `json:"user-tag"`
+	ModelTag string               `json:"model-tag"`
+	Access   UserAccessPermission `json:"access"`
+}
+
+// GrantTemporaryAccessRequest holds the parameters for making
+// GrantTemporaryAccess calls.
+type GrantTemporaryAccessRequest struct {
+	Grants []GrantTemporaryAccess `json:"grants"`
+}
+
+// GrantTemporaryAccess holds the parameters for granting a user
+// access to a model for a limited time.
+type GrantTemporaryAccess struct {
+	UserTag  string               `json:"user-tag"`
+	ModelTag string               `json:"model-tag"`
+	Access   UserAccessPermission `json:"access"`
+
+	// TTL is how long the access is granted for, after which
+	// the user's previous access to the model is restored.
+	TTL time.Duration `json:"ttl"`
+}
+
+// TemporaryAccessResult holds the result of granting temporary access.
+type TemporaryAccessResult struct {
+	// Expires is when the granted access will be revoked.
+	Expires *time.Time `json:"expires,omitempty"`
+
+	// PreviousAccess is the access that will be restored
+	// when the grant expires. It is empty if the user had no
+	// access to the model.
+	PreviousAccess string `json:"previous-access,omitempty"`
+
+	Error *Error `json:"error,omitempty"`
+}
+
+// TemporaryAccessResults holds the results of the bulk
+// GrantTemporaryAccess API call.
+type TemporaryAccessResults struct {
+	Results []TemporaryAccessResult `json:"results"`
+}
+
+// UserDefaults holds the preferences a user has stored on the
+// controller, which are returned to clients at login.
+type UserDefaults struct {
+	// DefaultModelTag is the tag of the model the user prefers
+	// to work with, if any.
+	DefaultModelTag string `json:"default-model-tag,omitempty"`
+
+	// Timezone is the IANA name of the timezone the user prefers
+	// times to be displayed in, if any.
+	Timezone string `json:"timezone,omitempty"`
+
+	// DisplayOptions holds free-form presentation settings.
+	DisplayOptions map[string]string `json:"display-options,omitempty"`
+}
+
+// SetUserDefaultsArgs holds the parameters for making
+// SetUserDefaults calls.
+type SetUserDefaultsArgs struct {
+	Args []SetUserDefaults `json:"args"`
+}
+
+// SetUserDefaults holds the preferences to store for a user.
+type SetUserDefaults struct {
+	UserTag  string       `json:"user-tag"`
+	Defaults UserDefaults `json:"defaults"`
+}
+
+// UserNotification describes the delivery of a notification
+// about an event on a user's account.
+type UserNotification struct {
+	// Event identifies the kind of account event.
+	Event string `json:"event"`
+
+	// Channel identifies how the notification was delivered,
+	// either "webhook" or "email".
+	Channel string `json:"channel"`
+
+	// Time is when delivery of the notification was attempted.
+	Time time.Time `json:"time"`
+
+	// Delivered is true if the notification was delivered.
+	Delivered bool `json:"delivered"`
+
+	// Error holds the reason the notification was not delivered.
+	Error string `json:"error,omitempty"`
+}
+
+// UserNotificationsResult holds the notifications for a user.
+type UserNotificationsResult struct {
+	Result []UserNotification `json:"result,omitempty"`
+	Error  *Error             `json:"error,omitempty"`
+}
+
+// UserNotificationsResults holds the results of the bulk
+// UserNotifications API call.
+type UserNotificationsResults struct {
+	Results []UserNotificationsResult `json:"results"`
+}
+
+// PreviewUsernames holds the names of users that would be
+// added, for the PreviewUsername API call.
+type PreviewUsernames struct {
+	Usernames []string `json:"usernames"`
+}
+
+// UserPermissions holds the access a user has been granted to the
+// controller, and to the models, clouds and offers that it hosts.
+// Models and offers are keyed by UUID, and clouds by name.
+type UserPermissions struct {
+	Username string `json:"username" yaml:"username"`
+
+	// Labels holds the labels attached to the user, for
+	// reference when reviewing access. They are not changed
+	// by ImportPermissions.
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	Controller string            `json:"controller,omitempty" yaml:"controller,omitempty"`
+	Models     map[string]string `json:"models,omitempty" yaml:"models,omitempty"`
+	Clouds     map[string]string `json:"clouds,omitempty" yaml:"clouds,omitempty"`
+	Offers     map[string]string `json:"offers,omitempty" yaml:"offers,omitempty"`
+}
+
+// PermissionsDocument holds the access granted to every user of
+// a controller, as returned by the ExportPermissions API call.
+// Juju has no user groups; access granted to everyone@external
+// applies to all external users.
+type PermissionsDocument struct {
+	Users []UserPermissions `json:"users" yaml:"users"`
+}
+
+// ExportPermissionsArgs holds the arguments for the
+// ExportPermissions API call.
+type ExportPermissionsArgs struct {
+	// Labels, if not empty, restricts the document to
+	// the users that have all of the labels.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// ImportPermissionsArgs holds the arguments for the
+// ImportPermissions API call.
+type ImportPermissionsArgs struct {
+	// Users holds the access to grant to each user.
+	Users []UserPermissions `json:"users"`
+
+	// Revoke reports whether access that the users have been
+	// granted, but which is not in Users, should be revoked.
+	Revoke bool `json:"revoke,omitempty"`
+}
+
+// RequestAccessArgs holds the parameters for making RequestAccess calls.
+type RequestAccessArgs struct {
+	Requests []RequestAccess `json:"requests"`
+}
+
+// RequestAccess holds the parameters for requesting
+// access to a model for the calling user.
+type RequestAccess struct {
+	ModelTag string               `json:"model-tag"`
+	Access   UserAccessPermission `json:"access"`
+
+	// Reason is why the user needs the access,
+	// for the model's administrators to review.
+	Reason string `json:"reason,omitempty"`
+}
+
+// AccessRequest describes a user's request for access to a model.
+type AccessRequest struct {
+	ID        string    `json:"id"`
+	UserTag   string    `json:"user-tag"`
+	ModelTag  string    `json:"model-tag"`
+	Access    string    `json:"access"`
+	Reason    string    `json:"reason,omitempty"`
+	Requested time.Time `json:"requested"`
+
+	// Status is one of "pending", "approved" or "denied".
+	Status string `json:"status"`
+
+	// DecidedBy is the tag of the user that approved or
+	// denied the request, if it is no longer pending.
+	DecidedBy string `json:"decided-by,omitempty"`
+
+	// Decided is when the request was approved or denied.
+	Decided *time.Time `json:"decided,omitempty"`
+}
+
+// AccessRequestResult holds an access request, or the
+// error encountered making or reading it.
+type AccessRequestResult struct {
+	Result *AccessRequest `json:"result,omitempty"`
+	Error  *Error         `json:"error,omitempty"`
+}
+
+// AccessRequestResults holds the results of the bulk
+// RequestAccess API call.
+type AccessRequestResults struct {
+	Results []AccessRequestResult `json:"results"`
+}
+
+// AccessRequestsResult holds the access requests
+// returned by the ListAccessRequests API call.
+type AccessRequestsResult struct {
+	Requests []AccessRequest `json:"requests"`
+}
+
+// AccessRequestIDs holds the IDs of the access requests
+// to approve or deny.
+type AccessRequestIDs struct {
+	IDs []string `json:"ids"`
+}
+
+// TOTPEnrollment holds the secret with which a user's authenticator
+// application is provisioned, to enroll it as a second factor.
+type TOTPEnrollment struct {
+	// Secret is the secret, base32 encoded.
+	Secret string `json:"secret"`
+
+	// ProvisioningURI is the otpauth URI holding the secret,
+	// usually presented to the authenticator as a QR code.
+	ProvisioningURI string `json:"provisioning-uri"`
+}
+
+// TOTPEnrollmentResult holds a TOTP enrollment, or the
+// error encountered starting it.
+type TOTPEnrollmentResult struct {
+	Result *TOTPEnrollment `json:"result,omitempty"`
+	Error  *Error          `json:"error,omitempty"`
+}
+
+// TOTPEnrollmentResults holds the results of the bulk
+// EnrollTOTP API call.
+type TOTPEnrollmentResults struct {
+	Results []TOTPEnrollmentResult `json:"results"`
+}
+
+// VerifyTOTPArgs holds the parameters for making VerifyTOTP calls.
+type VerifyTOTPArgs struct {
+	Args []VerifyTOTPArg `json:"args"`
+}
+
+// VerifyTOTPArg holds a code from the authenticator of the
+// user with the tag, completing its enrollment.
+type VerifyTOTPArg struct {
+	Tag  string `json:"tag"`
+	Code string `json:"code"`
+}
+
+// RecoveryCodesResult holds the recovery codes issued to a user
+// when their second factor enrollment is complete, or the error
+// encountered completing it.
+type RecoveryCodesResult struct {
+	RecoveryCodes []string `json:"recovery-codes,omitempty"`
+	Error         *Error   `json:"error,omitempty"`
+}
+
+// RecoveryCodesResults holds the results of the bulk
+// VerifyTOTP API call.
+type RecoveryCodesResults struct {
+	Results []RecoveryCodesResult `json:"results"`
+}
+
+// PermissionEvent describes a change to a user's controller or model
+// access whose delivery to the permission webhook has been abandoned.
+type PermissionEvent struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	UserTag   string    `json:"user-tag"`
+	TargetTag string    `json:"target-tag"`
+	Access    string    `json:"access"`
+	Time      time.Time `json:"time"`
+
+	// By is the tag of the user that changed the access. It is empty
+	// if the access was changed by the controller itself.
+	By string `json:"by,omitempty"`
+
+	// Attempts is the number of failed attempts to deliver the event.
+	Attempts int `json:"attempts"`
+
+	// LastError is the reason the last attempt failed.
+	LastError string `json:"last-error,omitempty"`
+}
+
+// PermissionEventsResult holds the result of a
+// PermissionWebhookDeadLetters API call.
+type PermissionEventsResult struct {
+	Events []PermissionEvent `json:"events"`
+}
+
+// PermissionEventIDs holds the IDs of the permission
+// events to retry delivering.
+type PermissionEventIDs struct {
+	IDs []string `json:"ids"`
+}
+
+// LoginActivityArgs holds the arguments of a LoginActivity API call.
+type LoginActivityArgs struct {
+	// Entities holds the tags of the users to report on.
+	Entities []Entity `json:"entities"`
+
+	// Since is the start of the period to report on.
+	Since time.Time `json:"since"`
+}
+
+// ModelLoginActivity summarises a user's logins to a model,
+// or to the controller, over the period reported on.
+type ModelLoginActivity struct {
+	// ModelTag is the tag of the model the user logged in
+	// to. It is empty for logins to the controller itself.
+	ModelTag string `json:"model-tag,omitempty"`
+
+	Connections    int       `json:"connections"`
+	LastConnection time.Time `json:"last-connection"`
+
+	// Addresses holds the distinct addresses
+	// the user logged in from.
+	Addresses []string `json:"addresses"`
+}
+
+// LoginActivityResult holds a user's login activity,
+// or the error encountered retrieving it.
+type LoginActivityResult struct {
+	Models []ModelLoginActivity `json:"models,omitempty"`
+	Error  *Error               `json:"error,omitempty"`
+}
+
+// LoginActivityResults holds the results of a LoginActivity API call.
+type LoginActivityResults struct {
+	Results []LoginActivityResult `json:"results"`
+}
+
+// TransferCredentialArgs holds the arguments of a TransferCredential API call.
+type TransferCredentialArgs struct {
+	Credentials []TransferCredentialArg `json:"credentials"`
+}
+
+// TransferCredentialArg identifies a cloud credential
+// and the user to transfer its ownership to.
+type TransferCredentialArg struct {
+	CredentialTag string `json:"credential-tag"`
+	NewOwnerTag   string `json:"new-owner-tag"`
+}
+
+// TransferredCredentialModel identifies a model that was
+// updated to use a transferred cloud credential.
+type TransferredCredentialModel struct {
+	ModelUUID string `json:"uuid"`
+	ModelName string `json:"name"`
+}
+
+// TransferCredentialResult holds the tag of a transferred cloud
+// credential and the models that use it, or the error encountered
+// transferring it.
+type TransferCredentialResult struct {
+	CredentialTag string                       `json:"credential-tag,omitempty"`
+	Models        []TransferredCredentialModel `json:"models,omitempty"`
+	Error         *Error                       `json:"error,omitempty"`
+}
+
+// TransferCredentialResults holds the results of a TransferCredential API call.
+type TransferCredentialResults struct {
+	Results []TransferCredentialResult `json:"results"`
+}
+
+// PasswordExpiryArgs holds the arguments of a PasswordExpiry API call.
+type PasswordExpiryArgs struct {
+	// Within is how soon the passwords of the
+	// users reported on must expire.
+	Within time.Duration `json:"within"`
+}
+
+// UserPasswordExpiry reports when a user's password expires.
+type UserPasswordExpiry struct {
+	UserTag     string    `json:"user-tag"`
+	PasswordSet time.Time `json:"password-set"`
+	Expires     time.Time `json:"expires"`
+	Expired     bool      `json:"expired"`
+}
+
+// PasswordExpiryResult holds the results of a PasswordExpiry API call.
+type PasswordExpiryResult struct {
+	// MaxAge is the length of time after which passwords expire.
+	// It is zero if passwords do not expire.
+	MaxAge time.Duration `json:"max-age"`
+
+	// Users holds the users whose passwords have expired or
+	// are about to, in the order in which they expire.
+	Users []UserPasswordExpiry `json:"users"`
+}
+
+// UpdateUserLabelsArgs holds the parameters for making
+// UpdateUserLabels calls.
+type UpdateUserLabelsArgs struct {
+	Args []UpdateUserLabels `json:"args"`
+}
+
+// UpdateUserLabels holds the changes to make to the labels of a user.
+type UpdateUserLabels struct {
+	UserTag string `json:"user-tag"`
+
+	// Set holds the labels to attach to the user, replacing
+	// the values of any existing labels with the same keys.
+	Set map[string]string `json:"set,omitempty"`
+
+	// Unset holds the keys of the labels to remove.
+	Unset []string `json:"unset,omitempty"`
+}
+
+// DeviceLoginResult holds the result of a StartDeviceLogin API call.
+type DeviceLoginResult struct {
+	// DeviceCode identifies the login when polling for its
+	// completion. It is not shown to the user.
+	DeviceCode string `json:"device-code"`
+
+	// UserCode is the code that the user enters at the
+	// verification URI to approve the login.
+	UserCode string `json:"user-code"`
+
+	// VerificationURI is where the user approves the login.
+	VerificationURI string `json:"verification-uri"`
+
+	// VerificationURIComplete, if set, is the verification URI
+	// with the user code included.
+	VerificationURIComplete string `json:"verification-uri-complete,omitempty"`
+
+	// Interval is the minimum time to wait between polls.
+	Interval time.Duration `json:"interval"`
+
+	// Expiry is when the login expires.
+	Expiry time.Time `json:"expiry"`
+}
+
+// PollDeviceLoginArgs holds the parameters for making
+// PollDeviceLogin calls.
+type PollDeviceLoginArgs struct {
+	Logins []PollDeviceLogin `json:"logins"`
+}
+
+// PollDeviceLogin identifies a device login to poll for completion.
+type PollDeviceLogin struct {
+	DeviceCode string `json:"device-code"`
+
+	// BakeryVersion is the version of the bakery with which
+	// the login macaroon is minted.
+	BakeryVersion bakery.Version `json:"bakery-version,omitempty"`
+}
+
+// PollDeviceLoginResult holds the result of polling a device login.
+type PollDeviceLoginResult struct {
+	// UserTag is the tag of the user that logged in.
+	UserTag string `json:"user-tag,omitempty"`
+
+	// Macaroon is presented when logging in as the user.
+	Macaroon *macaroon.Macaroon `json:"macaroon,omitempty"`
+
+	Error *Error `json:"error,omitempty"`
+}
+
+// PollDeviceLoginResults holds the results of a PollDeviceLogin API call.
+type PollDeviceLoginResults struct {
+	Results []PollDeviceLoginResult `json:"results"`
+}
+
+// Session describes a user's live connection to an API server.
+type Session struct {
+	// SessionID identifies the session on the API server serving it.
+	SessionID string `json:"session-id"`
+
+	UserTag string `json:"user-tag"`
+
+	// ModelTag is the tag of the model the user is connected
+	// to. It is empty for connections to the controller itself.
+	ModelTag string `json:"model-tag,omitempty"`
+
+	// RemoteAddress is the address the user connected from.
+	RemoteAddress string `json:"remote-address"`
+
+	// UserAgent is the agent string sent by the client, if any.
+	UserAgent string `json:"user-agent,omitempty"`
+
+	// Started is when the user logged in.
+	Started time.Time `json:"started"`
+
+	// ImpersonatorTag is the tag of the controller superuser
+	// logged in as the user, if the session is an impersonation.
+	ImpersonatorTag string `json:"impersonator-tag,omitempty"`
+}
+
+// SessionsResult holds a user's live sessions,
+// or the error encountered listing them.
+type SessionsResult struct {
+	Sessions []Session `json:"sessions,omitempty"`
+	Error    *Error    `json:"error,omitempty"`
+}
+
+// SessionsResults holds the results of a ListSessions API call.
+type SessionsResults struct {
+	Results []SessionsResult `json:"results"`
+}
+
+// TerminateSessionArgs holds the parameters for making
+// TerminateSession calls.
+type TerminateSessionArgs struct {
+	SessionIDs []string `json:"session-ids"`
+}
+
+// GuestAccessArgs holds the parameters for making EnableGuestAccess
+// calls: the models the guest user may see.
+type GuestAccessArgs struct {
+	ModelTags []string `json:"model-tags"`
+}
+
+// GuestAccessResult reports whether clients may log in as the
+// read-only guest user, and the models the guest user may see.
+type GuestAccessResult struct {
+	Enabled   bool     `json:"enabled"`
+	ModelTags []string `json:"model-tags"`
+}
+
+// ImpersonateArgs holds the parameters for making Impersonate calls.
+type ImpersonateArgs struct {
+	Impersonations []Impersonation `json:"impersonations"`
+}
+
+// Impersonation identifies a user as whom a controller
+// superuser wants to log in, and why.
+type Impersonation struct {
+	UserTag string `json:"user-tag"`
+
+	// Reason is recorded with the impersonation, to
+	// explain it to those reviewing the audit log.
+	Reason string `json:"reason"`
+
+	// BakeryVersion is the version of the bakery with which
+	// the impersonation macaroon is minted.
+	BakeryVersion bakery.Version `json:"bakery-version,omitempty"`
+}
+
+// ImpersonateResult holds a macaroon with which a controller
+// superuser can log in as another user, or an error.
+type ImpersonateResult struct {
+	// Macaroon is presented when logging in as the user.
+	Macaroon *macaroon.Macaroon `json:"macaroon,omitempty"`
+
+	// Expiry is when the macaroon expires.
+	Expiry time.Time `json:"expiry,omitempty"`
+
+	Error *Error `json:"error,omitempty"`
+}
+
+// ImpersonateResults holds the results of an Impersonate API call.
+type ImpersonateResults struct {
+	Results []ImpersonateResult `json:"results"`
+}
+
+// AccessReportArgs holds the arguments for the AccessReport API call.
+type AccessReportArgs struct {
+	// Labels, if not empty, restricts the report to
+	// the local users that have all of the labels.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// AccessReport describes the users of a controller and the access they
+// have been granted, as returned by the AccessReport API call. Its JSON
+// encoding conforms to the schema of the same version returned by the
+// AccessReportSchema API call.
+type AccessReport struct {
+	SchemaVersion  int                `json:"schema-version"`
+	ControllerUUID string             `json:"controller-uuid"`
+	Users          []AccessReportUser `json:"users"`
+}
+
+// AccessReportUser describes a user in an access report.
+type AccessReportUser struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"display-name,omitempty"`
+
+	// External reports whether the user is managed by an
+	// external identity provider rather than the controller.
+	External bool `json:"external"`
+	Disabled bool `json:"disabled"`
+
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Controller is the user's access to the controller, and Models,
+	// Clouds and Offers the user's access to models keyed by UUID,
+	// clouds keyed by name and offers keyed by UUID.
+	Controller string            `json:"controller,omitempty"`
+	Models     map[string]string `json:"models,omitempty"`
+	Clouds     map[string]string `json:"clouds,omitempty"`
+	Offers     map[string]string `json:"offers,omitempty"`
+}
+
+// AccessReportSchema holds the JSON schema to which access
+// reports conform, as returned by the AccessReportSchema API call.
+type AccessReportSchema struct {
+	Version int    `json:"version"`
+	Schema  string `json:"schema"`
+}
@@ -24,15 +24,37 @@ var anonymousFacadeNames = set.NewStrings(
 	"StringsWatcher",
 )
 
-func anonymousFacadesOnly(facadeName, _ string) error {
+// anonymousFacadeMethods holds the methods of facades that otherwise
+// require an authenticated user that can be called using an anonymous
+// login, so that users can log in with an external identity provider
+// before they have any other credentials.
+var anonymousFacadeMethods = map[string]set.Strings{
+	"UserManager": set.NewStrings(
+		"StartDeviceLogin",
+		"PollDeviceLogin",
+	),
+}
+
+func anonymousFacadesOnly(facadeName, methodName string) error {
 	if !IsAnonymousFacade(facadeName) {
 		return errors.NewNotSupported(nil, fmt.Sprintf("facade %q not supported for anonymous API connections", facadeName))
 	}
+	return anonymousMethodsOnly(facadeName, methodName)
+}
+
+// anonymousMethodsOnly allows anonymous connections to call only the
+// anonymous methods of the facades in anonymousFacadeMethods.
+func anonymousMethodsOnly(facadeName, methodName string) error {
+	methods, ok := anonymousFacadeMethods[facadeName]
+	if ok && !methods.Contains(methodName) {
+		return errors.NewNotSupported(nil, fmt.Sprintf("method %s.%s not supported for anonymous API connections", facadeName, methodName))
+	}
 	return nil
 }
 
 // IsAnonymousFacade reports whether the given facade name can be accessed
 // using an anonymous connection.
 func IsAnonymousFacade(facadeName string) bool {
-	return anonymousFacadeNames.Contains(facadeName)
+	_, ok := anonymousFacadeMethods[facadeName]
+	return ok || anonymousFacadeNames.Contains(facadeName)
 }
@@ -29,6 +29,18 @@ func (s *restrictAnonymousSuite) TestAllowed(c *gc.C) {
 	s.assertMethod(c, "CrossModelRelations", 1, "RegisterRemoteRelations")
 }
 
+func (s *restrictAnonymousSuite) TestDeviceLoginAllowed(c *gc.C) {
+	s.assertMethod(c, "UserManager", 17, "StartDeviceLogin")
+	s.assertMethod(c, "UserManager", 17, "PollDeviceLogin")
+}
+
+func (s *restrictAnonymousSuite) TestMethodNotAllowed(c *gc.C) {
+	caller, err := s.root.FindMethod("UserManager", 17, "AddUser")
+	c.Assert(err, gc.ErrorMatches, `method UserManager.AddUser not supported for anonymous API connections`)
+	c.Assert(errors.IsNotSupported(err), jc.IsTrue)
+	c.Assert(caller, gc.IsNil)
+}
+
 func (s *restrictAnonymousSuite) TestNotAllowed(c *gc.C) {
 	caller, err := s.root.FindMethod("Client", 1, "FullStatus")
 	c.Assert(err, gc.ErrorMatches, `facade "Client" not supported for anonymous API connections`)
@@ -0,0 +1,78 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package apiserver
+
+import (
+	"github.com/juju/collections/set"
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/httpcontext"
+	"github.com/juju/juju/state"
+)
+
+// guestMethodsOnly allows only the read-only calls that may be made
+// by clients logged in as the guest user.
+func guestMethodsOnly(facadeName, methodName string) error {
+	methods, ok := allowedGuestMethods[facadeName]
+	if !ok || !methods.Contains(methodName) {
+		return common.ErrPerm
+	}
+	return nil
+}
+
+// IsGuestFacade reports whether the given facade name can be
+// accessed by clients logged in as the guest user.
+func IsGuestFacade(facadeName string) bool {
+	_, ok := allowedGuestMethods[facadeName]
+	return ok
+}
+
+// allowedGuestMethods stores the api calls that are not blocked
+// for clients logged in as the guest user. They only read the
+// models to which the guest user has been granted access.
+var allowedGuestMethods = map[string]set.Strings{
+	"Client": set.NewStrings(
+		"FullStatus", // for "juju status"
+	),
+	"ModelManager": set.NewStrings(
+		// for "juju models" and "juju show-model"
+		"ListModels",
+		"ListModelSummaries",
+		"ModelInfo",
+	),
+	"Storage": set.NewStrings(
+		// for "juju status --storage"
+		"ListFilesystems",
+		"ListVolumes",
+		"ListStorageDetails",
+	),
+	"Pinger": set.NewStrings(
+		"Ping",
+	),
+}
+
+// isGuestLogin returns whether the login request is a login as the
+// guest user, which is made without credentials.
+func isGuestLogin(tag names.UserTag, credentials string, macaroons int) bool {
+	return tag.IsLocal() && tag.Name() == state.GuestUserName && credentials == "" && macaroons == 0
+}
+
+// authenticateGuest authenticates a login as the guest user, which
+// is allowed only while the controller config enables guest access.
+func (a *admin) authenticateGuest() (httpcontext.AuthInfo, error) {
+	if !a.srv.shared.guestAccess() {
+		return httpcontext.AuthInfo{}, errors.Unauthorizedf("guest access is disabled")
+	}
+	user, err := a.root.state.User(names.NewUserTag(state.GuestUserName))
+	if err != nil && !errors.IsNotFound(err) {
+		return httpcontext.AuthInfo{}, errors.Trace(err)
+	}
+	// As with other logins, hide whether the model exists.
+	if err != nil || !user.IsGuest() || user.IsDisabled() || a.root.model == nil {
+		return httpcontext.AuthInfo{}, errors.Unauthorizedf("invalid entity name or password")
+	}
+	return httpcontext.AuthInfo{Entity: user}, nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package apiserver_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver"
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/testing"
+)
+
+type restrictGuestSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&restrictGuestSuite{})
+
+func (r *restrictGuestSuite) TestAllowedMethods(c *gc.C) {
+	root := apiserver.TestingGuestRoot()
+	checkAllowed := func(facade, method string, version int) {
+		caller, err := root.FindMethod(facade, version, method)
+		c.Check(err, jc.ErrorIsNil)
+		c.Check(caller, gc.NotNil)
+	}
+	checkAllowed("Client", "FullStatus", 1)
+	checkAllowed("ModelManager", "ListModelSummaries", 8)
+	checkAllowed("ModelManager", "ModelInfo", 8)
+	checkAllowed("Storage", "ListStorageDetails", 6)
+	checkAllowed("Pinger", "Ping", 1)
+}
+
+func (r *restrictGuestSuite) TestFindDisallowedMethod(c *gc.C) {
+	root := apiserver.TestingGuestRoot()
+	caller, err := root.FindMethod("Client", 1, "AddMachines")
+	c.Assert(errors.Cause(err), gc.Equals, common.ErrPerm)
+	c.Assert(caller, gc.IsNil)
+	caller, err = root.FindMethod("UserManager", 18, "UserInfo")
+	c.Assert(errors.Cause(err), gc.Equals, common.ErrPerm)
+	c.Assert(caller, gc.IsNil)
+}
+
+func (r *restrictGuestSuite) TestIsGuestFacade(c *gc.C) {
+	c.Check(apiserver.IsGuestFacade("ModelManager"), jc.IsTrue)
+	c.Check(apiserver.IsGuestFacade("Application"), jc.IsFalse)
+}
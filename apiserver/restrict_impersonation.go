@@ -0,0 +1,48 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package apiserver
+
+import (
+	"github.com/juju/collections/set"
+
+	"github.com/juju/juju/apiserver/common"
+)
+
+// impersonationMethodsBlocked blocks the calls that may not be made by
+// controller superusers logged in as another user. The impersonated
+// user's own permissions apply to all other calls.
+func impersonationMethodsBlocked(facadeName, methodName string) error {
+	if methods, ok := blockedImpersonationMethods[facadeName]; ok && methods.Contains(methodName) {
+		return common.ErrPerm
+	}
+	return nil
+}
+
+// blockedImpersonationMethods stores the api calls that are blocked for
+// superusers logged in as another user. They change the user's password,
+// second factor or cloud credentials, reveal the secrets of those
+// credentials, or would let the impersonation be extended beyond the life
+// of the macaroon it was made with.
+var blockedImpersonationMethods = map[string]set.Strings{
+	"Cloud": set.NewStrings(
+		"AddCredentials",
+		"CredentialContents",
+		"UpdateCredentials",
+		"UpdateCredentialsCheckModels",
+		"RevokeCredentials",
+		"RevokeCredentialsCheckModels",
+	),
+	"ModelManager": set.NewStrings(
+		"ChangeModelCredential",
+	),
+	"UserManager": set.NewStrings(
+		"SetPassword",
+		"ResetPassword",
+		"EnrollTOTP",
+		"VerifyTOTP",
+		"RemoveTOTP",
+		"TransferCredential",
+		"Impersonate",
+	),
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package apiserver_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver"
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/testing"
+)
+
+type restrictImpersonationSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&restrictImpersonationSuite{})
+
+func (r *restrictImpersonationSuite) TestAllowedMethods(c *gc.C) {
+	root := apiserver.TestingImpersonationRoot()
+	checkAllowed := func(facade, method string, version int) {
+		caller, err := root.FindMethod(facade, version, method)
+		c.Check(err, jc.ErrorIsNil)
+		c.Check(caller, gc.NotNil)
+	}
+	checkAllowed("Client", "FullStatus", 1)
+	checkAllowed("ModelManager", "ModelInfo", 8)
+	checkAllowed("UserManager", "UserInfo", 20)
+	checkAllowed("Pinger", "Ping", 1)
+	checkAllowed("Cloud", "Credential", 6)
+}
+
+func (r *restrictImpersonationSuite) TestFindBlockedMethod(c *gc.C) {
+	root := apiserver.TestingImpersonationRoot()
+	for _, method := range []string{"SetPassword", "EnrollTOTP", "Impersonate"} {
+		caller, err := root.FindMethod("UserManager", 20, method)
+		c.Check(errors.Cause(err), gc.Equals, common.ErrPerm, gc.Commentf("method %s", method))
+		c.Check(caller, gc.IsNil)
+	}
+}
+
+func (r *restrictImpersonationSuite) TestFindBlockedCredentialMethod(c *gc.C) {
+	root := apiserver.TestingImpersonationRoot()
+	checkBlocked := func(facade, method string, version int) {
+		caller, err := root.FindMethod(facade, version, method)
+		c.Check(errors.Cause(err), gc.Equals, common.ErrPerm, gc.Commentf("method %s.%s", facade, method))
+		c.Check(caller, gc.IsNil)
+	}
+	checkBlocked("Cloud", "CredentialContents", 6)
+	checkBlocked("Cloud", "AddCredentials", 6)
+	checkBlocked("Cloud", "UpdateCredentialsCheckModels", 6)
+	checkBlocked("Cloud", "RevokeCredentialsCheckModels", 6)
+	checkBlocked("Cloud", "UpdateCredentials", 2)
+	checkBlocked("Cloud", "RevokeCredentials", 2)
+	checkBlocked("ModelManager", "ChangeModelCredential", 8)
+}
@@ -0,0 +1,52 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package apiserver
+
+import (
+	"time"
+
+	"github.com/juju/collections/set"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/state"
+)
+
+// passwordExpiredMethodsOnly allows only the calls needed to change
+// an expired password, for users who must change it before they can
+// use the controller.
+func passwordExpiredMethodsOnly(facadeName, methodName string) error {
+	methods, ok := allowedMethodsWithExpiredPassword[facadeName]
+	if !ok || !methods.Contains(methodName) {
+		return common.ErrPasswordExpired
+	}
+	return nil
+}
+
+// allowedMethodsWithExpiredPassword stores the api calls that
+// are not blocked for users whose passwords have expired.
+var allowedMethodsWithExpiredPassword = map[string]set.Strings{
+	"UserManager": set.NewStrings(
+		"SetPassword",
+	),
+	"Pinger": set.NewStrings(
+		"Ping",
+	),
+}
+
+// passwordExpired returns whether the authenticated entity is a local
+// user whose password has expired, if passwords expire after maxAge.
+// External users have no password to expire.
+func passwordExpired(entity state.Entity, maxAge time.Duration) bool {
+	if maxAge <= 0 {
+		return false
+	}
+	if tag, ok := entity.Tag().(names.UserTag); !ok || !tag.IsLocal() {
+		return false
+	}
+	user, ok := entity.(interface {
+		PasswordExpired(time.Duration) bool
+	})
+	return ok && user.PasswordExpired(maxAge)
+}
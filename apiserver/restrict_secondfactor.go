@@ -0,0 +1,49 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package apiserver
+
+import (
+	"github.com/juju/collections/set"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/state"
+)
+
+// secondFactorEnrollmentMethodsOnly allows only the calls needed to
+// enroll a second factor, for users who must enroll one before they
+// can use the controller.
+func secondFactorEnrollmentMethodsOnly(facadeName, methodName string) error {
+	methods, ok := allowedMethodsBeforeSecondFactorEnrollment[facadeName]
+	if !ok || !methods.Contains(methodName) {
+		return common.ErrSecondFactorEnrollmentRequired
+	}
+	return nil
+}
+
+// allowedMethodsBeforeSecondFactorEnrollment stores the api calls
+// that are not blocked for users that have yet to enroll a second
+// factor, when the controller requires one.
+var allowedMethodsBeforeSecondFactorEnrollment = map[string]set.Strings{
+	"UserManager": set.NewStrings(
+		"EnrollTOTP",
+		"VerifyTOTP",
+	),
+	"Pinger": set.NewStrings(
+		"Ping",
+	),
+}
+
+// secondFactorEnrolled returns whether the authenticated entity is a
+// local user that has enrolled a second factor. External users are
+// treated as enrolled, since their identity manager authenticates them.
+func secondFactorEnrolled(entity state.Entity) bool {
+	if tag, ok := entity.Tag().(names.UserTag); !ok || !tag.IsLocal() {
+		return true
+	}
+	user, ok := entity.(interface {
+		SecondFactorEnrolled() bool
+	})
+	return ok && user.SecondFactorEnrolled()
+}
@@ -0,0 +1,42 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package apiserver_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver"
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/testing"
+)
+
+type restrictSecondFactorSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&restrictSecondFactorSuite{})
+
+func (r *restrictSecondFactorSuite) TestAllowedMethods(c *gc.C) {
+	root := apiserver.TestingSecondFactorEnrollmentRoot()
+	checkAllowed := func(facade, method string, version int) {
+		caller, err := root.FindMethod(facade, version, method)
+		c.Check(err, jc.ErrorIsNil)
+		c.Check(caller, gc.NotNil)
+	}
+	checkAllowed("UserManager", "EnrollTOTP", 10)
+	checkAllowed("UserManager", "VerifyTOTP", 10)
+	checkAllowed("Pinger", "Ping", 1)
+}
+
+func (r *restrictSecondFactorSuite) TestFindDisallowedMethod(c *gc.C) {
+	root := apiserver.TestingSecondFactorEnrollmentRoot()
+	caller, err := root.FindMethod("UserManager", 10, "RemoveTOTP")
+	c.Assert(errors.Cause(err), gc.Equals, common.ErrSecondFactorEnrollmentRequired)
+	c.Assert(caller, gc.IsNil)
+	caller, err = root.FindMethod("Client", 1, "FullStatus")
+	c.Assert(errors.Cause(err), gc.Equals, common.ErrSecondFactorEnrollmentRequired)
+	c.Assert(caller, gc.IsNil)
+}
@@ -32,9 +32,10 @@ func IsMethodAllowedDuringUpgrade(facadeName, methodName string) bool {
 // facade versions as well.
 var allowedMethodsDuringUpgrades = map[string]set.Strings{
 	"Client": set.NewStrings(
-		"FullStatus",          // for "juju status"
-		"FindTools",           // for "juju upgrade-model", before we can reset upgrade to re-run
-		"AbortCurrentUpgrade", // for "juju upgrade-model", so that we can reset upgrade to re-run
+		"FullStatus",            // for "juju status"
+		"FindTools",             // for "juju upgrade-model", before we can reset upgrade to re-run
+		"AbortCurrentUpgrade",   // for "juju upgrade-model", so that we can reset upgrade to re-run
+		"ApproveCurrentUpgrade", // so that database upgrades awaiting approval can proceed
 
 	),
 	"SSHClient": set.NewStrings( // allow all SSH client related calls
@@ -31,6 +31,10 @@ func (r *restrictUpgradesSuite) TestAllowedMethods(c *gc.C) {
 	checkAllowed("SSHClient", "PublicAddress")
 	checkAllowed("SSHClient", "Proxy")
 	checkAllowed("Pinger", "Ping")
+
+	caller, err := root.FindMethod("Client", 3, "ApproveCurrentUpgrade")
+	c.Check(err, jc.ErrorIsNil)
+	c.Check(caller, gc.NotNil)
 }
 
 func (r *restrictUpgradesSuite) TestFindDisallowedMethod(c *gc.C) {
@@ -6,6 +6,7 @@ package apiserver
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/url"
 	"reflect"
 	"sync"
@@ -67,12 +68,23 @@ type apiHandler struct {
 	// serverHost is the host:port of the API server that the client
 	// connected to.
 	serverHost string
+
+	// clientAddress is the address the client connected from,
+	// without its port.
+	clientAddress string
+
+	// userAgent is the agent string sent by the client, if any.
+	userAgent string
+
+	// terminate is closed to close the connection when the
+	// session of the user logged in on it is terminated.
+	terminate chan struct{}
 }
 
 var _ = (*apiHandler)(nil)
 
 // newAPIHandler returns a new apiHandler.
-func newAPIHandler(srv *Server, st *state.State, rpcConn *rpc.Conn, modelUUID string, connectionID uint64, serverHost string) (*apiHandler, error) {
+func newAPIHandler(srv *Server, st *state.State, rpcConn *rpc.Conn, modelUUID string, connectionID uint64, serverHost, remoteAddr, userAgent string) (*apiHandler, error) {
 	m, err := st.Model()
 	if err != nil {
 		if !errors.IsNotFound(err) {
@@ -88,15 +100,22 @@ func newAPIHandler(srv *Server, st *state.State, rpcConn *rpc.Conn, modelUUID st
 		}
 	}
 
+	clientAddress := remoteAddr
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		clientAddress = host
+	}
 	r := &apiHandler{
-		state:        st,
-		model:        m,
-		resources:    common.NewResources(),
-		shared:       srv.shared,
-		rpcConn:      rpcConn,
-		modelUUID:    modelUUID,
-		connectionID: connectionID,
-		serverHost:   serverHost,
+		state:         st,
+		model:         m,
+		resources:     common.NewResources(),
+		shared:        srv.shared,
+		rpcConn:       rpcConn,
+		modelUUID:     modelUUID,
+		connectionID:  connectionID,
+		serverHost:    serverHost,
+		clientAddress: clientAddress,
+		userAgent:     userAgent,
+		terminate:     make(chan struct{}),
 	}
 
 	if err := r.resources.RegisterNamed("machineID", common.StringResource(srv.tag.Id())); err != nil {
@@ -109,6 +128,24 @@ func newAPIHandler(srv *Server, st *state.State, rpcConn *rpc.Conn, modelUUID st
 		return nil, errors.Trace(err)
 	}
 
+	// The user manager facade needs the authenticator to mint
+	// macaroons for users that log in with a device login.
+	if err := r.resources.RegisterNamed(
+		"localMacaroonAuthenticator",
+		common.ValueResource{Value: srv.authenticator},
+	); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	// The user manager facade lists and terminates
+	// the sessions of users logged in to the server.
+	if err := r.resources.RegisterNamed(
+		"sessionRegistry",
+		common.ValueResource{Value: srv.sessions},
+	); err != nil {
+		return nil, errors.Trace(err)
+	}
+
 	// Facades involved with managing application offers need the auth context
 	// to mint and validate macaroons.
 	localOfferAccessEndpoint := url.URL{
@@ -229,6 +266,22 @@ func restrictAPIRoot(
 		}
 		apiRoot = restrictedRoot
 	}
+	// A user whose password has expired must change it before
+	// enrolling a second factor, which requires a new login.
+	if auth.anonymousLogin {
+		apiRoot = restrictRoot(apiRoot, anonymousMethodsOnly)
+	}
+	if auth.guestLogin {
+		apiRoot = restrictRoot(apiRoot, guestMethodsOnly)
+	}
+	if auth.impersonator.Id() != "" {
+		apiRoot = restrictRoot(apiRoot, impersonationMethodsBlocked)
+	}
+	if auth.passwordExpired {
+		apiRoot = restrictRoot(apiRoot, passwordExpiredMethodsOnly)
+	} else if auth.secondFactorEnrollmentRequired {
+		apiRoot = restrictRoot(apiRoot, secondFactorEnrollmentMethodsOnly)
+	}
 	if auth.controllerOnlyLogin {
 		apiRoot = restrictRoot(apiRoot, controllerFacadesOnly)
 	} else {
@@ -437,6 +437,14 @@ func (a *mockAuthenticator) CreateLocalLoginMacaroon(ctx context.Context, tag na
 	return nil, nil
 }
 
+func (a *mockAuthenticator) CreateLoginMacaroon(ctx context.Context, tag names.UserTag, version bakery.Version) (*macaroon.Macaroon, error) {
+	return nil, nil
+}
+
+func (a *mockAuthenticator) CreateImpersonationMacaroon(ctx context.Context, tag, impersonator names.UserTag, version bakery.Version) (*macaroon.Macaroon, error) {
+	return nil, nil
+}
+
 type mockEntity struct {
 	tag names.Tag
 }
@@ -0,0 +1,128 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package apiserver
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/apiserver/params"
+)
+
+// sessionRegistry records the live sessions of the users logged in to
+// the API server, so that they can be listed and terminated through
+// the user manager facade. Each API server only knows of the sessions
+// it is serving.
+type sessionRegistry struct {
+	mu       sync.Mutex
+	sessions map[uint64]*session
+}
+
+// session holds a user's live session, and the channel
+// closed to terminate it.
+type session struct {
+	info      params.Session
+	user      names.UserTag
+	terminate chan struct{}
+}
+
+func newSessionRegistry() *sessionRegistry {
+	return &sessionRegistry{
+		sessions: make(map[uint64]*session),
+	}
+}
+
+// add records the session of a user logged in on the connection with
+// the input id. The terminate channel is closed if the session is
+// terminated.
+func (r *sessionRegistry) add(connectionID uint64, user names.UserTag, info params.Session, terminate chan struct{}) {
+	info.SessionID = strconv.FormatUint(connectionID, 10)
+	info.UserTag = user.String()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[connectionID] = &session{
+		info:      info,
+		user:      user,
+		terminate: terminate,
+	}
+}
+
+// remove forgets the session on the connection with the input
+// id, if any, once the connection has closed.
+func (r *sessionRegistry) remove(connectionID uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, connectionID)
+}
+
+// UserSessions returns the live sessions of the user,
+// in the order in which they were started.
+func (r *sessionRegistry) UserSessions(user names.UserTag) []params.Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ids []uint64
+	for id, s := range r.sessions {
+		if s.user == user {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	result := make([]params.Session, len(ids))
+	for i, id := range ids {
+		result[i] = r.sessions[id].info
+	}
+	return result
+}
+
+// Session returns the live session with the input id,
+// or a NotFound error if there is none.
+func (r *sessionRegistry) Session(sessionID string) (params.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, s, err := r.session(sessionID)
+	if err != nil {
+		return params.Session{}, errors.Trace(err)
+	}
+	return s.info, nil
+}
+
+// TerminateSession closes the connection of the live session with the
+// input id, or returns a NotFound error if there is none. Requests in
+// flight on the connection are allowed to complete.
+func (r *sessionRegistry) TerminateSession(sessionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	connectionID, s, err := r.session(sessionID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// The session is forgotten as it is terminated,
+	// so that it is only terminated once.
+	delete(r.sessions, connectionID)
+	close(s.terminate)
+	return nil
+}
+
+// session returns the id of the connection of the live session
+// with the input id, and the session. It must be called with the
+// registry's mutex held.
+func (r *sessionRegistry) session(sessionID string) (uint64, *session, error) {
+	id, err := strconv.ParseUint(sessionID, 10, 64)
+	if err != nil {
+		return 0, nil, errors.NotFoundf("session %q", sessionID)
+	}
+	s, ok := r.sessions[id]
+	if !ok {
+		return 0, nil, errors.NotFoundf("session %q", sessionID)
+	}
+	return id, s, nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package apiserver
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/apiserver/params"
+)
+
+type sessionRegistrySuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&sessionRegistrySuite{})
+
+func (s *sessionRegistrySuite) TestUserSessions(c *gc.C) {
+	r := newSessionRegistry()
+	bob := names.NewUserTag("bob")
+	r.add(12, bob, params.Session{RemoteAddress: "10.0.0.2"}, make(chan struct{}))
+	r.add(3, bob, params.Session{RemoteAddress: "10.0.0.1"}, make(chan struct{}))
+	r.add(7, names.NewUserTag("alex"), params.Session{}, make(chan struct{}))
+
+	c.Assert(r.UserSessions(bob), jc.DeepEquals, []params.Session{{
+		SessionID:     "3",
+		UserTag:       "user-bob",
+		RemoteAddress: "10.0.0.1",
+	}, {
+		SessionID:     "12",
+		UserTag:       "user-bob",
+		RemoteAddress: "10.0.0.2",
+	}})
+
+	r.remove(3)
+	c.Assert(r.UserSessions(bob), gc.HasLen, 1)
+	_, err := r.Session("3")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *sessionRegistrySuite) TestTerminateSession(c *gc.C) {
+	r := newSessionRegistry()
+	terminate := make(chan struct{})
+	r.add(3, names.NewUserTag("bob"), params.Session{}, terminate)
+
+	err := r.TerminateSession("3")
+	c.Assert(err, jc.ErrorIsNil)
+	select {
+	case <-terminate:
+	default:
+		c.Fatalf("session not terminated")
+	}
+
+	// A terminated session is forgotten, so that
+	// it cannot be terminated again.
+	err = r.TerminateSession("3")
+	c.Assert(err, gc.ErrorMatches, `session "3" not found`)
+	err = r.TerminateSession("not-a-session")
+	c.Assert(err, gc.ErrorMatches, `session "not-a-session" not found`)
+}
@@ -165,3 +165,21 @@ func (c *sharedServerContext) maxDebugLogDuration() time.Duration {
 	defer c.configMutex.RUnlock()
 	return c.controllerConfig.MaxDebugLogDuration()
 }
+
+func (c *sharedServerContext) requireSecondFactor() bool {
+	c.configMutex.RLock()
+	defer c.configMutex.RUnlock()
+	return c.controllerConfig.RequireSecondFactor()
+}
+
+func (c *sharedServerContext) passwordMaxAge() time.Duration {
+	c.configMutex.RLock()
+	defer c.configMutex.RUnlock()
+	return c.controllerConfig.PasswordMaxAge()
+}
+
+func (c *sharedServerContext) guestAccess() bool {
+	c.configMutex.RLock()
+	defer c.configMutex.RUnlock()
+	return c.controllerConfig.GuestAccess()
+}
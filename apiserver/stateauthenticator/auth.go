@@ -23,6 +23,7 @@ import (
 	"github.com/juju/juju/apiserver/common"
 	"github.com/juju/juju/apiserver/httpcontext"
 	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/core/permission"
 	"github.com/juju/juju/state"
 )
 
@@ -79,6 +80,26 @@ func (a *Authenticator) CreateLocalLoginMacaroon(ctx context.Context, tag names.
 	return mac.M(), nil
 }
 
+// CreateLoginMacaroon is part of the
+// httpcontext.LocalMacaroonAuthenticator interface.
+func (a *Authenticator) CreateLoginMacaroon(ctx context.Context, tag names.UserTag, version bakery.Version) (*macaroon.Macaroon, error) {
+	mac, err := a.authContext.CreateLoginMacaroon(ctx, tag, version)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return mac.M(), nil
+}
+
+// CreateImpersonationMacaroon is part of the
+// httpcontext.LocalMacaroonAuthenticator interface.
+func (a *Authenticator) CreateImpersonationMacaroon(ctx context.Context, tag, impersonator names.UserTag, version bakery.Version) (*macaroon.Macaroon, error) {
+	mac, err := a.authContext.CreateImpersonationMacaroon(ctx, tag, impersonator, version)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return mac.M(), nil
+}
+
 // AddHandlers adds the handlers to the given mux for handling local
 // macaroon logins.
 func (a *Authenticator) AddHandlers(mux *apiserverhttp.Mux) {
@@ -176,6 +197,15 @@ func (a *Authenticator) checkCreds(
 	}
 
 	authInfo := httpcontext.AuthInfo{Entity: entity}
+	if impersonated, ok := entity.(*authentication.ImpersonatedEntity); ok {
+		if err := checkImpersonator(st, impersonated.Impersonator); err != nil {
+			return httpcontext.AuthInfo{}, errors.Trace(err)
+		}
+		authInfo.Entity = impersonated.Entity
+		authInfo.Impersonator = impersonated.Impersonator
+		// The user's own last login is left as it is.
+		return authInfo, nil
+	}
 	type withIsManager interface {
 		IsManager() bool
 	}
@@ -202,6 +232,34 @@ func (a *Authenticator) checkCreds(
 	return authInfo, nil
 }
 
+// checkImpersonator returns an error unless the impersonator is still an
+// enabled controller superuser. Impersonation macaroons outlive the check
+// made when they are created, so revoking superuser access or disabling
+// the impersonator must end their impersonations.
+func checkImpersonator(st *state.State, impersonator names.UserTag) error {
+	user, err := st.User(impersonator)
+	if _, deleted := errors.Cause(err).(state.DeletedUserError); errors.IsNotFound(err) || deleted {
+		return errors.Trace(common.ErrPerm)
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	if user.IsDisabled() {
+		logger.Infof("refusing impersonation by disabled user %q", impersonator.Id())
+		return errors.Trace(common.ErrPerm)
+	}
+	access, err := st.UserAccess(impersonator, st.ControllerTag())
+	if errors.IsNotFound(err) {
+		return errors.Trace(common.ErrPerm)
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	if access.Access != permission.SuperuserAccess {
+		logger.Infof("refusing impersonation by %q, who is no longer a controller superuser", impersonator.Id())
+		return errors.Trace(common.ErrPerm)
+	}
+	return nil
+}
+
 // LoginRequest extracts basic auth login details from an http.Request.
 //
 // TODO(axw) we shouldn't be using params types here.
@@ -7,13 +7,18 @@ import (
 	"context"
 
 	"github.com/juju/clock"
+	"github.com/juju/errors"
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 	"gopkg.in/juju/names.v3"
+	"gopkg.in/macaroon-bakery.v2/bakery"
+	"gopkg.in/macaroon.v2"
 
 	"github.com/juju/juju/apiserver/authentication"
+	"github.com/juju/juju/apiserver/httpcontext"
 	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/apiserver/stateauthenticator"
+	"github.com/juju/juju/core/permission"
 	"github.com/juju/juju/state"
 	statetesting "github.com/juju/juju/state/testing"
 	"github.com/juju/juju/testing/factory"
@@ -71,6 +76,36 @@ func (s *agentAuthenticatorSuite) TestNotSupportedTag(c *gc.C) {
 	c.Assert(authenticator, gc.IsNil)
 }
 
+func (s *agentAuthenticatorSuite) TestImpersonationEndsWithSuperuser(c *gc.C) {
+	root := s.Factory.MakeUser(c, &factory.UserParams{Name: "root"})
+	_, err := s.State.SetUserAccess(root.UserTag(), s.State.ControllerTag(), permission.SuperuserAccess)
+	c.Assert(err, jc.ErrorIsNil)
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+
+	mac, err := s.authenticator.CreateImpersonationMacaroon(
+		context.TODO(), alex.UserTag(), root.UserTag(), bakery.LatestVersion)
+	c.Assert(err, jc.ErrorIsNil)
+	login := func() (httpcontext.AuthInfo, error) {
+		return s.authenticator.AuthenticateLoginRequest(
+			context.TODO(), "testing.invalid:1234", s.State.ModelUUID(), params.LoginRequest{
+				AuthTag:   alex.Tag().String(),
+				Macaroons: []macaroon.Slice{{mac}},
+			})
+	}
+
+	authInfo, err := login()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(authInfo.Entity.Tag(), gc.Equals, alex.Tag())
+	c.Check(authInfo.Impersonator, gc.Equals, root.UserTag())
+
+	// Revoking superuser access ends the impersonation,
+	// although the macaroon has not yet expired.
+	_, err = s.State.SetUserAccess(root.UserTag(), s.State.ControllerTag(), permission.LoginAccess)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = login()
+	c.Assert(err, jc.Satisfies, errors.IsUnauthorized)
+}
+
 type userFinder struct {
 	user state.Entity
 }
@@ -140,6 +140,19 @@ func (ctxt *authContext) CreateLocalLoginMacaroon(ctx context.Context, tag names
 	return authentication.CreateLocalLoginMacaroon(ctx, tag, ctxt.localUserThirdPartyBakery.Oven, ctxt.clock, version)
 }
 
+// CreateLoginMacaroon creates a macaroon with which the local user can log
+// in until it expires, for users whose identity has been established
+// without a password, such as by a device login.
+func (ctxt *authContext) CreateLoginMacaroon(ctx context.Context, tag names.UserTag, version bakery.Version) (*bakery.Macaroon, error) {
+	return authentication.CreateLoginMacaroon(ctx, tag, ctxt.localUserBakery, ctxt.clock, version)
+}
+
+// CreateImpersonationMacaroon creates a macaroon with which the impersonator
+// can log in as the local user until it expires.
+func (ctxt *authContext) CreateImpersonationMacaroon(ctx context.Context, tag, impersonator names.UserTag, version bakery.Version) (*bakery.Macaroon, error) {
+	return authentication.CreateImpersonationMacaroon(ctx, tag, impersonator, ctxt.localUserBakery, ctxt.clock, version)
+}
+
 // CheckLocalLoginCaveat parses and checks that the given caveat string is
 // valid for a local login request, and returns the tag of the local user
 // that the caveat asserts is logged in. checkers.ErrCaveatNotRecognized will
@@ -68,6 +68,7 @@ func (h *localLoginHandlers) serveLoginPost(response http.ResponseWriter, req *h
 	}
 	username := req.Form.Get("user")
 	password := req.Form.Get("password")
+	secondFactor := req.Form.Get("second-factor")
 	if !names.IsValidUser(username) {
 		return nil, errors.NotValidf("username %q", username)
 	}
@@ -78,7 +79,8 @@ func (h *localLoginHandlers) serveLoginPost(response http.ResponseWriter, req *h
 
 	authenticator := h.authCtxt.authenticator(req.Host)
 	if _, err := authenticator.Authenticate(req.Context(), h.finder, userTag, params.LoginRequest{
-		Credentials: password,
+		Credentials:  password,
+		SecondFactor: secondFactor,
 	}); err != nil {
 		// Mark the interaction as done (but failed),
 		// unblocking a pending "/auth/wait" request.
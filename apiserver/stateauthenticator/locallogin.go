@@ -79,6 +79,8 @@ func (h *localLoginHandlers) formHandler(w http.ResponseWriter, req *http.Reques
 
 		username := loginRequest.Body.Form["user"].(string)
 		password := loginRequest.Body.Form["password"].(string)
+		// The second factor is only required of users who have enrolled one.
+		secondFactor, _ := loginRequest.Body.Form["second-factor"].(string)
 		userTag := names.NewUserTag(username)
 		if !userTag.IsLocal() {
 			h.bakeryError(w, errors.NotValidf("non-local username %q", username))
@@ -87,7 +89,8 @@ func (h *localLoginHandlers) formHandler(w http.ResponseWriter, req *http.Reques
 
 		authenticator := h.authCtxt.authenticator(req.Host)
 		if _, err := authenticator.Authenticate(ctx, h.finder, userTag, params.LoginRequest{
-			Credentials: password,
+			Credentials:  password,
+			SecondFactor: secondFactor,
 		}); err != nil {
 			h.bakeryError(w, err)
 			return
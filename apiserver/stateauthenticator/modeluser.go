@@ -123,6 +123,33 @@ func (u *modelUserEntity) PasswordValid(pass string) bool {
 	return u.user.PasswordValid(pass)
 }
 
+// SecondFactorEnrolled returns whether the local user has
+// enrolled a second authentication factor.
+func (u *modelUserEntity) SecondFactorEnrolled() bool {
+	if u.user == nil {
+		return false
+	}
+	return u.user.SecondFactorEnrolled()
+}
+
+// SecondFactorValid returns whether the code is a valid
+// second factor for the local user.
+func (u *modelUserEntity) SecondFactorValid(code string) (bool, error) {
+	if u.user == nil {
+		return false, nil
+	}
+	return u.user.SecondFactorValid(code)
+}
+
+// PasswordExpired returns whether the local user's password
+// has expired, if passwords expire after maxAge.
+func (u *modelUserEntity) PasswordExpired(maxAge time.Duration) bool {
+	if u.user == nil {
+		return false
+	}
+	return u.user.PasswordExpired(maxAge)
+}
+
 // Tag implements state.Entity.Tag.
 func (u *modelUserEntity) Tag() names.Tag {
 	return u.tag
@@ -4,11 +4,16 @@
 package commands
 
 import (
+	"fmt"
 	"strings"
+	"time"
 
+	"github.com/dustin/go-humanize"
+	"github.com/juju/clock"
 	"github.com/juju/cmd"
 	"github.com/juju/collections/set"
 	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
 	"gopkg.in/juju/names.v3"
 	"gopkg.in/macaroon-bakery.v2/httpbakery"
 	"gopkg.in/macaroon.v2"
@@ -23,9 +28,20 @@ import (
 	"github.com/juju/juju/jujuclient"
 )
 
+const (
+	// migrationPollInterval is the time between checks of the
+	// progress of a migration when waiting for it to complete.
+	migrationPollInterval = 2 * time.Second
+
+	// progressBarWidth is the number of characters in the bar
+	// showing the progress of the transfer of a model.
+	progressBarWidth = 20
+)
+
 func newMigrateCommand() modelcmd.ModelCommand {
 	var cmd migrateCommand
 	cmd.newAPIRoot = cmd.CommandBase.NewAPIRoot
+	cmd.clock = clock.WallClock
 	return modelcmd.Wrap(&cmd, modelcmd.WrapSkipModelFlags)
 }
 
@@ -33,12 +49,15 @@ func newMigrateCommand() modelcmd.ModelCommand {
 type migrateCommand struct {
 	modelcmd.ModelCommandBase
 	targetController string
+	wait             bool
+	permissions      string
 
 	// Overridden by tests
 	newAPIRoot func(jujuclient.ClientStore, string, string) (api.Connection, error)
 	migAPI     map[string]migrateAPI
 	modelAPI   modelInfoAPI
 	userAPI    userListAPI
+	clock      clock.Clock
 }
 
 type migrateAPI interface {
@@ -76,9 +95,28 @@ In order to start a migration, the target controller must be in the
 juju client's local configuration cache. See the juju "login" command
 for details of how to do this.
 
-This command only starts a model migration - it does not wait for its
-completion. The progress of a migration can be tracked using the
-"status" command and by consulting the logs.
+By default this command only starts a model migration - it does not
+wait for its completion. The progress of a migration can be tracked
+using the "status" command and by consulting the logs.
+
+With --wait, the command waits for the migration to complete, showing
+the progress of the transfer of the model's entities and binaries to
+the target controller. If a migration of the model is already in
+progress, --wait attaches to it rather than starting a new one, so an
+interrupted wait can be resumed.
+
+A user's access to the source controller and to the model's cloud does
+not migrate with the model unless --permissions is specified. It gives
+the policy for users who already have access on the target controller:
+"keep" leaves their access as it is, "greater" raises it where the
+migrated access is greater, and "replace" replaces it. Only the access
+of external users is migrated, as local users of the two controllers
+are different users, and superuser access is migrated as login access.
+
+Examples:
+    juju migrate mymodel target-controller
+    juju migrate --wait mymodel target-controller
+    juju migrate --permissions greater mymodel target-controller
 
 See also:
     login
@@ -96,6 +134,13 @@ func (c *migrateCommand) Info() *cmd.Info {
 	})
 }
 
+// SetFlags implements cmd.Command.
+func (c *migrateCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.ModelCommandBase.SetFlags(f)
+	f.BoolVar(&c.wait, "wait", false, "Wait for the migration to complete, showing its progress")
+	f.StringVar(&c.permissions, "permissions", "", "Migrate users' controller and cloud access, resolving conflicts by keep, greater or replace")
+}
+
 // Init implements cmd.Command.
 func (c *migrateCommand) Init(args []string) error {
 	if len(args) < 1 {
@@ -113,6 +158,12 @@ func (c *migrateCommand) Init(args []string) error {
 	}
 
 	c.targetController = args[1]
+
+	switch c.permissions {
+	case "", "keep", "greater", "replace":
+	default:
+		return errors.Errorf("invalid --permissions %q, expected keep, greater or replace", c.permissions)
+	}
 	return nil
 }
 
@@ -131,6 +182,16 @@ func (c *migrateCommand) Run(ctx *cmd.Context) error {
 		return errors.Trace(err)
 	}
 	spec.ModelUUID = uuids[0]
+	if c.wait {
+		migration, err := c.getMigrationStatus(spec.ModelUUID)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if migration != nil && migration.End == nil {
+			ctx.Infof("Migration already in progress, waiting for it to complete")
+			return c.waitForMigration(ctx, spec.ModelUUID)
+		}
+	}
 	if err := c.checkMigrationFeasibility(spec); err != nil {
 		return errors.Trace(err)
 	}
@@ -148,9 +209,107 @@ func (c *migrateCommand) Run(ctx *cmd.Context) error {
 		return err
 	}
 	ctx.Infof("Migration started with ID %q", id)
+	if c.wait {
+		return c.waitForMigration(ctx, spec.ModelUUID)
+	}
 	return nil
 }
 
+// getMigrationStatus returns the status of the latest migration of
+// the model, or nil if the model has never been migrated. If the
+// model no longer exists on the controller, a NotFound error is
+// returned.
+func (c *migrateCommand) getMigrationStatus(modelUUID string) (*params.ModelMigrationStatus, error) {
+	api, err := c.getModelAPI()
+	if err != nil {
+		return nil, err
+	}
+	defer api.Close()
+
+	infoRes, err := api.ModelInfo([]names.ModelTag{names.NewModelTag(modelUUID)})
+	if err != nil {
+		return nil, err
+	}
+	if infoRes[0].Error != nil {
+		if params.IsCodeNotFound(infoRes[0].Error) {
+			return nil, errors.NotFoundf("model %q", modelUUID)
+		}
+		return nil, infoRes[0].Error
+	}
+	return infoRes[0].Result.Migration, nil
+}
+
+// waitForMigration reports the progress of the migration of the model
+// until it completes. Once a migration succeeds the model is removed
+// from the source controller, so the model no longer being found
+// indicates success.
+func (c *migrateCommand) waitForMigration(ctx *cmd.Context, modelUUID string) error {
+	var lastStatus, lastProgress string
+	for {
+		migration, err := c.getMigrationStatus(modelUUID)
+		if errors.IsNotFound(err) {
+			ctx.Infof("Migration complete")
+			return nil
+		} else if err != nil {
+			return errors.Trace(err)
+		}
+		if migration == nil {
+			return errors.New("migration not found")
+		}
+		if migration.Status != lastStatus {
+			ctx.Infof("%s", migration.Status)
+			lastStatus = migration.Status
+		}
+		if migration.Progress != nil {
+			if progress := formatImportProgress(migration.Progress); progress != lastProgress {
+				ctx.Infof("%s", progress)
+				lastProgress = progress
+			}
+		}
+		if migration.End != nil {
+			if strings.HasPrefix(migration.Status, "aborted") {
+				return errors.New("migration aborted")
+			}
+			ctx.Infof("Migration complete")
+			return nil
+		}
+		<-c.clock.After(migrationPollInterval)
+	}
+}
+
+// formatImportProgress renders the progress of the transfer of a model
+// to the target controller as a progress bar, followed by the number
+// of entities of each kind imported and the size of the binaries
+// uploaded.
+func formatImportProgress(progress *params.MigrationImportProgress) string {
+	var imported, total int
+	var counts []string
+	for _, entity := range progress.Entities {
+		imported += entity.Imported
+		total += entity.Total
+		if entity.Total > 0 {
+			counts = append(counts, fmt.Sprintf("%s %d/%d", entity.Kind, entity.Imported, entity.Total))
+		}
+	}
+	percent := 100
+	if total > 0 {
+		percent = imported * 100 / total
+	}
+	filled := percent * progressBarWidth / 100
+	result := fmt.Sprintf("[%s%s] %3d%%",
+		strings.Repeat("#", filled),
+		strings.Repeat("-", progressBarWidth-filled),
+		percent,
+	)
+	if len(counts) > 0 {
+		result += " " + strings.Join(counts, ", ")
+	}
+	if progress.BinariesBytes > 0 {
+		result += fmt.Sprintf("; %s of binaries uploaded", humanize.IBytes(uint64(progress.BinariesBytes)))
+	}
+	return result
+}
+
 func (c *migrateCommand) getMigrationSpec() (*controller.MigrationSpec, error) {
 	store := c.ClientStore()
 
@@ -181,6 +340,7 @@ func (c *migrateCommand) getMigrationSpec() (*controller.MigrationSpec, error) {
 		TargetUser:            accountInfo.User,
 		TargetPassword:        accountInfo.Password,
 		TargetMacaroons:       macs,
+		Permissions:           c.permissions,
 	}, nil
 }
 
@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/juju/clock"
 	"github.com/juju/cmd"
 	"github.com/juju/cmd/cmdtesting"
 	jc "github.com/juju/testing/checkers"
@@ -262,6 +263,89 @@ func (s *MigrateSuite) TestSuccess(c *gc.C) {
 	})
 }
 
+func (s *MigrateSuite) TestPermissions(c *gc.C) {
+	_, err := s.makeAndRun(c, "--permissions", "greater", "model", "target")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(s.api.specSeen.Permissions, gc.Equals, "greater")
+}
+
+func (s *MigrateSuite) TestInvalidPermissions(c *gc.C) {
+	_, err := s.makeAndRun(c, "--permissions", "overwrite", "model", "target")
+	c.Assert(err, gc.ErrorMatches, `invalid --permissions "overwrite", expected keep, greater or replace`)
+	c.Check(s.api.specSeen, gc.IsNil)
+}
+
+func (s *MigrateSuite) TestWait(c *gc.C) {
+	end := time.Now()
+	s.modelAPI.migrations = []*params.ModelMigrationStatus{nil, {
+		Status: "successful, removing model from source controller",
+		End:    &end,
+		Progress: &params.MigrationImportProgress{
+			Entities: []params.EntityImportProgress{
+				{Kind: "machines", Imported: 2, Total: 2},
+				{Kind: "relations", Imported: 0, Total: 0},
+				{Kind: "units", Imported: 3, Total: 3},
+			},
+			BinariesBytes: 1024,
+		},
+	}}
+
+	ctx, err := s.makeAndRun(c, "--wait", "model", "target")
+	c.Assert(err, jc.ErrorIsNil)
+
+	c.Check(cmdtesting.Stderr(ctx), gc.Equals, `
+Migration started with ID "uuid:0"
+successful, removing model from source controller
+[####################] 100% machines 2/2, units 3/3; 1.0 KiB of binaries uploaded
+Migration complete
+`[1:])
+	c.Check(s.api.specSeen, gc.NotNil)
+}
+
+func (s *MigrateSuite) TestWaitAttachesToRunningMigration(c *gc.C) {
+	end := time.Now()
+	s.modelAPI.migrations = []*params.ModelMigrationStatus{{
+		Status: "importing model into target controller",
+	}, {
+		Status: "importing model into target controller",
+		Progress: &params.MigrationImportProgress{
+			Entities: []params.EntityImportProgress{
+				{Kind: "machines", Imported: 1, Total: 4},
+			},
+		},
+	}, {
+		Status: "aborted, removing model from target controller: boom",
+		End:    &end,
+	}}
+
+	ctx, err := s.makeAndRun(c, "--wait", "model", "target")
+	c.Assert(err, gc.ErrorMatches, "migration aborted")
+
+	c.Check(cmdtesting.Stderr(ctx), gc.Equals, `
+Migration already in progress, waiting for it to complete
+importing model into target controller
+[#####---------------]  25% machines 1/4
+aborted, removing model from target controller: boom
+`[1:])
+	c.Check(s.api.specSeen, gc.IsNil) // No new migration should have been started.
+}
+
+func (s *MigrateSuite) TestWaitModelRemoved(c *gc.C) {
+	s.modelAPI.migrations = []*params.ModelMigrationStatus{{
+		Status: "successful, removing model from source controller",
+	}}
+	cmd := s.makeCommand()
+	inner := modelcmd.InnerCommand(cmd).(*migrateCommand)
+	inner.modelAPI = &removedModelAPI{fakeModelAPI: s.modelAPI}
+
+	ctx, err := cmdtesting.RunCommand(c, cmd, "--wait", "model", "target")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(cmdtesting.Stderr(ctx), gc.Equals, `
+Migration already in progress, waiting for it to complete
+Migration complete
+`[1:])
+}
+
 func (s *MigrateSuite) TestSuccessMacaroons(c *gc.C) {
 	err := s.store.UpdateAccount("target", jujuclient.AccountDetails{
 		User:     "targetuser",
@@ -436,9 +520,22 @@ func (s *MigrateSuite) makeCommand() modelcmd.ModelCommand {
 	inner.newAPIRoot = func(jujuclient.ClientStore, string, string) (api.Connection, error) {
 		return s.targetControllerAPI, nil
 	}
+	inner.clock = immediateClock{}
 	return cmd
 }
 
+// immediateClock is a clock whose timers fire immediately, so
+// that waiting for a migration doesn't slow down the tests.
+type immediateClock struct {
+	clock.Clock
+}
+
+func (immediateClock) After(time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- time.Time{}
+	return ch
+}
+
 type fakeMigrateAPI struct {
 	specSeen    *controller.MigrationSpec
 	identityURL string
@@ -460,6 +557,11 @@ func (*fakeMigrateAPI) Close() error {
 type fakeModelAPI struct {
 	models    []base.UserModel
 	modelInfo []params.ModelInfo
+
+	// migrations holds the migration statuses reported for the
+	// model in turn; the last one is reported once the others
+	// have been.
+	migrations []*params.ModelMigrationStatus
 }
 
 func (m *fakeModelAPI) ListModels(user string) ([]base.UserModel, error) {
@@ -484,6 +586,11 @@ func (m *fakeModelAPI) ModelInfo(tags []names.ModelTag) ([]params.ModelInfoResul
 		err = &params.Error{
 			Code: params.CodeNotFound,
 		}
+	} else if len(m.migrations) > 0 {
+		mi.Migration = m.migrations[0]
+		if len(m.migrations) > 1 {
+			m.migrations = m.migrations[1:]
+		}
 	}
 
 	return []params.ModelInfoResult{
@@ -498,6 +605,23 @@ func (m *fakeModelAPI) Close() error {
 	return nil
 }
 
+// removedModelAPI reports the model as found only once, as
+// happens when a migration completes.
+type removedModelAPI struct {
+	*fakeModelAPI
+	calls int
+}
+
+func (m *removedModelAPI) ModelInfo(tags []names.ModelTag) ([]params.ModelInfoResult, error) {
+	m.calls++
+	if m.calls > 1 {
+		return []params.ModelInfoResult{{
+			Error: &params.Error{Code: params.CodeNotFound},
+		}}, nil
+	}
+	return m.fakeModelAPI.ModelInfo(tags)
+}
+
 type fakeUserAPI struct {
 	users []params.UserInfo
 }
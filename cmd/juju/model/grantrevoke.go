@@ -29,8 +29,13 @@ Users with read access are limited in what they can do with models:
 Valid access levels for models are:
     read
     write
+    user-admin
     admin
 
+Users with user-admin access to a model may also add users, enable and
+disable users whose access is limited to the models they administer, and
+grant users read or write access to those models.
+
 Valid access levels for controllers are:
     login
     superuser
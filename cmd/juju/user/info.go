@@ -4,6 +4,8 @@
 package user
 
 import (
+	"time"
+
 	"github.com/juju/clock"
 	"github.com/juju/cmd"
 	"github.com/juju/errors"
@@ -25,12 +27,18 @@ var helpDetails = `
 By default, the YAML format is used and the user name is the current
 user.
 
+The --activity option adds a summary of the user's logins to each model,
+and to the controller, over the period given by --since: the number of
+connections, when the last was made, and the addresses they were made
+from. Controller superusers may show the activity of any user.
+
 
 Examples:
     juju show-user
     juju show-user jsmith
     juju show-user --format json
     juju show-user --format yaml
+    juju show-user jsmith --activity --since 168h
     
 See also: 
     add-user
@@ -40,6 +48,7 @@ See also:
 // UserInfoAPI defines the API methods that the info command uses.
 type UserInfoAPI interface {
 	UserInfo([]string, usermanager.IncludeDisabled) ([]params.UserInfo, error)
+	LoginActivity(string, time.Time) ([]params.ModelLoginActivity, error)
 	Close() error
 }
 
@@ -68,6 +77,8 @@ func NewShowUserCommand() cmd.Command {
 type infoCommand struct {
 	infoCommandBase
 	Username string
+	activity bool
+	since    time.Duration
 }
 
 // UserInfo defines the serialization behaviour of the user information.
@@ -78,6 +89,17 @@ type UserInfo struct {
 	DateCreated    string `yaml:"date-created,omitempty" json:"date-created,omitempty"`
 	LastConnection string `yaml:"last-connection,omitempty" json:"last-connection,omitempty"`
 	Disabled       bool   `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+
+	Activity []ModelActivity `yaml:"activity,omitempty" json:"activity,omitempty"`
+}
+
+// ModelActivity defines the serialization behaviour of a user's
+// login activity for a model, or for the controller.
+type ModelActivity struct {
+	Model          string   `yaml:"model" json:"model"`
+	Connections    int      `yaml:"connections" json:"connections"`
+	LastConnection string   `yaml:"last-connection" json:"last-connection"`
+	Addresses      []string `yaml:"addresses,omitempty" json:"addresses,omitempty"`
 }
 
 // Info implements Command.Info.
@@ -93,6 +115,8 @@ func (c *infoCommand) Info() *cmd.Info {
 // SetFlags implements Command.SetFlags.
 func (c *infoCommand) SetFlags(f *gnuflag.FlagSet) {
 	c.infoCommandBase.SetFlags(f)
+	f.BoolVar(&c.activity, "activity", false, "Show the user's login activity")
+	f.DurationVar(&c.since, "since", 30*24*time.Hour, "The period to show login activity for")
 	c.out.AddFlags(f, "yaml", output.DefaultFormatters)
 }
 
@@ -135,9 +159,49 @@ func (c *infoCommand) Run(ctx *cmd.Context) (err error) {
 	if len(output) != 1 {
 		return errors.Errorf("expected 1 result, got %d", len(output))
 	}
+	if c.activity {
+		activity, err := client.LoginActivity(username, c.clock.Now().Add(-c.since))
+		if err != nil {
+			return errors.Trace(err)
+		}
+		output[0].Activity = c.apiActivityToModelActivity(activity)
+	}
 	return c.out.Write(ctx, output[0])
 }
 
+// apiActivityToModelActivity converts the login activity returned by the
+// API, naming the models known to the client store and showing the UUIDs
+// of any others.
+func (c *infoCommand) apiActivityToModelActivity(activity []params.ModelLoginActivity) []ModelActivity {
+	modelNames := make(map[string]string)
+	if controllerName, err := c.ControllerName(); err == nil {
+		if models, err := c.ClientStore().AllModels(controllerName); err == nil {
+			for name, details := range models {
+				modelNames[details.ModelUUID] = name
+			}
+		}
+	}
+	now := c.clock.Now()
+	output := make([]ModelActivity, len(activity))
+	for i, a := range activity {
+		model := "controller"
+		if tag, err := names.ParseModelTag(a.ModelTag); err == nil {
+			model = tag.Id()
+			if name, ok := modelNames[tag.Id()]; ok {
+				model = name
+			}
+		}
+		lastConnection := a.LastConnection
+		output[i] = ModelActivity{
+			Model:          model,
+			Connections:    a.Connections,
+			LastConnection: common.LastConnection(&lastConnection, now, c.exactTime),
+			Addresses:      a.Addresses,
+		}
+	}
+	return output
+}
+
 func (c *infoCommandBase) apiUsersToUserInfoSlice(users []params.UserInfo) []UserInfo {
 	var output []UserInfo
 	var now = c.clock.Now()
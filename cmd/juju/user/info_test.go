@@ -16,6 +16,8 @@ import (
 	"github.com/juju/juju/apiserver/common"
 	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/cmd/juju/user"
+	"github.com/juju/juju/core/model"
+	"github.com/juju/juju/jujuclient"
 )
 
 var logger = loggo.GetLogger("juju.cmd.user.test")
@@ -68,6 +70,24 @@ func (*fakeUserInfoAPI) UserInfo(usernames []string, all usermanager.IncludeDisa
 	return []params.UserInfo{info}, nil
 }
 
+func (*fakeUserInfoAPI) LoginActivity(username string, since time.Time) ([]params.ModelLoginActivity, error) {
+	logger.Infof("fakeUserInfoAPI.LoginActivity(%v, %v)", username, since)
+	return []params.ModelLoginActivity{{
+		Connections:    1,
+		LastConnection: lastConnection,
+		Addresses:      []string{"10.0.0.1"},
+	}, {
+		ModelTag:       "model-deadbeef-0bad-400d-8000-4b1d0d06f00d",
+		Connections:    3,
+		LastConnection: lastConnection,
+		Addresses:      []string{"10.0.0.1", "10.0.0.2"},
+	}, {
+		ModelTag:       "model-deadbeef-0bad-400d-8000-5b1d0d06f00d",
+		Connections:    2,
+		LastConnection: lastConnection,
+	}}, nil
+}
+
 func (s *UserInfoCommandSuite) TestUserInfo(c *gc.C) {
 	context, err := cmdtesting.RunCommand(c, s.NewShowUserCommand())
 	c.Assert(err, jc.ErrorIsNil)
@@ -143,3 +163,33 @@ func (s *UserInfoCommandSuite) TestTooManyArgs(c *gc.C) {
 	_, err := cmdtesting.RunCommand(c, s.NewShowUserCommand(), "username", "whoops")
 	c.Assert(err, gc.ErrorMatches, `unrecognized args: \["whoops"\]`)
 }
+
+func (s *UserInfoCommandSuite) TestUserInfoActivity(c *gc.C) {
+	s.store.Models["testing"] = &jujuclient.ControllerModels{
+		Models: map[string]jujuclient.ModelDetails{
+			"current-user/default": {ModelUUID: "deadbeef-0bad-400d-8000-4b1d0d06f00d", ModelType: model.IAAS},
+		},
+	}
+	context, err := cmdtesting.RunCommand(c, s.NewShowUserCommand(), "--activity", "--exact-time")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cmdtesting.Stdout(context), gc.Equals, `user-name: current-user
+access: add-model
+date-created: 1981-02-27 16:10:05 +0000 UTC
+last-connection: 2014-01-01 00:00:00 +0000 UTC
+activity:
+- model: controller
+  connections: 1
+  last-connection: 2014-01-01 00:00:00 +0000 UTC
+  addresses:
+  - 10.0.0.1
+- model: current-user/default
+  connections: 3
+  last-connection: 2014-01-01 00:00:00 +0000 UTC
+  addresses:
+  - 10.0.0.1
+  - 10.0.0.2
+- model: deadbeef-0bad-400d-8000-5b1d0d06f00d
+  connections: 2
+  last-connection: 2014-01-01 00:00:00 +0000 UTC
+`)
+}
@@ -52,6 +52,10 @@ func (f *fakeClock) Now() time.Time {
 	return f.now
 }
 
+func (*fakeUserListAPI) LoginActivity(string, time.Time) ([]params.ModelLoginActivity, error) {
+	return nil, nil
+}
+
 func (f *fakeUserListAPI) ModelUserInfo() ([]params.ModelUserInfo, error) {
 	last1 := time.Date(2015, 3, 20, 0, 0, 0, 0, time.UTC)
 	last2 := time.Date(2015, 3, 1, 0, 0, 0, 0, time.UTC)
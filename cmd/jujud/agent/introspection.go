@@ -37,6 +37,7 @@ type introspectionConfig struct {
 	MachineLock        machinelock.Lock
 	PrometheusGatherer prometheus.Gatherer
 	PresenceRecorder   presence.Recorder
+	RelationInjector   introspection.RelationInjector
 	NewSocketName      func(names.Tag) string
 	WorkerFunc         func(config introspection.Config) (worker.Worker, error)
 }
@@ -62,6 +63,7 @@ func startIntrospection(cfg introspectionConfig) error {
 		MachineLock:        cfg.MachineLock,
 		PrometheusGatherer: cfg.PrometheusGatherer,
 		Presence:           cfg.PresenceRecorder,
+		RelationInjector:   cfg.RelationInjector,
 	})
 	if err != nil {
 		return errors.Trace(err)
@@ -602,6 +602,8 @@ func (a *MachineAgent) makeEngineCreator(agentName string, previousAgentVersion
 			ControllerLeaseDuration:           time.Minute,
 			LogPruneInterval:                  5 * time.Minute,
 			TransactionPruneInterval:          time.Hour,
+			TemporaryAccessRevokeInterval:     time.Minute,
+			PermissionWebhookInterval:         10 * time.Second,
 			MachineLock:                       a.machineLock,
 			SetStatePool:                      statePoolReporter.set,
 			RegisterIntrospectionHTTPHandlers: registerIntrospectionHandlers,
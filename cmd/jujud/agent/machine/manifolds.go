@@ -35,6 +35,7 @@ import (
 	"github.com/juju/juju/state"
 	proxyconfig "github.com/juju/juju/utils/proxy"
 	jworker "github.com/juju/juju/worker"
+	"github.com/juju/juju/worker/accessexpiry"
 	"github.com/juju/juju/worker/agent"
 	"github.com/juju/juju/worker/agentconfigupdater"
 	"github.com/juju/juju/worker/apiaddressupdater"
@@ -75,6 +76,7 @@ import (
 	"github.com/juju/juju/worker/modelworkermanager"
 	"github.com/juju/juju/worker/multiwatcher"
 	"github.com/juju/juju/worker/peergrouper"
+	"github.com/juju/juju/worker/permissionwebhook"
 	prworker "github.com/juju/juju/worker/presence"
 	"github.com/juju/juju/worker/proxyupdater"
 	psworker "github.com/juju/juju/worker/pubsub"
@@ -94,6 +96,7 @@ import (
 	"github.com/juju/juju/worker/terminationworker"
 	"github.com/juju/juju/worker/toolsversionchecker"
 	"github.com/juju/juju/worker/txnpruner"
+	"github.com/juju/juju/worker/unitstatemirror"
 	"github.com/juju/juju/worker/upgradedatabase"
 	"github.com/juju/juju/worker/upgrader"
 	"github.com/juju/juju/worker/upgradeseries"
@@ -233,6 +236,14 @@ type ManifoldsConfig struct {
 	// are pruned from the database.
 	TransactionPruneInterval time.Duration
 
+	// TemporaryAccessRevokeInterval defines how frequently expired
+	// temporary model access grants are revoked.
+	TemporaryAccessRevokeInterval time.Duration
+
+	// PermissionWebhookInterval defines how frequently queued changes
+	// to users' access are posted to the permission webhook.
+	PermissionWebhookInterval time.Duration
+
 	// SetStatePool is used by the state worker for informing the agent of
 	// the StatePool that it creates, so we can pass it to the introspection
 	// worker running outside of the dependency engine.
@@ -654,6 +665,24 @@ func commonManifolds(config ManifoldsConfig) dependency.Manifolds {
 			},
 		))),
 
+		accessExpiryName: ifNotMigrating(ifPrimaryController(accessexpiry.Manifold(
+			accessexpiry.ManifoldConfig{
+				ClockName:      clockName,
+				StateName:      stateName,
+				RevokeInterval: config.TemporaryAccessRevokeInterval,
+				NewWorker:      accessexpiry.New,
+			},
+		))),
+
+		permissionWebhookName: ifNotMigrating(ifPrimaryController(permissionwebhook.Manifold(
+			permissionwebhook.ManifoldConfig{
+				ClockName:    clockName,
+				StateName:    stateName,
+				PostInterval: config.PermissionWebhookInterval,
+				NewWorker:    permissionwebhook.New,
+			},
+		))),
+
 		httpServerArgsName: httpserverargs.Manifold(httpserverargs.ManifoldConfig{
 			ClockName:             clockName,
 			ControllerPortName:    controllerPortName,
@@ -749,6 +778,15 @@ func commonManifolds(config ManifoldsConfig) dependency.Manifolds {
 			NewWorker: auditconfigupdater.New,
 		})),
 
+		// The unit state mirror runs on every controller, so that
+		// the unit state can be recovered from whichever controller
+		// is restored.
+		unitStateMirrorName: ifDatabaseUpgradeComplete(ifController(unitstatemirror.Manifold(unitstatemirror.ManifoldConfig{
+			StateName: stateName,
+			Logger:    loggo.GetLogger("juju.worker.unitstatemirror"),
+			NewWorker: unitstatemirror.NewWorker,
+		}))),
+
 		raftTransportName: ifController(rafttransport.Manifold(rafttransport.ManifoldConfig{
 			ClockName:         clockName,
 			AgentName:         agentName,
@@ -1154,6 +1192,8 @@ const (
 	isControllerFlagName          = "is-controller-flag"
 	instanceMutaterName           = "instance-mutater"
 	txnPrunerName                 = "transaction-pruner"
+	accessExpiryName              = "access-expiry"
+	permissionWebhookName         = "permission-webhook"
 	certificateWatcherName        = "certificate-watcher"
 	modelCacheName                = "model-cache"
 	modelCacheInitializedFlagName = "model-cache-initialized-flag"
@@ -1164,6 +1204,7 @@ const (
 	restoreWatcherName            = "restore-watcher"
 	certificateUpdaterName        = "certificate-updater"
 	auditConfigUpdaterName        = "audit-config-updater"
+	unitStateMirrorName           = "unit-state-mirror"
 	leaseManagerName              = "lease-manager"
 	legacyLeasesFlagName          = "legacy-leases-flag"
 
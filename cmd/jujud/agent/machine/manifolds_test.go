@@ -58,6 +58,7 @@ func (ms *ManifoldsSuite) TestManifoldNamesIAAS(c *gc.C) {
 			Agent: &mockAgent{},
 		}),
 		[]string{
+			"access-expiry",
 			"agent",
 			"agent-config-updater",
 			"api-address-updater",
@@ -98,6 +99,7 @@ func (ms *ManifoldsSuite) TestManifoldNamesIAAS(c *gc.C) {
 			"model-worker-manager",
 			"multiwatcher",
 			"peer-grouper",
+			"permission-webhook",
 			"presence",
 			"proxy-config-updater",
 			"pubsub-forwarder",
@@ -119,6 +121,7 @@ func (ms *ManifoldsSuite) TestManifoldNamesIAAS(c *gc.C) {
 			"transaction-pruner",
 			"unconverted-api-workers",
 			"unit-agent-deployer",
+			"unit-state-mirror",
 			"upgrade-check-flag",
 			"upgrade-check-gate",
 			"upgrade-database-flag",
@@ -140,6 +143,7 @@ func (ms *ManifoldsSuite) TestManifoldNamesCAAS(c *gc.C) {
 			Agent: &mockAgent{},
 		}),
 		[]string{
+			"access-expiry",
 			"agent",
 			"agent-config-updater",
 			"api-caller",
@@ -169,6 +173,7 @@ func (ms *ManifoldsSuite) TestManifoldNamesCAAS(c *gc.C) {
 			"model-worker-manager",
 			"multiwatcher",
 			"peer-grouper",
+			"permission-webhook",
 			"presence",
 			"proxy-config-updater",
 			"pubsub-forwarder",
@@ -185,6 +190,7 @@ func (ms *ManifoldsSuite) TestManifoldNamesCAAS(c *gc.C) {
 			"termination-signal-handler",
 			"transaction-pruner",
 			"unconverted-api-workers",
+			"unit-state-mirror",
 			"upgrade-check-flag",
 			"upgrade-check-gate",
 			"upgrade-database-flag",
@@ -252,6 +258,7 @@ func (ms *ManifoldsSuite) TestMigrationGuardsUsed(c *gc.C) {
 		"state",
 		"state-config-watcher",
 		"termination-signal-handler",
+		"unit-state-mirror",
 		"migration-fortress",
 		"migration-inactive-flag",
 		"migration-minion",
@@ -301,12 +308,15 @@ func (*ManifoldsSuite) TestSingularGuardsUsed(c *gc.C) {
 		"lease-manager",
 		"legacy-leases-flag",
 		"raft-transport",
+		"unit-state-mirror",
 		"upgrade-database-flag",
 		"upgrade-database-gate",
 		"upgrade-database-runner",
 	)
 	primaryControllerWorkers := set.NewStrings(
+		"access-expiry",
 		"external-controller-updater",
+		"permission-webhook",
 		"transaction-pruner",
 	)
 	for name, manifold := range manifolds {
@@ -408,6 +418,23 @@ func (ms *ManifoldsSuite) TestManifoldsDependencies(c *gc.C) {
 
 var expectedMachineManifoldsWithDependencies = map[string][]string{
 
+	"access-expiry": {
+		"agent",
+		"api-caller",
+		"api-config-watcher",
+		"clock",
+		"is-controller-flag",
+		"is-primary-controller-flag",
+		"migration-fortress",
+		"migration-inactive-flag",
+		"state",
+		"state-config-watcher",
+		"upgrade-check-flag",
+		"upgrade-check-gate",
+		"upgrade-steps-flag",
+		"upgrade-steps-gate",
+	},
+
 	"agent": {},
 
 	"agent-config-updater": {
@@ -812,6 +839,23 @@ var expectedMachineManifoldsWithDependencies = map[string][]string{
 		"upgrade-steps-gate",
 	},
 
+	"permission-webhook": {
+		"agent",
+		"api-caller",
+		"api-config-watcher",
+		"clock",
+		"is-controller-flag",
+		"is-primary-controller-flag",
+		"migration-fortress",
+		"migration-inactive-flag",
+		"state",
+		"state-config-watcher",
+		"upgrade-check-flag",
+		"upgrade-check-gate",
+		"upgrade-steps-flag",
+		"upgrade-steps-gate",
+	},
+
 	"presence": {"agent", "central-hub", "state-config-watcher"},
 
 	"proxy-config-updater": {
@@ -1039,6 +1083,15 @@ var expectedMachineManifoldsWithDependencies = map[string][]string{
 		"upgrade-steps-gate",
 	},
 
+	"unit-state-mirror": {
+		"agent",
+		"is-controller-flag",
+		"state",
+		"state-config-watcher",
+		"upgrade-database-flag",
+		"upgrade-database-gate",
+	},
+
 	"upgrade-check-flag": {"upgrade-check-gate"},
 
 	"upgrade-check-gate": {},
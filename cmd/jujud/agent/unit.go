@@ -11,6 +11,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/juju/gnuflag"
 	"github.com/juju/loggo"
+	"github.com/juju/utils/featureflag"
 	"github.com/juju/utils/voyeur"
 	"github.com/prometheus/client_golang/prometheus"
 	"gopkg.in/juju/names.v3"
@@ -25,11 +26,13 @@ import (
 	"github.com/juju/juju/cmd/jujud/agent/unit"
 	cmdutil "github.com/juju/juju/cmd/jujud/util"
 	"github.com/juju/juju/core/machinelock"
+	"github.com/juju/juju/feature"
 	"github.com/juju/juju/upgrades"
 	jworker "github.com/juju/juju/worker"
 	"github.com/juju/juju/worker/gate"
 	"github.com/juju/juju/worker/introspection"
 	"github.com/juju/juju/worker/logsender"
+	"github.com/juju/juju/worker/uniter/remotestate"
 	"github.com/juju/juju/worker/upgradesteps"
 )
 
@@ -191,6 +194,16 @@ func (a *UnitAgent) APIWorkers() (worker.Worker, error) {
 		return nil, errors.Trace(err)
 	}
 
+	// Synthetic relation changes can only be injected, through the
+	// introspection socket, when the feature flag is enabled.
+	var relationInjector *remotestate.Injector
+	var introspectionInjector introspection.RelationInjector
+	if featureflag.Enabled(feature.InjectRelationState) {
+		logger.Warningf("relation state injection enabled, for testing charms only")
+		relationInjector = remotestate.NewInjector()
+		introspectionInjector = relationInjector
+	}
+
 	manifolds := unitManifolds(unit.ManifoldsConfig{
 		Agent:                agent.APIHostPortsSetter{a},
 		LogSource:            a.bufferedLogger.Logs(),
@@ -205,6 +218,7 @@ func (a *UnitAgent) APIWorkers() (worker.Worker, error) {
 		UpgradeCheckLock:     a.initialUpgradeCheckComplete,
 		MachineLock:          machineLock,
 		Clock:                clock.WallClock,
+		RelationInjector:     relationInjector,
 	})
 
 	engine, err := dependency.NewEngine(dependencyEngineConfig())
@@ -223,6 +237,7 @@ func (a *UnitAgent) APIWorkers() (worker.Worker, error) {
 		NewSocketName:      DefaultIntrospectionSocketName,
 		PrometheusGatherer: a.prometheusRegistry,
 		MachineLock:        machineLock,
+		RelationInjector:   introspectionInjector,
 		WorkerFunc:         introspection.NewWorker,
 	}); err != nil {
 		// If the introspection worker failed to start, we just log error
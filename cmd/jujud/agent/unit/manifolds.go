@@ -42,6 +42,7 @@ import (
 	"github.com/juju/juju/worker/proxyupdater"
 	"github.com/juju/juju/worker/retrystrategy"
 	"github.com/juju/juju/worker/uniter"
+	"github.com/juju/juju/worker/uniter/remotestate"
 	"github.com/juju/juju/worker/upgrader"
 	"github.com/juju/juju/worker/upgradesteps"
 )
@@ -102,6 +103,12 @@ type ManifoldsConfig struct {
 
 	// Clock supplies timekeeping services to various workers.
 	Clock clock.Clock
+
+	// RelationInjector, if not nil, is used by the uniter to accept
+	// synthetic relation changes injected through the introspection
+	// socket. It is only set when the inject-relation-state feature
+	// flag is enabled.
+	RelationInjector *remotestate.Injector
 }
 
 // Manifolds returns a set of co-configured manifolds covering the various
@@ -318,6 +325,7 @@ func Manifolds(config ManifoldsConfig) dependency.Manifolds {
 			CharmDirName:          charmDirName,
 			HookRetryStrategyName: hookRetryStrategyName,
 			TranslateResolverErr:  uniter.TranslateFortressErrors,
+			RelationInjector:      config.RelationInjector,
 		})),
 
 		// TODO (mattyw) should be added to machine agent.
@@ -0,0 +1,337 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package simulateupgrade provides a command that runs the database
+// upgrade steps against a scratch copy of a controller's database,
+// so that field upgrades can be tested before touching production.
+package simulateupgrade
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/juju/clock"
+	"github.com/juju/cmd"
+	"github.com/juju/collections/set"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"github.com/juju/loggo"
+	"github.com/juju/version"
+	"gopkg.in/juju/names.v3"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/juju/juju/agent"
+	jujucmd "github.com/juju/juju/cmd"
+	"github.com/juju/juju/cmd/output"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/upgrades"
+	jujuversion "github.com/juju/juju/version"
+	"github.com/juju/juju/worker/upgradedatabase"
+)
+
+var logger = loggo.GetLogger("juju.cmd.jujud.simulateupgrade")
+
+const (
+	// jujuDB is the database holding the controller's state.
+	jujuDB = "juju"
+
+	// controllersC is the collection recording the controller's
+	// UUID and that of its model.
+	controllersC = "controllers"
+
+	// controllerModelKey identifies the controllers document
+	// that records the controller model.
+	controllerModelKey = "e"
+
+	dialTimeout = 30 * time.Second
+)
+
+// NewCommand returns a new Command instance which implements the
+// "jujud simulate-upgrade" command.
+func NewCommand() cmd.Command {
+	return &simulateUpgradeCommand{
+		runCommand: runCommand,
+	}
+}
+
+type simulateUpgradeCommand struct {
+	cmd.CommandBase
+	out output.Output
+
+	dumpDir      string
+	mongoAddress string
+	fromVersion  string
+
+	from       version.Number
+	runCommand func(name string, args ...string) error
+}
+
+// Info implements cmd.Command.
+func (c *simulateUpgradeCommand) Info() *cmd.Info {
+	doc := `
+This tool loads a dump of a controller's database, taken with mongodump
+and usually anonymized, into a scratch MongoDB server and runs the
+database upgrade steps for this version of Juju against it. It reports
+how long each step took, any step that failed, and the changes each step
+made to the number of documents in each collection.
+
+The scratch server must not already hold a Juju database, and should be
+disposable: the upgrade is run for real against the restored copy. The
+restore is done with mongorestore, which must be installed.
+
+By default the upgrade is run from the agent version recorded for the
+controller model in the dump. Use --from to override it, for instance
+if the dump was taken part way through a failed upgrade.
+
+Examples:
+
+    jujud simulate-upgrade --dump ./controller-dump
+    jujud simulate-upgrade --dump ./controller-dump --mongo localhost:27018 --format json
+`[1:]
+	return jujucmd.Info(&cmd.Info{
+		Name:    "simulate-upgrade",
+		Purpose: "run the database upgrade steps against a copy of a controller's database",
+		Doc:     doc,
+	})
+}
+
+// SetFlags implements cmd.Command.
+func (c *simulateUpgradeCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "yaml", output.DefaultFormatters)
+	f.StringVar(&c.dumpDir, "dump", "", "directory holding the mongodump of the controller's database")
+	f.StringVar(&c.mongoAddress, "mongo", "localhost:27017", "address of the scratch MongoDB server")
+	f.StringVar(&c.fromVersion, "from", "", "version to upgrade from (optional)")
+}
+
+// Init implements cmd.Command.
+func (c *simulateUpgradeCommand) Init(args []string) error {
+	if c.dumpDir == "" {
+		return errors.New("--dump option is required")
+	}
+	if c.fromVersion != "" {
+		v, err := version.Parse(c.fromVersion)
+		if err != nil {
+			return errors.Annotate(err, "invalid --from version")
+		}
+		c.from = v
+	}
+	return cmd.CheckEmpty(args)
+}
+
+// Run implements cmd.Command.
+func (c *simulateUpgradeCommand) Run(ctx *cmd.Context) error {
+	session, err := mgo.DialWithTimeout(c.mongoAddress, dialTimeout)
+	if err != nil {
+		return errors.Annotatef(err, "cannot connect to scratch database at %s", c.mongoAddress)
+	}
+	defer session.Close()
+
+	if err := checkScratchDatabase(session); err != nil {
+		return errors.Annotatef(err, "cannot use scratch database at %s", c.mongoAddress)
+	}
+	ctx.Infof("restoring %s into %s", c.dumpDir, c.mongoAddress)
+	if err := c.restore(ctx.AbsPath(c.dumpDir)); err != nil {
+		return errors.Trace(err)
+	}
+
+	controllerTag, modelTag, err := readControllerIdentity(session)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	pool, err := state.OpenStatePool(state.OpenParams{
+		Clock:              clock.WallClock,
+		ControllerTag:      controllerTag,
+		ControllerModelTag: modelTag,
+		MongoSession:       session,
+	})
+	if err != nil {
+		return errors.Annotate(err, "cannot open restored database")
+	}
+	defer pool.Close()
+
+	from := c.from
+	if from == version.Zero {
+		if from, err = controllerAgentVersion(pool); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
+	dataDir, err := ioutil.TempDir("", "juju-simulate-upgrade")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer os.RemoveAll(dataDir)
+	agentConfig, err := scratchAgentConfig(pool, dataDir, from)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	ctx.Infof("simulating upgrade from %v to %v", from, jujuversion.Current)
+	report, err := upgradedatabase.Simulate(upgradedatabase.SimulationConfig{
+		FromVersion: from,
+		ToVersion:   jujuversion.Current,
+		Context:     upgrades.NewContext(agentConfig, nil, upgrades.NewStateBackend(pool)),
+		PerformUpgrade: func(
+			v version.Number, t []upgrades.Target, context upgrades.Context, observer upgrades.StepObserver,
+		) error {
+			return upgrades.PerformObservedStateUpgrade(v, t, context, observer)
+		},
+		ValidateUpgrade: upgrades.ValidateStateUpgrade,
+		CountDocuments: func() (map[string]int, error) {
+			return countDocuments(session.DB(jujuDB))
+		},
+		Clock: clock.WallClock,
+	})
+	if err != nil {
+		return errors.Annotate(err, "simulating upgrade")
+	}
+	if err := c.out.Write(ctx, report); err != nil {
+		return errors.Trace(err)
+	}
+	if report.Failed() {
+		return cmd.NewRcPassthroughError(1)
+	}
+	return nil
+}
+
+// checkScratchDatabase ensures that the scratch server does not already
+// hold a Juju database, so that the simulation can never be pointed at
+// a running controller by mistake.
+func checkScratchDatabase(session *mgo.Session) error {
+	databases, err := session.DatabaseNames()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if set.NewStrings(databases...).Contains(jujuDB) {
+		return errors.New("server already holds a Juju database")
+	}
+	return nil
+}
+
+// restore loads the dump into the scratch database.
+func (c *simulateUpgradeCommand) restore(dumpDir string) error {
+	mongorestore, err := mongorestorePath()
+	if err != nil {
+		return errors.Annotate(err, "mongorestore not available")
+	}
+	args := []string{
+		"--host", c.mongoAddress,
+		// A batch size this small is known to mitigate
+		// EOF errors from mongorestore; see TOOLS-939.
+		"--batchSize", "10",
+		dumpDir,
+	}
+	logger.Infof("restoring database with params %v", args)
+	if err := c.runCommand(mongorestore, args...); err != nil {
+		return errors.Annotate(err, "cannot restore database")
+	}
+	return nil
+}
+
+// mongorestorePath returns the path to mongorestore, preferring the
+// one installed alongside Juju's database.
+func mongorestorePath() (string, error) {
+	for _, name := range []string{"juju-db.mongorestore", "mongorestore"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", errors.NotFoundf("mongorestore")
+}
+
+func runCommand(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return errors.Annotatef(err, "%s failed: %s", filepath.Base(name), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// readControllerIdentity returns the tags of the controller
+// and controller model recorded in the restored database.
+func readControllerIdentity(session *mgo.Session) (names.ControllerTag, names.ModelTag, error) {
+	controllers := session.DB(jujuDB).C(controllersC)
+	var modelDoc struct {
+		ModelUUID string `bson:"model-uuid"`
+	}
+	if err := controllers.FindId(controllerModelKey).One(&modelDoc); err != nil {
+		return names.ControllerTag{}, names.ModelTag{}, errors.Annotate(err, "cannot read controller model from dump")
+	}
+	var settingsDoc struct {
+		Settings bson.M `bson:"settings"`
+	}
+	if err := controllers.FindId(state.ControllerSettingsGlobalKey).One(&settingsDoc); err != nil {
+		return names.ControllerTag{}, names.ModelTag{}, errors.Annotate(err, "cannot read controller settings from dump")
+	}
+	controllerUUID, _ := settingsDoc.Settings["controller-uuid"].(string)
+	if !names.IsValidController(controllerUUID) || !names.IsValidModel(modelDoc.ModelUUID) {
+		return names.ControllerTag{}, names.ModelTag{}, errors.New("dump does not hold a valid controller")
+	}
+	return names.NewControllerTag(controllerUUID), names.NewModelTag(modelDoc.ModelUUID), nil
+}
+
+// controllerAgentVersion returns the agent version of the controller
+// model, which is the version that last upgraded the database.
+func controllerAgentVersion(pool *state.StatePool) (version.Number, error) {
+	model, err := pool.SystemState().Model()
+	if err != nil {
+		return version.Zero, errors.Trace(err)
+	}
+	v, err := model.AgentVersion()
+	if err != nil {
+		return version.Zero, errors.Annotate(err, "cannot determine version to upgrade from")
+	}
+	return v, nil
+}
+
+// scratchAgentConfig returns an agent config for the upgrade steps that
+// need one, with its data and log directories in dataDir so that steps
+// writing to them cannot touch a real agent.
+func scratchAgentConfig(pool *state.StatePool, dataDir string, from version.Number) (agent.ConfigSetter, error) {
+	st := pool.SystemState()
+	controllerConfig, err := st.ControllerConfig()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	caCert, _ := controllerConfig.CACert()
+	config, err := agent.NewAgentConfig(agent.AgentConfigParams{
+		Paths: agent.Paths{
+			DataDir: dataDir,
+			LogDir:  filepath.Join(dataDir, "log"),
+		},
+		Tag:               names.NewMachineTag("0"),
+		UpgradedToVersion: from,
+		Password:          "simulated",
+		Controller:        st.ControllerTag(),
+		Model:             st.ControllerModelTag(),
+		APIAddresses:      []string{"localhost:17070"},
+		CACert:            caCert,
+	})
+	return config, errors.Trace(err)
+}
+
+// countDocuments returns the number of documents in each collection of
+// the database, ignoring the collections used by mongo itself.
+func countDocuments(db *mgo.Database) (map[string]int, error) {
+	collections, err := db.CollectionNames()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	counts := make(map[string]int, len(collections))
+	for _, name := range collections {
+		if strings.HasPrefix(name, "system.") {
+			continue
+		}
+		n, err := db.C(name).Count()
+		if err != nil {
+			return nil, errors.Annotatef(err, "counting %s", name)
+		}
+		counts[name] = n
+	}
+	return counts, nil
+}
@@ -0,0 +1,35 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package simulateupgrade_test
+
+import (
+	"github.com/juju/cmd/cmdtesting"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/cmd/jujud/simulateupgrade"
+	"github.com/juju/juju/testing"
+)
+
+type SimulateUpgradeSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&SimulateUpgradeSuite{})
+
+func (s *SimulateUpgradeSuite) TestInit(c *gc.C) {
+	err := cmdtesting.InitCommand(simulateupgrade.NewCommand(), []string{"--dump", "dump", "--from", "2.7.6"})
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *SimulateUpgradeSuite) TestInitErrors(c *gc.C) {
+	s.assertInitError(c, "--dump option is required")
+	s.assertInitError(c, `invalid --from version: invalid version "2.7-nope"`, "--dump", "dump", "--from", "2.7-nope")
+	s.assertInitError(c, `unrecognized args: \["extra"\]`, "--dump", "dump", "extra")
+}
+
+func (*SimulateUpgradeSuite) assertInitError(c *gc.C, expect string, args ...string) {
+	err := cmdtesting.InitCommand(simulateupgrade.NewCommand(), args)
+	c.Assert(err, gc.ErrorMatches, expect)
+}
@@ -5,6 +5,8 @@ package controller
 
 import (
 	"fmt"
+	"net"
+	"net/mail"
 	"net/url"
 	"regexp"
 	"time"
@@ -257,6 +259,9 @@ const (
 	// other systems to operate concurrently.
 	DefaultPruneTxnSleepTime = "10ms"
 
+	// DefaultOIDCUsernameClaim is the default value for oidc-username-claim.
+	DefaultOIDCUsernameClaim = "preferred_username"
+
 	// JujuHASpace is the network space within which the MongoDB replica-set
 	// should communicate.
 	JujuHASpace = "juju-ha-space"
@@ -279,6 +284,90 @@ const (
 
 	// MeteringURL is the key for the url to use for metrics
 	MeteringURL = "metering-url"
+
+	// UpgradeCanaryModel is the UUID of the model that is validated
+	// after the database upgrade steps have run, before the upgrade
+	// is marked complete.
+	UpgradeCanaryModel = "upgrade-canary-model"
+
+	// UserNotificationWebhookURL is the URL to which notifications of
+	// user account events are posted.
+	UserNotificationWebhookURL = "user-notification-webhook-url"
+
+	// UserNotificationSMTPServer is the host:port of the SMTP server
+	// used to email notifications of user account events.
+	UserNotificationSMTPServer = "user-notification-smtp-server"
+
+	// UserNotificationEmailFrom is the address from which notifications
+	// of user account events are emailed.
+	UserNotificationEmailFrom = "user-notification-email-from"
+
+	// UserNotificationEmailTo is the address to which notifications
+	// of user account events are emailed.
+	UserNotificationEmailTo = "user-notification-email-to"
+
+	// UsernamePattern is a regular expression that the names of
+	// new local users must match in full.
+	UsernamePattern = "username-pattern"
+
+	// ReservedUsernames is a list of names that cannot be
+	// given to new local users.
+	ReservedUsernames = "reserved-usernames"
+
+	// MaxUsernameLength is the maximum length of the names of
+	// new local users. Zero means there is no limit.
+	MaxUsernameLength = "max-username-length"
+
+	// RequireSecondFactor sets whether local users must enroll a
+	// second authentication factor before they can use the controller.
+	RequireSecondFactor = "require-second-factor"
+
+	// PermissionWebhookURL is the URL to which changes to users'
+	// controller and model access are posted.
+	PermissionWebhookURL = "permission-webhook-url"
+
+	// PermissionWebhookSecret is the key with which posts to the
+	// permission webhook are signed.
+	PermissionWebhookSecret = "permission-webhook-secret"
+
+	// PasswordMaxAge is the length of time after which the passwords
+	// of local users expire, and must be changed before the users can
+	// do anything else. Zero means passwords do not expire.
+	PasswordMaxAge = "password-max-age"
+
+	// UpgradeRequiresApproval sets whether database upgrades pause, once
+	// the schema upgrade steps have run and before the backfill steps,
+	// until an operator approves the upgrade to proceed.
+	UpgradeRequiresApproval = "upgrade-requires-approval"
+
+	// UpgradeRemoveOrphans sets whether the documents of removed models,
+	// units and machines found when database upgrades complete are
+	// removed, rather than only reported.
+	UpgradeRemoveOrphans = "upgrade-remove-orphans"
+
+	// MirrorUnitState sets whether controllers mirror the state persisted
+	// by unit agents to files in the backup directory as it changes, so
+	// that it can be recovered when restoring from an older backup.
+	MirrorUnitState = "mirror-unit-state"
+
+	// OIDCIssuerURL is the URL of the OpenID Connect identity provider
+	// with which local users can log in using the device authorization
+	// flow, from which the provider's endpoints are discovered.
+	OIDCIssuerURL = "oidc-issuer-url"
+
+	// OIDCClientID is the client ID with which the controller is
+	// registered at the OpenID Connect identity provider.
+	OIDCClientID = "oidc-client-id"
+
+	// OIDCUsernameClaim is the claim, in the user info returned by the
+	// OpenID Connect identity provider, that names the local user that
+	// logs in.
+	OIDCUsernameClaim = "oidc-username-claim"
+
+	// GuestAccess sets whether clients may log in, without credentials,
+	// as the built-in read-only guest user, which can see only the models
+	// to which it has been granted access.
+	GuestAccess = "guest-access"
 )
 
 var (
@@ -322,6 +411,25 @@ var (
 		CAASImageRepo,
 		Features,
 		MeteringURL,
+		UpgradeCanaryModel,
+		UserNotificationWebhookURL,
+		UserNotificationSMTPServer,
+		UserNotificationEmailFrom,
+		UserNotificationEmailTo,
+		UsernamePattern,
+		ReservedUsernames,
+		MaxUsernameLength,
+		RequireSecondFactor,
+		PermissionWebhookURL,
+		PermissionWebhookSecret,
+		PasswordMaxAge,
+		UpgradeRequiresApproval,
+		UpgradeRemoveOrphans,
+		MirrorUnitState,
+		OIDCIssuerURL,
+		OIDCClientID,
+		OIDCUsernameClaim,
+		GuestAccess,
 	}
 
 	// AllowedUpdateConfigAttributes contains all of the controller
@@ -352,6 +460,25 @@ var (
 		CAASOperatorImagePath,
 		CAASImageRepo,
 		Features,
+		UpgradeCanaryModel,
+		UserNotificationWebhookURL,
+		UserNotificationSMTPServer,
+		UserNotificationEmailFrom,
+		UserNotificationEmailTo,
+		UsernamePattern,
+		ReservedUsernames,
+		MaxUsernameLength,
+		RequireSecondFactor,
+		PermissionWebhookURL,
+		PermissionWebhookSecret,
+		PasswordMaxAge,
+		UpgradeRequiresApproval,
+		UpgradeRemoveOrphans,
+		MirrorUnitState,
+		OIDCIssuerURL,
+		OIDCClientID,
+		OIDCUsernameClaim,
+		GuestAccess,
 	)
 
 	// DefaultAuditLogExcludeMethods is the default list of methods to
@@ -362,6 +489,10 @@ var (
 		ReadOnlyMethodsWildcard,
 	}
 
+	// DefaultReservedUsernames is the default list of names
+	// that cannot be given to new local users.
+	DefaultReservedUsernames = []string{"admin", "everyone"}
+
 	methodNameRE = regexp.MustCompile(`[[:alpha:]][[:alnum:]]*\.[[:alpha:]][[:alnum:]]*`)
 )
 
@@ -762,6 +893,137 @@ func (c Config) MeteringURL() string {
 	return url
 }
 
+// UpgradeCanaryModel returns the UUID of the model to validate after
+// upgrading the database, or an empty string if there is none.
+func (c Config) UpgradeCanaryModel() string {
+	return c.asString(UpgradeCanaryModel)
+}
+
+// UserNotificationWebhookURL returns the URL to which notifications of
+// user account events are posted, or an empty string if there is none.
+func (c Config) UserNotificationWebhookURL() string {
+	return c.asString(UserNotificationWebhookURL)
+}
+
+// UserNotificationSMTPServer returns the SMTP server used to email
+// notifications of user account events, or an empty string if there is none.
+func (c Config) UserNotificationSMTPServer() string {
+	return c.asString(UserNotificationSMTPServer)
+}
+
+// UserNotificationEmailFrom returns the address from which
+// notifications of user account events are emailed.
+func (c Config) UserNotificationEmailFrom() string {
+	return c.asString(UserNotificationEmailFrom)
+}
+
+// UserNotificationEmailTo returns the address to which
+// notifications of user account events are emailed.
+func (c Config) UserNotificationEmailTo() string {
+	return c.asString(UserNotificationEmailTo)
+}
+
+// UsernamePattern returns the regular expression that the names of new
+// local users must match in full, or an empty string if there is none.
+func (c Config) UsernamePattern() string {
+	return c.asString(UsernamePattern)
+}
+
+// ReservedUsernames returns the set of names that
+// cannot be given to new local users.
+func (c Config) ReservedUsernames() set.Strings {
+	if value, ok := c[ReservedUsernames]; ok {
+		value := value.([]interface{})
+		items := set.NewStrings()
+		for _, item := range value {
+			items.Add(item.(string))
+		}
+		return items
+	}
+	return set.NewStrings(DefaultReservedUsernames...)
+}
+
+// MaxUsernameLength returns the maximum length of the names
+// of new local users, or zero if there is no limit.
+func (c Config) MaxUsernameLength() int {
+	return c.intOrDefault(MaxUsernameLength, 0)
+}
+
+// RequireSecondFactor reports whether local users must enroll a second
+// authentication factor before they can use the controller.
+func (c Config) RequireSecondFactor() bool {
+	value, _ := c[RequireSecondFactor].(bool)
+	return value
+}
+
+// PermissionWebhookURL returns the URL to which changes to users' access
+// are posted, or an empty string if there is none.
+func (c Config) PermissionWebhookURL() string {
+	return c.asString(PermissionWebhookURL)
+}
+
+// PermissionWebhookSecret returns the key with which
+// posts to the permission webhook are signed.
+func (c Config) PermissionWebhookSecret() string {
+	return c.asString(PermissionWebhookSecret)
+}
+
+// PasswordMaxAge returns the length of time after which the passwords
+// of local users expire, or zero if they do not expire.
+func (c Config) PasswordMaxAge() time.Duration {
+	return c.durationOrDefault(PasswordMaxAge, 0)
+}
+
+// UpgradeRequiresApproval reports whether database upgrades pause
+// for an operator to approve them before they are completed.
+func (c Config) UpgradeRequiresApproval() bool {
+	value, _ := c[UpgradeRequiresApproval].(bool)
+	return value
+}
+
+// UpgradeRemoveOrphans reports whether the orphaned documents found
+// when database upgrades complete are removed.
+func (c Config) UpgradeRemoveOrphans() bool {
+	value, _ := c[UpgradeRemoveOrphans].(bool)
+	return value
+}
+
+// MirrorUnitState reports whether controllers mirror the state
+// persisted by unit agents to files in the backup directory.
+func (c Config) MirrorUnitState() bool {
+	value, _ := c[MirrorUnitState].(bool)
+	return value
+}
+
+// OIDCIssuerURL returns the URL of the OpenID Connect identity provider
+// with which users can log in using the device authorization flow, or
+// an empty string if there is none.
+func (c Config) OIDCIssuerURL() string {
+	return c.asString(OIDCIssuerURL)
+}
+
+// OIDCClientID returns the client ID with which the controller
+// is registered at the OpenID Connect identity provider.
+func (c Config) OIDCClientID() string {
+	return c.asString(OIDCClientID)
+}
+
+// OIDCUsernameClaim returns the claim in the user info returned by
+// the OpenID Connect identity provider that names the local user.
+func (c Config) OIDCUsernameClaim() string {
+	if value := c.asString(OIDCUsernameClaim); value != "" {
+		return value
+	}
+	return DefaultOIDCUsernameClaim
+}
+
+// GuestAccess reports whether clients may log in
+// as the built-in read-only guest user.
+func (c Config) GuestAccess() bool {
+	value, _ := c[GuestAccess].(bool)
+	return value
+}
+
 // Validate ensures that config is a valid configuration.
 func Validate(c Config) error {
 	if v, ok := c[IdentityPublicKey].(string); ok {
@@ -797,6 +1059,26 @@ func Validate(c Config) error {
 		return errors.Errorf("controller-uuid: expected UUID, got string(%q)", uuid)
 	}
 
+	if uuid, ok := c[UpgradeCanaryModel].(string); ok && uuid != "" && !utils.IsValidUUIDString(uuid) {
+		return errors.Errorf("%s: expected UUID, got string(%q)", UpgradeCanaryModel, uuid)
+	}
+
+	if err := validateUsernamePolicy(c); err != nil {
+		return errors.Trace(err)
+	}
+
+	if err := validateUserNotifications(c); err != nil {
+		return errors.Trace(err)
+	}
+
+	if err := validatePermissionWebhook(c); err != nil {
+		return errors.Trace(err)
+	}
+
+	if err := validateOIDC(c); err != nil {
+		return errors.Trace(err)
+	}
+
 	if v, ok := c[AgentRateLimitMax].(int); ok {
 		if v < 0 {
 			return errors.NotValidf("negative %s (%d)", AgentRateLimitMax, v)
@@ -826,6 +1108,10 @@ func Validate(c Config) error {
 		}
 	}
 
+	if v, ok := c[PasswordMaxAge].(time.Duration); ok && v < 0 {
+		return errors.Errorf("%s cannot be negative", PasswordMaxAge)
+	}
+
 	if v, ok := c[ModelLogsSize].(string); ok {
 		mb, err := utils.ParseSize(v)
 		if err != nil {
@@ -940,6 +1226,93 @@ func Validate(c Config) error {
 	return nil
 }
 
+// validatePermissionWebhook checks the permission webhook settings.
+// Posts to the webhook are always signed, so a secret is required.
+func validatePermissionWebhook(c Config) error {
+	v, _ := c[PermissionWebhookURL].(string)
+	if v == "" {
+		return nil
+	}
+	u, err := url.Parse(v)
+	if err != nil {
+		return errors.Annotatef(err, "invalid %s", PermissionWebhookURL)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.NotValidf("%s %q scheme", PermissionWebhookURL, v)
+	}
+	if secret, _ := c[PermissionWebhookSecret].(string); secret == "" {
+		return errors.Errorf("%s is required when %s is set", PermissionWebhookSecret, PermissionWebhookURL)
+	}
+	return nil
+}
+
+// validateOIDC checks the OpenID Connect identity provider settings.
+// The user's identity is obtained from the provider by the controller,
+// so the provider must be reached over https.
+func validateOIDC(c Config) error {
+	v, _ := c[OIDCIssuerURL].(string)
+	if v == "" {
+		return nil
+	}
+	u, err := url.Parse(v)
+	if err != nil {
+		return errors.Annotatef(err, "invalid %s", OIDCIssuerURL)
+	}
+	if u.Scheme != "https" {
+		return errors.NotValidf("%s %q scheme", OIDCIssuerURL, v)
+	}
+	if clientID, _ := c[OIDCClientID].(string); clientID == "" {
+		return errors.Errorf("%s is required when %s is set", OIDCClientID, OIDCIssuerURL)
+	}
+	return nil
+}
+
+// validateUserNotifications checks the user account event notification
+// settings. Email notifications need a server, and both addresses.
+func validateUserNotifications(c Config) error {
+	if v, ok := c[UserNotificationWebhookURL].(string); ok && v != "" {
+		u, err := url.Parse(v)
+		if err != nil {
+			return errors.Annotatef(err, "invalid %s", UserNotificationWebhookURL)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return errors.NotValidf("%s %q scheme", UserNotificationWebhookURL, v)
+		}
+	}
+
+	server, _ := c[UserNotificationSMTPServer].(string)
+	if server == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		return errors.Annotatef(err, "invalid %s", UserNotificationSMTPServer)
+	}
+	for _, key := range []string{UserNotificationEmailFrom, UserNotificationEmailTo} {
+		address, _ := c[key].(string)
+		if address == "" {
+			return errors.Errorf("%s is required when %s is set", key, UserNotificationSMTPServer)
+		}
+		if _, err := mail.ParseAddress(address); err != nil {
+			return errors.Annotatef(err, "invalid %s", key)
+		}
+	}
+	return nil
+}
+
+// validateUsernamePolicy checks the policy applied to the names of new
+// local users.
+func validateUsernamePolicy(c Config) error {
+	if v, ok := c[UsernamePattern].(string); ok && v != "" {
+		if _, err := regexp.Compile(v); err != nil {
+			return errors.Annotatef(err, "invalid %s", UsernamePattern)
+		}
+	}
+	if v, ok := c[MaxUsernameLength].(int); ok && v < 0 {
+		return errors.Errorf("%s cannot be negative", MaxUsernameLength)
+	}
+	return nil
+}
+
 func (c Config) validateSpaceConfig(key, topic string) error {
 	val := c[key]
 	if val == nil {
@@ -996,77 +1369,115 @@ func GenerateControllerCertAndKey(caCert, caKey string, hostAddresses []string)
 }
 
 var configChecker = schema.FieldMap(schema.Fields{
-	AgentRateLimitMax:       schema.ForceInt(),
-	AgentRateLimitRate:      schema.TimeDuration(),
-	AuditingEnabled:         schema.Bool(),
-	AuditLogCaptureArgs:     schema.Bool(),
-	AuditLogMaxSize:         schema.String(),
-	AuditLogMaxBackups:      schema.ForceInt(),
-	AuditLogExcludeMethods:  schema.List(schema.String()),
-	APIPort:                 schema.ForceInt(),
-	APIPortOpenDelay:        schema.String(),
-	ControllerAPIPort:       schema.ForceInt(),
-	ControllerName:          schema.String(),
-	StatePort:               schema.ForceInt(),
-	IdentityURL:             schema.String(),
-	IdentityPublicKey:       schema.String(),
-	SetNUMAControlPolicyKey: schema.Bool(),
-	AutocertURLKey:          schema.String(),
-	AutocertDNSNameKey:      schema.String(),
-	AllowModelAccessKey:     schema.Bool(),
-	MongoMemoryProfile:      schema.String(),
-	MaxDebugLogDuration:     schema.TimeDuration(),
-	MaxTxnLogSize:           schema.String(),
-	MaxPruneTxnBatchSize:    schema.ForceInt(),
-	MaxPruneTxnPasses:       schema.ForceInt(),
-	ModelLogfileMaxBackups:  schema.ForceInt(),
-	ModelLogfileMaxSize:     schema.String(),
-	ModelLogsSize:           schema.String(),
-	PruneTxnQueryCount:      schema.ForceInt(),
-	PruneTxnSleepTime:       schema.String(),
-	JujuHASpace:             schema.String(),
-	JujuManagementSpace:     schema.String(),
-	CAASOperatorImagePath:   schema.String(),
-	CAASImageRepo:           schema.String(),
-	Features:                schema.List(schema.String()),
-	CharmStoreURL:           schema.String(),
-	MeteringURL:             schema.String(),
+	AgentRateLimitMax:          schema.ForceInt(),
+	AgentRateLimitRate:         schema.TimeDuration(),
+	AuditingEnabled:            schema.Bool(),
+	AuditLogCaptureArgs:        schema.Bool(),
+	AuditLogMaxSize:            schema.String(),
+	AuditLogMaxBackups:         schema.ForceInt(),
+	AuditLogExcludeMethods:     schema.List(schema.String()),
+	APIPort:                    schema.ForceInt(),
+	APIPortOpenDelay:           schema.String(),
+	ControllerAPIPort:          schema.ForceInt(),
+	ControllerName:             schema.String(),
+	StatePort:                  schema.ForceInt(),
+	IdentityURL:                schema.String(),
+	IdentityPublicKey:          schema.String(),
+	SetNUMAControlPolicyKey:    schema.Bool(),
+	AutocertURLKey:             schema.String(),
+	AutocertDNSNameKey:         schema.String(),
+	AllowModelAccessKey:        schema.Bool(),
+	MongoMemoryProfile:         schema.String(),
+	MaxDebugLogDuration:        schema.TimeDuration(),
+	MaxTxnLogSize:              schema.String(),
+	MaxPruneTxnBatchSize:       schema.ForceInt(),
+	MaxPruneTxnPasses:          schema.ForceInt(),
+	ModelLogfileMaxBackups:     schema.ForceInt(),
+	ModelLogfileMaxSize:        schema.String(),
+	ModelLogsSize:              schema.String(),
+	PruneTxnQueryCount:         schema.ForceInt(),
+	PruneTxnSleepTime:          schema.String(),
+	JujuHASpace:                schema.String(),
+	JujuManagementSpace:        schema.String(),
+	CAASOperatorImagePath:      schema.String(),
+	CAASImageRepo:              schema.String(),
+	Features:                   schema.List(schema.String()),
+	CharmStoreURL:              schema.String(),
+	MeteringURL:                schema.String(),
+	UpgradeCanaryModel:         schema.String(),
+	UserNotificationWebhookURL: schema.String(),
+	UserNotificationSMTPServer: schema.String(),
+	UserNotificationEmailFrom:  schema.String(),
+	UserNotificationEmailTo:    schema.String(),
+	UsernamePattern:            schema.String(),
+	ReservedUsernames:          schema.List(schema.String()),
+	MaxUsernameLength:          schema.ForceInt(),
+	RequireSecondFactor:        schema.Bool(),
+	PermissionWebhookURL:       schema.String(),
+	PermissionWebhookSecret:    schema.String(),
+	PasswordMaxAge:             schema.TimeDuration(),
+	UpgradeRequiresApproval:    schema.Bool(),
+	UpgradeRemoveOrphans:       schema.Bool(),
+	MirrorUnitState:            schema.Bool(),
+	OIDCIssuerURL:              schema.String(),
+	OIDCClientID:               schema.String(),
+	OIDCUsernameClaim:          schema.String(),
+	GuestAccess:                schema.Bool(),
 }, schema.Defaults{
-	AgentRateLimitMax:       schema.Omit,
-	AgentRateLimitRate:      schema.Omit,
-	APIPort:                 DefaultAPIPort,
-	APIPortOpenDelay:        DefaultAPIPortOpenDelay,
-	ControllerAPIPort:       schema.Omit,
-	ControllerName:          schema.Omit,
-	AuditingEnabled:         DefaultAuditingEnabled,
-	AuditLogCaptureArgs:     DefaultAuditLogCaptureArgs,
-	AuditLogMaxSize:         fmt.Sprintf("%vM", DefaultAuditLogMaxSizeMB),
-	AuditLogMaxBackups:      DefaultAuditLogMaxBackups,
-	AuditLogExcludeMethods:  DefaultAuditLogExcludeMethods,
-	StatePort:               DefaultStatePort,
-	IdentityURL:             schema.Omit,
-	IdentityPublicKey:       schema.Omit,
-	SetNUMAControlPolicyKey: DefaultNUMAControlPolicy,
-	AutocertURLKey:          schema.Omit,
-	AutocertDNSNameKey:      schema.Omit,
-	AllowModelAccessKey:     schema.Omit,
-	MongoMemoryProfile:      DefaultMongoMemoryProfile,
-	MaxDebugLogDuration:     DefaultMaxDebugLogDuration,
-	MaxTxnLogSize:           fmt.Sprintf("%vM", DefaultMaxTxnLogCollectionMB),
-	MaxPruneTxnBatchSize:    DefaultMaxPruneTxnBatchSize,
-	MaxPruneTxnPasses:       DefaultMaxPruneTxnPasses,
-	ModelLogfileMaxBackups:  DefaultModelLogfileMaxBackups,
-	ModelLogfileMaxSize:     fmt.Sprintf("%vM", DefaultModelLogfileMaxSize),
-	ModelLogsSize:           fmt.Sprintf("%vM", DefaultModelLogsSizeMB),
-	PruneTxnQueryCount:      DefaultPruneTxnQueryCount,
-	PruneTxnSleepTime:       DefaultPruneTxnSleepTime,
-	JujuHASpace:             schema.Omit,
-	JujuManagementSpace:     schema.Omit,
-	CAASOperatorImagePath:   schema.Omit,
-	CAASImageRepo:           schema.Omit,
-	Features:                schema.Omit,
-	CharmStoreURL:           csclient.ServerURL,
-	MeteringURL:             romulus.DefaultAPIRoot,
+	AgentRateLimitMax:          schema.Omit,
+	AgentRateLimitRate:         schema.Omit,
+	APIPort:                    DefaultAPIPort,
+	APIPortOpenDelay:           DefaultAPIPortOpenDelay,
+	ControllerAPIPort:          schema.Omit,
+	ControllerName:             schema.Omit,
+	AuditingEnabled:            DefaultAuditingEnabled,
+	AuditLogCaptureArgs:        DefaultAuditLogCaptureArgs,
+	AuditLogMaxSize:            fmt.Sprintf("%vM", DefaultAuditLogMaxSizeMB),
+	AuditLogMaxBackups:         DefaultAuditLogMaxBackups,
+	AuditLogExcludeMethods:     DefaultAuditLogExcludeMethods,
+	StatePort:                  DefaultStatePort,
+	IdentityURL:                schema.Omit,
+	IdentityPublicKey:          schema.Omit,
+	SetNUMAControlPolicyKey:    DefaultNUMAControlPolicy,
+	AutocertURLKey:             schema.Omit,
+	AutocertDNSNameKey:         schema.Omit,
+	AllowModelAccessKey:        schema.Omit,
+	MongoMemoryProfile:         DefaultMongoMemoryProfile,
+	MaxDebugLogDuration:        DefaultMaxDebugLogDuration,
+	MaxTxnLogSize:              fmt.Sprintf("%vM", DefaultMaxTxnLogCollectionMB),
+	MaxPruneTxnBatchSize:       DefaultMaxPruneTxnBatchSize,
+	MaxPruneTxnPasses:          DefaultMaxPruneTxnPasses,
+	ModelLogfileMaxBackups:     DefaultModelLogfileMaxBackups,
+	ModelLogfileMaxSize:        fmt.Sprintf("%vM", DefaultModelLogfileMaxSize),
+	ModelLogsSize:              fmt.Sprintf("%vM", DefaultModelLogsSizeMB),
+	PruneTxnQueryCount:         DefaultPruneTxnQueryCount,
+	PruneTxnSleepTime:          DefaultPruneTxnSleepTime,
+	JujuHASpace:                schema.Omit,
+	JujuManagementSpace:        schema.Omit,
+	CAASOperatorImagePath:      schema.Omit,
+	CAASImageRepo:              schema.Omit,
+	Features:                   schema.Omit,
+	CharmStoreURL:              csclient.ServerURL,
+	MeteringURL:                romulus.DefaultAPIRoot,
+	UpgradeCanaryModel:         schema.Omit,
+	UserNotificationWebhookURL: schema.Omit,
+	UserNotificationSMTPServer: schema.Omit,
+	UserNotificationEmailFrom:  schema.Omit,
+	UserNotificationEmailTo:    schema.Omit,
+	UsernamePattern:            schema.Omit,
+	ReservedUsernames:          schema.Omit,
+	MaxUsernameLength:          schema.Omit,
+	RequireSecondFactor:        schema.Omit,
+	PermissionWebhookURL:       schema.Omit,
+	PermissionWebhookSecret:    schema.Omit,
+	PasswordMaxAge:             schema.Omit,
+	UpgradeRequiresApproval:    schema.Omit,
+	UpgradeRemoveOrphans:       schema.Omit,
+	MirrorUnitState:            schema.Omit,
+	OIDCIssuerURL:              schema.Omit,
+	OIDCClientID:               schema.Omit,
+	OIDCUsernameClaim:          schema.Omit,
+	GuestAccess:                schema.Omit,
 })
 
 // ConfigSchema holds information on all the fields defined by
@@ -1219,4 +1630,80 @@ Use "caas-image-repo" instead.`,
 		Type:        environschema.Tstring,
 		Description: `The url for metrics`,
 	},
+	UpgradeCanaryModel: {
+		Type:        environschema.Tstring,
+		Description: `The UUID of the model validated after upgrading the database, before the upgrade is marked complete`,
+	},
+	UserNotificationWebhookURL: {
+		Type:        environschema.Tstring,
+		Description: `The URL to which notifications of user account events are posted`,
+	},
+	UserNotificationSMTPServer: {
+		Type:        environschema.Tstring,
+		Description: `The host:port of the SMTP server used to email notifications of user account events`,
+	},
+	UserNotificationEmailFrom: {
+		Type:        environschema.Tstring,
+		Description: `The address from which notifications of user account events are emailed`,
+	},
+	UserNotificationEmailTo: {
+		Type:        environschema.Tstring,
+		Description: `The address to which notifications of user account events are emailed`,
+	},
+	UsernamePattern: {
+		Type:        environschema.Tstring,
+		Description: `A regular expression that the names of new local users must match in full`,
+	},
+	ReservedUsernames: {
+		Type:        environschema.FieldType("list of strings"),
+		Description: `A list of names that cannot be given to new local users`,
+	},
+	MaxUsernameLength: {
+		Type:        environschema.Tint,
+		Description: `The maximum length of the names of new local users, or 0 for no limit`,
+	},
+	RequireSecondFactor: {
+		Type:        environschema.Tbool,
+		Description: `Determines if local users must enroll a second authentication factor before they can use the controller`,
+	},
+	PermissionWebhookURL: {
+		Type:        environschema.Tstring,
+		Description: `The URL to which changes to users' controller and model access are posted`,
+	},
+	PermissionWebhookSecret: {
+		Type:        environschema.Tstring,
+		Description: `The key with which posts to the permission webhook are signed with HMAC-SHA256`,
+	},
+	PasswordMaxAge: {
+		Type:        environschema.Tstring,
+		Description: `The length of time after which the passwords of local users expire, or 0 if they do not expire`,
+	},
+	UpgradeRequiresApproval: {
+		Type:        environschema.Tbool,
+		Description: `Determines if database upgrades pause for an operator to approve them once the schema upgrade steps have run`,
+	},
+	UpgradeRemoveOrphans: {
+		Type:        environschema.Tbool,
+		Description: `Determines if the documents of removed models, units and machines found when database upgrades complete are removed, rather than only reported`,
+	},
+	MirrorUnitState: {
+		Type:        environschema.Tbool,
+		Description: `Determines if controllers mirror the state persisted by unit agents to files in the backup directory as it changes`,
+	},
+	OIDCIssuerURL: {
+		Type:        environschema.Tstring,
+		Description: `The URL of the OpenID Connect identity provider with which local users can log in using the device authorization flow`,
+	},
+	OIDCClientID: {
+		Type:        environschema.Tstring,
+		Description: `The client ID with which the controller is registered at the OpenID Connect identity provider`,
+	},
+	OIDCUsernameClaim: {
+		Type:        environschema.Tstring,
+		Description: `The claim in the OpenID Connect user info that names the local user logging in (default "` + DefaultOIDCUsernameClaim + `")`,
+	},
+	GuestAccess: {
+		Type:        environschema.Tbool,
+		Description: `Determines if clients may log in without credentials as the read-only guest user`,
+	},
 }
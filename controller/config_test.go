@@ -97,6 +97,95 @@ var validateTests = []struct {
 		controller.CACertKey:         testing.CACert,
 	},
 	expectError: `controller-uuid: expected UUID, got string\("xxx"\)`,
+}, {
+	about: "bad upgrade canary model UUID",
+	config: controller.Config{
+		controller.CACertKey:          testing.CACert,
+		controller.UpgradeCanaryModel: "xxx",
+	},
+	expectError: `upgrade-canary-model: expected UUID, got string\("xxx"\)`,
+}, {
+	about: "bad user notification webhook URL scheme",
+	config: controller.Config{
+		controller.CACertKey:                  testing.CACert,
+		controller.UserNotificationWebhookURL: "ftp://example.com/hook",
+	},
+	expectError: `user-notification-webhook-url "ftp://example.com/hook" scheme not valid`,
+}, {
+	about: "bad user notification SMTP server",
+	config: controller.Config{
+		controller.CACertKey:                  testing.CACert,
+		controller.UserNotificationSMTPServer: "smtp.example.com",
+	},
+	expectError: `invalid user-notification-smtp-server: .*missing port in address`,
+}, {
+	about: "user notification SMTP server without from address",
+	config: controller.Config{
+		controller.CACertKey:                  testing.CACert,
+		controller.UserNotificationSMTPServer: "smtp.example.com:25",
+		controller.UserNotificationEmailTo:    "audit@example.com",
+	},
+	expectError: `user-notification-email-from is required when user-notification-smtp-server is set`,
+}, {
+	about: "bad user notification email to address",
+	config: controller.Config{
+		controller.CACertKey:                  testing.CACert,
+		controller.UserNotificationSMTPServer: "smtp.example.com:25",
+		controller.UserNotificationEmailFrom:  "juju@example.com",
+		controller.UserNotificationEmailTo:    "audit",
+	},
+	expectError: `invalid user-notification-email-to: .*`,
+}, {
+	about: "bad permission webhook URL scheme",
+	config: controller.Config{
+		controller.CACertKey:               testing.CACert,
+		controller.PermissionWebhookURL:    "ftp://example.com/hook",
+		controller.PermissionWebhookSecret: "sekrit",
+	},
+	expectError: `permission-webhook-url "ftp://example.com/hook" scheme not valid`,
+}, {
+	about: "permission webhook URL without secret",
+	config: controller.Config{
+		controller.CACertKey:            testing.CACert,
+		controller.PermissionWebhookURL: "https://example.com/hook",
+	},
+	expectError: `permission-webhook-secret is required when permission-webhook-url is set`,
+}, {
+	about: "bad OIDC issuer URL scheme",
+	config: controller.Config{
+		controller.CACertKey:     testing.CACert,
+		controller.OIDCIssuerURL: "http://example.com",
+		controller.OIDCClientID:  "juju",
+	},
+	expectError: `oidc-issuer-url "http://example.com" scheme not valid`,
+}, {
+	about: "OIDC issuer URL without client ID",
+	config: controller.Config{
+		controller.CACertKey:     testing.CACert,
+		controller.OIDCIssuerURL: "https://example.com",
+	},
+	expectError: `oidc-client-id is required when oidc-issuer-url is set`,
+}, {
+	about: "bad username pattern",
+	config: controller.Config{
+		controller.CACertKey:       testing.CACert,
+		controller.UsernamePattern: "[a-z",
+	},
+	expectError: `invalid username-pattern: .*missing closing \].*`,
+}, {
+	about: "negative max username length",
+	config: controller.Config{
+		controller.CACertKey:         testing.CACert,
+		controller.MaxUsernameLength: -1,
+	},
+	expectError: `max-username-length cannot be negative`,
+}, {
+	about: "negative password max age",
+	config: controller.Config{
+		controller.CACertKey:      testing.CACert,
+		controller.PasswordMaxAge: -time.Hour,
+	},
+	expectError: `password-max-age cannot be negative`,
 }}
 
 func (s *ConfigSuite) TestValidate(c *gc.C) {
@@ -512,6 +601,152 @@ func (s *ConfigSuite) TestAuditLogExcludeMethodsType(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, `audit-log-exclude-methods\[0\]: expected string, got int\(2\)`)
 }
 
+func (s *ConfigSuite) TestUsernamePolicy(c *gc.C) {
+	cfg, err := controller.NewConfig(
+		testing.ControllerTag.Id(),
+		testing.CACert,
+		map[string]interface{}{},
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.UsernamePattern(), gc.Equals, "")
+	c.Assert(cfg.ReservedUsernames().SortedValues(), jc.DeepEquals, []string{"admin", "everyone"})
+	c.Assert(cfg.MaxUsernameLength(), gc.Equals, 0)
+
+	cfg, err = controller.NewConfig(
+		testing.ControllerTag.Id(),
+		testing.CACert,
+		map[string]interface{}{
+			"username-pattern":    "[a-z]+",
+			"reserved-usernames":  []interface{}{"root"},
+			"max-username-length": "16",
+		},
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.UsernamePattern(), gc.Equals, "[a-z]+")
+	c.Assert(cfg.ReservedUsernames().SortedValues(), jc.DeepEquals, []string{"root"})
+	c.Assert(cfg.MaxUsernameLength(), gc.Equals, 16)
+}
+
+func (s *ConfigSuite) TestRequireSecondFactor(c *gc.C) {
+	cfg, err := controller.NewConfig(
+		testing.ControllerTag.Id(),
+		testing.CACert,
+		map[string]interface{}{},
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.RequireSecondFactor(), jc.IsFalse)
+
+	cfg, err = controller.NewConfig(
+		testing.ControllerTag.Id(),
+		testing.CACert,
+		map[string]interface{}{
+			controller.RequireSecondFactor: true,
+		},
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.RequireSecondFactor(), jc.IsTrue)
+}
+
+func (s *ConfigSuite) TestPasswordMaxAge(c *gc.C) {
+	cfg, err := controller.NewConfig(
+		testing.ControllerTag.Id(),
+		testing.CACert,
+		map[string]interface{}{},
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.PasswordMaxAge(), gc.Equals, time.Duration(0))
+
+	cfg, err = controller.NewConfig(
+		testing.ControllerTag.Id(),
+		testing.CACert,
+		map[string]interface{}{
+			controller.PasswordMaxAge: "2160h",
+		},
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.PasswordMaxAge(), gc.Equals, 90*24*time.Hour)
+}
+
+func (s *ConfigSuite) TestUpgradeRequiresApproval(c *gc.C) {
+	cfg, err := controller.NewConfig(
+		testing.ControllerTag.Id(),
+		testing.CACert,
+		map[string]interface{}{},
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.UpgradeRequiresApproval(), jc.IsFalse)
+
+	cfg, err = controller.NewConfig(
+		testing.ControllerTag.Id(),
+		testing.CACert,
+		map[string]interface{}{
+			controller.UpgradeRequiresApproval: true,
+		},
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.UpgradeRequiresApproval(), jc.IsTrue)
+}
+
+func (s *ConfigSuite) TestUpgradeRemoveOrphans(c *gc.C) {
+	cfg, err := controller.NewConfig(
+		testing.ControllerTag.Id(),
+		testing.CACert,
+		map[string]interface{}{},
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.UpgradeRemoveOrphans(), jc.IsFalse)
+
+	cfg, err = controller.NewConfig(
+		testing.ControllerTag.Id(),
+		testing.CACert,
+		map[string]interface{}{
+			controller.UpgradeRemoveOrphans: true,
+		},
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.UpgradeRemoveOrphans(), jc.IsTrue)
+}
+
+func (s *ConfigSuite) TestMirrorUnitState(c *gc.C) {
+	cfg, err := controller.NewConfig(
+		testing.ControllerTag.Id(),
+		testing.CACert,
+		map[string]interface{}{},
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.MirrorUnitState(), jc.IsFalse)
+
+	cfg, err = controller.NewConfig(
+		testing.ControllerTag.Id(),
+		testing.CACert,
+		map[string]interface{}{
+			controller.MirrorUnitState: true,
+		},
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.MirrorUnitState(), jc.IsTrue)
+}
+
+func (s *ConfigSuite) TestGuestAccess(c *gc.C) {
+	cfg, err := controller.NewConfig(
+		testing.ControllerTag.Id(),
+		testing.CACert,
+		map[string]interface{}{},
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.GuestAccess(), jc.IsFalse)
+
+	cfg, err = controller.NewConfig(
+		testing.ControllerTag.Id(),
+		testing.CACert,
+		map[string]interface{}{
+			controller.GuestAccess: true,
+		},
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.GuestAccess(), jc.IsTrue)
+}
+
 func (s *ConfigSuite) TestAuditLogFloatBackupsLoadedDirectly(c *gc.C) {
 	// We still need to be able to handle floats in data loaded from the DB.
 	cfg := controller.Config{
@@ -33,6 +33,7 @@ type Conversation struct {
 	ModelUUID      string `json:"model-uuid"`
 	ConversationID string `json:"conversation-id"` // uint64 in hex
 	ConnectionID   string `json:"connection-id"`   // uint64 in hex (using %X to match the value in log files)
+	ImpersonatedBy string `json:"impersonated-by,omitempty"`
 }
 
 // ConversationArgs is the information needed to create a method recorder.
@@ -42,6 +43,11 @@ type ConversationArgs struct {
 	ModelName    string
 	ModelUUID    string
 	ConnectionID uint64
+
+	// ImpersonatedBy is the name of the user impersonating the user
+	// who logged in, if any. It is recorded with the conversation and
+	// with each of its requests.
+	ImpersonatedBy string
 }
 
 // Request represents a call to an API facade made as part of
@@ -55,6 +61,7 @@ type Request struct {
 	Method         string `json:"method"`
 	Version        int    `json:"version"`
 	Args           string `json:"args,omitempty"`
+	ImpersonatedBy string `json:"impersonated-by,omitempty"`
 }
 
 // RequestArgs is the information about an API call that we want to
@@ -109,10 +116,11 @@ type AuditLog interface {
 
 // Recorder records method calls for a specific API connection.
 type Recorder struct {
-	log          AuditLog
-	clock        clock.Clock
-	connectionID string
-	callID       string
+	log            AuditLog
+	clock          clock.Clock
+	connectionID   string
+	callID         string
+	impersonatedBy string
 }
 
 // NewRecorder creates a Recorder for the connection described (and
@@ -128,15 +136,17 @@ func NewRecorder(log AuditLog, clock clock.Clock, c ConversationArgs) (*Recorder
 		When:           clock.Now().Format(time.RFC3339),
 		ModelName:      c.ModelName,
 		ModelUUID:      c.ModelUUID,
+		ImpersonatedBy: c.ImpersonatedBy,
 	})
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 	return &Recorder{
-		log:          log,
-		clock:        clock,
-		callID:       callID,
-		connectionID: connectionID,
+		log:            log,
+		clock:          clock,
+		callID:         callID,
+		connectionID:   connectionID,
+		impersonatedBy: c.ImpersonatedBy,
 	}, nil
 }
 
@@ -151,6 +161,7 @@ func (r *Recorder) AddRequest(m RequestArgs) error {
 		Method:         m.Method,
 		Version:        m.Version,
 		Args:           m.Args,
+		ImpersonatedBy: r.impersonatedBy,
 	}))
 }
 
@@ -142,6 +142,43 @@ func (s *AuditLogSuite) TestRecorder(c *gc.C) {
 	})
 }
 
+func (s *AuditLogSuite) TestRecorderImpersonated(c *gc.C) {
+	var log fakeLog
+	logTime, err := time.Parse(time.RFC3339, "2017-11-27T15:45:23Z")
+	c.Assert(err, jc.ErrorIsNil)
+	clock := testclock.NewClock(logTime)
+	rec, err := auditlog.NewRecorder(&log, clock, auditlog.ConversationArgs{
+		Who:            "wildbirds and peacedrums",
+		What:           "Doubt/Hope",
+		ModelName:      "admin/default",
+		ConnectionID:   687,
+		ImpersonatedBy: "admin",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	err = rec.AddRequest(auditlog.RequestArgs{
+		RequestID: 246,
+		Facade:    "Death Vessel",
+		Method:    "Horchata",
+		Version:   5,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	log.stub.CheckCallNames(c, "AddConversation", "AddRequest")
+	calls := log.stub.Calls()
+	rec0 := calls[0].Args[0].(auditlog.Conversation)
+	c.Assert(rec0.ImpersonatedBy, gc.Equals, "admin")
+	c.Assert(calls[1].Args[0], gc.DeepEquals, auditlog.Request{
+		ConversationID: rec0.ConversationID,
+		ConnectionID:   "2AF",
+		RequestID:      246,
+		When:           "2017-11-27T15:45:23Z",
+		Facade:         "Death Vessel",
+		Method:         "Horchata",
+		Version:        5,
+		ImpersonatedBy: "admin",
+	})
+}
+
 type fakeLog struct {
 	stub testing.Stub
 }
@@ -0,0 +1,159 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package migration
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/version"
+)
+
+// The annotations with which the provenance of a migrated model is
+// recorded in the target controller.
+const (
+	ExporterVersionAnnotation = "migration-exporter-version"
+	ExportedAnnotation        = "migration-exported"
+	ImportedAnnotation        = "migration-imported"
+	HashAnnotation            = "migration-sha256"
+)
+
+// ExportManifest describes a serialized model, so that the target
+// controller can verify that the model it imports is the model that
+// the source controller exported, neither truncated nor tampered with.
+type ExportManifest struct {
+	// ExporterVersion is the version of the controller
+	// that exported the model.
+	ExporterVersion version.Number
+
+	// Exported is when the model was exported.
+	Exported time.Time
+
+	// Hash is the hex encoded SHA256 hash of the serialized model.
+	Hash string
+
+	// Sections holds the hex encoded SHA256 hash of each top level
+	// section of the serialized model, keyed by the section name, so
+	// that a verification failure can report which sections differ.
+	Sections map[string]string
+}
+
+// NewExportManifest returns the manifest for the serialized model.
+func NewExportManifest(serialized []byte, exporterVersion version.Number, exported time.Time) ExportManifest {
+	sections := make(map[string]string)
+	for name, content := range serializedSections(serialized) {
+		sections[name] = hash(content)
+	}
+	return ExportManifest{
+		ExporterVersion: exporterVersion,
+		Exported:        exported.UTC(),
+		Hash:            hash(serialized),
+		Sections:        sections,
+	}
+}
+
+// Verify returns an error satisfying errors.IsNotValid if the serialized
+// model does not match the manifest, naming the sections that differ.
+func (m ExportManifest) Verify(serialized []byte) error {
+	if m.Hash == "" {
+		return errors.NotValidf("manifest without hash")
+	}
+	if hash(serialized) == m.Hash {
+		return nil
+	}
+
+	var changed, missing, unexpected []string
+	sections := serializedSections(serialized)
+	for name, expected := range m.Sections {
+		content, ok := sections[name]
+		if !ok {
+			missing = append(missing, name)
+		} else if hash(content) != expected {
+			changed = append(changed, name)
+		}
+	}
+	for name := range sections {
+		if _, ok := m.Sections[name]; !ok {
+			unexpected = append(unexpected, name)
+		}
+	}
+
+	var details []string
+	for _, d := range []struct {
+		label string
+		names []string
+	}{
+		{"missing", missing},
+		{"changed", changed},
+		{"unexpected", unexpected},
+	} {
+		if len(d.names) > 0 {
+			sort.Strings(d.names)
+			details = append(details, fmt.Sprintf("%s sections: %s", d.label, strings.Join(d.names, ", ")))
+		}
+	}
+	if len(details) == 0 {
+		details = append(details, fmt.Sprintf("%d bytes", len(serialized)))
+	}
+	return errors.NotValidf("serialized model (%s)", strings.Join(details, "; "))
+}
+
+// Annotations returns the annotations recording the
+// provenance of the model when it is imported.
+func (m ExportManifest) Annotations(imported time.Time) map[string]string {
+	return map[string]string{
+		ExporterVersionAnnotation: m.ExporterVersion.String(),
+		ExportedAnnotation:        m.Exported.UTC().Format(time.RFC3339),
+		ImportedAnnotation:        imported.UTC().Format(time.RFC3339),
+		HashAnnotation:            m.Hash,
+	}
+}
+
+// serializedSections splits a serialized model into its top level
+// sections, keyed by name. Each section runs from the line holding
+// its key to the line before the next key.
+func serializedSections(serialized []byte) map[string][]byte {
+	sections := make(map[string][]byte)
+	var name string
+	start, offset := 0, 0
+	for _, line := range bytes.SplitAfter(serialized, []byte("\n")) {
+		if key, ok := sectionKey(line); ok {
+			if name != "" {
+				sections[name] = serialized[start:offset]
+			}
+			name, start = key, offset
+		}
+		offset += len(line)
+	}
+	if name != "" {
+		sections[name] = serialized[start:]
+	}
+	return sections
+}
+
+// sectionKey returns the key of a top level mapping entry,
+// and whether the line holds one.
+func sectionKey(line []byte) (string, bool) {
+	if len(line) == 0 {
+		return "", false
+	}
+	switch line[0] {
+	case ' ', '\t', '-', '#', '\n', '\r':
+		return "", false
+	}
+	i := bytes.IndexByte(line, ':')
+	if i <= 0 {
+		return "", false
+	}
+	return string(line[:i]), true
+}
+
+func hash(content []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(content))
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package migration_test
+
+import (
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/version"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/core/migration"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type ManifestSuite struct {
+	coretesting.BaseSuite
+}
+
+var _ = gc.Suite(new(ManifestSuite))
+
+const serializedModel = `applications:
+- name: mysql
+  units:
+  - name: mysql/0
+machines:
+- id: "0"
+owner: admin
+version: 1
+`
+
+func (s *ManifestSuite) newManifest() migration.ExportManifest {
+	exported := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	return migration.NewExportManifest([]byte(serializedModel), version.MustParse("2.8.0"), exported)
+}
+
+func (s *ManifestSuite) TestNewExportManifest(c *gc.C) {
+	manifest := s.newManifest()
+	c.Check(manifest.ExporterVersion, gc.Equals, version.MustParse("2.8.0"))
+	c.Check(manifest.Exported, gc.Equals, time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC))
+	c.Check(manifest.Hash, gc.HasLen, 64)
+	c.Check(manifest.Sections, gc.HasLen, 4)
+	for _, name := range []string{"applications", "machines", "owner", "version"} {
+		c.Check(manifest.Sections[name], gc.HasLen, 64, gc.Commentf("section %q", name))
+	}
+}
+
+func (s *ManifestSuite) TestVerify(c *gc.C) {
+	manifest := s.newManifest()
+	c.Assert(manifest.Verify([]byte(serializedModel)), jc.ErrorIsNil)
+}
+
+func (s *ManifestSuite) TestVerifyNoHash(c *gc.C) {
+	err := migration.ExportManifest{}.Verify([]byte(serializedModel))
+	c.Assert(err, gc.ErrorMatches, "manifest without hash not valid")
+}
+
+func (s *ManifestSuite) TestVerifyTampered(c *gc.C) {
+	manifest := s.newManifest()
+	tampered := strings.Replace(serializedModel, "admin", "mallory", 1)
+	err := manifest.Verify([]byte(tampered))
+	c.Assert(err, gc.ErrorMatches, `serialized model \(changed sections: owner\) not valid`)
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *ManifestSuite) TestVerifyTruncated(c *gc.C) {
+	manifest := s.newManifest()
+	truncated := serializedModel[:strings.Index(serializedModel, "owner:")]
+	err := manifest.Verify([]byte(truncated))
+	c.Assert(err, gc.ErrorMatches, `serialized model \(missing sections: owner, version\) not valid`)
+}
+
+func (s *ManifestSuite) TestVerifyAddedSection(c *gc.C) {
+	manifest := s.newManifest()
+	err := manifest.Verify([]byte(serializedModel + "users: []\n"))
+	c.Assert(err, gc.ErrorMatches, `serialized model \(unexpected sections: users\) not valid`)
+}
+
+func (s *ManifestSuite) TestAnnotations(c *gc.C) {
+	manifest := s.newManifest()
+	imported := time.Date(2020, 4, 1, 13, 30, 0, 0, time.UTC)
+	c.Assert(manifest.Annotations(imported), jc.DeepEquals, map[string]string{
+		"migration-exporter-version": "2.8.0",
+		"migration-exported":         "2020-04-01T12:00:00Z",
+		"migration-imported":         "2020-04-01T13:30:00Z",
+		"migration-sha256":           manifest.Hash,
+	})
+}
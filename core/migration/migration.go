@@ -13,6 +13,11 @@ import (
 	"github.com/juju/juju/resource"
 )
 
+// LeadershipPinEntity identifies a model migration as the entity
+// pinning application leadership, so that it does not change while
+// the model is in transit between controllers.
+const LeadershipPinEntity = "model-migration"
+
 // MigrationStatus returns the details for a migration as needed by
 // the migrationmaster worker.
 type MigrationStatus struct {
@@ -51,6 +56,11 @@ type SerializedModel struct {
 
 	// Resources represents all the resources in use in the model.
 	Resources []SerializedModelResource
+
+	// Manifest describes the serialized model, so that its integrity
+	// can be verified on import. It is nil if the source controller
+	// does not provide one.
+	Manifest *ExportManifest
 }
 
 // SerializedModelResource defines the resource revisions for a
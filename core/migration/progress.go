@@ -0,0 +1,56 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package migration
+
+// The kinds of entity whose import into the
+// target controller is reported as progress.
+const (
+	ImportMachines           = "machines"
+	ImportApplications       = "applications"
+	ImportUnits              = "units"
+	ImportRemoteApplications = "remote-applications"
+	ImportRelations          = "relations"
+	ImportStorage            = "storage"
+)
+
+// EntityProgress reports how many of the entities of
+// a kind have been imported into the target controller.
+type EntityProgress struct {
+	// Kind identifies the kind of entity.
+	Kind string
+
+	// Imported is the number of entities imported so far.
+	Imported int
+
+	// Total is the number of entities to be imported.
+	Total int
+}
+
+// ImportProgress reports the progress of the transfer of a
+// model to the target controller of its migration.
+type ImportProgress struct {
+	// Entities reports the progress of the import of
+	// each kind of entity, as reported by the target
+	// controller.
+	Entities []EntityProgress
+
+	// BinariesBytes is the number of bytes of charms, agent
+	// binaries and resources uploaded to the target controller.
+	BinariesBytes int64
+}
+
+// Imported returns the number of entities of all kinds
+// imported so far, and the number to be imported.
+func (p ImportProgress) Imported() (imported, total int) {
+	for _, entity := range p.Entities {
+		imported += entity.Imported
+		total += entity.Total
+	}
+	return imported, total
+}
+
+// IsZero returns true if no progress has been reported.
+func (p ImportProgress) IsZero() bool {
+	return len(p.Entities) == 0 && p.BinariesBytes == 0
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package migration_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/core/migration"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type ImportProgressSuite struct {
+	coretesting.BaseSuite
+}
+
+var _ = gc.Suite(new(ImportProgressSuite))
+
+func (s *ImportProgressSuite) TestIsZero(c *gc.C) {
+	progress := migration.ImportProgress{}
+	c.Check(progress.IsZero(), jc.IsTrue)
+}
+
+func (s *ImportProgressSuite) TestIsZeroBinariesBytesSet(c *gc.C) {
+	progress := migration.ImportProgress{
+		BinariesBytes: 1024,
+	}
+	c.Check(progress.IsZero(), jc.IsFalse)
+}
+
+func (s *ImportProgressSuite) TestImported(c *gc.C) {
+	progress := migration.ImportProgress{
+		Entities: []migration.EntityProgress{
+			{Kind: migration.ImportMachines, Imported: 3, Total: 4},
+			{Kind: migration.ImportUnits, Imported: 2, Total: 10},
+			{Kind: migration.ImportRelations},
+		},
+	}
+	imported, total := progress.Imported()
+	c.Check(imported, gc.Equals, 5)
+	c.Check(total, gc.Equals, 14)
+}
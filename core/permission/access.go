@@ -21,6 +21,11 @@ const (
 	// WriteAccess allows a user to make changes to a permission subject.
 	WriteAccess Access = "write"
 
+	// UserAdminAccess allows a user to make changes to a model, and to
+	// add users, enable and disable them, and grant them access, within
+	// the models to which the user has been granted it.
+	UserAdminAccess Access = "user-admin"
+
 	// ConsumeAccess allows a user to consume a permission subject.
 	ConsumeAccess Access = "consume"
 
@@ -42,7 +47,7 @@ const (
 // Validate returns error if the current is not a valid access level.
 func (a Access) Validate() error {
 	switch a {
-	case NoAccess, AdminAccess, ReadAccess, WriteAccess,
+	case NoAccess, AdminAccess, ReadAccess, WriteAccess, UserAdminAccess,
 		LoginAccess, AddModelAccess, SuperuserAccess:
 		return nil
 	}
@@ -53,7 +58,7 @@ func (a Access) Validate() error {
 // model access level.
 func ValidateModelAccess(access Access) error {
 	switch access {
-	case ReadAccess, WriteAccess, AdminAccess:
+	case ReadAccess, WriteAccess, UserAdminAccess, AdminAccess:
 		return nil
 	}
 	return errors.NotValidf("%q model access", access)
@@ -121,8 +126,10 @@ func (a Access) modelValue() int {
 		return 1
 	case WriteAccess:
 		return 2
-	case AdminAccess:
+	case UserAdminAccess:
 		return 3
+	case AdminAccess:
+		return 4
 	default:
 		return -1
 	}
@@ -65,6 +65,21 @@ func (*accessSuite) TestEqualOrGreaterModelAccessThan(c *gc.C) {
 	c.Check(admin.EqualOrGreaterModelAccessThan(admin), jc.IsTrue)
 }
 
+func (*accessSuite) TestUserAdminModelAccess(c *gc.C) {
+	userAdmin := permission.UserAdminAccess
+	c.Check(permission.ValidateModelAccess(userAdmin), jc.ErrorIsNil)
+	c.Check(permission.ValidateControllerAccess(userAdmin), gc.NotNil)
+
+	// User administration of a model implies write access to it,
+	// but not full control over it.
+	c.Check(userAdmin.EqualOrGreaterModelAccessThan(permission.WriteAccess), jc.IsTrue)
+	c.Check(userAdmin.GreaterModelAccessThan(permission.WriteAccess), jc.IsTrue)
+	c.Check(userAdmin.EqualOrGreaterModelAccessThan(userAdmin), jc.IsTrue)
+	c.Check(userAdmin.EqualOrGreaterModelAccessThan(permission.AdminAccess), jc.IsFalse)
+	c.Check(permission.AdminAccess.GreaterModelAccessThan(userAdmin), jc.IsTrue)
+	c.Check(userAdmin.EqualOrGreaterControllerAccessThan(permission.LoginAccess), jc.IsFalse)
+}
+
 func (*accessSuite) TestGreaterModelAccessThan(c *gc.C) {
 	// A very boring but necessary test to test explicit responses.
 	var (
@@ -0,0 +1,116 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package totp implements the time-based one-time passwords of RFC 6238,
+// as generated by common authenticator applications, for use as a second
+// authentication factor.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+const (
+	// SecretSize is the size in bytes of generated secrets.
+	SecretSize = 20
+
+	// Digits is the number of digits in a code.
+	Digits = 6
+
+	// Period is how long each code is valid for.
+	Period = 30 * time.Second
+
+	// Skew is the number of periods either side of the current
+	// one whose codes are also accepted, to allow for clock drift
+	// between the client and the controller.
+	Skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a new random secret.
+func GenerateSecret() ([]byte, error) {
+	secret := make([]byte, SecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, errors.Annotate(err, "generating secret")
+	}
+	return secret, nil
+}
+
+// EncodeSecret returns the secret encoded in base32, as it is
+// entered into authenticator applications.
+func EncodeSecret(secret []byte) string {
+	return encoding.EncodeToString(secret)
+}
+
+// ProvisioningURI returns the otpauth URI with which an authenticator
+// application is provisioned with the secret, usually by way of a QR
+// code. The issuer names the service, and the account the user.
+func ProvisioningURI(issuer, account string, secret []byte) string {
+	query := url.Values{
+		"secret":    {EncodeSecret(secret)},
+		"issuer":    {issuer},
+		"algorithm": {"SHA1"},
+		"digits":    {fmt.Sprint(Digits)},
+		"period":    {fmt.Sprint(int(Period / time.Second))},
+	}
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// Step returns the time step, counted in periods since the Unix
+// epoch, that the specified time falls in.
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Code returns the code for the secret during the specified time step.
+func Code(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation, as described in RFC 4226 section 5.3.
+	offset := sum[len(sum)-1] & 0xf
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulus := uint32(1)
+	for i := 0; i < Digits; i++ {
+		modulus *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%modulus)
+}
+
+// Match returns the time step of the code for the secret that matches
+// the supplied code, looking at the time steps within Skew of the
+// specified time, and whether there is one. Callers should refuse
+// codes for steps no later than the last one used, so that a code
+// cannot be replayed.
+func Match(secret []byte, code string, t time.Time) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+	now := Step(t)
+	for step := now - Skew; step <= now+Skew; step++ {
+		if subtle.ConstantTimeCompare([]byte(Code(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,89 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package totp_test
+
+import (
+	"net/url"
+	"time"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/core/totp"
+)
+
+type totpSuite struct{}
+
+var _ = gc.Suite(&totpSuite{})
+
+// rfcSecret is the SHA1 secret used by the test vectors in RFC 6238.
+var rfcSecret = []byte("12345678901234567890")
+
+func (s *totpSuite) TestCode(c *gc.C) {
+	// The RFC 6238 test vectors, truncated to six digits.
+	for i, t := range []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	} {
+		c.Logf("test %d: %d", i, t.unix)
+		c.Check(totp.Code(rfcSecret, totp.Step(time.Unix(t.unix, 0))), gc.Equals, t.code)
+	}
+}
+
+func (s *totpSuite) TestMatch(c *gc.C) {
+	now := time.Unix(1234567890, 0)
+	step := totp.Step(now)
+
+	matched, ok := totp.Match(rfcSecret, "005924", now)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(matched, gc.Equals, step)
+
+	// Codes from adjacent periods are accepted, to allow for clock drift.
+	matched, ok = totp.Match(rfcSecret, totp.Code(rfcSecret, step-1), now)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(matched, gc.Equals, step-1)
+	matched, ok = totp.Match(rfcSecret, totp.Code(rfcSecret, step+1), now)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(matched, gc.Equals, step+1)
+
+	_, ok = totp.Match(rfcSecret, totp.Code(rfcSecret, step-2), now)
+	c.Assert(ok, jc.IsFalse)
+	_, ok = totp.Match(rfcSecret, "5924", now)
+	c.Assert(ok, jc.IsFalse)
+	_, ok = totp.Match([]byte("another secret"), "005924", now)
+	c.Assert(ok, jc.IsFalse)
+}
+
+func (s *totpSuite) TestGenerateSecret(c *gc.C) {
+	secret, err := totp.GenerateSecret()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(secret, gc.HasLen, totp.SecretSize)
+
+	other, err := totp.GenerateSecret()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(other, gc.Not(jc.DeepEquals), secret)
+}
+
+func (s *totpSuite) TestProvisioningURI(c *gc.C) {
+	uri := totp.ProvisioningURI("juju", "bob", rfcSecret)
+	u, err := url.Parse(uri)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(u.Scheme, gc.Equals, "otpauth")
+	c.Assert(u.Host, gc.Equals, "totp")
+	c.Assert(u.Path, gc.Equals, "/juju:bob")
+	c.Assert(u.Query(), jc.DeepEquals, url.Values{
+		"secret":    {"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"},
+		"issuer":    {"juju"},
+		"algorithm": {"SHA1"},
+		"digits":    {"6"},
+		"period":    {"30"},
+	})
+}
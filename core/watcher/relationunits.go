@@ -3,9 +3,15 @@
 
 package watcher
 
+import "time"
+
 // UnitSettings specifies the version of some unit's settings in some relation.
 type UnitSettings struct {
 	Version int64
+
+	// Observed is when the controller observed the unit's
+	// settings change, or the unit entering the relation scope.
+	Observed time.Time
 }
 
 // RelationUnitsChange describes the membership and settings of; or changes to;
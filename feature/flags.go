@@ -62,3 +62,24 @@ const JujuV3 = "juju-v3"
 
 // K8sOperators indicates that it's allowed to deploy charms with mode=operator
 const K8sOperators = "k8s-operators"
+
+// RelationGoodbyeData causes the uniter to run a final relation-changed hook,
+// carrying the last remote application data, before relation-broken.
+const RelationGoodbyeData = "relation-goodbye-data"
+
+// RelationReconciliation causes the uniter to plan, in a single pass, the
+// hooks needed to bring a relation in line with its remote state when its
+// members rejoin or change en masse, so that repeated changes to some of
+// the members are coalesced rather than run ahead of the others.
+const RelationReconciliation = "relation-reconciliation"
+
+// TolerateUnknownHooks causes the uniter to set aside, with a warning,
+// the hooks of kinds it does not know that were recorded in its local
+// state by a newer agent, rather than failing to start. This allows a
+// unit's agent to be downgraded.
+const TolerateUnknownHooks = "tolerate-unknown-hooks"
+
+// InjectRelationState allows synthetic relation changes to be injected
+// into the uniter's remote state through the unit agent's introspection
+// socket. It is intended only for testing charms.
+const InjectRelationState = "inject-relation-state"
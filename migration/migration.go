@@ -59,15 +59,26 @@ type StateImporter interface {
 // model UUID passed.
 type ClaimerFunc func(string) (leadership.Claimer, error)
 
+// PinnerFunc is a function that returns a leadership pinner for the
+// model UUID passed.
+type PinnerFunc func(string) (leadership.Pinner, error)
+
 // ImportModel deserializes a model description from the bytes, transforms
 // the model config based on information from the controller model, and then
-// imports that as a new database model.
-func ImportModel(importer StateImporter, getClaimer ClaimerFunc, bytes []byte) (*state.Model, *state.State, error) {
+// imports that as a new database model. The leadership of the imported
+// applications is claimed for the exported leaders and, if a pinner is
+// available, pinned until the migration completes.
+func ImportModel(importer StateImporter, getClaimer ClaimerFunc, getPinner PinnerFunc, bytes []byte) (*state.Model, *state.State, error) {
 	model, err := description.Deserialize(bytes)
 	if err != nil {
 		return nil, nil, errors.Trace(err)
 	}
+	return ImportDescribedModel(importer, getClaimer, getPinner, model)
+}
 
+// ImportDescribedModel imports the model description as ImportModel does,
+// for callers that have already deserialized it.
+func ImportDescribedModel(importer StateImporter, getClaimer ClaimerFunc, getPinner PinnerFunc, model description.Model) (*state.Model, *state.State, error) {
 	dbModel, dbState, err := importer.Import(model)
 	if err != nil {
 		return nil, nil, errors.Trace(err)
@@ -81,7 +92,10 @@ func ImportModel(importer StateImporter, getClaimer ClaimerFunc, bytes []byte) (
 	// If we're using legacy-leases we get the claimer from the new
 	// state - otherwise use the function passed in.
 	//
-	var claimer leadership.Claimer
+	var (
+		claimer leadership.Claimer
+		pinner  leadership.Pinner
+	)
 	if config.Features().Contains(feature.LegacyLeases) {
 		claimer = dbState.LeadershipClaimer()
 	} else {
@@ -89,6 +103,12 @@ func ImportModel(importer StateImporter, getClaimer ClaimerFunc, bytes []byte) (
 		if err != nil {
 			return nil, nil, errors.Annotate(err, "getting leadership claimer")
 		}
+		if getPinner != nil {
+			pinner, err = getPinner(dbModel.UUID())
+			if err != nil {
+				return nil, nil, errors.Annotate(err, "getting leadership pinner")
+			}
+		}
 	}
 
 	logger.Debugf("importing leadership")
@@ -102,9 +122,8 @@ func ImportModel(importer StateImporter, getClaimer ClaimerFunc, bytes []byte) (
 		// long enough to make sure we cover the time taken to migrate
 		// a reasonable sized model. We don't yet know how long this
 		// is going to be, but we need something.
-		// TODO(babbageclunk): Handle this better - maybe a way to
-		// suppress leadership expiries for a model until it's
-		// finished importing?
+		// Where leadership can be pinned, it also is, so that it
+		// does not expire before the model is activated.
 		logger.Debugf("%q is the leader for %q", application.Leader(), application.Name())
 		err := claimer.ClaimLeadership(
 			application.Name(),
@@ -118,6 +137,16 @@ func ImportModel(importer StateImporter, getClaimer ClaimerFunc, bytes []byte) (
 				application.Leader(),
 			)
 		}
+		if pinner == nil {
+			continue
+		}
+		if err := pinner.PinLeadership(application.Name(), migration.LeadershipPinEntity); err != nil {
+			return nil, nil, errors.Annotatef(
+				err,
+				"pinning leadership for %q",
+				application.Name(),
+			)
+		}
 	}
 
 	return dbModel, dbState, nil
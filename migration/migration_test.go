@@ -66,13 +66,17 @@ func (s *ImportSuite) SetUpTest(c *gc.C) {
 func (s *ImportSuite) TestBadBytes(c *gc.C) {
 	bytes := []byte("not a model")
 	controller := state.NewController(s.StatePool)
-	model, st, err := migration.ImportModel(controller, fakeGetClaimer, bytes)
+	model, st, err := migration.ImportModel(controller, fakeGetClaimer, nil, bytes)
 	c.Check(st, gc.IsNil)
 	c.Check(model, gc.IsNil)
 	c.Assert(err, gc.ErrorMatches, "yaml: unmarshal errors:\n.*")
 }
 
 func (s *ImportSuite) exportImport(c *gc.C, getClaimer migration.ClaimerFunc) *state.State {
+	return s.exportImportPinned(c, getClaimer, nil)
+}
+
+func (s *ImportSuite) exportImportPinned(c *gc.C, getClaimer migration.ClaimerFunc, getPinner migration.PinnerFunc) *state.State {
 	model, err := s.State.Export()
 	c.Assert(err, jc.ErrorIsNil)
 
@@ -89,7 +93,7 @@ func (s *ImportSuite) exportImport(c *gc.C, getClaimer migration.ClaimerFunc) *s
 	c.Check(err, jc.ErrorIsNil)
 
 	controller := state.NewController(s.StatePool)
-	dbModel, dbState, err := migration.ImportModel(controller, getClaimer, bytes)
+	dbModel, dbState, err := migration.ImportModel(controller, getClaimer, getPinner, bytes)
 	c.Assert(err, jc.ErrorIsNil)
 	s.AddCleanup(func(*gc.C) { dbState.Close() })
 
@@ -122,6 +126,30 @@ func (s *ImportSuite) TestImportsLeadership(c *gc.C) {
 	claimer.stub.CheckCall(c, 0, "ClaimLeadership", "wordpress", "wordpress/1", time.Minute)
 }
 
+func (s *ImportSuite) TestImportPinsLeadership(c *gc.C) {
+	s.makeApplicationWithUnits(c, "wordpress", 3)
+	s.makeUnitApplicationLeader(c, "wordpress/1", "wordpress")
+	s.makeApplicationWithUnits(c, "mysql", 2)
+
+	var (
+		claimer   fakeClaimer
+		pinner    fakePinner
+		modelUUID string
+	)
+	getClaimer := func(string) (leadership.Claimer, error) {
+		return &claimer, nil
+	}
+	dbState := s.exportImportPinned(c, getClaimer, func(uuid string) (leadership.Pinner, error) {
+		modelUUID = uuid
+		return &pinner, nil
+	})
+	c.Assert(modelUUID, gc.Equals, dbState.ModelUUID())
+	claimer.stub.CheckCallNames(c, "ClaimLeadership")
+	pinner.stub.CheckCalls(c, []testing.StubCall{
+		{"PinLeadership", []interface{}{"wordpress", coremigration.LeadershipPinEntity}},
+	})
+}
+
 func (s *ImportSuite) TestImportsLeadershipLegacy(c *gc.C) {
 	err := s.State.UpdateControllerConfig(map[string]interface{}{
 		"features": []interface{}{feature.LegacyLeases},
@@ -397,3 +425,13 @@ func (c *fakeClaimer) ClaimLeadership(application, unit string, duration time.Du
 	c.stub.AddCall("ClaimLeadership", application, unit, duration)
 	return c.stub.NextErr()
 }
+
+type fakePinner struct {
+	leadership.Pinner
+	stub testing.Stub
+}
+
+func (p *fakePinner) PinLeadership(application, entity string) error {
+	p.stub.AddCall("PinLeadership", application, entity)
+	return p.stub.NextErr()
+}
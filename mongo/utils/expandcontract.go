@@ -0,0 +1,58 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package utils
+
+import "gopkg.in/mgo.v2/bson"
+
+// RenamedField describes a document field being renamed by a staged
+// upgrade. The expand phase copies the old field to the new one, while
+// agents of the previous version may still read and write the old field;
+// the contract phase removes the old field once no such agents remain.
+// In between, writers must set both fields and readers must accept
+// either.
+type RenamedField struct {
+	// Old is the name of the field being replaced.
+	Old string
+
+	// New is the name of the replacement field.
+	New string
+}
+
+// Set returns the $set contents that write the value
+// to both the old and new fields.
+func (f RenamedField) Set(value interface{}) bson.D {
+	return bson.D{{f.Old, value}, {f.New, value}}
+}
+
+// Unset returns the $unset contents that remove the old field,
+// as is done by the contract phase.
+func (f RenamedField) Unset() bson.D {
+	return bson.D{{f.Old, 1}}
+}
+
+// Get returns the value of the field in the supplied document, preferring
+// the new field and falling back to the old, and whether either was set.
+func (f RenamedField) Get(doc bson.M) (interface{}, bool) {
+	if value, ok := doc[f.New]; ok {
+		return value, true
+	}
+	value, ok := doc[f.Old]
+	return value, ok
+}
+
+// NotExpanded returns a query matching the documents
+// that the expand phase has not yet copied.
+func (f RenamedField) NotExpanded() bson.D {
+	return bson.D{
+		{f.Old, bson.D{{"$exists", true}}},
+		{f.New, bson.D{{"$exists", false}}},
+	}
+}
+
+// NotContracted returns a query matching the documents
+// from which the contract phase has not yet removed the
+// old field.
+func (f RenamedField) NotContracted() bson.D {
+	return bson.D{{f.Old, bson.D{{"$exists", true}}}}
+}
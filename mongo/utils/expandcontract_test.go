@@ -0,0 +1,63 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package utils_test
+
+import (
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/juju/juju/mongo/utils"
+)
+
+type renamedFieldSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&renamedFieldSuite{})
+
+var portsField = utils.RenamedField{Old: "ports", New: "port-ranges"}
+
+func (s *renamedFieldSuite) TestSet(c *gc.C) {
+	c.Check(portsField.Set(80), jc.DeepEquals, bson.D{{"ports", 80}, {"port-ranges", 80}})
+	c.Check(portsField.Unset(), jc.DeepEquals, bson.D{{"ports", 1}})
+}
+
+func (s *renamedFieldSuite) TestGet(c *gc.C) {
+	for i, test := range []struct {
+		doc   bson.M
+		value interface{}
+		ok    bool
+	}{{
+		doc: bson.M{},
+	}, {
+		doc:   bson.M{"ports": 80},
+		value: 80,
+		ok:    true,
+	}, {
+		doc:   bson.M{"port-ranges": 443},
+		value: 443,
+		ok:    true,
+	}, {
+		doc:   bson.M{"ports": 80, "port-ranges": 443},
+		value: 443,
+		ok:    true,
+	}} {
+		c.Logf("test %d", i)
+		value, ok := portsField.Get(test.doc)
+		c.Check(value, gc.Equals, test.value)
+		c.Check(ok, gc.Equals, test.ok)
+	}
+}
+
+func (s *renamedFieldSuite) TestQueries(c *gc.C) {
+	c.Check(portsField.NotExpanded(), jc.DeepEquals, bson.D{
+		{"ports", bson.D{{"$exists", true}}},
+		{"port-ranges", bson.D{{"$exists", false}}},
+	})
+	c.Check(portsField.NotContracted(), jc.DeepEquals, bson.D{
+		{"ports", bson.D{{"$exists", true}}},
+	})
+}
@@ -0,0 +1,266 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/core/permission"
+)
+
+// AccessRequestStatus describes the progress of a request for access.
+type AccessRequestStatus string
+
+const (
+	// AccessRequestPending is the status of a request
+	// that has been neither approved nor denied.
+	AccessRequestPending AccessRequestStatus = "pending"
+
+	// AccessRequestApproved is the status of a request that has
+	// been approved, and the access granted.
+	AccessRequestApproved AccessRequestStatus = "approved"
+
+	// AccessRequestDenied is the status of a request that has been denied.
+	AccessRequestDenied AccessRequestStatus = "denied"
+)
+
+// accessRequestDoc records a user's request for access to a model.
+// The document is kept once the request has been decided, so that
+// there is a record of who decided it and when.
+type accessRequestDoc struct {
+	DocID     string    `bson:"_id"`
+	ModelUUID string    `bson:"model-uuid"`
+	UserName  string    `bson:"user"`
+	Access    string    `bson:"access"`
+	Reason    string    `bson:"reason,omitempty"`
+	Requested time.Time `bson:"requested"`
+	Status    string    `bson:"status"`
+	DecidedBy string    `bson:"decided-by,omitempty"`
+	Decided   time.Time `bson:"decided,omitempty"`
+}
+
+// AccessRequest describes a user's request for access to a model.
+type AccessRequest struct {
+	// ID identifies the request.
+	ID string
+
+	// User is the user requesting access.
+	User names.UserTag
+
+	// Model is the model the access is requested for.
+	Model names.ModelTag
+
+	// Access is the access level requested.
+	Access permission.Access
+
+	// Reason is why the user says they need the access.
+	Reason string
+
+	// Requested is when the request was made.
+	Requested time.Time
+
+	// Status records whether the request has been decided.
+	Status AccessRequestStatus
+
+	// DecidedBy is the user that approved or denied the
+	// request, if it is no longer pending.
+	DecidedBy names.UserTag
+
+	// Decided is when the request was approved or denied,
+	// or the zero time if it is still pending.
+	Decided time.Time
+}
+
+func newAccessRequest(doc accessRequestDoc) AccessRequest {
+	req := AccessRequest{
+		ID:        doc.DocID,
+		User:      names.NewUserTag(doc.UserName),
+		Model:     names.NewModelTag(doc.ModelUUID),
+		Access:    permission.Access(doc.Access),
+		Reason:    doc.Reason,
+		Requested: doc.Requested.UTC(),
+		Status:    AccessRequestStatus(doc.Status),
+		Decided:   doc.Decided.UTC(),
+	}
+	if doc.DecidedBy != "" {
+		req.DecidedBy = names.NewUserTag(doc.DecidedBy)
+	}
+	return req
+}
+
+var pendingAccessRequestDoc = bson.D{{"status", string(AccessRequestPending)}}
+
+// RequestModelAccess records the user's request for the specified access
+// to the model, to be approved or denied by an administrator of the model.
+// A user may only have one pending request for each model.
+func (st *State) RequestModelAccess(
+	subject names.UserTag,
+	model names.ModelTag,
+	access permission.Access,
+	reason string,
+) (AccessRequest, error) {
+	if err := permission.ValidateModelAccess(access); err != nil {
+		return AccessRequest{}, errors.Trace(err)
+	}
+	if exists, err := st.ModelExists(model.Id()); err != nil {
+		return AccessRequest{}, errors.Trace(err)
+	} else if !exists {
+		return AccessRequest{}, errors.NotFoundf("model %q", model.Id())
+	}
+
+	requests, closer := st.db().GetCollection(accessRequestsC)
+	defer closer()
+
+	doc := accessRequestDoc{
+		DocID:     bson.NewObjectId().Hex(),
+		ModelUUID: model.Id(),
+		UserName:  subject.Id(),
+		Access:    accessToString(access),
+		Reason:    reason,
+		Status:    string(AccessRequestPending),
+	}
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		current, err := st.UserAccess(subject, model)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, errors.Trace(err)
+		}
+		if err == nil && current.Access.EqualOrGreaterModelAccessThan(access) {
+			return nil, errors.AlreadyExistsf("%q access to model %q for user %q", current.Access, model.Id(), subject.Id())
+		}
+
+		query := append(bson.D{
+			{"model-uuid", model.Id()},
+			{"user", subject.Id()},
+		}, pendingAccessRequestDoc...)
+		if n, err := requests.Find(query).Count(); err != nil {
+			return nil, errors.Trace(err)
+		} else if n > 0 {
+			return nil, errors.AlreadyExistsf("pending access request for user %q on model %q", subject.Id(), model.Id())
+		}
+
+		doc.Requested = st.nowToTheSecond()
+		return []txn.Op{{
+			C:      accessRequestsC,
+			Id:     doc.DocID,
+			Assert: txn.DocMissing,
+			Insert: &doc,
+		}}, nil
+	}
+	if err := st.db().Run(buildTxn); err != nil {
+		return AccessRequest{}, errors.Annotatef(err, "requesting access for %q", subject.Id())
+	}
+	logger.Infof("user %q requested %q access to model %q", subject.Id(), access, model.Id())
+	return newAccessRequest(doc), nil
+}
+
+// AccessRequest returns the access request with the given ID.
+func (st *State) AccessRequest(id string) (AccessRequest, error) {
+	requests, closer := st.db().GetCollection(accessRequestsC)
+	defer closer()
+
+	var doc accessRequestDoc
+	if err := requests.FindId(id).One(&doc); err == mgo.ErrNotFound {
+		return AccessRequest{}, errors.NotFoundf("access request %q", id)
+	} else if err != nil {
+		return AccessRequest{}, errors.Trace(err)
+	}
+	return newAccessRequest(doc), nil
+}
+
+// PendingAccessRequests returns the requests for access that have
+// been neither approved nor denied, oldest first.
+func (st *State) PendingAccessRequests() ([]AccessRequest, error) {
+	requests, closer := st.db().GetCollection(accessRequestsC)
+	defer closer()
+
+	var docs []accessRequestDoc
+	if err := requests.Find(pendingAccessRequestDoc).Sort("requested", "_id").All(&docs); err != nil {
+		return nil, errors.Trace(err)
+	}
+	result := make([]AccessRequest, len(docs))
+	for i, doc := range docs {
+		result[i] = newAccessRequest(doc)
+	}
+	return result, nil
+}
+
+// ApproveAccessRequest grants the user the access they requested to the
+// model and records the request as approved. If the user already has
+// the requested access, or more, it is left as it is.
+func (st *State) ApproveAccessRequest(id string, approvedBy names.UserTag) error {
+	return errors.Annotatef(st.decideAccessRequest(id, approvedBy, AccessRequestApproved), "approving access request %q", id)
+}
+
+// DenyAccessRequest records the request as denied.
+func (st *State) DenyAccessRequest(id string, deniedBy names.UserTag) error {
+	return errors.Annotatef(st.decideAccessRequest(id, deniedBy, AccessRequestDenied), "denying access request %q", id)
+}
+
+func (st *State) decideAccessRequest(id string, decidedBy names.UserTag, status AccessRequestStatus) error {
+	req, err := st.AccessRequest(id)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if req, err = st.AccessRequest(id); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		if req.Status != AccessRequestPending {
+			return nil, errors.Errorf("request already %s", req.Status)
+		}
+		now := st.nowToTheSecond()
+		ops := []txn.Op{{
+			C:      accessRequestsC,
+			Id:     id,
+			Assert: pendingAccessRequestDoc,
+			Update: bson.D{{"$set", bson.D{
+				{"status", string(status)},
+				{"decided-by", decidedBy.Id()},
+				{"decided", now},
+			}}},
+		}}
+		if status != AccessRequestApproved {
+			return ops, nil
+		}
+
+		current, err := st.UserAccess(req.User, req.Model)
+		switch {
+		case errors.IsNotFound(err):
+			if req.User.IsLocal() {
+				if _, err := st.User(req.User); err != nil {
+					return nil, errors.Trace(err)
+				}
+			}
+			ops = append(ops, createModelUserOps(
+				req.Model.Id(), req.User, decidedBy, "", now, req.Access,
+			)...)
+		case err != nil:
+			return nil, errors.Trace(err)
+		case !current.Access.EqualOrGreaterModelAccessThan(req.Access):
+			op := updatePermissionOp(modelKey(req.Model.Id()), userGlobalKey(userAccessID(req.User)), req.Access)
+			// Only grant the access if it hasn't been changed
+			// while the transaction was being built.
+			op.Assert = bson.D{{"access", accessToString(current.Access)}}
+			ops = append(ops, op)
+		}
+		return ops, nil
+	}
+	db, dbCloser := st.db().CopyForModel(req.Model.Id())
+	defer dbCloser()
+	if err := db.Run(buildTxn); err != nil {
+		return errors.Trace(err)
+	}
+	logger.Infof("%s %q access to model %q for user %q by %q",
+		status, req.Access, req.Model.Id(), req.User.Id(), decidedBy.Id())
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/core/permission"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing/factory"
+)
+
+type AccessRequestSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&AccessRequestSuite{})
+
+func (s *AccessRequestSuite) TestRequestModelAccess(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", NoModelUser: true})
+
+	req, err := s.State.RequestModelAccess(user.UserTag(), s.Model.ModelTag(), permission.WriteAccess, "deploying")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(req.ID, gc.Not(gc.Equals), "")
+	c.Assert(req.User, gc.Equals, user.UserTag())
+	c.Assert(req.Model, gc.Equals, s.Model.ModelTag())
+	c.Assert(req.Access, gc.Equals, permission.WriteAccess)
+	c.Assert(req.Reason, gc.Equals, "deploying")
+	c.Assert(req.Status, gc.Equals, state.AccessRequestPending)
+	c.Assert(req.Decided.IsZero(), jc.IsTrue)
+
+	pending, err := s.State.PendingAccessRequests()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(pending, jc.DeepEquals, []state.AccessRequest{req})
+
+	// Only one request may be pending for each user and model.
+	_, err = s.State.RequestModelAccess(user.UserTag(), s.Model.ModelTag(), permission.AdminAccess, "")
+	c.Assert(err, jc.Satisfies, errors.IsAlreadyExists)
+}
+
+func (s *AccessRequestSuite) TestRequestModelAccessAlreadyHeld(c *gc.C) {
+	user := s.Factory.MakeModelUser(c, &factory.ModelUserParams{Access: permission.WriteAccess})
+
+	_, err := s.State.RequestModelAccess(user.UserTag, s.Model.ModelTag(), permission.ReadAccess, "")
+	c.Assert(err, jc.Satisfies, errors.IsAlreadyExists)
+}
+
+func (s *AccessRequestSuite) TestRequestModelAccessInvalid(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", NoModelUser: true})
+
+	_, err := s.State.RequestModelAccess(user.UserTag(), s.Model.ModelTag(), permission.SuperuserAccess, "")
+	c.Assert(err, gc.ErrorMatches, `"superuser" model access not valid`)
+
+	_, err = s.State.RequestModelAccess(
+		user.UserTag(), names.NewModelTag("deadbeef-0bad-400d-8000-4b1d0d06f00d"), permission.ReadAccess, "")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *AccessRequestSuite) TestApproveAccessRequestNewModelUser(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", NoModelUser: true})
+	req, err := s.State.RequestModelAccess(user.UserTag(), s.Model.ModelTag(), permission.WriteAccess, "")
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.State.ApproveAccessRequest(req.ID, s.Owner)
+	c.Assert(err, jc.ErrorIsNil)
+
+	access, err := s.State.UserAccess(user.UserTag(), s.Model.ModelTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(access.Access, gc.Equals, permission.WriteAccess)
+
+	req, err = s.State.AccessRequest(req.ID)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(req.Status, gc.Equals, state.AccessRequestApproved)
+	c.Assert(req.DecidedBy, gc.Equals, s.Owner)
+	c.Assert(req.Decided.IsZero(), jc.IsFalse)
+
+	pending, err := s.State.PendingAccessRequests()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(pending, gc.HasLen, 0)
+
+	// A decided request cannot be decided again.
+	err = s.State.DenyAccessRequest(req.ID, s.Owner)
+	c.Assert(err, gc.ErrorMatches, `denying access request ".*": request already approved`)
+}
+
+func (s *AccessRequestSuite) TestApproveAccessRequestExistingModelUser(c *gc.C) {
+	user := s.Factory.MakeModelUser(c, &factory.ModelUserParams{Access: permission.ReadAccess})
+	req, err := s.State.RequestModelAccess(user.UserTag, s.Model.ModelTag(), permission.AdminAccess, "")
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.State.ApproveAccessRequest(req.ID, s.Owner)
+	c.Assert(err, jc.ErrorIsNil)
+
+	access, err := s.State.UserAccess(user.UserTag, s.Model.ModelTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(access.Access, gc.Equals, permission.AdminAccess)
+}
+
+func (s *AccessRequestSuite) TestDenyAccessRequest(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", NoModelUser: true})
+	req, err := s.State.RequestModelAccess(user.UserTag(), s.Model.ModelTag(), permission.WriteAccess, "")
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.State.DenyAccessRequest(req.ID, s.Owner)
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = s.State.UserAccess(user.UserTag(), s.Model.ModelTag())
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	req, err = s.State.AccessRequest(req.ID)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(req.Status, gc.Equals, state.AccessRequestDenied)
+	c.Assert(req.DecidedBy, gc.Equals, s.Owner)
+
+	// A new request can be made once the previous one is decided.
+	_, err = s.State.RequestModelAccess(user.UserTag(), s.Model.ModelTag(), permission.ReadAccess, "")
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *AccessRequestSuite) TestAccessRequestNotFound(c *gc.C) {
+	_, err := s.State.AccessRequest("nope")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	err = s.State.ApproveAccessRequest("nope", s.Owner)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
@@ -97,6 +97,30 @@ func allCollections() CollectionSchema {
 		// upgrades and schema migrations.
 		upgradeInfoC: {global: true},
 
+		// This collection records the schema version of the documents
+		// in each collection changed by an upgrade step, so that
+		// documents missed by past migrations can be detected.
+		schemaVersionsC: {
+			global:    true,
+			rawAccess: true,
+		},
+
+		// This collection records the upgrade steps that were skipped
+		// because the controller feature flag gating them was not set,
+		// so that they can be run once the flag is enabled.
+		gatedUpgradeStepsC: {
+			global:    true,
+			rawAccess: true,
+		},
+
+		// This collection records the contract phases of expand/contract
+		// upgrade steps, which are deferred until every agent runs a
+		// version that no longer relies on the data they remove.
+		contractUpgradeStepsC: {
+			global:    true,
+			rawAccess: true,
+		},
+
 		// This collection holds a convenient representation of the content of
 		// the simplestreams data source pointing to binaries required by juju.
 		//
@@ -140,6 +164,10 @@ func allCollections() CollectionSchema {
 		// migration minions.
 		migrationsMinionSyncC: {global: true},
 
+		// This collection tracks the progress of the import of a
+		// model being migrated to this controller.
+		migrationsImportProgressC: {},
+
 		// This collection holds user information that's not specific to any
 		// one model.
 		usersC: {
@@ -210,6 +238,59 @@ func allCollections() CollectionSchema {
 			}},
 		},
 
+		// This collection records time limited grants of model access,
+		// so that the access can be revoked once the grant expires.
+		temporaryAccessC: {
+			global: true,
+			indexes: []mgo.Index{{
+				Key: []string{"expires"},
+			}},
+		},
+
+		// This collection records users' requests for access to models,
+		// which are approved or denied by the models' administrators.
+		accessRequestsC: {
+			global: true,
+			indexes: []mgo.Index{{
+				Key: []string{"status", "requested"},
+			}, {
+				Key: []string{"model-uuid", "user", "status"},
+			}},
+		},
+
+		// This collection records the delivery of notifications
+		// of events on user accounts.
+		userNotificationsC: {
+			global:    true,
+			rawAccess: true,
+			indexes: []mgo.Index{{
+				Key: []string{"user", "time"},
+			}},
+		},
+
+		// This collection records each user's logins to the API
+		// server, for reporting their recent activity. Records
+		// expire once they are older than loginActivityRetention.
+		userLoginActivityC: {
+			global:    true,
+			rawAccess: true,
+			indexes: []mgo.Index{{
+				Key: []string{"user", "time"},
+			}, {
+				Key:         []string{"time"},
+				ExpireAfter: loginActivityRetention,
+			}},
+		},
+
+		// This collection queues changes to users' access for
+		// delivery to the permission webhook.
+		permissionEventsC: {
+			global: true,
+			indexes: []mgo.Index{{
+				Key: []string{"dead", "next-attempt"},
+			}},
+		},
+
 		// This collection holds information cached by autocert certificate
 		// acquisition.
 		autocertCacheC: {
@@ -288,8 +369,27 @@ func allCollections() CollectionSchema {
 				Key: []string{"model-uuid", "machineid"},
 			}},
 		},
-		unitStatesC: {},
-		minUnitsC:   {},
+		unitStatesC: {
+			indexes: []mgo.Index{{
+				Key: []string{"model-uuid", "application"},
+			}},
+		},
+
+		// This collection holds the chunks of relation state that
+		// overflow unit state documents, keyed by the unit's
+		// global key and the chunk index.
+		unitStateChunksC: {},
+
+		// This collection holds values moved out of unit state
+		// documents that could not be repaired, so that they can
+		// be recovered by hand.
+		unitStatesQuarantineC: {},
+
+		// This collection holds the counters maintained by the charm
+		// deployed to each application, shared by all of its units.
+		charmCountersC: {},
+
+		minUnitsC: {},
 
 		// This collection holds documents that indicate units which are queued
 		// to be assigned to machines. It is used exclusively by the
@@ -553,6 +653,7 @@ func allCollections() CollectionSchema {
 // it in allCollections, above; and please keep this list sorted for easy
 // inspection.
 const (
+	accessRequestsC            = "accessRequests"
 	actionNotificationsC       = "actionnotifications"
 	actionresultsC             = "actionresults"
 	actionsC                   = "actions"
@@ -563,6 +664,7 @@ const (
 	blockDevicesC              = "blockdevices"
 	blocksC                    = "blocks"
 	charmsC                    = "charms"
+	charmCountersC             = "charmcounters"
 	cleanupsC                  = "cleanups"
 	cloudimagemetadataC        = "cloudimagemetadata"
 	cloudsC                    = "clouds"
@@ -594,6 +696,7 @@ const (
 	minUnitsC                  = "minunits"
 	migrationsActiveC          = "migrations.active"
 	migrationsC                = "migrations"
+	migrationsImportProgressC  = "migrations.importprogress"
 	migrationsMinionSyncC      = "migrations.minionsync"
 	migrationsStatusC          = "migrations.status"
 	modelUserLastConnectionC   = "modelUserLastConnection"
@@ -603,6 +706,7 @@ const (
 	openedPortsC               = "openedPorts"
 	operationsC                = "operations"
 	payloadsC                  = "payloads"
+	permissionEventsC          = "permissionEvents"
 	permissionsC               = "permissions"
 	podSpecsC                  = "podSpecs"
 	providerIDsC               = "providerIDs"
@@ -610,11 +714,14 @@ const (
 	relationScopesC            = "relationscopes"
 	relationsC                 = "relations"
 	restoreInfoC               = "restoreInfo"
+	schemaVersionsC            = "schemaVersions"
 	sequenceC                  = "sequence"
 	applicationsC              = "applications"
 	endpointBindingsC          = "endpointbindings"
 	settingsC                  = "settings"
 	generationsC               = "generations"
+	gatedUpgradeStepsC         = "gatedUpgradeSteps"
+	contractUpgradeStepsC      = "contractUpgradeSteps"
 	refcountsC                 = "refcounts"
 	sshHostKeysC               = "sshhostkeys"
 	spacesC                    = "spaces"
@@ -628,13 +735,18 @@ const (
 	linkLayerDevicesC          = "linklayerdevices"
 	linkLayerDevicesRefsC      = "linklayerdevicesrefs"
 	ipAddressesC               = "ip.addresses"
+	temporaryAccessC           = "temporaryAccess"
 	toolsmetadataC             = "toolsmetadata"
 	txnLogC                    = "txns.log"
 	txnsC                      = "txns"
 	unitsC                     = "units"
 	unitStatesC                = "unitstates"
+	unitStateChunksC           = "unitstatechunks"
+	unitStatesQuarantineC      = "unitstatesquarantine"
 	upgradeInfoC               = "upgradeInfo"
 	userLastLoginC             = "userLastLogin"
+	userLoginActivityC         = "userLoginActivity"
+	userNotificationsC         = "userNotifications"
 	usermodelnameC             = "usermodelname"
 	usersC                     = "users"
 	volumeAttachmentsC         = "volumeattachments"
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/juju/errors"
+	jujutxn "github.com/juju/txn"
 	"gopkg.in/juju/names.v3"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
@@ -27,18 +28,28 @@ type annotatorDoc struct {
 }
 
 // SetAnnotations adds key/value pairs to annotations in MongoDB.
-func (m *Model) SetAnnotations(entity GlobalEntity, annotations map[string]string) (err error) {
-	defer errors.DeferredAnnotatef(&err, "cannot update annotations on %s", entity.Tag())
+func (m *Model) SetAnnotations(entity GlobalEntity, annotations map[string]string) error {
 	if len(annotations) == 0 {
 		return nil
 	}
+	op, err := m.SetAnnotationsOperation(entity, annotations)
+	if err != nil {
+		return errors.Annotatef(err, "cannot update annotations on %s", entity.Tag())
+	}
+	return m.st.ApplyOperation(op)
+}
+
+// SetAnnotationsOperation returns a ModelOperation for adding key/value
+// pairs to the annotations of the input entity. Values that are empty
+// cause the corresponding key to be removed.
+func (m *Model) SetAnnotationsOperation(entity GlobalEntity, annotations map[string]string) (ModelOperation, error) {
 	// Collect in separate maps pairs to be inserted/updated or removed.
 	toRemove := make(bson.M)
 	toInsert := make(map[string]string)
 	toUpdate := make(bson.M)
 	for key, value := range annotations {
 		if strings.Contains(key, ".") {
-			return fmt.Errorf("invalid key %q", key)
+			return nil, fmt.Errorf("invalid key %q", key)
 		}
 		if value == "" {
 			toRemove[key] = true
@@ -47,27 +58,54 @@ func (m *Model) SetAnnotations(entity GlobalEntity, annotations map[string]strin
 			toUpdate[key] = value
 		}
 	}
-	// Set up and call the necessary transactions - if the document does not
-	// already exist, one of the clients will create it and the others will
-	// fail, then all the rest of the clients should succeed on their second
-	// attempt. If the referred-to entity has disappeared, and removed its
-	// annotations in the meantime, we consider that worthy of an error
-	// (will be fixed when new entities can never share names with old ones).
-	buildTxn := func(attempt int) ([]txn.Op, error) {
-		annotations, closer := m.st.db().GetCollection(annotationsC)
-		defer closer()
-		if count, err := annotations.FindId(entity.globalKey()).Count(); err != nil {
-			return nil, err
-		} else if count == 0 {
-			// Check that the annotator entity was not previously destroyed.
-			if attempt != 0 {
-				return nil, fmt.Errorf("%s no longer exists", entity.Tag())
-			}
-			return insertAnnotationsOps(m.st, entity, toInsert)
+	return &setAnnotationsOperation{
+		st:       m.st,
+		entity:   entity,
+		toInsert: toInsert,
+		toUpdate: toUpdate,
+		toRemove: toRemove,
+	}, nil
+}
+
+// setAnnotationsOperation is a ModelOperation for updating
+// the annotations of an entity.
+type setAnnotationsOperation struct {
+	st       *State
+	entity   GlobalEntity
+	toInsert map[string]string
+	toUpdate bson.M
+	toRemove bson.M
+}
+
+// Build is part of the ModelOperation interface.
+//
+// If the annotations document does not already exist, one of the clients
+// will create it and the others will fail, then all the rest of the clients
+// should succeed on their second attempt. If the referred-to entity has
+// disappeared, and removed its annotations in the meantime, we consider that
+// worthy of an error (will be fixed when new entities can never share names
+// with old ones).
+func (op *setAnnotationsOperation) Build(attempt int) ([]txn.Op, error) {
+	if len(op.toInsert)+len(op.toRemove) == 0 {
+		return nil, jujutxn.ErrNoOperations
+	}
+	annotations, closer := op.st.db().GetCollection(annotationsC)
+	defer closer()
+	if count, err := annotations.FindId(op.entity.globalKey()).Count(); err != nil {
+		return nil, err
+	} else if count == 0 {
+		// Check that the annotator entity was not previously destroyed.
+		if attempt != 0 {
+			return nil, fmt.Errorf("%s no longer exists", op.entity.Tag())
 		}
-		return updateAnnotations(m.st, entity, toUpdate, toRemove), nil
+		return insertAnnotationsOps(op.st, op.entity, op.toInsert)
 	}
-	return m.st.db().Run(buildTxn)
+	return updateAnnotations(op.st, op.entity, op.toUpdate, op.toRemove), nil
+}
+
+// Done is part of the ModelOperation interface.
+func (op *setAnnotationsOperation) Done(err error) error {
+	return errors.Annotatef(err, "cannot update annotations on %s", op.entity.Tag())
 }
 
 // Annotations returns all the annotations corresponding to an entity.
@@ -573,6 +573,7 @@ func (a *Application) removeOps(asserts bson.D, op *ForcedOperation) ([]txn.Op,
 		removeConstraintsOp(globalKey),
 		annotationRemoveOp(a.st, globalKey),
 		removeLeadershipSettingsOp(name),
+		removeCharmCountersOp(a.st, globalKey),
 		removeStatusOp(a.st, globalKey),
 		removeStatusOp(a.st, applicationGlobalOperatorKey(name)),
 		removeSettingsOp(settingsC, a.applicationConfigKey()),
@@ -2112,6 +2113,10 @@ func (a *Application) removeUnitOps(u *Unit, asserts bson.D, op *ForcedOperation
 	if op.FatalError(err) {
 		return nil, errors.Trace(err)
 	}
+	stateChunkOps, err := removeUnitStateChunksOps(a.st, u.globalKey())
+	if op.FatalError(err) {
+		return nil, errors.Trace(err)
+	}
 
 	observedFieldsMatch := bson.D{
 		{"charmurl", u.doc.CharmURL},
@@ -2135,6 +2140,7 @@ func (a *Application) removeUnitOps(u *Unit, asserts bson.D, op *ForcedOperation
 	}
 	ops = append(ops, portsOps...)
 	ops = append(ops, resOps...)
+	ops = append(ops, stateChunkOps...)
 	ops = append(ops, hostOps...)
 
 	m, err := a.st.Model()
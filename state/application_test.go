@@ -4402,3 +4402,79 @@ func (s *ApplicationSuite) TestDeployedMachinesNotAssignedUnit(c *gc.C) {
 	c.Assert(err, jc.ErrorIsNil)
 	c.Assert(machines, gc.HasLen, 0)
 }
+
+func (s *ApplicationSuite) TestAdjustCharmCounter(c *gc.C) {
+	value, err := s.mysql.CharmCounter("next-id")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, int64(0))
+
+	value, err = s.mysql.AdjustCharmCounter("next-id", 1, state.CounterLimits{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, int64(1))
+	value, err = s.mysql.AdjustCharmCounter("next-id", 2, state.CounterLimits{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, int64(3))
+	value, err = s.mysql.AdjustCharmCounter("spare.slots", -2, state.CounterLimits{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, int64(-2))
+
+	value, err = s.mysql.CharmCounter("next-id")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, int64(3))
+	value, err = s.mysql.CharmCounter("spare.slots")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, int64(-2))
+}
+
+func (s *ApplicationSuite) TestAdjustCharmCounterLimits(c *gc.C) {
+	floor, ceiling := int64(0), int64(2)
+	limits := state.CounterLimits{Floor: &floor, Ceiling: &ceiling}
+
+	_, err := s.mysql.AdjustCharmCounter("slots", -1, limits)
+	c.Assert(errors.Cause(err), gc.Equals, state.ErrCounterLimit)
+	for i := 1; i <= 2; i++ {
+		value, err := s.mysql.AdjustCharmCounter("slots", 1, limits)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(value, gc.Equals, int64(i))
+	}
+	_, err = s.mysql.AdjustCharmCounter("slots", 1, limits)
+	c.Assert(errors.Cause(err), gc.Equals, state.ErrCounterLimit)
+
+	value, err := s.mysql.CharmCounter("slots")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, int64(2))
+}
+
+func (s *ApplicationSuite) TestAdjustCharmCounterConcurrent(c *gc.C) {
+	_, err := s.mysql.AdjustCharmCounter("next-id", 1, state.CounterLimits{})
+	c.Assert(err, jc.ErrorIsNil)
+
+	defer state.SetBeforeHooks(c, s.State, func() {
+		app, err := s.State.Application("mysql")
+		c.Assert(err, jc.ErrorIsNil)
+		_, err = app.AdjustCharmCounter("next-id", 1, state.CounterLimits{})
+		c.Assert(err, jc.ErrorIsNil)
+	}).Check()
+
+	// Neither adjustment is lost.
+	value, err := s.mysql.AdjustCharmCounter("next-id", 1, state.CounterLimits{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, int64(3))
+}
+
+func (s *ApplicationSuite) TestAdjustCharmCounterNotAlive(c *gc.C) {
+	_, err := s.mysql.AdjustCharmCounter("next-id", 1, state.CounterLimits{})
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.mysql.AddUnit(state.AddUnitParams{})
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.mysql.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = s.mysql.AdjustCharmCounter("next-id", 1, state.CounterLimits{})
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	// The counter can still be read.
+	value, err := s.mysql.AdjustCharmCounter("next-id", 0, state.CounterLimits{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, int64(1))
+}
@@ -0,0 +1,152 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"github.com/juju/errors"
+	jujutxn "github.com/juju/txn"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	mgoutils "github.com/juju/juju/mongo/utils"
+)
+
+// ErrCounterLimit is returned by AdjustCharmCounter when the adjustment
+// would take the counter beyond its floor or ceiling.
+var ErrCounterLimit = errors.New("charm counter limit reached")
+
+// charmCountersDoc records the counters maintained by the charm deployed
+// to an application, which are shared by all of the application's units.
+type charmCountersDoc struct {
+	// DocID is always the same as the application's global key.
+	DocID    string `bson:"_id"`
+	TxnRevno int64  `bson:"txn-revno"`
+
+	// Counters holds the value of each counter, keyed by
+	// escaped counter key.
+	Counters map[string]int64 `bson:"counters,omitempty"`
+}
+
+// removeCharmCountersOp returns the operation needed to remove the charm
+// counters document associated with the given application globalKey.
+func removeCharmCountersOp(mb modelBackend, globalKey string) txn.Op {
+	return txn.Op{
+		C:      charmCountersC,
+		Id:     mb.docID(globalKey),
+		Remove: true,
+	}
+}
+
+// CounterLimits bounds the value to which a charm counter may be
+// adjusted. Limits that are nil are not applied.
+type CounterLimits struct {
+	Floor   *int64
+	Ceiling *int64
+}
+
+// exceeded returns true if the value is beyond the limits.
+func (l CounterLimits) exceeded(value int64) bool {
+	return l.Floor != nil && value < *l.Floor || l.Ceiling != nil && value > *l.Ceiling
+}
+
+// CharmCounter returns the value of the application's charm counter
+// with the input key, which is zero if the counter was never adjusted.
+func (a *Application) CharmCounter(key string) (int64, error) {
+	coll, closer := a.st.db().GetCollection(charmCountersC)
+	defer closer()
+
+	var doc charmCountersDoc
+	field := "counters." + mgoutils.EscapeKey(key)
+	err := coll.FindId(a.globalKey()).Select(bson.D{{field, 1}}).One(&doc)
+	if err != nil && err != mgo.ErrNotFound {
+		return 0, errors.Annotatef(err, "cannot read counter %q for application %q", key, a.doc.Name)
+	}
+	return doc.Counters[mgoutils.EscapeKey(key)], nil
+}
+
+// AdjustCharmCounter atomically adds delta, which may be negative, to the
+// application's charm counter with the input key, and returns the new
+// value. It allows the units of the application to allocate sequence
+// numbers, or otherwise coordinate, without a read-modify-write of the
+// whole of their charm state. If the new value would be beyond the
+// limits, the counter is unchanged and ErrCounterLimit is returned.
+//
+// The adjustment is asserted against the value of the counter read, so
+// adjustments made concurrently by other units are never lost. Only the
+// counters of Alive applications can be adjusted; an adjustment of zero
+// returns the value of the counter, so that it can be read while the
+// application is being torn down.
+func (a *Application) AdjustCharmCounter(key string, delta int64, limits CounterLimits) (int64, error) {
+	if key == "" {
+		return 0, errors.NotValidf("empty counter key")
+	}
+	coll, closer := a.st.db().GetCollection(charmCountersC)
+	defer closer()
+
+	globalKey := a.globalKey()
+	escapedKey := mgoutils.EscapeKey(key)
+	field := "counters." + escapedKey
+	var value int64
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := a.Refresh(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		var doc charmCountersDoc
+		err := coll.FindId(globalKey).One(&doc)
+		if err != nil && err != mgo.ErrNotFound {
+			return nil, errors.Trace(err)
+		}
+		exists := err == nil
+		current, set := doc.Counters[escapedKey]
+		value = current + delta
+		if (delta > 0) != (value > current) || limits.exceeded(value) {
+			// The first condition catches overflow.
+			return nil, ErrCounterLimit
+		}
+		if delta == 0 {
+			return nil, jujutxn.ErrNoOperations
+		}
+		if a.Life() != Alive {
+			return nil, errors.NotFoundf("application %s", a.doc.Name)
+		}
+
+		ops := []txn.Op{{
+			C:      applicationsC,
+			Id:     a.doc.DocID,
+			Assert: isAliveDoc,
+		}}
+		if !exists {
+			return append(ops, txn.Op{
+				C:      charmCountersC,
+				Id:     globalKey,
+				Assert: txn.DocMissing,
+				Insert: charmCountersDoc{
+					DocID:    globalKey,
+					Counters: map[string]int64{escapedKey: value},
+				},
+			}), nil
+		}
+
+		// Assert on the counter itself rather than the txn-revno, so
+		// that adjustments to the application's other counters do not
+		// cause the adjustment to be retried.
+		assert := bson.D{{field, current}}
+		if !set {
+			assert = bson.D{{field, bson.D{{"$exists", false}}}}
+		}
+		return append(ops, txn.Op{
+			C:      charmCountersC,
+			Id:     globalKey,
+			Assert: assert,
+			Update: bson.D{{"$set", bson.D{{field, value}}}},
+		}), nil
+	}
+	if err := a.st.db().Run(buildTxn); err != nil {
+		return 0, errors.Annotatef(err, "cannot adjust counter %q for application %q", key, a.doc.Name)
+	}
+	return value, nil
+}
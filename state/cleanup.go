@@ -240,6 +240,12 @@ func (st *State) cleanupRelationSettings(prefix string) error {
 	if err := Apply(st.database, change); err != nil {
 		return errors.Trace(err)
 	}
+	// Units whose uniter stopped before it finished departing the
+	// relation may still hold state for it, or for other relations
+	// removed in the same way.
+	if err := st.pruneRelationState(); err != nil {
+		return errors.Annotate(err, "pruning relation state")
+	}
 	return nil
 }
 
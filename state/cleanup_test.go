@@ -313,6 +313,43 @@ func (s *CleanupSuite) TestCleanupRelationSettings(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, `cannot read settings for unit "riak/0" in relation "riak:ring": unit "riak/0": settings not found`)
 }
 
+func (s *CleanupSuite) TestCleanupRelationState(c *gc.C) {
+	s.PatchValue(state.RelationStateChunkSize, 10)
+
+	// Create a relation with a unit in scope.
+	pr := newPeerRelation(c, s.State)
+	preventPeerUnitsDestroyRemove(c, pr)
+	rel := pr.ru0.Relation()
+	err := pr.ru0.EnterScope(nil)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Another unit holds state for the relation, as though its uniter
+	// stopped before departing it, and for relations yet to be added.
+	// Each entry overflows into a chunk of its own.
+	us := state.NewUnitState()
+	us.SetRelationState(map[int]string{rel.Id(): "abcd", 1000: "efgh", 1001: "ijkl"})
+	err = pr.u1.SetState(us)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.countRelationStateChunks(c), gc.Equals, 2)
+
+	// The relation is removed once the unit leaves scope.
+	err = pr.app.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertCleanupCount(c, 2)
+	err = pr.ru0.LeaveScope()
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertNeedsCleanup(c)
+
+	// The departed relation's state is pruned on
+	// cleanup, and the remaining state compacted.
+	s.assertCleanupCount(c, 1)
+	uState, err := pr.u1.State()
+	c.Assert(err, jc.ErrorIsNil)
+	rState, _ := uState.RelationState()
+	c.Check(rState, jc.DeepEquals, map[int]string{1000: "efgh", 1001: "ijkl"})
+	c.Check(s.countRelationStateChunks(c), gc.Equals, 1)
+}
+
 func (s *CleanupSuite) TestCleanupModelBranches(c *gc.C) {
 	s.assertDoesNotNeedCleanup(c)
 
@@ -1315,6 +1352,12 @@ func (s *CleanupSuite) TestForceDestroyApplicationRemovesUnitsThatAreAlreadyDyin
 	assertRemoved(c, mysql)
 }
 
+func (s *CleanupSuite) countRelationStateChunks(c *gc.C) int {
+	n, err := s.Session.DB("juju").C(state.UnitStateChunksC).Count()
+	c.Assert(err, jc.ErrorIsNil)
+	return n
+}
+
 func (s *CleanupSuite) assertCleanupRuns(c *gc.C) {
 	err := s.State.Cleanup()
 	c.Assert(err, jc.ErrorIsNil)
@@ -514,6 +514,76 @@ func (st *State) RemoveModelsCredential(tag names.CloudCredentialTag) error {
 	return st.db().Run(buildTxn)
 }
 
+// TransferCloudCredential transfers the ownership of the cloud credential
+// with the given tag to newOwner, without changing its content. Models
+// that use the credential are updated to use the transferred credential.
+// The tag of the transferred credential is returned, together with the
+// models that were updated, keyed by model UUID.
+func (st *State) TransferCloudCredential(tag names.CloudCredentialTag, newOwner names.UserTag) (names.CloudCredentialTag, map[string]string, error) {
+	if newOwner.IsLocal() {
+		user, err := st.User(newOwner)
+		if err != nil {
+			return names.CloudCredentialTag{}, nil, errors.Trace(err)
+		}
+		if user.IsDisabled() {
+			return names.CloudCredentialTag{}, nil, errors.NotValidf("transferring credential to disabled user %q", newOwner.Id())
+		}
+	}
+	id := fmt.Sprintf("%s/%s/%s", tag.Cloud().Id(), newOwner.Id(), tag.Name())
+	if !names.IsValidCloudCredential(id) {
+		return names.CloudCredentialTag{}, nil, errors.NotValidf("cloud credential ID %q", id)
+	}
+	newTag := names.NewCloudCredentialTag(id)
+	if newTag == tag {
+		return names.CloudCredentialTag{}, nil, errors.NotValidf("transferring credential %q to its owner", tag.Id())
+	}
+
+	var models map[string]string
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		logger.Tracef("creating operations to transfer cloud credential, attempt %d", attempt)
+		credential, err := st.CloudCredential(tag)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if _, err := st.CloudCredential(newTag); err == nil {
+			return nil, errors.AlreadyExistsf("cloud credential %q", newTag.Id())
+		} else if !errors.IsNotFound(err) {
+			return nil, errors.Trace(err)
+		}
+		docs, err := st.modelsWithCredential(tag)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, errors.Trace(err)
+		}
+
+		doc := credential.cloudCredentialDoc
+		doc.DocID = ""
+		doc.Owner = newOwner.Id()
+		ops := []txn.Op{{
+			C:      cloudCredentialsC,
+			Id:     cloudCredentialDocID(newTag),
+			Assert: txn.DocMissing,
+			Insert: &doc,
+		}}
+		models = make(map[string]string, len(docs))
+		for _, model := range docs {
+			models[model.UUID] = model.Name
+			ops = append(ops, txn.Op{
+				C:  modelsC,
+				Id: model.UUID,
+				Assert: append(bson.D{
+					{"cloud-credential", tag.Id()},
+				}, notDeadDoc...),
+				Update: bson.D{{"$set", bson.D{{"cloud-credential", newTag.Id()}}}},
+			})
+		}
+		return append(ops, removeCloudCredentialOps(tag)...), nil
+	}
+	if err := st.db().Run(buildTxn); err != nil {
+		return names.CloudCredentialTag{}, nil, errors.Annotatef(err, "transferring cloud credential %q", tag.Id())
+	}
+	return newTag, models, nil
+}
+
 // CredentialOwnerModelAccess stores cloud credential model information for the credential owner
 // or an error retrieving it.
 type CredentialOwnerModelAccess struct {
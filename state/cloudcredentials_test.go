@@ -283,6 +283,79 @@ func (s *CloudCredentialsSuite) TestRemoveModelsCredentialNotUsed(c *gc.C) {
 	c.Assert(err, jc.ErrorIsNil)
 }
 
+func (s *CloudCredentialsSuite) TestTransferCloudCredential(c *gc.C) {
+	cloudName, credentialOwner, credentialTag := assertCredentialCreated(c, s.ConnSuite)
+	modelUUID := assertModelCreated(c, s.ConnSuite, cloudName, credentialTag, credentialOwner.Tag(), "model-for-cloud")
+	newOwner := s.Factory.MakeUser(c, &factory.UserParams{Name: "mary"}).UserTag()
+
+	newTag, models, err := s.State.TransferCloudCredential(credentialTag, newOwner)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(newTag, gc.Equals, names.NewCloudCredentialTag("stratus/mary/foobar"))
+	c.Assert(models, jc.DeepEquals, map[string]string{modelUUID: "model-for-cloud"})
+
+	_, err = s.State.CloudCredential(credentialTag)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	transferred, err := s.State.CloudCredential(newTag)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(transferred.Owner, gc.Equals, "mary")
+	c.Assert(transferred.AuthType, gc.Equals, string(cloud.AccessKeyAuthType))
+	c.Assert(transferred.Attributes, jc.DeepEquals, map[string]string{
+		"foo": "foo val",
+		"bar": "bar val",
+	})
+
+	aModel, helper, err := s.StatePool.GetModel(modelUUID)
+	c.Assert(err, jc.ErrorIsNil)
+	defer helper.Release()
+	modelCredentialTag, isSet := aModel.CloudCredentialTag()
+	c.Assert(isSet, jc.IsTrue)
+	c.Assert(modelCredentialTag, gc.Equals, newTag)
+}
+
+func (s *CloudCredentialsSuite) TestTransferCloudCredentialNotUsed(c *gc.C) {
+	_, _, credentialTag := assertCredentialCreated(c, s.ConnSuite)
+	newOwner := s.Factory.MakeUser(c, &factory.UserParams{Name: "mary"}).UserTag()
+
+	newTag, models, err := s.State.TransferCloudCredential(credentialTag, newOwner)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(models, gc.HasLen, 0)
+	_, err = s.State.CloudCredential(newTag)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *CloudCredentialsSuite) TestTransferCloudCredentialNotFound(c *gc.C) {
+	newOwner := s.Factory.MakeUser(c, &factory.UserParams{Name: "mary"}).UserTag()
+	_, _, err := s.State.TransferCloudCredential(names.NewCloudCredentialTag("stratus/bob/foobar"), newOwner)
+	c.Assert(err, gc.ErrorMatches, `transferring cloud credential "stratus/bob/foobar": cloud credential "stratus/bob/foobar" not found`)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *CloudCredentialsSuite) TestTransferCloudCredentialAlreadyExists(c *gc.C) {
+	cloudName, _, credentialTag := assertCredentialCreated(c, s.ConnSuite)
+	newOwner := s.Factory.MakeUser(c, &factory.UserParams{Name: "mary"}).UserTag()
+	createCredential(c, s.ConnSuite, cloudName, "mary", "foobar")
+
+	_, _, err := s.State.TransferCloudCredential(credentialTag, newOwner)
+	c.Assert(err, gc.ErrorMatches, `transferring cloud credential "stratus/bob/foobar": cloud credential "stratus/mary/foobar" already exists`)
+	c.Assert(err, jc.Satisfies, errors.IsAlreadyExists)
+	_, err = s.State.CloudCredential(credentialTag)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *CloudCredentialsSuite) TestTransferCloudCredentialToDisabledUser(c *gc.C) {
+	_, _, credentialTag := assertCredentialCreated(c, s.ConnSuite)
+	newOwner := s.Factory.MakeUser(c, &factory.UserParams{Name: "mary", Disabled: true}).UserTag()
+
+	_, _, err := s.State.TransferCloudCredential(credentialTag, newOwner)
+	c.Assert(err, gc.ErrorMatches, `transferring credential to disabled user "mary" not valid`)
+}
+
+func (s *CloudCredentialsSuite) TestTransferCloudCredentialToOwner(c *gc.C) {
+	_, credentialOwner, credentialTag := assertCredentialCreated(c, s.ConnSuite)
+	_, _, err := s.State.TransferCloudCredential(credentialTag, credentialOwner.UserTag())
+	c.Assert(err, gc.ErrorMatches, `transferring credential "stratus/bob/foobar" to its owner not valid`)
+}
+
 func (s *CloudCredentialsSuite) assertCredentialInvalidated(c *gc.C, tag names.CloudCredentialTag) {
 	err := s.State.AddCloud(cloud.Cloud{
 		Name:      "stratus",
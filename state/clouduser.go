@@ -19,6 +19,16 @@ import (
 
 // CreateCloudAccess creates a new access permission for a user on a cloud.
 func (st *State) CreateCloudAccess(cloud string, user names.UserTag, access permission.Access) error {
+	return errors.Trace(st.createCloudAccess(cloud, user, access, nil))
+}
+
+// CreateCloudAccessWithEvent creates a new access permission for a user on
+// a cloud, queueing the permission event in the same transaction.
+func (st *State) CreateCloudAccessWithEvent(cloud string, user names.UserTag, access permission.Access, ev PermissionEvent) error {
+	return errors.Trace(st.createCloudAccess(cloud, user, access, &ev))
+}
+
+func (st *State) createCloudAccess(cloud string, user names.UserTag, access permission.Access, ev *PermissionEvent) error {
 	if err := permission.ValidateCloudAccess(access); err != nil {
 		return errors.Trace(err)
 	}
@@ -35,8 +45,12 @@ func (st *State) CreateCloudAccess(cloud string, user names.UserTag, access perm
 	}
 
 	op := createPermissionOp(cloudGlobalKey(cloud), userGlobalKey(userAccessID(user)), access)
+	eventOps, err := st.optionalPermissionEventOps(ev)
+	if err != nil {
+		return errors.Trace(err)
+	}
 
-	err := st.db().RunTransaction([]txn.Op{op})
+	err = st.db().RunTransaction(append([]txn.Op{op}, eventOps...))
 	if err == txn.ErrAborted {
 		err = errors.AlreadyExistsf("permission for user %q for cloud %q", user.Id(), cloud)
 	}
@@ -86,13 +100,27 @@ func (st *State) UpdateCloudAccess(cloud string, user names.UserTag, access perm
 
 // RemoveCloudAccess removes the access permission for a user on a cloud.
 func (st *State) RemoveCloudAccess(cloud string, user names.UserTag) error {
+	return errors.Trace(st.removeCloudAccess(cloud, user, nil))
+}
+
+// RemoveCloudAccessWithEvent removes the access permission for a user on
+// a cloud, queueing the permission event in the same transaction.
+func (st *State) RemoveCloudAccessWithEvent(cloud string, user names.UserTag, ev PermissionEvent) error {
+	return errors.Trace(st.removeCloudAccess(cloud, user, &ev))
+}
+
+func (st *State) removeCloudAccess(cloud string, user names.UserTag, ev *PermissionEvent) error {
 	buildTxn := func(int) ([]txn.Op, error) {
 		_, err := st.GetCloudAccess(cloud, user)
 		if err != nil {
 			return nil, err
 		}
 		ops := []txn.Op{removePermissionOp(cloudGlobalKey(cloud), userGlobalKey(userAccessID(user)))}
-		return ops, nil
+		eventOps, err := st.optionalPermissionEventOps(ev)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return append(ops, eventOps...), nil
 	}
 
 	err := st.db().Run(buildTxn)
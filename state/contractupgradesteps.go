@@ -0,0 +1,123 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/version"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/juju/juju/tools"
+)
+
+// contractUpgradeStepDoc records the contract phase of an expand/contract
+// upgrade step, which is deferred until every agent runs a version that
+// no longer relies on the data that the step removes.
+type contractUpgradeStepDoc struct {
+	// DocID is the description of the step.
+	DocID           string         `bson:"_id"`
+	MinAgentVersion version.Number `bson:"min-agent-version"`
+	Deferred        time.Time      `bson:"deferred"`
+}
+
+// ContractUpgradeStep describes the deferred contract
+// phase of an expand/contract upgrade step.
+type ContractUpgradeStep struct {
+	// Description is the description of the step.
+	Description string
+
+	// MinAgentVersion is the version that every agent must
+	// be running at least before the step can be run.
+	MinAgentVersion version.Number
+
+	// Deferred is when the step was deferred.
+	Deferred time.Time
+}
+
+// ContractUpgradeSteps returns the contract upgrade steps that have been
+// deferred and have not run since, ordered by description.
+func (st *State) ContractUpgradeSteps() ([]ContractUpgradeStep, error) {
+	coll, closer := st.db().GetCollection(contractUpgradeStepsC)
+	defer closer()
+
+	var docs []contractUpgradeStepDoc
+	if err := coll.Find(nil).Sort("_id").All(&docs); err != nil {
+		return nil, errors.Annotate(err, "reading contract upgrade steps")
+	}
+	result := make([]ContractUpgradeStep, len(docs))
+	for i, doc := range docs {
+		result[i] = ContractUpgradeStep{
+			Description:     doc.DocID,
+			MinAgentVersion: doc.MinAgentVersion,
+			Deferred:        doc.Deferred.UTC(),
+		}
+	}
+	return result, nil
+}
+
+// AddContractUpgradeStep records that the contract upgrade step with the
+// input description was deferred until every agent runs at least the
+// input version.
+func (st *State) AddContractUpgradeStep(step string, minAgentVersion version.Number) error {
+	if step == "" {
+		return errors.NotValidf("empty upgrade step description")
+	}
+	if minAgentVersion == version.Zero {
+		return errors.NotValidf("zero minimum agent version")
+	}
+	coll, closer := st.db().GetCollection(contractUpgradeStepsC)
+	defer closer()
+
+	_, err := coll.Writeable().UpsertId(step, bson.D{{"$set", bson.D{
+		{"min-agent-version", minAgentVersion},
+		{"deferred", st.nowToTheSecond()},
+	}}})
+	return errors.Annotatef(err, "recording contract upgrade step %q", step)
+}
+
+// RemoveContractUpgradeStep removes the record of the deferred contract
+// upgrade step with the input description, once it has run. It is not
+// an error if no such step is recorded.
+func (st *State) RemoveContractUpgradeStep(step string) error {
+	coll, closer := st.db().GetCollection(contractUpgradeStepsC)
+	defer closer()
+
+	err := coll.Writeable().RemoveId(step)
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return errors.Annotatef(err, "removing contract upgrade step %q", step)
+}
+
+// MinAgentVersion returns the lowest version run by the machine and unit
+// agents of all of the controller's models. Agents that have not yet
+// reported their version are ignored. If none have, the zero version is
+// returned.
+func (st *State) MinAgentVersion() (version.Number, error) {
+	var min version.Number
+	for _, collName := range []string{machinesC, unitsC} {
+		coll, closer := st.db().GetRawCollection(collName)
+		var docs []struct {
+			Tools *tools.Tools `bson:"tools"`
+		}
+		query := bson.D{{"tools", bson.D{{"$exists", true}}}}
+		err := coll.Find(query).Select(bson.D{{"tools.version", 1}}).All(&docs)
+		closer()
+		if err != nil {
+			return version.Zero, errors.Annotatef(err, "reading %s agent versions", collName)
+		}
+		for _, doc := range docs {
+			if doc.Tools == nil {
+				continue
+			}
+			if v := doc.Tools.Version.Number; min == version.Zero || v.Compare(min) < 0 {
+				min = v
+			}
+		}
+	}
+	return min, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/version"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/testing/factory"
+)
+
+type ContractUpgradeStepsSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&ContractUpgradeStepsSuite{})
+
+func (s *ContractUpgradeStepsSuite) TestContractUpgradeStepsNone(c *gc.C) {
+	steps, err := s.State.ContractUpgradeSteps()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(steps, gc.HasLen, 0)
+}
+
+func (s *ContractUpgradeStepsSuite) TestAddAndRemoveContractUpgradeSteps(c *gc.C) {
+	err := s.State.AddContractUpgradeStep("remove unit ports", version.MustParse("2.8.1"))
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.AddContractUpgradeStep("drop legacy leases", version.MustParse("2.8.0"))
+	c.Assert(err, jc.ErrorIsNil)
+	// Recording a step again is not an error.
+	err = s.State.AddContractUpgradeStep("remove unit ports", version.MustParse("2.8.1"))
+	c.Assert(err, jc.ErrorIsNil)
+
+	steps, err := s.State.ContractUpgradeSteps()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(steps, gc.HasLen, 2)
+	c.Check(steps[0].Description, gc.Equals, "drop legacy leases")
+	c.Check(steps[0].MinAgentVersion, gc.Equals, version.MustParse("2.8.0"))
+	c.Check(steps[0].Deferred.IsZero(), jc.IsFalse)
+	c.Check(steps[1].Description, gc.Equals, "remove unit ports")
+	c.Check(steps[1].MinAgentVersion, gc.Equals, version.MustParse("2.8.1"))
+
+	err = s.State.RemoveContractUpgradeStep("drop legacy leases")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.RemoveContractUpgradeStep("drop legacy leases")
+	c.Assert(err, jc.ErrorIsNil)
+
+	steps, err = s.State.ContractUpgradeSteps()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(steps, gc.HasLen, 1)
+	c.Check(steps[0].Description, gc.Equals, "remove unit ports")
+}
+
+func (s *ContractUpgradeStepsSuite) TestAddContractUpgradeStepInvalid(c *gc.C) {
+	err := s.State.AddContractUpgradeStep("", version.MustParse("2.8.1"))
+	c.Assert(err, gc.ErrorMatches, "empty upgrade step description not valid")
+	err = s.State.AddContractUpgradeStep("remove unit ports", version.Zero)
+	c.Assert(err, gc.ErrorMatches, "zero minimum agent version not valid")
+}
+
+func (s *ContractUpgradeStepsSuite) TestMinAgentVersion(c *gc.C) {
+	min, err := s.State.MinAgentVersion()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(min, gc.Equals, version.Zero)
+
+	machine := s.Factory.MakeMachine(c, nil)
+	err = machine.SetAgentVersion(version.MustParseBinary("2.8.1-bionic-amd64"))
+	c.Assert(err, jc.ErrorIsNil)
+	unit := s.Factory.MakeUnit(c, nil)
+	err = unit.SetAgentVersion(version.MustParseBinary("2.8.0-bionic-amd64"))
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Agents in other models count too.
+	st := s.Factory.MakeModel(c, nil)
+	defer st.Close()
+	other := factory.NewFactory(st, s.StatePool).MakeMachine(c, nil)
+	err = other.SetAgentVersion(version.MustParseBinary("2.7.6-bionic-amd64"))
+	c.Assert(err, jc.ErrorIsNil)
+
+	min, err = s.State.MinAgentVersion()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(min, gc.Equals, version.MustParse("2.7.6"))
+}
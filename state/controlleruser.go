@@ -19,13 +19,18 @@ import (
 const defaultControllerPermission = permission.LoginAccess
 
 // setAccess changes the user's access permissions on the controller.
-func (st *State) setControllerAccess(access permission.Access, userGlobalKey string) error {
+// The permission event, if any, is queued in the same transaction.
+func (st *State) setControllerAccess(access permission.Access, userGlobalKey string, ev *PermissionEvent) error {
 	if err := permission.ValidateControllerAccess(access); err != nil {
 		return errors.Trace(err)
 	}
 	op := updatePermissionOp(controllerKey(st.ControllerUUID()), userGlobalKey, access)
+	eventOps, err := st.optionalPermissionEventOps(ev)
+	if err != nil {
+		return errors.Trace(err)
+	}
 
-	err := st.db().RunTransaction([]txn.Op{op})
+	err = st.db().RunTransaction(append([]txn.Op{op}, eventOps...))
 	if err == txn.ErrAborted {
 		return errors.NotFoundf("existing permissions")
 	}
@@ -83,10 +88,15 @@ func removeControllerUserOps(controllerUUID string, user names.UserTag) []txn.Op
 
 }
 
-// RemoveControllerUser removes a user from the database.
-func (st *State) removeControllerUser(user names.UserTag) error {
+// RemoveControllerUser removes a user from the database. The permission
+// event, if any, is queued in the same transaction.
+func (st *State) removeControllerUser(user names.UserTag, ev *PermissionEvent) error {
 	ops := removeControllerUserOps(st.ControllerUUID(), user)
-	err := st.db().RunTransaction(ops)
+	eventOps, err := st.optionalPermissionEventOps(ev)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	err = st.db().RunTransaction(append(ops, eventOps...))
 	if err == txn.ErrAborted {
 		err = errors.NewNotFound(nil, fmt.Sprintf("controller user %q does not exist", user.Id()))
 	}
@@ -188,3 +188,55 @@ func IsIncompatibleSeriesError(err interface{}) bool {
 	_, ok := value.(*ErrIncompatibleSeries)
 	return ok
 }
+
+// InvalidUnitStateKey describes why the value stored for
+// a charm state key cannot be persisted.
+type InvalidUnitStateKey struct {
+	// Key is the offending charm state key.
+	Key string
+
+	// Size is the size in bytes of the key and its value.
+	Size int
+
+	// Reason describes the problem with the key or its value.
+	Reason string
+}
+
+// ErrInvalidUnitState is a standard error to indicate that the charm state
+// of a unit cannot be persisted, identifying the keys responsible.
+type ErrInvalidUnitState struct {
+	// Keys holds the offending keys, sorted by key.
+	Keys []InvalidUnitStateKey
+
+	// TotalSize is the size in bytes of the charm state,
+	// if it exceeds the limit on the total size.
+	TotalSize int
+}
+
+func (e *ErrInvalidUnitState) Error() string {
+	var problems []string
+	for _, k := range e.Keys {
+		problems = append(problems, fmt.Sprintf("key %q %s", k.Key, k.Reason))
+	}
+	if e.TotalSize > 0 {
+		problems = append(problems, fmt.Sprintf(
+			"total size of %d bytes exceeds the %d byte limit", e.TotalSize, maxCharmStateSize))
+	}
+	return "invalid charm state: " + strings.Join(problems, "; ")
+}
+
+// IsInvalidUnitStateError returns if the given error or its cause is
+// ErrInvalidUnitState.
+func IsInvalidUnitStateError(err interface{}) bool {
+	if err == nil {
+		return false
+	}
+	// In case of a wrapped error, check the cause first.
+	value := err
+	cause := errors.Cause(err.(error))
+	if cause != nil {
+		value = cause
+	}
+	_, ok := value.(*ErrInvalidUnitState)
+	return ok
+}
@@ -52,11 +52,13 @@ const (
 	GUISettingsC      = guisettingsC
 	GlobalSettingsC   = globalSettingsC
 	SettingsC         = settingsC
+	UnitStateChunksC  = unitStateChunksC
 )
 
 var (
 	BinarystorageNew              = &binarystorageNew
 	ImageStorageNewStorage        = &imageStorageNewStorage
+	RelationStateChunkSize        = &relationStateChunkSize
 	MachineIdLessThan             = machineIdLessThan
 	GetOrCreatePorts              = getOrCreatePorts
 	GetPorts                      = getPorts
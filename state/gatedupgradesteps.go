@@ -0,0 +1,90 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// gatedUpgradeStepDoc records an upgrade step that was skipped
+// because the controller feature flag gating it was not set.
+type gatedUpgradeStepDoc struct {
+	// DocID is the description of the step.
+	DocID       string    `bson:"_id"`
+	FeatureFlag string    `bson:"feature-flag"`
+	Skipped     time.Time `bson:"skipped"`
+}
+
+// GatedUpgradeStep describes an upgrade step that was skipped because
+// the controller feature flag gating it was not set.
+type GatedUpgradeStep struct {
+	// Description is the description of the step.
+	Description string
+
+	// FeatureFlag is the controller feature flag
+	// that must be set for the step to run.
+	FeatureFlag string
+
+	// Skipped is when the step was last skipped.
+	Skipped time.Time
+}
+
+// GatedUpgradeSteps returns the upgrade steps that have been skipped
+// because their feature flags were not set, and have not run since,
+// ordered by description.
+func (st *State) GatedUpgradeSteps() ([]GatedUpgradeStep, error) {
+	coll, closer := st.db().GetCollection(gatedUpgradeStepsC)
+	defer closer()
+
+	var docs []gatedUpgradeStepDoc
+	if err := coll.Find(nil).Sort("_id").All(&docs); err != nil {
+		return nil, errors.Annotate(err, "reading gated upgrade steps")
+	}
+	result := make([]GatedUpgradeStep, len(docs))
+	for i, doc := range docs {
+		result[i] = GatedUpgradeStep{
+			Description: doc.DocID,
+			FeatureFlag: doc.FeatureFlag,
+			Skipped:     doc.Skipped.UTC(),
+		}
+	}
+	return result, nil
+}
+
+// AddGatedUpgradeStep records that the upgrade step with the input
+// description was skipped because the input feature flag was not set.
+func (st *State) AddGatedUpgradeStep(step, featureFlag string) error {
+	if step == "" {
+		return errors.NotValidf("empty upgrade step description")
+	}
+	if featureFlag == "" {
+		return errors.NotValidf("empty feature flag")
+	}
+	coll, closer := st.db().GetCollection(gatedUpgradeStepsC)
+	defer closer()
+
+	_, err := coll.Writeable().UpsertId(step, bson.D{{"$set", bson.D{
+		{"feature-flag", featureFlag},
+		{"skipped", st.nowToTheSecond()},
+	}}})
+	return errors.Annotatef(err, "recording gated upgrade step %q", step)
+}
+
+// RemoveGatedUpgradeStep removes the record of the skipped upgrade
+// step with the input description, once it has run. It is not an
+// error if no such step is recorded.
+func (st *State) RemoveGatedUpgradeStep(step string) error {
+	coll, closer := st.db().GetCollection(gatedUpgradeStepsC)
+	defer closer()
+
+	err := coll.Writeable().RemoveId(step)
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return errors.Annotatef(err, "removing gated upgrade step %q", step)
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+type GatedUpgradeStepsSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&GatedUpgradeStepsSuite{})
+
+func (s *GatedUpgradeStepsSuite) TestGatedUpgradeStepsNone(c *gc.C) {
+	steps, err := s.State.GatedUpgradeSteps()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(steps, gc.HasLen, 0)
+}
+
+func (s *GatedUpgradeStepsSuite) TestAddAndRemoveGatedUpgradeSteps(c *gc.C) {
+	err := s.State.AddGatedUpgradeStep("split unit docs", "unit-split")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.AddGatedUpgradeStep("migrate leases", "raft-leases")
+	c.Assert(err, jc.ErrorIsNil)
+	// Recording a step again is not an error.
+	err = s.State.AddGatedUpgradeStep("split unit docs", "unit-split")
+	c.Assert(err, jc.ErrorIsNil)
+
+	steps, err := s.State.GatedUpgradeSteps()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(steps, gc.HasLen, 2)
+	c.Check(steps[0].Description, gc.Equals, "migrate leases")
+	c.Check(steps[0].FeatureFlag, gc.Equals, "raft-leases")
+	c.Check(steps[0].Skipped.IsZero(), jc.IsFalse)
+	c.Check(steps[1].Description, gc.Equals, "split unit docs")
+	c.Check(steps[1].FeatureFlag, gc.Equals, "unit-split")
+
+	err = s.State.RemoveGatedUpgradeStep("migrate leases")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.RemoveGatedUpgradeStep("migrate leases")
+	c.Assert(err, jc.ErrorIsNil)
+
+	steps, err = s.State.GatedUpgradeSteps()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(steps, gc.HasLen, 1)
+	c.Check(steps[0].Description, gc.Equals, "split unit docs")
+}
+
+func (s *GatedUpgradeStepsSuite) TestAddGatedUpgradeStepInvalid(c *gc.C) {
+	err := s.State.AddGatedUpgradeStep("", "unit-split")
+	c.Assert(err, gc.ErrorMatches, "empty upgrade step description not valid")
+	err = s.State.AddGatedUpgradeStep("split unit docs", "")
+	c.Assert(err, gc.ErrorMatches, "empty feature flag not valid")
+}
@@ -0,0 +1,119 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2"
+)
+
+// CollectionIndexes reports the indexes of a collection that did not
+// match those declared for it by the collection schema.
+type CollectionIndexes struct {
+	// Collection is the name of the collection.
+	Collection string
+
+	// Created holds the names of the declared indexes
+	// that were missing, and have been created.
+	Created []string
+
+	// Unexpected holds the names of the indexes that are not
+	// declared for the collection, and may be stale.
+	Unexpected []string
+}
+
+// EnsureIndexes ensures that the indexes declared for each collection by
+// the collection schema exist. Indexes are otherwise only created when the
+// controller is bootstrapped, so indexes declared since then by upgraded
+// versions would be missing. Missing indexes are built in the background,
+// so that the collections remain available while they are built. Indexes
+// that are not declared by the schema are reported rather than removed,
+// so that an operator can decide whether to remove them. Only the
+// collections with missing or unexpected indexes are returned. Indexes
+// are compared by name, so an index whose options have changed is not
+// recreated.
+func (st *State) EnsureIndexes() ([]CollectionIndexes, error) {
+	schema := allCollections()
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var result []CollectionIndexes
+	for _, name := range names {
+		indexes, err := st.ensureCollectionIndexes(name, schema[name].indexes)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if len(indexes.Created) > 0 || len(indexes.Unexpected) > 0 {
+			result = append(result, indexes)
+		}
+	}
+	return result, nil
+}
+
+func (st *State) ensureCollectionIndexes(name string, declared []mgo.Index) (CollectionIndexes, error) {
+	result := CollectionIndexes{Collection: name}
+	coll, closer := st.db().GetRawCollection(name)
+	defer closer()
+
+	existing, err := coll.Indexes()
+	if err != nil && !isMgoNamespaceNotFound(err) {
+		return result, errors.Annotatef(err, "reading indexes of %q", name)
+	}
+	found := make(map[string]bool, len(existing))
+	for _, index := range existing {
+		found[index.Name] = true
+	}
+
+	expected := map[string]bool{"_id_": true}
+	for _, index := range declared {
+		indexName := mongoIndexName(index)
+		expected[indexName] = true
+		if found[indexName] {
+			continue
+		}
+		index.Background = true
+		if err := coll.EnsureIndex(index); err != nil {
+			return result, errors.Annotatef(err, "creating index %q of %q", indexName, name)
+		}
+		result.Created = append(result.Created, indexName)
+	}
+	for _, index := range existing {
+		if !expected[index.Name] {
+			result.Unexpected = append(result.Unexpected, index.Name)
+		}
+	}
+	sort.Strings(result.Unexpected)
+	return result, nil
+}
+
+// mongoIndexName returns the name given by the database server to the
+// index, which is derived from its key unless it is named explicitly.
+func mongoIndexName(index mgo.Index) string {
+	if index.Name != "" {
+		return index.Name
+	}
+	parts := make([]string, len(index.Key))
+	for i, field := range index.Key {
+		kind := "1"
+		switch {
+		case strings.HasPrefix(field, "+"):
+			field = field[1:]
+		case strings.HasPrefix(field, "-"):
+			field, kind = field[1:], "-1"
+		case strings.HasPrefix(field, "$"):
+			if colon := strings.Index(field, ":"); colon > 0 {
+				field, kind = field[colon+1:], field[1:colon]
+			}
+		}
+		parts[i] = fmt.Sprintf("%s_%s", field, kind)
+	}
+	return strings.Join(parts, "_")
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/mgo.v2"
+
+	"github.com/juju/juju/state"
+)
+
+type IndexesSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&IndexesSuite{})
+
+func (s *IndexesSuite) TestEnsureIndexesNoChanges(c *gc.C) {
+	result, err := s.State.EnsureIndexes()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.HasLen, 0)
+}
+
+func (s *IndexesSuite) TestEnsureIndexes(c *gc.C) {
+	machines := s.Session.DB("juju").C("machines")
+	err := machines.DropIndexName("model-uuid_1_machineid_1")
+	c.Assert(err, jc.ErrorIsNil)
+	err = machines.EnsureIndex(mgo.Index{Key: []string{"-series"}})
+	c.Assert(err, jc.ErrorIsNil)
+
+	result, err := s.State.EnsureIndexes()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, []state.CollectionIndexes{{
+		Collection: "machines",
+		Created:    []string{"model-uuid_1_machineid_1"},
+		Unexpected: []string{"series_-1"},
+	}})
+
+	indexes, err := machines.Indexes()
+	c.Assert(err, jc.ErrorIsNil)
+	var names []string
+	for _, index := range indexes {
+		names = append(names, index.Name)
+	}
+	c.Assert(names, jc.SameContents, []string{"_id_", "model-uuid_1_machineid_1", "series_-1"})
+
+	// Unexpected indexes are reported again until they are removed.
+	result, err = s.State.EnsureIndexes()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, []state.CollectionIndexes{{
+		Collection: "machines",
+		Unexpected: []string{"series_-1"},
+	}})
+}
@@ -0,0 +1,135 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"sort"
+	"time"
+
+	"github.com/juju/collections/set"
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// loginActivityRetention is how long the record
+// of each login to the API server is kept.
+const loginActivityRetention = 90 * 24 * time.Hour
+
+// userLoginActivityDoc records a user's login to the API server.
+type userLoginActivityDoc struct {
+	DocID     bson.ObjectId `bson:"_id"`
+	UserName  string        `bson:"user"`
+	ModelUUID string        `bson:"model-uuid,omitempty"`
+	Address   string        `bson:"address,omitempty"`
+	Time      time.Time     `bson:"time"`
+}
+
+// LoginActivity summarises a user's logins to
+// a model, or to the controller, over a period.
+type LoginActivity struct {
+	// User is the user who logged in.
+	User names.UserTag
+
+	// ModelUUID identifies the model the user logged in to.
+	// It is empty for logins to the controller itself.
+	ModelUUID string
+
+	// Connections is the number of times the user logged in.
+	Connections int
+
+	// LastConnection is when the user last logged in.
+	LastConnection time.Time
+
+	// Addresses holds the distinct addresses
+	// the user logged in from, sorted.
+	Addresses []string
+}
+
+// RecordLogin records that the user logged in to the model with the given
+// UUID, or to the controller if it is empty, from the given address.
+func (st *State) RecordLogin(user names.UserTag, modelUUID, address string) error {
+	logins, closer := st.db().GetCollection(userLoginActivityC)
+	defer closer()
+
+	loginsW := logins.Writeable()
+
+	// As with the last login time, the record is written
+	// without requiring write majority, nor sync to disk.
+	session := loginsW.Underlying().Database.Session
+	session.SetSafe(&mgo.Safe{})
+
+	doc := userLoginActivityDoc{
+		DocID:     bson.NewObjectId(),
+		UserName:  user.Id(),
+		ModelUUID: modelUUID,
+		Address:   address,
+		Time:      st.nowToTheSecond(),
+	}
+	err := loginsW.Insert(&doc)
+	return errors.Annotatef(err, "recording login for user %q", user.Id())
+}
+
+// LoginActivity returns a summary of the logins since the given time, for
+// each of the users and the models they logged in to, ordered by user and
+// then model. If no users are specified, the logins of all users are
+// summarised.
+func (st *State) LoginActivity(since time.Time, users ...names.UserTag) ([]LoginActivity, error) {
+	logins, closer := st.db().GetCollection(userLoginActivityC)
+	defer closer()
+
+	query := bson.D{{"time", bson.D{{"$gte", since}}}}
+	if len(users) > 0 {
+		ids := make([]string, len(users))
+		for i, user := range users {
+			ids[i] = user.Id()
+		}
+		query = append(query, bson.DocElem{"user", bson.D{{"$in", ids}}})
+	}
+
+	type key struct {
+		user, model string
+	}
+	activity := make(map[key]*LoginActivity)
+	addresses := make(map[key]set.Strings)
+	var doc userLoginActivityDoc
+	iter := logins.Find(query).Iter()
+	for iter.Next(&doc) {
+		k := key{doc.UserName, doc.ModelUUID}
+		a, ok := activity[k]
+		if !ok {
+			a = &LoginActivity{
+				User:      names.NewUserTag(doc.UserName),
+				ModelUUID: doc.ModelUUID,
+			}
+			activity[k] = a
+			addresses[k] = set.NewStrings()
+		}
+		a.Connections++
+		if doc.Time.After(a.LastConnection) {
+			a.LastConnection = doc.Time
+		}
+		if doc.Address != "" {
+			addresses[k].Add(doc.Address)
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return nil, errors.Annotate(err, "cannot read login activity")
+	}
+
+	result := make([]LoginActivity, 0, len(activity))
+	for k, a := range activity {
+		a.LastConnection = a.LastConnection.UTC()
+		a.Addresses = addresses[k].SortedValues()
+		result = append(result, *a)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].User.Id() != result[j].User.Id() {
+			return result[i].User.Id() < result[j].User.Id()
+		}
+		return result[i].ModelUUID < result[j].ModelUUID
+	})
+	return result, nil
+}
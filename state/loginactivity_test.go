@@ -0,0 +1,83 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"time"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/state"
+)
+
+type LoginActivitySuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&LoginActivitySuite{})
+
+func (s *LoginActivitySuite) record(c *gc.C, user, modelUUID, address string) {
+	err := s.State.RecordLogin(names.NewUserTag(user), modelUUID, address)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *LoginActivitySuite) TestNoActivity(c *gc.C) {
+	activity, err := s.State.LoginActivity(time.Time{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(activity, gc.HasLen, 0)
+}
+
+func (s *LoginActivitySuite) TestAggregatesPerUserAndModel(c *gc.C) {
+	modelUUID := s.Model.UUID()
+	s.record(c, "bob", modelUUID, "10.0.0.2")
+	s.Clock.Advance(time.Minute)
+	s.record(c, "bob", modelUUID, "10.0.0.1")
+	s.Clock.Advance(time.Minute)
+	s.record(c, "bob", modelUUID, "10.0.0.2")
+	s.record(c, "bob", "", "10.0.0.3")
+	s.record(c, "mary", modelUUID, "")
+	last := s.Clock.Now().Truncate(time.Second).UTC()
+
+	activity, err := s.State.LoginActivity(time.Time{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(activity, gc.HasLen, 3)
+	c.Check(activity[0], jc.DeepEquals, state.LoginActivity{
+		User:           names.NewUserTag("bob"),
+		Connections:    1,
+		LastConnection: last,
+		Addresses:      []string{"10.0.0.3"},
+	})
+	c.Check(activity[1], jc.DeepEquals, state.LoginActivity{
+		User:           names.NewUserTag("bob"),
+		ModelUUID:      modelUUID,
+		Connections:    3,
+		LastConnection: last,
+		Addresses:      []string{"10.0.0.1", "10.0.0.2"},
+	})
+	c.Check(activity[2], jc.DeepEquals, state.LoginActivity{
+		User:           names.NewUserTag("mary"),
+		ModelUUID:      modelUUID,
+		Connections:    1,
+		LastConnection: last,
+		Addresses:      []string{},
+	})
+}
+
+func (s *LoginActivitySuite) TestFiltersByUserAndTime(c *gc.C) {
+	modelUUID := s.Model.UUID()
+	s.record(c, "bob", modelUUID, "10.0.0.1")
+	s.Clock.Advance(time.Hour)
+	since := s.Clock.Now().Truncate(time.Second)
+	s.record(c, "bob", modelUUID, "10.0.0.2")
+	s.record(c, "mary", modelUUID, "10.0.0.3")
+
+	activity, err := s.State.LoginActivity(since, names.NewUserTag("bob"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(activity, gc.HasLen, 1)
+	c.Check(activity[0].User, gc.Equals, names.NewUserTag("bob"))
+	c.Check(activity[0].Connections, gc.Equals, 1)
+	c.Check(activity[0].Addresses, jc.DeepEquals, []string{"10.0.0.2"})
+}
@@ -0,0 +1,215 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+
+	"github.com/juju/description"
+	"github.com/juju/errors"
+)
+
+const (
+	// controllerSectionAnnotation is the model annotation holding the
+	// encrypted controller section of an export of the controller model.
+	controllerSectionAnnotation = "juju-controller-section"
+
+	// controllerSectionVersion is the version of the
+	// format of the controller section.
+	controllerSectionVersion = 1
+
+	// ControllerSectionKeySize is the size in bytes of the key with
+	// which the controller section of an export is encrypted.
+	ControllerSectionKeySize = 32
+)
+
+// ControllerSection holds the controller-wide material exported with
+// the controller model when it is requested, with which a controller can
+// be rebuilt more quickly. It is only ever held in the exported model
+// encrypted, as the autocert cache entries include private keys.
+type ControllerSection struct {
+	Version        int    `json:"version"`
+	ControllerUUID string `json:"controller-uuid"`
+
+	// Config holds the controller config attributes.
+	Config map[string]interface{} `json:"config"`
+
+	// CACert is the controller's CA certificate. The CA's private key
+	// is not exported; CACertFingerprint and ServingCertFingerprint
+	// identify the certificates that the rebuilt controller must be
+	// given, so that clients and agents continue to trust it.
+	CACert                 string `json:"ca-cert"`
+	CACertFingerprint      string `json:"ca-cert-fingerprint"`
+	ServingCertFingerprint string `json:"serving-cert-fingerprint,omitempty"`
+
+	// AutocertCache holds the entries of the cache of certificates
+	// obtained from the ACME server, so that they need not be
+	// obtained again.
+	AutocertCache []AutocertCacheEntry `json:"autocert-cache,omitempty"`
+}
+
+// AutocertCacheEntry is an entry in the controller's autocert cache.
+type AutocertCacheEntry struct {
+	Name string `json:"name"`
+	Data []byte `json:"data"`
+}
+
+// controllerSectionAnnotations returns the model annotations holding the
+// controller section, encrypted with the key in the export config, or
+// nil if the section was not requested.
+func (e *exporter) controllerSectionAnnotations() (map[string]string, error) {
+	key := e.cfg.ControllerSectionKey
+	if key == nil {
+		return nil, nil
+	}
+	if !e.st.IsController() {
+		return nil, errors.NotValidf("controller section in export of non-controller model")
+	}
+	section, err := e.st.controllerSection()
+	if err != nil {
+		return nil, errors.Annotate(err, "reading controller section")
+	}
+	data, err := json.Marshal(section)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	sealed, err := sealControllerSection(key, data)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	e.logger.Infof("exporting controller section with %d autocert cache entries", len(section.AutocertCache))
+	return map[string]string{controllerSectionAnnotation: sealed}, nil
+}
+
+// controllerSection reads the controller section from the database.
+func (st *State) controllerSection() (*ControllerSection, error) {
+	cfg, err := st.ControllerConfig()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	caCert, _ := cfg.CACert()
+	section := &ControllerSection{
+		Version:           controllerSectionVersion,
+		ControllerUUID:    cfg.ControllerUUID(),
+		Config:            cfg,
+		CACert:            caCert,
+		CACertFingerprint: certFingerprint(caCert),
+	}
+	info, err := st.StateServingInfo()
+	if err == nil {
+		section.ServingCertFingerprint = certFingerprint(info.Cert)
+	} else if !errors.IsNotFound(err) {
+		return nil, errors.Trace(err)
+	}
+
+	coll, closer := st.db().GetCollection(autocertCacheC)
+	defer closer()
+	var docs []autocertCacheDoc
+	if err := coll.Find(nil).Sort("_id").All(&docs); err != nil {
+		return nil, errors.Annotate(err, "reading autocert cache")
+	}
+	for _, doc := range docs {
+		section.AutocertCache = append(section.AutocertCache, AutocertCacheEntry{
+			Name: doc.Name,
+			Data: doc.Data,
+		})
+	}
+	return section, nil
+}
+
+// certFingerprint returns the hex-encoded SHA-256 hash of the
+// PEM-encoded certificate.
+func certFingerprint(cert string) string {
+	hash := sha256.Sum256([]byte(cert))
+	return hex.EncodeToString(hash[:])
+}
+
+// ControllerSectionOf returns the controller section held in the exported
+// controller model, decrypted with the key with which it was exported.
+// A NotFound error is returned if the model holds no controller section.
+func ControllerSectionOf(model description.Model, key []byte) (*ControllerSection, error) {
+	sealed, ok := model.Annotations()[controllerSectionAnnotation]
+	if !ok {
+		return nil, errors.NotFoundf("controller section")
+	}
+	data, err := openControllerSection(key, sealed)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var section ControllerSection
+	if err := json.Unmarshal(data, &section); err != nil {
+		return nil, errors.Annotate(err, "decoding controller section")
+	}
+	if section.Version != controllerSectionVersion {
+		return nil, errors.NotSupportedf("controller section version %d", section.Version)
+	}
+	return &section, nil
+}
+
+// RestoreAutocertCache adds the entries of the controller section's
+// autocert cache to the controller's cache, replacing any of the same
+// names.
+func (st *State) RestoreAutocertCache(section *ControllerSection) error {
+	cache := autocertCache{st}
+	for _, entry := range section.AutocertCache {
+		if err := cache.Put(context.Background(), entry.Name, entry.Data); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// sealControllerSection encrypts the data with AES-256-GCM, returning
+// the nonce and ciphertext, base64-encoded.
+func sealControllerSection(key, data []byte) (string, error) {
+	gcm, err := controllerSectionCipher(key)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", errors.Trace(err)
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, data, nil)), nil
+}
+
+// openControllerSection decrypts data encrypted by sealControllerSection.
+func openControllerSection(key []byte, sealed string) ([]byte, error) {
+	gcm, err := controllerSectionCipher(key)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, errors.Annotate(err, "decoding controller section")
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.NotValidf("controller section")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	data, err = gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("cannot decrypt controller section: wrong key, or section modified")
+	}
+	return data, nil
+}
+
+func controllerSectionCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != ControllerSectionKeySize {
+		return nil, errors.NotValidf("controller section key of %d bytes", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -277,6 +277,99 @@ func (mr *MockFirewallRuleMockRecorder) WhitelistCIDRs() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WhitelistCIDRs", reflect.TypeOf((*MockFirewallRule)(nil).WhitelistCIDRs))
 }
 
+// MockOfferConnection is a mock of OfferConnection interface
+type MockOfferConnection struct {
+	ctrl     *gomock.Controller
+	recorder *MockOfferConnectionMockRecorder
+}
+
+// MockOfferConnectionMockRecorder is the mock recorder for MockOfferConnection
+type MockOfferConnectionMockRecorder struct {
+	mock *MockOfferConnection
+}
+
+// NewMockOfferConnection creates a new mock instance
+func NewMockOfferConnection(ctrl *gomock.Controller) *MockOfferConnection {
+	mock := &MockOfferConnection{ctrl: ctrl}
+	mock.recorder = &MockOfferConnectionMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockOfferConnection) EXPECT() *MockOfferConnectionMockRecorder {
+	return m.recorder
+}
+
+// OfferUUID mocks base method
+func (m *MockOfferConnection) OfferUUID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OfferUUID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// OfferUUID indicates an expected call of OfferUUID
+func (mr *MockOfferConnectionMockRecorder) OfferUUID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OfferUUID", reflect.TypeOf((*MockOfferConnection)(nil).OfferUUID))
+}
+
+// RelationID mocks base method
+func (m *MockOfferConnection) RelationID() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RelationID")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// RelationID indicates an expected call of RelationID
+func (mr *MockOfferConnectionMockRecorder) RelationID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RelationID", reflect.TypeOf((*MockOfferConnection)(nil).RelationID))
+}
+
+// RelationKey mocks base method
+func (m *MockOfferConnection) RelationKey() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RelationKey")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// RelationKey indicates an expected call of RelationKey
+func (mr *MockOfferConnectionMockRecorder) RelationKey() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RelationKey", reflect.TypeOf((*MockOfferConnection)(nil).RelationKey))
+}
+
+// SourceModelUUID mocks base method
+func (m *MockOfferConnection) SourceModelUUID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SourceModelUUID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// SourceModelUUID indicates an expected call of SourceModelUUID
+func (mr *MockOfferConnectionMockRecorder) SourceModelUUID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SourceModelUUID", reflect.TypeOf((*MockOfferConnection)(nil).SourceModelUUID))
+}
+
+// UserName mocks base method
+func (m *MockOfferConnection) UserName() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserName")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// UserName indicates an expected call of UserName
+func (mr *MockOfferConnectionMockRecorder) UserName() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserName", reflect.TypeOf((*MockOfferConnection)(nil).UserName))
+}
+
 // MockRemoteEntity is a mock of RemoteEntity interface
 type MockRemoteEntity struct {
 	ctrl     *gomock.Controller
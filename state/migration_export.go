@@ -5,6 +5,7 @@ package state
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
@@ -18,6 +19,7 @@ import (
 
 	"github.com/juju/juju/core/crossmodel"
 	"github.com/juju/juju/core/network"
+	"github.com/juju/juju/environs/config"
 	"github.com/juju/juju/feature"
 	"github.com/juju/juju/payload"
 	"github.com/juju/juju/resource"
@@ -69,20 +71,73 @@ type ExportConfig struct {
 	SkipApplicationOffers    bool
 	SkipOfferConnections     bool
 	SkipExternalControllers  bool
+
+	// Applications, if not empty, restricts the export to the named
+	// applications, along with their units, the machines and storage
+	// those use, the remote applications they are related to, and the
+	// relations between them. Relations to local applications that are
+	// left out are severed, and recorded in the model's annotations.
+	// Model-wide and cross-model entities, such as spaces, subnets and
+	// offer connections, are exported in full.
+	Applications []string
+
+	// ControllerSectionKey, if set, requests that the controller's
+	// config, certificate references and autocert cache are exported
+	// with the controller model, encrypted with the key, which must be
+	// ControllerSectionKeySize bytes long. See ControllerSectionOf.
+	ControllerSectionKey []byte
+
+	// Permissions, if set, requests that the controller access and
+	// cloud access of the model's users are exported with the model,
+	// to be applied by the target controller with this policy. See
+	// PermissionsSectionOf.
+	Permissions PermissionConflictPolicy
+}
+
+// isFull returns true if the config exports the whole model.
+func (cfg ExportConfig) isFull() bool {
+	if len(cfg.Applications) > 0 {
+		return false
+	}
+	cfg.Applications = nil
+	cfg.ControllerSectionKey = nil
+	cfg.Permissions = ""
+	return reflect.DeepEqual(cfg, ExportConfig{})
 }
 
 // ExportPartial the current model for the State optionally skipping
 // aspects as defined by the ExportConfig.
 func (st *State) ExportPartial(cfg ExportConfig) (description.Model, error) {
+	model, _, err := st.exportImpl(cfg)
+	return model, err
+}
+
+// ExportApplications exports the subset of the current model's
+// applications named in the ExportConfig, as ExportPartial does. It
+// also returns the relations severed by leaving the other applications
+// out, describing what will break if the model is split in this way.
+func (st *State) ExportApplications(cfg ExportConfig) (description.Model, []SeveredRelation, error) {
+	if len(cfg.Applications) == 0 {
+		return nil, nil, errors.NotValidf("export without applications")
+	}
 	return st.exportImpl(cfg)
 }
 
 // Export the current model for the State.
 func (st *State) Export() (description.Model, error) {
-	return st.exportImpl(ExportConfig{})
+	model, _, err := st.exportImpl(ExportConfig{})
+	return model, err
+}
+
+func (st *State) exportImpl(cfg ExportConfig) (description.Model, []SeveredRelation, error) {
+	export, err := st.exportModel(cfg)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	return export.model, export.filter.severedRelations(), nil
 }
 
-func (st *State) exportImpl(cfg ExportConfig) (description.Model, error) {
+func (st *State) exportModel(cfg ExportConfig) (*exporter, error) {
 	dbModel, err := st.Model()
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -93,6 +148,11 @@ func (st *State) exportImpl(cfg ExportConfig) (description.Model, error) {
 		dbModel: dbModel,
 		logger:  loggo.GetLogger("juju.state.export-model"),
 	}
+	if len(cfg.Applications) > 0 {
+		if export.filter, err = newExportFilter(st, cfg.Applications); err != nil {
+			return nil, errors.Annotate(err, "filtering applications")
+		}
+	}
 	if err := export.readAllStatuses(); err != nil {
 		return nil, errors.Annotate(err, "reading statuses")
 	}
@@ -128,7 +188,7 @@ func (st *State) exportImpl(cfg ExportConfig) (description.Model, error) {
 		Cloud:              dbModel.CloudName(),
 		CloudRegion:        dbModel.CloudRegion(),
 		Owner:              dbModel.Owner(),
-		Config:             modelConfig.Settings,
+		Config:             withAgentConfigOverrides(modelConfig.Settings),
 		LatestToolsVersion: dbModel.LatestToolsVersion(),
 		EnvironVersion:     dbModel.EnvironVersion(),
 		Blocks:             blocks,
@@ -148,7 +208,22 @@ func (st *State) exportImpl(cfg ExportConfig) (description.Model, error) {
 		})
 	}
 	modelKey := dbModel.globalKey()
-	export.model.SetAnnotations(export.getAnnotations(modelKey))
+	annotations := export.modelAnnotations(modelKey)
+	controllerSection, err := export.controllerSectionAnnotations()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(controllerSection) > 0 {
+		annotations = mergeAnnotations(annotations, controllerSection)
+	}
+	permissionsSection, err := export.permissionsSectionAnnotations()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(permissionsSection) > 0 {
+		annotations = mergeAnnotations(annotations, permissionsSection)
+	}
+	export.model.SetAnnotations(annotations)
 	if err := export.sequences(); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -217,8 +292,7 @@ func (st *State) exportImpl(cfg ExportConfig) (description.Model, error) {
 
 	// If we are doing a partial export, it doesn't really make sense
 	// to validate the model.
-	fullExport := ExportConfig{}
-	if cfg == fullExport {
+	if cfg.isFull() {
 		if err := export.model.Validate(); err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -227,13 +301,80 @@ func (st *State) exportImpl(cfg ExportConfig) (description.Model, error) {
 	export.model.SetSLA(dbModel.SLALevel(), dbModel.SLAOwner(), string(dbModel.SLACredential()))
 	export.model.SetMeterStatus(dbModel.MeterStatus().Code.String(), dbModel.MeterStatus().Info)
 
-	if featureflag.Enabled(feature.StrictMigration) {
+	// Values belonging to the entities left out of a
+	// filtered export are expected to remain unexported.
+	if featureflag.Enabled(feature.StrictMigration) && export.filter == nil {
 		if err := export.checkUnexportedValues(); err != nil {
 			return nil, errors.Trace(err)
 		}
 	}
 
-	return export.model, nil
+	return &export, nil
+}
+
+// agentConfigAttributes are the model config attributes that are passed
+// on to the model's agents, and determine how they log and how they and
+// the machines they run on reach the network.
+var agentConfigAttributes = []string{
+	"logging-config",
+	config.HTTPProxyKey,
+	config.HTTPSProxyKey,
+	config.FTPProxyKey,
+	config.NoProxyKey,
+	config.JujuHTTPProxyKey,
+	config.JujuHTTPSProxyKey,
+	config.JujuFTPProxyKey,
+	config.JujuNoProxyKey,
+	config.AptHTTPProxyKey,
+	config.AptHTTPSProxyKey,
+	config.AptFTPProxyKey,
+	config.AptNoProxyKey,
+	"apt-mirror",
+	config.SnapHTTPProxyKey,
+	config.SnapHTTPSProxyKey,
+	config.SnapStoreProxyKey,
+	config.SnapStoreAssertionsKey,
+	config.SnapStoreProxyURLKey,
+}
+
+// logForwardingAttributes are the model config attributes that determine
+// whether and where the model's logs are forwarded, with the values that
+// leave forwarding disabled. The certificates and key are only valid
+// together, so are given explicit values alongside the syslog host.
+var logForwardingAttributes = map[string]interface{}{
+	config.LogForwardEnabled:      false,
+	config.LogFwdSyslogHost:       "",
+	config.LogFwdSyslogCACert:     "",
+	config.LogFwdSyslogClientCert: "",
+	config.LogFwdSyslogClientKey:  "",
+}
+
+// withAgentConfigOverrides returns the model config settings to export,
+// with every agent config and log forwarding attribute given an explicit
+// value. A model created before an attribute was introduced has no value
+// for it, and its agents treat it as empty; the importing controller would
+// otherwise fill it in from its own defaults, and agents recreated there
+// would behave differently, or the model's logs would be forwarded to
+// wherever the importing controller's defaults point.
+func withAgentConfigOverrides(settings map[string]interface{}) map[string]interface{} {
+	if settings == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		result[k] = v
+	}
+	for _, attr := range agentConfigAttributes {
+		if _, ok := result[attr]; !ok {
+			result[attr] = ""
+		}
+	}
+	for attr, value := range logForwardingAttributes {
+		if _, ok := result[attr]; !ok {
+			result[attr] = value
+		}
+	}
+	return result
 }
 
 // ExportStateMigration defines a migration for exporting various entities into
@@ -270,6 +411,7 @@ type exporter struct {
 	dbModel *Model
 	model   description.Model
 	logger  loggo.Logger
+	filter  *exportFilter
 
 	annotations             map[string]annotatorDoc
 	constraints             map[string]bson.M
@@ -380,6 +522,9 @@ func (e *exporter) machines() error {
 	machineMap := make(map[string]description.Machine)
 
 	for _, machine := range machines {
+		if !e.filter.includesMachine(machine.Id()) {
+			continue
+		}
 		e.logger.Debugf("export machine %s", machine.Id())
 
 		var exParent description.Machine
@@ -680,6 +825,9 @@ func (e *exporter) applications() error {
 	}
 
 	for _, application := range applications {
+		if !e.filter.includesApplication(application.Name()) {
+			continue
+		}
 		applicationUnits := e.units[application.Name()]
 		leader := leaders[application.Name()]
 		resources, err := resourcesSt.ListResources(application.Name())
@@ -920,7 +1068,9 @@ func (e *exporter) addApplication(ctx addApplicationContext) error {
 		}
 		// TODO: hml 18-mar-2020
 		// add the rest of unit.State() to model migration
-		unitState, err := unit.State()
+		// Branches are not migrated, so only the master
+		// charm state is exported.
+		unitState, err := unit.readState(false)
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -1115,6 +1265,9 @@ func (e *exporter) relations() error {
 	}
 
 	for _, relation := range rels {
+		if !e.filter.includesRelation(relation) {
+			continue
+		}
 		exRelation := e.model.AddRelation(description.RelationArgs{
 			Id:  relation.Id(),
 			Key: relation.String(),
@@ -1435,6 +1588,9 @@ func (e *exporter) linklayerdevices() error {
 	}
 	e.logger.Debugf("read %d ip devices", len(linklayerdevices))
 	for _, device := range linklayerdevices {
+		if !e.filter.includesMachine(device.MachineID()) {
+			continue
+		}
 		e.model.AddLinkLayerDevice(description.LinkLayerDeviceArgs{
 			ProviderID:  string(device.ProviderID()),
 			MachineID:   device.MachineID(),
@@ -1485,6 +1641,9 @@ func (e *exporter) ipaddresses() error {
 	}
 	e.logger.Debugf("read %d ip addresses", len(ipaddresses))
 	for _, addr := range ipaddresses {
+		if !e.filter.includesMachine(addr.MachineID()) {
+			continue
+		}
 		e.model.AddIPAddress(description.IPAddressArgs{
 			ProviderID:       string(addr.ProviderID()),
 			DeviceName:       addr.DeviceName(),
@@ -1509,6 +1668,9 @@ func (e *exporter) sshHostKeys() error {
 		return errors.Trace(err)
 	}
 	for _, machine := range machines {
+		if !e.filter.includesMachine(machine.Id()) {
+			continue
+		}
 		keys, err := e.st.GetSSHHostKeys(machine.MachineTag())
 		if errors.IsNotFound(err) {
 			continue
@@ -1570,6 +1732,9 @@ func (e *exporter) actions() error {
 	}
 	e.logger.Debugf("read %d actions", len(actions))
 	for _, action := range actions {
+		if !e.filter.includesReceiver(action.Receiver()) {
+			continue
+		}
 		results, message := action.Results()
 		arg := description.ActionArgs{
 			Receiver:   action.Receiver(),
@@ -1799,6 +1964,30 @@ func (e *exporter) readAllConstraints() error {
 // getAnnotations doesn't really care if there are any there or not
 // for the key, but if they were there, they are removed so we can
 // check at the end of the export for anything we have forgotten.
+// modelAnnotations returns the annotations of the model, along with
+// those recording the relations severed by a filtered export.
+func (e *exporter) modelAnnotations(key string) map[string]string {
+	annotations := e.getAnnotations(key)
+	severed := e.filter.severedAnnotations()
+	if len(severed) == 0 {
+		return annotations
+	}
+	return mergeAnnotations(annotations, severed)
+}
+
+// mergeAnnotations returns the annotations in a and b, with
+// those in b replacing any of the same key in a.
+func mergeAnnotations(a, b map[string]string) map[string]string {
+	result := make(map[string]string, len(a)+len(b))
+	for k, v := range a {
+		result[k] = v
+	}
+	for k, v := range b {
+		result[k] = v
+	}
+	return result
+}
+
 func (e *exporter) getAnnotations(key string) map[string]string {
 	result, found := e.annotations[key]
 	if found {
@@ -2079,9 +2268,12 @@ func (s remoteApplicationsShim) AllRemoteApplications() ([]migrations.MigrationR
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	result := make([]migrations.MigrationRemoteApplication, len(remoteApps))
-	for k, v := range remoteApps {
-		result[k] = remoteApplicationShim{RemoteApplication: v}
+	result := make([]migrations.MigrationRemoteApplication, 0, len(remoteApps))
+	for _, v := range remoteApps {
+		if !s.exporter.filter.includesApplication(v.Name()) {
+			continue
+		}
+		result = append(result, remoteApplicationShim{RemoteApplication: v})
 	}
 	return result, nil
 }
@@ -2179,6 +2371,9 @@ func (e *exporter) volumes() error {
 	iter := coll.Find(nil).Sort("_id").Iter()
 	defer func() { _ = iter.Close() }()
 	for iter.Next(&doc) {
+		if !e.filter.includesStorage(doc.StorageId, volumeHosts(attachments[doc.Name])...) {
+			continue
+		}
 		vol := &volume{e.st, doc}
 		plan := attachmentPlans[doc.Name]
 		if err := e.addVolume(vol, attachments[doc.Name], plan); err != nil {
@@ -2345,6 +2540,9 @@ func (e *exporter) filesystems() error {
 	iter := coll.Find(nil).Sort("_id").Iter()
 	defer func() { _ = iter.Close() }()
 	for iter.Next(&doc) {
+		if !e.filter.includesStorage(doc.StorageId, filesystemHosts(attachments[doc.FilesystemId])...) {
+			continue
+		}
 		fs := &filesystem{e.st, doc}
 		if err := e.addFilesystem(fs, attachments[doc.FilesystemId]); err != nil {
 			return errors.Trace(err)
@@ -2451,6 +2649,9 @@ func (e *exporter) storageInstances() error {
 	iter := coll.Find(nil).Sort("_id").Iter()
 	defer func() { _ = iter.Close() }()
 	for iter.Next(&doc) {
+		if !e.filter.includesStorage(doc.Id) {
+			continue
+		}
 		instance := &storageInstance{sb, doc}
 		if err := e.addStorage(instance, attachments[doc.Id]); err != nil {
 			return errors.Trace(err)
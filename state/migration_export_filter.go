@@ -0,0 +1,243 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/juju/collections/set"
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+)
+
+// severedRelationAnnotationPrefix prefixes the keys of the model
+// annotations that record each relation severed by a filtered export.
+// The relation's ID follows the prefix, and the annotation's value is
+// the relation's key.
+const severedRelationAnnotationPrefix = "severed-relation-"
+
+// SeveredRelation describes a relation left out of an export filtered
+// to a subset of the model's applications, because one of its
+// applications was exported and the other was not.
+type SeveredRelation struct {
+	// Id is the ID of the relation.
+	Id int
+
+	// Key is the key of the relation, for example
+	// "wordpress:db mysql:server".
+	Key string
+
+	// Endpoint is the endpoint of the exported application.
+	Endpoint string
+
+	// RemoteEndpoint is the endpoint of the application
+	// that was left out.
+	RemoteEndpoint string
+}
+
+// String describes what breaks when the relation is severed.
+func (r SeveredRelation) String() string {
+	return fmt.Sprintf("%s loses relation %d to %s", r.Endpoint, r.Id, r.RemoteEndpoint)
+}
+
+// exportFilter restricts an export to a subset of the model's
+// applications, along with the units, machines and storage they use,
+// the remote applications they are related to, and the relations
+// between them. A nil filter includes everything.
+type exportFilter struct {
+	applications set.Strings
+	units        set.Strings
+	machines     set.Strings
+	storage      set.Strings
+	severed      []SeveredRelation
+}
+
+// newExportFilter returns a filter including the named applications,
+// which must exist in the model.
+func newExportFilter(st *State, applications []string) (*exportFilter, error) {
+	f := &exportFilter{
+		applications: set.NewStrings(),
+		units:        set.NewStrings(),
+		machines:     set.NewStrings(),
+		storage:      set.NewStrings(),
+	}
+	sb, err := NewStorageBackend(st)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, name := range applications {
+		app, err := st.Application(name)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		f.applications.Add(app.Name())
+		units, err := app.AllUnits()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		for _, unit := range units {
+			f.units.Add(unit.Name())
+			attachments, err := sb.UnitStorageAttachments(unit.UnitTag())
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			for _, attachment := range attachments {
+				f.storage.Add(attachment.StorageInstance().Id())
+			}
+			machineId, err := unit.AssignedMachineId()
+			if errors.IsNotAssigned(err) {
+				continue
+			} else if err != nil {
+				return nil, errors.Trace(err)
+			}
+			// Containers are exported nested in their hosts.
+			for id := machineId; id != ""; id = ParentId(id) {
+				f.machines.Add(id)
+			}
+		}
+	}
+	if err := f.addRelated(st); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return f, nil
+}
+
+// addRelated adds the remote applications related to the included
+// applications, and records the relations to local applications that
+// are not included as severed.
+func (f *exportFilter) addRelated(st *State) error {
+	remoteApps, err := st.AllRemoteApplications()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	remote := set.NewStrings()
+	for _, app := range remoteApps {
+		remote.Add(app.Name())
+	}
+	included := set.NewStrings(f.applications.Values()...)
+
+	rels, err := st.AllRelations()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, rel := range rels {
+		eps := rel.Endpoints()
+		for _, ep := range eps {
+			if !included.Contains(ep.ApplicationName) {
+				continue
+			}
+			for _, other := range eps {
+				switch {
+				case included.Contains(other.ApplicationName):
+				case remote.Contains(other.ApplicationName):
+					f.applications.Add(other.ApplicationName)
+				default:
+					f.severed = append(f.severed, SeveredRelation{
+						Id:             rel.Id(),
+						Key:            rel.String(),
+						Endpoint:       ep.String(),
+						RemoteEndpoint: other.String(),
+					})
+				}
+			}
+		}
+	}
+	sort.Slice(f.severed, func(i, j int) bool {
+		return f.severed[i].Id < f.severed[j].Id
+	})
+	return nil
+}
+
+// includesApplication returns true if the local or
+// remote application with the given name is included.
+func (f *exportFilter) includesApplication(name string) bool {
+	return f == nil || f.applications.Contains(name)
+}
+
+// includesMachine returns true if the machine is included.
+func (f *exportFilter) includesMachine(id string) bool {
+	return f == nil || f.machines.Contains(id)
+}
+
+// includesReceiver returns true if the unit or
+// machine receiving an action is included.
+func (f *exportFilter) includesReceiver(receiver string) bool {
+	if f == nil {
+		return true
+	}
+	if names.IsValidUnit(receiver) {
+		return f.units.Contains(receiver)
+	}
+	return f.machines.Contains(receiver)
+}
+
+// includesRelation returns true if all of the
+// relation's applications are included.
+func (f *exportFilter) includesRelation(rel *Relation) bool {
+	if f == nil {
+		return true
+	}
+	for _, ep := range rel.Endpoints() {
+		if !f.applications.Contains(ep.ApplicationName) {
+			return false
+		}
+	}
+	return true
+}
+
+// includesStorage returns true if the storage instance is
+// attached to an included unit, or the volume or filesystem
+// backing it is attached to one of the included hosts.
+func (f *exportFilter) includesStorage(storageId string, hosts ...string) bool {
+	if f == nil || f.storage.Contains(storageId) {
+		return true
+	}
+	for _, host := range hosts {
+		if f.machines.Contains(host) || f.units.Contains(host) {
+			return true
+		}
+	}
+	return false
+}
+
+// severedAnnotations returns the model annotations
+// recording the relations severed by the filter.
+func (f *exportFilter) severedAnnotations() map[string]string {
+	if f == nil || len(f.severed) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(f.severed))
+	for _, rel := range f.severed {
+		result[severedRelationAnnotationPrefix+strconv.Itoa(rel.Id)] = rel.Key
+	}
+	return result
+}
+
+// severedRelations returns the relations severed by the filter.
+func (f *exportFilter) severedRelations() []SeveredRelation {
+	if f == nil {
+		return nil
+	}
+	return f.severed
+}
+
+// volumeHosts returns the hosts the volume is attached to.
+func volumeHosts(attachments []volumeAttachmentDoc) []string {
+	hosts := make([]string, len(attachments))
+	for i, doc := range attachments {
+		hosts[i] = doc.Host
+	}
+	return hosts
+}
+
+// filesystemHosts returns the hosts the filesystem is attached to.
+func filesystemHosts(attachments []filesystemAttachmentDoc) []string {
+	hosts := make([]string, len(attachments))
+	for i, doc := range attachments {
+		hosts[i] = doc.Host
+	}
+	return hosts
+}
@@ -5,6 +5,7 @@ package state_test
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
@@ -225,6 +226,212 @@ func (s *MigrationExportSuite) TestModelInfo(c *gc.C) {
 	})
 }
 
+func (s *MigrationExportSuite) TestModelAgentConfigOverrides(c *gc.C) {
+	err := s.Model.UpdateModelConfig(map[string]interface{}{
+		"logging-config":  "<root>=DEBUG",
+		"apt-http-proxy":  "http://apt-proxy",
+		"juju-http-proxy": "http://juju-proxy",
+	}, nil)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Models created before an attribute was introduced have no
+	// value for it, which the target controller must not default.
+	modelSettings, err := s.State.ReadSettings(state.SettingsC, state.ModelGlobalKey)
+	c.Assert(err, jc.ErrorIsNil)
+	modelSettings.Delete("snap-store-proxy-url")
+	modelSettings.Delete("juju-no-proxy")
+	_, err = modelSettings.Write()
+	c.Assert(err, jc.ErrorIsNil)
+
+	model, err := s.State.Export()
+	c.Assert(err, jc.ErrorIsNil)
+
+	modelCfg := model.Config()
+	c.Check(modelCfg["logging-config"], gc.Equals, "<root>=DEBUG")
+	c.Check(modelCfg["apt-http-proxy"], gc.Equals, "http://apt-proxy")
+	c.Check(modelCfg["juju-http-proxy"], gc.Equals, "http://juju-proxy")
+	c.Check(modelCfg["snap-store-proxy-url"], gc.Equals, "")
+	c.Check(modelCfg["juju-no-proxy"], gc.Equals, "")
+}
+
+func (s *MigrationExportSuite) TestModelLogForwardingOverrides(c *gc.C) {
+	err := s.Model.UpdateModelConfig(map[string]interface{}{
+		"syslog-host": "syslog.example.com:6514",
+	}, nil)
+	c.Assert(err, jc.ErrorIsNil)
+
+	modelSettings, err := s.State.ReadSettings(state.SettingsC, state.ModelGlobalKey)
+	c.Assert(err, jc.ErrorIsNil)
+	modelSettings.Delete("logforward-enabled")
+	modelSettings.Delete("syslog-ca-cert")
+	_, err = modelSettings.Write()
+	c.Assert(err, jc.ErrorIsNil)
+
+	model, err := s.State.Export()
+	c.Assert(err, jc.ErrorIsNil)
+
+	modelCfg := model.Config()
+	c.Check(modelCfg["syslog-host"], gc.Equals, "syslog.example.com:6514")
+	c.Check(modelCfg["logforward-enabled"], gc.Equals, false)
+	c.Check(modelCfg["syslog-ca-cert"], gc.Equals, "")
+}
+
+func (s *MigrationExportSuite) TestControllerSection(c *gc.C) {
+	ctx := context.Background()
+	err := s.State.AutocertCache().Put(ctx, "example.com", []byte("cert and key"))
+	c.Assert(err, jc.ErrorIsNil)
+
+	key := bytes.Repeat([]byte{1}, state.ControllerSectionKeySize)
+	model, err := s.State.ExportPartial(state.ExportConfig{ControllerSectionKey: key})
+	c.Assert(err, jc.ErrorIsNil)
+
+	for _, value := range model.Annotations() {
+		c.Check(value, gc.Not(jc.Contains), "cert and key")
+	}
+	section, err := state.ControllerSectionOf(model, key)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(section.ControllerUUID, gc.Equals, s.State.ControllerUUID())
+	c.Check(section.Config["controller-uuid"], gc.Equals, s.State.ControllerUUID())
+	c.Check(section.CACert, gc.Not(gc.Equals), "")
+	c.Check(section.CACertFingerprint, gc.HasLen, 64)
+	c.Check(section.AutocertCache, jc.DeepEquals, []state.AutocertCacheEntry{{
+		Name: "example.com",
+		Data: []byte("cert and key"),
+	}})
+
+	_, err = state.ControllerSectionOf(model, bytes.Repeat([]byte{2}, state.ControllerSectionKeySize))
+	c.Check(err, gc.ErrorMatches, "cannot decrypt controller section: wrong key, or section modified")
+
+	err = s.State.AutocertCache().Delete(ctx, "example.com")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.RestoreAutocertCache(section)
+	c.Assert(err, jc.ErrorIsNil)
+	data, err := s.State.AutocertCache().Get(ctx, "example.com")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(string(data), gc.Equals, "cert and key")
+}
+
+func (s *MigrationExportSuite) TestControllerSectionNotRequested(c *gc.C) {
+	model, err := s.State.Export()
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = state.ControllerSectionOf(model, bytes.Repeat([]byte{1}, state.ControllerSectionKeySize))
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *MigrationExportSuite) TestControllerSectionHostedModel(c *gc.C) {
+	st := s.Factory.MakeModel(c, nil)
+	defer st.Close()
+
+	key := bytes.Repeat([]byte{1}, state.ControllerSectionKeySize)
+	_, err := st.ExportPartial(state.ExportConfig{ControllerSectionKey: key})
+	c.Check(err, gc.ErrorMatches, "controller section in export of non-controller model not valid")
+}
+
+func (s *MigrationExportSuite) TestPermissionsSection(c *gc.C) {
+	bob := names.NewUserTag("bob@external")
+	s.Factory.MakeModelUser(c, &factory.ModelUserParams{User: bob.Id()})
+	_, err := s.State.AddControllerUser(state.UserAccessSpec{
+		User: bob, CreatedBy: s.Owner, Access: permission.AddModelAccess,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	cloud := s.Model.CloudName()
+	err = s.State.CreateCloudAccess(cloud, bob, permission.AddModelAccess)
+	c.Assert(err, jc.ErrorIsNil)
+
+	model, err := s.State.ExportPartial(state.ExportConfig{Permissions: state.PermissionConflictKeep})
+	c.Assert(err, jc.ErrorIsNil)
+	section, err := state.PermissionsSectionOf(model)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(section.Policy, gc.Equals, state.PermissionConflictKeep)
+	c.Check(section.Cloud, gc.Equals, cloud)
+	c.Check(section.Controller, jc.SameContents, []state.UserPermission{
+		{User: s.Owner.Id(), Access: permission.SuperuserAccess},
+		{User: bob.Id(), Access: permission.AddModelAccess},
+	})
+	c.Check(section.CloudAccess, jc.SameContents, []state.UserPermission{
+		{User: s.Owner.Id(), Access: permission.AdminAccess},
+		{User: bob.Id(), Access: permission.AddModelAccess},
+	})
+
+	// Access already held is kept, and missing access is granted.
+	_, err = s.State.SetUserAccess(bob, s.State.ControllerTag(), permission.LoginAccess)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.RemoveCloudAccess(cloud, bob)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.ApplyPermissionsSection(section, s.Owner)
+	c.Assert(err, jc.ErrorIsNil)
+	access, err := s.State.UserAccess(bob, s.State.ControllerTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(access.Access, gc.Equals, permission.LoginAccess)
+	cloudAccess, err := s.State.GetCloudAccess(cloud, bob)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(cloudAccess, gc.Equals, permission.AddModelAccess)
+
+	section.Policy = state.PermissionConflictGreater
+	err = s.State.ApplyPermissionsSection(section, s.Owner)
+	c.Assert(err, jc.ErrorIsNil)
+	access, err = s.State.UserAccess(bob, s.State.ControllerTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(access.Access, gc.Equals, permission.AddModelAccess)
+}
+
+func (s *MigrationExportSuite) TestPermissionsSectionNeverGrantsSuperuser(c *gc.C) {
+	bob := names.NewUserTag("bob@external")
+	_, err := s.State.AddControllerUser(state.UserAccessSpec{
+		User: bob, CreatedBy: s.Owner, Access: permission.LoginAccess,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	carol := s.Factory.MakeUser(c, &factory.UserParams{Name: "carol"}).UserTag()
+	_, err = s.State.SetUserAccess(carol, s.State.ControllerTag(), permission.LoginAccess)
+	c.Assert(err, jc.ErrorIsNil)
+
+	section := &state.PermissionsSection{
+		Policy: state.PermissionConflictReplace,
+		Controller: []state.UserPermission{
+			{User: bob.Id(), Access: permission.SuperuserAccess},
+			{User: carol.Id(), Access: permission.SuperuserAccess},
+			{User: "dave@external", Access: permission.SuperuserAccess},
+		},
+	}
+	err = s.State.ApplyPermissionsSection(section, s.Owner)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// External users get no more than login access, and local users of
+	// the source controller are not taken to be those of this one.
+	access, err := s.State.UserAccess(bob, s.State.ControllerTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(access.Access, gc.Equals, permission.LoginAccess)
+	access, err = s.State.UserAccess(names.NewUserTag("dave@external"), s.State.ControllerTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(access.Access, gc.Equals, permission.LoginAccess)
+	access, err = s.State.UserAccess(carol, s.State.ControllerTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(access.Access, gc.Equals, permission.LoginAccess)
+
+	// The capped access never lowers access already held.
+	_, err = s.State.SetUserAccess(bob, s.State.ControllerTag(), permission.SuperuserAccess)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.ApplyPermissionsSection(section, s.Owner)
+	c.Assert(err, jc.ErrorIsNil)
+	access, err = s.State.UserAccess(bob, s.State.ControllerTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(access.Access, gc.Equals, permission.SuperuserAccess)
+}
+
+func (s *MigrationExportSuite) TestPermissionsSectionNotRequested(c *gc.C) {
+	model, err := s.State.Export()
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = state.PermissionsSectionOf(model)
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *MigrationExportSuite) TestControllerSectionBadKey(c *gc.C) {
+	_, err := s.State.ExportPartial(state.ExportConfig{ControllerSectionKey: []byte("short")})
+	c.Check(err, gc.ErrorMatches, "controller section key of 5 bytes not valid")
+}
+
 func (s *MigrationExportSuite) TestModelUsers(c *gc.C) {
 	// Make sure we have some last connection times for the admin user,
 	// and create a few other users.
@@ -1094,6 +1301,78 @@ func (s *MigrationExportSuite) TestRelations(c *gc.C) {
 	c.Check(status.Value(), gc.Equals, "joining")
 }
 
+func (s *MigrationExportSuite) TestExportApplicationsSeversRelations(c *gc.C) {
+	wordpress := state.AddTestingApplication(c, s.State, "wordpress", state.AddTestingCharm(c, s.State, "wordpress"))
+	mysql := state.AddTestingApplication(c, s.State, "mysql", state.AddTestingCharm(c, s.State, "mysql"))
+	eps, err := s.State.InferEndpoints("mysql", "wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	rel, err := s.State.AddRelation(eps...)
+	c.Assert(err, jc.ErrorIsNil)
+	wordpress_0 := s.Factory.MakeUnit(c, &factory.UnitParams{Application: wordpress})
+	s.Factory.MakeUnit(c, &factory.UnitParams{Application: mysql})
+	machineId, err := wordpress_0.AssignedMachineId()
+	c.Assert(err, jc.ErrorIsNil)
+
+	model, severed, err := s.State.ExportApplications(state.ExportConfig{
+		Applications: []string{"wordpress"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	apps := model.Applications()
+	c.Assert(apps, gc.HasLen, 1)
+	c.Check(apps[0].Name(), gc.Equals, "wordpress")
+	c.Check(apps[0].Units(), gc.HasLen, 1)
+	machines := model.Machines()
+	c.Assert(machines, gc.HasLen, 1)
+	c.Check(machines[0].Id(), gc.Equals, machineId)
+	c.Check(model.Relations(), gc.HasLen, 0)
+
+	c.Check(severed, jc.DeepEquals, []state.SeveredRelation{{
+		Id:             rel.Id(),
+		Key:            rel.String(),
+		Endpoint:       "wordpress:db",
+		RemoteEndpoint: "mysql:server",
+	}})
+	c.Check(severed[0].String(), gc.Equals, fmt.Sprintf("wordpress:db loses relation %d to mysql:server", rel.Id()))
+	c.Check(model.Annotations(), jc.DeepEquals, map[string]string{
+		fmt.Sprintf("severed-relation-%d", rel.Id()): rel.String(),
+	})
+}
+
+func (s *MigrationExportSuite) TestExportApplicationsKeepsRelationsBetweenThem(c *gc.C) {
+	wordpress := state.AddTestingApplication(c, s.State, "wordpress", state.AddTestingCharm(c, s.State, "wordpress"))
+	mysql := state.AddTestingApplication(c, s.State, "mysql", state.AddTestingCharm(c, s.State, "mysql"))
+	state.AddTestingApplication(c, s.State, "varnish", state.AddTestingCharm(c, s.State, "varnish"))
+	eps, err := s.State.InferEndpoints("mysql", "wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.State.AddRelation(eps...)
+	c.Assert(err, jc.ErrorIsNil)
+	s.Factory.MakeUnit(c, &factory.UnitParams{Application: wordpress})
+	s.Factory.MakeUnit(c, &factory.UnitParams{Application: mysql})
+
+	model, severed, err := s.State.ExportApplications(state.ExportConfig{
+		Applications: []string{"wordpress", "mysql"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(model.Applications(), gc.HasLen, 2)
+	c.Check(model.Machines(), gc.HasLen, 2)
+	c.Check(model.Relations(), gc.HasLen, 1)
+	c.Check(severed, gc.HasLen, 0)
+}
+
+func (s *MigrationExportSuite) TestExportApplicationsUnknownApplication(c *gc.C) {
+	_, _, err := s.State.ExportApplications(state.ExportConfig{
+		Applications: []string{"wordpress"},
+	})
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	c.Assert(err, gc.ErrorMatches, `filtering applications: application "wordpress" not found`)
+}
+
+func (s *MigrationExportSuite) TestExportApplicationsWithoutApplications(c *gc.C) {
+	_, _, err := s.State.ExportApplications(state.ExportConfig{})
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
 func (s *MigrationExportSuite) TestSubordinateRelations(c *gc.C) {
 	wordpress := state.AddTestingApplication(c, s.State, "wordpress", state.AddTestingCharm(c, s.State, "wordpress"))
 	mysql := state.AddTestingApplication(c, s.State, "mysql", state.AddTestingCharm(c, s.State, "mysql"))
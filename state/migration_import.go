@@ -22,6 +22,7 @@ import (
 	"github.com/juju/juju/cloud"
 	"github.com/juju/juju/core/constraints"
 	"github.com/juju/juju/core/instance"
+	coremigration "github.com/juju/juju/core/migration"
 	"github.com/juju/juju/core/network"
 	"github.com/juju/juju/core/permission"
 	"github.com/juju/juju/core/status"
@@ -49,6 +50,9 @@ func (ctrl *Controller) Import(model description.Model) (_ *Model, _ *State, err
 		// We have an existing matching model.
 		return nil, nil, errors.AlreadyExistsf("model %s", modelUUID)
 	}
+	if err := st.DeferMigrationIfUpgrading(modelUUID, MigrationIncoming); err != nil {
+		return nil, nil, errors.Trace(err)
+	}
 
 	// Unfortunately a version was released that exports v4 models
 	// with the Type field blank. Treat this as IAAS.
@@ -132,12 +136,20 @@ func (ctrl *Controller) Import(model description.Model) (_ *Model, _ *State, err
 		return nil, nil, errors.Trace(err)
 	}
 
+	// Record how many entities are to be imported, so that the
+	// progress of the import can be reported while it runs.
+	progress, err := newImportProgress(newSt, model)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+
 	// I would have loved to use import, but that is a reserved word.
 	restore := importer{
-		st:      newSt,
-		dbModel: dbModel,
-		model:   model,
-		logger:  logger,
+		st:       newSt,
+		dbModel:  dbModel,
+		model:    model,
+		logger:   logger,
+		progress: progress,
 	}
 	if err := restore.sequences(); err != nil {
 		return nil, nil, errors.Annotate(err, "sequences")
@@ -189,6 +201,9 @@ func (ctrl *Controller) Import(model description.Model) (_ *Model, _ *State, err
 	if err := restore.remoteEntities(); err != nil {
 		return nil, nil, errors.Annotate(err, "remoteentitites")
 	}
+	if err := restore.offerConnections(); err != nil {
+		return nil, nil, errors.Annotate(err, "offerconnections")
+	}
 	if err := restore.externalControllers(); err != nil {
 		return nil, nil, errors.Annotate(err, "externalcontrollers")
 	}
@@ -263,6 +278,9 @@ type importer struct {
 	dbModel *Model
 	model   description.Model
 	logger  loggo.Logger
+	// progress records the number of entities of each kind imported
+	// so far. It is nil if the progress of the import isn't reported.
+	progress *importProgress
 	// applicationUnits is populated at the end of loading the applications, and is a
 	// map of application name to the units of that application.
 	applicationUnits map[string]map[string]*Unit
@@ -275,7 +293,17 @@ func (i *importer) modelExtras() error {
 		}
 	}
 
-	if annotations := i.model.Annotations(); len(annotations) > 0 {
+	// The controller and permissions sections are only read from the
+	// exported model, as they describe the source controller; they are
+	// never stored.
+	annotations := i.model.Annotations()
+	for _, key := range []string{controllerSectionAnnotation, permissionsSectionAnnotation} {
+		if _, ok := annotations[key]; ok {
+			annotations = mergeAnnotations(annotations, nil)
+			delete(annotations, key)
+		}
+	}
+	if len(annotations) > 0 {
 		if err := i.dbModel.SetAnnotations(i.dbModel, annotations); err != nil {
 			return errors.Trace(err)
 		}
@@ -485,6 +513,9 @@ func (i *importer) machine(m description.Machine) error {
 	if err := i.importMachineBlockDevices(machine, m); err != nil {
 		return errors.Trace(err)
 	}
+	if err := i.progress.imported(coremigration.ImportMachines, 1); err != nil {
+		return errors.Trace(err)
+	}
 
 	// Now that this machine exists in the database, process each of the
 	// containers in this machine.
@@ -752,6 +783,9 @@ func (i *importer) applications() error {
 			i.logger.Errorf("error importing application %s: %s", s.Name(), err)
 			return errors.Annotate(err, s.Name())
 		}
+		if err := i.progress.imported(coremigration.ImportApplications, 1); err != nil {
+			return errors.Trace(err)
+		}
 	}
 
 	if err := i.loadUnits(); err != nil {
@@ -898,6 +932,9 @@ func (i *importer) application(a description.Application) error {
 		if err := i.unit(a, unit); err != nil {
 			return errors.Trace(err)
 		}
+		if err := i.progress.imported(coremigration.ImportUnits, 1); err != nil {
+			return errors.Trace(err)
+		}
 	}
 
 	if err := i.applicationOffers(a); err != nil {
@@ -1312,6 +1349,12 @@ func (i *importer) remoteApplications() error {
 	if err := migration.Run(); err != nil {
 		return errors.Trace(err)
 	}
+	// Remote applications are imported together, so they are
+	// all reported as imported at once.
+	count := len(i.model.RemoteApplications())
+	if err := i.progress.imported(coremigration.ImportRemoteApplications, count); err != nil {
+		return errors.Trace(err)
+	}
 	i.logger.Debugf("importing remote applications succeeded")
 	return nil
 }
@@ -1362,7 +1405,10 @@ func (i *importer) makeRemoteApplicationDoc(app description.RemoteApplication) *
 			Name:      ep.Name(),
 			Role:      charm.RelationRole(ep.Role()),
 			Interface: ep.Interface(),
-			// TODO: Role, Scope
+			// The scope is not exported, but cross model
+			// relations only support the global scope.
+			Scope: charm.ScopeGlobal,
+			// TODO: Limit
 		}
 	}
 	doc.Endpoints = eps
@@ -1400,6 +1446,9 @@ func (i *importer) relations() error {
 			i.logger.Errorf("error importing relation %s: %s", r.Key(), err)
 			return errors.Annotate(err, r.Key())
 		}
+		if err := i.progress.imported(coremigration.ImportRelations, 1); err != nil {
+			return errors.Trace(err)
+		}
 	}
 
 	i.logger.Debugf("importing relations succeeded")
@@ -1520,6 +1569,26 @@ func (i *importer) remoteEntities() error {
 	return nil
 }
 
+func (i *importer) offerConnections() error {
+	i.logger.Debugf("importing offer connections")
+	migration := &ImportStateMigration{
+		src: i.model,
+		dst: i.st.db(),
+	}
+	migration.Add(func() error {
+		m := ImportOfferConnections{}
+		return m.Execute(stateModelNamspaceShim{
+			Model: migration.src,
+			st:    i.st,
+		}, migration.dst)
+	})
+	if err := migration.Run(); err != nil {
+		return errors.Trace(err)
+	}
+	i.logger.Debugf("importing offer connections succeeded")
+	return nil
+}
+
 func (i *importer) relationNetworks() error {
 	i.logger.Debugf("importing relation networks")
 	migration := &ImportStateMigration{
@@ -1996,6 +2065,9 @@ func (i *importer) storageInstances() error {
 			i.logger.Errorf("error importing storage %s: %s", storage.Tag(), err)
 			return errors.Trace(err)
 		}
+		if err := i.progress.imported(coremigration.ImportStorage, 1); err != nil {
+			return errors.Trace(err)
+		}
 	}
 	i.logger.Debugf("importing storage instances succeeded")
 	return nil
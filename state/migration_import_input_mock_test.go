@@ -508,3 +508,54 @@ func (mr *MockFirewallRulesInputMockRecorder) FirewallRules() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FirewallRules", reflect.TypeOf((*MockFirewallRulesInput)(nil).FirewallRules))
 }
+
+// MockOfferConnectionsInput is a mock of OfferConnectionsInput interface
+type MockOfferConnectionsInput struct {
+	ctrl     *gomock.Controller
+	recorder *MockOfferConnectionsInputMockRecorder
+}
+
+// MockOfferConnectionsInputMockRecorder is the mock recorder for MockOfferConnectionsInput
+type MockOfferConnectionsInputMockRecorder struct {
+	mock *MockOfferConnectionsInput
+}
+
+// NewMockOfferConnectionsInput creates a new mock instance
+func NewMockOfferConnectionsInput(ctrl *gomock.Controller) *MockOfferConnectionsInput {
+	mock := &MockOfferConnectionsInput{ctrl: ctrl}
+	mock.recorder = &MockOfferConnectionsInputMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockOfferConnectionsInput) EXPECT() *MockOfferConnectionsInputMockRecorder {
+	return m.recorder
+}
+
+// DocID mocks base method
+func (m *MockOfferConnectionsInput) DocID(arg0 string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DocID", arg0)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// DocID indicates an expected call of DocID
+func (mr *MockOfferConnectionsInputMockRecorder) DocID(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DocID", reflect.TypeOf((*MockOfferConnectionsInput)(nil).DocID), arg0)
+}
+
+// OfferConnections mocks base method
+func (m *MockOfferConnectionsInput) OfferConnections() []description.OfferConnection {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OfferConnections")
+	ret0, _ := ret[0].([]description.OfferConnection)
+	return ret0
+}
+
+// OfferConnections indicates an expected call of OfferConnections
+func (mr *MockOfferConnectionsInputMockRecorder) OfferConnections() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OfferConnections", reflect.TypeOf((*MockOfferConnectionsInput)(nil).OfferConnections))
+}
@@ -0,0 +1,171 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+
+	"github.com/juju/description"
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/core/migration"
+)
+
+// importProgressKey is the key of the document recording the progress
+// of the import of a migrated model.
+const importProgressKey = "import"
+
+// importProgressBatchSize is the number of entities of a kind imported
+// between writes of the import progress.
+const importProgressBatchSize = 50
+
+// importProgressDoc records how many of the entities of each kind
+// have been imported into a migrated model, so that the progress of
+// the import can be reported while it runs.
+type importProgressDoc struct {
+	DocID     string              `bson:"_id"`
+	ModelUUID string              `bson:"model-uuid"`
+	Entities  []entityProgressDoc `bson:"entities"`
+}
+
+// entityProgressDoc records the progress of the
+// import of the entities of a kind.
+type entityProgressDoc struct {
+	Kind     string `bson:"kind"`
+	Imported int    `bson:"imported"`
+	Total    int    `bson:"total"`
+}
+
+// ImportProgress returns how many of the entities of each kind have
+// been imported into the model, while it is being migrated to this
+// controller. A NotFound error is returned if the import of the
+// model has not started, or the model was not migrated.
+func (m *Model) ImportProgress() ([]migration.EntityProgress, error) {
+	coll, closer := m.st.db().GetCollection(migrationsImportProgressC)
+	defer closer()
+
+	var doc importProgressDoc
+	if err := coll.FindId(importProgressKey).One(&doc); err == mgo.ErrNotFound {
+		return nil, errors.NotFoundf("import progress for model %q", m.UUID())
+	} else if err != nil {
+		return nil, errors.Annotate(err, "reading import progress")
+	}
+	return entityProgressFromDocs(doc.Entities), nil
+}
+
+func entityProgressFromDocs(docs []entityProgressDoc) []migration.EntityProgress {
+	result := make([]migration.EntityProgress, len(docs))
+	for i, doc := range docs {
+		result[i] = migration.EntityProgress{
+			Kind:     doc.Kind,
+			Imported: doc.Imported,
+			Total:    doc.Total,
+		}
+	}
+	return result
+}
+
+func entityProgressToDocs(progress []migration.EntityProgress) []entityProgressDoc {
+	result := make([]entityProgressDoc, len(progress))
+	for i, entity := range progress {
+		result[i] = entityProgressDoc{
+			Kind:     entity.Kind,
+			Imported: entity.Imported,
+			Total:    entity.Total,
+		}
+	}
+	return result
+}
+
+// importProgress tracks the progress of the import of a model,
+// writing it to the database as batches of entities are imported.
+type importProgress struct {
+	st       *State
+	entities []entityProgressDoc
+	index    map[string]int
+}
+
+// newImportProgress records that none of the entities of the
+// model have yet been imported, and returns the tracker of the
+// progress of their import.
+func newImportProgress(st *State, model description.Model) (*importProgress, error) {
+	totals := []entityProgressDoc{
+		{Kind: migration.ImportMachines, Total: countMachines(model.Machines())},
+		{Kind: migration.ImportApplications, Total: len(model.Applications())},
+		{Kind: migration.ImportUnits, Total: countUnits(model.Applications())},
+		{Kind: migration.ImportRemoteApplications, Total: len(model.RemoteApplications())},
+		{Kind: migration.ImportRelations, Total: len(model.Relations())},
+		{Kind: migration.ImportStorage, Total: len(model.Storages())},
+	}
+	p := &importProgress{
+		st:       st,
+		entities: totals,
+		index:    make(map[string]int),
+	}
+	for i, entity := range totals {
+		p.index[entity.Kind] = i
+	}
+	ops := []txn.Op{{
+		C:      migrationsImportProgressC,
+		Id:     importProgressKey,
+		Assert: txn.DocMissing,
+		Insert: &importProgressDoc{
+			DocID:     st.docID(importProgressKey),
+			ModelUUID: st.ModelUUID(),
+			Entities:  totals,
+		},
+	}}
+	if err := st.db().RunTransaction(ops); err != nil {
+		return nil, errors.Annotate(err, "recording import progress")
+	}
+	return p, nil
+}
+
+// imported records that count more entities of the given kind have
+// been imported. The progress is written when a batch of entities
+// has been imported, and once all of them have.
+func (p *importProgress) imported(kind string, count int) error {
+	if p == nil {
+		// The import of the model is not being reported.
+		return nil
+	}
+	i, ok := p.index[kind]
+	if !ok {
+		return errors.NotValidf("entity kind %q", kind)
+	}
+	entity := &p.entities[i]
+	before := entity.Imported
+	entity.Imported += count
+	if entity.Imported < entity.Total && entity.Imported/importProgressBatchSize == before/importProgressBatchSize {
+		return nil
+	}
+	ops := []txn.Op{{
+		C:      migrationsImportProgressC,
+		Id:     importProgressKey,
+		Assert: txn.DocExists,
+		Update: bson.D{{"$set", bson.D{
+			{fmt.Sprintf("entities.%d.imported", i), entity.Imported},
+		}}},
+	}}
+	return errors.Annotatef(p.st.db().RunTransaction(ops), "recording import progress of %s", kind)
+}
+
+func countMachines(machines []description.Machine) int {
+	count := len(machines)
+	for _, m := range machines {
+		count += countMachines(m.Containers())
+	}
+	return count
+}
+
+func countUnits(applications []description.Application) int {
+	count := 0
+	for _, app := range applications {
+		count += len(app.Units())
+	}
+	return count
+}
@@ -3,6 +3,8 @@
 package state
 
 import (
+	"strconv"
+
 	"github.com/juju/description"
 	"github.com/juju/errors"
 	"gopkg.in/mgo.v2/txn"
@@ -371,6 +373,55 @@ func (ImportRemoteEntities) Execute(src RemoteEntitiesInput, runner TransactionR
 	return nil
 }
 
+// OfferConnectionsDescription defines an in-place usage for reading offer
+// connections.
+type OfferConnectionsDescription interface {
+	OfferConnections() []description.OfferConnection
+}
+
+// OfferConnectionsInput describes the input used for migrating offer
+// connections.
+type OfferConnectionsInput interface {
+	DocModelNamespace
+	OfferConnectionsDescription
+}
+
+// ImportOfferConnections describes a way to import offer connections from a
+// description.
+type ImportOfferConnections struct{}
+
+// Execute the import on the offer connections description, carefully
+// modelling the dependencies we have. Importing the connections made to
+// offers in the model allows cross model relations to resume once the
+// model has been migrated, without being re-established by the consumer.
+func (ImportOfferConnections) Execute(src OfferConnectionsInput, runner TransactionRunner) error {
+	offerConnections := src.OfferConnections()
+	if len(offerConnections) == 0 {
+		return nil
+	}
+	ops := make([]txn.Op, len(offerConnections))
+	for i, conn := range offerConnections {
+		docID := src.DocID(strconv.Itoa(conn.RelationID()))
+		ops[i] = txn.Op{
+			C:      offerConnectionsC,
+			Id:     docID,
+			Assert: txn.DocMissing,
+			Insert: &offerConnectionDoc{
+				DocID:           docID,
+				RelationId:      conn.RelationID(),
+				RelationKey:     conn.RelationKey(),
+				OfferUUID:       conn.OfferUUID(),
+				UserName:        conn.UserName(),
+				SourceModelUUID: conn.SourceModelUUID(),
+			},
+		}
+	}
+	if err := runner.RunTransaction(ops); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
 // RelationNetworksDescription defines an in-place usage for reading relation networks.
 type RelationNetworksDescription interface {
 	RelationNetworks() []description.RelationNetwork
@@ -382,6 +382,98 @@ func (s *MigrationImportTasksSuite) remoteEntity(ctrl *gomock.Controller, id, to
 	return entity
 }
 
+func (s *MigrationImportTasksSuite) TestImportOfferConnections(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+
+	conn0 := s.offerConnection(ctrl, 1, "wordpress:db mysql:server", "offer-uuid-1", "fred")
+	conn1 := s.offerConnection(ctrl, 4, "haproxy:backend mysql:server", "offer-uuid-1", "mary")
+
+	model := NewMockOfferConnectionsInput(ctrl)
+	model.EXPECT().OfferConnections().Return([]description.OfferConnection{conn0, conn1})
+	model.EXPECT().DocID("1").Return("uuid:1")
+	model.EXPECT().DocID("4").Return("uuid:4")
+
+	runner := NewMockTransactionRunner(ctrl)
+	runner.EXPECT().RunTransaction([]txn.Op{
+		{
+			C:      offerConnectionsC,
+			Id:     "uuid:1",
+			Assert: txn.DocMissing,
+			Insert: &offerConnectionDoc{
+				DocID:           "uuid:1",
+				RelationId:      1,
+				RelationKey:     "wordpress:db mysql:server",
+				OfferUUID:       "offer-uuid-1",
+				UserName:        "fred",
+				SourceModelUUID: "source-uuid",
+			},
+		},
+		{
+			C:      offerConnectionsC,
+			Id:     "uuid:4",
+			Assert: txn.DocMissing,
+			Insert: &offerConnectionDoc{
+				DocID:           "uuid:4",
+				RelationId:      4,
+				RelationKey:     "haproxy:backend mysql:server",
+				OfferUUID:       "offer-uuid-1",
+				UserName:        "mary",
+				SourceModelUUID: "source-uuid",
+			},
+		},
+	}).Return(nil)
+
+	m := ImportOfferConnections{}
+	err := m.Execute(model, runner)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *MigrationImportTasksSuite) TestImportOfferConnectionsWithNoConnections(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+
+	model := NewMockOfferConnectionsInput(ctrl)
+	model.EXPECT().OfferConnections().Return([]description.OfferConnection{})
+
+	runner := NewMockTransactionRunner(ctrl)
+	// No call to RunTransaction if there are no operations.
+
+	m := ImportOfferConnections{}
+	err := m.Execute(model, runner)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *MigrationImportTasksSuite) TestImportOfferConnectionsWithTransactionRunnerReturnsError(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+
+	conn0 := s.offerConnection(ctrl, 1, "wordpress:db mysql:server", "offer-uuid-1", "fred")
+
+	model := NewMockOfferConnectionsInput(ctrl)
+	model.EXPECT().OfferConnections().Return([]description.OfferConnection{conn0})
+	model.EXPECT().DocID("1").Return("uuid:1")
+
+	runner := NewMockTransactionRunner(ctrl)
+	runner.EXPECT().RunTransaction(gomock.Any()).Return(errors.New("fail"))
+
+	m := ImportOfferConnections{}
+	err := m.Execute(model, runner)
+	c.Assert(err, gc.ErrorMatches, "fail")
+}
+
+func (s *MigrationImportTasksSuite) offerConnection(
+	ctrl *gomock.Controller, relationID int, relationKey, offerUUID, userName string,
+) *MockOfferConnection {
+	conn := NewMockOfferConnection(ctrl)
+	conn.EXPECT().RelationID().Return(relationID).AnyTimes()
+	conn.EXPECT().RelationKey().Return(relationKey)
+	conn.EXPECT().OfferUUID().Return(offerUUID)
+	conn.EXPECT().UserName().Return(userName)
+	conn.EXPECT().SourceModelUUID().Return("source-uuid")
+	return conn
+}
+
 func (s *MigrationImportTasksSuite) TestImportRelationNetworks(c *gc.C) {
 	ctrl := gomock.NewController(c)
 	defer ctrl.Finish()
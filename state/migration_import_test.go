@@ -25,6 +25,7 @@ import (
 	"github.com/juju/juju/core/crossmodel"
 	"github.com/juju/juju/core/firewall"
 	"github.com/juju/juju/core/instance"
+	"github.com/juju/juju/core/migration"
 	"github.com/juju/juju/core/model"
 	"github.com/juju/juju/core/network"
 	corenetwork "github.com/juju/juju/core/network"
@@ -418,6 +419,34 @@ func (s *MigrationImportSuite) TestMachines(c *gc.C) {
 	c.Assert(*characteristics.RootDiskSource, gc.Equals, "bunyan")
 }
 
+func (s *MigrationImportSuite) TestImportProgress(c *gc.C) {
+	machine := s.Factory.MakeMachine(c, nil)
+	_ = s.Factory.MakeMachineNested(c, machine.Id(), nil)
+	app := s.Factory.MakeApplication(c, nil)
+	_ = s.Factory.MakeUnit(c, &factory.UnitParams{
+		Application: app,
+		Machine:     machine,
+	})
+
+	newModel, _ := s.importModel(c, s.State)
+
+	progress, err := newModel.ImportProgress()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(progress, jc.DeepEquals, []migration.EntityProgress{
+		{Kind: migration.ImportMachines, Imported: 2, Total: 2},
+		{Kind: migration.ImportApplications, Imported: 1, Total: 1},
+		{Kind: migration.ImportUnits, Imported: 1, Total: 1},
+		{Kind: migration.ImportRemoteApplications},
+		{Kind: migration.ImportRelations},
+		{Kind: migration.ImportStorage},
+	})
+}
+
+func (s *MigrationImportSuite) TestImportProgressNotImported(c *gc.C) {
+	_, err := s.Model.ImportProgress()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
 func (s *MigrationImportSuite) TestMachineDevices(c *gc.C) {
 	machine := s.Factory.MakeMachine(c, nil)
 	// Create two devices, first with all fields set, second just to show that
@@ -1121,6 +1150,33 @@ func (s *MigrationImportSuite) TestRemoteEntities(c *gc.C) {
 	c.Assert(token, gc.Equals, "ccc-ddd-zzz")
 }
 
+func (s *MigrationImportSuite) TestOfferConnections(c *gc.C) {
+	_, err := s.State.AddOfferConnection(state.AddOfferConnectionParams{
+		OfferUUID:       "offer-uuid",
+		RelationId:      1,
+		RelationKey:     "remote:db mysql:server",
+		SourceModelUUID: "f47ac10b-58cc-4372-a567-0e02b2c3d479",
+		Username:        "fred",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, newSt := s.importModel(c, s.State)
+
+	conns, err := newSt.AllOfferConnections()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(conns, gc.HasLen, 1)
+	conn := conns[0]
+	c.Assert(conn.OfferUUID(), gc.Equals, "offer-uuid")
+	c.Assert(conn.RelationId(), gc.Equals, 1)
+	c.Assert(conn.RelationKey(), gc.Equals, "remote:db mysql:server")
+	c.Assert(conn.SourceModelUUID(), gc.Equals, "f47ac10b-58cc-4372-a567-0e02b2c3d479")
+	c.Assert(conn.UserName(), gc.Equals, "fred")
+
+	conn, err = newSt.OfferConnectionForRelation("remote:db mysql:server")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(conn.OfferUUID(), gc.Equals, "offer-uuid")
+}
+
 func (s *MigrationImportSuite) TestRelationNetworks(c *gc.C) {
 	wordpress := s.AddTestingApplication(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
 	wordpressEP, err := wordpress.Endpoint("db")
@@ -1147,6 +1203,31 @@ func (s *MigrationImportSuite) TestRelationNetworks(c *gc.C) {
 	c.Assert(entity0.CIDRS(), gc.DeepEquals, []string{"192.168.1.0/16"})
 }
 
+func (s *MigrationImportSuite) TestRelationEgressNetworks(c *gc.C) {
+	wordpress := s.AddTestingApplication(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	wordpressEP, err := wordpress.Endpoint("db")
+	c.Assert(err, jc.ErrorIsNil)
+	mysql := s.AddTestingApplication(c, "mysql", s.AddTestingCharm(c, "mysql"))
+	mysqlEP, err := mysql.Endpoint("server")
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.State.AddRelation(wordpressEP, mysqlEP)
+	c.Assert(err, jc.ErrorIsNil)
+
+	srcRelationNetworks := state.NewRelationEgressNetworks(s.State)
+	_, err = srcRelationNetworks.Save("wordpress:db mysql:server", true, []string{"10.0.0.0/8"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, newSt := s.importModel(c, s.State)
+
+	// The direction of the networks is retained.
+	networks, err := state.NewRelationEgressNetworks(newSt).Networks("wordpress:db mysql:server")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(networks.CIDRS(), gc.DeepEquals, []string{"10.0.0.0/8"})
+
+	_, err = state.NewRelationIngressNetworks(newSt).Networks("wordpress:db mysql:server")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
 func (s *MigrationImportSuite) TestRelations(c *gc.C) {
 	wordpress := state.AddTestingApplication(c, s.State, "wordpress", state.AddTestingCharm(c, s.State, "wordpress"))
 	state.AddTestingApplication(c, s.State, "mysql", state.AddTestingCharm(c, s.State, "mysql"))
@@ -2207,6 +2288,8 @@ func (s *MigrationImportSuite) assertRemoteApplicationEndpoints(c *gc.C, expecte
 		receivedEndpoint := receivedEndpoints[k]
 		c.Assert(receivedEndpoint.Interface, gc.Equals, expectedEndpoint.Interface)
 		c.Assert(receivedEndpoint.Name, gc.Equals, expectedEndpoint.Name)
+		c.Assert(receivedEndpoint.Role, gc.Equals, expectedEndpoint.Role)
+		c.Assert(receivedEndpoint.Scope, gc.Equals, expectedEndpoint.Scope)
 	}
 }
 
@@ -115,9 +115,20 @@ func (s *MigrationSuite) TestKnownCollections(c *gc.C) {
 		// Users aren't migrated.
 		usersC,
 		userLastLoginC,
+		// Login activity is recorded by the controller.
+		userLoginActivityC,
 		// Controller users contain extra data about users therefore
 		// are not migrated either.
 		controllerUsersC,
+		// Access requests are decided by controller users.
+		accessRequestsC,
+		// Temporary access grants are controller specific, and
+		// expire independently of the model.
+		temporaryAccessC,
+		// User notifications record controller user account events.
+		userNotificationsC,
+		// Permission events are queued for the controller's webhook.
+		permissionEventsC,
 		// userenvnameC is just to provide a unique key constraint.
 		usermodelnameC,
 		// Metrics aren't migrated.
@@ -131,6 +142,15 @@ func (s *MigrationSuite) TestKnownCollections(c *gc.C) {
 		// upgradeInfoC is used to coordinate upgrades and schema migrations,
 		// and aren't needed for model migrations.
 		upgradeInfoC,
+		// schemaVersionsC records the schema migrations run by
+		// controller upgrades.
+		schemaVersionsC,
+		// gatedUpgradeStepsC records upgrade steps awaiting
+		// the controller's feature flags.
+		gatedUpgradeStepsC,
+		// contractUpgradeStepsC records upgrade steps awaiting
+		// the upgrade of the controller's agents.
+		contractUpgradeStepsC,
 		// Not exported, but the tools will possibly need to be either bundled
 		// with the representation or sent separately.
 		toolsmetadataC,
@@ -147,6 +167,7 @@ func (s *MigrationSuite) TestKnownCollections(c *gc.C) {
 		migrationsStatusC,
 		migrationsActiveC,
 		migrationsMinionSyncC,
+		migrationsImportProgressC,
 
 		// The container ref document is primarily there to keep track
 		// of a particular machine's containers. The migration format
@@ -214,6 +235,16 @@ func (s *MigrationSuite) TestKnownCollections(c *gc.C) {
 		// running within a unit. This is a new feature that is not
 		// backwards compatible with older controllers.
 		unitStatesC,
+		unitStateChunksC,
+
+		// Values quarantined from unit state documents are left
+		// for recovery by hand on the source controller.
+		unitStatesQuarantineC,
+
+		// As with unit state, charm counters are created when the
+		// charm first adjusts them, and are not supported by older
+		// controllers.
+		charmCountersC,
 	)
 
 	// THIS SET WILL BE REMOVED WHEN MIGRATIONS ARE COMPLETE
@@ -0,0 +1,263 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"encoding/json"
+
+	"github.com/juju/description"
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/core/permission"
+)
+
+const (
+	// permissionsSectionAnnotation is the model annotation holding
+	// the permissions section of an export of a model.
+	permissionsSectionAnnotation = "juju-permissions-section"
+
+	// permissionsSectionVersion is the version of the
+	// format of the permissions section.
+	permissionsSectionVersion = 1
+)
+
+// PermissionsSection holds the controller access and cloud access of the
+// users of an exported model, which do not migrate with the model as
+// they belong to the source controller. The target controller applies
+// them once the model is imported, so that its users don't lose access.
+// It is only exported when requested for the migration.
+type PermissionsSection struct {
+	Version int `json:"version"`
+
+	// Policy determines how the permissions are applied
+	// to users who already have access on the target.
+	Policy PermissionConflictPolicy `json:"policy"`
+
+	// Controller holds the users' access to the source controller.
+	Controller []UserPermission `json:"controller,omitempty"`
+
+	// Cloud is the model's cloud, and CloudAccess holds the users'
+	// access to it.
+	Cloud       string           `json:"cloud"`
+	CloudAccess []UserPermission `json:"cloud-access,omitempty"`
+}
+
+// UserPermission is a user's access to a controller or cloud.
+type UserPermission struct {
+	User   string            `json:"user"`
+	Access permission.Access `json:"access"`
+}
+
+// PermissionConflictPolicy determines how permissions from a permissions
+// section are applied to users who already have access on the target.
+type PermissionConflictPolicy string
+
+const (
+	// PermissionConflictKeep leaves existing access as it is.
+	PermissionConflictKeep PermissionConflictPolicy = "keep"
+
+	// PermissionConflictGreater raises existing access to the exported
+	// access where that is greater, and otherwise leaves it as it is.
+	PermissionConflictGreater PermissionConflictPolicy = "greater"
+
+	// PermissionConflictReplace replaces existing access with the
+	// exported access.
+	PermissionConflictReplace PermissionConflictPolicy = "replace"
+)
+
+// Validate returns an error if the policy is not one of those defined.
+func (p PermissionConflictPolicy) Validate() error {
+	switch p {
+	case PermissionConflictKeep, PermissionConflictGreater, PermissionConflictReplace:
+		return nil
+	}
+	return errors.NotValidf("permission conflict policy %q", p)
+}
+
+// permissionsSectionAnnotations returns the model annotations holding
+// the permissions section, or nil if the section was not requested.
+func (e *exporter) permissionsSectionAnnotations() (map[string]string, error) {
+	if e.cfg.Permissions == "" {
+		return nil, nil
+	}
+	if err := e.cfg.Permissions.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	section, err := e.st.permissionsSection(e.dbModel)
+	if err != nil {
+		return nil, errors.Annotate(err, "reading permissions section")
+	}
+	section.Policy = e.cfg.Permissions
+	data, err := json.Marshal(section)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	e.logger.Infof("exporting permissions section with %d controller and %d cloud grants",
+		len(section.Controller), len(section.CloudAccess))
+	return map[string]string{permissionsSectionAnnotation: string(data)}, nil
+}
+
+// permissionsSection reads the controller and cloud access of the
+// model's users from the database.
+func (st *State) permissionsSection(model *Model) (*PermissionsSection, error) {
+	users, err := model.Users()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	section := &PermissionsSection{
+		Version: permissionsSectionVersion,
+		Cloud:   model.CloudName(),
+	}
+	for _, user := range users {
+		access, err := st.UserAccess(user.UserTag, st.controllerTag)
+		if err == nil {
+			section.Controller = append(section.Controller, UserPermission{
+				User:   user.UserTag.Id(),
+				Access: access.Access,
+			})
+		} else if !errors.IsNotFound(err) {
+			return nil, errors.Trace(err)
+		}
+		cloudAccess, err := st.GetCloudAccess(section.Cloud, user.UserTag)
+		if err == nil {
+			section.CloudAccess = append(section.CloudAccess, UserPermission{
+				User:   user.UserTag.Id(),
+				Access: cloudAccess,
+			})
+		} else if !errors.IsNotFound(err) {
+			return nil, errors.Trace(err)
+		}
+	}
+	return section, nil
+}
+
+// PermissionsSectionOf returns the permissions section held in the
+// exported model. A NotFound error is returned if the model holds no
+// permissions section.
+func PermissionsSectionOf(model description.Model) (*PermissionsSection, error) {
+	data, ok := model.Annotations()[permissionsSectionAnnotation]
+	if !ok {
+		return nil, errors.NotFoundf("permissions section")
+	}
+	var section PermissionsSection
+	if err := json.Unmarshal([]byte(data), &section); err != nil {
+		return nil, errors.Annotate(err, "decoding permissions section")
+	}
+	if section.Version != permissionsSectionVersion {
+		return nil, errors.NotSupportedf("permissions section version %d", section.Version)
+	}
+	if err := section.Policy.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &section, nil
+}
+
+// ApplyPermissionsSection grants the controller and cloud access held in
+// the permissions section, on behalf of the user by, resolving access
+// already held according to the section's policy. Only the access of
+// external users is applied, as a local user of the source controller is
+// not the same user as a local user of this controller with the same
+// name. Controller superuser access is never granted by a migration:
+// it is capped at login access.
+func (st *State) ApplyPermissionsSection(section *PermissionsSection, by names.UserTag) error {
+	if err := section.Policy.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+	for _, grant := range section.Controller {
+		user, skip, err := permissionGrantUser(grant)
+		if err != nil {
+			return errors.Trace(err)
+		} else if skip {
+			continue
+		}
+		access, policy := grant.Access, section.Policy
+		if access == permission.SuperuserAccess {
+			// Access held on this controller is
+			// never lowered by the capped access.
+			logger.Infof("granting login rather than superuser access to %q", grant.User)
+			access = permission.LoginAccess
+			if policy == PermissionConflictReplace {
+				policy = PermissionConflictGreater
+			}
+		}
+		if err := st.applyControllerPermission(user, access, policy, by); err != nil {
+			return errors.Annotatef(err, "applying controller access for %q", grant.User)
+		}
+	}
+	for _, grant := range section.CloudAccess {
+		user, skip, err := permissionGrantUser(grant)
+		if err != nil {
+			return errors.Trace(err)
+		} else if skip {
+			continue
+		}
+		if err := st.applyCloudPermission(section.Cloud, user, grant.Access, section.Policy); err != nil {
+			return errors.Annotatef(err, "applying %q cloud access for %q", section.Cloud, grant.User)
+		}
+	}
+	return nil
+}
+
+// permissionGrantUser returns the user of the grant, and whether the
+// grant is skipped as the user is local to the source controller.
+func permissionGrantUser(grant UserPermission) (names.UserTag, bool, error) {
+	if !names.IsValidUser(grant.User) {
+		return names.UserTag{}, false, errors.NotValidf("user %q", grant.User)
+	}
+	user := names.NewUserTag(grant.User)
+	if user.IsLocal() {
+		logger.Infof("skipping %q access for local user %q of the source controller", grant.Access, grant.User)
+		return user, true, nil
+	}
+	return user, false, nil
+}
+
+func (st *State) applyControllerPermission(user names.UserTag, access permission.Access, policy PermissionConflictPolicy, by names.UserTag) error {
+	existing, err := st.UserAccess(user, st.controllerTag)
+	if errors.IsNotFound(err) {
+		_, err := st.AddControllerUser(UserAccessSpec{User: user, CreatedBy: by, Access: access})
+		return errors.Trace(err)
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	if !permissionConflictReplaces(policy, existing.Access, access, existing.Access.EqualOrGreaterControllerAccessThan) {
+		return nil
+	}
+	_, err = st.SetUserAccess(user, st.controllerTag, access)
+	return errors.Trace(err)
+}
+
+func (st *State) applyCloudPermission(cloud string, user names.UserTag, access permission.Access, policy PermissionConflictPolicy) error {
+	existing, err := st.GetCloudAccess(cloud, user)
+	if errors.IsNotFound(err) {
+		return errors.Trace(st.CreateCloudAccess(cloud, user, access))
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	if !permissionConflictReplaces(policy, existing, access, existing.EqualOrGreaterCloudAccessThan) {
+		return nil
+	}
+	return errors.Trace(st.UpdateCloudAccess(cloud, user, access))
+}
+
+// permissionConflictReplaces returns true if the policy replaces the
+// existing access with the exported access. existingCovers reports
+// whether the existing access is equal to or greater than another.
+func permissionConflictReplaces(
+	policy PermissionConflictPolicy,
+	existing, exported permission.Access,
+	existingCovers func(permission.Access) bool,
+) bool {
+	if existing == exported {
+		return false
+	}
+	switch policy {
+	case PermissionConflictGreater:
+		return !existingCovers(exported)
+	case PermissionConflictReplace:
+		return true
+	}
+	return false
+}
@@ -402,6 +402,11 @@ func (g *Generation) Commit(userName string) (int, error) {
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		stateOps, err := g.commitUnitStateTxnOps()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		ops = append(ops, stateOps...)
 
 		// Get the new sequence as late as we can.
 		// If assigned is empty, indicating no changes under this branch,
@@ -488,6 +493,53 @@ func (g *Generation) commitConfigTxnOps() ([]txn.Op, error) {
 	return ops, nil
 }
 
+// commitUnitStateTxnOps returns the operations that merge the charm state
+// persisted by units while tracking the branch into their master charm
+// state. Keys written under the branch replace those in the master state;
+// keys removed under the branch are retained.
+func (g *Generation) commitUnitStateTxnOps() ([]txn.Op, error) {
+	docs, err := g.unitStateDocs()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	key := branchStateKey(g.BranchName())
+	ops := make([]txn.Op, len(docs))
+	for i, doc := range docs {
+		merged := make(map[string]string, len(doc.State)+len(doc.BranchState[key]))
+		for k, v := range doc.State {
+			merged[k] = v
+		}
+		for k, v := range doc.BranchState[key] {
+			merged[k] = v
+		}
+		update := bson.D{{"$unset", bson.D{{"branch-state." + key, nil}}}}
+		if len(merged) > 0 {
+			update = append(update, bson.DocElem{"$set", bson.D{{"state", merged}}})
+		}
+		ops[i] = txn.Op{
+			C:      unitStatesC,
+			Id:     doc.DocID,
+			Assert: bson.D{{"txn-revno", doc.TxnRevno}},
+			Update: update,
+		}
+	}
+	return ops, nil
+}
+
+// unitStateDocs returns the state documents of units that
+// have persisted charm state while tracking the branch.
+func (g *Generation) unitStateDocs() ([]unitStateDoc, error) {
+	col, closer := g.st.db().GetCollection(unitStatesC)
+	defer closer()
+
+	var docs []unitStateDoc
+	field := "branch-state." + branchStateKey(g.BranchName())
+	if err := col.Find(bson.D{{field, bson.D{{"$exists", true}}}}).All(&docs); err != nil {
+		return nil, errors.Annotatef(err, "reading unit state for branch %q", g.BranchName())
+	}
+	return docs, nil
+}
+
 // Abort marks the generation as completed however no value is assigned from
 // the generation sequence.
 func (g *Generation) Abort(userName string) error {
@@ -284,6 +284,54 @@ func (s *generationSuite) TestCommitAppliesConfigDeltas(c *gc.C) {
 	c.Check(cfg, gc.DeepEquals, charm.Settings(newCfg))
 }
 
+func (s *generationSuite) TestUnitStateOnBranch(c *gc.C) {
+	s.setupTestingClock(c)
+	gen := s.setupAssignAllUnits(c)
+	unit0, unit1 := s.setupUnitState(c)
+
+	c.Assert(gen.AssignUnit("riak/0"), jc.ErrorIsNil)
+
+	// The master state is read until state is written on the branch.
+	s.assertCharmState(c, unit0, map[string]string{"a": "1", "b": "2"})
+
+	us := state.NewUnitState()
+	us.SetState(map[string]string{"a": "3", "c.d": "4"})
+	c.Assert(unit0.SetState(us), jc.ErrorIsNil)
+
+	s.assertCharmState(c, unit0, map[string]string{"a": "3", "c.d": "4"})
+	s.assertCharmState(c, unit1, map[string]string{"a": "1", "b": "2"})
+
+	values, err := unit0.StateValues("a", "b", "c.d")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(values, jc.DeepEquals, map[string]string{"a": "3", "c.d": "4"})
+	values, err = unit1.StateValues("a", "b", "c.d")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(values, jc.DeepEquals, map[string]string{"a": "1", "b": "2"})
+}
+
+func (s *generationSuite) TestCommitMergesUnitState(c *gc.C) {
+	s.setupTestingClock(c)
+	gen := s.setupAssignAllUnits(c)
+	unit0, unit1 := s.setupUnitState(c)
+
+	c.Assert(gen.AssignUnit("riak/0"), jc.ErrorIsNil)
+	us := state.NewUnitState()
+	us.SetState(map[string]string{"a": "3", "c.d": "4"})
+	c.Assert(unit0.SetState(us), jc.ErrorIsNil)
+	c.Assert(gen.Refresh(), jc.ErrorIsNil)
+
+	_, err := gen.Commit(branchCommitter)
+	c.Assert(err, jc.ErrorIsNil)
+
+	s.assertCharmState(c, unit0, map[string]string{"a": "3", "b": "2", "c.d": "4"})
+	s.assertCharmState(c, unit1, map[string]string{"a": "1", "b": "2"})
+
+	// State written after the commit is written to the master state.
+	us.SetState(map[string]string{"a": "5"})
+	c.Assert(unit0.SetState(us), jc.ErrorIsNil)
+	s.assertCharmState(c, unit0, map[string]string{"a": "5"})
+}
+
 func (s *generationSuite) TestAbortSuccess(c *gc.C) {
 	s.setupTestingClock(c)
 
@@ -524,6 +572,28 @@ options:
 	return s.addBranch(c)
 }
 
+// setupUnitState sets master charm state for the first two riak units,
+// which must already have been added.
+func (s *generationSuite) setupUnitState(c *gc.C) (*state.Unit, *state.Unit) {
+	us := state.NewUnitState()
+	us.SetState(map[string]string{"a": "1", "b": "2"})
+
+	unit0, err := s.State.Unit("riak/0")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(unit0.SetState(us), jc.ErrorIsNil)
+	unit1, err := s.State.Unit("riak/1")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(unit1.SetState(us), jc.ErrorIsNil)
+	return unit0, unit1
+}
+
+func (s *generationSuite) assertCharmState(c *gc.C, unit *state.Unit, expected map[string]string) {
+	us, err := unit.State()
+	c.Assert(err, jc.ErrorIsNil)
+	charmState, _ := us.State()
+	c.Check(charmState, gc.DeepEquals, expected)
+}
+
 func (s *generationSuite) setupAssignUnits(c *gc.C) *state.Generation {
 	var cfgYAML = `
 options:
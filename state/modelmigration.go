@@ -76,6 +76,15 @@ type ModelMigration interface {
 	// current progress of the migration.
 	SetStatusMessage(text string) error
 
+	// ImportProgress returns the progress of the transfer of the
+	// model to the target controller, as last reported.
+	ImportProgress() migration.ImportProgress
+
+	// SetImportProgress records the progress of the transfer of
+	// the model to the target controller. Unlike status messages,
+	// progress is not recorded in the migration's status history.
+	SetImportProgress(progress migration.ImportProgress) error
+
 	// SubmitMinionReport records a report from a migration minion
 	// worker about the success or failure to complete its actions for
 	// a given migration phase.
@@ -98,6 +107,11 @@ type ModelMigration interface {
 	// ModelUserAccess returns the type of access that the given tag had to
 	// the model prior to it being migrated.
 	ModelUserAccess(names.Tag) permission.Access
+
+	// Permissions returns the policy with which the target controller
+	// applies the controller and cloud access of the model's users, or
+	// the empty string if their access is not migrated.
+	Permissions() PermissionConflictPolicy
 }
 
 // MinionReports indicates the sets of agents whose migration minion
@@ -162,6 +176,11 @@ type modelMigDoc struct {
 
 	// The list of users and their access-level to the model being migrated.
 	ModelUsers []modelMigUserDoc `bson:"model-users,omitempty"`
+
+	// Permissions holds the policy with which the target controller
+	// applies the controller and cloud access of the model's users.
+	// It is empty if their access is not migrated.
+	Permissions string `bson:"permissions,omitempty"`
 }
 
 type modelMigUserDoc struct {
@@ -205,6 +224,17 @@ type modelMigStatusDoc struct {
 	// StatusMessage holds a human readable message about the
 	// migration's progress.
 	StatusMessage string `bson:"status-message"`
+
+	// ImportProgress holds the progress of the transfer of the
+	// model to the target controller, as last reported.
+	ImportProgress *modelMigImportProgressDoc `bson:"import-progress,omitempty"`
+}
+
+// modelMigImportProgressDoc records the progress of the transfer of
+// a model to the target controller of its migration.
+type modelMigImportProgressDoc struct {
+	Entities      []entityProgressDoc `bson:"entities"`
+	BinariesBytes int64               `bson:"binaries-bytes"`
 }
 
 type modelMigMinionSyncDoc struct {
@@ -270,6 +300,11 @@ func (mig *modelMigration) InitiatedBy() string {
 	return mig.doc.InitiatedBy
 }
 
+// Permissions implements ModelMigration.
+func (mig *modelMigration) Permissions() PermissionConflictPolicy {
+	return PermissionConflictPolicy(mig.doc.Permissions)
+}
+
 // TargetInfo implements ModelMigration.
 func (mig *modelMigration) TargetInfo() (*migration.TargetInfo, error) {
 	authTag, err := names.ParseUserTag(mig.doc.TargetAuthTag)
@@ -427,6 +462,37 @@ func (mig *modelMigration) SetStatusMessage(text string) error {
 	return nil
 }
 
+// ImportProgress implements ModelMigration.
+func (mig *modelMigration) ImportProgress() migration.ImportProgress {
+	doc := mig.statusDoc.ImportProgress
+	if doc == nil {
+		return migration.ImportProgress{}
+	}
+	return migration.ImportProgress{
+		Entities:      entityProgressFromDocs(doc.Entities),
+		BinariesBytes: doc.BinariesBytes,
+	}
+}
+
+// SetImportProgress implements ModelMigration.
+func (mig *modelMigration) SetImportProgress(progress migration.ImportProgress) error {
+	doc := &modelMigImportProgressDoc{
+		Entities:      entityProgressToDocs(progress.Entities),
+		BinariesBytes: progress.BinariesBytes,
+	}
+	ops := []txn.Op{{
+		C:      migrationsStatusC,
+		Id:     mig.statusDoc.Id,
+		Update: bson.M{"$set": bson.M{"import-progress": doc}},
+		Assert: txn.DocExists,
+	}}
+	if err := mig.st.db().RunTransaction(ops); err != nil {
+		return errors.Annotate(err, "failed to set migration import progress")
+	}
+	mig.statusDoc.ImportProgress = doc
+	return nil
+}
+
 // SubmitMinionReport implements ModelMigration.
 func (mig *modelMigration) SubmitMinionReport(tag names.Tag, phase migration.Phase, success bool) error {
 	globalKey, err := agentTagToGlobalKey(tag)
@@ -638,6 +704,11 @@ func (mig *modelMigration) ModelUserAccess(tag names.Tag) permission.Access {
 type MigrationSpec struct {
 	InitiatedBy names.UserTag
 	TargetInfo  migration.TargetInfo
+
+	// Permissions, if set, requests that the controller and cloud
+	// access of the model's users are migrated with the model, and
+	// applied by the target controller with this policy.
+	Permissions PermissionConflictPolicy
 }
 
 // Validate returns an error if the MigrationSpec contains bad
@@ -646,12 +717,18 @@ func (spec *MigrationSpec) Validate() error {
 	if !names.IsValidUser(spec.InitiatedBy.Id()) {
 		return errors.NotValidf("InitiatedBy")
 	}
+	if spec.Permissions != "" {
+		if err := spec.Permissions.Validate(); err != nil {
+			return errors.Trace(err)
+		}
+	}
 	return spec.TargetInfo.Validate()
 }
 
 // CreateMigration initialises state that tracks a model migration. It
 // will return an error if there is already a model migration in
-// progress.
+// progress, or one satisfying IsUpgradeInProgressError if the controller
+// database is being upgraded.
 func (st *State) CreateMigration(spec MigrationSpec) (ModelMigration, error) {
 	if st.IsController() {
 		return nil, errors.New("controllers can't be migrated")
@@ -689,6 +766,11 @@ func (st *State) CreateMigration(spec MigrationSpec) (ModelMigration, error) {
 			return nil, errors.New("already in progress")
 		}
 
+		upgradeOp, err := assertNoDatabaseUpgradeOp(st)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+
 		macsJSON, err := macaroonsToJSON(spec.TargetInfo.Macaroons)
 		if err != nil {
 			return nil, errors.Trace(err)
@@ -718,6 +800,7 @@ func (st *State) CreateMigration(spec MigrationSpec) (ModelMigration, error) {
 			TargetPassword:        spec.TargetInfo.Password,
 			TargetMacaroons:       macsJSON,
 			ModelUsers:            userDocs,
+			Permissions:           string(spec.Permissions),
 		}
 
 		statusDoc = modelMigStatusDoc{
@@ -750,11 +833,16 @@ func (st *State) CreateMigration(spec MigrationSpec) (ModelMigration, error) {
 			Update: bson.M{"$set": bson.M{
 				"migration-mode": MigrationModeExporting,
 			}},
-		}, model.assertActiveOp(),
+		}, model.assertActiveOp(), upgradeOp,
 		}...)
 		return ops, nil
 	}
-	if err := st.db().Run(buildTxn); err != nil {
+	if err := st.db().Run(buildTxn); IsUpgradeInProgressError(err) {
+		if err := recordUpgradeDeferredMigration(st, modelUUID, MigrationOutgoing); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return nil, errors.Annotate(err, "failed to create migration")
+	} else if err != nil {
 		return nil, errors.Annotate(err, "failed to create migration")
 	}
 
@@ -94,6 +94,24 @@ func (s *MigrationSuite) TestCreate(c *gc.C) {
 
 	c.Assert(model.Refresh(), jc.ErrorIsNil)
 	c.Check(model.MigrationMode(), gc.Equals, state.MigrationModeExporting)
+	c.Check(mig.Permissions(), gc.Equals, state.PermissionConflictPolicy(""))
+}
+
+func (s *MigrationSuite) TestCreateWithPermissions(c *gc.C) {
+	s.stdSpec.Permissions = state.PermissionConflictGreater
+	mig, err := s.State2.CreateMigration(s.stdSpec)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(mig.Permissions(), gc.Equals, state.PermissionConflictGreater)
+
+	mig, err = s.State2.LatestMigration()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(mig.Permissions(), gc.Equals, state.PermissionConflictGreater)
+}
+
+func (s *MigrationSuite) TestCreateWithInvalidPermissions(c *gc.C) {
+	s.stdSpec.Permissions = "overwrite"
+	_, err := s.State2.CreateMigration(s.stdSpec)
+	c.Assert(err, gc.ErrorMatches, `permission conflict policy "overwrite" not valid`)
 }
 
 func (s *MigrationSuite) TestIsMigrationActive(c *gc.C) {
@@ -525,6 +543,33 @@ func (s *MigrationSuite) TestStatusMessage(c *gc.C) {
 	c.Check(mig2.StatusMessage(), gc.Equals, "foo bar")
 }
 
+func (s *MigrationSuite) TestImportProgress(c *gc.C) {
+	mig, err := s.State2.CreateMigration(s.stdSpec)
+	c.Assert(err, jc.ErrorIsNil)
+
+	mig2, err := s.State2.LatestMigration()
+	c.Assert(err, jc.ErrorIsNil)
+
+	c.Check(mig.ImportProgress().IsZero(), jc.IsTrue)
+
+	progress := migration.ImportProgress{
+		Entities: []migration.EntityProgress{
+			{Kind: migration.ImportMachines, Imported: 2, Total: 3},
+			{Kind: migration.ImportUnits, Imported: 0, Total: 5},
+		},
+		BinariesBytes: 4096,
+	}
+	err = mig.SetImportProgress(progress)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(mig.ImportProgress(), jc.DeepEquals, progress)
+
+	c.Assert(mig2.Refresh(), jc.ErrorIsNil)
+	c.Check(mig2.ImportProgress(), jc.DeepEquals, progress)
+
+	// Progress isn't recorded in the status history.
+	c.Check(mig2.StatusMessage(), gc.Equals, "starting")
+}
+
 func (s *MigrationSuite) TestWatchForMigration(c *gc.C) {
 	// Start watching for migration.
 	w, wc := s.createMigrationWatcher(c, s.State2)
@@ -0,0 +1,309 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/juju/collections/set"
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// orphanRemovalBatchSize is the number of orphaned documents
+// removed by each transaction.
+const orphanRemovalBatchSize = 100
+
+// OrphanReport reports the documents of a collection found by an
+// orphan detector to refer to a model, unit or machine that no
+// longer exists.
+type OrphanReport struct {
+	// Detector is the name of the detector that found the documents.
+	Detector string
+
+	// Collection is the name of the collection.
+	Collection string
+
+	// Count is the number of orphaned documents found.
+	Count int
+
+	// Removed is true if the orphaned documents were removed.
+	Removed bool
+}
+
+// orphanDetector finds documents left behind when the entity they
+// belong to was removed, as may happen when the removal of the entity
+// was interrupted, or was made by an older version that did not know
+// of the collection.
+type orphanDetector struct {
+	// name identifies the detector in reports.
+	name string
+
+	// find returns the ids of the orphaned documents, keyed by the
+	// collection that holds them.
+	find func(*orphanSweep) (map[string][]interface{}, error)
+}
+
+// orphanDetectors are the detectors run, in order, by SweepOrphans.
+// Documents belonging to removed models are found first, and are not
+// reported again by the detectors that follow.
+var orphanDetectors = []orphanDetector{{
+	name: "removed-models",
+	find: (*orphanSweep).findRemovedModelDocs,
+}, {
+	name: "removed-units",
+	find: func(s *orphanSweep) (map[string][]interface{}, error) {
+		return s.findRemovedEntityDocs(unitsC, "u#", statusesC, unitStatesC, unitStateChunksC, meterStatusC)
+	},
+}, {
+	name: "superseded-relation-state",
+	find: (*orphanSweep).findSupersededRelationStateChunks,
+}, {
+	name: "removed-machines",
+	find: func(s *orphanSweep) (map[string][]interface{}, error) {
+		return s.findRemovedEntityDocs(machinesC, "m#", statusesC, constraintsC)
+	},
+}}
+
+// SweepOrphans runs each of the orphan detectors over the database,
+// reporting, for each collection, the number of documents found to
+// refer to models, units or machines that no longer exist. If remove
+// is true, the orphaned documents are also removed. Only collections
+// holding orphaned documents are reported.
+//
+// It is intended to be run while nothing else is writing to the
+// database, as during a database upgrade, as entities created while
+// the sweep is running may otherwise have their documents reported.
+func (st *State) SweepOrphans(remove bool) ([]OrphanReport, error) {
+	sweep := &orphanSweep{st: st}
+	var result []OrphanReport
+	for _, detector := range orphanDetectors {
+		found, err := detector.find(sweep)
+		if err != nil {
+			return nil, errors.Annotatef(err, "running orphan detector %q", detector.name)
+		}
+		collections := make([]string, 0, len(found))
+		for name := range found {
+			collections = append(collections, name)
+		}
+		sort.Strings(collections)
+
+		for _, name := range collections {
+			ids := found[name]
+			if remove {
+				if err := st.removeOrphans(name, ids); err != nil {
+					return nil, errors.Annotatef(err, "removing documents of %q orphaned by %s", name, detector.name)
+				}
+			}
+			result = append(result, OrphanReport{
+				Detector:   detector.name,
+				Collection: name,
+				Count:      len(ids),
+				Removed:    remove,
+			})
+		}
+	}
+	return result, nil
+}
+
+// removeOrphans removes the documents with the ids from the collection.
+// The ids are those stored in the database, so the transactions are not
+// subject to model filtering.
+func (st *State) removeOrphans(collection string, ids []interface{}) error {
+	for len(ids) > 0 {
+		batch := ids
+		if len(batch) > orphanRemovalBatchSize {
+			batch = batch[:orphanRemovalBatchSize]
+		}
+		ids = ids[len(batch):]
+
+		ops := make([]txn.Op, len(batch))
+		for i, id := range batch {
+			ops[i] = txn.Op{
+				C:      collection,
+				Id:     id,
+				Remove: true,
+			}
+		}
+		if err := st.runRawTransaction(ops); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// orphanSweep holds what is read by the orphan detectors
+// during a sweep, so that it is only read once.
+type orphanSweep struct {
+	st     *State
+	models set.Strings
+}
+
+// modelUUIDs returns the UUIDs of the models that exist.
+func (s *orphanSweep) modelUUIDs() (set.Strings, error) {
+	if s.models != nil {
+		return s.models, nil
+	}
+	ids, err := s.docIDs(modelsC, nil)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	s.models = set.NewStrings()
+	for _, id := range ids {
+		s.models.Add(id.(string))
+	}
+	return s.models, nil
+}
+
+// findRemovedModelDocs finds the documents in the model collections
+// whose model-uuid field names a model that does not exist. Raw access
+// collections are not transactional, and are not swept.
+func (s *orphanSweep) findRemovedModelDocs() (map[string][]interface{}, error) {
+	models, err := s.modelUUIDs()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	result := make(map[string][]interface{})
+	for name, info := range s.st.database.Schema() {
+		if info.global || info.rawAccess {
+			continue
+		}
+		coll, closer := s.st.db().GetRawCollection(name)
+		var uuids []string
+		err := coll.Find(nil).Distinct("model-uuid", &uuids)
+		closer()
+		if err != nil && !isMgoNamespaceNotFound(err) {
+			return nil, errors.Annotatef(err, "reading models of %q", name)
+		}
+		var removed []string
+		for _, uuid := range uuids {
+			if !models.Contains(uuid) {
+				removed = append(removed, uuid)
+			}
+		}
+		if len(removed) == 0 {
+			continue
+		}
+		ids, err := s.docIDs(name, bson.D{{"model-uuid", bson.D{{"$in", removed}}}})
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		result[name] = ids
+	}
+	return result, nil
+}
+
+// findRemovedEntityDocs finds the documents in the collections keyed by
+// the global key of an entity, with the given prefix, that is not in the
+// entities collection. The entity is identified by the part of the key
+// between the prefix and any following "#". Documents of models that do
+// not exist are left to the removed models detector.
+func (s *orphanSweep) findRemovedEntityDocs(entities, prefix string, collections ...string) (map[string][]interface{}, error) {
+	models, err := s.modelUUIDs()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	entityIDs, err := s.docIDs(entities, nil)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	existing := set.NewStrings()
+	for _, id := range entityIDs {
+		existing.Add(id.(string))
+	}
+
+	result := make(map[string][]interface{})
+	selector := bson.D{{"_id", bson.D{{"$regex", "^[^:]+:" + prefix}}}}
+	for _, name := range collections {
+		ids, err := s.docIDs(name, selector)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		for _, id := range ids {
+			docID, _ := id.(string)
+			modelUUID, localID, ok := splitDocID(docID)
+			if !ok || !models.Contains(modelUUID) {
+				continue
+			}
+			entity := strings.SplitN(strings.TrimPrefix(localID, prefix), "#", 2)[0]
+			if !existing.Contains(ensureModelUUID(modelUUID, entity)) {
+				result[name] = append(result[name], id)
+			}
+		}
+	}
+	return result, nil
+}
+
+// findSupersededRelationStateChunks finds the chunks of relation state
+// of existing units that the unit state documents do not refer to, as
+// are left behind when the removal of the chunks replaced by new
+// relation state fails. Chunks of units that no longer exist are left
+// to the removed units detector.
+func (s *orphanSweep) findSupersededRelationStateChunks() (map[string][]interface{}, error) {
+	models, err := s.modelUUIDs()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	unitIDs, err := s.docIDs(unitsC, nil)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	units := set.NewStrings()
+	for _, id := range unitIDs {
+		units.Add(id.(string))
+	}
+
+	coll, closer := s.st.db().GetRawCollection(unitStatesC)
+	defer closer()
+	var stateDocs []unitStateDoc
+	query := bson.D{{"relation-state-chunks", bson.D{{"$gt", 0}}}}
+	selector := bson.D{{"relation-state-chunks", 1}, {"relation-state-generation", 1}}
+	if err := coll.Find(query).Select(selector).All(&stateDocs); err != nil {
+		return nil, errors.Annotatef(err, "reading %q", unitStatesC)
+	}
+	current := set.NewStrings()
+	for _, doc := range stateDocs {
+		for i := 1; i <= doc.RelationStateChunks; i++ {
+			current.Add(relationStateChunkKey(doc.DocID, doc.RelationStateGeneration, i))
+		}
+	}
+
+	chunkIDs, err := s.docIDs(unitStateChunksC, nil)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	result := make(map[string][]interface{})
+	for _, id := range chunkIDs {
+		docID, _ := id.(string)
+		modelUUID, localID, ok := splitDocID(docID)
+		if !ok || !models.Contains(modelUUID) || current.Contains(docID) {
+			continue
+		}
+		unitName := strings.SplitN(strings.TrimPrefix(localID, "u#"), "#", 2)[0]
+		if units.Contains(ensureModelUUID(modelUUID, unitName)) {
+			result[unitStateChunksC] = append(result[unitStateChunksC], id)
+		}
+	}
+	return result, nil
+}
+
+// docIDs returns the ids, as stored in the database, of the
+// documents in the collection matching the selector.
+func (s *orphanSweep) docIDs(collection string, selector interface{}) ([]interface{}, error) {
+	coll, closer := s.st.db().GetRawCollection(collection)
+	defer closer()
+
+	var docs []bson.M
+	err := coll.Find(selector).Select(bson.D{{"_id", 1}}).All(&docs)
+	if err != nil && !isMgoNamespaceNotFound(err) {
+		return nil, errors.Annotatef(err, "reading %q", collection)
+	}
+	ids := make([]interface{}, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc["_id"]
+	}
+	return ids, nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/juju/juju/state"
+)
+
+type OrphansSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&OrphansSuite{})
+
+func (s *OrphansSuite) TestSweepOrphansNone(c *gc.C) {
+	s.Factory.MakeUnit(c, nil)
+
+	result, err := s.State.SweepOrphans(true)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.HasLen, 0)
+}
+
+func (s *OrphansSuite) addOrphans(c *gc.C) {
+	uuid := s.State.ModelUUID()
+	removedUUID := "deadbeef-0bad-400d-8000-4b1d0d06f00d"
+	db := s.Session.DB("juju")
+	for _, doc := range []struct {
+		collection string
+		doc        bson.M
+	}{
+		{"annotations", bson.M{"_id": removedUUID + ":m#0", "model-uuid": removedUUID}},
+		{"statuses", bson.M{"_id": removedUUID + ":u#gone/0", "model-uuid": removedUUID}},
+		{"statuses", bson.M{"_id": uuid + ":u#gone/0", "model-uuid": uuid}},
+		{"statuses", bson.M{"_id": uuid + ":u#gone/0#charm", "model-uuid": uuid}},
+		{"unitstates", bson.M{"_id": uuid + ":u#gone/0#charm", "model-uuid": uuid}},
+		{"statuses", bson.M{"_id": uuid + ":m#42", "model-uuid": uuid}},
+		{"constraints", bson.M{"_id": uuid + ":m#42", "model-uuid": uuid}},
+	} {
+		err := db.C(doc.collection).Insert(doc.doc)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+}
+
+func (s *OrphansSuite) TestSweepOrphansReports(c *gc.C) {
+	unit := s.Factory.MakeUnit(c, nil)
+	s.addOrphans(c)
+
+	expected := []state.OrphanReport{
+		{Detector: "removed-models", Collection: "annotations", Count: 1},
+		{Detector: "removed-models", Collection: "statuses", Count: 1},
+		{Detector: "removed-units", Collection: "statuses", Count: 2},
+		{Detector: "removed-units", Collection: "unitstates", Count: 1},
+		{Detector: "removed-machines", Collection: "constraints", Count: 1},
+		{Detector: "removed-machines", Collection: "statuses", Count: 1},
+	}
+	result, err := s.State.SweepOrphans(false)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, expected)
+
+	// The documents are reported again until they are removed.
+	result, err = s.State.SweepOrphans(false)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, expected)
+
+	// The documents of the existing unit are left alone.
+	_, err = unit.Status()
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *OrphansSuite) TestSweepOrphansRemoves(c *gc.C) {
+	unit := s.Factory.MakeUnit(c, nil)
+	s.addOrphans(c)
+
+	result, err := s.State.SweepOrphans(true)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.HasLen, 6)
+	for _, report := range result {
+		c.Check(report.Removed, jc.IsTrue)
+	}
+
+	result, err = s.State.SweepOrphans(true)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.HasLen, 0)
+
+	count, err := s.Session.DB("juju").C("statuses").Find(bson.M{"_id": bson.M{"$regex": ":u#gone/0"}}).Count()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(count, gc.Equals, 0)
+
+	_, err = unit.Status()
+	c.Assert(err, jc.ErrorIsNil)
+	machineID, err := unit.AssignedMachineId()
+	c.Assert(err, jc.ErrorIsNil)
+	machine, err := s.State.Machine(machineID)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = machine.Constraints()
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *OrphansSuite) TestSweepOrphansSupersededRelationStateChunks(c *gc.C) {
+	s.PatchValue(state.RelationStateChunkSize, 10)
+	unit := s.Factory.MakeUnit(c, nil)
+	relationState := map[int]string{1: "abcd", 2: "efgh", 3: "ijkl"}
+	unitState := state.NewUnitState()
+	unitState.SetRelationState(relationState)
+	err := unit.SetState(unitState)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// A chunk left behind by relation state since replaced.
+	uuid := s.State.ModelUUID()
+	err = s.Session.DB("juju").C("unitstatechunks").Insert(bson.M{
+		"_id":            uuid + ":u#" + unit.Name() + "#charm#superseded#1",
+		"model-uuid":     uuid,
+		"relation-state": bson.M{"3": "ijkl"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	result, err := s.State.SweepOrphans(true)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, []state.OrphanReport{
+		{Detector: "superseded-relation-state", Collection: "unitstatechunks", Count: 1, Removed: true},
+	})
+
+	// The chunk in use is left alone.
+	uState, err := unit.State()
+	c.Assert(err, jc.ErrorIsNil)
+	rState, _ := uState.RelationState()
+	c.Assert(rState, jc.DeepEquals, relationState)
+}
@@ -0,0 +1,283 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/core/permission"
+)
+
+// maxPermissionEvents is the maximum number of events returned
+// by DuePermissionEvents and PermissionEventDeadLetters.
+const maxPermissionEvents = 100
+
+// PermissionEventKind identifies the kind of change
+// to a user's access that an event describes.
+type PermissionEventKind string
+
+const (
+	// PermissionGranted is the kind of event
+	// recording that access was granted.
+	PermissionGranted PermissionEventKind = "grant"
+
+	// PermissionRevoked is the kind of event
+	// recording that access was revoked.
+	PermissionRevoked PermissionEventKind = "revoke"
+)
+
+// permissionEventDoc records a change to a user's controller or model
+// access that is to be posted to the permission webhook.
+type permissionEventDoc struct {
+	DocID       bson.ObjectId `bson:"_id"`
+	Kind        string        `bson:"kind"`
+	UserName    string        `bson:"user"`
+	Target      string        `bson:"target"`
+	Access      string        `bson:"access"`
+	By          string        `bson:"by,omitempty"`
+	Time        time.Time     `bson:"time"`
+	Attempts    int           `bson:"attempts"`
+	NextAttempt time.Time     `bson:"next-attempt"`
+	LastError   string        `bson:"last-error,omitempty"`
+	Dead        bool          `bson:"dead"`
+}
+
+// PermissionEvent describes a change to a user's controller
+// or model access, and its delivery to the permission webhook.
+type PermissionEvent struct {
+	// ID uniquely identifies the event.
+	ID string
+
+	// Kind identifies whether access was granted or revoked.
+	Kind PermissionEventKind
+
+	// User is the user whose access changed.
+	User names.UserTag
+
+	// Target is the controller or model the access applies to.
+	Target names.Tag
+
+	// Access is the access level granted or revoked.
+	Access permission.Access
+
+	// By is the user that changed the access. It is empty
+	// if the access was changed by the controller itself,
+	// for example when temporary access expired.
+	By names.UserTag
+
+	// Time is when the access changed. It is set
+	// by QueuePermissionEvent if it is zero.
+	Time time.Time
+
+	// Attempts is the number of failed attempts
+	// to deliver the event.
+	Attempts int
+
+	// NextAttempt is when delivery of the event
+	// should next be attempted.
+	NextAttempt time.Time
+
+	// LastError holds the reason the last
+	// attempt to deliver the event failed.
+	LastError string
+
+	// Dead is true if delivery of the event has
+	// been abandoned after too many failures.
+	Dead bool
+}
+
+// QueuePermissionEvent queues the event for delivery to the permission
+// webhook. Nothing is queued if the controller has no webhook configured.
+// Changes to access made by State queue their events in the same
+// transaction; this is for changes that are not.
+func (st *State) QueuePermissionEvent(ev PermissionEvent) error {
+	ops, err := st.permissionEventOps(ev)
+	if err != nil || len(ops) == 0 {
+		return errors.Trace(err)
+	}
+	err = st.db().RunTransaction(ops)
+	return errors.Annotatef(err, "queueing %s of %q access for user %q", ev.Kind, ev.Access, ev.User.Id())
+}
+
+// permissionEventOps returns the operations that queue the event for
+// delivery to the permission webhook, so that it can be queued in the
+// same transaction as the change to access that it describes. No
+// operations are returned if the controller has no webhook configured.
+func (st *State) permissionEventOps(ev PermissionEvent) ([]txn.Op, error) {
+	if ev.Kind != PermissionGranted && ev.Kind != PermissionRevoked {
+		return nil, errors.NotValidf("permission event kind %q", ev.Kind)
+	}
+	if ev.Target == nil {
+		return nil, errors.NotValidf("permission event without target")
+	}
+	cfg, err := st.ControllerConfig()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if cfg.PermissionWebhookURL() == "" {
+		return nil, nil
+	}
+	if ev.Time.IsZero() {
+		ev.Time = st.nowToTheSecond()
+	}
+	doc := &permissionEventDoc{
+		DocID:       bson.NewObjectId(),
+		Kind:        string(ev.Kind),
+		UserName:    ev.User.Id(),
+		Target:      ev.Target.String(),
+		Access:      string(ev.Access),
+		By:          ev.By.Id(),
+		Time:        ev.Time,
+		NextAttempt: ev.Time,
+	}
+	return []txn.Op{{
+		C:      permissionEventsC,
+		Id:     doc.DocID,
+		Assert: txn.DocMissing,
+		Insert: doc,
+	}}, nil
+}
+
+// optionalPermissionEventOps returns the operations that queue the
+// event, if there is one, for delivery to the permission webhook.
+func (st *State) optionalPermissionEventOps(ev *PermissionEvent) ([]txn.Op, error) {
+	if ev == nil {
+		return nil, nil
+	}
+	ops, err := st.permissionEventOps(*ev)
+	return ops, errors.Annotatef(err, "queueing %s of %q access for user %q", ev.Kind, ev.Access, ev.User.Id())
+}
+
+// DuePermissionEvents returns the events whose delivery
+// is due at the given time, oldest first.
+func (st *State) DuePermissionEvents(now time.Time) ([]PermissionEvent, error) {
+	return st.permissionEvents(bson.D{
+		{"dead", false},
+		{"next-attempt", bson.D{{"$lte", now}}},
+	})
+}
+
+// PermissionEventDeadLetters returns the events whose
+// delivery has been abandoned, oldest first.
+func (st *State) PermissionEventDeadLetters() ([]PermissionEvent, error) {
+	return st.permissionEvents(bson.D{{"dead", true}})
+}
+
+func (st *State) permissionEvents(query bson.D) ([]PermissionEvent, error) {
+	events, closer := st.db().GetCollection(permissionEventsC)
+	defer closer()
+
+	var docs []permissionEventDoc
+	err := events.Find(query).Sort("time", "_id").Limit(maxPermissionEvents).All(&docs)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	result := make([]PermissionEvent, 0, len(docs))
+	for _, doc := range docs {
+		target, err := names.ParseTag(doc.Target)
+		if err != nil {
+			return nil, errors.Annotatef(err, "permission event %q", doc.DocID.Hex())
+		}
+		ev := PermissionEvent{
+			ID:          doc.DocID.Hex(),
+			Kind:        PermissionEventKind(doc.Kind),
+			User:        names.NewUserTag(doc.UserName),
+			Target:      target,
+			Access:      permission.Access(doc.Access),
+			Time:        doc.Time.UTC(),
+			Attempts:    doc.Attempts,
+			NextAttempt: doc.NextAttempt.UTC(),
+			LastError:   doc.LastError,
+			Dead:        doc.Dead,
+		}
+		if doc.By != "" {
+			ev.By = names.NewUserTag(doc.By)
+		}
+		result = append(result, ev)
+	}
+	return result, nil
+}
+
+// PermissionEventDelivered records that the event
+// was delivered, removing it from the queue.
+func (st *State) PermissionEventDelivered(id string) error {
+	docID, err := permissionEventID(id)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	err = st.db().RunTransaction([]txn.Op{{
+		C:      permissionEventsC,
+		Id:     docID,
+		Assert: txn.DocExists,
+		Remove: true,
+	}})
+	if err == txn.ErrAborted {
+		return errors.NotFoundf("permission event %q", id)
+	}
+	return errors.Trace(err)
+}
+
+// PermissionEventFailed records a failed attempt to deliver the event.
+// Delivery is retried at retryAt, or if retryAt is zero the event is
+// abandoned and kept as a dead letter, to be inspected by an operator.
+func (st *State) PermissionEventFailed(id string, reason string, retryAt time.Time) error {
+	docID, err := permissionEventID(id)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	set := bson.D{{"last-error", reason}}
+	if retryAt.IsZero() {
+		set = append(set, bson.DocElem{"dead", true})
+	} else {
+		set = append(set, bson.DocElem{"next-attempt", retryAt})
+	}
+	err = st.db().RunTransaction([]txn.Op{{
+		C:      permissionEventsC,
+		Id:     docID,
+		Assert: txn.DocExists,
+		Update: bson.D{
+			{"$set", set},
+			{"$inc", bson.D{{"attempts", 1}}},
+		},
+	}})
+	if err == txn.ErrAborted {
+		return errors.NotFoundf("permission event %q", id)
+	}
+	return errors.Trace(err)
+}
+
+// RetryPermissionEvent returns the abandoned event to
+// the queue, to be delivered as soon as possible.
+func (st *State) RetryPermissionEvent(id string) error {
+	docID, err := permissionEventID(id)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	err = st.db().RunTransaction([]txn.Op{{
+		C:      permissionEventsC,
+		Id:     docID,
+		Assert: bson.D{{"dead", true}},
+		Update: bson.D{{"$set", bson.D{
+			{"dead", false},
+			{"attempts", 0},
+			{"next-attempt", st.nowToTheSecond()},
+		}}},
+	}})
+	if err == txn.ErrAborted {
+		return errors.NotFoundf("abandoned permission event %q", id)
+	}
+	return errors.Trace(err)
+}
+
+func permissionEventID(id string) (bson.ObjectId, error) {
+	if !bson.IsObjectIdHex(id) {
+		return "", errors.NotValidf("permission event ID %q", id)
+	}
+	return bson.ObjectIdHex(id), nil
+}
@@ -0,0 +1,198 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/controller"
+	"github.com/juju/juju/core/permission"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing/factory"
+)
+
+type PermissionEventsSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&PermissionEventsSuite{})
+
+func (s *PermissionEventsSuite) enableWebhook(c *gc.C) {
+	err := s.State.UpdateControllerConfig(map[string]interface{}{
+		controller.PermissionWebhookURL:    "https://audit.example.com/juju",
+		controller.PermissionWebhookSecret: "sekrit",
+	}, nil)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *PermissionEventsSuite) queue(c *gc.C, kind state.PermissionEventKind, user string) {
+	err := s.State.QueuePermissionEvent(state.PermissionEvent{
+		Kind:   kind,
+		User:   names.NewUserTag(user),
+		Target: s.Model.ModelTag(),
+		Access: permission.WriteAccess,
+		By:     names.NewUserTag("admin"),
+	})
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *PermissionEventsSuite) TestQueueWithoutWebhook(c *gc.C) {
+	s.queue(c, state.PermissionGranted, "bob")
+	events, err := s.State.DuePermissionEvents(s.Clock.Now())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(events, gc.HasLen, 0)
+}
+
+func (s *PermissionEventsSuite) TestQueueInvalidKind(c *gc.C) {
+	err := s.State.QueuePermissionEvent(state.PermissionEvent{
+		Kind:   "promote",
+		User:   names.NewUserTag("bob"),
+		Target: s.Model.ModelTag(),
+	})
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *PermissionEventsSuite) TestAccessChangeQueuesEvent(c *gc.C) {
+	s.enableWebhook(c)
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", NoModelUser: true})
+	err := s.State.RemoveUserAccess(user.UserTag(), s.State.ControllerTag())
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = s.State.AddControllerUserWithEvent(state.UserAccessSpec{
+		User:      user.UserTag(),
+		CreatedBy: s.Owner,
+		Access:    permission.SuperuserAccess,
+	}, state.PermissionEvent{
+		Kind:   state.PermissionGranted,
+		User:   user.UserTag(),
+		Target: s.State.ControllerTag(),
+		Access: permission.SuperuserAccess,
+		By:     s.Owner,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	events, err := s.State.DuePermissionEvents(s.Clock.Now())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(events, gc.HasLen, 1)
+	c.Check(events[0].Kind, gc.Equals, state.PermissionGranted)
+	c.Check(events[0].User, gc.Equals, user.UserTag())
+	c.Check(events[0].Target, gc.Equals, s.State.ControllerTag())
+	c.Check(events[0].Access, gc.Equals, permission.SuperuserAccess)
+}
+
+func (s *PermissionEventsSuite) TestFailedAccessChangeQueuesNoEvent(c *gc.C) {
+	s.enableWebhook(c)
+	bob := names.NewUserTag("bob@external")
+	err := s.State.RemoveControllerUserWithEvent(bob, state.PermissionEvent{
+		Kind:   state.PermissionRevoked,
+		User:   bob,
+		Target: s.State.ControllerTag(),
+		Access: permission.LoginAccess,
+		By:     s.Owner,
+	})
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	events, err := s.State.DuePermissionEvents(s.Clock.Now())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(events, gc.HasLen, 0)
+}
+
+func (s *PermissionEventsSuite) TestQueueAndDeliver(c *gc.C) {
+	s.enableWebhook(c)
+	s.queue(c, state.PermissionGranted, "bob")
+	s.Clock.Advance(time.Minute)
+	s.queue(c, state.PermissionRevoked, "mary")
+
+	events, err := s.State.DuePermissionEvents(s.Clock.Now())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(events, gc.HasLen, 2)
+	c.Check(events[0].Kind, gc.Equals, state.PermissionGranted)
+	c.Check(events[0].User, gc.Equals, names.NewUserTag("bob"))
+	c.Check(events[0].Target, gc.Equals, s.Model.ModelTag())
+	c.Check(events[0].Access, gc.Equals, permission.WriteAccess)
+	c.Check(events[0].By, gc.Equals, names.NewUserTag("admin"))
+	c.Check(events[0].Attempts, gc.Equals, 0)
+	c.Check(events[1].Kind, gc.Equals, state.PermissionRevoked)
+	c.Check(events[1].User, gc.Equals, names.NewUserTag("mary"))
+
+	err = s.State.PermissionEventDelivered(events[0].ID)
+	c.Assert(err, jc.ErrorIsNil)
+	events, err = s.State.DuePermissionEvents(s.Clock.Now())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(events, gc.HasLen, 1)
+	c.Check(events[0].User, gc.Equals, names.NewUserTag("mary"))
+
+	err = s.State.PermissionEventDelivered(unknownPermissionEventID)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *PermissionEventsSuite) TestFailedDeliveryRetried(c *gc.C) {
+	s.enableWebhook(c)
+	s.queue(c, state.PermissionGranted, "bob")
+	events, err := s.State.DuePermissionEvents(s.Clock.Now())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(events, gc.HasLen, 1)
+
+	retryAt := s.Clock.Now().Add(time.Minute)
+	err = s.State.PermissionEventFailed(events[0].ID, "connection refused", retryAt)
+	c.Assert(err, jc.ErrorIsNil)
+
+	events, err = s.State.DuePermissionEvents(s.Clock.Now())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(events, gc.HasLen, 0)
+
+	events, err = s.State.DuePermissionEvents(retryAt)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(events, gc.HasLen, 1)
+	c.Check(events[0].Attempts, gc.Equals, 1)
+	c.Check(events[0].LastError, gc.Equals, "connection refused")
+}
+
+func (s *PermissionEventsSuite) TestDeadLetters(c *gc.C) {
+	s.enableWebhook(c)
+	s.queue(c, state.PermissionGranted, "bob")
+	events, err := s.State.DuePermissionEvents(s.Clock.Now())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(events, gc.HasLen, 1)
+	id := events[0].ID
+
+	err = s.State.RetryPermissionEvent(id)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	err = s.State.PermissionEventFailed(id, "500 Internal Server Error", time.Time{})
+	c.Assert(err, jc.ErrorIsNil)
+	events, err = s.State.DuePermissionEvents(s.Clock.Now().Add(time.Hour))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(events, gc.HasLen, 0)
+
+	dead, err := s.State.PermissionEventDeadLetters()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dead, gc.HasLen, 1)
+	c.Check(dead[0].ID, gc.Equals, id)
+	c.Check(dead[0].Dead, jc.IsTrue)
+	c.Check(dead[0].LastError, gc.Equals, "500 Internal Server Error")
+
+	err = s.State.RetryPermissionEvent(id)
+	c.Assert(err, jc.ErrorIsNil)
+	dead, err = s.State.PermissionEventDeadLetters()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dead, gc.HasLen, 0)
+	events, err = s.State.DuePermissionEvents(s.Clock.Now())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(events, gc.HasLen, 1)
+	c.Check(events[0].Attempts, gc.Equals, 0)
+}
+
+func (s *PermissionEventsSuite) TestInvalidID(c *gc.C) {
+	err := s.State.PermissionEventDelivered("foo")
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
+// unknownPermissionEventID is a well formed ID that identifies no event.
+const unknownPermissionEventID = "5e9d8c1f2a3b4c5d6e7f8091"
@@ -0,0 +1,266 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/juju/juju/core/permission"
+)
+
+// UserPermissions describes the access a user has been granted to the
+// controller, and to the models, clouds and offers that it hosts.
+type UserPermissions struct {
+	// User is the user that has been granted access.
+	User names.UserTag
+
+	// Controller is the user's access to the controller,
+	// or empty if the user has none.
+	Controller permission.Access
+
+	// Models holds the user's access to models, keyed by model UUID.
+	Models map[string]permission.Access
+
+	// Clouds holds the user's access to clouds, keyed by cloud name.
+	Clouds map[string]permission.Access
+
+	// Offers holds the user's access to application offers,
+	// keyed by offer UUID.
+	Offers map[string]permission.Access
+}
+
+func newUserPermissions(user names.UserTag) *UserPermissions {
+	return &UserPermissions{
+		User:   user,
+		Models: make(map[string]permission.Access),
+		Clouds: make(map[string]permission.Access),
+		Offers: make(map[string]permission.Access),
+	}
+}
+
+// add records the access granted on the object with the
+// input global key, ignoring keys of unknown objects.
+func (p *UserPermissions) add(controllerUUID, objectGlobalKey string, access permission.Access) {
+	parts := strings.SplitN(objectGlobalKey, "#", 2)
+	if len(parts) != 2 {
+		return
+	}
+	switch parts[0] {
+	case controllerGlobalKey:
+		if parts[1] == controllerUUID {
+			p.Controller = access
+		}
+	case modelGlobalKey:
+		p.Models[parts[1]] = access
+	case "cloud":
+		p.Clouds[parts[1]] = access
+	case applicationOfferGlobalKey:
+		p.Offers[parts[1]] = access
+	}
+}
+
+// AllUserPermissions returns the access granted to each user that has
+// been granted access to anything hosted by the controller, ordered by
+// user name. External users, including everyone@external, are included.
+func (st *State) AllUserPermissions() ([]UserPermissions, error) {
+	return st.userPermissions(nil)
+}
+
+// UserPermissions returns the access granted to the input user.
+func (st *State) UserPermissions(user names.UserTag) (UserPermissions, error) {
+	subject := userGlobalKey(userAccessID(user))
+	perms, err := st.userPermissions(bson.D{{"subject-global-key", subject}})
+	if err != nil {
+		return UserPermissions{}, errors.Trace(err)
+	}
+	if len(perms) == 0 {
+		return *newUserPermissions(user), nil
+	}
+	return perms[0], nil
+}
+
+func (st *State) userPermissions(query bson.D) ([]UserPermissions, error) {
+	permissions, closer := st.db().GetCollection(permissionsC)
+	defer closer()
+
+	var docs []permissionDoc
+	if err := permissions.Find(query).All(&docs); err != nil {
+		return nil, errors.Annotate(err, "reading permissions")
+	}
+	controllerUUID := st.ControllerUUID()
+	byUser := make(map[string]*UserPermissions)
+	for _, doc := range docs {
+		userID := userIDFromGlobalKey(doc.SubjectGlobalKey)
+		if !names.IsValidUser(userID) {
+			continue
+		}
+		perms, ok := byUser[userID]
+		if !ok {
+			perms = newUserPermissions(names.NewUserTag(userID))
+			byUser[userID] = perms
+		}
+		perms.add(controllerUUID, doc.ObjectGlobalKey, stringToAccess(doc.Access))
+	}
+	result := make([]UserPermissions, 0, len(byUser))
+	for _, perms := range byUser {
+		result = append(result, *perms)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].User.Id() < result[j].User.Id()
+	})
+	return result, nil
+}
+
+// SetUserPermissions grants the user the access described by the input
+// permissions, on behalf of createdBy. If revoke is true, any access the
+// user has that is not described is revoked; otherwise it is left alone.
+// Changes are made one object at a time, so an error may leave some of
+// the access granted.
+func (st *State) SetUserPermissions(perms UserPermissions, createdBy names.UserTag, revoke bool) error {
+	user := perms.User
+	if user.IsLocal() {
+		if _, err := st.User(user); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	current, err := st.UserPermissions(user)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	controllerTag := st.ControllerTag()
+	if err := st.setUserAccess(user, controllerTag, current.Controller, perms.Controller, revoke, func() error {
+		_, err := st.AddControllerUser(UserAccessSpec{User: user, CreatedBy: createdBy, Access: perms.Controller})
+		return err
+	}); err != nil {
+		return errors.Annotate(err, "controller access")
+	}
+
+	for _, uuid := range permissionKeys(perms.Models, current.Models, revoke) {
+		if err := st.setModelPermission(user, createdBy, uuid, current.Models[uuid], perms.Models[uuid]); err != nil {
+			return errors.Annotatef(err, "model %q access", uuid)
+		}
+	}
+	for _, cloud := range permissionKeys(perms.Clouds, current.Clouds, revoke) {
+		if err := st.setCloudPermission(user, cloud, current.Clouds[cloud], perms.Clouds[cloud]); err != nil {
+			return errors.Annotatef(err, "cloud %q access", cloud)
+		}
+	}
+	for _, uuid := range permissionKeys(perms.Offers, current.Offers, revoke) {
+		if err := st.setOfferPermission(user, uuid, current.Offers[uuid], perms.Offers[uuid]); err != nil {
+			return errors.Annotatef(err, "offer %q access", uuid)
+		}
+	}
+	return nil
+}
+
+// permissionKeys returns the sorted keys of the objects whose access is
+// to be set: those in desired, and if revoke is true, those in current.
+func permissionKeys(desired, current map[string]permission.Access, revoke bool) []string {
+	var keys []string
+	for key := range desired {
+		keys = append(keys, key)
+	}
+	if revoke {
+		for key := range current {
+			if _, ok := desired[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// setUserAccess changes the user's access to the controller or a model from
+// current to desired, calling add if the user has no access yet. No access
+// is removed unless revoke is true.
+func (st *State) setUserAccess(
+	user names.UserTag, target names.Tag, current, desired permission.Access, revoke bool, add func() error,
+) error {
+	switch {
+	case desired == current:
+		return nil
+	case desired == "":
+		if !revoke {
+			return nil
+		}
+		return errors.Trace(st.RemoveUserAccess(user, target))
+	case current == "":
+		return errors.Trace(add())
+	}
+	_, err := st.SetUserAccess(user, target, desired)
+	return errors.Trace(err)
+}
+
+func (st *State) setModelPermission(user, createdBy names.UserTag, uuid string, current, desired permission.Access) error {
+	m, closer, err := st.model(uuid)
+	defer func() { _ = closer() }()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if m == nil {
+		return errors.NotFoundf("model %q", uuid)
+	}
+	// Model users are removed using the model's own state.
+	return errors.Trace(m.st.setUserAccess(user, m.ModelTag(), current, desired, true, func() error {
+		_, err := m.AddUser(UserAccessSpec{User: user, CreatedBy: createdBy, Access: desired})
+		return err
+	}))
+}
+
+func (st *State) setCloudPermission(user names.UserTag, cloud string, current, desired permission.Access) error {
+	switch {
+	case desired == current:
+		return nil
+	case desired == "":
+		return errors.Trace(st.RemoveCloudAccess(cloud, user))
+	case current == "":
+		if _, err := st.Cloud(cloud); err != nil {
+			return errors.Trace(err)
+		}
+		return errors.Trace(st.CreateCloudAccess(cloud, user, desired))
+	}
+	return errors.Trace(st.UpdateCloudAccess(cloud, user, desired))
+}
+
+func (st *State) setOfferPermission(user names.UserTag, offerUUID string, current, desired permission.Access) error {
+	if desired == current {
+		return nil
+	}
+	// Offers are named within the model that hosts them, and changes to
+	// offer access can suspend relations in that model, so the changes
+	// are made using the hosting model's state.
+	offers, closer := st.db().GetRawCollection(applicationOffersC)
+	defer closer()
+	var doc struct {
+		ModelUUID string `bson:"model-uuid"`
+		OfferName string `bson:"offer-name"`
+	}
+	if err := offers.Find(bson.D{{"offer-uuid", offerUUID}}).One(&doc); err != nil {
+		return errors.NotFoundf("offer %q", offerUUID)
+	}
+	m, modelCloser, err := st.model(doc.ModelUUID)
+	defer func() { _ = modelCloser() }()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if m == nil {
+		return errors.NotFoundf("offer %q", offerUUID)
+	}
+
+	offer := names.NewApplicationOfferTag(doc.OfferName)
+	switch {
+	case desired == "":
+		return errors.Trace(m.st.RemoveOfferAccess(offer, user))
+	case current == "":
+		return errors.Trace(m.st.CreateOfferAccess(offer, user, desired))
+	}
+	return errors.Trace(m.st.UpdateOfferAccess(offer, user, desired))
+}
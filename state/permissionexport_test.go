@@ -0,0 +1,116 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/core/permission"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing/factory"
+)
+
+type PermissionExportSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&PermissionExportSuite{})
+
+func (s *PermissionExportSuite) TestAllUserPermissions(c *gc.C) {
+	bob := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", Access: permission.WriteAccess})
+	err := s.State.CreateCloudAccess(s.Model.CloudName(), bob.UserTag(), permission.AddModelAccess)
+	c.Assert(err, jc.ErrorIsNil)
+
+	all, err := s.State.AllUserPermissions()
+	c.Assert(err, jc.ErrorIsNil)
+	var ids []string
+	for _, perms := range all {
+		ids = append(ids, perms.User.Id())
+	}
+	c.Assert(ids, jc.DeepEquals, []string{"bob", "test-admin"})
+	c.Assert(all[0], jc.DeepEquals, state.UserPermissions{
+		User:       bob.UserTag(),
+		Controller: permission.LoginAccess,
+		Models:     map[string]permission.Access{s.Model.UUID(): permission.WriteAccess},
+		Clouds:     map[string]permission.Access{s.Model.CloudName(): permission.AddModelAccess},
+		Offers:     map[string]permission.Access{},
+	})
+}
+
+func (s *PermissionExportSuite) TestUserPermissionsNone(c *gc.C) {
+	perms, err := s.State.UserPermissions(names.NewUserTag("fred@external"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(perms.Controller, gc.Equals, permission.NoAccess)
+	c.Assert(perms.Models, gc.HasLen, 0)
+}
+
+func (s *PermissionExportSuite) TestSetUserPermissions(c *gc.C) {
+	bob := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", NoModelUser: true})
+	desired := state.UserPermissions{
+		User:       bob.UserTag(),
+		Controller: permission.SuperuserAccess,
+		Models:     map[string]permission.Access{s.Model.UUID(): permission.ReadAccess},
+		Clouds:     map[string]permission.Access{s.Model.CloudName(): permission.AddModelAccess},
+		Offers:     map[string]permission.Access{},
+	}
+	err := s.State.SetUserPermissions(desired, s.Owner, false)
+	c.Assert(err, jc.ErrorIsNil)
+
+	perms, err := s.State.UserPermissions(bob.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(perms, jc.DeepEquals, desired)
+
+	// Changing access updates the existing permissions.
+	desired.Models[s.Model.UUID()] = permission.AdminAccess
+	err = s.State.SetUserPermissions(desired, s.Owner, false)
+	c.Assert(err, jc.ErrorIsNil)
+	perms, err = s.State.UserPermissions(bob.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(perms, jc.DeepEquals, desired)
+}
+
+func (s *PermissionExportSuite) TestSetUserPermissionsRevoke(c *gc.C) {
+	bob := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob"})
+	desired := state.UserPermissions{
+		User:       bob.UserTag(),
+		Controller: permission.LoginAccess,
+	}
+
+	// Access not described is left alone unless it is revoked.
+	err := s.State.SetUserPermissions(desired, s.Owner, false)
+	c.Assert(err, jc.ErrorIsNil)
+	perms, err := s.State.UserPermissions(bob.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(perms.Models, gc.HasLen, 1)
+
+	err = s.State.SetUserPermissions(desired, s.Owner, true)
+	c.Assert(err, jc.ErrorIsNil)
+	perms, err = s.State.UserPermissions(bob.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(perms.Controller, gc.Equals, permission.LoginAccess)
+	c.Assert(perms.Models, gc.HasLen, 0)
+	_, err = s.State.UserAccess(bob.UserTag(), s.Model.ModelTag())
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *PermissionExportSuite) TestSetUserPermissionsUnknownCloud(c *gc.C) {
+	bob := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", NoModelUser: true})
+	err := s.State.SetUserPermissions(state.UserPermissions{
+		User:       bob.UserTag(),
+		Controller: permission.LoginAccess,
+		Clouds:     map[string]permission.Access{"nope": permission.AddModelAccess},
+	}, s.Owner, false)
+	c.Assert(err, gc.ErrorMatches, `cloud "nope" access: cloud "nope" not found`)
+}
+
+func (s *PermissionExportSuite) TestSetUserPermissionsUnknownUser(c *gc.C) {
+	err := s.State.SetUserPermissions(state.UserPermissions{
+		User:       names.NewUserTag("nobody"),
+		Controller: permission.LoginAccess,
+	}, s.Owner, false)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
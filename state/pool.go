@@ -116,6 +116,11 @@ type StatePool struct {
 
 	// watcherRunner makes sure the TxnWatcher stays running.
 	watcherRunner *worker.Runner
+
+	// upgradeBatcher, if set, determines how upgrade steps
+	// batch the documents that they rewrite. It is protected
+	// by mu.
+	upgradeBatcher UpgradeBatcher
 }
 
 // OpenStatePool returns a new StatePool instance.
@@ -0,0 +1,91 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// schemaVersionDoc records the schema version of the documents in
+// a collection, as left by the last upgrade step to change them.
+type schemaVersionDoc struct {
+	// DocID is the name of the collection.
+	DocID   string    `bson:"_id"`
+	Version int       `bson:"version"`
+	Step    string    `bson:"step"`
+	Updated time.Time `bson:"updated"`
+}
+
+// SchemaVersions returns the schema version recorded for each
+// collection changed by an upgrade step, keyed by collection name.
+// Collections last changed before versions were recorded are absent.
+func (st *State) SchemaVersions() (map[string]int, error) {
+	coll, closer := st.db().GetCollection(schemaVersionsC)
+	defer closer()
+
+	var docs []schemaVersionDoc
+	if err := coll.Find(nil).All(&docs); err != nil {
+		return nil, errors.Annotate(err, "reading schema versions")
+	}
+	result := make(map[string]int, len(docs))
+	for _, doc := range docs {
+		result[doc.DocID] = doc.Version
+	}
+	return result, nil
+}
+
+// SetSchemaVersion records that the upgrade step with the
+// input description left the documents in the collection at
+// the input schema version.
+func (st *State) SetSchemaVersion(collection string, version int, step string) error {
+	if collection == "" {
+		return errors.NotValidf("empty collection name")
+	}
+	coll, closer := st.db().GetCollection(schemaVersionsC)
+	defer closer()
+
+	_, err := coll.Writeable().UpsertId(collection, bson.D{{"$set", bson.D{
+		{"version", version},
+		{"step", step},
+		{"updated", st.nowToTheSecond()},
+	}}})
+	return errors.Annotatef(err, "recording schema version of %q", collection)
+}
+
+// SampleMissingFields checks a random sample, of at most the input size,
+// of the documents in the collection across all models, and returns the
+// fields missing from each document that lacks any of the input fields,
+// keyed by document ID.
+func (st *State) SampleMissingFields(collection string, fields []string, size int) (map[string][]string, error) {
+	if size <= 0 {
+		return nil, errors.NotValidf("sample size %d", size)
+	}
+	coll, closer := st.db().GetRawCollection(collection)
+	defer closer()
+
+	var docs []bson.M
+	err := coll.Pipe([]bson.M{{"$sample": bson.M{"size": size}}}).All(&docs)
+	if err != nil {
+		return nil, errors.Annotatef(err, "sampling %q", collection)
+	}
+	result := make(map[string][]string)
+	for _, doc := range docs {
+		var missing []string
+		for _, field := range fields {
+			if _, ok := doc[field]; !ok {
+				missing = append(missing, field)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			result[fmt.Sprint(doc["_id"])] = missing
+		}
+	}
+	return result, nil
+}
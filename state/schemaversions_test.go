@@ -0,0 +1,70 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+type SchemaVersionsSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&SchemaVersionsSuite{})
+
+func (s *SchemaVersionsSuite) TestSchemaVersionsNone(c *gc.C) {
+	versions, err := s.State.SchemaVersions()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(versions, gc.HasLen, 0)
+}
+
+func (s *SchemaVersionsSuite) TestSetSchemaVersion(c *gc.C) {
+	err := s.State.SetSchemaVersion("machines", 1, "add machine field")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.SetSchemaVersion("subnets", 1, "add subnet field")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.SetSchemaVersion("machines", 2, "change machine field")
+	c.Assert(err, jc.ErrorIsNil)
+
+	versions, err := s.State.SchemaVersions()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(versions, jc.DeepEquals, map[string]int{
+		"machines": 2,
+		"subnets":  1,
+	})
+}
+
+func (s *SchemaVersionsSuite) TestSetSchemaVersionEmptyCollection(c *gc.C) {
+	err := s.State.SetSchemaVersion("", 1, "add field")
+	c.Assert(err, gc.ErrorMatches, "empty collection name not valid")
+}
+
+func (s *SchemaVersionsSuite) TestSampleMissingFields(c *gc.C) {
+	m0 := s.Factory.MakeMachine(c, nil)
+	m1 := s.Factory.MakeMachine(c, nil)
+
+	missing, err := s.State.SampleMissingFields("machines", []string{"series", "life"}, 10)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(missing, gc.HasLen, 0)
+
+	missing, err = s.State.SampleMissingFields("machines", []string{"series", "no-such-field"}, 10)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(missing, jc.DeepEquals, map[string][]string{
+		s.State.ModelUUID() + ":" + m0.Id(): {"no-such-field"},
+		s.State.ModelUUID() + ":" + m1.Id(): {"no-such-field"},
+	})
+}
+
+func (s *SchemaVersionsSuite) TestSampleMissingFieldsSampleSize(c *gc.C) {
+	s.Factory.MakeMachine(c, nil)
+	s.Factory.MakeMachine(c, nil)
+
+	missing, err := s.State.SampleMissingFields("machines", []string{"no-such-field"}, 1)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(missing, gc.HasLen, 1)
+
+	_, err = s.State.SampleMissingFields("machines", []string{"series"}, 0)
+	c.Assert(err, gc.ErrorMatches, "sample size 0 not valid")
+}
@@ -459,6 +459,60 @@ func setStatus(db Database, params setStatusParams) (err error) {
 	return errors.Trace(err)
 }
 
+// setStatusOperation is a ModelOperation for setting the status
+// described by params, allowing it to be composed with other
+// operations into a single transaction.
+type setStatusOperation struct {
+	db     Database
+	params setStatusParams
+	doc    statusDoc
+}
+
+func newSetStatusOperation(db Database, params setStatusParams) (*setStatusOperation, error) {
+	if params.updated == nil {
+		return nil, errors.NotValidf("nil updated time")
+	}
+	return &setStatusOperation{
+		db:     db,
+		params: params,
+		doc: statusDoc{
+			Status:     params.status,
+			StatusInfo: params.message,
+			StatusData: utils.EscapeKeys(params.rawData),
+			Updated:    params.updated.UnixNano(),
+		},
+	}, nil
+}
+
+// Build is part of the ModelOperation interface.
+func (op *setStatusOperation) Build(attempt int) ([]txn.Op, error) {
+	var buildTxn jujutxn.TransactionSource = func(int) ([]txn.Op, error) {
+		return statusSetOps(op.db, op.doc, op.params.globalKey)
+	}
+	if op.params.token != nil {
+		buildTxn = buildTxnWithLeadership(buildTxn, op.params.token)
+	}
+	return buildTxn(attempt)
+}
+
+// Done is part of the ModelOperation interface.
+// Status history is only recorded once the status has been set.
+func (op *setStatusOperation) Done(err error) error {
+	if cause := errors.Cause(err); cause == mgo.ErrNotFound {
+		return errors.NotFoundf(op.params.badge)
+	} else if err != nil {
+		return errors.Annotate(err, "cannot set status")
+	}
+	historyDoc := &op.doc
+	if op.params.historyOverwrite != nil {
+		historyDoc = op.params.historyOverwrite
+	}
+	// Failures to record history are logged by probablyUpdateStatusHistory
+	// and must not be reported as failure to set the status.
+	_, _ = probablyUpdateStatusHistory(op.db, op.params.globalKey, *historyDoc)
+	return nil
+}
+
 func statusSetOps(db Database, doc statusDoc, globalKey string) ([]txn.Op, error) {
 	update := bson.D{{"$set", &doc}}
 	txnRevno, err := readTxnRevno(db, statusesC, globalKey)
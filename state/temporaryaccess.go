@@ -0,0 +1,324 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	jujutxn "github.com/juju/txn"
+	"gopkg.in/juju/names.v3"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/core/permission"
+)
+
+// temporaryAccessDoc records a time limited elevation of a user's
+// access to a model. The document is kept once the grant has been
+// revoked, so that there is a record of when the access was removed.
+type temporaryAccessDoc struct {
+	DocID          string    `bson:"_id"`
+	ModelUUID      string    `bson:"model-uuid"`
+	UserName       string    `bson:"user"`
+	Access         string    `bson:"access"`
+	PreviousAccess string    `bson:"previous-access"`
+	GrantedBy      string    `bson:"granted-by"`
+	Granted        time.Time `bson:"granted"`
+	Expires        time.Time `bson:"expires"`
+	Revoked        time.Time `bson:"revoked,omitempty"`
+}
+
+// TemporaryAccessGrant describes a time limited grant of access
+// to a model for a user.
+type TemporaryAccessGrant struct {
+	// User is the user that has been granted access.
+	User names.UserTag
+
+	// Model is the model the access applies to.
+	Model names.ModelTag
+
+	// Access is the access level that has been granted.
+	Access permission.Access
+
+	// PreviousAccess is the access the user had on the model before
+	// the grant, which is restored when the grant expires. It is
+	// permission.NoAccess if the user had no access to the model.
+	PreviousAccess permission.Access
+
+	// GrantedBy is the user that granted the access.
+	GrantedBy names.UserTag
+
+	// Granted is when the access was granted.
+	Granted time.Time
+
+	// Expires is when the access will be revoked.
+	Expires time.Time
+
+	// Revoked is when the access was revoked, or the zero time
+	// if the grant is still active.
+	Revoked time.Time
+}
+
+func newTemporaryAccessGrant(doc temporaryAccessDoc) TemporaryAccessGrant {
+	return TemporaryAccessGrant{
+		User:           names.NewUserTag(doc.UserName),
+		Model:          names.NewModelTag(doc.ModelUUID),
+		Access:         permission.Access(doc.Access),
+		PreviousAccess: permission.Access(doc.PreviousAccess),
+		GrantedBy:      names.NewUserTag(doc.GrantedBy),
+		Granted:        doc.Granted.UTC(),
+		Expires:        doc.Expires.UTC(),
+		Revoked:        doc.Revoked.UTC(),
+	}
+}
+
+func temporaryAccessID(modelUUID string, user names.UserTag) string {
+	return modelUUID + ":" + userAccessID(user)
+}
+
+var activeTemporaryAccessDoc = bson.D{{"revoked", bson.D{{"$exists", false}}}}
+
+// GrantTemporaryModelAccess grants the user the specified access to the
+// model until the ttl has passed, after which the access the user had
+// beforehand is restored by RevokeExpiredTemporaryAccess.
+// Granting temporary access to a user that already holds an active grant
+// for the model replaces the access level and expiry of that grant.
+func (st *State) GrantTemporaryModelAccess(
+	subject names.UserTag,
+	model names.ModelTag,
+	access permission.Access,
+	ttl time.Duration,
+	grantedBy names.UserTag,
+) (TemporaryAccessGrant, error) {
+	if err := permission.ValidateModelAccess(access); err != nil {
+		return TemporaryAccessGrant{}, errors.Trace(err)
+	}
+	if ttl <= 0 {
+		return TemporaryAccessGrant{}, errors.NotValidf("temporary access ttl %v", ttl)
+	}
+	if exists, err := st.ModelExists(model.Id()); err != nil {
+		return TemporaryAccessGrant{}, errors.Trace(err)
+	} else if !exists {
+		return TemporaryAccessGrant{}, errors.NotFoundf("model %q", model.Id())
+	}
+	if subject.IsLocal() {
+		if _, err := st.User(subject); err != nil {
+			return TemporaryAccessGrant{}, errors.Trace(err)
+		}
+	}
+
+	grants, closer := st.db().GetCollection(temporaryAccessC)
+	defer closer()
+
+	id := temporaryAccessID(model.Id(), subject)
+	subjectKey := userGlobalKey(userAccessID(subject))
+	var doc temporaryAccessDoc
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		now := st.nowToTheSecond()
+		doc = temporaryAccessDoc{
+			DocID:     id,
+			ModelUUID: model.Id(),
+			UserName:  subject.Id(),
+			Access:    accessToString(access),
+			GrantedBy: grantedBy.Id(),
+			Granted:   now,
+			Expires:   now.Add(ttl),
+		}
+
+		var existing temporaryAccessDoc
+		err := grants.FindId(id).One(&existing)
+		if err != nil && err != mgo.ErrNotFound {
+			return nil, errors.Trace(err)
+		}
+		exists := err == nil
+		active := exists && existing.Revoked.IsZero()
+
+		var ops []txn.Op
+		current, err := st.UserAccess(subject, model)
+		switch {
+		case errors.IsNotFound(err):
+			doc.PreviousAccess = accessToString(permission.NoAccess)
+			ops = append(ops, createModelUserOps(
+				model.Id(), subject, grantedBy, "", now, access,
+			)...)
+		case err != nil:
+			return nil, errors.Trace(err)
+		case !active && current.Access.EqualOrGreaterModelAccessThan(access):
+			return nil, errors.AlreadyExistsf("%q access to model %q for user %q", current.Access, model.Id(), subject.Id())
+		default:
+			doc.PreviousAccess = accessToString(current.Access)
+			ops = append(ops, updatePermissionOp(modelKey(model.Id()), subjectKey, access))
+		}
+
+		switch {
+		case active:
+			// Keep the access the user had before the original
+			// grant, so that is what will be restored on expiry.
+			doc.PreviousAccess = existing.PreviousAccess
+			ops = append(ops, txn.Op{
+				C:      temporaryAccessC,
+				Id:     id,
+				Assert: activeTemporaryAccessDoc,
+				Update: bson.D{{"$set", bson.D{
+					{"access", doc.Access},
+					{"granted-by", doc.GrantedBy},
+					{"granted", doc.Granted},
+					{"expires", doc.Expires},
+				}}},
+			})
+		case exists:
+			// Replace the record of the previous, revoked, grant.
+			ops = append(ops, txn.Op{
+				C:      temporaryAccessC,
+				Id:     id,
+				Assert: bson.D{{"revoked", bson.D{{"$exists", true}}}},
+				Update: bson.D{
+					{"$set", bson.D{
+						{"access", doc.Access},
+						{"previous-access", doc.PreviousAccess},
+						{"granted-by", doc.GrantedBy},
+						{"granted", doc.Granted},
+						{"expires", doc.Expires},
+					}},
+					{"$unset", bson.D{{"revoked", nil}}},
+				},
+			})
+		default:
+			ops = append(ops, txn.Op{
+				C:      temporaryAccessC,
+				Id:     id,
+				Assert: txn.DocMissing,
+				Insert: &doc,
+			})
+		}
+		return ops, nil
+	}
+	db, dbCloser := st.db().CopyForModel(model.Id())
+	defer dbCloser()
+	if err := db.Run(buildTxn); err != nil {
+		return TemporaryAccessGrant{}, errors.Annotatef(err, "granting temporary access to %q", subject.Id())
+	}
+	logger.Infof("user %q granted %q access to model %q by %q until %s",
+		subject.Id(), access, model.Id(), grantedBy.Id(), doc.Expires.Format(time.RFC3339))
+	return newTemporaryAccessGrant(doc), nil
+}
+
+// TemporaryAccessGrants returns all of the temporary access grants,
+// including those that have already been revoked.
+func (st *State) TemporaryAccessGrants() ([]TemporaryAccessGrant, error) {
+	grants, closer := st.db().GetCollection(temporaryAccessC)
+	defer closer()
+
+	var docs []temporaryAccessDoc
+	if err := grants.Find(nil).Sort("expires").All(&docs); err != nil {
+		return nil, errors.Trace(err)
+	}
+	result := make([]TemporaryAccessGrant, len(docs))
+	for i, doc := range docs {
+		result[i] = newTemporaryAccessGrant(doc)
+	}
+	return result, nil
+}
+
+// RevokeExpiredTemporaryAccess restores the previous access of every user
+// whose temporary access grant has expired, records the time at which the
+// grant was revoked, and returns the grants that were revoked.
+// If a user's access to the model has been changed since the grant was
+// made, that access is left as it is and the grant is only marked revoked.
+func (st *State) RevokeExpiredTemporaryAccess() ([]TemporaryAccessGrant, error) {
+	grants, closer := st.db().GetCollection(temporaryAccessC)
+	defer closer()
+
+	var docs []temporaryAccessDoc
+	query := append(bson.D{{"expires", bson.D{{"$lte", st.clock().Now()}}}}, activeTemporaryAccessDoc...)
+	if err := grants.Find(query).All(&docs); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var revoked []TemporaryAccessGrant
+	for _, doc := range docs {
+		updated, err := st.revokeTemporaryAccess(doc)
+		if errors.Cause(err) == jujutxn.ErrNoOperations {
+			continue
+		}
+		if err != nil {
+			return revoked, errors.Annotatef(err, "revoking temporary access for %q on model %q", doc.UserName, doc.ModelUUID)
+		}
+		grant := newTemporaryAccessGrant(updated)
+		logger.Infof("revoked temporary %q access to model %q for user %q, restored %q",
+			grant.Access, doc.ModelUUID, doc.UserName, grant.PreviousAccess)
+		revoked = append(revoked, grant)
+	}
+	return revoked, nil
+}
+
+// revokeTemporaryAccess revokes the grant, queueing the permission event
+// for the revocation in the same transaction.
+func (st *State) revokeTemporaryAccess(doc temporaryAccessDoc) (temporaryAccessDoc, error) {
+	grants, closer := st.db().GetCollection(temporaryAccessC)
+	defer closer()
+
+	user := names.NewUserTag(doc.UserName)
+	objectKey := modelKey(doc.ModelUUID)
+	subjectKey := userGlobalKey(userAccessID(user))
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := grants.FindId(doc.DocID).One(&doc); err == mgo.ErrNotFound {
+				return nil, jujutxn.ErrNoOperations
+			} else if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if !doc.Revoked.IsZero() || doc.Expires.After(st.clock().Now()) {
+				return nil, jujutxn.ErrNoOperations
+			}
+		}
+		doc.Revoked = st.nowToTheSecond()
+		eventOps, err := st.permissionEventOps(PermissionEvent{
+			Kind:   PermissionRevoked,
+			User:   user,
+			Target: names.NewModelTag(doc.ModelUUID),
+			Access: permission.Access(doc.Access),
+			Time:   doc.Revoked,
+		})
+		if err != nil {
+			return nil, errors.Annotate(err, "queueing permission event")
+		}
+		ops := []txn.Op{{
+			C:      temporaryAccessC,
+			Id:     doc.DocID,
+			Assert: append(bson.D{{"expires", doc.Expires}}, activeTemporaryAccessDoc...),
+			Update: bson.D{{"$set", bson.D{{"revoked", doc.Revoked}}}},
+		}}
+
+		perm, err := st.userPermission(objectKey, subjectKey)
+		if errors.IsNotFound(err) {
+			// The user has since been removed from the model.
+			return append(ops, eventOps...), nil
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if perm.access() != permission.Access(doc.Access) {
+			// The access has been changed since the grant was
+			// made, so leave it alone.
+			return append(ops, eventOps...), nil
+		}
+		if permission.Access(doc.PreviousAccess) == permission.NoAccess {
+			ops = append(ops, removeModelUserOps(doc.ModelUUID, user)...)
+		} else {
+			ops = append(ops, updatePermissionOp(objectKey, subjectKey, permission.Access(doc.PreviousAccess)))
+		}
+		// Only restore the previous access if it hasn't been
+		// changed while the transaction was being built.
+		ops[1].Assert = bson.D{{"access", doc.Access}}
+		return append(ops, eventOps...), nil
+	}
+	db, dbCloser := st.db().CopyForModel(doc.ModelUUID)
+	defer dbCloser()
+	if err := db.Run(buildTxn); err != nil {
+		return doc, errors.Trace(err)
+	}
+	return doc, nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/core/permission"
+	"github.com/juju/juju/testing/factory"
+)
+
+type TemporaryAccessSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&TemporaryAccessSuite{})
+
+func (s *TemporaryAccessSuite) TestGrantTemporaryAccessNewModelUser(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", NoModelUser: true})
+
+	grant, err := s.State.GrantTemporaryModelAccess(
+		user.UserTag(), s.Model.ModelTag(), permission.AdminAccess, time.Hour, s.Owner)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(grant.User, gc.Equals, user.UserTag())
+	c.Assert(grant.Model, gc.Equals, s.Model.ModelTag())
+	c.Assert(grant.Access, gc.Equals, permission.AdminAccess)
+	c.Assert(grant.PreviousAccess, gc.Equals, permission.NoAccess)
+	c.Assert(grant.GrantedBy, gc.Equals, s.Owner)
+	c.Assert(grant.Expires.Sub(grant.Granted), gc.Equals, time.Hour)
+	c.Assert(grant.Revoked.IsZero(), jc.IsTrue)
+
+	access, err := s.State.UserAccess(user.UserTag(), s.Model.ModelTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(access.Access, gc.Equals, permission.AdminAccess)
+}
+
+func (s *TemporaryAccessSuite) TestGrantTemporaryAccessAlreadyHeld(c *gc.C) {
+	user := s.Factory.MakeModelUser(c, &factory.ModelUserParams{Access: permission.AdminAccess})
+
+	_, err := s.State.GrantTemporaryModelAccess(
+		user.UserTag, s.Model.ModelTag(), permission.WriteAccess, time.Hour, s.Owner)
+	c.Assert(err, jc.Satisfies, errors.IsAlreadyExists)
+}
+
+func (s *TemporaryAccessSuite) TestGrantTemporaryAccessInvalid(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", NoModelUser: true})
+
+	_, err := s.State.GrantTemporaryModelAccess(
+		user.UserTag(), s.Model.ModelTag(), permission.SuperuserAccess, time.Hour, s.Owner)
+	c.Assert(err, gc.ErrorMatches, `"superuser" model access not valid`)
+
+	_, err = s.State.GrantTemporaryModelAccess(
+		user.UserTag(), s.Model.ModelTag(), permission.AdminAccess, 0, s.Owner)
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+
+	_, err = s.State.GrantTemporaryModelAccess(
+		user.UserTag(), names.NewModelTag("deadbeef-0bad-400d-8000-4b1d0d06f00d"), permission.AdminAccess, time.Hour, s.Owner)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *TemporaryAccessSuite) TestRevokeExpiredRestoresPreviousAccess(c *gc.C) {
+	user := s.Factory.MakeModelUser(c, &factory.ModelUserParams{Access: permission.ReadAccess})
+	_, err := s.State.GrantTemporaryModelAccess(
+		user.UserTag, s.Model.ModelTag(), permission.AdminAccess, time.Hour, s.Owner)
+	c.Assert(err, jc.ErrorIsNil)
+
+	revoked, err := s.State.RevokeExpiredTemporaryAccess()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(revoked, gc.HasLen, 0)
+
+	s.Clock.Advance(time.Hour)
+	revoked, err = s.State.RevokeExpiredTemporaryAccess()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(revoked, gc.HasLen, 1)
+	c.Assert(revoked[0].User, gc.Equals, user.UserTag)
+	c.Assert(revoked[0].PreviousAccess, gc.Equals, permission.ReadAccess)
+	c.Assert(revoked[0].Revoked.IsZero(), jc.IsFalse)
+
+	access, err := s.State.UserAccess(user.UserTag, s.Model.ModelTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(access.Access, gc.Equals, permission.ReadAccess)
+
+	// The revoked grant is kept as a record.
+	grants, err := s.State.TemporaryAccessGrants()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(grants, gc.HasLen, 1)
+	c.Assert(grants[0].Revoked.IsZero(), jc.IsFalse)
+
+	revoked, err = s.State.RevokeExpiredTemporaryAccess()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(revoked, gc.HasLen, 0)
+}
+
+func (s *TemporaryAccessSuite) TestRevokeExpiredRemovesNewModelUser(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", NoModelUser: true})
+	_, err := s.State.GrantTemporaryModelAccess(
+		user.UserTag(), s.Model.ModelTag(), permission.WriteAccess, time.Minute, s.Owner)
+	c.Assert(err, jc.ErrorIsNil)
+
+	s.Clock.Advance(time.Minute)
+	revoked, err := s.State.RevokeExpiredTemporaryAccess()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(revoked, gc.HasLen, 1)
+
+	_, err = s.State.UserAccess(user.UserTag(), s.Model.ModelTag())
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *TemporaryAccessSuite) TestRevokeExpiredLeavesChangedAccess(c *gc.C) {
+	user := s.Factory.MakeModelUser(c, &factory.ModelUserParams{Access: permission.ReadAccess})
+	_, err := s.State.GrantTemporaryModelAccess(
+		user.UserTag, s.Model.ModelTag(), permission.AdminAccess, time.Hour, s.Owner)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.State.SetUserAccess(user.UserTag, s.Model.ModelTag(), permission.WriteAccess)
+	c.Assert(err, jc.ErrorIsNil)
+
+	s.Clock.Advance(time.Hour)
+	revoked, err := s.State.RevokeExpiredTemporaryAccess()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(revoked, gc.HasLen, 1)
+
+	access, err := s.State.UserAccess(user.UserTag, s.Model.ModelTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(access.Access, gc.Equals, permission.WriteAccess)
+}
+
+func (s *TemporaryAccessSuite) TestGrantTemporaryAccessExtendsActiveGrant(c *gc.C) {
+	user := s.Factory.MakeModelUser(c, &factory.ModelUserParams{Access: permission.ReadAccess})
+	_, err := s.State.GrantTemporaryModelAccess(
+		user.UserTag, s.Model.ModelTag(), permission.WriteAccess, time.Hour, s.Owner)
+	c.Assert(err, jc.ErrorIsNil)
+
+	s.Clock.Advance(30 * time.Minute)
+	grant, err := s.State.GrantTemporaryModelAccess(
+		user.UserTag, s.Model.ModelTag(), permission.AdminAccess, time.Hour, s.Owner)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(grant.Access, gc.Equals, permission.AdminAccess)
+	c.Assert(grant.PreviousAccess, gc.Equals, permission.ReadAccess)
+
+	s.Clock.Advance(30 * time.Minute)
+	revoked, err := s.State.RevokeExpiredTemporaryAccess()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(revoked, gc.HasLen, 0)
+
+	s.Clock.Advance(30 * time.Minute)
+	revoked, err = s.State.RevokeExpiredTemporaryAccess()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(revoked, gc.HasLen, 1)
+
+	access, err := s.State.UserAccess(user.UserTag, s.Model.ModelTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(access.Access, gc.Equals, permission.ReadAccess)
+}
@@ -1359,8 +1359,29 @@ func (u *Unit) Status() (status.StatusInfo, error) {
 // the effort to separate Unit from UnitAgent. Now the SetStatus for UnitAgent is in
 // the UnitAgent struct.
 func (u *Unit) SetStatus(unitStatus status.StatusInfo) error {
+	params, err := u.setStatusParams(unitStatus)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return setStatus(u.st.db(), params)
+}
+
+// SetStatusOperation returns a ModelOperation for setting the status
+// of the unit's workload. It can be composed with other operations,
+// such as SetStateOperation, using ComposeModelOperations so that all
+// of the changes are applied in a single transaction.
+func (u *Unit) SetStatusOperation(unitStatus status.StatusInfo) (ModelOperation, error) {
+	params, err := u.setStatusParams(unitStatus)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	op, err := newSetStatusOperation(u.st.db(), params)
+	return op, errors.Trace(err)
+}
+
+func (u *Unit) setStatusParams(unitStatus status.StatusInfo) (setStatusParams, error) {
 	if !status.ValidWorkloadStatus(unitStatus.Status) {
-		return errors.Errorf("cannot set invalid status %q", unitStatus.Status)
+		return setStatusParams{}, errors.Errorf("cannot set invalid status %q", unitStatus.Status)
 	}
 
 	var newHistory *statusDoc
@@ -1371,20 +1392,20 @@ func (u *Unit) SetStatus(unitStatus status.StatusInfo) error {
 		cloudContainerStatus, err := getStatus(u.st.db(), globalCloudContainerKey(u.Name()), "cloud container")
 		if err != nil {
 			if !errors.IsNotFound(err) {
-				return errors.Trace(err)
+				return setStatusParams{}, errors.Trace(err)
 			}
 		}
 		expectWorkload, err := expectWorkload(u.st, u.ApplicationName())
 		if err != nil {
-			return errors.Trace(err)
+			return setStatusParams{}, errors.Trace(err)
 		}
 		newHistory, err = caasHistoryRewriteDoc(unitStatus, cloudContainerStatus, expectWorkload, caasUnitDisplayStatus, u.st.clock())
 		if err != nil {
-			return errors.Trace(err)
+			return setStatusParams{}, errors.Trace(err)
 		}
 	}
 
-	return setStatus(u.st.db(), setStatusParams{
+	return setStatusParams{
 		badge:            "unit",
 		globalKey:        u.globalKey(),
 		status:           unitStatus.Status,
@@ -1392,7 +1413,7 @@ func (u *Unit) SetStatus(unitStatus status.StatusInfo) error {
 		rawData:          unitStatus.Data,
 		updated:          timeOrNow(unitStatus.Since, u.st.clock()),
 		historyOverwrite: newHistory,
-	})
+	}, nil
 }
 
 // OpenClosePortsOnSubnet opens and closes the given port ranges for the unit
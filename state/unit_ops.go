@@ -4,6 +4,10 @@
 package state
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/juju/errors"
 	jujutxn "github.com/juju/txn"
 	"gopkg.in/mgo.v2"
@@ -16,16 +20,70 @@ import (
 type unitSetStateOperation struct {
 	u        *Unit
 	newState *UnitState
+
+	// relationState switches the unit state document
+	// to the new relation state, if it is set.
+	relationState *relationStateSwitch
 }
 
+// The limits on the size of the charm state persisted for a unit.
+// They keep the unit state document well inside the document size
+// limit imposed by mongo.
+const (
+	maxCharmStateValueSize = 1 << 20
+	maxCharmStateSize      = 8 << 20
+)
+
 // Build implements ModelOperation.
 func (op *unitSetStateOperation) Build(attempt int) ([]txn.Op, error) {
 	if op.newState == nil || !op.newState.Modified() {
 		return nil, jujutxn.ErrNoOperations
 	}
+	if uState, found := op.newState.State(); found {
+		if err := validateCharmState(uState); err != nil {
+			return nil, errors.Annotatef(err, "cannot persist state for unit %q", op.u)
+		}
+	}
 	return op.buildTxn(attempt)
 }
 
+// validateCharmState returns an ErrInvalidUnitState listing the keys
+// of the charm state that cannot be stored, or that would not be read
+// back as they were written, and the keys whose values are too big.
+func validateCharmState(uState map[string]string) error {
+	var (
+		invalid   []InvalidUnitStateKey
+		totalSize int
+	)
+	for k, v := range uState {
+		size := len(k) + len(v)
+		totalSize += size
+		var reason string
+		switch {
+		case k == "":
+			reason = "is empty"
+		case strings.Contains(k, "\x00"):
+			reason = "contains a null character"
+		case mgoutils.UnescapeKey(mgoutils.EscapeKey(k)) != k:
+			reason = "contains a full-width dot or dollar sign"
+		case size > maxCharmStateValueSize:
+			reason = fmt.Sprintf("and its value total %d bytes, exceeding the %d byte limit", size, maxCharmStateValueSize)
+		default:
+			continue
+		}
+		invalid = append(invalid, InvalidUnitStateKey{Key: k, Size: size, Reason: reason})
+	}
+	if len(invalid) == 0 && totalSize <= maxCharmStateSize {
+		return nil
+	}
+	sort.Slice(invalid, func(i, j int) bool { return invalid[i].Key < invalid[j].Key })
+	err := &ErrInvalidUnitState{Keys: invalid}
+	if totalSize > maxCharmStateSize {
+		err.TotalSize = totalSize
+	}
+	return err
+}
+
 func (op *unitSetStateOperation) buildTxn(attempt int) ([]txn.Op, error) {
 	if attempt > 0 {
 		if err := op.u.Refresh(); err != nil {
@@ -47,26 +105,95 @@ func (op *unitSetStateOperation) buildTxn(attempt int) ([]txn.Op, error) {
 		Assert: isAliveDoc,
 	}
 
-	var stDoc unitStateDoc
+	ops := []txn.Op{unitAliveOp}
+
+	// Charm state written while the unit tracks an in-flight branch is
+	// kept apart from the master charm state until the branch is
+	// committed. The branch must still be in-flight when it is written.
+	var branchKey string
+	_, stateSet := op.newState.State()
+	_, _, stateMerged := op.newState.StateChanges()
+	if stateSet || stateMerged {
+		branch, err := op.u.stateBranch()
+		if err != nil {
+			return nil, errors.Annotatef(err, "cannot persist state for unit %q", op.u)
+		}
+		if branch != nil {
+			branchKey = branchStateKey(branch.BranchName())
+			ops = append(ops, txn.Op{
+				C:      generationsC,
+				Id:     branch.doc.DocId,
+				Assert: bson.D{{"completed", 0}},
+			})
+		}
+	}
+
+	// Relation state too big for the unit state document overflows
+	// into chunks held by sibling documents, which are written before
+	// the unit state document is switched to them.
+	var rChunks []map[string]string
 	unitGlobalKey := op.u.globalKey()
+	rState, rStateSet := op.newState.RelationState()
+	if rStateSet {
+		rChunks = splitRelationState(rState)
+		if op.relationState == nil {
+			op.relationState = op.u.st.newRelationStateSwitch(unitGlobalKey)
+		}
+	}
+
+	var stDoc unitStateDoc
 	if err := coll.FindId(unitGlobalKey).One(&stDoc); err != nil {
 		if err != mgo.ErrNotFound {
 			return nil, errors.Annotatef(err, "cannot persist state for unit %q", op.u)
 		}
 
-		return []txn.Op{unitAliveOp, {
+		if err := op.validateMerge(unitStateDoc{}, branchKey); err != nil {
+			return nil, errors.Annotatef(err, "cannot persist state for unit %q", op.u)
+		}
+		newStDoc := op.newUnitStateDoc(unitGlobalKey, branchKey)
+		if rStateSet {
+			if _, _, err := op.relationState.update(&unitStateDoc{}, rChunks); err != nil {
+				return nil, errors.Annotatef(err, "cannot persist state for unit %q", op.u)
+			}
+			if len(rChunks) > 0 {
+				newStDoc.RelationState = rChunks[0]
+			}
+			if len(rChunks) > 1 {
+				newStDoc.RelationStateChunks = len(rChunks) - 1
+				newStDoc.RelationStateGeneration = op.relationState.generation
+			}
+		}
+		return append(ops, txn.Op{
 			C:      unitStatesC,
 			Id:     unitGlobalKey,
 			Assert: txn.DocMissing,
-			Insert: op.newUnitStateDoc(unitGlobalKey),
-		}}, nil
+			Insert: newStDoc,
+		}), nil
 	}
 
 	// We have an existing doc, see what changes need to be made.
-	setFields, unsetFields := op.fields(stDoc)
+	if err := op.validateMerge(stDoc, branchKey); err != nil {
+		return nil, errors.Annotatef(err, "cannot persist state for unit %q", op.u)
+	}
+	setFields, unsetFields := op.fields(stDoc, branchKey)
+	if rStateSet {
+		rSetFields, rUnsetFields, err := op.relationState.update(&stDoc, rChunks)
+		if err != nil {
+			return nil, errors.Annotatef(err, "cannot persist state for unit %q", op.u)
+		}
+		setFields = append(setFields, rSetFields...)
+		unsetFields = append(unsetFields, rUnsetFields...)
+	}
 	if len(setFields) <= 0 && len(unsetFields) <= 0 {
 		return nil, jujutxn.ErrNoOperations
 	}
+	stateOp := txn.Op{
+		C:  unitStatesC,
+		Id: unitGlobalKey,
+		Assert: bson.D{
+			{"txn-revno", stDoc.TxnRevno},
+		},
+	}
 	updateFields := bson.D{}
 	if len(setFields) > 0 {
 		updateFields = append(updateFields, bson.DocElem{"$set", setFields})
@@ -74,50 +201,67 @@ func (op *unitSetStateOperation) buildTxn(attempt int) ([]txn.Op, error) {
 	if len(unsetFields) > 0 {
 		updateFields = append(updateFields, bson.DocElem{"$unset", unsetFields})
 	}
-	return []txn.Op{unitAliveOp, {
-		C:  unitStatesC,
-		Id: unitGlobalKey,
-		Assert: bson.D{
-			{"txn-revno", stDoc.TxnRevno},
-		},
-		Update: updateFields,
-	}}, nil
+	stateOp.Update = updateFields
+	return append(ops, stateOp), nil
 }
 
-func (op *unitSetStateOperation) newUnitStateDoc(unitGlobalKey string) unitStateDoc {
+func (op *unitSetStateOperation) newUnitStateDoc(unitGlobalKey, branchKey string) unitStateDoc {
 	newStDoc := unitStateDoc{
-		DocID: unitGlobalKey,
+		DocID:       unitGlobalKey,
+		Application: op.u.doc.Application,
 	}
-	if uState, found := op.newState.State(); found {
+	uState, found := op.newState.State()
+	if changes, _, merged := op.newState.StateChanges(); merged {
+		uState, found = changes, true
+	}
+	if found {
 		escapedState := make(map[string]string, len(uState))
 		for k, v := range uState {
 			escapedState[mgoutils.EscapeKey(k)] = v
 		}
-		newStDoc.State = escapedState
-	}
-	if rState, found := op.newState.relationStateBSONFriendly(); found {
-		newStDoc.RelationState = rState
+		if branchKey != "" {
+			newStDoc.BranchState = map[string]map[string]string{branchKey: escapedState}
+		} else {
+			newStDoc.State = escapedState
+		}
 	}
 	if uniterState, found := op.newState.UniterState(); found {
-		newStDoc.UniterState = uniterState
+		newStDoc.UniterState = &uniterState
 	}
 	if storState, found := op.newState.StorageState(); found {
-		newStDoc.StorageState = storState
+		newStDoc.StorageState = &storState
 	}
 	return newStDoc
 }
 
 // fields returns set and unset bson required to update the unit state doc
-// based the current data stored compared to this operation.
-func (op *unitSetStateOperation) fields(currentDoc unitStateDoc) (bson.D, bson.D) {
+// based the current data stored compared to this operation. If branchKey
+// is not empty, charm state is written to the branch state with that key.
+// The relation state is switched separately, by a relationStateSwitch.
+func (op *unitSetStateOperation) fields(currentDoc unitStateDoc, branchKey string) (bson.D, bson.D) {
 	// Handling fields of op.newState:
 	// If a pointer is nil, ignore it.
-	// If the value referenced by the pointer is empty, remove that thing.
+	// If the value referenced by the pointer is an empty map, remove that thing.
+	// An empty string is kept, so that it is not confused with a string never written.
 	// If there is a value referenced by the pointer, set the value if a string, or merge the data.
 	setFields := bson.D{}
 	unsetFields := bson.D{}
 
-	if uState, found := op.newState.State(); found {
+	if _, _, merged := op.newState.StateChanges(); merged {
+		set, unset := op.mergeFields(currentDoc, branchKey)
+		setFields = append(setFields, set...)
+		unsetFields = append(unsetFields, unset...)
+	} else if uState, found := op.newState.State(); found && branchKey != "" {
+		// Empty branch state is kept, so that it is not confused with
+		// the charm not having written any state on the branch.
+		escapedState := make(bson.M, len(uState))
+		for k, v := range uState {
+			escapedState[mgoutils.EscapeKey(k)] = v
+		}
+		if !currentDoc.branchStateMatches(branchKey, escapedState) {
+			setFields = append(setFields, bson.DocElem{"branch-state." + branchKey, escapedState})
+		}
+	} else if found {
 		if len(uState) == 0 {
 			unsetFields = append(unsetFields, bson.DocElem{Name: "state"})
 		} else {
@@ -133,31 +277,94 @@ func (op *unitSetStateOperation) fields(currentDoc unitStateDoc) (bson.D, bson.D
 	}
 
 	if uniterState, found := op.newState.UniterState(); found {
-		if uniterState == "" {
-			unsetFields = append(unsetFields, bson.DocElem{Name: "uniter-state"})
-		} else if uniterState != currentDoc.UniterState {
+		if currentDoc.UniterState == nil || uniterState != *currentDoc.UniterState {
 			setFields = append(setFields, bson.DocElem{"uniter-state", uniterState})
 		}
 	}
 
-	if rState, found := op.newState.relationStateBSONFriendly(); found {
-		if len(rState) == 0 {
-			unsetFields = append(unsetFields, bson.DocElem{Name: "relation-state"})
-		} else if matches := currentDoc.relationStateMatches(rState); !matches {
-			setFields = append(setFields, bson.DocElem{"relation-state", rState})
+	if storState, found := op.newState.StorageState(); found {
+		if currentDoc.StorageState == nil || storState != *currentDoc.StorageState {
+			setFields = append(setFields, bson.DocElem{"storage-state", storState})
 		}
 	}
 
-	if storState, found := op.newState.StorageState(); found {
-		if storState == "" {
-			unsetFields = append(unsetFields, bson.DocElem{Name: "storage-state"})
-		} else if storState != currentDoc.StorageState {
-			setFields = append(setFields, bson.DocElem{"storage-state", storState})
+	return setFields, unsetFields
+}
+
+// mergeFields returns the set and unset bson required to merge the
+// changes to individual keys of the charm state into the state stored
+// in the current doc, one field per key, so that keys written by other
+// updates are preserved. If branchKey is not empty, the changes are
+// merged into the branch state with that key, which is first copied
+// from the master charm state if the unit has not yet written any
+// state while tracking the branch.
+func (op *unitSetStateOperation) mergeFields(currentDoc unitStateDoc, branchKey string) (bson.D, bson.D) {
+	setFields := bson.D{}
+	unsetFields := bson.D{}
+
+	field, current := "state", currentDoc.State
+	if branchKey != "" {
+		branchState, ok := currentDoc.BranchState[branchKey]
+		if !ok {
+			merged := op.newState.mergeInto(unescapeCharmState(currentDoc.State))
+			escapedState := make(bson.M, len(merged))
+			for k, v := range merged {
+				escapedState[mgoutils.EscapeKey(k)] = v
+			}
+			return append(setFields, bson.DocElem{"branch-state." + branchKey, escapedState}), unsetFields
 		}
+		field, current = "branch-state."+branchKey, branchState
 	}
 
+	changes, deleted, _ := op.newState.StateChanges()
+	for _, k := range deleted {
+		key := mgoutils.EscapeKey(k)
+		if _, ok := current[key]; ok {
+			unsetFields = append(unsetFields, bson.DocElem{Name: field + "." + key})
+		}
+	}
+	keys := make([]string, 0, len(changes))
+	for k := range changes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		key := mgoutils.EscapeKey(k)
+		if cur, ok := current[key]; !ok || cur != changes[k] {
+			setFields = append(setFields, bson.DocElem{field + "." + key, changes[k]})
+		}
+	}
 	return setFields, unsetFields
 }
 
+// validateMerge checks that the charm state resulting from merging the
+// changes to individual keys into the state stored in the current doc
+// can be stored.
+func (op *unitSetStateOperation) validateMerge(currentDoc unitStateDoc, branchKey string) error {
+	if _, _, merged := op.newState.StateChanges(); !merged {
+		return nil
+	}
+	current := currentDoc.State
+	if branchState, ok := currentDoc.BranchState[branchKey]; ok && branchKey != "" {
+		current = branchState
+	}
+	return validateCharmState(op.newState.mergeInto(unescapeCharmState(current)))
+}
+
+// unescapeCharmState returns the charm state
+// stored with the input escaped keys.
+func unescapeCharmState(escaped map[string]string) map[string]string {
+	result := make(map[string]string, len(escaped))
+	for k, v := range escaped {
+		result[mgoutils.UnescapeKey(k)] = v
+	}
+	return result
+}
+
 // Done implements ModelOperation.
-func (op *unitSetStateOperation) Done(err error) error { return err }
+func (op *unitSetStateOperation) Done(err error) error {
+	if op.relationState != nil {
+		op.relationState.done(err)
+	}
+	return err
+}
@@ -6,6 +6,7 @@ package state_test
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time" // Only used for time types.
 
 	"github.com/juju/errors"
@@ -196,6 +197,153 @@ func (s *UnitSuite) TestUnitStateDeleteState(c *gc.C) {
 	assertUnitStateStorageState(c, uState, initialStorageState)
 }
 
+func (s *UnitSuite) TestUnitStateMergeState(c *gc.C) {
+	_, initialUniterState, initialRelationState, initialStorageState := s.testUnitSuite(c)
+
+	newUS := state.NewUnitState()
+	newUS.MergeState(map[string]string{
+		"foo":     "baz",
+		"new.key": "new",
+	}, []string{"key.with.$", "missing"})
+	err := s.unit.SetState(newUS)
+	c.Assert(err, jc.ErrorIsNil)
+
+	uState, err := s.unit.State()
+	c.Assert(err, jc.ErrorIsNil)
+	assertUnitStateState(c, uState, map[string]string{
+		"foo":          "baz",
+		"key.with.dot": "must work",
+		"new.key":      "new",
+	})
+	assertUnitStateUniterState(c, uState, initialUniterState)
+	assertUnitStateRelationState(c, uState, initialRelationState)
+	assertUnitStateStorageState(c, uState, initialStorageState)
+}
+
+func (s *UnitSuite) TestUnitStateMergeStateNoStateDoc(c *gc.C) {
+	newUS := state.NewUnitState()
+	newUS.MergeState(map[string]string{"foo": "bar"}, nil)
+	err := s.unit.SetState(newUS)
+	c.Assert(err, jc.ErrorIsNil)
+
+	uState, err := s.unit.State()
+	c.Assert(err, jc.ErrorIsNil)
+	assertUnitStateState(c, uState, map[string]string{"foo": "bar"})
+}
+
+func (s *UnitSuite) TestUnitStateMergeStateConcurrentKeysSurvive(c *gc.C) {
+	s.testUnitSuite(c)
+
+	defer state.SetBeforeHooks(c, s.State, func() {
+		us := state.NewUnitState()
+		us.MergeState(map[string]string{"written-by": "other hook"}, nil)
+		c.Assert(s.unit.SetState(us), jc.ErrorIsNil)
+	}).Check()
+
+	newUS := state.NewUnitState()
+	newUS.MergeState(map[string]string{"written-by-me": "this hook"}, []string{"foo"})
+	err := s.unit.SetState(newUS)
+	c.Assert(err, jc.ErrorIsNil)
+
+	uState, err := s.unit.State()
+	c.Assert(err, jc.ErrorIsNil)
+	assertUnitStateState(c, uState, map[string]string{
+		"key.with.dot":  "must work",
+		"key.with.$":    "must work to",
+		"written-by":    "other hook",
+		"written-by-me": "this hook",
+	})
+}
+
+func (s *UnitSuite) TestUnitStateMergeStateInvalidKey(c *gc.C) {
+	initialState, _, _, _ := s.testUnitSuite(c)
+
+	newUS := state.NewUnitState()
+	newUS.MergeState(map[string]string{"a\x00b": "1"}, nil)
+	err := s.unit.SetState(newUS)
+	c.Assert(err, jc.Satisfies, state.IsInvalidUnitStateError)
+
+	uState, err := s.unit.State()
+	c.Assert(err, jc.ErrorIsNil)
+	assertUnitStateState(c, uState, initialState)
+}
+
+func (s *UnitSuite) TestUnitStateMergeIntoSetState(c *gc.C) {
+	us := state.NewUnitState()
+	us.SetState(map[string]string{"foo": "bar", "baz": "qux"})
+	us.MergeState(map[string]string{"new": "value"}, []string{"baz"})
+	st, found := us.State()
+	c.Assert(found, jc.IsTrue)
+	c.Check(st, jc.DeepEquals, map[string]string{"foo": "bar", "new": "value"})
+	_, _, merged := us.StateChanges()
+	c.Check(merged, jc.IsFalse)
+
+	// Setting the whole state discards any changes merged before.
+	us = state.NewUnitState()
+	us.MergeState(map[string]string{"new": "value"}, nil)
+	us.MergeState(nil, []string{"new"})
+	changes, deleted, merged := us.StateChanges()
+	c.Check(merged, jc.IsTrue)
+	c.Check(changes, gc.HasLen, 0)
+	c.Check(deleted, jc.DeepEquals, []string{"new"})
+	us.SetState(map[string]string{"foo": "bar"})
+	_, _, merged = us.StateChanges()
+	c.Check(merged, jc.IsFalse)
+}
+
+func (s *UnitSuite) TestUnitStateClearUniterState(c *gc.C) {
+	// Set initial state; this should create a new unitstate doc
+	initialState, _, initialRelationState, initialStorageState := s.testUnitSuite(c)
+
+	newUS := state.NewUnitState()
+	newUS.SetUniterState("")
+	err := s.unit.SetState(newUS)
+	c.Assert(err, gc.IsNil)
+
+	// Ensure the uniter state is reported as cleared, not as never written.
+	uState, err := s.unit.State()
+	c.Assert(err, gc.IsNil)
+	assertUnitStateUniterState(c, uState, "")
+
+	// Ensure the other state did not change.
+	assertUnitStateState(c, uState, initialState)
+	assertUnitStateRelationState(c, uState, initialRelationState)
+	assertUnitStateStorageState(c, uState, initialStorageState)
+}
+
+func (s *UnitSuite) TestUnitStateClearStorageState(c *gc.C) {
+	// Set initial state; this should create a new unitstate doc
+	initialState, initialUniterState, initialRelationState, _ := s.testUnitSuite(c)
+
+	newUS := state.NewUnitState()
+	newUS.SetStorageState("")
+	err := s.unit.SetState(newUS)
+	c.Assert(err, gc.IsNil)
+
+	// Ensure the storage state is reported as cleared, not as never written.
+	uState, err := s.unit.State()
+	c.Assert(err, gc.IsNil)
+	assertUnitStateStorageState(c, uState, "")
+
+	// Ensure the other state did not change.
+	assertUnitStateState(c, uState, initialState)
+	assertUnitStateUniterState(c, uState, initialUniterState)
+	assertUnitStateRelationState(c, uState, initialRelationState)
+}
+
+func (s *UnitSuite) TestUnitStateEmptyStorageStateNoStateDoc(c *gc.C) {
+	newUS := state.NewUnitState()
+	newUS.SetStorageState("")
+	err := s.unit.SetState(newUS)
+	c.Assert(err, gc.IsNil)
+
+	uState, err := s.unit.State()
+	c.Assert(err, gc.IsNil)
+	assertUnitStateStorageState(c, uState, "")
+	_, found := uState.UniterState()
+	c.Assert(found, jc.IsFalse)
+}
+
 func (s *UnitSuite) TestUnitStateDeleteRelationState(c *gc.C) {
 	// Set initial state; this should create a new unitstate doc
 	initialState, initialUniterState, _, initialStorageState := s.testUnitSuite(c)
@@ -281,6 +429,484 @@ func assertUnitStateStorageState(c *gc.C, uState *state.UnitState, expected stri
 	c.Assert(obtained, gc.Equals, expected)
 }
 
+func (s *UnitSuite) TestUnitStateDyingReadOnly(c *gc.C) {
+	initialState, initialUniterState, initialRelationState, initialStorageState := s.testUnitSuite(c)
+
+	preventUnitDestroyRemove(c, s.unit)
+	err := s.unit.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.unit.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.unit.Life(), gc.Equals, state.Dying)
+
+	// State can be read while the unit is being torn down.
+	uState, err := s.unit.State()
+	c.Assert(err, jc.ErrorIsNil)
+	assertUnitStateState(c, uState, initialState)
+	assertUnitStateUniterState(c, uState, initialUniterState)
+	assertUnitStateRelationState(c, uState, initialRelationState)
+	assertUnitStateStorageState(c, uState, initialStorageState)
+
+	// But it can not be written.
+	newUS := state.NewUnitState()
+	newUS.SetState(map[string]string{"foo": "42"})
+	err = s.unit.SetState(newUS)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *UnitSuite) TestUnitStateInvalidKeys(c *gc.C) {
+	initialState, _, _, _ := s.testUnitSuite(c)
+
+	newUS := state.NewUnitState()
+	newUS.SetState(map[string]string{
+		"ok":            "fine",
+		"a\x00b":        "1",
+		"full\uff0ekey": "2",
+		"big":           strings.Repeat("x", 1<<20),
+	})
+	err := s.unit.SetState(newUS)
+	c.Assert(err, jc.Satisfies, state.IsInvalidUnitStateError)
+	c.Assert(err, gc.ErrorMatches, `cannot persist state for unit "wordpress/0": invalid charm state: `+
+		`key "a\\x00b" contains a null character; `+
+		`key "big" and its value total 1048579 bytes, exceeding the 1048576 byte limit; `+
+		`key "full．key" contains a full-width dot or dollar sign`)
+	c.Assert(errors.Cause(err).(*state.ErrInvalidUnitState).Keys, gc.HasLen, 3)
+
+	// Nothing is written.
+	uState, err := s.unit.State()
+	c.Assert(err, jc.ErrorIsNil)
+	assertUnitStateState(c, uState, initialState)
+}
+
+func (s *UnitSuite) TestUnitStateTotalSizeLimit(c *gc.C) {
+	s.testUnitSuite(c)
+
+	charmState := make(map[string]string)
+	for i := 0; i < 9; i++ {
+		charmState[fmt.Sprintf("key-%d", i)] = strings.Repeat("x", 1<<20-5)
+	}
+	newUS := state.NewUnitState()
+	newUS.SetState(charmState)
+	err := s.unit.SetState(newUS)
+	c.Assert(err, jc.Satisfies, state.IsInvalidUnitStateError)
+	c.Assert(err, gc.ErrorMatches, `.*invalid charm state: total size of 9437184 bytes exceeds the 8388608 byte limit`)
+}
+
+func (s *UnitSuite) countRelationStateChunks(c *gc.C) int {
+	n, err := s.Session.DB("juju").C(state.UnitStateChunksC).Count()
+	c.Assert(err, jc.ErrorIsNil)
+	return n
+}
+
+func (s *UnitSuite) TestUnitStateRelationStateBeyondDocumentSize(c *gc.C) {
+	initialState, initialUniterState, _, initialStorageState := s.testUnitSuite(c)
+
+	// Twenty relations of 1MiB each is more than a 16MiB
+	// BSON document can hold, so the state is split into
+	// chunks of three relations.
+	relationState := make(map[int]string)
+	for i := 1; i <= 20; i++ {
+		relationState[i] = strings.Repeat("x", 1<<20)
+	}
+	newUS := state.NewUnitState()
+	newUS.SetRelationState(relationState)
+	err := s.unit.SetState(newUS)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.countRelationStateChunks(c), gc.Equals, 6)
+
+	uState, err := s.unit.State()
+	c.Assert(err, jc.ErrorIsNil)
+	assertUnitStateRelationState(c, uState, relationState)
+	assertUnitStateState(c, uState, initialState)
+	assertUnitStateUniterState(c, uState, initialUniterState)
+	assertUnitStateStorageState(c, uState, initialStorageState)
+
+	// The chunks of each version of the state are written by
+	// transactions of their own, as mgo/txn holds the documents
+	// inserted by a transaction in its transaction document, so
+	// replacing one version beyond the document size limit with
+	// another does not hit the limit either. The chunks of the
+	// version replaced are removed.
+	for i := 1; i <= 20; i++ {
+		relationState[i] = strings.Repeat("y", 1<<20)
+	}
+	relationState[21] = strings.Repeat("z", 1<<20)
+	relationState[22] = strings.Repeat("z", 1<<20)
+	newUS.SetRelationState(relationState)
+	err = s.unit.SetState(newUS)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.countRelationStateChunks(c), gc.Equals, 7)
+
+	uState, err = s.unit.State()
+	c.Assert(err, jc.ErrorIsNil)
+	assertUnitStateRelationState(c, uState, relationState)
+
+	// Once the state fits in the unit state document again,
+	// the chunks are removed.
+	relationState = map[int]string{1: "one", 2: "two"}
+	newUS.SetRelationState(relationState)
+	err = s.unit.SetState(newUS)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.countRelationStateChunks(c), gc.Equals, 0)
+
+	uState, err = s.unit.State()
+	c.Assert(err, jc.ErrorIsNil)
+	assertUnitStateRelationState(c, uState, relationState)
+}
+
+func (s *UnitSuite) TestUnitStateRelationStateChunkBoundary(c *gc.C) {
+	s.PatchValue(state.RelationStateChunkSize, 10)
+
+	// Each entry is 5 bytes of key and value,
+	// so two fill the unit state document.
+	relationState := map[int]string{1: "abcd", 2: "efgh"}
+	newUS := state.NewUnitState()
+	newUS.SetRelationState(relationState)
+	err := s.unit.SetState(newUS)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.countRelationStateChunks(c), gc.Equals, 0)
+
+	// A single byte more overflows into a chunk.
+	relationState[3] = ""
+	err = s.unit.SetState(newUS)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.countRelationStateChunks(c), gc.Equals, 1)
+
+	uState, err := s.unit.State()
+	c.Assert(err, jc.ErrorIsNil)
+	assertUnitStateRelationState(c, uState, relationState)
+
+	// Changing only the overflowed entries replaces the chunk.
+	relationState[3] = "ijkl"
+	relationState[4] = "mnop"
+	err = s.unit.SetState(newUS)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.countRelationStateChunks(c), gc.Equals, 1)
+
+	states, err := s.State.ApplicationUnitStates(s.application.Name())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(states, gc.HasLen, 1)
+	assertUnitStateRelationState(c, states[s.unit.Name()], relationState)
+
+	it, err := s.State.UnitStates("", 10)
+	c.Assert(err, jc.ErrorIsNil)
+	entries, err := it.Next()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(entries, gc.HasLen, 1)
+	assertUnitStateRelationState(c, entries[0].State, relationState)
+
+	// Writing the same state again is a no-op.
+	defer state.SetFailIfTransaction(c, s.State).Check()
+	err = s.unit.SetState(newUS)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *UnitSuite) TestUnitStateRelationStateChunksRemovedWithUnit(c *gc.C) {
+	s.PatchValue(state.RelationStateChunkSize, 10)
+
+	newUS := state.NewUnitState()
+	newUS.SetRelationState(map[int]string{1: "abcd", 2: "efgh", 3: "ijkl", 4: "mnop", 5: "qrst"})
+	err := s.unit.SetState(newUS)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.countRelationStateChunks(c), gc.Equals, 2)
+
+	err = s.unit.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.unit.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.countRelationStateChunks(c), gc.Equals, 0)
+}
+
+func (s *UnitSuite) TestUnitStateDeadNotFound(c *gc.C) {
+	s.testUnitSuite(c)
+
+	err := s.unit.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = s.unit.State()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *UnitSuite) TestUnitStateValue(c *gc.C) {
+	s.testUnitSuite(c)
+
+	value, ok, err := s.unit.StateValue("key.with.$")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(ok, jc.IsTrue)
+	c.Check(value, gc.Equals, "must work to")
+
+	_, ok, err = s.unit.StateValue("missing")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(ok, jc.IsFalse)
+
+	values, err := s.unit.StateValues("foo", "key.with.dot", "missing")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(values, jc.DeepEquals, map[string]string{
+		"foo":          "bar",
+		"key.with.dot": "must work",
+	})
+}
+
+func (s *UnitSuite) TestUnitStateValueNoStateDoc(c *gc.C) {
+	_, ok, err := s.unit.StateValue("foo")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(ok, jc.IsFalse)
+}
+
+func (s *UnitSuite) TestUnitStateValueDeadNotFound(c *gc.C) {
+	s.testUnitSuite(c)
+
+	err := s.unit.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, _, err = s.unit.StateValue("foo")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *UnitSuite) TestApplicationUnitStates(c *gc.C) {
+	initialState, initialUniterState, initialRelationState, initialStorageState := s.testUnitSuite(c)
+
+	// Units without persisted state are not included.
+	_, err := s.application.AddUnit(state.AddUnitParams{})
+	c.Assert(err, jc.ErrorIsNil)
+	// Nor are the units of other applications.
+	other := s.AddTestingApplication(c, "mysql", s.AddTestingCharm(c, "mysql"))
+	otherUnit, err := other.AddUnit(state.AddUnitParams{})
+	c.Assert(err, jc.ErrorIsNil)
+	err = otherUnit.SwapUniterState(state.UniterStateHash(""), "other")
+	c.Assert(err, jc.ErrorIsNil)
+
+	states, err := s.State.ApplicationUnitStates("wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(states, gc.HasLen, 1)
+	uState := states[s.unit.Name()]
+	c.Assert(uState, gc.NotNil)
+	assertUnitStateState(c, uState, initialState)
+	assertUnitStateUniterState(c, uState, initialUniterState)
+	assertUnitStateRelationState(c, uState, initialRelationState)
+	assertUnitStateStorageState(c, uState, initialStorageState)
+
+	n, err := s.State.CountApplicationUnitStates("wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(n, gc.Equals, 1)
+	n, err = s.State.CountApplicationUnitStates("mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(n, gc.Equals, 1)
+}
+
+func (s *UnitSuite) TestUniterStateFormats(c *gc.C) {
+	us := state.NewUnitState()
+	us.SetUniterState("uniter")
+	us.SetStorageState("")
+	err := s.unit.SetState(us)
+	c.Assert(err, jc.ErrorIsNil)
+
+	uniterOnly, err := s.application.AddUnit(state.AddUnitParams{})
+	c.Assert(err, jc.ErrorIsNil)
+	err = uniterOnly.SwapUniterState(state.UniterStateHash(""), "uniter")
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Units with only charm state persisted, and units with no
+	// persisted state at all, keep their uniter state locally.
+	charmOnly, err := s.application.AddUnit(state.AddUnitParams{})
+	c.Assert(err, jc.ErrorIsNil)
+	us = state.NewUnitState()
+	us.SetState(map[string]string{"foo": "bar"})
+	err = charmOnly.SetState(us)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.application.AddUnit(state.AddUnitParams{})
+	c.Assert(err, jc.ErrorIsNil)
+
+	formats, err := s.State.UniterStateFormats()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(formats, jc.DeepEquals, map[state.UniterStateFormat]int{
+		state.UniterStateFormatLocal:      2,
+		state.UniterStateFormatUniterOnly: 1,
+		state.UniterStateFormatController: 1,
+	})
+}
+
+func (s *UnitSuite) TestUnitStatesIterator(c *gc.C) {
+	units := []*state.Unit{s.unit}
+	for i := 0; i < 2; i++ {
+		unit, err := s.application.AddUnit(state.AddUnitParams{})
+		c.Assert(err, jc.ErrorIsNil)
+		units = append(units, unit)
+	}
+	// Units without persisted state are not included.
+	_, err := s.application.AddUnit(state.AddUnitParams{})
+	c.Assert(err, jc.ErrorIsNil)
+	for _, unit := range units {
+		err := unit.SwapUniterState(state.UniterStateHash(""), unit.Name())
+		c.Assert(err, jc.ErrorIsNil)
+	}
+
+	it, err := s.State.UnitStates("", 2)
+	c.Assert(err, jc.ErrorIsNil)
+	batch, err := it.Next()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(batch, gc.HasLen, 2)
+	for i, entry := range batch {
+		c.Check(entry.UnitName, gc.Equals, units[i].Name())
+		assertUnitStateUniterState(c, entry.State, units[i].Name())
+	}
+	c.Assert(it.Resume(), gc.Equals, units[1].Name())
+
+	// A new iterator resumes after the units already read.
+	it, err = s.State.UnitStates(it.Resume(), 2)
+	c.Assert(err, jc.ErrorIsNil)
+	batch, err = it.Next()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(batch, gc.HasLen, 1)
+	c.Check(batch[0].UnitName, gc.Equals, units[2].Name())
+	c.Assert(it.Resume(), gc.Equals, units[2].Name())
+
+	batch, err = it.Next()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(batch, gc.HasLen, 0)
+}
+
+func (s *UnitSuite) TestUnitStatesIteratorInvalidArgs(c *gc.C) {
+	_, err := s.State.UnitStates("", 0)
+	c.Assert(err, gc.ErrorMatches, "unit state batch size 0 not valid")
+	_, err = s.State.UnitStates("wordpress", 10)
+	c.Assert(err, gc.ErrorMatches, `unit state resume token "wordpress" not valid`)
+}
+
+func (s *UnitSuite) TestSwapUniterState(c *gc.C) {
+	initialState, initialUniterState, initialRelationState, initialStorageState := s.testUnitSuite(c)
+
+	err := s.unit.SwapUniterState(state.UniterStateHash(initialUniterState), "upgraded")
+	c.Assert(err, jc.ErrorIsNil)
+
+	uState, err := s.unit.State()
+	c.Assert(err, jc.ErrorIsNil)
+	assertUnitStateUniterState(c, uState, "upgraded")
+
+	// Ensure the other state did not change.
+	assertUnitStateState(c, uState, initialState)
+	assertUnitStateRelationState(c, uState, initialRelationState)
+	assertUnitStateStorageState(c, uState, initialStorageState)
+}
+
+func (s *UnitSuite) TestSwapUniterStateNoExistingState(c *gc.C) {
+	err := s.unit.SwapUniterState(state.UniterStateHash(""), "upgraded")
+	c.Assert(err, jc.ErrorIsNil)
+
+	uState, err := s.unit.State()
+	c.Assert(err, jc.ErrorIsNil)
+	assertUnitStateUniterState(c, uState, "upgraded")
+}
+
+func (s *UnitSuite) TestSwapUniterStateMismatch(c *gc.C) {
+	_, initialUniterState, _, _ := s.testUnitSuite(c)
+
+	err := s.unit.SwapUniterState(state.UniterStateHash("stale"), "upgraded")
+	c.Assert(errors.Cause(err), gc.Equals, state.ErrUniterStateChanged)
+
+	uState, err := s.unit.State()
+	c.Assert(err, jc.ErrorIsNil)
+	assertUnitStateUniterState(c, uState, initialUniterState)
+}
+
+func (s *UnitSuite) TestSwapUniterStateConcurrentUpdate(c *gc.C) {
+	_, initialUniterState, _, _ := s.testUnitSuite(c)
+
+	defer state.SetBeforeHooks(c, s.State, func() {
+		us := state.NewUnitState()
+		us.SetUniterState("written by uniter")
+		c.Assert(s.unit.SetState(us), jc.ErrorIsNil)
+	}).Check()
+
+	err := s.unit.SwapUniterState(state.UniterStateHash(initialUniterState), "upgraded")
+	c.Assert(errors.Cause(err), gc.Equals, state.ErrUniterStateChanged)
+
+	uState, err := s.unit.State()
+	c.Assert(err, jc.ErrorIsNil)
+	assertUnitStateUniterState(c, uState, "written by uniter")
+}
+
+func (s *UnitSuite) TestSwapUniterStateDying(c *gc.C) {
+	_, initialUniterState, _, _ := s.testUnitSuite(c)
+
+	preventUnitDestroyRemove(c, s.unit)
+	err := s.unit.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.unit.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.unit.SwapUniterState(state.UniterStateHash(initialUniterState), "upgraded")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *UnitSuite) TestSetStateWithStatusAndAnnotationsOperation(c *gc.C) {
+	newUS := state.NewUnitState()
+	newUS.SetState(map[string]string{"foo": "bar"})
+	stateOp := s.unit.SetStateOperation(newUS)
+	statusOp, err := s.unit.SetStatusOperation(status.StatusInfo{
+		Status:  status.Active,
+		Message: "configured",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	annotationsOp, err := s.Model.SetAnnotationsOperation(s.unit, map[string]string{"owner": "ops"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.State.ApplyOperation(state.ComposeModelOperations(stateOp, statusOp, annotationsOp))
+	c.Assert(err, jc.ErrorIsNil)
+
+	uState, err := s.unit.State()
+	c.Assert(err, jc.ErrorIsNil)
+	assertUnitStateState(c, uState, map[string]string{"foo": "bar"})
+
+	unitStatus, err := s.unit.Status()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(unitStatus.Status, gc.Equals, status.Active)
+	c.Check(unitStatus.Message, gc.Equals, "configured")
+
+	history, err := s.unit.StatusHistory(status.StatusHistoryFilter{Size: 1})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, 1)
+	c.Check(history[0].Message, gc.Equals, "configured")
+
+	annotations, err := s.Model.Annotations(s.unit)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(annotations, jc.DeepEquals, map[string]string{"owner": "ops"})
+}
+
+func (s *UnitSuite) TestSetStateWithStatusOperationIsAtomic(c *gc.C) {
+	preventUnitDestroyRemove(c, s.unit)
+	err := s.unit.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.unit.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+
+	before, err := s.unit.Status()
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Unit state can not be written for a dying unit,
+	// so the status change must not be applied either.
+	newUS := state.NewUnitState()
+	newUS.SetState(map[string]string{"foo": "bar"})
+	statusOp, err := s.unit.SetStatusOperation(status.StatusInfo{
+		Status:  status.Blocked,
+		Message: "should not be set",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.State.ApplyOperation(state.ComposeModelOperations(s.unit.SetStateOperation(newUS), statusOp))
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	after, err := s.unit.Status()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(after.Status, gc.Equals, before.Status)
+	c.Check(after.Message, gc.Equals, before.Message)
+}
+
+func (s *UnitSuite) TestSetStatusOperationInvalidStatus(c *gc.C) {
+	_, err := s.unit.SetStatusOperation(status.StatusInfo{Status: status.Executing})
+	c.Assert(err, gc.ErrorMatches, `cannot set invalid status "executing"`)
+}
+
 func (s *UnitSuite) TestUnitStateNopMutation(c *gc.C) {
 	// Set initial state; this should create a new unitstate doc
 	initialState := map[string]string{
@@ -4,9 +4,15 @@
 package state
 
 import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/juju/errors"
+	jujutxn "github.com/juju/txn"
+	"gopkg.in/juju/names.v3"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 	"gopkg.in/mgo.v2/txn"
@@ -20,32 +26,76 @@ type unitStateDoc struct {
 	DocID    string `bson:"_id"`
 	TxnRevno int64  `bson:"txn-revno"`
 
+	// Application is the name of the unit's application, so that
+	// the state of all of an application's units can be queried
+	// using the collection's application index.
+	Application string `bson:"application,omitempty"`
+
 	// The following maps to UnitState:
 
 	// State encodes the unit's persisted state as a list of key-value pairs.
 	State map[string]string `bson:"state,omitempty"`
 
+	// BranchState holds the state persisted by the charm while the unit
+	// tracks an in-flight branch, keyed by escaped branch name, so that
+	// it does not clobber State. It is merged into State when the branch
+	// is committed.
+	BranchState map[string]map[string]string `bson:"branch-state,omitempty"`
+
 	// UniterState is a serialized yaml string containing the uniters internal
-	// state for this unit.
-	UniterState string `bson:"uniter-state,omitempty"`
+	// state for this unit. It is nil if the uniter has never written its
+	// state, and may be empty if the uniter has cleared it.
+	UniterState *string `bson:"uniter-state,omitempty"`
 
 	// RelationState is a serialized yaml string containing relation internal
-	// state for this unit from the uniter.
+	// state for this unit from the uniter. When it is too big to be held
+	// by one document, only the first chunk of it is held here.
 	RelationState map[string]string `bson:"relation-state,omitempty"`
 
+	// RelationStateChunks is the number of chunks of the relation state
+	// that overflowed into unitStateChunkDocs.
+	RelationStateChunks int `bson:"relation-state-chunks,omitempty"`
+
+	// RelationStateGeneration identifies the unitStateChunkDocs holding
+	// the overflowed chunks. Each version of the relation state that
+	// overflows is written to chunks of a new generation, which the
+	// document is then switched to.
+	RelationStateGeneration string `bson:"relation-state-generation,omitempty"`
+
 	// StorageState is a serialized yaml string containing storage internal
-	// state for this unit from the uniter.
-	StorageState string `bson:"storage-state,omitempty"`
+	// state for this unit from the uniter. It is nil if the uniter has never
+	// written the storage state, and may be empty if the uniter has cleared it.
+	StorageState *string `bson:"storage-state,omitempty"`
+}
+
+// stringValue returns the string referenced by s,
+// or the empty string if s is nil.
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
 }
 
 // stateMatches returns true if the State map within the unitStateDoc matches
 // the provided st argument.
 func (d *unitStateDoc) stateMatches(st bson.M) bool {
-	if len(st) != len(d.State) {
+	return charmStateMatches(d.State, st)
+}
+
+// branchStateMatches returns true if the charm state persisted while
+// tracking the branch with the input key matches the provided st argument.
+func (d *unitStateDoc) branchStateMatches(branchKey string, st bson.M) bool {
+	current, ok := d.BranchState[branchKey]
+	return ok && charmStateMatches(current, st)
+}
+
+func charmStateMatches(current map[string]string, st bson.M) bool {
+	if len(st) != len(current) {
 		return false
 	}
 
-	for k, v := range d.State {
+	for k, v := range current {
 		if st[k] != v {
 			return false
 		}
@@ -78,7 +128,16 @@ func (d *unitStateDoc) relationData() (map[int]string, error) {
 // IgnoreRelationsState has been called first, therefore if arg is empty,
 // returns a nil map to be set.
 func (d *unitStateDoc) relationStateMatches(newRS map[string]string) bool {
-	for k, v := range d.RelationState {
+	return relationStateMatches(d.RelationState, newRS)
+}
+
+func relationStateMatches(current, newRS map[string]string) bool {
+	// The entries of relation state move between chunks as it grows
+	// and shrinks, so a chunk only matches if it holds the same keys.
+	if len(current) != len(newRS) {
+		return false
+	}
+	for k, v := range current {
 		if newRS[k] != v {
 			return false
 		}
@@ -86,6 +145,289 @@ func (d *unitStateDoc) relationStateMatches(newRS map[string]string) bool {
 	return true
 }
 
+// unitStateChunkDoc holds a chunk of the relation state of a unit that
+// overflowed the unit's state document, so that units in very many
+// relations are not limited by the size of a single document. Chunks
+// are never changed once written; new relation state is written to
+// chunks of a new generation.
+type unitStateChunkDoc struct {
+	// DocID is the unit's global key followed by the generation and
+	// chunk index, as returned by relationStateChunkKey.
+	DocID    string `bson:"_id"`
+	TxnRevno int64  `bson:"txn-revno"`
+
+	// RelationState holds the entries of the chunk.
+	RelationState map[string]string `bson:"relation-state"`
+}
+
+// relationStateChunkSize is the most bytes of relation state keys and
+// values held by each document. The first chunk is held by the unit
+// state document itself, alongside the charm state, and the rest by
+// unitStateChunkDocs. It is a variable so that tests can patch it.
+var relationStateChunkSize = 4 << 20
+
+// relationStateChunkKey returns the key of the document holding the
+// chunk of relation state of the generation with the input index,
+// counting the chunk held by the unit state document as 0. The unit
+// state document id may be used in place of the global key, to get
+// the id of the chunk document.
+func relationStateChunkKey(unitGlobalKey, generation string, index int) string {
+	return fmt.Sprintf("%s#%s#%d", unitGlobalKey, generation, index)
+}
+
+// splitRelationState splits the relation state into chunks holding at
+// most relationStateChunkSize bytes of keys and values each, taking the
+// entries in relation id order so that the split is stable. An entry
+// bigger than the chunk size is held by a chunk of its own. No chunks
+// are returned for empty relation state.
+func splitRelationState(rState map[int]string) []map[string]string {
+	ids := make([]int, 0, len(rState))
+	for id := range rState {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var (
+		chunks []map[string]string
+		size   int
+	)
+	for _, id := range ids {
+		k, v := strconv.Itoa(id), rState[id]
+		entrySize := len(k) + len(v)
+		if len(chunks) == 0 || (size > 0 && size+entrySize > relationStateChunkSize) {
+			chunks = append(chunks, make(map[string]string))
+			size = 0
+		}
+		chunks[len(chunks)-1][k] = v
+		size += entrySize
+	}
+	return chunks
+}
+
+// relationStateChunks returns the chunks of relation state that
+// overflowed the unit state document, keyed by chunk index. Chunks
+// that are not found are omitted. The chunks are read by the id of
+// the unit state document, so that those of any model can be read.
+func (st *State) relationStateChunks(d *unitStateDoc) (map[int]unitStateChunkDoc, error) {
+	if d.RelationStateChunks == 0 {
+		return nil, nil
+	}
+	indexes := make(map[string]int, d.RelationStateChunks)
+	ids := make([]string, d.RelationStateChunks)
+	for i := range ids {
+		ids[i] = relationStateChunkKey(d.DocID, d.RelationStateGeneration, i+1)
+		indexes[ids[i]] = i + 1
+	}
+
+	coll, closer := st.db().GetRawCollection(unitStateChunksC)
+	defer closer()
+
+	var docs []unitStateChunkDoc
+	if err := coll.Find(bson.D{{"_id", bson.D{{"$in", ids}}}}).All(&docs); err != nil {
+		return nil, errors.Annotatef(err, "reading relation state chunks of %q", d.DocID)
+	}
+	chunks := make(map[int]unitStateChunkDoc, len(docs))
+	for _, doc := range docs {
+		chunks[indexes[doc.DocID]] = doc
+	}
+	return chunks, nil
+}
+
+// maxRelationStateReads is the most times that a unit state document is
+// read while assembling its relation state, before giving up on finding
+// the chunks that it refers to.
+const maxRelationStateReads = 3
+
+// assembleRelationState merges the chunks of relation state that
+// overflowed the unit state document into its RelationState. The chunks
+// of a document that has since been switched to a new generation may be
+// removed before they are read, in which case the document is read
+// again, until its revision is stable. Chunks missing from a document
+// that has not changed are an error.
+func (st *State) assembleRelationState(d *unitStateDoc) error {
+	for i := 1; ; i++ {
+		chunks, err := st.relationStateChunks(d)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if len(chunks) == d.RelationStateChunks {
+			d.mergeRelationState(chunks)
+			return nil
+		}
+		missing := errors.Errorf("relation state of %q is missing %d of %d chunks",
+			d.DocID, d.RelationStateChunks-len(chunks), d.RelationStateChunks)
+		if i == maxRelationStateReads {
+			return missing
+		}
+		changed, err := st.rereadUnitStateDoc(d)
+		if err != nil {
+			return errors.Trace(err)
+		} else if !changed {
+			return missing
+		}
+	}
+}
+
+// rereadUnitStateDoc reads the unit state document again, replacing d
+// if its revision has changed, and returns whether it has. The document
+// is read by its id, so that that of any model can be read.
+func (st *State) rereadUnitStateDoc(d *unitStateDoc) (bool, error) {
+	coll, closer := st.db().GetRawCollection(unitStatesC)
+	defer closer()
+
+	var current unitStateDoc
+	if err := coll.FindId(d.DocID).One(&current); err == mgo.ErrNotFound {
+		return false, errors.NotFoundf("unit state %q", d.DocID)
+	} else if err != nil {
+		return false, errors.Annotatef(err, "reading unit state %q", d.DocID)
+	}
+	if current.TxnRevno == d.TxnRevno {
+		return false, nil
+	}
+	*d = current
+	return true, nil
+}
+
+// mergeRelationState merges the chunks of relation state into the
+// document's RelationState.
+func (d *unitStateDoc) mergeRelationState(chunks map[int]unitStateChunkDoc) {
+	if len(chunks) == 0 {
+		return
+	}
+	// The document's map is not changed, as copies
+	// of the document may share it.
+	merged := make(map[string]string, len(d.RelationState))
+	for k, v := range d.RelationState {
+		merged[k] = v
+	}
+	for _, chunk := range chunks {
+		for k, v := range chunk.RelationState {
+			merged[k] = v
+		}
+	}
+	d.RelationState = merged
+}
+
+// pruneRelationState removes the relation state held for the model's
+// units about relations that no longer exist, which is left behind when
+// a uniter stops before it has finished departing a relation. The state
+// remaining is split into chunks again, so that chunks emptied by the
+// removal are removed too.
+func (st *State) pruneRelationState() error {
+	// Relations added while the state is pruned have ids no
+	// lower than the relation sequence, so are never pruned.
+	sequences, err := st.Sequences()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	nextRelationId := sequences["relation"]
+
+	relations, closer := st.db().GetCollection(relationsC)
+	defer closer()
+
+	var relationDocs []struct {
+		Id int `bson:"id"`
+	}
+	if err := relations.Find(nil).Select(bson.D{{"id", 1}}).All(&relationDocs); err != nil {
+		return errors.Annotate(err, "reading relations")
+	}
+	exists := make(map[int]bool, len(relationDocs))
+	for _, doc := range relationDocs {
+		exists[doc.Id] = true
+	}
+	departed := func(id int) bool {
+		return id < nextRelationId && !exists[id]
+	}
+
+	unitStates, closer := st.db().GetCollection(unitStatesC)
+	defer closer()
+
+	var stateDocs []struct {
+		DocID string `bson:"_id"`
+	}
+	query := bson.D{{"relation-state", bson.D{{"$exists", true}}}}
+	if err := unitStates.Find(query).Select(bson.D{{"_id", 1}}).All(&stateDocs); err != nil {
+		return errors.Annotate(err, "reading unit states")
+	}
+	for _, doc := range stateDocs {
+		if err := st.pruneUnitRelationState(st.localID(doc.DocID), departed); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// pruneUnitRelationState removes the relation state held for the unit
+// with the input global key about the relations that are departed.
+func (st *State) pruneUnitRelationState(unitGlobalKey string, departed func(int) bool) error {
+	coll, closer := st.db().GetCollection(unitStatesC)
+	defer closer()
+
+	var pruned int
+	chunks := st.newRelationStateSwitch(unitGlobalKey)
+	buildTxn := func(int) ([]txn.Op, error) {
+		var stDoc unitStateDoc
+		if err := coll.FindId(unitGlobalKey).One(&stDoc); err == mgo.ErrNotFound {
+			return nil, jujutxn.ErrNoOperations
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		current := stDoc
+		if err := st.assembleRelationState(&current); err != nil {
+			return nil, errors.Trace(err)
+		}
+		rState := make(map[int]string)
+		pruned = 0
+		for k, v := range current.RelationState {
+			id, err := strconv.Atoi(k)
+			if err != nil {
+				return nil, errors.Annotatef(err, "relation state of %q", unitGlobalKey)
+			}
+			if departed(id) {
+				pruned++
+				continue
+			}
+			rState[id] = v
+		}
+		if pruned == 0 {
+			return nil, jujutxn.ErrNoOperations
+		}
+
+		setFields, unsetFields, err := chunks.update(&stDoc, splitRelationState(rState))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		update := bson.D{}
+		if len(setFields) > 0 {
+			update = append(update, bson.DocElem{"$set", setFields})
+		}
+		if len(unsetFields) > 0 {
+			update = append(update, bson.DocElem{"$unset", unsetFields})
+		}
+		return []txn.Op{{
+			C:      unitStatesC,
+			Id:     unitGlobalKey,
+			Assert: bson.D{{"txn-revno", stDoc.TxnRevno}},
+			Update: update,
+		}}, nil
+	}
+	err := st.db().Run(buildTxn)
+	chunks.done(err)
+	if err != nil {
+		return errors.Annotatef(err, "pruning relation state of %q", unitGlobalKey)
+	}
+	if pruned > 0 {
+		logger.Infof("removed state of %d departed relations held for %q", pruned, unitGlobalKey)
+	}
+	return nil
+}
+
+// branchStateKey returns the key in a unitStateDoc's BranchState
+// under which charm state for the named branch is persisted.
+func branchStateKey(branchName string) string {
+	return mgoutils.EscapeKey(branchName)
+}
+
 // removeUnitStateOp returns the operation needed to remove the unit state
 // document associated with the given globalKey.
 func removeUnitStateOp(mb modelBackend, globalKey string) txn.Op {
@@ -96,6 +438,26 @@ func removeUnitStateOp(mb modelBackend, globalKey string) txn.Op {
 	}
 }
 
+// removeUnitStateChunksOps returns the operations needed to remove the
+// chunks of relation state that overflowed the unit state document
+// associated with the given globalKey.
+func removeUnitStateChunksOps(st *State, globalKey string) ([]txn.Op, error) {
+	coll, closer := st.db().GetCollection(unitStatesC)
+	defer closer()
+
+	var stDoc unitStateDoc
+	err := coll.FindId(globalKey).Select(bson.D{
+		{"relation-state-chunks", 1},
+		{"relation-state-generation", 1},
+	}).One(&stDoc)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return removeRelationStateChunksOps(globalKey, stDoc.RelationStateGeneration, stDoc.RelationStateChunks), nil
+}
+
 // UnitState contains the various state saved for this unit,
 // including from the charm itself and the uniter.
 type UnitState struct {
@@ -103,6 +465,13 @@ type UnitState struct {
 	state    map[string]string
 	stateSet bool
 
+	// stateChanges and stateDeleted record changes to individual keys
+	// of the unit's persisted state, which are merged into the state
+	// already stored rather than replacing it.
+	stateChanges map[string]string
+	stateDeleted []string
+	stateMerged  bool
+
 	// uniterState is a serialized yaml string containing the uniters internal
 	// state for this unit.
 	uniterState    string
@@ -126,13 +495,82 @@ func NewUnitState() *UnitState {
 
 // Modified returns true if any of the struct have been set.
 func (u *UnitState) Modified() bool {
-	return u.relationStateSet || u.storageStateSet || u.stateSet || u.uniterStateSet
+	return u.relationStateSet || u.storageStateSet || u.stateSet || u.stateMerged || u.uniterStateSet
 }
 
-// SetState sets the state value.
+// SetState sets the state value, which replaces the whole of
+// the state already stored. Any changes previously recorded
+// with MergeState are discarded.
 func (u *UnitState) SetState(state map[string]string) {
 	u.stateSet = true
 	u.state = state
+	u.stateChanges = nil
+	u.stateDeleted = nil
+	u.stateMerged = false
+}
+
+// MergeState records changes to individual keys of the state, which
+// are merged into the state already stored, so that keys written
+// concurrently by other updates are preserved. The changed keys are
+// set to their new values, and the deleted keys are removed. If the
+// whole state has been set with SetState, the changes are applied to
+// that instead.
+func (u *UnitState) MergeState(changes map[string]string, deleted []string) {
+	if u.stateSet {
+		state := make(map[string]string, len(u.state)+len(changes))
+		for k, v := range u.state {
+			state[k] = v
+		}
+		for _, k := range deleted {
+			delete(state, k)
+		}
+		for k, v := range changes {
+			state[k] = v
+		}
+		u.state = state
+		return
+	}
+	if u.stateChanges == nil {
+		u.stateChanges = make(map[string]string, len(changes))
+	}
+	for _, k := range deleted {
+		delete(u.stateChanges, k)
+		u.stateDeleted = append(u.stateDeleted, k)
+	}
+	for k, v := range changes {
+		u.stateChanges[k] = v
+	}
+	// A key that is changed is no longer deleted.
+	remaining := u.stateDeleted[:0]
+	for _, k := range u.stateDeleted {
+		if _, changed := u.stateChanges[k]; !changed {
+			remaining = append(remaining, k)
+		}
+	}
+	u.stateDeleted = remaining
+	u.stateMerged = true
+}
+
+// StateChanges returns the changes to individual keys of the state
+// recorded with MergeState, and a bool indicating whether any were.
+func (u *UnitState) StateChanges() (map[string]string, []string, bool) {
+	return u.stateChanges, u.stateDeleted, u.stateMerged
+}
+
+// mergeInto returns the result of merging the changes recorded with
+// MergeState into the current state.
+func (u *UnitState) mergeInto(current map[string]string) map[string]string {
+	result := make(map[string]string, len(current)+len(u.stateChanges))
+	for k, v := range current {
+		result[k] = v
+	}
+	for _, k := range u.stateDeleted {
+		delete(result, k)
+	}
+	for k, v := range u.stateChanges {
+		result[k] = v
+	}
+	return result
 }
 
 // State returns the unit's state and bool indicating
@@ -165,16 +603,6 @@ func (u *UnitState) RelationState() (map[int]string, bool) {
 	return u.relationState, u.relationStateSet
 }
 
-// relationStateBSONFriendly makes a map[int]string BSON friendly by
-// translating the int map key to a string.
-func (u *UnitState) relationStateBSONFriendly() (map[string]string, bool) {
-	stringData := make(map[string]string, len(u.relationState))
-	for k, v := range u.relationState {
-		stringData[strconv.Itoa(k)] = v
-	}
-	return stringData, u.relationStateSet
-}
-
 // SetStorageState sets the storage state value.
 func (u *UnitState) SetStorageState(state string) {
 	u.storageStateSet = true
@@ -203,9 +631,22 @@ func (u *Unit) SetStateOperation(unitState *UnitState) ModelOperation {
 }
 
 // State returns the persisted state for a unit.
+// State can be read for units that are Alive or Dying, so that hooks run
+// during teardown have access to it. Only Alive units can have their
+// state written.
+// If the unit is tracking an in-flight branch, the charm state
+// persisted while tracking the branch, if any, is returned in place
+// of the master charm state.
 func (u *Unit) State() (*UnitState, error) {
+	return u.readState(true)
+}
+
+// readState returns the persisted state for a unit, with the charm state
+// of any tracked branch in place of the master charm state if useBranch
+// is true.
+func (u *Unit) readState(useBranch bool) (*UnitState, error) {
 	us := NewUnitState()
-	if u.Life() != Alive {
+	if u.Life() == Dead {
 		return us, errors.NotFoundf("unit %s", u.Name())
 	}
 
@@ -219,25 +660,349 @@ func (u *Unit) State() (*UnitState, error) {
 		}
 		return us, errors.Trace(err)
 	}
+	if err := u.st.assembleRelationState(&stDoc); err != nil {
+		return us, errors.Trace(err)
+	}
 
-	if stDoc.RelationState != nil {
-		rState, err := stDoc.relationData()
+	charmState := stDoc.State
+	if useBranch && len(stDoc.BranchState) > 0 {
+		branch, err := u.stateBranch()
+		if err != nil {
+			return us, errors.Trace(err)
+		}
+		if branch != nil {
+			if branchState, ok := stDoc.BranchState[branchStateKey(branch.BranchName())]; ok {
+				charmState = branchState
+			}
+		}
+	}
+	return stDoc.unitState(charmState)
+}
+
+// StateValue returns the value of the input key of the charm state
+// persisted for the unit, and whether the key is set. Only the value of
+// the key is read from the database, rather than the whole charm state.
+// As with State, the charm state persisted while tracking an in-flight
+// branch, if any, is read in place of the master charm state.
+func (u *Unit) StateValue(key string) (string, bool, error) {
+	values, err := u.StateValues(key)
+	if err != nil {
+		return "", false, errors.Trace(err)
+	}
+	value, ok := values[key]
+	return value, ok, nil
+}
+
+// StateValues returns the values of the input keys of the charm state
+// persisted for the unit. Keys that are not set are omitted from the
+// result. Only the values of the keys are read from the database.
+func (u *Unit) StateValues(keys ...string) (map[string]string, error) {
+	if u.Life() == Dead {
+		return nil, errors.NotFoundf("unit %s", u.Name())
+	}
+	result := make(map[string]string)
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	var branchKey string
+	branch, err := u.stateBranch()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	fields := bson.M{}
+	if branch != nil {
+		// Branch state replaces the master state wholesale, so
+		// whether it has been written must be known.
+		branchKey = branchStateKey(branch.BranchName())
+		fields["branch-state."+branchKey] = 1
+	}
+	for _, key := range keys {
+		fields["state."+mgoutils.EscapeKey(key)] = 1
+	}
+
+	coll, closer := u.st.db().GetCollection(unitStatesC)
+	defer closer()
+
+	var stDoc unitStateDoc
+	if err := coll.FindId(u.globalKey()).Select(fields).One(&stDoc); err == mgo.ErrNotFound {
+		return result, nil
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	charmState := stDoc.State
+	if branchState, ok := stDoc.BranchState[branchKey]; ok && branch != nil {
+		charmState = branchState
+	}
+	for _, key := range keys {
+		if value, ok := charmState[mgoutils.EscapeKey(key)]; ok {
+			result[key] = value
+		}
+	}
+	return result, nil
+}
+
+// unitState returns the UnitState recorded by the document, with the
+// input charm state in place of the document's master charm state.
+func (d *unitStateDoc) unitState(charmState map[string]string) (*UnitState, error) {
+	us := NewUnitState()
+	if d.RelationState != nil {
+		rState, err := d.relationData()
 		if err != nil {
 			return us, errors.Trace(err)
 		}
 		us.SetRelationState(rState)
 	}
 
-	if stDoc.State != nil {
-		unitState := make(map[string]string, len(stDoc.State))
-		for k, v := range stDoc.State {
+	if charmState != nil {
+		unitState := make(map[string]string, len(charmState))
+		for k, v := range charmState {
 			unitState[mgoutils.UnescapeKey(k)] = v
 		}
 		us.SetState(unitState)
 	}
 
-	us.SetUniterState(stDoc.UniterState)
-	us.SetStorageState(stDoc.StorageState)
+	// Only report the uniter and storage state as set if they have been
+	// written, so that state deliberately cleared by the uniter can be
+	// told apart from state it has never written.
+	if d.UniterState != nil {
+		us.SetUniterState(*d.UniterState)
+	}
+	if d.StorageState != nil {
+		us.SetStorageState(*d.StorageState)
+	}
 
 	return us, nil
 }
+
+// ApplicationUnitStates returns the persisted state of each unit of the
+// named application that has any, keyed by unit name. The master charm
+// state is returned for units tracking an in-flight branch.
+func (st *State) ApplicationUnitStates(appName string) (map[string]*UnitState, error) {
+	coll, closer := st.db().GetCollection(unitStatesC)
+	defer closer()
+
+	var docs []unitStateDoc
+	if err := coll.Find(bson.D{{"application", appName}}).All(&docs); err != nil {
+		return nil, errors.Annotatef(err, "reading unit state for application %q", appName)
+	}
+	result := make(map[string]*UnitState, len(docs))
+	for _, doc := range docs {
+		if err := st.assembleRelationState(&doc); err != nil {
+			return nil, errors.Annotatef(err, "reading unit state for application %q", appName)
+		}
+		us, err := doc.unitState(doc.State)
+		if err != nil {
+			return nil, errors.Annotatef(err, "reading unit state for application %q", appName)
+		}
+		result[unitNameFromStateDocID(st.localID(doc.DocID))] = us
+	}
+	return result, nil
+}
+
+// CountApplicationUnitStates returns the number of units of the
+// named application that have persisted state. The query is
+// covered by the unit state collection's application index.
+func (st *State) CountApplicationUnitStates(appName string) (int, error) {
+	coll, closer := st.db().GetCollection(unitStatesC)
+	defer closer()
+
+	n, err := coll.Find(bson.D{{"application", appName}}).Count()
+	return n, errors.Annotatef(err, "counting unit state for application %q", appName)
+}
+
+// UnitStateEntry holds the persisted state of a unit,
+// as read in a batch by a UnitStatesIterator.
+type UnitStateEntry struct {
+	// UnitName is the name of the unit.
+	UnitName string
+
+	// State holds the state persisted for the unit.
+	State *UnitState
+}
+
+// UnitStatesIterator reads the persisted state of the units of a model
+// in batches, so that the state of all units need not be held in memory
+// at once. As with ApplicationUnitStates, the master charm state is read
+// for units tracking an in-flight branch.
+type UnitStatesIterator struct {
+	st     *State
+	size   int
+	resume string
+	done   bool
+}
+
+// UnitStates returns an iterator over the persisted state of the units
+// of the model, which reads batches of at most size units. Iteration
+// starts after the unit identified by the resume token, as returned by
+// the Resume method of an earlier iterator, or at the first unit if the
+// token is empty.
+func (st *State) UnitStates(resume string, size int) (*UnitStatesIterator, error) {
+	if size <= 0 {
+		return nil, errors.NotValidf("unit state batch size %d", size)
+	}
+	if resume != "" && !names.IsValidUnit(resume) {
+		return nil, errors.NotValidf("unit state resume token %q", resume)
+	}
+	return &UnitStatesIterator{
+		st:     st,
+		size:   size,
+		resume: resume,
+	}, nil
+}
+
+// Next returns the next batch of unit states, in an order that is stable
+// across iterators. An empty batch is returned once all have been read.
+func (it *UnitStatesIterator) Next() ([]UnitStateEntry, error) {
+	if it.done {
+		return nil, nil
+	}
+	coll, closer := it.st.db().GetCollection(unitStatesC)
+	defer closer()
+
+	query := bson.D{}
+	if it.resume != "" {
+		query = bson.D{{"_id", bson.D{{"$gt", it.st.docID(unitGlobalKey(it.resume))}}}}
+	}
+	// Read one document more than the batch holds,
+	// to learn whether this is the last batch.
+	var docs []unitStateDoc
+	if err := coll.Find(query).Sort("_id").Limit(it.size + 1).All(&docs); err != nil {
+		return nil, errors.Annotate(err, "reading unit states")
+	}
+	if len(docs) <= it.size {
+		it.done = true
+	} else {
+		docs = docs[:it.size]
+	}
+
+	result := make([]UnitStateEntry, len(docs))
+	for i, doc := range docs {
+		unitName := unitNameFromStateDocID(it.st.localID(doc.DocID))
+		if err := it.st.assembleRelationState(&doc); err != nil {
+			return nil, errors.Annotatef(err, "reading unit state for unit %q", unitName)
+		}
+		us, err := doc.unitState(doc.State)
+		if err != nil {
+			return nil, errors.Annotatef(err, "reading unit state for unit %q", unitName)
+		}
+		result[i] = UnitStateEntry{UnitName: unitName, State: us}
+	}
+	if len(result) > 0 {
+		it.resume = result[len(result)-1].UnitName
+	}
+	return result, nil
+}
+
+// Resume returns the token from which a later iterator can resume
+// reading after the batches already returned by this one.
+func (it *UnitStatesIterator) Resume() string {
+	return it.resume
+}
+
+// unitNameFromStateDocID returns the name of the unit
+// whose state is recorded by the document with the input
+// local ID, which is always the unit's global key.
+func unitNameFromStateDocID(localID string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(localID, "u#"), "#charm")
+}
+
+// stateBranch returns the in-flight branch tracked by the unit, under
+// which charm state is persisted apart from the master charm state,
+// or nil if the unit is not tracking a branch.
+func (u *Unit) stateBranch() (*Generation, error) {
+	m, err := u.st.Model()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	branch, err := m.unitBranch(u.Name())
+	return branch, errors.Trace(err)
+}
+
+// ErrUniterStateChanged is returned by SwapUniterState when the
+// unit's uniter state no longer matches the expected prior value.
+var ErrUniterStateChanged = errors.New("uniter state changed")
+
+// UniterStateHash returns the hash of the input uniter state,
+// in the form expected by SwapUniterState.
+func UniterStateHash(uniterState string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(uniterState)))
+}
+
+// SwapUniterState replaces the unit's uniter state with newState, but only
+// if the hash of the current uniter state, as returned by UniterStateHash,
+// matches priorHash; ErrUniterStateChanged is returned otherwise. An empty
+// newState removes the uniter state.
+//
+// It is used by agent upgrade steps that rewrite the uniter state in a new
+// schema, so that an update written by a uniter that restarts part way
+// through the upgrade is not lost.
+func (u *Unit) SwapUniterState(priorHash, newState string) error {
+	coll, closer := u.st.db().GetCollection(unitStatesC)
+	defer closer()
+
+	unitGlobalKey := u.globalKey()
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := u.Refresh(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		if u.Life() != Alive {
+			return nil, errors.NotFoundf("unit %s", u.Name())
+		}
+
+		var stDoc unitStateDoc
+		err := coll.FindId(unitGlobalKey).One(&stDoc)
+		if err != nil && err != mgo.ErrNotFound {
+			return nil, errors.Trace(err)
+		}
+		exists := err == nil
+		current := stringValue(stDoc.UniterState)
+		if UniterStateHash(current) != priorHash {
+			return nil, ErrUniterStateChanged
+		}
+		if current == newState {
+			return nil, jujutxn.ErrNoOperations
+		}
+
+		ops := []txn.Op{{
+			C:      unitsC,
+			Id:     u.doc.DocID,
+			Assert: isAliveDoc,
+		}}
+		if !exists {
+			return append(ops, txn.Op{
+				C:      unitStatesC,
+				Id:     unitGlobalKey,
+				Assert: txn.DocMissing,
+				Insert: unitStateDoc{
+					DocID:       unitGlobalKey,
+					Application: u.doc.Application,
+					UniterState: &newState,
+				},
+			}), nil
+		}
+
+		// Assert on the uniter state itself rather than the txn-revno,
+		// so that changes to the other parts of the unit state made
+		// concurrently do not cause the swap to fail.
+		assert := bson.D{{"uniter-state", current}}
+		if stDoc.UniterState == nil {
+			assert = bson.D{{"uniter-state", bson.D{{"$exists", false}}}}
+		}
+		update := bson.D{{"$set", bson.D{{"uniter-state", newState}}}}
+		if newState == "" {
+			update = bson.D{{"$unset", bson.D{{"uniter-state", nil}}}}
+		}
+		return append(ops, txn.Op{
+			C:      unitStatesC,
+			Id:     unitGlobalKey,
+			Assert: assert,
+			Update: update,
+		}), nil
+	}
+	err := u.st.db().Run(buildTxn)
+	return errors.Annotatef(err, "cannot swap uniter state for unit %q", u)
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type unitStateInternalSuite struct {
+	internalStateSuite
+
+	docID string
+}
+
+var _ = gc.Suite(&unitStateInternalSuite{})
+
+func (s *unitStateInternalSuite) SetUpTest(c *gc.C) {
+	s.internalStateSuite.SetUpTest(c)
+	uuid := s.state.ModelUUID()
+	s.docID = uuid + ":u#wordpress/0#charm"
+
+	coll, closer := s.state.db().GetRawCollection(unitStatesC)
+	defer closer()
+	err := coll.Insert(bson.M{
+		"_id":                       s.docID,
+		"model-uuid":                uuid,
+		"txn-revno":                 int64(1),
+		"application":               "wordpress",
+		"relation-state":            bson.M{"7": "changed-pending: true\n"},
+		"relation-state-chunks":     1,
+		"relation-state-generation": "g1",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	s.insertChunk(c, "g1", bson.M{"8": "changed-pending: false\n"})
+}
+
+func (s *unitStateInternalSuite) insertChunk(c *gc.C, generation string, relationState bson.M) {
+	chunks, closer := s.state.db().GetRawCollection(unitStateChunksC)
+	defer closer()
+	err := chunks.Insert(bson.M{
+		"_id":            relationStateChunkKey(s.docID, generation, 1),
+		"model-uuid":     s.state.ModelUUID(),
+		"txn-revno":      int64(1),
+		"relation-state": relationState,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *unitStateInternalSuite) removeChunk(c *gc.C, generation string) {
+	chunks, closer := s.state.db().GetRawCollection(unitStateChunksC)
+	defer closer()
+	err := chunks.RemoveId(relationStateChunkKey(s.docID, generation, 1))
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *unitStateInternalSuite) readDoc(c *gc.C) unitStateDoc {
+	coll, closer := s.state.db().GetRawCollection(unitStatesC)
+	defer closer()
+	var doc unitStateDoc
+	err := coll.FindId(s.docID).One(&doc)
+	c.Assert(err, jc.ErrorIsNil)
+	return doc
+}
+
+func (s *unitStateInternalSuite) TestAssembleRelationState(c *gc.C) {
+	doc := s.readDoc(c)
+	err := s.state.assembleRelationState(&doc)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(doc.RelationState, jc.DeepEquals, map[string]string{
+		"7": "changed-pending: true\n",
+		"8": "changed-pending: false\n",
+	})
+}
+
+func (s *unitStateInternalSuite) TestAssembleRelationStateSwitchedGeneration(c *gc.C) {
+	doc := s.readDoc(c)
+
+	// Between the document being read and its chunks being read,
+	// the document is switched to a new generation of chunks, and
+	// those of the old generation are removed.
+	s.insertChunk(c, "g2", bson.M{"8": "changed-pending: true\n"})
+	coll, closer := s.state.db().GetRawCollection(unitStatesC)
+	defer closer()
+	err := coll.UpdateId(s.docID, bson.M{"$set": bson.M{
+		"txn-revno":                 int64(2),
+		"relation-state-generation": "g2",
+	}})
+	c.Assert(err, jc.ErrorIsNil)
+	s.removeChunk(c, "g1")
+
+	err = s.state.assembleRelationState(&doc)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(doc.TxnRevno, gc.Equals, int64(2))
+	c.Check(doc.RelationState, jc.DeepEquals, map[string]string{
+		"7": "changed-pending: true\n",
+		"8": "changed-pending: true\n",
+	})
+}
+
+func (s *unitStateInternalSuite) TestAssembleRelationStateMissingChunk(c *gc.C) {
+	doc := s.readDoc(c)
+
+	// The chunk is removed while the document still refers to it.
+	s.removeChunk(c, "g1")
+
+	err := s.state.assembleRelationState(&doc)
+	c.Assert(err, gc.ErrorMatches, `relation state of ".*:u#wordpress/0#charm" is missing 1 of 1 chunks`)
+}
@@ -0,0 +1,411 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+	"gopkg.in/yaml.v2"
+
+	mgoutils "github.com/juju/juju/mongo/utils"
+)
+
+// UnitStateFix describes how a problem with a unit state
+// document is fixed.
+type UnitStateFix string
+
+const (
+	// UnitStateRepair indicates that the problem is fixed in place,
+	// without losing any of the state persisted for the unit.
+	UnitStateRepair UnitStateFix = "repair"
+
+	// UnitStateQuarantine indicates that the bad value is moved out
+	// of the unit state document, into the unit state quarantine,
+	// from where it can be recovered by hand.
+	UnitStateQuarantine UnitStateFix = "quarantine"
+)
+
+// UnitStateProblem describes a value in a unit state document that
+// does not conform to the current schema.
+type UnitStateProblem struct {
+	// UnitName is the name of the unit whose state has the problem.
+	UnitName string
+
+	// Field is the field of the unit state document holding the
+	// bad value, such as "state" or "uniter-state".
+	Field string
+
+	// Key identifies the bad value within a field holding a map.
+	Key string
+
+	// Detail describes the problem.
+	Detail string
+
+	// Fix describes how the problem is fixed.
+	Fix UnitStateFix
+}
+
+// unitStateQuarantineDoc records a value quarantined
+// from a unit state document.
+type unitStateQuarantineDoc struct {
+	DocID       string    `bson:"_id"`
+	ModelUUID   string    `bson:"model-uuid"`
+	UnitName    string    `bson:"unit"`
+	Field       string    `bson:"field"`
+	Key         string    `bson:"key,omitempty"`
+	Value       string    `bson:"value"`
+	Detail      string    `bson:"detail"`
+	Quarantined time.Time `bson:"quarantined"`
+}
+
+// CheckUnitStates validates the model's unit state documents against
+// the current schema, returning the problems found. Keys of charm state
+// must be escaped, relation state must be for relations that exist and
+// the uniter's state must be well-formed YAML.
+func (st *State) CheckUnitStates() ([]UnitStateProblem, error) {
+	problems, _, err := st.checkUnitStates()
+	return problems, errors.Trace(err)
+}
+
+// RepairUnitStates validates the model's unit state documents, as
+// CheckUnitStates does, and fixes the problems found, returning them.
+// Each document is fixed in a transaction that fails if the document
+// has changed since it was checked, in which case it is left for a
+// later repair.
+func (st *State) RepairUnitStates() ([]UnitStateProblem, error) {
+	problems, fixes, err := st.checkUnitStates()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, fix := range fixes {
+		if err := st.repairUnitState(fix); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return problems, nil
+}
+
+// unitStateRepair holds the changes that
+// fix the unit state of a unit.
+type unitStateRepair struct {
+	unitName string
+
+	// doc is the unit state document as it was checked,
+	// without the relation state held by its chunks.
+	doc unitStateDoc
+
+	set, unset    bson.D
+	quarantineOps []txn.Op
+
+	// relationState is the repaired relation state,
+	// if relationStateChanged is true.
+	relationState        map[string]string
+	relationStateChanged bool
+}
+
+// repairUnitState applies the fix to the unit state document. Repaired
+// relation state is switched to as it is by SetState, so that relation
+// state that overflows the document is not held by the transaction.
+func (st *State) repairUnitState(fix unitStateRepair) error {
+	set, unset := fix.set, fix.unset
+	var chunks *relationStateSwitch
+	if fix.relationStateChanged {
+		rState := make(map[int]string, len(fix.relationState))
+		for k, v := range fix.relationState {
+			// The keys have been checked to be relation ids.
+			id, _ := strconv.Atoi(k)
+			rState[id] = v
+		}
+		chunks = st.newRelationStateSwitch(st.localID(fix.doc.DocID))
+		rSet, rUnset, err := chunks.update(&fix.doc, splitRelationState(rState))
+		if err != nil {
+			return errors.Annotatef(err, "repairing unit state for unit %q", fix.unitName)
+		}
+		set, unset = append(set, rSet...), append(unset, rUnset...)
+	}
+	var update bson.D
+	if len(set) > 0 {
+		update = append(update, bson.DocElem{Name: "$set", Value: set})
+	}
+	if len(unset) > 0 {
+		update = append(update, bson.DocElem{Name: "$unset", Value: unset})
+	}
+	ops := []txn.Op{{
+		C:      unitStatesC,
+		Id:     fix.doc.DocID,
+		Assert: bson.D{{"txn-revno", fix.doc.TxnRevno}},
+		Update: update,
+	}}
+	err := st.db().RunTransaction(append(ops, fix.quarantineOps...))
+	if chunks != nil {
+		chunks.done(err)
+	}
+	if err == txn.ErrAborted {
+		return errors.Errorf("unit state for unit %q changed while being repaired", fix.unitName)
+	} else if err != nil {
+		return errors.Annotatef(err, "repairing unit state for unit %q", fix.unitName)
+	}
+	return nil
+}
+
+func (st *State) checkUnitStates() ([]UnitStateProblem, []unitStateRepair, error) {
+	relations, err := st.AllRelations()
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	relationIds := make(map[int]bool, len(relations))
+	for _, rel := range relations {
+		relationIds[rel.Id()] = true
+	}
+
+	coll, closer := st.db().GetCollection(unitStatesC)
+	defer closer()
+
+	var (
+		problems []UnitStateProblem
+		fixes    []unitStateRepair
+		doc      unitStateDoc
+	)
+	iter := coll.Find(nil).Sort("_id").Iter()
+	for iter.Next(&doc) {
+		checker := unitStateChecker{
+			st:          st,
+			unitName:    unitNameFromStateDocID(st.localID(doc.DocID)),
+			relationIds: relationIds,
+			now:         st.clock().Now(),
+		}
+		// Relation state that overflows the document is
+		// checked along with that held by the document.
+		assembled := doc
+		if err := st.assembleRelationState(&assembled); err != nil {
+			_ = iter.Close()
+			return nil, nil, errors.Trace(err)
+		}
+		set, unset := checker.check(assembled)
+		if len(checker.problems) > 0 {
+			problems = append(problems, checker.problems...)
+			fixes = append(fixes, unitStateRepair{
+				unitName:             checker.unitName,
+				doc:                  doc,
+				set:                  set,
+				unset:                unset,
+				quarantineOps:        checker.quarantineOps,
+				relationState:        checker.relationState,
+				relationStateChanged: checker.relationStateChanged,
+			})
+		}
+		// Clear the document, so that the maps of the next
+		// are not merged into those of this one.
+		doc = unitStateDoc{}
+	}
+	if err := iter.Close(); err != nil {
+		return nil, nil, errors.Annotate(err, "reading unit states")
+	}
+	return problems, fixes, nil
+}
+
+// unitStateChecker checks a single unit state document,
+// building up the changes that fix the problems found.
+type unitStateChecker struct {
+	st          *State
+	unitName    string
+	relationIds map[int]bool
+	now         time.Time
+
+	problems      []UnitStateProblem
+	quarantineOps []txn.Op
+
+	// relationState is the repaired relation state, if
+	// relationStateChanged is true. It is not included in
+	// the fields returned by check, as it may need to be
+	// split into chunks.
+	relationState        map[string]string
+	relationStateChanged bool
+}
+
+// check returns the fields to set and unset that fix the
+// problems it finds in the document, other than those with
+// its relation state.
+func (c *unitStateChecker) check(doc unitStateDoc) (bson.D, bson.D) {
+	var set, unset bson.D
+	updateField := func(field string, changed bool, value interface{}, empty bool) {
+		switch {
+		case !changed:
+		case empty:
+			unset = append(unset, bson.DocElem{Name: field, Value: nil})
+		default:
+			set = append(set, bson.DocElem{Name: field, Value: value})
+		}
+	}
+
+	state, changed := c.checkKeys("state", "", doc.State)
+	updateField("state", changed, state, len(state) == 0)
+
+	branchState, changed := c.checkBranchState(doc.BranchState)
+	updateField("branch-state", changed, branchState, len(branchState) == 0)
+
+	c.relationState, c.relationStateChanged = c.checkRelationState(doc.RelationState)
+
+	if c.checkYAML("uniter-state", "", stringValue(doc.UniterState)) {
+		updateField("uniter-state", true, nil, true)
+	}
+	if c.checkYAML("storage-state", "", stringValue(doc.StorageState)) {
+		updateField("storage-state", true, nil, true)
+	}
+	return set, unset
+}
+
+// checkKeys returns the input charm state with its unescaped keys
+// escaped, and whether it has been changed. A value whose escaped
+// key is already in use is quarantined.
+func (c *unitStateChecker) checkKeys(field, prefix string, state map[string]string) (map[string]string, bool) {
+	var bad []string
+	for key := range state {
+		if mgoutils.EscapeKey(key) != key {
+			bad = append(bad, key)
+		}
+	}
+	if len(bad) == 0 {
+		return state, false
+	}
+	sort.Strings(bad)
+	result := make(map[string]string, len(state))
+	for key, value := range state {
+		result[key] = value
+	}
+	for _, key := range bad {
+		value := result[key]
+		delete(result, key)
+		escaped := mgoutils.EscapeKey(key)
+		if _, ok := state[escaped]; ok {
+			c.quarantine(field, prefix+key, value, fmt.Sprintf("unescaped key %q conflicts with escaped key", key))
+			continue
+		}
+		result[escaped] = value
+		c.addProblem(field, prefix+key, fmt.Sprintf("unescaped key %q", key), UnitStateRepair)
+	}
+	return result, true
+}
+
+// checkBranchState checks the charm state persisted for branches, whose
+// branch names and charm state keys must all be escaped.
+func (c *unitStateChecker) checkBranchState(branchState map[string]map[string]string) (map[string]map[string]string, bool) {
+	var (
+		result  = make(map[string]map[string]string, len(branchState))
+		changed bool
+	)
+	branchKeys := make([]string, 0, len(branchState))
+	for branchKey := range branchState {
+		branchKeys = append(branchKeys, branchKey)
+	}
+	sort.Strings(branchKeys)
+	for _, branchKey := range branchKeys {
+		state, stateChanged := c.checkKeys("branch-state", branchKey+"/", branchState[branchKey])
+		changed = changed || stateChanged
+		escaped := mgoutils.EscapeKey(branchKey)
+		if escaped == branchKey {
+			result[branchKey] = state
+			continue
+		}
+		changed = true
+		if _, ok := branchState[escaped]; ok {
+			for key, value := range state {
+				c.quarantine("branch-state", branchKey+"/"+key, value,
+					fmt.Sprintf("unescaped branch name %q conflicts with escaped branch name", branchKey))
+			}
+			continue
+		}
+		result[escaped] = state
+		c.addProblem("branch-state", branchKey, fmt.Sprintf("unescaped branch name %q", branchKey), UnitStateRepair)
+	}
+	return result, changed
+}
+
+// checkRelationState checks that relation state is keyed by the id of
+// a relation that exists, and is well-formed YAML. State for relations
+// that no longer exist is of no use, so it is discarded; state that
+// cannot be read is quarantined.
+func (c *unitStateChecker) checkRelationState(relationState map[string]string) (map[string]string, bool) {
+	keys := make([]string, 0, len(relationState))
+	for key := range relationState {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make(map[string]string, len(relationState))
+	var changed bool
+	for _, key := range keys {
+		value := relationState[key]
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			c.quarantine("relation-state", key, value, fmt.Sprintf("relation id %q not valid", key))
+			changed = true
+			continue
+		}
+		if !c.relationIds[id] {
+			c.addProblem("relation-state", key, fmt.Sprintf("relation %d not found", id), UnitStateRepair)
+			changed = true
+			continue
+		}
+		if c.checkYAML("relation-state", key, value) {
+			changed = true
+			continue
+		}
+		result[key] = value
+	}
+	return result, changed
+}
+
+// checkYAML returns true, quarantining the value,
+// if it is not a well-formed YAML map.
+func (c *unitStateChecker) checkYAML(field, key, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		return false
+	}
+	var out map[string]interface{}
+	err := yaml.Unmarshal([]byte(value), &out)
+	if err == nil {
+		return false
+	}
+	c.quarantine(field, key, value, fmt.Sprintf("malformed YAML: %v", err))
+	return true
+}
+
+func (c *unitStateChecker) addProblem(field, key, detail string, fix UnitStateFix) {
+	c.problems = append(c.problems, UnitStateProblem{
+		UnitName: c.unitName,
+		Field:    field,
+		Key:      key,
+		Detail:   detail,
+		Fix:      fix,
+	})
+}
+
+// quarantine records the problem, and the operation
+// that moves the value into the quarantine.
+func (c *unitStateChecker) quarantine(field, key, value, detail string) {
+	c.addProblem(field, key, detail, UnitStateQuarantine)
+	docID := c.st.docID(bson.NewObjectId().Hex())
+	c.quarantineOps = append(c.quarantineOps, txn.Op{
+		C:      unitStatesQuarantineC,
+		Id:     docID,
+		Assert: txn.DocMissing,
+		Insert: &unitStateQuarantineDoc{
+			DocID:       docID,
+			ModelUUID:   c.st.ModelUUID(),
+			UnitName:    c.unitName,
+			Field:       field,
+			Key:         key,
+			Value:       value,
+			Detail:      detail,
+			Quarantined: c.now,
+		},
+	})
+}
@@ -0,0 +1,175 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type unitStateCheckSuite struct {
+	internalStateSuite
+}
+
+var _ = gc.Suite(&unitStateCheckSuite{})
+
+func (s *unitStateCheckSuite) insertUnitStates(c *gc.C) {
+	coll, closer := s.state.db().GetRawCollection(unitStatesC)
+	defer closer()
+
+	uuid := s.state.ModelUUID()
+	err := coll.Insert(bson.M{
+		"_id":          uuid + ":u#mysql/0#charm",
+		"model-uuid":   uuid,
+		"txn-revno":    int64(1),
+		"application":  "mysql",
+		"state":        bson.M{"key": "value"},
+		"uniter-state": "leader: true\n",
+	}, bson.M{
+		"_id":         uuid + ":u#wordpress/0#charm",
+		"model-uuid":  uuid,
+		"txn-revno":   int64(1),
+		"application": "wordpress",
+		"state":       bson.M{"a.b": "1", "c": "2"},
+		"relation-state": bson.M{
+			"7":   "changed-pending: true\n",
+			"bad": "members: {}\n",
+		},
+		"uniter-state":  "op: [\n",
+		"storage-state": "storage: {}\n",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *unitStateCheckSuite) checkProblems(c *gc.C, problems []UnitStateProblem) {
+	c.Assert(problems, gc.HasLen, 4)
+	c.Check(problems[0], jc.DeepEquals, UnitStateProblem{
+		UnitName: "wordpress/0",
+		Field:    "state",
+		Key:      "a.b",
+		Detail:   `unescaped key "a.b"`,
+		Fix:      UnitStateRepair,
+	})
+	c.Check(problems[1], jc.DeepEquals, UnitStateProblem{
+		UnitName: "wordpress/0",
+		Field:    "relation-state",
+		Key:      "7",
+		Detail:   "relation 7 not found",
+		Fix:      UnitStateRepair,
+	})
+	c.Check(problems[2], jc.DeepEquals, UnitStateProblem{
+		UnitName: "wordpress/0",
+		Field:    "relation-state",
+		Key:      "bad",
+		Detail:   `relation id "bad" not valid`,
+		Fix:      UnitStateQuarantine,
+	})
+	c.Check(problems[3].UnitName, gc.Equals, "wordpress/0")
+	c.Check(problems[3].Field, gc.Equals, "uniter-state")
+	c.Check(problems[3].Detail, gc.Matches, "malformed YAML: .*")
+	c.Check(problems[3].Fix, gc.Equals, UnitStateQuarantine)
+}
+
+func (s *unitStateCheckSuite) TestCheckUnitStates(c *gc.C) {
+	s.insertUnitStates(c)
+
+	problems, err := s.state.CheckUnitStates()
+	c.Assert(err, jc.ErrorIsNil)
+	s.checkProblems(c, problems)
+
+	// Checking does not change the documents.
+	coll, closer := s.state.db().GetCollection(unitStatesC)
+	defer closer()
+	var doc unitStateDoc
+	err = coll.FindId("u#wordpress/0#charm").One(&doc)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(doc.UniterState, gc.Equals, "op: [\n")
+	c.Check(doc.State, jc.DeepEquals, map[string]string{"a.b": "1", "c": "2"})
+}
+
+func (s *unitStateCheckSuite) TestRepairUnitStates(c *gc.C) {
+	s.insertUnitStates(c)
+
+	problems, err := s.state.RepairUnitStates()
+	c.Assert(err, jc.ErrorIsNil)
+	s.checkProblems(c, problems)
+
+	coll, closer := s.state.db().GetCollection(unitStatesC)
+	defer closer()
+	var doc unitStateDoc
+	err = coll.FindId("u#wordpress/0#charm").One(&doc)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(doc.State, jc.DeepEquals, map[string]string{"a．b": "1", "c": "2"})
+	c.Check(doc.RelationState, gc.HasLen, 0)
+	c.Check(doc.UniterState, gc.Equals, "")
+	c.Check(doc.StorageState, gc.Equals, "storage: {}\n")
+
+	var mysqlDoc unitStateDoc
+	err = coll.FindId("u#mysql/0#charm").One(&mysqlDoc)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(mysqlDoc.TxnRevno, gc.Equals, int64(1))
+
+	quarantine, closer := s.state.db().GetCollection(unitStatesQuarantineC)
+	defer closer()
+	var quarantined []unitStateQuarantineDoc
+	err = quarantine.Find(nil).Sort("field").All(&quarantined)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(quarantined, gc.HasLen, 2)
+	c.Check(quarantined[0].UnitName, gc.Equals, "wordpress/0")
+	c.Check(quarantined[0].Field, gc.Equals, "relation-state")
+	c.Check(quarantined[0].Key, gc.Equals, "bad")
+	c.Check(quarantined[0].Value, gc.Equals, "members: {}\n")
+	c.Check(quarantined[1].Field, gc.Equals, "uniter-state")
+	c.Check(quarantined[1].Value, gc.Equals, "op: [\n")
+
+	// Once repaired, no problems remain.
+	problems, err = s.state.CheckUnitStates()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(problems, gc.HasLen, 0)
+}
+
+func (s *unitStateCheckSuite) TestRepairUnitStatesRelationStateChunks(c *gc.C) {
+	uuid := s.state.ModelUUID()
+	coll, closer := s.state.db().GetRawCollection(unitStatesC)
+	defer closer()
+	err := coll.Insert(bson.M{
+		"_id":                       uuid + ":u#wordpress/0#charm",
+		"model-uuid":                uuid,
+		"txn-revno":                 int64(1),
+		"application":               "wordpress",
+		"relation-state":            bson.M{"7": "changed-pending: true\n"},
+		"relation-state-chunks":     1,
+		"relation-state-generation": "g1",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	chunks, closer := s.state.db().GetRawCollection(unitStateChunksC)
+	defer closer()
+	err = chunks.Insert(bson.M{
+		"_id":            uuid + ":u#wordpress/0#charm#g1#1",
+		"model-uuid":     uuid,
+		"txn-revno":      int64(1),
+		"relation-state": bson.M{"8": "changed-pending: false\n"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	// The relation state held by the chunk is checked too.
+	problems, err := s.state.RepairUnitStates()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(problems, gc.HasLen, 2)
+	c.Check(problems[0].Key, gc.Equals, "7")
+	c.Check(problems[0].Detail, gc.Equals, "relation 7 not found")
+	c.Check(problems[1].Key, gc.Equals, "8")
+	c.Check(problems[1].Detail, gc.Equals, "relation 8 not found")
+
+	var doc unitStateDoc
+	err = coll.FindId(uuid + ":u#wordpress/0#charm").One(&doc)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(doc.RelationState, gc.HasLen, 0)
+	c.Check(doc.RelationStateChunks, gc.Equals, 0)
+	c.Check(doc.RelationStateGeneration, gc.Equals, "")
+	n, err := chunks.Count()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(n, gc.Equals, 0)
+}
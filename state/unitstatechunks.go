@@ -0,0 +1,225 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// relationStateSwitch switches the relation state of a unit from the
+// version held by its unit state document to a new one. mgo/txn holds
+// the documents written by a transaction in its transaction document,
+// which is limited in size like any other, so the chunks of relation
+// state that overflow the unit state document are each written by a
+// transaction of their own, under a new generation, before the unit
+// state document is switched to that generation. Whichever chunks are
+// left unused once the switch has been made, or has failed, are then
+// removed.
+type relationStateSwitch struct {
+	st            *State
+	unitGlobalKey string
+
+	// generation identifies the overflow chunks written for the new
+	// relation state, and written holds all of its chunks, including
+	// the first, which is held by the unit state document.
+	generation string
+	written    []map[string]string
+
+	// replacedGeneration and replacedChunks identify the overflow
+	// chunks of the relation state being replaced.
+	replacedGeneration string
+	replacedChunks     int
+
+	// switched is true if the last transaction built
+	// switches the unit state document to the new state.
+	switched bool
+}
+
+func (st *State) newRelationStateSwitch(unitGlobalKey string) *relationStateSwitch {
+	return &relationStateSwitch{
+		st:            st,
+		unitGlobalKey: unitGlobalKey,
+	}
+}
+
+// update returns the fields of the unit state document to set and unset
+// to switch it from its current relation state to the input chunks,
+// having written the chunks that overflow it. No fields are returned if
+// the relation state is unchanged. It is called for each attempt at the
+// transaction, so overflow chunks already written for the same chunks
+// are reused.
+func (s *relationStateSwitch) update(current *unitStateDoc, chunks []map[string]string) (bson.D, bson.D, error) {
+	s.switched = false
+	unchanged, err := s.st.relationStateUnchanged(current, chunks)
+	if err != nil || unchanged {
+		return nil, nil, errors.Trace(err)
+	}
+	if err := s.prepare(chunks); err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	s.switched = true
+	s.replacedGeneration = current.RelationStateGeneration
+	s.replacedChunks = current.RelationStateChunks
+
+	setFields, unsetFields := bson.D{}, bson.D{}
+	if len(chunks) == 0 {
+		unsetFields = append(unsetFields, bson.DocElem{Name: "relation-state"})
+	} else {
+		setFields = append(setFields, bson.DocElem{"relation-state", chunks[0]})
+	}
+	if overflow := len(chunks) - 1; overflow > 0 {
+		setFields = append(setFields,
+			bson.DocElem{"relation-state-chunks", overflow},
+			bson.DocElem{"relation-state-generation", s.generation},
+		)
+	} else {
+		if current.RelationStateChunks != 0 {
+			unsetFields = append(unsetFields, bson.DocElem{Name: "relation-state-chunks"})
+		}
+		if current.RelationStateGeneration != "" {
+			unsetFields = append(unsetFields, bson.DocElem{Name: "relation-state-generation"})
+		}
+	}
+	return setFields, unsetFields, nil
+}
+
+// prepare writes the chunks that overflow the unit state document
+// under a new generation, unless the same chunks have already been
+// written. Overflow chunks written for other chunks are removed.
+func (s *relationStateSwitch) prepare(chunks []map[string]string) error {
+	if s.written != nil && relationStateChunksMatch(s.written, chunks) {
+		return nil
+	}
+	s.discard()
+	if len(chunks) <= 1 {
+		return nil
+	}
+	generation := bson.NewObjectId().Hex()
+	for i := 1; i < len(chunks); i++ {
+		key := relationStateChunkKey(s.unitGlobalKey, generation, i)
+		err := s.st.db().RunTransaction([]txn.Op{{
+			C:      unitStateChunksC,
+			Id:     key,
+			Assert: txn.DocMissing,
+			Insert: unitStateChunkDoc{
+				DocID:         key,
+				RelationState: chunks[i],
+			},
+		}})
+		if err != nil {
+			s.remove(generation, i-1)
+			return errors.Annotatef(err, "writing relation state chunk %d of %q", i, s.unitGlobalKey)
+		}
+	}
+	s.generation, s.written = generation, chunks
+	return nil
+}
+
+// done removes the overflow chunks left unused once the transaction
+// that switches the unit state document has been run with the input
+// result: those replaced if the switch was made, and otherwise those
+// written for the switch.
+func (s *relationStateSwitch) done(err error) {
+	if err == nil && s.switched {
+		s.remove(s.replacedGeneration, s.replacedChunks)
+		return
+	}
+	if s.generation == "" {
+		return
+	}
+	// The outcome of a transaction that failed for reasons other than
+	// its assertions may not be known, so chunks that the unit state
+	// document has been switched to anyway are kept.
+	coll, closer := s.st.db().GetCollection(unitStatesC)
+	defer closer()
+	var stDoc unitStateDoc
+	lookupErr := coll.FindId(s.unitGlobalKey).Select(bson.D{{"relation-state-generation", 1}}).One(&stDoc)
+	if lookupErr != nil && lookupErr != mgo.ErrNotFound {
+		logger.Warningf("cannot read relation state generation of %q: %v", s.unitGlobalKey, lookupErr)
+		return
+	}
+	if stDoc.RelationStateGeneration != s.generation {
+		s.discard()
+	}
+}
+
+// discard removes the overflow chunks written for the switch.
+func (s *relationStateSwitch) discard() {
+	if s.generation != "" {
+		s.remove(s.generation, len(s.written)-1)
+	}
+	s.generation, s.written = "", nil
+}
+
+// remove removes the overflow chunks of the generation. Failure to do
+// so only leaves unused chunks behind, which are found by the orphan
+// sweep, so it is logged rather than returned.
+func (s *relationStateSwitch) remove(generation string, count int) {
+	ops := removeRelationStateChunksOps(s.unitGlobalKey, generation, count)
+	if len(ops) == 0 {
+		return
+	}
+	if err := s.st.db().RunTransaction(ops); err != nil {
+		logger.Warningf("cannot remove unused relation state chunks of %q: %v", s.unitGlobalKey, err)
+	}
+}
+
+// removeRelationStateChunksOps returns the operations needed to remove
+// the overflow chunks of the generation of a unit's relation state.
+func removeRelationStateChunksOps(unitGlobalKey, generation string, count int) []txn.Op {
+	ops := make([]txn.Op, count)
+	for i := range ops {
+		ops[i] = txn.Op{
+			C:      unitStateChunksC,
+			Id:     relationStateChunkKey(unitGlobalKey, generation, i+1),
+			Remove: true,
+		}
+	}
+	return ops
+}
+
+// relationStateUnchanged returns true if the relation state held by
+// the unit state document and its overflow chunks is the input chunks.
+// The chunks split from the same relation state are always the same,
+// so they are compared chunk by chunk.
+func (st *State) relationStateUnchanged(d *unitStateDoc, chunks []map[string]string) (bool, error) {
+	var first map[string]string
+	if len(chunks) > 0 {
+		first = chunks[0]
+	}
+	overflow := len(chunks) - 1
+	if overflow < 0 {
+		overflow = 0
+	}
+	if !relationStateMatches(d.RelationState, first) || d.RelationStateChunks != overflow {
+		return false, nil
+	}
+	current, err := st.relationStateChunks(d)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	for i := 1; i < len(chunks); i++ {
+		if doc, ok := current[i]; !ok || !relationStateMatches(doc.RelationState, chunks[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// relationStateChunksMatch returns true if the
+// two lists of relation state chunks are the same.
+func relationStateChunksMatch(a, b []map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !relationStateMatches(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// UniterStateFormat identifies where a unit's uniter keeps its
+// internal state, as derived from the unit's state document.
+type UniterStateFormat int
+
+const (
+	// UniterStateFormatLocal indicates that the uniter has never
+	// written its state to the controller, so keeps it all on the
+	// unit's disk.
+	UniterStateFormatLocal UniterStateFormat = iota
+
+	// UniterStateFormatUniterOnly indicates that the uniter has
+	// written its operation state to the controller, but not its
+	// storage state, which it still keeps on the unit's disk.
+	UniterStateFormatUniterOnly
+
+	// UniterStateFormatController indicates that the uniter
+	// keeps all of its state in the controller.
+	UniterStateFormatController
+)
+
+// CurrentUniterStateFormat is the format of the uniter
+// state written by agents of this version.
+const CurrentUniterStateFormat = UniterStateFormatController
+
+// UniterStateFormats returns the number of the model's units whose
+// uniter state is in each format. Formats that no unit has are absent.
+// Only the presence of the uniter and storage state is checked, not
+// their contents; see CheckUnitStates.
+func (st *State) UniterStateFormats() (map[UniterStateFormat]int, error) {
+	units, closer := st.db().GetCollection(unitsC)
+	defer closer()
+
+	var unitDocs []struct {
+		Name string `bson:"name"`
+	}
+	if err := units.Find(nil).Select(bson.D{{"name", 1}}).All(&unitDocs); err != nil {
+		return nil, errors.Annotate(err, "reading units")
+	}
+	// Units without a unit state document have never
+	// written any state to the controller.
+	formats := make(map[string]UniterStateFormat, len(unitDocs))
+	for _, doc := range unitDocs {
+		formats[doc.Name] = UniterStateFormatLocal
+	}
+
+	unitStates, closer := st.db().GetCollection(unitStatesC)
+	defer closer()
+
+	for format, query := range map[UniterStateFormat]bson.D{
+		UniterStateFormatUniterOnly: {
+			{"uniter-state", bson.D{{"$exists", true}}},
+			{"storage-state", bson.D{{"$exists", false}}},
+		},
+		UniterStateFormatController: {
+			{"uniter-state", bson.D{{"$exists", true}}},
+			{"storage-state", bson.D{{"$exists", true}}},
+		},
+	} {
+		var stateDocs []struct {
+			DocID string `bson:"_id"`
+		}
+		if err := unitStates.Find(query).Select(bson.D{{"_id", 1}}).All(&stateDocs); err != nil {
+			return nil, errors.Annotate(err, "reading unit states")
+		}
+		for _, doc := range stateDocs {
+			// Only units that exist are counted, in case the state
+			// document of a unit being removed outlives it.
+			unitName := unitNameFromStateDocID(st.localID(doc.DocID))
+			if _, ok := formats[unitName]; ok {
+				formats[unitName] = format
+			}
+		}
+	}
+
+	result := make(map[UniterStateFormat]int)
+	for _, format := range formats {
+		result[format]++
+	}
+	return result, nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/yaml.v2"
+)
+
+// MirroredUnitState holds the state persisted by a unit agent,
+// as written to a unit state mirror.
+type MirroredUnitState struct {
+	ModelUUID string `yaml:"model-uuid"`
+	UnitName  string `yaml:"unit"`
+
+	// TxnRevno is the revision of the unit state document
+	// from which the state was mirrored.
+	TxnRevno int64 `yaml:"txn-revno"`
+
+	// Mirrored is when the state was mirrored.
+	Mirrored time.Time `yaml:"mirrored"`
+
+	State         map[string]string            `yaml:"state,omitempty"`
+	BranchState   map[string]map[string]string `yaml:"branch-state,omitempty"`
+	UniterState   *string                      `yaml:"uniter-state,omitempty"`
+	RelationState map[string]string            `yaml:"relation-state,omitempty"`
+	StorageState  *string                      `yaml:"storage-state,omitempty"`
+}
+
+// UnitStateMirror writes the unit state documents of all models to
+// files in a directory, one file per unit under a directory per model,
+// so that the state persisted by unit agents can be recovered when a
+// controller is restored from a backup taken before it was persisted.
+// Only documents that have changed since they were last mirrored are
+// written.
+type UnitStateMirror struct {
+	st  *State
+	dir string
+
+	// revnos holds the revision of each document
+	// last mirrored, keyed by global document id.
+	revnos map[string]int64
+}
+
+// NewUnitStateMirror returns a UnitStateMirror that mirrors unit state
+// to files under dir, reading the revisions of the state already
+// mirrored there. The State must be the controller's state.
+func NewUnitStateMirror(st *State, dir string) (*UnitStateMirror, error) {
+	if !st.IsController() {
+		return nil, errors.NotValidf("mirroring unit state from non-controller model")
+	}
+	// The state persisted by charms may hold secrets.
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Annotate(err, "creating unit state mirror directory")
+	}
+	mirrored, err := ReadUnitStateMirror(dir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	revnos := make(map[string]int64, len(mirrored))
+	for _, unitState := range mirrored {
+		docID := ensureModelUUID(unitState.ModelUUID, unitGlobalKey(unitState.UnitName))
+		revnos[docID] = unitState.TxnRevno
+	}
+	return &UnitStateMirror{
+		st:     st,
+		dir:    dir,
+		revnos: revnos,
+	}, nil
+}
+
+// Mirror writes the unit state documents with the given global ids to
+// the mirror, skipping those whose revision is already mirrored, and
+// removing the mirrored state of those that no longer exist. It returns
+// the number of documents written.
+func (m *UnitStateMirror) Mirror(docIDs []string) (int, error) {
+	if len(docIDs) == 0 {
+		return 0, nil
+	}
+	coll, closer := m.st.db().GetRawCollection(unitStatesC)
+	defer closer()
+
+	found := make(map[string]bool, len(docIDs))
+	var (
+		written int
+		doc     unitStateDoc
+	)
+	iter := coll.Find(bson.M{"_id": bson.M{"$in": docIDs}}).Iter()
+	for iter.Next(&doc) {
+		found[doc.DocID] = true
+		if revno, ok := m.revnos[doc.DocID]; ok && revno == doc.TxnRevno {
+			doc = unitStateDoc{}
+			continue
+		}
+		// Relation state that overflows the document is held by chunks,
+		// which are never changed in place: the document is switched to
+		// new chunks, so its revision covers them too.
+		if err := m.st.assembleRelationState(&doc); err != nil {
+			_ = iter.Close()
+			return written, errors.Trace(err)
+		}
+		if err := m.write(doc); err != nil {
+			_ = iter.Close()
+			return written, errors.Trace(err)
+		}
+		m.revnos[doc.DocID] = doc.TxnRevno
+		written++
+		// Clear the document, so that the maps of the next
+		// are not merged into those of this one.
+		doc = unitStateDoc{}
+	}
+	if err := iter.Close(); err != nil {
+		return written, errors.Annotate(err, "reading unit states")
+	}
+	for _, docID := range docIDs {
+		if found[docID] {
+			continue
+		}
+		if err := m.remove(docID); err != nil {
+			return written, errors.Trace(err)
+		}
+	}
+	return written, nil
+}
+
+func (m *UnitStateMirror) write(doc unitStateDoc) error {
+	modelUUID, localID, ok := splitDocID(doc.DocID)
+	if !ok {
+		return errors.NotValidf("unit state document id %q", doc.DocID)
+	}
+	unitState := MirroredUnitState{
+		ModelUUID:     modelUUID,
+		UnitName:      unitNameFromStateDocID(localID),
+		TxnRevno:      doc.TxnRevno,
+		Mirrored:      m.st.clock().Now().UTC(),
+		State:         doc.State,
+		BranchState:   doc.BranchState,
+		UniterState:   doc.UniterState,
+		RelationState: doc.RelationState,
+		StorageState:  doc.StorageState,
+	}
+	data, err := yaml.Marshal(unitState)
+	if err != nil {
+		return errors.Annotatef(err, "marshalling state of unit %q", unitState.UnitName)
+	}
+	modelDir := filepath.Join(m.dir, modelUUID)
+	if err := os.MkdirAll(modelDir, 0700); err != nil {
+		return errors.Annotatef(err, "creating unit state mirror directory for model %q", modelUUID)
+	}
+	path := filepath.Join(modelDir, mirroredUnitStateFile(unitState.UnitName))
+	if err := utils.AtomicWriteFile(path, data, 0600); err != nil {
+		return errors.Annotatef(err, "mirroring state of unit %q", unitState.UnitName)
+	}
+	return nil
+}
+
+func (m *UnitStateMirror) remove(docID string) error {
+	modelUUID, localID, ok := splitDocID(docID)
+	if !ok {
+		return errors.NotValidf("unit state document id %q", docID)
+	}
+	unitName := unitNameFromStateDocID(localID)
+	path := filepath.Join(m.dir, modelUUID, mirroredUnitStateFile(unitName))
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.Annotatef(err, "removing mirrored state of unit %q", unitName)
+	}
+	delete(m.revnos, docID)
+	return nil
+}
+
+// mirroredUnitStateFile returns the name of the file
+// to which the state of the named unit is mirrored.
+func mirroredUnitStateFile(unitName string) string {
+	return strings.Replace(unitName, "/", "-", -1) + ".yaml"
+}
+
+// ReadUnitStateMirror returns the unit state mirrored to files under
+// dir, as written by a UnitStateMirror, grouped by model.
+func ReadUnitStateMirror(dir string) ([]MirroredUnitState, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "*.yaml"))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	result := make([]MirroredUnitState, 0, len(paths))
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Annotate(err, "reading mirrored unit state")
+		}
+		var unitState MirroredUnitState
+		if err := yaml.Unmarshal(data, &unitState); err != nil {
+			return nil, errors.Annotatef(err, "reading mirrored unit state from %q", path)
+		}
+		result = append(result, unitState)
+	}
+	return result, nil
+}
@@ -0,0 +1,169 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"os"
+	"path/filepath"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type unitStateMirrorSuite struct {
+	internalStateSuite
+
+	dir string
+}
+
+var _ = gc.Suite(&unitStateMirrorSuite{})
+
+func (s *unitStateMirrorSuite) SetUpTest(c *gc.C) {
+	s.internalStateSuite.SetUpTest(c)
+	s.dir = filepath.Join(c.MkDir(), "unit-state")
+
+	coll, closer := s.state.db().GetRawCollection(unitStatesC)
+	defer closer()
+
+	uuid := s.state.ModelUUID()
+	err := coll.Insert(bson.M{
+		"_id":          uuid + ":u#mysql/0#charm",
+		"model-uuid":   uuid,
+		"txn-revno":    int64(1),
+		"application":  "mysql",
+		"state":        bson.M{"key": "value"},
+		"uniter-state": "leader: true\n",
+	}, bson.M{
+		"_id":            uuid + ":u#wordpress/0#charm",
+		"model-uuid":     uuid,
+		"txn-revno":      int64(2),
+		"application":    "wordpress",
+		"relation-state": bson.M{"7": "changed-pending: true\n"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *unitStateMirrorSuite) docIDs() []string {
+	uuid := s.state.ModelUUID()
+	return []string{
+		uuid + ":u#mysql/0#charm",
+		uuid + ":u#wordpress/0#charm",
+	}
+}
+
+func (s *unitStateMirrorSuite) TestMirror(c *gc.C) {
+	mirror, err := NewUnitStateMirror(s.state, s.dir)
+	c.Assert(err, jc.ErrorIsNil)
+
+	written, err := mirror.Mirror(s.docIDs())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(written, gc.Equals, 2)
+
+	mirrored, err := ReadUnitStateMirror(s.dir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(mirrored, gc.HasLen, 2)
+	uuid := s.state.ModelUUID()
+	c.Check(mirrored[0].ModelUUID, gc.Equals, uuid)
+	c.Check(mirrored[0].UnitName, gc.Equals, "mysql/0")
+	c.Check(mirrored[0].TxnRevno, gc.Equals, int64(1))
+	c.Check(mirrored[0].State, jc.DeepEquals, map[string]string{"key": "value"})
+	c.Check(mirrored[0].UniterState, gc.Equals, "leader: true\n")
+	c.Check(mirrored[0].RelationState, gc.HasLen, 0)
+	c.Check(mirrored[1].UnitName, gc.Equals, "wordpress/0")
+	c.Check(mirrored[1].RelationState, jc.DeepEquals, map[string]string{"7": "changed-pending: true\n"})
+
+	info, err := os.Stat(filepath.Join(s.dir, uuid, "mysql-0.yaml"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(info.Mode().Perm(), gc.Equals, os.FileMode(0600))
+}
+
+func (s *unitStateMirrorSuite) TestMirrorChangedOnly(c *gc.C) {
+	mirror, err := NewUnitStateMirror(s.state, s.dir)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = mirror.Mirror(s.docIDs())
+	c.Assert(err, jc.ErrorIsNil)
+
+	written, err := mirror.Mirror(s.docIDs())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(written, gc.Equals, 0)
+
+	coll, closer := s.state.db().GetRawCollection(unitStatesC)
+	defer closer()
+	err = coll.UpdateId(s.docIDs()[1], bson.M{
+		"$set": bson.M{"txn-revno": int64(3), "uniter-state": "started: true\n"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	written, err = mirror.Mirror(s.docIDs())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(written, gc.Equals, 1)
+
+	mirrored, err := ReadUnitStateMirror(s.dir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(mirrored, gc.HasLen, 2)
+	c.Check(mirrored[1].TxnRevno, gc.Equals, int64(3))
+	c.Check(mirrored[1].UniterState, gc.Equals, "started: true\n")
+
+	// A new mirror knows the revisions already mirrored.
+	mirror, err = NewUnitStateMirror(s.state, s.dir)
+	c.Assert(err, jc.ErrorIsNil)
+	written, err = mirror.Mirror(s.docIDs())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(written, gc.Equals, 0)
+}
+
+func (s *unitStateMirrorSuite) TestMirrorRelationStateChunks(c *gc.C) {
+	uuid := s.state.ModelUUID()
+	coll, closer := s.state.db().GetRawCollection(unitStatesC)
+	defer closer()
+	err := coll.UpdateId(s.docIDs()[1], bson.M{"$set": bson.M{
+		"relation-state-chunks":     1,
+		"relation-state-generation": "g1",
+	}})
+	c.Assert(err, jc.ErrorIsNil)
+	chunks, closer := s.state.db().GetRawCollection(unitStateChunksC)
+	defer closer()
+	err = chunks.Insert(bson.M{
+		"_id":            uuid + ":u#wordpress/0#charm#g1#1",
+		"model-uuid":     uuid,
+		"txn-revno":      int64(1),
+		"relation-state": bson.M{"8": "changed-pending: false\n"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	mirror, err := NewUnitStateMirror(s.state, s.dir)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = mirror.Mirror(s.docIDs())
+	c.Assert(err, jc.ErrorIsNil)
+
+	mirrored, err := ReadUnitStateMirror(s.dir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(mirrored, gc.HasLen, 2)
+	c.Check(mirrored[1].RelationState, jc.DeepEquals, map[string]string{
+		"7": "changed-pending: true\n",
+		"8": "changed-pending: false\n",
+	})
+}
+
+func (s *unitStateMirrorSuite) TestMirrorRemoved(c *gc.C) {
+	mirror, err := NewUnitStateMirror(s.state, s.dir)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = mirror.Mirror(s.docIDs())
+	c.Assert(err, jc.ErrorIsNil)
+
+	coll, closer := s.state.db().GetRawCollection(unitStatesC)
+	defer closer()
+	err = coll.RemoveId(s.docIDs()[0])
+	c.Assert(err, jc.ErrorIsNil)
+
+	written, err := mirror.Mirror(s.docIDs()[:1])
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(written, gc.Equals, 0)
+
+	mirrored, err := ReadUnitStateMirror(s.dir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(mirrored, gc.HasLen, 1)
+	c.Check(mirrored[0].UnitName, gc.Equals, "wordpress/0")
+}
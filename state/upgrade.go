@@ -37,6 +37,8 @@ package state
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/juju/collections/set"
@@ -89,6 +91,139 @@ type upgradeInfoDoc struct {
 	Started          time.Time      `bson:"started"`
 	ControllersReady []string       `bson:"controllersReady"`
 	ControllersDone  []string       `bson:"controllersDone"`
+
+	StepCollections []upgradeStepCollectionsDoc `bson:"stepCollections,omitempty"`
+	StepPhases      []upgradeStepPhaseDoc       `bson:"stepPhases,omitempty"`
+	SkippedModels   []upgradeSkippedModelDoc    `bson:"skippedModels,omitempty"`
+
+	DeferredMigrations []upgradeDeferredMigrationDoc `bson:"deferredMigrations,omitempty"`
+
+	StepEstimates       []upgradeStepEstimateDoc `bson:"stepEstimates,omitempty"`
+	EstimatedCompletion time.Time                `bson:"estimatedCompletion,omitempty"`
+
+	RestartOrder         []string `bson:"restartOrder,omitempty"`
+	ControllersRestarted []string `bson:"controllersRestarted,omitempty"`
+
+	AwaitingApproval bool `bson:"awaitingApproval,omitempty"`
+	Approved         bool `bson:"approved,omitempty"`
+
+	MongoTargetVersion string                 `bson:"mongoTargetVersion,omitempty"`
+	MongoUpgradePhases []mongoUpgradePhaseDoc `bson:"mongoUpgradePhases,omitempty"`
+}
+
+// mongoUpgradePhaseDoc records a single phase of
+// the upgrade of the mongo servers to a new version.
+type mongoUpgradePhaseDoc struct {
+	Kind         MongoUpgradePhaseKind `bson:"kind"`
+	ControllerId string                `bson:"controller-id,omitempty"`
+	Done         bool                  `bson:"done,omitempty"`
+}
+
+// MongoUpgradePhaseKind identifies what is done
+// by a phase of a mongo server version upgrade.
+type MongoUpgradePhaseKind string
+
+const (
+	// MongoUpgradeMember is the phase in which a controller restarts
+	// its mongo server to run the newer mongod installed alongside it.
+	MongoUpgradeMember MongoUpgradePhaseKind = "upgrade-member"
+
+	// MongoSetFeatureCompatibility is the phase in which the feature
+	// compatibility version of the replica set is raised to the target
+	// version, once all of its members run the newer mongod.
+	MongoSetFeatureCompatibility MongoUpgradePhaseKind = "set-feature-compatibility-version"
+)
+
+// MongoUpgradePhase describes a phase of the upgrade of the mongo
+// servers to a new version, and whether it has been done.
+type MongoUpgradePhase struct {
+	Kind MongoUpgradePhaseKind
+
+	// ControllerId identifies the controller whose
+	// mongo server is upgraded by a member phase.
+	ControllerId string
+
+	Done bool
+}
+
+// upgradeSkippedModelDoc records a model that a database
+// upgrade step failed for, and so was skipped.
+type upgradeSkippedModelDoc struct {
+	ModelUUID string `bson:"model-uuid"`
+	Error     string `bson:"error"`
+}
+
+// UpgradeSkippedModel describes a model that a database upgrade
+// step failed for. The model is skipped so that the rest of the
+// controller can be upgraded, and must be followed up on.
+type UpgradeSkippedModel struct {
+	ModelUUID string
+	Error     string
+}
+
+// upgradeStepCollectionsDoc records the collections
+// touched by a single database upgrade step.
+type upgradeStepCollectionsDoc struct {
+	Description string   `bson:"description"`
+	Read        []string `bson:"read,omitempty"`
+	Write       []string `bson:"write,omitempty"`
+}
+
+// UpgradeStepCollections describes the collections that a
+// database upgrade step declares it reads from and writes to.
+type UpgradeStepCollections struct {
+	Description string
+	Read        []string
+	Write       []string
+}
+
+// upgradeStepPhaseDoc records the phase of a
+// single expand/contract database upgrade step.
+type upgradeStepPhaseDoc struct {
+	Description string           `bson:"description"`
+	Phase       UpgradeStepPhase `bson:"phase"`
+	Done        bool             `bson:"done,omitempty"`
+}
+
+// UpgradeStepPhase identifies the phase of an expand/contract
+// migration that a database upgrade step carries out.
+type UpgradeStepPhase string
+
+const (
+	// UpgradeExpandPhase is the phase in which a step adds data in its
+	// new shape alongside the old, before the new binaries start. It is
+	// run with the other upgrade steps.
+	UpgradeExpandPhase UpgradeStepPhase = "expand"
+
+	// UpgradeContractPhase is the phase in which a step removes the data
+	// in its old shape. It is deferred until every agent runs a version
+	// that no longer relies on it.
+	UpgradeContractPhase UpgradeStepPhase = "contract"
+)
+
+// UpgradeStepPhaseInfo describes the phase of an expand/contract
+// database upgrade step, and whether the step has run. Contract
+// steps are not run during the upgrade itself.
+type UpgradeStepPhaseInfo struct {
+	Description string
+	Phase       UpgradeStepPhase
+	Done        bool
+}
+
+// upgradeStepEstimateDoc records the estimated
+// duration of a single database upgrade step.
+type upgradeStepEstimateDoc struct {
+	Description string        `bson:"description"`
+	Estimate    time.Duration `bson:"estimate"`
+	Done        bool          `bson:"done,omitempty"`
+}
+
+// UpgradeStepEstimate describes how long a database upgrade step is
+// estimated to take, and whether it has completed.
+type UpgradeStepEstimate struct {
+	Description string
+	Estimate    time.Duration
+	Done        bool
 }
 
 // UpgradeInfo is used to synchronise controller upgrades.
@@ -133,6 +268,411 @@ func (info *UpgradeInfo) ControllersDone() []string {
 	return result
 }
 
+// StepCollections returns the collections touched by each of the
+// database upgrade steps run for this upgrade, in the order that
+// the steps are run.
+func (info *UpgradeInfo) StepCollections() []UpgradeStepCollections {
+	result := make([]UpgradeStepCollections, len(info.doc.StepCollections))
+	for i, doc := range info.doc.StepCollections {
+		result[i] = UpgradeStepCollections{
+			Description: doc.Description,
+			Read:        append([]string(nil), doc.Read...),
+			Write:       append([]string(nil), doc.Write...),
+		}
+	}
+	return result
+}
+
+// SetStepCollections records the collections touched by each of the
+// database upgrade steps run for this upgrade, replacing any that
+// were previously recorded.
+func (info *UpgradeInfo) SetStepCollections(steps []UpgradeStepCollections) error {
+	if info.doc.Id != currentUpgradeId {
+		return errors.New("cannot set step collections on non-current upgrade")
+	}
+	docs := make([]upgradeStepCollectionsDoc, len(steps))
+	for i, step := range steps {
+		docs[i] = upgradeStepCollectionsDoc{
+			Description: step.Description,
+			Read:        step.Read,
+			Write:       step.Write,
+		}
+	}
+	ops := []txn.Op{{
+		C:      upgradeInfoC,
+		Id:     currentUpgradeId,
+		Assert: assertExpectedVersions(info.doc.PreviousVersion, info.doc.TargetVersion),
+		Update: bson.D{{"$set", bson.D{{"stepCollections", docs}}}},
+	}}
+	err := info.st.db().RunTransaction(ops)
+	if err == txn.ErrAborted {
+		return errors.New("cannot set upgrade step collections: current upgrade info has changed")
+	} else if err != nil {
+		return errors.Annotate(err, "cannot set upgrade step collections")
+	}
+	info.doc.StepCollections = docs
+	return nil
+}
+
+// StepPhases returns the phase of each of the expand/contract database
+// upgrade steps for this upgrade, in the order that the steps are run.
+func (info *UpgradeInfo) StepPhases() []UpgradeStepPhaseInfo {
+	result := make([]UpgradeStepPhaseInfo, len(info.doc.StepPhases))
+	for i, doc := range info.doc.StepPhases {
+		result[i] = UpgradeStepPhaseInfo{
+			Description: doc.Description,
+			Phase:       doc.Phase,
+			Done:        doc.Done,
+		}
+	}
+	return result
+}
+
+// SetStepPhases records the phase of each of the expand/contract database
+// upgrade steps for this upgrade, and whether each has run, replacing any
+// that were previously recorded.
+func (info *UpgradeInfo) SetStepPhases(steps []UpgradeStepPhaseInfo) error {
+	if info.doc.Id != currentUpgradeId {
+		return errors.New("cannot set step phases on non-current upgrade")
+	}
+	docs := make([]upgradeStepPhaseDoc, len(steps))
+	for i, step := range steps {
+		docs[i] = upgradeStepPhaseDoc{
+			Description: step.Description,
+			Phase:       step.Phase,
+			Done:        step.Done,
+		}
+	}
+	ops := []txn.Op{{
+		C:      upgradeInfoC,
+		Id:     currentUpgradeId,
+		Assert: assertExpectedVersions(info.doc.PreviousVersion, info.doc.TargetVersion),
+		Update: bson.D{{"$set", bson.D{{"stepPhases", docs}}}},
+	}}
+	err := info.st.db().RunTransaction(ops)
+	if err == txn.ErrAborted {
+		return errors.New("cannot set upgrade step phases: current upgrade info has changed")
+	} else if err != nil {
+		return errors.Annotate(err, "cannot set upgrade step phases")
+	}
+	info.doc.StepPhases = docs
+	return nil
+}
+
+// StepEstimates returns the estimated duration of each of the database
+// upgrade steps run for this upgrade, in the order that the steps are run.
+func (info *UpgradeInfo) StepEstimates() []UpgradeStepEstimate {
+	result := make([]UpgradeStepEstimate, len(info.doc.StepEstimates))
+	for i, doc := range info.doc.StepEstimates {
+		result[i] = UpgradeStepEstimate{
+			Description: doc.Description,
+			Estimate:    doc.Estimate,
+			Done:        doc.Done,
+		}
+	}
+	return result
+}
+
+// EstimatedCompletion returns when the database upgrade steps are
+// estimated to complete, or the zero time if there is no estimate.
+func (info *UpgradeInfo) EstimatedCompletion() time.Time {
+	return info.doc.EstimatedCompletion
+}
+
+// SetEstimate records the estimated duration of each of the database
+// upgrade steps run for this upgrade, and when the steps are estimated
+// to complete, replacing any estimate that was previously recorded.
+func (info *UpgradeInfo) SetEstimate(steps []UpgradeStepEstimate, completion time.Time) error {
+	if info.doc.Id != currentUpgradeId {
+		return errors.New("cannot set estimate on non-current upgrade")
+	}
+	docs := make([]upgradeStepEstimateDoc, len(steps))
+	for i, step := range steps {
+		docs[i] = upgradeStepEstimateDoc{
+			Description: step.Description,
+			Estimate:    step.Estimate,
+			Done:        step.Done,
+		}
+	}
+	completion = completion.UTC().Round(time.Second)
+	ops := []txn.Op{{
+		C:      upgradeInfoC,
+		Id:     currentUpgradeId,
+		Assert: assertExpectedVersions(info.doc.PreviousVersion, info.doc.TargetVersion),
+		Update: bson.D{{"$set", bson.D{
+			{"stepEstimates", docs},
+			{"estimatedCompletion", completion},
+		}}},
+	}}
+	err := info.st.db().RunTransaction(ops)
+	if err == txn.ErrAborted {
+		return errors.New("cannot set upgrade estimate: current upgrade info has changed")
+	} else if err != nil {
+		return errors.Annotate(err, "cannot set upgrade estimate")
+	}
+	info.doc.StepEstimates = docs
+	info.doc.EstimatedCompletion = completion
+	return nil
+}
+
+// RestartOrder returns the ids of the controllers in the order in
+// which they are to restart once the database has been upgraded.
+func (info *UpgradeInfo) RestartOrder() []string {
+	result := make([]string, len(info.doc.RestartOrder))
+	copy(result, info.doc.RestartOrder)
+	return result
+}
+
+// SetRestartOrder records the order in which the controllers are
+// to restart once the database has been upgraded, replacing any
+// order that was previously recorded.
+func (info *UpgradeInfo) SetRestartOrder(controllerIds []string) error {
+	if info.doc.Id != currentUpgradeId {
+		return errors.New("cannot set restart order on non-current upgrade")
+	}
+	ops := []txn.Op{{
+		C:      upgradeInfoC,
+		Id:     currentUpgradeId,
+		Assert: assertExpectedVersions(info.doc.PreviousVersion, info.doc.TargetVersion),
+		Update: bson.D{{"$set", bson.D{{"restartOrder", controllerIds}}}},
+	}}
+	err := info.st.db().RunTransaction(ops)
+	if err == txn.ErrAborted {
+		return errors.New("cannot set controller restart order: current upgrade info has changed")
+	} else if err != nil {
+		return errors.Annotate(err, "cannot set controller restart order")
+	}
+	info.doc.RestartOrder = append([]string(nil), controllerIds...)
+	return nil
+}
+
+// ControllersRestarted returns the ids of the controllers that
+// have restarted since the database was upgraded.
+func (info *UpgradeInfo) ControllersRestarted() []string {
+	result := make([]string, len(info.doc.ControllersRestarted))
+	copy(result, info.doc.ControllersRestarted)
+	return result
+}
+
+// SetControllerRestarted records that the controller with the
+// supplied id has restarted since the database was upgraded.
+func (info *UpgradeInfo) SetControllerRestarted(controllerId string) error {
+	if info.doc.Id != currentUpgradeId {
+		return errors.New("cannot record restart on non-current upgrade")
+	}
+	ops := []txn.Op{{
+		C:      upgradeInfoC,
+		Id:     currentUpgradeId,
+		Assert: assertExpectedVersions(info.doc.PreviousVersion, info.doc.TargetVersion),
+		Update: bson.D{{"$addToSet", bson.D{{"controllersRestarted", controllerId}}}},
+	}}
+	err := info.st.db().RunTransaction(ops)
+	if err == txn.ErrAborted {
+		return errors.New("cannot record controller restart: current upgrade info has changed")
+	} else if err != nil {
+		return errors.Annotate(err, "cannot record controller restart")
+	}
+	if !set.NewStrings(info.doc.ControllersRestarted...).Contains(controllerId) {
+		info.doc.ControllersRestarted = append(info.doc.ControllersRestarted, controllerId)
+	}
+	return nil
+}
+
+// MongoTargetVersion returns the version to which the mongo servers
+// are upgraded, or an empty string if they are not being upgraded.
+func (info *UpgradeInfo) MongoTargetVersion() string {
+	return info.doc.MongoTargetVersion
+}
+
+// MongoUpgradePhases returns the phases of the upgrade of the mongo
+// servers, in the order in which they are to be done.
+func (info *UpgradeInfo) MongoUpgradePhases() []MongoUpgradePhase {
+	result := make([]MongoUpgradePhase, len(info.doc.MongoUpgradePhases))
+	for i, doc := range info.doc.MongoUpgradePhases {
+		result[i] = MongoUpgradePhase{
+			Kind:         doc.Kind,
+			ControllerId: doc.ControllerId,
+			Done:         doc.Done,
+		}
+	}
+	return result
+}
+
+// SetMongoUpgradePlan records the version to which the mongo servers
+// are to be upgraded, and the phases in which they are upgraded, in
+// order. It fails if a plan has already been recorded, so that a plan
+// is not replaced while it is being carried out.
+func (info *UpgradeInfo) SetMongoUpgradePlan(targetVersion string, phases []MongoUpgradePhase) error {
+	if info.doc.Id != currentUpgradeId {
+		return errors.New("cannot set mongo upgrade plan on non-current upgrade")
+	}
+	docs := make([]mongoUpgradePhaseDoc, len(phases))
+	for i, phase := range phases {
+		docs[i] = mongoUpgradePhaseDoc{
+			Kind:         phase.Kind,
+			ControllerId: phase.ControllerId,
+			Done:         phase.Done,
+		}
+	}
+	ops := []txn.Op{{
+		C:  upgradeInfoC,
+		Id: currentUpgradeId,
+		Assert: append(assertExpectedVersions(info.doc.PreviousVersion, info.doc.TargetVersion),
+			bson.DocElem{"mongoUpgradePhases", bson.D{{"$exists", false}}}),
+		Update: bson.D{{"$set", bson.D{
+			{"mongoTargetVersion", targetVersion},
+			{"mongoUpgradePhases", docs},
+		}}},
+	}}
+	err := info.st.db().RunTransaction(ops)
+	if err == txn.ErrAborted {
+		return errors.New("cannot set mongo upgrade plan: current upgrade info has changed")
+	} else if err != nil {
+		return errors.Annotate(err, "cannot set mongo upgrade plan")
+	}
+	info.doc.MongoTargetVersion = targetVersion
+	info.doc.MongoUpgradePhases = docs
+	return nil
+}
+
+// SetMongoUpgradePhaseDone records that the phase of the upgrade
+// of the mongo servers, with the input index, has been done.
+func (info *UpgradeInfo) SetMongoUpgradePhaseDone(index int) error {
+	if info.doc.Id != currentUpgradeId {
+		return errors.New("cannot record mongo upgrade phase on non-current upgrade")
+	}
+	if index < 0 || index >= len(info.doc.MongoUpgradePhases) {
+		return errors.NotFoundf("mongo upgrade phase %d", index)
+	}
+	ops := []txn.Op{{
+		C:      upgradeInfoC,
+		Id:     currentUpgradeId,
+		Assert: assertExpectedVersions(info.doc.PreviousVersion, info.doc.TargetVersion),
+		Update: bson.D{{"$set", bson.D{{fmt.Sprintf("mongoUpgradePhases.%d.done", index), true}}}},
+	}}
+	err := info.st.db().RunTransaction(ops)
+	if err == txn.ErrAborted {
+		return errors.New("cannot record mongo upgrade phase: current upgrade info has changed")
+	} else if err != nil {
+		return errors.Annotate(err, "cannot record mongo upgrade phase")
+	}
+	info.doc.MongoUpgradePhases[index].Done = true
+	return nil
+}
+
+// AwaitingApproval returns true if the database upgrade has paused
+// for an operator to approve it before proceeding.
+func (info *UpgradeInfo) AwaitingApproval() bool {
+	return info.doc.AwaitingApproval
+}
+
+// Approved returns true if an operator has approved
+// the database upgrade to proceed.
+func (info *UpgradeInfo) Approved() bool {
+	return info.doc.Approved
+}
+
+// SetAwaitingApproval records that the database upgrade has paused
+// for an operator to approve it before proceeding. It is an error
+// to do so once the upgrade has been approved.
+func (info *UpgradeInfo) SetAwaitingApproval() error {
+	if info.doc.Id != currentUpgradeId {
+		return errors.New("cannot await approval of non-current upgrade")
+	}
+	ops := []txn.Op{{
+		C:  upgradeInfoC,
+		Id: currentUpgradeId,
+		Assert: append(
+			assertExpectedVersions(info.doc.PreviousVersion, info.doc.TargetVersion),
+			bson.DocElem{Name: "approved", Value: bson.D{{"$ne", true}}},
+		),
+		Update: bson.D{{"$set", bson.D{{"awaitingApproval", true}}}},
+	}}
+	err := info.st.db().RunTransaction(ops)
+	if err == txn.ErrAborted {
+		return errors.New("cannot await upgrade approval: current upgrade info has changed")
+	} else if err != nil {
+		return errors.Annotate(err, "cannot await upgrade approval")
+	}
+	info.doc.AwaitingApproval = true
+	return nil
+}
+
+// Approve records that an operator has approved the database upgrade
+// to proceed. It is an error to do so if the upgrade is not awaiting
+// approval.
+func (info *UpgradeInfo) Approve() error {
+	if info.doc.Id != currentUpgradeId {
+		return errors.New("cannot approve non-current upgrade")
+	}
+	ops := []txn.Op{{
+		C:  upgradeInfoC,
+		Id: currentUpgradeId,
+		Assert: append(
+			assertExpectedVersions(info.doc.PreviousVersion, info.doc.TargetVersion),
+			bson.DocElem{Name: "awaitingApproval", Value: true},
+		),
+		Update: bson.D{{"$set", bson.D{
+			{"awaitingApproval", false},
+			{"approved", true},
+		}}},
+	}}
+	err := info.st.db().RunTransaction(ops)
+	if err == txn.ErrAborted {
+		return errors.New("cannot approve upgrade: upgrade is not awaiting approval")
+	} else if err != nil {
+		return errors.Annotate(err, "cannot approve upgrade")
+	}
+	info.doc.AwaitingApproval = false
+	info.doc.Approved = true
+	return nil
+}
+
+// SkippedModels returns the models that database upgrade steps
+// failed for during this upgrade, in the order they failed.
+// A model is recorded once for each step that failed for it.
+func (info *UpgradeInfo) SkippedModels() []UpgradeSkippedModel {
+	result := make([]UpgradeSkippedModel, len(info.doc.SkippedModels))
+	for i, doc := range info.doc.SkippedModels {
+		result[i] = UpgradeSkippedModel{
+			ModelUUID: doc.ModelUUID,
+			Error:     doc.Error,
+		}
+	}
+	return result
+}
+
+// recordUpgradeSkippedModels records the models that a database upgrade
+// step failed for against the current upgrade, if there is one.
+func recordUpgradeSkippedModels(st *State, failed map[string]error) error {
+	uuids := make([]string, 0, len(failed))
+	for uuid := range failed {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+	docs := make([]upgradeSkippedModelDoc, len(uuids))
+	for i, uuid := range uuids {
+		logger.Errorf("skipping database upgrade of model %q: %v", uuid, failed[uuid])
+		docs[i] = upgradeSkippedModelDoc{
+			ModelUUID: uuid,
+			Error:     failed[uuid].Error(),
+		}
+	}
+
+	ops := []txn.Op{{
+		C:      upgradeInfoC,
+		Id:     currentUpgradeId,
+		Assert: txn.DocExists,
+		Update: bson.D{{"$push", bson.D{{"skippedModels", bson.D{{"$each", docs}}}}}},
+	}}
+	err := st.db().RunTransaction(ops)
+	if err == txn.ErrAborted {
+		// There is no upgrade in progress to record them against.
+		return nil
+	}
+	return errors.Annotate(err, "cannot record models skipped by upgrade")
+}
+
 // Refresh updates the contents of the UpgradeInfo from underlying state.
 func (info *UpgradeInfo) Refresh() error {
 	doc, err := currentUpgradeInfoDoc(info.st)
@@ -518,6 +1058,16 @@ func (st *State) IsUpgrading() (bool, error) {
 	}
 }
 
+// CurrentUpgradeInfo returns the UpgradeInfo for the upgrade in
+// progress, or a NotFound error if there is no upgrade in progress.
+func (st *State) CurrentUpgradeInfo() (*UpgradeInfo, error) {
+	doc, err := currentUpgradeInfoDoc(st)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UpgradeInfo{st: st, doc: *doc}, nil
+}
+
 // AbortCurrentUpgrade archives any current UpgradeInfo and sets its
 // status to UpgradeAborted. Nothing happens if there's no current
 // UpgradeInfo.
@@ -534,6 +1084,17 @@ func (st *State) AbortCurrentUpgrade() error {
 
 }
 
+// ApproveCurrentUpgrade approves the current upgrade to proceed,
+// if it is awaiting approval. A NotFound error is returned if
+// there is no current upgrade.
+func (st *State) ApproveCurrentUpgrade() error {
+	info, err := st.CurrentUpgradeInfo()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(info.Approve())
+}
+
 func currentUpgradeInfoDoc(st *State) (*upgradeInfoDoc, error) {
 	var doc upgradeInfoDoc
 	upgradeInfo, closer := st.db().GetCollection(upgradeInfoC)
@@ -581,3 +1142,25 @@ func (st *State) ClearUpgradeInfo() error {
 	err := st.db().RunTransaction(ops)
 	return errors.Annotate(err, "cannot clear upgrade info")
 }
+
+// CollectionStats returns the number of documents in the named
+// collection, and their total size in bytes, so that the time taken
+// by the upgrade steps that process the collection can be estimated.
+// A collection that does not exist is reported as empty.
+func (st *State) CollectionStats(name string) (count, size int64, err error) {
+	coll, closer := st.db().GetRawCollection(name)
+	defer closer()
+	var stats struct {
+		Count int64 `bson:"count"`
+		Size  int64 `bson:"size"`
+	}
+	if err := coll.Database.Run(bson.D{{"collStats", coll.Name}}, &stats); err != nil {
+		// See collStats for the errors returned by
+		// the different versions of mongo.
+		if strings.Contains(err.Error(), "not found") {
+			return 0, 0, nil
+		}
+		return 0, 0, errors.Annotatef(err, "reading stats of collection %q", name)
+	}
+	return stats.Count, stats.Size, nil
+}
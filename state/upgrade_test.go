@@ -5,15 +5,19 @@ package state_test
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/juju/errors"
 	jc "github.com/juju/testing/checkers"
+	"github.com/juju/utils"
 	"github.com/juju/version"
 	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v3"
 	"gopkg.in/juju/worker.v1"
 
 	"github.com/juju/juju/core/constraints"
 	"github.com/juju/juju/core/instance"
+	"github.com/juju/juju/core/migration"
 	"github.com/juju/juju/core/status"
 	"github.com/juju/juju/state"
 	statetesting "github.com/juju/juju/state/testing"
@@ -707,6 +711,245 @@ func (s *UpgradeSuite) TestClearUpgradeInfo(c *gc.C) {
 	s.assertUpgrading(c, true)
 }
 
+func (s *UpgradeSuite) TestSetStepCollections(c *gc.C) {
+	info, err := s.State.EnsureUpgradeInfo(s.serverIdA, vers("1.2.3"), vers("2.4.5"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(info.StepCollections(), gc.HasLen, 0)
+
+	steps := []state.UpgradeStepCollections{{
+		Description: "move units",
+		Read:        []string{"units", "machines"},
+		Write:       []string{"units"},
+	}, {
+		Description: "undeclared step",
+	}}
+	err = info.SetStepCollections(steps)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(info.StepCollections(), jc.DeepEquals, []state.UpgradeStepCollections{{
+		Description: "move units",
+		Read:        []string{"units", "machines"},
+		Write:       []string{"units"},
+	}, {
+		Description: "undeclared step",
+	}})
+
+	current, err := s.State.CurrentUpgradeInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(current.StepCollections(), jc.DeepEquals, info.StepCollections())
+}
+
+func (s *UpgradeSuite) TestSetStepPhases(c *gc.C) {
+	info, err := s.State.EnsureUpgradeInfo(s.serverIdA, vers("1.2.3"), vers("2.4.5"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(info.StepPhases(), gc.HasLen, 0)
+
+	steps := []state.UpgradeStepPhaseInfo{{
+		Description: "copy unit ports",
+		Phase:       state.UpgradeExpandPhase,
+		Done:        true,
+	}, {
+		Description: "remove unit ports",
+		Phase:       state.UpgradeContractPhase,
+	}}
+	err = info.SetStepPhases(steps)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(info.StepPhases(), jc.DeepEquals, steps)
+
+	current, err := s.State.CurrentUpgradeInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(current.StepPhases(), jc.DeepEquals, steps)
+}
+
+func (s *UpgradeSuite) TestSetEstimate(c *gc.C) {
+	info, err := s.State.EnsureUpgradeInfo(s.serverIdA, vers("1.2.3"), vers("2.4.5"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(info.StepEstimates(), gc.HasLen, 0)
+	c.Check(info.EstimatedCompletion().IsZero(), jc.IsTrue)
+
+	steps := []state.UpgradeStepEstimate{{
+		Description: "move units",
+		Estimate:    time.Minute,
+		Done:        true,
+	}, {
+		Description: "move machines",
+		Estimate:    2 * time.Second,
+	}}
+	completion := time.Date(2020, 4, 1, 12, 30, 0, 0, time.UTC)
+	err = info.SetEstimate(steps, completion)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(info.StepEstimates(), jc.DeepEquals, steps)
+	c.Check(info.EstimatedCompletion().Equal(completion), jc.IsTrue)
+
+	current, err := s.State.CurrentUpgradeInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(current.StepEstimates(), jc.DeepEquals, steps)
+	c.Check(current.EstimatedCompletion().Equal(completion), jc.IsTrue)
+}
+
+func (s *UpgradeSuite) TestCollectionStats(c *gc.C) {
+	// The controller machine is added by SetUpTest.
+	count, size, err := s.State.CollectionStats("machines")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(count, gc.Equals, int64(1))
+	c.Check(size > 0, jc.IsTrue)
+
+	count, size, err = s.State.CollectionStats("nonexistent")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(count, gc.Equals, int64(0))
+	c.Check(size, gc.Equals, int64(0))
+}
+
+func (s *UpgradeSuite) TestRestartOrder(c *gc.C) {
+	info, err := s.State.EnsureUpgradeInfo(s.serverIdA, vers("1.2.3"), vers("2.4.5"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(info.RestartOrder(), gc.HasLen, 0)
+	c.Check(info.ControllersRestarted(), gc.HasLen, 0)
+
+	err = info.SetRestartOrder([]string{"1", "2", "0"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(info.RestartOrder(), jc.DeepEquals, []string{"1", "2", "0"})
+
+	err = info.SetControllerRestarted("1")
+	c.Assert(err, jc.ErrorIsNil)
+	err = info.SetControllerRestarted("1")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(info.ControllersRestarted(), jc.DeepEquals, []string{"1"})
+
+	current, err := s.State.CurrentUpgradeInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(current.RestartOrder(), jc.DeepEquals, []string{"1", "2", "0"})
+	c.Check(current.ControllersRestarted(), jc.DeepEquals, []string{"1"})
+}
+
+func (s *UpgradeSuite) TestMongoUpgradePlan(c *gc.C) {
+	info, err := s.State.EnsureUpgradeInfo(s.serverIdA, vers("1.2.3"), vers("2.4.5"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(info.MongoTargetVersion(), gc.Equals, "")
+	c.Check(info.MongoUpgradePhases(), gc.HasLen, 0)
+
+	phases := []state.MongoUpgradePhase{
+		{Kind: state.MongoUpgradeMember, ControllerId: "1"},
+		{Kind: state.MongoUpgradeMember, ControllerId: "0"},
+		{Kind: state.MongoSetFeatureCompatibility},
+	}
+	err = info.SetMongoUpgradePlan("4.0", phases)
+	c.Assert(err, jc.ErrorIsNil)
+	err = info.SetMongoUpgradePhaseDone(1)
+	c.Assert(err, jc.ErrorIsNil)
+	err = info.SetMongoUpgradePhaseDone(3)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	phases[1].Done = true
+	current, err := s.State.CurrentUpgradeInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(current.MongoTargetVersion(), gc.Equals, "4.0")
+	c.Check(current.MongoUpgradePhases(), jc.DeepEquals, phases)
+
+	// A plan being carried out is not replaced.
+	err = current.SetMongoUpgradePlan("4.4", nil)
+	c.Assert(err, gc.ErrorMatches, "cannot set mongo upgrade plan: current upgrade info has changed")
+}
+
+func (s *UpgradeSuite) TestApproval(c *gc.C) {
+	info, err := s.State.EnsureUpgradeInfo(s.serverIdA, vers("1.2.3"), vers("2.4.5"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(info.AwaitingApproval(), jc.IsFalse)
+	c.Check(info.Approved(), jc.IsFalse)
+
+	err = s.State.ApproveCurrentUpgrade()
+	c.Assert(err, gc.ErrorMatches, "cannot approve upgrade: upgrade is not awaiting approval")
+
+	err = info.SetAwaitingApproval()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(info.AwaitingApproval(), jc.IsTrue)
+
+	err = s.State.ApproveCurrentUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = info.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(info.AwaitingApproval(), jc.IsFalse)
+	c.Check(info.Approved(), jc.IsTrue)
+
+	err = info.SetAwaitingApproval()
+	c.Assert(err, gc.ErrorMatches, "cannot await upgrade approval: current upgrade info has changed")
+}
+
+func (s *UpgradeSuite) TestDeferMigrationIfUpgrading(c *gc.C) {
+	err := s.State.DeferMigrationIfUpgrading("model-a", state.MigrationIncoming)
+	c.Assert(err, jc.ErrorIsNil)
+
+	info, err := s.State.EnsureUpgradeInfo(s.serverIdA, vers("1.2.3"), vers("2.4.5"))
+	c.Assert(err, jc.ErrorIsNil)
+	for i := 0; i < 2; i++ {
+		err = s.State.DeferMigrationIfUpgrading("model-a", state.MigrationIncoming)
+		c.Assert(err, jc.Satisfies, state.IsUpgradeInProgressError)
+		c.Assert(err, gc.ErrorMatches, "controller database is being upgraded: upgrade in progress")
+	}
+	err = s.State.DeferMigrationIfUpgrading("model-a", state.MigrationOutgoing)
+	c.Assert(err, jc.Satisfies, state.IsUpgradeInProgressError)
+
+	// Each migration is recorded once, however often it is refused.
+	err = info.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	deferred := info.DeferredMigrations()
+	c.Assert(deferred, gc.HasLen, 2)
+	c.Check(deferred[0].ModelUUID, gc.Equals, "model-a")
+	c.Check(deferred[0].Direction, gc.Equals, state.MigrationIncoming)
+	c.Check(deferred[0].Attempted.IsZero(), jc.IsFalse)
+	c.Check(deferred[1].Direction, gc.Equals, state.MigrationOutgoing)
+
+	// Migrations are allowed once the database has been upgraded.
+	err = info.SetStatus(state.UpgradeDBComplete)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.DeferMigrationIfUpgrading("model-b", state.MigrationIncoming)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *UpgradeSuite) TestCreateMigrationDuringDatabaseUpgrade(c *gc.C) {
+	st := s.Factory.MakeModel(c, nil)
+	defer st.Close()
+	spec := state.MigrationSpec{
+		InitiatedBy: names.NewUserTag("admin"),
+		TargetInfo: migration.TargetInfo{
+			ControllerTag: names.NewControllerTag(utils.MustNewUUID().String()),
+			Addrs:         []string{"1.2.3.4:5555"},
+			CACert:        "cert",
+			AuthTag:       names.NewUserTag("user"),
+			Password:      "password",
+		},
+	}
+	info, err := s.State.EnsureUpgradeInfo(s.serverIdA, vers("1.2.3"), vers("2.4.5"))
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = st.CreateMigration(spec)
+	c.Assert(err, jc.Satisfies, state.IsUpgradeInProgressError)
+	active, err := st.IsMigrationActive()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(active, jc.IsFalse)
+	err = info.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	deferred := info.DeferredMigrations()
+	c.Assert(deferred, gc.HasLen, 1)
+	c.Check(deferred[0].ModelUUID, gc.Equals, st.ModelUUID())
+	c.Check(deferred[0].Direction, gc.Equals, state.MigrationOutgoing)
+
+	err = info.SetStatus(state.UpgradeDBComplete)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = st.CreateMigration(spec)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *UpgradeSuite) TestApproveCurrentUpgradeNotFound(c *gc.C) {
+	err := s.State.ApproveCurrentUpgrade()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *UpgradeSuite) TestCurrentUpgradeInfoNotFound(c *gc.C) {
+	_, err := s.State.CurrentUpgradeInfo()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
 func (s *UpgradeSuite) TestApplicationUnitSeqToSequence(c *gc.C) {
 	v123 := vers("1.2.3")
 	v124 := vers("1.2.4")
@@ -0,0 +1,110 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// UpgradeBatcher determines the size of the batches in which upgrade
+// steps rewrite documents, and how long they pause between batches, so
+// that steps rewriting large numbers of documents do not overwhelm the
+// database.
+type UpgradeBatcher interface {
+	// BatchSize returns the largest number of
+	// documents to rewrite in the next batch.
+	BatchSize() int
+
+	// BatchWritten records that a batch of the given number of
+	// documents was written in the given time, and returns how
+	// long to pause before writing the next batch.
+	BatchWritten(docs int, took time.Duration) time.Duration
+}
+
+// defaultUpgradeBatchSize is the number of documents rewritten in each
+// batch by upgrade steps run against a pool without an UpgradeBatcher.
+const defaultUpgradeBatchSize = 1000
+
+// fixedUpgradeBatcher is an UpgradeBatcher that writes
+// batches of a fixed size, without pausing between them.
+type fixedUpgradeBatcher int
+
+// BatchSize is part of the UpgradeBatcher interface.
+func (b fixedUpgradeBatcher) BatchSize() int {
+	return int(b)
+}
+
+// BatchWritten is part of the UpgradeBatcher interface.
+func (fixedUpgradeBatcher) BatchWritten(int, time.Duration) time.Duration {
+	return 0
+}
+
+// SetUpgradeBatcher sets the UpgradeBatcher used by upgrade steps run
+// against the pool. If it is nil, batches of a fixed size are used.
+func (p *StatePool) SetUpgradeBatcher(batcher UpgradeBatcher) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.upgradeBatcher = batcher
+}
+
+func (p *StatePool) getUpgradeBatcher() UpgradeBatcher {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.upgradeBatcher == nil {
+		return fixedUpgradeBatcher(defaultUpgradeBatchSize)
+	}
+	return p.upgradeBatcher
+}
+
+// upgradeBatchWriter accumulates the operations with which an upgrade
+// step rewrites documents, and runs them in transactions of the size
+// chosen by the pool's UpgradeBatcher.
+type upgradeBatchWriter struct {
+	st      *State
+	batcher UpgradeBatcher
+
+	ops   []txn.Op
+	docs  int
+	pause time.Duration
+}
+
+func newUpgradeBatchWriter(pool *StatePool) *upgradeBatchWriter {
+	return &upgradeBatchWriter{
+		st:      pool.SystemState(),
+		batcher: pool.getUpgradeBatcher(),
+	}
+}
+
+// add adds the operations that rewrite a single document to the batch,
+// writing the batch once it holds as many documents as are allowed.
+func (w *upgradeBatchWriter) add(ops ...txn.Op) error {
+	w.ops = append(w.ops, ops...)
+	w.docs++
+	if w.docs < w.batcher.BatchSize() {
+		return nil
+	}
+	return errors.Trace(w.flush())
+}
+
+// flush writes the batch, after pausing for as long as the
+// batcher requested when the previous batch was written.
+func (w *upgradeBatchWriter) flush() error {
+	if len(w.ops) == 0 {
+		return nil
+	}
+	if w.pause > 0 {
+		<-w.st.clock().After(w.pause)
+	}
+	start := w.st.clock().Now()
+	if err := w.st.runRawTransaction(w.ops); err != nil {
+		return errors.Trace(err)
+	}
+	w.pause = w.batcher.BatchWritten(w.docs, w.st.clock().Now().Sub(start))
+	w.ops = nil
+	w.docs = 0
+	return nil
+}
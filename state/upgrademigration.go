@@ -0,0 +1,141 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// MigrationDirection identifies whether a model
+// migration leaves or enters the controller.
+type MigrationDirection string
+
+const (
+	// MigrationOutgoing is the direction of a migration
+	// of a model from the controller to another.
+	MigrationOutgoing MigrationDirection = "outgoing"
+
+	// MigrationIncoming is the direction of a migration
+	// of a model from another controller to the controller.
+	MigrationIncoming MigrationDirection = "incoming"
+)
+
+// upgradeDeferredMigrationDoc records a model migration that was
+// refused because the controller database was being upgraded.
+type upgradeDeferredMigrationDoc struct {
+	ModelUUID string             `bson:"model-uuid"`
+	Direction MigrationDirection `bson:"direction"`
+	Attempted time.Time          `bson:"attempted"`
+}
+
+// UpgradeDeferredMigration describes a model migration that was refused
+// because the controller database was being upgraded. The migration is
+// expected to be attempted again once the upgrade has completed.
+type UpgradeDeferredMigration struct {
+	ModelUUID string
+	Direction MigrationDirection
+
+	// Attempted is when the migration was first refused.
+	Attempted time.Time
+}
+
+// DeferredMigrations returns the model migrations refused while
+// the controller database was upgraded, in the order they were
+// first refused. Each model is recorded once in each direction.
+func (info *UpgradeInfo) DeferredMigrations() []UpgradeDeferredMigration {
+	result := make([]UpgradeDeferredMigration, len(info.doc.DeferredMigrations))
+	for i, doc := range info.doc.DeferredMigrations {
+		result[i] = UpgradeDeferredMigration{
+			ModelUUID: doc.ModelUUID,
+			Direction: doc.Direction,
+			Attempted: doc.Attempted,
+		}
+	}
+	return result
+}
+
+// errDatabaseUpgrading is returned when a model migration is refused
+// because the controller database is being upgraded. Exporting or
+// importing a model while upgrade steps rewrite its documents could
+// leave them inconsistent.
+var errDatabaseUpgrading = errors.Annotate(errUpgradeInProgress, "controller database is being upgraded")
+
+// DeferMigrationIfUpgrading returns an error satisfying
+// IsUpgradeInProgressError if the controller database is being upgraded,
+// recording the migration of the model in the given direction as deferred
+// until the upgrade has completed. Callers should try the migration again
+// later.
+func (st *State) DeferMigrationIfUpgrading(modelUUID string, direction MigrationDirection) error {
+	doc, err := currentUpgradeInfoDoc(st)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	if doc.Status != UpgradePending {
+		return nil
+	}
+	if err := recordUpgradeDeferredMigration(st, modelUUID, direction); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(errDatabaseUpgrading)
+}
+
+// recordUpgradeDeferredMigration records the migration of the model in
+// the given direction against the database upgrade in progress, unless
+// it has already been recorded.
+func recordUpgradeDeferredMigration(st *State, modelUUID string, direction MigrationDirection) error {
+	logger.Infof("deferring %s migration of model %q until the database upgrade completes", direction, modelUUID)
+	ops := []txn.Op{{
+		C:  upgradeInfoC,
+		Id: currentUpgradeId,
+		Assert: bson.D{
+			{"status", UpgradePending},
+			{"deferredMigrations", bson.D{{"$not", bson.D{{"$elemMatch", bson.D{
+				{"model-uuid", modelUUID},
+				{"direction", direction},
+			}}}}}},
+		},
+		Update: bson.D{{"$push", bson.D{{"deferredMigrations", upgradeDeferredMigrationDoc{
+			ModelUUID: modelUUID,
+			Direction: direction,
+			Attempted: st.clock().Now().UTC(),
+		}}}}},
+	}}
+	err := st.db().RunTransaction(ops)
+	if err == txn.ErrAborted {
+		// Either the migration has already been recorded, or
+		// the database upgrade has completed in the meantime.
+		return nil
+	}
+	return errors.Annotate(err, "cannot record migration deferred by upgrade")
+}
+
+// assertNoDatabaseUpgradeOp returns an operation that asserts that the
+// controller database is not being upgraded, or errDatabaseUpgrading if
+// it is.
+func assertNoDatabaseUpgradeOp(st *State) (txn.Op, error) {
+	doc, err := currentUpgradeInfoDoc(st)
+	if errors.IsNotFound(err) {
+		return txn.Op{
+			C:      upgradeInfoC,
+			Id:     currentUpgradeId,
+			Assert: txn.DocMissing,
+		}, nil
+	} else if err != nil {
+		return txn.Op{}, errors.Trace(err)
+	}
+	if doc.Status == UpgradePending {
+		return txn.Op{}, errDatabaseUpgrading
+	}
+	return txn.Op{
+		C:      upgradeInfoC,
+		Id:     currentUpgradeId,
+		Assert: bson.D{{"status", bson.D{{"$ne", UpgradePending}}}},
+	}, nil
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/juju/collections/set"
@@ -38,8 +39,15 @@ import (
 
 var upgradesLogger = loggo.GetLogger("juju.state.upgrade")
 
+// upgradeModelParallelism is the number of models that
+// runForAllModelStates runs an upgrade step against at once.
+const upgradeModelParallelism = 4
+
 // runForAllModelStates will run runner function for every model passing a state
-// for that model.
+// for that model. Models are upgraded in parallel, and independently: if the
+// runner fails for a hosted model, the model is skipped, recorded against the
+// current upgrade for follow-up, and the rest of the models are upgraded
+// regardless. A failure for the controller model is returned.
 func runForAllModelStates(pool *StatePool, runner func(st *State) error) error {
 	st := pool.SystemState()
 	models, closer := st.db().GetCollection(modelsC)
@@ -51,20 +59,44 @@ func runForAllModelStates(pool *StatePool, runner func(st *State) error) error {
 		return errors.Annotate(err, "failed to read models")
 	}
 
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		failed = make(map[string]error)
+		sem    = make(chan struct{}, upgradeModelParallelism)
+	)
 	for _, modelDoc := range modelDocs {
 		modelUUID := modelDoc["_id"].(string)
-		model, err := pool.Get(modelUUID)
-		if err != nil {
-			return errors.Annotatef(err, "failed to open model %q", modelUUID)
-		}
-		defer func() {
-			model.Release()
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := runForModelState(pool, modelUUID, runner); err != nil {
+				mu.Lock()
+				failed[modelUUID] = err
+				mu.Unlock()
+			}
 		}()
-		if err := runner(model.State); err != nil {
-			return errors.Annotatef(err, "model UUID %q", modelUUID)
-		}
 	}
-	return nil
+	wg.Wait()
+
+	if err, ok := failed[st.ControllerModelUUID()]; ok {
+		return errors.Annotatef(err, "model UUID %q", st.ControllerModelUUID())
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return errors.Trace(recordUpgradeSkippedModels(st, failed))
+}
+
+func runForModelState(pool *StatePool, modelUUID string, runner func(st *State) error) error {
+	model, err := pool.Get(modelUUID)
+	if err != nil {
+		return errors.Annotatef(err, "failed to open model %q", modelUUID)
+	}
+	defer model.Release()
+	return errors.Trace(runner(model.State))
 }
 
 // readBsonDField returns the value of a given field in a bson.D.
@@ -2912,3 +2944,109 @@ func AddMachineIDToSubordinates(pool *StatePool) error {
 	}
 	return st.runRawTransaction(ops)
 }
+
+// RemoveMachineIDFromSubordinates reverses AddMachineIDToSubordinates,
+// clearing the machine ID of subordinate units as it was before.
+//
+// The subordinate units are rewritten in batches of the size chosen by
+// the pool's UpgradeBatcher.
+func RemoveMachineIDFromSubordinates(pool *StatePool) error {
+	st := pool.SystemState()
+	coll, closer := st.db().GetRawCollection(unitsC)
+	defer closer()
+
+	writer := newUpgradeBatchWriter(pool)
+	var doc struct {
+		DocID string `bson:"_id"`
+	}
+	iter := coll.Find(bson.D{
+		{"principal", bson.D{{"$nin", []interface{}{"", nil}}}},
+		{"machineid", bson.D{{"$nin", []interface{}{"", nil}}}},
+	}).Select(bson.D{{"_id", 1}}).Iter()
+	for iter.Next(&doc) {
+		if err := writer.add(txn.Op{
+			C:      unitsC,
+			Id:     doc.DocID,
+			Update: bson.D{{"$set", bson.D{{"machineid", ""}}}},
+		}); err != nil {
+			_ = iter.Close()
+			return errors.Trace(err)
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(writer.flush())
+}
+
+// AddApplicationToUnitStates records the application name on unit
+// state documents, so that they are found by the unit state
+// collection's application index.
+//
+// There is a unit state document for each unit, so they are rewritten in
+// batches of the size chosen by the pool's UpgradeBatcher.
+func AddApplicationToUnitStates(pool *StatePool) error {
+	st := pool.SystemState()
+	coll, closer := st.db().GetRawCollection(unitStatesC)
+	defer closer()
+
+	writer := newUpgradeBatchWriter(pool)
+	var doc struct {
+		DocID     string `bson:"_id"`
+		ModelUUID string `bson:"model-uuid"`
+	}
+	iter := coll.Find(bson.D{{"application", bson.D{{"$exists", false}}}}).Select(bson.D{{"_id", 1}, {"model-uuid", 1}}).Iter()
+	for iter.Next(&doc) {
+		localID := strings.TrimPrefix(doc.DocID, doc.ModelUUID+":")
+		unitName := unitNameFromStateDocID(localID)
+		appName, err := names.UnitApplication(unitName)
+		if err != nil {
+			logger.Warningf("unit state %q does not belong to a unit: %v", doc.DocID, err)
+			continue
+		}
+		if err := writer.add(txn.Op{
+			C:      unitStatesC,
+			Id:     doc.DocID,
+			Assert: txn.DocExists,
+			Update: bson.D{{"$set", bson.D{{"application", appName}}}},
+		}); err != nil {
+			_ = iter.Close()
+			return errors.Trace(err)
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(writer.flush())
+}
+
+// RemoveApplicationFromUnitStates reverses AddApplicationToUnitStates,
+// removing the application name from unit state documents.
+//
+// The unit state documents are rewritten in batches of the size chosen
+// by the pool's UpgradeBatcher.
+func RemoveApplicationFromUnitStates(pool *StatePool) error {
+	st := pool.SystemState()
+	coll, closer := st.db().GetRawCollection(unitStatesC)
+	defer closer()
+
+	writer := newUpgradeBatchWriter(pool)
+	var doc struct {
+		DocID string `bson:"_id"`
+	}
+	iter := coll.Find(bson.D{{"application", bson.D{{"$exists", true}}}}).Select(bson.D{{"_id", 1}}).Iter()
+	for iter.Next(&doc) {
+		if err := writer.add(txn.Op{
+			C:      unitStatesC,
+			Id:     doc.DocID,
+			Update: bson.D{{"$unset", bson.D{{"application", 1}}}},
+		}); err != nil {
+			_ = iter.Close()
+			return errors.Trace(err)
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(writer.flush())
+}
@@ -6,6 +6,7 @@ package state
 import (
 	"fmt"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/juju/clock/testclock"
@@ -14,6 +15,7 @@ import (
 	"github.com/juju/loggo"
 	jc "github.com/juju/testing/checkers"
 	"github.com/juju/utils"
+	"github.com/juju/version"
 	"github.com/kr/pretty"
 	gc "gopkg.in/check.v1"
 	"gopkg.in/juju/charm.v6"
@@ -1049,6 +1051,59 @@ func (s *upgradesSuite) makeModel(c *gc.C, name string, attr coretesting.Attrs)
 	return st
 }
 
+func (s *upgradesSuite) TestRunForAllModelStatesSkipsFailedModels(c *gc.C) {
+	m1 := s.makeModel(c, "m1", coretesting.Attrs{})
+	defer m1.Close()
+	m2 := s.makeModel(c, "m2", coretesting.Attrs{})
+	defer m2.Close()
+
+	coll, closer := s.state.db().GetRawCollection(upgradeInfoC)
+	defer closer()
+	err := coll.Insert(&upgradeInfoDoc{
+		Id:              currentUpgradeId,
+		PreviousVersion: version.MustParse("2.7.6"),
+		TargetVersion:   version.MustParse("2.8.0"),
+		Status:          UpgradeRunning,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	var (
+		mu       sync.Mutex
+		upgraded []string
+	)
+	err = runForAllModelStates(s.pool, func(st *State) error {
+		if st.ModelUUID() == m1.ModelUUID() {
+			return errors.New("corrupt")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		upgraded = append(upgraded, st.ModelUUID())
+		return nil
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(upgraded, jc.SameContents, []string{s.state.ModelUUID(), m2.ModelUUID()})
+
+	info, err := s.state.CurrentUpgradeInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.SkippedModels(), jc.DeepEquals, []UpgradeSkippedModel{{
+		ModelUUID: m1.ModelUUID(),
+		Error:     "corrupt",
+	}})
+}
+
+func (s *upgradesSuite) TestRunForAllModelStatesControllerModelFailure(c *gc.C) {
+	m1 := s.makeModel(c, "m1", coretesting.Attrs{})
+	defer m1.Close()
+
+	err := runForAllModelStates(s.pool, func(st *State) error {
+		if st.ModelUUID() == s.state.ControllerModelUUID() {
+			return errors.New("corrupt")
+		}
+		return nil
+	})
+	c.Assert(err, gc.ErrorMatches, `model UUID ".*": corrupt`)
+}
+
 func (s *upgradesSuite) TestAddStatusHistoryPruneSettings(c *gc.C) {
 	s.checkAddPruneSettings(c, "max-status-history-age", "max-status-history-size", config.DefaultStatusHistoryAge, config.DefaultStatusHistorySize, AddStatusHistoryPruneSettings)
 }
@@ -4304,6 +4359,165 @@ func (s *upgradesSuite) TestAddMachineIDToSubordinates(c *gc.C) {
 	s.assertUpgradedData(c, AddMachineIDToSubordinates, upgradedData(col, expected))
 }
 
+func (s *upgradesSuite) TestRemoveMachineIDFromSubordinates(c *gc.C) {
+	col, closer := s.state.db().GetRawCollection(unitsC)
+	defer closer()
+
+	uuid := utils.MustNewUUID().String()
+	err := col.Insert(bson.M{
+		"_id":        uuid + ":principal/1",
+		"model-uuid": uuid,
+		"machineid":  "1",
+	}, bson.M{
+		"_id":        uuid + ":telegraf/1",
+		"model-uuid": uuid,
+		"principal":  "principal/1",
+		"machineid":  "1",
+	}, bson.M{
+		"_id":        uuid + ":livepatch/1",
+		"model-uuid": uuid,
+		"principal":  "principal/1",
+		"machineid":  "",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	expected := bsonMById{
+		{
+			"_id":        uuid + ":livepatch/1",
+			"model-uuid": uuid,
+			"principal":  "principal/1",
+			"machineid":  "",
+		}, {
+			"_id":        uuid + ":principal/1",
+			"model-uuid": uuid,
+			"machineid":  "1",
+		}, {
+			"_id":        uuid + ":telegraf/1",
+			"model-uuid": uuid,
+			"principal":  "principal/1",
+			"machineid":  "",
+		},
+	}
+
+	sort.Sort(expected)
+	s.assertUpgradedData(c, RemoveMachineIDFromSubordinates, upgradedData(col, expected))
+}
+
+func (s *upgradesSuite) TestAddApplicationToUnitStates(c *gc.C) {
+	col, closer := s.state.db().GetRawCollection(unitStatesC)
+	defer closer()
+
+	uuid1 := utils.MustNewUUID().String()
+	uuid2 := utils.MustNewUUID().String()
+
+	err := col.Insert(bson.M{
+		"_id":          uuid1 + ":u#wordpress/0#charm",
+		"model-uuid":   uuid1,
+		"uniter-state": "foo",
+	}, bson.M{
+		"_id":         uuid1 + ":u#mysql/1#charm",
+		"model-uuid":  uuid1,
+		"application": "mysql",
+	}, bson.M{
+		"_id":        uuid2 + ":u#wordpress/3#charm",
+		"model-uuid": uuid2,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	expected := bsonMById{
+		{
+			"_id":         uuid1 + ":u#mysql/1#charm",
+			"model-uuid":  uuid1,
+			"application": "mysql",
+		}, {
+			"_id":          uuid1 + ":u#wordpress/0#charm",
+			"model-uuid":   uuid1,
+			"uniter-state": "foo",
+			"application":  "wordpress",
+		}, {
+			"_id":         uuid2 + ":u#wordpress/3#charm",
+			"model-uuid":  uuid2,
+			"application": "wordpress",
+		},
+	}
+
+	sort.Sort(expected)
+	s.assertUpgradedData(c, AddApplicationToUnitStates, upgradedData(col, expected))
+}
+
+func (s *upgradesSuite) TestRemoveApplicationFromUnitStates(c *gc.C) {
+	col, closer := s.state.db().GetRawCollection(unitStatesC)
+	defer closer()
+
+	uuid := utils.MustNewUUID().String()
+	err := col.Insert(bson.M{
+		"_id":          uuid + ":u#wordpress/0#charm",
+		"model-uuid":   uuid,
+		"uniter-state": "foo",
+		"application":  "wordpress",
+	}, bson.M{
+		"_id":        uuid + ":u#mysql/1#charm",
+		"model-uuid": uuid,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	expected := bsonMById{
+		{
+			"_id":        uuid + ":u#mysql/1#charm",
+			"model-uuid": uuid,
+		}, {
+			"_id":          uuid + ":u#wordpress/0#charm",
+			"model-uuid":   uuid,
+			"uniter-state": "foo",
+		},
+	}
+
+	sort.Sort(expected)
+	s.assertUpgradedData(c, RemoveApplicationFromUnitStates, upgradedData(col, expected))
+}
+
+// recordingUpgradeBatcher is an UpgradeBatcher
+// that records the sizes of the batches written.
+type recordingUpgradeBatcher struct {
+	size    int
+	batches []int
+}
+
+func (b *recordingUpgradeBatcher) BatchSize() int {
+	return b.size
+}
+
+func (b *recordingUpgradeBatcher) BatchWritten(docs int, _ time.Duration) time.Duration {
+	b.batches = append(b.batches, docs)
+	return 0
+}
+
+func (s *upgradesSuite) TestAddApplicationToUnitStatesBatched(c *gc.C) {
+	col, closer := s.state.db().GetRawCollection(unitStatesC)
+	defer closer()
+
+	uuid := utils.MustNewUUID().String()
+	for i := 0; i < 5; i++ {
+		err := col.Insert(bson.M{
+			"_id":        fmt.Sprintf("%s:u#wordpress/%d#charm", uuid, i),
+			"model-uuid": uuid,
+		})
+		c.Assert(err, jc.ErrorIsNil)
+	}
+
+	batcher := &recordingUpgradeBatcher{size: 2}
+	s.pool.SetUpgradeBatcher(batcher)
+	defer s.pool.SetUpgradeBatcher(nil)
+
+	err := AddApplicationToUnitStates(s.pool)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(batcher.batches, jc.DeepEquals, []int{2, 2, 1})
+
+	n, err := col.Find(bson.D{{"application", "wordpress"}}).Count()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(n, gc.Equals, 5)
+}
+
 type docById []bson.M
 
 func (d docById) Len() int           { return len(d) }
@@ -11,6 +11,7 @@ package state
 import (
 	"crypto/rand"
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -23,6 +24,7 @@ import (
 	"gopkg.in/mgo.v2/txn"
 
 	"github.com/juju/juju/core/permission"
+	"github.com/juju/juju/mongo"
 )
 
 const userGlobalKeyPrefix = "us"
@@ -52,7 +54,7 @@ func (st *State) checkUserExists(name string) (bool, error) {
 
 // AddUser adds a user to the database.
 func (st *State) AddUser(name, displayName, password, creator string) (*User, error) {
-	return st.addUser(name, displayName, password, creator, nil)
+	return st.addUser(name, displayName, password, creator, nil, false)
 }
 
 // AddUserWithSecretKey adds the user with the specified name, and assigns it
@@ -67,10 +69,10 @@ func (st *State) AddUserWithSecretKey(name, displayName, creator string) (*User,
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	return st.addUser(name, displayName, "", creator, secretKey)
+	return st.addUser(name, displayName, "", creator, secretKey, false)
 }
 
-func (st *State) addUser(name, displayName, password, creator string, secretKey []byte) (*User, error) {
+func (st *State) addUser(name, displayName, password, creator string, secretKey []byte, guest bool) (*User, error) {
 	if !names.IsValidUserName(name) {
 		return nil, errors.Errorf("invalid user name %q", name)
 	}
@@ -86,6 +88,7 @@ func (st *State) addUser(name, displayName, password, creator string, secretKey
 			SecretKey:   secretKey,
 			CreatedBy:   creator,
 			DateCreated: dateCreated,
+			Guest:       guest,
 		},
 	}
 
@@ -96,6 +99,7 @@ func (st *State) addUser(name, displayName, password, creator string, secretKey
 		}
 		user.doc.PasswordHash = utils.UserPasswordHash(password, salt)
 		user.doc.PasswordSalt = salt
+		user.doc.PasswordSetTime = dateCreated
 	}
 
 	ops := []txn.Op{{
@@ -166,6 +170,8 @@ func createInitialUserOps(controllerUUID string, user names.UserTag, password, s
 		PasswordSalt: salt,
 		CreatedBy:    user.Name(),
 		DateCreated:  dateCreated,
+
+		PasswordSetTime: dateCreated,
 	}
 	ops := []txn.Op{{
 		C:      usersC,
@@ -226,11 +232,124 @@ func (st *State) User(tag names.UserTag) (*User, error) {
 // includeDeactivated is true it also returns inactive users. At this point it
 // never returns deleted users.
 func (st *State) AllUsers(includeDeactivated bool) ([]*User, error) {
-	var result []*User
+	users, closer := st.db().GetCollection(usersC)
+	defer closer()
+
+	result, err := st.readUsers(users.Find(usersQuery(includeDeactivated)))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// Always return a predictable order, sort by Name.
+	sort.Sort(userList(result))
+	return result, nil
+}
+
+// UserSortField identifies the attribute used to order users
+// returned by UsersPage.
+type UserSortField string
+
+const (
+	// UserSortName orders users by name.
+	UserSortName UserSortField = "name"
+
+	// UserSortDateCreated orders users by the date that they were created.
+	// Users created at the same time are ordered by name.
+	UserSortDateCreated UserSortField = "date-created"
+)
+
+// UsersPageArgs describes a page of users to be returned by UsersPage.
+type UsersPageArgs struct {
+	// NamePrefix, if not empty, restricts the page to users whose
+	// names start with it. Matching is case-insensitive.
+	NamePrefix string
+
+	// IncludeDeactivated indicates whether deactivated
+	// users should be included in the page.
+	IncludeDeactivated bool
+
+	// Labels, if not empty, restricts the page to users
+	// that have all of the labels.
+	Labels map[string]string
+
+	// SortBy is the attribute used to order the users.
+	// If empty, users are ordered by name.
+	SortBy UserSortField
+
+	// Descending reverses the sort order.
+	Descending bool
+
+	// Offset is the number of matching users to skip
+	// before the page begins.
+	Offset int
+
+	// Limit is the maximum number of users to return.
+	// Zero means no limit.
+	Limit int
+}
+
+// Validate returns an error if the page arguments are not valid.
+func (args UsersPageArgs) Validate() error {
+	switch args.SortBy {
+	case "", UserSortName, UserSortDateCreated:
+	default:
+		return errors.NotValidf("sort field %q", args.SortBy)
+	}
+	if args.Offset < 0 {
+		return errors.NotValidf("negative offset %d", args.Offset)
+	}
+	if args.Limit < 0 {
+		return errors.NotValidf("negative limit %d", args.Limit)
+	}
+	for key, value := range args.Labels {
+		if err := ValidateUserLabel(key, value); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// UsersPage returns a page of users matching the input arguments.
+// The filtering, ordering and paging are all done by the database, so that
+// controllers with a large number of users do not need to read all of them.
+// As with AllUsers, deleted users are never returned.
+func (st *State) UsersPage(args UsersPageArgs) ([]*User, error) {
+	if err := args.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
 
 	users, closer := st.db().GetCollection(usersC)
 	defer closer()
 
+	query := usersQuery(args.IncludeDeactivated)
+	if args.NamePrefix != "" {
+		// User document IDs are the lower-cased user name.
+		prefix := regexp.QuoteMeta(strings.ToLower(args.NamePrefix))
+		query = append(query, bson.DocElem{"_id", bson.D{{"$regex", "^" + prefix}}})
+	}
+	query = append(query, labelsQuery(args.Labels)...)
+
+	var sortFields []string
+	if args.SortBy == UserSortDateCreated {
+		sortFields = append(sortFields, "datecreated")
+	}
+	sortFields = append(sortFields, "_id")
+	if args.Descending {
+		for i, field := range sortFields {
+			sortFields[i] = "-" + field
+		}
+	}
+
+	q := users.Find(query).Sort(sortFields...).Skip(args.Offset)
+	if args.Limit > 0 {
+		q = q.Limit(args.Limit)
+	}
+	result, err := st.readUsers(q)
+	return result, errors.Trace(err)
+}
+
+// usersQuery returns a query matching all users that have not been deleted.
+// If includeDeactivated is false, deactivated users are also excluded.
+func usersQuery(includeDeactivated bool) bson.D {
 	var query bson.D
 	// TODO(redir): Provide option to retrieve deleted users in future PR.
 	// e.g. if !includeDelted.
@@ -251,7 +370,13 @@ func (st *State) AllUsers(includeDeactivated bool) ([]*User, error) {
 			"deactivated", bson.D{{"$ne", true}},
 		})
 	}
-	iter := users.Find(query).Iter()
+	return query
+}
+
+// readUsers returns the users matched by the input query.
+func (st *State) readUsers(q mongo.Query) ([]*User, error) {
+	var result []*User
+	iter := q.Iter()
 	defer iter.Close()
 
 	var doc userDoc
@@ -261,8 +386,6 @@ func (st *State) AllUsers(includeDeactivated bool) ([]*User, error) {
 	if err := iter.Close(); err != nil {
 		return nil, errors.Trace(err)
 	}
-	// Always return a predictable order, sort by Name.
-	sort.Sort(userList(result))
 	return result, nil
 }
 
@@ -284,6 +407,26 @@ type userDoc struct {
 	PasswordSalt string    `bson:"passwordsalt"`
 	CreatedBy    string    `bson:"createdby"`
 	DateCreated  time.Time `bson:"datecreated"`
+
+	Defaults *userDefaultsDoc `bson:"defaults,omitempty"`
+
+	// Labels holds arbitrary key-value pairs attached to the user,
+	// so that users can be organised for access reviews.
+	Labels map[string]string `bson:"labels,omitempty"`
+
+	// PasswordSetTime records when the password was last set. It is
+	// not recorded for passwords set before expiry was supported.
+	PasswordSetTime time.Time `bson:"password-set-time,omitempty"`
+
+	// Guest is true for the built-in guest user, as which clients
+	// log in without credentials while guest access is enabled.
+	Guest bool `bson:"guest,omitempty"`
+
+	// The second authentication factor enrolled by the user, if any.
+	TOTPSecret    []byte   `bson:"totp-secret,omitempty"`
+	TOTPEnrolled  bool     `bson:"totp-enrolled,omitempty"`
+	TOTPLastStep  int64    `bson:"totp-last-step,omitempty"`
+	RecoveryCodes []string `bson:"recovery-codes,omitempty"`
 }
 
 type userLastLoginDoc struct {
@@ -421,9 +564,11 @@ func (u *User) SetPasswordHash(pwHash string, pwSalt string) error {
 		// explicit check before login.
 		return errors.Annotate(err, "cannot set password hash")
 	}
+	setTime := u.st.nowToTheSecond()
 	update := bson.D{{"$set", bson.D{
 		{"passwordhash", pwHash},
 		{"passwordsalt", pwSalt},
+		{"password-set-time", setTime},
 	}}}
 	if u.doc.SecretKey != nil {
 		update = append(update,
@@ -442,6 +587,7 @@ func (u *User) SetPasswordHash(pwHash string, pwSalt string) error {
 	}
 	u.doc.PasswordHash = pwHash
 	u.doc.PasswordSalt = pwSalt
+	u.doc.PasswordSetTime = setTime
 	u.doc.SecretKey = nil
 	return nil
 }
@@ -174,6 +174,63 @@ func (s *UserSuite) TestAllUsersSkipsDeletedUsers(c *gc.C) {
 
 }
 
+func (s *UserSuite) usersPageNames(c *gc.C, args state.UsersPageArgs) []string {
+	users, err := s.State.UsersPage(args)
+	c.Assert(err, jc.ErrorIsNil)
+	var got []string
+	for _, u := range users {
+		got = append(got, u.Name())
+	}
+	return got
+}
+
+func (s *UserSuite) TestUsersPage(c *gc.C) {
+	_ = s.Factory.MakeUser(c, &factory.UserParams{Name: "bob"})
+	_ = s.Factory.MakeUser(c, &factory.UserParams{Name: "Bobby"})
+	_ = s.Factory.MakeUser(c, &factory.UserParams{Name: "bobcat", Disabled: true})
+	_ = s.Factory.MakeUser(c, &factory.UserParams{Name: "mary"})
+	deleted := s.Factory.MakeUser(c, &factory.UserParams{Name: "bobsleigh"})
+	err := s.State.RemoveUser(deleted.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+
+	got := s.usersPageNames(c, state.UsersPageArgs{})
+	c.Check(got, jc.DeepEquals, []string{"bob", "Bobby", "mary", "test-admin"})
+
+	got = s.usersPageNames(c, state.UsersPageArgs{NamePrefix: "BOB", IncludeDeactivated: true})
+	c.Check(got, jc.DeepEquals, []string{"bob", "Bobby", "bobcat"})
+
+	got = s.usersPageNames(c, state.UsersPageArgs{NamePrefix: "bob", IncludeDeactivated: true, Descending: true})
+	c.Check(got, jc.DeepEquals, []string{"bobcat", "Bobby", "bob"})
+
+	got = s.usersPageNames(c, state.UsersPageArgs{IncludeDeactivated: true, Offset: 1, Limit: 2})
+	c.Check(got, jc.DeepEquals, []string{"Bobby", "bobcat"})
+
+	got = s.usersPageNames(c, state.UsersPageArgs{Offset: 10})
+	c.Check(got, gc.HasLen, 0)
+}
+
+func (s *UserSuite) TestUsersPageSortDateCreated(c *gc.C) {
+	s.Clock.Advance(time.Minute)
+	_ = s.Factory.MakeUser(c, &factory.UserParams{Name: "zoe"})
+	s.Clock.Advance(time.Minute)
+	_ = s.Factory.MakeUser(c, &factory.UserParams{Name: "adam"})
+
+	got := s.usersPageNames(c, state.UsersPageArgs{SortBy: state.UserSortDateCreated})
+	c.Check(got, jc.DeepEquals, []string{"test-admin", "zoe", "adam"})
+
+	got = s.usersPageNames(c, state.UsersPageArgs{SortBy: state.UserSortDateCreated, Descending: true, Limit: 2})
+	c.Check(got, jc.DeepEquals, []string{"adam", "zoe"})
+}
+
+func (s *UserSuite) TestUsersPageInvalidArgs(c *gc.C) {
+	_, err := s.State.UsersPage(state.UsersPageArgs{SortBy: "shoe-size"})
+	c.Check(err, gc.ErrorMatches, `sort field "shoe-size" not valid`)
+	_, err = s.State.UsersPage(state.UsersPageArgs{Offset: -1})
+	c.Check(err, gc.ErrorMatches, `negative offset -1 not valid`)
+	_, err = s.State.UsersPage(state.UsersPageArgs{Limit: -1})
+	c.Check(err, gc.ErrorMatches, `negative limit -1 not valid`)
+}
+
 func (s *UserSuite) TestRemoveUser(c *gc.C) {
 	user := s.Factory.MakeUser(c, &factory.UserParams{Password: "so sekrit"})
 
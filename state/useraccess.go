@@ -48,7 +48,7 @@ func (m *Model) AddUser(spec UserAccessSpec) (permission.UserAccess, error) {
 		uuid:      m.UUID(),
 		globalKey: modelGlobalKey,
 	}
-	return m.st.addUserAccess(spec, target)
+	return m.st.addUserAccess(spec, target, nil)
 }
 
 // AddControllerUser adds a new user for the current controller to the database.
@@ -56,10 +56,19 @@ func (st *State) AddControllerUser(spec UserAccessSpec) (permission.UserAccess,
 	if err := permission.ValidateControllerAccess(spec.Access); err != nil {
 		return permission.UserAccess{}, errors.Annotate(err, "adding controller user")
 	}
-	return st.addUserAccess(spec, userAccessTarget{globalKey: controllerGlobalKey})
+	return st.addUserAccess(spec, userAccessTarget{globalKey: controllerGlobalKey}, nil)
 }
 
-func (st *State) addUserAccess(spec UserAccessSpec, target userAccessTarget) (permission.UserAccess, error) {
+// AddControllerUserWithEvent adds a new user for the current controller
+// to the database, queueing the permission event in the same transaction.
+func (st *State) AddControllerUserWithEvent(spec UserAccessSpec, ev PermissionEvent) (permission.UserAccess, error) {
+	if err := permission.ValidateControllerAccess(spec.Access); err != nil {
+		return permission.UserAccess{}, errors.Annotate(err, "adding controller user")
+	}
+	return st.addUserAccess(spec, userAccessTarget{globalKey: controllerGlobalKey}, &ev)
+}
+
+func (st *State) addUserAccess(spec UserAccessSpec, target userAccessTarget, ev *PermissionEvent) (permission.UserAccess, error) {
 	// Ensure local user exists in state before adding them as an model user.
 	if spec.User.IsLocal() {
 		localUser, err := st.User(spec.User)
@@ -104,6 +113,11 @@ func (st *State) addUserAccess(spec UserAccessSpec, target userAccessTarget) (pe
 	default:
 		return permission.UserAccess{}, errors.NotSupportedf("user access global key %q", target.globalKey)
 	}
+	eventOps, err := st.optionalPermissionEventOps(ev)
+	if err != nil {
+		return permission.UserAccess{}, errors.Trace(err)
+	}
+	ops = append(ops, eventOps...)
 	err = st.db().RunTransactionFor(target.uuid, ops)
 	if err == txn.ErrAborted {
 		err = errors.AlreadyExistsf("user access %q", spec.User.Id())
@@ -232,7 +246,7 @@ func (st *State) SetUserAccess(subject names.UserTag, target names.Tag, access p
 	case names.ModelTagKind:
 		err = st.setModelAccess(access, userGlobalKey(userAccessID(subject)), target.Id())
 	case names.ControllerTagKind:
-		err = st.setControllerAccess(access, userGlobalKey(userAccessID(subject)))
+		err = st.setControllerAccess(access, userGlobalKey(userAccessID(subject)), nil)
 	default:
 		return permission.UserAccess{}, errors.NotValidf("%q as a target", target.Kind())
 	}
@@ -248,7 +262,22 @@ func (st *State) RemoveUserAccess(subject names.UserTag, target names.Tag) error
 	case names.ModelTagKind:
 		return errors.Trace(st.removeModelUser(subject))
 	case names.ControllerTagKind:
-		return errors.Trace(st.removeControllerUser(subject))
+		return errors.Trace(st.removeControllerUser(subject, nil))
 	}
 	return errors.NotValidf("%q as a target", target.Kind())
 }
+
+// SetControllerAccessWithEvent sets <access> level on the controller to
+// <subject>, queueing the permission event in the same transaction.
+func (st *State) SetControllerAccessWithEvent(subject names.UserTag, access permission.Access, ev PermissionEvent) (permission.UserAccess, error) {
+	if err := st.setControllerAccess(access, userGlobalKey(userAccessID(subject)), &ev); err != nil {
+		return permission.UserAccess{}, errors.Trace(err)
+	}
+	return st.UserAccess(subject, st.controllerTag)
+}
+
+// RemoveControllerUserWithEvent removes access for subject to the
+// controller, queueing the permission event in the same transaction.
+func (st *State) RemoveControllerUserWithEvent(subject names.UserTag, ev PermissionEvent) error {
+	return errors.Trace(st.removeControllerUser(subject, &ev))
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils"
+	"gopkg.in/juju/names.v3"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/core/permission"
+)
+
+// userDefaultsDoc holds the preferences a user has stored
+// on the controller, so they are available to any client.
+type userDefaultsDoc struct {
+	DefaultModel   string            `bson:"default-model,omitempty"`
+	Timezone       string            `bson:"timezone,omitempty"`
+	DisplayOptions map[string]string `bson:"display-options,omitempty"`
+}
+
+// UserDefaults holds the preferences a user has stored on the
+// controller. They are returned to clients when the user logs in.
+type UserDefaults struct {
+	// DefaultModel is the UUID of the model the user prefers
+	// to work with, if any.
+	DefaultModel string
+
+	// Timezone is the IANA name of the timezone the user prefers
+	// times to be displayed in, if any.
+	Timezone string
+
+	// DisplayOptions holds free-form presentation settings,
+	// interpreted by the CLI and dashboard.
+	DisplayOptions map[string]string
+}
+
+// Validate returns an error if the defaults are not valid.
+func (d UserDefaults) Validate() error {
+	if d.DefaultModel != "" && !utils.IsValidUUIDString(d.DefaultModel) {
+		return errors.NotValidf("default model %q", d.DefaultModel)
+	}
+	if d.Timezone != "" {
+		if _, err := time.LoadLocation(d.Timezone); err != nil {
+			return errors.NotValidf("timezone %q", d.Timezone)
+		}
+	}
+	for key := range d.DisplayOptions {
+		if key == "" || strings.ContainsAny(key, ".$") {
+			return errors.NotValidf("display option %q", key)
+		}
+	}
+	return nil
+}
+
+// Defaults returns the preferences stored for the user.
+func (u *User) Defaults() UserDefaults {
+	doc := u.doc.Defaults
+	if doc == nil {
+		return UserDefaults{}
+	}
+	var options map[string]string
+	if len(doc.DisplayOptions) > 0 {
+		options = make(map[string]string, len(doc.DisplayOptions))
+		for k, v := range doc.DisplayOptions {
+			options[k] = v
+		}
+	}
+	return UserDefaults{
+		DefaultModel:   doc.DefaultModel,
+		Timezone:       doc.Timezone,
+		DisplayOptions: options,
+	}
+}
+
+// SetDefaults replaces the preferences stored for the user.
+// If a default model is specified, the user must have access to it.
+func (u *User) SetDefaults(defaults UserDefaults) error {
+	if err := u.ensureNotDeleted(); err != nil {
+		return errors.Annotate(err, "cannot set defaults")
+	}
+	if err := defaults.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+	var ops []txn.Op
+	if defaults.DefaultModel != "" {
+		if ok, err := u.canAccessModel(defaults.DefaultModel); err != nil {
+			return errors.Trace(err)
+		} else if !ok {
+			return errors.NotValidf("default model %q for user %q without access to it", defaults.DefaultModel, u.Name())
+		}
+		ops = append(ops, txn.Op{
+			C:      modelsC,
+			Id:     defaults.DefaultModel,
+			Assert: txn.DocExists,
+		})
+	}
+
+	var doc *userDefaultsDoc
+	update := bson.D{{"$unset", bson.D{{"defaults", nil}}}}
+	if defaults.DefaultModel != "" || defaults.Timezone != "" || len(defaults.DisplayOptions) > 0 {
+		doc = &userDefaultsDoc{
+			DefaultModel:   defaults.DefaultModel,
+			Timezone:       defaults.Timezone,
+			DisplayOptions: defaults.DisplayOptions,
+		}
+		update = bson.D{{"$set", bson.D{{"defaults", doc}}}}
+	}
+	ops = append(ops, txn.Op{
+		C:      usersC,
+		Id:     strings.ToLower(u.Name()),
+		Assert: txn.DocExists,
+		Update: update,
+	})
+	if err := u.st.db().RunTransaction(ops); err != nil {
+		return errors.Annotatef(err, "cannot set defaults of user %q", u.Name())
+	}
+	u.doc.Defaults = doc
+	return nil
+}
+
+// canAccessModel returns whether the user has access to the model,
+// either as a model user or as a controller superuser.
+func (u *User) canAccessModel(modelUUID string) (bool, error) {
+	if exists, err := u.st.ModelExists(modelUUID); err != nil || !exists {
+		return false, errors.Trace(err)
+	}
+	_, err := u.st.UserAccess(u.UserTag(), names.NewModelTag(modelUUID))
+	if err == nil {
+		return true, nil
+	} else if !errors.IsNotFound(err) {
+		return false, errors.Trace(err)
+	}
+	access, err := u.st.UserAccess(u.UserTag(), u.st.controllerTag)
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, errors.Trace(err)
+	}
+	return access.Access == permission.SuperuserAccess, nil
+}
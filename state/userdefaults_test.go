@@ -0,0 +1,83 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing/factory"
+)
+
+type UserDefaultsSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&UserDefaultsSuite{})
+
+func (s *UserDefaultsSuite) TestDefaultsUnset(c *gc.C) {
+	user := s.Factory.MakeUser(c, nil)
+	c.Assert(user.Defaults(), jc.DeepEquals, state.UserDefaults{})
+}
+
+func (s *UserDefaultsSuite) TestSetDefaults(c *gc.C) {
+	user := s.Factory.MakeUser(c, nil)
+	defaults := state.UserDefaults{
+		DefaultModel:   s.Model.UUID(),
+		Timezone:       "Europe/London",
+		DisplayOptions: map[string]string{"format": "tabular", "color": "true"},
+	}
+	err := user.SetDefaults(defaults)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.Defaults(), jc.DeepEquals, defaults)
+
+	user, err = s.State.User(user.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.Defaults(), jc.DeepEquals, defaults)
+
+	err = user.SetDefaults(state.UserDefaults{})
+	c.Assert(err, jc.ErrorIsNil)
+	err = user.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.Defaults(), jc.DeepEquals, state.UserDefaults{})
+}
+
+func (s *UserDefaultsSuite) TestSetDefaultsSuperuserAnyModel(c *gc.C) {
+	st := s.Factory.MakeModel(c, nil)
+	defer st.Close()
+
+	user, err := s.State.User(s.Owner)
+	c.Assert(err, jc.ErrorIsNil)
+	err = user.SetDefaults(state.UserDefaults{DefaultModel: st.ModelUUID()})
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *UserDefaultsSuite) TestSetDefaultsModelWithoutAccess(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{NoModelUser: true})
+	err := user.SetDefaults(state.UserDefaults{DefaultModel: s.Model.UUID()})
+	c.Assert(err, gc.ErrorMatches, `default model ".*" for user ".*" without access to it not valid`)
+}
+
+func (s *UserDefaultsSuite) TestSetDefaultsInvalid(c *gc.C) {
+	user := s.Factory.MakeUser(c, nil)
+	for i, test := range []struct {
+		defaults state.UserDefaults
+		err      string
+	}{{
+		defaults: state.UserDefaults{DefaultModel: "not-a-uuid"},
+		err:      `default model "not-a-uuid" not valid`,
+	}, {
+		defaults: state.UserDefaults{Timezone: "Mars/Olympus_Mons"},
+		err:      `timezone "Mars/Olympus_Mons" not valid`,
+	}, {
+		defaults: state.UserDefaults{DisplayOptions: map[string]string{"a.b": "c"}},
+		err:      `display option "a.b" not valid`,
+	}} {
+		c.Logf("test %d", i)
+		err := user.SetDefaults(test.defaults)
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+	c.Assert(user.Defaults(), jc.DeepEquals, state.UserDefaults{})
+}
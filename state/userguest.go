@@ -0,0 +1,37 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+)
+
+// GuestUserName is the name of the built-in local user as which
+// clients log in, without credentials, while the controller config
+// enables guest access.
+const GuestUserName = "juju-guest"
+
+// EnsureGuestUser returns the built-in guest user, adding it on behalf
+// of creator if it does not exist yet. The guest user has neither a
+// password nor a secret key. An error satisfying errors.IsAlreadyExists
+// is returned if a user that is not the guest user has its name.
+func (st *State) EnsureGuestUser(creator string) (*User, error) {
+	user, err := st.User(names.NewUserTag(GuestUserName))
+	if errors.IsNotFound(err) {
+		user, err = st.addUser(GuestUserName, "Guest", "", creator, nil, true)
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if !user.IsGuest() {
+		return nil, errors.AlreadyExistsf("user %q that is not the guest user", GuestUserName)
+	}
+	return user, nil
+}
+
+// IsGuest reports whether the user is the built-in guest user.
+func (u *User) IsGuest() bool {
+	return u.doc.Guest
+}
@@ -0,0 +1,44 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing/factory"
+)
+
+type UserGuestSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&UserGuestSuite{})
+
+func (s *UserGuestSuite) TestEnsureGuestUser(c *gc.C) {
+	user, err := s.State.EnsureGuestUser(s.Owner.Name())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(user.Name(), gc.Equals, state.GuestUserName)
+	c.Check(user.IsGuest(), jc.IsTrue)
+	c.Check(user.SecretKey(), gc.IsNil)
+	c.Check(user.PasswordValid(""), jc.IsFalse)
+
+	// The guest user is only added once.
+	again, err := s.State.EnsureGuestUser(s.Owner.Name())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(again.DateCreated(), gc.Equals, user.DateCreated())
+}
+
+func (s *UserGuestSuite) TestEnsureGuestUserNameTaken(c *gc.C) {
+	s.Factory.MakeUser(c, &factory.UserParams{Name: state.GuestUserName})
+	_, err := s.State.EnsureGuestUser(s.Owner.Name())
+	c.Assert(err, jc.Satisfies, errors.IsAlreadyExists)
+}
+
+func (s *UserGuestSuite) TestIsGuest(c *gc.C) {
+	user := s.Factory.MakeUser(c, nil)
+	c.Assert(user.IsGuest(), jc.IsFalse)
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+const (
+	// maxUserLabelKeyLength is the longest a user label key may be.
+	maxUserLabelKeyLength = 63
+
+	// maxUserLabelValueLength is the longest a user label value may be.
+	maxUserLabelValueLength = 255
+)
+
+// validUserLabelKey matches the keys that may be used for user labels.
+// Keys are used in the names of document fields, so may not contain
+// "." or "$".
+var validUserLabelKey = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// ValidateUserLabel returns an error if the key and value
+// cannot be used as a user label.
+func ValidateUserLabel(key, value string) error {
+	if len(key) > maxUserLabelKeyLength || !validUserLabelKey.MatchString(key) {
+		return errors.NotValidf("label key %q", key)
+	}
+	if len(value) > maxUserLabelValueLength {
+		return errors.NotValidf("value of label %q longer than %d characters", key, maxUserLabelValueLength)
+	}
+	return nil
+}
+
+// Labels returns the labels attached to the user, such as
+// team=platform or contractor=true.
+func (u *User) Labels() map[string]string {
+	if len(u.doc.Labels) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(u.doc.Labels))
+	for key, value := range u.doc.Labels {
+		labels[key] = value
+	}
+	return labels
+}
+
+// UpdateLabels attaches the labels in set to the user, replacing the
+// values of any with the same keys, and removes the labels with the
+// keys in unset. Labels not mentioned are left alone.
+func (u *User) UpdateLabels(set map[string]string, unset []string) error {
+	if err := u.ensureNotDeleted(); err != nil {
+		return errors.Annotate(err, "cannot update labels")
+	}
+	var setFields, unsetFields bson.D
+	for key, value := range set {
+		if err := ValidateUserLabel(key, value); err != nil {
+			return errors.Trace(err)
+		}
+		setFields = append(setFields, bson.DocElem{Name: "labels." + key, Value: value})
+	}
+	for _, key := range unset {
+		if err := ValidateUserLabel(key, ""); err != nil {
+			return errors.Trace(err)
+		}
+		if _, ok := set[key]; ok {
+			return errors.NotValidf("label %q both set and unset", key)
+		}
+		unsetFields = append(unsetFields, bson.DocElem{Name: "labels." + key, Value: nil})
+	}
+	var update bson.D
+	if len(setFields) > 0 {
+		update = append(update, bson.DocElem{Name: "$set", Value: setFields})
+	}
+	if len(unsetFields) > 0 {
+		update = append(update, bson.DocElem{Name: "$unset", Value: unsetFields})
+	}
+	if len(update) == 0 {
+		return nil
+	}
+	ops := []txn.Op{{
+		C:      usersC,
+		Id:     strings.ToLower(u.Name()),
+		Assert: txn.DocExists,
+		Update: update,
+	}}
+	if err := u.st.db().RunTransaction(ops); err != nil {
+		return errors.Annotatef(err, "cannot update labels of user %q", u.Name())
+	}
+	return errors.Trace(u.Refresh())
+}
+
+// labelsQuery returns the query elements matching
+// documents that have all of the input labels.
+func labelsQuery(labels map[string]string) bson.D {
+	var query bson.D
+	for key, value := range labels {
+		query = append(query, bson.DocElem{Name: "labels." + key, Value: value})
+	}
+	return query
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"strings"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing/factory"
+)
+
+type UserLabelsSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&UserLabelsSuite{})
+
+func (s *UserLabelsSuite) TestLabelsUnset(c *gc.C) {
+	user := s.Factory.MakeUser(c, nil)
+	c.Assert(user.Labels(), gc.IsNil)
+}
+
+func (s *UserLabelsSuite) TestUpdateLabels(c *gc.C) {
+	user := s.Factory.MakeUser(c, nil)
+	err := user.UpdateLabels(map[string]string{"team": "platform", "contractor": "true"}, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.Labels(), jc.DeepEquals, map[string]string{"team": "platform", "contractor": "true"})
+
+	err = user.UpdateLabels(map[string]string{"team": "storage"}, []string{"contractor", "missing"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.Labels(), jc.DeepEquals, map[string]string{"team": "storage"})
+
+	user, err = s.State.User(user.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.Labels(), jc.DeepEquals, map[string]string{"team": "storage"})
+}
+
+func (s *UserLabelsSuite) TestUpdateLabelsInvalid(c *gc.C) {
+	user := s.Factory.MakeUser(c, nil)
+	err := user.UpdateLabels(map[string]string{"team.name": "platform"}, nil)
+	c.Check(err, gc.ErrorMatches, `label key "team.name" not valid`)
+	err = user.UpdateLabels(map[string]string{"team": strings.Repeat("x", 256)}, nil)
+	c.Check(err, gc.ErrorMatches, `value of label "team" longer than 255 characters not valid`)
+	err = user.UpdateLabels(nil, []string{"$team"})
+	c.Check(err, gc.ErrorMatches, `label key "\$team" not valid`)
+	err = user.UpdateLabels(map[string]string{"team": "platform"}, []string{"team"})
+	c.Check(err, gc.ErrorMatches, `label "team" both set and unset not valid`)
+	c.Check(user.Labels(), gc.IsNil)
+}
+
+func (s *UserLabelsSuite) TestUpdateLabelsDeletedUser(c *gc.C) {
+	user := s.Factory.MakeUser(c, nil)
+	err := s.State.RemoveUser(user.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	err = user.UpdateLabels(map[string]string{"team": "platform"}, nil)
+	c.Assert(err, gc.ErrorMatches, `cannot update labels: user ".*" is permanently deleted`)
+}
+
+func (s *UserLabelsSuite) TestUsersPageLabels(c *gc.C) {
+	for name, labels := range map[string]map[string]string{
+		"alice": {"team": "platform", "contractor": "true"},
+		"bob":   {"team": "platform"},
+		"carol": {"team": "storage", "contractor": "true"},
+	} {
+		user := s.Factory.MakeUser(c, &factory.UserParams{Name: name})
+		err := user.UpdateLabels(labels, nil)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+
+	for _, test := range []struct {
+		labels   map[string]string
+		expected []string
+	}{{
+		labels:   map[string]string{"team": "platform"},
+		expected: []string{"alice", "bob"},
+	}, {
+		labels:   map[string]string{"contractor": "true"},
+		expected: []string{"alice", "carol"},
+	}, {
+		labels:   map[string]string{"team": "platform", "contractor": "true"},
+		expected: []string{"alice"},
+	}, {
+		labels: map[string]string{"team": "security"},
+	}} {
+		users, err := s.State.UsersPage(state.UsersPageArgs{Labels: test.labels})
+		c.Assert(err, jc.ErrorIsNil)
+		var got []string
+		for _, user := range users {
+			got = append(got, user.Name())
+		}
+		c.Check(got, jc.DeepEquals, test.expected, gc.Commentf("labels %v", test.labels))
+	}
+
+	_, err := s.State.UsersPage(state.UsersPageArgs{Labels: map[string]string{"a.b": "c"}})
+	c.Assert(err, gc.ErrorMatches, `label key "a.b" not valid`)
+}
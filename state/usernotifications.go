@@ -0,0 +1,102 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// maxUserNotifications is the maximum number of
+// notifications returned by UserNotifications.
+const maxUserNotifications = 100
+
+// userNotificationDoc records the delivery of a notification
+// about an event on a user's account.
+type userNotificationDoc struct {
+	DocID     bson.ObjectId `bson:"_id"`
+	UserName  string        `bson:"user"`
+	Event     string        `bson:"event"`
+	Channel   string        `bson:"channel"`
+	Time      time.Time     `bson:"time"`
+	Delivered bool          `bson:"delivered"`
+	Error     string        `bson:"error,omitempty"`
+}
+
+// UserNotification describes the delivery of a notification
+// about an event on a user's account.
+type UserNotification struct {
+	// User is the user whose account the event was on.
+	User names.UserTag
+
+	// Event identifies the kind of account event.
+	Event string
+
+	// Channel identifies how the notification was delivered.
+	Channel string
+
+	// Time is when delivery of the notification was attempted.
+	// It is set by RecordUserNotification if it is zero.
+	Time time.Time
+
+	// Delivered is true if the notification was delivered.
+	Delivered bool
+
+	// Error holds the reason the notification
+	// was not delivered, if it was not.
+	Error string
+}
+
+// RecordUserNotification records the delivery status
+// of a notification of an event on a user's account.
+func (st *State) RecordUserNotification(n UserNotification) error {
+	if n.Event == "" || n.Channel == "" {
+		return errors.NotValidf("user notification without event or channel")
+	}
+	if n.Time.IsZero() {
+		n.Time = st.nowToTheSecond()
+	}
+	notifications, closer := st.db().GetCollection(userNotificationsC)
+	defer closer()
+
+	doc := userNotificationDoc{
+		DocID:     bson.NewObjectId(),
+		UserName:  n.User.Id(),
+		Event:     n.Event,
+		Channel:   n.Channel,
+		Time:      n.Time,
+		Delivered: n.Delivered,
+		Error:     n.Error,
+	}
+	err := notifications.Writeable().Insert(&doc)
+	return errors.Annotatef(err, "recording %s notification for user %q", n.Event, n.User.Id())
+}
+
+// UserNotifications returns the most recent notifications of events on
+// the user's account, with their delivery status, oldest first.
+func (st *State) UserNotifications(user names.UserTag) ([]UserNotification, error) {
+	notifications, closer := st.db().GetCollection(userNotificationsC)
+	defer closer()
+
+	var docs []userNotificationDoc
+	err := notifications.Find(bson.D{{"user", user.Id()}}).Sort("-time", "-_id").Limit(maxUserNotifications).All(&docs)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	result := make([]UserNotification, len(docs))
+	for i, doc := range docs {
+		result[len(docs)-1-i] = UserNotification{
+			User:      names.NewUserTag(doc.UserName),
+			Event:     doc.Event,
+			Channel:   doc.Channel,
+			Time:      doc.Time.UTC(),
+			Delivered: doc.Delivered,
+			Error:     doc.Error,
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/state"
+)
+
+type UserNotificationsSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&UserNotificationsSuite{})
+
+func (s *UserNotificationsSuite) TestRecordUserNotifications(c *gc.C) {
+	bob := names.NewUserTag("bob")
+	err := s.State.RecordUserNotification(state.UserNotification{
+		User:      bob,
+		Event:     "user-created",
+		Channel:   "webhook",
+		Delivered: true,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	s.Clock.Advance(time.Minute)
+	err = s.State.RecordUserNotification(state.UserNotification{
+		User:    bob,
+		Event:   "password-reset",
+		Channel: "email",
+		Error:   "connection refused",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.State.RecordUserNotification(state.UserNotification{
+		User:      names.NewUserTag("mary"),
+		Event:     "user-disabled",
+		Channel:   "webhook",
+		Delivered: true,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	notifications, err := s.State.UserNotifications(bob)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(notifications, gc.HasLen, 2)
+	c.Check(notifications[0].User, gc.Equals, bob)
+	c.Check(notifications[0].Event, gc.Equals, "user-created")
+	c.Check(notifications[0].Channel, gc.Equals, "webhook")
+	c.Check(notifications[0].Delivered, jc.IsTrue)
+	c.Check(notifications[0].Error, gc.Equals, "")
+	c.Check(notifications[1].Event, gc.Equals, "password-reset")
+	c.Check(notifications[1].Channel, gc.Equals, "email")
+	c.Check(notifications[1].Delivered, jc.IsFalse)
+	c.Check(notifications[1].Error, gc.Equals, "connection refused")
+	c.Check(notifications[1].Time.Sub(notifications[0].Time), gc.Equals, time.Minute)
+}
+
+func (s *UserNotificationsSuite) TestRecordUserNotificationInvalid(c *gc.C) {
+	err := s.State.RecordUserNotification(state.UserNotification{
+		User:  names.NewUserTag("bob"),
+		Event: "user-created",
+	})
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *UserNotificationsSuite) TestUserNotificationsNone(c *gc.C) {
+	notifications, err := s.State.UserNotifications(names.NewUserTag("bob"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(notifications, gc.HasLen, 0)
+}
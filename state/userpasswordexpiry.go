@@ -0,0 +1,71 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"sort"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// PasswordSetTime returns when the user's password was last set, in UTC.
+// For passwords set before the time was recorded, the time the user was
+// created is returned, so that such passwords are treated as the oldest
+// they can be. The zero time is returned if the user has no password.
+func (u *User) PasswordSetTime() time.Time {
+	if u.doc.PasswordHash == "" {
+		return time.Time{}
+	}
+	if u.doc.PasswordSetTime.IsZero() {
+		return u.doc.DateCreated.UTC()
+	}
+	return u.doc.PasswordSetTime.UTC()
+}
+
+// PasswordExpiry returns when the user's password expires, if passwords
+// expire after maxAge, and whether it expires at all. Passwords do not
+// expire if maxAge is zero, and users without passwords have none to
+// expire.
+func (u *User) PasswordExpiry(maxAge time.Duration) (time.Time, bool) {
+	setTime := u.PasswordSetTime()
+	if maxAge <= 0 || setTime.IsZero() {
+		return time.Time{}, false
+	}
+	return setTime.Add(maxAge), true
+}
+
+// PasswordExpired returns whether the user's password has expired, if
+// passwords expire after maxAge.
+func (u *User) PasswordExpired(maxAge time.Duration) bool {
+	expiry, ok := u.PasswordExpiry(maxAge)
+	return ok && !u.st.clock().Now().Before(expiry)
+}
+
+// UsersWithExpiringPasswords returns the enabled local users whose
+// passwords, if passwords expire after maxAge, expire within the given
+// length of time, including those whose passwords have already expired.
+// The users are ordered by when their passwords expire.
+func (st *State) UsersWithExpiringPasswords(maxAge, within time.Duration) ([]*User, error) {
+	if maxAge <= 0 {
+		return nil, nil
+	}
+	users, err := st.AllUsers(false)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	deadline := st.clock().Now().Add(within)
+	var result []*User
+	for _, user := range users {
+		if expiry, ok := user.PasswordExpiry(maxAge); ok && expiry.Before(deadline) {
+			result = append(result, user)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		expiry1, _ := result[i].PasswordExpiry(maxAge)
+		expiry2, _ := result[j].PasswordExpiry(maxAge)
+		return expiry1.Before(expiry2)
+	})
+	return result, nil
+}
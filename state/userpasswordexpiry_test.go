@@ -0,0 +1,105 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"time"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing/factory"
+)
+
+type UserPasswordExpirySuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&UserPasswordExpirySuite{})
+
+const passwordMaxAge = 30 * 24 * time.Hour
+
+func (s *UserPasswordExpirySuite) TestPasswordSetTimeRecorded(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob"})
+	created := user.PasswordSetTime()
+	c.Assert(created, gc.Equals, user.DateCreated())
+
+	s.Clock.Advance(time.Hour)
+	err := user.SetPassword("new-password")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.PasswordSetTime(), gc.Equals, created.Add(time.Hour))
+
+	err = user.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.PasswordSetTime(), gc.Equals, created.Add(time.Hour))
+}
+
+func (s *UserPasswordExpirySuite) TestPasswordExpired(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob"})
+	expiry, ok := user.PasswordExpiry(passwordMaxAge)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(expiry, gc.Equals, user.PasswordSetTime().Add(passwordMaxAge))
+	c.Assert(user.PasswordExpired(passwordMaxAge), jc.IsFalse)
+
+	s.Clock.Advance(passwordMaxAge)
+	c.Assert(user.PasswordExpired(passwordMaxAge), jc.IsTrue)
+
+	// Passwords do not expire without a maximum age.
+	c.Assert(user.PasswordExpired(0), jc.IsFalse)
+
+	// Setting the password renews it.
+	err := user.SetPassword("new-password")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.PasswordExpired(passwordMaxAge), jc.IsFalse)
+}
+
+func (s *UserPasswordExpirySuite) TestPasswordExpiryWithoutPassword(c *gc.C) {
+	user, err := s.State.AddUserWithSecretKey("bob", "display", "admin")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.PasswordSetTime().IsZero(), jc.IsTrue)
+
+	s.Clock.Advance(passwordMaxAge)
+	_, ok := user.PasswordExpiry(passwordMaxAge)
+	c.Assert(ok, jc.IsFalse)
+	c.Assert(user.PasswordExpired(passwordMaxAge), jc.IsFalse)
+}
+
+func (s *UserPasswordExpirySuite) TestUsersWithExpiringPasswords(c *gc.C) {
+	bob := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob"})
+	s.Clock.Advance(10 * 24 * time.Hour)
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "mary"})
+	s.Factory.MakeUser(c, &factory.UserParams{Name: "jim", Disabled: true})
+	s.Clock.Advance(10 * 24 * time.Hour)
+
+	users, err := s.State.UsersWithExpiringPasswords(passwordMaxAge, 15*24*time.Hour)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(userNames(users), jc.DeepEquals, []string{"bob"})
+
+	users, err = s.State.UsersWithExpiringPasswords(passwordMaxAge, 25*24*time.Hour)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(userNames(users), jc.DeepEquals, []string{"bob", "mary"})
+
+	err = bob.SetPassword("new-password")
+	c.Assert(err, jc.ErrorIsNil)
+	users, err = s.State.UsersWithExpiringPasswords(passwordMaxAge, 25*24*time.Hour)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(userNames(users), jc.DeepEquals, []string{"mary"})
+
+	users, err = s.State.UsersWithExpiringPasswords(0, 25*24*time.Hour)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(users, gc.HasLen, 0)
+}
+
+// userNames returns the names of the users, other than the
+// controller admin, whose password was set at bootstrap.
+func userNames(users []*state.User) []string {
+	var names []string
+	for _, user := range users {
+		if user.Name() != "admin" {
+			names = append(names, user.Name())
+		}
+	}
+	return names
+}
@@ -0,0 +1,231 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/core/totp"
+)
+
+// recoveryCodeCount is the number of recovery codes issued when a user
+// enrolls a second factor. Each can be used in place of a code from the
+// user's authenticator once, should they lose access to it.
+const recoveryCodeCount = 10
+
+// SecondFactorEnrolled returns whether the user has enrolled a
+// second authentication factor, which must then be presented
+// along with their password when they log in.
+func (u *User) SecondFactorEnrolled() bool {
+	return u.doc.TOTPEnrolled
+}
+
+// RecoveryCodesRemaining returns the number of the user's
+// recovery codes that have not yet been used.
+func (u *User) RecoveryCodesRemaining() int {
+	return len(u.doc.RecoveryCodes)
+}
+
+// StartTOTPEnrollment generates a new TOTP secret for the user and
+// returns it, to be provisioned in the user's authenticator application.
+// The enrollment is not complete until it is confirmed with a code from
+// the authenticator, by CompleteTOTPEnrollment. It is an error if the user
+// has already enrolled a second factor.
+func (u *User) StartTOTPEnrollment() ([]byte, error) {
+	var secret []byte
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if err := u.ensureNotDeleted(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if u.doc.TOTPEnrolled {
+			return nil, errors.AlreadyExistsf("second factor")
+		}
+		var err error
+		if secret, err = totp.GenerateSecret(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return []txn.Op{{
+			C:      usersC,
+			Id:     strings.ToLower(u.Name()),
+			Assert: bson.D{{"totp-enrolled", bson.D{{"$ne", true}}}},
+			Update: bson.D{
+				{"$set", bson.D{{"totp-secret", secret}}},
+				{"$unset", bson.D{
+					{"totp-last-step", nil},
+					{"recovery-codes", nil},
+				}},
+			},
+		}}, nil
+	}
+	if err := u.st.db().Run(buildTxn); err != nil {
+		return nil, errors.Annotatef(err, "cannot enroll second factor for user %q", u.Name())
+	}
+	u.doc.TOTPSecret = secret
+	u.doc.TOTPLastStep = 0
+	u.doc.RecoveryCodes = nil
+	return secret, nil
+}
+
+// CompleteTOTPEnrollment completes the enrollment started by
+// StartTOTPEnrollment, if the code is valid for the secret generated
+// then. It returns the user's recovery codes, which are not recorded
+// and so cannot be retrieved again.
+func (u *User) CompleteTOTPEnrollment(code string) ([]string, error) {
+	if err := u.ensureNotDeleted(); err != nil {
+		return nil, errors.Annotatef(err, "cannot enroll second factor for user %q", u.Name())
+	}
+	if u.doc.TOTPEnrolled {
+		return nil, errors.AlreadyExistsf("second factor for user %q", u.Name())
+	}
+	if len(u.doc.TOTPSecret) == 0 {
+		return nil, errors.NotFoundf("second factor enrollment for user %q", u.Name())
+	}
+	step, ok := totp.Match(u.doc.TOTPSecret, code, u.st.clock().Now())
+	if !ok {
+		return nil, errors.NotValidf("code")
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		var err error
+		if codes[i], err = generateRecoveryCode(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		hashes[i] = utils.AgentPasswordHash(codes[i])
+	}
+	ops := []txn.Op{{
+		C:  usersC,
+		Id: strings.ToLower(u.Name()),
+		// The enrollment must not have been restarted with a new secret
+		// since the code was checked.
+		Assert: bson.D{
+			{"totp-secret", u.doc.TOTPSecret},
+			{"totp-enrolled", bson.D{{"$ne", true}}},
+		},
+		Update: bson.D{{"$set", bson.D{
+			{"totp-enrolled", true},
+			{"totp-last-step", step},
+			{"recovery-codes", hashes},
+		}}},
+	}}
+	if err := u.st.db().RunTransaction(ops); err == txn.ErrAborted {
+		return nil, errors.Errorf("cannot enroll second factor for user %q: enrollment changed concurrently", u.Name())
+	} else if err != nil {
+		return nil, errors.Annotatef(err, "cannot enroll second factor for user %q", u.Name())
+	}
+	u.doc.TOTPEnrolled = true
+	u.doc.TOTPLastStep = step
+	u.doc.RecoveryCodes = hashes
+	return codes, nil
+}
+
+// SecondFactorValid returns whether the code is a valid second factor for
+// the user: either the current code from their authenticator, or one of
+// their recovery codes. Each authenticator code and recovery code is only
+// accepted once. The caller should call user.Refresh before calling this.
+func (u *User) SecondFactorValid(code string) (bool, error) {
+	if !u.doc.TOTPEnrolled || u.IsDisabled() || u.IsDeleted() {
+		return false, nil
+	}
+	lowercaseName := strings.ToLower(u.Name())
+
+	var ops []txn.Op
+	if step, ok := totp.Match(u.doc.TOTPSecret, code, u.st.clock().Now()); ok {
+		if step <= u.doc.TOTPLastStep {
+			// The code has already been used.
+			return false, nil
+		}
+		ops = []txn.Op{{
+			C:  usersC,
+			Id: lowercaseName,
+			Assert: bson.D{
+				{"totp-enrolled", true},
+				{"totp-last-step", bson.D{{"$lt", step}}},
+			},
+			Update: bson.D{{"$set", bson.D{{"totp-last-step", step}}}},
+		}}
+	} else {
+		hash := utils.AgentPasswordHash(normaliseRecoveryCode(code))
+		found := false
+		for _, h := range u.doc.RecoveryCodes {
+			if h == hash {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+		ops = []txn.Op{{
+			C:      usersC,
+			Id:     lowercaseName,
+			Assert: bson.D{{"recovery-codes", hash}},
+			Update: bson.D{{"$pull", bson.D{{"recovery-codes", hash}}}},
+		}}
+		logger.Infof("user %q used a recovery code, %d remaining", u.Name(), len(u.doc.RecoveryCodes)-1)
+	}
+	// Recording the use of the code in the same transaction that
+	// asserts it is unused ensures that it can only be used once,
+	// even by concurrent logins.
+	if err := u.st.db().RunTransaction(ops); err == txn.ErrAborted {
+		return false, nil
+	} else if err != nil {
+		return false, errors.Annotatef(err, "cannot check second factor for user %q", u.Name())
+	}
+	return true, u.Refresh()
+}
+
+// RemoveSecondFactor removes the user's second factor, including any
+// enrollment in progress, so that they can log in with only their
+// password. It is used when a user loses access to their authenticator
+// and recovery codes, or before enrolling a new authenticator.
+func (u *User) RemoveSecondFactor() error {
+	if err := u.ensureNotDeleted(); err != nil {
+		return errors.Annotatef(err, "cannot remove second factor for user %q", u.Name())
+	}
+	ops := []txn.Op{{
+		C:      usersC,
+		Id:     strings.ToLower(u.Name()),
+		Assert: txn.DocExists,
+		Update: bson.D{{"$unset", bson.D{
+			{"totp-secret", nil},
+			{"totp-enrolled", nil},
+			{"totp-last-step", nil},
+			{"recovery-codes", nil},
+		}}},
+	}}
+	if err := u.st.db().RunTransaction(ops); err != nil {
+		return errors.Annotatef(err, "cannot remove second factor for user %q", u.Name())
+	}
+	u.doc.TOTPSecret = nil
+	u.doc.TOTPEnrolled = false
+	u.doc.TOTPLastStep = 0
+	u.doc.RecoveryCodes = nil
+	return nil
+}
+
+// generateRecoveryCode returns a random recovery code, formatted as two
+// groups of five hex digits to make it easier to copy.
+func generateRecoveryCode() (string, error) {
+	var b [5]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", errors.Trace(err)
+	}
+	code := fmt.Sprintf("%x", b[:])
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normaliseRecoveryCode returns the recovery code as it was generated,
+// ignoring differences in case and surrounding space in what was entered.
+func normaliseRecoveryCode(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
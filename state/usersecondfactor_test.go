@@ -0,0 +1,130 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/core/totp"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing/factory"
+)
+
+type UserSecondFactorSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&UserSecondFactorSuite{})
+
+func (s *UserSecondFactorSuite) currentCode(secret []byte) string {
+	return totp.Code(secret, totp.Step(s.Clock.Now()))
+}
+
+func (s *UserSecondFactorSuite) enroll(c *gc.C, user *state.User) ([]byte, []string) {
+	secret, err := user.StartTOTPEnrollment()
+	c.Assert(err, jc.ErrorIsNil)
+	codes, err := user.CompleteTOTPEnrollment(s.currentCode(secret))
+	c.Assert(err, jc.ErrorIsNil)
+	return secret, codes
+}
+
+func (s *UserSecondFactorSuite) TestEnrollment(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob"})
+	c.Assert(user.SecondFactorEnrolled(), jc.IsFalse)
+
+	secret, err := user.StartTOTPEnrollment()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(secret, gc.HasLen, totp.SecretSize)
+	c.Assert(user.SecondFactorEnrolled(), jc.IsFalse)
+
+	_, err = user.CompleteTOTPEnrollment(totp.Code(secret, totp.Step(s.Clock.Now())+10))
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+
+	codes, err := user.CompleteTOTPEnrollment(s.currentCode(secret))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(codes, gc.HasLen, 10)
+	c.Assert(user.SecondFactorEnrolled(), jc.IsTrue)
+	c.Assert(user.RecoveryCodesRemaining(), gc.Equals, 10)
+
+	err = user.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.SecondFactorEnrolled(), jc.IsTrue)
+
+	// A second factor cannot be enrolled over an existing one.
+	_, err = user.StartTOTPEnrollment()
+	c.Assert(err, jc.Satisfies, errors.IsAlreadyExists)
+}
+
+func (s *UserSecondFactorSuite) TestCompleteEnrollmentNotStarted(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob"})
+	_, err := user.CompleteTOTPEnrollment("123456")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *UserSecondFactorSuite) TestSecondFactorValid(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob"})
+	secret, _ := s.enroll(c, user)
+
+	// The code used to enroll cannot be used again.
+	valid, err := user.SecondFactorValid(s.currentCode(secret))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(valid, jc.IsFalse)
+
+	s.Clock.Advance(totp.Period)
+	code := s.currentCode(secret)
+	valid, err = user.SecondFactorValid(code)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(valid, jc.IsTrue)
+
+	// Nor can any other code.
+	valid, err = user.SecondFactorValid(code)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(valid, jc.IsFalse)
+
+	s.Clock.Advance(5 * time.Minute)
+	valid, err = user.SecondFactorValid(code)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(valid, jc.IsFalse)
+}
+
+func (s *UserSecondFactorSuite) TestRecoveryCodes(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob"})
+	_, codes := s.enroll(c, user)
+
+	valid, err := user.SecondFactorValid(" " + strings.ToUpper(codes[3]) + "\n")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(valid, jc.IsTrue)
+	c.Assert(user.RecoveryCodesRemaining(), gc.Equals, 9)
+
+	// Each recovery code can only be used once.
+	valid, err = user.SecondFactorValid(codes[3])
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(valid, jc.IsFalse)
+
+	valid, err = user.SecondFactorValid("nope")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(valid, jc.IsFalse)
+}
+
+func (s *UserSecondFactorSuite) TestRemoveSecondFactor(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob"})
+	_, codes := s.enroll(c, user)
+
+	err := user.RemoveSecondFactor()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.SecondFactorEnrolled(), jc.IsFalse)
+
+	valid, err := user.SecondFactorValid(codes[0])
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(valid, jc.IsFalse)
+
+	// A new authenticator can then be enrolled.
+	s.enroll(c, user)
+	c.Assert(user.SecondFactorEnrolled(), jc.IsTrue)
+}
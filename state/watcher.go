@@ -202,6 +202,17 @@ func (st *State) WatchModels() StringsWatcher {
 	})
 }
 
+// WatchAllUnitStates returns a StringsWatcher that notifies of changes
+// to the unit state documents of all models. The ids reported are the
+// global document ids, which include the model UUID. As for any
+// collectionWatcher, the removal of a document is not reported.
+func (st *State) WatchAllUnitStates() StringsWatcher {
+	return newCollectionWatcher(st, colWCfg{
+		col:    unitStatesC,
+		global: true,
+	})
+}
+
 // WatchModelLives returns a StringsWatcher that notifies of changes
 // to any model life values. The watcher will not send any more events
 // for a model after it has been observed to be Dead.
@@ -1257,9 +1268,9 @@ func emptyRelationUnitsChanges(changes *corewatcher.RelationUnitsChange) bool {
 	return len(changes.Changed)+len(changes.AppChanged)+len(changes.Departed) == 0
 }
 
-func setRelationUnitChangeVersion(changes *corewatcher.RelationUnitsChange, key string, version int64) {
+func setRelationUnitChangeVersion(changes *corewatcher.RelationUnitsChange, key string, version int64, observed time.Time) {
 	name := unitNameFromScopeKey(key)
-	settings := corewatcher.UnitSettings{Version: version}
+	settings := corewatcher.UnitSettings{Version: version, Observed: observed}
 	if changes.Changed == nil {
 		changes.Changed = map[string]corewatcher.UnitSettings{}
 	}
@@ -1295,7 +1306,7 @@ func (w *relationUnitsWatcher) mergeSettings(changes *corewatcher.RelationUnitsC
 		return errors.Trace(err)
 	}
 	w.logger.Tracef("relationUnitsWatcher %q merging key %q version: %d", w.sw.prefix, key, version)
-	setRelationUnitChangeVersion(changes, key, version)
+	setRelationUnitChangeVersion(changes, key, version, w.backend.clock().Now())
 	return nil
 }
 
@@ -3117,9 +3128,18 @@ func (w *collectionWatcher) initial() ([]string, error) {
 	var doc struct {
 		DocId string `bson:"_id"`
 	}
-	coll, closer := w.db.GetCollection(w.col)
-	defer closer()
-	iter := coll.Find(nil).Iter()
+	var iter mongo.Iterator
+	if w.colWCfg.global {
+		// A global watcher reports the documents of all models,
+		// so it must not be limited to those of this model.
+		coll, closer := w.db.GetRawCollection(w.col)
+		defer closer()
+		iter = coll.Find(nil).Iter()
+	} else {
+		coll, closer := w.db.GetCollection(w.col)
+		defer closer()
+		iter = coll.Find(nil).Iter()
+	}
 	for iter.Next(&doc) {
 		if w.filter == nil || w.filter(doc.DocId) {
 			id := doc.DocId
@@ -7,7 +7,9 @@ import (
 	"io"
 	"time"
 
+	"github.com/juju/errors"
 	"github.com/juju/replicaset"
+	"github.com/juju/version"
 
 	"github.com/juju/juju/cloud"
 	"github.com/juju/juju/controller"
@@ -78,6 +80,62 @@ type StateBackend interface {
 	RemoveControllerConfigMaxLogAgeAndSize() error
 	IncrementTasksSequence() error
 	AddMachineIDToSubordinates() error
+	RemoveMachineIDFromSubordinates() error
+	AddApplicationToUnitStates() error
+	RemoveApplicationFromUnitStates() error
+
+	// ValidateModel runs the input validation checks
+	// against the model with the input UUID.
+	ValidateModel(string, []ValidationCheck) error
+
+	// SchemaVersions returns the schema version recorded
+	// for each collection migrated by an upgrade step.
+	SchemaVersions() (map[string]int, error)
+
+	// SetSchemaVersion records the schema version of a
+	// collection, as left by the described upgrade step.
+	SetSchemaVersion(collection string, version int, step string) error
+
+	// SampleMissingFields returns the input fields missing from each
+	// document in a random sample of a collection's documents.
+	SampleMissingFields(collection string, fields []string, size int) (map[string][]string, error)
+
+	// GatedUpgradeSteps returns the upgrade steps skipped because
+	// their feature flags were not set.
+	GatedUpgradeSteps() ([]state.GatedUpgradeStep, error)
+
+	// AddGatedUpgradeStep records that the described upgrade step
+	// was skipped because the feature flag was not set.
+	AddGatedUpgradeStep(step, featureFlag string) error
+
+	// RemoveGatedUpgradeStep removes the record of the
+	// described skipped upgrade step, once it has run.
+	RemoveGatedUpgradeStep(step string) error
+
+	// ContractUpgradeSteps returns the contract upgrade steps
+	// deferred until every agent has been upgraded.
+	ContractUpgradeSteps() ([]state.ContractUpgradeStep, error)
+
+	// AddContractUpgradeStep records that the described contract
+	// upgrade step was deferred until every agent runs at least
+	// the input version.
+	AddContractUpgradeStep(step string, minAgentVersion version.Number) error
+
+	// RemoveContractUpgradeStep removes the record of the
+	// described deferred contract step, once it has run.
+	RemoveContractUpgradeStep(step string) error
+
+	// MinAgentVersion returns the lowest version
+	// run by the agents in the controller.
+	MinAgentVersion() (version.Number, error)
+
+	// EnsureIndexes creates the missing indexes declared by the
+	// collection schema, and reports those that are not declared.
+	EnsureIndexes() ([]state.CollectionIndexes, error)
+
+	// SweepOrphans reports, and if remove is true removes, the
+	// documents of models, units and machines that no longer exist.
+	SweepOrphans(remove bool) ([]state.OrphanReport, error)
 }
 
 // Model is an interface providing access to the details of a model within the
@@ -318,3 +376,79 @@ func (s stateBackend) IncrementTasksSequence() error {
 func (s stateBackend) AddMachineIDToSubordinates() error {
 	return state.AddMachineIDToSubordinates(s.pool)
 }
+
+func (s stateBackend) RemoveMachineIDFromSubordinates() error {
+	return state.RemoveMachineIDFromSubordinates(s.pool)
+}
+
+func (s stateBackend) AddApplicationToUnitStates() error {
+	return state.AddApplicationToUnitStates(s.pool)
+}
+
+func (s stateBackend) RemoveApplicationFromUnitStates() error {
+	return state.RemoveApplicationFromUnitStates(s.pool)
+}
+
+func (s stateBackend) ValidateModel(modelUUID string, checks []ValidationCheck) error {
+	st, err := s.pool.Get(modelUUID)
+	if err != nil {
+		return errors.Annotatef(err, "getting canary model %q", modelUUID)
+	}
+	defer st.Release()
+
+	for _, check := range checks {
+		logger.Debugf("running upgrade validation check %q on model %q", check.Description, modelUUID)
+		if err := check.Run(st.State); err != nil {
+			return errors.Annotatef(err, "validation check %q failed on model %q", check.Description, modelUUID)
+		}
+	}
+	return nil
+}
+
+func (s stateBackend) SchemaVersions() (map[string]int, error) {
+	return s.pool.SystemState().SchemaVersions()
+}
+
+func (s stateBackend) SetSchemaVersion(collection string, version int, step string) error {
+	return s.pool.SystemState().SetSchemaVersion(collection, version, step)
+}
+
+func (s stateBackend) SampleMissingFields(collection string, fields []string, size int) (map[string][]string, error) {
+	return s.pool.SystemState().SampleMissingFields(collection, fields, size)
+}
+
+func (s stateBackend) GatedUpgradeSteps() ([]state.GatedUpgradeStep, error) {
+	return s.pool.SystemState().GatedUpgradeSteps()
+}
+
+func (s stateBackend) AddGatedUpgradeStep(step, featureFlag string) error {
+	return s.pool.SystemState().AddGatedUpgradeStep(step, featureFlag)
+}
+
+func (s stateBackend) RemoveGatedUpgradeStep(step string) error {
+	return s.pool.SystemState().RemoveGatedUpgradeStep(step)
+}
+
+func (s stateBackend) ContractUpgradeSteps() ([]state.ContractUpgradeStep, error) {
+	return s.pool.SystemState().ContractUpgradeSteps()
+}
+
+func (s stateBackend) AddContractUpgradeStep(step string, minAgentVersion version.Number) error {
+	return s.pool.SystemState().AddContractUpgradeStep(step, minAgentVersion)
+}
+
+func (s stateBackend) RemoveContractUpgradeStep(step string) error {
+	return s.pool.SystemState().RemoveContractUpgradeStep(step)
+}
+
+func (s stateBackend) MinAgentVersion() (version.Number, error) {
+	return s.pool.SystemState().MinAgentVersion()
+}
+
+func (s stateBackend) EnsureIndexes() ([]state.CollectionIndexes, error) {
+	return s.pool.SystemState().EnsureIndexes()
+}
+
+func (s stateBackend) SweepOrphans(remove bool) ([]state.OrphanReport, error) {
+	return s.pool.SystemState().SweepOrphans(remove)
+}
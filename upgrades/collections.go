@@ -0,0 +1,60 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades
+
+import (
+	"github.com/juju/version"
+)
+
+// Collections describes the database collections
+// that an upgrade step reads from and writes to.
+type Collections struct {
+	// Read are the collections read by the step.
+	Read []string
+
+	// Write are the collections modified by the step.
+	Write []string
+}
+
+// CollectionsStep is implemented by upgrade steps that declare
+// the database collections they touch.
+type CollectionsStep interface {
+	Step
+
+	// Collections returns the collections touched by the step.
+	Collections() Collections
+}
+
+// StepCollections associates an upgrade step, identified
+// by its description, with the collections it touches.
+type StepCollections struct {
+	Description string
+	Collections
+}
+
+// StateUpgradeCollections returns the collections touched by each of the
+// state upgrade steps that would be run from the input version for the
+// input targets, in the order that the steps are run.
+// Steps that do not declare their collections are included with empty
+// collections, so that the returned slice describes every step run.
+func StateUpgradeCollections(from version.Number, targets []Target) []StepCollections {
+	return upgradeCollections(newStateUpgradeOpsIterator(from), targets)
+}
+
+func upgradeCollections(ops *opsIterator, targets []Target) []StepCollections {
+	var result []StepCollections
+	for ops.Next() {
+		for _, step := range ops.Get().Steps() {
+			if !targetsMatch(targets, step.Targets()) {
+				continue
+			}
+			sc := StepCollections{Description: step.Description()}
+			if cs, ok := step.(CollectionsStep); ok {
+				sc.Collections = cs.Collections()
+			}
+			result = append(result, sc)
+		}
+	}
+	return result
+}
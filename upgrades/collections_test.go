@@ -0,0 +1,78 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/version"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/testing"
+	"github.com/juju/juju/upgrades"
+)
+
+type collectionsSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&collectionsSuite{})
+
+type collectionsStep struct {
+	*mockUpgradeStep
+	collections upgrades.Collections
+}
+
+func (s *collectionsStep) Collections() upgrades.Collections {
+	return s.collections
+}
+
+func (s *collectionsSuite) TestStateUpgradeCollections(c *gc.C) {
+	steps := []upgrades.Step{
+		&collectionsStep{
+			mockUpgradeStep: newUpgradeStep("move units", upgrades.DatabaseMaster),
+			collections: upgrades.Collections{
+				Read:  []string{"units", "machines"},
+				Write: []string{"units"},
+			},
+		},
+		&collectionsStep{
+			mockUpgradeStep: newUpgradeStep("host step", upgrades.HostMachine),
+			collections: upgrades.Collections{
+				Read: []string{"machines"},
+			},
+		},
+		newUpgradeStep("plain step", upgrades.DatabaseMaster),
+	}
+	s.PatchValue(upgrades.StateUpgradeOperations, func() []upgrades.Operation {
+		return []upgrades.Operation{&mockUpgradeOperation{
+			targetVersion: version.MustParse("1.20.0"),
+			steps:         steps,
+		}}
+	})
+
+	targets := []upgrades.Target{upgrades.DatabaseMaster}
+	collections := upgrades.StateUpgradeCollections(version.MustParse("1.18.0"), targets)
+	c.Check(collections, jc.DeepEquals, []upgrades.StepCollections{{
+		Description: "move units",
+		Collections: upgrades.Collections{
+			Read:  []string{"units", "machines"},
+			Write: []string{"units"},
+		},
+	}, {
+		Description: "plain step",
+	}})
+
+	// Steps for versions already upgraded to are not included.
+	collections = upgrades.StateUpgradeCollections(version.MustParse("1.20.0"), targets)
+	c.Check(collections, gc.HasLen, 0)
+}
+
+func (s *collectionsSuite) TestStateStepsDeclareCollections(c *gc.C) {
+	targets := []upgrades.Target{upgrades.DatabaseMaster}
+	collections := upgrades.StateUpgradeCollections(version.Zero, targets)
+	c.Assert(collections, gc.Not(gc.HasLen), 0)
+	for _, sc := range collections {
+		c.Check(len(sc.Read)+len(sc.Write) > 0, jc.IsTrue, gc.Commentf("step %q", sc.Description))
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/version"
+)
+
+// Cost describes how long an upgrade step is expected to take,
+// in terms of the size of the collections it processes.
+type Cost struct {
+	// Fixed is the time taken by the step
+	// regardless of the size of the collections.
+	Fixed time.Duration
+
+	// PerDocument is the time taken for each
+	// document in the collections.
+	PerDocument time.Duration
+
+	// PerMiB is the time taken for each MiB
+	// of data in the collections.
+	PerMiB time.Duration
+
+	// Collections are the collections processed by the step.
+	// If empty, those that the step declares it writes are used.
+	Collections []string
+}
+
+// declared returns true if the cost describes any time taken.
+func (c Cost) declared() bool {
+	return c.Fixed != 0 || c.PerDocument != 0 || c.PerMiB != 0
+}
+
+// CostStep is implemented by upgrade steps that declare
+// a model of how long they take to run.
+type CostStep interface {
+	Step
+
+	// Cost returns the cost model of the step.
+	Cost() Cost
+}
+
+// defaultCost is the cost of steps that declare none.
+var defaultCost = Cost{
+	Fixed:       time.Second,
+	PerDocument: 100 * time.Microsecond,
+}
+
+// CollectionStats describes the size of a database collection.
+type CollectionStats struct {
+	// Count is the number of documents in the collection.
+	Count int64
+
+	// Size is the total size (in bytes) of the documents.
+	Size int64
+}
+
+// StepEstimate associates an upgrade step, identified by its
+// description, with an estimate of how long it takes to run.
+type StepEstimate struct {
+	Description string
+	Duration    time.Duration
+}
+
+// EstimateStateUpgrade returns an estimate of how long each of the state
+// upgrade steps that would be run from the input version for the input
+// targets takes to run, in the order that the steps are run. The sizes
+// of the collections processed by the steps are read using stats, once
+// for each collection. Steps that do not declare their cost are estimated
+// from the collections they write.
+func EstimateStateUpgrade(
+	from version.Number, targets []Target, stats func(string) (CollectionStats, error),
+) ([]StepEstimate, error) {
+	return estimateUpgrade(newStateUpgradeOpsIterator(from), targets, stats)
+}
+
+func estimateUpgrade(
+	ops *opsIterator, targets []Target, stats func(string) (CollectionStats, error),
+) ([]StepEstimate, error) {
+	read := make(map[string]CollectionStats)
+	collectionStats := func(name string) (CollectionStats, error) {
+		if s, ok := read[name]; ok {
+			return s, nil
+		}
+		s, err := stats(name)
+		if err != nil {
+			return CollectionStats{}, errors.Annotatef(err, "reading size of collection %q", name)
+		}
+		read[name] = s
+		return s, nil
+	}
+
+	var result []StepEstimate
+	for ops.Next() {
+		for _, step := range ops.Get().Steps() {
+			if !targetsMatch(targets, step.Targets()) {
+				continue
+			}
+			duration, err := estimateStep(step, collectionStats)
+			if err != nil {
+				return nil, errors.Annotatef(err, "estimating upgrade step %q", step.Description())
+			}
+			result = append(result, StepEstimate{
+				Description: step.Description(),
+				Duration:    duration,
+			})
+		}
+	}
+	return result, nil
+}
+
+// estimateStep returns the estimated time taken to run the step, going
+// by its declared cost, or the default cost if it declares none.
+func estimateStep(step Step, stats func(string) (CollectionStats, error)) (time.Duration, error) {
+	cost := defaultCost
+	if cs, ok := step.(CostStep); ok && cs.Cost().declared() {
+		cost = cs.Cost()
+	}
+	collections := cost.Collections
+	if len(collections) == 0 {
+		if cs, ok := step.(CollectionsStep); ok {
+			collections = cs.Collections().Write
+		}
+	}
+
+	duration := cost.Fixed
+	for _, name := range collections {
+		s, err := stats(name)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		duration += time.Duration(s.Count) * cost.PerDocument
+		duration += time.Duration(float64(s.Size) / (1 << 20) * float64(cost.PerMiB))
+	}
+	return duration, nil
+}
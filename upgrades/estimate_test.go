@@ -0,0 +1,112 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/version"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/testing"
+	"github.com/juju/juju/upgrades"
+)
+
+type estimateSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&estimateSuite{})
+
+type costStep struct {
+	*collectionsStep
+	cost upgrades.Cost
+}
+
+func (s *costStep) Cost() upgrades.Cost {
+	return s.cost
+}
+
+func (s *estimateSuite) patchSteps(steps ...upgrades.Step) {
+	s.PatchValue(upgrades.StateUpgradeOperations, func() []upgrades.Operation {
+		return []upgrades.Operation{&mockUpgradeOperation{
+			targetVersion: version.MustParse("1.20.0"),
+			steps:         steps,
+		}}
+	})
+}
+
+func (s *estimateSuite) TestEstimateStateUpgrade(c *gc.C) {
+	s.patchSteps(
+		&costStep{
+			collectionsStep: &collectionsStep{
+				mockUpgradeStep: newUpgradeStep("move units", upgrades.DatabaseMaster),
+				collections:     upgrades.Collections{Write: []string{"units"}},
+			},
+			cost: upgrades.Cost{
+				Fixed:       time.Second,
+				PerDocument: time.Millisecond,
+				PerMiB:      time.Second,
+			},
+		},
+		&costStep{
+			collectionsStep: &collectionsStep{
+				mockUpgradeStep: newUpgradeStep("count machines", upgrades.DatabaseMaster),
+				collections:     upgrades.Collections{Read: []string{"machines"}},
+			},
+			cost: upgrades.Cost{
+				PerDocument: 10 * time.Millisecond,
+				Collections: []string{"machines", "units"},
+			},
+		},
+		&collectionsStep{
+			mockUpgradeStep: newUpgradeStep("undeclared", upgrades.DatabaseMaster),
+			collections:     upgrades.Collections{Write: []string{"units"}},
+		},
+		newUpgradeStep("host step", upgrades.HostMachine),
+	)
+
+	var read []string
+	stats := func(name string) (upgrades.CollectionStats, error) {
+		read = append(read, name)
+		switch name {
+		case "units":
+			return upgrades.CollectionStats{Count: 1000, Size: 2 << 20}, nil
+		case "machines":
+			return upgrades.CollectionStats{Count: 10, Size: 1 << 20}, nil
+		}
+		return upgrades.CollectionStats{}, errors.NotFoundf("collection %q", name)
+	}
+	targets := []upgrades.Target{upgrades.DatabaseMaster}
+	estimates, err := upgrades.EstimateStateUpgrade(version.MustParse("1.18.0"), targets, stats)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(estimates, jc.DeepEquals, []upgrades.StepEstimate{{
+		Description: "move units",
+		Duration:    time.Second + time.Second + 2*time.Second,
+	}, {
+		Description: "count machines",
+		Duration:    100*time.Millisecond + 10*time.Second,
+	}, {
+		Description: "undeclared",
+		Duration:    time.Second + 100*time.Millisecond,
+	}})
+	// Each collection is only read once.
+	c.Check(read, jc.DeepEquals, []string{"units", "machines"})
+}
+
+func (s *estimateSuite) TestEstimateStateUpgradeStatsError(c *gc.C) {
+	s.patchSteps(&collectionsStep{
+		mockUpgradeStep: newUpgradeStep("move units", upgrades.DatabaseMaster),
+		collections:     upgrades.Collections{Write: []string{"units"}},
+	})
+
+	stats := func(name string) (upgrades.CollectionStats, error) {
+		return upgrades.CollectionStats{}, errors.New("boom")
+	}
+	targets := []upgrades.Target{upgrades.DatabaseMaster}
+	_, err := upgrades.EstimateStateUpgrade(version.MustParse("1.18.0"), targets, stats)
+	c.Assert(err, gc.ErrorMatches, `estimating upgrade step "move units": reading size of collection "units": boom`)
+}
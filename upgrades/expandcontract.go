@@ -0,0 +1,186 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/version"
+)
+
+// Phase identifies the phase of a staged, expand/contract migration
+// that an upgrade step performs.
+type Phase string
+
+const (
+	// PhaseExpand steps add the new form of the data alongside the
+	// old. They are run with the other upgrade steps, before agents
+	// of the new version start, so must leave the data usable by
+	// agents of the previous version.
+	PhaseExpand Phase = "expand"
+
+	// PhaseContract steps remove the old form of the data. They are
+	// deferred by the upgrade, and only run once every agent in the
+	// controller has been upgraded to at least the version of the
+	// operation that declared them.
+	PhaseContract Phase = "contract"
+)
+
+// PhasedStep is implemented by upgrade steps that are one phase of a
+// staged migration. Between the phases, agents of both versions use the
+// data, so it must be written in both forms and read from either; see
+// the mongo/utils package.
+type PhasedStep interface {
+	Step
+
+	// Phase returns the migration phase that the step performs. If it
+	// is empty, the step is not part of a staged migration.
+	Phase() Phase
+}
+
+// StepPhase describes an upgrade step that is
+// one phase of a staged migration.
+type StepPhase struct {
+	// Description is the description of the step.
+	Description string
+
+	// Phase is the migration phase that the step performs.
+	Phase Phase
+}
+
+// StateUpgradePhases returns, in upgrade order, the steps of a staged
+// migration that are part of the upgrade from the input version on
+// the targets.
+func StateUpgradePhases(from version.Number, targets []Target) []StepPhase {
+	var result []StepPhase
+	ops := newStateUpgradeOpsIterator(from)
+	for ops.Next() {
+		for _, step := range ops.Get().Steps() {
+			phase := stepPhase(step)
+			if phase == "" || !targetsMatch(targets, step.Targets()) {
+				continue
+			}
+			result = append(result, StepPhase{
+				Description: step.Description(),
+				Phase:       phase,
+			})
+		}
+	}
+	return result
+}
+
+// ContractStep describes a contract step that was deferred by an upgrade
+// until every agent has been upgraded.
+type ContractStep struct {
+	// Description is the description of the step.
+	Description string
+
+	// MinAgentVersion is the lowest agent version
+	// at which the step can be run.
+	MinAgentVersion version.Number
+
+	// Ready is true if every agent now runs at least
+	// the minimum version, so that the step can be run.
+	Ready bool
+}
+
+// ContractSteps returns the deferred contract steps that have not run
+// since, reporting whether each is now ready to run.
+// Backend retrieval is lazy, as it requires a real state pool.
+func ContractSteps(backend func() StateBackend) ([]ContractStep, error) {
+	st := backend()
+	recorded, err := st.ContractUpgradeSteps()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(recorded) == 0 {
+		return nil, nil
+	}
+	minVersion, err := st.MinAgentVersion()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	result := make([]ContractStep, len(recorded))
+	for i, step := range recorded {
+		result[i] = ContractStep{
+			Description:     step.Description,
+			MinAgentVersion: step.MinAgentVersion,
+			Ready:           minVersion != version.Zero && minVersion.Compare(step.MinAgentVersion) >= 0,
+		}
+	}
+	return result, nil
+}
+
+// RunContractSteps runs, in upgrade order, the deferred contract steps
+// that are now ready. Each is recorded as no longer deferred once it has
+// run. A ready step that is also gated behind a feature flag that is not
+// set is recorded as gated instead, so that it is run once the flag is
+// enabled. As with other upgrade steps, they are run on the primary
+// controller, and the first to fail aborts the rest.
+// Context retrieval is lazy, as it requires a real state pool.
+// If observer is not nil, it is notified of each step before it is run.
+func RunContractSteps(contextGetter func() Context, observer StepObserver) error {
+	context := contextGetter().StateContext()
+	deferred, err := ContractSteps(context.State)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	ready := make(map[string]bool)
+	for _, step := range deferred {
+		if step.Ready {
+			ready[step.Description] = true
+		}
+	}
+	if len(ready) == 0 {
+		return nil
+	}
+
+	targets := []Target{DatabaseMaster}
+	ops := newStateUpgradeOpsIterator(version.Zero)
+	for ops.Next() {
+		for _, step := range ops.Get().Steps() {
+			if !ready[step.Description()] || !targetsMatch(targets, step.Targets()) {
+				continue
+			}
+			gated, err := skipGatedStep(context, step)
+			if err != nil {
+				return &upgradeError{
+					description: step.Description(),
+					err:         err,
+				}
+			}
+			if !gated {
+				if err := runStep(context, step, observer); err != nil {
+					return errors.Trace(err)
+				}
+			}
+			if err := context.State().RemoveContractUpgradeStep(step.Description()); err != nil {
+				return &upgradeError{
+					description: step.Description(),
+					err:         err,
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// stepPhase returns the migration phase that the step performs,
+// or an empty Phase if it is not part of a staged migration.
+func stepPhase(step Step) Phase {
+	if ps, ok := step.(PhasedStep); ok {
+		return ps.Phase()
+	}
+	return ""
+}
+
+// deferContractStep returns true if the step is a contract step, having
+// recorded that it must be run once every agent runs at least the input
+// version.
+func deferContractStep(context Context, step Step, minAgentVersion version.Number) (bool, error) {
+	if stepPhase(step) != PhaseContract {
+		return false, nil
+	}
+	logger.Infof("deferring upgrade step %q until all agents run %v", step.Description(), minAgentVersion)
+	return true, errors.Trace(context.State().AddContractUpgradeStep(step.Description(), minAgentVersion))
+}
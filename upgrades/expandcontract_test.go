@@ -0,0 +1,173 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades_test
+
+import (
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/version"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/upgrades"
+	jujuversion "github.com/juju/juju/version"
+)
+
+type expandContractSuite struct {
+	coretesting.BaseSuite
+}
+
+var _ = gc.Suite(&expandContractSuite{})
+
+type phasedStep struct {
+	*mockUpgradeStep
+	phase upgrades.Phase
+}
+
+func (s *phasedStep) Phase() upgrades.Phase {
+	return s.phase
+}
+
+func (s *expandContractSuite) patchPhasedSteps() {
+	s.PatchValue(&jujuversion.Current, version.MustParse("1.22.0"))
+	s.PatchValue(upgrades.StateUpgradeOperations, func() []upgrades.Operation {
+		return []upgrades.Operation{&mockUpgradeOperation{
+			targetVersion: version.MustParse("1.20.0"),
+			steps: []upgrades.Step{
+				&phasedStep{
+					mockUpgradeStep: newUpgradeStep("copy unit ports", upgrades.DatabaseMaster),
+					phase:           upgrades.PhaseExpand,
+				},
+				newUpgradeStep("plain step", upgrades.DatabaseMaster),
+			},
+		}, &mockUpgradeOperation{
+			targetVersion: version.MustParse("1.22.0"),
+			steps: []upgrades.Step{
+				&phasedStep{
+					mockUpgradeStep: newUpgradeStep("remove unit ports", upgrades.DatabaseMaster),
+					phase:           upgrades.PhaseContract,
+				},
+			},
+		}}
+	})
+}
+
+func (s *expandContractSuite) TestUpgradeDefersContractSteps(c *gc.C) {
+	s.patchPhasedSteps()
+	st := &contractStateBackend{}
+	ctx := &mockContext{state: st}
+
+	err := upgrades.PerformStateUpgrade(version.MustParse("1.18.0"), targets(upgrades.DatabaseMaster), ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ctx.messages, jc.DeepEquals, []string{"copy unit ports", "plain step"})
+	st.CheckCalls(c, []testing.StubCall{
+		{"AddContractUpgradeStep", []interface{}{"remove unit ports", version.MustParse("1.22.0")}},
+	})
+}
+
+func (s *expandContractSuite) TestStateUpgradePhases(c *gc.C) {
+	s.patchPhasedSteps()
+	phases := upgrades.StateUpgradePhases(version.MustParse("1.18.0"), targets(upgrades.DatabaseMaster))
+	c.Assert(phases, jc.DeepEquals, []upgrades.StepPhase{
+		{Description: "copy unit ports", Phase: upgrades.PhaseExpand},
+		{Description: "remove unit ports", Phase: upgrades.PhaseContract},
+	})
+
+	phases = upgrades.StateUpgradePhases(version.MustParse("1.20.0"), targets(upgrades.DatabaseMaster))
+	c.Assert(phases, jc.DeepEquals, []upgrades.StepPhase{
+		{Description: "remove unit ports", Phase: upgrades.PhaseContract},
+	})
+}
+
+func (s *expandContractSuite) TestContractSteps(c *gc.C) {
+	st := &contractStateBackend{
+		minVersion: version.MustParse("1.22.0"),
+		deferred: []state.ContractUpgradeStep{
+			{Description: "remove unit ports", MinAgentVersion: version.MustParse("1.22.0")},
+			{Description: "drop legacy leases", MinAgentVersion: version.MustParse("1.24.0")},
+		},
+	}
+	steps, err := upgrades.ContractSteps(func() upgrades.StateBackend { return st })
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(steps, jc.DeepEquals, []upgrades.ContractStep{
+		{Description: "remove unit ports", MinAgentVersion: version.MustParse("1.22.0"), Ready: true},
+		{Description: "drop legacy leases", MinAgentVersion: version.MustParse("1.24.0")},
+	})
+}
+
+func (s *expandContractSuite) TestContractStepsNone(c *gc.C) {
+	st := &contractStateBackend{}
+	steps, err := upgrades.ContractSteps(func() upgrades.StateBackend { return st })
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(steps, gc.HasLen, 0)
+	st.CheckCallNames(c, "ContractUpgradeSteps")
+}
+
+func (s *expandContractSuite) TestRunContractStepsOnceReady(c *gc.C) {
+	s.patchPhasedSteps()
+	st := &contractStateBackend{
+		minVersion: version.MustParse("1.22.0"),
+		deferred: []state.ContractUpgradeStep{
+			{Description: "remove unit ports", MinAgentVersion: version.MustParse("1.22.0")},
+		},
+	}
+	ctx := &mockContext{state: st}
+
+	var observed []string
+	err := upgrades.RunContractSteps(func() upgrades.Context { return ctx }, func(description string) error {
+		observed = append(observed, description)
+		return nil
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ctx.messages, jc.DeepEquals, []string{"remove unit ports"})
+	c.Assert(observed, jc.DeepEquals, []string{"remove unit ports"})
+	st.CheckCalls(c, []testing.StubCall{
+		{"ContractUpgradeSteps", nil},
+		{"MinAgentVersion", nil},
+		{"RemoveContractUpgradeStep", []interface{}{"remove unit ports"}},
+	})
+}
+
+func (s *expandContractSuite) TestRunContractStepsNotReady(c *gc.C) {
+	s.patchPhasedSteps()
+	st := &contractStateBackend{
+		minVersion: version.MustParse("1.20.0"),
+		deferred: []state.ContractUpgradeStep{
+			{Description: "remove unit ports", MinAgentVersion: version.MustParse("1.22.0")},
+		},
+	}
+	ctx := &mockContext{state: st}
+
+	err := upgrades.RunContractSteps(func() upgrades.Context { return ctx }, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ctx.messages, gc.HasLen, 0)
+	st.CheckCallNames(c, "ContractUpgradeSteps", "MinAgentVersion")
+}
+
+type contractStateBackend struct {
+	mockStateBackend
+	minVersion version.Number
+	deferred   []state.ContractUpgradeStep
+}
+
+func (st *contractStateBackend) ContractUpgradeSteps() ([]state.ContractUpgradeStep, error) {
+	st.MethodCall(st, "ContractUpgradeSteps")
+	return st.deferred, st.NextErr()
+}
+
+func (st *contractStateBackend) AddContractUpgradeStep(step string, minAgentVersion version.Number) error {
+	st.MethodCall(st, "AddContractUpgradeStep", step, minAgentVersion)
+	return st.NextErr()
+}
+
+func (st *contractStateBackend) RemoveContractUpgradeStep(step string) error {
+	st.MethodCall(st, "RemoveContractUpgradeStep", step)
+	return st.NextErr()
+}
+
+func (st *contractStateBackend) MinAgentVersion() (version.Number, error) {
+	st.MethodCall(st, "MinAgentVersion")
+	return st.minVersion, st.NextErr()
+}
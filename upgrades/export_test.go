@@ -10,6 +10,11 @@ var (
 	StateUpgradeOperations = &stateUpgradeOperations
 
 	SetJujuFolderPermissionsToAdm = setJujuFolderPermissionsToAdm
+
+	AvailableMemory   = &availableMemory
+	ParseMemAvailable = parseMemAvailable
+
+	ValidationChecksForTest = &validationChecks
 )
 
 type ModelConfigUpdater environConfigUpdater
@@ -0,0 +1,166 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades
+
+import (
+	"github.com/juju/collections/set"
+	"github.com/juju/errors"
+	"github.com/juju/version"
+)
+
+// FeatureStep is implemented by upgrade steps that are gated behind a
+// controller feature flag, so that risky data migrations can ship dark
+// and be enabled on each controller once they have been validated.
+// A gated step is skipped while its flag is not set, and recorded so
+// that it is run once the flag is enabled.
+type FeatureStep interface {
+	Step
+
+	// FeatureFlag returns the controller feature flag that must
+	// be set for the step to run. If it is empty, the step is
+	// not gated.
+	FeatureFlag() string
+}
+
+// GatedStep describes an upgrade step that was skipped because the
+// controller feature flag gating it was not set.
+type GatedStep struct {
+	// Description is the description of the step.
+	Description string
+
+	// FeatureFlag is the feature flag gating the step.
+	FeatureFlag string
+
+	// Enabled is true if the feature flag is now set,
+	// so that the step is ready to run.
+	Enabled bool
+}
+
+// GatedSteps returns the upgrade steps that were skipped because their
+// feature flags were not set, and have not run since, reporting whether
+// each is now enabled.
+// Backend retrieval is lazy, as it requires a real state pool.
+func GatedSteps(backend func() StateBackend) ([]GatedStep, error) {
+	st := backend()
+	recorded, err := st.GatedUpgradeSteps()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(recorded) == 0 {
+		return nil, nil
+	}
+	cfg, err := st.ControllerConfig()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	features := cfg.Features()
+	result := make([]GatedStep, len(recorded))
+	for i, step := range recorded {
+		result[i] = GatedStep{
+			Description: step.Description,
+			FeatureFlag: step.FeatureFlag,
+			Enabled:     features.Contains(step.FeatureFlag),
+		}
+	}
+	return result, nil
+}
+
+// RunGatedSteps runs, in upgrade order, the skipped upgrade steps whose
+// feature flags are now set. Each is recorded as no longer gated once
+// it has run. As with other upgrade steps, they are run on the primary
+// controller, and the first to fail aborts the rest.
+// Context retrieval is lazy, as it requires a real state pool.
+// If observer is not nil, it is notified of each step before it is run.
+func RunGatedSteps(contextGetter func() Context, observer StepObserver) error {
+	context := contextGetter().StateContext()
+	gated, err := GatedSteps(context.State)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	enabled := make(map[string]bool)
+	for _, step := range gated {
+		if step.Enabled {
+			enabled[step.Description] = true
+		}
+	}
+	if len(enabled) == 0 {
+		return nil
+	}
+
+	targets := []Target{DatabaseMaster}
+	ops := newStateUpgradeOpsIterator(version.Zero)
+	for ops.Next() {
+		for _, step := range ops.Get().Steps() {
+			if !enabled[step.Description()] || !targetsMatch(targets, step.Targets()) {
+				continue
+			}
+			if err := runStep(context, step, observer); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+// skippedGatedSteps returns the descriptions of the steps gated behind
+// feature flags that were skipped, and have not run since. The database
+// is only consulted if any of the steps for the targets are gated.
+func skippedGatedSteps(context Context, ops *opsIterator, targets []Target) (set.Strings, error) {
+	result := set.NewStrings()
+	anyGated := false
+	for ops.Next() {
+		for _, step := range ops.Get().Steps() {
+			if featureFlag(step) != "" && targetsMatch(targets, step.Targets()) {
+				anyGated = true
+			}
+		}
+	}
+	if !anyGated {
+		return result, nil
+	}
+	recorded, err := context.State().GatedUpgradeSteps()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, step := range recorded {
+		result.Add(step.Description)
+	}
+	return result, nil
+}
+
+// featureFlag returns the feature flag gating the step,
+// or an empty string if it is not gated.
+func featureFlag(step Step) string {
+	if fs, ok := step.(FeatureStep); ok {
+		return fs.FeatureFlag()
+	}
+	return ""
+}
+
+// skipGatedStep returns true if the step is gated behind a feature
+// flag that is not set, having recorded that it was skipped.
+func skipGatedStep(context Context, step Step) (bool, error) {
+	flag := featureFlag(step)
+	if flag == "" {
+		return false, nil
+	}
+	cfg, err := context.State().ControllerConfig()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	if cfg.Features().Contains(flag) {
+		return false, nil
+	}
+	logger.Infof("skipping upgrade step %q until feature flag %q is enabled", step.Description(), flag)
+	return true, errors.Trace(context.State().AddGatedUpgradeStep(step.Description(), flag))
+}
+
+// clearGatedStep records that the step, if it is gated
+// behind a feature flag, is no longer awaiting the flag.
+func clearGatedStep(context Context, step Step) error {
+	if featureFlag(step) == "" {
+		return nil
+	}
+	return errors.Trace(context.State().RemoveGatedUpgradeStep(step.Description()))
+}
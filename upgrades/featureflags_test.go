@@ -0,0 +1,172 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades_test
+
+import (
+	"github.com/juju/collections/set"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/version"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/controller"
+	"github.com/juju/juju/state"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/upgrades"
+	jujuversion "github.com/juju/juju/version"
+)
+
+type featureFlagsSuite struct {
+	coretesting.BaseSuite
+}
+
+var _ = gc.Suite(&featureFlagsSuite{})
+
+type featureStep struct {
+	*mockUpgradeStep
+	flag string
+}
+
+func (s *featureStep) FeatureFlag() string {
+	return s.flag
+}
+
+func (s *featureFlagsSuite) patchFeatureSteps() {
+	s.PatchValue(&jujuversion.Current, version.MustParse("1.22.0"))
+	s.PatchValue(upgrades.StateUpgradeOperations, func() []upgrades.Operation {
+		return []upgrades.Operation{&mockUpgradeOperation{
+			targetVersion: version.MustParse("1.20.0"),
+			steps: []upgrades.Step{
+				&featureStep{
+					mockUpgradeStep: newUpgradeStep("split unit docs", upgrades.DatabaseMaster),
+					flag:            "unit-split",
+				},
+				newUpgradeStep("plain step", upgrades.DatabaseMaster),
+			},
+		}, &mockUpgradeOperation{
+			targetVersion: version.MustParse("1.22.0"),
+			steps: []upgrades.Step{
+				&featureStep{
+					mockUpgradeStep: newUpgradeStep("migrate leases", upgrades.DatabaseMaster),
+					flag:            "raft-leases",
+				},
+			},
+		}}
+	})
+}
+
+func (s *featureFlagsSuite) TestUpgradeSkipsGatedSteps(c *gc.C) {
+	s.patchFeatureSteps()
+	st := &featureStateBackend{features: set.NewStrings("raft-leases")}
+	ctx := &mockContext{state: st}
+
+	err := upgrades.PerformStateUpgrade(version.MustParse("1.18.0"), targets(upgrades.DatabaseMaster), ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ctx.messages, jc.DeepEquals, []string{"plain step", "migrate leases"})
+	st.CheckCalls(c, []testing.StubCall{
+		{"ControllerConfig", nil},
+		{"AddGatedUpgradeStep", []interface{}{"split unit docs", "unit-split"}},
+		{"ControllerConfig", nil},
+		{"RemoveGatedUpgradeStep", []interface{}{"migrate leases"}},
+	})
+}
+
+func (s *featureFlagsSuite) TestGatedSteps(c *gc.C) {
+	st := &featureStateBackend{
+		features: set.NewStrings("raft-leases"),
+		gated: []state.GatedUpgradeStep{
+			{Description: "migrate leases", FeatureFlag: "raft-leases"},
+			{Description: "split unit docs", FeatureFlag: "unit-split"},
+		},
+	}
+	gated, err := upgrades.GatedSteps(func() upgrades.StateBackend { return st })
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(gated, jc.DeepEquals, []upgrades.GatedStep{
+		{Description: "migrate leases", FeatureFlag: "raft-leases", Enabled: true},
+		{Description: "split unit docs", FeatureFlag: "unit-split"},
+	})
+}
+
+func (s *featureFlagsSuite) TestGatedStepsNone(c *gc.C) {
+	st := &featureStateBackend{}
+	gated, err := upgrades.GatedSteps(func() upgrades.StateBackend { return st })
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(gated, gc.HasLen, 0)
+	st.CheckCallNames(c, "GatedUpgradeSteps")
+}
+
+func (s *featureFlagsSuite) TestRunGatedStepsOnceEnabled(c *gc.C) {
+	s.patchFeatureSteps()
+	st := &featureStateBackend{
+		features: set.NewStrings("unit-split"),
+		gated: []state.GatedUpgradeStep{
+			{Description: "migrate leases", FeatureFlag: "raft-leases"},
+			{Description: "split unit docs", FeatureFlag: "unit-split"},
+		},
+	}
+	ctx := &mockContext{state: st}
+
+	var observed []string
+	err := upgrades.RunGatedSteps(func() upgrades.Context { return ctx }, func(description string) error {
+		observed = append(observed, description)
+		return nil
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ctx.messages, jc.DeepEquals, []string{"split unit docs"})
+	c.Assert(observed, jc.DeepEquals, []string{"split unit docs"})
+	st.CheckCalls(c, []testing.StubCall{
+		{"GatedUpgradeSteps", nil},
+		{"ControllerConfig", nil},
+		{"RemoveGatedUpgradeStep", []interface{}{"split unit docs"}},
+	})
+}
+
+func (s *featureFlagsSuite) TestRunGatedStepsNoneEnabled(c *gc.C) {
+	s.patchFeatureSteps()
+	st := &featureStateBackend{
+		gated: []state.GatedUpgradeStep{
+			{Description: "split unit docs", FeatureFlag: "unit-split"},
+		},
+	}
+	ctx := &mockContext{state: st}
+
+	err := upgrades.RunGatedSteps(func() upgrades.Context { return ctx }, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ctx.messages, gc.HasLen, 0)
+	st.CheckCallNames(c, "GatedUpgradeSteps", "ControllerConfig")
+}
+
+type featureStateBackend struct {
+	mockStateBackend
+	features set.Strings
+	gated    []state.GatedUpgradeStep
+}
+
+func (st *featureStateBackend) ControllerConfig() (controller.Config, error) {
+	st.MethodCall(st, "ControllerConfig")
+	cfg := controller.Config{}
+	var features []interface{}
+	for _, f := range st.features.SortedValues() {
+		features = append(features, f)
+	}
+	if len(features) > 0 {
+		cfg[controller.Features] = features
+	}
+	return cfg, st.NextErr()
+}
+
+func (st *featureStateBackend) GatedUpgradeSteps() ([]state.GatedUpgradeStep, error) {
+	st.MethodCall(st, "GatedUpgradeSteps")
+	return st.gated, st.NextErr()
+}
+
+func (st *featureStateBackend) AddGatedUpgradeStep(step, featureFlag string) error {
+	st.MethodCall(st, "AddGatedUpgradeStep", step, featureFlag)
+	return st.NextErr()
+}
+
+func (st *featureStateBackend) RemoveGatedUpgradeStep(step string) error {
+	st.MethodCall(st, "RemoveGatedUpgradeStep", step)
+	return st.NextErr()
+}
@@ -0,0 +1,21 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/state"
+)
+
+// EnsureIndexes creates the indexes declared by the running version for
+// each collection that are missing from the database, and reports the
+// indexes that are not declared, so that they can be removed. It is run
+// as a phase of each database upgrade, so that slow queries after an
+// upgrade are not caused by indexes the new version relies on.
+// Backend retrieval is lazy, as it requires a real state pool.
+func EnsureIndexes(backend func() StateBackend) ([]state.CollectionIndexes, error) {
+	indexes, err := backend().EnsureIndexes()
+	return indexes, errors.Trace(err)
+}
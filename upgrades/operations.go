@@ -68,8 +68,11 @@ type opsIterator struct {
 	current int
 }
 
+// newStateUpgradeOpsIterator returns an iterator over the state upgrade
+// operations in the order in which their steps are run: the steps of
+// each stage of the upgrade in turn.
 func newStateUpgradeOpsIterator(from version.Number) *opsIterator {
-	return newOpsIterator(from, jujuversion.Current, stateUpgradeOperations())
+	return newStagedStateUpgradeOpsIterator(from, stages...)
 }
 
 func newUpgradeOpsIterator(from version.Number) *opsIterator {
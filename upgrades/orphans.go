@@ -0,0 +1,26 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/state"
+)
+
+// SweepOrphans reports the documents left behind by models, units and
+// machines that no longer exist, which would otherwise only be found by
+// support scripts run against the database. The documents are removed
+// if upgrade-remove-orphans is set in the controller config. It is run
+// as the final phase of each database upgrade.
+// Backend retrieval is lazy, as it requires a real state pool.
+func SweepOrphans(backend func() StateBackend) ([]state.OrphanReport, error) {
+	st := backend()
+	cfg, err := st.ControllerConfig()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	reports, err := st.SweepOrphans(cfg.UpgradeRemoveOrphans())
+	return reports, errors.Trace(err)
+}
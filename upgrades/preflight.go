@@ -0,0 +1,147 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/juju/errors"
+	"github.com/juju/version"
+
+	"github.com/juju/juju/mongo"
+)
+
+// Requirements describes the host resources that an upgrade step
+// needs to be available before it can be safely run.
+type Requirements struct {
+	// DiskMiB is the free disk space (in MiB) required
+	// on the volume holding the Mongo database.
+	DiskMiB uint64
+
+	// MemoryMiB is the available memory (in MiB) required.
+	MemoryMiB uint64
+}
+
+// RequirementsStep is implemented by upgrade steps that declare
+// the resources they need in order to run.
+type RequirementsStep interface {
+	Step
+
+	// Requirements returns the resources needed by the step.
+	Requirements() Requirements
+}
+
+// stepRequirements returns the resources declared by the input step,
+// or zero requirements if the step declares none.
+func stepRequirements(step Step) Requirements {
+	if rs, ok := step.(RequirementsStep); ok {
+		return rs.Requirements()
+	}
+	return Requirements{}
+}
+
+// StateUpgradeRequirements returns the resources needed to run all of the
+// state upgrade steps from the input version for the input targets.
+// Steps are run in sequence, so the requirement is the largest
+// declared by any single step.
+func StateUpgradeRequirements(from version.Number, targets []Target) Requirements {
+	return upgradeRequirements(newStateUpgradeOpsIterator(from), targets)
+}
+
+func upgradeRequirements(ops *opsIterator, targets []Target) Requirements {
+	var req Requirements
+	for ops.Next() {
+		for _, step := range ops.Get().Steps() {
+			if !targetsMatch(targets, step.Targets()) {
+				continue
+			}
+			stepReq := stepRequirements(step)
+			if stepReq.DiskMiB > req.DiskMiB {
+				req.DiskMiB = stepReq.DiskMiB
+			}
+			if stepReq.MemoryMiB > req.MemoryMiB {
+				req.MemoryMiB = stepReq.MemoryMiB
+			}
+		}
+	}
+	return req
+}
+
+// PreflightStateUpgrade checks that the host has enough free disk space on
+// the Mongo database volume and enough available memory to run the state
+// upgrade steps from the input version for the input targets.
+// A descriptive error is returned if either resource is insufficient.
+func PreflightStateUpgrade(from version.Number, targets []Target, dataDir string) error {
+	req := StateUpgradeRequirements(from, targets)
+
+	dbDir := mongo.DbDir(dataDir)
+	if _, err := os.Stat(dbDir); err != nil {
+		// Mongo may not be co-located with the agent (CAAS), in which
+		// case the best we can do is to check the data directory.
+		dbDir = dataDir
+	}
+	if req.DiskMiB > 0 {
+		if err := CheckFreeDiskSpace(dbDir, req.DiskMiB); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if req.MemoryMiB > 0 {
+		if err := CheckAvailableMemory(req.MemoryMiB); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// CheckAvailableMemory returns a helpful error if there
+// isn't at least thresholdMib MiB of memory available.
+func CheckAvailableMemory(thresholdMib uint64) error {
+	available, err := availableMemory()
+	if err != nil {
+		return errors.Annotate(err, "determining available memory")
+	}
+	if available < thresholdMib*humanize.MiByte {
+		return errors.Errorf("not enough available memory for upgrade: %s available, require %dMiB",
+			humanize.IBytes(available), thresholdMib)
+	}
+	return nil
+}
+
+// availableMemory returns the memory (in bytes) that is available
+// for starting new applications without swapping.
+// It is a variable so that it can be replaced in tests.
+var availableMemory = func() (uint64, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer func() { _ = f.Close() }()
+	return parseMemAvailable(f)
+}
+
+// parseMemAvailable reads the MemAvailable entry, in bytes,
+// from the input /proc/meminfo formatted content.
+func parseMemAvailable(r io.Reader) (uint64, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemAvailable:" {
+			continue
+		}
+		kib, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, errors.Annotatef(err, "parsing %q", scanner.Text())
+		}
+		return kib * humanize.KiByte, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, errors.Trace(err)
+	}
+	return 0, errors.NotFoundf("MemAvailable in /proc/meminfo")
+}
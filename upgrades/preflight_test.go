@@ -0,0 +1,116 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades_test
+
+import (
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/version"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/testing"
+	"github.com/juju/juju/upgrades"
+)
+
+type preflightSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&preflightSuite{})
+
+type requirementsStep struct {
+	*mockUpgradeStep
+	req upgrades.Requirements
+}
+
+func (s *requirementsStep) Requirements() upgrades.Requirements {
+	return s.req
+}
+
+func (s *preflightSuite) patchOperations(disk, memory uint64) {
+	steps := []upgrades.Step{
+		&requirementsStep{
+			mockUpgradeStep: newUpgradeStep("big step", upgrades.DatabaseMaster),
+			req:             upgrades.Requirements{DiskMiB: disk, MemoryMiB: 1},
+		},
+		&requirementsStep{
+			mockUpgradeStep: newUpgradeStep("hungry step", upgrades.DatabaseMaster),
+			req:             upgrades.Requirements{DiskMiB: 1, MemoryMiB: memory},
+		},
+		&requirementsStep{
+			mockUpgradeStep: newUpgradeStep("host step", upgrades.HostMachine),
+			req:             upgrades.Requirements{DiskMiB: disk * 2, MemoryMiB: memory * 2},
+		},
+		newUpgradeStep("plain step", upgrades.DatabaseMaster),
+	}
+	s.PatchValue(upgrades.StateUpgradeOperations, func() []upgrades.Operation {
+		return []upgrades.Operation{&mockUpgradeOperation{
+			targetVersion: version.MustParse("1.20.0"),
+			steps:         steps,
+		}}
+	})
+}
+
+func (s *preflightSuite) TestStateUpgradeRequirements(c *gc.C) {
+	s.patchOperations(100, 200)
+
+	req := upgrades.StateUpgradeRequirements(version.MustParse("1.18.0"), []upgrades.Target{upgrades.DatabaseMaster})
+	c.Check(req, gc.Equals, upgrades.Requirements{DiskMiB: 100, MemoryMiB: 200})
+
+	// Steps for versions already upgraded to are not considered.
+	req = upgrades.StateUpgradeRequirements(version.MustParse("1.20.0"), []upgrades.Target{upgrades.DatabaseMaster})
+	c.Check(req, gc.Equals, upgrades.Requirements{})
+}
+
+func (s *preflightSuite) TestPreflightStateUpgradeSuccess(c *gc.C) {
+	s.patchOperations(1, 1)
+	s.PatchValue(upgrades.AvailableMemory, func() (uint64, error) { return humanize.GiByte, nil })
+
+	err := upgrades.PreflightStateUpgrade(version.MustParse("1.18.0"), []upgrades.Target{upgrades.DatabaseMaster}, c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *preflightSuite) TestPreflightStateUpgradeInsufficientDisk(c *gc.C) {
+	// Expect an impossibly large amount of free disk.
+	s.patchOperations(uint64(humanize.PiByte/humanize.MiByte), 1)
+	s.PatchValue(upgrades.AvailableMemory, func() (uint64, error) { return humanize.GiByte, nil })
+
+	dir := c.MkDir()
+	err := upgrades.PreflightStateUpgrade(version.MustParse("1.18.0"), []upgrades.Target{upgrades.DatabaseMaster}, dir)
+	c.Assert(err, gc.ErrorMatches, `not enough free disk space on ".*" for upgrade: .* available, require 1073741824MiB`)
+}
+
+func (s *preflightSuite) TestPreflightStateUpgradeInsufficientMemory(c *gc.C) {
+	s.patchOperations(1, 2048)
+	s.PatchValue(upgrades.AvailableMemory, func() (uint64, error) { return humanize.GiByte, nil })
+
+	err := upgrades.PreflightStateUpgrade(version.MustParse("1.18.0"), []upgrades.Target{upgrades.DatabaseMaster}, c.MkDir())
+	c.Assert(err, gc.ErrorMatches, `not enough available memory for upgrade: 1.0 GiB available, require 2048MiB`)
+}
+
+func (s *preflightSuite) TestPreflightStateUpgradeMemoryError(c *gc.C) {
+	s.patchOperations(1, 1)
+	s.PatchValue(upgrades.AvailableMemory, func() (uint64, error) { return 0, errors.New("boom") })
+
+	err := upgrades.PreflightStateUpgrade(version.MustParse("1.18.0"), []upgrades.Target{upgrades.DatabaseMaster}, c.MkDir())
+	c.Assert(err, gc.ErrorMatches, `determining available memory: boom`)
+}
+
+func (s *preflightSuite) TestParseMemAvailable(c *gc.C) {
+	meminfo := `
+MemTotal:       16302520 kB
+MemFree:         1035972 kB
+MemAvailable:    8151260 kB
+Buffers:          523640 kB
+`[1:]
+	available, err := upgrades.ParseMemAvailable(strings.NewReader(meminfo))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(available, gc.Equals, uint64(8151260*humanize.KiByte))
+
+	_, err = upgrades.ParseMemAvailable(strings.NewReader("MemTotal: 16302520 kB\n"))
+	c.Check(err, jc.Satisfies, errors.IsNotFound)
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/juju/collections/set"
+	"github.com/juju/errors"
+	"github.com/juju/version"
+)
+
+// ReversibleStep is implemented by upgrade steps that declare whether
+// they can be safely re-run and undone, so that an upgrade cancelled by
+// an operator can be rolled back.
+type ReversibleStep interface {
+	Step
+
+	// Idempotent returns true if the step, and its reverse, can be
+	// safely run more than once, including after only partially
+	// completing.
+	Idempotent() bool
+
+	// Reversible returns true if the changes made by
+	// the step can be undone by calling Reverse.
+	Reversible() bool
+
+	// Reverse undoes the changes made by Run.
+	Reverse(Context) error
+}
+
+// RollbackBlockedError is returned when an upgrade cannot be rolled back,
+// because steps that have been run cannot be safely undone.
+type RollbackBlockedError struct {
+	// Steps holds the descriptions of the steps
+	// that prevent the upgrade being rolled back.
+	Steps []string
+}
+
+// Error is part of the error interface.
+func (e *RollbackBlockedError) Error() string {
+	steps := make([]string, len(e.Steps))
+	for i, step := range e.Steps {
+		steps[i] = fmt.Sprintf("%q", step)
+	}
+	return fmt.Sprintf("rollback blocked by irreversible upgrade steps: %s", strings.Join(steps, ", "))
+}
+
+// IsRollbackBlocked returns true if the cause
+// of the input error is a RollbackBlockedError.
+func IsRollbackBlocked(err error) bool {
+	_, ok := errors.Cause(err).(*RollbackBlockedError)
+	return ok
+}
+
+// RollbackStateUpgrade undoes the state upgrade steps that were run from
+// the input version for the input targets, in the reverse of the order
+// they were run in. The input stage is the one that was being run when
+// the upgrade stopped, so the steps of later stages were not run. The
+// input error is the one returned from the upgrade; if it identifies a
+// failed step, only the steps up to and including that one are
+// considered to have been run. If it identifies the step before which
+// the upgrade was stopped, only the steps before that one are.
+//
+// Nothing is undone, and a RollbackBlockedError is returned, if any
+// of the steps that were run are not reversible, or if the failed step
+// is not idempotent, in which case it is not safe to reverse it from
+// a partially completed state.
+func RollbackStateUpgrade(from version.Number, targets []Target, stage Stage, upgradeErr error, context Context) error {
+	var (
+		lastStep string
+		lastRun  bool
+	)
+	if ue, ok := errors.Cause(upgradeErr).(*upgradeError); ok {
+		lastStep, lastRun = ue.description, !ue.notRun
+	}
+	gated, err := skippedGatedSteps(context.StateContext(), newStateUpgradeOpsIterator(from), targets)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	ops := newStagedStateUpgradeOpsIterator(from, stagesTo(stage)...)
+	steps, err := rollbackPlan(ops, targets, lastStep, lastRun, gated)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, step := range steps {
+		logger.Infof("reversing upgrade step: %v", step.Description())
+		if err := step.Reverse(context.StateContext()); err != nil {
+			logger.Errorf("reversing upgrade step %q failed: %v", step.Description(), err)
+			return &upgradeError{
+				description: "reversing " + step.Description(),
+				err:         err,
+			}
+		}
+	}
+	logger.Infof("all upgrade steps reversed successfully")
+	return nil
+}
+
+// rollbackPlan returns the steps to be reversed, in the order they are to
+// be reversed, in order to undo the steps that were run up to the step
+// with the input description. That step is included if lastRun is true,
+// in which case it failed. If the description is empty, all of the steps
+// are considered to have been run. The gated steps, which were skipped
+// because their feature flags were not set, and the deferred contract
+// steps are not reversed.
+func rollbackPlan(ops *opsIterator, targets []Target, lastStep string, lastRun bool, gated set.Strings) ([]ReversibleStep, error) {
+	var (
+		steps    []ReversibleStep
+		blocking []string
+	)
+	for ops.Next() {
+		for _, step := range ops.Get().Steps() {
+			if !targetsMatch(targets, step.Targets()) || gated.Contains(step.Description()) ||
+				stepPhase(step) == PhaseContract {
+				continue
+			}
+			if step.Description() == lastStep && !lastRun {
+				return rollbackResult(steps, blocking)
+			}
+			failed := step.Description() == lastStep
+			rs, ok := step.(ReversibleStep)
+			switch {
+			case !ok || !rs.Reversible():
+				blocking = append(blocking, step.Description())
+			case failed && !rs.Idempotent():
+				blocking = append(blocking, step.Description())
+			default:
+				steps = append([]ReversibleStep{rs}, steps...)
+			}
+			if failed {
+				return rollbackResult(steps, blocking)
+			}
+		}
+	}
+	return rollbackResult(steps, blocking)
+}
+
+func rollbackResult(steps []ReversibleStep, blocking []string) ([]ReversibleStep, error) {
+	if len(blocking) > 0 {
+		return nil, &RollbackBlockedError{Steps: blocking}
+	}
+	return steps, nil
+}
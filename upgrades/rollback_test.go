@@ -0,0 +1,166 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/version"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing"
+	"github.com/juju/juju/upgrades"
+)
+
+type rollbackSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&rollbackSuite{})
+
+type reversibleStep struct {
+	*mockUpgradeStep
+	idempotent bool
+	reversible bool
+}
+
+func (s *reversibleStep) Idempotent() bool {
+	return s.idempotent
+}
+
+func (s *reversibleStep) Reversible() bool {
+	return s.reversible
+}
+
+func (s *reversibleStep) Reverse(ctx upgrades.Context) error {
+	context := ctx.(*mockContext)
+	context.messages = append(context.messages, "reverse "+s.msg)
+	return nil
+}
+
+func newReversibleStep(msg string, idempotent, reversible bool) *reversibleStep {
+	return &reversibleStep{
+		mockUpgradeStep: newUpgradeStep(msg, upgrades.DatabaseMaster),
+		idempotent:      idempotent,
+		reversible:      reversible,
+	}
+}
+
+func (s *rollbackSuite) patchOperations(steps ...upgrades.Step) {
+	s.PatchValue(upgrades.StateUpgradeOperations, func() []upgrades.Operation {
+		return []upgrades.Operation{&mockUpgradeOperation{
+			targetVersion: version.MustParse("1.20.0"),
+			steps:         steps,
+		}}
+	})
+}
+
+func (s *rollbackSuite) rollback(upgradeErr error) (*mockContext, error) {
+	ctx := &mockContext{}
+	err := upgrades.RollbackStateUpgrade(
+		version.MustParse("1.18.0"), []upgrades.Target{upgrades.DatabaseMaster}, upgrades.StageBackfill, upgradeErr, ctx)
+	return ctx, err
+}
+
+func (s *rollbackSuite) TestRollbackAllStepsInReverse(c *gc.C) {
+	s.patchOperations(
+		newReversibleStep("step 1", false, true),
+		newUpgradeStep("host step", upgrades.HostMachine),
+		newReversibleStep("step 2", true, true),
+	)
+
+	ctx, err := s.rollback(errors.New("cancelled"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ctx.messages, jc.DeepEquals, []string{"reverse step 2", "reverse step 1"})
+}
+
+func (s *rollbackSuite) TestRollbackStopsAtFailedStep(c *gc.C) {
+	s.patchOperations(
+		newReversibleStep("step 1", false, true),
+		newReversibleStep("step 2 error", true, true),
+		newUpgradeStep("irreversible step", upgrades.DatabaseMaster),
+	)
+	ctx := &mockContext{}
+	targets := []upgrades.Target{upgrades.DatabaseMaster}
+	upgradeErr := upgrades.PerformStateUpgrade(version.MustParse("1.18.0"), targets, ctx)
+	c.Assert(upgradeErr, gc.ErrorMatches, "step 2 error: upgrade error occurred")
+
+	ctx, err := s.rollback(upgradeErr)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ctx.messages, jc.DeepEquals, []string{"reverse step 2 error", "reverse step 1"})
+}
+
+func (s *rollbackSuite) TestRollbackStoppedUpgrade(c *gc.C) {
+	s.patchOperations(
+		newReversibleStep("step 1", false, true),
+		newReversibleStep("step 2", false, true),
+		newUpgradeStep("irreversible step", upgrades.DatabaseMaster),
+	)
+	ctx := &mockContext{}
+	targets := []upgrades.Target{upgrades.DatabaseMaster}
+	observer := func(description string) error {
+		if description == "step 2" {
+			return errors.New("upgrade aborted")
+		}
+		return nil
+	}
+	upgradeErr := upgrades.PerformObservedStateUpgrade(version.MustParse("1.18.0"), targets, ctx, observer)
+	c.Assert(upgradeErr, gc.ErrorMatches, "stopped before step 2: upgrade aborted")
+	c.Assert(ctx.messages, jc.DeepEquals, []string{"step 1"})
+
+	// The step the upgrade stopped before was not run, so is not
+	// reversed, and need not be idempotent.
+	ctx, err := s.rollback(upgradeErr)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ctx.messages, jc.DeepEquals, []string{"reverse step 1"})
+}
+
+func (s *rollbackSuite) TestRollbackBlockedByIrreversibleSteps(c *gc.C) {
+	s.patchOperations(
+		newReversibleStep("step 1", true, true),
+		newUpgradeStep("plain step", upgrades.DatabaseMaster),
+		newReversibleStep("one way step", true, false),
+	)
+
+	ctx, err := s.rollback(nil)
+	c.Assert(err, gc.ErrorMatches, `rollback blocked by irreversible upgrade steps: "plain step", "one way step"`)
+	c.Assert(err, jc.Satisfies, upgrades.IsRollbackBlocked)
+	c.Assert(err.(*upgrades.RollbackBlockedError).Steps, jc.DeepEquals, []string{"plain step", "one way step"})
+	c.Assert(ctx.messages, gc.HasLen, 0)
+}
+
+func (s *rollbackSuite) TestRollbackBlockedByNonIdempotentFailedStep(c *gc.C) {
+	s.patchOperations(
+		newReversibleStep("step 1", true, true),
+		newReversibleStep("step 2 error", false, true),
+	)
+	ctx := &mockContext{}
+	targets := []upgrades.Target{upgrades.DatabaseMaster}
+	upgradeErr := upgrades.PerformStateUpgrade(version.MustParse("1.18.0"), targets, ctx)
+	c.Assert(upgradeErr, gc.NotNil)
+
+	ctx, err := s.rollback(upgradeErr)
+	c.Assert(err, gc.ErrorMatches, `rollback blocked by irreversible upgrade steps: "step 2 error"`)
+	c.Assert(ctx.messages, gc.HasLen, 0)
+}
+
+func (s *rollbackSuite) TestRollbackSkipsGatedSteps(c *gc.C) {
+	s.patchOperations(
+		newReversibleStep("step 1", true, true),
+		&featureStep{
+			mockUpgradeStep: newUpgradeStep("split unit docs", upgrades.DatabaseMaster),
+			flag:            "unit-split",
+		},
+		newReversibleStep("step 2", true, true),
+	)
+	st := &featureStateBackend{
+		gated: []state.GatedUpgradeStep{{Description: "split unit docs", FeatureFlag: "unit-split"}},
+	}
+	ctx := &mockContext{state: st}
+	err := upgrades.RollbackStateUpgrade(
+		version.MustParse("1.18.0"), []upgrades.Target{upgrades.DatabaseMaster}, upgrades.StageBackfill, nil, ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ctx.messages, jc.DeepEquals, []string{"reverse step 2", "reverse step 1"})
+}
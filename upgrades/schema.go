@@ -0,0 +1,146 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades
+
+import (
+	"sort"
+
+	"github.com/juju/errors"
+	"github.com/juju/version"
+)
+
+// SchemaSampleSize is the number of documents in each
+// collection that are checked for schema drift.
+const SchemaSampleSize = 100
+
+// SchemaVersion describes the shape of the documents in a
+// collection once an upgrade step has migrated them.
+type SchemaVersion struct {
+	// Collection is the name of the migrated collection.
+	Collection string
+
+	// Version is the schema version of the documents after the step
+	// has run. Versions increase with each step that changes the
+	// shape of the collection's documents.
+	Version int
+
+	// Fields are the fields that every document in the
+	// collection has at this version.
+	Fields []string
+}
+
+// SchemaStep is implemented by upgrade steps that change the
+// shape of the documents in one or more collections.
+type SchemaStep interface {
+	Step
+
+	// SchemaVersions returns the versions of the
+	// collections migrated by the step.
+	SchemaVersions() []SchemaVersion
+}
+
+// SchemaDrift describes a collection with documents that do not have
+// the shape expected by the running version of Juju, most likely
+// because they were missed by a past migration.
+type SchemaDrift struct {
+	// Collection is the name of the collection.
+	Collection string
+
+	// ExpectedVersion is the version declared by the last
+	// upgrade step to migrate the collection.
+	ExpectedVersion int
+
+	// RecordedVersion is the version recorded in the
+	// database by the last migration that was run.
+	RecordedVersion int
+
+	// MissingFields holds the expected fields missing from each
+	// of the sampled documents, keyed by document ID.
+	MissingFields map[string][]string
+}
+
+// ExpectedSchemaVersions returns the latest version declared by the
+// state upgrade steps for each collection they migrate, up to and
+// including the steps for the running version of Juju.
+func ExpectedSchemaVersions() []SchemaVersion {
+	return expectedSchemaVersions(newStateUpgradeOpsIterator(version.Zero))
+}
+
+func expectedSchemaVersions(ops *opsIterator) []SchemaVersion {
+	latest := make(map[string]SchemaVersion)
+	for ops.Next() {
+		for _, step := range ops.Get().Steps() {
+			ss, ok := step.(SchemaStep)
+			if !ok {
+				continue
+			}
+			for _, sv := range ss.SchemaVersions() {
+				if sv.Version >= latest[sv.Collection].Version {
+					latest[sv.Collection] = sv
+				}
+			}
+		}
+	}
+	result := make([]SchemaVersion, 0, len(latest))
+	for _, sv := range latest {
+		result = append(result, sv)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Collection < result[j].Collection
+	})
+	return result
+}
+
+// recordSchemaVersions records the versions of the collections
+// migrated by the step, if it declares any.
+func recordSchemaVersions(context Context, step Step) error {
+	ss, ok := step.(SchemaStep)
+	if !ok {
+		return nil
+	}
+	for _, sv := range ss.SchemaVersions() {
+		if err := context.State().SetSchemaVersion(sv.Collection, sv.Version, step.Description()); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// CheckSchemaDrift compares the schema version recorded for each
+// collection migrated by the state upgrade steps with the version
+// expected by the running version of Juju, and checks a sample of each
+// collection's documents for the expected fields. It returns the
+// collections that have drifted from their expected shape.
+// Collections with no recorded version were last migrated before
+// versions were recorded, so only their documents are checked.
+// Backend retrieval is lazy, as it requires a real state pool.
+func CheckSchemaDrift(backend func() StateBackend) ([]SchemaDrift, error) {
+	expected := ExpectedSchemaVersions()
+	if len(expected) == 0 {
+		return nil, nil
+	}
+	st := backend()
+	recorded, err := st.SchemaVersions()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var result []SchemaDrift
+	for _, sv := range expected {
+		missing, err := st.SampleMissingFields(sv.Collection, sv.Fields, SchemaSampleSize)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		recordedVersion, ok := recorded[sv.Collection]
+		if len(missing) == 0 && (!ok || recordedVersion >= sv.Version) {
+			continue
+		}
+		result = append(result, SchemaDrift{
+			Collection:      sv.Collection,
+			ExpectedVersion: sv.Version,
+			RecordedVersion: recordedVersion,
+			MissingFields:   missing,
+		})
+	}
+	return result, nil
+}
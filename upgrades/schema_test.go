@@ -0,0 +1,170 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades_test
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/version"
+	gc "gopkg.in/check.v1"
+
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/upgrades"
+	jujuversion "github.com/juju/juju/version"
+)
+
+type schemaSuite struct {
+	coretesting.BaseSuite
+}
+
+var _ = gc.Suite(&schemaSuite{})
+
+type schemaStep struct {
+	*mockUpgradeStep
+	schema []upgrades.SchemaVersion
+}
+
+func (s *schemaStep) SchemaVersions() []upgrades.SchemaVersion {
+	return s.schema
+}
+
+func (s *schemaSuite) patchSchemaSteps() {
+	s.PatchValue(&jujuversion.Current, version.MustParse("1.22.0"))
+	s.PatchValue(upgrades.StateUpgradeOperations, func() []upgrades.Operation {
+		return []upgrades.Operation{&mockUpgradeOperation{
+			targetVersion: version.MustParse("1.20.0"),
+			steps: []upgrades.Step{
+				&schemaStep{
+					mockUpgradeStep: newUpgradeStep("add unit field", upgrades.DatabaseMaster),
+					schema: []upgrades.SchemaVersion{{
+						Collection: "units", Version: 1, Fields: []string{"a"},
+					}, {
+						Collection: "machines", Version: 1, Fields: []string{"m"},
+					}},
+				},
+				newUpgradeStep("plain step", upgrades.DatabaseMaster),
+			},
+		}, &mockUpgradeOperation{
+			targetVersion: version.MustParse("1.22.0"),
+			steps: []upgrades.Step{
+				&schemaStep{
+					mockUpgradeStep: newUpgradeStep("add another unit field", upgrades.DatabaseMaster),
+					schema: []upgrades.SchemaVersion{{
+						Collection: "units", Version: 2, Fields: []string{"a", "b"},
+					}},
+				},
+			},
+		}}
+	})
+}
+
+func (s *schemaSuite) TestExpectedSchemaVersions(c *gc.C) {
+	s.patchSchemaSteps()
+	c.Assert(upgrades.ExpectedSchemaVersions(), jc.DeepEquals, []upgrades.SchemaVersion{{
+		Collection: "machines", Version: 1, Fields: []string{"m"},
+	}, {
+		Collection: "units", Version: 2, Fields: []string{"a", "b"},
+	}})
+}
+
+func (s *schemaSuite) TestUpgradeRecordsSchemaVersions(c *gc.C) {
+	s.patchSchemaSteps()
+	st := &schemaStateBackend{}
+	ctx := &mockContext{state: st}
+
+	err := upgrades.PerformStateUpgrade(version.MustParse("1.18.0"), targets(upgrades.DatabaseMaster), ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	st.CheckCalls(c, []testing.StubCall{
+		{"SetSchemaVersion", []interface{}{"units", 1, "add unit field"}},
+		{"SetSchemaVersion", []interface{}{"machines", 1, "add unit field"}},
+		{"SetSchemaVersion", []interface{}{"units", 2, "add another unit field"}},
+	})
+}
+
+func (s *schemaSuite) TestUpgradeRecordSchemaVersionError(c *gc.C) {
+	s.patchSchemaSteps()
+	st := &schemaStateBackend{}
+	st.SetErrors(errors.New("boom"))
+	ctx := &mockContext{state: st}
+
+	err := upgrades.PerformStateUpgrade(version.MustParse("1.18.0"), targets(upgrades.DatabaseMaster), ctx)
+	c.Assert(err, gc.ErrorMatches, "add unit field: boom")
+	st.CheckCallNames(c, "SetSchemaVersion")
+}
+
+func (s *schemaSuite) TestCheckSchemaDriftNone(c *gc.C) {
+	s.patchSchemaSteps()
+	st := &schemaStateBackend{
+		recorded: map[string]int{"units": 2, "machines": 1},
+	}
+	drift, err := upgrades.CheckSchemaDrift(func() upgrades.StateBackend { return st })
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(drift, gc.HasLen, 0)
+	st.CheckCalls(c, []testing.StubCall{
+		{"SchemaVersions", nil},
+		{"SampleMissingFields", []interface{}{"machines", []string{"m"}, upgrades.SchemaSampleSize}},
+		{"SampleMissingFields", []interface{}{"units", []string{"a", "b"}, upgrades.SchemaSampleSize}},
+	})
+}
+
+func (s *schemaSuite) TestCheckSchemaDrift(c *gc.C) {
+	s.patchSchemaSteps()
+	st := &schemaStateBackend{
+		// Machines predate the registry, so only their documents are checked.
+		recorded: map[string]int{"units": 1},
+		missing: map[string]map[string][]string{
+			"machines": {"uuid:0": {"m"}},
+		},
+	}
+	drift, err := upgrades.CheckSchemaDrift(func() upgrades.StateBackend { return st })
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(drift, jc.DeepEquals, []upgrades.SchemaDrift{{
+		Collection:      "machines",
+		ExpectedVersion: 1,
+		MissingFields:   map[string][]string{"uuid:0": {"m"}},
+	}, {
+		Collection:      "units",
+		ExpectedVersion: 2,
+		RecordedVersion: 1,
+	}})
+}
+
+func (s *schemaSuite) TestCheckSchemaDriftError(c *gc.C) {
+	s.patchSchemaSteps()
+	st := &schemaStateBackend{}
+	st.SetErrors(nil, errors.New("boom"))
+	_, err := upgrades.CheckSchemaDrift(func() upgrades.StateBackend { return st })
+	c.Assert(err, gc.ErrorMatches, "boom")
+}
+
+func (s *schemaSuite) TestStateStepsDeclareSchemaVersions(c *gc.C) {
+	s.PatchValue(&jujuversion.Current, version.MustParse("2.8.0"))
+	for _, sv := range upgrades.ExpectedSchemaVersions() {
+		c.Check(sv.Collection, gc.Not(gc.Equals), "")
+		c.Check(sv.Version > 0, jc.IsTrue, gc.Commentf("collection %q", sv.Collection))
+		c.Check(sv.Fields, gc.Not(gc.HasLen), 0, gc.Commentf("collection %q", sv.Collection))
+	}
+}
+
+type schemaStateBackend struct {
+	mockStateBackend
+	recorded map[string]int
+	missing  map[string]map[string][]string
+}
+
+func (st *schemaStateBackend) SchemaVersions() (map[string]int, error) {
+	st.MethodCall(st, "SchemaVersions")
+	return st.recorded, st.NextErr()
+}
+
+func (st *schemaStateBackend) SetSchemaVersion(collection string, version int, step string) error {
+	st.MethodCall(st, "SetSchemaVersion", collection, version, step)
+	return st.NextErr()
+}
+
+func (st *schemaStateBackend) SampleMissingFields(collection string, fields []string, size int) (map[string][]string, error) {
+	st.MethodCall(st, "SampleMissingFields", collection, fields, size)
+	return st.missing[collection], st.NextErr()
+}
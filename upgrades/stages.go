@@ -0,0 +1,90 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades
+
+import (
+	"github.com/juju/version"
+
+	jujuversion "github.com/juju/juju/version"
+)
+
+// Stage identifies the stage of a database upgrade in which a step is
+// run. The steps of every operation in one stage are run before those
+// of the next, so that a controller that requires upgrades to be
+// approved can pause the upgrade between them.
+type Stage string
+
+const (
+	// StageSchema steps migrate the structure of the data: they add,
+	// rename and remove collections, documents and fields. They are
+	// run first, and are the stage of steps that declare none.
+	StageSchema Stage = "schema"
+
+	// StageBackfill steps fill in the data of existing documents in
+	// the structure left by the schema steps, such as by populating a
+	// new field. They may only depend on the schema steps, as all of
+	// those are run before any backfill step.
+	StageBackfill Stage = "backfill"
+)
+
+// stages holds the stages of a database upgrade in the order they run.
+var stages = []Stage{StageSchema, StageBackfill}
+
+// StagedStep is implemented by upgrade steps that declare
+// the stage of the database upgrade in which they are run.
+type StagedStep interface {
+	Step
+
+	// Stage returns the stage in which the step is run. If it is
+	// empty, the step is run with the schema steps.
+	Stage() Stage
+}
+
+// stepStage returns the stage of the database upgrade in which the step
+// is run.
+func stepStage(step Step) Stage {
+	if ss, ok := step.(StagedStep); ok && ss.Stage() != "" {
+		return ss.Stage()
+	}
+	return StageSchema
+}
+
+// stagedOperations returns the operations holding the steps of each of
+// the input stages, in turn, in the order of the input operations.
+// Operations left with no steps are dropped.
+func stagedOperations(ops []Operation, only ...Stage) []Operation {
+	var result []Operation
+	for _, stage := range only {
+		for _, op := range ops {
+			var steps []Step
+			for _, step := range op.Steps() {
+				if stepStage(step) == stage {
+					steps = append(steps, step)
+				}
+			}
+			if len(steps) > 0 {
+				result = append(result, upgradeToVersion{op.TargetVersion(), steps})
+			}
+		}
+	}
+	return result
+}
+
+// stagesTo returns the stages of a database upgrade
+// up to and including the input stage.
+func stagesTo(last Stage) []Stage {
+	for i, stage := range stages {
+		if stage == last {
+			return stages[:i+1]
+		}
+	}
+	return stages
+}
+
+// newStagedStateUpgradeOpsIterator returns an iterator over the state
+// upgrade operations holding the steps of the input stages, in the
+// order in which the steps are run.
+func newStagedStateUpgradeOpsIterator(from version.Number, only ...Stage) *opsIterator {
+	return newOpsIterator(from, jujuversion.Current, stagedOperations(stateUpgradeOperations(), only...))
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/version"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/testing"
+	"github.com/juju/juju/upgrades"
+	jujuversion "github.com/juju/juju/version"
+)
+
+type stagesSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&stagesSuite{})
+
+type stagedStep struct {
+	*reversibleStep
+	stage upgrades.Stage
+}
+
+func (s *stagedStep) Stage() upgrades.Stage {
+	return s.stage
+}
+
+func newBackfillStep(msg string, reversible bool) *stagedStep {
+	return &stagedStep{
+		reversibleStep: newReversibleStep(msg, true, reversible),
+		stage:          upgrades.StageBackfill,
+	}
+}
+
+func (s *stagesSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	s.PatchValue(&jujuversion.Current, version.MustParse("1.22.0"))
+	s.PatchValue(upgrades.StateUpgradeOperations, func() []upgrades.Operation {
+		return []upgrades.Operation{
+			&mockUpgradeOperation{
+				targetVersion: version.MustParse("1.21.0"),
+				steps: []upgrades.Step{
+					newReversibleStep("add field", true, true),
+					newBackfillStep("fill field", false),
+				},
+			},
+			&mockUpgradeOperation{
+				targetVersion: version.MustParse("1.22.0"),
+				steps: []upgrades.Step{
+					newBackfillStep("fill collection", true),
+					newReversibleStep("add collection", true, true),
+				},
+			},
+		}
+	})
+}
+
+func (s *stagesSuite) TestPerformStateUpgradeRunsStagesInTurn(c *gc.C) {
+	ctx := &mockContext{}
+	err := upgrades.PerformStateUpgrade(version.MustParse("1.20.0"), targets(upgrades.DatabaseMaster), ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ctx.messages, jc.DeepEquals, []string{"add field", "add collection", "fill field", "fill collection"})
+}
+
+func (s *stagesSuite) TestPerformStateUpgradeStage(c *gc.C) {
+	var observed []string
+	observer := func(description string) error {
+		observed = append(observed, description)
+		return nil
+	}
+	ctx := &mockContext{}
+	err := upgrades.PerformStateUpgradeStage(
+		version.MustParse("1.20.0"), targets(upgrades.DatabaseMaster), upgrades.StageSchema, ctx, observer)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(observed, jc.DeepEquals, []string{"add field", "add collection"})
+
+	observed = nil
+	err = upgrades.PerformStateUpgradeStage(
+		version.MustParse("1.20.0"), targets(upgrades.DatabaseMaster), upgrades.StageBackfill, ctx, observer)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(observed, jc.DeepEquals, []string{"fill field", "fill collection"})
+}
+
+func (s *stagesSuite) TestRollbackSchemaStage(c *gc.C) {
+	// The irreversible backfill step was not run, so does not
+	// prevent the schema steps from being rolled back.
+	ctx := &mockContext{}
+	err := upgrades.RollbackStateUpgrade(
+		version.MustParse("1.20.0"), targets(upgrades.DatabaseMaster), upgrades.StageSchema, nil, ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ctx.messages, jc.DeepEquals, []string{"reverse add collection", "reverse add field"})
+}
+
+func (s *stagesSuite) TestRollbackBackfillStage(c *gc.C) {
+	ctx := &mockContext{}
+	err := upgrades.RollbackStateUpgrade(
+		version.MustParse("1.20.0"), targets(upgrades.DatabaseMaster), upgrades.StageBackfill, nil, ctx)
+	c.Assert(err, gc.ErrorMatches, `rollback blocked by irreversible upgrade steps: "fill field"`)
+}
@@ -14,6 +14,16 @@ func stateStepsFor20() []Step {
 		&upgradeStep{
 			description: "strip @local from local user names",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read: []string{
+					"cloudCredentials", "models", "usermodelname", "controllerusers",
+					"modelusers", "permissions", "modelUserLastConnection",
+				},
+				Write: []string{
+					"cloudCredentials", "models", "usermodelname", "controllerusers",
+					"modelusers", "permissions", "modelUserLastConnection",
+				},
+			},
 			run: func(context Context) error {
 				return context.State().StripLocalUserDomain()
 			},
@@ -21,6 +31,10 @@ func stateStepsFor20() []Step {
 		&upgradeStep{
 			description: "rename addmodel permission to add-model",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"permissions"},
+				Write: []string{"permissions"},
+			},
 			run: func(context Context) error {
 				return context.State().RenameAddModelPermission()
 			},
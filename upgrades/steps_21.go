@@ -9,6 +9,10 @@ func stateStepsFor21() []Step {
 		&upgradeStep{
 			description: "add attempt to migration docs",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"migrations"},
+				Write: []string{"migrations"},
+			},
 			run: func(context Context) error {
 				return context.State().AddMigrationAttempt()
 			},
@@ -16,6 +20,10 @@ func stateStepsFor21() []Step {
 		&upgradeStep{
 			description: "add sequences to track used local charm revisions",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"charms"},
+				Write: []string{"sequence", "charms"},
+			},
 			run: func(context Context) error {
 				return context.State().AddLocalCharmSequences()
 			},
@@ -23,6 +31,10 @@ func stateStepsFor21() []Step {
 		&upgradeStep{
 			description: "update lxd cloud/credentials",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"clouds", "cloudCredentials"},
+				Write: []string{"clouds", "cloudCredentials"},
+			},
 			run: func(context Context) error {
 				return updateLXDCloudCredentials(context.State())
 			},
@@ -14,6 +14,10 @@ func stateStepsFor22() []Step {
 		&upgradeStep{
 			description: "add machineid to non-detachable storage docs",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"volumes", "volumeattachments", "filesystems", "filesystemAttachments", "settings"},
+				Write: []string{"volumes", "filesystems"},
+			},
 			run: func(context Context) error {
 				return context.State().AddNonDetachableStorageMachineId()
 			},
@@ -21,6 +25,10 @@ func stateStepsFor22() []Step {
 		&upgradeStep{
 			description: "remove application config settings with nil value",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"settings"},
+				Write: []string{"settings"},
+			},
 			run: func(context Context) error {
 				return context.State().RemoveNilValueApplicationSettings()
 			},
@@ -28,6 +36,10 @@ func stateStepsFor22() []Step {
 		&upgradeStep{
 			description: "add controller log collection sizing config settings",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"controllers"},
+				Write: []string{"controllers"},
+			},
 			run: func(context Context) error {
 				return context.State().AddControllerLogCollectionsSizeSettings()
 			},
@@ -35,6 +47,10 @@ func stateStepsFor22() []Step {
 		&upgradeStep{
 			description: "add status history pruning config settings",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"settings"},
+				Write: []string{"settings"},
+			},
 			run: func(context Context) error {
 				return context.State().AddStatusHistoryPruneSettings()
 			},
@@ -42,6 +58,10 @@ func stateStepsFor22() []Step {
 		&upgradeStep{
 			description: "add storage constraints to storage instance docs",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"storageinstances", "volumes", "filesystems", "storageconstraints"},
+				Write: []string{"storageinstances"},
+			},
 			run: func(context Context) error {
 				return context.State().AddStorageInstanceConstraints()
 			},
@@ -49,6 +69,10 @@ func stateStepsFor22() []Step {
 		&upgradeStep{
 			description: "split log collections",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"logs"},
+				Write: []string{"logs"},
+			},
 			run: func(context Context) error {
 				return context.State().SplitLogCollections()
 			},
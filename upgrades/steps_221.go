@@ -9,6 +9,10 @@ func stateStepsFor221() []Step {
 		&upgradeStep{
 			description: "add update-status hook config settings",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"settings"},
+				Write: []string{"settings"},
+			},
 			run: func(context Context) error {
 				return context.State().AddUpdateStatusHookSettings()
 			},
@@ -16,6 +20,10 @@ func stateStepsFor221() []Step {
 		&upgradeStep{
 			description: "correct relation unit counts for subordinates",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"relations", "relationscopes", "applications"},
+				Write: []string{"relations", "relationscopes"},
+			},
 			run: func(context Context) error {
 				return context.State().CorrectRelationUnitCounts()
 			},
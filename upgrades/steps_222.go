@@ -9,6 +9,10 @@ func stateStepsFor222() []Step {
 		&upgradeStep{
 			description: "add environ-version to model docs",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"models"},
+				Write: []string{"models"},
+			},
 			run: func(context Context) error {
 				return context.State().AddModelEnvironVersion()
 			},
@@ -9,6 +9,10 @@ func stateStepsFor223() []Step {
 		&upgradeStep{
 			description: "add max-action-age and max-action-size config settings",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"settings"},
+				Write: []string{"settings"},
+			},
 			run: func(context Context) error {
 				return context.State().AddActionPruneSettings()
 			},
@@ -9,6 +9,10 @@ func stateStepsFor23() []Step {
 		&upgradeStep{
 			description: "add a 'type' field to model documents",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"models"},
+				Write: []string{"models"},
+			},
 			run: func(context Context) error {
 				return context.State().AddModelType()
 			},
@@ -16,6 +20,10 @@ func stateStepsFor23() []Step {
 		&upgradeStep{
 			description: "migrate old leases",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"leases"},
+				Write: []string{"leases"},
+			},
 			run: func(context Context) error {
 				return context.State().MigrateLeasesToGlobalTime()
 			},
@@ -29,6 +37,10 @@ func stateStepsFor231() []Step {
 		&upgradeStep{
 			description: "add status to relations",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"relations", "statuses"},
+				Write: []string{"statuses"},
+			},
 			run: func(context Context) error {
 				return context.State().AddRelationStatus()
 			},
@@ -9,6 +9,10 @@ func stateStepsFor232() []Step {
 		&upgradeStep{
 			description: "move or drop the old audit log collection",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"audit.log"},
+				Write: []string{"audit.log", "old-audit.log"},
+			},
 			run: func(context Context) error {
 				return context.State().MoveOldAuditLog()
 			},
@@ -9,6 +9,10 @@ func stateStepsFor234() []Step {
 		&upgradeStep{
 			description: "delete cloud image metadata cache",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"cloudimagemetadata"},
+				Write: []string{"cloudimagemetadata"},
+			},
 			run: func(context Context) error {
 				return context.State().DeleteCloudImageMetadata()
 			},
@@ -9,6 +9,10 @@ func stateStepsFor236() []Step {
 		&upgradeStep{
 			description: "ensure container-image-stream config defaults to released",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"settings"},
+				Write: []string{"settings"},
+			},
 			run: func(context Context) error {
 				return context.State().EnsureContainerImageStreamDefault()
 			},
@@ -9,6 +9,10 @@ func stateStepsFor237() []Step {
 		&upgradeStep{
 			description: "ensure container-image-stream isn't set in applications",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"settings"},
+				Write: []string{"settings"},
+			},
 			run: func(context Context) error {
 				return context.State().RemoveContainerImageStreamFromNonModelSettings()
 			},
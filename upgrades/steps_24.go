@@ -19,6 +19,10 @@ func stateStepsFor24() []Step {
 		&upgradeStep{
 			description: "move or drop the old audit log collection",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"audit.log"},
+				Write: []string{"audit.log", "old-audit.log"},
+			},
 			run: func(context Context) error {
 				return context.State().MoveOldAuditLog()
 			},
@@ -26,6 +30,10 @@ func stateStepsFor24() []Step {
 		&upgradeStep{
 			description: "move controller info Mongo space to controller config HA space if valid",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"controllers"},
+				Write: []string{"controllers"},
+			},
 			run: func(context Context) error {
 				return context.State().MoveMongoSpaceToHASpaceConfig()
 			},
@@ -33,6 +41,10 @@ func stateStepsFor24() []Step {
 		&upgradeStep{
 			description: "create empty application settings for all applications",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"applications", "settings"},
+				Write: []string{"settings"},
+			},
 			run: func(context Context) error {
 				return context.State().CreateMissingApplicationConfig()
 			},
@@ -40,6 +52,10 @@ func stateStepsFor24() []Step {
 		&upgradeStep{
 			description: "remove votingmachineids",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"controllers"},
+				Write: []string{"controllers"},
+			},
 			run: func(context Context) error {
 				return context.State().RemoveVotingMachineIds()
 			},
@@ -47,6 +63,10 @@ func stateStepsFor24() []Step {
 		&upgradeStep{
 			description: "add cloud model counts",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"clouds", "models", "globalRefcounts"},
+				Write: []string{"globalRefcounts"},
+			},
 			run: func(context Context) error {
 				return context.State().AddCloudModelCounts()
 			},
@@ -9,6 +9,10 @@ func stateStepsFor25() []Step {
 		&upgradeStep{
 			description: `migrate storage records to use "hostid" field`,
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"volumes", "filesystems", "volumeattachments", "filesystemAttachments"},
+				Write: []string{"volumes", "filesystems", "volumeattachments", "filesystemAttachments"},
+			},
 			run: func(context Context) error {
 				return context.State().MigrateStorageMachineIdFields()
 			},
@@ -21,6 +25,10 @@ func stateStepsFor25() []Step {
 		&upgradeStep{
 			description: "migrate add-model permissions",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"controllers", "permissions"},
+				Write: []string{"permissions"},
+			},
 			run: func(context Context) error {
 				return context.State().MigrateAddModelPermissions()
 			},
@@ -28,6 +36,10 @@ func stateStepsFor25() []Step {
 		&upgradeStep{
 			description: "set enable-disk-uuid (if on vsphere)",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"settings"},
+				Write: []string{"settings"},
+			},
 			run: func(context Context) error {
 				return context.State().SetEnableDiskUUIDOnVsphere()
 			},
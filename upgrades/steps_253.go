@@ -9,6 +9,10 @@ func stateStepsFor253() []Step {
 		&upgradeStep{
 			description: "update inherited controller config global key",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"models", "globalSettings"},
+				Write: []string{"globalSettings"},
+			},
 			run: func(context Context) error {
 				return context.State().UpdateInheritedControllerConfig()
 			},
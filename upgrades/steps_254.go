@@ -10,6 +10,10 @@ func stateStepsFor254() []Step {
 		&upgradeStep{
 			description: "ensure default modification status is set for machines",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"machines", "statuses"},
+				Write: []string{"statuses"},
+			},
 			run: func(context Context) error {
 				return context.State().EnsureDefaultModificationStatus()
 			},
@@ -17,6 +21,10 @@ func stateStepsFor254() []Step {
 		&upgradeStep{
 			description: "ensure device constraints exists for applications",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"applications", "deviceConstraints"},
+				Write: []string{"deviceConstraints"},
+			},
 			run: func(context Context) error {
 				return context.State().EnsureApplicationDeviceConstraints()
 			},
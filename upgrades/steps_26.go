@@ -9,6 +9,10 @@ func stateStepsFor26() []Step {
 		&upgradeStep{
 			description: "update k8s storage config",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"models", "clouds", "cloudCredentials", "settings", "globalSettings"},
+				Write: []string{"settings", "globalSettings"},
+			},
 			run: func(context Context) error {
 				return context.State().UpdateKubernetesStorageConfig()
 			},
@@ -16,6 +20,9 @@ func stateStepsFor26() []Step {
 		&upgradeStep{
 			description: "remove instanceCharmProfileData collection",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Write: []string{"instanceCharmProfileData"},
+			},
 			run: func(context Context) error {
 				return context.State().RemoveInstanceCharmProfileDataCollection()
 			},
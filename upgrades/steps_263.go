@@ -37,6 +37,10 @@ func stateStepsFor263() []Step {
 		&upgradeStep{
 			description: "update model name index of k8s models",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"models", "usermodelname"},
+				Write: []string{"usermodelname"},
+			},
 			run: func(context Context) error {
 				return context.State().UpdateK8sModelNameIndex()
 			},
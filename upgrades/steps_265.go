@@ -9,6 +9,10 @@ func stateStepsFor265() []Step {
 		&upgradeStep{
 			description: "add models-logs-size to controller config",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"controllers"},
+				Write: []string{"controllers"},
+			},
 			run: func(context Context) error {
 				return context.State().AddModelLogsSize()
 			},
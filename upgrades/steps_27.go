@@ -21,6 +21,10 @@ func stateStepsFor27() []Step {
 		&upgradeStep{
 			description: "add controller node docs",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"machines"},
+				Write: []string{"machines", "controllerNodes", "controllers"},
+			},
 			run: func(context Context) error {
 				return context.State().AddControllerNodeDocs()
 			},
@@ -28,6 +32,15 @@ func stateStepsFor27() []Step {
 		&upgradeStep{
 			description: "recreate spaces with IDs",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"spaces"},
+				Write: []string{"spaces", "sequence"},
+			},
+			schema: []SchemaVersion{{
+				Collection: "spaces",
+				Version:    1,
+				Fields:     []string{"spaceid"},
+			}},
 			run: func(context Context) error {
 				return context.State().AddSpaceIdToSpaceDocs()
 			},
@@ -35,6 +48,10 @@ func stateStepsFor27() []Step {
 		&upgradeStep{
 			description: "change subnet AvailabilityZone to AvailabilityZones",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"subnets"},
+				Write: []string{"subnets"},
+			},
 			run: func(context Context) error {
 				return context.State().ChangeSubnetAZtoSlice()
 			},
@@ -42,6 +59,10 @@ func stateStepsFor27() []Step {
 		&upgradeStep{
 			description: "change subnet SpaceName to SpaceID",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"subnets", "spaces"},
+				Write: []string{"subnets"},
+			},
 			run: func(context Context) error {
 				return context.State().ChangeSubnetSpaceNameToSpaceID()
 			},
@@ -49,6 +70,15 @@ func stateStepsFor27() []Step {
 		&upgradeStep{
 			description: "recreate subnets with IDs",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"subnets"},
+				Write: []string{"subnets", "sequence"},
+			},
+			schema: []SchemaVersion{{
+				Collection: "subnets",
+				Version:    1,
+				Fields:     []string{"subnet-id"},
+			}},
 			run: func(context Context) error {
 				return context.State().AddSubnetIdToSubnetDocs()
 			},
@@ -56,6 +86,10 @@ func stateStepsFor27() []Step {
 		&upgradeStep{
 			description: "replace portsDoc.SubnetID as a CIDR with an ID.",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"openedPorts", "subnets"},
+				Write: []string{"openedPorts"},
+			},
 			run: func(context Context) error {
 				return context.State().ReplacePortsDocSubnetIDCIDR()
 			},
@@ -63,6 +97,10 @@ func stateStepsFor27() []Step {
 		&upgradeStep{
 			description: "ensure application settings exist for all relations",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"settings", "relations"},
+				Write: []string{"settings"},
+			},
 			run: func(context Context) error {
 				return context.State().EnsureRelationApplicationSettings()
 			},
@@ -70,6 +108,10 @@ func stateStepsFor27() []Step {
 		&upgradeStep{
 			description: "ensure stored addresses refer to space by ID, and remove old space name/provider ID",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"spaces", "machines", "cloudservices", "cloudcontainers"},
+				Write: []string{"machines", "cloudservices", "cloudcontainers"},
+			},
 			run: func(context Context) error {
 				return context.State().ConvertAddressSpaceIDs()
 			},
@@ -77,6 +119,10 @@ func stateStepsFor27() []Step {
 		&upgradeStep{
 			description: "replace space name in endpointBindingDoc bindings with an space ID",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"endpointbindings", "spaces"},
+				Write: []string{"endpointbindings"},
+			},
 			run: func(context Context) error {
 				return context.State().ReplaceSpaceNameWithIDEndpointBindings()
 			},
@@ -84,6 +130,10 @@ func stateStepsFor27() []Step {
 		&upgradeStep{
 			description: `ensure model config for default-space is "" if either absent or is set to "_default"`,
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"models", "settings"},
+				Write: []string{"settings"},
+			},
 			run: func(context Context) error {
 				return context.State().EnsureDefaultSpaceSetting()
 			},
@@ -91,6 +141,10 @@ func stateStepsFor27() []Step {
 		&upgradeStep{
 			description: "remove controller config for max-logs-age and max-logs-size if set",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"controllers"},
+				Write: []string{"controllers"},
+			},
 			run: func(context Context) error {
 				return context.State().RemoveControllerConfigMaxLogAgeAndSize()
 			},
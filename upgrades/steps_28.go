@@ -4,6 +4,8 @@
 package upgrades
 
 import (
+	"time"
+
 	"github.com/juju/errors"
 	"gopkg.in/juju/names.v3"
 
@@ -17,16 +19,68 @@ func stateStepsFor28() []Step {
 		&upgradeStep{
 			description: "increment tasks sequence by 1",
 			targets:     []Target{DatabaseMaster},
+			collections: Collections{
+				Read:  []string{"sequence"},
+				Write: []string{"sequence"},
+			},
+			cost: Cost{Fixed: 100 * time.Millisecond},
+			// Incrementing the sequence again only skips a task ID,
+			// so the step is safe to re-run, and leaving it
+			// incremented is safe for the previous version.
+			idempotent: true,
 			run: func(context Context) error {
 				return context.State().IncrementTasksSequence()
 			},
+			reverse: func(Context) error {
+				return nil
+			},
 		},
 		&upgradeStep{
 			description: "add machine ID to subordinate units",
 			targets:     []Target{DatabaseMaster},
+			stage:       StageBackfill,
+			// Every unit document is rewritten, growing
+			// the journal and oplog by as much again.
+			requirements: Requirements{DiskMiB: 256, MemoryMiB: 256},
+			collections: Collections{
+				Read:  []string{"units"},
+				Write: []string{"units"},
+			},
+			cost: Cost{
+				Fixed:       time.Second,
+				PerDocument: 200 * time.Microsecond,
+			},
+			idempotent: true,
 			run: func(context Context) error {
 				return context.State().AddMachineIDToSubordinates()
 			},
+			reverse: func(context Context) error {
+				return context.State().RemoveMachineIDFromSubordinates()
+			},
+		},
+		&upgradeStep{
+			description: "add application name to unit states",
+			targets:     []Target{DatabaseMaster},
+			stage:       StageBackfill,
+			// Unit states hold charm and relation state,
+			// so they are the largest documents rewritten.
+			requirements: Requirements{DiskMiB: 1024, MemoryMiB: 512},
+			collections: Collections{
+				Read:  []string{"unitstates"},
+				Write: []string{"unitstates"},
+			},
+			cost: Cost{
+				Fixed:       time.Second,
+				PerDocument: 200 * time.Microsecond,
+				PerMiB:      50 * time.Millisecond,
+			},
+			idempotent: true,
+			run: func(context Context) error {
+				return context.State().AddApplicationToUnitStates()
+			},
+			reverse: func(context Context) error {
+				return context.State().RemoveApplicationFromUnitStates()
+			},
 		},
 	}
 }
@@ -4,6 +4,8 @@
 package upgrades_test
 
 import (
+	"github.com/dustin/go-humanize"
+	"github.com/juju/errors"
 	jc "github.com/juju/testing/checkers"
 	"github.com/juju/version"
 	gc "gopkg.in/check.v1"
@@ -28,9 +30,65 @@ func (s *steps28Suite) TestIncrementTasksSequence(c *gc.C) {
 func (s *steps28Suite) TestAddMachineIDToSubordinates(c *gc.C) {
 	step := findStateStep(c, v280, "add machine ID to subordinate units")
 	c.Assert(step.Targets(), jc.DeepEquals, []upgrades.Target{upgrades.DatabaseMaster})
+	c.Assert(step.(upgrades.RequirementsStep).Requirements(), gc.Equals, upgrades.Requirements{DiskMiB: 256, MemoryMiB: 256})
+}
+
+func (s *steps28Suite) TestAddApplicationToUnitStates(c *gc.C) {
+	step := findStateStep(c, v280, "add application name to unit states")
+	c.Assert(step.Targets(), jc.DeepEquals, []upgrades.Target{upgrades.DatabaseMaster})
+	c.Assert(step.(upgrades.RequirementsStep).Requirements(), gc.Equals, upgrades.Requirements{DiskMiB: 1024, MemoryMiB: 512})
+}
+
+func (s *steps28Suite) TestPreflightRefusesWithoutBackfillRequirements(c *gc.C) {
+	from := version.MustParse("2.7.6")
+	targets := []upgrades.Target{upgrades.DatabaseMaster}
+	c.Assert(upgrades.StateUpgradeRequirements(from, targets), gc.Equals, upgrades.Requirements{DiskMiB: 1024, MemoryMiB: 512})
+
+	s.PatchValue(upgrades.AvailableMemory, func() (uint64, error) { return 256 * humanize.MiByte, nil })
+	err := upgrades.PreflightStateUpgrade(from, targets, c.MkDir())
+	c.Assert(err, gc.ErrorMatches, `not enough available memory for upgrade: 256 MiB available, require 512MiB`)
+
+	s.PatchValue(upgrades.AvailableMemory, func() (uint64, error) { return humanize.GiByte, nil })
+	err = upgrades.PreflightStateUpgrade(from, targets, c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *steps28Suite) TestStateStepsReversible(c *gc.C) {
+	for _, description := range []string{
+		"increment tasks sequence by 1",
+		"add machine ID to subordinate units",
+		"add application name to unit states",
+	} {
+		step := findStateStep(c, v280, description).(upgrades.ReversibleStep)
+		c.Check(step.Reversible(), jc.IsTrue, gc.Commentf(description))
+		c.Check(step.Idempotent(), jc.IsTrue, gc.Commentf(description))
+	}
+}
+
+func (s *steps28Suite) TestRollbackFrom27(c *gc.C) {
+	st := &rollback28StateBackend{}
+	ctx := &mockContext{state: st}
+	err := upgrades.RollbackStateUpgrade(
+		version.MustParse("2.7.6"), []upgrades.Target{upgrades.DatabaseMaster}, upgrades.StageBackfill, errors.New("cancelled"), ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	st.CheckCallNames(c, "RemoveApplicationFromUnitStates", "RemoveMachineIDFromSubordinates")
 }
 
 func (s *steps28Suite) TestPopulateRebootHandledFlagsForDeployedUnits(c *gc.C) {
 	step := findStep(c, v280, "ensure currently running units do not fire start hooks thinking a reboot has occurred")
 	c.Assert(step.Targets(), jc.DeepEquals, []upgrades.Target{upgrades.HostMachine})
 }
+
+type rollback28StateBackend struct {
+	mockStateBackend
+}
+
+func (mock *rollback28StateBackend) RemoveMachineIDFromSubordinates() error {
+	mock.MethodCall(mock, "RemoveMachineIDFromSubordinates")
+	return mock.NextErr()
+}
+
+func (mock *rollback28StateBackend) RemoveApplicationFromUnitStates() error {
+	mock.MethodCall(mock, "RemoveApplicationFromUnitStates")
+	return mock.NextErr()
+}
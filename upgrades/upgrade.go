@@ -85,9 +85,16 @@ func (u upgradeToVersion) TargetVersion() version.Number {
 type upgradeError struct {
 	description string
 	err         error
+
+	// notRun is true if the upgrade was stopped
+	// before the step was run.
+	notRun bool
 }
 
 func (e *upgradeError) Error() string {
+	if e.notRun {
+		return fmt.Sprintf("stopped before %s: %v", e.description, e.err)
+	}
 	return fmt.Sprintf("%s: %v", e.description, e.err)
 }
 
@@ -100,7 +107,7 @@ func PerformUpgrade(from version.Number, targets []Target, context Context) erro
 		}
 	}
 	ops := newUpgradeOpsIterator(from)
-	if err := runUpgradeSteps(ops, targets, context.APIContext()); err != nil {
+	if err := runUpgradeSteps(ops, targets, context.APIContext(), "", nil); err != nil {
 		return errors.Trace(err)
 	}
 	logger.Infof("All upgrade steps completed successfully")
@@ -110,7 +117,53 @@ func PerformUpgrade(from version.Number, targets []Target, context Context) erro
 // PerformStateUpgrade runs the upgrades steps
 // that target Controller or DatabaseMaster.
 func PerformStateUpgrade(from version.Number, targets []Target, context Context) error {
-	return errors.Trace(runUpgradeSteps(newStateUpgradeOpsIterator(from), targets, context.StateContext()))
+	return PerformObservedStateUpgrade(from, targets, context, nil)
+}
+
+// StepObserver is called with the description of each upgrade step,
+// immediately before it is run. If it returns an error, the step is not
+// run, and the upgrade is stopped with that error.
+type StepObserver func(description string) error
+
+// PerformObservedStateUpgrade runs the upgrade steps that target Controller
+// or DatabaseMaster, as PerformStateUpgrade does, notifying the observer,
+// if it is not nil, of each step as it is started. The steps of every
+// stage of the upgrade are run, one stage after another.
+func PerformObservedStateUpgrade(from version.Number, targets []Target, context Context, observer StepObserver) error {
+	return errors.Trace(runUpgradeSteps(newStateUpgradeOpsIterator(from), targets, context.StateContext(), "", observer))
+}
+
+// PerformStateUpgradeStage runs the upgrade steps of the input stage that
+// target Controller or DatabaseMaster, as PerformObservedStateUpgrade
+// does. The steps of the stages before it must already have been run.
+func PerformStateUpgradeStage(from version.Number, targets []Target, stage Stage, context Context, observer StepObserver) error {
+	ops := newStagedStateUpgradeOpsIterator(from, stage)
+	return errors.Trace(runUpgradeSteps(ops, targets, context.StateContext(), "", observer))
+}
+
+// PerformResumedStateUpgrade runs the upgrade steps of the input stage
+// that target Controller or DatabaseMaster, as PerformStateUpgradeStage
+// does, but starting from the step with the input description. The steps
+// before it are taken to have completed in an earlier attempt, and are
+// not run again. If there is no such step, a NotFound error is returned
+// and no steps are run.
+func PerformResumedStateUpgrade(
+	from version.Number, targets []Target, stage Stage, step string, context Context, observer StepObserver,
+) error {
+	ops := newStagedStateUpgradeOpsIterator(from, stage)
+	return errors.Trace(runUpgradeSteps(ops, targets, context.StateContext(), step, observer))
+}
+
+// FailedStep returns the description of the upgrade step that failed with
+// the input error, along with the error returned by the step itself, or
+// by the observer that stopped the upgrade before it. If the error was
+// not returned for a step, the description is empty and the error is
+// returned unchanged.
+func FailedStep(err error) (string, error) {
+	if ue, ok := errors.Cause(err).(*upgradeError); ok {
+		return ue.description, ue.err
+	}
+	return "", err
 }
 
 func hasStateTarget(targets []Target) bool {
@@ -129,19 +182,95 @@ func hasStateTarget(targets []Target) bool {
 // subsequent steps may required successful completion of earlier
 // ones. The steps must be idempotent so that the entire upgrade
 // operation can be retried.
-func runUpgradeSteps(ops *opsIterator, targets []Target, context Context) error {
+// Once a step has run, the schema versions it declares are recorded.
+// Steps gated behind a controller feature flag that is not set are
+// skipped, and recorded so that they can be run once it is enabled.
+// Contract steps of staged migrations are deferred, and recorded so that
+// they can be run once every agent has been upgraded.
+// If resume is not empty, the steps before the one with that description
+// are skipped, having been run by an earlier attempt.
+// If observer is not nil, it is notified of each step before it is run,
+// and may stop the upgrade between steps by returning an error.
+func runUpgradeSteps(ops *opsIterator, targets []Target, context Context, resume string, observer StepObserver) error {
 	for ops.Next() {
 		for _, step := range ops.Get().Steps() {
-			if targetsMatch(targets, step.Targets()) {
-				logger.Infof("running upgrade step: %v", step.Description())
-				if err := step.Run(context); err != nil {
-					logger.Errorf("upgrade step %q failed: %v", step.Description(), err)
-					return &upgradeError{
-						description: step.Description(),
-						err:         err,
-					}
+			if !targetsMatch(targets, step.Targets()) {
+				continue
+			}
+			if resume != "" {
+				if step.Description() != resume {
+					continue
+				}
+				logger.Infof("resuming upgrade steps from: %v", resume)
+				resume = ""
+			}
+			gated, err := skipGatedStep(context, step)
+			if err != nil {
+				logger.Errorf("checking feature flag for upgrade step %q failed: %v", step.Description(), err)
+				return &upgradeError{
+					description: step.Description(),
+					err:         err,
+				}
+			}
+			if gated {
+				continue
+			}
+			deferred, err := deferContractStep(context, step, ops.Get().TargetVersion())
+			if err != nil {
+				logger.Errorf("deferring contract upgrade step %q failed: %v", step.Description(), err)
+				return &upgradeError{
+					description: step.Description(),
+					err:         err,
 				}
 			}
+			if deferred {
+				continue
+			}
+			if err := runStep(context, step, observer); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	if resume != "" {
+		return errors.NotFoundf("upgrade step %q", resume)
+	}
+	return nil
+}
+
+// runStep runs the upgrade step, then records the schema versions it
+// declares, and that it is no longer awaiting its feature flag. The step
+// is not run if the observer stops the upgrade.
+func runStep(context Context, step Step, observer StepObserver) error {
+	logger.Infof("running upgrade step: %v", step.Description())
+	if observer != nil {
+		if err := observer(step.Description()); err != nil {
+			logger.Infof("upgrade stopped before step %q: %v", step.Description(), err)
+			return &upgradeError{
+				description: step.Description(),
+				err:         err,
+				notRun:      true,
+			}
+		}
+	}
+	if err := step.Run(context); err != nil {
+		logger.Errorf("upgrade step %q failed: %v", step.Description(), err)
+		return &upgradeError{
+			description: step.Description(),
+			err:         err,
+		}
+	}
+	if err := recordSchemaVersions(context, step); err != nil {
+		logger.Errorf("recording schema versions for upgrade step %q failed: %v", step.Description(), err)
+		return &upgradeError{
+			description: step.Description(),
+			err:         err,
+		}
+	}
+	if err := clearGatedStep(context, step); err != nil {
+		logger.Errorf("clearing gated upgrade step %q failed: %v", step.Description(), err)
+		return &upgradeError{
+			description: step.Description(),
+			err:         err,
 		}
 	}
 	return nil
@@ -162,12 +291,30 @@ func targetsMatch(machineTargets []Target, stepTargets []Target) bool {
 
 // upgradeStep is a default Step implementation.
 type upgradeStep struct {
-	description string
-	targets     []Target
-	run         func(Context) error
+	description  string
+	targets      []Target
+	requirements Requirements
+	collections  Collections
+	cost         Cost
+	schema       []SchemaVersion
+	featureFlag  string
+	phase        Phase
+	stage        Stage
+	idempotent   bool
+	run          func(Context) error
+	reverse      func(Context) error
 }
 
-var _ Step = (*upgradeStep)(nil)
+var (
+	_ RequirementsStep = (*upgradeStep)(nil)
+	_ CollectionsStep  = (*upgradeStep)(nil)
+	_ CostStep         = (*upgradeStep)(nil)
+	_ SchemaStep       = (*upgradeStep)(nil)
+	_ FeatureStep      = (*upgradeStep)(nil)
+	_ PhasedStep       = (*upgradeStep)(nil)
+	_ StagedStep       = (*upgradeStep)(nil)
+	_ ReversibleStep   = (*upgradeStep)(nil)
+)
 
 // Description is defined on the Step interface.
 func (step *upgradeStep) Description() string {
@@ -179,7 +326,60 @@ func (step *upgradeStep) Targets() []Target {
 	return step.targets
 }
 
+// Requirements is defined on the RequirementsStep interface.
+func (step *upgradeStep) Requirements() Requirements {
+	return step.requirements
+}
+
+// Collections is defined on the CollectionsStep interface.
+func (step *upgradeStep) Collections() Collections {
+	return step.collections
+}
+
+// Cost is defined on the CostStep interface.
+func (step *upgradeStep) Cost() Cost {
+	return step.cost
+}
+
+// SchemaVersions is defined on the SchemaStep interface.
+func (step *upgradeStep) SchemaVersions() []SchemaVersion {
+	return step.schema
+}
+
+// FeatureFlag is defined on the FeatureStep interface.
+func (step *upgradeStep) FeatureFlag() string {
+	return step.featureFlag
+}
+
+// Phase is defined on the PhasedStep interface.
+func (step *upgradeStep) Phase() Phase {
+	return step.phase
+}
+
+// Stage is defined on the StagedStep interface.
+func (step *upgradeStep) Stage() Stage {
+	return step.stage
+}
+
 // Run is defined on the Step interface.
 func (step *upgradeStep) Run(context Context) error {
 	return step.run(context)
 }
+
+// Idempotent is defined on the ReversibleStep interface.
+func (step *upgradeStep) Idempotent() bool {
+	return step.idempotent
+}
+
+// Reversible is defined on the ReversibleStep interface.
+func (step *upgradeStep) Reversible() bool {
+	return step.reverse != nil
+}
+
+// Reverse is defined on the ReversibleStep interface.
+func (step *upgradeStep) Reverse(context Context) error {
+	if step.reverse == nil {
+		return errors.NotSupportedf("reversing upgrade step %q", step.description)
+	}
+	return step.reverse(context)
+}
@@ -497,6 +497,77 @@ func (s *upgradeSuite) TestPerformUpgrade(c *gc.C) {
 	}
 }
 
+func (s *upgradeSuite) TestPerformObservedStateUpgrade(c *gc.C) {
+	s.PatchValue(upgrades.StateUpgradeOperations, stateUpgradeOperations)
+	s.PatchValue(&jujuversion.Current, version.MustParse("1.22.0"))
+	ctx := &mockContext{state: &mockStateBackend{}}
+
+	var observed []string
+	observer := func(description string) error {
+		observed = append(observed, description)
+		return nil
+	}
+	err := upgrades.PerformObservedStateUpgrade(
+		version.MustParse("1.20.0"), targets(upgrades.DatabaseMaster), ctx, observer)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(observed, jc.DeepEquals, []string{"state step 1 - 1.21.0", "state step 1 - 1.22.0"})
+
+	// The failed step is observed, but none after it.
+	observed = nil
+	err = upgrades.PerformObservedStateUpgrade(
+		version.MustParse("1.10.0"), targets(upgrades.Controller), ctx, observer)
+	c.Assert(err, gc.ErrorMatches, "state step 2 error: upgrade error occurred")
+	c.Assert(observed, jc.DeepEquals, []string{"state step 1 - 1.11.0", "state step 2 error"})
+}
+
+func (s *upgradeSuite) TestPerformResumedStateUpgrade(c *gc.C) {
+	s.PatchValue(upgrades.StateUpgradeOperations, stateUpgradeOperations)
+	s.PatchValue(&jujuversion.Current, version.MustParse("1.22.0"))
+	ctx := &mockContext{state: &mockStateBackend{}}
+
+	var observed []string
+	observer := func(description string) error {
+		observed = append(observed, description)
+		return nil
+	}
+	err := upgrades.PerformResumedStateUpgrade(
+		version.MustParse("1.20.0"), targets(upgrades.DatabaseMaster), upgrades.StageSchema, "state step 1 - 1.22.0", ctx, observer)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(observed, jc.DeepEquals, []string{"state step 1 - 1.22.0"})
+}
+
+func (s *upgradeSuite) TestPerformResumedStateUpgradeStepNotFound(c *gc.C) {
+	s.PatchValue(upgrades.StateUpgradeOperations, stateUpgradeOperations)
+	s.PatchValue(&jujuversion.Current, version.MustParse("1.22.0"))
+	ctx := &mockContext{state: &mockStateBackend{}}
+
+	var observed []string
+	observer := func(description string) error {
+		observed = append(observed, description)
+		return nil
+	}
+	err := upgrades.PerformResumedStateUpgrade(
+		version.MustParse("1.20.0"), targets(upgrades.DatabaseMaster), upgrades.StageSchema, "state step 2 - 1.22.0", ctx, observer)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	c.Assert(observed, gc.HasLen, 0)
+}
+
+func (s *upgradeSuite) TestFailedStep(c *gc.C) {
+	s.PatchValue(upgrades.StateUpgradeOperations, stateUpgradeOperations)
+	s.PatchValue(&jujuversion.Current, version.MustParse("1.22.0"))
+	ctx := &mockContext{state: &mockStateBackend{}}
+
+	err := upgrades.PerformStateUpgrade(version.MustParse("1.10.0"), targets(upgrades.Controller), ctx)
+	step, cause := upgrades.FailedStep(err)
+	c.Check(step, gc.Equals, "state step 2 error")
+	c.Check(cause, gc.ErrorMatches, "upgrade error occurred")
+
+	other := errors.New("boom")
+	step, cause = upgrades.FailedStep(other)
+	c.Check(step, gc.Equals, "")
+	c.Check(cause, gc.Equals, other)
+}
+
 type contextStep struct {
 	useAPI bool
 }
@@ -0,0 +1,147 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/core/actions"
+	"github.com/juju/juju/state"
+)
+
+// ValidationCheck is run against the canary model once the database
+// upgrade steps have completed, and must pass before the upgrade is
+// marked as complete.
+type ValidationCheck struct {
+	// Description is a human readable description of the check.
+	Description string
+
+	// Run performs the check against the canary model.
+	Run func(*state.State) error
+}
+
+// validationChecks holds the checks run against the canary model.
+// The first entries exercise the basic operations that every model
+// is expected to support after an upgrade.
+var validationChecks = []ValidationCheck{{
+	Description: "list machines",
+	Run:         validateListMachines,
+}, {
+	Description: "read model status",
+	Run:         validateModelStatus,
+}, {
+	Description: "enqueue noop action",
+	Run:         validateEnqueueAction,
+}}
+
+// RegisterValidationCheck adds a check to be run against the canary model
+// after the database upgrade steps have completed. Authors of upgrade steps
+// that could leave a model unusable should register a check for it, from
+// an init function in the file defining the steps.
+func RegisterValidationCheck(check ValidationCheck) {
+	if check.Description == "" || check.Run == nil {
+		panic("upgrade validation check requires a description and a run function")
+	}
+	validationChecks = append(validationChecks, check)
+}
+
+// ValidationChecks returns the checks that are run against the canary model.
+func ValidationChecks() []ValidationCheck {
+	checks := make([]ValidationCheck, len(validationChecks))
+	copy(checks, validationChecks)
+	return checks
+}
+
+// ValidateStateUpgrade runs the registered validation checks against the
+// canary model nominated in the controller configuration.
+// If there is no canary model, no checks are run.
+func ValidateStateUpgrade(context Context) error {
+	st := context.State()
+	cfg, err := st.ControllerConfig()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	modelUUID := cfg.UpgradeCanaryModel()
+	if modelUUID == "" {
+		logger.Debugf("no upgrade canary model configured, skipping validation")
+		return nil
+	}
+	logger.Infof("validating upgrade against canary model %q", modelUUID)
+	return errors.Trace(st.ValidateModel(modelUUID, ValidationChecks()))
+}
+
+func validateListMachines(st *state.State) error {
+	_, err := st.AllMachines()
+	return errors.Trace(err)
+}
+
+func validateModelStatus(st *state.State) error {
+	model, err := st.Model()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if _, err := model.Status(); err != nil {
+		return errors.Trace(err)
+	}
+	applications, err := st.AllApplications()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, app := range applications {
+		if _, err := app.Status(); err != nil {
+			return errors.Annotatef(err, "application %q", app.Name())
+		}
+		units, err := app.AllUnits()
+		if err != nil {
+			return errors.Annotatef(err, "application %q", app.Name())
+		}
+		for _, unit := range units {
+			if _, err := unit.Status(); err != nil {
+				return errors.Annotatef(err, "unit %q", unit.Name())
+			}
+			if _, err := unit.AgentStatus(); err != nil {
+				return errors.Annotatef(err, "unit %q", unit.Name())
+			}
+		}
+	}
+	return nil
+}
+
+// validateEnqueueAction enqueues a juju-run action that does nothing
+// on the first alive machine in the model, and cancels it straight away.
+// Models without machines are not checked.
+func validateEnqueueAction(st *state.State) error {
+	machines, err := st.AllMachines()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var machine *state.Machine
+	for _, m := range machines {
+		if m.Life() == state.Alive {
+			machine = m
+			break
+		}
+	}
+	if machine == nil {
+		return nil
+	}
+
+	model, err := st.Model()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	operationID, err := model.EnqueueOperation("upgrade validation")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	action, err := machine.AddAction(operationID, actions.JujuRunActionName, map[string]interface{}{
+		"command": "true",
+		"timeout": 0,
+	})
+	if err != nil {
+		return errors.Annotatef(err, "machine %q", machine.Id())
+	}
+	_, err = action.Cancel()
+	return errors.Annotatef(err, "cancelling action %q", action.Id())
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgrades_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/controller"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing"
+	"github.com/juju/juju/upgrades"
+)
+
+type validationSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&validationSuite{})
+
+func (s *validationSuite) TestDefaultChecks(c *gc.C) {
+	var descriptions []string
+	for _, check := range upgrades.ValidationChecks() {
+		descriptions = append(descriptions, check.Description)
+	}
+	c.Assert(descriptions, jc.DeepEquals, []string{
+		"list machines",
+		"read model status",
+		"enqueue noop action",
+	})
+}
+
+func (s *validationSuite) TestRegisterValidationCheck(c *gc.C) {
+	s.PatchValue(upgrades.ValidationChecksForTest, []upgrades.ValidationCheck(nil))
+
+	check := upgrades.ValidationCheck{
+		Description: "read units",
+		Run:         func(*state.State) error { return nil },
+	}
+	upgrades.RegisterValidationCheck(check)
+	checks := upgrades.ValidationChecks()
+	c.Assert(checks, gc.HasLen, 1)
+	c.Assert(checks[0].Description, gc.Equals, "read units")
+
+	c.Assert(func() {
+		upgrades.RegisterValidationCheck(upgrades.ValidationCheck{Description: "no run"})
+	}, gc.PanicMatches, "upgrade validation check requires a description and a run function")
+}
+
+func (s *validationSuite) TestValidateStateUpgradeNoCanary(c *gc.C) {
+	st := &validationStateBackend{config: controller.Config{}}
+	ctx := &mockContext{state: st}
+
+	err := upgrades.ValidateStateUpgrade(ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	st.CheckCallNames(c, "ControllerConfig")
+}
+
+func (s *validationSuite) TestValidateStateUpgrade(c *gc.C) {
+	st := &validationStateBackend{config: controller.Config{
+		controller.UpgradeCanaryModel: "deadbeef-0bad-400d-8000-4b1d0d06f00d",
+	}}
+	st.SetErrors(nil, errors.New("boom"))
+	ctx := &mockContext{state: st}
+
+	err := upgrades.ValidateStateUpgrade(ctx)
+	c.Assert(err, gc.ErrorMatches, "boom")
+	st.CheckCallNames(c, "ControllerConfig", "ValidateModel")
+	st.CheckCall(c, 1, "ValidateModel", "deadbeef-0bad-400d-8000-4b1d0d06f00d", len(upgrades.ValidationChecks()))
+}
+
+type validationStateBackend struct {
+	mockStateBackend
+	config controller.Config
+}
+
+func (st *validationStateBackend) ControllerConfig() (controller.Config, error) {
+	st.MethodCall(st, "ControllerConfig")
+	return st.config, st.NextErr()
+}
+
+func (st *validationStateBackend) ValidateModel(modelUUID string, checks []upgrades.ValidationCheck) error {
+	st.MethodCall(st, "ValidateModel", modelUUID, len(checks))
+	return st.NextErr()
+}
@@ -0,0 +1,45 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package accessexpiry
+
+import (
+	"time"
+
+	"github.com/juju/clock"
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+	"gopkg.in/juju/worker.v1"
+
+	"github.com/juju/juju/state"
+	jworker "github.com/juju/juju/worker"
+)
+
+var logger = loggo.GetLogger("juju.worker.accessexpiry")
+
+// AccessRevoker defines the interface for types capable of
+// revoking expired temporary access grants.
+type AccessRevoker interface {
+	RevokeExpiredTemporaryAccess() ([]state.TemporaryAccessGrant, error)
+}
+
+// New returns a worker which periodically revokes temporary
+// model access grants that have expired.
+func New(revoker AccessRevoker, interval time.Duration, clock clock.Clock) worker.Worker {
+	return jworker.NewSimpleWorker(func(stopCh <-chan struct{}) error {
+		for {
+			select {
+			case <-clock.After(interval):
+				revoked, err := revoker.RevokeExpiredTemporaryAccess()
+				if err != nil {
+					return errors.Annotate(err, "revoking expired access, accessexpiry stopping")
+				}
+				if len(revoked) > 0 {
+					logger.Debugf("revoked %d expired temporary access grant(s)", len(revoked))
+				}
+			case <-stopCh:
+				return nil
+			}
+		}
+	})
+}
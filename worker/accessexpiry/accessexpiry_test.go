@@ -0,0 +1,89 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package accessexpiry_test
+
+import (
+	"time"
+
+	"github.com/juju/clock"
+	"github.com/juju/clock/testclock"
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/worker.v1/workertest"
+
+	"github.com/juju/juju/state"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/worker/accessexpiry"
+)
+
+type AccessExpirySuite struct {
+	coretesting.BaseSuite
+}
+
+var _ = gc.Suite(&AccessExpirySuite{})
+
+func (s *AccessExpirySuite) TestRevokes(c *gc.C) {
+	fakeRevoker := newFakeAccessRevoker(nil)
+	testClock := testclock.NewClock(time.Now())
+	interval := time.Minute
+	w := accessexpiry.New(fakeRevoker, interval, testClock)
+	defer workertest.CleanKill(c, w)
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-testClock.Alarms():
+		case <-time.After(coretesting.LongWait):
+			c.Fatalf("timed out waiting for worker to wait")
+		}
+		testClock.Advance(interval)
+		select {
+		case <-fakeRevoker.revokeCh:
+		case <-time.After(coretesting.LongWait):
+			c.Fatal("timed out waiting for revocation to happen")
+		}
+	}
+}
+
+func (s *AccessExpirySuite) TestRevokeError(c *gc.C) {
+	fakeRevoker := newFakeAccessRevoker(errors.New("boom"))
+	testClock := testclock.NewClock(time.Now())
+	w := accessexpiry.New(fakeRevoker, time.Minute, testClock)
+	defer workertest.DirtyKill(c, w)
+
+	select {
+	case <-testClock.Alarms():
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for worker to wait")
+	}
+	testClock.Advance(time.Minute)
+	<-fakeRevoker.revokeCh
+	err := workertest.CheckKilled(c, w)
+	c.Assert(err, gc.ErrorMatches, "revoking expired access, accessexpiry stopping: boom")
+}
+
+func (s *AccessExpirySuite) TestStops(c *gc.C) {
+	w := accessexpiry.New(newFakeAccessRevoker(nil), time.Minute, clock.WallClock)
+	workertest.CleanKill(c, w)
+	c.Check(w.Wait(), jc.ErrorIsNil)
+}
+
+func newFakeAccessRevoker(err error) *fakeAccessRevoker {
+	return &fakeAccessRevoker{
+		revokeCh: make(chan bool),
+		err:      err,
+	}
+}
+
+type fakeAccessRevoker struct {
+	revokeCh chan bool
+	err      error
+}
+
+// RevokeExpiredTemporaryAccess implements the accessexpiry.AccessRevoker
+// interface.
+func (r *fakeAccessRevoker) RevokeExpiredTemporaryAccess() ([]state.TemporaryAccessGrant, error) {
+	r.revokeCh <- true
+	return nil, r.err
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package accessexpiry_test
+
+import (
+	"time"
+
+	"github.com/juju/clock"
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/worker.v1"
+	"gopkg.in/juju/worker.v1/workertest"
+
+	"github.com/juju/juju/worker/accessexpiry"
+)
+
+type ManifoldSuite struct {
+	testing.IsolationSuite
+	stub   testing.Stub
+	config accessexpiry.ManifoldConfig
+	worker worker.Worker
+}
+
+var _ = gc.Suite(&ManifoldSuite{})
+
+func (s *ManifoldSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.stub.ResetCalls()
+	s.config = s.validConfig()
+	s.worker = worker.NewRunner(worker.RunnerParams{})
+	s.AddCleanup(func(c *gc.C) { workertest.DirtyKill(c, s.worker) })
+}
+
+func (s *ManifoldSuite) validConfig() accessexpiry.ManifoldConfig {
+	return accessexpiry.ManifoldConfig{
+		ClockName:      "clock",
+		StateName:      "state",
+		RevokeInterval: time.Hour,
+		NewWorker: func(r accessexpiry.AccessRevoker, interval time.Duration, clock clock.Clock) worker.Worker {
+			s.stub.AddCall("NewWorker", r, interval, clock)
+			return s.worker
+		},
+	}
+}
+
+func (s *ManifoldSuite) TestValid(c *gc.C) {
+	c.Check(s.config.Validate(), jc.ErrorIsNil)
+}
+
+func (s *ManifoldSuite) TestMissingClockName(c *gc.C) {
+	s.config.ClockName = ""
+	s.checkNotValid(c, "empty ClockName not valid")
+}
+
+func (s *ManifoldSuite) TestMissingStateName(c *gc.C) {
+	s.config.StateName = ""
+	s.checkNotValid(c, "empty StateName not valid")
+}
+
+func (s *ManifoldSuite) TestZeroRevokeInterval(c *gc.C) {
+	s.config.RevokeInterval = 0
+	s.checkNotValid(c, "non-positive RevokeInterval not valid")
+}
+
+func (s *ManifoldSuite) TestMissingNewWorker(c *gc.C) {
+	s.config.NewWorker = nil
+	s.checkNotValid(c, "nil NewWorker not valid")
+}
+
+func (s *ManifoldSuite) checkNotValid(c *gc.C, expect string) {
+	err := s.config.Validate()
+	c.Check(err, gc.ErrorMatches, expect)
+	c.Check(err, jc.Satisfies, errors.IsNotValid)
+}
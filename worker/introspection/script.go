@@ -105,6 +105,14 @@ juju_engine_report () {
   juju_agent depengine $@
 }
 
+juju_dump_relations () {
+  juju_agent relations $@
+}
+
+juju_database_upgrade_report () {
+  juju_agent database-upgrade $@
+}
+
 juju_statepool_report () {
   juju_agent statepool $@
 }
@@ -144,6 +152,8 @@ if [ "$shell" = "bash" ]; then
   export -f juju_cpu_profile
   export -f juju_heap_profile
   export -f juju_engine_report
+  export -f juju_dump_relations
+  export -f juju_database_upgrade_report
   export -f juju_metrics
   export -f juju_statepool_report
   export -f juju_statetracker_report
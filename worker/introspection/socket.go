@@ -4,7 +4,9 @@
 package introspection
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"runtime"
@@ -15,6 +17,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"gopkg.in/juju/worker.v1"
+	"gopkg.in/juju/worker.v1/dependency"
 	"gopkg.in/tomb.v2"
 	"gopkg.in/yaml.v2"
 
@@ -39,6 +42,12 @@ type IntrospectionReporter interface {
 	IntrospectionReport() string
 }
 
+// RelationInjector accepts synthetic relation changes, encoded as
+// JSON, for the named unit.
+type RelationInjector interface {
+	InjectJSON(unitName string, data []byte) error
+}
+
 // Config describes the arguments required to create the introspection worker.
 type Config struct {
 	SocketName         string
@@ -48,6 +57,7 @@ type Config struct {
 	MachineLock        machinelock.Lock
 	PrometheusGatherer prometheus.Gatherer
 	Presence           presence.Recorder
+	RelationInjector   RelationInjector
 }
 
 // Validate checks the config values to assert they are valid to create the worker.
@@ -71,6 +81,7 @@ type socketListener struct {
 	machineLock        machinelock.Lock
 	prometheusGatherer prometheus.Gatherer
 	presence           presence.Recorder
+	relationInjector   RelationInjector
 	done               chan struct{}
 }
 
@@ -104,6 +115,7 @@ func NewWorker(config Config) (worker.Worker, error) {
 		machineLock:        config.MachineLock,
 		prometheusGatherer: config.PrometheusGatherer,
 		presence:           config.Presence,
+		relationInjector:   config.RelationInjector,
 		done:               make(chan struct{}),
 	}
 	go w.serve()
@@ -121,6 +133,7 @@ func (w *socketListener) serve() {
 			MachineLock:        w.machineLock,
 			PrometheusGatherer: w.prometheusGatherer,
 			Presence:           w.presence,
+			RelationInjector:   w.relationInjector,
 		}, mux.Handle)
 
 	srv := http.Server{Handler: mux}
@@ -159,6 +172,7 @@ type ReportSources struct {
 	MachineLock        machinelock.Lock
 	PrometheusGatherer prometheus.Gatherer
 	Presence           presence.Recorder
+	RelationInjector   RelationInjector
 }
 
 // AddHandlers calls the given function with http.Handlers
@@ -175,6 +189,21 @@ func RegisterHTTPHandlers(
 	handle("/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
 	handle("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
 	handle("/depengine", depengineHandler{sources.DependencyEngine})
+	handle("/relations", workerReportHandler{
+		reporter: sources.DependencyEngine,
+		key:      "relations",
+		missing:  "no relation state reported",
+	})
+	// Relation changes can only be injected when the
+	// inject-relation-state feature flag is enabled.
+	if sources.RelationInjector != nil {
+		handle("/relations/inject", relationInjectHandler{sources.RelationInjector})
+	}
+	handle("/database-upgrade", workerReportHandler{
+		reporter: sources.DependencyEngine,
+		key:      "database-upgrade",
+		missing:  "no database upgrade reported",
+	})
 	handle("/statepool", introspectionReporterHandler{
 		name:     "State Pool Report",
 		reporter: sources.StatePool,
@@ -215,6 +244,93 @@ func (h depengineHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Write(bytes)
 }
 
+// workerReportHandler serves a single entry from the reports
+// of the workers running in the dependency engine.
+type workerReportHandler struct {
+	reporter DepEngineReporter
+	// key is the entry in each worker report to serve.
+	key string
+	// missing is the message served if no worker reports the entry.
+	missing string
+}
+
+// ServeHTTP is part of the http.Handler interface.
+// It renders, as JSON, the entry reported by any worker
+// running in the dependency engine, keyed by manifold name.
+func (h workerReportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.reporter == nil {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(w, "missing dependency engine reporter")
+		return
+	}
+	reports := make(map[string]interface{})
+	manifolds, _ := h.reporter.Report()[dependency.KeyManifolds].(map[string]interface{})
+	for name, manifold := range manifolds {
+		manifoldReport, _ := manifold.(map[string]interface{})
+		workerReport, _ := manifoldReport[dependency.KeyReport].(map[string]interface{})
+		if report, ok := workerReport[h.key]; ok {
+			reports[name] = report
+		}
+	}
+	if len(reports) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(w, h.missing)
+		return
+	}
+	bytes, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "error: %v\n", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bytes)
+}
+
+type relationInjectHandler struct {
+	injector RelationInjector
+}
+
+// ServeHTTP is part of the http.Handler interface. It injects the
+// relation change in the request body into the remote state of the
+// unit named in the "unit" query parameter.
+func (h relationInjectHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprintf(w, "method %s not allowed\n", r.Method)
+		return
+	}
+	unitName := r.URL.Query().Get("unit")
+	if unitName == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, "missing unit")
+		return
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "error: %v\n", err)
+		return
+	}
+	if err := h.injector.InjectJSON(unitName, data); err != nil {
+		switch {
+		case errors.IsNotValid(err):
+			w.WriteHeader(http.StatusBadRequest)
+		case errors.IsNotFound(err):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		fmt.Fprintf(w, "error: %v\n", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
 type machineLockHandler struct {
 	lock machinelock.Lock
 }
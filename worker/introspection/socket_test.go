@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/juju/clock/testclock"
+	"github.com/juju/errors"
 	"github.com/juju/testing"
 	jc "github.com/juju/testing/checkers"
 	"github.com/prometheus/client_golang/prometheus"
@@ -61,6 +62,7 @@ type introspectionSuite struct {
 	reporter introspection.DepEngineReporter
 	gatherer prometheus.Gatherer
 	recorder presence.Recorder
+	injector introspection.RelationInjector
 }
 
 var _ = gc.Suite(&introspectionSuite{})
@@ -73,6 +75,7 @@ func (s *introspectionSuite) SetUpTest(c *gc.C) {
 	s.reporter = nil
 	s.worker = nil
 	s.recorder = nil
+	s.injector = nil
 	s.gatherer = newPrometheusGatherer()
 	s.startWorker(c)
 }
@@ -84,6 +87,7 @@ func (s *introspectionSuite) startWorker(c *gc.C) {
 		DepEngine:          s.reporter,
 		PrometheusGatherer: s.gatherer,
 		Presence:           s.recorder,
+		RelationInjector:   s.injector,
 	})
 	c.Assert(err, jc.ErrorIsNil)
 	s.worker = w
@@ -106,6 +110,20 @@ func (s *introspectionSuite) call(c *gc.C, url string) []byte {
 	return buf
 }
 
+func (s *introspectionSuite) post(c *gc.C, url, body string) []byte {
+	path := "@" + s.name
+	conn, err := net.Dial("unix", path)
+	c.Assert(err, jc.ErrorIsNil)
+	defer conn.Close()
+
+	_, err = fmt.Fprintf(conn, "POST %s HTTP/1.0\r\nContent-Length: %d\r\n\r\n%s", url, len(body), body)
+	c.Assert(err, jc.ErrorIsNil)
+
+	buf, err := ioutil.ReadAll(conn)
+	c.Assert(err, jc.ErrorIsNil)
+	return buf
+}
+
 func (s *introspectionSuite) TestCmdLine(c *gc.C) {
 	buf := s.call(c, "/debug/pprof/cmdline")
 	c.Assert(buf, gc.NotNil)
@@ -170,6 +188,148 @@ func (s *introspectionSuite) TestEngineReporter(c *gc.C) {
 	matches(c, buf, "working: true")
 }
 
+func (s *introspectionSuite) TestMissingRelationsReport(c *gc.C) {
+	workertest.CheckKill(c, s.worker)
+	s.reporter = &reporter{
+		values: map[string]interface{}{
+			"manifolds": map[string]interface{}{
+				"agent": map[string]interface{}{
+					"report": map[string]interface{}{"working": true},
+				},
+			},
+		},
+	}
+	s.startWorker(c)
+	buf := s.call(c, "/relations")
+
+	matches(c, buf, "404 Not Found")
+	matches(c, buf, "no relation state reported")
+}
+
+func (s *introspectionSuite) TestRelationsReport(c *gc.C) {
+	workertest.CheckKill(c, s.worker)
+	s.reporter = &reporter{
+		values: map[string]interface{}{
+			"manifolds": map[string]interface{}{
+				"uniter": map[string]interface{}{
+					"report": map[string]interface{}{
+						"relations": map[string]interface{}{
+							"relations": map[string]interface{}{
+								"1": map[string]interface{}{"endpoint": "db"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	s.startWorker(c)
+	buf := s.call(c, "/relations")
+
+	matches(c, buf, "200 OK")
+	matches(c, buf, "Content-Type: application/json")
+	matches(c, buf, `"endpoint": "db"`)
+}
+
+func (s *introspectionSuite) TestDatabaseUpgradeReport(c *gc.C) {
+	workertest.CheckKill(c, s.worker)
+	s.reporter = &reporter{
+		values: map[string]interface{}{
+			"manifolds": map[string]interface{}{
+				"upgrade-database-runner": map[string]interface{}{
+					"report": map[string]interface{}{
+						"database-upgrade": map[string]interface{}{
+							"phase": "running steps",
+							"step":  map[string]interface{}{"description": "add a collection"},
+						},
+					},
+				},
+			},
+		},
+	}
+	s.startWorker(c)
+	buf := s.call(c, "/database-upgrade")
+
+	matches(c, buf, "200 OK")
+	matches(c, buf, "Content-Type: application/json")
+	matches(c, buf, `"phase": "running steps"`)
+	matches(c, buf, `"description": "add a collection"`)
+}
+
+func (s *introspectionSuite) TestMissingDatabaseUpgradeReport(c *gc.C) {
+	workertest.CheckKill(c, s.worker)
+	s.reporter = &reporter{
+		values: map[string]interface{}{
+			"manifolds": map[string]interface{}{
+				"agent": map[string]interface{}{
+					"report": map[string]interface{}{"working": true},
+				},
+			},
+		},
+	}
+	s.startWorker(c)
+	buf := s.call(c, "/database-upgrade")
+
+	matches(c, buf, "404 Not Found")
+	matches(c, buf, "no database upgrade reported")
+}
+
+func (s *introspectionSuite) TestRelationInjectDisabled(c *gc.C) {
+	buf := s.post(c, "/relations/inject?unit=mysql/0", "{}")
+	matches(c, buf, "404 Not Found")
+	matches(c, buf, "page not found")
+}
+
+func (s *introspectionSuite) TestRelationInject(c *gc.C) {
+	workertest.CheckKill(c, s.worker)
+	injector := &injector{}
+	s.injector = injector
+	s.startWorker(c)
+
+	body := `{"relation-id": 1, "changed": {"wordpress/0": 2}}`
+	buf := s.post(c, "/relations/inject?unit=mysql/0", body)
+	matches(c, buf, "200 OK")
+	matches(c, buf, "^ok$")
+	c.Assert(injector.unitName, gc.Equals, "mysql/0")
+	c.Assert(string(injector.data), gc.Equals, body)
+}
+
+func (s *introspectionSuite) TestRelationInjectGET(c *gc.C) {
+	workertest.CheckKill(c, s.worker)
+	s.injector = &injector{}
+	s.startWorker(c)
+
+	buf := s.call(c, "/relations/inject?unit=mysql/0")
+	matches(c, buf, "405 Method Not Allowed")
+}
+
+func (s *introspectionSuite) TestRelationInjectMissingUnit(c *gc.C) {
+	workertest.CheckKill(c, s.worker)
+	s.injector = &injector{}
+	s.startWorker(c)
+
+	buf := s.post(c, "/relations/inject", "{}")
+	matches(c, buf, "400 Bad Request")
+	matches(c, buf, "missing unit")
+}
+
+func (s *introspectionSuite) TestRelationInjectErrors(c *gc.C) {
+	workertest.CheckKill(c, s.worker)
+	injector := &injector{}
+	s.injector = injector
+	s.startWorker(c)
+
+	injector.err = errors.NotValidf("unit name %q", "bad")
+	buf := s.post(c, "/relations/inject?unit=mysql/0", "{}")
+	matches(c, buf, "400 Bad Request")
+	matches(c, buf, `unit name "bad" not valid`)
+
+	injector.err = errors.NotFoundf("unit %q", "mysql/0")
+	buf = s.post(c, "/relations/inject?unit=mysql/0", "{}")
+	matches(c, buf, "404 Not Found")
+	matches(c, buf, `unit "mysql/0" not found`)
+}
+
 func (s *introspectionSuite) TestMissingPresenceReporter(c *gc.C) {
 	buf := s.call(c, "/presence/")
 	matches(c, buf, "404 Not Found")
@@ -235,6 +395,18 @@ func (r *reporter) Report() map[string]interface{} {
 	return r.values
 }
 
+type injector struct {
+	unitName string
+	data     []byte
+	err      error
+}
+
+func (i *injector) InjectJSON(unitName string, data []byte) error {
+	i.unitName = unitName
+	i.data = data
+	return i.err
+}
+
 func newPrometheusGatherer() prometheus.Gatherer {
 	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "tau", Help: "Tau."})
 	counter.Add(6.283185)
@@ -0,0 +1,95 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package migrationmaster
+
+import (
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+
+	"github.com/juju/juju/api/migrationtarget"
+	"github.com/juju/juju/apiserver/params"
+	coremigration "github.com/juju/juju/core/migration"
+)
+
+// importProgress accumulates the progress of the transfer of a model
+// to the target controller, and records it with the source controller
+// each time it changes.
+type importProgress struct {
+	facade Facade
+	logger loggo.Logger
+
+	mu       sync.Mutex
+	progress coremigration.ImportProgress
+}
+
+// setEntities records the progress of the import of the model's
+// entities, as reported by the target controller.
+func (p *importProgress) setEntities(entities []coremigration.EntityProgress) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.progress.Entities = entities
+	p.report()
+}
+
+// addBinariesBytes records that more bytes of the model's binaries
+// have been uploaded to the target controller.
+func (p *importProgress) addBinariesBytes(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.progress.BinariesBytes += n
+	p.report()
+}
+
+// report records the progress with the source controller. The
+// progress is only informative, so failures are logged rather than
+// interrupting the migration.
+func (p *importProgress) report() {
+	if err := p.facade.SetImportProgress(p.progress); err != nil {
+		p.logger.Debugf("failed to set import progress: %v", err)
+	}
+}
+
+// pollImportProgress periodically asks the target controller for the
+// progress of the import of the model, until done is closed or the
+// worker is dying.
+func (w *Worker) pollImportProgress(
+	targetClient *migrationtarget.Client, modelUUID string,
+	progress *importProgress, done <-chan struct{},
+) {
+	clk := w.config.Clock
+	for {
+		select {
+		case <-done:
+			return
+		case <-w.catacomb.Dying():
+			return
+		case <-clk.After(importProgressInterval):
+			if !w.updateImportProgress(targetClient, modelUUID, progress) {
+				return
+			}
+		}
+	}
+}
+
+// updateImportProgress asks the target controller for the progress of
+// the import of the model, and records it. It returns false if the
+// target controller doesn't report the progress of imports.
+func (w *Worker) updateImportProgress(
+	targetClient *migrationtarget.Client, modelUUID string, progress *importProgress,
+) bool {
+	entities, err := targetClient.ImportProgress(modelUUID)
+	if errors.IsNotSupported(err) {
+		return false
+	} else if params.IsCodeNotFound(err) {
+		// The import hasn't started yet.
+		return true
+	} else if err != nil {
+		w.logger.Debugf("failed to get import progress: %v", err)
+		return true
+	}
+	progress.setEntities(entities)
+	return true
+}
@@ -58,6 +58,14 @@ const (
 	// reports from minions and while it's transferring log messages
 	// to the newly-migrated model.
 	progressUpdateInterval = 30 * time.Second
+
+	// importProgressInterval is the time between requests to the
+	// target controller for the progress of the import of a model.
+	importProgressInterval = 5 * time.Second
+
+	// upgradeRetryInterval is the time between attempts to export or
+	// import a model while either controller's database is upgraded.
+	upgradeRetryInterval = time.Minute
 )
 
 // Facade exposes controller functionality to a Worker.
@@ -78,6 +86,10 @@ type Facade interface {
 	// progress of a migration.
 	SetStatusMessage(string) error
 
+	// SetImportProgress records the progress of the transfer of the
+	// model to the target controller.
+	SetImportProgress(coremigration.ImportProgress) error
+
 	// Prechecks performs pre-migration checks on the model and
 	// (source) controller.
 	Prechecks() error
@@ -369,21 +381,59 @@ func (w *Worker) doIMPORT(targetInfo coremigration.TargetInfo, modelUUID string)
 type uploadWrapper struct {
 	client    *migrationtarget.Client
 	modelUUID string
+	progress  *importProgress
 }
 
 // UploadTools prepends the model UUID to the args passed to the migration client.
 func (w *uploadWrapper) UploadTools(r io.ReadSeeker, vers version.Binary, additionalSeries ...string) (tools.List, error) {
-	return w.client.UploadTools(w.modelUUID, r, vers, additionalSeries...)
+	size, err := contentSize(r)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	result, err := w.client.UploadTools(w.modelUUID, r, vers, additionalSeries...)
+	if err == nil {
+		w.progress.addBinariesBytes(size)
+	}
+	return result, err
 }
 
 // UploadCharm prepends the model UUID to the args passed to the migration client.
 func (w *uploadWrapper) UploadCharm(curl *charm.URL, content io.ReadSeeker) (*charm.URL, error) {
-	return w.client.UploadCharm(w.modelUUID, curl, content)
+	size, err := contentSize(content)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	result, err := w.client.UploadCharm(w.modelUUID, curl, content)
+	if err == nil {
+		w.progress.addBinariesBytes(size)
+	}
+	return result, err
 }
 
 // UploadResource prepends the model UUID to the args passed to the migration client.
 func (w *uploadWrapper) UploadResource(res resource.Resource, content io.ReadSeeker) error {
-	return w.client.UploadResource(w.modelUUID, res, content)
+	size, err := contentSize(content)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	err = w.client.UploadResource(w.modelUUID, res, content)
+	if err == nil {
+		w.progress.addBinariesBytes(size)
+	}
+	return err
+}
+
+// contentSize returns the size of the content, leaving it
+// positioned at its start.
+func contentSize(content io.ReadSeeker) (int64, error) {
+	size, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, errors.Annotate(err, "finding content size")
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return 0, errors.Annotate(err, "rewinding content")
+	}
+	return size, nil
 }
 
 // SetPlaceholderResource prepends the model UUID to the args passed to the migration client.
@@ -398,7 +448,11 @@ func (w *uploadWrapper) SetUnitResource(unitName string, res resource.Resource)
 
 func (w *Worker) transferModel(targetInfo coremigration.TargetInfo, modelUUID string) error {
 	w.setInfoStatus("exporting model")
-	serialized, err := w.config.Facade.Export()
+	var serialized coremigration.SerializedModel
+	err := w.pauseDuringUpgrade("model export", func() (err error) {
+		serialized, err = w.config.Facade.Export()
+		return err
+	})
 	if err != nil {
 		return errors.Annotate(err, "model export failed")
 	}
@@ -410,10 +464,28 @@ func (w *Worker) transferModel(targetInfo coremigration.TargetInfo, modelUUID st
 	}
 	defer conn.Close()
 	targetClient := migrationtarget.NewClient(conn)
-	err = targetClient.Import(serialized.Bytes)
+
+	// Report the progress of the import while it runs, so that it
+	// can be shown to the user.
+	progress := &importProgress{
+		facade: w.config.Facade,
+		logger: w.logger,
+	}
+	done := make(chan struct{})
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		w.pollImportProgress(targetClient, modelUUID, progress, done)
+	}()
+	err = w.pauseDuringUpgrade("model import", func() error {
+		return targetClient.Import(serialized.Bytes, serialized.Manifest)
+	})
+	close(done)
+	<-polled
 	if err != nil {
 		return errors.Annotate(err, "failed to import model into target controller")
 	}
+	w.updateImportProgress(targetClient, modelUUID, progress)
 
 	if wrench.IsActive("migrationmaster", "die-in-export") {
 		// Simulate a abort causing failure to test last status not over written.
@@ -421,7 +493,7 @@ func (w *Worker) transferModel(targetInfo coremigration.TargetInfo, modelUUID st
 	}
 
 	w.setInfoStatus("uploading model binaries into target controller")
-	wrapper := &uploadWrapper{targetClient, modelUUID}
+	wrapper := &uploadWrapper{targetClient, modelUUID, progress}
 	err = w.config.UploadBinaries(migration.UploadBinariesConfig{
 		Charms:          serialized.Charms,
 		CharmDownloader: w.config.CharmDownloader,
@@ -438,6 +510,26 @@ func (w *Worker) transferModel(targetInfo coremigration.TargetInfo, modelUUID st
 	return errors.Annotate(err, "failed to migrate binaries")
 }
 
+// pauseDuringUpgrade calls f until it returns an error other than one
+// reporting that a controller database is being upgraded, waiting
+// between attempts. Models are neither exported nor imported while a
+// controller database is upgraded, so the migration is paused rather
+// than aborted.
+func (w *Worker) pauseDuringUpgrade(what string, f func() error) error {
+	for {
+		err := f()
+		if !params.IsCodeUpgradeInProgress(err) {
+			return err
+		}
+		w.setInfoStatus("%s paused while controller database is upgraded", what)
+		select {
+		case <-w.catacomb.Dying():
+			return w.catacomb.ErrDying()
+		case <-w.config.Clock.After(upgradeRetryInterval):
+		}
+	}
+}
+
 func (w *Worker) doPROCESSRELATIONS(status coremigration.MigrationStatus) (coremigration.Phase, error) {
 	err := w.processRelations(status.TargetInfo, status.ModelUUID)
 	if err != nil {
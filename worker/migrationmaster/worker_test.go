@@ -8,6 +8,7 @@ import (
 	"net/textproto"
 	"net/url"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/juju/clock/testclock"
@@ -91,6 +92,12 @@ var (
 			params.SerializedModel{Bytes: fakeModelBytes},
 		},
 	}
+	importProgressCall = jujutesting.StubCall{
+		"MigrationTarget.ImportProgress",
+		[]interface{}{
+			params.ModelArgs{ModelTag: modelTag.String()},
+		},
+	}
 	activateCall = jujutesting.StubCall{
 		"MigrationTarget.Activate",
 		[]interface{}{
@@ -509,6 +516,32 @@ func (s *Suite) TestExportFailure(c *gc.C) {
 	))
 }
 
+func (s *Suite) TestExportPausedDuringUpgrade(c *gc.C) {
+	s.facade.queueStatus(s.makeStatus(coremigration.IMPORT))
+	s.facade.exportUpgrading = 1
+	s.facade.exportErr = errors.New("boom")
+
+	worker, err := migrationmaster.New(s.config)
+	c.Assert(err, jc.ErrorIsNil)
+	defer workertest.DirtyKill(c, worker)
+
+	// The export is retried once the controller
+	// database upgrade has had time to complete.
+	s.clock.WaitAdvance(time.Minute, coretesting.LongWait, 1)
+
+	err = workertest.CheckKilled(c, worker)
+	c.Assert(errors.Cause(err), gc.Equals, migrationmaster.ErrInactive)
+	s.stub.CheckCalls(c, joinCalls(
+		watchStatusLockdownCalls,
+		[]jujutesting.StubCall{
+			{"facade.Export", nil},
+			{"facade.Export", nil},
+		},
+		abortCalls,
+	))
+	c.Check(strings.Join(s.facade.statuses, "\n"), jc.Contains, "model export paused while controller database is upgraded")
+}
+
 func (s *Suite) TestAPIOpenFailure(c *gc.C) {
 	s.facade.queueStatus(s.makeStatus(coremigration.IMPORT))
 	s.connectionErr = errors.New("boom")
@@ -543,6 +576,37 @@ func (s *Suite) TestImportFailure(c *gc.C) {
 	))
 }
 
+func (s *Suite) TestImportProgress(c *gc.C) {
+	s.facade.queueStatus(s.makeStatus(coremigration.IMPORT))
+	s.connection.facadeVersion = 2
+	s.connection.importProgress = []params.EntityImportProgress{
+		{Kind: "machines", Imported: 2, Total: 2},
+		{Kind: "units", Imported: 3, Total: 3},
+	}
+	s.config.UploadBinaries = func(migration.UploadBinariesConfig) error {
+		return errors.New("boom")
+	}
+
+	s.checkWorkerReturns(c, migrationmaster.ErrInactive)
+	s.stub.CheckCalls(c, joinCalls(
+		watchStatusLockdownCalls,
+		[]jujutesting.StubCall{
+			{"facade.Export", nil},
+			apiOpenControllerCall,
+			importCall,
+			importProgressCall,
+			apiCloseCall,
+		},
+		abortCalls,
+	))
+	c.Assert(s.facade.importProgress, jc.DeepEquals, []coremigration.ImportProgress{{
+		Entities: []coremigration.EntityProgress{
+			{Kind: coremigration.ImportMachines, Imported: 2, Total: 2},
+			{Kind: coremigration.ImportUnits, Imported: 3, Total: 3},
+		},
+	}})
+}
+
 func (s *Suite) TestVALIDATIONMinionWaitWatchError(c *gc.C) {
 	s.checkMinionWaitWatchError(c, coremigration.VALIDATION)
 }
@@ -1095,6 +1159,7 @@ type stubMasterFacade struct {
 	prechecksErr        error
 	modelInfoErr        error
 	exportErr           error
+	exportUpgrading     int
 	processRelationsErr error
 
 	logMessages func(chan<- common.LogMessage)
@@ -1107,7 +1172,8 @@ type stubMasterFacade struct {
 
 	exportedResources []coremigration.SerializedModelResource
 
-	statuses []string
+	statuses       []string
+	importProgress []coremigration.ImportProgress
 }
 
 func (f *stubMasterFacade) triggerWatcher() {
@@ -1199,6 +1265,10 @@ func (f *stubMasterFacade) ModelInfo() (coremigration.ModelInfo, error) {
 
 func (f *stubMasterFacade) Export() (coremigration.SerializedModel, error) {
 	f.stub.AddCall("facade.Export")
+	if f.exportUpgrading > 0 {
+		f.exportUpgrading--
+		return coremigration.SerializedModel{}, &params.Error{Code: params.CodeUpgradeInProgress}
+	}
 	if f.exportErr != nil {
 		return coremigration.SerializedModel{}, f.exportErr
 	}
@@ -1230,6 +1300,11 @@ func (f *stubMasterFacade) SetStatusMessage(message string) error {
 	return nil
 }
 
+func (f *stubMasterFacade) SetImportProgress(progress coremigration.ImportProgress) error {
+	f.importProgress = append(f.importProgress, progress)
+	return nil
+}
+
 func (f *stubMasterFacade) Reap() error {
 	f.stub.AddCall("facade.Reap")
 	return nil
@@ -1284,9 +1359,15 @@ type stubConnection struct {
 
 	machineErrs     []string
 	checkMachineErr error
+
+	facadeVersion  int
+	importProgress []params.EntityImportProgress
 }
 
 func (c *stubConnection) BestFacadeVersion(string) int {
+	if c.facadeVersion != 0 {
+		return c.facadeVersion
+	}
 	return 1
 }
 
@@ -1299,6 +1380,10 @@ func (c *stubConnection) APICall(objType string, version int, id, request string
 			return c.prechecksErr
 		case "Import":
 			return c.importErr
+		case "ImportProgress":
+			results := response.(*params.MigrationImportProgress)
+			results.Entities = c.importProgress
+			return nil
 		case "ProcessRelations":
 			return c.processRelationsErr
 		case "Activate", "AdoptResources":
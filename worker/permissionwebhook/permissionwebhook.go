@@ -0,0 +1,178 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package permissionwebhook provides a worker that posts changes to users'
+// controller and model access to the webhook configured for the controller,
+// so that they can be recorded by an external audit system.
+package permissionwebhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/juju/clock"
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+	"gopkg.in/juju/worker.v1"
+
+	"github.com/juju/juju/controller"
+	"github.com/juju/juju/state"
+	jworker "github.com/juju/juju/worker"
+)
+
+var logger = loggo.GetLogger("juju.worker.permissionwebhook")
+
+const (
+	// SignatureHeader is the header holding the signature of each post
+	// to the webhook: "sha256=" followed by the hex encoded HMAC-SHA256
+	// of the body, keyed with the configured secret.
+	SignatureHeader = "X-Juju-Signature"
+
+	// MaxAttempts is the number of times delivery of an event is
+	// attempted before it is abandoned as a dead letter.
+	MaxAttempts = 8
+
+	// initialRetryDelay is the time to wait before first retrying
+	// delivery of an event. The delay doubles with each attempt.
+	initialRetryDelay = 10 * time.Second
+
+	// maxRetryDelay is the longest time to wait between attempts.
+	maxRetryDelay = time.Hour
+
+	// postTimeout is the time allowed for each post to the webhook.
+	postTimeout = 10 * time.Second
+)
+
+// EventQueue defines the interface for types holding the
+// queue of permission events to be posted to the webhook.
+type EventQueue interface {
+	ControllerConfig() (controller.Config, error)
+	DuePermissionEvents(now time.Time) ([]state.PermissionEvent, error)
+	PermissionEventDelivered(id string) error
+	PermissionEventFailed(id string, reason string, retryAt time.Time) error
+}
+
+// Event is the serialization of a permission event
+// as it is posted to the webhook.
+type Event struct {
+	ID     string    `json:"id"`
+	Kind   string    `json:"kind"`
+	User   string    `json:"user"`
+	Target string    `json:"target"`
+	Access string    `json:"access"`
+	By     string    `json:"by,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// New returns a worker which periodically posts the queued
+// permission events to the webhook, retrying failed posts with
+// an increasing delay until MaxAttempts is reached.
+func New(queue EventQueue, interval time.Duration, clock clock.Clock) worker.Worker {
+	return jworker.NewSimpleWorker(func(stopCh <-chan struct{}) error {
+		for {
+			select {
+			case <-clock.After(interval):
+				if err := deliverDue(queue, clock); err != nil {
+					return errors.Annotate(err, "delivering permission events, permissionwebhook stopping")
+				}
+			case <-stopCh:
+				return nil
+			}
+		}
+	})
+}
+
+// deliverDue posts each event whose delivery is due to the webhook,
+// and records the outcome.
+func deliverDue(queue EventQueue, clock clock.Clock) error {
+	cfg, err := queue.ControllerConfig()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	url, secret := cfg.PermissionWebhookURL(), cfg.PermissionWebhookSecret()
+	if url == "" {
+		return nil
+	}
+	events, err := queue.DuePermissionEvents(clock.Now())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, ev := range events {
+		postErr := post(url, secret, ev)
+		if postErr == nil {
+			if err := queue.PermissionEventDelivered(ev.ID); err != nil {
+				return errors.Trace(err)
+			}
+			continue
+		}
+		var retryAt time.Time
+		if ev.Attempts+1 < MaxAttempts {
+			retryAt = clock.Now().Add(RetryDelay(ev.Attempts))
+			logger.Debugf("cannot deliver permission event %s, retrying at %v: %v", ev.ID, retryAt, postErr)
+		} else {
+			logger.Warningf("abandoning permission event %s after %d attempts: %v", ev.ID, MaxAttempts, postErr)
+		}
+		if err := queue.PermissionEventFailed(ev.ID, postErr.Error(), retryAt); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// RetryDelay returns the time to wait before retrying
+// an event that has already failed the given number of times.
+func RetryDelay(failures int) time.Duration {
+	delay := initialRetryDelay
+	for i := 0; i < failures && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// Sign returns the value of the SignatureHeader for the body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// post posts the event, as signed JSON, to the URL.
+func post(url, secret string, ev state.PermissionEvent) error {
+	event := Event{
+		ID:     ev.ID,
+		Kind:   string(ev.Kind),
+		User:   ev.User.Id(),
+		Target: ev.Target.String(),
+		Access: string(ev.Access),
+		By:     ev.By.Id(),
+		Time:   ev.Time,
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(secret, body))
+	client := &http.Client{Timeout: postTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package permissionwebhook_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/juju/clock"
+	"github.com/juju/clock/testclock"
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v3"
+	"gopkg.in/juju/worker.v1/workertest"
+
+	"github.com/juju/juju/controller"
+	"github.com/juju/juju/core/permission"
+	"github.com/juju/juju/state"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/worker/permissionwebhook"
+)
+
+type PermissionWebhookSuite struct {
+	coretesting.BaseSuite
+
+	clock    *testclock.Clock
+	server   *httptest.Server
+	status   int
+	requests chan *http.Request
+	bodies   chan []byte
+}
+
+var _ = gc.Suite(&PermissionWebhookSuite{})
+
+func (s *PermissionWebhookSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	s.clock = testclock.NewClock(time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC))
+	s.status = http.StatusOK
+	s.requests = make(chan *http.Request, 1)
+	s.bodies = make(chan []byte, 1)
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		s.requests <- r
+		s.bodies <- body
+		w.WriteHeader(s.status)
+	}))
+	s.AddCleanup(func(*gc.C) { s.server.Close() })
+}
+
+func (s *PermissionWebhookSuite) event(attempts int) state.PermissionEvent {
+	return state.PermissionEvent{
+		ID:       "5e9d8c1f2a3b4c5d6e7f8091",
+		Kind:     state.PermissionGranted,
+		User:     names.NewUserTag("bob"),
+		Target:   names.NewModelTag("deadbeef-0bad-400d-8000-4b1d0d06f00d"),
+		Access:   permission.WriteAccess,
+		By:       names.NewUserTag("admin"),
+		Time:     s.clock.Now(),
+		Attempts: attempts,
+	}
+}
+
+func (s *PermissionWebhookSuite) tick(c *gc.C) {
+	select {
+	case <-s.clock.Alarms():
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for worker to wait")
+	}
+	s.clock.Advance(time.Minute)
+}
+
+func (s *PermissionWebhookSuite) TestDelivers(c *gc.C) {
+	queue := newFakeQueue(s.server.URL, s.event(0))
+	w := permissionwebhook.New(queue, time.Minute, s.clock)
+	defer workertest.CleanKill(c, w)
+	s.tick(c)
+
+	var req *http.Request
+	var body []byte
+	select {
+	case req = <-s.requests:
+		body = <-s.bodies
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for post")
+	}
+	c.Check(req.Method, gc.Equals, "POST")
+	c.Check(req.Header.Get("Content-Type"), gc.Equals, "application/json")
+	c.Check(req.Header.Get(permissionwebhook.SignatureHeader), gc.Equals, permissionwebhook.Sign("sekrit", body))
+	var ev permissionwebhook.Event
+	err := json.Unmarshal(body, &ev)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(ev, jc.DeepEquals, permissionwebhook.Event{
+		ID:     "5e9d8c1f2a3b4c5d6e7f8091",
+		Kind:   "grant",
+		User:   "bob",
+		Target: "model-deadbeef-0bad-400d-8000-4b1d0d06f00d",
+		Access: "write",
+		By:     "admin",
+		Time:   s.clock.Now(),
+	})
+
+	c.Check(<-queue.outcomes, gc.Equals, "delivered 5e9d8c1f2a3b4c5d6e7f8091")
+}
+
+func (s *PermissionWebhookSuite) TestRetriesFailedPost(c *gc.C) {
+	s.status = http.StatusInternalServerError
+	queue := newFakeQueue(s.server.URL, s.event(2))
+	w := permissionwebhook.New(queue, time.Minute, s.clock)
+	defer workertest.CleanKill(c, w)
+	s.tick(c)
+
+	select {
+	case outcome := <-queue.outcomes:
+		c.Check(outcome, gc.Equals, "failed 5e9d8c1f2a3b4c5d6e7f8091")
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for failure to be recorded")
+	}
+	c.Check(queue.reason, gc.Equals, "webhook returned 500 Internal Server Error")
+	c.Check(queue.retryAt, gc.Equals, s.clock.Now().Add(40*time.Second))
+}
+
+func (s *PermissionWebhookSuite) TestAbandonsAfterMaxAttempts(c *gc.C) {
+	s.status = http.StatusBadGateway
+	queue := newFakeQueue(s.server.URL, s.event(permissionwebhook.MaxAttempts-1))
+	w := permissionwebhook.New(queue, time.Minute, s.clock)
+	defer workertest.CleanKill(c, w)
+	s.tick(c)
+
+	select {
+	case outcome := <-queue.outcomes:
+		c.Check(outcome, gc.Equals, "failed 5e9d8c1f2a3b4c5d6e7f8091")
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for failure to be recorded")
+	}
+	c.Check(queue.retryAt.IsZero(), jc.IsTrue)
+}
+
+func (s *PermissionWebhookSuite) TestNoWebhookConfigured(c *gc.C) {
+	queue := newFakeQueue("", s.event(0))
+	w := permissionwebhook.New(queue, time.Minute, s.clock)
+	defer workertest.CleanKill(c, w)
+	s.tick(c)
+	// Wait for the worker to wait again, having
+	// checked the config and found no webhook.
+	s.tick(c)
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	c.Check(queue.dueCalls, gc.Equals, 0)
+}
+
+func (s *PermissionWebhookSuite) TestQueueError(c *gc.C) {
+	queue := newFakeQueue(s.server.URL)
+	queue.err = errors.New("boom")
+	w := permissionwebhook.New(queue, time.Minute, s.clock)
+	defer workertest.DirtyKill(c, w)
+	s.tick(c)
+
+	err := workertest.CheckKilled(c, w)
+	c.Assert(err, gc.ErrorMatches, "delivering permission events, permissionwebhook stopping: boom")
+}
+
+func (s *PermissionWebhookSuite) TestStops(c *gc.C) {
+	w := permissionwebhook.New(newFakeQueue(""), time.Minute, clock.WallClock)
+	workertest.CleanKill(c, w)
+	c.Check(w.Wait(), jc.ErrorIsNil)
+}
+
+func (s *PermissionWebhookSuite) TestRetryDelay(c *gc.C) {
+	for failures, expect := range []time.Duration{
+		10 * time.Second,
+		20 * time.Second,
+		40 * time.Second,
+		80 * time.Second,
+		160 * time.Second,
+		320 * time.Second,
+		640 * time.Second,
+		1280 * time.Second,
+		2560 * time.Second,
+		time.Hour,
+	} {
+		c.Check(permissionwebhook.RetryDelay(failures), gc.Equals, expect)
+	}
+	c.Check(permissionwebhook.RetryDelay(100), gc.Equals, time.Hour)
+}
+
+func newFakeQueue(url string, events ...state.PermissionEvent) *fakeQueue {
+	cfg := controller.Config{}
+	if url != "" {
+		cfg[controller.PermissionWebhookURL] = url
+		cfg[controller.PermissionWebhookSecret] = "sekrit"
+	}
+	return &fakeQueue{
+		cfg:      cfg,
+		events:   events,
+		outcomes: make(chan string, len(events)),
+	}
+}
+
+type fakeQueue struct {
+	mu       sync.Mutex
+	cfg      controller.Config
+	events   []state.PermissionEvent
+	err      error
+	dueCalls int
+	reason   string
+	retryAt  time.Time
+	outcomes chan string
+}
+
+// ControllerConfig implements permissionwebhook.EventQueue.
+func (q *fakeQueue) ControllerConfig() (controller.Config, error) {
+	return q.cfg, nil
+}
+
+// DuePermissionEvents implements permissionwebhook.EventQueue.
+func (q *fakeQueue) DuePermissionEvents(time.Time) ([]state.PermissionEvent, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.dueCalls++
+	events := q.events
+	q.events = nil
+	return events, q.err
+}
+
+// PermissionEventDelivered implements permissionwebhook.EventQueue.
+func (q *fakeQueue) PermissionEventDelivered(id string) error {
+	q.outcomes <- "delivered " + id
+	return nil
+}
+
+// PermissionEventFailed implements permissionwebhook.EventQueue.
+func (q *fakeQueue) PermissionEventFailed(id string, reason string, retryAt time.Time) error {
+	q.mu.Lock()
+	q.reason = reason
+	q.retryAt = retryAt
+	q.mu.Unlock()
+	q.outcomes <- "failed " + id
+	return nil
+}
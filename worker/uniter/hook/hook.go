@@ -6,7 +6,9 @@ package hook
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/juju/errors"
 	"gopkg.in/juju/charm.v6/hooks"
 	"gopkg.in/juju/names.v3"
 )
@@ -41,6 +43,26 @@ type Info struct {
 
 	// StorageId is the ID of the storage instance relevant to the hook.
 	StorageId string `yaml:"storage-id,omitempty"`
+
+	// Observed is when the controller observed the change associated
+	// with RemoteUnit, in UTC. It is only set when RemoteUnit is set,
+	// and the time of the change is known.
+	Observed time.Time `yaml:"observed,omitempty"`
+
+	// IngressNetworksVersion and EgressNetworksVersion identify the
+	// ingress and egress CIDRs of a cross model relation seen by the
+	// hook. They are only set when Kind is relation-changed and
+	// RemoteUnit is not set.
+	IngressNetworksVersion string `yaml:"ingress-networks-version,omitempty"`
+	EgressNetworksVersion  string `yaml:"egress-networks-version,omitempty"`
+}
+
+// SameHook returns true if the info describes the same hook as other,
+// regardless of when the change that triggered it was observed. The
+// same change is observed again when the unit's agent restarts.
+func (hi Info) SameHook(other Info) bool {
+	hi.Observed = other.Observed
+	return hi == other
 }
 
 // Validate returns an error if the info is not valid.
@@ -77,7 +99,26 @@ func (hi Info) Validate() error {
 	case LeaderElected, LeaderDeposed, LeaderSettingsChanged:
 		return nil
 	}
-	return fmt.Errorf("unknown hook kind %q", hi.Kind)
+	return &unknownKindError{kind: hi.Kind}
+}
+
+// unknownKindError is returned by Validate for hook kinds unknown to this
+// version of the agent, such as those recorded by a newer agent before
+// the unit's agent was downgraded.
+type unknownKindError struct {
+	kind hooks.Kind
+}
+
+// Error is part of the error interface.
+func (e *unknownKindError) Error() string {
+	return fmt.Sprintf("unknown hook kind %q", e.kind)
+}
+
+// IsUnknownKind returns true if the error is caused by
+// the validation of a hook of an unknown kind.
+func IsUnknownKind(err error) bool {
+	_, ok := errors.Cause(err).(*unknownKindError)
+	return ok
 }
 
 // Committer is an interface that may be used to convey the fact that the
@@ -4,9 +4,13 @@
 package hook_test
 
 import (
+	"time"
+
+	"github.com/juju/errors"
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 	"gopkg.in/juju/charm.v6/hooks"
+	"gopkg.in/yaml.v2"
 
 	"github.com/juju/juju/testing"
 	"github.com/juju/juju/worker/uniter/hook"
@@ -74,3 +78,54 @@ func (s *InfoSuite) TestValidate(c *gc.C) {
 		}
 	}
 }
+
+func (s *InfoSuite) TestIsUnknownKind(c *gc.C) {
+	err := hook.Info{Kind: hooks.Kind("grok")}.Validate()
+	c.Check(hook.IsUnknownKind(err), jc.IsTrue)
+	c.Check(hook.IsUnknownKind(errors.Annotate(err, "reading state")), jc.IsTrue)
+
+	err = hook.Info{Kind: hooks.RelationJoined}.Validate()
+	c.Check(hook.IsUnknownKind(err), jc.IsFalse)
+	c.Check(hook.IsUnknownKind(nil), jc.IsFalse)
+}
+
+func (s *InfoSuite) TestSameHook(c *gc.C) {
+	info := hook.Info{
+		Kind:              hooks.RelationChanged,
+		RelationId:        1,
+		RemoteUnit:        "x/0",
+		RemoteApplication: "x",
+		ChangeVersion:     3,
+		Observed:          time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC),
+	}
+	reobserved := info
+	reobserved.Observed = info.Observed.Add(time.Minute)
+	c.Check(info.SameHook(reobserved), jc.IsTrue)
+	c.Check(info.SameHook(hook.Info{}), jc.IsFalse)
+
+	changed := reobserved
+	changed.ChangeVersion = 4
+	c.Check(info.SameHook(changed), jc.IsFalse)
+}
+
+func (s *InfoSuite) TestObservedRoundTrip(c *gc.C) {
+	info := hook.Info{
+		Kind:              hooks.RelationJoined,
+		RelationId:        1,
+		RemoteUnit:        "x/0",
+		RemoteApplication: "x",
+		Observed:          time.Date(2020, 4, 1, 12, 0, 0, 123, time.UTC),
+	}
+	data, err := yaml.Marshal(info)
+	c.Assert(err, jc.ErrorIsNil)
+	var read hook.Info
+	err = yaml.Unmarshal(data, &read)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(read == info, jc.IsTrue)
+
+	// An unknown observation is omitted.
+	info.Observed = time.Time{}
+	data, err = yaml.Marshal(info)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(string(data), gc.Not(jc.Contains), "observed")
+}
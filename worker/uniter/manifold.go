@@ -20,6 +20,7 @@ import (
 	"github.com/juju/juju/worker/common/reboot"
 	"github.com/juju/juju/worker/fortress"
 	"github.com/juju/juju/worker/uniter/operation"
+	"github.com/juju/juju/worker/uniter/remotestate"
 	"github.com/juju/juju/worker/uniter/resolver"
 )
 
@@ -34,6 +35,10 @@ type ManifoldConfig struct {
 	CharmDirName          string
 	HookRetryStrategyName string
 	TranslateResolverErr  func(error) error
+
+	// RelationInjector, if not nil, delivers synthetic relation
+	// changes to the uniter. It is only used to test charms.
+	RelationInjector *remotestate.Injector
 }
 
 // Manifold returns a dependency manifold that runs a uniter worker,
@@ -107,6 +112,7 @@ func Manifold(config ManifoldConfig) dependency.Manifold {
 				TranslateResolverErr: config.TranslateResolverErr,
 				Clock:                manifoldConfig.Clock,
 				RebootQuerier:        reboot.NewMonitor(agentConfig.TransientDataDir()),
+				RelationInjector:     config.RelationInjector,
 			})
 			if err != nil {
 				return nil, errors.Trace(err)
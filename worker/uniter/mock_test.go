@@ -4,6 +4,7 @@
 package uniter_test
 
 import (
+	"github.com/juju/testing"
 	"gopkg.in/juju/names.v3"
 
 	"github.com/juju/juju/apiserver/params"
@@ -26,3 +27,16 @@ type nopResolver struct{}
 func (nopResolver) NextOp(resolver.LocalState, remotestate.Snapshot, operation.Factory) (operation.Operation, error) {
 	return nil, resolver.ErrNoOperation
 }
+
+type drainableResolver struct {
+	nopResolver
+	stub *testing.Stub
+}
+
+func (r drainableResolver) Drain() {
+	r.stub.AddCall("Drain")
+}
+
+func (r drainableResolver) Resume() {
+	r.stub.AddCall("Resume")
+}
@@ -130,6 +130,11 @@ func (opc *operationCallbacks) SetCurrentCharm(charmURL *corecharm.URL) error {
 	return opc.u.unit.SetCharmURL(charmURL)
 }
 
+// RenameRelationEndpoints is part of the operation.Callbacks interface.
+func (opc *operationCallbacks) RenameRelationEndpoints() error {
+	return opc.u.relationStateTracker.RenameEndpoints()
+}
+
 // SetExecutingStatus is part of the operation.Callbacks interface.
 func (opc *operationCallbacks) SetExecutingStatus(message string) error {
 	return setAgentStatus(opc.u, status.Executing, message, nil)
@@ -85,8 +85,15 @@ func (d *deploy) Execute(state State) (*State, error) {
 }
 
 // Commit restores state for any interrupted hook, or queues an install or
-// upgrade-charm hook if no hook was interrupted.
+// upgrade-charm hook if no hook was interrupted. On upgrade, any relation
+// endpoints renamed by the new charm are renamed first, so that the hooks
+// that follow run under the new names.
 func (d *deploy) Commit(state State) (*State, error) {
+	if d.kind == Upgrade {
+		if err := d.callbacks.RenameRelationEndpoints(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
 	change := &stateChange{
 		Kind: RunHook,
 	}
@@ -506,6 +506,7 @@ func (s *DeploySuite) TestCommitQueueInstallHook(c *gc.C) {
 		Step: operation.Queued,
 		Hook: &hook.Info{Kind: hooks.Install},
 	})
+	c.Check(callbacks.MockRenameRelationEndpoints.called, jc.IsFalse)
 }
 
 func (s *DeploySuite) testCommitQueueUpgradeHook(c *gc.C, newDeploy newDeploy) {
@@ -532,6 +533,7 @@ func (s *DeploySuite) testCommitQueueUpgradeHook(c *gc.C, newDeploy newDeploy) {
 		Step: operation.Queued,
 		Hook: &hook.Info{Kind: hooks.UpgradeCharm},
 	})
+	c.Check(callbacks.MockRenameRelationEndpoints.called, jc.IsTrue)
 }
 
 func (s *DeploySuite) TestCommitQueueUpgradeHook_Upgrade(c *gc.C) {
@@ -546,6 +548,36 @@ func (s *DeploySuite) TestCommitQueueUpgradeHook_ResolvedUpgrade(c *gc.C) {
 	s.testCommitQueueUpgradeHook(c, (operation.Factory).NewResolvedUpgrade)
 }
 
+func (s *DeploySuite) testCommitRenameRelationEndpointsError(c *gc.C, newDeploy newDeploy) {
+	callbacks := NewDeployCommitCallbacks(errors.New("bad upgrade manifest"))
+	deployer := &MockDeployer{
+		MockNotifyRevert:   &MockNoArgs{},
+		MockNotifyResolved: &MockNoArgs{},
+	}
+	factory := operation.NewFactory(operation.FactoryParams{
+		Deployer:  deployer,
+		Callbacks: callbacks,
+	})
+
+	op, err := newDeploy(factory, curl("cs:quantal/x-0"))
+	c.Assert(err, jc.ErrorIsNil)
+	newState, err := op.Commit(operation.State{
+		Kind:     operation.Upgrade,
+		Step:     operation.Done,
+		CharmURL: nil, // doesn't actually matter here
+	})
+	c.Check(err, gc.ErrorMatches, "bad upgrade manifest")
+	c.Check(newState, gc.IsNil)
+}
+
+func (s *DeploySuite) TestCommitRenameRelationEndpointsError_Upgrade(c *gc.C) {
+	s.testCommitRenameRelationEndpointsError(c, (operation.Factory).NewUpgrade)
+}
+
+func (s *DeploySuite) TestCommitRenameRelationEndpointsError_RevertUpgrade(c *gc.C) {
+	s.testCommitRenameRelationEndpointsError(c, (operation.Factory).NewRevertUpgrade)
+}
+
 func (s *DeploySuite) testCommitInterruptedHook(c *gc.C, newDeploy newDeploy) {
 	callbacks := NewDeployCommitCallbacks(nil)
 	deployer := &MockDeployer{
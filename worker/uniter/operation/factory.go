@@ -105,6 +105,22 @@ func (f *factory) NewSkipHook(hookInfo hook.Info) (Operation, error) {
 	return &skipOperation{hookOp}, nil
 }
 
+// NewQuarantineHook is part of the Factory interface.
+func (f *factory) NewQuarantineHook(hookInfo hook.Info) (Operation, error) {
+	if err := hookInfo.Validate(); err != nil {
+		return nil, err
+	}
+	if !hookInfo.Kind.IsRelation() {
+		return nil, errors.Errorf("cannot quarantine %q hook", hookInfo.Kind)
+	}
+	return &quarantineHook{info: hookInfo}, nil
+}
+
+// NewReleaseQuarantinedHooks is part of the Factory interface.
+func (f *factory) NewReleaseQuarantinedHooks() (Operation, error) {
+	return &releaseQuarantinedHooks{}, nil
+}
+
 // NewAction is part of the Factory interface.
 func (f *factory) NewAction(actionId string) (Operation, error) {
 	if !names.IsValidAction(actionId) {
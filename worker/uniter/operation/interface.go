@@ -109,6 +109,15 @@ type Factory interface {
 	// completed successfully, without executing the hook.
 	NewSkipHook(hookInfo hook.Info) (Operation, error)
 
+	// NewQuarantineHook creates an operation to set aside the supplied
+	// failed relation hook, without committing it, so that hooks for
+	// other relations can run while the failure is investigated.
+	NewQuarantineHook(hookInfo hook.Info) (Operation, error)
+
+	// NewReleaseQuarantinedHooks creates an operation to release all
+	// quarantined relation hooks, so that they are run again.
+	NewReleaseQuarantinedHooks() (Operation, error)
+
 	// NewAction creates an operation to execute the supplied action.
 	NewAction(actionId string) (Operation, error)
 
@@ -185,6 +194,12 @@ type Callbacks interface {
 	// charm or the application's settings for it. It's only used by Deploy operations.
 	SetCurrentCharm(charmURL *corecharm.URL) error
 
+	// RenameRelationEndpoints applies any relation endpoint renames
+	// declared by the newly deployed charm, so that relations established
+	// under the old endpoint names continue under the new ones. It's only
+	// used by Deploy operations that upgrade the charm.
+	RenameRelationEndpoints() error
+
 	// SetSeriesStatusUpgrade is intended to give the uniter a chance to
 	// upgrade the status of a running series upgrade before or after
 	// upgrade series hook code completes and, for display purposes, to
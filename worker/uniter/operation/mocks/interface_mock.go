@@ -249,6 +249,36 @@ func (mr *MockFactoryMockRecorder) NewNoOpUpgrade(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewNoOpUpgrade", reflect.TypeOf((*MockFactory)(nil).NewNoOpUpgrade), arg0)
 }
 
+// NewQuarantineHook mocks base method
+func (m *MockFactory) NewQuarantineHook(arg0 hook.Info) (operation.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewQuarantineHook", arg0)
+	ret0, _ := ret[0].(operation.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewQuarantineHook indicates an expected call of NewQuarantineHook
+func (mr *MockFactoryMockRecorder) NewQuarantineHook(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewQuarantineHook", reflect.TypeOf((*MockFactory)(nil).NewQuarantineHook), arg0)
+}
+
+// NewReleaseQuarantinedHooks mocks base method
+func (m *MockFactory) NewReleaseQuarantinedHooks() (operation.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewReleaseQuarantinedHooks")
+	ret0, _ := ret[0].(operation.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewReleaseQuarantinedHooks indicates an expected call of NewReleaseQuarantinedHooks
+func (mr *MockFactoryMockRecorder) NewReleaseQuarantinedHooks() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewReleaseQuarantinedHooks", reflect.TypeOf((*MockFactory)(nil).NewReleaseQuarantinedHooks))
+}
+
 // NewResignLeadership mocks base method
 func (m *MockFactory) NewResignLeadership() (operation.Operation, error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,116 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package operation
+
+import (
+	"fmt"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/worker/uniter/hook"
+	"github.com/juju/juju/worker/uniter/remotestate"
+)
+
+// quarantineHook sets aside a failed relation hook, so that the
+// uniter can continue to run hooks for the unit's other relations.
+// The hook is not committed, so the relation's state still requires
+// it to run; it runs again once it is released.
+type quarantineHook struct {
+	DoesNotRequireMachineLock
+
+	info hook.Info
+}
+
+// String is part of the Operation interface.
+func (qh *quarantineHook) String() string {
+	return fmt.Sprintf("quarantine %s (%d) hook", qh.info.Kind, qh.info.RelationId)
+}
+
+// Prepare is part of the Operation interface.
+func (qh *quarantineHook) Prepare(state State) (*State, error) {
+	if err := qh.checkState(state); err != nil {
+		return nil, err
+	}
+	return nil, ErrSkipExecute
+}
+
+// Execute is part of the Operation interface.
+func (qh *quarantineHook) Execute(state State) (*State, error) {
+	return nil, errors.New("prepare always errors; Execute is never valid")
+}
+
+// Commit is part of the Operation interface.
+func (qh *quarantineHook) Commit(state State) (*State, error) {
+	if err := qh.checkState(state); err != nil {
+		return nil, err
+	}
+	newState := stateChange{
+		Kind: Continue,
+		Step: Pending,
+	}.apply(state)
+	newState.QuarantinedHooks = copyQuarantined(state.QuarantinedHooks)
+	newState.QuarantinedHooks[qh.info.RelationId] = qh.info
+	if _, ok := state.RelationHookRetries[qh.info.RelationId]; ok {
+		newState.RelationHookRetries = copyRetries(state.RelationHookRetries)
+		delete(newState.RelationHookRetries, qh.info.RelationId)
+	}
+	return newState, nil
+}
+
+// RemoteStateChanged is called when the remote state changed during execution
+// of the operation.
+func (qh *quarantineHook) RemoteStateChanged(snapshot remotestate.Snapshot) {
+}
+
+// checkState returns an error unless the hook is the one that failed.
+func (qh *quarantineHook) checkState(state State) error {
+	if state.Kind != RunHook || state.Step != Pending || state.Hook == nil || *state.Hook != qh.info {
+		return errors.Errorf("cannot quarantine %s hook: hook has not failed", qh.info.Kind)
+	}
+	return nil
+}
+
+// releaseQuarantinedHooks releases all the quarantined relation hooks.
+// As they were never committed, the relations resolver selects them
+// to run again.
+type releaseQuarantinedHooks struct {
+	DoesNotRequireMachineLock
+}
+
+// String is part of the Operation interface.
+func (rq *releaseQuarantinedHooks) String() string {
+	return "release quarantined hooks"
+}
+
+// Prepare is part of the Operation interface.
+func (rq *releaseQuarantinedHooks) Prepare(state State) (*State, error) {
+	return nil, ErrSkipExecute
+}
+
+// Execute is part of the Operation interface.
+func (rq *releaseQuarantinedHooks) Execute(state State) (*State, error) {
+	return nil, errors.New("prepare always errors; Execute is never valid")
+}
+
+// Commit is part of the Operation interface.
+func (rq *releaseQuarantinedHooks) Commit(state State) (*State, error) {
+	if len(state.QuarantinedHooks) == 0 {
+		return nil, nil
+	}
+	state.QuarantinedHooks = nil
+	return &state, nil
+}
+
+// RemoteStateChanged is called when the remote state changed during execution
+// of the operation.
+func (rq *releaseQuarantinedHooks) RemoteStateChanged(snapshot remotestate.Snapshot) {
+}
+
+func copyQuarantined(quarantined map[int]hook.Info) map[int]hook.Info {
+	result := make(map[int]hook.Info, len(quarantined)+1)
+	for id, info := range quarantined {
+		result[id] = info
+	}
+	return result
+}
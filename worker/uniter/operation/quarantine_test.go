@@ -0,0 +1,134 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package operation_test
+
+import (
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/charm.v6/hooks"
+
+	"github.com/juju/juju/worker/uniter/hook"
+	"github.com/juju/juju/worker/uniter/operation"
+)
+
+type QuarantineSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&QuarantineSuite{})
+
+var (
+	quarantinedInfo = hook.Info{
+		Kind:              hooks.RelationChanged,
+		RelationId:        1,
+		RemoteUnit:        "mysql/0",
+		RemoteApplication: "mysql",
+	}
+	otherQuarantinedInfo = hook.Info{
+		Kind:              hooks.RelationJoined,
+		RelationId:        2,
+		RemoteUnit:        "wordpress/0",
+		RemoteApplication: "wordpress",
+	}
+)
+
+func (s *QuarantineSuite) TestNewQuarantineHook_NotRelationHook(c *gc.C) {
+	factory := operation.NewFactory(operation.FactoryParams{})
+	_, err := factory.NewQuarantineHook(hook.Info{Kind: hooks.ConfigChanged})
+	c.Assert(err, gc.ErrorMatches, `cannot quarantine "config-changed" hook`)
+}
+
+func (s *QuarantineSuite) TestQuarantineHook_Prepare_NotFailed(c *gc.C) {
+	factory := operation.NewFactory(operation.FactoryParams{})
+	op, err := factory.NewQuarantineHook(quarantinedInfo)
+	c.Assert(err, jc.ErrorIsNil)
+
+	newState, err := op.Prepare(operation.State{Kind: operation.Continue})
+	c.Check(newState, gc.IsNil)
+	c.Check(err, gc.ErrorMatches, "cannot quarantine relation-changed hook: hook has not failed")
+
+	info := otherQuarantinedInfo
+	_, err = op.Prepare(operation.State{
+		Kind: operation.RunHook,
+		Step: operation.Pending,
+		Hook: &info,
+	})
+	c.Check(err, gc.ErrorMatches, "cannot quarantine relation-changed hook: hook has not failed")
+}
+
+func (s *QuarantineSuite) TestQuarantineHook_Commit(c *gc.C) {
+	factory := operation.NewFactory(operation.FactoryParams{})
+	op, err := factory.NewQuarantineHook(quarantinedInfo)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(op.String(), gc.Equals, "quarantine relation-changed (1) hook")
+	c.Check(op.NeedsGlobalMachineLock(), jc.IsFalse)
+
+	info := quarantinedInfo
+	before := operation.State{
+		Kind:                operation.RunHook,
+		Step:                operation.Pending,
+		Hook:                &info,
+		Started:             true,
+		RelationHookRetries: map[int]int{1: 3, 2: 1},
+		QuarantinedHooks:    map[int]hook.Info{2: otherQuarantinedInfo},
+	}
+	newState, err := op.Prepare(before)
+	c.Check(newState, gc.IsNil)
+	c.Check(err, gc.Equals, operation.ErrSkipExecute)
+
+	newState, err = op.Commit(before)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(newState, gc.DeepEquals, &operation.State{
+		Kind:                operation.Continue,
+		Step:                operation.Pending,
+		Started:             true,
+		RelationHookRetries: map[int]int{2: 1},
+		QuarantinedHooks: map[int]hook.Info{
+			1: quarantinedInfo,
+			2: otherQuarantinedInfo,
+		},
+	})
+	// The original state is unchanged.
+	c.Check(before.QuarantinedHooks, jc.DeepEquals, map[int]hook.Info{2: otherQuarantinedInfo})
+	c.Check(before.RelationHookRetries, jc.DeepEquals, map[int]int{1: 3, 2: 1})
+}
+
+func (s *QuarantineSuite) TestReleaseQuarantinedHooks(c *gc.C) {
+	factory := operation.NewFactory(operation.FactoryParams{})
+	op, err := factory.NewReleaseQuarantinedHooks()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(op.String(), gc.Equals, "release quarantined hooks")
+
+	before := operation.State{
+		Kind:    operation.Continue,
+		Step:    operation.Pending,
+		Started: true,
+		QuarantinedHooks: map[int]hook.Info{
+			1: quarantinedInfo,
+			2: otherQuarantinedInfo,
+		},
+	}
+	newState, err := op.Prepare(before)
+	c.Check(newState, gc.IsNil)
+	c.Check(err, gc.Equals, operation.ErrSkipExecute)
+
+	newState, err = op.Commit(before)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(newState, gc.DeepEquals, &operation.State{
+		Kind:    operation.Continue,
+		Step:    operation.Pending,
+		Started: true,
+	})
+}
+
+func (s *QuarantineSuite) TestReleaseQuarantinedHooks_NoneQuarantined(c *gc.C) {
+	factory := operation.NewFactory(operation.FactoryParams{})
+	op, err := factory.NewReleaseQuarantinedHooks()
+	c.Assert(err, jc.ErrorIsNil)
+
+	newState, err := op.Commit(operation.State{Kind: operation.Continue})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(newState, gc.IsNil)
+}
@@ -83,11 +83,26 @@ func (rh *runHook) Prepare(state State) (*State, error) {
 	rh.name = name
 	rh.runner = rnr
 
-	return stateChange{
+	newState := stateChange{
 		Kind: RunHook,
 		Step: Pending,
 		Hook: &rh.info,
-	}.apply(state), nil
+	}.apply(state)
+	if rh.info.Kind.IsRelation() && state.Kind == RunHook && state.Step == Pending &&
+		state.Hook != nil && state.Hook.SameHook(rh.info) {
+		// The hook failed when last run, so this is a retry.
+		newState.RelationHookRetries = copyRetries(state.RelationHookRetries)
+		newState.RelationHookRetries[rh.info.RelationId]++
+	}
+	return newState, nil
+}
+
+func copyRetries(retries map[int]int) map[int]int {
+	result := make(map[int]int, len(retries)+1)
+	for id, n := range retries {
+		result[id] = n
+	}
+	return result
 }
 
 // RunningHookMessage returns the info message to print when running a hook.
@@ -95,10 +110,28 @@ func RunningHookMessage(hookName string) string {
 	return fmt.Sprintf("running %s hook", hookName)
 }
 
+// relationHookMessage returns the message recorded in the unit agent's
+// status history when a relation hook is run or completes. It identifies
+// the relation and the remote unit or application, so that the status log
+// can be used to reconstruct the timeline of relation changes.
+func relationHookMessage(action, hookName string, info hook.Info) string {
+	remote := info.RemoteUnit
+	if remote == "" {
+		remote = info.RemoteApplication
+	}
+	if remote != "" {
+		remote = " for " + remote
+	}
+	return fmt.Sprintf("%s %s hook%s on relation %d", action, hookName, remote, info.RelationId)
+}
+
 // Execute runs the hook.
 // Execute is part of the Operation interface.
 func (rh *runHook) Execute(state State) (*State, error) {
 	message := RunningHookMessage(rh.name)
+	if rh.info.Kind.IsRelation() {
+		message = relationHookMessage("running", rh.name, rh.info)
+	}
 	if err := rh.beforeHook(state); err != nil {
 		return nil, err
 	}
@@ -138,6 +171,14 @@ func (rh *runHook) Execute(state State) (*State, error) {
 	if rh.hookFound {
 		logger.Infof("ran %q hook (via %s)", rh.name, handlerType)
 		rh.callbacks.NotifyHookCompleted(rh.name, rh.runner.Context())
+		if rh.info.Kind.IsRelation() {
+			// The hook has run, so failing to record its completion
+			// must not cause it to be run again.
+			message := relationHookMessage("completed", rh.name, rh.info)
+			if err := rh.callbacks.SetExecutingStatus(message); err != nil {
+				logger.Warningf("cannot record completion of %q hook: %v", rh.name, err)
+			}
+		}
 	} else {
 		logger.Infof("skipped %q hook (missing)", rh.name)
 	}
@@ -286,6 +327,14 @@ func (rh *runHook) Commit(state State) (*State, error) {
 	}
 
 	newState := change.apply(state)
+	if _, ok := state.RelationHookRetries[rh.info.RelationId]; ok && rh.info.Kind.IsRelation() {
+		newState.RelationHookRetries = copyRetries(state.RelationHookRetries)
+		delete(newState.RelationHookRetries, rh.info.RelationId)
+	}
+	if _, ok := state.QuarantinedHooks[rh.info.RelationId]; ok && rh.info.Kind.IsRelation() {
+		newState.QuarantinedHooks = copyQuarantined(state.QuarantinedHooks)
+		delete(newState.QuarantinedHooks, rh.info.RelationId)
+	}
 
 	switch rh.info.Kind {
 	case hooks.Install:
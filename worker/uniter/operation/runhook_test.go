@@ -203,6 +203,36 @@ func (s *RunHookSuite) TestPrepareSuccess_Preserve(c *gc.C) {
 	)
 }
 
+func (s *RunHookSuite) TestPrepareSuccess_RelationHookRetry(c *gc.C) {
+	runnerFactory := NewRunHookRunnerFactory(errors.New("should not call"))
+	factory := operation.NewFactory(operation.FactoryParams{
+		RunnerFactory: runnerFactory,
+		Callbacks:     NewPrepareHookCallbacks(),
+	})
+	info := hook.Info{Kind: hooks.RelationChanged, RelationId: 1, RemoteUnit: "mysql/0", RemoteApplication: "mysql"}
+	op, err := factory.NewRunHook(info)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Running the hook for the first time does not count as a retry.
+	newState, err := op.Prepare(operation.State{
+		Kind:                operation.Continue,
+		Step:                operation.Pending,
+		RelationHookRetries: map[int]int{2: 1},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(newState.RelationHookRetries, jc.DeepEquals, map[int]int{2: 1})
+
+	// Running it again after it failed does.
+	before := *newState
+	newState, err = op.Prepare(before)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(newState.RelationHookRetries, jc.DeepEquals, map[int]int{1: 1, 2: 1})
+	newState, err = op.Prepare(*newState)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(newState.RelationHookRetries, jc.DeepEquals, map[int]int{1: 2, 2: 1})
+	c.Check(before.RelationHookRetries, jc.DeepEquals, map[int]int{2: 1})
+}
+
 func (s *RunHookSuite) getExecuteRunnerTest(
 	c *gc.C, newHook newHook, kind hooks.Kind, runErr error, contextOps ...func(*MockContext),
 ) (operation.Operation, *ExecuteHookCallbacks, *MockRunnerFactory) {
@@ -337,6 +367,53 @@ func (s *RunHookSuite) TestInstallHookWHenNoStatusSet(c *gc.C) {
 	c.Assert(status.Info, gc.Equals, "installing charm software")
 }
 
+func (s *RunHookSuite) TestExecuteRelationHookRecordsHistory(c *gc.C) {
+	runnerFactory := NewRunHookRunnerFactory(nil)
+	callbacks := &ExecuteHookCallbacks{
+		PrepareHookCallbacks:    NewPrepareHookCallbacks(),
+		MockNotifyHookCompleted: &MockNotify{},
+		MockNotifyHookFailed:    &MockNotify{},
+	}
+	factory := operation.NewFactory(operation.FactoryParams{
+		RunnerFactory: runnerFactory,
+		Callbacks:     callbacks,
+	})
+	op, err := factory.NewRunHook(hook.Info{Kind: hooks.RelationChanged, RelationId: 1, RemoteUnit: "mysql/0", RemoteApplication: "mysql"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	midState, err := op.Prepare(operation.State{})
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = op.Execute(*midState)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(callbacks.executingMessages, jc.DeepEquals, []string{
+		"running some-hook-name hook for mysql/0 on relation 1",
+		"completed some-hook-name hook for mysql/0 on relation 1",
+	})
+}
+
+func (s *RunHookSuite) TestExecuteMissingRelationHookNotCompleted(c *gc.C) {
+	runnerFactory := NewRunHookRunnerFactory(charmrunner.NewMissingHookError("blah-blah"))
+	callbacks := &ExecuteHookCallbacks{
+		PrepareHookCallbacks:    NewPrepareHookCallbacks(),
+		MockNotifyHookCompleted: &MockNotify{},
+		MockNotifyHookFailed:    &MockNotify{},
+	}
+	factory := operation.NewFactory(operation.FactoryParams{
+		RunnerFactory: runnerFactory,
+		Callbacks:     callbacks,
+	})
+	op, err := factory.NewRunHook(hook.Info{Kind: hooks.RelationCreated, RelationId: 2, RemoteApplication: "mysql"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	midState, err := op.Prepare(operation.State{})
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = op.Execute(*midState)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(callbacks.executingMessages, jc.DeepEquals, []string{
+		"running some-hook-name hook for mysql on relation 2",
+	})
+}
+
 func (s *RunHookSuite) testExecuteSuccess(
 	c *gc.C, before, after operation.State, setStatusCalled bool,
 ) {
@@ -869,3 +946,47 @@ func (s *RunHookSuite) TestNeedsGlobalMachineLock_Run(c *gc.C) {
 func (s *RunHookSuite) TestNeedsGlobalMachineLock_Skip(c *gc.C) {
 	s.testNeedsGlobalMachineLock(c, operation.Factory.NewSkipHook, false)
 }
+
+func (s *RunHookSuite) TestCommitSuccess_RelationHookRetriesReset(c *gc.C) {
+	for i, newHook := range []newHook{
+		operation.Factory.NewRunHook,
+		operation.Factory.NewSkipHook,
+	} {
+		c.Logf("variant %d", i)
+		s.testCommitSuccess(c,
+			newHook,
+			hook.Info{Kind: hooks.RelationChanged, RelationId: 1, RemoteUnit: "mysql/0", RemoteApplication: "mysql"},
+			operation.State{RelationHookRetries: map[int]int{1: 3, 2: 1}},
+			operation.State{
+				Kind:                operation.Continue,
+				Step:                operation.Pending,
+				RelationHookRetries: map[int]int{2: 1},
+			},
+		)
+	}
+}
+
+func (s *RunHookSuite) TestCommitSuccess_QuarantinedHookReleased(c *gc.C) {
+	info := hook.Info{Kind: hooks.RelationChanged, RelationId: 1, RemoteUnit: "mysql/0", RemoteApplication: "mysql"}
+	other := hook.Info{Kind: hooks.RelationJoined, RelationId: 2, RemoteUnit: "wordpress/0", RemoteApplication: "wordpress"}
+	for i, newHook := range []newHook{
+		operation.Factory.NewRunHook,
+		operation.Factory.NewSkipHook,
+	} {
+		c.Logf("variant %d", i)
+		s.testCommitSuccess(c,
+			newHook,
+			info,
+			operation.State{
+				Kind:             operation.Continue,
+				Step:             operation.Pending,
+				QuarantinedHooks: map[int]hook.Info{1: info, 2: other},
+			},
+			operation.State{
+				Kind:             operation.Continue,
+				Step:             operation.Pending,
+				QuarantinedHooks: map[int]hook.Info{2: other},
+			},
+		)
+	}
+}
@@ -5,6 +5,7 @@ package operation
 
 import (
 	"os"
+	"sort"
 
 	"github.com/juju/errors"
 	"github.com/juju/utils"
@@ -111,6 +112,21 @@ type State struct {
 	// machine/container addresses - it's used to determine whether we
 	// need to run config-changed.
 	AddressesHash string `yaml:"addresses-hash,omitempty"`
+
+	// RelationHookRetries holds, by relation id, the number of times a
+	// failed hook for the relation has been retried. The count is reset
+	// when the hook is committed, whether it succeeded or was skipped.
+	RelationHookRetries map[int]int `yaml:"relation-hook-retries,omitempty"`
+
+	// QuarantinedHooks holds, by relation id, the failed hooks that have
+	// been set aside, uncommitted, so that hooks for other relations can
+	// run. No hooks run for a relation while its hook is quarantined.
+	QuarantinedHooks map[int]hook.Info `yaml:"quarantined-hooks,omitempty"`
+
+	// UnknownHooks holds the hooks of kinds unknown to this agent that
+	// were set aside, rather than run, when the state was read. They are
+	// preserved so that they are not lost if the agent is upgraded again.
+	UnknownHooks []hook.Info `yaml:"unknown-hooks,omitempty"`
 }
 
 // validate returns an error if the state violates expectations.
@@ -175,6 +191,37 @@ func (st State) validate() (err error) {
 	return nil
 }
 
+// setAsideUnknownHooks moves the current hook, and any quarantined
+// hooks, of kinds unknown to this agent to UnknownHooks, and returns them.
+func (st *State) setAsideUnknownHooks() []hook.Info {
+	var unknown []hook.Info
+	if st.Hook != nil && hook.IsUnknownKind(st.Hook.Validate()) {
+		unknown = append(unknown, *st.Hook)
+		st.Hook = nil
+		if st.Kind == RunHook {
+			st.Kind = Continue
+			st.Step = Pending
+		}
+	}
+	relationIds := make([]int, 0, len(st.QuarantinedHooks))
+	for relationId := range st.QuarantinedHooks {
+		relationIds = append(relationIds, relationId)
+	}
+	sort.Ints(relationIds)
+	for _, relationId := range relationIds {
+		info := st.QuarantinedHooks[relationId]
+		if hook.IsUnknownKind(info.Validate()) {
+			unknown = append(unknown, info)
+			delete(st.QuarantinedHooks, relationId)
+		}
+	}
+	if len(st.QuarantinedHooks) == 0 {
+		st.QuarantinedHooks = nil
+	}
+	st.UnknownHooks = append(st.UnknownHooks, unknown...)
+	return unknown
+}
+
 // stateChange is useful for a variety of Operation implementations.
 type stateChange struct {
 	Kind            Kind
@@ -215,11 +262,36 @@ func (f *StateFile) Read() (*State, error) {
 		}
 	}
 	if err := st.validate(); err != nil {
-		return nil, errors.Errorf("cannot read %q: %v", f.path, err)
+		return nil, errors.Annotatef(err, "cannot read %q", f.path)
 	}
 	return &st, nil
 }
 
+// SetAsideUnknownHooks rewrites the state file, setting aside the hooks
+// of kinds unknown to this agent, as may have been recorded by a newer
+// agent before the unit's agent was downgraded. The hooks are preserved
+// in the state's UnknownHooks, but are never run; if the current
+// operation was to run one of them, the uniter continues as if it had
+// been skipped. The hooks set aside are returned. If the file does not
+// exist it returns ErrNoStateFile.
+func (f *StateFile) SetAsideUnknownHooks() ([]hook.Info, error) {
+	var st State
+	if err := utils.ReadYaml(f.path, &st); err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoStateFile
+		}
+		return nil, errors.Trace(err)
+	}
+	unknown := st.setAsideUnknownHooks()
+	if len(unknown) == 0 {
+		return nil, nil
+	}
+	if err := f.Write(&st); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return unknown, nil
+}
+
 // Write stores the supplied state to the file.
 func (f *StateFile) Write(st *State) error {
 	if err := st.validate(); err != nil {
@@ -259,3 +259,53 @@ func (s *StateFileSuite) TestStates(c *gc.C) {
 		c.Assert(st, jc.DeepEquals, &t.st)
 	}
 }
+
+func (s *StateFileSuite) TestSetAsideUnknownHooks(c *gc.C) {
+	path := filepath.Join(c.MkDir(), "uniter")
+	file := operation.NewStateFile(path)
+	_, err := file.SetAsideUnknownHooks()
+	c.Assert(err, gc.Equals, operation.ErrNoStateFile)
+
+	joined := hook.Info{
+		Kind:              hooks.RelationJoined,
+		RelationId:        1,
+		RemoteUnit:        "mysql/0",
+		RemoteApplication: "mysql",
+	}
+	future := hook.Info{Kind: hooks.Kind("relation-future"), RelationId: 2}
+	pending := hook.Info{Kind: hooks.Kind("machine-exploded")}
+	err = utils.WriteYaml(path, &operation.State{
+		Kind:      operation.RunHook,
+		Step:      operation.Pending,
+		Installed: true,
+		Hook:      &pending,
+		QuarantinedHooks: map[int]hook.Info{
+			1: joined,
+			2: future,
+		},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = file.Read()
+	c.Assert(hook.IsUnknownKind(err), jc.IsTrue)
+
+	unknown, err := file.SetAsideUnknownHooks()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(unknown, jc.DeepEquals, []hook.Info{pending, future})
+
+	// The hooks of known kinds are kept, and
+	// those of unknown kinds are preserved.
+	st, err := file.Read()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(st, jc.DeepEquals, &operation.State{
+		Kind:             operation.Continue,
+		Step:             operation.Pending,
+		Installed:        true,
+		QuarantinedHooks: map[int]hook.Info{1: joined},
+		UnknownHooks:     []hook.Info{pending, future},
+	})
+
+	// Once set aside, there is nothing more to do.
+	unknown, err = file.SetAsideUnknownHooks()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(unknown, gc.HasLen, 0)
+}
@@ -48,6 +48,7 @@ type DeployCallbacks struct {
 	*MockGetArchiveInfo
 	*MockSetCurrentCharm
 	MockInitializeMetricsTimers *MockNoArgs
+	MockRenameRelationEndpoints *MockNoArgs
 }
 
 func (cb *DeployCallbacks) GetArchiveInfo(charmURL *corecharm.URL) (charm.BundleInfo, error) {
@@ -62,6 +63,10 @@ func (cb *DeployCallbacks) InitializeMetricsTimers() error {
 	return cb.MockInitializeMetricsTimers.Call()
 }
 
+func (cb *DeployCallbacks) RenameRelationEndpoints() error {
+	return cb.MockRenameRelationEndpoints.Call()
+}
+
 type MockBundleInfo struct {
 	charm.BundleInfo
 }
@@ -173,7 +178,8 @@ func (mock *MockPrepareHook) Call(hookInfo hook.Info) (string, error) {
 type PrepareHookCallbacks struct {
 	operation.Callbacks
 	*MockPrepareHook
-	executingMessage string
+	executingMessage  string
+	executingMessages []string
 }
 
 func (cb *PrepareHookCallbacks) PrepareHook(hookInfo hook.Info) (string, error) {
@@ -182,6 +188,7 @@ func (cb *PrepareHookCallbacks) PrepareHook(hookInfo hook.Info) (string, error)
 
 func (cb *PrepareHookCallbacks) SetExecutingStatus(message string) error {
 	cb.executingMessage = message
+	cb.executingMessages = append(cb.executingMessages, message)
 	return nil
 }
 
@@ -459,6 +466,7 @@ func NewDeployCallbacks() *DeployCallbacks {
 func NewDeployCommitCallbacks(err error) *DeployCallbacks {
 	return &DeployCallbacks{
 		MockInitializeMetricsTimers: &MockNoArgs{err: err},
+		MockRenameRelationEndpoints: &MockNoArgs{err: err},
 	}
 }
 func NewMockDeployer() *MockDeployer {
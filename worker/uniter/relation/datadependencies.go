@@ -0,0 +1,104 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package relation
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils"
+	"gopkg.in/juju/charm.v6"
+
+	"github.com/juju/juju/worker/uniter/hook"
+)
+
+// DataDependenciesFile is the name of the file, in the root of a charm,
+// in which the charm declares the relation endpoints whose data is read
+// by the hooks of each of its endpoints. For example:
+//
+//	reads:
+//	  url: [db, cache]
+//
+// declares that the url endpoint's hooks read the data of the db and
+// cache relations. The settings cached for those relations are refreshed
+// when a url hook is run, so that it does not see stale data.
+const DataDependenciesFile = "relation-data.yaml"
+
+// dataDependenciesManifest defines the serialization of the data
+// dependencies file.
+type dataDependenciesManifest struct {
+	Reads map[string][]string `yaml:"reads"`
+}
+
+// ReadDataDependencies returns the data dependencies declared by the charm
+// deployed to charmDir, mapping each endpoint to the endpoints whose
+// relation data its hooks read. If the charm declares none, nil is
+// returned. All of the endpoints must be endpoints of the charm.
+func ReadDataDependencies(charmDir string) (map[string][]string, error) {
+	var manifest dataDependenciesManifest
+	path := filepath.Join(charmDir, DataDependenciesFile)
+	if err := utils.ReadYaml(path, &manifest); os.IsNotExist(errors.Cause(err)) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Annotate(err, "reading charm data dependencies")
+	}
+	if len(manifest.Reads) == 0 {
+		return nil, nil
+	}
+	ch, err := charm.ReadCharmDir(charmDir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	endpoints := ch.Meta().CombinedRelations()
+	for endpoint, reads := range manifest.Reads {
+		if _, ok := endpoints[endpoint]; !ok {
+			return nil, errors.NotValidf("data dependencies of unknown endpoint %q", endpoint)
+		}
+		for _, read := range reads {
+			if _, ok := endpoints[read]; !ok {
+				return nil, errors.NotValidf("endpoint %q reading unknown endpoint %q", endpoint, read)
+			}
+		}
+	}
+	return manifest.Reads, nil
+}
+
+// staleRelations records the relations whose data is read
+// by a relation hook selected by the resolver.
+type staleRelations struct {
+	info        hook.Info
+	relationIds []int
+}
+
+// InvalidateDependencies is part of the RelationStateTracker interface.
+func (r *relationStateTracker) InvalidateDependencies(hookInfo hook.Info, endpoints []string) {
+	var relationIds []int
+	for id, relationer := range r.relationers {
+		if id == hookInfo.RelationId {
+			continue
+		}
+		name := relationer.EndpointName()
+		for _, endpoint := range endpoints {
+			if name == endpoint {
+				relationIds = append(relationIds, id)
+				break
+			}
+		}
+	}
+	sort.Ints(relationIds)
+	r.stale = &staleRelations{
+		info:        hookInfo,
+		relationIds: relationIds,
+	}
+}
+
+// StaleRelations is part of the RelationStateTracker interface.
+func (r *relationStateTracker) StaleRelations(hookInfo hook.Info) []int {
+	if r.stale == nil || r.stale.info != hookInfo {
+		return nil
+	}
+	return r.stale.relationIds
+}
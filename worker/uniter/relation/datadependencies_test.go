@@ -0,0 +1,144 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package relation_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/golang/mock/gomock"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/charm.v6/hooks"
+
+	"github.com/juju/juju/core/life"
+	"github.com/juju/juju/testcharms"
+	"github.com/juju/juju/worker/uniter/hook"
+	"github.com/juju/juju/worker/uniter/operation"
+	"github.com/juju/juju/worker/uniter/relation"
+	"github.com/juju/juju/worker/uniter/relation/mocks"
+	"github.com/juju/juju/worker/uniter/remotestate"
+	"github.com/juju/juju/worker/uniter/resolver"
+)
+
+type dataDependenciesSuite struct {
+	testing.IsolationSuite
+
+	charmDir string
+}
+
+var _ = gc.Suite(&dataDependenciesSuite{})
+
+func (s *dataDependenciesSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.charmDir = testcharms.Repo.ClonedDirPath(c.MkDir(), "wordpress")
+}
+
+func (s *dataDependenciesSuite) writeDependencies(c *gc.C, content string) {
+	path := filepath.Join(s.charmDir, relation.DataDependenciesFile)
+	err := ioutil.WriteFile(path, []byte(content), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *dataDependenciesSuite) TestReadDataDependenciesNone(c *gc.C) {
+	deps, err := relation.ReadDataDependencies(s.charmDir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(deps, gc.IsNil)
+}
+
+func (s *dataDependenciesSuite) TestReadDataDependencies(c *gc.C) {
+	s.writeDependencies(c, "reads:\n  url: [db, cache]\n  cache: [db]\n")
+	deps, err := relation.ReadDataDependencies(s.charmDir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(deps, jc.DeepEquals, map[string][]string{
+		"url":   {"db", "cache"},
+		"cache": {"db"},
+	})
+}
+
+func (s *dataDependenciesSuite) TestReadDataDependenciesInvalid(c *gc.C) {
+	for i, test := range []struct {
+		content string
+		err     string
+	}{{
+		content: "reads:\n  website: [db]\n",
+		err:     `data dependencies of unknown endpoint "website" not valid`,
+	}, {
+		content: "reads:\n  url: [database]\n",
+		err:     `endpoint "url" reading unknown endpoint "database" not valid`,
+	}, {
+		content: "reads: [db]\n",
+		err:     `reading charm data dependencies: .*`,
+	}} {
+		c.Logf("test %d: %s", i, test.content)
+		s.writeDependencies(c, test.content)
+		_, err := relation.ReadDataDependencies(s.charmDir)
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
+
+func (s *dataDependenciesSuite) brokenRelationState(c *gc.C, r *mocks.MockRelationStateTracker) (resolver.LocalState, remotestate.Snapshot) {
+	dir, err := relation.ReadStateDir(c.MkDir(), 1)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dir.Ensure(), jc.ErrorIsNil)
+
+	r.EXPECT().SynchronizeScopes(gomock.Any()).Return(nil)
+	r.EXPECT().IsKnown(1).Return(true)
+	r.EXPECT().IsImplicit(1).Return(false, nil)
+	r.EXPECT().StateDir(1).Return(dir, nil)
+	r.EXPECT().IsPeerRelation(1).Return(false, nil)
+	r.EXPECT().RemoteApplication(1).Return("mysql")
+
+	localState := resolver.LocalState{
+		State: operation.State{
+			Kind: operation.Continue,
+		},
+	}
+	remoteState := remotestate.Snapshot{
+		Life: life.Alive,
+		Relations: map[int]remotestate.RelationSnapshot{
+			1: {Life: life.Dying},
+		},
+	}
+	return localState, remoteState
+}
+
+func (s *dataDependenciesSuite) TestResolverInvalidatesDependencies(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+
+	r := mocks.NewMockRelationStateTracker(ctrl)
+	localState, remoteState := s.brokenRelationState(c, r)
+	r.EXPECT().Name(1).Return("db", nil)
+	r.EXPECT().InvalidateDependencies(hook.Info{
+		Kind:              hooks.RelationBroken,
+		RelationId:        1,
+		RemoteApplication: "mysql",
+	}, []string{"cache"})
+
+	relationsResolver := relation.NewRelationResolver(r, nil, relation.WithDataDependencies(map[string][]string{
+		"db":  {"cache"},
+		"url": {"db"},
+	}))
+	op, err := relationsResolver.NextOp(localState, remoteState, &mockOperations{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(op.String(), gc.Equals, "run hook relation-broken with relation 1")
+}
+
+func (s *dataDependenciesSuite) TestResolverNoDependenciesForEndpoint(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+
+	r := mocks.NewMockRelationStateTracker(ctrl)
+	localState, remoteState := s.brokenRelationState(c, r)
+	r.EXPECT().Name(1).Return("db", nil)
+
+	relationsResolver := relation.NewRelationResolver(r, nil, relation.WithDataDependencies(map[string][]string{
+		"url": {"db"},
+	}))
+	op, err := relationsResolver.NextOp(localState, remoteState, &mockOperations{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(op.String(), gc.Equals, "run hook relation-broken with relation 1")
+}
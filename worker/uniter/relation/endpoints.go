@@ -0,0 +1,119 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package relation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils"
+	"gopkg.in/juju/charm.v6"
+	"gopkg.in/juju/names.v3"
+)
+
+// UpgradeManifestFile is the name of the file, in the root of a charm,
+// in which the charm declares how the relation endpoints of earlier
+// revisions map to its own, so that relations established under the old
+// endpoint names continue across an upgrade to it. For example:
+//
+//	endpoint-renames:
+//	  db: database
+//
+// Relations keep the endpoint names they were established with, so later
+// revisions of the charm must keep declaring the rename for as long as
+// such relations may exist.
+const UpgradeManifestFile = "upgrade.yaml"
+
+// upgradeManifest defines the serialization of the upgrade manifest.
+type upgradeManifest struct {
+	EndpointRenames map[string]string `yaml:"endpoint-renames"`
+}
+
+// ReadEndpointRenames returns the endpoint renames declared in the upgrade
+// manifest of the charm deployed to charmDir, mapping the old name of each
+// renamed endpoint to its new one. If the charm has no upgrade manifest,
+// nil is returned. Each new name must be an endpoint of the charm, and no
+// old name may be, or it would be ambiguous which the relation belongs to.
+func ReadEndpointRenames(charmDir string) (map[string]string, error) {
+	var manifest upgradeManifest
+	path := filepath.Join(charmDir, UpgradeManifestFile)
+	if err := utils.ReadYaml(path, &manifest); os.IsNotExist(errors.Cause(err)) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Annotate(err, "reading charm upgrade manifest")
+	}
+	if len(manifest.EndpointRenames) == 0 {
+		return nil, nil
+	}
+	ch, err := charm.ReadCharmDir(charmDir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	endpoints := ch.Meta().CombinedRelations()
+	renamedTo := make(map[string]string)
+	for oldName, newName := range manifest.EndpointRenames {
+		if _, ok := endpoints[newName]; !ok {
+			return nil, errors.NotValidf("rename of endpoint %q to unknown endpoint %q", oldName, newName)
+		}
+		if _, ok := endpoints[oldName]; ok {
+			return nil, errors.NotValidf("rename of existing endpoint %q", oldName)
+		}
+		if other, ok := renamedTo[newName]; ok {
+			return nil, errors.NotValidf("rename of both %q and %q to endpoint %q", other, oldName, newName)
+		}
+		renamedTo[newName] = oldName
+	}
+	return manifest.EndpointRenames, nil
+}
+
+// renameRelationTag returns the tag that the relation would have once the
+// application's endpoints are renamed. Relation keys are ordered by
+// endpoint role, so renaming an endpoint does not reorder the key.
+func renameRelationTag(tag names.RelationTag, appName string, renames map[string]string) names.RelationTag {
+	if len(renames) == 0 {
+		return tag
+	}
+	endpoints := strings.Split(tag.Id(), " ")
+	for i, endpoint := range endpoints {
+		parts := strings.SplitN(endpoint, ":", 2)
+		if len(parts) != 2 || parts[0] != appName {
+			continue
+		}
+		if newName, ok := renames[parts[1]]; ok {
+			endpoints[i] = appName + ":" + newName
+		}
+	}
+	key := strings.Join(endpoints, " ")
+	if !names.IsValidRelation(key) {
+		return tag
+	}
+	return names.NewRelationTag(key)
+}
+
+// moveKeyedStateDir moves the state recorded in dirPath for the relation
+// under the tag it had before its endpoint was renamed, to be keyed by the
+// tag it has now.
+func moveKeyedStateDir(dirPath string, from, to names.RelationTag) error {
+	fromPath := filepath.Join(dirPath, from.String())
+	toPath := filepath.Join(dirPath, to.String())
+	lock, err := acquireStateDirLock(fromPath)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if _, err = os.Stat(toPath); err == nil {
+		err = errors.AlreadyExistsf("relation state %q", toPath)
+	} else if os.IsNotExist(err) {
+		err = os.Rename(fromPath, toPath)
+	}
+	if releaseErr := lock.release(); err == nil {
+		err = releaseErr
+	}
+	if err != nil {
+		return errors.Annotatef(err, "cannot move relation state for renamed endpoint")
+	}
+	logger.Infof("moved relation state from %q to %q", fromPath, toPath)
+	return nil
+}
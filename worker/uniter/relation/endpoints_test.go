@@ -0,0 +1,136 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package relation_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/testcharms"
+	"github.com/juju/juju/worker/uniter/relation"
+)
+
+type endpointRenamesSuite struct {
+	testing.IsolationSuite
+
+	charmDir string
+}
+
+var _ = gc.Suite(&endpointRenamesSuite{})
+
+func (s *endpointRenamesSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.charmDir = testcharms.Repo.ClonedDirPath(c.MkDir(), "wordpress")
+}
+
+func (s *endpointRenamesSuite) writeManifest(c *gc.C, content string) {
+	path := filepath.Join(s.charmDir, relation.UpgradeManifestFile)
+	err := ioutil.WriteFile(path, []byte(content), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *endpointRenamesSuite) TestReadEndpointRenamesNoManifest(c *gc.C) {
+	renames, err := relation.ReadEndpointRenames(s.charmDir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(renames, gc.IsNil)
+}
+
+func (s *endpointRenamesSuite) TestReadEndpointRenames(c *gc.C) {
+	s.writeManifest(c, "endpoint-renames:\n  database: db\n  website: url\n")
+	renames, err := relation.ReadEndpointRenames(s.charmDir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(renames, jc.DeepEquals, map[string]string{
+		"database": "db",
+		"website":  "url",
+	})
+}
+
+func (s *endpointRenamesSuite) TestReadEndpointRenamesInvalid(c *gc.C) {
+	for i, test := range []struct {
+		manifest string
+		err      string
+	}{{
+		manifest: "endpoint-renames:\n  database: storage\n",
+		err:      `rename of endpoint "database" to unknown endpoint "storage" not valid`,
+	}, {
+		manifest: "endpoint-renames:\n  cache: db\n",
+		err:      `rename of existing endpoint "cache" not valid`,
+	}, {
+		manifest: "endpoint-renames:\n  database: db\n  mysql: db\n",
+		err:      `rename of both "(database|mysql)" and "(database|mysql)" to endpoint "db" not valid`,
+	}, {
+		manifest: "endpoint-renames: [db]\n",
+		err:      `reading charm upgrade manifest: .*`,
+	}} {
+		c.Logf("test %d: %s", i, test.manifest)
+		s.writeManifest(c, test.manifest)
+		_, err := relation.ReadEndpointRenames(s.charmDir)
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
+
+func (s *endpointRenamesSuite) TestRenameRelationTag(c *gc.C) {
+	renames := map[string]string{"database": "db"}
+	for i, test := range []struct {
+		key      string
+		expected string
+	}{{
+		key:      "wordpress:database mysql:server",
+		expected: "wordpress:db mysql:server",
+	}, {
+		// Only the unit's own application's endpoints are renamed.
+		key:      "blog:database mysql:database",
+		expected: "blog:database mysql:database",
+	}, {
+		key:      "wordpress:url",
+		expected: "wordpress:url",
+	}} {
+		c.Logf("test %d: %s", i, test.key)
+		tag := relation.RenameRelationTag(names.NewRelationTag(test.key), "wordpress", renames)
+		c.Check(tag, gc.Equals, names.NewRelationTag(test.expected))
+	}
+}
+
+func (s *endpointRenamesSuite) TestMoveKeyedStateDir(c *gc.C) {
+	basedir := c.MkDir()
+	oldTag := names.NewRelationTag("wordpress:database mysql:server")
+	dir, err := relation.ReadKeyedStateDir(basedir, oldTag, 1)
+	c.Assert(err, jc.ErrorIsNil)
+	err = dir.Ensure()
+	c.Assert(err, jc.ErrorIsNil)
+	err = dir.Write(joinedHook)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = relation.MoveKeyedStateDir(basedir, oldTag, wordpressTag)
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = os.Stat(filepath.Join(basedir, oldTag.String()))
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+	dir, err = relation.ReadKeyedStateDir(basedir, wordpressTag, 1)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dir.State().Members, jc.DeepEquals, map[string]int64{"mysql/0": 1})
+}
+
+func (s *endpointRenamesSuite) TestMoveKeyedStateDirTargetExists(c *gc.C) {
+	basedir := c.MkDir()
+	oldTag := names.NewRelationTag("wordpress:database mysql:server")
+	for _, tag := range []names.RelationTag{oldTag, wordpressTag} {
+		dir, err := relation.ReadKeyedStateDir(basedir, tag, 123)
+		c.Assert(err, jc.ErrorIsNil)
+		err = dir.Ensure()
+		c.Assert(err, jc.ErrorIsNil)
+	}
+
+	err := relation.MoveKeyedStateDir(basedir, oldTag, wordpressTag)
+	c.Assert(errors.Cause(err), jc.Satisfies, errors.IsAlreadyExists)
+	_, err = os.Stat(filepath.Join(basedir, oldTag.String()))
+	c.Assert(err, jc.ErrorIsNil)
+}
@@ -0,0 +1,13 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package relation
+
+var (
+	LockTimeout     = &lockTimeout
+	StaleLockAge    = &staleLockAge
+	LockHolderAlive = &lockHolderAlive
+
+	RenameRelationTag = renameRelationTag
+	MoveKeyedStateDir = moveKeyedStateDir
+)
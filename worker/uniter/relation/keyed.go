@@ -0,0 +1,209 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package relation
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/juju/collections/set"
+	"github.com/juju/errors"
+	"github.com/juju/utils"
+	"gopkg.in/juju/names.v3"
+)
+
+// relationIdFile is the name of the file, inside a state directory keyed
+// by relation tag, that records the ID of the relation the state belongs
+// to. Relation IDs can be reused for a different relation after a model
+// migration or cross model relation changes, and a relation can be
+// re-established under the same key with a new ID, so the recorded ID
+// is used to detect state that has gone stale.
+// Unit files are always named with a "-", so cannot clash with it.
+const relationIdFile = "relation.yaml"
+
+// relationIdInfo defines the serialization of the relation ID file.
+type relationIdInfo struct {
+	RelationId *int `yaml:"relation-id"`
+}
+
+// ReadKeyedStateDir loads a StateDir from the subdirectory of dirPath named
+// for the supplied relation tag. If the directory does not exist, but one
+// named for the relation ID in the layout used by older agents does, that
+// directory is moved into place and loaded, provided its members confirm
+// that it belongs to the relation; otherwise it is discarded, as the ID
+// may since have been reused for another relation. If the directory records a
+// different relation ID, its state belongs to an earlier relation with the
+// same key; it is discarded, and empty state is returned.
+func ReadKeyedStateDir(dirPath string, tag names.RelationTag, relationId int) (*StateDir, error) {
+	path := filepath.Join(dirPath, tag.String())
+	lock, err := acquireStateDirLock(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = prepareKeyedStateDir(dirPath, path, tag, relationId)
+	if releaseErr := lock.release(); err == nil {
+		err = releaseErr
+	}
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot load relation state from %q", path)
+	}
+	d, err := readStateDir(path, relationId)
+	if err != nil {
+		return nil, err
+	}
+	d.tag = tag
+	return d, nil
+}
+
+// prepareKeyedStateDir migrates any legacy state for the relation to path,
+// or removes the state at path if it belongs to an earlier relation.
+func prepareKeyedStateDir(dirPath, path string, tag names.RelationTag, relationId int) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		legacyPath := filepath.Join(dirPath, strconv.Itoa(relationId))
+		return errors.Trace(migrateLegacyStateDir(legacyPath, path, tag, relationId))
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	recorded, err := readRelationId(path)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if recorded == nil || *recorded == relationId {
+		return nil
+	}
+	logger.Infof("discarding stale state for relation %q recorded with id %d, now %d",
+		tag.Id(), *recorded, relationId)
+	return errors.Trace(os.RemoveAll(path))
+}
+
+// readKeyedStateDir loads the StateDir at path, which is named for the
+// supplied relation tag, using the relation ID recorded inside it.
+// If no relation ID has been recorded, nil is returned.
+func readKeyedStateDir(path string, tag names.RelationTag) (*StateDir, error) {
+	recorded, err := readRelationId(path)
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot load relation state from %q", path)
+	}
+	if recorded == nil {
+		return nil, nil
+	}
+	d, err := readStateDir(path, *recorded)
+	if err != nil {
+		return nil, err
+	}
+	d.tag = tag
+	return d, nil
+}
+
+// migrateLegacyStateDir moves the state directory at legacyPath, named
+// for a relation ID, to path, and records the relation ID inside it.
+// If there is no directory at legacyPath, nothing is done. If the
+// directory cannot be confirmed to hold the state of the relation with
+// the tag, it is removed instead.
+func migrateLegacyStateDir(legacyPath, path string, tag names.RelationTag, relationId int) error {
+	if _, err := os.Stat(legacyPath); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	confirmed, err := legacyStateDirConfirmed(legacyPath, tag, relationId)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !confirmed {
+		logger.Infof("discarding relation state in %q, which cannot be confirmed to belong to relation %q",
+			legacyPath, tag.Id())
+		return errors.Trace(os.RemoveAll(legacyPath))
+	}
+	if err := os.Rename(legacyPath, path); err != nil {
+		return errors.Trace(err)
+	}
+	if err := writeRelationId(path, relationId); err != nil {
+		return errors.Trace(err)
+	}
+	logger.Infof("moved relation state from %q to %q", legacyPath, path)
+	return nil
+}
+
+// legacyStateDirConfirmed returns true if the state directory at
+// legacyPath, named for the relation ID, records at least one member,
+// and every member it records is a unit or application at one of the
+// endpoints of the relation with the tag. Relation IDs are not unique
+// across model migrations and cross model relations, so the ID alone
+// does not show that the state belongs to the relation.
+func legacyStateDirConfirmed(legacyPath string, tag names.RelationTag, relationId int) (bool, error) {
+	d, err := readStateDir(legacyPath, relationId)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	apps := relationApplications(tag)
+	members := 0
+	for unitName := range d.state.Members {
+		appName, err := names.UnitApplication(unitName)
+		if err != nil || !apps.Contains(appName) {
+			return false, nil
+		}
+		members++
+	}
+	for appName := range d.state.ApplicationMembers {
+		if !apps.Contains(appName) {
+			return false, nil
+		}
+		members++
+	}
+	return members > 0, nil
+}
+
+// relationApplications returns the names of the applications
+// at the endpoints of the relation with the tag.
+func relationApplications(tag names.RelationTag) set.Strings {
+	apps := set.NewStrings()
+	for _, endpoint := range strings.Split(tag.Id(), " ") {
+		if parts := strings.SplitN(endpoint, ":", 2); len(parts) == 2 {
+			apps.Add(parts[0])
+		}
+	}
+	return apps
+}
+
+// readRelationId returns the relation ID recorded in the state directory
+// at path, or nil if there is none.
+func readRelationId(path string) (*int, error) {
+	var info relationIdInfo
+	if err := utils.ReadYaml(filepath.Join(path, relationIdFile), &info); os.IsNotExist(errors.Cause(err)) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Annotate(err, "reading relation id")
+	}
+	if info.RelationId == nil {
+		return nil, errors.New(`reading relation id: "relation-id" not set`)
+	}
+	return info.RelationId, nil
+}
+
+// writeRelationId records the relation ID in the state directory at path.
+func writeRelationId(path string, relationId int) error {
+	info := relationIdInfo{RelationId: &relationId}
+	return errors.Annotate(utils.WriteYaml(filepath.Join(path, relationIdFile), &info), "writing relation id")
+}
+
+// keyed returns true if the directory is named for a relation
+// tag, rather than in the legacy layout named for a relation ID.
+func (d *StateDir) keyed() bool {
+	return d.tag != names.RelationTag{}
+}
+
+// discard removes the directory and everything in it.
+func (d *StateDir) discard() error {
+	return d.withLock(func() error {
+		if err := os.RemoveAll(d.path); err != nil {
+			return errors.Trace(err)
+		}
+		d.state.Members = nil
+		d.state.ApplicationMembers = nil
+		return nil
+	})
+}
@@ -0,0 +1,216 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package relation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/juju/clock"
+	"github.com/juju/errors"
+	"github.com/juju/utils"
+)
+
+// ErrStateDirLocked is returned when a relation state directory remains
+// locked by another process for longer than the lock timeout.
+var ErrStateDirLocked = errors.New("relation state directory locked by another process")
+
+// lockSuffix is appended to the path of a state directory to name the
+// file that locks it. The lock file sits beside the directory rather
+// than in it, so that it never prevents the directory being removed,
+// and it is not a directory, so it is never read as relation state.
+const lockSuffix = ".lock"
+
+var (
+	// lockClock is used to time out and back off lock acquisition.
+	lockClock clock.Clock = clock.WallClock
+
+	// lockDelay is how long to wait between attempts to acquire a lock.
+	lockDelay = 10 * time.Millisecond
+
+	// lockTimeout is how long to wait for a lock held by another
+	// process before giving up. Commits only take a moment, so
+	// this is long enough to ride out a slow disk.
+	lockTimeout = 5 * time.Second
+
+	// staleLockAge is how old a lock must be before it is checked
+	// for having been left behind by a process that died holding it,
+	// and is stolen if so.
+	staleLockAge = time.Minute
+
+	// lockHolderAlive returns whether the process with
+	// the given pid, which holds a lock, is running.
+	lockHolderAlive = processAlive
+)
+
+// lockInfo defines the serialization of a lock file, recording
+// which process holds the lock and when it was acquired.
+type lockInfo struct {
+	PID      int       `yaml:"pid"`
+	Acquired time.Time `yaml:"acquired"`
+}
+
+// stateDirLock is an advisory lock on a relation state directory, held
+// while changes to the directory are committed. It protects the state
+// from being written concurrently by another agent process, such as
+// one left running during an upgrade.
+type stateDirLock struct {
+	path string
+	file os.FileInfo
+}
+
+// acquireStateDirLock locks the state directory at path, waiting for
+// any other process holding the lock to release it. A lock that has
+// been held for longer than staleLockAge is stolen, unless the process
+// holding it is still running.
+func acquireStateDirLock(path string) (*stateDirLock, error) {
+	lockPath := path + lockSuffix
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		return nil, errors.Annotatef(err, "cannot lock relation state %q", path)
+	}
+	deadline := lockClock.Now().Add(lockTimeout)
+	for {
+		lock, err := tryStateDirLock(lockPath)
+		if err == nil {
+			return lock, nil
+		}
+		if !os.IsExist(errors.Cause(err)) {
+			return nil, errors.Annotatef(err, "cannot lock relation state %q", path)
+		}
+
+		held, err := os.Stat(lockPath)
+		if os.IsNotExist(err) {
+			// Released since we tried; try again straight away.
+			continue
+		} else if err != nil {
+			return nil, errors.Annotatef(err, "cannot lock relation state %q", path)
+		}
+		// The lock file may be only partly written, in which case the
+		// holder is unknown but the file's age still shows staleness.
+		var info lockInfo
+		_ = utils.ReadYaml(lockPath, &info)
+		if lockClock.Now().Sub(held.ModTime()) > staleLockAge && !lockHeldByLiveProcess(info) {
+			if err := stealStateDirLock(lockPath, held, info); err != nil {
+				return nil, errors.Annotatef(err, "cannot lock relation state %q", path)
+			}
+			continue
+		}
+		if !lockClock.Now().Before(deadline) {
+			return nil, errors.Annotatef(ErrStateDirLocked,
+				"cannot lock relation state %q held by %s", path, describeHolder(info))
+		}
+		<-lockClock.After(lockDelay)
+	}
+}
+
+// tryStateDirLock creates the lock file at lockPath, failing with
+// an error satisfying os.IsExist if it is already held.
+func tryStateDirLock(lockPath string) (*stateDirLock, error) {
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	fi, err := f.Stat()
+	if err == nil {
+		_, err = fmt.Fprintf(f, "pid: %d\nacquired: %s\n",
+			os.Getpid(), lockClock.Now().UTC().Format(time.RFC3339Nano))
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(lockPath)
+		return nil, errors.Trace(err)
+	}
+	return &stateDirLock{path: lockPath, file: fi}, nil
+}
+
+// stealStateDirLock removes the stale lock file at lockPath. The file is
+// moved aside first, and only removed if it is the same stale file, so
+// that a lock freshly acquired by another process is not stolen too.
+func stealStateDirLock(lockPath string, stale os.FileInfo, info lockInfo) error {
+	stolenPath := fmt.Sprintf("%s.%d", lockPath, os.Getpid())
+	if err := os.Rename(lockPath, stolenPath); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	stolen, err := os.Stat(stolenPath)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !os.SameFile(stale, stolen) {
+		// Another process stole the stale lock and acquired it
+		// before we moved it aside; give it back if we can.
+		if err := os.Link(stolenPath, lockPath); err != nil && !os.IsExist(err) {
+			return errors.Trace(err)
+		}
+		return errors.Trace(os.Remove(stolenPath))
+	}
+	logger.Warningf("stealing stale lock %q held by %s", lockPath, describeHolder(info))
+	return errors.Trace(os.Remove(stolenPath))
+}
+
+// release unlocks the state directory. It returns an error if the lock
+// was stolen while it was held, since another process may then have
+// changed the directory concurrently.
+func (l *stateDirLock) release() error {
+	current, err := os.Stat(l.path)
+	if os.IsNotExist(err) || (err == nil && !os.SameFile(l.file, current)) {
+		return errors.Errorf("lock %q stolen while held", l.path)
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(os.Remove(l.path))
+}
+
+// lockHeldByLiveProcess returns whether the process recorded as holding
+// a lock is still running. A lock whose holder is unknown, as its file
+// was never completely written, is not held by a live process once it
+// is stale.
+func lockHeldByLiveProcess(info lockInfo) bool {
+	return info.PID != 0 && lockHolderAlive(info.PID)
+}
+
+func describeHolder(info lockInfo) string {
+	if info.PID == 0 {
+		return "unknown process"
+	}
+	return fmt.Sprintf("process %d since %s", info.PID, info.Acquired.Format(time.RFC3339))
+}
+
+// processAlive returns whether the process with the given pid is
+// running. On Windows, finding a process fails once it has exited;
+// elsewhere it always succeeds, and the process is sent the null
+// signal, which a running process it cannot signal refuses.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	defer p.Release()
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
+
+// withLock runs f while holding the lock on the state directory.
+func (d *StateDir) withLock(f func() error) (err error) {
+	lock, err := acquireStateDirLock(d.path)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer func() {
+		if releaseErr := lock.release(); err == nil {
+			err = releaseErr
+		}
+	}()
+	return f()
+}
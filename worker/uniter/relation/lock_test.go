@@ -0,0 +1,129 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package relation_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/charm.v6/hooks"
+
+	"github.com/juju/juju/worker/uniter/hook"
+	"github.com/juju/juju/worker/uniter/relation"
+)
+
+type stateDirLockSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&stateDirLockSuite{})
+
+func (s *stateDirLockSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.PatchValue(relation.LockTimeout, 50*time.Millisecond)
+}
+
+var joinedHook = hook.Info{
+	Kind:          hooks.RelationJoined,
+	RelationId:    1,
+	RemoteUnit:    "mysql/0",
+	ChangeVersion: 1,
+}
+
+func (s *stateDirLockSuite) writeLock(c *gc.C, path string, age time.Duration) {
+	err := ioutil.WriteFile(path, []byte("pid: 4242\nacquired: 2020-01-01T00:00:00Z\n"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	modified := time.Now().Add(-age)
+	err = os.Chtimes(path, modified, modified)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *stateDirLockSuite) TestWriteReleasesLock(c *gc.C) {
+	basedir := c.MkDir()
+	dir, err := relation.ReadStateDir(basedir, 1)
+	c.Assert(err, jc.ErrorIsNil)
+	err = dir.Ensure()
+	c.Assert(err, jc.ErrorIsNil)
+	err = dir.Write(joinedHook)
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = os.Stat(filepath.Join(basedir, "1.lock"))
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+}
+
+func (s *stateDirLockSuite) TestWriteLockedByOtherProcess(c *gc.C) {
+	basedir := c.MkDir()
+	dir, err := relation.ReadStateDir(basedir, 1)
+	c.Assert(err, jc.ErrorIsNil)
+	err = dir.Ensure()
+	c.Assert(err, jc.ErrorIsNil)
+	s.writeLock(c, filepath.Join(basedir, "1.lock"), 0)
+
+	err = dir.Write(joinedHook)
+	c.Assert(errors.Cause(err), gc.Equals, relation.ErrStateDirLocked)
+	c.Assert(err, gc.ErrorMatches, `.*cannot lock relation state ".*" held by process 4242 since 2020-01-01T00:00:00Z: .*`)
+	c.Assert(dir.State().Members, gc.HasLen, 0)
+	_, err = os.Stat(filepath.Join(basedir, "1", "mysql-0"))
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+}
+
+func (s *stateDirLockSuite) TestWriteStealsStaleLock(c *gc.C) {
+	s.PatchValue(relation.StaleLockAge, time.Minute)
+	s.PatchValue(relation.LockHolderAlive, func(pid int) bool {
+		c.Check(pid, gc.Equals, 4242)
+		return false
+	})
+	basedir := c.MkDir()
+	dir, err := relation.ReadStateDir(basedir, 1)
+	c.Assert(err, jc.ErrorIsNil)
+	err = dir.Ensure()
+	c.Assert(err, jc.ErrorIsNil)
+	s.writeLock(c, filepath.Join(basedir, "1.lock"), time.Hour)
+
+	err = dir.Write(joinedHook)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dir.State().Members, jc.DeepEquals, map[string]int64{"mysql/0": 1})
+
+	fis, err := ioutil.ReadDir(basedir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(fis, gc.HasLen, 1)
+	c.Assert(fis[0].Name(), gc.Equals, "1")
+}
+
+func (s *stateDirLockSuite) TestWriteKeepsStaleLockOfLiveProcess(c *gc.C) {
+	s.PatchValue(relation.StaleLockAge, time.Minute)
+	s.PatchValue(relation.LockHolderAlive, func(pid int) bool { return true })
+	basedir := c.MkDir()
+	dir, err := relation.ReadStateDir(basedir, 1)
+	c.Assert(err, jc.ErrorIsNil)
+	err = dir.Ensure()
+	c.Assert(err, jc.ErrorIsNil)
+	s.writeLock(c, filepath.Join(basedir, "1.lock"), time.Hour)
+
+	// The holder may be slow rather than dead, so its lock is kept.
+	err = dir.Write(joinedHook)
+	c.Assert(errors.Cause(err), gc.Equals, relation.ErrStateDirLocked)
+	c.Assert(dir.State().Members, gc.HasLen, 0)
+	_, err = os.Stat(filepath.Join(basedir, "1.lock"))
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *stateDirLockSuite) TestReadKeyedStateDirLocked(c *gc.C) {
+	basedir := c.MkDir()
+	s.writeLock(c, filepath.Join(basedir, "relation-wordpress.db#mysql.server.lock"), 0)
+
+	_, err := relation.ReadKeyedStateDir(basedir, wordpressTag, 123)
+	c.Assert(errors.Cause(err), gc.Equals, relation.ErrStateDirLocked)
+
+	// The lock file is not mistaken for relation state.
+	dirs, err := relation.ReadAllStateDirs(basedir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dirs, gc.HasLen, 0)
+}
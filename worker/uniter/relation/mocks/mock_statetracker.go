@@ -6,6 +6,7 @@ package mocks
 
 import (
 	gomock "github.com/golang/mock/gomock"
+	params "github.com/juju/juju/apiserver/params"
 	hook "github.com/juju/juju/worker/uniter/hook"
 	relation "github.com/juju/juju/worker/uniter/relation"
 	remotestate "github.com/juju/juju/worker/uniter/remotestate"
@@ -79,6 +80,18 @@ func (mr *MockRelationStateTrackerMockRecorder) HasContainerScope(arg0 interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasContainerScope", reflect.TypeOf((*MockRelationStateTracker)(nil).HasContainerScope), arg0)
 }
 
+// InvalidateDependencies mocks base method
+func (m *MockRelationStateTracker) InvalidateDependencies(arg0 hook.Info, arg1 []string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "InvalidateDependencies", arg0, arg1)
+}
+
+// InvalidateDependencies indicates an expected call of InvalidateDependencies
+func (mr *MockRelationStateTrackerMockRecorder) InvalidateDependencies(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateDependencies", reflect.TypeOf((*MockRelationStateTracker)(nil).InvalidateDependencies), arg0, arg1)
+}
+
 // IsImplicit mocks base method
 func (m *MockRelationStateTracker) IsImplicit(arg0 int) (bool, error) {
 	m.ctrl.T.Helper()
@@ -138,6 +151,47 @@ func (mr *MockRelationStateTrackerMockRecorder) Name(arg0 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Name", reflect.TypeOf((*MockRelationStateTracker)(nil).Name), arg0)
 }
 
+// PinSettings mocks base method
+func (m *MockRelationStateTracker) PinSettings(arg0 hook.Info, arg1 params.Settings) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PinSettings", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PinSettings indicates an expected call of PinSettings
+func (mr *MockRelationStateTrackerMockRecorder) PinSettings(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PinSettings", reflect.TypeOf((*MockRelationStateTracker)(nil).PinSettings), arg0, arg1)
+}
+
+// PrefetchSettings mocks base method
+func (m *MockRelationStateTracker) PrefetchSettings(arg0 hook.Info) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PrefetchSettings", arg0)
+}
+
+// PrefetchSettings indicates an expected call of PrefetchSettings
+func (mr *MockRelationStateTrackerMockRecorder) PrefetchSettings(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrefetchSettings", reflect.TypeOf((*MockRelationStateTracker)(nil).PrefetchSettings), arg0)
+}
+
+// PrefetchedSettings mocks base method
+func (m *MockRelationStateTracker) PrefetchedSettings(arg0 hook.Info) (params.Settings, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PrefetchedSettings", arg0)
+	ret0, _ := ret[0].(params.Settings)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// PrefetchedSettings indicates an expected call of PrefetchedSettings
+func (mr *MockRelationStateTrackerMockRecorder) PrefetchedSettings(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrefetchedSettings", reflect.TypeOf((*MockRelationStateTracker)(nil).PrefetchedSettings), arg0)
+}
+
 // PrepareHook mocks base method
 func (m *MockRelationStateTracker) PrepareHook(arg0 hook.Info) (string, error) {
 	m.ctrl.T.Helper()
@@ -181,6 +235,34 @@ func (mr *MockRelationStateTrackerMockRecorder) RemoteApplication(arg0 interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteApplication", reflect.TypeOf((*MockRelationStateTracker)(nil).RemoteApplication), arg0)
 }
 
+// RenameEndpoints mocks base method
+func (m *MockRelationStateTracker) RenameEndpoints() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenameEndpoints")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RenameEndpoints indicates an expected call of RenameEndpoints
+func (mr *MockRelationStateTrackerMockRecorder) RenameEndpoints() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameEndpoints", reflect.TypeOf((*MockRelationStateTracker)(nil).RenameEndpoints))
+}
+
+// Report mocks base method
+func (m *MockRelationStateTracker) Report() map[string]interface{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Report")
+	ret0, _ := ret[0].(map[string]interface{})
+	return ret0
+}
+
+// Report indicates an expected call of Report
+func (mr *MockRelationStateTrackerMockRecorder) Report() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Report", reflect.TypeOf((*MockRelationStateTracker)(nil).Report))
+}
+
 // StateDir mocks base method
 func (m *MockRelationStateTracker) StateDir(arg0 int) (*relation.StateDir, error) {
 	m.ctrl.T.Helper()
@@ -196,6 +278,20 @@ func (mr *MockRelationStateTrackerMockRecorder) StateDir(arg0 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StateDir", reflect.TypeOf((*MockRelationStateTracker)(nil).StateDir), arg0)
 }
 
+// StaleRelations mocks base method
+func (m *MockRelationStateTracker) StaleRelations(arg0 hook.Info) []int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StaleRelations", arg0)
+	ret0, _ := ret[0].([]int)
+	return ret0
+}
+
+// StaleRelations indicates an expected call of StaleRelations
+func (mr *MockRelationStateTrackerMockRecorder) StaleRelations(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StaleRelations", reflect.TypeOf((*MockRelationStateTracker)(nil).StaleRelations), arg0)
+}
+
 // SynchronizeScopes mocks base method
 func (m *MockRelationStateTracker) SynchronizeScopes(arg0 remotestate.Snapshot) error {
 	m.ctrl.T.Helper()
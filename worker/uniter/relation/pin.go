@@ -0,0 +1,120 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package relation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/worker/uniter/hook"
+)
+
+// pinnedSettingsFile is the name of the file, inside a state directory,
+// that records the remote settings snapshot pinned for the relation hook
+// being run. Unit files are always named with a "-", so cannot clash
+// with it.
+const pinnedSettingsFile = "pinned.yaml"
+
+// SettingsPin identifies the snapshot of remote settings that a relation
+// hook sees. It is recorded when the hook's context is created, and
+// cleared once the hook is committed.
+type SettingsPin struct {
+	// Remote is the name of the remote unit or
+	// application whose settings are pinned.
+	Remote string `yaml:"remote"`
+
+	// ChangeVersion is the change version of the
+	// remote settings that triggered the hook.
+	ChangeVersion int64 `yaml:"change-version"`
+
+	// Hash is the hash of the pinned settings.
+	Hash string `yaml:"settings-hash"`
+}
+
+// SettingsHash returns a hash of the supplied relation settings, which
+// is the same for settings with the same keys and values.
+func SettingsHash(settings params.Settings) (string, error) {
+	if settings == nil {
+		settings = params.Settings{}
+	}
+	// Maps are marshalled with their keys sorted.
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// Pin records the supplied settings pin in the state directory,
+// replacing any already recorded.
+func (d *StateDir) Pin(pin SettingsPin) (err error) {
+	defer errors.DeferredAnnotatef(&err, "failed to pin settings of %q on state directory", pin.Remote)
+	return d.withLock(func() error {
+		if err := utils.WriteYaml(filepath.Join(d.path, pinnedSettingsFile), &pin); err != nil {
+			return err
+		}
+		d.state.Pin = &pin
+		return nil
+	})
+}
+
+// clearPin removes any settings pin recorded in the state directory.
+func (d *StateDir) clearPin() error {
+	if err := os.Remove(filepath.Join(d.path, pinnedSettingsFile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	d.state.Pin = nil
+	return nil
+}
+
+// readPin returns the settings pin recorded in the
+// state directory at path, or nil if there is none.
+func readPin(path string) (*SettingsPin, error) {
+	var pin SettingsPin
+	if err := utils.ReadYaml(filepath.Join(path, pinnedSettingsFile), &pin); os.IsNotExist(errors.Cause(err)) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Annotate(err, "reading pinned settings")
+	}
+	return &pin, nil
+}
+
+// PinSettings is part of the RelationStateTracker interface.
+func (r *relationStateTracker) PinSettings(hookInfo hook.Info, settings params.Settings) error {
+	relationer, found := r.relationers[hookInfo.RelationId]
+	if !found {
+		return errors.Errorf("unknown relation: %d", hookInfo.RelationId)
+	}
+	remote := hookInfo.RemoteUnit
+	if remote == "" {
+		remote = hookInfo.RemoteApplication
+	}
+	hash, err := SettingsHash(settings)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// A hook that failed is pinned again when it is retried, with the
+	// settings read at that time. Settings that have changed since the
+	// first attempt explain a retried hook behaving differently.
+	if pin := relationer.dir.State().Pin; pin != nil && pin.Remote == remote &&
+		pin.ChangeVersion == hookInfo.ChangeVersion && pin.Hash != hash {
+		logger.Infof("settings of %q changed since %v hook was first run", remote, hookInfo.Kind)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return relationer.dir.Pin(SettingsPin{
+		Remote:        remote,
+		ChangeVersion: hookInfo.ChangeVersion,
+		Hash:          hash,
+	})
+}
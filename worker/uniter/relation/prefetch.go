@@ -0,0 +1,81 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package relation
+
+import (
+	"gopkg.in/juju/charm.v6/hooks"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/worker/uniter/hook"
+)
+
+// settingsPrefetch holds the remote settings being read ahead
+// of running a relation-changed hook.
+type settingsPrefetch struct {
+	info hook.Info
+	done chan struct{}
+
+	// settings and err are only valid once done is closed.
+	settings params.Settings
+	err      error
+}
+
+// PrefetchSettings is part of the RelationStateTracker interface.
+func (r *relationStateTracker) PrefetchSettings(hookInfo hook.Info) {
+	if hookInfo.Kind != hooks.RelationChanged {
+		return
+	}
+	relationer, found := r.relationers[hookInfo.RelationId]
+	if !found {
+		return
+	}
+	name := hookInfo.RemoteUnit
+	if name == "" {
+		name = hookInfo.RemoteApplication
+	}
+
+	r.prefetchMu.Lock()
+	defer r.prefetchMu.Unlock()
+	if r.prefetch != nil && r.prefetch.info == hookInfo {
+		// The resolver selects the same hook again if
+		// its operation is not run straight away.
+		return
+	}
+	prefetch := &settingsPrefetch{
+		info: hookInfo,
+		done: make(chan struct{}),
+	}
+	r.prefetch = prefetch
+
+	ru := relationer.ru
+	go func() {
+		defer close(prefetch.done)
+		prefetch.settings, prefetch.err = ru.ReadSettings(name)
+	}()
+}
+
+// PrefetchedSettings is part of the RelationStateTracker interface.
+func (r *relationStateTracker) PrefetchedSettings(hookInfo hook.Info) (params.Settings, bool) {
+	r.prefetchMu.Lock()
+	prefetch := r.prefetch
+	if prefetch == nil || prefetch.info != hookInfo {
+		r.prefetchMu.Unlock()
+		return nil, false
+	}
+	// The settings are only used once, so that a retried
+	// hook sees settings read at the time it is retried.
+	r.prefetch = nil
+	r.prefetchMu.Unlock()
+
+	select {
+	case <-prefetch.done:
+	case <-r.abort:
+		return nil, false
+	}
+	if prefetch.err != nil {
+		logger.Debugf("cannot prefetch settings for %v hook: %v", hookInfo.Kind, prefetch.err)
+		return nil, false
+	}
+	return prefetch.settings, true
+}
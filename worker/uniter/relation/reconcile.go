@@ -0,0 +1,221 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package relation
+
+import (
+	"github.com/juju/collections/set"
+	"github.com/juju/errors"
+	"gopkg.in/juju/charm.v6/hooks"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/worker/uniter/hook"
+	"github.com/juju/juju/worker/uniter/remotestate"
+)
+
+// WithReconciliation returns an option that causes the resolver, when
+// more than one hook is needed to bring a relation's local state in line
+// with its remote state, to plan all of them in a single pass over the
+// relation's membership. This is typically the case after the units of
+// a remote application are bounced, and rejoin or rewrite their settings
+// en masse.
+//
+// Without a plan, the resolver selects the first hook needed by the
+// members in name order each time, so a member whose settings change
+// again while the others are still being processed has its
+// relation-changed hook run again before theirs; with many members
+// changing repeatedly, hooks churn through the first of them. A plan
+// instead runs one hook for each member, with the settings current when
+// the hook is selected, so that later changes are coalesced. Changes
+// made to members after their hook has run are picked up once the plan
+// is complete. The plan is abandoned if the membership of the relation
+// changes.
+func WithReconciliation() ResolverOption {
+	return func(r *relationsResolver) {
+		r.reconcile = true
+		r.reconciliations = make(map[int]*reconciliation)
+	}
+}
+
+// reconciliation holds the hooks planned to bring the
+// local state of a relation in line with its remote state.
+type reconciliation struct {
+	// members holds the remote members of the
+	// relation when the hooks were planned.
+	members set.Strings
+
+	// hooks holds the planned hooks that remain to be run.
+	hooks []hook.Info
+
+	// selected indicates whether the first of the hooks has been
+	// selected to run, with the change version it records.
+	selected bool
+}
+
+// planReconciliation returns the hooks needed to bring the local state of
+// a relation in line with its remote state, in the order in which they
+// would be selected without a plan: departures, then application changes,
+// then joins, then unit changes.
+func planReconciliation(local *State, remote remotestate.RelationSnapshot) (*reconciliation, error) {
+	relationId := local.RelationId
+	unitNames := set.NewStrings()
+	for unitName := range local.Members {
+		unitNames.Add(unitName)
+	}
+	for unitName := range remote.Members {
+		unitNames.Add(unitName)
+	}
+
+	var departs, joins, changes []hook.Info
+	for _, unitName := range unitNames.SortedValues() {
+		appName, err := names.UnitApplication(unitName)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		info := hook.Info{
+			RelationId:        relationId,
+			RemoteUnit:        unitName,
+			RemoteApplication: appName,
+		}
+		localVersion, isLocal := local.Members[unitName]
+		remoteVersion, isRemote := remote.Members[unitName]
+		switch {
+		case !isRemote:
+			info.Kind = hooks.RelationDeparted
+			departs = append(departs, info)
+		case !isLocal:
+			info.Kind = hooks.RelationJoined
+			joins = append(joins, info)
+		case localVersion != remoteVersion:
+			info.Kind = hooks.RelationChanged
+			changes = append(changes, info)
+		}
+	}
+
+	appNames := set.NewStrings()
+	for appName := range remote.ApplicationMembers {
+		appNames.Add(appName)
+	}
+	var appChanges []hook.Info
+	for _, appName := range appNames.SortedValues() {
+		if local.ApplicationMembers[appName] != remote.ApplicationMembers[appName] {
+			appChanges = append(appChanges, hook.Info{
+				Kind:              hooks.RelationChanged,
+				RelationId:        relationId,
+				RemoteApplication: appName,
+			})
+		}
+	}
+
+	plan := &reconciliation{
+		members: set.NewStrings(),
+	}
+	for unitName := range remote.Members {
+		plan.members.Add(unitName)
+	}
+	plan.hooks = append(plan.hooks, departs...)
+	plan.hooks = append(plan.hooks, appChanges...)
+	plan.hooks = append(plan.hooks, joins...)
+	plan.hooks = append(plan.hooks, changes...)
+	return plan, nil
+}
+
+// sameMembers returns true if the remote members of the
+// relation are those for which the hooks were planned.
+func (p *reconciliation) sameMembers(remote remotestate.RelationSnapshot) bool {
+	if len(remote.Members) != p.members.Size() {
+		return false
+	}
+	for unitName := range remote.Members {
+		if !p.members.Contains(unitName) {
+			return false
+		}
+	}
+	return true
+}
+
+// next returns the first of the planned hooks that is still needed, with
+// the change version current in the state, and whether there is one.
+// Planned hooks that are no longer needed, or that have been committed
+// since they were selected, are dropped from the plan.
+func (p *reconciliation) next(local *State, remote remotestate.RelationSnapshot) (hook.Info, bool) {
+	for len(p.hooks) > 0 {
+		info := &p.hooks[0]
+		if p.selected && committed(local, *info) {
+			p.hooks, p.selected = p.hooks[1:], false
+			continue
+		}
+		if version, needed := neededVersion(local, remote, *info); needed {
+			info.ChangeVersion = version
+			info.Observed = remote.MembersObserved[info.RemoteUnit]
+			p.selected = true
+			return *info, true
+		}
+		p.hooks, p.selected = p.hooks[1:], false
+	}
+	return hook.Info{}, false
+}
+
+// neededVersion returns the change version with which the hook would
+// run, and whether it is needed to bring the local state of the relation
+// in line with its remote state.
+func neededVersion(local *State, remote remotestate.RelationSnapshot, info hook.Info) (int64, bool) {
+	localVersion, isLocal := local.Members[info.RemoteUnit]
+	switch {
+	case info.Kind == hooks.RelationDeparted:
+		return localVersion, isLocal
+	case info.Kind == hooks.RelationJoined:
+		return remote.Members[info.RemoteUnit], !isLocal
+	case info.RemoteUnit == "":
+		version, ok := remote.ApplicationMembers[info.RemoteApplication]
+		return version, ok && local.ApplicationMembers[info.RemoteApplication] != version
+	default:
+		version := remote.Members[info.RemoteUnit]
+		return version, isLocal && localVersion != version
+	}
+}
+
+// committed returns true if the local state of the
+// relation records that the hook has been run.
+func committed(local *State, info hook.Info) bool {
+	localVersion, isLocal := local.Members[info.RemoteUnit]
+	switch {
+	case info.Kind == hooks.RelationDeparted:
+		return !isLocal
+	case info.Kind == hooks.RelationJoined:
+		return isLocal
+	case info.RemoteUnit == "":
+		return local.ApplicationMembers[info.RemoteApplication] == info.ChangeVersion
+	default:
+		return localVersion == info.ChangeVersion
+	}
+}
+
+// reconciledHook returns the next hook planned for the relation, and
+// whether there is one. A plan is made when more than one hook is needed
+// and there is no plan for the current membership of the relation.
+func (r *relationsResolver) reconciledHook(local *State, remote remotestate.RelationSnapshot) (hook.Info, bool, error) {
+	relationId := local.RelationId
+	plan, ok := r.reconciliations[relationId]
+	if ok && !plan.sameMembers(remote) {
+		logger.Debugf("membership of relation %d changed, abandoning reconciliation", relationId)
+		plan, ok = nil, false
+	}
+	if !ok {
+		var err error
+		if plan, err = planReconciliation(local, remote); err != nil {
+			return hook.Info{}, false, errors.Trace(err)
+		}
+		if len(plan.hooks) < 2 {
+			delete(r.reconciliations, relationId)
+			return hook.Info{}, false, nil
+		}
+		logger.Debugf("reconciling relation %d with %d hooks", relationId, len(plan.hooks))
+		r.reconciliations[relationId] = plan
+	}
+	info, ok := plan.next(local, remote)
+	if !ok {
+		delete(r.reconciliations, relationId)
+	}
+	return info, ok, nil
+}
@@ -0,0 +1,198 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package relation_test
+
+import (
+	"github.com/golang/mock/gomock"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/charm.v6/hooks"
+
+	"github.com/juju/juju/core/life"
+	"github.com/juju/juju/worker/uniter/hook"
+	"github.com/juju/juju/worker/uniter/operation"
+	"github.com/juju/juju/worker/uniter/relation"
+	"github.com/juju/juju/worker/uniter/relation/mocks"
+	"github.com/juju/juju/worker/uniter/remotestate"
+	"github.com/juju/juju/worker/uniter/resolver"
+)
+
+type reconciliationSuite struct {
+	testing.IsolationSuite
+
+	dir         *relation.StateDir
+	remoteState remotestate.Snapshot
+}
+
+var _ = gc.Suite(&reconciliationSuite{})
+
+var reconciledUnits = []string{"mysql/0", "mysql/1", "mysql/2"}
+
+func (s *reconciliationSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+
+	var err error
+	s.dir, err = relation.ReadStateDir(c.MkDir(), 1)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.dir.Ensure(), jc.ErrorIsNil)
+
+	// All the members are known at version 1, and
+	// have since changed their settings en masse.
+	members := make(map[string]int64)
+	for _, unitName := range reconciledUnits {
+		for _, kind := range []hooks.Kind{hooks.RelationJoined, hooks.RelationChanged} {
+			s.commit(c, hook.Info{
+				Kind:              kind,
+				RelationId:        1,
+				RemoteUnit:        unitName,
+				RemoteApplication: "mysql",
+				ChangeVersion:     1,
+			})
+		}
+		members[unitName] = 2
+	}
+	s.remoteState = remotestate.Snapshot{
+		Life: life.Alive,
+		Relations: map[int]remotestate.RelationSnapshot{
+			1: {
+				Life:    life.Alive,
+				Members: members,
+			},
+		},
+	}
+}
+
+func (s *reconciliationSuite) commit(c *gc.C, info hook.Info) {
+	c.Assert(s.dir.Write(info), jc.ErrorIsNil)
+}
+
+func (s *reconciliationSuite) newResolver(ctrl *gomock.Controller, options ...relation.ResolverOption) resolver.Resolver {
+	r := mocks.NewMockRelationStateTracker(ctrl)
+	r.EXPECT().SynchronizeScopes(gomock.Any()).Return(nil).AnyTimes()
+	r.EXPECT().IsKnown(1).Return(true).AnyTimes()
+	r.EXPECT().IsImplicit(1).Return(false, nil).AnyTimes()
+	r.EXPECT().StateDir(1).Return(s.dir, nil).AnyTimes()
+	r.EXPECT().IsPeerRelation(1).Return(false, nil).AnyTimes()
+	return relation.NewRelationResolver(r, nil, options...)
+}
+
+func (s *reconciliationSuite) setMemberVersion(unitName string, version int64) {
+	s.remoteState.Relations[1].Members[unitName] = version
+}
+
+func (s *reconciliationSuite) nextHook(c *gc.C, r resolver.Resolver) hook.Info {
+	localState := resolver.LocalState{
+		State: operation.State{
+			Kind: operation.Continue,
+		},
+	}
+	op, err := r.NextOp(localState, s.remoteState, &mockOperations{})
+	c.Assert(err, jc.ErrorIsNil)
+	return op.(*mockOperation).hookInfo
+}
+
+func changedHook(unitName string, version int64) hook.Info {
+	return hook.Info{
+		Kind:              hooks.RelationChanged,
+		RelationId:        1,
+		RemoteUnit:        unitName,
+		RemoteApplication: "mysql",
+		ChangeVersion:     version,
+	}
+}
+
+func (s *reconciliationSuite) TestReconciliationCoalescesChanges(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+	r := s.newResolver(ctrl, relation.WithReconciliation())
+
+	info := s.nextHook(c, r)
+	c.Assert(info, jc.DeepEquals, changedHook("mysql/0", 2))
+	s.commit(c, info)
+
+	// Further changes to mysql/0 wait for the other members,
+	// and changes to those are seen by their hooks.
+	s.setMemberVersion("mysql/0", 3)
+	s.setMemberVersion("mysql/2", 3)
+	info = s.nextHook(c, r)
+	c.Assert(info, jc.DeepEquals, changedHook("mysql/1", 2))
+	s.commit(c, info)
+
+	s.setMemberVersion("mysql/2", 4)
+	info = s.nextHook(c, r)
+	c.Assert(info, jc.DeepEquals, changedHook("mysql/2", 4))
+	s.commit(c, info)
+
+	info = s.nextHook(c, r)
+	c.Assert(info, jc.DeepEquals, changedHook("mysql/0", 3))
+	s.commit(c, info)
+
+	_, err := r.NextOp(resolver.LocalState{
+		State: operation.State{Kind: operation.Continue},
+	}, s.remoteState, &mockOperations{})
+	c.Assert(err, jc.Satisfies, resolver.IsNoOperation)
+}
+
+func (s *reconciliationSuite) TestNoReconciliationByDefault(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+	r := s.newResolver(ctrl)
+
+	info := s.nextHook(c, r)
+	c.Assert(info, jc.DeepEquals, changedHook("mysql/0", 2))
+	s.commit(c, info)
+
+	s.setMemberVersion("mysql/0", 3)
+	info = s.nextHook(c, r)
+	c.Assert(info, jc.DeepEquals, changedHook("mysql/0", 3))
+}
+
+func (s *reconciliationSuite) TestReconciliationAbandonedWhenMembershipChanges(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+	r := s.newResolver(ctrl, relation.WithReconciliation())
+
+	info := s.nextHook(c, r)
+	c.Assert(info, jc.DeepEquals, changedHook("mysql/0", 2))
+	s.commit(c, info)
+
+	delete(s.remoteState.Relations[1].Members, "mysql/2")
+	info = s.nextHook(c, r)
+	c.Assert(info, jc.DeepEquals, hook.Info{
+		Kind:              hooks.RelationDeparted,
+		RelationId:        1,
+		RemoteUnit:        "mysql/2",
+		RemoteApplication: "mysql",
+		ChangeVersion:     1,
+	})
+	s.commit(c, info)
+
+	info = s.nextHook(c, r)
+	c.Assert(info, jc.DeepEquals, changedHook("mysql/1", 2))
+}
+
+func (s *reconciliationSuite) TestReconciliationJoinsEnMasse(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+	r := s.newResolver(ctrl, relation.WithReconciliation())
+	s.remoteState.Relations[1].Members["mysql/3"] = 1
+	s.remoteState.Relations[1].Members["mysql/4"] = 1
+
+	var kinds []string
+	for i := 0; i < 7; i++ {
+		info := s.nextHook(c, r)
+		kinds = append(kinds, string(info.Kind)+" "+info.RemoteUnit)
+		s.commit(c, info)
+	}
+	c.Assert(kinds, jc.DeepEquals, []string{
+		"relation-joined mysql/3",
+		"relation-changed mysql/3",
+		"relation-joined mysql/4",
+		"relation-changed mysql/4",
+		"relation-changed mysql/0",
+		"relation-changed mysql/1",
+		"relation-changed mysql/2",
+	})
+}
@@ -19,6 +19,10 @@ type Relationer struct {
 	ru    *apiuniter.RelationUnit
 	dir   *StateDir
 	dying bool
+
+	// renamedTo holds the name of the local endpoint in the deployed
+	// charm, if an upgrade renamed it; hooks are run under that name.
+	renamedTo string
 }
 
 // NewRelationer creates a new Relationer. The unit will not join the
@@ -37,7 +41,26 @@ func (r *Relationer) ContextInfo() *context.RelationInfo {
 	for memberName := range members {
 		memberNames = append(memberNames, memberName)
 	}
-	return &context.RelationInfo{r.ru, memberNames}
+	return &context.RelationInfo{
+		RelationUnit: r.ru,
+		MemberNames:  memberNames,
+		EndpointName: r.renamedTo,
+	}
+}
+
+// EndpointName returns the name of the local endpoint of the relation in
+// the deployed charm, taking into account any rename by a charm upgrade.
+func (r *Relationer) EndpointName() string {
+	if r.renamedTo != "" {
+		return r.renamedTo
+	}
+	return r.ru.Endpoint().Name
+}
+
+// renameEndpoint applies the endpoint renames declared
+// by the deployed charm to the relation's local endpoint.
+func (r *Relationer) renameEndpoint(renames map[string]string) {
+	r.renamedTo = renames[r.ru.Endpoint().Name]
 }
 
 // IsImplicit returns whether the local relation endpoint is implicit. Implicit
@@ -101,8 +124,7 @@ func (r *Relationer) PrepareHook(hi hook.Info) (hookName string, err error) {
 	if err = r.dir.State().Validate(hi); err != nil {
 		return
 	}
-	name := r.ru.Endpoint().Name
-	return fmt.Sprintf("%s-%s", name, hi.Kind), nil
+	return fmt.Sprintf("%s-%s", r.EndpointName(), hi.Kind), nil
 }
 
 // CommitHook persists the fact of the supplied hook's completion.
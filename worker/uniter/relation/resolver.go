@@ -4,6 +4,11 @@
 package relation
 
 import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/juju/collections/set"
 	"github.com/juju/errors"
 	"github.com/juju/loggo"
@@ -26,19 +31,131 @@ type SubordinateDestroyer interface {
 	DestroyAllSubordinates() error
 }
 
+// ResolverOption is a function that configures optional
+// behaviour of the relations resolver.
+type ResolverOption func(*relationsResolver)
+
+// WithGoodbyeData returns an option that causes relation-broken to be
+// preceded by a final relation-changed hook for each remote application
+// whose data changed since the last hook was run, when the relation is
+// broken because the remote application is departing. This gives charms
+// the chance to capture the last data (such as deregistration
+// information) written by the departing application. Relations broken
+// because the unit is dying, or because they are suspended, go straight
+// to relation-broken.
+func WithGoodbyeData() ResolverOption {
+	return func(r *relationsResolver) {
+		r.goodbyeData = true
+	}
+}
+
+// WithSettingsPrefetch returns an option that causes the remote settings
+// seen by a relation-changed hook to be read as soon as the hook is
+// selected, rather than when its context is created.
+func WithSettingsPrefetch() ResolverOption {
+	return func(r *relationsResolver) {
+		r.prefetchSettings = true
+	}
+}
+
+// WithDataDependencies returns an option that causes the settings cached
+// for the relations whose data a hook reads, as declared by the charm in
+// its data dependencies, to be refreshed when the hook is selected. The
+// dependencies map each endpoint to the endpoints whose data it reads.
+func WithDataDependencies(dependencies map[string][]string) ResolverOption {
+	return func(r *relationsResolver) {
+		r.dataDependencies = dependencies
+	}
+}
+
+// DrainableResolver is a resolver.Resolver that can be asked to stop
+// dispatching relation hooks, so that none run while the charm is
+// being upgraded.
+type DrainableResolver interface {
+	resolver.Resolver
+
+	// Drain stops the resolver dispatching relation hooks. Hooks that
+	// are already in-flight are committed by the uniter resolver as
+	// usual.
+	Drain()
+
+	// Resume allows the resolver to dispatch relation hooks again.
+	Resume()
+}
+
 // NewRelationResolver returns a resolver that handles all relation-related
 // hooks (except relation-created) and is wired to the provided RelationStateTracker
 // instance.
-func NewRelationResolver(stateTracker RelationStateTracker, subordinateDestroyer SubordinateDestroyer) resolver.Resolver {
-	return &relationsResolver{
+func NewRelationResolver(
+	stateTracker RelationStateTracker, subordinateDestroyer SubordinateDestroyer, options ...ResolverOption,
+) DrainableResolver {
+	r := &relationsResolver{
 		stateTracker:         stateTracker,
 		subordinateDestroyer: subordinateDestroyer,
 	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
 }
 
 type relationsResolver struct {
 	stateTracker         RelationStateTracker
 	subordinateDestroyer SubordinateDestroyer
+
+	// goodbyeData indicates whether a final relation-changed hook
+	// carrying the last remote application data should be run
+	// before relation-broken.
+	goodbyeData bool
+
+	// prefetchSettings indicates whether the remote settings for a
+	// relation-changed hook should be read once the hook is selected.
+	prefetchSettings bool
+
+	// dataDependencies maps each endpoint to the endpoints
+	// whose relation data its hooks read.
+	dataDependencies map[string][]string
+
+	// reconcile indicates whether the hooks needed to bring a relation
+	// in line with its remote state are planned in a single pass, and
+	// reconciliations holds the plans for each relation.
+	reconcile       bool
+	reconciliations map[int]*reconciliation
+
+	// suspensionDamper, if not nil, holds back changes to the
+	// suspension of relations, and wakeSuspension is called with
+	// the time until the first of them takes effect.
+	suspensionDamper *SuspensionDamper
+	wakeSuspension   func(time.Duration)
+
+	mu       sync.Mutex
+	draining bool
+}
+
+// Drain is part of the DrainableResolver interface.
+func (r *relationsResolver) Drain() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.draining {
+		logger.Infof("draining relation hooks")
+	}
+	r.draining = true
+}
+
+// Resume is part of the DrainableResolver interface.
+func (r *relationsResolver) Resume() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.draining {
+		logger.Infof("resuming relation hooks")
+	}
+	r.draining = false
+}
+
+func (r *relationsResolver) isDraining() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.draining
 }
 
 // NextOp implements resolver.Resolver.
@@ -47,27 +164,46 @@ func (r *relationsResolver) NextOp(localState resolver.LocalState, remoteState r
 		return nil, errors.Trace(err)
 	}
 
+	if err := r.dampSuspensions(remoteState); err != nil {
+		return nil, errors.Trace(err)
+	}
+
 	if err := r.stateTracker.SynchronizeScopes(remoteState); err != nil {
 		return nil, errors.Trace(err)
 	}
 
 	if localState.Kind != operation.Continue {
-		return nil, resolver.ErrNoOperation
+		return nil, resolver.NewNoOperationReason(
+			resolver.OperationPending, "waiting for %s operation to complete", localState.Kind)
+	}
+
+	if r.isDraining() {
+		return nil, resolver.NewNoOperationReason(resolver.Draining, "relation hooks are drained")
 	}
 
 	// Check whether we need to fire a hook for any of the relations
+	var idle idleRelations
 	for relationId, relationSnapshot := range remoteState.Relations {
 		if !r.stateTracker.IsKnown(relationId) {
 			continue
 		} else if isImplicit, _ := r.stateTracker.IsImplicit(relationId); isImplicit {
 			continue
 		}
+		if _, ok := localState.QuarantinedHooks[relationId]; ok {
+			idle.add(relationId, resolver.NewNoOperationReason(
+				resolver.QuarantinedRelation, "relation %d is quarantined", relationId))
+			continue
+		}
 
 		// If either the unit or the relation are Dying, or the
 		// relation becomes suspended, then the relation should be
-		// broken.
-		var remoteBroken bool
+		// broken. Only a relation that is Dying while the unit is
+		// not, and that isn't suspended, is broken because the
+		// remote application is departing.
+		var remoteBroken, remoteDeparting bool
+		lastSnapshot := relationSnapshot
 		if remoteState.Life == life.Dying || relationSnapshot.Life == life.Dying || relationSnapshot.Suspended {
+			remoteDeparting = remoteState.Life != life.Dying && !relationSnapshot.Suspended
 			relationSnapshot = remotestate.RelationSnapshot{}
 			remoteBroken = true
 			// TODO(axw) if relation is implicit, leave scope & remove.
@@ -79,14 +215,33 @@ func (r *relationsResolver) NextOp(localState resolver.LocalState, remoteState r
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
-		hook, err := r.nextHookForRelation(stateDir, relationSnapshot, remoteBroken)
-		if err == resolver.ErrNoOperation {
+		hook, err := r.nextHookForRelation(stateDir, relationSnapshot, lastSnapshot, remoteBroken, remoteDeparting)
+		if errors.Cause(err) == resolver.ErrNoOperation {
+			idle.add(relationId, err)
 			continue
 		}
+		if r.prefetchSettings {
+			r.stateTracker.PrefetchSettings(hook)
+		}
+		if len(r.dataDependencies) > 0 {
+			r.invalidateDependencies(hook)
+		}
 		return opFactory.NewRunHook(hook)
 	}
 
-	return nil, resolver.ErrNoOperation
+	return nil, idle.reason()
+}
+
+// invalidateDependencies records the relations whose data
+// is read by the supplied hook, according to its endpoint.
+func (r *relationsResolver) invalidateDependencies(hookInfo hook.Info) {
+	endpoint, err := r.stateTracker.Name(hookInfo.RelationId)
+	if err != nil {
+		return
+	}
+	if reads := r.dataDependencies[endpoint]; len(reads) > 0 {
+		r.stateTracker.InvalidateDependencies(hookInfo, reads)
+	}
 }
 
 // maybeDestroySubordinates checks whether the remote state indicates that the
@@ -118,7 +273,9 @@ func (r *relationsResolver) maybeDestroySubordinates(remoteState remotestate.Sna
 	return nil
 }
 
-func (r *relationsResolver) nextHookForRelation(localStateDir *StateDir, remote remotestate.RelationSnapshot, remoteBroken bool) (hook.Info, error) {
+func (r *relationsResolver) nextHookForRelation(
+	localStateDir *StateDir, remote, last remotestate.RelationSnapshot, remoteBroken, remoteDeparting bool,
+) (hook.Info, error) {
 	// If there's a guaranteed next hook, return that.
 	local := localStateDir.State()
 	relationId := local.RelationId
@@ -137,9 +294,23 @@ func (r *relationsResolver) nextHookForRelation(localStateDir *StateDir, remote
 			RemoteUnit:        unitName,
 			RemoteApplication: appName,
 			ChangeVersion:     remote.Members[unitName],
+			Observed:          remote.MembersObserved[unitName],
 		}, nil
 	}
 
+	// A relation that is to be broken needs no reconciliation.
+	if r.reconcile && remoteBroken {
+		delete(r.reconciliations, relationId)
+	} else if r.reconcile {
+		hi, ok, err := r.reconciledHook(local, remote)
+		if err != nil {
+			return hook.Info{}, errors.Trace(err)
+		}
+		if ok {
+			return hi, nil
+		}
+	}
+
 	// Get related app names, trigger all app hooks first
 	allAppNames := set.NewStrings()
 	for appName := range local.ApplicationMembers {
@@ -195,7 +366,20 @@ func (r *relationsResolver) nextHookForRelation(localStateDir *StateDir, remote
 		if !localStateDir.Exists() {
 			// The relation may have been suspended and then
 			// removed, so we don't want to run the hook twice.
-			return hook.Info{}, resolver.ErrNoOperation
+			if last.Suspended {
+				return hook.Info{}, resolver.NewNoOperationReason(
+					resolver.SuspendedRelation, "relation %d is suspended", relationId)
+			}
+			return hook.Info{}, resolver.NewNoOperationReason(
+				resolver.AllUpToDate, "relation %d is broken", relationId)
+		}
+
+		// The departing application's final data is only of use
+		// if the relation is broken because it is departing.
+		if r.goodbyeData && remoteDeparting {
+			if hi, ok := goodbyeHook(local, last); ok {
+				return hi, nil
+			}
 		}
 
 		return hook.Info{
@@ -219,11 +403,13 @@ func (r *relationsResolver) nextHookForRelation(localStateDir *StateDir, remote
 		//  is nothing for them to respond to.
 		if oldVersion := local.ApplicationMembers[appName]; oldVersion != changeVersion {
 			return hook.Info{
-				Kind:              hooks.RelationChanged,
-				RelationId:        relationId,
-				RemoteUnit:        "",
-				RemoteApplication: appName,
-				ChangeVersion:     changeVersion,
+				Kind:                   hooks.RelationChanged,
+				RelationId:             relationId,
+				RemoteUnit:             "",
+				RemoteApplication:      appName,
+				ChangeVersion:          changeVersion,
+				IngressNetworksVersion: remote.IngressNetworksVersion,
+				EgressNetworksVersion:  remote.EgressNetworksVersion,
 			}, nil
 		}
 	}
@@ -245,6 +431,7 @@ func (r *relationsResolver) nextHookForRelation(localStateDir *StateDir, remote
 				RemoteUnit:        unitName,
 				RemoteApplication: appName,
 				ChangeVersion:     changeVersion,
+				Observed:          remote.MembersObserved[unitName],
 			}, nil
 		}
 	}
@@ -275,12 +462,62 @@ func (r *relationsResolver) nextHookForRelation(localStateDir *StateDir, remote
 				RemoteUnit:        unitName,
 				RemoteApplication: appName,
 				ChangeVersion:     remoteChangeVersion,
+				Observed:          remote.MembersObserved[unitName],
+			}, nil
+		}
+	}
+
+	// Changes to the networks of a cross model relation are seen by the
+	// charm in a relation-changed hook for the remote application, even
+	// though its data has not changed.
+	if local.IngressNetworksVersion != remote.IngressNetworksVersion ||
+		local.EgressNetworksVersion != remote.EgressNetworksVersion {
+		appName := r.stateTracker.RemoteApplication(relationId)
+		if appName != "" {
+			return hook.Info{
+				Kind:                   hooks.RelationChanged,
+				RelationId:             relationId,
+				RemoteApplication:      appName,
+				ChangeVersion:          remote.ApplicationMembers[appName],
+				IngressNetworksVersion: remote.IngressNetworksVersion,
+				EgressNetworksVersion:  remote.EgressNetworksVersion,
 			}, nil
 		}
 	}
 
 	// Nothing left to do for this relation.
-	return hook.Info{}, resolver.ErrNoOperation
+	if len(remote.Members) == 0 && len(remote.ApplicationMembers) == 0 && !isPeer {
+		return hook.Info{}, resolver.NewNoOperationReason(
+			resolver.WaitingRemoteApp, "relation %d has no remote units", relationId)
+	}
+	return hook.Info{}, resolver.NewNoOperationReason(
+		resolver.AllUpToDate, "relation %d is up to date", relationId)
+}
+
+// goodbyeHook returns a relation-changed hook for the first remote
+// application in the last known snapshot of a breaking relation, whose
+// data has changed since a hook was last run for it.
+// False is returned if there is no such application.
+func goodbyeHook(local *State, last remotestate.RelationSnapshot) (hook.Info, bool) {
+	appNames := set.NewStrings()
+	for appName := range last.ApplicationMembers {
+		appNames.Add(appName)
+	}
+	for _, appName := range appNames.SortedValues() {
+		changeVersion := last.ApplicationMembers[appName]
+		if local.ApplicationMembers[appName] == changeVersion {
+			continue
+		}
+		return hook.Info{
+			Kind:                   hooks.RelationChanged,
+			RelationId:             local.RelationId,
+			RemoteApplication:      appName,
+			ChangeVersion:          changeVersion,
+			IngressNetworksVersion: last.IngressNetworksVersion,
+			EgressNetworksVersion:  last.EgressNetworksVersion,
+		}, true
+	}
+	return hook.Info{}, false
 }
 
 // NewCreatedRelationResolver returns a resolver that handles relation-created
@@ -313,7 +550,7 @@ func (r *createdRelationsResolver) NextOp(localState resolver.LocalState, remote
 
 		hook, err := r.nextHookForRelation(relationId, relationSnapshot)
 		if err != nil {
-			if err == resolver.ErrNoOperation {
+			if errors.Cause(err) == resolver.ErrNoOperation {
 				continue
 			}
 
@@ -338,3 +575,47 @@ func (r *createdRelationsResolver) nextHookForRelation(relationId int, relationS
 		RemoteApplication: r.stateTracker.RemoteApplication(relationId),
 	}, nil
 }
+
+// idleRelations collects the reasons that no hooks need to run for each
+// relation, so that the most significant of them can be reported.
+type idleRelations struct {
+	reasons map[int]*resolver.NoOperationReason
+}
+
+func (i *idleRelations) add(relationId int, err error) {
+	reason, ok := resolver.NoOperationReasonOf(err)
+	if !ok {
+		reason = &resolver.NoOperationReason{Code: resolver.AllUpToDate}
+	}
+	if i.reasons == nil {
+		i.reasons = make(map[int]*resolver.NoOperationReason)
+	}
+	i.reasons[relationId] = reason
+}
+
+// reason returns an ErrNoOperation explaining why no hooks are to be run.
+// Quarantined relations, then relations that are waiting on something,
+// take precedence over those that are up to date.
+func (i *idleRelations) reason() error {
+	relationIds := make([]int, 0, len(i.reasons))
+	for relationId := range i.reasons {
+		relationIds = append(relationIds, relationId)
+	}
+	sort.Ints(relationIds)
+	for _, code := range []resolver.NoOperationCode{
+		resolver.QuarantinedRelation,
+		resolver.WaitingRemoteApp,
+		resolver.SuspendedRelation,
+	} {
+		var details []string
+		for _, relationId := range relationIds {
+			if reason := i.reasons[relationId]; reason.Code == code {
+				details = append(details, reason.Detail)
+			}
+		}
+		if len(details) > 0 {
+			return resolver.NewNoOperationReason(code, "%s", strings.Join(details, "; "))
+		}
+	}
+	return resolver.NewNoOperationReason(resolver.AllUpToDate, "%d relation(s) up to date", len(relationIds))
+}
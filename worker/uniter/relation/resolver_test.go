@@ -8,8 +8,10 @@ import (
 	"os"
 	"path/filepath"
 	"sync/atomic"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/juju/clock/testclock"
 	"github.com/juju/errors"
 	"github.com/juju/testing"
 	jc "github.com/juju/testing/checkers"
@@ -58,6 +60,8 @@ type relationResolverSuite struct {
 var (
 	_ = gc.Suite(&relationResolverSuite{})
 	_ = gc.Suite(&relationCreatedResolverSuite{})
+	_ = gc.Suite(&goodbyeDataResolverSuite{})
+	_ = gc.Suite(&relationNetworksResolverSuite{})
 )
 
 type apiCall struct {
@@ -278,6 +282,15 @@ func (s *relationResolverSuite) TestNextOpNothing(c *gc.C) {
 	relationsResolver := relation.NewRelationResolver(r, nil)
 	_, err = relationsResolver.NextOp(localState, remoteState, &mockOperations{})
 	c.Assert(errors.Cause(err), gc.Equals, resolver.ErrNoOperation)
+	reason, ok := resolver.NoOperationReasonOf(err)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(reason.Code, gc.Equals, resolver.AllUpToDate)
+
+	localState.Kind = operation.RunHook
+	_, err = relationsResolver.NextOp(localState, remoteState, &mockOperations{})
+	reason, ok = resolver.NoOperationReasonOf(err)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(reason.Code, gc.Equals, resolver.OperationPending)
 }
 
 func relationJoinedAPICalls() []apiCall {
@@ -371,11 +384,120 @@ func (s *relationResolverSuite) TestHookRelationJoined(c *gc.C) {
 	s.assertHookRelationJoined(c, &numCalls, relationJoinedAPICalls()...)
 }
 
+func (s *relationResolverSuite) TestDrainAndResume(c *gc.C) {
+	var numCalls int32
+	r := s.assertHookRelationJoined(c, &numCalls, relationJoinedAPICalls()...)
+
+	localState := resolver.LocalState{
+		State: operation.State{
+			Kind: operation.Continue,
+		},
+	}
+	remoteState := remotestate.Snapshot{
+		Relations: map[int]remotestate.RelationSnapshot{
+			1: {
+				Life: life.Alive,
+				Members: map[string]int64{
+					"wordpress/0": 1,
+				},
+			},
+		},
+	}
+	relationsResolver := relation.NewRelationResolver(r, nil)
+
+	// The pending relation-changed hook is not dispatched while drained.
+	relationsResolver.Drain()
+	_, err := relationsResolver.NextOp(localState, remoteState, &mockOperations{})
+	c.Assert(errors.Cause(err), gc.Equals, resolver.ErrNoOperation)
+	reason, ok := resolver.NoOperationReasonOf(err)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(reason.Code, gc.Equals, resolver.Draining)
+
+	relationsResolver.Resume()
+	op, err := relationsResolver.NextOp(localState, remoteState, &mockOperations{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(op.String(), gc.Equals, "run hook relation-changed on unit wordpress/0 with relation 1")
+}
+
+func (s *relationResolverSuite) TestQuarantinedRelation(c *gc.C) {
+	var numCalls int32
+	r := s.assertHookRelationJoined(c, &numCalls, relationJoinedAPICalls()...)
+
+	localState := resolver.LocalState{
+		State: operation.State{
+			Kind: operation.Continue,
+			QuarantinedHooks: map[int]hook.Info{
+				1: {
+					Kind:              hooks.RelationChanged,
+					RelationId:        1,
+					RemoteUnit:        "wordpress/0",
+					RemoteApplication: "wordpress",
+				},
+			},
+		},
+	}
+	remoteState := remotestate.Snapshot{
+		Relations: map[int]remotestate.RelationSnapshot{
+			1: {
+				Life: life.Alive,
+				Members: map[string]int64{
+					"wordpress/0": 1,
+				},
+			},
+		},
+	}
+	relationsResolver := relation.NewRelationResolver(r, nil)
+
+	// The pending relation-changed hook is not dispatched
+	// while the relation is quarantined.
+	_, err := relationsResolver.NextOp(localState, remoteState, &mockOperations{})
+	c.Assert(errors.Cause(err), gc.Equals, resolver.ErrNoOperation)
+	reason, ok := resolver.NoOperationReasonOf(err)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(reason.Code, gc.Equals, resolver.QuarantinedRelation)
+	c.Assert(reason.Detail, gc.Equals, "relation 1 is quarantined")
+
+	localState.QuarantinedHooks = nil
+	op, err := relationsResolver.NextOp(localState, remoteState, &mockOperations{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(op.String(), gc.Equals, "run hook relation-changed on unit wordpress/0 with relation 1")
+}
+
+func (s *relationResolverSuite) TestReport(c *gc.C) {
+	var numCalls int32
+	r := s.assertHookRelationJoined(c, &numCalls, relationJoinedAPICalls()...)
+
+	state := map[string]interface{}{
+		"members": map[string]interface{}{
+			"wordpress/0": int64(1),
+		},
+		"application-members": map[string]interface{}{},
+		"changed-pending":     "wordpress/0",
+	}
+	c.Assert(r.Report(), jc.DeepEquals, map[string]interface{}{
+		"relations": map[string]interface{}{
+			"1": map[string]interface{}{
+				"endpoint":           "mysql",
+				"scope":              "global",
+				"remote-application": "",
+				"dying":              false,
+				"implicit":           false,
+				"peer":               false,
+				"relation-created":   false,
+				"state":              state,
+			},
+		},
+		"on-disk": map[string]interface{}{
+			"1": state,
+		},
+	})
+}
+
 func (s *relationResolverSuite) assertHookRelationChanged(
 	c *gc.C, r relation.RelationStateTracker,
 	remoteRelationSnapshot remotestate.RelationSnapshot,
 	numCalls *int32,
-) {
+) hook.Info {
 	numCallsBefore := *numCalls
 	localState := resolver.LocalState{
 		State: operation.State{
@@ -394,10 +516,12 @@ func (s *relationResolverSuite) assertHookRelationChanged(
 	c.Assert(op.String(), gc.Equals, "run hook relation-changed on unit wordpress/0 with relation 1")
 
 	// Commit the operation so we save local state for any next operation.
-	_, err = r.PrepareHook(op.(*mockOperation).hookInfo)
+	hookInfo := op.(*mockOperation).hookInfo
+	_, err = r.PrepareHook(hookInfo)
 	c.Assert(err, jc.ErrorIsNil)
-	err = r.CommitHook(op.(*mockOperation).hookInfo)
+	err = r.CommitHook(hookInfo)
 	c.Assert(err, jc.ErrorIsNil)
+	return hookInfo
 }
 
 func (s *relationResolverSuite) TestHookRelationChanged(c *gc.C) {
@@ -437,6 +561,27 @@ func (s *relationResolverSuite) TestHookRelationChanged(c *gc.C) {
 	}, &numCalls)
 }
 
+func (s *relationResolverSuite) TestHookRelationChangedObserved(c *gc.C) {
+	var numCalls int32
+	apiCalls := relationJoinedAPICalls()
+	r := s.assertHookRelationJoined(c, &numCalls, apiCalls...)
+
+	observed := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	hookInfo := s.assertHookRelationChanged(c, r, remotestate.RelationSnapshot{
+		Life:            life.Alive,
+		Members:         map[string]int64{"wordpress/0": 1},
+		MembersObserved: map[string]time.Time{"wordpress/0": observed},
+	}, &numCalls)
+	c.Check(hookInfo.Observed, gc.Equals, observed)
+
+	// A change whose observation is not known has none.
+	hookInfo = s.assertHookRelationChanged(c, r, remotestate.RelationSnapshot{
+		Life:    life.Alive,
+		Members: map[string]int64{"wordpress/0": 2},
+	}, &numCalls)
+	c.Check(hookInfo.Observed.IsZero(), jc.IsTrue)
+}
+
 func (s *relationResolverSuite) TestHookRelationChangedApplication(c *gc.C) {
 	var numCalls int32
 	apiCalls := relationJoinedAPICalls()
@@ -481,6 +626,169 @@ func (s *relationResolverSuite) TestHookRelationChangedApplication(c *gc.C) {
 	c.Assert(op.String(), gc.Equals, "run hook relation-changed on app wordpress with relation 1")
 }
 
+func (s *relationResolverSuite) TestHookRelationChangedPrefetchesSettings(c *gc.C) {
+	var numCalls int32
+	settingsArgs := params.RelationUnitPairs{RelationUnitPairs: []params.RelationUnitPair{{
+		Relation:   "relation-wordpress.db#mysql.db",
+		LocalUnit:  "unit-wordpress-0",
+		RemoteUnit: "unit-wordpress-0",
+	}}}
+	settingsResults := params.SettingsResults{Results: []params.SettingsResult{{
+		Settings: params.Settings{"foo": "bar"},
+	}}}
+	apiCalls := append(relationJoinedAPICalls(),
+		uniterAPICall("ReadRemoteSettings", settingsArgs, settingsResults, nil),
+	)
+	r := s.assertHookRelationJoined(c, &numCalls, apiCalls...)
+
+	localState := resolver.LocalState{
+		State: operation.State{
+			Kind: operation.Continue,
+		},
+	}
+	remoteState := remotestate.Snapshot{
+		Relations: map[int]remotestate.RelationSnapshot{
+			1: {
+				Life: life.Alive,
+				Members: map[string]int64{
+					"wordpress/0": 1,
+				},
+			},
+		},
+	}
+	relationsResolver := relation.NewRelationResolver(r, nil, relation.WithSettingsPrefetch())
+	op, err := relationsResolver.NextOp(localState, remoteState, &mockOperations{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(op.String(), gc.Equals, "run hook relation-changed on unit wordpress/0 with relation 1")
+	hookInfo := op.(*mockOperation).hookInfo
+
+	// Selecting the same hook again does not read the settings again.
+	_, err = relationsResolver.NextOp(localState, remoteState, &mockOperations{})
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Settings prefetched for another hook are not returned.
+	otherInfo := hookInfo
+	otherInfo.ChangeVersion++
+	_, ok := r.PrefetchedSettings(otherInfo)
+	c.Assert(ok, jc.IsFalse)
+
+	settings, ok := r.PrefetchedSettings(hookInfo)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(settings, jc.DeepEquals, params.Settings{"foo": "bar"})
+	assertNumCalls(c, &numCalls, 10)
+
+	// The settings are only returned once.
+	_, ok = r.PrefetchedSettings(hookInfo)
+	c.Assert(ok, jc.IsFalse)
+}
+
+func (s *relationResolverSuite) TestHookRelationChangedPrefetchError(c *gc.C) {
+	var numCalls int32
+	settingsArgs := params.RelationUnitPairs{RelationUnitPairs: []params.RelationUnitPair{{
+		Relation:   "relation-wordpress.db#mysql.db",
+		LocalUnit:  "unit-wordpress-0",
+		RemoteUnit: "unit-wordpress-0",
+	}}}
+	apiCalls := append(relationJoinedAPICalls(),
+		uniterAPICall("ReadRemoteSettings", settingsArgs, nil, errors.New("boom")),
+	)
+	r := s.assertHookRelationJoined(c, &numCalls, apiCalls...)
+
+	hookInfo := hook.Info{
+		Kind:              hooks.RelationChanged,
+		RelationId:        1,
+		RemoteUnit:        "wordpress/0",
+		RemoteApplication: "wordpress",
+		ChangeVersion:     1,
+	}
+	r.PrefetchSettings(hookInfo)
+	_, ok := r.PrefetchedSettings(hookInfo)
+	c.Assert(ok, jc.IsFalse)
+	assertNumCalls(c, &numCalls, 10)
+}
+
+func (s *relationResolverSuite) TestPinSettings(c *gc.C) {
+	var numCalls int32
+	r := s.assertHookRelationJoined(c, &numCalls, relationJoinedAPICalls()...)
+
+	hookInfo := hook.Info{
+		Kind:              hooks.RelationChanged,
+		RelationId:        1,
+		RemoteUnit:        "wordpress/0",
+		RemoteApplication: "wordpress",
+		ChangeVersion:     1,
+	}
+	settings := params.Settings{"foo": "bar"}
+	err := r.PinSettings(hookInfo, settings)
+	c.Assert(err, jc.ErrorIsNil)
+
+	hash, err := relation.SettingsHash(settings)
+	c.Assert(err, jc.ErrorIsNil)
+	dir, err := r.StateDir(1)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dir.State().Pin, jc.DeepEquals, &relation.SettingsPin{
+		Remote:        "wordpress/0",
+		ChangeVersion: 1,
+		Hash:          hash,
+	})
+	state := r.Report()["relations"].(map[string]interface{})["1"].(map[string]interface{})["state"]
+	c.Assert(state.(map[string]interface{})["pinned"], jc.DeepEquals, map[string]interface{}{
+		"remote":         "wordpress/0",
+		"change-version": int64(1),
+		"settings-hash":  hash,
+	})
+
+	// The pin lasts until the hook is committed.
+	_, err = r.PrepareHook(hookInfo)
+	c.Assert(err, jc.ErrorIsNil)
+	err = r.CommitHook(hookInfo)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dir.State().Pin, gc.IsNil)
+	assertNumCalls(c, &numCalls, 9)
+}
+
+func (s *relationResolverSuite) TestPinSettingsUnknownRelation(c *gc.C) {
+	var numCalls int32
+	r := s.assertHookRelationJoined(c, &numCalls, relationJoinedAPICalls()...)
+
+	err := r.PinSettings(hook.Info{
+		Kind:              hooks.RelationChanged,
+		RelationId:        2,
+		RemoteApplication: "varnish",
+	}, params.Settings{})
+	c.Assert(err, gc.ErrorMatches, "unknown relation: 2")
+}
+
+func (s *relationResolverSuite) TestInvalidateDependencies(c *gc.C) {
+	var numCalls int32
+	r := s.assertHookRelationJoined(c, &numCalls, relationJoinedAPICalls()...)
+
+	hookInfo := hook.Info{
+		Kind:              hooks.RelationChanged,
+		RelationId:        2,
+		RemoteApplication: "varnish",
+		ChangeVersion:     1,
+	}
+	r.InvalidateDependencies(hookInfo, []string{"mysql", "cache"})
+	c.Assert(r.StaleRelations(hookInfo), jc.DeepEquals, []int{1})
+
+	// Relations are only stale for the hook they were recorded for.
+	otherInfo := hookInfo
+	otherInfo.ChangeVersion++
+	c.Assert(r.StaleRelations(otherInfo), gc.HasLen, 0)
+
+	// A hook's own relation is never stale.
+	ownInfo := hook.Info{
+		Kind:              hooks.RelationChanged,
+		RelationId:        1,
+		RemoteUnit:        "wordpress/0",
+		RemoteApplication: "wordpress",
+		ChangeVersion:     1,
+	}
+	r.InvalidateDependencies(ownInfo, []string{"mysql"})
+	c.Assert(r.StaleRelations(ownInfo), gc.HasLen, 0)
+}
+
 func (s *relationResolverSuite) TestHookRelationChangedSuspended(c *gc.C) {
 	var numCalls int32
 	apiCalls := relationJoinedAPICalls()
@@ -613,6 +921,57 @@ func (s *relationResolverSuite) TestHookRelationBrokenWhenSuspended(c *gc.C) {
 	c.Assert(op.String(), gc.Equals, "run hook relation-broken with relation 1")
 }
 
+func (s *relationResolverSuite) TestHookRelationSuspensionDamped(c *gc.C) {
+	var numCalls int32
+	r := s.assertHookRelationJoined(c, &numCalls, relationJoinedAPICalls()...)
+	relationSnapshot := remotestate.RelationSnapshot{
+		Life: life.Alive,
+		Members: map[string]int64{
+			"wordpress/0": 1,
+		},
+		ApplicationMembers: map[string]int64{
+			"wordpress": 0,
+		},
+	}
+	s.assertHookRelationChanged(c, r, relationSnapshot, &numCalls)
+
+	clock := testclock.NewClock(time.Now())
+	damper, err := relation.NewSuspensionDamper(
+		filepath.Join(s.relationsDir, relation.SuspensionFile), clock,
+		func(int) time.Duration { return time.Minute },
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	var wakes []time.Duration
+	relationsResolver := relation.NewRelationResolver(r, nil, relation.WithSuspensionDamping(
+		damper, func(delay time.Duration) { wakes = append(wakes, delay) },
+	))
+	localState := resolver.LocalState{
+		State: operation.State{
+			Kind: operation.Continue,
+		},
+	}
+	nextOp := func(suspended bool) (operation.Operation, error) {
+		relationSnapshot.Suspended = suspended
+		return relationsResolver.NextOp(localState, remotestate.Snapshot{
+			Relations: map[int]remotestate.RelationSnapshot{1: relationSnapshot},
+		}, &mockOperations{})
+	}
+
+	_, err = nextOp(false)
+	c.Assert(errors.Cause(err), gc.Equals, resolver.ErrNoOperation)
+
+	// The suspension is held back until it has been stable for the
+	// window, and the resolver is woken when it is due.
+	_, err = nextOp(true)
+	c.Assert(errors.Cause(err), gc.Equals, resolver.ErrNoOperation)
+	c.Assert(wakes, jc.DeepEquals, []time.Duration{time.Minute})
+
+	clock.Advance(time.Minute)
+	op, err := nextOp(true)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(op.String(), gc.Equals, "run hook relation-departed on unit wordpress/0 with relation 1")
+}
+
 func (s *relationResolverSuite) TestHookRelationBrokenOnlyOnce(c *gc.C) {
 	var numCalls int32
 	apiCalls := relationJoinedAPICalls()
@@ -645,6 +1004,10 @@ func (s *relationResolverSuite) TestHookRelationBrokenOnlyOnce(c *gc.C) {
 	c.Assert(err, jc.ErrorIsNil)
 	_, err = relationsResolver.NextOp(localState, remoteState, &mockOperations{})
 	c.Assert(errors.Cause(err), gc.Equals, resolver.ErrNoOperation)
+	reason, ok := resolver.NoOperationReasonOf(err)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(reason.Code, gc.Equals, resolver.SuspendedRelation)
+	c.Assert(reason.Detail, gc.Equals, "relation 1 is suspended")
 }
 
 func (s *relationResolverSuite) TestCommitHook(c *gc.C) {
@@ -1157,3 +1520,264 @@ func (s *relationCreatedResolverSuite) TestCreatedRelationResolverFordRelationNo
 		},
 	})
 }
+
+type goodbyeDataResolverSuite struct{}
+
+func (s *goodbyeDataResolverSuite) setupStateDir(c *gc.C) *relation.StateDir {
+	dir, err := relation.ReadStateDir(c.MkDir(), 1)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dir.Ensure(), jc.ErrorIsNil)
+	err = dir.Write(hook.Info{
+		Kind:              hooks.RelationChanged,
+		RelationId:        1,
+		RemoteApplication: "mysql",
+		ChangeVersion:     1,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	return dir
+}
+
+func (s *goodbyeDataResolverSuite) dyingRelationState() (resolver.LocalState, remotestate.Snapshot) {
+	localState := resolver.LocalState{
+		State: operation.State{
+			Kind: operation.Continue,
+		},
+	}
+	remoteState := remotestate.Snapshot{
+		Life: life.Alive,
+		Relations: map[int]remotestate.RelationSnapshot{
+			1: {
+				Life: life.Dying,
+				ApplicationMembers: map[string]int64{
+					"mysql": 2,
+				},
+			},
+		},
+	}
+	return localState, remoteState
+}
+
+func (s *goodbyeDataResolverSuite) expectBreakingRelation(r *mocks.MockRelationStateTracker, dir *relation.StateDir) {
+	r.EXPECT().SynchronizeScopes(gomock.Any()).Return(nil)
+	r.EXPECT().IsKnown(1).Return(true)
+	r.EXPECT().IsImplicit(1).Return(false, nil)
+	r.EXPECT().StateDir(1).Return(dir, nil)
+	r.EXPECT().IsPeerRelation(1).Return(false, nil)
+}
+
+func (s *goodbyeDataResolverSuite) TestGoodbyeDataChangedBeforeBroken(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+
+	dir := s.setupStateDir(c)
+	r := mocks.NewMockRelationStateTracker(ctrl)
+	s.expectBreakingRelation(r, dir)
+
+	localState, remoteState := s.dyingRelationState()
+	relationsResolver := relation.NewRelationResolver(r, nil, relation.WithGoodbyeData())
+	op, err := relationsResolver.NextOp(localState, remoteState, &mockOperations{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(op, gc.DeepEquals, &mockOperation{
+		hookInfo: hook.Info{
+			Kind:              hooks.RelationChanged,
+			RelationId:        1,
+			RemoteApplication: "mysql",
+			ChangeVersion:     2,
+		},
+	})
+}
+
+func (s *goodbyeDataResolverSuite) TestGoodbyeDataAlreadySeenBroken(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+
+	dir := s.setupStateDir(c)
+	r := mocks.NewMockRelationStateTracker(ctrl)
+	s.expectBreakingRelation(r, dir)
+	r.EXPECT().RemoteApplication(1).Return("mysql")
+
+	localState, remoteState := s.dyingRelationState()
+	snapshot := remoteState.Relations[1]
+	snapshot.ApplicationMembers["mysql"] = 1
+	remoteState.Relations[1] = snapshot
+
+	relationsResolver := relation.NewRelationResolver(r, nil, relation.WithGoodbyeData())
+	op, err := relationsResolver.NextOp(localState, remoteState, &mockOperations{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(op.String(), gc.Equals, "run hook relation-broken with relation 1")
+}
+
+func (s *goodbyeDataResolverSuite) TestNoGoodbyeDataByDefault(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+
+	dir := s.setupStateDir(c)
+	r := mocks.NewMockRelationStateTracker(ctrl)
+	s.expectBreakingRelation(r, dir)
+	r.EXPECT().RemoteApplication(1).Return("mysql")
+
+	localState, remoteState := s.dyingRelationState()
+	relationsResolver := relation.NewRelationResolver(r, nil)
+	op, err := relationsResolver.NextOp(localState, remoteState, &mockOperations{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(op.String(), gc.Equals, "run hook relation-broken with relation 1")
+}
+
+func (s *goodbyeDataResolverSuite) TestNoGoodbyeDataForDyingUnit(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+
+	dir := s.setupStateDir(c)
+	r := mocks.NewMockRelationStateTracker(ctrl)
+	r.EXPECT().HasContainerScope(1).Return(false, nil)
+	s.expectBreakingRelation(r, dir)
+	r.EXPECT().RemoteApplication(1).Return("mysql")
+
+	localState, remoteState := s.dyingRelationState()
+	remoteState.Life = life.Dying
+	snapshot := remoteState.Relations[1]
+	snapshot.Life = life.Alive
+	remoteState.Relations[1] = snapshot
+
+	relationsResolver := relation.NewRelationResolver(r, nil, relation.WithGoodbyeData())
+	op, err := relationsResolver.NextOp(localState, remoteState, &mockOperations{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(op.String(), gc.Equals, "run hook relation-broken with relation 1")
+}
+
+func (s *goodbyeDataResolverSuite) TestNoGoodbyeDataForSuspendedRelation(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+
+	dir := s.setupStateDir(c)
+	r := mocks.NewMockRelationStateTracker(ctrl)
+	s.expectBreakingRelation(r, dir)
+	r.EXPECT().RemoteApplication(1).Return("mysql")
+
+	localState, remoteState := s.dyingRelationState()
+	snapshot := remoteState.Relations[1]
+	snapshot.Life = life.Alive
+	snapshot.Suspended = true
+	remoteState.Relations[1] = snapshot
+
+	relationsResolver := relation.NewRelationResolver(r, nil, relation.WithGoodbyeData())
+	op, err := relationsResolver.NextOp(localState, remoteState, &mockOperations{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(op.String(), gc.Equals, "run hook relation-broken with relation 1")
+}
+
+type relationNetworksResolverSuite struct{}
+
+func (s *relationNetworksResolverSuite) setupStateDir(c *gc.C) *relation.StateDir {
+	dir, err := relation.ReadStateDir(c.MkDir(), 1)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dir.Ensure(), jc.ErrorIsNil)
+	err = dir.Write(hook.Info{
+		Kind:                   hooks.RelationChanged,
+		RelationId:             1,
+		RemoteApplication:      "mysql",
+		ChangeVersion:          1,
+		IngressNetworksVersion: "ingress-1",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	return dir
+}
+
+func (s *relationNetworksResolverSuite) relationState(ingress, egress string) (resolver.LocalState, remotestate.Snapshot) {
+	localState := resolver.LocalState{
+		State: operation.State{
+			Kind: operation.Continue,
+		},
+	}
+	remoteState := remotestate.Snapshot{
+		Life: life.Alive,
+		Relations: map[int]remotestate.RelationSnapshot{
+			1: {
+				Life:                   life.Alive,
+				ApplicationMembers:     map[string]int64{"mysql": 1},
+				IngressNetworksVersion: ingress,
+				EgressNetworksVersion:  egress,
+			},
+		},
+	}
+	return localState, remoteState
+}
+
+func (s *relationNetworksResolverSuite) expectRelation(r *mocks.MockRelationStateTracker, dir *relation.StateDir) {
+	r.EXPECT().SynchronizeScopes(gomock.Any()).Return(nil)
+	r.EXPECT().IsKnown(1).Return(true)
+	r.EXPECT().IsImplicit(1).Return(false, nil)
+	r.EXPECT().StateDir(1).Return(dir, nil)
+	r.EXPECT().IsPeerRelation(1).Return(false, nil)
+}
+
+func (s *relationNetworksResolverSuite) TestNetworksChanged(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+
+	dir := s.setupStateDir(c)
+	r := mocks.NewMockRelationStateTracker(ctrl)
+	s.expectRelation(r, dir)
+	r.EXPECT().RemoteApplication(1).Return("mysql")
+
+	localState, remoteState := s.relationState("ingress-2", "egress-1")
+	relationsResolver := relation.NewRelationResolver(r, nil)
+	op, err := relationsResolver.NextOp(localState, remoteState, &mockOperations{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(op, gc.DeepEquals, &mockOperation{
+		hookInfo: hook.Info{
+			Kind:                   hooks.RelationChanged,
+			RelationId:             1,
+			RemoteApplication:      "mysql",
+			ChangeVersion:          1,
+			IngressNetworksVersion: "ingress-2",
+			EgressNetworksVersion:  "egress-1",
+		},
+	})
+
+	// Once the hook is committed, the networks are up to date.
+	err = dir.Write(op.(*mockOperation).hookInfo)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dir.State().IngressNetworksVersion, gc.Equals, "ingress-2")
+	c.Assert(dir.State().EgressNetworksVersion, gc.Equals, "egress-1")
+}
+
+func (s *relationNetworksResolverSuite) TestNetworksRemoved(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+
+	dir := s.setupStateDir(c)
+	r := mocks.NewMockRelationStateTracker(ctrl)
+	s.expectRelation(r, dir)
+	r.EXPECT().RemoteApplication(1).Return("mysql")
+
+	localState, remoteState := s.relationState("", "")
+	relationsResolver := relation.NewRelationResolver(r, nil)
+	op, err := relationsResolver.NextOp(localState, remoteState, &mockOperations{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(op, gc.DeepEquals, &mockOperation{
+		hookInfo: hook.Info{
+			Kind:              hooks.RelationChanged,
+			RelationId:        1,
+			RemoteApplication: "mysql",
+			ChangeVersion:     1,
+		},
+	})
+}
+
+func (s *relationNetworksResolverSuite) TestNetworksUnchanged(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+
+	dir := s.setupStateDir(c)
+	r := mocks.NewMockRelationStateTracker(ctrl)
+	s.expectRelation(r, dir)
+
+	localState, remoteState := s.relationState("ingress-1", "")
+	relationsResolver := relation.NewRelationResolver(r, nil)
+	_, err := relationsResolver.NextOp(localState, remoteState, &mockOperations{})
+	c.Assert(errors.Cause(err), gc.Equals, resolver.ErrNoOperation)
+	reason, ok := resolver.NoOperationReasonOf(err)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(reason.Code, gc.Equals, resolver.AllUpToDate)
+}
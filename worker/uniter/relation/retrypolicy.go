@@ -0,0 +1,115 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package relation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/charm.v6"
+)
+
+// RetryPolicy overrides the model's hook retry strategy for
+// the failed hooks of a relation endpoint.
+type RetryPolicy struct {
+	// MaxRetries is the number of times a failed hook is retried
+	// automatically, after which it waits to be resolved.
+	MaxRetries int
+
+	// Backoff is the delay before the first retry.
+	// It is doubled for each retry after that.
+	Backoff time.Duration
+
+	// MaxBackoff is the longest delay between retries.
+	MaxBackoff time.Duration
+
+	// Quarantine, if true, sets the failed hook aside once it has
+	// been retried MaxRetries times, so that the unit's other
+	// relations can progress until it is resolved.
+	Quarantine bool
+}
+
+// Delay returns the delay before retrying a hook
+// that has already been retried the given number of times.
+func (p RetryPolicy) Delay(retries int) time.Duration {
+	delay := p.Backoff
+	for i := 0; i < retries && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// The charm config options with which a charm declares, and an operator
+// overrides, the retry policy for an endpoint's hooks. The endpoint name
+// replaces %s.
+const (
+	maxRetriesOption      = "%s-relation-max-retries"
+	retryBackoffOption    = "%s-relation-retry-backoff"
+	retryMaxBackoffOption = "%s-relation-retry-max-backoff"
+	quarantineOption      = "%s-relation-quarantine"
+)
+
+// RetryPolicyFromSettings returns the retry policy for the endpoint
+// configured in the charm settings, and whether there is one. A policy
+// requires the max retries option to be set; the backoff options are
+// durations, with the max backoff defaulting to the backoff, and the
+// quarantine option is a boolean.
+func RetryPolicyFromSettings(settings charm.Settings, endpoint string) (RetryPolicy, bool, error) {
+	var policy RetryPolicy
+	maxRetries, ok := settings[fmt.Sprintf(maxRetriesOption, endpoint)]
+	if !ok || maxRetries == nil {
+		return policy, false, nil
+	}
+	switch v := maxRetries.(type) {
+	case int64:
+		policy.MaxRetries = int(v)
+	case int:
+		policy.MaxRetries = v
+	default:
+		return policy, false, errors.NotValidf("%s value %v", fmt.Sprintf(maxRetriesOption, endpoint), maxRetries)
+	}
+	if policy.MaxRetries < 0 {
+		return policy, false, errors.NotValidf("negative %s", fmt.Sprintf(maxRetriesOption, endpoint))
+	}
+
+	var err error
+	if policy.Backoff, err = durationSetting(settings, fmt.Sprintf(retryBackoffOption, endpoint)); err != nil {
+		return policy, false, errors.Trace(err)
+	}
+	if policy.MaxBackoff, err = durationSetting(settings, fmt.Sprintf(retryMaxBackoffOption, endpoint)); err != nil {
+		return policy, false, errors.Trace(err)
+	}
+	if policy.MaxBackoff < policy.Backoff {
+		policy.MaxBackoff = policy.Backoff
+	}
+	key := fmt.Sprintf(quarantineOption, endpoint)
+	switch v := settings[key].(type) {
+	case nil:
+	case bool:
+		policy.Quarantine = v
+	default:
+		return policy, false, errors.NotValidf("%s value %v", key, v)
+	}
+	return policy, true, nil
+}
+
+func durationSetting(settings charm.Settings, key string) (time.Duration, error) {
+	value, ok := settings[key]
+	if !ok || value == nil || value == "" {
+		return 0, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return 0, errors.NotValidf("%s value %v", key, value)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, errors.NotValidf("%s value %q", key, s)
+	}
+	return d, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package relation_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/charm.v6"
+
+	"github.com/juju/juju/worker/uniter/relation"
+)
+
+type retryPolicySuite struct{}
+
+var _ = gc.Suite(&retryPolicySuite{})
+
+func (s *retryPolicySuite) TestNoPolicy(c *gc.C) {
+	_, ok, err := relation.RetryPolicyFromSettings(charm.Settings{
+		"db-relation-retry-backoff": "1m",
+	}, "db")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ok, jc.IsFalse)
+}
+
+func (s *retryPolicySuite) TestPolicy(c *gc.C) {
+	policy, ok, err := relation.RetryPolicyFromSettings(charm.Settings{
+		"db-relation-max-retries":       int64(3),
+		"db-relation-retry-backoff":     "10s",
+		"db-relation-retry-max-backoff": "30s",
+		"web-relation-max-retries":      int64(1),
+	}, "db")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(policy, jc.DeepEquals, relation.RetryPolicy{
+		MaxRetries: 3,
+		Backoff:    10 * time.Second,
+		MaxBackoff: 30 * time.Second,
+	})
+}
+
+func (s *retryPolicySuite) TestMaxBackoffDefaultsToBackoff(c *gc.C) {
+	policy, ok, err := relation.RetryPolicyFromSettings(charm.Settings{
+		"db-relation-max-retries":   int64(2),
+		"db-relation-retry-backoff": "1m",
+	}, "db")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(policy.MaxBackoff, gc.Equals, time.Minute)
+}
+
+func (s *retryPolicySuite) TestQuarantine(c *gc.C) {
+	policy, ok, err := relation.RetryPolicyFromSettings(charm.Settings{
+		"db-relation-max-retries": int64(2),
+		"db-relation-quarantine":  true,
+	}, "db")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(policy, jc.DeepEquals, relation.RetryPolicy{
+		MaxRetries: 2,
+		Quarantine: true,
+	})
+}
+
+func (s *retryPolicySuite) TestInvalidSettings(c *gc.C) {
+	for i, settings := range []charm.Settings{{
+		"db-relation-max-retries": "3",
+	}, {
+		"db-relation-max-retries": int64(-1),
+	}, {
+		"db-relation-max-retries":   int64(3),
+		"db-relation-retry-backoff": "soon",
+	}, {
+		"db-relation-max-retries":       int64(3),
+		"db-relation-retry-max-backoff": int64(10),
+	}, {
+		"db-relation-max-retries": int64(3),
+		"db-relation-quarantine":  "yes",
+	}} {
+		c.Logf("test %d", i)
+		_, ok, err := relation.RetryPolicyFromSettings(settings, "db")
+		c.Check(err, jc.Satisfies, errors.IsNotValid)
+		c.Check(ok, jc.IsFalse)
+	}
+}
+
+func (s *retryPolicySuite) TestDelay(c *gc.C) {
+	policy := relation.RetryPolicy{
+		MaxRetries: 5,
+		Backoff:    time.Second,
+		MaxBackoff: 5 * time.Second,
+	}
+	var delays []time.Duration
+	for retries := 0; retries < 5; retries++ {
+		delays = append(delays, policy.Delay(retries))
+	}
+	c.Assert(delays, jc.DeepEquals, []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second,
+	})
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/juju/utils"
 	"gopkg.in/juju/charm.v6/hooks"
+	"gopkg.in/juju/names.v3"
 
 	"github.com/juju/juju/worker/uniter/hook"
 )
@@ -36,13 +37,30 @@ type State struct {
 	// ChangedPending indicates that a "relation-changed" hook for the given
 	// unit name must be the first hook.Info to be sent to the output channel.
 	ChangedPending string
+
+	// IngressNetworksVersion and EgressNetworksVersion identify the
+	// ingress and egress CIDRs of a cross model relation seen by the
+	// last relation-changed hook run for the remote application.
+	IngressNetworksVersion string
+	EgressNetworksVersion  string
+
+	// Pin identifies the remote settings seen by the relation hook
+	// being run, so that relation-get returns the settings that
+	// triggered it. It is nil when no hook is being run.
+	Pin *SettingsPin
 }
 
 // copy returns an independent copy of the state.
 func (s *State) copy() *State {
 	copy := &State{
-		RelationId:     s.RelationId,
-		ChangedPending: s.ChangedPending,
+		RelationId:             s.RelationId,
+		ChangedPending:         s.ChangedPending,
+		IngressNetworksVersion: s.IngressNetworksVersion,
+		EgressNetworksVersion:  s.EgressNetworksVersion,
+	}
+	if s.Pin != nil {
+		pin := *s.Pin
+		copy.Pin = &pin
 	}
 	if s.Members != nil {
 		copy.Members = make(map[string]int64, len(s.Members))
@@ -109,12 +127,17 @@ func (s *State) Validate(hi hook.Info) (err error) {
 }
 
 // StateDir is a filesystem-backed representation of the state of a
-// relation. Concurrent modifications to the underlying state directory
-// will have undefined consequences.
+// relation. Changes to the underlying state directory are committed
+// while holding an advisory lock on it, so that another agent process
+// cannot change it concurrently.
 type StateDir struct {
 	// path identifies the directory holding persistent state.
 	path string
 
+	// tag identifies the relation for directories keyed by relation
+	// tag rather than by relation ID. It is the zero value otherwise.
+	tag names.RelationTag
+
 	// state is the cached state of the directory, which is guaranteed
 	// to be synchronized with the true state so long as no concurrent
 	// changes are made to the directory.
@@ -128,11 +151,15 @@ func (d *StateDir) State() *State {
 
 // ReadStateDir loads a StateDir from the subdirectory of dirPath named
 // for the supplied RelationId. If the directory does not exist, no error
-// is returned,
+// is returned.
 func ReadStateDir(dirPath string, relationId int) (d *StateDir, err error) {
+	return readStateDir(filepath.Join(dirPath, strconv.Itoa(relationId)), relationId)
+}
+
+func readStateDir(path string, relationId int) (d *StateDir, err error) {
 	d = &StateDir{
-		filepath.Join(dirPath, strconv.Itoa(relationId)),
-		State{
+		path: path,
+		state: State{
 			RelationId:         relationId,
 			Members:            map[string]int64{},
 			ApplicationMembers: map[string]int64{},
@@ -179,6 +206,8 @@ func ReadStateDir(dirPath string, relationId int) (d *StateDir, err error) {
 		}
 		if isApp {
 			d.state.ApplicationMembers[unitOrAppName] = *info.ChangeVersion
+			d.state.IngressNetworksVersion = info.IngressNetworksVersion
+			d.state.EgressNetworksVersion = info.EgressNetworksVersion
 		} else {
 			d.state.Members[unitOrAppName] = *info.ChangeVersion
 		}
@@ -189,12 +218,36 @@ func ReadStateDir(dirPath string, relationId int) (d *StateDir, err error) {
 			d.state.ChangedPending = unitOrAppName
 		}
 	}
+	if d.state.Pin, err = readPin(d.path); err != nil {
+		return nil, err
+	}
 	return d, nil
 }
 
 // ReadAllStateDirs loads and returns every StateDir persisted directly inside
-// the supplied dirPath. If dirPath does not exist, no error is returned.
-func ReadAllStateDirs(dirPath string) (dirs map[int]*StateDir, err error) {
+// the supplied dirPath, keyed by relation ID. Directories keyed by relation
+// tag are returned in preference to those named for the same relation ID.
+// If dirPath does not exist, no error is returned.
+func ReadAllStateDirs(dirPath string) (map[int]*StateDir, error) {
+	all, err := readAllStateDirs(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	if all == nil {
+		return nil, nil
+	}
+	dirs := map[int]*StateDir{}
+	for _, dir := range all {
+		id := dir.state.RelationId
+		if existing, ok := dirs[id]; ok && existing.keyed() {
+			continue
+		}
+		dirs[id] = dir
+	}
+	return dirs, nil
+}
+
+func readAllStateDirs(dirPath string) (dirs []*StateDir, err error) {
 	defer errors.DeferredAnnotatef(&err, "cannot load relations state from %q", dirPath)
 	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
 		return nil, nil
@@ -205,27 +258,48 @@ func ReadAllStateDirs(dirPath string) (dirs map[int]*StateDir, err error) {
 	if err != nil {
 		return nil, err
 	}
-	dirs = map[int]*StateDir{}
+	dirs = []*StateDir{}
 	for _, fi := range fis {
-		// Entries with integer names must be directories containing StateDir
-		// data; all other names will be ignored.
-		relationId, err := strconv.Atoi(fi.Name())
-		if err != nil {
+		// Entries with integer names, or named for a relation tag, must
+		// be directories containing StateDir data; all other names will
+		// be ignored.
+		if relationId, err := strconv.Atoi(fi.Name()); err == nil {
+			dir, err := ReadStateDir(dirPath, relationId)
+			if err != nil {
+				return nil, err
+			}
+			dirs = append(dirs, dir)
+			continue
+		}
+		tag, err := names.ParseRelationTag(fi.Name())
+		if err != nil || !fi.IsDir() {
 			// This doesn't look like a relation.
 			continue
 		}
-		dir, err := ReadStateDir(dirPath, relationId)
+		dir, err := readKeyedStateDir(filepath.Join(dirPath, fi.Name()), tag)
 		if err != nil {
 			return nil, err
 		}
-		dirs[relationId] = dir
+		if dir != nil {
+			dirs = append(dirs, dir)
+		}
 	}
 	return dirs, nil
 }
 
 // Ensure creates the directory if it does not already exist.
 func (d *StateDir) Ensure() error {
-	return os.MkdirAll(d.path, 0755)
+	return d.withLock(d.ensure)
+}
+
+func (d *StateDir) ensure() error {
+	if err := os.MkdirAll(d.path, 0755); err != nil {
+		return err
+	}
+	if d.keyed() {
+		return writeRelationId(d.path, d.state.RelationId)
+	}
+	return nil
 }
 
 // Exists returns true if the directory for this state exists.
@@ -237,11 +311,18 @@ func (d *StateDir) Exists() bool {
 // Write atomically writes to disk the relation state change in hi.
 // It must be called after the respective hook was executed successfully.
 // Write doesn't validate hi but guarantees that successive writes of
-// the same hi are idempotent.
+// the same hi are idempotent. Any settings pinned for the hook are
+// cleared once the change is written.
 func (d *StateDir) Write(hi hook.Info) (err error) {
 	defer errors.DeferredAnnotatef(&err, "failed to write %q hook info for %q on state directory", hi.Kind, hi.RemoteUnit)
+	return d.withLock(func() error {
+		return d.write(hi)
+	})
+}
+
+func (d *StateDir) write(hi hook.Info) (err error) {
 	if hi.Kind == hooks.RelationBroken {
-		return d.Remove()
+		return d.remove()
 	}
 	name := strings.Replace(hi.RemoteUnit, "/", "-", 1)
 	isApp := false
@@ -260,15 +341,24 @@ func (d *StateDir) Write(hi hook.Info) (err error) {
 		} else {
 			delete(d.state.Members, hi.RemoteUnit)
 		}
-		return nil
+		return d.clearPin()
+	}
+	di := diskInfo{
+		ChangeVersion:  &hi.ChangeVersion,
+		ChangedPending: hi.Kind == hooks.RelationJoined,
+	}
+	if isApp {
+		di.IngressNetworksVersion = hi.IngressNetworksVersion
+		di.EgressNetworksVersion = hi.EgressNetworksVersion
 	}
-	di := diskInfo{&hi.ChangeVersion, hi.Kind == hooks.RelationJoined}
 	if err := utils.WriteYaml(path, &di); err != nil {
 		return err
 	}
 	// If write was successful, update own state.
 	if isApp {
 		d.state.ApplicationMembers[hi.RemoteApplication] = hi.ChangeVersion
+		d.state.IngressNetworksVersion = hi.IngressNetworksVersion
+		d.state.EgressNetworksVersion = hi.EgressNetworksVersion
 	} else {
 		d.state.Members[hi.RemoteUnit] = hi.ChangeVersion
 	}
@@ -277,11 +367,15 @@ func (d *StateDir) Write(hi hook.Info) (err error) {
 	} else {
 		d.state.ChangedPending = ""
 	}
-	return nil
+	return d.clearPin()
 }
 
 // Remove removes the directory if it exists and is empty.
 func (d *StateDir) Remove() error {
+	return d.withLock(d.remove)
+}
+
+func (d *StateDir) remove() error {
 	// Note(jam): 2019-10-22 os.Remove() requires the directory to be empty, but
 	//  we added "foo-app" but we won't call RelationDeparted for "foo" and thus won't
 	//  delete "foo-app". Instead, during relation-broken, we cleanup all related applications.
@@ -291,6 +385,15 @@ func (d *StateDir) Remove() error {
 			return errors.Trace(err)
 		}
 	}
+	if d.keyed() {
+		path := filepath.Join(d.path, relationIdFile)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return errors.Trace(err)
+		}
+	}
+	if err := d.clearPin(); err != nil {
+		return errors.Trace(err)
+	}
 	if err := os.Remove(d.path); err != nil && !os.IsNotExist(err) {
 		return errors.Trace(err)
 	}
@@ -300,8 +403,11 @@ func (d *StateDir) Remove() error {
 	return nil
 }
 
-// diskInfo defines the relation unit data serialization.
+// diskInfo defines the relation unit data serialization. The versions
+// of the relation's networks are only recorded for applications.
 type diskInfo struct {
-	ChangeVersion  *int64 `yaml:"change-version"`
-	ChangedPending bool   `yaml:"changed-pending,omitempty"`
+	ChangeVersion          *int64 `yaml:"change-version"`
+	ChangedPending         bool   `yaml:"changed-pending,omitempty"`
+	IngressNetworksVersion string `yaml:"ingress-networks-version,omitempty"`
+	EgressNetworksVersion  string `yaml:"egress-networks-version,omitempty"`
 }
@@ -13,7 +13,9 @@ import (
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 	"gopkg.in/juju/charm.v6/hooks"
+	"gopkg.in/juju/names.v3"
 
+	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/worker/uniter/hook"
 	"github.com/juju/juju/worker/uniter/relation"
 )
@@ -358,6 +360,211 @@ func (s *StateDirSuite) TestWrite(c *gc.C) {
 	}
 }
 
+func (s *StateDirSuite) TestWriteNetworksVersions(c *gc.C) {
+	basedir := c.MkDir()
+	setUpDir(c, basedir, "123", map[string]string{
+		"foo-1":   "change-version: 0\n",
+		"foo-app": "change-version: 0\n",
+	})
+	dir, err := relation.ReadStateDir(basedir, 123)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// The versions of the networks are only recorded for applications.
+	err = dir.Write(hook.Info{
+		Kind:                   hooks.RelationChanged,
+		RelationId:             123,
+		RemoteUnit:             "foo/1",
+		RemoteApplication:      "foo",
+		ChangeVersion:          1,
+		IngressNetworksVersion: "unit-ingress",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dir.State().IngressNetworksVersion, gc.Equals, "")
+
+	err = dir.Write(hook.Info{
+		Kind:                   hooks.RelationChanged,
+		RelationId:             123,
+		RemoteApplication:      "foo",
+		ChangeVersion:          1,
+		IngressNetworksVersion: "ingress",
+		EgressNetworksVersion:  "egress",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dir.State().IngressNetworksVersion, gc.Equals, "ingress")
+	c.Assert(dir.State().EgressNetworksVersion, gc.Equals, "egress")
+
+	// They are read back with the application.
+	dir, err = relation.ReadStateDir(basedir, 123)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dir.State().IngressNetworksVersion, gc.Equals, "ingress")
+	c.Assert(dir.State().EgressNetworksVersion, gc.Equals, "egress")
+}
+
+func (s *StateDirSuite) TestPinClearedOnWrite(c *gc.C) {
+	basedir := c.MkDir()
+	setUpDir(c, basedir, "123", map[string]string{
+		"foo-1": "change-version: 0\n",
+	})
+	dir, err := relation.ReadStateDir(basedir, 123)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dir.State().Pin, gc.IsNil)
+
+	pin := relation.SettingsPin{Remote: "foo/1", ChangeVersion: 1, Hash: "deadbeef"}
+	err = dir.Pin(pin)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dir.State().Pin, gc.DeepEquals, &pin)
+
+	// The pin is read back with the state, as the hook
+	// may be run again after the agent restarts.
+	dir, err = relation.ReadStateDir(basedir, 123)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dir.State().Pin, gc.DeepEquals, &pin)
+	c.Assert(msi(dir.State().Members), gc.DeepEquals, msi{"foo/1": 0})
+
+	// Committing the hook clears the pin.
+	err = dir.Write(hook.Info{
+		Kind:              hooks.RelationChanged,
+		RelationId:        123,
+		RemoteUnit:        "foo/1",
+		RemoteApplication: "foo",
+		ChangeVersion:     1,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dir.State().Pin, gc.IsNil)
+	_, err = os.Stat(filepath.Join(basedir, "123", "pinned.yaml"))
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+}
+
+func (s *StateDirSuite) TestPinRemovedWithState(c *gc.C) {
+	basedir := c.MkDir()
+	dir, err := relation.ReadStateDir(basedir, 123)
+	c.Assert(err, jc.ErrorIsNil)
+	err = dir.Ensure()
+	c.Assert(err, jc.ErrorIsNil)
+	err = dir.Pin(relation.SettingsPin{Remote: "foo", Hash: "deadbeef"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = dir.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = os.Stat(filepath.Join(basedir, "123"))
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+}
+
+func (s *StateDirSuite) TestSettingsHash(c *gc.C) {
+	hash, err := relation.SettingsHash(params.Settings{"a": "1", "b": "2"})
+	c.Assert(err, jc.ErrorIsNil)
+	same, err := relation.SettingsHash(params.Settings{"b": "2", "a": "1"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(same, gc.Equals, hash)
+
+	other, err := relation.SettingsHash(params.Settings{"a": "1", "b": "3"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(other, gc.Not(gc.Equals), hash)
+
+	empty, err := relation.SettingsHash(nil)
+	c.Assert(err, jc.ErrorIsNil)
+	none, err := relation.SettingsHash(params.Settings{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(empty, gc.Equals, none)
+}
+
+var wordpressTag = names.NewRelationTag("wordpress:db mysql:server")
+
+func (s *StateDirSuite) TestReadKeyedStateDirEnsure(c *gc.C) {
+	basedir := c.MkDir()
+	reldir := filepath.Join(basedir, "relation-wordpress.db#mysql.server")
+
+	dir, err := relation.ReadKeyedStateDir(basedir, wordpressTag, 123)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dir.State().RelationId, gc.Equals, 123)
+	_, err = os.Stat(reldir)
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+
+	err = dir.Ensure()
+	c.Assert(err, jc.ErrorIsNil)
+	data, err := ioutil.ReadFile(filepath.Join(reldir, "relation.yaml"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "relation-id: 123\n")
+
+	err = dir.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = os.Stat(reldir)
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+}
+
+func (s *StateDirSuite) TestReadKeyedStateDirMigratesLegacyDir(c *gc.C) {
+	basedir := c.MkDir()
+	setUpDir(c, basedir, "123", map[string]string{
+		"mysql-0": "change-version: 7\n",
+	})
+
+	dir, err := relation.ReadKeyedStateDir(basedir, wordpressTag, 123)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(msi(dir.State().Members), gc.DeepEquals, msi{"mysql/0": 7})
+
+	_, err = os.Stat(filepath.Join(basedir, "123"))
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+	reldir := filepath.Join(basedir, "relation-wordpress.db#mysql.server")
+	_, err = os.Stat(filepath.Join(reldir, "mysql-0"))
+	c.Assert(err, jc.ErrorIsNil)
+
+	dirs, err := relation.ReadAllStateDirs(basedir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dirs, gc.HasLen, 1)
+	c.Assert(msi(dirs[123].State().Members), gc.DeepEquals, msi{"mysql/0": 7})
+}
+
+func (s *StateDirSuite) TestReadKeyedStateDirDiscardsUnconfirmedLegacyDir(c *gc.C) {
+	basedir := c.MkDir()
+	setUpDir(c, basedir, "123", map[string]string{
+		"mysql-0": "change-version: 7\n",
+	})
+
+	// Relation ID 123 now identifies another relation, whose
+	// endpoints do not include the members of the legacy state.
+	otherTag := names.NewRelationTag("wordpress:cache memcached:cache")
+	dir, err := relation.ReadKeyedStateDir(basedir, otherTag, 123)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(msi(dir.State().Members), gc.DeepEquals, msi{})
+	c.Assert(dir.Exists(), jc.IsFalse)
+
+	_, err = os.Stat(filepath.Join(basedir, "123"))
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+	dirs, err := relation.ReadAllStateDirs(basedir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(dirs, gc.HasLen, 0)
+}
+
+func (s *StateDirSuite) TestReadKeyedStateDirDiscardsStaleState(c *gc.C) {
+	basedir := c.MkDir()
+	setUpDir(c, basedir, "relation-wordpress.db#mysql.server", map[string]string{
+		"relation.yaml": "relation-id: 5\n",
+		"mysql-0":       "change-version: 7\n",
+	})
+
+	// The same relation key with a new ID is a different relation,
+	// so the recorded change versions must not be replayed.
+	dir, err := relation.ReadKeyedStateDir(basedir, wordpressTag, 123)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(msi(dir.State().Members), gc.DeepEquals, msi{})
+	c.Assert(dir.Exists(), jc.IsFalse)
+}
+
+func (s *StateDirSuite) TestReadKeyedStateDirIgnoresReusedLegacyId(c *gc.C) {
+	basedir := c.MkDir()
+	setUpDir(c, basedir, "relation-wordpress.db#mysql.server", map[string]string{
+		"relation.yaml": "relation-id: 123\n",
+		"mysql-0":       "change-version: 7\n",
+	})
+
+	// Relation ID 123 now identifies another relation,
+	// which must not see the state of the first.
+	otherTag := names.NewRelationTag("wordpress:cache memcached:cache")
+	dir, err := relation.ReadKeyedStateDir(basedir, otherTag, 123)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(msi(dir.State().Members), gc.DeepEquals, msi{})
+}
+
 func (s *StateDirSuite) TestRemove(c *gc.C) {
 	basedir := c.MkDir()
 	dir, err := relation.ReadStateDir(basedir, 1)
@@ -429,6 +636,13 @@ func (s *ReadAllStateDirsSuite) TestReadAllStateDirs(c *gc.C) {
 		"baz-0": "change-version: 3\n",
 		"baz-1": "change-version: 4\n",
 	})
+	setUpDir(c, relsdir, "relation-wordpress.db#mysql.server", map[string]string{
+		"relation.yaml": "relation-id: 11\n",
+		"mysql-0":       "change-version: 5\n",
+	})
+	setUpDir(c, relsdir, "relation-riak.ring", map[string]string{
+		"riak-1": "change-version: 6\n",
+	})
 
 	dirs, err := relation.ReadAllStateDirs(relsdir)
 	c.Assert(err, jc.ErrorIsNil)
@@ -439,7 +653,8 @@ func (s *ReadAllStateDirsSuite) TestReadAllStateDirs(c *gc.C) {
 	assertState(c, dirs[456], relsdir, 456, msi{"bar/0": 3, "bar/1": 4}, msi{}, "", false)
 	assertState(c, dirs[789], relsdir, 789, msi{}, msi{}, "", false)
 	assertState(c, dirs[10], relsdir, 10, msi{}, msi{"baz": 2}, "", false)
-	c.Assert(dirs, gc.HasLen, 4)
+	c.Assert(msi(dirs[11].State().Members), gc.DeepEquals, msi{"mysql/0": 5})
+	c.Assert(dirs, gc.HasLen, 5)
 }
 
 func setUpDir(c *gc.C, basedir, name string, contents map[string]string) string {
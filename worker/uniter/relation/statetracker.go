@@ -4,6 +4,9 @@
 package relation
 
 import (
+	"strconv"
+	"sync"
+
 	"github.com/juju/errors"
 	"github.com/juju/juju/api/uniter"
 	"github.com/juju/juju/apiserver/params"
@@ -70,6 +73,41 @@ type RelationStateTracker interface {
 	// Name returns the name of the relation with the supplied id, or an error
 	// if the relation is unknown.
 	Name(id int) (string, error)
+
+	// PrefetchSettings starts reading the remote settings that will be
+	// seen by the supplied relation-changed hook, so that the read
+	// overlaps with preparing to run the hook. Other hooks are ignored.
+	PrefetchSettings(hook.Info)
+
+	// PrefetchedSettings returns the remote settings read for the
+	// supplied hook by PrefetchSettings, waiting for the read to
+	// complete, and whether they could be read.
+	PrefetchedSettings(hook.Info) (params.Settings, bool)
+
+	// InvalidateDependencies records that the supplied relation hook
+	// reads the data of the relations on the supplied endpoints, so
+	// that their cached settings are refreshed for the hook.
+	InvalidateDependencies(hook.Info, []string)
+
+	// StaleRelations returns the ids of the relations whose data the
+	// supplied hook reads, as recorded by InvalidateDependencies.
+	StaleRelations(hook.Info) []int
+
+	// PinSettings records in the local relation state the snapshot of
+	// remote settings that the supplied relation hook sees, until the
+	// hook is committed.
+	PinSettings(hook.Info, params.Settings) error
+
+	// Report returns a checkpoint of the tracked relations, combining the
+	// in-memory state with the state persisted in the relations directory,
+	// for use when debugging relation hook problems.
+	Report() map[string]interface{}
+
+	// RenameEndpoints applies the endpoint renames declared by the
+	// deployed charm to the tracked relations, so that their hooks are
+	// run under the endpoint names of the charm. It is called when the
+	// charm is upgraded.
+	RenameEndpoints() error
 }
 
 // LeadershipContextFunc is a function that returns a leadership context.
@@ -101,6 +139,24 @@ type relationStateTracker struct {
 	remoteAppName   map[int]string
 	relationCreated map[int]bool
 	isPeerRelation  map[int]bool
+
+	// endpointRenames maps the old names of any endpoints renamed
+	// by the deployed charm to their new names.
+	endpointRenames map[string]string
+
+	// mu guards the relation maps and state dirs against concurrent
+	// access by Report. Only writes need to hold the lock, since all
+	// other access happens on the uniter's goroutine.
+	mu sync.Mutex
+
+	// prefetch holds the settings being read ahead of the
+	// next relation-changed hook, guarded by prefetchMu.
+	prefetchMu sync.Mutex
+	prefetch   *settingsPrefetch
+
+	// stale holds the relations whose data is read by the
+	// next relation hook, as declared by the charm.
+	stale *staleRelations
 }
 
 // NewRelationStateTracker returns a new RelationStateTracker instance.
@@ -167,20 +223,40 @@ func (r *relationStateTracker) loadInitialState() error {
 		// Since we are already in scope, the relation-created hook
 		// must have fired in the past so we can mark the relation as
 		// already created.
+		r.mu.Lock()
 		r.relationCreated[relation.Id()] = true
+		r.mu.Unlock()
 	}
 
-	knownDirs, err := ReadAllStateDirs(r.relationsDir)
+	if r.endpointRenames, err = ReadEndpointRenames(r.charmDir); err != nil {
+		return errors.Trace(err)
+	}
+	knownDirs, err := readAllStateDirs(r.relationsDir)
 	if err != nil {
 		return errors.Trace(err)
 	}
 
-	for id, dir := range knownDirs {
-		if rel, ok := activeRelations[id]; ok {
-			if err := r.joinRelation(rel, dir); err != nil {
+	appName := r.unit.ApplicationName()
+	for _, dir := range knownDirs {
+		id := dir.state.RelationId
+		rel, ok := activeRelations[id]
+		switch {
+		case ok && (!dir.keyed() || dir.tag == rel.Tag()):
+			// The state is loaded below, by relation tag.
+		case ok && renameRelationTag(dir.tag, appName, r.endpointRenames) == rel.Tag():
+			// The relation's key changed when our endpoint was
+			// renamed, so the state is moved to the new key.
+			if err := moveKeyedStateDir(r.relationsDir, dir.tag, rel.Tag()); err != nil {
+				return errors.Trace(err)
+			}
+		case ok:
+			// The relation ID has been reused for a different
+			// relation, so the state is stale.
+			logger.Infof("discarding stale state for relation %q with reused id %d", dir.tag.Id(), id)
+			if err := dir.discard(); err != nil {
 				return errors.Trace(err)
 			}
-		} else if !relationSuspended[id] {
+		case !relationSuspended[id]:
 			// Relations which are suspended may become active
 			// again so we keep the local state, otherwise we
 			// remove it.
@@ -190,12 +266,12 @@ func (r *relationStateTracker) loadInitialState() error {
 		}
 	}
 
+	// Local state is keyed by relation tag, so that relation IDs reused
+	// after a model migration cannot replay stale change versions. State
+	// recorded by older agents under the relation ID is moved into place.
 	for _, id := range orderedIds {
 		rel := activeRelations[id]
-		if _, ok := knownDirs[id]; ok {
-			continue
-		}
-		dir, err := ReadStateDir(r.relationsDir, id)
+		dir, err := ReadKeyedStateDir(r.relationsDir, rel.Tag(), id)
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -217,6 +293,7 @@ func (r *relationStateTracker) joinRelation(rel *uniter.Relation, dir *StateDir)
 		return errors.Trace(err)
 	}
 	relationer := NewRelationer(ru, dir)
+	relationer.renameEndpoint(r.endpointRenames)
 	unitWatcher, err := r.unit.Watch()
 	if err != nil {
 		return errors.Trace(err)
@@ -260,7 +337,9 @@ func (r *relationStateTracker) joinRelation(rel *uniter.Relation, dir *StateDir)
 					return errors.Trace(err)
 				}
 			}
+			r.mu.Lock()
 			r.relationers[rel.Id()] = relationer
+			r.mu.Unlock()
 			return nil
 		}
 	}
@@ -306,17 +385,19 @@ func (r *relationStateTracker) SynchronizeScopes(remote remotestate.Snapshot) er
 		ep, err := rel.Endpoint()
 		if err != nil {
 			return errors.Trace(err)
-		} else if !ep.ImplementedBy(charmSpec) {
+		} else if !r.implementedBy(*ep, charmSpec) {
 			logger.Warningf("skipping relation with unknown endpoint %q", ep.Name)
 			continue
 		}
 
 		// Keep track of peer relations
 		if ep.Role == charm.RolePeer {
+			r.mu.Lock()
 			r.isPeerRelation[id] = true
+			r.mu.Unlock()
 		}
 
-		dir, err := ReadStateDir(r.relationsDir, id)
+		dir, err := ReadKeyedStateDir(r.relationsDir, rel.Tag(), id)
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -330,7 +411,9 @@ func (r *relationStateTracker) SynchronizeScopes(remote remotestate.Snapshot) er
 		}
 
 		// Keep track of the remote application
+		r.mu.Lock()
 		r.remoteAppName[id] = rel.OtherApplication()
+		r.mu.Unlock()
 	}
 
 	if !r.subordinate {
@@ -355,10 +438,26 @@ func (r *relationStateTracker) SynchronizeScopes(remote remotestate.Snapshot) er
 	return r.unit.Destroy()
 }
 
+// implementedBy returns whether the endpoint is implemented by the charm,
+// either under its own name or under the name the charm renamed it to.
+func (r *relationStateTracker) implementedBy(ep uniter.Endpoint, ch charm.Charm) bool {
+	if ep.ImplementedBy(ch) {
+		return true
+	}
+	newName, ok := r.endpointRenames[ep.Name]
+	if !ok {
+		return false
+	}
+	ep.Name = newName
+	return ep.ImplementedBy(ch)
+}
+
 // setDying notifies the relationer identified by the supplied id that the
 // only hook executions to be requested should be those necessary to cleanly
 // exit the relation.
 func (r *relationStateTracker) setDying(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	relationer, found := r.relationers[id]
 	if !found {
 		return nil
@@ -441,6 +540,8 @@ func (r *relationStateTracker) PrepareHook(hookInfo hook.Info) (string, error) {
 
 // CommitHook is part of the RelationStateTracker interface.
 func (r *relationStateTracker) CommitHook(hookInfo hook.Info) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	defer func() {
 		if err != nil {
 			return
@@ -479,5 +580,97 @@ func (r *relationStateTracker) Name(id int) (string, error) {
 	if !found {
 		return "", errors.Errorf("unknown relation: %d", id)
 	}
-	return relationer.ru.Endpoint().Name, nil
+	return relationer.EndpointName(), nil
+}
+
+// RenameEndpoints is part of the RelationStateTracker interface.
+func (r *relationStateTracker) RenameEndpoints() error {
+	renames, err := ReadEndpointRenames(r.charmDir)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.endpointRenames = renames
+	for id, relationer := range r.relationers {
+		relationer.renameEndpoint(renames)
+		if name := relationer.EndpointName(); name != relationer.ru.Endpoint().Name {
+			logger.Infof("running hooks for relation %d under renamed endpoint %q", id, name)
+		}
+	}
+	return nil
+}
+
+// Report is part of the RelationStateTracker interface.
+func (r *relationStateTracker) Report() map[string]interface{} {
+	report := map[string]interface{}{
+		"relations": r.relationsReport(),
+	}
+
+	// The on-disk state is read afresh so that any divergence from
+	// the in-memory state can be seen. It is read without holding
+	// the lock, so that hooks are not held up by the disk, and only
+	// read: directories left by older agents are not migrated here.
+	dirs, err := ReadAllStateDirs(r.relationsDir)
+	if err != nil {
+		report["on-disk-error"] = err.Error()
+		return report
+	}
+	onDisk := make(map[string]interface{})
+	for id, dir := range dirs {
+		onDisk[strconv.Itoa(id)] = stateReport(dir.State())
+	}
+	report["on-disk"] = onDisk
+	return report
+}
+
+// relationsReport returns a snapshot of the in-memory state
+// of each relation, taken while holding the lock.
+func (r *relationStateTracker) relationsReport() map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	relations := make(map[string]interface{})
+	for id, relationer := range r.relationers {
+		ep := relationer.ru.Endpoint()
+		relations[strconv.Itoa(id)] = map[string]interface{}{
+			"endpoint":           ep.Name,
+			"scope":              string(ep.Scope),
+			"remote-application": r.remoteAppName[id],
+			"dying":              relationer.dying,
+			"implicit":           ep.IsImplicit(),
+			"peer":               r.isPeerRelation[id],
+			"relation-created":   r.relationCreated[id],
+			"state":              stateReport(relationer.dir.State()),
+		}
+	}
+	return relations
+}
+
+// stateReport returns the change versions and pending hook
+// information recorded in the input relation state.
+func stateReport(st *State) map[string]interface{} {
+	report := map[string]interface{}{
+		"members":             versionsReport(st.Members),
+		"application-members": versionsReport(st.ApplicationMembers),
+	}
+	if st.ChangedPending != "" {
+		report["changed-pending"] = st.ChangedPending
+	}
+	if st.Pin != nil {
+		report["pinned"] = map[string]interface{}{
+			"remote":         st.Pin.Remote,
+			"change-version": st.Pin.ChangeVersion,
+			"settings-hash":  st.Pin.Hash,
+		}
+	}
+	return report
+}
+
+func versionsReport(versions map[string]int64) map[string]interface{} {
+	report := make(map[string]interface{}, len(versions))
+	for name, version := range versions {
+		report[name] = version
+	}
+	return report
 }
@@ -0,0 +1,179 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package relation
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/juju/clock"
+	"github.com/juju/errors"
+	"github.com/juju/utils"
+	"gopkg.in/juju/charm.v6"
+
+	"github.com/juju/juju/worker/uniter/remotestate"
+)
+
+// SuspensionFile is the name of the file, in the relations state
+// directory, in which the suspension state of each relation that the
+// resolver acts on is recorded while suspension changes are damped.
+const SuspensionFile = "suspension.yaml"
+
+// suspensionWindowOption is the charm config option with which a charm
+// declares, and an operator overrides, how long the suspension of an
+// endpoint's relations must remain changed before the unit acts on the
+// change. The endpoint name replaces %s.
+const suspensionWindowOption = "%s-relation-suspension-window"
+
+// SuspensionWindowFromSettings returns the suspension stabilization
+// window configured for the endpoint in the charm settings, or zero if
+// there is none, in which case suspension changes are acted on at once.
+func SuspensionWindowFromSettings(settings charm.Settings, endpoint string) (time.Duration, error) {
+	window, err := durationSetting(settings, fmt.Sprintf(suspensionWindowOption, endpoint))
+	return window, errors.Trace(err)
+}
+
+// WithSuspensionDamping returns an option that causes the resolver to
+// act on the suspension of a relation, or its resumption, only once it
+// has remained suspended, or resumed, for the relation's stabilization
+// window. Offers that are suspended and resumed repeatedly, as is common
+// when cross-model relations flap, would otherwise churn the unit
+// through relation-departed, -broken and -joined hooks each time.
+//
+// While a change is pending the relation is treated as it was before
+// the change, and wake is called with the time remaining until the first
+// of the pending changes takes effect, so that the resolver is run again
+// then.
+func WithSuspensionDamping(damper *SuspensionDamper, wake func(time.Duration)) ResolverOption {
+	return func(r *relationsResolver) {
+		r.suspensionDamper = damper
+		r.wakeSuspension = wake
+	}
+}
+
+// dampSuspensions replaces the suspension of each of the relations in
+// the remote state with that which the resolver acts on.
+func (r *relationsResolver) dampSuspensions(remoteState remotestate.Snapshot) error {
+	if r.suspensionDamper == nil {
+		return nil
+	}
+	delay, err := r.suspensionDamper.Damp(remoteState.Relations)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if delay > 0 && r.wakeSuspension != nil {
+		r.wakeSuspension(delay)
+	}
+	return nil
+}
+
+// suspensionState records the suspension of a relation acted on by the
+// resolver, and when the remote suspension first differed from it.
+type suspensionState struct {
+	Suspended bool      `yaml:"suspended"`
+	Changed   time.Time `yaml:"changed,omitempty"`
+}
+
+// suspensionFile defines the serialization of the suspension file.
+type suspensionFile struct {
+	Relations map[int]suspensionState `yaml:"relations,omitempty"`
+}
+
+// SuspensionDamper holds back changes to the suspension of relations
+// until they have been stable for a window. The state it holds is written
+// to disk whenever it changes, so that restarting the agent does not
+// defeat the damping.
+type SuspensionDamper struct {
+	path   string
+	clock  clock.Clock
+	window func(relationId int) time.Duration
+
+	mu        sync.Mutex
+	relations map[int]suspensionState
+}
+
+// NewSuspensionDamper returns a SuspensionDamper that records its state
+// in the file at path, reading any state recorded there previously. The
+// window function returns the stabilization window for a relation; a
+// zero window causes changes to the relation's suspension to be acted on
+// at once.
+func NewSuspensionDamper(path string, clock clock.Clock, window func(relationId int) time.Duration) (*SuspensionDamper, error) {
+	var file suspensionFile
+	if err := utils.ReadYaml(path, &file); err != nil && !os.IsNotExist(errors.Cause(err)) {
+		return nil, errors.Annotate(err, "reading relation suspension state")
+	}
+	if file.Relations == nil {
+		file.Relations = make(map[int]suspensionState)
+	}
+	return &SuspensionDamper{
+		path:      path,
+		clock:     clock,
+		window:    window,
+		relations: file.Relations,
+	}, nil
+}
+
+// Damp replaces the suspension of each of the relations with that which
+// is to be acted on, and returns the time remaining until the first of
+// the changes held back takes effect, or zero if there are none. Relations
+// seen for the first time are acted on as they are, and those that are
+// no longer present are forgotten.
+func (d *SuspensionDamper) Damp(relations map[int]remotestate.RelationSnapshot) (time.Duration, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.clock.Now()
+	var (
+		changed bool
+		delay   time.Duration
+	)
+	for id := range d.relations {
+		if _, ok := relations[id]; !ok {
+			delete(d.relations, id)
+			changed = true
+		}
+	}
+	for id, snapshot := range relations {
+		state, ok := d.relations[id]
+		if !ok {
+			d.relations[id] = suspensionState{Suspended: snapshot.Suspended}
+			changed = true
+			continue
+		}
+		before := state
+		switch {
+		case snapshot.Suspended == state.Suspended:
+			state.Changed = time.Time{}
+		case state.Changed.IsZero():
+			state.Changed = now
+			fallthrough
+		default:
+			remaining := d.window(id) - now.Sub(state.Changed)
+			if remaining <= 0 {
+				logger.Debugf("relation %d suspension changed to %v", id, snapshot.Suspended)
+				state = suspensionState{Suspended: snapshot.Suspended}
+				break
+			}
+			logger.Debugf("relation %d suspension change to %v held back for %v", id, snapshot.Suspended, remaining)
+			if delay == 0 || remaining < delay {
+				delay = remaining
+			}
+		}
+		if state != before {
+			d.relations[id] = state
+			changed = true
+		}
+		snapshot.Suspended = state.Suspended
+		relations[id] = snapshot
+	}
+	if !changed {
+		return delay, nil
+	}
+	if err := utils.WriteYaml(d.path, &suspensionFile{Relations: d.relations}); err != nil {
+		return 0, errors.Annotate(err, "writing relation suspension state")
+	}
+	return delay, nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package relation_test
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/juju/clock/testclock"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/charm.v6"
+
+	"github.com/juju/juju/core/life"
+	"github.com/juju/juju/worker/uniter/relation"
+	"github.com/juju/juju/worker/uniter/remotestate"
+)
+
+type suspensionSuite struct {
+	clock *testclock.Clock
+	path  string
+}
+
+var _ = gc.Suite(&suspensionSuite{})
+
+func (s *suspensionSuite) SetUpTest(c *gc.C) {
+	s.clock = testclock.NewClock(time.Now())
+	s.path = filepath.Join(c.MkDir(), relation.SuspensionFile)
+}
+
+func (s *suspensionSuite) newDamper(c *gc.C, window time.Duration) *relation.SuspensionDamper {
+	damper, err := relation.NewSuspensionDamper(s.path, s.clock, func(int) time.Duration {
+		return window
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	return damper
+}
+
+// damp returns the suspension of relation 1 acted on when it is remotely
+// suspended as input, and the delay until a pending change takes effect.
+func damp(c *gc.C, damper *relation.SuspensionDamper, suspended bool) (bool, time.Duration) {
+	relations := map[int]remotestate.RelationSnapshot{
+		1: {Life: life.Alive, Suspended: suspended},
+	}
+	delay, err := damper.Damp(relations)
+	c.Assert(err, jc.ErrorIsNil)
+	return relations[1].Suspended, delay
+}
+
+func (s *suspensionSuite) TestWindowFromSettings(c *gc.C) {
+	window, err := relation.SuspensionWindowFromSettings(charm.Settings{
+		"db-relation-suspension-window": "5m",
+	}, "db")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(window, gc.Equals, 5*time.Minute)
+
+	window, err = relation.SuspensionWindowFromSettings(charm.Settings{}, "db")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(window, gc.Equals, time.Duration(0))
+
+	_, err = relation.SuspensionWindowFromSettings(charm.Settings{
+		"db-relation-suspension-window": "soon",
+	}, "db")
+	c.Assert(err, gc.ErrorMatches, `db-relation-suspension-window value "soon" not valid`)
+}
+
+func (s *suspensionSuite) TestNoWindow(c *gc.C) {
+	damper := s.newDamper(c, 0)
+	for _, suspended := range []bool{false, true, false} {
+		acted, delay := damp(c, damper, suspended)
+		c.Check(acted, gc.Equals, suspended)
+		c.Check(delay, gc.Equals, time.Duration(0))
+	}
+}
+
+func (s *suspensionSuite) TestChangeHeldBack(c *gc.C) {
+	damper := s.newDamper(c, time.Minute)
+	suspended, delay := damp(c, damper, false)
+	c.Assert(suspended, jc.IsFalse)
+	c.Assert(delay, gc.Equals, time.Duration(0))
+
+	suspended, delay = damp(c, damper, true)
+	c.Assert(suspended, jc.IsFalse)
+	c.Assert(delay, gc.Equals, time.Minute)
+
+	s.clock.Advance(40 * time.Second)
+	suspended, delay = damp(c, damper, true)
+	c.Assert(suspended, jc.IsFalse)
+	c.Assert(delay, gc.Equals, 20*time.Second)
+
+	s.clock.Advance(20 * time.Second)
+	suspended, delay = damp(c, damper, true)
+	c.Assert(suspended, jc.IsTrue)
+	c.Assert(delay, gc.Equals, time.Duration(0))
+}
+
+func (s *suspensionSuite) TestFlappingIgnored(c *gc.C) {
+	damper := s.newDamper(c, time.Minute)
+	damp(c, damper, false)
+	for i := 0; i < 5; i++ {
+		suspended, _ := damp(c, damper, true)
+		c.Assert(suspended, jc.IsFalse)
+		s.clock.Advance(30 * time.Second)
+		suspended, delay := damp(c, damper, false)
+		c.Assert(suspended, jc.IsFalse)
+		c.Assert(delay, gc.Equals, time.Duration(0))
+		s.clock.Advance(30 * time.Second)
+	}
+
+	// The window starts again with each change.
+	_, delay := damp(c, damper, true)
+	c.Assert(delay, gc.Equals, time.Minute)
+}
+
+func (s *suspensionSuite) TestStatePersisted(c *gc.C) {
+	damper := s.newDamper(c, time.Minute)
+	damp(c, damper, false)
+	damp(c, damper, true)
+	s.clock.Advance(40 * time.Second)
+
+	// A restarted agent continues to hold back the change
+	// for the remainder of the window.
+	damper = s.newDamper(c, time.Minute)
+	suspended, delay := damp(c, damper, true)
+	c.Assert(suspended, jc.IsFalse)
+	c.Assert(delay, gc.Equals, 20*time.Second)
+
+	s.clock.Advance(20 * time.Second)
+	suspended, _ = damp(c, damper, true)
+	c.Assert(suspended, jc.IsTrue)
+
+	damper = s.newDamper(c, time.Minute)
+	suspended, delay = damp(c, damper, false)
+	c.Assert(suspended, jc.IsTrue)
+	c.Assert(delay, gc.Equals, time.Minute)
+}
+
+func (s *suspensionSuite) TestRemovedRelationsForgotten(c *gc.C) {
+	damper := s.newDamper(c, time.Minute)
+	damp(c, damper, true)
+
+	_, err := damper.Damp(map[int]remotestate.RelationSnapshot{})
+	c.Assert(err, jc.ErrorIsNil)
+
+	// A relation with the same id is acted on as it is.
+	suspended, delay := damp(c, damper, false)
+	c.Assert(suspended, jc.IsFalse)
+	c.Assert(delay, gc.Equals, time.Duration(0))
+}
@@ -0,0 +1,167 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remotestate
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/names.v3"
+
+	"github.com/juju/juju/core/watcher"
+)
+
+// RelationInjection describes a synthetic change to the remote units
+// and application of a relation, as it is seen by a unit. Injecting
+// changes lets a test harness exercise a charm's relation hooks
+// without a real counterpart application.
+type RelationInjection struct {
+	// RelationId identifies the relation to change. The unit
+	// must already be a member of the relation.
+	RelationId int `json:"relation-id"`
+
+	// Changed holds the settings version of each remote unit that
+	// has joined the relation, or whose settings have changed.
+	Changed map[string]int64 `json:"changed,omitempty"`
+
+	// AppChanged holds the settings version of each remote
+	// application whose settings have changed.
+	AppChanged map[string]int64 `json:"app-changed,omitempty"`
+
+	// Departed holds the names of the remote units
+	// that have departed the relation.
+	Departed []string `json:"departed,omitempty"`
+}
+
+// Validate returns an error if the injection is not valid.
+func (inj RelationInjection) Validate() error {
+	for unit := range inj.Changed {
+		if !names.IsValidUnit(unit) {
+			return errors.NotValidf("unit name %q", unit)
+		}
+	}
+	for app := range inj.AppChanged {
+		if !names.IsValidApplication(app) {
+			return errors.NotValidf("application name %q", app)
+		}
+	}
+	for _, unit := range inj.Departed {
+		if !names.IsValidUnit(unit) {
+			return errors.NotValidf("unit name %q", unit)
+		}
+	}
+	return nil
+}
+
+// injectionRequest passes an injection to a remote state watcher,
+// which replies with the result of applying it.
+type injectionRequest struct {
+	injection RelationInjection
+	result    chan error
+}
+
+// injectionTarget is a remote state watcher registered
+// with an Injector.
+type injectionTarget struct {
+	requests chan injectionRequest
+	abort    <-chan struct{}
+}
+
+// Injector passes synthetic relation changes to the remote state
+// watchers of the units running in an agent. It is only for testing
+// charms, and is only created when the inject-relation-state feature
+// flag is set.
+type Injector struct {
+	mu      sync.Mutex
+	targets map[string]injectionTarget
+}
+
+// NewInjector returns a new Injector with no units registered.
+func NewInjector() *Injector {
+	return &Injector{
+		targets: make(map[string]injectionTarget),
+	}
+}
+
+// Inject applies the change to the remote state of the named unit,
+// waking its uniter to run the relation hooks the change causes.
+func (i *Injector) Inject(unitName string, injection RelationInjection) error {
+	if err := injection.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+	i.mu.Lock()
+	target, ok := i.targets[unitName]
+	i.mu.Unlock()
+	if !ok {
+		return errors.NotFoundf("unit %q", unitName)
+	}
+
+	req := injectionRequest{
+		injection: injection,
+		result:    make(chan error, 1),
+	}
+	select {
+	case target.requests <- req:
+	case <-target.abort:
+		return errors.Errorf("unit %q remote state watcher stopped", unitName)
+	}
+	select {
+	case err := <-req.result:
+		return errors.Trace(err)
+	case <-target.abort:
+		return errors.Errorf("unit %q remote state watcher stopped", unitName)
+	}
+}
+
+// InjectJSON applies the JSON encoded RelationInjection to the remote
+// state of the named unit. It is used by the agent's introspection
+// socket.
+func (i *Injector) InjectJSON(unitName string, data []byte) error {
+	var injection RelationInjection
+	if err := json.Unmarshal(data, &injection); err != nil {
+		return errors.NewNotValid(err, "relation injection")
+	}
+	return errors.Trace(i.Inject(unitName, injection))
+}
+
+// register returns the channel on which injections for the named unit
+// are delivered, until the returned function is called or abort is closed.
+func (i *Injector) register(unitName string, abort <-chan struct{}) (<-chan injectionRequest, func()) {
+	requests := make(chan injectionRequest)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.targets[unitName] = injectionTarget{requests: requests, abort: abort}
+	return requests, func() {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		if i.targets[unitName].requests == requests {
+			delete(i.targets, unitName)
+		}
+	}
+}
+
+// relationInjected applies an injected change to the snapshot
+// of a relation the unit is a member of.
+func (w *RemoteStateWatcher) relationInjected(injection RelationInjection) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	snapshot, ok := w.current.Relations[injection.RelationId]
+	if !ok {
+		return errors.NotFoundf("relation %d", injection.RelationId)
+	}
+	logger.Warningf("injecting synthetic change to relation %d: %+v", injection.RelationId, injection)
+	for unit, version := range injection.Changed {
+		// Synthetic changes were never observed by the controller.
+		snapshot.setMember(unit, watcher.UnitSettings{Version: version})
+	}
+	for app, version := range injection.AppChanged {
+		snapshot.ApplicationMembers[app] = version
+	}
+	for _, unit := range injection.Departed {
+		delete(snapshot.Members, unit)
+		delete(snapshot.MembersObserved, unit)
+	}
+	return nil
+}
@@ -105,6 +105,8 @@ type mockState struct {
 	relations                   map[names.RelationTag]*mockRelation
 	storageAttachment           map[params.StorageAttachmentId]params.StorageAttachment
 	relationUnitsWatchers       map[names.RelationTag]*mockRelationUnitsWatcher
+	relationIngressWatchers     map[names.RelationTag]*mockStringsWatcher
+	relationEgressWatchers      map[names.RelationTag]*mockStringsWatcher
 	relationAppWatchers         map[names.RelationTag]map[string]*mockNotifyWatcher
 	storageAttachmentWatchers   map[names.StorageTag]*mockNotifyWatcher
 	updateStatusInterval        time.Duration
@@ -175,6 +177,31 @@ func (st *mockState) WatchRelationUnits(
 	return watcher, nil
 }
 
+func (st *mockState) WatchRelationIngressNetworks(
+	relationTag names.RelationTag, unitTag names.UnitTag,
+) (watcher.StringsWatcher, error) {
+	return st.watchRelationNetworks(st.relationIngressWatchers, relationTag, unitTag)
+}
+
+func (st *mockState) WatchRelationEgressNetworks(
+	relationTag names.RelationTag, unitTag names.UnitTag,
+) (watcher.StringsWatcher, error) {
+	return st.watchRelationNetworks(st.relationEgressWatchers, relationTag, unitTag)
+}
+
+func (st *mockState) watchRelationNetworks(
+	watchers map[names.RelationTag]*mockStringsWatcher, relationTag names.RelationTag, unitTag names.UnitTag,
+) (watcher.StringsWatcher, error) {
+	if unitTag != st.unit.tag {
+		return nil, &params.Error{Code: params.CodeNotFound}
+	}
+	watcher, ok := watchers[relationTag]
+	if !ok {
+		return nil, &params.Error{Code: params.CodeNotFound}
+	}
+	return watcher, nil
+}
+
 func (st *mockState) WatchStorageAttachment(
 	storageTag names.StorageTag, unitTag names.UnitTag,
 ) (watcher.NotifyWatcher, error) {
@@ -0,0 +1,106 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remotestate
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/worker.v1"
+	"gopkg.in/juju/worker.v1/catacomb"
+
+	"github.com/juju/juju/core/watcher"
+)
+
+type wrappedRelationNetworksWatcher struct {
+	catacomb   catacomb.Catacomb
+	relationId int
+	ingress    watcher.StringsChannel
+	egress     watcher.StringsChannel
+	out        chan<- relationNetworksChange
+}
+
+type relationNetworksChange struct {
+	relationId int
+	ingress    bool
+	cidrs      []string
+}
+
+// wrapRelationNetworksWatcher creates a new worker that takes values from
+// the Changes chans of the supplied ingress and egress networks watchers,
+// annotates them with the supplied relation id and their direction, and
+// delivers them on the supplied out chan.
+//
+// The caller releases responsibility for stopping the supplied watchers and
+// waiting for errors, *whether or not this method succeeds*.
+func wrapRelationNetworksWatcher(
+	relationId int,
+	ingress, egress watcher.StringsWatcher,
+	out chan<- relationNetworksChange,
+) (*wrappedRelationNetworksWatcher, error) {
+	rnw := &wrappedRelationNetworksWatcher{
+		relationId: relationId,
+		ingress:    ingress.Changes(),
+		egress:     egress.Changes(),
+		out:        out,
+	}
+	err := catacomb.Invoke(catacomb.Plan{
+		Site: &rnw.catacomb,
+		Work: rnw.loop,
+		Init: []worker.Worker{ingress, egress},
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return rnw, nil
+}
+
+// Kill is part of the worker.Worker interface.
+func (w *wrappedRelationNetworksWatcher) Kill() {
+	w.catacomb.Kill(nil)
+}
+
+// Wait is part of the worker.Worker interface.
+func (w *wrappedRelationNetworksWatcher) Wait() error {
+	return w.catacomb.Wait()
+}
+
+func (w *wrappedRelationNetworksWatcher) loop() error {
+	for {
+		var change relationNetworksChange
+		select {
+		case <-w.catacomb.Dying():
+			return w.catacomb.ErrDying()
+		case cidrs, ok := <-w.ingress:
+			if !ok {
+				return errors.New("ingress watcher closed channel")
+			}
+			change = relationNetworksChange{w.relationId, true, cidrs}
+		case cidrs, ok := <-w.egress:
+			if !ok {
+				return errors.New("egress watcher closed channel")
+			}
+			change = relationNetworksChange{w.relationId, false, cidrs}
+		}
+		select {
+		case <-w.catacomb.Dying():
+			return w.catacomb.ErrDying()
+		case w.out <- change:
+		}
+	}
+}
+
+// networksVersion returns a version identifying the supplied CIDRs,
+// regardless of their order, or an empty string if there are none.
+func networksVersion(cidrs []string) string {
+	if len(cidrs) == 0 {
+		return ""
+	}
+	sorted := append([]string(nil), cidrs...)
+	sort.Strings(sorted)
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(sorted, ","))))
+}
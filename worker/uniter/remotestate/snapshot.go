@@ -4,12 +4,15 @@
 package remotestate
 
 import (
+	"time"
+
 	"gopkg.in/juju/charm.v6"
 	"gopkg.in/juju/names.v3"
 
 	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/core/life"
 	"github.com/juju/juju/core/model"
+	"github.com/juju/juju/core/watcher"
 )
 
 // Snapshot is a snapshot of the remote state of the unit.
@@ -109,6 +112,28 @@ type RelationSnapshot struct {
 
 	// ApplicationMembers tracks the Change version of each member's application data bag
 	ApplicationMembers map[string]int64
+
+	// MembersObserved records when the controller observed the
+	// latest change to each member's data bag, where it is known.
+	MembersObserved map[string]time.Time
+
+	// IngressNetworksVersion and EgressNetworksVersion identify the
+	// ingress and egress CIDRs of a cross model relation. They are
+	// empty when the relation has no such CIDRs.
+	IngressNetworksVersion string
+	EgressNetworksVersion  string
+}
+
+// setMember records the latest settings version of a member, and when
+// the controller observed it. An earlier observation is discarded when
+// the change's observation is not known.
+func (s RelationSnapshot) setMember(unit string, settings watcher.UnitSettings) {
+	s.Members[unit] = settings.Version
+	if settings.Observed.IsZero() {
+		delete(s.MembersObserved, unit)
+		return
+	}
+	s.MembersObserved[unit] = settings.Observed.UTC()
 }
 
 // StorageSnapshot has information relating to a storage
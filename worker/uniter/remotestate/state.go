@@ -28,6 +28,8 @@ type State interface {
 	StorageAttachmentLife([]params.StorageAttachmentId) ([]params.LifeResult, error)
 	Unit(names.UnitTag) (Unit, error)
 	WatchRelationUnits(names.RelationTag, names.UnitTag) (watcher.RelationUnitsWatcher, error)
+	WatchRelationIngressNetworks(names.RelationTag, names.UnitTag) (watcher.StringsWatcher, error)
+	WatchRelationEgressNetworks(names.RelationTag, names.UnitTag) (watcher.StringsWatcher, error)
 	WatchStorageAttachment(names.StorageTag, names.UnitTag) (watcher.NotifyWatcher, error)
 	WatchUpdateStatusHookInterval() (watcher.NotifyWatcher, error)
 	UpdateStatusHookInterval() (time.Duration, error)
@@ -33,15 +33,19 @@ type RemoteStateWatcher struct {
 	modelType                 model.ModelType
 	relations                 map[names.RelationTag]*wrappedRelationUnitsWatcher
 	relationUnitsChanges      chan relationUnitsChange
+	relationNetworks          map[names.RelationTag]*wrappedRelationNetworksWatcher
+	relationNetworksChanges   chan relationNetworksChange
 	storageAttachmentWatchers map[names.StorageTag]*storageAttachmentWatcher
 	storageAttachmentChanges  chan storageAttachmentChange
 	leadershipTracker         leadership.Tracker
 	updateStatusChannel       UpdateStatusTimerFunc
 	commandChannel            <-chan string
 	retryHookChannel          watcher.NotifyChannel
+	relationSuspensionChannel watcher.NotifyChannel
 	applicationChannel        watcher.NotifyChannel
 	runningStatusChannel      watcher.NotifyChannel
 	runningStatusFunc         RunningStatusFunc
+	injector                  *Injector
 
 	catacomb catacomb.Catacomb
 
@@ -67,6 +71,15 @@ type WatcherConfig struct {
 	RunningStatusFunc    RunningStatusFunc
 	UnitTag              names.UnitTag
 	ModelType            model.ModelType
+
+	// RelationSuspensionChannel, if not nil, signals that a change
+	// to the suspension of a relation, held back by the uniter until
+	// it stabilises, is due to take effect.
+	RelationSuspensionChannel watcher.NotifyChannel
+
+	// RelationInjector, if not nil, delivers synthetic relation
+	// changes from a test harness to the watcher.
+	RelationInjector *Injector
 }
 
 func (w WatcherConfig) validate() error {
@@ -94,16 +107,20 @@ func NewWatcher(config WatcherConfig) (*RemoteStateWatcher, error) {
 		st:                        config.State,
 		relations:                 make(map[names.RelationTag]*wrappedRelationUnitsWatcher),
 		relationUnitsChanges:      make(chan relationUnitsChange),
+		relationNetworks:          make(map[names.RelationTag]*wrappedRelationNetworksWatcher),
+		relationNetworksChanges:   make(chan relationNetworksChange),
 		storageAttachmentWatchers: make(map[names.StorageTag]*storageAttachmentWatcher),
 		storageAttachmentChanges:  make(chan storageAttachmentChange),
 		leadershipTracker:         config.LeadershipTracker,
 		updateStatusChannel:       config.UpdateStatusChannel,
 		commandChannel:            config.CommandChannel,
 		retryHookChannel:          config.RetryHookChannel,
+		relationSuspensionChannel: config.RelationSuspensionChannel,
 		applicationChannel:        config.ApplicationChannel,
 		runningStatusChannel:      config.RunningStatusChannel,
 		runningStatusFunc:         config.RunningStatusFunc,
 		modelType:                 config.ModelType,
+		injector:                  config.RelationInjector,
 		// Note: it is important that the out channel be buffered!
 		// The remote state watcher will perform a non-blocking send
 		// on the channel to wake up the observer. It is non-blocking
@@ -149,10 +166,13 @@ func (w *RemoteStateWatcher) Snapshot() Snapshot {
 	snapshot.Relations = make(map[int]RelationSnapshot)
 	for id, relationSnapshot := range w.current.Relations {
 		relationSnapshotCopy := RelationSnapshot{
-			Life:               relationSnapshot.Life,
-			Suspended:          relationSnapshot.Suspended,
-			Members:            make(map[string]int64),
-			ApplicationMembers: make(map[string]int64),
+			Life:                   relationSnapshot.Life,
+			Suspended:              relationSnapshot.Suspended,
+			Members:                make(map[string]int64),
+			ApplicationMembers:     make(map[string]int64),
+			MembersObserved:        make(map[string]time.Time),
+			IngressNetworksVersion: relationSnapshot.IngressNetworksVersion,
+			EgressNetworksVersion:  relationSnapshot.EgressNetworksVersion,
 		}
 		for name, version := range relationSnapshot.Members {
 			relationSnapshotCopy.Members[name] = version
@@ -160,6 +180,9 @@ func (w *RemoteStateWatcher) Snapshot() Snapshot {
 		for name, version := range relationSnapshot.ApplicationMembers {
 			relationSnapshotCopy.ApplicationMembers[name] = version
 		}
+		for name, observed := range relationSnapshot.MembersObserved {
+			relationSnapshotCopy.MembersObserved[name] = observed
+		}
 		snapshot.Relations[id] = relationSnapshotCopy
 	}
 	snapshot.Storage = make(map[names.StorageTag]StorageSnapshot)
@@ -414,6 +437,13 @@ func (w *RemoteStateWatcher) loop(unitTag names.UnitTag) (err error) {
 		updateStatusTimer = w.updateStatusChannel(updateStatusInterval).After()
 	}
 
+	var injections <-chan injectionRequest
+	if w.injector != nil {
+		var unregister func()
+		injections, unregister = w.injector.register(unitTag.Id(), w.catacomb.Dying())
+		defer unregister()
+	}
+
 	for {
 		select {
 		case <-w.catacomb.Dying():
@@ -574,6 +604,10 @@ func (w *RemoteStateWatcher) loop(unitTag names.UnitTag) (err error) {
 				return errors.Trace(err)
 			}
 
+		case change := <-w.relationNetworksChanges:
+			logger.Debugf("got a relation networks change: %v", change)
+			w.relationNetworksChanged(change)
+
 		case <-updateStatusTimer:
 			logger.Debugf("update status timer triggered")
 			w.updateStatusChanged()
@@ -592,6 +626,19 @@ func (w *RemoteStateWatcher) loop(unitTag names.UnitTag) (err error) {
 			}
 			logger.Debugf("retry hook timer triggered")
 			w.retryHookTimerTriggered()
+
+		case _, ok := <-w.relationSuspensionChannel:
+			if !ok {
+				return errors.New("relationSuspensionChannel closed")
+			}
+			logger.Debugf("relation suspension timer triggered")
+
+		case req := <-injections:
+			err := w.relationInjected(req.injection)
+			req.result <- err
+			if err != nil {
+				continue
+			}
 		}
 
 		// Something changed.
@@ -734,6 +781,7 @@ func (w *RemoteStateWatcher) relationsChanged(keys []string) error {
 				delete(w.relations, relationTag)
 				delete(w.current.Relations, ruw.relationId)
 			}
+			w.stopRelationNetworks(relationTag)
 		} else if err != nil {
 			return errors.Trace(err)
 		} else {
@@ -764,6 +812,7 @@ func (w *RemoteStateWatcher) ensureRelationUnits(rel Relation) error {
 				}
 				delete(w.relations, relationTag)
 			}
+			w.stopRelationNetworks(relationTag)
 		}
 		return nil
 	}
@@ -799,6 +848,7 @@ func (w *RemoteStateWatcher) watchRelationUnits(rel Relation) error {
 		Suspended:          rel.Suspended(),
 		Members:            make(map[string]int64),
 		ApplicationMembers: make(map[string]int64),
+		MembersObserved:    make(map[string]time.Time),
 	}
 	// Handle the first change to populate the Members map.
 	select {
@@ -809,7 +859,7 @@ func (w *RemoteStateWatcher) watchRelationUnits(rel Relation) error {
 			return errors.New("relation units watcher closed")
 		}
 		for unit, settings := range change.Changed {
-			relationSnapshot.Members[unit] = settings.Version
+			relationSnapshot.setMember(unit, settings)
 		}
 		for app, settingsVersion := range change.AppChanged {
 			relationSnapshot.ApplicationMembers[app] = settingsVersion
@@ -824,11 +874,80 @@ func (w *RemoteStateWatcher) watchRelationUnits(rel Relation) error {
 	if err := w.catacomb.Add(innerRUW); err != nil {
 		return errors.Trace(err)
 	}
-	w.current.Relations[rel.Id()] = relationSnapshot
 	w.relations[rel.Tag()] = innerRUW
+	if err := w.watchRelationNetworks(rel, &relationSnapshot); err != nil {
+		return errors.Trace(err)
+	}
+	w.current.Relations[rel.Id()] = relationSnapshot
 	return nil
 }
 
+// watchRelationNetworks starts watching the ingress and egress networks
+// of the given relation, waits for their first events, and records the
+// versions of the networks in the relation's snapshot. Nothing is watched
+// if the controller cannot report them.
+func (w *RemoteStateWatcher) watchRelationNetworks(rel Relation, relationSnapshot *RelationSnapshot) error {
+	ingress, err := w.st.WatchRelationIngressNetworks(rel.Tag(), w.unit.Tag())
+	if errors.IsNotImplemented(err) || params.IsCodeNotFoundOrCodeUnauthorized(err) {
+		return nil
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	if err := w.catacomb.Add(ingress); err != nil {
+		return errors.Trace(err)
+	}
+	egress, err := w.st.WatchRelationEgressNetworks(rel.Tag(), w.unit.Tag())
+	if params.IsCodeNotFoundOrCodeUnauthorized(err) {
+		return worker.Stop(ingress)
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	if err := w.catacomb.Add(egress); err != nil {
+		return errors.Trace(err)
+	}
+
+	// Handle the first changes to populate the network versions.
+	for _, watch := range []struct {
+		changes watcher.StringsChannel
+		version *string
+	}{
+		{ingress.Changes(), &relationSnapshot.IngressNetworksVersion},
+		{egress.Changes(), &relationSnapshot.EgressNetworksVersion},
+	} {
+		select {
+		case <-w.catacomb.Dying():
+			return w.catacomb.ErrDying()
+		case cidrs, ok := <-watch.changes:
+			if !ok {
+				return errors.New("relation networks watcher closed")
+			}
+			*watch.version = networksVersion(cidrs)
+		}
+	}
+
+	innerRNW, err := wrapRelationNetworksWatcher(rel.Id(), ingress, egress, w.relationNetworksChanges)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := w.catacomb.Add(innerRNW); err != nil {
+		return errors.Trace(err)
+	}
+	w.relationNetworks[rel.Tag()] = innerRNW
+	return nil
+}
+
+// stopRelationNetworks stops watching the networks of the relation.
+func (w *RemoteStateWatcher) stopRelationNetworks(relationTag names.RelationTag) {
+	rnw, ok := w.relationNetworks[relationTag]
+	if !ok {
+		return
+	}
+	if err := worker.Stop(rnw); err != nil {
+		logger.Debugf("error stopping relation networks watcher: %v", err)
+	}
+	delete(w.relationNetworks, relationTag)
+}
+
 // relationUnitsChanged responds to relation units changes.
 func (w *RemoteStateWatcher) relationUnitsChanged(change relationUnitsChange) error {
 	w.mu.Lock()
@@ -838,17 +957,35 @@ func (w *RemoteStateWatcher) relationUnitsChanged(change relationUnitsChange) er
 		return nil
 	}
 	for unit, settings := range change.Changed {
-		snapshot.Members[unit] = settings.Version
+		snapshot.setMember(unit, settings)
 	}
 	for app, settingsVersion := range change.AppChanged {
 		snapshot.ApplicationMembers[app] = settingsVersion
 	}
 	for _, unit := range change.Departed {
 		delete(snapshot.Members, unit)
+		delete(snapshot.MembersObserved, unit)
 	}
 	return nil
 }
 
+// relationNetworksChanged responds to changes to
+// the ingress or egress networks of a relation.
+func (w *RemoteStateWatcher) relationNetworksChanged(change relationNetworksChange) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	snapshot, ok := w.current.Relations[change.relationId]
+	if !ok {
+		return
+	}
+	if change.ingress {
+		snapshot.IngressNetworksVersion = networksVersion(change.cidrs)
+	} else {
+		snapshot.EgressNetworksVersion = networksVersion(change.cidrs)
+	}
+	w.current.Relations[change.relationId] = snapshot
+}
+
 // storageAttachmentChanged responds to storage attachment changes.
 func (w *RemoteStateWatcher) storageAttachmentChanged(change storageAttachmentChange) {
 	w.mu.Lock()
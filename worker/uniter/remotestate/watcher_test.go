@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/juju/clock/testclock"
+	"github.com/juju/errors"
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 	"gopkg.in/juju/charm.v6"
@@ -28,6 +29,7 @@ type WatcherSuite struct {
 	leadership *mockLeadershipTracker
 	watcher    *remotestate.RemoteStateWatcher
 	clock      *testclock.Clock
+	injector   *remotestate.Injector
 
 	applicationWatcher   *mockNotifyWatcher
 	runningStatusWatcher *mockNotifyWatcher
@@ -70,6 +72,8 @@ func (s *WatcherSuite) SetUpTest(c *gc.C) {
 		relations:                   make(map[names.RelationTag]*mockRelation),
 		storageAttachment:           make(map[params.StorageAttachmentId]params.StorageAttachment),
 		relationUnitsWatchers:       make(map[names.RelationTag]*mockRelationUnitsWatcher),
+		relationIngressWatchers:     make(map[names.RelationTag]*mockStringsWatcher),
+		relationEgressWatchers:      make(map[names.RelationTag]*mockStringsWatcher),
 		relationAppWatchers:         make(map[names.RelationTag]map[string]*mockNotifyWatcher),
 		storageAttachmentWatchers:   make(map[names.StorageTag]*mockNotifyWatcher),
 		updateStatusInterval:        5 * time.Minute,
@@ -83,6 +87,7 @@ func (s *WatcherSuite) SetUpTest(c *gc.C) {
 	}
 
 	s.clock = testclock.NewClock(time.Now())
+	s.injector = remotestate.NewInjector()
 }
 
 func (s *WatcherSuiteIAAS) SetUpTest(c *gc.C) {
@@ -100,6 +105,7 @@ func (s *WatcherSuiteIAAS) SetUpTest(c *gc.C) {
 		LeadershipTracker:   s.leadership,
 		UnitTag:             s.st.unit.tag,
 		UpdateStatusChannel: statusTicker,
+		RelationInjector:    s.injector,
 	})
 	c.Assert(err, jc.ErrorIsNil)
 	s.watcher = w
@@ -122,6 +128,7 @@ func (s *WatcherSuiteCAAS) SetUpTest(c *gc.C) {
 		ApplicationChannel:   s.applicationWatcher.Changes(),
 		RunningStatusChannel: s.runningStatusWatcher.Changes(),
 		RunningStatusFunc:    func() (bool, error) { return s.running, nil },
+		RelationInjector:     s.injector,
 	})
 	c.Assert(err, jc.ErrorIsNil)
 	s.watcher = w
@@ -596,8 +603,12 @@ func (s *WatcherSuite) TestRelationsChanged(c *gc.C) {
 	// There should not be any signal until the relation units watcher has
 	// returned its initial event also.
 	assertNoNotifyEvent(c, s.watcher.RemoteStateChanged(), "remote state change")
+	observed := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
 	s.st.relationUnitsWatchers[relationTag].changes <- watcher.RelationUnitsChange{
-		Changed:    map[string]watcher.UnitSettings{"mysql/1": {1}, "mysql/2": {2}},
+		Changed: map[string]watcher.UnitSettings{
+			"mysql/1": {Version: 1, Observed: observed},
+			"mysql/2": {Version: 2},
+		},
 		AppChanged: map[string]int64{"mysql": 1},
 	}
 	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
@@ -610,6 +621,7 @@ func (s *WatcherSuite) TestRelationsChanged(c *gc.C) {
 				Suspended:          false,
 				Members:            map[string]int64{"mysql/1": 1, "mysql/2": 2},
 				ApplicationMembers: map[string]int64{"mysql": 1},
+				MembersObserved:    map[string]time.Time{"mysql/1": observed},
 			},
 		},
 	)
@@ -642,7 +654,7 @@ func (s *WatcherSuite) TestRelationsSuspended(c *gc.C) {
 	s.st.unit.relationsWatcher.changes <- []string{relationTag.Id()}
 	assertNoNotifyEvent(c, s.watcher.RemoteStateChanged(), "remote state change")
 	s.st.relationUnitsWatchers[relationTag].changes <- watcher.RelationUnitsChange{
-		Changed:    map[string]watcher.UnitSettings{"mysql/1": {1}, "mysql/2": {2}},
+		Changed:    map[string]watcher.UnitSettings{"mysql/1": {Version: 1}, "mysql/2": {Version: 2}},
 		AppChanged: map[string]int64{"mysql": 1},
 	}
 	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
@@ -666,13 +678,13 @@ func (s *WatcherSuite) TestRelationUnitsChanged(c *gc.C) {
 
 	s.st.unit.relationsWatcher.changes <- []string{relationTag.Id()}
 	s.st.relationUnitsWatchers[relationTag].changes <- watcher.RelationUnitsChange{
-		Changed:    map[string]watcher.UnitSettings{"mysql/1": {1}},
+		Changed:    map[string]watcher.UnitSettings{"mysql/1": {Version: 1}},
 		AppChanged: map[string]int64{"mysql": 1},
 	}
 	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
 
 	s.st.relationUnitsWatchers[relationTag].changes <- watcher.RelationUnitsChange{
-		Changed: map[string]watcher.UnitSettings{"mysql/1": {2}, "mysql/2": {1}},
+		Changed: map[string]watcher.UnitSettings{"mysql/1": {Version: 2}, "mysql/2": {Version: 1}},
 	}
 	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
 	c.Assert( // Members is updated
@@ -712,6 +724,136 @@ func (s *WatcherSuite) TestRelationUnitsChanged(c *gc.C) {
 	)
 }
 
+func (s *WatcherSuite) TestRelationNetworksChanged(c *gc.C) {
+	s.signalAll()
+	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
+
+	relationTag := names.NewRelationTag("mysql:db wordpress:db")
+	s.st.relations[relationTag] = &mockRelation{
+		tag: relationTag, id: 123, life: life.Alive,
+	}
+	s.st.relationUnitsWatchers[relationTag] = newMockRelationUnitsWatcher()
+	s.st.relationIngressWatchers[relationTag] = newMockStringsWatcher()
+	s.st.relationEgressWatchers[relationTag] = newMockStringsWatcher()
+
+	s.st.unit.relationsWatcher.changes <- []string{relationTag.Id()}
+	s.st.relationUnitsWatchers[relationTag].changes <- watcher.RelationUnitsChange{
+		Changed:    map[string]watcher.UnitSettings{"mysql/1": {Version: 1}},
+		AppChanged: map[string]int64{"mysql": 1},
+	}
+	// There should not be any signal until the networks
+	// watchers have returned their initial events also.
+	assertNoNotifyEvent(c, s.watcher.RemoteStateChanged(), "remote state change")
+	s.st.relationIngressWatchers[relationTag].changes <- []string{"10.0.0.0/24", "192.168.1.0/24"}
+	s.st.relationEgressWatchers[relationTag].changes <- nil
+	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
+	snapshot := s.watcher.Snapshot().Relations[123]
+	ingressVersion := snapshot.IngressNetworksVersion
+	c.Assert(ingressVersion, gc.Not(gc.Equals), "")
+	c.Assert(snapshot.EgressNetworksVersion, gc.Equals, "")
+
+	// The order of the CIDRs does not matter.
+	s.st.relationIngressWatchers[relationTag].changes <- []string{"192.168.1.0/24", "10.0.0.0/24"}
+	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
+	c.Assert(s.watcher.Snapshot().Relations[123].IngressNetworksVersion, gc.Equals, ingressVersion)
+
+	s.st.relationEgressWatchers[relationTag].changes <- []string{"10.0.0.0/24", "192.168.1.0/24"}
+	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
+	c.Assert(s.watcher.Snapshot().Relations[123].EgressNetworksVersion, gc.Equals, ingressVersion)
+
+	s.st.relationIngressWatchers[relationTag].changes <- nil
+	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
+	c.Assert(s.watcher.Snapshot().Relations[123].IngressNetworksVersion, gc.Equals, "")
+
+	// The networks watchers are stopped with the relation.
+	delete(s.st.relations, relationTag)
+	s.st.unit.relationsWatcher.changes <- []string{relationTag.Id()}
+	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
+	c.Assert(s.st.relationIngressWatchers[relationTag].Stopped(), jc.IsTrue)
+	c.Assert(s.st.relationEgressWatchers[relationTag].Stopped(), jc.IsTrue)
+}
+
+func (s *WatcherSuite) TestRelationInjected(c *gc.C) {
+	s.signalAll()
+	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
+
+	relationTag := names.NewRelationTag("mysql:peer")
+	s.st.relations[relationTag] = &mockRelation{
+		tag: relationTag, id: 123, life: life.Alive,
+	}
+	s.st.relationUnitsWatchers[relationTag] = newMockRelationUnitsWatcher()
+
+	s.st.unit.relationsWatcher.changes <- []string{relationTag.Id()}
+	s.st.relationUnitsWatchers[relationTag].changes <- watcher.RelationUnitsChange{
+		Changed: map[string]watcher.UnitSettings{"mysql/1": {Version: 1}},
+	}
+	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
+
+	err := s.injector.Inject("mysql/0", remotestate.RelationInjection{
+		RelationId: 123,
+		Changed:    map[string]int64{"mysql/2": 1},
+		AppChanged: map[string]int64{"mysql": 3},
+		Departed:   []string{"mysql/1"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
+	c.Assert(
+		s.watcher.Snapshot().Relations[123].Members,
+		jc.DeepEquals,
+		map[string]int64{"mysql/2": 1},
+	)
+	c.Assert(
+		s.watcher.Snapshot().Relations[123].ApplicationMembers,
+		jc.DeepEquals,
+		map[string]int64{"mysql": 3},
+	)
+
+	// Real changes are still applied on top of injected ones.
+	s.st.relationUnitsWatchers[relationTag].changes <- watcher.RelationUnitsChange{
+		Changed: map[string]watcher.UnitSettings{"mysql/2": {Version: 2}},
+	}
+	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
+	c.Assert(
+		s.watcher.Snapshot().Relations[123].Members,
+		jc.DeepEquals,
+		map[string]int64{"mysql/2": 2},
+	)
+}
+
+func (s *WatcherSuite) TestRelationInjectedUnknownRelation(c *gc.C) {
+	s.signalAll()
+	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
+
+	err := s.injector.Inject("mysql/0", remotestate.RelationInjection{
+		RelationId: 123,
+		Changed:    map[string]int64{"wordpress/0": 1},
+	})
+	c.Assert(err, gc.ErrorMatches, "relation 123 not found")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	assertNoNotifyEvent(c, s.watcher.RemoteStateChanged(), "remote state change")
+}
+
+func (s *WatcherSuite) TestRelationInjectedUnknownUnit(c *gc.C) {
+	s.signalAll()
+	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
+
+	err := s.injector.Inject("wordpress/0", remotestate.RelationInjection{RelationId: 123})
+	c.Assert(err, gc.ErrorMatches, `unit "wordpress/0" not found`)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *WatcherSuite) TestRelationInjectedJSON(c *gc.C) {
+	s.signalAll()
+	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
+
+	err := s.injector.InjectJSON("mysql/0", []byte(`{"relation-id": "x"}`))
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+
+	err = s.injector.InjectJSON("mysql/0", []byte(`{"relation-id": 123, "changed": {"mysql": 1}}`))
+	c.Assert(err, gc.ErrorMatches, `unit name "mysql" not valid`)
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
 func (s *WatcherSuite) TestRelationUnitsDontLeakReferences(c *gc.C) {
 	s.signalAll()
 	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
@@ -724,7 +866,7 @@ func (s *WatcherSuite) TestRelationUnitsDontLeakReferences(c *gc.C) {
 
 	s.st.unit.relationsWatcher.changes <- []string{relationTag.Id()}
 	s.st.relationUnitsWatchers[relationTag].changes <- watcher.RelationUnitsChange{
-		Changed: map[string]watcher.UnitSettings{"mysql/1": {1}},
+		Changed: map[string]watcher.UnitSettings{"mysql/1": {Version: 1}},
 	}
 	assertNotifyEvent(c, s.watcher.RemoteStateChanged(), "waiting for remote state change")
 
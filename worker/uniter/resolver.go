@@ -4,6 +4,9 @@
 package uniter
 
 import (
+	"sort"
+	"time"
+
 	"github.com/juju/errors"
 	"gopkg.in/juju/charm.v6"
 	"gopkg.in/juju/charm.v6/hooks"
@@ -13,25 +16,28 @@ import (
 	"github.com/juju/juju/core/model"
 	"github.com/juju/juju/worker/uniter/hook"
 	"github.com/juju/juju/worker/uniter/operation"
+	"github.com/juju/juju/worker/uniter/relation"
 	"github.com/juju/juju/worker/uniter/remotestate"
 	"github.com/juju/juju/worker/uniter/resolver"
 )
 
 // ResolverConfig defines configuration for the uniter resolver.
 type ResolverConfig struct {
-	ModelType           model.ModelType
-	ClearResolved       func() error
-	ReportHookError     func(hook.Info) error
-	ShouldRetryHooks    bool
-	StartRetryHookTimer func()
-	StopRetryHookTimer  func()
-	UpgradeSeries       resolver.Resolver
-	Leadership          resolver.Resolver
-	Actions             resolver.Resolver
-	CreatedRelations    resolver.Resolver
-	Relations           resolver.Resolver
-	Storage             resolver.Resolver
-	Commands            resolver.Resolver
+	ModelType               model.ModelType
+	ClearResolved           func() error
+	ReportHookError         func(hook.Info) error
+	ShouldRetryHooks        bool
+	StartRetryHookTimer     func()
+	StopRetryHookTimer      func()
+	RelationRetryPolicy     func(int) (relation.RetryPolicy, bool)
+	StartRelationRetryTimer func(time.Duration)
+	UpgradeSeries           resolver.Resolver
+	Leadership              resolver.Resolver
+	Actions                 resolver.Resolver
+	CreatedRelations        resolver.Resolver
+	Relations               resolver.Resolver
+	Storage                 resolver.Resolver
+	Commands                resolver.Resolver
 }
 
 type uniterResolver struct {
@@ -78,6 +84,7 @@ func (s *uniterResolver) NextOp(
 	}
 
 	if localState.Kind == operation.Upgrade {
+		s.drainRelations()
 		if localState.Conflicted {
 			return s.nextOpConflicted(localState, remoteState, opFactory)
 		}
@@ -93,6 +100,9 @@ func (s *uniterResolver) NextOp(
 		return nil, resolver.ErrRestart
 	}
 
+	// The charm upgrade, if any, has completed.
+	s.resumeRelations()
+
 	if s.retryHookTimerStarted && (localState.Kind != operation.RunHook || localState.Step != operation.Pending) {
 		// The hook-retry timer is running, but there is no pending
 		// hook operation. We're not in an error state, so stop the
@@ -151,6 +161,9 @@ func (s *uniterResolver) NextOp(
 		}
 
 	case operation.Continue:
+		if len(localState.QuarantinedHooks) > 0 && remoteState.ResolvedMode != params.ResolvedNone {
+			return s.nextOpQuarantined(localState, remoteState, opFactory)
+		}
 		logger.Debugf("no operations in progress; waiting for changes")
 		return s.nextOp(localState, remoteState, opFactory)
 
@@ -199,11 +212,15 @@ func (s *uniterResolver) nextOpHookError(
 	}
 
 	if remoteState.ForceCharmUpgrade && charmModified(localState, remoteState) {
+		s.drainRelations()
 		return s.upgradeOpForModel(opFactory, remoteState.CharmURL)
 	}
 
 	switch remoteState.ResolvedMode {
 	case params.ResolvedNone:
+		if policy, ok := s.relationRetryPolicy(*localState.Hook); ok {
+			return s.nextOpRelationHookError(policy, localState, remoteState, opFactory)
+		}
 		if remoteState.RetryHookVersion > localState.RetryHookVersion {
 			// We've been asked to retry: clear the hook timer
 			// started state so we'll restart it if this fails.
@@ -244,6 +261,94 @@ func (s *uniterResolver) nextOpHookError(
 	}
 }
 
+// relationRetryPolicy returns the retry policy for the hook
+// if it is a relation hook with an overriding policy.
+func (s *uniterResolver) relationRetryPolicy(hookInfo hook.Info) (relation.RetryPolicy, bool) {
+	if !hookInfo.Kind.IsRelation() || s.config.RelationRetryPolicy == nil {
+		return relation.RetryPolicy{}, false
+	}
+	return s.config.RelationRetryPolicy(hookInfo.RelationId)
+}
+
+// nextOpRelationHookError retries a failed relation hook according to the
+// retry policy of its endpoint, rather than the hook retry strategy. Once
+// the hook has been retried the maximum number of times, it waits to be
+// resolved by the operator; if the policy quarantines failed hooks, the
+// hook is set aside so that the unit's other relations can progress.
+func (s *uniterResolver) nextOpRelationHookError(
+	policy relation.RetryPolicy,
+	localState resolver.LocalState,
+	remoteState remotestate.Snapshot,
+	opFactory operation.Factory,
+) (operation.Operation, error) {
+	retries := localState.RelationHookRetries[localState.Hook.RelationId]
+	if remoteState.RetryHookVersion > localState.RetryHookVersion {
+		s.retryHookTimerStarted = false
+		return opFactory.NewRunHook(*localState.Hook)
+	}
+	if retries >= policy.MaxRetries {
+		if policy.Quarantine {
+			logger.Infof("quarantining %q hook for relation %d", localState.Hook.Kind, localState.Hook.RelationId)
+			return opFactory.NewQuarantineHook(*localState.Hook)
+		}
+		return nil, resolver.ErrNoOperation
+	}
+	if !s.retryHookTimerStarted {
+		s.config.StartRelationRetryTimer(policy.Delay(retries))
+		s.retryHookTimerStarted = true
+	}
+	return nil, resolver.ErrNoOperation
+}
+
+// nextOpQuarantined resolves the quarantined relation hooks. Retrying
+// releases them all, so that the relations resolver runs them again;
+// otherwise they are skipped, in relation order, one at a time.
+func (s *uniterResolver) nextOpQuarantined(
+	localState resolver.LocalState,
+	remoteState remotestate.Snapshot,
+	opFactory operation.Factory,
+) (operation.Operation, error) {
+	switch remoteState.ResolvedMode {
+	case params.ResolvedRetryHooks:
+		if err := s.config.ClearResolved(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return opFactory.NewReleaseQuarantinedHooks()
+	case params.ResolvedNoHooks:
+		relationIds := make([]int, 0, len(localState.QuarantinedHooks))
+		for relationId := range localState.QuarantinedHooks {
+			relationIds = append(relationIds, relationId)
+		}
+		sort.Ints(relationIds)
+		if len(relationIds) == 1 {
+			if err := s.config.ClearResolved(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		return opFactory.NewSkipHook(localState.QuarantinedHooks[relationIds[0]])
+	default:
+		return nil, errors.Errorf(
+			"unknown resolved mode %q", remoteState.ResolvedMode,
+		)
+	}
+}
+
+// drainRelations asks the relations resolver to stop dispatching hooks,
+// so that no relation hooks for the old charm run once the charm
+// upgrade has started.
+func (s *uniterResolver) drainRelations() {
+	if relations, ok := s.config.Relations.(relation.DrainableResolver); ok {
+		relations.Drain()
+	}
+}
+
+// resumeRelations allows the relations resolver to dispatch hooks again.
+func (s *uniterResolver) resumeRelations() {
+	if relations, ok := s.config.Relations.(relation.DrainableResolver); ok {
+		relations.Resume()
+	}
+}
+
 func charmModified(local resolver.LocalState, remote remotestate.Snapshot) bool {
 	// CAAS models may not yet have read the charm url from state.
 	if remote.CharmURL == nil {
@@ -303,6 +408,7 @@ func (s *uniterResolver) nextOp(
 	}
 
 	if charmModified(localState, remoteState) {
+		s.drainRelations()
 		return s.upgradeOpForModel(opFactory, remoteState.CharmURL)
 	}
 
@@ -323,5 +429,7 @@ func (s *uniterResolver) nextOp(
 		return opFactory.NewRunHook(hook.Info{Kind: hooks.UpdateStatus})
 	}
 
-	return nil, resolver.ErrNoOperation
+	// Relations are the last thing considered, so pass on
+	// any reason they gave for having nothing to do.
+	return nil, err
 }
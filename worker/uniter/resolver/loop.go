@@ -30,6 +30,11 @@ type LoopConfig struct {
 	Abort         <-chan struct{}
 	OnIdle        func() error
 	CharmDirGuard fortress.Guard
+
+	// OnNoOperation, if non-nil, is called whenever the resolver
+	// has no operation to run, with the reason it gave for that
+	// decision, or nil if it gave none.
+	OnNoOperation func(*NoOperationReason)
 }
 
 // Loop repeatedly waits for remote state changes, feeding the local and
@@ -117,6 +122,13 @@ func Loop(cfg LoopConfig, localState *LocalState) error {
 			// If a resolver is waiting for events to
 			// complete, the agent is not idle.
 		case ErrNoOperation:
+			reason, ok := NoOperationReasonOf(err)
+			if ok {
+				logger.Debugf("%v", reason)
+			}
+			if cfg.OnNoOperation != nil {
+				cfg.OnNoOperation(reason)
+			}
 			if cfg.OnIdle != nil {
 				if err := cfg.OnIdle(); err != nil {
 					return errors.Trace(err)
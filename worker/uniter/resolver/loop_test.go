@@ -28,6 +28,7 @@ type LoopSuite struct {
 	charmURL  *charm.URL
 	abort     chan struct{}
 	onIdle    func() error
+	onNoOp    func(*resolver.NoOperationReason)
 }
 
 var _ = gc.Suite(&LoopSuite{})
@@ -58,6 +59,7 @@ func (s *LoopSuite) loop() (resolver.LocalState, error) {
 		Abort:         s.abort,
 		OnIdle:        s.onIdle,
 		CharmDirGuard: &mockCharmDirGuard{},
+		OnNoOperation: s.onNoOp,
 	}, &localState)
 	return localState, err
 }
@@ -105,6 +107,30 @@ func (s *LoopSuite) TestOnIdleError(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, "onIdle failed")
 }
 
+func (s *LoopSuite) TestOnNoOperationReason(c *gc.C) {
+	s.resolver = resolver.ResolverFunc(func(
+		_ resolver.LocalState,
+		_ remotestate.Snapshot,
+		_ operation.Factory,
+	) (operation.Operation, error) {
+		return nil, resolver.NewNoOperationReason(resolver.WaitingRemoteApp, "relation %d has no remote units", 1)
+	})
+	var reasons []*resolver.NoOperationReason
+	s.onNoOp = func(reason *resolver.NoOperationReason) {
+		reasons = append(reasons, reason)
+	}
+	s.onIdle = func() error {
+		return errors.New("onIdle failed")
+	}
+	close(s.abort)
+	_, err := s.loop()
+	c.Assert(err, gc.ErrorMatches, "onIdle failed")
+	c.Assert(reasons, jc.DeepEquals, []*resolver.NoOperationReason{{
+		Code:   resolver.WaitingRemoteApp,
+		Detail: "relation 1 has no remote units",
+	}})
+}
+
 func (s *LoopSuite) TestErrWaitingNoOnIdle(c *gc.C) {
 	var onIdleCalled bool
 	s.onIdle = func() error {
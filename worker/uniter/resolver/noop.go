@@ -0,0 +1,88 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package resolver
+
+import (
+	"fmt"
+)
+
+// NoOperationCode identifies why a resolver has no operation to run.
+type NoOperationCode string
+
+const (
+	// AllUpToDate indicates that the local state already
+	// reflects the remote state.
+	AllUpToDate NoOperationCode = "all-up-to-date"
+
+	// OperationPending indicates that another operation must
+	// complete before the resolver can schedule anything.
+	OperationPending NoOperationCode = "operation-pending"
+
+	// WaitingRemoteApp indicates that a relation is waiting
+	// for units of the remote application to join it.
+	WaitingRemoteApp NoOperationCode = "waiting-remote-app"
+
+	// SuspendedRelation indicates that a relation has been
+	// suspended and already broken.
+	SuspendedRelation NoOperationCode = "suspended-relation"
+
+	// QuarantinedRelation indicates that a relation's failed
+	// hook has been set aside until it is resolved.
+	QuarantinedRelation NoOperationCode = "quarantined-relation"
+
+	// Draining indicates that the resolver has been asked to stop
+	// scheduling operations, for example during a charm upgrade.
+	Draining NoOperationCode = "draining"
+)
+
+// NoOperationReason is an ErrNoOperation that explains why the
+// resolver decided there was nothing to run. Its cause is
+// ErrNoOperation, so callers comparing errors.Cause(err) with
+// ErrNoOperation are unaffected.
+type NoOperationReason struct {
+	// Code identifies the reason.
+	Code NoOperationCode
+
+	// Detail is a human readable explanation of the reason.
+	Detail string
+}
+
+// NewNoOperationReason returns an error, whose cause is
+// ErrNoOperation, carrying the supplied reason.
+func NewNoOperationReason(code NoOperationCode, format string, args ...interface{}) error {
+	return &NoOperationReason{
+		Code:   code,
+		Detail: fmt.Sprintf(format, args...),
+	}
+}
+
+// Error is part of the error interface.
+func (r *NoOperationReason) Error() string {
+	if r.Detail == "" {
+		return fmt.Sprintf("%s (%s)", ErrNoOperation, r.Code)
+	}
+	return fmt.Sprintf("%s (%s): %s", ErrNoOperation, r.Code, r.Detail)
+}
+
+// Cause returns ErrNoOperation.
+func (r *NoOperationReason) Cause() error {
+	return ErrNoOperation
+}
+
+// NoOperationReasonOf returns the reason attached to err, if err is
+// an ErrNoOperation that carries one. Reasons wrapped using the
+// juju/errors package are found.
+func NoOperationReasonOf(err error) (*NoOperationReason, bool) {
+	for err != nil {
+		if reason, ok := err.(*NoOperationReason); ok {
+			return reason, true
+		}
+		wrapper, ok := err.(interface{ Underlying() error })
+		if !ok {
+			break
+		}
+		err = wrapper.Underlying()
+	}
+	return nil, false
+}
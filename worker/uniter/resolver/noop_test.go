@@ -0,0 +1,40 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package resolver_test
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/worker/uniter/resolver"
+)
+
+type NoOperationReasonSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&NoOperationReasonSuite{})
+
+func (s *NoOperationReasonSuite) TestCause(c *gc.C) {
+	err := resolver.NewNoOperationReason(resolver.SuspendedRelation, "relation %d is suspended", 2)
+	c.Assert(err, gc.ErrorMatches, `no operations \(suspended-relation\): relation 2 is suspended`)
+	c.Assert(errors.Cause(err), gc.Equals, resolver.ErrNoOperation)
+	c.Assert(errors.Cause(errors.Trace(err)), gc.Equals, resolver.ErrNoOperation)
+}
+
+func (s *NoOperationReasonSuite) TestNoOperationReasonOf(c *gc.C) {
+	err := resolver.NewNoOperationReason(resolver.AllUpToDate, "")
+	c.Assert(err, gc.ErrorMatches, `no operations \(all-up-to-date\)`)
+
+	reason, ok := resolver.NoOperationReasonOf(errors.Annotate(errors.Trace(err), "relations"))
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(reason.Code, gc.Equals, resolver.AllUpToDate)
+
+	_, ok = resolver.NoOperationReasonOf(resolver.ErrNoOperation)
+	c.Assert(ok, jc.IsFalse)
+	_, ok = resolver.NoOperationReasonOf(nil)
+	c.Assert(ok, jc.IsFalse)
+}
@@ -4,6 +4,8 @@
 package uniter_test
 
 import (
+	"time"
+
 	"github.com/juju/errors"
 	"github.com/juju/testing"
 	jc "github.com/juju/testing/checkers"
@@ -19,6 +21,7 @@ import (
 	"github.com/juju/juju/worker/uniter/hook"
 	"github.com/juju/juju/worker/uniter/leadership"
 	"github.com/juju/juju/worker/uniter/operation"
+	"github.com/juju/juju/worker/uniter/relation"
 	"github.com/juju/juju/worker/uniter/remotestate"
 	"github.com/juju/juju/worker/uniter/resolver"
 	"github.com/juju/juju/worker/uniter/storage"
@@ -287,6 +290,136 @@ func (s *resolverSuite) TestHookErrorStartRetryTimerAgain(c *gc.C) {
 	s.stub.CheckCallNames(c, "StartRetryHookTimer", "StartRetryHookTimer")
 }
 
+func (s *resolverSuite) relationHookErrorState(retries int) resolver.LocalState {
+	s.reportHookError = func(hook.Info) error { return nil }
+	s.resolverConfig.RelationRetryPolicy = func(id int) (relation.RetryPolicy, bool) {
+		s.stub.AddCall("RelationRetryPolicy", id)
+		return relation.RetryPolicy{MaxRetries: 2, Backoff: time.Second, MaxBackoff: time.Minute}, true
+	}
+	s.resolverConfig.StartRelationRetryTimer = func(delay time.Duration) {
+		s.stub.AddCall("StartRelationRetryTimer", delay)
+	}
+	s.resolver = uniter.NewUniterResolver(s.resolverConfig)
+	return resolver.LocalState{
+		CharmModifiedVersion: s.charmModifiedVersion,
+		CharmURL:             s.charmURL,
+		State: operation.State{
+			Kind:      operation.RunHook,
+			Step:      operation.Pending,
+			Installed: true,
+			Started:   true,
+			Hook: &hook.Info{
+				Kind:              hooks.RelationChanged,
+				RelationId:        1,
+				RemoteUnit:        "mysql/0",
+				RemoteApplication: "mysql",
+			},
+			RelationHookRetries: map[int]int{1: retries},
+		},
+	}
+}
+
+func (s *resolverSuite) TestRelationHookErrorStartsRelationRetryTimer(c *gc.C) {
+	localState := s.relationHookErrorState(1)
+
+	_, err := s.resolver.NextOp(localState, s.remoteState, s.opFactory)
+	c.Assert(err, gc.Equals, resolver.ErrNoOperation)
+	s.stub.CheckCalls(c, []testing.StubCall{
+		{"RelationRetryPolicy", []interface{}{1}},
+		{"StartRelationRetryTimer", []interface{}{2 * time.Second}},
+	})
+
+	s.remoteState.RetryHookVersion = 1
+	op, err := s.resolver.NextOp(localState, s.remoteState, s.opFactory)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(op.String(), gc.Equals, "run relation-changed (1; unit: mysql/0) hook")
+}
+
+func (s *resolverSuite) TestRelationHookErrorMaxRetries(c *gc.C) {
+	localState := s.relationHookErrorState(2)
+
+	// The hook waits to be resolved, rather than being retried
+	// according to the hook retry strategy.
+	_, err := s.resolver.NextOp(localState, s.remoteState, s.opFactory)
+	c.Assert(err, gc.Equals, resolver.ErrNoOperation)
+	s.stub.CheckCallNames(c, "RelationRetryPolicy")
+}
+
+func (s *resolverSuite) TestRelationHookErrorQuarantine(c *gc.C) {
+	localState := s.relationHookErrorState(2)
+	s.resolverConfig.RelationRetryPolicy = func(int) (relation.RetryPolicy, bool) {
+		return relation.RetryPolicy{MaxRetries: 2, Quarantine: true}, true
+	}
+	s.resolver = uniter.NewUniterResolver(s.resolverConfig)
+
+	op, err := s.resolver.NextOp(localState, s.remoteState, s.opFactory)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(op.String(), gc.Equals, "quarantine relation-changed (1) hook")
+}
+
+func (s *resolverSuite) quarantinedState() resolver.LocalState {
+	return resolver.LocalState{
+		CharmModifiedVersion: s.charmModifiedVersion,
+		CharmURL:             s.charmURL,
+		State: operation.State{
+			Kind:      operation.Continue,
+			Installed: true,
+			Started:   true,
+			QuarantinedHooks: map[int]hook.Info{
+				1: {Kind: hooks.RelationChanged, RelationId: 1, RemoteUnit: "mysql/0", RemoteApplication: "mysql"},
+				2: {Kind: hooks.RelationJoined, RelationId: 2, RemoteUnit: "wordpress/0", RemoteApplication: "wordpress"},
+			},
+		},
+	}
+}
+
+func (s *resolverSuite) TestQuarantinedHooksResolvedRetryHooks(c *gc.C) {
+	s.clearResolved = func() error {
+		s.stub.AddCall("ClearResolved")
+		return nil
+	}
+	s.remoteState.ResolvedMode = params.ResolvedRetryHooks
+
+	op, err := s.resolver.NextOp(s.quarantinedState(), s.remoteState, s.opFactory)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(op.String(), gc.Equals, "release quarantined hooks")
+	s.stub.CheckCallNames(c, "ClearResolved")
+}
+
+func (s *resolverSuite) TestQuarantinedHooksResolvedNoHooks(c *gc.C) {
+	s.clearResolved = func() error {
+		s.stub.AddCall("ClearResolved")
+		return nil
+	}
+	s.remoteState.ResolvedMode = params.ResolvedNoHooks
+	localState := s.quarantinedState()
+
+	// The quarantined hooks are skipped in relation order, and
+	// the unit is only marked resolved once the last is skipped.
+	op, err := s.resolver.NextOp(localState, s.remoteState, s.opFactory)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(op.String(), gc.Equals, "skip run relation-changed (1; unit: mysql/0) hook")
+	s.stub.CheckNoCalls(c)
+
+	delete(localState.QuarantinedHooks, 1)
+	op, err = s.resolver.NextOp(localState, s.remoteState, s.opFactory)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(op.String(), gc.Equals, "skip run relation-joined (2; unit: wordpress/0) hook")
+	s.stub.CheckCallNames(c, "ClearResolved")
+}
+
+func (s *resolverSuite) TestRelationHookErrorNoPolicy(c *gc.C) {
+	localState := s.relationHookErrorState(0)
+	s.resolverConfig.RelationRetryPolicy = func(int) (relation.RetryPolicy, bool) {
+		return relation.RetryPolicy{}, false
+	}
+	s.resolver = uniter.NewUniterResolver(s.resolverConfig)
+
+	_, err := s.resolver.NextOp(localState, s.remoteState, s.opFactory)
+	c.Assert(err, gc.Equals, resolver.ErrNoOperation)
+	s.stub.CheckCallNames(c, "StartRetryHookTimer")
+}
+
 func (s *resolverSuite) TestResolvedRetryHooksStopRetryTimer(c *gc.C) {
 	// Resolving a failed hook should stop the retry timer.
 	s.testResolveHookErrorStopRetryTimer(c, params.ResolvedRetryHooks)
@@ -425,3 +558,38 @@ func (s *resolverSuite) TestNoOperationIfHashesAllMatch(c *gc.C) {
 	_, err := s.resolver.NextOp(localState, s.remoteState, s.opFactory)
 	c.Assert(err, gc.Equals, resolver.ErrNoOperation)
 }
+
+func (s *caasResolverSuite) TestCharmUpgradeDrainsRelations(c *gc.C) {
+	s.resolverConfig.Relations = drainableResolver{stub: &s.stub}
+	s.resolver = uniter.NewUniterResolver(s.resolverConfig)
+
+	localState := resolver.LocalState{
+		CharmModifiedVersion: s.charmModifiedVersion,
+		CharmURL:             charm.MustParseURL("cs:precise/mysql-1"),
+		State: operation.State{
+			Kind:      operation.Continue,
+			Installed: true,
+			Started:   true,
+		},
+	}
+	_, err := s.resolver.NextOp(localState, s.remoteState, s.opFactory)
+	c.Assert(err, jc.ErrorIsNil)
+	s.stub.CheckCallNames(c, "Resume", "Drain")
+
+	// Relation hooks stay drained while the upgrade is in progress.
+	s.stub.ResetCalls()
+	localState.Kind = operation.Upgrade
+	localState.Step = operation.Pending
+	_, err = s.resolver.NextOp(localState, s.remoteState, s.opFactory)
+	c.Assert(err, jc.ErrorIsNil)
+	s.stub.CheckCallNames(c, "Drain")
+
+	// And resume once it has completed.
+	s.stub.ResetCalls()
+	localState.Kind = operation.Continue
+	localState.Step = ""
+	localState.CharmURL = s.charmURL
+	_, err = s.resolver.NextOp(localState, s.remoteState, s.opFactory)
+	c.Assert(err, gc.Equals, resolver.ErrNoOperation)
+	s.stub.CheckCallNames(c, "Resume")
+}
@@ -123,6 +123,31 @@ func (cache *RelationCache) InvalidateApplication(appName string) {
 	delete(cache.applications, appName)
 }
 
+// InvalidateAll ensures that the next attempt to read the settings of any
+// remote unit or application will use fresh data, without changing the
+// relation's membership.
+func (cache *RelationCache) InvalidateAll() {
+	for memberName := range cache.members {
+		cache.members[memberName] = nil
+	}
+	cache.applications = SettingsMap{}
+	cache.others = SettingsMap{}
+}
+
+// SetMember ensures that the named remote unit will be considered a member
+// of the relation, and caches the supplied settings for it. It is used to
+// seed the cache with settings read ahead of running a hook.
+func (cache *RelationCache) SetMember(memberName string, settings params.Settings) {
+	cache.members[memberName] = settings
+}
+
+// SetApplication caches the supplied settings for the named remote
+// application. It is used to seed the cache with settings read ahead of
+// running a hook.
+func (cache *RelationCache) SetApplication(appName string, settings params.Settings) {
+	cache.applications[appName] = settings
+}
+
 // RemoveMember ensures that the named remote unit will not be considered a
 // member of the relation,
 func (cache *RelationCache) RemoveMember(memberName string) {
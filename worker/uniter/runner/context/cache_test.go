@@ -122,6 +122,45 @@ func (s *RelationCacheSuite) TestInvalidateMemberUncachesMemberSettings(c *gc.C)
 	c.Assert(s.calls, jc.DeepEquals, []string{"x/2", "x/2"})
 }
 
+func (s *RelationCacheSuite) TestInvalidateAllUncachesSettings(c *gc.C) {
+	s.results = []settingsResult{{
+		params.Settings{"foo": "bar"}, nil,
+	}, {
+		params.Settings{"app": "v1"}, nil,
+	}, {
+		params.Settings{"baz": "qux"}, nil,
+	}, {
+		params.Settings{"app": "v2"}, nil,
+	}}
+	cache := context.NewRelationCache(s.ReadSettings, []string{"x/2"})
+
+	_, err := cache.Settings("x/2")
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = cache.ApplicationSettings("x")
+	c.Assert(err, jc.ErrorIsNil)
+
+	cache.InvalidateAll()
+	c.Assert(cache.MemberNames(), jc.DeepEquals, []string{"x/2"})
+	settings, err := cache.Settings("x/2")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(settings, jc.DeepEquals, params.Settings{"baz": "qux"})
+	settings, err = cache.ApplicationSettings("x")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(settings, jc.DeepEquals, params.Settings{"app": "v2"})
+	c.Assert(s.calls, jc.DeepEquals, []string{"x/2", "x", "x/2", "x"})
+}
+
+func (s *RelationCacheSuite) TestSetMemberCachesMemberSettings(c *gc.C) {
+	cache := context.NewRelationCache(s.ReadSettings, nil)
+	cache.SetMember("x/2", params.Settings{"foo": "bar"})
+	c.Assert(cache.MemberNames(), jc.DeepEquals, []string{"x/2"})
+
+	settings, err := cache.Settings("x/2")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(settings, jc.DeepEquals, params.Settings{"foo": "bar"})
+	c.Assert(s.calls, gc.HasLen, 0)
+}
+
 func (s *RelationCacheSuite) TestInvalidateMemberUncachesOtherSettings(c *gc.C) {
 	s.results = []settingsResult{{
 		params.Settings{"foo": "bar"}, nil,
@@ -250,3 +289,13 @@ func (s *RelationCacheSuite) TestInvalidateApplicationSettings(c *gc.C) {
 	c.Assert(settings, jc.DeepEquals, params.Settings{"foo": "baz"})
 	c.Assert(s.calls, jc.DeepEquals, []string{"x", "x"})
 }
+
+func (s *RelationCacheSuite) TestSetApplicationCachesApplicationSettings(c *gc.C) {
+	cache := context.NewRelationCache(s.ReadSettings, nil)
+	cache.SetApplication("x", params.Settings{"foo": "bar"})
+
+	settings, err := cache.ApplicationSettings("x")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(settings, jc.DeepEquals, params.Settings{"foo": "bar"})
+	c.Assert(s.calls, gc.HasLen, 0)
+}
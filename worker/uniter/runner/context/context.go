@@ -208,6 +208,11 @@ type HookContext struct {
 	// relation-set --app.
 	remoteApplicationName string
 
+	// remoteChangeObserved is when the controller observed the change to
+	// the remote unit that triggered the executing relation hook. It is
+	// zero if the context is not running such a hook, or it is not known.
+	remoteChangeObserved time.Time
+
 	// relations contains the context for every relation the unit is a member
 	// of, keyed on relation id.
 	relations map[int]*ContextRelation
@@ -936,6 +941,7 @@ func (ctx *HookContext) HookVars(paths Paths, remote bool) ([]string, error) {
 			"JUJU_RELATION_ID="+r.FakeId(),
 			"JUJU_REMOTE_UNIT="+ctx.remoteUnitName,
 			"JUJU_REMOTE_APP="+ctx.remoteApplicationName,
+			"JUJU_REMOTE_CHANGE_OBSERVED="+formatObserved(ctx.remoteChangeObserved),
 		)
 	} else if !errors.IsNotFound(err) {
 		return nil, errors.Trace(err)
@@ -951,6 +957,15 @@ func (ctx *HookContext) HookVars(paths Paths, remote bool) ([]string, error) {
 	return append(vars, OSDependentEnvVars(paths)...), nil
 }
 
+// formatObserved formats the time at which a change was observed
+// for the hook environment, as empty if it is not known.
+func formatObserved(observed time.Time) string {
+	if observed.IsZero() {
+		return ""
+	}
+	return observed.UTC().Format(time.RFC3339Nano)
+}
+
 func (ctx *HookContext) handleReboot(ctxErr error) error {
 	logger.Tracef("checking for reboot request")
 	rebootPriority := ctx.GetRebootPriority()
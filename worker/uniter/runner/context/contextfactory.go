@@ -61,6 +61,19 @@ type StorageContextAccessor interface {
 // creation time.
 type RelationsFunc func() map[int]*RelationInfo
 
+// PrefetchedSettingsFunc is used to get the remote relation settings read
+// ahead of running a relation hook, and whether any were read.
+type PrefetchedSettingsFunc func(hook.Info) (params.Settings, bool)
+
+// PinSettingsFunc is used to record the remote relation settings seen by
+// a relation hook, as pinned when its context is created.
+type PinSettingsFunc func(hook.Info, params.Settings) error
+
+// StaleRelationsFunc is used to get the ids of the relations whose remote
+// settings are read by a relation hook, and so must not be served from the
+// cache when its context is created.
+type StaleRelationsFunc func(hook.Info) []int
+
 type contextFactory struct {
 	// API connection fields; unit should be deprecated, but isn't yet.
 	unit    *uniter.Unit
@@ -82,6 +95,15 @@ type contextFactory struct {
 	getRelationInfos RelationsFunc
 	relationCaches   map[int]*RelationCache
 
+	// Callback to get remote settings read ahead of running a hook.
+	getPrefetchedSettings PrefetchedSettingsFunc
+
+	// Callback to get the relations whose cached settings a hook reads.
+	getStaleRelations StaleRelationsFunc
+
+	// Callback to record the remote settings pinned for a hook.
+	pinSettings PinSettingsFunc
+
 	// For generating "unique" context ids.
 	rand *rand.Rand
 }
@@ -96,6 +118,23 @@ type FactoryConfig struct {
 	Storage          StorageContextAccessor
 	Paths            Paths
 	Clock            Clock
+
+	// GetPrefetchedSettings, if set, is used to seed the relation
+	// settings cache when creating a relation-changed hook context,
+	// saving a round trip to the controller.
+	GetPrefetchedSettings PrefetchedSettingsFunc
+
+	// GetStaleRelations, if set, is used to find the relations whose
+	// cached settings are invalidated when creating a relation hook
+	// context, because the charm declares that the hook reads them.
+	GetStaleRelations StaleRelationsFunc
+
+	// PinSettings, if set, causes the remote settings that trigger a
+	// relation-joined or relation-changed hook to be read when its
+	// context is created, and to be returned by relation-get for the
+	// remainder of the hook, even if they change. It is used to record
+	// the settings pinned for the hook.
+	PinSettings PinSettingsFunc
 }
 
 // NewContextFactory returns a ContextFactory capable of creating execution contexts backed
@@ -143,6 +182,10 @@ func NewContextFactory(config FactoryConfig) (ContextFactory, error) {
 		zone:             zone,
 		principal:        principal,
 		modelType:        m.ModelType,
+
+		getPrefetchedSettings: config.GetPrefetchedSettings,
+		getStaleRelations:     config.GetStaleRelations,
+		pinSettings:           config.PinSettings,
 	}
 	return f, nil
 }
@@ -210,6 +253,7 @@ func (f *contextFactory) HookContext(hookInfo hook.Info) (*HookContext, error) {
 		ctx.relationId = hookInfo.RelationId
 		ctx.remoteUnitName = hookInfo.RemoteUnit
 		ctx.remoteApplicationName = hookInfo.RemoteApplication
+		ctx.remoteChangeObserved = hookInfo.Observed
 		relation, found := ctx.relations[hookInfo.RelationId]
 		if !found {
 			return nil, errors.Errorf("unknown relation id: %v", hookInfo.RelationId)
@@ -217,11 +261,23 @@ func (f *contextFactory) HookContext(hookInfo hook.Info) (*HookContext, error) {
 		if hookInfo.Kind == hooks.RelationDeparted {
 			relation.cache.RemoveMember(hookInfo.RemoteUnit)
 		} else if hookInfo.RemoteUnit != "" {
-			// Clear remote settings cache for changing remote unit.
-			relation.cache.InvalidateMember(hookInfo.RemoteUnit)
+			// Clear remote settings cache for changing remote unit,
+			// replacing it with any settings read ahead of the hook.
+			if settings, ok := f.prefetchedSettings(hookInfo); ok {
+				relation.cache.SetMember(hookInfo.RemoteUnit, settings)
+			} else {
+				relation.cache.InvalidateMember(hookInfo.RemoteUnit)
+			}
 		} else if hookInfo.RemoteApplication != "" {
 			// relation.cache.InvalidateApplication(hookInfo.RemoteApplication)
+			if settings, ok := f.prefetchedSettings(hookInfo); ok {
+				relation.cache.SetApplication(hookInfo.RemoteApplication, settings)
+			}
 		}
+		if err := f.pinRemoteSettings(relation, hookInfo); err != nil {
+			return nil, errors.Trace(err)
+		}
+		f.invalidateStaleRelations(ctx, hookInfo)
 		hookName = fmt.Sprintf("%s-%s", relation.Name(), hookInfo.Kind)
 	}
 	if hookInfo.Kind.IsStorage() {
@@ -262,6 +318,64 @@ func (f *contextFactory) ModelType() model.ModelType {
 	return f.modelType
 }
 
+// prefetchedSettings returns the remote settings read ahead of running
+// the supplied relation-changed hook, if there are any.
+func (f *contextFactory) prefetchedSettings(hookInfo hook.Info) (params.Settings, bool) {
+	if f.getPrefetchedSettings == nil || hookInfo.Kind != hooks.RelationChanged {
+		return nil, false
+	}
+	return f.getPrefetchedSettings(hookInfo)
+}
+
+// pinRemoteSettings reads the remote settings that triggered the supplied
+// relation-joined or relation-changed hook, unless they were read ahead
+// of it, and records them as pinned for the hook. The settings remain in
+// the relation's cache for the duration of the hook, so that relation-get
+// returns the data that triggered it rather than any later change. If the
+// settings cannot be read, the hook is run without them pinned, and reads
+// them when it first runs relation-get, as it would otherwise.
+func (f *contextFactory) pinRemoteSettings(relation *ContextRelation, hookInfo hook.Info) error {
+	if f.pinSettings == nil {
+		return nil
+	}
+	if hookInfo.Kind != hooks.RelationJoined && hookInfo.Kind != hooks.RelationChanged {
+		return nil
+	}
+	var (
+		settings params.Settings
+		err      error
+	)
+	switch {
+	case hookInfo.RemoteUnit != "":
+		settings, err = relation.cache.Settings(hookInfo.RemoteUnit)
+	case hookInfo.RemoteApplication != "":
+		settings, err = relation.cache.ApplicationSettings(hookInfo.RemoteApplication)
+	default:
+		return nil
+	}
+	if err != nil {
+		logger.Warningf("cannot pin remote settings for %v hook: %v", hookInfo.Kind, err)
+		return nil
+	}
+	return errors.Annotatef(f.pinSettings(hookInfo, settings), "pinning remote settings for %v hook", hookInfo.Kind)
+}
+
+// invalidateStaleRelations discards the cached settings of the relations
+// that the supplied relation hook reads data from, other than its own.
+func (f *contextFactory) invalidateStaleRelations(ctx *HookContext, hookInfo hook.Info) {
+	if f.getStaleRelations == nil {
+		return
+	}
+	for _, id := range f.getStaleRelations(hookInfo) {
+		if id == hookInfo.RelationId {
+			continue
+		}
+		if relation, found := ctx.relations[id]; found {
+			relation.cache.InvalidateAll()
+		}
+	}
+}
+
 // getContextRelations updates the factory's relation caches, and uses them
 // to construct ContextRelations for a fresh context.
 func (f *contextFactory) getContextRelations() map[int]*ContextRelation {
@@ -278,7 +392,11 @@ func (f *contextFactory) getContextRelations() map[int]*ContextRelation {
 			cache = NewRelationCache(relationUnit.ReadSettings, memberNames)
 		}
 		relationCaches[id] = cache
-		contextRelations[id] = NewContextRelation(relationUnit, cache)
+		contextRelation := NewContextRelation(relationUnit, cache)
+		if info.EndpointName != "" {
+			contextRelation.endpointName = info.EndpointName
+		}
+		contextRelations[id] = contextRelation
 	}
 	f.relationCaches = relationCaches
 	return contextRelations
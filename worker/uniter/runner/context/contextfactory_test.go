@@ -39,6 +39,8 @@ type ContextFactorySuite struct {
 	paths      runnertesting.RealPaths
 	factory    context.ContextFactory
 	membership map[int][]string
+	prefetched map[hook.Info]params.Settings
+	stale      map[hook.Info][]int
 }
 
 var _ = gc.Suite(&ContextFactorySuite{})
@@ -47,15 +49,19 @@ func (s *ContextFactorySuite) SetUpTest(c *gc.C) {
 	s.HookContextSuite.SetUpTest(c)
 	s.paths = runnertesting.NewRealPaths(c)
 	s.membership = map[int][]string{}
+	s.prefetched = map[hook.Info]params.Settings{}
+	s.stale = map[hook.Info][]int{}
 
 	contextFactory, err := context.NewContextFactory(context.FactoryConfig{
-		State:            s.uniter,
-		Unit:             s.apiUnit,
-		Tracker:          &runnertesting.FakeTracker{},
-		GetRelationInfos: s.getRelationInfos,
-		Storage:          s.storage,
-		Paths:            s.paths,
-		Clock:            testclock.NewClock(time.Time{}),
+		State:                 s.uniter,
+		Unit:                  s.apiUnit,
+		Tracker:               &runnertesting.FakeTracker{},
+		GetRelationInfos:      s.getRelationInfos,
+		GetPrefetchedSettings: s.getPrefetchedSettings,
+		GetStaleRelations:     s.getStaleRelations,
+		Storage:               s.storage,
+		Paths:                 s.paths,
+		Clock:                 testclock.NewClock(time.Time{}),
 	})
 	c.Assert(err, jc.ErrorIsNil)
 	s.factory = contextFactory
@@ -110,6 +116,15 @@ func (s *ContextFactorySuite) getRelationInfos() map[int]*context.RelationInfo {
 	return info
 }
 
+func (s *ContextFactorySuite) getPrefetchedSettings(hookInfo hook.Info) (params.Settings, bool) {
+	settings, ok := s.prefetched[hookInfo]
+	return settings, ok
+}
+
+func (s *ContextFactorySuite) getStaleRelations(hookInfo hook.Info) []int {
+	return s.stale[hookInfo]
+}
+
 func (s *ContextFactorySuite) testLeadershipContextWiring(c *gc.C, createContext func() *context.HookContext) {
 	var stub testing.Stub
 	stub.SetErrors(errors.New("bam"))
@@ -651,6 +666,182 @@ func (s *ContextFactorySuite) TestNewHookContextRelationChangedUpdatesRelationCo
 	c.Assert(found, jc.IsFalse)
 }
 
+func (s *ContextFactorySuite) TestNewHookContextRelationChangedUsesPrefetchedSettings(c *gc.C) {
+	s.setUpCacheMethods(c)
+	s.membership[1] = []string{"r/0", "r/4"}
+	s.updateCache(1, "r/0", params.Settings{"foo": "bar"})
+	s.updateCache(1, "r/4", params.Settings{"baz": "qux"})
+	hookInfo := hook.Info{
+		Kind:              hooks.RelationChanged,
+		RelationId:        1,
+		RemoteUnit:        "r/4",
+		RemoteApplication: "r",
+		ChangeVersion:     2,
+	}
+	s.prefetched[hookInfo] = params.Settings{"baz": "quux"}
+
+	ctx, err := s.factory.HookContext(hookInfo)
+	c.Assert(err, jc.ErrorIsNil)
+	rel := s.AssertRelationContext(c, ctx, 1, "r/4", "r")
+	c.Assert(rel.UnitNames(), jc.DeepEquals, []string{"r/0", "r/4"})
+	cached0, member := s.getCache(1, "r/0")
+	c.Assert(cached0, jc.DeepEquals, params.Settings{"foo": "bar"})
+	c.Assert(member, jc.IsTrue)
+	cached4, member := s.getCache(1, "r/4")
+	c.Assert(cached4, jc.DeepEquals, params.Settings{"baz": "quux"})
+	c.Assert(member, jc.IsTrue)
+}
+
+func (s *ContextFactorySuite) TestNewHookContextRelationChangedUsesPrefetchedApplicationSettings(c *gc.C) {
+	s.setUpCacheMethods(c)
+	s.membership[1] = []string{"r/0"}
+	hookInfo := hook.Info{
+		Kind:              hooks.RelationChanged,
+		RelationId:        1,
+		RemoteApplication: "r",
+		ChangeVersion:     3,
+	}
+	s.prefetched[hookInfo] = params.Settings{"frob": "nizzle"}
+
+	ctx, err := s.factory.HookContext(hookInfo)
+	c.Assert(err, jc.ErrorIsNil)
+	s.AssertRelationContext(c, ctx, 1, "", "r")
+	cachedApp, found := s.getAppCache(1, "r")
+	c.Assert(cachedApp, jc.DeepEquals, params.Settings{"frob": "nizzle"})
+	c.Assert(found, jc.IsTrue)
+}
+
+// setUpPinning replaces the suite's factory with one that pins the remote
+// settings of relation hooks, recording them with the supplied function.
+func (s *ContextFactorySuite) setUpPinning(c *gc.C, pinSettings context.PinSettingsFunc) {
+	contextFactory, err := context.NewContextFactory(context.FactoryConfig{
+		State:                 s.uniter,
+		Unit:                  s.apiUnit,
+		Tracker:               &runnertesting.FakeTracker{},
+		GetRelationInfos:      s.getRelationInfos,
+		GetPrefetchedSettings: s.getPrefetchedSettings,
+		GetStaleRelations:     s.getStaleRelations,
+		PinSettings:           pinSettings,
+		Storage:               s.storage,
+		Paths:                 s.paths,
+		Clock:                 testclock.NewClock(time.Time{}),
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	s.factory = contextFactory
+}
+
+func (s *ContextFactorySuite) TestNewHookContextRelationChangedPinsSettings(c *gc.C) {
+	pinned := map[hook.Info]params.Settings{}
+	s.setUpPinning(c, func(hookInfo hook.Info, settings params.Settings) error {
+		pinned[hookInfo] = settings
+		return nil
+	})
+	s.setUpCacheMethods(c)
+	s.membership[1] = []string{"r/0", "r/4"}
+	hookInfo := hook.Info{
+		Kind:              hooks.RelationChanged,
+		RelationId:        1,
+		RemoteUnit:        "r/4",
+		RemoteApplication: "r",
+		ChangeVersion:     2,
+	}
+	s.prefetched[hookInfo] = params.Settings{"baz": "quux"}
+
+	ctx, err := s.factory.HookContext(hookInfo)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(pinned, jc.DeepEquals, map[hook.Info]params.Settings{
+		hookInfo: {"baz": "quux"},
+	})
+
+	// The pinned settings are returned for the remainder of the hook,
+	// rather than being read again.
+	rel := s.AssertRelationContext(c, ctx, 1, "r/4", "r")
+	settings, err := rel.ReadSettings("r/4")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(settings, jc.DeepEquals, params.Settings{"baz": "quux"})
+}
+
+func (s *ContextFactorySuite) TestNewHookContextRelationChangedPinsApplicationSettings(c *gc.C) {
+	pinned := map[hook.Info]params.Settings{}
+	s.setUpPinning(c, func(hookInfo hook.Info, settings params.Settings) error {
+		pinned[hookInfo] = settings
+		return nil
+	})
+	s.setUpCacheMethods(c)
+	s.membership[1] = []string{"r/0"}
+	hookInfo := hook.Info{
+		Kind:              hooks.RelationChanged,
+		RelationId:        1,
+		RemoteApplication: "r",
+		ChangeVersion:     3,
+	}
+	s.prefetched[hookInfo] = params.Settings{"frob": "nizzle"}
+
+	_, err := s.factory.HookContext(hookInfo)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(pinned, jc.DeepEquals, map[hook.Info]params.Settings{
+		hookInfo: {"frob": "nizzle"},
+	})
+}
+
+func (s *ContextFactorySuite) TestNewHookContextRelationDepartedNotPinned(c *gc.C) {
+	s.setUpPinning(c, func(hook.Info, params.Settings) error {
+		c.Fatalf("settings should not be pinned")
+		return nil
+	})
+	s.setUpCacheMethods(c)
+	s.membership[1] = []string{"r/4"}
+
+	_, err := s.factory.HookContext(hook.Info{
+		Kind:       hooks.RelationDeparted,
+		RelationId: 1,
+		RemoteUnit: "r/0",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *ContextFactorySuite) TestNewHookContextPinSettingsError(c *gc.C) {
+	s.setUpPinning(c, func(hook.Info, params.Settings) error {
+		return errors.New("disk full")
+	})
+	s.setUpCacheMethods(c)
+	s.membership[1] = []string{"r/4"}
+	hookInfo := hook.Info{
+		Kind:              hooks.RelationChanged,
+		RelationId:        1,
+		RemoteUnit:        "r/4",
+		RemoteApplication: "r",
+	}
+	s.prefetched[hookInfo] = params.Settings{"baz": "quux"}
+
+	_, err := s.factory.HookContext(hookInfo)
+	c.Assert(err, gc.ErrorMatches, "pinning remote settings for relation-changed hook: disk full")
+}
+
+func (s *ContextFactorySuite) TestNewHookContextInvalidatesStaleRelations(c *gc.C) {
+	s.setUpCacheMethods(c)
+	s.membership[0] = []string{"r/0"}
+	s.membership[1] = []string{"r/4", "r/5"}
+	s.updateCache(0, "r/0", params.Settings{"foo": "bar"})
+	s.updateCache(1, "r/5", params.Settings{"baz": "qux"})
+	hookInfo := hook.Info{
+		Kind:       hooks.RelationJoined,
+		RelationId: 1,
+		RemoteUnit: "r/4",
+	}
+	s.stale[hookInfo] = []int{0, 1}
+
+	_, err := s.factory.HookContext(hookInfo)
+	c.Assert(err, jc.ErrorIsNil)
+	cached0, member := s.getCache(0, "r/0")
+	c.Assert(cached0, gc.IsNil)
+	c.Assert(member, jc.IsTrue)
+	// The hook's own relation is left to the usual handling.
+	cached5, member := s.getCache(1, "r/5")
+	c.Assert(cached5, jc.DeepEquals, params.Settings{"baz": "qux"})
+	c.Assert(member, jc.IsTrue)
+}
+
 func (s *ContextFactorySuite) TestNewHookContextRelationDepartedUpdatesRelationContextAndCaches(c *gc.C) {
 	// Update member settings to have actual values, so we can check that
 	// the depart for r/0 leaves r/4's cache alone (while discarding r/0's).
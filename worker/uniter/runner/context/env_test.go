@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"sort"
+	"time"
 
 	jujuos "github.com/juju/os"
 	"github.com/juju/os/series"
@@ -127,12 +128,14 @@ func (s *EnvSuite) getContext(newProxyOnly bool) (ctx *context.HookContext, expe
 }
 
 func (s *EnvSuite) setRelation(ctx *context.HookContext) (expectVars []string) {
-	context.SetEnvironmentHookContextRelation(ctx, 22, "an-endpoint", "that-unit/456", "that-app")
+	observed := time.Date(2020, 4, 1, 12, 0, 0, 500, time.UTC)
+	context.SetEnvironmentHookContextRelation(ctx, 22, "an-endpoint", "that-unit/456", "that-app", observed)
 	return []string{
 		"JUJU_RELATION=an-endpoint",
 		"JUJU_RELATION_ID=an-endpoint:22",
 		"JUJU_REMOTE_UNIT=that-unit/456",
 		"JUJU_REMOTE_APP=that-app",
+		"JUJU_REMOTE_CHANGE_OBSERVED=2020-04-01T12:00:00.0000005Z",
 	}
 }
 
@@ -4,6 +4,8 @@
 package context
 
 import (
+	"time"
+
 	"github.com/juju/errors"
 	"github.com/juju/proxy"
 	"gopkg.in/juju/charm.v6"
@@ -106,10 +108,11 @@ func NewMockUnitHookContextWithState(mockUnit *mocks.MockHookUnit, state *uniter
 
 // SetEnvironmentHookContextRelation exists purely to set the fields used in hookVars.
 // It makes no assumptions about the validity of context.
-func SetEnvironmentHookContextRelation(context *HookContext, relationId int, endpointName, remoteUnitName string, remoteAppName string) {
+func SetEnvironmentHookContextRelation(context *HookContext, relationId int, endpointName, remoteUnitName string, remoteAppName string, remoteChangeObserved time.Time) {
 	context.relationId = relationId
 	context.remoteUnitName = remoteUnitName
 	context.remoteApplicationName = remoteAppName
+	context.remoteChangeObserved = remoteChangeObserved
 	context.relations = map[int]*ContextRelation{
 		relationId: {
 			endpointName: endpointName,
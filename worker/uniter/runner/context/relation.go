@@ -18,6 +18,11 @@ import (
 type RelationInfo struct {
 	RelationUnit *uniter.RelationUnit
 	MemberNames  []string
+
+	// EndpointName, if set, is the name of the local endpoint in the
+	// deployed charm, which differs from the relation's endpoint name
+	// when a charm upgrade has renamed the endpoint.
+	EndpointName string
 }
 
 // ContextRelation is the implementation of hooks.ContextRelation.
@@ -103,12 +108,16 @@ func (ctx *ContextRelation) WriteSettings() error {
 	return errors.Trace(ctx.ru.UpdateRelationSettings(unitSettings, appSettings))
 }
 
-// FinalSettings returns the changes made to the relation settings (unit and application)
+// FinalSettings returns the changes made to the relation settings (unit
+// and application). The changes are buffered in the context while the
+// hook runs, and both are written in the single CommitHookChanges call
+// made when the hook is committed, so that they land atomically. Settings
+// that were read but not changed are not returned, so are not written.
 func (ctx *ContextRelation) FinalSettings() (unitSettings, appSettings params.Settings) {
 	if ctx.applicationSettings != nil && ctx.applicationSettings.IsDirty() {
 		appSettings = ctx.applicationSettings.FinalResult()
 	}
-	if ctx.settings != nil {
+	if ctx.settings != nil && ctx.settings.IsDirty() {
 		unitSettings = ctx.settings.FinalResult()
 	}
 	return unitSettings, appSettings
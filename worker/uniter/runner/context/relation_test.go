@@ -166,6 +166,29 @@ func (s *ContextRelationSuite) TestLocalSettings(c *gc.C) {
 	c.Assert(settings, gc.DeepEquals, map[string]interface{}{"change": "exciting"})
 }
 
+func (s *ContextRelationSuite) TestFinalSettings(c *gc.C) {
+	ctx := context.NewContextRelation(s.apiRelUnit, nil)
+	unitSettings, appSettings := ctx.FinalSettings()
+	c.Assert(unitSettings, gc.IsNil)
+	c.Assert(appSettings, gc.IsNil)
+
+	// Settings that are only read are not written on commit.
+	node, err := ctx.Settings()
+	c.Assert(err, jc.ErrorIsNil)
+	unitSettings, appSettings = ctx.FinalSettings()
+	c.Assert(unitSettings, gc.IsNil)
+	c.Assert(appSettings, gc.IsNil)
+
+	node.Set("change", "exciting")
+	node.Delete("gone")
+	unitSettings, appSettings = ctx.FinalSettings()
+	c.Assert(unitSettings["change"], gc.Equals, "exciting")
+	deleted, ok := unitSettings["gone"]
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(deleted, gc.Equals, "")
+	c.Assert(appSettings, gc.IsNil)
+}
+
 func convertSettings(settings params.Settings) map[string]interface{} {
 	result := make(map[string]interface{})
 	for k, v := range settings {
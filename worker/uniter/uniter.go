@@ -6,13 +6,18 @@ package uniter
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/juju/clock"
 	"github.com/juju/errors"
 	"github.com/juju/loggo"
 	"github.com/juju/utils"
 	"github.com/juju/utils/exec"
+	"github.com/juju/utils/featureflag"
 	corecharm "gopkg.in/juju/charm.v6"
 	"gopkg.in/juju/charm.v6/hooks"
 	"gopkg.in/juju/names.v3"
@@ -28,6 +33,7 @@ import (
 	"github.com/juju/juju/core/model"
 	"github.com/juju/juju/core/status"
 	"github.com/juju/juju/core/watcher"
+	"github.com/juju/juju/feature"
 	jworker "github.com/juju/juju/worker"
 	"github.com/juju/juju/worker/fortress"
 	"github.com/juju/juju/worker/uniter/actions"
@@ -82,6 +88,15 @@ type Uniter struct {
 	clock     clock.Clock
 
 	relationStateTracker relation.RelationStateTracker
+	// relationStateTrackerMutex guards the relationStateTracker
+	// field, which is read by Report from outside the uniter loop.
+	relationStateTrackerMutex sync.Mutex
+
+	// idleReason is the reason the resolver last gave for having
+	// no operation to run, guarded by idleReasonMutex as it is
+	// read by Report.
+	idleReason      *resolver.NoOperationReason
+	idleReasonMutex sync.Mutex
 
 	// Cache the last reported status information
 	// so we don't make unnecessary api calls.
@@ -127,6 +142,10 @@ type Uniter struct {
 	// runningStatusFunc used to determine the unit's running status.
 	runningStatusFunc remotestate.RunningStatusFunc
 
+	// relationInjector, if set, delivers synthetic relation changes
+	// to the remote state watcher. It is only used to test charms.
+	relationInjector *remotestate.Injector
+
 	// hookRetryStrategy represents configuration for hook retries
 	hookRetryStrategy params.RetryStrategy
 
@@ -160,6 +179,7 @@ type UniterParams struct {
 	RunningStatusChannel    watcher.NotifyChannel
 	RunningStatusFunc       remotestate.RunningStatusFunc
 	SocketConfig            *SocketConfig
+	RelationInjector        *remotestate.Injector
 	// TODO (mattyw, wallyworld, fwereade) Having the observer here make this approach a bit more legitimate, but it isn't.
 	// the observer is only a stop gap to be used in tests. A better approach would be to have the uniter tests start hooks
 	// that write to files, and have the tests watch the output to know that hooks have finished.
@@ -220,6 +240,7 @@ func newUniter(uniterParams *UniterParams) func() (worker.Worker, error) {
 		applicationChannel:      uniterParams.ApplicationChannel,
 		runningStatusChannel:    uniterParams.RunningStatusChannel,
 		runningStatusFunc:       uniterParams.RunningStatusFunc,
+		relationInjector:        uniterParams.RelationInjector,
 		runListener:             uniterParams.RunListener,
 		rebootQuerier:           uniterParams.RebootQuerier,
 	}
@@ -315,12 +336,76 @@ func (u *Uniter) loop(unitTag names.UnitTag) (err error) {
 		},
 		Clock: u.clock,
 	})
+	// Relation endpoints may override the retry strategy
+	// with their own backoff, using a one-shot timer.
+	var (
+		relationRetryTimer   clock.Timer
+		relationRetryTimerMu sync.Mutex
+	)
+	startRelationRetryTimer := func(delay time.Duration) {
+		relationRetryTimerMu.Lock()
+		defer relationRetryTimerMu.Unlock()
+		if relationRetryTimer != nil {
+			relationRetryTimer.Stop()
+		}
+		relationRetryTimer = u.clock.AfterFunc(delay, func() {
+			select {
+			case retryHookChan <- struct{}{}:
+			default:
+			}
+		})
+	}
+	stopRetryHookTimers := func() {
+		retryHookTimer.Reset()
+		relationRetryTimerMu.Lock()
+		defer relationRetryTimerMu.Unlock()
+		if relationRetryTimer != nil {
+			relationRetryTimer.Stop()
+			relationRetryTimer = nil
+		}
+	}
 	defer func() {
 		// Whenever we exit the uniter we want to stop a potentially
 		// running timer so it doesn't trigger for nothing.
-		retryHookTimer.Reset()
+		stopRetryHookTimers()
 	}()
 
+	// Relation endpoints may hold back changes to the suspension of
+	// their relations until they stabilise; a one-shot timer wakes
+	// the remote state watcher when the first of them is due.
+	relationSuspensionChan := make(chan struct{}, 1)
+	var (
+		relationSuspensionTimer   clock.Timer
+		relationSuspensionTimerMu sync.Mutex
+	)
+	startRelationSuspensionTimer := func(delay time.Duration) {
+		relationSuspensionTimerMu.Lock()
+		defer relationSuspensionTimerMu.Unlock()
+		if relationSuspensionTimer != nil {
+			relationSuspensionTimer.Stop()
+		}
+		relationSuspensionTimer = u.clock.AfterFunc(delay, func() {
+			select {
+			case relationSuspensionChan <- struct{}{}:
+			default:
+			}
+		})
+	}
+	defer func() {
+		relationSuspensionTimerMu.Lock()
+		defer relationSuspensionTimerMu.Unlock()
+		if relationSuspensionTimer != nil {
+			relationSuspensionTimer.Stop()
+		}
+	}()
+	suspensionDamper, err := relation.NewSuspensionDamper(
+		filepath.Join(u.paths.State.RelationsDir, relation.SuspensionFile),
+		u.clock, u.relationSuspensionWindow,
+	)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
 	restartWatcher := func() error {
 		watcherMu.Lock()
 		defer watcherMu.Unlock()
@@ -332,16 +417,18 @@ func (u *Uniter) loop(unitTag names.UnitTag) (err error) {
 		var err error
 		watcher, err = remotestate.NewWatcher(
 			remotestate.WatcherConfig{
-				State:                remotestate.NewAPIState(u.st),
-				LeadershipTracker:    u.leadershipTracker,
-				UnitTag:              unitTag,
-				UpdateStatusChannel:  u.updateStatusAt,
-				CommandChannel:       u.commandChannel,
-				RetryHookChannel:     retryHookChan,
-				ApplicationChannel:   u.applicationChannel,
-				RunningStatusChannel: u.runningStatusChannel,
-				RunningStatusFunc:    u.runningStatusFunc,
-				ModelType:            u.modelType,
+				State:                     remotestate.NewAPIState(u.st),
+				LeadershipTracker:         u.leadershipTracker,
+				UnitTag:                   unitTag,
+				UpdateStatusChannel:       u.updateStatusAt,
+				CommandChannel:            u.commandChannel,
+				RetryHookChannel:          retryHookChan,
+				RelationSuspensionChannel: relationSuspensionChan,
+				ApplicationChannel:        u.applicationChannel,
+				RunningStatusChannel:      u.runningStatusChannel,
+				RunningStatusFunc:         u.runningStatusFunc,
+				ModelType:                 u.modelType,
+				RelationInjector:          u.relationInjector,
 			})
 		if err != nil {
 			return errors.Trace(err)
@@ -361,6 +448,11 @@ func (u *Uniter) loop(unitTag names.UnitTag) (err error) {
 			// error state.
 			return nil
 		}
+		if len(opState.QuarantinedHooks) > 0 {
+			// Quarantined hooks are still failed hooks,
+			// waiting to be resolved by the operator.
+			return u.reportQuarantinedHooks(opState.QuarantinedHooks)
+		}
 		return setAgentStatus(u, status.Idle, "", nil)
 	}
 
@@ -398,19 +490,40 @@ func (u *Uniter) loop(unitTag names.UnitTag) (err error) {
 			break
 		}
 
+		relationOptions := []relation.ResolverOption{
+			relation.WithSettingsPrefetch(),
+			relation.WithSuspensionDamping(suspensionDamper, startRelationSuspensionTimer),
+		}
+		if featureflag.Enabled(feature.RelationGoodbyeData) {
+			relationOptions = append(relationOptions, relation.WithGoodbyeData())
+		}
+		if featureflag.Enabled(feature.RelationReconciliation) {
+			relationOptions = append(relationOptions, relation.WithReconciliation())
+		}
+		// The loop is restarted when the charm is upgraded,
+		// so the dependencies of the current charm are read.
+		dataDependencies, depsErr := relation.ReadDataDependencies(u.paths.State.CharmDir)
+		if depsErr != nil {
+			logger.Warningf("ignoring relation data dependencies: %v", depsErr)
+		} else if len(dataDependencies) > 0 {
+			relationOptions = append(relationOptions, relation.WithDataDependencies(dataDependencies))
+		}
+
 		cfg := ResolverConfig{
-			ModelType:           u.modelType,
-			ClearResolved:       clearResolved,
-			ReportHookError:     u.reportHookError,
-			ShouldRetryHooks:    u.hookRetryStrategy.ShouldRetry,
-			StartRetryHookTimer: retryHookTimer.Start,
-			StopRetryHookTimer:  retryHookTimer.Reset,
-			Actions:             actions.NewResolver(),
-			UpgradeSeries:       upgradeseries.NewResolver(),
-			Leadership:          uniterleadership.NewResolver(),
-			CreatedRelations:    relation.NewCreatedRelationResolver(u.relationStateTracker),
-			Relations:           relation.NewRelationResolver(u.relationStateTracker, u.unit),
-			Storage:             storage.NewResolver(u.storage, u.modelType),
+			ModelType:               u.modelType,
+			ClearResolved:           clearResolved,
+			ReportHookError:         u.reportHookError,
+			ShouldRetryHooks:        u.hookRetryStrategy.ShouldRetry,
+			StartRetryHookTimer:     retryHookTimer.Start,
+			StopRetryHookTimer:      stopRetryHookTimers,
+			RelationRetryPolicy:     u.relationRetryPolicy,
+			StartRelationRetryTimer: startRelationRetryTimer,
+			Actions:                 actions.NewResolver(),
+			UpgradeSeries:           upgradeseries.NewResolver(),
+			Leadership:              uniterleadership.NewResolver(),
+			CreatedRelations:        relation.NewCreatedRelationResolver(u.relationStateTracker),
+			Relations:               relation.NewRelationResolver(u.relationStateTracker, u.unit, relationOptions...),
+			Storage:                 storage.NewResolver(u.storage, u.modelType),
 			Commands: runcommands.NewCommandsResolver(
 				u.commands, watcher.CommandCompleted,
 			),
@@ -440,6 +553,7 @@ func (u *Uniter) loop(unitTag names.UnitTag) (err error) {
 				Abort:         u.catacomb.Dying(),
 				OnIdle:        onIdle,
 				CharmDirGuard: u.charmDirGuard,
+				OnNoOperation: u.setIdleReason,
 			}, &localState)
 
 			err = u.translateResolverErr(err)
@@ -590,7 +704,9 @@ func (u *Uniter) init(unitTag names.UnitTag) (err error) {
 	if err != nil {
 		return errors.Annotatef(err, "cannot create relation state tracker")
 	}
+	u.relationStateTrackerMutex.Lock()
 	u.relationStateTracker = relStateTracker
+	u.relationStateTrackerMutex.Unlock()
 	u.commands = runcommands.NewCommands()
 	u.commandChannel = make(chan string)
 
@@ -626,6 +742,10 @@ func (u *Uniter) init(unitTag names.UnitTag) (err error) {
 		Storage:          u.storage,
 		Paths:            u.paths,
 		Clock:            u.clock,
+
+		GetPrefetchedSettings: u.relationStateTracker.PrefetchedSettings,
+		GetStaleRelations:     u.relationStateTracker.StaleRelations,
+		PinSettings:           u.relationStateTracker.PinSettings,
 	})
 	if err != nil {
 		return err
@@ -673,6 +793,9 @@ func (u *Uniter) init(unitTag names.UnitTag) (err error) {
 	}
 
 	operationExecutor, err := u.newOperationExecutor(u.paths.State.OperationsFile, initialState, u.acquireExecutionLock)
+	if hook.IsUnknownKind(err) && featureflag.Enabled(feature.TolerateUnknownHooks) {
+		operationExecutor, err = u.setAsideUnknownHooks(initialState)
+	}
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -704,6 +827,22 @@ func (u *Uniter) init(unitTag names.UnitTag) (err error) {
 	return nil
 }
 
+// setAsideUnknownHooks sets aside the hooks of unknown kinds recorded in
+// the operation state file by a newer agent, so that the unit's agent can
+// be downgraded, and returns the executor created from the state that
+// remains. The hooks are preserved in the state file, but are not run.
+func (u *Uniter) setAsideUnknownHooks(initialState operation.State) (operation.Executor, error) {
+	path := u.paths.State.OperationsFile
+	unknown, err := operation.NewStateFile(path).SetAsideUnknownHooks()
+	if err != nil {
+		return nil, errors.Annotate(err, "setting aside unknown hooks")
+	}
+	for _, info := range unknown {
+		logger.Warningf("skipping %q hook, unknown to this agent, recorded in %q", info.Kind, path)
+	}
+	return u.newOperationExecutor(path, initialState, u.acquireExecutionLock)
+}
+
 func (u *Uniter) Kill() {
 	u.catacomb.Kill(nil)
 }
@@ -712,6 +851,37 @@ func (u *Uniter) Wait() error {
 	return u.catacomb.Wait()
 }
 
+// Report provides information for the engine report.
+func (u *Uniter) Report() map[string]interface{} {
+	u.relationStateTrackerMutex.Lock()
+	tracker := u.relationStateTracker
+	u.relationStateTrackerMutex.Unlock()
+
+	result := make(map[string]interface{})
+	if tracker != nil {
+		result["relations"] = tracker.Report()
+	}
+
+	u.idleReasonMutex.Lock()
+	idleReason := u.idleReason
+	u.idleReasonMutex.Unlock()
+	if idleReason != nil {
+		result["idle-reason"] = map[string]interface{}{
+			"code":   string(idleReason.Code),
+			"detail": idleReason.Detail,
+		}
+	}
+	return result
+}
+
+// setIdleReason records the reason the resolver last gave
+// for having no operation to run, for the engine report.
+func (u *Uniter) setIdleReason(reason *resolver.NoOperationReason) {
+	u.idleReasonMutex.Lock()
+	defer u.idleReasonMutex.Unlock()
+	u.idleReason = reason
+}
+
 func (u *Uniter) getApplicationCharmURL() (*corecharm.URL, error) {
 	// TODO(fwereade): pretty sure there's no reason to make 2 API calls here.
 	app, err := u.st.Application(u.unit.ApplicationTag())
@@ -747,6 +917,52 @@ func (u *Uniter) acquireExecutionLock(action string) (func(), error) {
 	return releaser, nil
 }
 
+// relationRetryPolicy returns the retry policy configured for the endpoint
+// of the relation with the supplied id, if any. Policies that cannot be
+// read are logged and ignored, so that the hook retry strategy applies.
+func (u *Uniter) relationRetryPolicy(relationId int) (relation.RetryPolicy, bool) {
+	endpoint, err := u.relationStateTracker.Name(relationId)
+	if err != nil {
+		logger.Warningf("cannot get retry policy for relation %d: %v", relationId, err)
+		return relation.RetryPolicy{}, false
+	}
+	settings, err := u.unit.ConfigSettings()
+	if err != nil {
+		logger.Warningf("cannot get retry policy for %q relation: %v", endpoint, err)
+		return relation.RetryPolicy{}, false
+	}
+	policy, ok, err := relation.RetryPolicyFromSettings(settings, endpoint)
+	if err != nil {
+		logger.Warningf("cannot get retry policy for %q relation: %v", endpoint, err)
+		return relation.RetryPolicy{}, false
+	}
+	return policy, ok
+}
+
+// relationSuspensionWindow returns the window for which changes to the
+// suspension of the relation with the supplied id are held back, as
+// configured for its endpoint. Windows that cannot be read are logged
+// and ignored, so that changes are acted on at once.
+func (u *Uniter) relationSuspensionWindow(relationId int) time.Duration {
+	endpoint, err := u.relationStateTracker.Name(relationId)
+	if err != nil {
+		// The relation is not yet known to the unit,
+		// so there is no change to hold back.
+		return 0
+	}
+	settings, err := u.unit.ConfigSettings()
+	if err != nil {
+		logger.Warningf("cannot get suspension window for %q relation: %v", endpoint, err)
+		return 0
+	}
+	window, err := relation.SuspensionWindowFromSettings(settings, endpoint)
+	if err != nil {
+		logger.Warningf("cannot get suspension window for %q relation: %v", endpoint, err)
+		return 0
+	}
+	return window
+}
+
 func (u *Uniter) reportHookError(hookInfo hook.Info) error {
 	// Set the agent status to "error". We must do this here in case the
 	// hook is interrupted (e.g. unit agent crashes), rather than immediately
@@ -768,3 +984,30 @@ func (u *Uniter) reportHookError(hookInfo hook.Info) error {
 	statusMessage := fmt.Sprintf("hook failed: %q", hookName)
 	return setAgentStatus(u, status.Error, statusMessage, statusData)
 }
+
+// reportQuarantinedHooks sets the agent status to "error", listing the
+// quarantined relation hooks, so that the operator can resolve them
+// while the unit's other relations progress.
+func (u *Uniter) reportQuarantinedHooks(quarantined map[int]hook.Info) error {
+	relationIds := make([]int, 0, len(quarantined))
+	for relationId := range quarantined {
+		relationIds = append(relationIds, relationId)
+	}
+	sort.Ints(relationIds)
+	hookNames := make([]string, len(relationIds))
+	quotedNames := make([]string, len(relationIds))
+	for i, relationId := range relationIds {
+		relationName, err := u.relationStateTracker.Name(relationId)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		hookNames[i] = fmt.Sprintf("%s-%s", relationName, quarantined[relationId].Kind)
+		quotedNames[i] = fmt.Sprintf("%q", hookNames[i])
+	}
+	statusData := map[string]interface{}{
+		"relation-ids": relationIds,
+		"hooks":        hookNames,
+	}
+	statusMessage := fmt.Sprintf("hooks quarantined: %s", strings.Join(quotedNames, ", "))
+	return setAgentStatus(u, status.Error, statusMessage, statusData)
+}
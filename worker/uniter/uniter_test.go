@@ -1105,7 +1105,7 @@ func (s *UniterSuite) TestUniterRelations(c *gc.C) {
 			stopUniter{},
 			custom{func(c *gc.C, ctx *context) {
 				// Check the state dir was created, and remove it.
-				path := fmt.Sprintf("state/relations/%d", ctx.relation.Id())
+				path := fmt.Sprintf("state/relations/%s", ctx.relation.Tag())
 				ft.Dir{path, 0755}.Check(c, ctx.path)
 				ft.Removed{path}.Create(c, ctx.path)
 
@@ -1121,7 +1121,7 @@ func (s *UniterSuite) TestUniterRelations(c *gc.C) {
 			waitHooks{"config-changed"},
 			custom{func(c *gc.C, ctx *context) {
 				// Check the state dir was recreated.
-				path := fmt.Sprintf("state/relations/%d", ctx.relation.Id())
+				path := fmt.Sprintf("state/relations/%s", ctx.relation.Tag())
 				ft.Dir{path, 0755}.Check(c, ctx.path)
 
 				// Check that config-changed did record the joined relations.
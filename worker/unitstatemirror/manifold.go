@@ -0,0 +1,123 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package unitstatemirror
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/worker.v1"
+	"gopkg.in/juju/worker.v1/dependency"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/worker/common"
+	workerstate "github.com/juju/juju/worker/state"
+)
+
+// mirrorDirName is the name of the directory, within the backup
+// directory, to which unit state is mirrored.
+const mirrorDirName = "juju-unit-state"
+
+// ManifoldConfig holds the information needed to run a unit state
+// mirror worker in a dependency.Engine.
+type ManifoldConfig struct {
+	StateName string
+	Logger    Logger
+	NewWorker func(Config) (worker.Worker, error)
+}
+
+// Validate validates the manifold configuration.
+func (config ManifoldConfig) Validate() error {
+	if config.StateName == "" {
+		return errors.NotValidf("empty StateName")
+	}
+	if config.Logger == nil {
+		return errors.NotValidf("nil Logger")
+	}
+	if config.NewWorker == nil {
+		return errors.NotValidf("nil NewWorker")
+	}
+	return nil
+}
+
+// Manifold returns a dependency.Manifold to run a unit state mirror
+// worker.
+func Manifold(config ManifoldConfig) dependency.Manifold {
+	return dependency.Manifold{
+		Inputs: []string{
+			config.StateName,
+		},
+		Start:  config.start,
+		Filter: bounceErrChanged,
+	}
+}
+
+func (config ManifoldConfig) start(context dependency.Context) (_ worker.Worker, err error) {
+	if err := config.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var stTracker workerstate.StateTracker
+	if err := context.Get(config.StateName, &stTracker); err != nil {
+		return nil, errors.Trace(err)
+	}
+	statePool, err := stTracker.Use()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer func() {
+		if err != nil {
+			stTracker.Done()
+		}
+	}()
+
+	st := statePool.SystemState()
+	w, err := config.NewWorker(Config{
+		Backend: st,
+		Logger:  config.Logger,
+		NewMirror: func() (Mirror, error) {
+			dir, err := mirrorDir(st)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			mirror, err := state.NewUnitStateMirror(st, dir)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return mirror, nil
+		},
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return common.NewCleanupWorker(w, func() { stTracker.Done() }), nil
+}
+
+// mirrorDir returns the directory to which unit state is mirrored, in
+// the backup directory configured for the controller model, so that it
+// sits alongside the backups from which the controller is restored.
+func mirrorDir(st *state.State) (string, error) {
+	model, err := st.Model()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	cfg, err := model.ModelConfig()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	backupDir := cfg.BackupDir()
+	if backupDir == "" {
+		backupDir = os.TempDir()
+	}
+	return filepath.Join(backupDir, mirrorDirName), nil
+}
+
+// bounceErrChanged converts ErrChanged to dependency.ErrBounce.
+func bounceErrChanged(err error) error {
+	if errors.Cause(err) == ErrChanged {
+		return dependency.ErrBounce
+	}
+	return err
+}
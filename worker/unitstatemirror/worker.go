@@ -0,0 +1,163 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package unitstatemirror
+
+import (
+	"github.com/juju/errors"
+	"gopkg.in/juju/worker.v1"
+	"gopkg.in/juju/worker.v1/catacomb"
+
+	"github.com/juju/juju/controller"
+	"github.com/juju/juju/state"
+)
+
+// ErrChanged indicates that the controller configuration has changed
+// whether unit state is mirrored, and the worker should be restarted.
+var ErrChanged = errors.New("unit state mirroring changed")
+
+// Logger represents the methods used by the worker to log details.
+type Logger interface {
+	Debugf(string, ...interface{})
+	Infof(string, ...interface{})
+}
+
+// Backend lets the worker follow the controller configuration and
+// changes to the unit state of all models. (Primary implementation
+// is State.)
+type Backend interface {
+	WatchControllerConfig() state.NotifyWatcher
+	ControllerConfig() (controller.Config, error)
+	WatchAllUnitStates() state.StringsWatcher
+}
+
+// Mirror writes changed unit state documents to the mirror. (Primary
+// implementation is *state.UnitStateMirror.)
+type Mirror interface {
+	Mirror(docIDs []string) (int, error)
+}
+
+// Config holds the dependencies and configuration for a Worker.
+type Config struct {
+	Backend Backend
+	Logger  Logger
+
+	// NewMirror returns the Mirror to which unit state is written.
+	// It is only called if mirroring is enabled.
+	NewMirror func() (Mirror, error)
+}
+
+// Validate returns an error if the config cannot be expected
+// to drive a functional Worker.
+func (config Config) Validate() error {
+	if config.Backend == nil {
+		return errors.NotValidf("nil Backend")
+	}
+	if config.Logger == nil {
+		return errors.NotValidf("nil Logger")
+	}
+	if config.NewMirror == nil {
+		return errors.NotValidf("nil NewMirror")
+	}
+	return nil
+}
+
+// NewWorker returns a worker that, while the controller configuration
+// enables it, mirrors the unit state documents of all models as they
+// change. The worker stops with ErrChanged when mirroring is enabled
+// or disabled.
+func NewWorker(config Config) (worker.Worker, error) {
+	if err := config.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	w := &mirrorWorker{config: config}
+	err := catacomb.Invoke(catacomb.Plan{
+		Site: &w.catacomb,
+		Work: w.loop,
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return w, nil
+}
+
+type mirrorWorker struct {
+	catacomb catacomb.Catacomb
+	config   Config
+}
+
+// Kill is part of the worker.Worker interface.
+func (w *mirrorWorker) Kill() {
+	w.catacomb.Kill(nil)
+}
+
+// Wait is part of the worker.Worker interface.
+func (w *mirrorWorker) Wait() error {
+	return w.catacomb.Wait()
+}
+
+func (w *mirrorWorker) loop() error {
+	backend := w.config.Backend
+	configWatcher := backend.WatchControllerConfig()
+	if err := w.catacomb.Add(configWatcher); err != nil {
+		return errors.Trace(err)
+	}
+	enabled, err := w.enabled()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	var (
+		mirror  Mirror
+		changes <-chan []string
+	)
+	if enabled {
+		mirror, err = w.config.NewMirror()
+		if err != nil {
+			return errors.Annotate(err, "creating unit state mirror")
+		}
+		unitStateWatcher := backend.WatchAllUnitStates()
+		if err := w.catacomb.Add(unitStateWatcher); err != nil {
+			return errors.Trace(err)
+		}
+		changes = unitStateWatcher.Changes()
+		w.config.Logger.Infof("mirroring unit state")
+	}
+
+	for {
+		select {
+		case <-w.catacomb.Dying():
+			return w.catacomb.ErrDying()
+		case _, ok := <-configWatcher.Changes():
+			if !ok {
+				return errors.New("controller config watcher closed")
+			}
+			nowEnabled, err := w.enabled()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if nowEnabled != enabled {
+				return ErrChanged
+			}
+		case docIDs, ok := <-changes:
+			if !ok {
+				return errors.New("unit state watcher closed")
+			}
+			written, err := mirror.Mirror(docIDs)
+			if err != nil {
+				return errors.Annotate(err, "mirroring unit state")
+			}
+			if written > 0 {
+				w.config.Logger.Debugf("mirrored state of %d units", written)
+			}
+		}
+	}
+}
+
+func (w *mirrorWorker) enabled() (bool, error) {
+	cfg, err := w.config.Backend.ControllerConfig()
+	if err != nil {
+		return false, errors.Annotate(err, "reading controller config")
+	}
+	return cfg.MirrorUnitState(), nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package unitstatemirror_test
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/loggo"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/worker.v1/workertest"
+
+	"github.com/juju/juju/controller"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/watcher/watchertest"
+	jujutesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/worker/unitstatemirror"
+)
+
+type workerSuite struct {
+	jujutesting.BaseSuite
+
+	backend *fakeBackend
+	mirror  *fakeMirror
+}
+
+var _ = gc.Suite(&workerSuite{})
+
+func (s *workerSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	s.backend = &fakeBackend{
+		configChanges:    make(chan struct{}, 1),
+		unitStateChanges: make(chan []string, 1),
+	}
+	s.mirror = &fakeMirror{mirrored: make(chan []string, 1)}
+}
+
+func (s *workerSuite) config() unitstatemirror.Config {
+	return unitstatemirror.Config{
+		Backend: s.backend,
+		Logger:  loggo.GetLogger("test"),
+		NewMirror: func() (unitstatemirror.Mirror, error) {
+			return s.mirror, nil
+		},
+	}
+}
+
+func (s *workerSuite) TestValidate(c *gc.C) {
+	config := s.config()
+	config.NewMirror = nil
+	_, err := unitstatemirror.NewWorker(config)
+	c.Assert(err, gc.ErrorMatches, "nil NewMirror not valid")
+}
+
+func (s *workerSuite) TestMirrorsChanges(c *gc.C) {
+	s.backend.setEnabled(true)
+	w, err := unitstatemirror.NewWorker(s.config())
+	c.Assert(err, jc.ErrorIsNil)
+	defer workertest.CleanKill(c, w)
+
+	s.backend.unitStateChanges <- []string{"uuid:u#mysql/0#charm"}
+	select {
+	case docIDs := <-s.mirror.mirrored:
+		c.Check(docIDs, jc.DeepEquals, []string{"uuid:u#mysql/0#charm"})
+	case <-time.After(jujutesting.LongWait):
+		c.Fatalf("timed out waiting for unit state to be mirrored")
+	}
+}
+
+func (s *workerSuite) TestDisabled(c *gc.C) {
+	w, err := unitstatemirror.NewWorker(s.config())
+	c.Assert(err, jc.ErrorIsNil)
+	defer workertest.CleanKill(c, w)
+
+	s.backend.unitStateChanges <- []string{"uuid:u#mysql/0#charm"}
+	select {
+	case <-s.mirror.mirrored:
+		c.Fatalf("unit state mirrored while disabled")
+	case <-time.After(jujutesting.ShortWait):
+	}
+	c.Check(s.backend.watchedUnitStates(), jc.IsFalse)
+}
+
+func (s *workerSuite) TestEnabledChanged(c *gc.C) {
+	w, err := unitstatemirror.NewWorker(s.config())
+	c.Assert(err, jc.ErrorIsNil)
+	defer workertest.DirtyKill(c, w)
+
+	// Unrelated changes to the controller config are ignored.
+	s.backend.configChanges <- struct{}{}
+	workertest.CheckAlive(c, w)
+
+	s.backend.setEnabled(true)
+	s.backend.configChanges <- struct{}{}
+	err = workertest.CheckKilled(c, w)
+	c.Assert(err, gc.Equals, unitstatemirror.ErrChanged)
+}
+
+type fakeBackend struct {
+	mu               sync.Mutex
+	enabled          bool
+	watched          bool
+	configChanges    chan struct{}
+	unitStateChanges chan []string
+}
+
+func (b *fakeBackend) setEnabled(enabled bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.enabled = enabled
+}
+
+func (b *fakeBackend) watchedUnitStates() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.watched
+}
+
+func (b *fakeBackend) WatchControllerConfig() state.NotifyWatcher {
+	return watchertest.NewNotifyWatcher(b.configChanges)
+}
+
+func (b *fakeBackend) ControllerConfig() (controller.Config, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return controller.Config{controller.MirrorUnitState: b.enabled}, nil
+}
+
+func (b *fakeBackend) WatchAllUnitStates() state.StringsWatcher {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.watched = true
+	return watchertest.NewStringsWatcher(b.unitStateChanges)
+}
+
+type fakeMirror struct {
+	mirrored chan []string
+}
+
+func (m *fakeMirror) Mirror(docIDs []string) (int, error) {
+	m.mirrored <- docIDs
+	return len(docIDs), nil
+}
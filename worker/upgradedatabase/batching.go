@@ -0,0 +1,161 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgradedatabase
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/juju/state"
+)
+
+// The limits within which the adaptive batcher sizes the batches
+// of documents rewritten by upgrade steps.
+const (
+	minBatchSize     = 10
+	maxBatchSize     = 5000
+	initialBatchSize = 100
+
+	// maxBatchPause is the longest pause between batches.
+	maxBatchPause = 10 * time.Second
+)
+
+// The load beyond which the adaptive batcher backs off. The batcher
+// grows its batches again once the load is below half of each limit.
+const (
+	// targetBatchTime is the longest that writing a batch should take.
+	targetBatchTime = time.Second
+
+	// maxReplicationLag is the furthest that a secondary
+	// should fall behind the primary.
+	maxReplicationLag = 5 * time.Second
+
+	// maxWriteLatency is the longest that the database
+	// should take, on average, for a write operation.
+	maxWriteLatency = 100 * time.Millisecond
+)
+
+// adaptiveBatcher is a state.UpgradeBatcher that adjusts the size of
+// the batches in which upgrade steps rewrite documents, and the pause
+// between them, according to the load on the database. After each
+// batch it reads the replication lag and write latency reported by
+// the database; if either, or the time taken by the batch, is too
+// high, it halves the batch size and lengthens the pause. While the
+// database copes comfortably, it grows the batches and shortens the
+// pause. This keeps the controller responsive while steps rewrite
+// large numbers of documents.
+type adaptiveBatcher struct {
+	pool   Pool
+	logger Logger
+
+	mu    sync.Mutex
+	size  int
+	pause time.Duration
+
+	// The write latency counters read after the previous batch,
+	// from which the average latency of each batch is calculated.
+	sampled   bool
+	latencies time.Duration
+	writes    int64
+
+	// The figures reported for the most recent batch,
+	// and the totals across all batches.
+	took    time.Duration
+	lag     time.Duration
+	latency time.Duration
+	batches int
+	docs    int
+}
+
+var _ state.UpgradeBatcher = (*adaptiveBatcher)(nil)
+
+func newAdaptiveBatcher(pool Pool, logger Logger) *adaptiveBatcher {
+	return &adaptiveBatcher{
+		pool:   pool,
+		logger: logger,
+		size:   initialBatchSize,
+	}
+}
+
+// BatchSize is part of the state.UpgradeBatcher interface.
+func (b *adaptiveBatcher) BatchSize() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.size
+}
+
+// BatchWritten is part of the state.UpgradeBatcher interface.
+func (b *adaptiveBatcher) BatchWritten(docs int, took time.Duration) time.Duration {
+	// Metrics that cannot be read are disregarded;
+	// the time taken by the batch still counts.
+	lag, err := b.pool.ReplicationLag()
+	if err != nil {
+		b.logger.Debugf("cannot read database replication lag: %v", err)
+	}
+	latencies, writes, err := b.pool.WriteLatency()
+	if err != nil {
+		b.logger.Debugf("cannot read database write latency: %v", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var latency time.Duration
+	if err == nil {
+		if b.sampled && writes > b.writes {
+			latency = (latencies - b.latencies) / time.Duration(writes-b.writes)
+		}
+		b.sampled = true
+		b.latencies = latencies
+		b.writes = writes
+	}
+	b.took = took
+	b.lag = lag
+	b.latency = latency
+	b.batches++
+	b.docs += docs
+
+	switch {
+	case lag > maxReplicationLag || latency > maxWriteLatency || took > targetBatchTime:
+		b.size /= 2
+		if b.size < minBatchSize {
+			b.size = minBatchSize
+		}
+		b.pause *= 2
+		if b.pause < took {
+			b.pause = took
+		}
+		if b.pause > maxBatchPause {
+			b.pause = maxBatchPause
+		}
+		b.logger.Debugf("database under load (lag %v, write latency %v, batch took %v); batch size now %d, pausing %v",
+			lag, latency, took, b.size, b.pause)
+	case lag < maxReplicationLag/2 && latency < maxWriteLatency/2 && took < targetBatchTime/2:
+		b.size += b.size / 4
+		if b.size > maxBatchSize {
+			b.size = maxBatchSize
+		}
+		b.pause /= 2
+	}
+	return b.pause
+}
+
+// report returns the batching of the documents rewritten by upgrade
+// steps, or nil if no documents have been rewritten.
+func (b *adaptiveBatcher) report() map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.batches == 0 {
+		return nil
+	}
+	return map[string]interface{}{
+		"batch-size":      b.size,
+		"pause":           b.pause.String(),
+		"batches":         b.batches,
+		"documents":       b.docs,
+		"last-batch-time": b.took.String(),
+		"replication-lag": b.lag.String(),
+		"write-latency":   b.latency.String(),
+	}
+}
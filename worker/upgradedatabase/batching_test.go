@@ -0,0 +1,131 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgradedatabase_test
+
+import (
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/worker/upgradedatabase"
+	. "github.com/juju/juju/worker/upgradedatabase/mocks"
+)
+
+type batchingSuite struct {
+	testing.IsolationSuite
+
+	pool   *MockPool
+	logger *MockLogger
+}
+
+var _ = gc.Suite(&batchingSuite{})
+
+func (s *batchingSuite) setupMocks(c *gc.C) *gomock.Controller {
+	ctrl := gomock.NewController(c)
+	s.pool = NewMockPool(ctrl)
+	s.logger = NewMockLogger(ctrl)
+	s.logger.EXPECT().Debugf(gomock.Any(), gomock.Any()).AnyTimes()
+	return ctrl
+}
+
+// expectLoad sets expectations for the database reporting the
+// replication lag, and a write latency per operation, after a batch.
+func (s *batchingSuite) expectLoad(lag, latency time.Duration, ops *int64) {
+	s.pool.EXPECT().ReplicationLag().Return(lag, nil)
+	s.pool.EXPECT().WriteLatency().DoAndReturn(func() (time.Duration, int64, error) {
+		*ops += 100
+		return time.Duration(*ops) * latency, *ops, nil
+	})
+}
+
+func (s *batchingSuite) TestGrowsWhileDatabaseIsIdle(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	batcher, report := upgradedatabase.NewAdaptiveBatcher(s.pool, s.logger)
+	c.Check(report(), gc.IsNil)
+	c.Assert(batcher.BatchSize(), gc.Equals, 100)
+
+	var ops int64
+	s.expectLoad(0, time.Millisecond, &ops)
+	pause := batcher.BatchWritten(100, 10*time.Millisecond)
+	c.Check(pause, gc.Equals, time.Duration(0))
+	c.Check(batcher.BatchSize(), gc.Equals, 125)
+
+	s.expectLoad(time.Second, time.Millisecond, &ops)
+	pause = batcher.BatchWritten(125, 10*time.Millisecond)
+	c.Check(pause, gc.Equals, time.Duration(0))
+	c.Check(batcher.BatchSize(), gc.Equals, 156)
+
+	c.Check(report(), jc.DeepEquals, map[string]interface{}{
+		"batch-size":      156,
+		"pause":           "0s",
+		"batches":         2,
+		"documents":       225,
+		"last-batch-time": "10ms",
+		"replication-lag": "1s",
+		"write-latency":   "1ms",
+	})
+}
+
+func (s *batchingSuite) TestBacksOffOnReplicationLag(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	batcher, _ := upgradedatabase.NewAdaptiveBatcher(s.pool, s.logger)
+
+	var ops int64
+	s.expectLoad(10*time.Second, time.Millisecond, &ops)
+	pause := batcher.BatchWritten(100, 200*time.Millisecond)
+	c.Check(pause, gc.Equals, 200*time.Millisecond)
+	c.Check(batcher.BatchSize(), gc.Equals, 50)
+
+	s.expectLoad(10*time.Second, time.Millisecond, &ops)
+	pause = batcher.BatchWritten(50, 100*time.Millisecond)
+	c.Check(pause, gc.Equals, 400*time.Millisecond)
+	c.Check(batcher.BatchSize(), gc.Equals, 25)
+
+	// Once the secondaries catch up, the pause shortens again.
+	s.expectLoad(0, time.Millisecond, &ops)
+	pause = batcher.BatchWritten(25, 100*time.Millisecond)
+	c.Check(pause, gc.Equals, 200*time.Millisecond)
+	c.Check(batcher.BatchSize(), gc.Equals, 31)
+}
+
+func (s *batchingSuite) TestBacksOffOnWriteLatency(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	batcher, _ := upgradedatabase.NewAdaptiveBatcher(s.pool, s.logger)
+
+	// The first batch establishes the latency counters.
+	var ops int64
+	s.expectLoad(0, time.Second, &ops)
+	batcher.BatchWritten(100, 10*time.Millisecond)
+	c.Assert(batcher.BatchSize(), gc.Equals, 125)
+
+	s.expectLoad(0, time.Second, &ops)
+	pause := batcher.BatchWritten(125, 10*time.Millisecond)
+	c.Check(pause, gc.Equals, 10*time.Millisecond)
+	c.Check(batcher.BatchSize(), gc.Equals, 62)
+}
+
+func (s *batchingSuite) TestBacksOffOnSlowBatch(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	batcher, _ := upgradedatabase.NewAdaptiveBatcher(s.pool, s.logger)
+
+	s.pool.EXPECT().ReplicationLag().Return(time.Duration(0), errors.New("boom")).Times(3)
+	s.pool.EXPECT().WriteLatency().Return(time.Duration(0), int64(0), errors.New("boom")).Times(3)
+
+	pause := batcher.BatchWritten(100, 30*time.Second)
+	c.Check(pause, gc.Equals, 10*time.Second)
+	c.Check(batcher.BatchSize(), gc.Equals, 50)
+
+	batcher.BatchWritten(50, 30*time.Second)
+	pause = batcher.BatchWritten(25, 30*time.Second)
+	c.Check(pause, gc.Equals, 10*time.Second)
+	c.Check(batcher.BatchSize(), gc.Equals, 12)
+}
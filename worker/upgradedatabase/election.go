@@ -0,0 +1,127 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgradedatabase
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/tomb.v2"
+
+	"github.com/juju/juju/core/status"
+	"github.com/juju/juju/upgrades"
+)
+
+const (
+	// electionTimeout is the longest that the worker waits for a Mongo
+	// primary to be elected after the primary steps down while the
+	// upgrade steps are run.
+	electionTimeout = 2 * time.Minute
+
+	// electionPollInterval is how often the worker
+	// checks whether a primary has been elected.
+	electionPollInterval = 5 * time.Second
+
+	// maxElectionResumes is the number of times that the upgrade steps
+	// are resumed after elections within a single upgrade attempt.
+	maxElectionResumes = 3
+)
+
+// primaryMovedError is returned when the Mongo primary steps down while
+// the upgrade steps are run, and another controller is elected primary.
+type primaryMovedError struct {
+	step string
+}
+
+// Error is part of the error interface.
+func (e *primaryMovedError) Error() string {
+	return fmt.Sprintf("mongo primary moved to another controller during step %q", e.step)
+}
+
+// isPrimaryMoved returns true if the error is a primaryMovedError.
+func isPrimaryMoved(err error) bool {
+	_, ok := errors.Cause(err).(*primaryMovedError)
+	return ok
+}
+
+// resumeAfterElection resumes the upgrade steps from the step that failed
+// with the input error, if it failed because the Mongo primary stepped
+// down, once a primary has been elected. The failed step is the last one
+// reported to the worker as started; the steps before it completed, so
+// are not run again. The steps are only resumed if this controller is
+// elected again; otherwise a *primaryMovedError is returned, as the steps
+// must only be run against the primary. Any other error, including that
+// of a resumed step failing for another reason, is returned so that the
+// upgrade attempt is retried or failed as usual.
+// The agent config is not held while waiting for the election.
+func (w *upgradeDB) resumeAfterElection(upgradeErr error) error {
+	for i := 0; i < maxElectionResumes; i++ {
+		_, cause := upgrades.FailedStep(upgradeErr)
+		step, _ := w.progress.currentStep()
+		if step == "" || !w.pool.IsPrimaryChange(cause) {
+			return upgradeErr
+		}
+		w.recordError(upgradeErr)
+		w.logger.Infof("mongo primary stepped down during upgrade step %q, waiting for election", step)
+		w.setPhase(phaseElecting)
+		w.setStatus(status.Started, fmt.Sprintf("waiting for mongo primary election during database upgrade to %v", w.toVersion))
+
+		isPrimary, err := w.awaitElection()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !isPrimary {
+			return &primaryMovedError{step: step}
+		}
+
+		w.logger.Infof("resuming database upgrade to %v from step %q", w.toVersion, step)
+		w.setPhase(phaseRunning)
+		w.setStatus(status.Started, fmt.Sprintf("upgrading database to %v", w.toVersion))
+		upgradeErr = w.performStage(step)
+	}
+	return upgradeErr
+}
+
+// awaitElection waits for a Mongo primary to be elected, then returns
+// whether it runs on this controller. The connections to the replica set
+// are refreshed before each check, as a change of primary leaves them in
+// error. An error is returned if no primary is elected within the
+// election timeout, or if the worker is stopped while waiting.
+func (w *upgradeDB) awaitElection() (bool, error) {
+	timeout := w.clock.After(electionTimeout)
+	for {
+		select {
+		case <-w.clock.After(electionPollInterval):
+		case <-timeout:
+			return false, errors.Errorf("timed out waiting for mongo primary election")
+		case <-w.tomb.Dying():
+			return false, tomb.ErrDying
+		}
+
+		w.pool.Refresh()
+		elected, err := w.pool.PrimaryElected()
+		if err != nil {
+			w.logger.Debugf("checking for mongo primary election: %v", err)
+			continue
+		}
+		if !elected {
+			continue
+		}
+		isPrimary, err := w.pool.IsPrimary(w.tag.Id())
+		if err != nil {
+			w.logger.Debugf("checking for mongo primary election: %v", err)
+			continue
+		}
+		return isPrimary, nil
+	}
+}
+
+// reportPrimaryMoved reports that the upgrade steps could not be resumed
+// because another controller was elected Mongo primary.
+func (w *upgradeDB) reportPrimaryMoved(err error) {
+	w.logger.Errorf("database upgrade from %v to %v failed: %v", w.fromVersion, w.toVersion, err)
+	w.setPhase(phaseFailed)
+	w.setStatus(status.Error, fmt.Sprintf("upgrading database to %v: %v", w.toVersion, err))
+}
@@ -0,0 +1,176 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgradedatabase
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/upgrades"
+)
+
+// estimate tracks how long each of the upgrade steps is estimated to
+// take, and how long those that have completed actually took, so that
+// the estimated completion of the upgrade is refined as it progresses.
+// The remaining estimates are scaled by the ratio of the time taken by
+// the completed steps to the time estimated for them, so that an
+// estimate that proves optimistic for early steps is corrected for the
+// later ones.
+type estimate struct {
+	mu    sync.Mutex
+	steps []state.UpgradeStepEstimate
+
+	// current is the index of the step being run,
+	// or -1 if no step has been started, and
+	// stepStarted is when that step started.
+	current     int
+	stepStarted time.Time
+
+	// taken is the time taken by the steps completed in this
+	// attempt, and estimated the time estimated for them.
+	taken     time.Duration
+	estimated time.Duration
+
+	completion time.Time
+}
+
+// set records the estimated duration of each of the steps, returning
+// the estimate to be published.
+func (e *estimate) set(steps []upgrades.StepEstimate, now time.Time) ([]state.UpgradeStepEstimate, time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.steps = make([]state.UpgradeStepEstimate, len(steps))
+	for i, step := range steps {
+		e.steps[i] = state.UpgradeStepEstimate{
+			Description: step.Description,
+			Estimate:    step.Duration,
+		}
+	}
+	e.reset()
+	e.completion = e.completionFrom(now)
+	return e.published()
+}
+
+// startAttempt records the start of an attempt to run the upgrade
+// steps, which are all run again.
+func (e *estimate) startAttempt() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.reset()
+}
+
+func (e *estimate) reset() {
+	for i := range e.steps {
+		e.steps[i].Done = false
+	}
+	e.current = -1
+	e.taken = 0
+	e.estimated = 0
+}
+
+// startStep records that the step with the description has started,
+// and so that those before it have completed. It returns the updated
+// estimate to be published, and false if the step is not one of those
+// estimated.
+func (e *estimate) startStep(description string, now time.Time) ([]state.UpgradeStepEstimate, time.Time, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	next := -1
+	for i := e.current + 1; i < len(e.steps); i++ {
+		if e.steps[i].Description == description {
+			next = i
+			break
+		}
+	}
+	if next < 0 {
+		return nil, time.Time{}, false
+	}
+	e.completeCurrent(now)
+	// Steps passed over were skipped, and take no time.
+	for i := e.current + 1; i < next; i++ {
+		e.steps[i].Done = true
+	}
+	e.current = next
+	e.stepStarted = now
+	e.completion = e.completionFrom(now)
+	steps, completion := e.published()
+	return steps, completion, true
+}
+
+// finish records that all of the steps have completed, returning the
+// final estimate to be published, and false if there is no estimate.
+func (e *estimate) finish(now time.Time) ([]state.UpgradeStepEstimate, time.Time, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.steps) == 0 {
+		return nil, time.Time{}, false
+	}
+	e.completeCurrent(now)
+	for i := range e.steps {
+		e.steps[i].Done = true
+	}
+	e.current = len(e.steps) - 1
+	e.completion = now
+	steps, completion := e.published()
+	return steps, completion, true
+}
+
+func (e *estimate) completeCurrent(now time.Time) {
+	if e.current < 0 || e.steps[e.current].Done {
+		return
+	}
+	e.steps[e.current].Done = true
+	e.taken += now.Sub(e.stepStarted)
+	e.estimated += e.steps[e.current].Estimate
+}
+
+// completionFrom returns when the steps that have not completed are
+// estimated to complete, scaling their estimates by how long those
+// that have completed took, relative to their estimates.
+func (e *estimate) completionFrom(now time.Time) time.Time {
+	var remaining time.Duration
+	for _, step := range e.steps {
+		if !step.Done {
+			remaining += step.Estimate
+		}
+	}
+	if e.estimated > 0 && e.taken > 0 {
+		remaining = time.Duration(float64(remaining) * float64(e.taken) / float64(e.estimated))
+	}
+	return now.Add(remaining)
+}
+
+func (e *estimate) published() ([]state.UpgradeStepEstimate, time.Time) {
+	steps := make([]state.UpgradeStepEstimate, len(e.steps))
+	copy(steps, e.steps)
+	return steps, e.completion
+}
+
+// report returns the estimated completion of the upgrade steps, with
+// the time remaining calculated relative to now, or nil if there is
+// no estimate.
+func (e *estimate) report(now time.Time) map[string]interface{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.steps) == 0 {
+		return nil
+	}
+	var done int
+	for _, step := range e.steps {
+		if step.Done {
+			done++
+		}
+	}
+	remaining := e.completion.Sub(now)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return map[string]interface{}{
+		"estimated-completion": e.completion.Format(time.RFC3339),
+		"remaining":            remaining.Round(time.Second).String(),
+		"steps":                len(e.steps),
+		"steps-done":           done,
+	}
+}
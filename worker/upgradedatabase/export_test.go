@@ -0,0 +1,15 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgradedatabase
+
+import (
+	"github.com/juju/juju/state"
+)
+
+// NewAdaptiveBatcher returns an adaptive state.UpgradeBatcher for
+// testing, with a function returning its report.
+func NewAdaptiveBatcher(pool Pool, logger Logger) (state.UpgradeBatcher, func() map[string]interface{}) {
+	b := newAdaptiveBatcher(pool, logger)
+	return b, b.report
+}
@@ -4,6 +4,7 @@
 package upgradedatabase
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/juju/errors"
@@ -13,6 +14,7 @@ import (
 	"gopkg.in/juju/worker.v1/dependency"
 
 	"github.com/juju/juju/agent"
+	"github.com/juju/juju/mongo"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/upgrades"
 	"github.com/juju/juju/worker/gate"
@@ -76,22 +78,114 @@ func Manifold(cfg ManifoldConfig) dependency.Manifold {
 			}
 
 			// Wrap the upgrade steps execution so that we can generate a context lazily.
-			performUpgrade := func(v version.Number, t []upgrades.Target, c func() upgrades.Context) error {
-				return errors.Trace(upgrades.PerformStateUpgrade(v, t, c()))
+			performUpgrade := func(
+				v version.Number, t []upgrades.Target, stage upgrades.Stage, c func() upgrades.Context, observer upgrades.StepObserver,
+			) error {
+				return errors.Trace(upgrades.PerformStateUpgradeStage(v, t, stage, c(), observer))
+			}
+			resumeUpgrade := func(
+				v version.Number, t []upgrades.Target, stage upgrades.Stage, step string,
+				c func() upgrades.Context, observer upgrades.StepObserver,
+			) error {
+				return errors.Trace(upgrades.PerformResumedStateUpgrade(v, t, stage, step, c(), observer))
+			}
+			validateUpgrade := func(c func() upgrades.Context) error {
+				return errors.Trace(upgrades.ValidateStateUpgrade(c()))
+			}
+			rollbackUpgrade := func(
+				v version.Number, t []upgrades.Target, stage upgrades.Stage, upgradeErr error, c func() upgrades.Context,
+			) error {
+				return errors.Trace(upgrades.RollbackStateUpgrade(v, t, stage, upgradeErr, c()))
 			}
 
 			workerCfg := Config{
-				UpgradeComplete: upgradeStepsLock,
-				Tag:             tag,
-				Agent:           controllerAgent,
-				Logger:          cfg.Logger,
-				OpenState:       openState,
-				PerformUpgrade:  performUpgrade,
-				RetryStrategy:   utils.AttemptStrategy{Delay: 2 * time.Minute, Min: 5},
-				Clock:           cfg.Clock,
+				UpgradeComplete:       upgradeStepsLock,
+				Tag:                   tag,
+				Agent:                 controllerAgent,
+				Logger:                cfg.Logger,
+				OpenState:             openState,
+				PerformUpgrade:        performUpgrade,
+				ResumeUpgrade:         resumeUpgrade,
+				PreflightCheck:        upgrades.PreflightStateUpgrade,
+				StepCollections:       upgrades.StateUpgradeCollections,
+				EstimateUpgrade:       upgrades.EstimateStateUpgrade,
+				ValidateUpgrade:       validateUpgrade,
+				RollbackUpgrade:       rollbackUpgrade,
+				CheckSchemaDrift:      upgrades.CheckSchemaDrift,
+				GatedSteps:            upgrades.GatedSteps,
+				RunGatedSteps:         upgrades.RunGatedSteps,
+				StepPhases:            upgrades.StateUpgradePhases,
+				ContractSteps:         upgrades.ContractSteps,
+				RunContractSteps:      upgrades.RunContractSteps,
+				EnsureIndexes:         upgrades.EnsureIndexes,
+				SweepOrphans:          upgrades.SweepOrphans,
+				InstalledMongoVersion: installedMongoVersion,
+				RestartMongo:          restartMongo(controllerAgent),
+				RetryStrategy:         utils.AttemptStrategy{Delay: 2 * time.Minute, Min: 5},
+				StallTimeout:          30 * time.Minute,
+				RestartOnStall:        true,
+				Clock:                 cfg.Clock,
 			}
 			w, err := NewWorker(workerCfg)
 			return w, errors.Annotate(err, "starting database upgrade worker")
 		},
 	}
 }
+
+// installedMongoVersion returns the version of the mongod
+// installed on this controller, which its mongo server
+// runs once restarted.
+func installedMongoVersion() (mongo.Version, error) {
+	_, v, err := mongo.NewMongodFinder().FindBest()
+	return v, errors.Trace(err)
+}
+
+// restartMongo returns a function that restarts the mongo server on this
+// controller so that it runs the best mongod installed on it. Restarting
+// the mongo service as installed would run the mongod it was installed
+// with, so the service is first rewritten for the installed mongod, which
+// may be at a different path. The agent config records the mongo version
+// once the server has restarted.
+func restartMongo(controllerAgent agent.Agent) func() error {
+	return func() error {
+		_, installed, err := mongo.NewMongodFinder().FindBest()
+		if err != nil {
+			return errors.Annotate(err, "finding installed mongod")
+		}
+		agentConfig := controllerAgent.CurrentConfig()
+		si, ok := agentConfig.StateServingInfo()
+		if !ok {
+			return errors.Errorf("agent config has no state serving info")
+		}
+		var oplogSize int
+		if oplogSizeString := agentConfig.Value(agent.MongoOplogSize); oplogSizeString != "" {
+			if oplogSize, err = strconv.Atoi(oplogSizeString); err != nil {
+				return errors.Annotatef(err, "invalid oplog size: %q", oplogSizeString)
+			}
+		}
+		var numaCtlPolicy bool
+		if numaCtlString := agentConfig.Value(agent.NUMACtlPreference); numaCtlString != "" {
+			if numaCtlPolicy, err = strconv.ParseBool(numaCtlString); err != nil {
+				return errors.Annotatef(err, "invalid numactl preference: %q", numaCtlString)
+			}
+		}
+
+		if err := mongo.EnsureServiceInstalled(agentConfig.DataDir(),
+			si.StatePort,
+			oplogSize,
+			numaCtlPolicy,
+			installed,
+			true,
+			agentConfig.MongoMemoryProfile(),
+		); err != nil {
+			return errors.Annotatef(err, "rewriting mongo service for mongod %v", installed)
+		}
+		if err := mongo.ReStartService(); err != nil {
+			return errors.Trace(err)
+		}
+		return errors.Trace(controllerAgent.ChangeConfig(func(setter agent.ConfigSetter) error {
+			setter.SetMongoVersion(installed)
+			return nil
+		}))
+	}
+}
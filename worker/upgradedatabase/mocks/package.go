@@ -6,9 +6,12 @@ package mocks
 
 import (
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	controller "github.com/juju/juju/controller"
 	status "github.com/juju/juju/core/status"
+	mongo "github.com/juju/juju/mongo"
 	state "github.com/juju/juju/state"
 	upgradedatabase "github.com/juju/juju/worker/upgradedatabase"
 	version "github.com/juju/version"
@@ -125,6 +128,52 @@ func (mr *MockPoolMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockPool)(nil).Close))
 }
 
+// CollectionStats mocks base method
+func (m *MockPool) CollectionStats(arg0 string) (int64, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CollectionStats", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CollectionStats indicates an expected call of CollectionStats
+func (mr *MockPoolMockRecorder) CollectionStats(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CollectionStats", reflect.TypeOf((*MockPool)(nil).CollectionStats), arg0)
+}
+
+// ControllerConfig mocks base method
+func (m *MockPool) ControllerConfig() (controller.Config, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ControllerConfig")
+	ret0, _ := ret[0].(controller.Config)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ControllerConfig indicates an expected call of ControllerConfig
+func (mr *MockPoolMockRecorder) ControllerConfig() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ControllerConfig", reflect.TypeOf((*MockPool)(nil).ControllerConfig))
+}
+
+// ControllerIDs mocks base method
+func (m *MockPool) ControllerIDs() ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ControllerIDs")
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ControllerIDs indicates an expected call of ControllerIDs
+func (mr *MockPoolMockRecorder) ControllerIDs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ControllerIDs", reflect.TypeOf((*MockPool)(nil).ControllerIDs))
+}
+
 // EnsureUpgradeInfo mocks base method
 func (m *MockPool) EnsureUpgradeInfo(arg0 string, arg1, arg2 version.Number) (upgradedatabase.UpgradeInfo, error) {
 	m.ctrl.T.Helper()
@@ -140,6 +189,21 @@ func (mr *MockPoolMockRecorder) EnsureUpgradeInfo(arg0, arg1, arg2 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureUpgradeInfo", reflect.TypeOf((*MockPool)(nil).EnsureUpgradeInfo), arg0, arg1, arg2)
 }
 
+// FeatureCompatibilityVersion mocks base method
+func (m *MockPool) FeatureCompatibilityVersion() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FeatureCompatibilityVersion")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FeatureCompatibilityVersion indicates an expected call of FeatureCompatibilityVersion
+func (mr *MockPoolMockRecorder) FeatureCompatibilityVersion() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FeatureCompatibilityVersion", reflect.TypeOf((*MockPool)(nil).FeatureCompatibilityVersion))
+}
+
 // IsPrimary mocks base method
 func (m *MockPool) IsPrimary(arg0 string) (bool, error) {
 	m.ctrl.T.Helper()
@@ -155,6 +219,91 @@ func (mr *MockPoolMockRecorder) IsPrimary(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsPrimary", reflect.TypeOf((*MockPool)(nil).IsPrimary), arg0)
 }
 
+// IsPrimaryChange mocks base method
+func (m *MockPool) IsPrimaryChange(arg0 error) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsPrimaryChange", arg0)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsPrimaryChange indicates an expected call of IsPrimaryChange
+func (mr *MockPoolMockRecorder) IsPrimaryChange(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsPrimaryChange", reflect.TypeOf((*MockPool)(nil).IsPrimaryChange), arg0)
+}
+
+// MongoVersion mocks base method
+func (m *MockPool) MongoVersion() (mongo.Version, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MongoVersion")
+	ret0, _ := ret[0].(mongo.Version)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MongoVersion indicates an expected call of MongoVersion
+func (mr *MockPoolMockRecorder) MongoVersion() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MongoVersion", reflect.TypeOf((*MockPool)(nil).MongoVersion))
+}
+
+// PrimaryElected mocks base method
+func (m *MockPool) PrimaryElected() (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PrimaryElected")
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PrimaryElected indicates an expected call of PrimaryElected
+func (mr *MockPoolMockRecorder) PrimaryElected() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrimaryElected", reflect.TypeOf((*MockPool)(nil).PrimaryElected))
+}
+
+// Refresh mocks base method
+func (m *MockPool) Refresh() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Refresh")
+}
+
+// Refresh indicates an expected call of Refresh
+func (mr *MockPoolMockRecorder) Refresh() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockPool)(nil).Refresh))
+}
+
+// ReplicationLag mocks base method
+func (m *MockPool) ReplicationLag() (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplicationLag")
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReplicationLag indicates an expected call of ReplicationLag
+func (mr *MockPoolMockRecorder) ReplicationLag() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplicationLag", reflect.TypeOf((*MockPool)(nil).ReplicationLag))
+}
+
+// SetFeatureCompatibilityVersion mocks base method
+func (m *MockPool) SetFeatureCompatibilityVersion(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetFeatureCompatibilityVersion", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetFeatureCompatibilityVersion indicates an expected call of SetFeatureCompatibilityVersion
+func (mr *MockPoolMockRecorder) SetFeatureCompatibilityVersion(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFeatureCompatibilityVersion", reflect.TypeOf((*MockPool)(nil).SetFeatureCompatibilityVersion), arg0)
+}
+
 // SetStatus mocks base method
 func (m *MockPool) SetStatus(arg0 string, arg1 status.Status, arg2 string) error {
 	m.ctrl.T.Helper()
@@ -169,6 +318,77 @@ func (mr *MockPoolMockRecorder) SetStatus(arg0, arg1, arg2 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStatus", reflect.TypeOf((*MockPool)(nil).SetStatus), arg0, arg1, arg2)
 }
 
+// SetUpgradeBatcher mocks base method
+func (m *MockPool) SetUpgradeBatcher(arg0 state.UpgradeBatcher) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetUpgradeBatcher", arg0)
+}
+
+// SetUpgradeBatcher indicates an expected call of SetUpgradeBatcher
+func (mr *MockPoolMockRecorder) SetUpgradeBatcher(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUpgradeBatcher", reflect.TypeOf((*MockPool)(nil).SetUpgradeBatcher), arg0)
+}
+
+// StepDownPrimary mocks base method
+func (m *MockPool) StepDownPrimary() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StepDownPrimary")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StepDownPrimary indicates an expected call of StepDownPrimary
+func (mr *MockPoolMockRecorder) StepDownPrimary() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StepDownPrimary", reflect.TypeOf((*MockPool)(nil).StepDownPrimary))
+}
+
+// WatchControllerConfig mocks base method
+func (m *MockPool) WatchControllerConfig() state.NotifyWatcher {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchControllerConfig")
+	ret0, _ := ret[0].(state.NotifyWatcher)
+	return ret0
+}
+
+// WatchControllerConfig indicates an expected call of WatchControllerConfig
+func (mr *MockPoolMockRecorder) WatchControllerConfig() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchControllerConfig", reflect.TypeOf((*MockPool)(nil).WatchControllerConfig))
+}
+
+// WriteCount mocks base method
+func (m *MockPool) WriteCount() (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteCount")
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteCount indicates an expected call of WriteCount
+func (mr *MockPoolMockRecorder) WriteCount() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteCount", reflect.TypeOf((*MockPool)(nil).WriteCount))
+}
+
+// WriteLatency mocks base method
+func (m *MockPool) WriteLatency() (time.Duration, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteLatency")
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// WriteLatency indicates an expected call of WriteLatency
+func (mr *MockPoolMockRecorder) WriteLatency() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteLatency", reflect.TypeOf((*MockPool)(nil).WriteLatency))
+}
+
 // MockUpgradeInfo is a mock of UpgradeInfo interface
 type MockUpgradeInfo struct {
 	ctrl     *gomock.Controller
@@ -192,6 +412,76 @@ func (m *MockUpgradeInfo) EXPECT() *MockUpgradeInfoMockRecorder {
 	return m.recorder
 }
 
+// Approved mocks base method
+func (m *MockUpgradeInfo) Approved() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Approved")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Approved indicates an expected call of Approved
+func (mr *MockUpgradeInfoMockRecorder) Approved() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Approved", reflect.TypeOf((*MockUpgradeInfo)(nil).Approved))
+}
+
+// AwaitingApproval mocks base method
+func (m *MockUpgradeInfo) AwaitingApproval() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AwaitingApproval")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// AwaitingApproval indicates an expected call of AwaitingApproval
+func (mr *MockUpgradeInfoMockRecorder) AwaitingApproval() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AwaitingApproval", reflect.TypeOf((*MockUpgradeInfo)(nil).AwaitingApproval))
+}
+
+// ControllersRestarted mocks base method
+func (m *MockUpgradeInfo) ControllersRestarted() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ControllersRestarted")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// ControllersRestarted indicates an expected call of ControllersRestarted
+func (mr *MockUpgradeInfoMockRecorder) ControllersRestarted() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ControllersRestarted", reflect.TypeOf((*MockUpgradeInfo)(nil).ControllersRestarted))
+}
+
+// MongoTargetVersion mocks base method
+func (m *MockUpgradeInfo) MongoTargetVersion() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MongoTargetVersion")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// MongoTargetVersion indicates an expected call of MongoTargetVersion
+func (mr *MockUpgradeInfoMockRecorder) MongoTargetVersion() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MongoTargetVersion", reflect.TypeOf((*MockUpgradeInfo)(nil).MongoTargetVersion))
+}
+
+// MongoUpgradePhases mocks base method
+func (m *MockUpgradeInfo) MongoUpgradePhases() []state.MongoUpgradePhase {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MongoUpgradePhases")
+	ret0, _ := ret[0].([]state.MongoUpgradePhase)
+	return ret0
+}
+
+// MongoUpgradePhases indicates an expected call of MongoUpgradePhases
+func (mr *MockUpgradeInfoMockRecorder) MongoUpgradePhases() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MongoUpgradePhases", reflect.TypeOf((*MockUpgradeInfo)(nil).MongoUpgradePhases))
+}
+
 // Refresh mocks base method
 func (m *MockUpgradeInfo) Refresh() error {
 	m.ctrl.T.Helper()
@@ -206,6 +496,132 @@ func (mr *MockUpgradeInfoMockRecorder) Refresh() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockUpgradeInfo)(nil).Refresh))
 }
 
+// RestartOrder mocks base method
+func (m *MockUpgradeInfo) RestartOrder() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestartOrder")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// RestartOrder indicates an expected call of RestartOrder
+func (mr *MockUpgradeInfoMockRecorder) RestartOrder() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestartOrder", reflect.TypeOf((*MockUpgradeInfo)(nil).RestartOrder))
+}
+
+// SetAwaitingApproval mocks base method
+func (m *MockUpgradeInfo) SetAwaitingApproval() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAwaitingApproval")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetAwaitingApproval indicates an expected call of SetAwaitingApproval
+func (mr *MockUpgradeInfoMockRecorder) SetAwaitingApproval() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAwaitingApproval", reflect.TypeOf((*MockUpgradeInfo)(nil).SetAwaitingApproval))
+}
+
+// SetControllerRestarted mocks base method
+func (m *MockUpgradeInfo) SetControllerRestarted(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetControllerRestarted", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetControllerRestarted indicates an expected call of SetControllerRestarted
+func (mr *MockUpgradeInfoMockRecorder) SetControllerRestarted(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetControllerRestarted", reflect.TypeOf((*MockUpgradeInfo)(nil).SetControllerRestarted), arg0)
+}
+
+// SetEstimate mocks base method
+func (m *MockUpgradeInfo) SetEstimate(arg0 []state.UpgradeStepEstimate, arg1 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetEstimate", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetEstimate indicates an expected call of SetEstimate
+func (mr *MockUpgradeInfoMockRecorder) SetEstimate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetEstimate", reflect.TypeOf((*MockUpgradeInfo)(nil).SetEstimate), arg0, arg1)
+}
+
+// SetMongoUpgradePhaseDone mocks base method
+func (m *MockUpgradeInfo) SetMongoUpgradePhaseDone(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMongoUpgradePhaseDone", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMongoUpgradePhaseDone indicates an expected call of SetMongoUpgradePhaseDone
+func (mr *MockUpgradeInfoMockRecorder) SetMongoUpgradePhaseDone(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMongoUpgradePhaseDone", reflect.TypeOf((*MockUpgradeInfo)(nil).SetMongoUpgradePhaseDone), arg0)
+}
+
+// SetMongoUpgradePlan mocks base method
+func (m *MockUpgradeInfo) SetMongoUpgradePlan(arg0 string, arg1 []state.MongoUpgradePhase) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMongoUpgradePlan", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMongoUpgradePlan indicates an expected call of SetMongoUpgradePlan
+func (mr *MockUpgradeInfoMockRecorder) SetMongoUpgradePlan(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMongoUpgradePlan", reflect.TypeOf((*MockUpgradeInfo)(nil).SetMongoUpgradePlan), arg0, arg1)
+}
+
+// SetRestartOrder mocks base method
+func (m *MockUpgradeInfo) SetRestartOrder(arg0 []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRestartOrder", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRestartOrder indicates an expected call of SetRestartOrder
+func (mr *MockUpgradeInfoMockRecorder) SetRestartOrder(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRestartOrder", reflect.TypeOf((*MockUpgradeInfo)(nil).SetRestartOrder), arg0)
+}
+
+// SetStepCollections mocks base method
+func (m *MockUpgradeInfo) SetStepCollections(arg0 []state.UpgradeStepCollections) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetStepCollections", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetStepCollections indicates an expected call of SetStepCollections
+func (mr *MockUpgradeInfoMockRecorder) SetStepCollections(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStepCollections", reflect.TypeOf((*MockUpgradeInfo)(nil).SetStepCollections), arg0)
+}
+
+// SetStepPhases mocks base method
+func (m *MockUpgradeInfo) SetStepPhases(arg0 []state.UpgradeStepPhaseInfo) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetStepPhases", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetStepPhases indicates an expected call of SetStepPhases
+func (mr *MockUpgradeInfoMockRecorder) SetStepPhases(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStepPhases", reflect.TypeOf((*MockUpgradeInfo)(nil).SetStepPhases), arg0)
+}
+
 // SetStatus mocks base method
 func (m *MockUpgradeInfo) SetStatus(arg0 state.UpgradeStatus) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,308 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgradedatabase
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/core/status"
+	"github.com/juju/juju/mongo"
+	"github.com/juju/juju/state"
+)
+
+// mongoPhaseTimeout is the time the primary controller waits for another
+// controller to restart its mongo server, before the remaining phases of
+// the mongo upgrade are abandoned.
+const mongoPhaseTimeout = 10 * time.Minute
+
+// minFeatureCompatibilityVersion is the oldest
+// mongo with a feature compatibility version.
+var minFeatureCompatibilityVersion = mongo.Version{Major: 3, Minor: 4}
+
+// upgradeMongo upgrades the mongo servers to the version of the mongod
+// installed alongside the primary controller, once the database upgrade
+// steps have run. The upgrade is planned as phases recorded in the
+// upgrade info document: each controller in turn restarts its mongo
+// server, the secondaries first, after which the feature compatibility
+// version of the replica set is raised. A plan recorded by the worker
+// before it was restarted is resumed.
+//
+// Failure is logged, and the remaining phases are abandoned without
+// failing the upgrade. This is safe because the feature compatibility
+// version is only raised once every member runs the newer mongod, which
+// supports the features of the older.
+func (w *upgradeDB) upgradeMongo() {
+	if len(w.upgradeInfo.MongoUpgradePhases()) == 0 && !w.planMongoUpgrade() {
+		return
+	}
+	target := w.upgradeInfo.MongoTargetVersion()
+	w.setPhase(phaseUpgradingMongo)
+	w.setStatus(status.Started, fmt.Sprintf("upgrading mongo to %v", target))
+
+	for {
+		phases := w.upgradeInfo.MongoUpgradePhases()
+		w.progress.setMongoUpgrade(target, phases)
+		index, ok := nextMongoPhase(phases)
+		if !ok {
+			w.logger.Infof("mongo upgrade to %v completed", target)
+			w.checkStillPrimary()
+			return
+		}
+
+		var done bool
+		switch phase := phases[index]; {
+		case phase.Kind == state.MongoSetFeatureCompatibility:
+			done = w.raiseFeatureCompatibility(index, target)
+		case phase.ControllerId == w.tag.Id():
+			done = w.upgradeLocalMongo(index, target, mongoMembers(phases) > 1)
+		default:
+			done = w.awaitMongoPhase(index, phase.ControllerId)
+		}
+		if !done {
+			w.logger.Errorf("mongo upgrade to %v abandoned", target)
+			return
+		}
+	}
+}
+
+// checkStillPrimary logs whether the Mongo primary still runs on this
+// controller once the mongo upgrade is done. If it stepped down to
+// restart the mongo server here, another controller is likely to have
+// been elected, which then restarts last once the upgrade is complete.
+func (w *upgradeDB) checkStillPrimary() {
+	if !w.steppedDown {
+		return
+	}
+	isPrimary, err := w.pool.IsPrimary(w.tag.Id())
+	if err != nil {
+		w.logger.Errorf("failed to determine mongo primary: %v", err)
+		w.recordError(err)
+		return
+	}
+	if !isPrimary {
+		w.logger.Infof("mongo primary moved to another controller during the mongo upgrade")
+	}
+}
+
+// planMongoUpgrade records the phases in which the mongo servers are
+// upgraded to the version of the mongod installed on this controller,
+// if it is newer than the mongod run by the Mongo primary, or than the
+// feature compatibility version of the replica set. It returns false if
+// no upgrade is required, or if it could not be planned.
+func (w *upgradeDB) planMongoUpgrade() bool {
+	installed, err := w.installedMongo()
+	if err != nil {
+		w.logger.Errorf("reading installed mongo version: %v", err)
+		w.recordError(err)
+		return false
+	}
+	running, err := w.pool.MongoVersion()
+	if err != nil {
+		w.logger.Errorf("reading mongo version: %v", err)
+		w.recordError(err)
+		return false
+	}
+	target := mongo.Version{Major: installed.Major, Minor: installed.Minor}
+
+	var phases []state.MongoUpgradePhase
+	if installed.NewerThan(running) > 0 {
+		order, err := w.controllerOrder()
+		if err != nil {
+			w.logger.Errorf("failed to read controller IDs: %v", err)
+			w.recordError(err)
+			return false
+		}
+		for _, id := range order {
+			phases = append(phases, state.MongoUpgradePhase{
+				Kind:         state.MongoUpgradeMember,
+				ControllerId: id,
+			})
+		}
+	}
+	if w.featureCompatibilityBehind(target) {
+		phases = append(phases, state.MongoUpgradePhase{Kind: state.MongoSetFeatureCompatibility})
+	}
+	if len(phases) == 0 {
+		return false
+	}
+
+	w.logger.Infof("upgrading mongo from %v to %v", running, target)
+	if err := w.upgradeInfo.SetMongoUpgradePlan(target.String(), phases); err != nil {
+		w.logger.Errorf("failed to record mongo upgrade plan: %v", err)
+		w.recordError(err)
+		return false
+	}
+	return true
+}
+
+// featureCompatibilityBehind returns true if the feature compatibility
+// version of the replica set is older than the target version. Mongo
+// versions without a feature compatibility version are never behind.
+func (w *upgradeDB) featureCompatibilityBehind(target mongo.Version) bool {
+	if target.NewerThan(minFeatureCompatibilityVersion) < 0 {
+		return false
+	}
+	fcv, err := w.pool.FeatureCompatibilityVersion()
+	if err != nil {
+		w.logger.Errorf("reading feature compatibility version: %v", err)
+		w.recordError(err)
+		return false
+	}
+	current, err := mongo.NewVersion(fcv)
+	if err != nil {
+		w.logger.Errorf("parsing feature compatibility version %q: %v", fcv, err)
+		w.recordError(err)
+		return false
+	}
+	return target.NewerThan(current) > 0
+}
+
+// upgradeMongoTurn restarts the mongo server on this secondary controller
+// when the next phase of the mongo upgrade recorded by the primary is its
+// own. Should the restart fail, it is attempted again the next time the
+// upgrade info changes, until the primary abandons the mongo upgrade.
+func (w *upgradeDB) upgradeMongoTurn() {
+	phases := w.upgradeInfo.MongoUpgradePhases()
+	index, ok := nextMongoPhase(phases)
+	if !ok || phases[index].Kind != state.MongoUpgradeMember || phases[index].ControllerId != w.tag.Id() {
+		return
+	}
+	target := w.upgradeInfo.MongoTargetVersion()
+	w.progress.setMongoUpgrade(target, phases)
+	if w.upgradeLocalMongo(index, target, false) {
+		w.progress.setMongoUpgrade(target, w.upgradeInfo.MongoUpgradePhases())
+	}
+}
+
+// upgradeLocalMongo restarts the mongo server on this controller, so that
+// it runs the installed mongod, and records that the phase with the input
+// index is done. If stepDown is true the Mongo primary first steps down,
+// so that a secondary that already runs the newer mongod is elected in
+// its place.
+func (w *upgradeDB) upgradeLocalMongo(index int, target string, stepDown bool) bool {
+	w.logger.Infof("restarting mongo server to upgrade to %v", target)
+	if stepDown {
+		if err := w.pool.StepDownPrimary(); err != nil {
+			w.logger.Errorf("failed to step down mongo primary: %v", err)
+			w.recordError(err)
+		} else {
+			w.steppedDown = true
+		}
+	}
+	if err := w.restartMongo(); err != nil {
+		w.logger.Errorf("failed to restart mongo server: %v", err)
+		w.recordError(err)
+		return false
+	}
+	return w.mongoPhaseDone(index)
+}
+
+// raiseFeatureCompatibility sets the feature compatibility version of the
+// replica set to the target version, now that all of its members run the
+// newer mongod, and records that the phase with the input index is done.
+func (w *upgradeDB) raiseFeatureCompatibility(index int, target string) bool {
+	if err := w.pool.SetFeatureCompatibilityVersion(target); err != nil {
+		w.logger.Errorf("failed to raise feature compatibility version: %v", err)
+		w.recordError(err)
+		return false
+	}
+	w.logger.Infof("feature compatibility version set to %v", target)
+	return w.mongoPhaseDone(index)
+}
+
+// mongoPhaseDone records that the mongo upgrade phase with the input
+// index is done. As the connection to the database can be interrupted
+// by a mongo server restarting, failure to do so is retried.
+func (w *upgradeDB) mongoPhaseDone(index int) bool {
+	var err error
+	for attempt := w.retryStrategy.Start(); attempt.Next(); {
+		if err = w.upgradeInfo.SetMongoUpgradePhaseDone(index); err == nil {
+			return true
+		}
+	}
+	w.logger.Errorf("failed to record mongo upgrade phase: %v", err)
+	w.recordError(err)
+	return false
+}
+
+// awaitMongoPhase waits until the controller with the input ID has
+// restarted its mongo server, as the phase of the mongo upgrade with the
+// input index. It returns false if the worker is stopped while waiting,
+// or if the controller does not do so within mongoPhaseTimeout.
+func (w *upgradeDB) awaitMongoPhase(index int, controllerId string) bool {
+	w.setStatus(status.Started, fmt.Sprintf(
+		"waiting for controller %v to upgrade mongo to %v", controllerId, w.upgradeInfo.MongoTargetVersion()))
+	watcher := w.upgradeInfo.Watch()
+	defer func() { _ = watcher.Stop() }()
+
+	timeout := w.clock.After(mongoPhaseTimeout)
+	for {
+		select {
+		case <-watcher.Changes():
+			if err := w.upgradeInfo.Refresh(); err != nil {
+				w.logger.Errorf("unable to refresh upgrade info: %v", err)
+				w.recordError(err)
+				continue
+			}
+			if phases := w.upgradeInfo.MongoUpgradePhases(); index < len(phases) && phases[index].Done {
+				return true
+			}
+		case <-timeout:
+			err := errors.Errorf("timed out waiting for controller %v to upgrade mongo", controllerId)
+			w.logger.Errorf("%v", err)
+			w.recordError(err)
+			return false
+		case <-w.tomb.Dying():
+			return false
+		}
+	}
+}
+
+// pausedPhase returns the phase in which the primary controller has
+// paused the database upgrade, for longer than it is otherwise given to
+// complete it, or an empty string if the upgrade is not paused.
+func (w *upgradeDB) pausedPhase() string {
+	if w.upgradeInfo.AwaitingApproval() {
+		return phaseAwaitingApproval
+	}
+	if _, ok := nextMongoPhase(w.upgradeInfo.MongoUpgradePhases()); ok {
+		return phaseUpgradingMongo
+	}
+	return ""
+}
+
+// nextMongoPhase returns the index of the first of the mongo
+// upgrade phases not yet done, and false if all are done.
+func nextMongoPhase(phases []state.MongoUpgradePhase) (int, bool) {
+	for i, phase := range phases {
+		if !phase.Done {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// mongoMembers returns the number of controllers
+// whose mongo servers are upgraded by the phases.
+func mongoMembers(phases []state.MongoUpgradePhase) int {
+	count := 0
+	for _, phase := range phases {
+		if phase.Kind == state.MongoUpgradeMember {
+			count++
+		}
+	}
+	return count
+}
+
+// mongoPhaseDescription describes the mongo upgrade
+// phase for the worker's report.
+func mongoPhaseDescription(phase state.MongoUpgradePhase, target string) string {
+	if phase.Kind == state.MongoSetFeatureCompatibility {
+		return fmt.Sprintf("set feature compatibility version to %v", target)
+	}
+	return fmt.Sprintf("restart mongo server on controller %v", phase.ControllerId)
+}
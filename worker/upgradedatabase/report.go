@@ -0,0 +1,304 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgradedatabase
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/version"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/upgrades"
+)
+
+// The phases through which a database upgrade progresses,
+// as shown in the worker's report.
+const (
+	phaseWaiting          = "waiting for primary"
+	phasePreflight        = "pre-flight check"
+	phaseRunning          = "running steps"
+	phaseElecting         = "waiting for primary election"
+	phaseValidating       = "validating"
+	phaseAwaitingApproval = "awaiting approval"
+	phaseIndexing         = "ensuring indexes"
+	phaseSweeping         = "sweeping orphaned documents"
+	phaseUpgradingMongo   = "upgrading mongo"
+	phaseRollingBack      = "rolling back"
+	phaseRolledBack       = "rolled back"
+	phaseRestarting       = "restarting controllers"
+	phaseComplete         = "complete"
+	phaseFailed           = "failed"
+)
+
+// maxReportedErrors is the number of the most
+// recent upgrade errors included in the report.
+const maxReportedErrors = 10
+
+// reportedError is an error encountered during the upgrade.
+type reportedError struct {
+	time  time.Time
+	step  string
+	error string
+}
+
+// progress records how far the upgrade has got, so that it can be
+// reported through the agent's introspection socket. This is available
+// on the controller even when the API server is not.
+type progress struct {
+	mu          sync.Mutex
+	fromVersion version.Number
+	toVersion   version.Number
+	phase       string
+	started     time.Time
+	attempt     int
+	step        string
+	stepStarted time.Time
+	errors      []reportedError
+
+	driftChecked time.Time
+	drift        []upgrades.SchemaDrift
+
+	indexesChecked time.Time
+	indexes        []state.CollectionIndexes
+
+	orphansChecked time.Time
+	orphans        []state.OrphanReport
+
+	mongoTarget string
+	mongoPhases []state.MongoUpgradePhase
+}
+
+// setPhase records that the upgrade between the versions has entered
+// the phase. The start of the upgrade is taken to be the first time a
+// phase is set.
+func (p *progress) setPhase(phase string, from, to version.Number, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started.IsZero() {
+		p.started = now
+	}
+	p.fromVersion = from
+	p.toVersion = to
+	p.phase = phase
+	if phase != phaseRunning {
+		p.step = ""
+	}
+}
+
+// startAttempt records the start of an attempt to run the upgrade steps.
+func (p *progress) startAttempt() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.attempt++
+	p.step = ""
+}
+
+// startStep records the upgrade step that is being run.
+func (p *progress) startStep(description string, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.step = description
+	p.stepStarted = now
+}
+
+// currentStep returns the upgrade step being run and when it started,
+// or an empty description if no step is being run.
+func (p *progress) currentStep() (string, time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.step, p.stepStarted
+}
+
+// addError records an error encountered during the upgrade,
+// against the step being run if there is one.
+func (p *progress) addError(err error, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.errors = append(p.errors, reportedError{
+		time:  now,
+		step:  p.step,
+		error: err.Error(),
+	})
+	if len(p.errors) > maxReportedErrors {
+		p.errors = p.errors[len(p.errors)-maxReportedErrors:]
+	}
+}
+
+// report returns the progress of the upgrade, with elapsed
+// times calculated relative to now.
+func (p *progress) report(now time.Time) map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.phase == "" {
+		return nil
+	}
+	report := map[string]interface{}{
+		"from-version": p.fromVersion.String(),
+		"to-version":   p.toVersion.String(),
+		"phase":        p.phase,
+		"started":      p.started.Format(time.RFC3339),
+		"elapsed":      now.Sub(p.started).String(),
+	}
+	if p.attempt > 0 {
+		report["attempt"] = p.attempt
+	}
+	if p.step != "" {
+		report["step"] = map[string]interface{}{
+			"description": p.step,
+			"started":     p.stepStarted.Format(time.RFC3339),
+			"elapsed":     now.Sub(p.stepStarted).String(),
+		}
+	}
+	if len(p.errors) > 0 {
+		errors := make([]map[string]interface{}, len(p.errors))
+		for i, e := range p.errors {
+			errors[i] = map[string]interface{}{
+				"time":  e.time.Format(time.RFC3339),
+				"error": e.error,
+			}
+			if e.step != "" {
+				errors[i]["step"] = e.step
+			}
+		}
+		report["errors"] = errors
+	}
+	return report
+}
+
+// setDrift records the result of checking the database for schema drift.
+func (p *progress) setDrift(drift []upgrades.SchemaDrift, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.driftChecked = now
+	p.drift = drift
+}
+
+// driftReport returns the collections found to have drifted from their
+// expected schema, or nil if the database has not been checked.
+func (p *progress) driftReport() map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.driftChecked.IsZero() {
+		return nil
+	}
+	collections := make(map[string]interface{}, len(p.drift))
+	for _, d := range p.drift {
+		collection := map[string]interface{}{
+			"expected-version": d.ExpectedVersion,
+			"recorded-version": d.RecordedVersion,
+		}
+		if len(d.MissingFields) > 0 {
+			collection["missing-fields"] = d.MissingFields
+		}
+		collections[d.Collection] = collection
+	}
+	return map[string]interface{}{
+		"checked":     p.driftChecked.Format(time.RFC3339),
+		"collections": collections,
+	}
+}
+
+// setIndexes records the indexes created, and those found not to be
+// declared, when ensuring the database indexes.
+func (p *progress) setIndexes(indexes []state.CollectionIndexes, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.indexesChecked = now
+	p.indexes = indexes
+}
+
+// indexReport returns the collections whose indexes were created or
+// found not to be declared, or nil if the indexes have not been checked.
+func (p *progress) indexReport() map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.indexesChecked.IsZero() {
+		return nil
+	}
+	collections := make(map[string]interface{}, len(p.indexes))
+	for _, ci := range p.indexes {
+		collection := make(map[string]interface{})
+		if len(ci.Created) > 0 {
+			collection["created"] = ci.Created
+		}
+		if len(ci.Unexpected) > 0 {
+			collection["unexpected"] = ci.Unexpected
+		}
+		collections[ci.Collection] = collection
+	}
+	return map[string]interface{}{
+		"checked":     p.indexesChecked.Format(time.RFC3339),
+		"collections": collections,
+	}
+}
+
+// setOrphans records the result of sweeping the database
+// for documents of removed models, units and machines.
+func (p *progress) setOrphans(orphans []state.OrphanReport, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.orphansChecked = now
+	p.orphans = orphans
+}
+
+// orphanReport returns the number of orphaned documents found in each
+// collection by each detector, or nil if the database has not been swept.
+func (p *progress) orphanReport() map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.orphansChecked.IsZero() {
+		return nil
+	}
+	detectors := make(map[string]interface{})
+	removed := false
+	for _, o := range p.orphans {
+		collections, _ := detectors[o.Detector].(map[string]interface{})
+		if collections == nil {
+			collections = make(map[string]interface{})
+			detectors[o.Detector] = collections
+		}
+		collections[o.Collection] = o.Count
+		removed = removed || o.Removed
+	}
+	report := map[string]interface{}{
+		"checked":   p.orphansChecked.Format(time.RFC3339),
+		"detectors": detectors,
+	}
+	if removed {
+		report["removed"] = true
+	}
+	return report
+}
+
+// setMongoUpgrade records the version to which the mongo servers are
+// being upgraded, and the phases of the upgrade.
+func (p *progress) setMongoUpgrade(target string, phases []state.MongoUpgradePhase) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mongoTarget = target
+	p.mongoPhases = phases
+}
+
+// mongoUpgradeReport returns the phases of the upgrade of the mongo
+// servers, and whether each has been done, or nil if the mongo
+// servers are not being upgraded.
+func (p *progress) mongoUpgradeReport() map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.mongoPhases) == 0 {
+		return nil
+	}
+	phases := make([]map[string]interface{}, len(p.mongoPhases))
+	for i, phase := range p.mongoPhases {
+		phases[i] = map[string]interface{}{
+			"phase": mongoPhaseDescription(phase, p.mongoTarget),
+			"done":  phase.Done,
+		}
+	}
+	return map[string]interface{}{
+		"target-version": p.mongoTarget,
+		"phases":         phases,
+	}
+}
@@ -4,11 +4,16 @@
 package upgradedatabase
 
 import (
+	"io"
+	"strings"
 	"time"
 
 	"github.com/juju/errors"
 	"github.com/juju/version"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
 
+	"github.com/juju/juju/controller"
 	"github.com/juju/juju/core/status"
 	"github.com/juju/juju/mongo"
 	"github.com/juju/juju/state"
@@ -28,6 +33,9 @@ type Logger interface {
 
 // Clock provides an interface for dealing with clocks.
 type Clock interface {
+	// Now returns the current clock time.
+	Now() time.Time
+
 	// After waits for the duration to elapse and then sends the
 	// current time on the returned channel.
 	After(time.Duration) <-chan time.Time
@@ -47,6 +55,62 @@ type UpgradeInfo interface {
 
 	// Refresh refreshes the UpgradeInfo from state.
 	Refresh() error
+
+	// SetStepCollections records the collections
+	// touched by each of the upgrade steps.
+	SetStepCollections([]state.UpgradeStepCollections) error
+
+	// SetStepPhases records the phase of each of the upgrade
+	// steps that are phases of staged migrations.
+	SetStepPhases([]state.UpgradeStepPhaseInfo) error
+
+	// SetEstimate records the estimated duration of each of the
+	// upgrade steps, and when the upgrade is estimated to complete.
+	SetEstimate([]state.UpgradeStepEstimate, time.Time) error
+
+	// RestartOrder returns the order in which the
+	// controllers restart after the database upgrade.
+	RestartOrder() []string
+
+	// SetRestartOrder records the order in which the
+	// controllers restart after the database upgrade.
+	SetRestartOrder([]string) error
+
+	// ControllersRestarted returns the controllers that have
+	// restarted since the database upgrade completed.
+	ControllersRestarted() []string
+
+	// SetControllerRestarted records that the controller with
+	// the input ID has restarted after the database upgrade.
+	SetControllerRestarted(string) error
+
+	// AwaitingApproval returns true if the database
+	// upgrade is paused until an operator approves it.
+	AwaitingApproval() bool
+
+	// SetAwaitingApproval records that the database
+	// upgrade is paused until an operator approves it.
+	SetAwaitingApproval() error
+
+	// Approved returns true if an operator has
+	// approved the database upgrade to proceed.
+	Approved() bool
+
+	// MongoTargetVersion returns the version to
+	// which the mongo servers are being upgraded.
+	MongoTargetVersion() string
+
+	// MongoUpgradePhases returns the phases in which
+	// the mongo servers are upgraded, in order.
+	MongoUpgradePhases() []state.MongoUpgradePhase
+
+	// SetMongoUpgradePlan records the version to which the mongo
+	// servers are upgraded, and the phases in which they are.
+	SetMongoUpgradePlan(string, []state.MongoUpgradePhase) error
+
+	// SetMongoUpgradePhaseDone records that the mongo
+	// upgrade phase with the input index has been done.
+	SetMongoUpgradePhaseDone(int) error
 }
 
 // State describes methods required by the upgradeDB worker
@@ -63,6 +127,66 @@ type Pool interface {
 	// collection for coordinating the current upgrade.
 	EnsureUpgradeInfo(string, version.Number, version.Number) (UpgradeInfo, error)
 
+	// WriteCount returns the number of document writes
+	// made by the database server since it started.
+	WriteCount() (int64, error)
+
+	// WriteLatency returns the total time spent by the database
+	// server on write operations, and the number of write
+	// operations, since it started.
+	WriteLatency() (time.Duration, int64, error)
+
+	// ReplicationLag returns how far the furthest
+	// secondary is behind the Mongo primary.
+	ReplicationLag() (time.Duration, error)
+
+	// CollectionStats returns the number of documents in the
+	// named collection, and their total size in bytes.
+	CollectionStats(string) (int64, int64, error)
+
+	// SetUpgradeBatcher sets the batcher that determines how
+	// upgrade steps batch the documents that they rewrite.
+	SetUpgradeBatcher(state.UpgradeBatcher)
+
+	// ControllerIDs returns the IDs of all the controllers.
+	ControllerIDs() ([]string, error)
+
+	// StepDownPrimary asks the Mongo primary to step down,
+	// so that one of the secondaries is elected in its place.
+	StepDownPrimary() error
+
+	// IsPrimaryChange returns true if the input error was caused
+	// by the Mongo primary stepping down, or being shut down,
+	// while the failed operation was in progress.
+	IsPrimaryChange(error) bool
+
+	// PrimaryElected returns true if a member
+	// of the replica set is currently primary.
+	PrimaryElected() (bool, error)
+
+	// Refresh discards the connections to the replica set, which
+	// are left in error by a change of primary, so that subsequent
+	// operations connect to the current primary.
+	Refresh()
+
+	// WatchControllerConfig returns a watcher that notifies
+	// of changes to the controller config.
+	WatchControllerConfig() state.NotifyWatcher
+
+	// ControllerConfig returns the controller config.
+	ControllerConfig() (controller.Config, error)
+
+	// MongoVersion returns the version of the Mongo primary.
+	MongoVersion() (mongo.Version, error)
+
+	// FeatureCompatibilityVersion returns the
+	// feature compatibility version of the replica set.
+	FeatureCompatibilityVersion() (string, error)
+
+	// SetFeatureCompatibilityVersion sets the
+	// feature compatibility version of the replica set.
+	SetFeatureCompatibilityVersion(string) error
+
 	// Close closes the state pool.
 	Close() error
 }
@@ -117,3 +241,249 @@ func (p *pool) EnsureUpgradeInfo(controllerId string, fromVersion, toVersion ver
 	info, err := p.SystemState().EnsureUpgradeInfo(controllerId, fromVersion, toVersion)
 	return info, errors.Trace(err)
 }
+
+// WriteCount (Pool) returns the number of documents inserted, updated
+// and deleted by the database server since it started.
+func (p *pool) WriteCount() (int64, error) {
+	var serverStatus struct {
+		OpCounters struct {
+			Insert int64 `bson:"insert"`
+			Update int64 `bson:"update"`
+			Delete int64 `bson:"delete"`
+		} `bson:"opcounters"`
+	}
+	session := p.SystemState().MongoSession()
+	if err := session.Run(bson.D{{"serverStatus", 1}}, &serverStatus); err != nil {
+		return 0, errors.Annotate(err, "reading server status")
+	}
+	ops := serverStatus.OpCounters
+	return ops.Insert + ops.Update + ops.Delete, nil
+}
+
+// WriteLatency (Pool) returns the total time spent by the database server
+// on write operations, and the number of write operations, since it
+// started.
+func (p *pool) WriteLatency() (time.Duration, int64, error) {
+	var serverStatus struct {
+		OpLatencies struct {
+			Writes struct {
+				// Latency is in microseconds.
+				Latency int64 `bson:"latency"`
+				Ops     int64 `bson:"ops"`
+			} `bson:"writes"`
+		} `bson:"opLatencies"`
+	}
+	session := p.SystemState().MongoSession()
+	if err := session.Run(bson.D{{"serverStatus", 1}}, &serverStatus); err != nil {
+		return 0, 0, errors.Annotate(err, "reading server status")
+	}
+	writes := serverStatus.OpLatencies.Writes
+	return time.Duration(writes.Latency) * time.Microsecond, writes.Ops, nil
+}
+
+// ReplicationLag (Pool) returns how far the furthest secondary is behind
+// the Mongo primary, going by the last operations they have applied.
+func (p *pool) ReplicationLag() (time.Duration, error) {
+	st := p.SystemState()
+	model, err := st.Model()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	// TODO(CAAS) - bug 1849030 support HA
+	if model.Type() == state.ModelTypeCAAS {
+		return 0, nil
+	}
+
+	var replSetStatus struct {
+		Members []struct {
+			State      int       `bson:"state"`
+			OptimeDate time.Time `bson:"optimeDate"`
+		} `bson:"members"`
+	}
+	if err := st.MongoSession().Run(bson.D{{"replSetGetStatus", 1}}, &replSetStatus); err != nil {
+		return 0, errors.Annotate(err, "reading replica set status")
+	}
+	const (
+		primaryState   = 1
+		secondaryState = 2
+	)
+	var primary, oldest time.Time
+	for _, member := range replSetStatus.Members {
+		switch member.State {
+		case primaryState:
+			primary = member.OptimeDate
+		case secondaryState:
+			if oldest.IsZero() || member.OptimeDate.Before(oldest) {
+				oldest = member.OptimeDate
+			}
+		}
+	}
+	if primary.IsZero() || oldest.IsZero() || !oldest.Before(primary) {
+		return 0, nil
+	}
+	return primary.Sub(oldest), nil
+}
+
+// CollectionStats (Pool) returns the number of documents in the named
+// collection, and their total size in bytes.
+func (p *pool) CollectionStats(name string) (int64, int64, error) {
+	count, size, err := p.SystemState().CollectionStats(name)
+	return count, size, errors.Trace(err)
+}
+
+// ControllerIDs (Pool) returns the IDs of all the controllers.
+func (p *pool) ControllerIDs() ([]string, error) {
+	ids, err := p.SystemState().ControllerIds()
+	return ids, errors.Trace(err)
+}
+
+// WatchControllerConfig (Pool) returns a watcher that
+// notifies of changes to the controller config.
+func (p *pool) WatchControllerConfig() state.NotifyWatcher {
+	return p.SystemState().WatchControllerConfig()
+}
+
+// ControllerConfig (Pool) returns the controller config.
+func (p *pool) ControllerConfig() (controller.Config, error) {
+	cfg, err := p.SystemState().ControllerConfig()
+	return cfg, errors.Trace(err)
+}
+
+// StepDownPrimary (Pool) asks the Mongo primary to step down for a minute,
+// so that a secondary is elected primary while this controller restarts.
+// The primary closes all connections as it steps down, so the resulting
+// connection error is expected and ignored.
+func (p *pool) StepDownPrimary() error {
+	st := p.SystemState()
+	model, err := st.Model()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// TODO(CAAS) - bug 1849030 support HA
+	if model.Type() == state.ModelTypeCAAS {
+		return nil
+	}
+
+	session := st.MongoSession().Copy()
+	defer session.Close()
+	err = session.Run(bson.D{{"replSetStepDown", 60}}, nil)
+	if err == nil || err == io.EOF || strings.Contains(err.Error(), "connection") {
+		return nil
+	}
+	return errors.Annotate(err, "stepping down mongo primary")
+}
+
+// IsPrimaryChange (Pool) returns true if the input error was caused by the
+// Mongo primary stepping down, or being shut down, while the operation was
+// in progress. Such operations can be run again once a primary is elected.
+func (p *pool) IsPrimaryChange(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch cause := errors.Cause(err).(type) {
+	case *mgo.QueryError:
+		return isPrimaryChangeCode(cause.Code)
+	case *mgo.LastError:
+		return isPrimaryChangeCode(cause.Code)
+	}
+	if errors.Cause(err) == io.EOF {
+		return true
+	}
+	// Errors from the transaction runner and the upgrade steps are not
+	// always traced, so the messages of the errors are checked as well.
+	msg := err.Error()
+	return strings.Contains(msg, "not master") ||
+		strings.Contains(msg, "node is recovering") ||
+		strings.Contains(msg, "interrupted due to repl state change")
+}
+
+// isPrimaryChangeCode returns true if the mongo error code is one
+// returned for operations interrupted by a change of primary.
+func isPrimaryChangeCode(code int) bool {
+	switch code {
+	case 91, // ShutdownInProgress
+		189,   // PrimarySteppedDown
+		10107, // NotMaster
+		11600, // InterruptedAtShutdown
+		11602, // InterruptedDueToReplStateChange
+		13435, // NotMasterNoSlaveOk
+		13436: // NotMasterOrSecondary
+		return true
+	}
+	return false
+}
+
+// PrimaryElected (Pool) returns true if a member of the
+// replica set is currently in the primary state.
+func (p *pool) PrimaryElected() (bool, error) {
+	st := p.SystemState()
+	model, err := st.Model()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	// TODO(CAAS) - bug 1849030 support HA
+	if model.Type() == state.ModelTypeCAAS {
+		return true, nil
+	}
+
+	var replSetStatus struct {
+		Members []struct {
+			State int `bson:"state"`
+		} `bson:"members"`
+	}
+	if err := st.MongoSession().Run(bson.D{{"replSetGetStatus", 1}}, &replSetStatus); err != nil {
+		return false, errors.Annotate(err, "reading replica set status")
+	}
+	const primaryState = 1
+	for _, member := range replSetStatus.Members {
+		if member.State == primaryState {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Refresh (Pool) refreshes the system state's session, releasing
+// its connections so that new ones are made to the current primary.
+func (p *pool) Refresh() {
+	p.SystemState().MongoSession().Refresh()
+}
+
+// MongoVersion (Pool) returns the version of the mongod
+// run by the Mongo primary.
+func (p *pool) MongoVersion() (mongo.Version, error) {
+	info, err := p.SystemState().MongoSession().BuildInfo()
+	if err != nil {
+		return mongo.Version{}, errors.Annotate(err, "reading mongo build info")
+	}
+	var v mongo.Version
+	parts := append(info.VersionArray, 0, 0, 0)
+	v.Major, v.Minor, v.Point = parts[0], parts[1], parts[2]
+	return v, nil
+}
+
+// FeatureCompatibilityVersion (Pool) returns the feature compatibility
+// version of the replica set, which limits the features of the mongod
+// run by its members to those of that version.
+func (p *pool) FeatureCompatibilityVersion() (string, error) {
+	var result struct {
+		FeatureCompatibilityVersion struct {
+			Version string `bson:"version"`
+		} `bson:"featureCompatibilityVersion"`
+	}
+	session := p.SystemState().MongoSession()
+	err := session.Run(bson.D{{"getParameter", 1}, {"featureCompatibilityVersion", 1}}, &result)
+	if err != nil {
+		return "", errors.Annotate(err, "reading feature compatibility version")
+	}
+	return result.FeatureCompatibilityVersion.Version, nil
+}
+
+// SetFeatureCompatibilityVersion (Pool) sets the feature compatibility
+// version of the replica set. It must only be raised once all of the
+// members run a mongod of at least that version.
+func (p *pool) SetFeatureCompatibilityVersion(v string) error {
+	session := p.SystemState().MongoSession()
+	err := session.Run(bson.D{{"setFeatureCompatibilityVersion", v}}, nil)
+	return errors.Annotatef(err, "setting feature compatibility version to %q", v)
+}
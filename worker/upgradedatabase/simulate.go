@@ -0,0 +1,203 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgradedatabase
+
+import (
+	"sort"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/version"
+
+	"github.com/juju/juju/upgrades"
+)
+
+// SimulationConfig holds the dependencies of a simulated database
+// upgrade, which runs the upgrade steps against a scratch copy of a
+// controller's database rather than the controller itself.
+type SimulationConfig struct {
+	// FromVersion is the version of Juju that last
+	// upgraded the database being simulated.
+	FromVersion version.Number
+
+	// ToVersion is the version being upgraded to.
+	ToVersion version.Number
+
+	// Context is the upgrade context, connected to the
+	// scratch database.
+	Context upgrades.Context
+
+	// PerformUpgrade runs the upgrade steps, notifying the
+	// observer of each step before it is run.
+	PerformUpgrade func(version.Number, []upgrades.Target, upgrades.Context, upgrades.StepObserver) error
+
+	// ValidateUpgrade checks the database once the steps have run.
+	ValidateUpgrade func(upgrades.Context) error
+
+	// CountDocuments returns the number of documents
+	// in each collection of the scratch database.
+	CountDocuments func() (map[string]int, error)
+
+	// Clock is used to time the upgrade steps.
+	Clock Clock
+}
+
+// Validate returns an error if the simulation config is not valid.
+func (cfg SimulationConfig) Validate() error {
+	if cfg.FromVersion == version.Zero {
+		return errors.NotValidf("zero FromVersion")
+	}
+	if cfg.ToVersion == version.Zero {
+		return errors.NotValidf("zero ToVersion")
+	}
+	if cfg.Context == nil {
+		return errors.NotValidf("nil Context")
+	}
+	if cfg.PerformUpgrade == nil {
+		return errors.NotValidf("nil PerformUpgrade function")
+	}
+	if cfg.ValidateUpgrade == nil {
+		return errors.NotValidf("nil ValidateUpgrade function")
+	}
+	if cfg.CountDocuments == nil {
+		return errors.NotValidf("nil CountDocuments function")
+	}
+	if cfg.Clock == nil {
+		return errors.NotValidf("nil Clock")
+	}
+	return nil
+}
+
+// SimulationReport describes the outcome of a simulated upgrade.
+type SimulationReport struct {
+	FromVersion string `yaml:"from-version" json:"from-version"`
+	ToVersion   string `yaml:"to-version" json:"to-version"`
+	Started     string `yaml:"started" json:"started"`
+	Duration    string `yaml:"duration" json:"duration"`
+
+	// Steps holds the upgrade steps that were run,
+	// in order, up to and including any that failed.
+	Steps []SimulatedStep `yaml:"steps" json:"steps"`
+
+	// Error holds the error from the upgrade steps or from
+	// validating the upgraded database, if either failed.
+	Error string `yaml:"error,omitempty" json:"error,omitempty"`
+
+	// Deltas holds the changes to the number of documents in each
+	// collection made by the upgrade as a whole.
+	Deltas []DocumentDelta `yaml:"document-deltas,omitempty" json:"document-deltas,omitempty"`
+}
+
+// SimulatedStep describes an upgrade step run by a simulated upgrade.
+type SimulatedStep struct {
+	Description string          `yaml:"description" json:"description"`
+	Duration    string          `yaml:"duration" json:"duration"`
+	Error       string          `yaml:"error,omitempty" json:"error,omitempty"`
+	Deltas      []DocumentDelta `yaml:"document-deltas,omitempty" json:"document-deltas,omitempty"`
+}
+
+// DocumentDelta records the change in the number
+// of documents in a collection.
+type DocumentDelta struct {
+	Collection string `yaml:"collection" json:"collection"`
+	Before     int    `yaml:"before" json:"before"`
+	After      int    `yaml:"after" json:"after"`
+}
+
+// Failed returns whether the simulated upgrade failed.
+func (r *SimulationReport) Failed() bool {
+	return r.Error != ""
+}
+
+// Simulate runs the database upgrade steps as the upgrade worker would,
+// timing each step and recording the documents it adds and removes.
+// Failures of the steps themselves are recorded in the report rather than
+// returned; the error returned is for a failure of the simulation.
+func Simulate(cfg SimulationConfig) (*SimulationReport, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	started := cfg.Clock.Now()
+	initial, err := cfg.CountDocuments()
+	if err != nil {
+		return nil, errors.Annotate(err, "counting documents")
+	}
+
+	var (
+		steps       []SimulatedStep
+		stepStarted time.Time
+		before      = initial
+		countErr    error
+	)
+	// The observer is only told when each step starts, so each step is
+	// finished when the next starts, or the upgrade ends.
+	finishStep := func(stepErr error) {
+		if len(steps) == 0 || countErr != nil {
+			return
+		}
+		step := &steps[len(steps)-1]
+		step.Duration = cfg.Clock.Now().Sub(stepStarted).String()
+		if stepErr != nil {
+			step.Error = stepErr.Error()
+		}
+		after, err := cfg.CountDocuments()
+		if err != nil {
+			countErr = errors.Annotatef(err, "counting documents after %q", step.Description)
+			return
+		}
+		step.Deltas = documentDeltas(before, after)
+		before = after
+	}
+	observer := func(description string) error {
+		finishStep(nil)
+		steps = append(steps, SimulatedStep{Description: description})
+		stepStarted = cfg.Clock.Now()
+		return nil
+	}
+
+	targets := []upgrades.Target{upgrades.DatabaseMaster}
+	upgradeErr := cfg.PerformUpgrade(cfg.FromVersion, targets, cfg.Context, observer)
+	finishStep(upgradeErr)
+	if countErr != nil {
+		return nil, errors.Trace(countErr)
+	}
+	if upgradeErr == nil {
+		if err := cfg.ValidateUpgrade(cfg.Context); err != nil {
+			upgradeErr = errors.Annotate(err, "validating upgrade")
+		}
+	}
+
+	report := &SimulationReport{
+		FromVersion: cfg.FromVersion.String(),
+		ToVersion:   cfg.ToVersion.String(),
+		Started:     started.Format(time.RFC3339),
+		Duration:    cfg.Clock.Now().Sub(started).String(),
+		Steps:       steps,
+		Deltas:      documentDeltas(initial, before),
+	}
+	if upgradeErr != nil {
+		report.Error = upgradeErr.Error()
+	}
+	return report, nil
+}
+
+// documentDeltas returns the collections whose document counts differ,
+// sorted by collection name.
+func documentDeltas(before, after map[string]int) []DocumentDelta {
+	var deltas []DocumentDelta
+	for name, n := range after {
+		if before[name] != n {
+			deltas = append(deltas, DocumentDelta{Collection: name, Before: before[name], After: n})
+		}
+	}
+	for name, n := range before {
+		if _, ok := after[name]; !ok {
+			deltas = append(deltas, DocumentDelta{Collection: name, Before: n})
+		}
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		return deltas[i].Collection < deltas[j].Collection
+	})
+	return deltas
+}
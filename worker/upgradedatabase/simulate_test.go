@@ -0,0 +1,134 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgradedatabase_test
+
+import (
+	"time"
+
+	"github.com/juju/clock/testclock"
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/version"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/upgrades"
+	"github.com/juju/juju/worker/upgradedatabase"
+)
+
+type simulateSuite struct {
+	testing.IsolationSuite
+
+	clock  *testclock.Clock
+	counts map[string]int
+}
+
+var _ = gc.Suite(&simulateSuite{})
+
+func (s *simulateSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.clock = testclock.NewClock(time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC))
+	s.counts = map[string]int{"machines": 3, "units": 5}
+}
+
+func (s *simulateSuite) config(perform func(upgrades.StepObserver) error) upgradedatabase.SimulationConfig {
+	return upgradedatabase.SimulationConfig{
+		FromVersion: version.MustParse("2.7.6"),
+		ToVersion:   version.MustParse("2.8.0"),
+		Context:     upgrades.NewContext(nil, nil, nil),
+		PerformUpgrade: func(_ version.Number, targets []upgrades.Target, _ upgrades.Context, observer upgrades.StepObserver) error {
+			return perform(observer)
+		},
+		ValidateUpgrade: func(upgrades.Context) error { return nil },
+		CountDocuments: func() (map[string]int, error) {
+			counts := make(map[string]int)
+			for k, v := range s.counts {
+				counts[k] = v
+			}
+			return counts, nil
+		},
+		Clock: s.clock,
+	}
+}
+
+func (s *simulateSuite) TestValidate(c *gc.C) {
+	cfg := s.config(nil)
+	cfg.CountDocuments = nil
+	_, err := upgradedatabase.Simulate(cfg)
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *simulateSuite) TestSimulate(c *gc.C) {
+	report, err := upgradedatabase.Simulate(s.config(func(observer upgrades.StepObserver) error {
+		observer("add status history")
+		s.clock.Advance(2 * time.Second)
+		s.counts["statuseshistory"] = 4
+		observer("remove stale units")
+		s.clock.Advance(time.Second)
+		s.counts["units"] = 4
+		observer("no-op")
+		return nil
+	}))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(report.Failed(), jc.IsFalse)
+	c.Assert(report, jc.DeepEquals, &upgradedatabase.SimulationReport{
+		FromVersion: "2.7.6",
+		ToVersion:   "2.8.0",
+		Started:     "2020-05-01T12:00:00Z",
+		Duration:    "3s",
+		Steps: []upgradedatabase.SimulatedStep{{
+			Description: "add status history",
+			Duration:    "2s",
+			Deltas:      []upgradedatabase.DocumentDelta{{Collection: "statuseshistory", After: 4}},
+		}, {
+			Description: "remove stale units",
+			Duration:    "1s",
+			Deltas:      []upgradedatabase.DocumentDelta{{Collection: "units", Before: 5, After: 4}},
+		}, {
+			Description: "no-op",
+			Duration:    "0s",
+		}},
+		Deltas: []upgradedatabase.DocumentDelta{
+			{Collection: "statuseshistory", After: 4},
+			{Collection: "units", Before: 5, After: 4},
+		},
+	})
+}
+
+func (s *simulateSuite) TestSimulateStepFails(c *gc.C) {
+	validated := false
+	cfg := s.config(func(observer upgrades.StepObserver) error {
+		observer("remove stale units")
+		s.counts["units"] = 0
+		return errors.New("remove stale units: boom")
+	})
+	cfg.ValidateUpgrade = func(upgrades.Context) error {
+		validated = true
+		return nil
+	}
+	report, err := upgradedatabase.Simulate(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(report.Failed(), jc.IsTrue)
+	c.Assert(report.Error, gc.Equals, "remove stale units: boom")
+	c.Assert(report.Steps, gc.HasLen, 1)
+	c.Assert(report.Steps[0].Error, gc.Equals, "remove stale units: boom")
+	c.Assert(report.Steps[0].Deltas, jc.DeepEquals, []upgradedatabase.DocumentDelta{
+		{Collection: "units", Before: 5, After: 0},
+	})
+	c.Assert(validated, jc.IsFalse)
+}
+
+func (s *simulateSuite) TestSimulateValidationFails(c *gc.C) {
+	cfg := s.config(func(observer upgrades.StepObserver) error {
+		observer("step")
+		return nil
+	})
+	cfg.ValidateUpgrade = func(upgrades.Context) error {
+		return errors.New("canary model not found")
+	}
+	report, err := upgradedatabase.Simulate(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(report.Error, gc.Equals, "validating upgrade: canary model not found")
+	c.Assert(report.Steps[0].Error, gc.Equals, "")
+}
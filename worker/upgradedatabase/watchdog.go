@@ -0,0 +1,173 @@
+// Copyright 2020 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package upgradedatabase
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"time"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/upgrades"
+)
+
+// stallChecksPerTimeout is the number of times that the watchdog
+// checks for progress within each stall timeout period.
+const stallChecksPerTimeout = 4
+
+// stallError is returned when the upgrade steps make
+// no progress for longer than the stall timeout.
+type stallError struct {
+	step    string
+	elapsed time.Duration
+}
+
+// Error is part of the error interface.
+func (e *stallError) Error() string {
+	if e.step == "" {
+		return fmt.Sprintf("stalled before the first step after %v", e.elapsed)
+	}
+	return fmt.Sprintf("stalled in step %q after %v", e.step, e.elapsed)
+}
+
+// isStalled returns true if the error is a stallError.
+func isStalled(err error) bool {
+	_, ok := errors.Cause(err).(*stallError)
+	return ok
+}
+
+// watchdog records the last progress made by the upgrade steps.
+// Progress is either a new step being started, or a change in the
+// number of document writes made by the database server. Writes by
+// other agents also count, so a stall is only detected while the
+// controller is otherwise quiet, as it is while other workers wait
+// for the database upgrade to complete.
+type watchdog struct {
+	started      time.Time
+	step         string
+	stepStarted  time.Time
+	writes       int64
+	lastProgress time.Time
+}
+
+// performUpgradeWatched runs the upgrade steps, checking periodically that
+// they are making progress. If they make none for the stall timeout, the
+// stall is reported, diagnostics are written to the agent's log directory
+// and a *stallError is returned once the stalled steps have finished.
+// Steps cannot be interrupted, so waiting for them ensures that they are
+// never run again, by a retry or a restarted worker, while still running.
+// If resume is not empty, the steps are run from the one it describes.
+func (w *upgradeDB) performUpgradeWatched(contextGetter func() upgrades.Context, logDir string, resume string) error {
+	targets := []upgrades.Target{upgrades.DatabaseMaster}
+	perform := func() error {
+		if resume != "" {
+			return w.resumeUpgrade(w.fromVersion, targets, w.stage, resume, contextGetter, w.upgradeStepStarted)
+		}
+		return w.performUpgrade(w.fromVersion, targets, w.stage, contextGetter, w.upgradeStepStarted)
+	}
+	if w.stallTimeout == 0 {
+		return perform()
+	}
+
+	now := w.clock.Now()
+	dog := &watchdog{started: now, lastProgress: now}
+	dog.writes, _ = w.writeCount()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- perform()
+	}()
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-w.clock.After(w.stallTimeout / stallChecksPerTimeout):
+			if err := w.checkProgress(dog); err != nil {
+				w.captureStallDiagnostics(err, dog, logDir)
+				w.recordError(err)
+				w.reportStall(err)
+				w.awaitStalledSteps(done)
+				return err
+			}
+		}
+	}
+}
+
+// awaitStalledSteps waits for stalled upgrade steps to finish. If the
+// worker is killed meanwhile, its state pool is closed so that the steps
+// fail, and the worker only exits once they have.
+func (w *upgradeDB) awaitStalledSteps(done <-chan error) {
+	w.logger.Infof("waiting for stalled database upgrade steps to finish")
+	select {
+	case err := <-done:
+		w.logger.Infof("stalled database upgrade steps finished: %v", err)
+		return
+	case <-w.tomb.Dying():
+	}
+	w.closePool()
+	err := <-done
+	w.logger.Infof("stalled database upgrade steps finished: %v", err)
+}
+
+// checkProgress updates the watchdog with any progress made by
+// the upgrade steps, returning a *stallError if there has been
+// none for the stall timeout.
+func (w *upgradeDB) checkProgress(dog *watchdog) error {
+	now := w.clock.Now()
+	step, stepStarted := w.progress.currentStep()
+	if step != dog.step || !stepStarted.Equal(dog.stepStarted) {
+		dog.step = step
+		dog.stepStarted = stepStarted
+		dog.lastProgress = now
+	}
+	// If the write count cannot be read, the
+	// database is not making progress either.
+	if writes, err := w.writeCount(); err == nil && writes != dog.writes {
+		dog.writes = writes
+		dog.lastProgress = now
+	}
+	if now.Sub(dog.lastProgress) < w.stallTimeout {
+		return nil
+	}
+
+	since := dog.started
+	if dog.step != "" {
+		since = dog.stepStarted
+	}
+	return &stallError{step: dog.step, elapsed: now.Sub(since)}
+}
+
+func (w *upgradeDB) writeCount() (int64, error) {
+	writes, err := w.pool.WriteCount()
+	if err != nil {
+		w.logger.Debugf("cannot read database write count: %v", err)
+	}
+	return writes, err
+}
+
+// captureStallDiagnostics writes the stalled step and the stacks of the
+// agent's goroutines to a file in the log directory, so that the cause
+// of the stall can be found after the worker has been restarted.
+func (w *upgradeDB) captureStallDiagnostics(stallErr error, dog *watchdog, logDir string) {
+	now := w.clock.Now()
+	path := filepath.Join(logDir, fmt.Sprintf("database-upgrade-stall-%s.txt", now.UTC().Format("20060102-150405")))
+	f, err := os.Create(path)
+	if err != nil {
+		w.logger.Errorf("cannot write database upgrade stall diagnostics: %v", err)
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	_, _ = fmt.Fprintf(f, "database upgrade from %v to %v %v\n", w.fromVersion, w.toVersion, stallErr)
+	_, _ = fmt.Fprintf(f, "last progress: %s\n", dog.lastProgress.Format(time.RFC3339))
+	_, _ = fmt.Fprintf(f, "database writes: %d\n\n", dog.writes)
+	if err := pprof.Lookup("goroutine").WriteTo(f, 2); err != nil {
+		w.logger.Errorf("cannot write database upgrade stall diagnostics: %v", err)
+		return
+	}
+	w.logger.Errorf("database upgrade stall diagnostics written to %s", path)
+}
@@ -5,10 +5,13 @@ package upgradedatabase
 
 import (
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/juju/errors"
 	"github.com/juju/utils"
+	"github.com/juju/utils/set"
 	"github.com/juju/version"
 	"gopkg.in/juju/names.v3"
 	"gopkg.in/juju/worker.v1"
@@ -16,12 +19,22 @@ import (
 
 	"github.com/juju/juju/agent"
 	"github.com/juju/juju/core/status"
+	"github.com/juju/juju/mongo"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/upgrades"
 	jujuversion "github.com/juju/juju/version"
 	"github.com/juju/juju/worker/gate"
 )
 
+// restartTimeout is the time a controller waits for those ahead of it in
+// the restart order to restart, before restarting regardless.
+const restartTimeout = 10 * time.Minute
+
+// contractPollInterval is how often the primary controller checks
+// whether every agent has been upgraded, so that the deferred contract
+// steps of staged migrations can be run.
+const contractPollInterval = time.Minute
+
 // NewLock creates a gate.Lock to be used to synchronise workers
 // that need to start after database upgrades have completed.
 // The returned Lock should be passed to NewWorker.
@@ -65,11 +78,134 @@ type Config struct {
 	// We need the concrete type, because we are unable to indirect all the
 	// state methods that upgrade steps might require.
 	// This is OK for in-theatre operation, but is not suitable for testing.
-	PerformUpgrade func(version.Number, []upgrades.Target, func() upgrades.Context) error
+	// Only the steps of the input stage of the upgrade are run.
+	// The observer is notified of each step as it is started, so that the
+	// progress of the upgrade can be reported.
+	PerformUpgrade func(version.Number, []upgrades.Target, upgrades.Stage, func() upgrades.Context, upgrades.StepObserver) error
+
+	// ResumeUpgrade is a function pointer for executing the DB upgrade
+	// steps of the input stage from the step with the input description,
+	// skipping those before it. It is used to resume the steps after the
+	// Mongo primary stepped down and was re-elected while they were run.
+	// Context retrieval is lazy for the same reason as PerformUpgrade.
+	ResumeUpgrade func(version.Number, []upgrades.Target, upgrades.Stage, string, func() upgrades.Context, upgrades.StepObserver) error
+
+	// PreflightCheck is a function pointer for verifying that the host has
+	// the disk and memory capacity required by the upgrade steps, before any
+	// of them are run. It is supplied with the agent's data directory.
+	PreflightCheck func(version.Number, []upgrades.Target, string) error
+
+	// StepCollections is a function pointer for determining the database
+	// collections touched by each of the upgrade steps to be run.
+	// These are recorded in the upgrade info document so that the impact
+	// of a failed step can be assessed.
+	StepCollections func(version.Number, []upgrades.Target) []upgrades.StepCollections
+
+	// EstimateUpgrade is a function pointer for estimating how long each
+	// of the upgrade steps to be run takes, from the sizes of the
+	// collections they process, which it reads using the supplied
+	// function. The estimates, and the resulting estimated completion of
+	// the upgrade, are recorded in the upgrade info document and updated
+	// as the steps complete, so that operators can plan for the upgrade.
+	EstimateUpgrade func(version.Number, []upgrades.Target, func(string) (upgrades.CollectionStats, error)) ([]upgrades.StepEstimate, error)
+
+	// ValidateUpgrade is a function pointer for validating the upgraded
+	// database against a canary model, once the upgrade steps have run.
+	// The upgrade is only marked complete if validation succeeds.
+	// Context retrieval is lazy for the same reason as PerformUpgrade.
+	ValidateUpgrade func(func() upgrades.Context) error
+
+	// RollbackUpgrade is a function pointer for reversing the upgrade steps
+	// that have been run, when the upgrade is aborted by an operator.
+	// It is supplied with the stage of the upgrade that was being run, and
+	// the error from the last upgrade attempt, so that it can determine
+	// which steps were run.
+	// Context retrieval is lazy for the same reason as PerformUpgrade.
+	RollbackUpgrade func(version.Number, []upgrades.Target, upgrades.Stage, error, func() upgrades.Context) error
+
+	// CheckSchemaDrift is a function pointer for checking a sample of the
+	// documents in each collection migrated by upgrade steps for the shape
+	// expected by the running version, so that documents missed by past
+	// migrations are found. It is run by the primary controller, at startup
+	// and once the upgrade steps have run.
+	// Backend retrieval is lazy for the same reason as context retrieval
+	// for PerformUpgrade.
+	CheckSchemaDrift func(func() upgrades.StateBackend) ([]upgrades.SchemaDrift, error)
+
+	// GatedSteps is a function pointer for reading the upgrade steps that
+	// were skipped because the controller feature flags gating them were
+	// not set, and whether each flag is now set.
+	// Backend retrieval is lazy for the same reason as for CheckSchemaDrift.
+	GatedSteps func(func() upgrades.StateBackend) ([]upgrades.GatedStep, error)
+
+	// RunGatedSteps is a function pointer for running the skipped upgrade
+	// steps whose feature flags are now set. The primary controller runs
+	// them once the upgrade is complete, and again whenever the controller
+	// config changes, until no gated steps remain.
+	// Context retrieval is lazy for the same reason as PerformUpgrade.
+	RunGatedSteps func(func() upgrades.Context, upgrades.StepObserver) error
+
+	// StepPhases is a function pointer for determining the upgrade steps
+	// to be run that are phases of staged, expand/contract migrations.
+	// The phase of each, and whether it has run, is recorded in the
+	// upgrade info document.
+	StepPhases func(version.Number, []upgrades.Target) []upgrades.StepPhase
+
+	// ContractSteps is a function pointer for reading the contract steps
+	// deferred by upgrades until every agent has been upgraded, and
+	// whether each is now ready to run.
+	// Backend retrieval is lazy for the same reason as for CheckSchemaDrift.
+	ContractSteps func(func() upgrades.StateBackend) ([]upgrades.ContractStep, error)
+
+	// RunContractSteps is a function pointer for running the deferred
+	// contract steps that are now ready. The primary controller runs them
+	// once the upgrade is complete, and then periodically as agents are
+	// upgraded, until no contract steps remain.
+	// Context retrieval is lazy for the same reason as PerformUpgrade.
+	RunContractSteps func(func() upgrades.Context, upgrades.StepObserver) error
+
+	// EnsureIndexes is a function pointer for creating the indexes
+	// declared by the running version that are missing from the database,
+	// and reporting those that are not declared. It is run by the primary
+	// controller once the upgrade steps have run and been validated.
+	// Backend retrieval is lazy for the same reason as for CheckSchemaDrift.
+	EnsureIndexes func(func() upgrades.StateBackend) ([]state.CollectionIndexes, error)
+
+	// SweepOrphans is a function pointer for running the detectors of
+	// documents belonging to removed models, units and machines, which
+	// reports how many each finds and removes them if the controller is
+	// configured to. It is run by the primary controller as the final
+	// phase of the upgrade.
+	// Backend retrieval is lazy for the same reason as for CheckSchemaDrift.
+	SweepOrphans func(func() upgrades.StateBackend) ([]state.OrphanReport, error)
+
+	// InstalledMongoVersion is a function pointer for reading the version
+	// of the mongod installed on this controller, which its mongo server
+	// runs once restarted. The primary controller upgrades the mongo
+	// servers when it is newer than the version that they run.
+	InstalledMongoVersion func() (mongo.Version, error)
+
+	// RestartMongo is a function pointer for restarting the mongo server
+	// on this controller, so that it runs the installed mongod. Each of
+	// the controllers restarts its mongo server in turn, as a phase of
+	// the mongo upgrade recorded in the upgrade info document.
+	RestartMongo func() error
 
 	// RetryStrategy is the strategy to use for re-attempting failed upgrades.
 	RetryStrategy utils.AttemptStrategy
 
+	// StallTimeout is the period after which upgrade steps that have made
+	// no progress, neither starting a new step nor writing to the
+	// database, are considered stalled. Zero disables the watchdog.
+	StallTimeout time.Duration
+
+	// RestartOnStall determines what happens when the upgrade steps stall.
+	// If true, the worker exits with an error once the stalled steps have
+	// finished, so that it is restarted and the steps are run again.
+	// Otherwise the upgrade fails and is not retried until the agent is
+	// restarted.
+	RestartOnStall bool
+
 	// Clock is used to enforce time-out logic for controllers waiting for the
 	// master MongoDB upgrades to execute.
 	Clock Clock
@@ -99,10 +235,61 @@ func (cfg Config) Validate() error {
 	if cfg.PerformUpgrade == nil {
 		return errors.NotValidf("nil PerformUpgrade function")
 	}
+	if cfg.ResumeUpgrade == nil {
+		return errors.NotValidf("nil ResumeUpgrade function")
+	}
+	if cfg.PreflightCheck == nil {
+		return errors.NotValidf("nil PreflightCheck function")
+	}
+	if cfg.StepCollections == nil {
+		return errors.NotValidf("nil StepCollections function")
+	}
+	if cfg.EstimateUpgrade == nil {
+		return errors.NotValidf("nil EstimateUpgrade function")
+	}
+	if cfg.ValidateUpgrade == nil {
+		return errors.NotValidf("nil ValidateUpgrade function")
+	}
+	if cfg.RollbackUpgrade == nil {
+		return errors.NotValidf("nil RollbackUpgrade function")
+	}
+	if cfg.CheckSchemaDrift == nil {
+		return errors.NotValidf("nil CheckSchemaDrift function")
+	}
+	if cfg.GatedSteps == nil {
+		return errors.NotValidf("nil GatedSteps function")
+	}
+	if cfg.RunGatedSteps == nil {
+		return errors.NotValidf("nil RunGatedSteps function")
+	}
+	if cfg.StepPhases == nil {
+		return errors.NotValidf("nil StepPhases function")
+	}
+	if cfg.ContractSteps == nil {
+		return errors.NotValidf("nil ContractSteps function")
+	}
+	if cfg.RunContractSteps == nil {
+		return errors.NotValidf("nil RunContractSteps function")
+	}
+	if cfg.EnsureIndexes == nil {
+		return errors.NotValidf("nil EnsureIndexes function")
+	}
+	if cfg.SweepOrphans == nil {
+		return errors.NotValidf("nil SweepOrphans function")
+	}
+	if cfg.InstalledMongoVersion == nil {
+		return errors.NotValidf("nil InstalledMongoVersion function")
+	}
+	if cfg.RestartMongo == nil {
+		return errors.NotValidf("nil RestartMongo function")
+	}
 	a := utils.AttemptStrategy{}
 	if cfg.RetryStrategy == a {
 		return errors.NotValidf("empty RetryStrategy")
 	}
+	if cfg.StallTimeout < 0 {
+		return errors.NotValidf("negative StallTimeout")
+	}
 	if cfg.Clock == nil {
 		return errors.NotValidf("nil Clock")
 	}
@@ -116,17 +303,58 @@ type upgradeDB struct {
 	tomb            tomb.Tomb
 	upgradeComplete gate.Lock
 
-	tag            names.Tag
-	agent          agent.Agent
-	logger         Logger
-	pool           Pool
-	performUpgrade func(version.Number, []upgrades.Target, func() upgrades.Context) error
-	upgradeInfo    UpgradeInfo
-	retryStrategy  utils.AttemptStrategy
-	clock          Clock
+	tag              names.Tag
+	agent            agent.Agent
+	logger           Logger
+	pool             Pool
+	performUpgrade   func(version.Number, []upgrades.Target, upgrades.Stage, func() upgrades.Context, upgrades.StepObserver) error
+	resumeUpgrade    func(version.Number, []upgrades.Target, upgrades.Stage, string, func() upgrades.Context, upgrades.StepObserver) error
+	preflightCheck   func(version.Number, []upgrades.Target, string) error
+	stepCollections  func(version.Number, []upgrades.Target) []upgrades.StepCollections
+	estimateUpgrade  func(version.Number, []upgrades.Target, func(string) (upgrades.CollectionStats, error)) ([]upgrades.StepEstimate, error)
+	validateUpgrade  func(func() upgrades.Context) error
+	rollbackUpgrade  func(version.Number, []upgrades.Target, upgrades.Stage, error, func() upgrades.Context) error
+	checkDrift       func(func() upgrades.StateBackend) ([]upgrades.SchemaDrift, error)
+	gatedSteps       func(func() upgrades.StateBackend) ([]upgrades.GatedStep, error)
+	runGatedSteps    func(func() upgrades.Context, upgrades.StepObserver) error
+	stepPhases       func(version.Number, []upgrades.Target) []upgrades.StepPhase
+	contractSteps    func(func() upgrades.StateBackend) ([]upgrades.ContractStep, error)
+	runContractSteps func(func() upgrades.Context, upgrades.StepObserver) error
+	ensureIndexes    func(func() upgrades.StateBackend) ([]state.CollectionIndexes, error)
+	sweepOrphans     func(func() upgrades.StateBackend) ([]state.OrphanReport, error)
+	installedMongo   func() (mongo.Version, error)
+	restartMongo     func() error
+	upgradeInfo      UpgradeInfo
+	retryStrategy    utils.AttemptStrategy
+	stallTimeout     time.Duration
+	restartOnStall   bool
+	clock            Clock
 
 	fromVersion version.Number
 	toVersion   version.Number
+
+	// restarted is true if the worker has been restarted
+	// after the upgrade completed in the same agent.
+	restarted bool
+
+	// stage is the stage of the upgrade steps being run.
+	stage upgrades.Stage
+
+	// steppedDown is true if the Mongo primary stepped down on this
+	// controller to restart its mongo server during the mongo upgrade.
+	steppedDown bool
+
+	progress progress
+	estimate estimate
+	batcher  *adaptiveBatcher
+
+	// phases records the phase of each of the upgrade steps that are
+	// phases of staged migrations, and whether it has run.
+	phases []state.UpgradeStepPhaseInfo
+
+	// closePoolOnce ensures that the state pool is closed once, either
+	// when the worker exits or to stop stalled upgrade steps.
+	closePoolOnce sync.Once
 }
 
 // NewWorker validates the input configuration, then uses it to create,
@@ -139,30 +367,52 @@ func NewWorker(cfg Config) (worker.Worker, error) {
 	}
 
 	w := &upgradeDB{
-		upgradeComplete: cfg.UpgradeComplete,
-		tag:             cfg.Tag,
-		agent:           cfg.Agent,
-		logger:          cfg.Logger,
-		performUpgrade:  cfg.PerformUpgrade,
-		retryStrategy:   cfg.RetryStrategy,
-		clock:           cfg.Clock,
+		upgradeComplete:  cfg.UpgradeComplete,
+		tag:              cfg.Tag,
+		agent:            cfg.Agent,
+		logger:           cfg.Logger,
+		performUpgrade:   cfg.PerformUpgrade,
+		resumeUpgrade:    cfg.ResumeUpgrade,
+		preflightCheck:   cfg.PreflightCheck,
+		stepCollections:  cfg.StepCollections,
+		estimateUpgrade:  cfg.EstimateUpgrade,
+		validateUpgrade:  cfg.ValidateUpgrade,
+		rollbackUpgrade:  cfg.RollbackUpgrade,
+		checkDrift:       cfg.CheckSchemaDrift,
+		gatedSteps:       cfg.GatedSteps,
+		runGatedSteps:    cfg.RunGatedSteps,
+		stepPhases:       cfg.StepPhases,
+		contractSteps:    cfg.ContractSteps,
+		runContractSteps: cfg.RunContractSteps,
+		ensureIndexes:    cfg.EnsureIndexes,
+		sweepOrphans:     cfg.SweepOrphans,
+		installedMongo:   cfg.InstalledMongoVersion,
+		restartMongo:     cfg.RestartMongo,
+		retryStrategy:    cfg.RetryStrategy,
+		stallTimeout:     cfg.StallTimeout,
+		restartOnStall:   cfg.RestartOnStall,
+		clock:            cfg.Clock,
 	}
 	if w.pool, err = cfg.OpenState(); err != nil {
 		return nil, err
 	}
+	// Steps that rewrite many documents do so in batches
+	// sized according to the load on the database.
+	w.batcher = newAdaptiveBatcher(w.pool, w.logger)
+	w.pool.SetUpgradeBatcher(w.batcher)
 
 	w.tomb.Go(w.run)
 	return w, nil
 }
 
 func (w *upgradeDB) run() error {
-	defer func() {
-		if err := w.pool.Close(); err != nil {
-			w.logger.Errorf("failed closing state pool: %v", err)
-		}
-	}()
+	defer w.closePool()
 
 	if w.upgradeDone() {
+		if err := w.checkSchemaDriftOnStartup(); err != nil {
+			return errors.Trace(err)
+		}
+		w.watchDeferredSteps()
 		return nil
 	}
 
@@ -184,19 +434,28 @@ func (w *upgradeDB) run() error {
 	// If we are the primary we need to run the upgrade steps.
 	// Otherwise we watch state and unlock once the primary has run the steps.
 	if isPrimary {
-		w.runUpgrade()
-	} else {
-		w.watchUpgrade()
+		return errors.Trace(w.runUpgrade())
 	}
-
+	w.watchUpgrade()
 	return nil
 }
 
+// closePool closes the worker's state pool,
+// if it has not been closed already.
+func (w *upgradeDB) closePool() {
+	w.closePoolOnce.Do(func() {
+		if err := w.pool.Close(); err != nil {
+			w.logger.Errorf("failed closing state pool: %v", err)
+		}
+	})
+}
+
 // upgradeDone returns true if this worker
 // does not need to run any upgrade logic.
 func (w *upgradeDB) upgradeDone() bool {
 	// If we are already unlocked, there is nothing to do.
 	if w.upgradeComplete.IsUnlocked() {
+		w.restarted = true
 		return true
 	}
 
@@ -212,39 +471,765 @@ func (w *upgradeDB) upgradeDone() bool {
 	return false
 }
 
-func (w *upgradeDB) runUpgrade() {
+// runUpgrade runs the upgrade steps on the primary controller. An error
+// is only returned if the steps stalled and the worker is to be restarted.
+func (w *upgradeDB) runUpgrade() error {
+	w.setPhase(phasePreflight)
 	w.setStatus(status.Started, fmt.Sprintf("upgrading database to %v", w.toVersion))
 
-	if err := w.agent.ChangeConfig(w.runUpgradeSteps); err == nil {
+	// Abort before running any steps if we lack the capacity to complete
+	// them. Running out of disk mid-upgrade can leave the database in a
+	// state that is only recoverable by restoring the controller.
+	dataDir := w.agent.CurrentConfig().DataDir()
+	if err := w.preflightCheck(w.fromVersion, []upgrades.Target{upgrades.DatabaseMaster}, dataDir); err != nil {
+		w.logger.Errorf("database upgrade from %v to %v aborted by pre-flight check: %v",
+			w.fromVersion, w.toVersion, err)
+		w.recordError(err)
+		w.setPhase(phaseFailed)
+		w.setStatus(status.Error, fmt.Sprintf("upgrading database to %v: %v", w.toVersion, err))
+		return nil
+	}
+
+	w.recordStepCollections()
+	w.recordStepPhases()
+	w.recordEstimate()
+
+	err := w.runUpgradeSteps()
+	if isStalled(err) {
+		if w.restartOnStall {
+			return errors.Trace(err)
+		}
+		// Fail fast rather than retrying steps
+		// that have already stalled once.
+		<-w.tomb.Dying()
+		return nil
+	}
+	if err == nil {
+		w.recordExpandStepsRun()
+		w.checkIndexes()
+		w.checkOrphans()
+		w.upgradeMongo()
+		w.recordRestartOrder()
+
 		// Update the upgrade status document to unlock the other controllers.
 		if err = w.upgradeInfo.SetStatus(state.UpgradeDBComplete); err != nil {
 			w.logger.Errorf("failed to update upgrade info: %v", err)
+			w.recordError(err)
+			w.setPhase(phaseFailed)
 			w.setFailStatus()
-			return
+			return nil
 		}
 
 		w.logger.Infof("database upgrade to %v completed successfully.", w.toVersion)
+
+		// The other controllers restart first. Only once they are
+		// back is the Mongo primary stepped down, so that a controller
+		// is always available to serve the API.
+		if !w.awaitRestartTurn(nil) {
+			return nil
+		}
+		if len(w.upgradeInfo.RestartOrder()) > 1 {
+			w.stepDownBeforeRestart()
+		}
+
+		w.setPhase(phaseComplete)
 		w.setStatus(status.Started, fmt.Sprintf("database upgrade to %v completed", w.toVersion))
-		w.upgradeComplete.Unlock()
+		w.restart()
+		w.checkSchemaDrift()
+		w.watchDeferredSteps()
+	}
+	return nil
+}
+
+// stepDownBeforeRestart asks the Mongo primary to step down before this
+// controller restarts, if it still runs on this controller. Another
+// controller may have been elected primary while the upgrade ran, and
+// the primary does not step down again if it already did so to upgrade
+// the mongo server on this controller.
+func (w *upgradeDB) stepDownBeforeRestart() {
+	if w.steppedDown {
+		w.logger.Infof("mongo primary already stepped down on this controller")
+		return
+	}
+	isPrimary, err := w.pool.IsPrimary(w.tag.Id())
+	if err != nil {
+		w.logger.Errorf("failed to determine mongo primary: %v", err)
+		w.recordError(err)
+		return
+	}
+	if !isPrimary {
+		w.logger.Infof("mongo primary is no longer on this controller, not stepping down")
+		return
+	}
+	if err := w.pool.StepDownPrimary(); err != nil {
+		w.logger.Errorf("failed to step down mongo primary: %v", err)
+		w.recordError(err)
+	}
+}
+
+// recordRestartOrder records the order in which the controllers restart
+// once the database upgrade is complete: the secondaries in turn, by ID,
+// followed by the Mongo primary. Failure to record the order is logged,
+// and the controllers then restart together.
+func (w *upgradeDB) recordRestartOrder() {
+	order, err := w.controllerOrder()
+	if err != nil {
+		w.logger.Errorf("failed to read controller IDs: %v", err)
+		w.recordError(err)
+		return
+	}
+	if err := w.upgradeInfo.SetRestartOrder(order); err != nil {
+		w.logger.Errorf("failed to record controller restart order: %v", err)
+		w.recordError(err)
+	}
+}
+
+// controllerOrder returns the IDs of the controllers in the order in
+// which they take their turn once the database has been upgraded: the
+// secondaries, by ID, followed by the controller currently running the
+// Mongo primary. That need not be this controller, which may have stepped
+// down to restart its own mongo server. Should no primary be found, as
+// during an election, all controllers are ordered by ID.
+func (w *upgradeDB) controllerOrder() ([]string, error) {
+	ids, err := w.pool.ControllerIDs()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(ids) < 2 {
+		return ids, nil
+	}
+	var primary string
+	order := make([]string, 0, len(ids))
+	for _, id := range ids {
+		isPrimary, err := w.pool.IsPrimary(id)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if isPrimary && primary == "" {
+			primary = id
+			continue
+		}
+		order = append(order, id)
+	}
+	sort.Strings(order)
+	if primary != "" {
+		order = append(order, primary)
+	}
+	return order, nil
+}
+
+// pendingRestarts returns the controllers that are to restart before this
+// one, but have not yet done so. None are pending if this controller is
+// not in the restart order.
+func (w *upgradeDB) pendingRestarts() []string {
+	restarted := set.NewStrings(w.upgradeInfo.ControllersRestarted()...)
+	var pending []string
+	for _, id := range w.upgradeInfo.RestartOrder() {
+		if id == w.tag.Id() {
+			return pending
+		}
+		if !restarted.Contains(id) {
+			pending = append(pending, id)
+		}
+	}
+	return nil
+}
+
+// awaitRestartTurn waits until the controllers that are to restart before
+// this one have done so, watching for their progress with the input
+// watcher, or with a new one if it is nil. If they have not all restarted
+// within restartTimeout, this controller restarts regardless. It returns
+// false if the worker is stopped while waiting.
+func (w *upgradeDB) awaitRestartTurn(watcher state.NotifyWatcher) bool {
+	pending := w.pendingRestarts()
+	if len(pending) == 0 {
+		return true
+	}
+	w.setPhase(phaseRestarting)
+	w.setStatus(status.Started, fmt.Sprintf("waiting for controllers to restart after database upgrade to %v", w.toVersion))
+	if watcher == nil {
+		watcher = w.upgradeInfo.Watch()
+		defer func() { _ = watcher.Stop() }()
+	}
+
+	timeout := w.clock.After(restartTimeout)
+	for {
+		select {
+		case <-watcher.Changes():
+			if err := w.upgradeInfo.Refresh(); err != nil {
+				w.logger.Errorf("unable to refresh upgrade info: %v", err)
+				w.recordError(err)
+				continue
+			}
+			if pending = w.pendingRestarts(); len(pending) == 0 {
+				return true
+			}
+		case <-timeout:
+			err := errors.Errorf("timed out waiting for controllers %v to restart", pending)
+			w.logger.Errorf("%v", err)
+			w.recordError(err)
+			return true
+		case <-w.tomb.Dying():
+			return false
+		}
+	}
+}
+
+// restart unlocks the upgrade-complete gate, so that the API server
+// restarts, and records that this controller has restarted so that
+// the next in the restart order can follow.
+func (w *upgradeDB) restart() {
+	w.upgradeComplete.Unlock()
+	if err := w.upgradeInfo.SetControllerRestarted(w.tag.Id()); err != nil {
+		w.logger.Errorf("failed to record controller restart: %v", err)
+		w.recordError(err)
+	}
+}
+
+// recordStepCollections writes the collections touched by each of the
+// upgrade steps to the upgrade info document. Failure to do so is logged,
+// but does not prevent the upgrade from proceeding.
+func (w *upgradeDB) recordStepCollections() {
+	steps := w.stepCollections(w.fromVersion, []upgrades.Target{upgrades.DatabaseMaster})
+	collections := make([]state.UpgradeStepCollections, len(steps))
+	for i, step := range steps {
+		w.logger.Debugf("upgrade step %q reads %v, writes %v", step.Description, step.Read, step.Write)
+		collections[i] = state.UpgradeStepCollections{
+			Description: step.Description,
+			Read:        step.Read,
+			Write:       step.Write,
+		}
+	}
+	if err := w.upgradeInfo.SetStepCollections(collections); err != nil {
+		w.logger.Errorf("failed to record upgrade step collections: %v", err)
+	}
+}
+
+// recordStepPhases writes the phase of each of the upgrade steps that are
+// phases of staged migrations to the upgrade info document. Nothing is
+// written if there are no such steps. Failure to do so is logged, but
+// does not prevent the upgrade from proceeding.
+func (w *upgradeDB) recordStepPhases() {
+	steps := w.stepPhases(w.fromVersion, []upgrades.Target{upgrades.DatabaseMaster})
+	if len(steps) == 0 {
+		return
+	}
+	w.phases = make([]state.UpgradeStepPhaseInfo, len(steps))
+	for i, step := range steps {
+		w.logger.Debugf("upgrade step %q is the %s phase of a staged migration", step.Description, step.Phase)
+		w.phases[i] = state.UpgradeStepPhaseInfo{
+			Description: step.Description,
+			Phase:       state.UpgradeStepPhase(step.Phase),
+		}
+	}
+	if err := w.upgradeInfo.SetStepPhases(w.phases); err != nil {
+		w.logger.Errorf("failed to record upgrade step phases: %v", err)
+	}
+}
+
+// recordExpandStepsRun records in the upgrade info document that the
+// expand steps have run, once the upgrade steps have completed. The
+// contract steps are deferred until every agent has been upgraded, so
+// remain outstanding.
+func (w *upgradeDB) recordExpandStepsRun() {
+	if len(w.phases) == 0 {
+		return
+	}
+	for i, step := range w.phases {
+		if step.Phase == state.UpgradeExpandPhase {
+			w.phases[i].Done = true
+		}
+	}
+	if err := w.upgradeInfo.SetStepPhases(w.phases); err != nil {
+		w.logger.Errorf("failed to record upgrade step phases: %v", err)
+	}
+}
+
+// recordEstimate writes the estimated duration of each of the upgrade
+// steps, and the estimated completion of the upgrade, to the upgrade
+// info document. Failure to estimate or record them is logged, but does
+// not prevent the upgrade from proceeding.
+func (w *upgradeDB) recordEstimate() {
+	estimates, err := w.estimateUpgrade(w.fromVersion, []upgrades.Target{upgrades.DatabaseMaster}, w.collectionStats)
+	if err != nil {
+		w.logger.Errorf("failed to estimate upgrade duration: %v", err)
+		return
+	}
+	if len(estimates) == 0 {
+		return
+	}
+	steps, completion := w.estimate.set(estimates, w.clock.Now())
+	w.logger.Infof("database upgrade to %v estimated to complete at %v", w.toVersion, completion.Format(time.RFC3339))
+	w.publishEstimate(steps, completion)
+}
+
+// collectionStats returns the size of the named collection,
+// for estimating the duration of the upgrade steps.
+func (w *upgradeDB) collectionStats(name string) (upgrades.CollectionStats, error) {
+	count, size, err := w.pool.CollectionStats(name)
+	if err != nil {
+		return upgrades.CollectionStats{}, errors.Trace(err)
+	}
+	return upgrades.CollectionStats{Count: count, Size: size}, nil
+}
+
+// publishEstimate writes the estimate to the upgrade info document.
+func (w *upgradeDB) publishEstimate(steps []state.UpgradeStepEstimate, completion time.Time) {
+	if err := w.upgradeInfo.SetEstimate(steps, completion); err != nil {
+		w.logger.Errorf("failed to record upgrade estimate: %v", err)
 	}
 }
 
 // runUpgradeSteps runs the required database upgrade steps for the agent,
-// retrying on failure.
-func (w *upgradeDB) runUpgradeSteps(agentConfig agent.ConfigSetter) error {
+// then validates the result against the canary model. The schema steps are
+// run first, and the backfill steps once the upgrade has been approved, if
+// the controller config requires approval.
+// The agent config is only held while steps are run, rolled back or
+// validated, not while waiting for approval or for a Mongo primary to be
+// elected, as other workers in the agent cannot read it meanwhile.
+func (w *upgradeDB) runUpgradeSteps() error {
+	if err := w.runStage(upgrades.StageSchema); err != nil {
+		return errors.Trace(err)
+	}
+	if err := w.awaitApproval(); err != nil {
+		return errors.Trace(err)
+	}
+	if err := w.runStage(upgrades.StageBackfill); err != nil {
+		return errors.Trace(err)
+	}
+	if steps, completion, ok := w.estimate.finish(w.clock.Now()); ok {
+		w.publishEstimate(steps, completion)
+	}
+
+	w.setPhase(phaseValidating)
+	err := w.withAgentConfig(func(contextGetter func() upgrades.Context, _ string) error {
+		return w.validateUpgrade(contextGetter)
+	})
+	if err != nil {
+		w.logger.Errorf("database upgrade from %v to %v failed validation: %v", w.fromVersion, w.toVersion, err)
+		w.recordError(err)
+		w.setPhase(phaseFailed)
+		w.setStatus(status.Error, fmt.Sprintf("validating database upgrade to %v: %v", w.toVersion, err))
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// withAgentConfig calls f with a getter of the upgrade context and the
+// agent's log directory, holding the agent config for as long as f runs.
+func (w *upgradeDB) withAgentConfig(f func(contextGetter func() upgrades.Context, logDir string) error) error {
+	return w.agent.ChangeConfig(func(agentConfig agent.ConfigSetter) error {
+		return f(w.contextGetter(agentConfig), agentConfig.LogDir())
+	})
+}
+
+// performStage runs the upgrade steps of the current stage, from the
+// step described by resume if it is not empty, holding the agent config
+// while they run.
+func (w *upgradeDB) performStage(resume string) error {
+	return w.withAgentConfig(func(contextGetter func() upgrades.Context, logDir string) error {
+		return w.performUpgradeWatched(contextGetter, logDir, resume)
+	})
+}
+
+// runStage runs the database upgrade steps of the input stage, retrying
+// on failure. If the upgrade is aborted by an operator, the attempt is
+// stopped before its next step, or if it has failed, is not retried, and
+// the steps that were run are rolled back. Stalled steps are neither
+// retried nor rolled back, as they may still be running. Steps
+// interrupted by the Mongo primary stepping down are resumed once this
+// controller is re-elected, without using up an attempt; if another
+// controller is elected instead, the upgrade fails.
+func (w *upgradeDB) runStage(stage upgrades.Stage) error {
 	var upgradeErr error
-	contextGetter := w.contextGetter(agentConfig)
+	w.stage = stage
 
+	first := true
 	for attempt := w.retryStrategy.Start(); attempt.Next(); {
-		upgradeErr = w.performUpgrade(w.fromVersion, []upgrades.Target{upgrades.DatabaseMaster}, contextGetter)
+		w.setPhase(phaseRunning)
+		// The first attempt at the backfill steps continues
+		// the attempt that ran the schema steps.
+		if stage == upgrades.StageSchema || !first {
+			w.progress.startAttempt()
+			w.estimate.startAttempt()
+		}
+		first = false
+		upgradeErr = w.performStage("")
+		upgradeErr = w.resumeAfterElection(upgradeErr)
 		if upgradeErr == nil {
 			break
+		}
+		if errors.Cause(upgradeErr) == tomb.ErrDying {
+			return errors.Trace(upgradeErr)
+		}
+		if isStalled(upgradeErr) {
+			// The stall was reported by the watchdog.
+			return errors.Trace(upgradeErr)
+		}
+		w.recordError(upgradeErr)
+		if isPrimaryMoved(upgradeErr) {
+			w.reportPrimaryMoved(upgradeErr)
+			return errors.Trace(upgradeErr)
+		}
+		if w.upgradeAborted() {
+			w.rollback(upgradeErr)
+			return errors.Annotate(upgradeErr, "upgrade aborted")
+		}
+		w.reportUpgradeFailure(upgradeErr, attempt.HasNext())
+	}
+	if upgradeErr != nil {
+		w.setPhase(phaseFailed)
+		return errors.Trace(upgradeErr)
+	}
+	return nil
+}
+
+// awaitApproval pauses the upgrade, if the controller config requires
+// it, until an operator approves the upgrade to proceed. This separates
+// the schema steps, which migrate the structure of the data, from the
+// backfill steps, which fill in the data of existing documents, and the
+// creation of the indexes and the restart of the controllers that
+// complete the upgrade. If the upgrade is aborted instead, the schema
+// steps are rolled back, and an error is returned.
+func (w *upgradeDB) awaitApproval() error {
+	cfg, err := w.pool.ControllerConfig()
+	if err != nil {
+		w.recordError(err)
+		w.setPhase(phaseFailed)
+		w.setFailStatus()
+		return errors.Annotate(err, "reading controller config")
+	}
+	if !cfg.UpgradeRequiresApproval() {
+		return nil
+	}
+	// The approval survives the worker restarting, in
+	// which case the steps are not paused a second time.
+	if err := w.upgradeInfo.Refresh(); err != nil {
+		w.recordError(err)
+		w.setPhase(phaseFailed)
+		w.setFailStatus()
+		return errors.Annotate(err, "refreshing upgrade info")
+	}
+	if w.upgradeInfo.Approved() {
+		return nil
+	}
+
+	w.setPhase(phaseAwaitingApproval)
+	w.setStatus(status.Started, fmt.Sprintf("waiting for approval of database upgrade to %v", w.toVersion))
+	if err := w.upgradeInfo.SetAwaitingApproval(); err != nil {
+		w.recordError(err)
+		w.setPhase(phaseFailed)
+		w.setFailStatus()
+		return errors.Trace(err)
+	}
+	w.logger.Infof("database upgrade to %v is awaiting approval", w.toVersion)
+
+	watcher := w.upgradeInfo.Watch()
+	defer func() { _ = watcher.Stop() }()
+	for {
+		select {
+		case <-watcher.Changes():
+			if w.upgradeAborted() {
+				err := errors.New("upgrade aborted while awaiting approval")
+				w.rollback(nil)
+				return errors.Trace(err)
+			}
+			if w.upgradeInfo.Approved() {
+				w.logger.Infof("database upgrade to %v approved", w.toVersion)
+				w.setStatus(status.Started, fmt.Sprintf("upgrading database to %v", w.toVersion))
+				return nil
+			}
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		}
+	}
+}
+
+// upgradeAborted returns true if an operator has aborted the upgrade,
+// which archives the upgrade info document with an aborted status.
+func (w *upgradeDB) upgradeAborted() bool {
+	if err := w.upgradeInfo.Refresh(); err != nil {
+		if errors.IsNotFound(err) {
+			return true
+		}
+		w.logger.Errorf("unable to refresh upgrade info: %v", err)
+		return false
+	}
+	return w.upgradeInfo.Status() == state.UpgradeAborted
+}
+
+// rollback reverses the upgrade steps that were run before the upgrade was
+// aborted, reporting the steps that prevent it if that is not possible.
+func (w *upgradeDB) rollback(upgradeErr error) {
+	w.logger.Infof("database upgrade to %v aborted, rolling back", w.toVersion)
+	w.setPhase(phaseRollingBack)
+	err := w.withAgentConfig(func(contextGetter func() upgrades.Context, _ string) error {
+		return w.rollbackUpgrade(w.fromVersion, []upgrades.Target{upgrades.DatabaseMaster}, w.stage, upgradeErr, contextGetter)
+	})
+	if err != nil {
+		w.logger.Errorf("rolling back database upgrade from %v to %v failed: %v", w.fromVersion, w.toVersion, err)
+		w.recordError(err)
+		w.setPhase(phaseFailed)
+		w.setStatus(status.Error, fmt.Sprintf("rolling back database upgrade to %v: %v", w.toVersion, err))
+		return
+	}
+	w.logger.Infof("database upgrade to %v rolled back", w.toVersion)
+	w.setPhase(phaseRolledBack)
+	w.setStatus(status.Error, fmt.Sprintf("database upgrade to %v aborted and rolled back", w.toVersion))
+}
+
+// checkSchemaDriftOnStartup checks the database for schema drift when
+// the agent starts with no upgrade to run. As the check only reads the
+// database, it is run by the primary controller alone. It is not run
+// again when the worker is restarted in the same agent.
+func (w *upgradeDB) checkSchemaDriftOnStartup() error {
+	if w.restarted {
+		return nil
+	}
+	isPrimary, err := w.pool.IsPrimary(w.tag.Id())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if isPrimary {
+		w.checkSchemaDrift()
+	}
+	return nil
+}
+
+// checkSchemaDrift checks a sample of the documents in each collection
+// migrated by upgrade steps for the shape expected by this version,
+// logging an error for each collection with documents that have been
+// missed by past migrations. Failure to check is logged, but does not
+// stop the worker.
+func (w *upgradeDB) checkSchemaDrift() {
+	drift, err := w.checkDrift(w.stateBackend)
+	if err != nil {
+		w.logger.Errorf("checking database for schema drift: %v", err)
+		return
+	}
+	for _, d := range drift {
+		w.logger.Errorf(
+			"schema drift in collection %q: expected version %d, recorded version %d, %d sampled documents missing fields",
+			d.Collection, d.ExpectedVersion, d.RecordedVersion, len(d.MissingFields),
+		)
+	}
+	w.progress.setDrift(drift, w.clock.Now())
+}
+
+// checkIndexes creates the indexes declared by the upgraded version that
+// are missing from the database, so that queries relying on them are not
+// slow after the upgrade, and logs those that are not declared so that
+// they can be removed. Failure is logged, but does not fail the upgrade,
+// as missing indexes affect the performance of queries, not their results.
+func (w *upgradeDB) checkIndexes() {
+	w.setPhase(phaseIndexing)
+	indexes, err := w.ensureIndexes(w.stateBackend)
+	if err != nil {
+		w.logger.Errorf("ensuring database indexes: %v", err)
+		w.recordError(err)
+		return
+	}
+	for _, ci := range indexes {
+		if len(ci.Created) > 0 {
+			w.logger.Infof("created missing indexes %v of collection %q", ci.Created, ci.Collection)
+		}
+		if len(ci.Unexpected) > 0 {
+			w.logger.Infof("collection %q has indexes %v not declared by %v, which may be stale",
+				ci.Collection, ci.Unexpected, w.toVersion)
+		}
+	}
+	w.progress.setIndexes(indexes, w.clock.Now())
+}
+
+// checkOrphans sweeps the database for documents left behind by removed
+// models, units and machines, logging how many each detector finds, and
+// removing them if the controller is configured to. As with the indexes,
+// failure is logged, but does not fail the upgrade.
+func (w *upgradeDB) checkOrphans() {
+	w.setPhase(phaseSweeping)
+	orphans, err := w.sweepOrphans(w.stateBackend)
+	if err != nil {
+		w.logger.Errorf("sweeping database for orphaned documents: %v", err)
+		w.recordError(err)
+		return
+	}
+	for _, o := range orphans {
+		if o.Removed {
+			w.logger.Infof("removed %d documents of collection %q orphaned by %s", o.Count, o.Collection, o.Detector)
 		} else {
-			w.reportUpgradeFailure(upgradeErr, attempt.HasNext())
+			w.logger.Infof("found %d documents of collection %q orphaned by %s", o.Count, o.Collection, o.Detector)
 		}
 	}
+	w.progress.setOrphans(orphans, w.clock.Now())
+}
 
-	return errors.Trace(upgradeErr)
+// watchDeferredSteps runs, on the primary controller, the gated upgrade
+// steps and the deferred contract steps as they become ready to run,
+// until none remain or the worker is stopped.
+func (w *upgradeDB) watchDeferredSteps() {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.watchContractSteps()
+	}()
+	w.watchGatedSteps()
+	wg.Wait()
+}
+
+// watchContractSteps runs, on the primary controller, the contract steps
+// of staged migrations that were deferred by upgrades, once every agent
+// runs at least the version that declared them. Agent versions are not
+// watched, so while any contract steps remain they are checked
+// periodically, until the worker is stopped.
+func (w *upgradeDB) watchContractSteps() {
+	steps, err := w.contractSteps(w.stateBackend)
+	if err != nil {
+		w.logger.Errorf("reading contract upgrade steps: %v", err)
+		w.recordError(err)
+		return
+	}
+	if len(steps) == 0 {
+		return
+	}
+	isPrimary, err := w.pool.IsPrimary(w.tag.Id())
+	if err != nil {
+		w.logger.Errorf("checking for mongo primary: %v", err)
+		w.recordError(err)
+		return
+	}
+	if !isPrimary {
+		return
+	}
+
+	for w.runReadyContractSteps() {
+		select {
+		case <-w.clock.After(contractPollInterval):
+		case <-w.tomb.Dying():
+			return
+		}
+	}
+}
+
+// runReadyContractSteps runs the deferred contract steps that are now
+// ready. It returns true if any contract steps remain, either because
+// agents of earlier versions remain or because they failed. Failures are
+// logged, and the steps are attempted again at the next check.
+func (w *upgradeDB) runReadyContractSteps() bool {
+	steps, err := w.contractSteps(w.stateBackend)
+	if err != nil {
+		w.logger.Errorf("reading contract upgrade steps: %v", err)
+		w.recordError(err)
+		return true
+	}
+	var ready, pending []string
+	for _, step := range steps {
+		if step.Ready {
+			ready = append(ready, step.Description)
+		} else {
+			pending = append(pending, step.Description)
+		}
+	}
+	if len(pending) > 0 {
+		w.logger.Debugf("contract upgrade steps awaiting agent upgrades: %v", pending)
+	}
+	if len(ready) == 0 {
+		return len(pending) > 0
+	}
+
+	w.logger.Infof("running contract upgrade steps now that all agents are upgraded: %v", ready)
+	err = w.agent.ChangeConfig(func(agentConfig agent.ConfigSetter) error {
+		return w.runContractSteps(w.contextGetter(agentConfig), w.stepStarted)
+	})
+	if err != nil {
+		w.logger.Errorf("running contract upgrade steps: %v", err)
+		w.recordError(err)
+		return true
+	}
+	return len(pending) > 0
+}
+
+// watchGatedSteps runs, on the primary controller, the upgrade steps that
+// were skipped because their feature flags were not set, once the flags
+// are enabled. While any gated steps remain, it watches the controller
+// config for flags being enabled, until the worker is stopped.
+func (w *upgradeDB) watchGatedSteps() {
+	steps, err := w.gatedSteps(w.stateBackend)
+	if err != nil {
+		w.logger.Errorf("reading gated upgrade steps: %v", err)
+		w.recordError(err)
+		return
+	}
+	if len(steps) == 0 {
+		return
+	}
+	isPrimary, err := w.pool.IsPrimary(w.tag.Id())
+	if err != nil {
+		w.logger.Errorf("checking for mongo primary: %v", err)
+		w.recordError(err)
+		return
+	}
+	if !isPrimary {
+		return
+	}
+
+	// The watcher notifies immediately, so that the steps
+	// whose flags are already set are run straight away.
+	watcher := w.pool.WatchControllerConfig()
+	defer func() { _ = watcher.Stop() }()
+	for {
+		select {
+		case <-watcher.Changes():
+			if !w.runEnabledGatedSteps() {
+				return
+			}
+		case <-w.tomb.Dying():
+			return
+		}
+	}
+}
+
+// runEnabledGatedSteps runs the gated upgrade steps whose feature flags
+// are now set. It returns true if any gated steps remain, either because
+// their flags are not set or because they failed. Failures are logged,
+// and the steps are attempted again when the controller config changes.
+func (w *upgradeDB) runEnabledGatedSteps() bool {
+	steps, err := w.gatedSteps(w.stateBackend)
+	if err != nil {
+		w.logger.Errorf("reading gated upgrade steps: %v", err)
+		w.recordError(err)
+		return true
+	}
+	var enabled, pending []string
+	for _, step := range steps {
+		if step.Enabled {
+			enabled = append(enabled, step.Description)
+		} else {
+			pending = append(pending, step.Description)
+		}
+	}
+	if len(pending) > 0 {
+		w.logger.Debugf("upgrade steps awaiting feature flags: %v", pending)
+	}
+	if len(enabled) == 0 {
+		return len(pending) > 0
+	}
+
+	w.logger.Infof("running upgrade steps enabled by feature flags: %v", enabled)
+	err = w.agent.ChangeConfig(func(agentConfig agent.ConfigSetter) error {
+		return w.runGatedSteps(w.contextGetter(agentConfig), w.stepStarted)
+	})
+	if err != nil {
+		w.logger.Errorf("running gated upgrade steps: %v", err)
+		w.recordError(err)
+		return true
+	}
+	return len(pending) > 0
+}
+
+// stateBackend returns an upgrades.StateBackend for the state pool.
+func (w *upgradeDB) stateBackend() upgrades.StateBackend {
+	return upgrades.NewStateBackend(w.pool.(*pool).StatePool)
 }
 
 // contextGetter returns a function that creates an upgrade context.
@@ -258,27 +1243,60 @@ func (w *upgradeDB) contextGetter(agentConfig agent.ConfigSetter) func() upgrade
 }
 
 func (w *upgradeDB) watchUpgrade() {
+	w.setPhase(phaseWaiting)
 	w.setStatus(status.Started, fmt.Sprintf("waiting on primary database upgrade to %v", w.toVersion))
 
 	timeout := w.clock.After(10 * time.Minute)
 	watcher := w.upgradeInfo.Watch()
 	defer func() { _ = watcher.Stop() }()
 
+	var paused string
 	for {
 		select {
 		case <-watcher.Changes():
 			if err := w.upgradeInfo.Refresh(); err != nil {
 				w.logger.Errorf("unable to refresh upgrade info: %v", err)
+				w.recordError(err)
+				w.setPhase(phaseFailed)
 				w.setFailStatus()
 				return
 			}
 			if w.upgradeInfo.Status() == state.UpgradeDBComplete {
+				if !w.awaitRestartTurn(watcher) {
+					return
+				}
+				w.setPhase(phaseComplete)
 				w.setStatus(status.Started, fmt.Sprintf("confirmed primary database upgrade to %v", w.toVersion))
-				w.upgradeComplete.Unlock()
+				w.restart()
 				return
 			}
+			w.upgradeMongoTurn()
+
+			// An upgrade awaiting approval, or upgrading the mongo
+			// servers, can be paused for longer than the primary is
+			// otherwise given to complete it, so the timeout starts
+			// again once the upgrade proceeds.
+			if next := w.pausedPhase(); next != paused {
+				paused = next
+				switch paused {
+				case phaseAwaitingApproval:
+					timeout = nil
+					w.setPhase(phaseAwaitingApproval)
+					w.setStatus(status.Started, fmt.Sprintf("waiting for approval of database upgrade to %v", w.toVersion))
+				case phaseUpgradingMongo:
+					timeout = nil
+					w.setPhase(phaseUpgradingMongo)
+					w.setStatus(status.Started, fmt.Sprintf("waiting for mongo upgrade to %v", w.upgradeInfo.MongoTargetVersion()))
+				default:
+					timeout = w.clock.After(10 * time.Minute)
+					w.setPhase(phaseWaiting)
+					w.setStatus(status.Started, fmt.Sprintf("waiting on primary database upgrade to %v", w.toVersion))
+				}
+			}
 		case <-timeout:
 			w.logger.Errorf("timed out waiting for primary database upgrade")
+			w.recordError(errors.New("timed out waiting for primary database upgrade"))
+			w.setPhase(phaseFailed)
 			w.setFailStatus()
 			return
 		case <-w.tomb.Dying():
@@ -298,6 +1316,13 @@ func (w *upgradeDB) reportUpgradeFailure(err error, willRetry bool) {
 	w.setFailStatus()
 }
 
+// reportStall reports that the upgrade steps have stalled.
+func (w *upgradeDB) reportStall(err error) {
+	w.logger.Errorf("database upgrade from %v to %v %v", w.fromVersion, w.toVersion, err)
+	w.setPhase(phaseFailed)
+	w.setStatus(status.Error, fmt.Sprintf("upgrading database to %v: %v", w.toVersion, err))
+}
+
 func (w *upgradeDB) setFailStatus() {
 	w.setStatus(status.Error, fmt.Sprintf("upgrading database to %v", w.toVersion))
 }
@@ -308,6 +1333,68 @@ func (w *upgradeDB) setStatus(sts status.Status, msg string) {
 	}
 }
 
+// setPhase records the phase of the upgrade for the worker's report.
+func (w *upgradeDB) setPhase(phase string) {
+	w.progress.setPhase(phase, w.fromVersion, w.toVersion, w.clock.Now())
+}
+
+// upgradeStepStarted is the upgrades.StepObserver passed when performing
+// the upgrade. The upgrade is stopped before the step is run if it has
+// been aborted by an operator, so that the steps already run can be
+// rolled back without waiting for the rest to complete.
+func (w *upgradeDB) upgradeStepStarted(description string) error {
+	if w.upgradeAborted() {
+		return errors.New("upgrade aborted")
+	}
+	return w.stepStarted(description)
+}
+
+// stepStarted is the upgrades.StepObserver passed when running gated
+// and contract steps, recording each step for the worker's report, and
+// updating the estimated completion of the upgrade.
+func (w *upgradeDB) stepStarted(description string) error {
+	now := w.clock.Now()
+	w.progress.startStep(description, now)
+	if steps, completion, ok := w.estimate.startStep(description, now); ok {
+		w.publishEstimate(steps, completion)
+	}
+	return nil
+}
+
+// recordError records an upgrade error for the worker's report.
+func (w *upgradeDB) recordError(err error) {
+	w.progress.addError(err, w.clock.Now())
+}
+
+// Report is part of the dependency.Reporter interface. The progress of
+// the upgrade is served by the agent's introspection socket, so that it
+// is available on the controller when the API server is not.
+func (w *upgradeDB) Report() map[string]interface{} {
+	report := make(map[string]interface{})
+	if progress := w.progress.report(w.clock.Now()); progress != nil {
+		report["database-upgrade"] = progress
+	}
+	if estimate := w.estimate.report(w.clock.Now()); estimate != nil {
+		report["estimate"] = estimate
+	}
+	if drift := w.progress.driftReport(); drift != nil {
+		report["schema-drift"] = drift
+	}
+	if indexes := w.progress.indexReport(); indexes != nil {
+		report["indexes"] = indexes
+	}
+	if orphans := w.progress.orphanReport(); orphans != nil {
+		report["orphans"] = orphans
+	}
+	if mongoUpgrade := w.progress.mongoUpgradeReport(); mongoUpgrade != nil {
+		report["mongo-upgrade"] = mongoUpgrade
+	}
+	if batching := w.batcher.report(); batching != nil {
+		report["batching"] = batching
+	}
+	return report
+}
+
 // Kill is part of the worker.Worker interface.
 func (w *upgradeDB) Kill() {
 	w.tomb.Kill(nil)
@@ -5,6 +5,9 @@ package upgradedatabase_test
 
 import (
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/golang/mock/gomock"
@@ -21,7 +24,9 @@ import (
 	"gopkg.in/juju/worker.v1/workertest"
 
 	"github.com/juju/juju/agent"
+	"github.com/juju/juju/controller"
 	"github.com/juju/juju/core/status"
+	"github.com/juju/juju/mongo"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/upgrades"
 	jujuversion "github.com/juju/juju/version"
@@ -72,6 +77,10 @@ type workerSuite struct {
 	pool        *MockPool
 	upgradeInfo *MockUpgradeInfo
 	watcher     *MockNotifyWatcher
+
+	// logDir is the log directory of the agent config
+	// held while the upgrade steps are run.
+	logDir string
 }
 
 var _ = gc.Suite(&workerSuite{})
@@ -107,10 +116,78 @@ func (s *workerSuite) TestValidateConfig(c *gc.C) {
 	cfg.PerformUpgrade = nil
 	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
 
+	cfg = s.getConfig()
+	cfg.ResumeUpgrade = nil
+	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
+
+	cfg = s.getConfig()
+	cfg.PreflightCheck = nil
+	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
+
+	cfg = s.getConfig()
+	cfg.StepCollections = nil
+	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
+
+	cfg = s.getConfig()
+	cfg.EstimateUpgrade = nil
+	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
+
+	cfg = s.getConfig()
+	cfg.ValidateUpgrade = nil
+	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
+
+	cfg = s.getConfig()
+	cfg.RollbackUpgrade = nil
+	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
+
+	cfg = s.getConfig()
+	cfg.CheckSchemaDrift = nil
+	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
+
+	cfg = s.getConfig()
+	cfg.GatedSteps = nil
+	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
+
+	cfg = s.getConfig()
+	cfg.RunGatedSteps = nil
+	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
+
+	cfg = s.getConfig()
+	cfg.StepPhases = nil
+	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
+
+	cfg = s.getConfig()
+	cfg.ContractSteps = nil
+	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
+
+	cfg = s.getConfig()
+	cfg.RunContractSteps = nil
+	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
+
+	cfg = s.getConfig()
+	cfg.EnsureIndexes = nil
+	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
+
+	cfg = s.getConfig()
+	cfg.SweepOrphans = nil
+	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
+
+	cfg = s.getConfig()
+	cfg.InstalledMongoVersion = nil
+	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
+
+	cfg = s.getConfig()
+	cfg.RestartMongo = nil
+	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
+
 	cfg = s.getConfig()
 	cfg.RetryStrategy = utils.AttemptStrategy{}
 	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
 
+	cfg = s.getConfig()
+	cfg.StallTimeout = -time.Minute
+	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
+
 	cfg = s.getConfig()
 	cfg.Clock = nil
 	c.Check(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
@@ -152,11 +229,221 @@ func (s *workerSuite) TestAlreadyUpgradedNoWork(c *gc.C) {
 	s.agent.EXPECT().CurrentConfig().Return(s.agentCfg)
 	s.agentCfg.EXPECT().UpgradedToVersion().Return(jujuversion.Current)
 	s.lock.EXPECT().Unlock()
+	s.pool.EXPECT().IsPrimary("0").Return(false, nil)
 
-	w, err := upgradedatabase.NewWorker(s.getConfig())
+	cfg := s.getConfig()
+	cfg.CheckSchemaDrift = func(func() upgrades.StateBackend) ([]upgrades.SchemaDrift, error) {
+		c.Fatalf("schema drift checked on secondary")
+		return nil, nil
+	}
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestAlreadyUpgradedPrimaryChecksSchemaDrift(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	s.lock.EXPECT().IsUnlocked().Return(false)
+	s.agent.EXPECT().CurrentConfig().Return(s.agentCfg)
+	s.agentCfg.EXPECT().UpgradedToVersion().Return(jujuversion.Current)
+	s.logger.EXPECT().Debugf(gomock.Any(), gomock.Any()).AnyTimes()
+	s.lock.EXPECT().Unlock()
+	s.pool.EXPECT().IsPrimary("0").Return(true, nil)
+	s.logger.EXPECT().Errorf(
+		"schema drift in collection %q: expected version %d, recorded version %d, %d sampled documents missing fields",
+		"units", 2, 1, 1,
+	)
+
+	clk := testclock.NewClock(time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC))
+	cfg := s.getConfig()
+	cfg.Clock = clk
+	cfg.CheckSchemaDrift = func(func() upgrades.StateBackend) ([]upgrades.SchemaDrift, error) {
+		return []upgrades.SchemaDrift{{
+			Collection:      "units",
+			ExpectedVersion: 2,
+			RecordedVersion: 1,
+			MissingFields:   map[string][]string{"uuid:mysql/0": {"machineid"}},
+		}}, nil
+	}
+	w, err := upgradedatabase.NewWorker(cfg)
 	c.Assert(err, jc.ErrorIsNil)
+	workertest.CleanKill(c, w)
+
+	reporter, ok := w.(interface{ Report() map[string]interface{} })
+	c.Assert(ok, jc.IsTrue)
+	c.Check(reporter.Report(), jc.DeepEquals, map[string]interface{}{
+		"schema-drift": map[string]interface{}{
+			"checked": "2020-05-01T12:00:00Z",
+			"collections": map[string]interface{}{
+				"units": map[string]interface{}{
+					"expected-version": 2,
+					"recorded-version": 1,
+					"missing-fields":   map[string][]string{"uuid:mysql/0": {"machineid"}},
+				},
+			},
+		},
+	})
+}
+
+func (s *workerSuite) TestSchemaDriftCheckFailureLogged(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	s.lock.EXPECT().IsUnlocked().Return(false)
+	s.agent.EXPECT().CurrentConfig().Return(s.agentCfg)
+	s.agentCfg.EXPECT().UpgradedToVersion().Return(jujuversion.Current)
+	s.logger.EXPECT().Debugf(gomock.Any(), gomock.Any()).AnyTimes()
+	s.lock.EXPECT().Unlock()
+	s.pool.EXPECT().IsPrimary("0").Return(true, nil)
+	s.logger.EXPECT().Errorf("checking database for schema drift: %v", gomock.Any())
 
+	cfg := s.getConfig()
+	cfg.CheckSchemaDrift = func(func() upgrades.StateBackend) ([]upgrades.SchemaDrift, error) {
+		return nil, errors.New("boom")
+	}
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
 	workertest.CleanKill(c, w)
+
+	reporter, ok := w.(interface{ Report() map[string]interface{} })
+	c.Assert(ok, jc.IsTrue)
+	c.Check(reporter.Report(), gc.HasLen, 0)
+}
+
+func (s *workerSuite) TestAlreadyUpgradedRunsGatedStepsOnceEnabled(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.lock.EXPECT().IsUnlocked().Return(false)
+	s.agent.EXPECT().CurrentConfig().Return(s.agentCfg)
+	s.agentCfg.EXPECT().UpgradedToVersion().Return(jujuversion.Current)
+	s.lock.EXPECT().Unlock()
+	s.pool.EXPECT().IsPrimary("0").Return(true, nil).Times(2)
+
+	// The flag is not set when the worker starts, nor when the
+	// initial event arrives, but is enabled by the next change.
+	s.pool.EXPECT().WatchControllerConfig().Return(s.watcher)
+	changes := make(chan struct{}, 2)
+	changes <- struct{}{}
+	changes <- struct{}{}
+	s.watcher.EXPECT().Changes().Return(changes).MinTimes(1)
+	s.agent.EXPECT().ChangeConfig(gomock.Any()).DoAndReturn(func(f agent.ConfigMutator) error {
+		return f(s.cfgSetter)
+	})
+
+	reads := 0
+	ran := make(chan struct{}, 1)
+	cfg := s.getConfig()
+	cfg.GatedSteps = func(func() upgrades.StateBackend) ([]upgrades.GatedStep, error) {
+		reads++
+		step := upgrades.GatedStep{Description: "split unit docs", FeatureFlag: "unit-split"}
+		step.Enabled = reads > 2
+		return []upgrades.GatedStep{step}, nil
+	}
+	cfg.RunGatedSteps = func(func() upgrades.Context, upgrades.StepObserver) error {
+		ran <- struct{}{}
+		return nil
+	}
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	select {
+	case <-ran:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for gated steps to run")
+	}
+	// With no gated steps remaining, the worker stops watching.
+	c.Assert(workertest.CheckKilled(c, w), jc.ErrorIsNil)
+	c.Check(reads, gc.Equals, 3)
+}
+
+func (s *workerSuite) TestAlreadyUpgradedSecondaryIgnoresGatedSteps(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.lock.EXPECT().IsUnlocked().Return(false)
+	s.agent.EXPECT().CurrentConfig().Return(s.agentCfg)
+	s.agentCfg.EXPECT().UpgradedToVersion().Return(jujuversion.Current)
+	s.lock.EXPECT().Unlock()
+	s.pool.EXPECT().IsPrimary("0").Return(false, nil).Times(2)
+
+	cfg := s.getConfig()
+	cfg.GatedSteps = func(func() upgrades.StateBackend) ([]upgrades.GatedStep, error) {
+		return []upgrades.GatedStep{{Description: "split unit docs", FeatureFlag: "unit-split", Enabled: true}}, nil
+	}
+	cfg.RunGatedSteps = func(func() upgrades.Context, upgrades.StepObserver) error {
+		c.Fatalf("gated steps run on secondary")
+		return nil
+	}
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(workertest.CheckKilled(c, w), jc.ErrorIsNil)
+}
+
+func (s *workerSuite) TestAlreadyUpgradedRunsContractStepsOnceReady(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.lock.EXPECT().IsUnlocked().Return(false)
+	s.agent.EXPECT().CurrentConfig().Return(s.agentCfg)
+	s.agentCfg.EXPECT().UpgradedToVersion().Return(jujuversion.Current)
+	s.lock.EXPECT().Unlock()
+	s.pool.EXPECT().IsPrimary("0").Return(true, nil)
+	s.agent.EXPECT().ChangeConfig(gomock.Any()).DoAndReturn(func(f agent.ConfigMutator) error {
+		return f(s.cfgSetter)
+	})
+
+	reads := 0
+	ran := make(chan struct{}, 1)
+	cfg := s.getConfig()
+	cfg.ContractSteps = func(func() upgrades.StateBackend) ([]upgrades.ContractStep, error) {
+		reads++
+		return []upgrades.ContractStep{{
+			Description:     "remove unit ports",
+			MinAgentVersion: version.MustParse("2.8.0"),
+			Ready:           true,
+		}}, nil
+	}
+	cfg.RunContractSteps = func(func() upgrades.Context, upgrades.StepObserver) error {
+		ran <- struct{}{}
+		return nil
+	}
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	select {
+	case <-ran:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for contract steps to run")
+	}
+	// With no contract steps awaiting agent upgrades,
+	// the worker stops checking.
+	c.Assert(workertest.CheckKilled(c, w), jc.ErrorIsNil)
+	c.Check(reads, gc.Equals, 2)
+}
+
+func (s *workerSuite) TestAlreadyUpgradedSecondaryIgnoresContractSteps(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.lock.EXPECT().IsUnlocked().Return(false)
+	s.agent.EXPECT().CurrentConfig().Return(s.agentCfg)
+	s.agentCfg.EXPECT().UpgradedToVersion().Return(jujuversion.Current)
+	s.lock.EXPECT().Unlock()
+	s.pool.EXPECT().IsPrimary("0").Return(false, nil)
+
+	cfg := s.getConfig()
+	cfg.ContractSteps = func(func() upgrades.StateBackend) ([]upgrades.ContractStep, error) {
+		return []upgrades.ContractStep{{Description: "remove unit ports", Ready: true}}, nil
+	}
+	cfg.RunContractSteps = func(func() upgrades.Context, upgrades.StepObserver) error {
+		c.Fatalf("contract steps run on secondary")
+		return nil
+	}
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(workertest.CheckKilled(c, w), jc.ErrorIsNil)
 }
 
 func (s *workerSuite) TestNotPrimaryWatchForCompletionSuccess(c *gc.C) {
@@ -180,6 +467,11 @@ func (s *workerSuite) TestNotPrimaryWatchForCompletionSuccess(c *gc.C) {
 	s.upgradeInfo.EXPECT().Refresh().Return(nil).MinTimes(1)
 	s.upgradeInfo.EXPECT().Status().Return(state.UpgradeDBComplete)
 
+	// No restart order was recorded, so we restart immediately.
+	s.upgradeInfo.EXPECT().RestartOrder().Return(nil)
+	s.upgradeInfo.EXPECT().ControllersRestarted().Return(nil)
+	s.upgradeInfo.EXPECT().SetControllerRestarted("0").Return(nil)
+
 	s.pool.EXPECT().SetStatus("0", status.Started, "confirmed primary database upgrade to "+ver)
 
 	// We don't want to kill the worker while we are in the status observation
@@ -218,6 +510,8 @@ func (s *workerSuite) TestNotPrimaryWatchForCompletionTimeout(c *gc.C) {
 	// Primary does not complete the upgrade.
 	s.upgradeInfo.EXPECT().Refresh().Return(nil).AnyTimes()
 	s.upgradeInfo.EXPECT().Status().Return(state.UpgradePending).AnyTimes()
+	s.upgradeInfo.EXPECT().AwaitingApproval().Return(false).AnyTimes()
+	s.upgradeInfo.EXPECT().MongoUpgradePhases().Return(nil).AnyTimes()
 
 	s.logger.EXPECT().Errorf("timed out waiting for primary database upgrade")
 	s.pool.EXPECT().SetStatus("0", status.Error, "upgrading database to "+jujuversion.Current.String())
@@ -237,92 +531,1728 @@ func (s *workerSuite) TestNotPrimaryWatchForCompletionTimeout(c *gc.C) {
 	workertest.CleanKill(c, w)
 }
 
-func (s *workerSuite) TestUpgradedSuccessFirst(c *gc.C) {
+func (s *workerSuite) TestNotPrimaryWaitsForRestartTurn(c *gc.C) {
 	defer s.setupMocks(c).Finish()
-	s.ignoreLogging(c)
 
-	s.expectUpgradeRequired(true)
-	s.expectExecution()
+	s.expectUpgradeRequired(false)
+
+	ver := jujuversion.Current.String()
+	s.pool.EXPECT().SetStatus("0", status.Started, "waiting on primary database upgrade to "+ver)
+
+	// The first change sees the upgrade complete,
+	// the second the restart of controller 1.
+	s.upgradeInfo.EXPECT().Watch().Return(s.watcher)
+	changes := make(chan struct{}, 2)
+	changes <- struct{}{}
+	changes <- struct{}{}
+	s.watcher.EXPECT().Changes().Return(changes).MinTimes(1)
+
+	s.upgradeInfo.EXPECT().Refresh().Return(nil).MinTimes(1)
+	s.upgradeInfo.EXPECT().Status().Return(state.UpgradeDBComplete)
+	s.upgradeInfo.EXPECT().RestartOrder().Return([]string{"1", "0", "2"}).AnyTimes()
+	gomock.InOrder(
+		s.upgradeInfo.EXPECT().ControllersRestarted().Return(nil),
+		s.upgradeInfo.EXPECT().ControllersRestarted().Return([]string{"1"}),
+	)
 
-	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
-	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+jujuversion.Current.String())
 	s.pool.EXPECT().SetStatus(
-		"0", status.Started, fmt.Sprintf("database upgrade to %v completed", jujuversion.Current))
+		"0", status.Started, "waiting for controllers to restart after database upgrade to "+ver)
+	s.pool.EXPECT().SetStatus("0", status.Started, "confirmed primary database upgrade to "+ver)
 
-	s.lock.EXPECT().Unlock()
+	finished := make(chan struct{})
+	gomock.InOrder(
+		s.lock.EXPECT().Unlock().Do(func() {
+			close(finished)
+		}),
+		s.upgradeInfo.EXPECT().SetControllerRestarted("0").Return(nil),
+	)
 
 	w, err := upgradedatabase.NewWorker(s.getConfig())
 	c.Assert(err, jc.ErrorIsNil)
 
+	select {
+	case <-finished:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for restart")
+	}
 	workertest.CleanKill(c, w)
 }
 
-func (s *workerSuite) TestUpgradedRetryThenSuccess(c *gc.C) {
+func (s *workerSuite) TestNotPrimaryNoTimeoutAwaitingApproval(c *gc.C) {
 	defer s.setupMocks(c).Finish()
 
-	s.expectUpgradeRequired(true)
-	s.expectExecution()
+	s.expectUpgradeRequired(false)
 
-	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+jujuversion.Current.String())
+	ver := jujuversion.Current.String()
+	s.pool.EXPECT().SetStatus("0", status.Started, "waiting on primary database upgrade to "+ver)
 
-	cfg := s.getConfig()
-	msg := "database upgrade from %v to %v for %q failed (%s): %v"
-	s.logger.EXPECT().Errorf(msg, version.Number{}, jujuversion.Current, cfg.Tag, "will retry", gomock.Any())
+	// The first change sees the upgrade awaiting approval,
+	// the second sees it approved and completed.
+	s.upgradeInfo.EXPECT().Watch().Return(s.watcher)
+	changes := make(chan struct{}, 1)
+	changes <- struct{}{}
+	s.watcher.EXPECT().Changes().Return(changes).MinTimes(1)
 
-	s.pool.EXPECT().SetStatus("0", status.Error, "upgrading database to "+jujuversion.Current.String())
+	s.upgradeInfo.EXPECT().Refresh().Return(nil).Times(2)
+	gomock.InOrder(
+		s.upgradeInfo.EXPECT().Status().Return(state.UpgradePending),
+		s.upgradeInfo.EXPECT().Status().Return(state.UpgradeDBComplete),
+	)
+	s.upgradeInfo.EXPECT().MongoUpgradePhases().Return(nil).AnyTimes()
+	s.upgradeInfo.EXPECT().AwaitingApproval().Return(true)
 
-	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
-	s.logger.EXPECT().Infof("database upgrade to %v completed successfully.", jujuversion.Current)
+	awaiting := make(chan struct{})
 	s.pool.EXPECT().SetStatus(
-		"0", status.Started, fmt.Sprintf("database upgrade to %v completed", jujuversion.Current))
+		"0", status.Started, "waiting for approval of database upgrade to "+ver,
+	).Do(func(string, status.Status, string) {
+		close(awaiting)
+	})
 
-	s.lock.EXPECT().Unlock()
+	s.upgradeInfo.EXPECT().RestartOrder().Return(nil)
+	s.upgradeInfo.EXPECT().ControllersRestarted().Return(nil)
+	s.upgradeInfo.EXPECT().SetControllerRestarted("0").Return(nil)
+	s.pool.EXPECT().SetStatus("0", status.Started, "confirmed primary database upgrade to "+ver)
 
-	var failedOnce bool
-	cfg.PerformUpgrade = func(ver version.Number, targets []upgrades.Target, ctx func() upgrades.Context) error {
-		c.Check(ver, gc.Equals, version.Number{})
-		c.Check(targets, gc.DeepEquals, []upgrades.Target{upgrades.DatabaseMaster})
+	// Note that the time-out is not logged.
 
-		if !failedOnce {
-			failedOnce = true
-			return errors.New("boom")
-		}
-		return nil
-	}
+	finished := make(chan struct{})
+	s.lock.EXPECT().Unlock().Do(func() {
+		close(finished)
+	})
+
+	cfg := s.getConfig()
+	clk := testclock.NewClock(time.Now())
+	cfg.Clock = clk
 
 	w, err := upgradedatabase.NewWorker(cfg)
 	c.Assert(err, jc.ErrorIsNil)
 
+	select {
+	case <-awaiting:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for approval status")
+	}
+
+	// Advance the clock beyond the time-out duration for waiting on primary.
+	c.Assert(clk.WaitAdvance(time.Hour, testing.ShortWait, 1), jc.ErrorIsNil)
+	changes <- struct{}{}
+
+	select {
+	case <-finished:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for restart")
+	}
 	workertest.CleanKill(c, w)
 }
 
-func (s *workerSuite) TestUpgradedRetryAllFailed(c *gc.C) {
+func (s *workerSuite) TestUpgradedRestartsSecondariesFirst(c *gc.C) {
 	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
 
 	s.expectUpgradeRequired(true)
 	s.expectExecution()
 
-	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+jujuversion.Current.String())
+	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
+	s.pool.EXPECT().ControllerIDs().Return([]string{"2", "0", "1"}, nil)
+	s.pool.EXPECT().IsPrimary("0").Return(true, nil).Times(2)
+	s.pool.EXPECT().IsPrimary("1").Return(false, nil)
+	s.pool.EXPECT().IsPrimary("2").Return(false, nil)
+	s.upgradeInfo.EXPECT().SetRestartOrder([]string{"1", "2", "0"}).Return(nil)
+	s.upgradeInfo.EXPECT().RestartOrder().Return([]string{"1", "2", "0"}).AnyTimes()
+
+	// The secondaries restart after the first change.
+	s.upgradeInfo.EXPECT().Watch().Return(s.watcher)
+	changes := make(chan struct{}, 1)
+	changes <- struct{}{}
+	s.watcher.EXPECT().Changes().Return(changes).MinTimes(1)
+	s.upgradeInfo.EXPECT().Refresh().Return(nil)
+	gomock.InOrder(
+		s.upgradeInfo.EXPECT().ControllersRestarted().Return(nil),
+		s.upgradeInfo.EXPECT().ControllersRestarted().Return([]string{"2", "1"}),
+	)
 
-	cfg := s.getConfig()
-	msg := "database upgrade from %v to %v for %q failed (%s): %v"
-	s.logger.EXPECT().Errorf(msg, version.Number{}, jujuversion.Current, cfg.Tag, "will retry", gomock.Any()).MinTimes(1)
+	ver := jujuversion.Current.String()
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+ver)
+	s.pool.EXPECT().SetStatus(
+		"0", status.Started, "waiting for controllers to restart after database upgrade to "+ver)
+	s.pool.EXPECT().SetStatus("0", status.Started, "database upgrade to "+ver+" completed")
+
+	// The primary steps down before restarting itself.
+	finished := make(chan struct{})
+	gomock.InOrder(
+		s.pool.EXPECT().StepDownPrimary().Return(nil),
+		s.lock.EXPECT().Unlock().Do(func() {
+			close(finished)
+		}),
+		s.upgradeInfo.EXPECT().SetControllerRestarted("0").Return(nil),
+	)
+
+	w, err := upgradedatabase.NewWorker(s.getConfig())
+	c.Assert(err, jc.ErrorIsNil)
+
+	select {
+	case <-finished:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for restart")
+	}
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestUpgradedRestartsCurrentPrimaryLast(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+
+	// Controller 1 has been elected primary while the upgrade ran, so it
+	// restarts last, and this controller restarts without stepping down.
+	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
+	s.pool.EXPECT().ControllerIDs().Return([]string{"2", "0", "1"}, nil)
+	s.pool.EXPECT().IsPrimary("0").Return(false, nil)
+	s.pool.EXPECT().IsPrimary("1").Return(true, nil)
+	s.pool.EXPECT().IsPrimary("2").Return(false, nil)
+	s.upgradeInfo.EXPECT().SetRestartOrder([]string{"0", "2", "1"}).Return(nil)
+	s.upgradeInfo.EXPECT().RestartOrder().Return([]string{"0", "2", "1"}).AnyTimes()
+	s.upgradeInfo.EXPECT().ControllersRestarted().Return(nil).AnyTimes()
+	s.pool.EXPECT().IsPrimary("0").Return(false, nil)
+	s.pool.EXPECT().SetStatus("0", status.Started, gomock.Any()).AnyTimes()
+
+	finished := make(chan struct{})
+	s.lock.EXPECT().Unlock().Do(func() {
+		close(finished)
+	})
+	s.upgradeInfo.EXPECT().SetControllerRestarted("0").Return(nil)
+
+	w, err := upgradedatabase.NewWorker(s.getConfig())
+	c.Assert(err, jc.ErrorIsNil)
+
+	select {
+	case <-finished:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for restart")
+	}
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestUpgradedSuccessFirst(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+
+	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
+	s.expectSoleControllerRestart()
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+jujuversion.Current.String())
+	s.pool.EXPECT().SetStatus(
+		"0", status.Started, fmt.Sprintf("database upgrade to %v completed", jujuversion.Current))
+
+	s.lock.EXPECT().Unlock()
+
+	var drifted bool
+	cfg := s.getConfig()
+	cfg.CheckSchemaDrift = func(func() upgrades.StateBackend) ([]upgrades.SchemaDrift, error) {
+		drifted = true
+		return nil, nil
+	}
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	workertest.CleanKill(c, w)
+	c.Check(drifted, jc.IsTrue)
+}
+
+func (s *workerSuite) TestUpgradeEnsuresIndexes(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.logger.EXPECT().Debugf(gomock.Any(), gomock.Any()).AnyTimes()
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+	s.pool.EXPECT().SetStatus("0", gomock.Any(), gomock.Any()).AnyTimes()
+	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
+	s.expectSoleControllerRestart()
+	s.lock.EXPECT().Unlock()
+
+	gomock.InOrder(
+		s.logger.EXPECT().Infof("created missing indexes %v of collection %q", []string{"model-uuid_1_machineid_1"}, "machines"),
+		s.logger.EXPECT().Infof("collection %q has indexes %v not declared by %v, which may be stale",
+			"machines", []string{"series_-1"}, jujuversion.Current),
+		s.logger.EXPECT().Infof("database upgrade to %v completed successfully.", jujuversion.Current),
+	)
+
+	clk := testclock.NewClock(time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC))
+	cfg := s.getConfig()
+	cfg.Clock = clk
+	cfg.EnsureIndexes = func(func() upgrades.StateBackend) ([]state.CollectionIndexes, error) {
+		return []state.CollectionIndexes{{
+			Collection: "machines",
+			Created:    []string{"model-uuid_1_machineid_1"},
+			Unexpected: []string{"series_-1"},
+		}}, nil
+	}
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+	workertest.CleanKill(c, w)
+
+	reporter, ok := w.(interface{ Report() map[string]interface{} })
+	c.Assert(ok, jc.IsTrue)
+	c.Check(reporter.Report()["indexes"], jc.DeepEquals, map[string]interface{}{
+		"checked": "2020-05-01T12:00:00Z",
+		"collections": map[string]interface{}{
+			"machines": map[string]interface{}{
+				"created":    []string{"model-uuid_1_machineid_1"},
+				"unexpected": []string{"series_-1"},
+			},
+		},
+	})
+}
+
+func (s *workerSuite) TestUpgradeIndexFailureDoesNotFailUpgrade(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.logger.EXPECT().Debugf(gomock.Any(), gomock.Any()).AnyTimes()
+	s.logger.EXPECT().Infof(gomock.Any(), gomock.Any()).AnyTimes()
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+	s.pool.EXPECT().SetStatus("0", gomock.Any(), gomock.Any()).AnyTimes()
+	s.logger.EXPECT().Errorf("ensuring database indexes: %v", gomock.Any())
+	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
+	s.expectSoleControllerRestart()
+	s.lock.EXPECT().Unlock()
+
+	cfg := s.getConfig()
+	cfg.EnsureIndexes = func(func() upgrades.StateBackend) ([]state.CollectionIndexes, error) {
+		return nil, errors.New("boom")
+	}
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+	workertest.CleanKill(c, w)
+
+	reporter, ok := w.(interface{ Report() map[string]interface{} })
+	c.Assert(ok, jc.IsTrue)
+	c.Check(reporter.Report()["indexes"], gc.IsNil)
+}
+
+func (s *workerSuite) TestUpgradeSweepsOrphans(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.logger.EXPECT().Debugf(gomock.Any(), gomock.Any()).AnyTimes()
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+	s.pool.EXPECT().SetStatus("0", gomock.Any(), gomock.Any()).AnyTimes()
+	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
+	s.expectSoleControllerRestart()
+	s.lock.EXPECT().Unlock()
+
+	gomock.InOrder(
+		s.logger.EXPECT().Infof("removed %d documents of collection %q orphaned by %s", 2, "statuses", "removed-units"),
+		s.logger.EXPECT().Infof("removed %d documents of collection %q orphaned by %s", 1, "unitstates", "removed-units"),
+		s.logger.EXPECT().Infof("database upgrade to %v completed successfully.", jujuversion.Current),
+	)
+
+	clk := testclock.NewClock(time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC))
+	cfg := s.getConfig()
+	cfg.Clock = clk
+	cfg.EnsureIndexes = func(func() upgrades.StateBackend) ([]state.CollectionIndexes, error) {
+		return nil, nil
+	}
+	cfg.SweepOrphans = func(func() upgrades.StateBackend) ([]state.OrphanReport, error) {
+		return []state.OrphanReport{
+			{Detector: "removed-units", Collection: "statuses", Count: 2, Removed: true},
+			{Detector: "removed-units", Collection: "unitstates", Count: 1, Removed: true},
+		}, nil
+	}
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+	workertest.CleanKill(c, w)
+
+	reporter, ok := w.(interface{ Report() map[string]interface{} })
+	c.Assert(ok, jc.IsTrue)
+	c.Check(reporter.Report()["orphans"], jc.DeepEquals, map[string]interface{}{
+		"checked": "2020-05-01T12:00:00Z",
+		"detectors": map[string]interface{}{
+			"removed-units": map[string]interface{}{
+				"statuses":   2,
+				"unitstates": 1,
+			},
+		},
+		"removed": true,
+	})
+}
+
+func (s *workerSuite) TestUpgradeOrphanSweepFailureDoesNotFailUpgrade(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.logger.EXPECT().Debugf(gomock.Any(), gomock.Any()).AnyTimes()
+	s.logger.EXPECT().Infof(gomock.Any(), gomock.Any()).AnyTimes()
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+	s.pool.EXPECT().SetStatus("0", gomock.Any(), gomock.Any()).AnyTimes()
+	s.logger.EXPECT().Errorf("sweeping database for orphaned documents: %v", gomock.Any())
+	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
+	s.expectSoleControllerRestart()
+	s.lock.EXPECT().Unlock()
+
+	cfg := s.getConfig()
+	cfg.SweepOrphans = func(func() upgrades.StateBackend) ([]state.OrphanReport, error) {
+		return nil, errors.New("boom")
+	}
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+	workertest.CleanKill(c, w)
+
+	reporter, ok := w.(interface{ Report() map[string]interface{} })
+	c.Assert(ok, jc.IsTrue)
+	c.Check(reporter.Report()["orphans"], gc.IsNil)
+}
+
+func (s *workerSuite) TestUpgradeMongoInHAOrder(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.expectUpgradeRequired(true)
+	s.expectStepsRun()
+	s.pool.EXPECT().SetStatus("0", gomock.Any(), gomock.Any()).AnyTimes()
+	s.pool.EXPECT().ControllerIDs().Return([]string{"0", "1"}, nil).Times(2)
+
+	// Controller 1 is elected primary once this controller has
+	// stepped down to restart its mongo server.
+	var restarted bool
+	s.pool.EXPECT().IsPrimary("0").DoAndReturn(func(string) (bool, error) {
+		return !restarted, nil
+	}).AnyTimes()
+	s.pool.EXPECT().IsPrimary("1").DoAndReturn(func(string) (bool, error) {
+		return restarted, nil
+	}).AnyTimes()
+
+	// The mongo upgrade phases are recorded, and done, in the fake
+	// upgrade info, with controller 1 restarting its mongo server as
+	// the upgrade info changes.
+	var phases []state.MongoUpgradePhase
+	s.pool.EXPECT().MongoVersion().Return(mongo.Version{Major: 3, Minor: 6, Point: 8}, nil)
+	s.pool.EXPECT().FeatureCompatibilityVersion().Return("3.6", nil)
+	s.upgradeInfo.EXPECT().MongoUpgradePhases().DoAndReturn(func() []state.MongoUpgradePhase {
+		return append([]state.MongoUpgradePhase(nil), phases...)
+	}).AnyTimes()
+	s.upgradeInfo.EXPECT().MongoTargetVersion().Return("4.0").AnyTimes()
+	s.upgradeInfo.EXPECT().SetMongoUpgradePlan("4.0", []state.MongoUpgradePhase{
+		{Kind: state.MongoUpgradeMember, ControllerId: "1"},
+		{Kind: state.MongoUpgradeMember, ControllerId: "0"},
+		{Kind: state.MongoSetFeatureCompatibility},
+	}).DoAndReturn(func(_ string, planned []state.MongoUpgradePhase) error {
+		phases = planned
+		return nil
+	})
+	s.upgradeInfo.EXPECT().SetMongoUpgradePhaseDone(gomock.Any()).DoAndReturn(func(index int) error {
+		phases[index].Done = true
+		return nil
+	}).Times(2)
+
+	s.upgradeInfo.EXPECT().Watch().Return(s.watcher)
+	changes := make(chan struct{}, 1)
+	changes <- struct{}{}
+	s.watcher.EXPECT().Changes().Return(changes).MinTimes(1)
+	s.upgradeInfo.EXPECT().Refresh().DoAndReturn(func() error {
+		phases[0].Done = true
+		return nil
+	})
+
+	// The primary steps down before restarting its mongo server, and
+	// the feature compatibility version is only raised once it has.
+	gomock.InOrder(
+		s.pool.EXPECT().StepDownPrimary().Return(nil),
+		s.pool.EXPECT().SetFeatureCompatibilityVersion("4.0").Do(func(string) {
+			c.Check(restarted, jc.IsTrue)
+		}).Return(nil),
+		s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil),
+	)
+
+	// Controller 1, now the primary, restarts last, and this
+	// controller does not step down a second time.
+	s.upgradeInfo.EXPECT().SetRestartOrder([]string{"0", "1"}).Return(nil)
+	s.upgradeInfo.EXPECT().RestartOrder().Return([]string{"0", "1"}).AnyTimes()
+	s.upgradeInfo.EXPECT().ControllersRestarted().Return(nil).AnyTimes()
+	s.upgradeInfo.EXPECT().SetControllerRestarted("0").Return(nil)
+
+	finished := make(chan struct{})
+	s.lock.EXPECT().Unlock().Do(func() {
+		close(finished)
+	})
+
+	cfg := s.getConfig()
+	cfg.RestartMongo = func() error {
+		c.Check(phases[0].Done, jc.IsTrue)
+		restarted = true
+		return nil
+	}
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	select {
+	case <-finished:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for restart")
+	}
+	workertest.CleanKill(c, w)
+
+	reporter, ok := w.(interface{ Report() map[string]interface{} })
+	c.Assert(ok, jc.IsTrue)
+	c.Check(reporter.Report()["mongo-upgrade"], jc.DeepEquals, map[string]interface{}{
+		"target-version": "4.0",
+		"phases": []map[string]interface{}{
+			{"phase": "restart mongo server on controller 1", "done": true},
+			{"phase": "restart mongo server on controller 0", "done": true},
+			{"phase": "set feature compatibility version to 4.0", "done": true},
+		},
+	})
+}
+
+func (s *workerSuite) TestUpgradeMongoFeatureCompatibilityOnly(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.expectUpgradeRequired(true)
+	s.expectStepsRun()
+	s.pool.EXPECT().SetStatus("0", gomock.Any(), gomock.Any()).AnyTimes()
+
+	// The mongo servers already run the installed
+	// mongod, so none of them are restarted.
+	var phases []state.MongoUpgradePhase
+	s.pool.EXPECT().MongoVersion().Return(mongo.Version{Major: 4, Minor: 0, Point: 18}, nil)
+	s.pool.EXPECT().FeatureCompatibilityVersion().Return("3.6", nil)
+	s.upgradeInfo.EXPECT().MongoUpgradePhases().DoAndReturn(func() []state.MongoUpgradePhase {
+		return append([]state.MongoUpgradePhase(nil), phases...)
+	}).AnyTimes()
+	s.upgradeInfo.EXPECT().MongoTargetVersion().Return("4.0").AnyTimes()
+	s.upgradeInfo.EXPECT().SetMongoUpgradePlan("4.0", []state.MongoUpgradePhase{
+		{Kind: state.MongoSetFeatureCompatibility},
+	}).DoAndReturn(func(_ string, planned []state.MongoUpgradePhase) error {
+		phases = planned
+		return nil
+	})
+	s.pool.EXPECT().SetFeatureCompatibilityVersion("4.0").Return(nil)
+	s.upgradeInfo.EXPECT().SetMongoUpgradePhaseDone(0).DoAndReturn(func(index int) error {
+		phases[index].Done = true
+		return nil
+	})
+
+	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
+	s.expectSoleControllerRestart()
+	s.lock.EXPECT().Unlock()
+
+	cfg := s.getConfig()
+	cfg.RestartMongo = func() error {
+		c.Errorf("mongo server restarted")
+		return nil
+	}
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestNotPrimaryUpgradesMongoInTurn(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.expectUpgradeRequired(false)
+
+	ver := jujuversion.Current.String()
+	s.pool.EXPECT().SetStatus("0", status.Started, "waiting on primary database upgrade to "+ver)
+
+	s.upgradeInfo.EXPECT().Watch().Return(s.watcher)
+	changes := make(chan struct{}, 1)
+	changes <- struct{}{}
+	s.watcher.EXPECT().Changes().Return(changes).MinTimes(1)
+	s.upgradeInfo.EXPECT().Refresh().Return(nil)
+	s.upgradeInfo.EXPECT().Status().Return(state.UpgradePending)
+	s.upgradeInfo.EXPECT().AwaitingApproval().Return(false)
+
+	// This controller's mongo server is the first to be restarted.
+	phases := []state.MongoUpgradePhase{
+		{Kind: state.MongoUpgradeMember, ControllerId: "0"},
+		{Kind: state.MongoUpgradeMember, ControllerId: "1"},
+		{Kind: state.MongoSetFeatureCompatibility},
+	}
+	s.upgradeInfo.EXPECT().MongoUpgradePhases().DoAndReturn(func() []state.MongoUpgradePhase {
+		return append([]state.MongoUpgradePhase(nil), phases...)
+	}).AnyTimes()
+	s.upgradeInfo.EXPECT().MongoTargetVersion().Return("4.0").AnyTimes()
+
+	var restarted bool
+	s.upgradeInfo.EXPECT().SetMongoUpgradePhaseDone(0).DoAndReturn(func(index int) error {
+		c.Check(restarted, jc.IsTrue)
+		phases[index].Done = true
+		return nil
+	})
+
+	// The upgrade is paused while the other mongo servers are
+	// upgraded, so the wait on the primary does not time out.
+	waiting := make(chan struct{})
+	s.pool.EXPECT().SetStatus("0", status.Started, "waiting for mongo upgrade to 4.0").Do(
+		func(string, status.Status, string) {
+			close(waiting)
+		})
+
+	cfg := s.getConfig()
+	clk := testclock.NewClock(time.Now())
+	cfg.Clock = clk
+	cfg.RestartMongo = func() error {
+		restarted = true
+		return nil
+	}
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	select {
+	case <-waiting:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for mongo upgrade status")
+	}
+	c.Assert(clk.WaitAdvance(time.Hour, testing.ShortWait, 1), jc.ErrorIsNil)
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestUpgradedRetryThenSuccess(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+	s.expectNotAborted()
+
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+jujuversion.Current.String())
+
+	cfg := s.getConfig()
+	msg := "database upgrade from %v to %v for %q failed (%s): %v"
+	s.logger.EXPECT().Errorf(msg, version.Number{}, jujuversion.Current, cfg.Tag, "will retry", gomock.Any())
+
+	s.pool.EXPECT().SetStatus("0", status.Error, "upgrading database to "+jujuversion.Current.String())
+
+	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
+	s.expectSoleControllerRestart()
+	s.logger.EXPECT().Infof("database upgrade to %v completed successfully.", jujuversion.Current)
+	s.pool.EXPECT().SetStatus(
+		"0", status.Started, fmt.Sprintf("database upgrade to %v completed", jujuversion.Current))
+
+	s.lock.EXPECT().Unlock()
+
+	var failedOnce bool
+	cfg.PerformUpgrade = func(ver version.Number, targets []upgrades.Target, _ upgrades.Stage, ctx func() upgrades.Context, _ upgrades.StepObserver) error {
+		c.Check(ver, gc.Equals, version.Number{})
+		c.Check(targets, gc.DeepEquals, []upgrades.Target{upgrades.DatabaseMaster})
+
+		if !failedOnce {
+			failedOnce = true
+			return errors.New("boom")
+		}
+		return nil
+	}
+
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestUpgradedRetryAllFailed(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+	s.expectNotAborted()
+
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+jujuversion.Current.String())
+
+	cfg := s.getConfig()
+	msg := "database upgrade from %v to %v for %q failed (%s): %v"
+	s.logger.EXPECT().Errorf(msg, version.Number{}, jujuversion.Current, cfg.Tag, "will retry", gomock.Any()).MinTimes(1)
 	s.logger.EXPECT().Errorf(msg, version.Number{}, jujuversion.Current, cfg.Tag, "giving up", gomock.Any())
 
-	s.pool.EXPECT().SetStatus("0", status.Error, "upgrading database to "+jujuversion.Current.String()).MinTimes(1)
+	s.pool.EXPECT().SetStatus("0", status.Error, "upgrading database to "+jujuversion.Current.String()).MinTimes(1)
+
+	// Note that UpgradeComplete is not unlocked.
+
+	cfg.PerformUpgrade = func(ver version.Number, targets []upgrades.Target, _ upgrades.Stage, ctx func() upgrades.Context, _ upgrades.StepObserver) error {
+		c.Check(ver, gc.Equals, version.Number{})
+		c.Check(targets, gc.DeepEquals, []upgrades.Target{upgrades.DatabaseMaster})
+		return errors.New("boom")
+	}
+
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestUpgradeResumedAfterPrimaryReelected(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+	s.expectNotAborted()
+
+	ver := jujuversion.Current.String()
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+ver).Times(2)
+	electing := make(chan struct{})
+	s.pool.EXPECT().SetStatus(
+		"0", status.Started, "waiting for mongo primary election during database upgrade to "+ver,
+	).Do(func(string, status.Status, string) {
+		close(electing)
+	})
+
+	// The first check finds the election still in progress,
+	// the second finds this controller elected again.
+	stepDownErr := errors.New("not master")
+	s.pool.EXPECT().IsPrimaryChange(stepDownErr).Return(true)
+	s.pool.EXPECT().Refresh().Times(2)
+	gomock.InOrder(
+		s.pool.EXPECT().PrimaryElected().Return(false, nil),
+		s.pool.EXPECT().PrimaryElected().Return(true, nil),
+	)
+	s.pool.EXPECT().IsPrimary("0").Return(true, nil)
+
+	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
+	s.expectSoleControllerRestart()
+	s.pool.EXPECT().SetStatus("0", status.Started, "database upgrade to "+ver+" completed")
+
+	finished := make(chan struct{})
+	s.lock.EXPECT().Unlock().Do(func() {
+		close(finished)
+	})
+
+	cfg := s.getConfig()
+	clk := testclock.NewClock(time.Now())
+	cfg.Clock = clk
+	cfg.PerformUpgrade = func(
+		_ version.Number, _ []upgrades.Target, stage upgrades.Stage, _ func() upgrades.Context, observer upgrades.StepObserver,
+	) error {
+		if stage == upgrades.StageBackfill {
+			return nil
+		}
+		observer("step 1")
+		observer("step 2")
+		return stepDownErr
+	}
+	cfg.ResumeUpgrade = func(
+		_ version.Number, targets []upgrades.Target, stage upgrades.Stage, step string, _ func() upgrades.Context,
+		observer upgrades.StepObserver,
+	) error {
+		c.Check(targets, gc.DeepEquals, []upgrades.Target{upgrades.DatabaseMaster})
+		c.Check(step, gc.Equals, "step 2")
+		observer("step 2")
+		return nil
+	}
+
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	select {
+	case <-electing:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for primary election status")
+	}
+	c.Assert(clk.WaitAdvance(5*time.Second, testing.LongWait, 2), jc.ErrorIsNil)
+	c.Assert(clk.WaitAdvance(5*time.Second, testing.LongWait, 2), jc.ErrorIsNil)
+
+	select {
+	case <-finished:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for upgrade to complete")
+	}
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestUpgradeFailedPrimaryMoved(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+	s.expectNotAborted()
+
+	ver := jujuversion.Current.String()
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+ver)
+	electing := make(chan struct{})
+	s.pool.EXPECT().SetStatus(
+		"0", status.Started, "waiting for mongo primary election during database upgrade to "+ver,
+	).Do(func(string, status.Status, string) {
+		close(electing)
+	})
+
+	// Another controller is elected primary.
+	stepDownErr := errors.New("not master")
+	s.pool.EXPECT().IsPrimaryChange(stepDownErr).Return(true)
+	s.pool.EXPECT().Refresh()
+	s.pool.EXPECT().PrimaryElected().Return(true, nil)
+	s.pool.EXPECT().IsPrimary("0").Return(false, nil)
+
+	failed := make(chan struct{})
+	s.pool.EXPECT().SetStatus(
+		"0", status.Error, "upgrading database to "+ver+`: mongo primary moved to another controller during step "step 1"`,
+	).Do(func(string, status.Status, string) {
+		close(failed)
+	})
+
+	// Note that the steps are neither resumed nor retried,
+	// and UpgradeComplete is not unlocked.
+
+	cfg := s.getConfig()
+	clk := testclock.NewClock(time.Now())
+	cfg.Clock = clk
+	cfg.PerformUpgrade = func(
+		_ version.Number, _ []upgrades.Target, _ upgrades.Stage, _ func() upgrades.Context, observer upgrades.StepObserver,
+	) error {
+		observer("step 1")
+		return stepDownErr
+	}
+	cfg.ResumeUpgrade = func(version.Number, []upgrades.Target, upgrades.Stage, string, func() upgrades.Context, upgrades.StepObserver) error {
+		c.Fatalf("upgrade steps should not be resumed")
+		return nil
+	}
+
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	select {
+	case <-electing:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for primary election status")
+	}
+	c.Assert(clk.WaitAdvance(5*time.Second, testing.LongWait, 2), jc.ErrorIsNil)
+
+	select {
+	case <-failed:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for upgrade to fail")
+	}
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestPreflightCheckFailedNoUpgrade(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	s.expectUpgradeRequired(true)
+	s.agent.EXPECT().CurrentConfig().Return(s.agentCfg)
+	s.agentCfg.EXPECT().DataDir().Return("/var/lib/juju")
+
+	ver := jujuversion.Current.String()
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+ver)
+
+	msg := "database upgrade from %v to %v aborted by pre-flight check: %v"
+	s.logger.EXPECT().Errorf(msg, version.Number{}, jujuversion.Current, gomock.Any())
+	s.pool.EXPECT().SetStatus("0", status.Error, "upgrading database to "+ver+": not enough free disk space")
+
+	// Note that the upgrade steps are not run and UpgradeComplete is not unlocked.
+
+	cfg := s.getConfig()
+	cfg.PreflightCheck = func(ver version.Number, targets []upgrades.Target, dataDir string) error {
+		c.Check(ver, gc.Equals, version.Number{})
+		c.Check(targets, gc.DeepEquals, []upgrades.Target{upgrades.DatabaseMaster})
+		c.Check(dataDir, gc.Equals, "/var/lib/juju")
+		return errors.New("not enough free disk space")
+	}
+	cfg.PerformUpgrade = func(version.Number, []upgrades.Target, upgrades.Stage, func() upgrades.Context, upgrades.StepObserver) error {
+		c.Fatalf("upgrade steps should not be run")
+		return nil
+	}
+
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestStepCollectionsRecorded(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.expectUpgradeRequired(true)
+	s.upgradeInfo.EXPECT().SetStepCollections([]state.UpgradeStepCollections{{
+		Description: "move units",
+		Read:        []string{"units", "machines"},
+		Write:       []string{"units"},
+	}}).Return(nil)
+	s.expectExecution()
+
+	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
+	s.expectSoleControllerRestart()
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+jujuversion.Current.String())
+	s.pool.EXPECT().SetStatus(
+		"0", status.Started, fmt.Sprintf("database upgrade to %v completed", jujuversion.Current))
+	s.lock.EXPECT().Unlock()
+
+	cfg := s.getConfig()
+	cfg.StepCollections = func(ver version.Number, targets []upgrades.Target) []upgrades.StepCollections {
+		c.Check(ver, gc.Equals, version.Number{})
+		c.Check(targets, gc.DeepEquals, []upgrades.Target{upgrades.DatabaseMaster})
+		return []upgrades.StepCollections{{
+			Description: "move units",
+			Collections: upgrades.Collections{
+				Read:  []string{"units", "machines"},
+				Write: []string{"units"},
+			},
+		}}
+	}
+
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestStepPhasesRecorded(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.expectUpgradeRequired(true)
+	gomock.InOrder(
+		s.upgradeInfo.EXPECT().SetStepPhases([]state.UpgradeStepPhaseInfo{{
+			Description: "copy unit ports",
+			Phase:       state.UpgradeExpandPhase,
+		}, {
+			Description: "remove unit ports",
+			Phase:       state.UpgradeContractPhase,
+		}}).Return(nil),
+		// Once the upgrade steps have run, the expand step is done,
+		// while the contract step is deferred.
+		s.upgradeInfo.EXPECT().SetStepPhases([]state.UpgradeStepPhaseInfo{{
+			Description: "copy unit ports",
+			Phase:       state.UpgradeExpandPhase,
+			Done:        true,
+		}, {
+			Description: "remove unit ports",
+			Phase:       state.UpgradeContractPhase,
+		}}).Return(nil),
+	)
+	s.expectExecution()
+
+	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
+	s.expectSoleControllerRestart()
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+jujuversion.Current.String())
+	s.pool.EXPECT().SetStatus(
+		"0", status.Started, fmt.Sprintf("database upgrade to %v completed", jujuversion.Current))
+	s.lock.EXPECT().Unlock()
+
+	cfg := s.getConfig()
+	cfg.StepPhases = func(ver version.Number, targets []upgrades.Target) []upgrades.StepPhase {
+		c.Check(targets, gc.DeepEquals, []upgrades.Target{upgrades.DatabaseMaster})
+		return []upgrades.StepPhase{
+			{Description: "copy unit ports", Phase: upgrades.PhaseExpand},
+			{Description: "remove unit ports", Phase: upgrades.PhaseContract},
+		}
+	}
+
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestStepCollectionsRecordFailureStillUpgrades(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	s.expectUpgradeRequired(true)
+	s.upgradeInfo.EXPECT().SetStepCollections(gomock.Any()).Return(errors.New("boom"))
+	s.logger.EXPECT().Errorf("failed to record upgrade step collections: %v", gomock.Any())
+	s.expectExecution()
+
+	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
+	s.expectSoleControllerRestart()
+	s.logger.EXPECT().Infof("database upgrade to %v completed successfully.", jujuversion.Current)
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+jujuversion.Current.String())
+	s.pool.EXPECT().SetStatus(
+		"0", status.Started, fmt.Sprintf("database upgrade to %v completed", jujuversion.Current))
+	s.lock.EXPECT().Unlock()
+
+	w, err := upgradedatabase.NewWorker(s.getConfig())
+	c.Assert(err, jc.ErrorIsNil)
+
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestEstimateRecorded(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+	s.expectNotAborted()
+	s.pool.EXPECT().CollectionStats("units").Return(int64(1000), int64(2048), nil)
+
+	start := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	type published struct {
+		steps      []state.UpgradeStepEstimate
+		completion time.Time
+	}
+	var estimates []published
+	s.upgradeInfo.EXPECT().SetEstimate(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(steps []state.UpgradeStepEstimate, completion time.Time) error {
+			estimates = append(estimates, published{steps, completion})
+			return nil
+		},
+	).Times(4)
+
+	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
+	s.expectSoleControllerRestart()
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+jujuversion.Current.String())
+	s.pool.EXPECT().SetStatus(
+		"0", status.Started, fmt.Sprintf("database upgrade to %v completed", jujuversion.Current))
+	s.lock.EXPECT().Unlock()
+
+	clk := testclock.NewClock(start)
+	cfg := s.getConfig()
+	cfg.Clock = clk
+	cfg.EstimateUpgrade = func(
+		ver version.Number, targets []upgrades.Target, stats func(string) (upgrades.CollectionStats, error),
+	) ([]upgrades.StepEstimate, error) {
+		c.Check(targets, gc.DeepEquals, []upgrades.Target{upgrades.DatabaseMaster})
+		units, err := stats("units")
+		c.Check(err, jc.ErrorIsNil)
+		c.Check(units, gc.Equals, upgrades.CollectionStats{Count: 1000, Size: 2048})
+		return []upgrades.StepEstimate{
+			{Description: "move units", Duration: time.Second},
+			{Description: "move machines", Duration: 2 * time.Second},
+		}, nil
+	}
+	cfg.PerformUpgrade = func(
+		_ version.Number, _ []upgrades.Target, stage upgrades.Stage, _ func() upgrades.Context, observer upgrades.StepObserver,
+	) error {
+		if stage == upgrades.StageBackfill {
+			return nil
+		}
+		observer("move units")
+		// The first step takes twice as long as estimated,
+		// so the estimate for the second is doubled.
+		clk.Advance(2 * time.Second)
+		observer("move machines")
+		clk.Advance(time.Second)
+		return nil
+	}
+
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+	workertest.CleanKill(c, w)
+
+	notDone := []state.UpgradeStepEstimate{
+		{Description: "move units", Estimate: time.Second},
+		{Description: "move machines", Estimate: 2 * time.Second},
+	}
+	firstDone := []state.UpgradeStepEstimate{
+		{Description: "move units", Estimate: time.Second, Done: true},
+		{Description: "move machines", Estimate: 2 * time.Second},
+	}
+	allDone := []state.UpgradeStepEstimate{
+		{Description: "move units", Estimate: time.Second, Done: true},
+		{Description: "move machines", Estimate: 2 * time.Second, Done: true},
+	}
+	c.Check(estimates, jc.DeepEquals, []published{
+		{notDone, start.Add(3 * time.Second)},
+		{notDone, start.Add(3 * time.Second)},
+		{firstDone, start.Add(2*time.Second + 4*time.Second)},
+		{allDone, start.Add(3 * time.Second)},
+	})
+
+	reporter, ok := w.(interface{ Report() map[string]interface{} })
+	c.Assert(ok, jc.IsTrue)
+	c.Check(reporter.Report()["estimate"], jc.DeepEquals, map[string]interface{}{
+		"estimated-completion": "2020-05-01T12:00:03Z",
+		"remaining":            "0s",
+		"steps":                2,
+		"steps-done":           2,
+	})
+}
+
+func (s *workerSuite) TestEstimateFailureStillUpgrades(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	s.expectUpgradeRequired(true)
+	s.logger.EXPECT().Errorf("failed to estimate upgrade duration: %v", gomock.Any())
+	s.expectExecution()
+
+	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
+	s.expectSoleControllerRestart()
+	s.logger.EXPECT().Infof("database upgrade to %v completed successfully.", jujuversion.Current)
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+jujuversion.Current.String())
+	s.pool.EXPECT().SetStatus(
+		"0", status.Started, fmt.Sprintf("database upgrade to %v completed", jujuversion.Current))
+	s.lock.EXPECT().Unlock()
+
+	cfg := s.getConfig()
+	cfg.EstimateUpgrade = func(
+		version.Number, []upgrades.Target, func(string) (upgrades.CollectionStats, error),
+	) ([]upgrades.StepEstimate, error) {
+		return nil, errors.New("boom")
+	}
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestValidationFailedNotComplete(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+
+	ver := jujuversion.Current.String()
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+ver)
+
+	msg := "database upgrade from %v to %v failed validation: %v"
+	s.logger.EXPECT().Errorf(msg, version.Number{}, jujuversion.Current, gomock.Any())
+	s.pool.EXPECT().SetStatus("0", status.Error, "validating database upgrade to "+ver+": boom")
+
+	// Note that the upgrade info status is not set and UpgradeComplete is not unlocked.
+
+	cfg := s.getConfig()
+	var upgraded bool
+	cfg.PerformUpgrade = func(version.Number, []upgrades.Target, upgrades.Stage, func() upgrades.Context, upgrades.StepObserver) error {
+		upgraded = true
+		return nil
+	}
+	cfg.ValidateUpgrade = func(func() upgrades.Context) error {
+		c.Check(upgraded, jc.IsTrue)
+		return errors.New("boom")
+	}
+
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestUpgradeFailedNotValidated(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+	s.expectNotAborted()
+
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+jujuversion.Current.String())
+	s.pool.EXPECT().SetStatus("0", status.Error, "upgrading database to "+jujuversion.Current.String()).MinTimes(1)
+
+	cfg := s.getConfig()
+	cfg.PerformUpgrade = func(version.Number, []upgrades.Target, upgrades.Stage, func() upgrades.Context, upgrades.StepObserver) error {
+		return errors.New("boom")
+	}
+	cfg.ValidateUpgrade = func(func() upgrades.Context) error {
+		c.Fatalf("validation should not be run")
+		return nil
+	}
+
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestUpgradeAbortedRolledBack(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+	s.upgradeInfo.EXPECT().Refresh().Return(errors.NotFoundf("current upgrade info"))
+
+	ver := jujuversion.Current.String()
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+ver)
+	s.logger.EXPECT().Infof("database upgrade to %v aborted, rolling back", jujuversion.Current)
+	s.logger.EXPECT().Infof("database upgrade to %v rolled back", jujuversion.Current)
+	s.pool.EXPECT().SetStatus("0", status.Error, "database upgrade to "+ver+" aborted and rolled back")
+
+	// Note that the upgrade is not retried or validated,
+	// and UpgradeComplete is not unlocked.
+
+	cfg := s.getConfig()
+	var attempts int
+	upgradeErr := errors.New("boom")
+	cfg.PerformUpgrade = func(version.Number, []upgrades.Target, upgrades.Stage, func() upgrades.Context, upgrades.StepObserver) error {
+		attempts++
+		return upgradeErr
+	}
+	cfg.ValidateUpgrade = func(func() upgrades.Context) error {
+		c.Fatalf("validation should not be run")
+		return nil
+	}
+	var rolledBack bool
+	cfg.RollbackUpgrade = func(ver version.Number, targets []upgrades.Target, stage upgrades.Stage, err error, _ func() upgrades.Context) error {
+		c.Check(ver, gc.Equals, version.Number{})
+		c.Check(targets, gc.DeepEquals, []upgrades.Target{upgrades.DatabaseMaster})
+		c.Check(stage, gc.Equals, upgrades.StageSchema)
+		c.Check(err, gc.Equals, upgradeErr)
+		rolledBack = true
+		return nil
+	}
+
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	workertest.CleanKill(c, w)
+	c.Check(attempts, gc.Equals, 1)
+	c.Check(rolledBack, jc.IsTrue)
+}
+
+func (s *workerSuite) TestUpgradeAbortedBetweenStepsRolledBack(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+
+	// The upgrade is aborted once the first step has started, so
+	// it is found to be aborted before the second step is run.
+	s.upgradeInfo.EXPECT().Refresh().Return(nil).AnyTimes()
+	gomock.InOrder(
+		s.upgradeInfo.EXPECT().Status().Return(state.UpgradePending),
+		s.upgradeInfo.EXPECT().Status().Return(state.UpgradeAborted).AnyTimes(),
+	)
+
+	ver := jujuversion.Current.String()
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+ver)
+	s.logger.EXPECT().Infof("database upgrade to %v aborted, rolling back", jujuversion.Current)
+	s.logger.EXPECT().Infof("database upgrade to %v rolled back", jujuversion.Current)
+	s.pool.EXPECT().SetStatus("0", status.Error, "database upgrade to "+ver+" aborted and rolled back")
+
+	// Note that the upgrade is not retried or validated,
+	// and UpgradeComplete is not unlocked.
+
+	cfg := s.getConfig()
+	var (
+		observed []string
+		stopErr  error
+	)
+	cfg.PerformUpgrade = func(
+		_ version.Number, _ []upgrades.Target, _ upgrades.Stage, _ func() upgrades.Context, observer upgrades.StepObserver,
+	) error {
+		for _, step := range []string{"move units", "move machines"} {
+			if stopErr = observer(step); stopErr != nil {
+				return stopErr
+			}
+			observed = append(observed, step)
+		}
+		return nil
+	}
+	cfg.ValidateUpgrade = func(func() upgrades.Context) error {
+		c.Fatalf("validation should not be run")
+		return nil
+	}
+	var rolledBack bool
+	cfg.RollbackUpgrade = func(_ version.Number, _ []upgrades.Target, stage upgrades.Stage, err error, _ func() upgrades.Context) error {
+		c.Check(stage, gc.Equals, upgrades.StageSchema)
+		c.Check(err, gc.Equals, stopErr)
+		rolledBack = true
+		return nil
+	}
+
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
 
-	// Note that UpgradeComplete is not unlocked.
+	workertest.CleanKill(c, w)
+	c.Check(observed, jc.DeepEquals, []string{"move units"})
+	c.Check(stopErr, gc.ErrorMatches, "upgrade aborted")
+	c.Check(rolledBack, jc.IsTrue)
+}
 
-	cfg.PerformUpgrade = func(ver version.Number, targets []upgrades.Target, ctx func() upgrades.Context) error {
-		c.Check(ver, gc.Equals, version.Number{})
-		c.Check(targets, gc.DeepEquals, []upgrades.Target{upgrades.DatabaseMaster})
+func (s *workerSuite) TestUpgradeAbortedRollbackBlocked(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+	s.upgradeInfo.EXPECT().Refresh().Return(nil)
+	s.upgradeInfo.EXPECT().Status().Return(state.UpgradeAborted)
+
+	ver := jujuversion.Current.String()
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+ver)
+	s.logger.EXPECT().Infof("database upgrade to %v aborted, rolling back", jujuversion.Current)
+	s.logger.EXPECT().Errorf("rolling back database upgrade from %v to %v failed: %v",
+		version.Number{}, jujuversion.Current, gomock.Any())
+
+	blocked := &upgrades.RollbackBlockedError{Steps: []string{"move units"}}
+	s.pool.EXPECT().SetStatus("0", status.Error, "rolling back database upgrade to "+ver+": "+blocked.Error())
+
+	cfg := s.getConfig()
+	cfg.PerformUpgrade = func(version.Number, []upgrades.Target, upgrades.Stage, func() upgrades.Context, upgrades.StepObserver) error {
 		return errors.New("boom")
 	}
+	cfg.RollbackUpgrade = func(version.Number, []upgrades.Target, upgrades.Stage, error, func() upgrades.Context) error {
+		return blocked
+	}
+
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestUpgradeAwaitsApproval(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.expectApprovalRequired()
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+
+	ver := jujuversion.Current.String()
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+ver).Times(2)
+	s.pool.EXPECT().SetStatus("0", status.Started, "waiting for approval of database upgrade to "+ver)
+	s.pool.EXPECT().SetStatus("0", status.Started, "database upgrade to "+ver+" completed")
+
+	// The upgrade is approved after the first change, by which
+	// time only the schema steps have been run.
+	var stages []upgrades.Stage
+	s.upgradeInfo.EXPECT().Refresh().Return(nil).Times(2)
+	s.upgradeInfo.EXPECT().Status().Return(state.UpgradePending)
+	gomock.InOrder(
+		s.upgradeInfo.EXPECT().Approved().Return(false),
+		s.upgradeInfo.EXPECT().SetAwaitingApproval().Return(nil),
+		s.upgradeInfo.EXPECT().Watch().Return(s.watcher),
+		s.upgradeInfo.EXPECT().Approved().Do(func() {
+			c.Check(stages, jc.DeepEquals, []upgrades.Stage{upgrades.StageSchema})
+		}).Return(true),
+		s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil),
+	)
+	changes := make(chan struct{}, 1)
+	changes <- struct{}{}
+	s.watcher.EXPECT().Changes().Return(changes).MinTimes(1)
+
+	s.expectSoleControllerRestart()
+	s.lock.EXPECT().Unlock()
+
+	cfg := s.getConfig()
+	cfg.PerformUpgrade = func(
+		_ version.Number, _ []upgrades.Target, stage upgrades.Stage, _ func() upgrades.Context, _ upgrades.StepObserver,
+	) error {
+		stages = append(stages, stage)
+		return nil
+	}
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	workertest.CleanKill(c, w)
+	c.Check(stages, jc.DeepEquals, []upgrades.Stage{upgrades.StageSchema, upgrades.StageBackfill})
+}
+
+func (s *workerSuite) TestAgentConfigReadableAwaitingApproval(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	// As with the machine agent, the agent config cannot be
+	// read while it is being changed.
+	var mu sync.Mutex
+	s.agent.EXPECT().ChangeConfig(gomock.Any()).DoAndReturn(func(f agent.ConfigMutator) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return f(s.cfgSetter)
+	}).AnyTimes()
+
+	s.expectApprovalRequired()
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+	s.pool.EXPECT().SetStatus("0", status.Started, gomock.Any()).AnyTimes()
+
+	awaiting := make(chan struct{})
+	s.upgradeInfo.EXPECT().Refresh().Return(nil).AnyTimes()
+	s.upgradeInfo.EXPECT().Status().Return(state.UpgradePending).AnyTimes()
+	gomock.InOrder(
+		s.upgradeInfo.EXPECT().Approved().Return(false),
+		s.upgradeInfo.EXPECT().SetAwaitingApproval().Do(func() {
+			close(awaiting)
+		}).Return(nil),
+		s.upgradeInfo.EXPECT().Watch().Return(s.watcher),
+		s.upgradeInfo.EXPECT().Approved().Return(true),
+		s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil),
+	)
+	changes := make(chan struct{})
+	s.watcher.EXPECT().Changes().Return(changes).MinTimes(1)
+	s.expectSoleControllerRestart()
+	s.lock.EXPECT().Unlock()
+	s.agent.EXPECT().CurrentConfig().DoAndReturn(func() agent.Config {
+		mu.Lock()
+		defer mu.Unlock()
+		return s.agentCfg
+	})
+
+	w, err := upgradedatabase.NewWorker(s.getConfig())
+	c.Assert(err, jc.ErrorIsNil)
+	select {
+	case <-awaiting:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for upgrade to await approval")
+	}
+
+	read := make(chan struct{})
+	go func() {
+		_ = s.agent.CurrentConfig()
+		close(read)
+	}()
+	select {
+	case <-read:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("agent config held while awaiting approval")
+	}
+
+	changes <- struct{}{}
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestUpgradeAlreadyApproved(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.expectApprovalRequired()
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+
+	// The worker restarted after the upgrade was approved,
+	// so the upgrade is not paused a second time.
+	s.upgradeInfo.EXPECT().Refresh().Return(nil)
+	s.upgradeInfo.EXPECT().Approved().Return(true)
+
+	s.pool.EXPECT().SetStatus("0", status.Started, gomock.Any()).Times(2)
+	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
+	s.expectSoleControllerRestart()
+	s.lock.EXPECT().Unlock()
+
+	w, err := upgradedatabase.NewWorker(s.getConfig())
+	c.Assert(err, jc.ErrorIsNil)
+
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestUpgradeAbortedAwaitingApprovalRolledBack(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.expectApprovalRequired()
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+
+	ver := jujuversion.Current.String()
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+ver)
+	s.pool.EXPECT().SetStatus("0", status.Started, "waiting for approval of database upgrade to "+ver)
+	s.pool.EXPECT().SetStatus("0", status.Error, "database upgrade to "+ver+" aborted and rolled back")
+
+	gomock.InOrder(
+		s.upgradeInfo.EXPECT().Refresh().Return(nil),
+		s.upgradeInfo.EXPECT().Approved().Return(false),
+		s.upgradeInfo.EXPECT().SetAwaitingApproval().Return(nil),
+		s.upgradeInfo.EXPECT().Watch().Return(s.watcher),
+		s.upgradeInfo.EXPECT().Refresh().Return(errors.NotFoundf("current upgrade info")),
+	)
+	changes := make(chan struct{}, 1)
+	changes <- struct{}{}
+	s.watcher.EXPECT().Changes().Return(changes).MinTimes(1)
+
+	// Note that UpgradeComplete is not unlocked.
+
+	cfg := s.getConfig()
+	var rolledBack bool
+	cfg.RollbackUpgrade = func(_ version.Number, _ []upgrades.Target, stage upgrades.Stage, err error, _ func() upgrades.Context) error {
+		// All of the schema steps were run, so there is no failed
+		// step, and none of the backfill steps were.
+		c.Check(stage, gc.Equals, upgrades.StageSchema)
+		c.Check(err, jc.ErrorIsNil)
+		rolledBack = true
+		return nil
+	}
+	cfg.EnsureIndexes = func(func() upgrades.StateBackend) ([]state.CollectionIndexes, error) {
+		c.Fatalf("indexes should not be ensured")
+		return nil, nil
+	}
+	cfg.SweepOrphans = func(func() upgrades.StateBackend) ([]state.OrphanReport, error) {
+		c.Fatalf("orphans should not be swept")
+		return nil, nil
+	}
+
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+
+	workertest.CleanKill(c, w)
+	c.Check(rolledBack, jc.IsTrue)
+}
+
+func (s *workerSuite) TestReportUpgradeProgress(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+	s.expectNotAborted()
+	s.pool.EXPECT().IsPrimaryChange(gomock.Any()).Return(false)
+	s.pool.EXPECT().SetStatus("0", gomock.Any(), gomock.Any()).AnyTimes()
+	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
+	s.expectSoleControllerRestart()
+	s.lock.EXPECT().Unlock()
+
+	clk := testclock.NewClock(time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC))
+	cfg := s.getConfig()
+	cfg.Clock = clk
+
+	running := make(chan struct{})
+	proceed := make(chan struct{})
+	var attempts int
+	cfg.PerformUpgrade = func(
+		_ version.Number, _ []upgrades.Target, stage upgrades.Stage, _ func() upgrades.Context, observer upgrades.StepObserver,
+	) error {
+		if stage == upgrades.StageBackfill {
+			return nil
+		}
+		attempts++
+		observer("add a collection")
+		if attempts == 1 {
+			return errors.New("boom")
+		}
+		clk.Advance(time.Minute)
+		observer("move some documents")
+		close(running)
+		<-proceed
+		return nil
+	}
+
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+	reporter, ok := w.(interface{ Report() map[string]interface{} })
+	c.Assert(ok, jc.IsTrue)
+
+	select {
+	case <-running:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for upgrade steps to run")
+	}
+	c.Check(reporter.Report(), jc.DeepEquals, map[string]interface{}{
+		"database-upgrade": map[string]interface{}{
+			"from-version": "0.0.0",
+			"to-version":   jujuversion.Current.String(),
+			"phase":        "running steps",
+			"started":      "2020-05-01T12:00:00Z",
+			"elapsed":      "1m0s",
+			"attempt":      2,
+			"step": map[string]interface{}{
+				"description": "move some documents",
+				"started":     "2020-05-01T12:01:00Z",
+				"elapsed":     "0s",
+			},
+			"errors": []map[string]interface{}{{
+				"time":  "2020-05-01T12:00:00Z",
+				"step":  "add a collection",
+				"error": "boom",
+			}},
+		},
+	})
+	close(proceed)
+
+	workertest.CleanKill(c, w)
+	progress, _ := reporter.Report()["database-upgrade"].(map[string]interface{})
+	c.Check(progress["phase"], gc.Equals, "complete")
+	c.Check(progress["step"], gc.IsNil)
+}
+
+func (s *workerSuite) TestUpgradeStalledRestart(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+	s.expectNotAborted()
+	s.pool.EXPECT().WriteCount().Return(int64(42), nil).AnyTimes()
+
+	ver := jujuversion.Current.String()
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+ver)
+	stalled := make(chan struct{})
+	s.pool.EXPECT().SetStatus(
+		"0", status.Error, "upgrading database to "+ver+`: stalled in step "move units" after 1m15s`,
+	).Do(func(string, status.Status, string) {
+		close(stalled)
+	})
+
+	// Note that the upgrade is not retried, and UpgradeComplete is not unlocked.
+
+	clk := testclock.NewClock(time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC))
+	cfg := s.getConfig()
+	cfg.Clock = clk
+	cfg.StallTimeout = time.Minute
+	cfg.RestartOnStall = true
+
+	var (
+		mu       sync.Mutex
+		attempts int
+		active   int
+	)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	cfg.PerformUpgrade = func(
+		_ version.Number, _ []upgrades.Target, stage upgrades.Stage, _ func() upgrades.Context, observer upgrades.StepObserver,
+	) error {
+		if stage == upgrades.StageBackfill {
+			return nil
+		}
+		mu.Lock()
+		attempts++
+		active++
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			active--
+			mu.Unlock()
+		}()
+		observer("move units")
+		close(started)
+		<-release
+		return nil
+	}
+
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+	select {
+	case <-started:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for upgrade steps to run")
+	}
+
+	// Starting the step is progress, after which the
+	// step makes no progress for the stall timeout.
+	for i := 0; i < 5; i++ {
+		c.Assert(clk.WaitAdvance(15*time.Second, testing.LongWait, 1), jc.ErrorIsNil)
+	}
+	select {
+	case <-stalled:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for stall")
+	}
+
+	// The worker does not exit to be restarted, running the
+	// steps again, while the stalled steps are still running.
+	workertest.CheckAlive(c, w)
+	close(release)
+	err = workertest.CheckKilled(c, w)
+	c.Assert(err, gc.ErrorMatches, `stalled in step "move units" after 1m15s`)
+	mu.Lock()
+	c.Check(attempts, gc.Equals, 1)
+	c.Check(active, gc.Equals, 0)
+	mu.Unlock()
+
+	diagnostics, err := filepath.Glob(filepath.Join(s.logDir, "database-upgrade-stall-*.txt"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(diagnostics, gc.HasLen, 1)
+	content, err := ioutil.ReadFile(diagnostics[0])
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(string(content), jc.Contains, `stalled in step "move units" after 1m15s`)
+	c.Check(string(content), jc.Contains, "goroutine")
+}
+
+func (s *workerSuite) TestUpgradeStalledFailFast(c *gc.C) {
+	ctrl := s.setupMocks(c)
+	defer ctrl.Finish()
+	s.ignoreLogging(c)
+
+	// The stalled steps only fail once the state pool is closed.
+	release := make(chan struct{})
+	s.pool = NewMockPool(ctrl)
+	s.pool.EXPECT().Close().DoAndReturn(func() error {
+		close(release)
+		return nil
+	})
+	s.pool.EXPECT().SetUpgradeBatcher(gomock.Any()).AnyTimes()
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+	s.pool.EXPECT().WriteCount().Return(int64(0), errors.New("boom")).AnyTimes()
+
+	ver := jujuversion.Current.String()
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+ver)
+	stalled := make(chan struct{})
+	s.pool.EXPECT().SetStatus(
+		"0", status.Error, "upgrading database to "+ver+": stalled before the first step after 1m0s",
+	).Do(func(string, status.Status, string) {
+		close(stalled)
+	})
+
+	clk := testclock.NewClock(time.Now())
+	cfg := s.getConfig()
+	cfg.Clock = clk
+	cfg.StallTimeout = time.Minute
+
+	cfg.PerformUpgrade = func(version.Number, []upgrades.Target, upgrades.Stage, func() upgrades.Context, upgrades.StepObserver) error {
+		<-release
+		return errors.New("state pool closed")
+	}
+
+	w, err := upgradedatabase.NewWorker(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+	for i := 0; i < 4; i++ {
+		c.Assert(clk.WaitAdvance(15*time.Second, testing.LongWait, 1), jc.ErrorIsNil)
+	}
+	select {
+	case <-stalled:
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for stall")
+	}
+
+	// The worker stays up, rather than being restarted to run the steps
+	// again, and only exits once the stalled steps have failed.
+	workertest.CheckAlive(c, w)
+	workertest.CleanKill(c, w)
+	select {
+	case <-release:
+	default:
+		c.Fatalf("state pool not closed")
+	}
+}
+
+func (s *workerSuite) TestUpgradeWritingNotStalled(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+	s.ignoreLogging(c)
+
+	s.expectUpgradeRequired(true)
+	s.expectExecution()
+	var writes int64
+	s.pool.EXPECT().WriteCount().DoAndReturn(func() (int64, error) {
+		writes++
+		return writes, nil
+	}).AnyTimes()
+
+	s.pool.EXPECT().SetStatus("0", status.Started, "upgrading database to "+jujuversion.Current.String())
+	s.upgradeInfo.EXPECT().SetStatus(state.UpgradeDBComplete).Return(nil)
+	s.expectSoleControllerRestart()
+	s.pool.EXPECT().SetStatus(
+		"0", status.Started, fmt.Sprintf("database upgrade to %v completed", jujuversion.Current))
+	s.lock.EXPECT().Unlock()
+
+	clk := testclock.NewClock(time.Now())
+	cfg := s.getConfig()
+	cfg.Clock = clk
+	cfg.StallTimeout = time.Minute
+
+	release := make(chan struct{})
+	cfg.PerformUpgrade = func(version.Number, []upgrades.Target, upgrades.Stage, func() upgrades.Context, upgrades.StepObserver) error {
+		<-release
+		return nil
+	}
 
 	w, err := upgradedatabase.NewWorker(cfg)
 	c.Assert(err, jc.ErrorIsNil)
 
+	// The step writes to the database throughout.
+	for i := 0; i < 8; i++ {
+		c.Assert(clk.WaitAdvance(15*time.Second, testing.LongWait, 1), jc.ErrorIsNil)
+	}
+	close(release)
+
+	workertest.CleanKill(c, w)
+}
+
+func (s *workerSuite) TestReportNoUpgrade(c *gc.C) {
+	defer s.setupMocks(c).Finish()
+
+	s.lock.EXPECT().IsUnlocked().Return(true)
+
+	w, err := upgradedatabase.NewWorker(s.getConfig())
+	c.Assert(err, jc.ErrorIsNil)
 	workertest.CleanKill(c, w)
+
+	reporter, ok := w.(interface{ Report() map[string]interface{} })
+	c.Assert(ok, jc.IsTrue)
+	c.Check(reporter.Report(), gc.HasLen, 0)
 }
 
 func (s *workerSuite) getConfig() upgradedatabase.Config {
@@ -332,9 +2262,43 @@ func (s *workerSuite) getConfig() upgradedatabase.Config {
 		Agent:           s.agent,
 		Logger:          s.logger,
 		OpenState:       func() (upgradedatabase.Pool, error) { return s.pool, nil },
-		PerformUpgrade:  func(version.Number, []upgrades.Target, func() upgrades.Context) error { return nil },
-		RetryStrategy:   utils.AttemptStrategy{Delay: time.Millisecond, Min: 3},
-		Clock:           clock.WallClock,
+		PerformUpgrade: func(version.Number, []upgrades.Target, upgrades.Stage, func() upgrades.Context, upgrades.StepObserver) error {
+			return nil
+		},
+		ResumeUpgrade: func(version.Number, []upgrades.Target, upgrades.Stage, string, func() upgrades.Context, upgrades.StepObserver) error {
+			return nil
+		},
+		PreflightCheck:  func(version.Number, []upgrades.Target, string) error { return nil },
+		StepCollections: func(version.Number, []upgrades.Target) []upgrades.StepCollections { return nil },
+		EstimateUpgrade: func(version.Number, []upgrades.Target, func(string) (upgrades.CollectionStats, error)) ([]upgrades.StepEstimate, error) {
+			return nil, nil
+		},
+		ValidateUpgrade: func(func() upgrades.Context) error { return nil },
+		RollbackUpgrade: func(version.Number, []upgrades.Target, upgrades.Stage, error, func() upgrades.Context) error {
+			return nil
+		},
+		CheckSchemaDrift: func(func() upgrades.StateBackend) ([]upgrades.SchemaDrift, error) {
+			return nil, nil
+		},
+		GatedSteps: func(func() upgrades.StateBackend) ([]upgrades.GatedStep, error) {
+			return nil, nil
+		},
+		RunGatedSteps: func(func() upgrades.Context, upgrades.StepObserver) error { return nil },
+		StepPhases:    func(version.Number, []upgrades.Target) []upgrades.StepPhase { return nil },
+		ContractSteps: func(func() upgrades.StateBackend) ([]upgrades.ContractStep, error) {
+			return nil, nil
+		},
+		RunContractSteps: func(func() upgrades.Context, upgrades.StepObserver) error { return nil },
+		EnsureIndexes: func(func() upgrades.StateBackend) ([]state.CollectionIndexes, error) {
+			return nil, nil
+		},
+		SweepOrphans: func(func() upgrades.StateBackend) ([]state.OrphanReport, error) {
+			return nil, nil
+		},
+		InstalledMongoVersion: func() (mongo.Version, error) { return mongo.Version{Major: 4, Minor: 0, Point: 18}, nil },
+		RestartMongo:          func() error { return nil },
+		RetryStrategy:         utils.AttemptStrategy{Delay: time.Millisecond, Min: 3},
+		Clock:                 clock.WallClock,
 	}
 }
 
@@ -345,11 +2309,13 @@ func (s *workerSuite) setupMocks(c *gc.C) *gomock.Controller {
 	s.agent = NewMockAgent(ctrl)
 	s.agentCfg = NewMockConfig(ctrl)
 	s.cfgSetter = NewMockConfigSetter(ctrl)
+	s.logDir = c.MkDir()
 	s.logger = NewMockLogger(ctrl)
 	s.upgradeInfo = NewMockUpgradeInfo(ctrl)
 
 	s.pool = NewMockPool(ctrl)
 	s.pool.EXPECT().Close().Return(nil).MaxTimes(1)
+	s.pool.EXPECT().SetUpgradeBatcher(gomock.Any()).AnyTimes()
 
 	s.watcher = NewMockNotifyWatcher(ctrl)
 	s.watcher.EXPECT().Stop().Return(nil).MaxTimes(1)
@@ -370,10 +2336,55 @@ func (s *workerSuite) expectUpgradeRequired(isPrimary bool) {
 	s.pool.EXPECT().EnsureUpgradeInfo("0", fromVersion, jujuversion.Current).Return(s.upgradeInfo, nil)
 }
 
-// expectExecution simply executes the mutator passed to ChangeConfig.
-// In this case it is worker.runUpgradeSteps.
+// expectExecution sets expectations for a passing pre-flight check and
+// the recording of step collections, then simply executes the mutators
+// passed to ChangeConfig.
+// The mongo servers are found to need no upgrade.
 func (s *workerSuite) expectExecution() {
+	s.upgradeInfo.EXPECT().MongoUpgradePhases().Return(nil).AnyTimes()
+	s.pool.EXPECT().MongoVersion().Return(mongo.Version{Major: 4, Minor: 0, Point: 18}, nil).AnyTimes()
+	s.pool.EXPECT().FeatureCompatibilityVersion().Return("4.0", nil).AnyTimes()
+	s.expectStepsRun()
+}
+
+// expectStepsRun sets expectations for a passing pre-flight check and
+// the recording of step collections, then simply executes the mutators
+// passed to ChangeConfig, leaving the mongo upgrade to the test. The
+// agent config is changed separately for each run of the steps, and for
+// their validation.
+func (s *workerSuite) expectStepsRun() {
+	s.agent.EXPECT().CurrentConfig().Return(s.agentCfg)
+	s.agentCfg.EXPECT().DataDir().Return("/var/lib/juju")
+	s.upgradeInfo.EXPECT().SetStepCollections(gomock.Any()).Return(nil).AnyTimes()
 	s.agent.EXPECT().ChangeConfig(gomock.Any()).DoAndReturn(func(f agent.ConfigMutator) error {
 		return f(s.cfgSetter)
-	})
+	}).AnyTimes()
+	s.cfgSetter.EXPECT().LogDir().Return(s.logDir).AnyTimes()
+	s.pool.EXPECT().ControllerConfig().Return(controller.Config{}, nil).AnyTimes()
+}
+
+// expectApprovalRequired sets expectations for the controller config
+// requiring database upgrades to be approved. It must be called before
+// expectExecution, which otherwise finds approval not to be required.
+func (s *workerSuite) expectApprovalRequired() {
+	s.pool.EXPECT().ControllerConfig().Return(controller.Config{
+		controller.UpgradeRequiresApproval: true,
+	}, nil)
+}
+
+// expectSoleControllerRestart sets expectations for the primary being the
+// only controller, so that it restarts as soon as the upgrade completes.
+func (s *workerSuite) expectSoleControllerRestart() {
+	s.pool.EXPECT().ControllerIDs().Return([]string{"0"}, nil)
+	s.upgradeInfo.EXPECT().SetRestartOrder([]string{"0"}).Return(nil)
+	s.upgradeInfo.EXPECT().RestartOrder().Return([]string{"0"}).AnyTimes()
+	s.upgradeInfo.EXPECT().ControllersRestarted().Return(nil).AnyTimes()
+	s.upgradeInfo.EXPECT().SetControllerRestarted("0").Return(nil)
+}
+
+// expectNotAborted sets expectations for checks, before upgrade steps
+// and after failed attempts, that find the upgrade has not been aborted.
+func (s *workerSuite) expectNotAborted() {
+	s.upgradeInfo.EXPECT().Refresh().Return(nil).AnyTimes()
+	s.upgradeInfo.EXPECT().Status().Return(state.UpgradePending).AnyTimes()
 }